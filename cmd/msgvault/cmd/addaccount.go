@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/microsoft"
 	"github.com/wesm/msgvault/internal/oauth"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -16,11 +17,12 @@ var (
 	forceReauth                 bool
 	oauthAppName                string
 	noDefaultIdentityAddAccount bool
+	accountProvider             string
 )
 
 var addAccountCmd = &cobra.Command{
 	Use:   "add-account <email>",
-	Short: "Add a Gmail account via OAuth",
+	Short: "Add a Gmail or Outlook account via OAuth",
 	Long: `Add a Gmail account by completing the OAuth2 authorization flow.
 
 By default, opens a browser for authorization. Use --headless to see instructions
@@ -32,16 +34,36 @@ the existing token and start a fresh OAuth flow.
 For Google Workspace orgs that require their own OAuth app, use --oauth-app to
 specify a named app from config.toml.
 
+Use --provider outlook to add an Outlook.com or Microsoft 365 mailbox instead.
+It is synced through Microsoft Graph rather than IMAP, and needs the client_id
+of an Azure AD app with the Mail.Read delegated permission in the [microsoft]
+section of config.toml.
+
 Examples:
   msgvault add-account you@gmail.com
   msgvault add-account you@gmail.com --headless
   msgvault add-account you@gmail.com --force
   msgvault add-account you@acme.com --oauth-app acme
-  msgvault add-account you@gmail.com --display-name "Work Account"`,
+  msgvault add-account you@gmail.com --display-name "Work Account"
+  msgvault add-account you@outlook.com --provider outlook`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		email := args[0]
 
+		switch accountProvider {
+		case "gmail":
+		case "outlook":
+			if headless {
+				return fmt.Errorf("--headless is only supported for Gmail accounts")
+			}
+			if cmd.Flags().Changed("oauth-app") {
+				return fmt.Errorf("--oauth-app is only supported for Gmail accounts; Outlook uses the [microsoft] client_id")
+			}
+			return addOutlookAccount(cmd, email)
+		default:
+			return fmt.Errorf("unknown provider %q (supported: gmail, outlook)", accountProvider)
+		}
+
 		if headless && forceReauth {
 			return fmt.Errorf("--headless and --force cannot be used together: --force requires browser-based OAuth which is not available in headless mode")
 		}
@@ -106,7 +128,7 @@ Examples:
 			if forceReauth {
 				return fmt.Errorf("service accounts do not use --force; tokens are minted on demand from the configured service account key")
 			}
-			saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, oauth.Scopes)
+			saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, gmailScopes())
 			if saErr != nil {
 				return fmt.Errorf("service account: %w", saErr)
 			}
@@ -175,7 +197,7 @@ Examples:
 		}
 
 		// Create OAuth manager
-		oauthMgr, err := oauth.NewManager(clientSecretsPath, cfg.TokensDir(), logger)
+		oauthMgr, err := oauth.NewManagerWithScopes(clientSecretsPath, cfg.TokensDir(), logger, gmailScopes())
 		if err != nil {
 			return wrapOAuthError(fmt.Errorf("create oauth manager: %w", err))
 		}
@@ -304,6 +326,77 @@ Examples:
 	},
 }
 
+// addOutlookAccount authorizes an Outlook or Microsoft 365 mailbox for
+// Graph access and registers it as an "outlook" source.
+func addOutlookAccount(cmd *cobra.Command, email string) error {
+	if cfg.Microsoft.ClientID == "" {
+		return fmt.Errorf("microsoft OAuth not configured\n\n" +
+			"Add to your config.toml:\n\n" +
+			"  [microsoft]\n" +
+			"  client_id = \"your-azure-app-client-id\"\n\n" +
+			"The app needs the Mail.Read delegated permission for Microsoft Graph")
+	}
+	msMgr := microsoft.NewGraphManager(
+		cfg.Microsoft.ClientID,
+		cfg.Microsoft.EffectiveTenantID(),
+		cfg.TokensDir(),
+		logger,
+	)
+
+	if forceReauth && msMgr.HasToken(email) {
+		fmt.Printf("Removing existing token for %s...\n", email)
+		if err := msMgr.DeleteToken(email); err != nil {
+			return fmt.Errorf("delete existing token: %w", err)
+		}
+	}
+	authorized := false
+	if !msMgr.HasToken(email) {
+		fmt.Printf("Authorizing %s with Microsoft...\n", email)
+		if err := msMgr.Authorize(cmd.Context(), email); err != nil {
+			return fmt.Errorf("authorization failed: %w", err)
+		}
+		authorized = true
+	}
+
+	s, err := store.Open(cfg.DatabaseDSN())
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+	if err := s.InitSchema(); err != nil {
+		return fmt.Errorf("init schema: %w", err)
+	}
+	if err := runStartupMigrationsForIngest(s); err != nil {
+		return fmt.Errorf("startup migrations: %w", err)
+	}
+
+	source, err := s.GetOrCreateSource("outlook", email)
+	if err != nil {
+		return fmt.Errorf("create source: %w", err)
+	}
+	if accountDisplayName != "" {
+		if err := s.UpdateSourceDisplayName(source.ID, accountDisplayName); err != nil {
+			return fmt.Errorf("set display name: %w", err)
+		}
+	}
+	// Auto-default-identity must run BEFORE the legacy migration
+	// retry — see comment in account_identity.go.
+	if !noDefaultIdentityAddAccount {
+		confirmDefaultIdentity(cmd.OutOrStdout(), s, source.ID, email, email, "account-identifier")
+	}
+	if err := runPostSourceCreateMigrations(s); err != nil {
+		return fmt.Errorf("post-source-create migrations: %w", err)
+	}
+
+	if authorized {
+		fmt.Printf("\nOutlook account %s authorized successfully!\n", email)
+	} else {
+		fmt.Printf("Outlook account %s is already authorized.\n", email)
+	}
+	fmt.Println("Next step: msgvault sync-full", email)
+	return nil
+}
+
 func findGmailSource(
 	s *store.Store, email string,
 ) (*store.Source, error) {
@@ -325,5 +418,6 @@ func init() {
 	addAccountCmd.Flags().StringVar(&accountDisplayName, "display-name", "", "Display name for the account (e.g., \"Work\", \"Personal\")")
 	addAccountCmd.Flags().StringVar(&oauthAppName, "oauth-app", "", "Named OAuth app from config (for Google Workspace orgs)")
 	addAccountCmd.Flags().BoolVar(&noDefaultIdentityAddAccount, "no-default-identity", false, noDefaultIdentityHelp)
+	addAccountCmd.Flags().StringVar(&accountProvider, "provider", "gmail", "Mail provider: gmail or outlook (Microsoft 365 via Graph)")
 	rootCmd.AddCommand(addAccountCmd)
 }
@@ -1223,3 +1223,93 @@ func TestAddAccount_ForceServiceAccountReturnsActionableError(t *testing.T) {
 		t.Fatalf("error = %v, want service accounts do not use --force", err)
 	}
 }
+
+// TestAddAccount_OutlookProvider verifies flag validation for
+// --provider outlook and that an existing Graph token registers an
+// outlook source without re-authorizing.
+func TestAddAccount_OutlookProvider(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		args    []string
+		noMS    bool
+		wantErr string
+	}{
+		{"unknown provider", []string{"--provider", "yahoo"}, false, "unknown provider"},
+		{"headless", []string{"--provider", "outlook", "--headless"}, false, "--headless"},
+		{"oauth app", []string{"--provider", "outlook", "--oauth-app", "acme"}, false, "--oauth-app"},
+		{"not configured", []string{"--provider", "outlook"}, true, "microsoft OAuth not configured"},
+		{"existing token", []string{"--provider", "outlook", "--display-name", "Work"}, false, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			tokensDir := filepath.Join(tmpDir, "tokens")
+			if err := os.MkdirAll(tokensDir, 0700); err != nil {
+				t.Fatalf("mkdir: %v", err)
+			}
+			tokenData, _ := json.Marshal(map[string]string{
+				"access_token":  "fake",
+				"refresh_token": "fake",
+				"token_type":    "Bearer",
+			})
+			tokenPath := filepath.Join(tokensDir, "microsoft_graph_alice@example.com.json")
+			if err := os.WriteFile(tokenPath, tokenData, 0600); err != nil {
+				t.Fatalf("write token: %v", err)
+			}
+
+			savedCfg, savedLogger := cfg, logger
+			savedProvider, savedHeadless, savedApp, savedName := accountProvider, headless, oauthAppName, accountDisplayName
+			defer func() {
+				cfg, logger = savedCfg, savedLogger
+				accountProvider, headless, oauthAppName, accountDisplayName = savedProvider, savedHeadless, savedApp, savedName
+			}()
+			cfg = &config.Config{
+				HomeDir: tmpDir,
+				Data:    config.DataConfig{DataDir: tmpDir},
+			}
+			if !tc.noMS {
+				cfg.Microsoft.ClientID = "test-client-id"
+			}
+			logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+			testCmd := &cobra.Command{
+				Use: "add-account <email>", Args: cobra.ExactArgs(1),
+				RunE: addAccountCmd.RunE,
+			}
+			testCmd.Flags().StringVar(&oauthAppName, "oauth-app", "", "")
+			testCmd.Flags().BoolVar(&headless, "headless", false, "")
+			testCmd.Flags().BoolVar(&forceReauth, "force", false, "")
+			testCmd.Flags().StringVar(&accountDisplayName, "display-name", "", "")
+			testCmd.Flags().BoolVar(&noDefaultIdentityAddAccount, "no-default-identity", true, "")
+			testCmd.Flags().StringVar(&accountProvider, "provider", "gmail", "")
+
+			root := newTestRootCmd()
+			root.AddCommand(testCmd)
+			root.SetArgs(append([]string{"add-account", "alice@example.com"}, tc.args...))
+
+			err := root.Execute()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("error = %v, want it to mention %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			s, err := store.Open(filepath.Join(tmpDir, "msgvault.db"))
+			if err != nil {
+				t.Fatalf("open store: %v", err)
+			}
+			defer func() { _ = s.Close() }()
+			sources, err := s.GetSourcesByIdentifier("alice@example.com")
+			if err != nil {
+				t.Fatalf("get sources: %v", err)
+			}
+			if len(sources) != 1 || sources[0].SourceType != "outlook" ||
+				sources[0].DisplayName.String != "Work" {
+				t.Errorf("sources = %+v, want one outlook source named Work", sources)
+			}
+		})
+	}
+}
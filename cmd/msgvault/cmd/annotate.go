@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	noteClear bool
+	tagRemove bool
+)
+
+var noteCmd = &cobra.Command{
+	Use:   "note <message-id> [text]",
+	Short: "Show or set a local note on a message",
+	Long: `Show, set, or remove the note on a message. Notes, like pins and tags, are
+kept only in this archive and are never synced back to Gmail or any other
+source. Find them again with 'msgvault search note:<text>'.
+
+The message is given by its msgvault ID or its source message ID, as shown by
+'msgvault search'. Without text, the current note is printed.
+
+Examples:
+  msgvault note 12345 "renew the lease by May"
+  msgvault note 12345
+  msgvault note 12345 --clear`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAnnotatedMessage(cmd, args[0], func(s *store.Store, id int64) error {
+			if noteClear {
+				if len(args) > 1 {
+					return fmt.Errorf("--clear takes no note text")
+				}
+				if err := s.SetMessageNote(id, ""); err != nil {
+					return err
+				}
+				fmt.Printf("Removed the note on message %d\n", id)
+				return nil
+			}
+			if len(args) == 1 {
+				a, err := s.GetAnnotation(id)
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(a)
+				}
+				if a.Note == "" {
+					fmt.Printf("Message %d has no note\n", id)
+				} else {
+					fmt.Println(a.Note)
+				}
+				return nil
+			}
+			if err := s.SetMessageNote(id, strings.Join(args[1:], " ")); err != nil {
+				return err
+			}
+			fmt.Printf("Saved the note on message %d\n", id)
+			return nil
+		})
+	},
+}
+
+var pinCmd = &cobra.Command{
+	Use:   "pin <message-id>",
+	Short: "Pin a message",
+	Long: `Pin a message so 'msgvault search is:pinned' finds it. Pins are local and
+never synced back to the source.
+
+Example:
+  msgvault pin 12345`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAnnotatedMessage(cmd, args[0], func(s *store.Store, id int64) error {
+			if err := s.SetMessagePinned(id, true); err != nil {
+				return err
+			}
+			fmt.Printf("Pinned message %d\n", id)
+			return nil
+		})
+	},
+}
+
+var unpinCmd = &cobra.Command{
+	Use:   "unpin <message-id>",
+	Short: "Unpin a message",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAnnotatedMessage(cmd, args[0], func(s *store.Store, id int64) error {
+			if err := s.SetMessagePinned(id, false); err != nil {
+				return err
+			}
+			fmt.Printf("Unpinned message %d\n", id)
+			return nil
+		})
+	},
+}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <message-id> <tag>...",
+	Short: "Add local tags to a message",
+	Long: `Add tags of your own to a message, or with --remove take them off. Tags are
+single lowercase words, kept apart from the source's labels and never synced
+back to it. Find tagged messages with 'msgvault search tag:<tag>' and list
+the tags in use with 'msgvault list-tags'.
+
+Examples:
+  msgvault tag 12345 tax 2024
+  msgvault tag 12345 2024 --remove`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAnnotatedMessage(cmd, args[0], func(s *store.Store, id int64) error {
+			tags := args[1:]
+			if tagRemove {
+				if err := s.RemoveMessageTags(id, tags); err != nil {
+					return err
+				}
+			} else if err := s.AddMessageTags(id, tags); err != nil {
+				return err
+			}
+			a, err := s.GetAnnotation(id)
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(a)
+			}
+			if len(a.Tags) == 0 {
+				fmt.Printf("Message %d has no tags\n", id)
+			} else {
+				fmt.Printf("Message %d tags: %s\n", id, strings.Join(a.Tags, ", "))
+			}
+			return nil
+		})
+	},
+}
+
+var listTagsCmd = &cobra.Command{
+	Use:   "list-tags",
+	Short: "List local tags and how many messages carry each",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("list-tags"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		tags, err := s.ListTags()
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if tags == nil {
+				tags = []store.TagCount{}
+			}
+			return printJSON(tags)
+		}
+		if len(tags) == 0 {
+			fmt.Println("No tags yet. Tag a message with 'msgvault tag <message-id> <tag>'.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "TAG\tMESSAGES")
+		_, _ = fmt.Fprintln(w, "───\t────────")
+		for _, tc := range tags {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", tc.Tag, formatCount(tc.Count))
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+// withAnnotatedMessage opens the local store, resolves messageRef to a
+// message ID, and calls fn with both.
+func withAnnotatedMessage(cmd *cobra.Command, messageRef string, fn func(s *store.Store, id int64) error) error {
+	if err := MustBeLocal(cmd.Name()); err != nil {
+		return err
+	}
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	resolved, err := resolveMessage(query.NewSQLiteEngine(s.DB()), cmd, messageRef)
+	if err != nil {
+		return err
+	}
+	return fn(s, resolved.ID)
+}
+
+func init() {
+	noteCmd.Flags().BoolVar(&noteClear, "clear", false, "remove the note")
+	tagCmd.Flags().BoolVar(&tagRemove, "remove", false, "remove the tags instead of adding them")
+	rootCmd.AddCommand(noteCmd)
+	rootCmd.AddCommand(pinCmd)
+	rootCmd.AddCommand(unpinCmd)
+	rootCmd.AddCommand(tagCmd)
+	rootCmd.AddCommand(listTagsCmd)
+}
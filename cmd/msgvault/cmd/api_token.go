@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var apiTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for the server",
+	Long: `API tokens authenticate clients of 'msgvault serve' over REST, gRPC,
+JMAP, and IMAP, alongside the keys in config.toml. Tokens are stored
+hashed in the vault, so each is shown only once, when created.
+
+Each token carries one or more scopes:
+  read    messages, attachments, stats, and aggregates (also JMAP, IMAP)
+  search  the search endpoints
+  admin   everything, including adding accounts, syncs, and raw SQL
+
+Once any token is active, the server requires authentication even if
+config.toml sets no api_key. Send a token like an API key:
+  Authorization: Bearer mvt_...`,
+}
+
+var apiTokenCreateCmd = &cobra.Command{
+	Use:   "create <name> --scope read,search",
+	Short: "Create an API token and print it once",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAPITokenCreate,
+}
+
+var apiTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	RunE:  runAPITokenList,
+}
+
+var apiTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "Revoke an API token",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAPITokenRevoke,
+}
+
+var apiTokenScopes []string
+
+func runAPITokenCreate(_ *cobra.Command, args []string) error {
+	st, err := openStoreAndInit()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = st.Close() }()
+
+	var scopes []string
+	for _, sc := range apiTokenScopes {
+		if sc = strings.TrimSpace(strings.ToLower(sc)); sc != "" {
+			scopes = append(scopes, sc)
+		}
+	}
+	token, t, err := st.CreateAPIToken(args[0], scopes)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created token %q with scopes %s.\n", t.Name, strings.Join(t.Scopes, ","))
+	fmt.Println("Copy it now; it will not be shown again:")
+	fmt.Println()
+	fmt.Println(token)
+	return nil
+}
+
+func runAPITokenList(_ *cobra.Command, _ []string) error {
+	st, err := openStoreAndInit()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = st.Close() }()
+
+	tokens, err := st.ListAPITokens()
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		out := []apiTokenJSON{}
+		for _, t := range tokens {
+			tj := apiTokenJSON{Name: t.Name, Scopes: t.Scopes, CreatedAt: t.CreatedAt}
+			if t.LastUsedAt.Valid {
+				tj.LastUsedAt = &t.LastUsedAt.Time
+			}
+			if t.RevokedAt.Valid {
+				tj.RevokedAt = &t.RevokedAt.Time
+			}
+			out = append(out, tj)
+		}
+		return printJSON(out)
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No API tokens.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSCOPES\tCREATED\tLAST USED\tSTATUS")
+	for _, t := range tokens {
+		lastUsed := "never"
+		if t.LastUsedAt.Valid {
+			lastUsed = t.LastUsedAt.Time.Local().Format("2006-01-02 15:04")
+		}
+		status := "active"
+		if t.RevokedAt.Valid {
+			status = "revoked " + t.RevokedAt.Time.Local().Format("2006-01-02")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			t.Name, strings.Join(t.Scopes, ","),
+			t.CreatedAt.Local().Format("2006-01-02 15:04"),
+			lastUsed, status)
+	}
+	_ = w.Flush()
+	return nil
+}
+
+// apiTokenJSON is one token in 'token list --json' output. The secret
+// is never stored, so it is never shown.
+type apiTokenJSON struct {
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+func runAPITokenRevoke(_ *cobra.Command, args []string) error {
+	st, err := openStoreAndInit()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = st.Close() }()
+
+	if err := st.RevokeAPIToken(args[0]); err != nil {
+		if errors.Is(err, store.ErrAPITokenNotFound) {
+			return fmt.Errorf("no active token named %q", args[0])
+		}
+		return err
+	}
+	fmt.Printf("Revoked token %q.\n", args[0])
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(apiTokenCmd)
+	apiTokenCmd.AddCommand(apiTokenCreateCmd)
+	apiTokenCmd.AddCommand(apiTokenListCmd)
+	apiTokenCmd.AddCommand(apiTokenRevokeCmd)
+
+	apiTokenCreateCmd.Flags().StringSliceVar(&apiTokenScopes,
+		"scope", nil, "Comma-separated scopes: read, search, admin")
+	_ = apiTokenCreateCmd.MarkFlagRequired("scope")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestAPITokenCommands(t *testing.T) {
+	savedCfg, savedScopes := cfg, apiTokenScopes
+	defer func() { cfg, apiTokenScopes = savedCfg, savedScopes }()
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+
+	apiTokenScopes = []string{"Read", " search "}
+	done := captureStdout(t)
+	if err := runAPITokenCreate(&cobra.Command{}, []string{"laptop"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	out := done()
+	if !strings.Contains(out, `"laptop" with scopes read,search`) {
+		t.Errorf("create output missing scopes:\n%s", out)
+	}
+	if !strings.Contains(out, store.APITokenPrefix) {
+		t.Errorf("create output missing token:\n%s", out)
+	}
+
+	apiTokenScopes = []string{"write"}
+	if err := runAPITokenCreate(&cobra.Command{}, []string{"bad"}); err == nil {
+		t.Error("expected unknown scope to be rejected")
+	}
+
+	if err := runAPITokenRevoke(&cobra.Command{}, []string{"missing"}); err == nil ||
+		!strings.Contains(err.Error(), `no active token named "missing"`) {
+		t.Errorf("revoke missing: err = %v", err)
+	}
+
+	done = captureStdout(t)
+	if err := runAPITokenRevoke(&cobra.Command{}, []string{"laptop"}); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	_ = done()
+
+	done = captureStdout(t)
+	if err := runAPITokenList(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	out = done()
+	for _, want := range []string{"NAME", "laptop", "read,search", "never", "revoked"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list output missing %q:\n%s", want, out)
+		}
+	}
+}
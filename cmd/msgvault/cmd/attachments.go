@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/rules"
+)
+
+var attachmentsFetchQuery string
+
+var attachmentsCmd = &cobra.Command{
+	Use:   "attachments",
+	Short: "Manage the attachments of archived messages",
+}
+
+var attachmentsFetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Extract the attachments a sync with --skip-attachments left out",
+	Long: `Extract the attachments of messages synced with --skip-attachments (or
+[sync] skip_attachments) into the attachments directory.
+
+Such messages are archived with their raw MIME, so their attachments are
+counted and searchable with has:attachment, but not stored as files until
+this command extracts them. Nothing is downloaded again.
+
+Use --query to limit the run to messages matching a search (same syntax
+as 'search'). Messages whose attachments are already stored are skipped,
+so the command can be run again after an interruption.
+
+Examples:
+  msgvault attachments fetch
+  msgvault attachments fetch --query "from:bob@example.com after:2024-01-01"`,
+	Args: cobra.NoArgs,
+	RunE: runAttachmentsFetch,
+}
+
+func runAttachmentsFetch(cmd *cobra.Command, _ []string) error {
+	if err := MustBeLocal("attachments fetch"); err != nil {
+		return err
+	}
+
+	ctx := cmd.Context()
+	lock, err := acquireOpLock(ctx, "attachments fetch")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	opts := importer.FetchAttachmentsOptions{AttachmentsDir: cfg.AttachmentsDir()}
+	if strings.TrimSpace(attachmentsFetchQuery) != "" {
+		q, err := parseLocalQuery(s, attachmentsFetchQuery)
+		if err != nil {
+			return err
+		}
+		msgs, err := rules.Matching(s, q, 0, 0)
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		opts.IDs = make([]int64, len(msgs))
+		for i, m := range msgs {
+			opts.IDs[i] = m.ID
+		}
+		if len(opts.IDs) == 0 {
+			fmt.Println("No messages match.")
+			return nil
+		}
+	}
+	opts.Progress = func(done, total int) {
+		if done%1000 == 0 {
+			fmt.Fprintf(os.Stderr, "Fetched attachments of %s of %s messages...\n",
+				formatCount(int64(done)), formatCount(int64(total)))
+		}
+	}
+
+	res, err := importer.FetchAttachments(ctx, s, opts, logger)
+	if err != nil {
+		return fmt.Errorf("fetch attachments: %w", err)
+	}
+	if res.Scanned == 0 {
+		fmt.Println("No attachments to fetch.")
+		return nil
+	}
+
+	if res.Files > 0 {
+		if _, err := buildCache(cfg.DatabaseDSN(), cfg.AnalyticsDir(), true); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cache rebuild failed: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Run 'msgvault build-cache --full-rebuild' to retry.\n")
+		}
+	}
+
+	fmt.Printf("Stored %s attachments of %s messages",
+		formatCount(int64(res.Files)), formatCount(int64(res.Fetched)))
+	if res.Failed > 0 {
+		fmt.Printf("; %s messages failed (see the log)", formatCount(int64(res.Failed)))
+	}
+	fmt.Println(".")
+	return nil
+}
+
+func init() {
+	attachmentsFetchCmd.Flags().StringVar(&attachmentsFetchQuery, "query", "",
+		"only fetch the attachments of messages matching this search query")
+	attachmentsCmd.AddCommand(attachmentsFetchCmd)
+	rootCmd.AddCommand(attachmentsCmd)
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/bench"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	benchMessages int
+	benchSeed     int64
+	benchRuns     int
+	benchDir      string
+	benchKeep     bool
+	benchNoCache  bool
+	benchSave     string
+	benchCompare  string
+	benchReal     bool
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Benchmark ingest and queries on a synthetic vault",
+	Long: `Generate a synthetic vault and measure ingest throughput, full-text
+search latency, and aggregate query latency (against SQLite and the
+Parquet analytics cache). Your own vault is not touched.
+
+The same --messages and --seed always generate the same vault, so runs
+are comparable. Save a run with --save and compare a later one against
+it with --compare to spot regressions or weigh hardware; --dir puts the
+synthetic vault on the disk you want to measure. --realistic generates
+the varied corpus of 'msgvault dev gen' instead of plain text mail.
+
+Examples:
+  msgvault bench
+  msgvault bench --messages 100000 --save baseline.json
+  msgvault bench --messages 100000 --compare baseline.json --dir /mnt/nas`,
+	Args: cobra.NoArgs,
+	RunE: runBench,
+}
+
+func runBench(cmd *cobra.Command, _ []string) error {
+	if benchMessages <= 0 {
+		return fmt.Errorf("--messages must be positive")
+	}
+	if benchRuns <= 0 {
+		return fmt.Errorf("--runs must be positive")
+	}
+	var baseline *bench.Report
+	if benchCompare != "" {
+		var err error
+		if baseline, err = loadBenchReport(benchCompare); err != nil {
+			return err
+		}
+	}
+
+	// Cache build progress goes to stderr under --json.
+	jsonOut, restore := humanOutputToStderr()
+	defer restore()
+
+	dir, err := os.MkdirTemp(benchDir, "msgvault-bench-")
+	if err != nil {
+		return fmt.Errorf("create bench vault: %w", err)
+	}
+	if benchKeep {
+		defer fmt.Fprintf(os.Stderr, "Synthetic vault kept at %s\n", dir)
+	} else {
+		defer func() { _ = os.RemoveAll(dir) }()
+	}
+
+	opts := bench.Options{
+		Messages:  benchMessages,
+		Seed:      benchSeed,
+		Runs:      benchRuns,
+		Dir:       dir,
+		Realistic: benchReal,
+		Progress:  func(stage string) { fmt.Fprintf(os.Stderr, "%s...\n", stage) },
+	}
+	if !benchNoCache {
+		opts.Analytics = benchAnalytics
+	}
+	rep, err := bench.Run(cmd.Context(), opts)
+	if err != nil {
+		return err
+	}
+	rep.Version = Version
+
+	if benchSave != "" {
+		if err := saveBenchReport(benchSave, rep); err != nil {
+			return err
+		}
+	}
+
+	if jsonOutput {
+		out := map[string]any{"report": rep}
+		if baseline != nil {
+			out["comparison"] = bench.Compare(baseline, rep)
+		}
+		return printJSONTo(jsonOut, out)
+	}
+	printBenchReport(os.Stdout, rep)
+	if baseline != nil {
+		fmt.Printf("\nCompared with %s (%s, %d messages):\n", benchCompare,
+			baseline.StartedAt.Format("2006-01-02 15:04"), baseline.Messages)
+		if baseline.Messages != rep.Messages || baseline.Seed != rep.Seed {
+			fmt.Println("Note: the baseline used a different vault size or seed.")
+		}
+		if baseline.Realistic != rep.Realistic {
+			fmt.Println("Note: the baseline used a different corpus (--realistic).")
+		}
+		printBenchComparison(os.Stdout, bench.Compare(baseline, rep))
+	}
+	if benchSave != "" {
+		fmt.Printf("\nSaved to %s\n", benchSave)
+	}
+	return nil
+}
+
+// benchAnalytics builds the Parquet cache for the bench vault and opens
+// a DuckDB engine over it, as the TUI does.
+func benchAnalytics(_ context.Context, st *store.Store, dbPath string) (query.Engine, error) {
+	analyticsDir := filepath.Join(filepath.Dir(dbPath), "analytics")
+	if _, err := buildCache(dbPath, analyticsDir, true); err != nil {
+		return nil, err
+	}
+	return query.NewDuckDBEngine(analyticsDir, dbPath, st.DB())
+}
+
+func printBenchReport(w io.Writer, rep *bench.Report) {
+	_, _ = fmt.Fprintf(w, "msgvault %s bench: %s messages (seed %d), %d runs per query, %s/%s, %d CPUs\n",
+		rep.Version, formatCount(int64(rep.Messages)), rep.Seed, rep.Runs, rep.OS, rep.Arch, rep.CPUs)
+	_, _ = fmt.Fprintf(w, "Vault size: %s\n\n", formatSize(rep.VaultSize))
+
+	var skipped []bench.Result
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STAGE\tRESULT\tP95\tROWS")
+	for _, r := range rep.Results {
+		switch {
+		case r.Skipped != "":
+			skipped = append(skipped, r)
+		case r.Unit == bench.UnitMilliseconds:
+			_, _ = fmt.Fprintf(tw, "%s\t%.2f ms\t%.2f ms\t%d\n", r.Name, r.Value, r.P95, r.Hits)
+		default:
+			_, _ = fmt.Fprintf(tw, "%s\t%s %s\t\t%d\n", r.Name, formatCount(int64(r.Value)), r.Unit, r.Hits)
+		}
+	}
+	_ = tw.Flush()
+	for _, r := range skipped {
+		_, _ = fmt.Fprintf(w, "Not measured: %s (%s)\n", r.Name, r.Skipped)
+	}
+}
+
+func printBenchComparison(w io.Writer, deltas []bench.Delta) {
+	if len(deltas) == 0 {
+		_, _ = fmt.Fprintln(w, "No measurements in common.")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STAGE\tBASELINE\tCURRENT\tCHANGE")
+	for _, d := range deltas {
+		verdict := "worse"
+		if d.Better {
+			verdict = "better"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%.2f %s\t%.2f %s\t%+.1f%% %s\n",
+			d.Name, d.Baseline, d.Unit, d.Current, d.Unit, d.Change, verdict)
+	}
+	_ = tw.Flush()
+}
+
+func loadBenchReport(path string) (*bench.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read baseline: %w", err)
+	}
+	var rep bench.Report
+	if err := json.Unmarshal(data, &rep); err != nil {
+		return nil, fmt.Errorf("parse baseline %s: %w", path, err)
+	}
+	return &rep, nil
+}
+
+func saveBenchReport(path string, rep *bench.Report) error {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("save report: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().IntVar(&benchMessages, "messages", 10000, "Number of synthetic messages to generate")
+	benchCmd.Flags().Int64Var(&benchSeed, "seed", 1, "Generator seed; the same seed yields the same vault")
+	benchCmd.Flags().IntVar(&benchRuns, "runs", 20, "Timed repetitions of each query")
+	benchCmd.Flags().StringVar(&benchDir, "dir", "", "Create the synthetic vault under this directory (default: system temp dir)")
+	benchCmd.Flags().BoolVar(&benchKeep, "keep", false, "Keep the synthetic vault instead of deleting it")
+	benchCmd.Flags().BoolVar(&benchNoCache, "no-cache", false, "Skip building and timing the Parquet analytics cache")
+	benchCmd.Flags().StringVar(&benchSave, "save", "", "Save the report as JSON to this file")
+	benchCmd.Flags().StringVar(&benchCompare, "compare", "", "Compare against a report saved with --save")
+	benchCmd.Flags().BoolVar(&benchReal, "realistic", false, "Generate the realistic corpus of 'msgvault dev gen'")
+}
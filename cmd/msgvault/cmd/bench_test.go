@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestBench_SaveAndCompare(t *testing.T) {
+	savedMessages, savedRuns, savedDir := benchMessages, benchRuns, benchDir
+	savedNoCache, savedSave, savedCompare := benchNoCache, benchSave, benchCompare
+	defer func() {
+		benchMessages, benchRuns, benchDir = savedMessages, savedRuns, savedDir
+		benchNoCache, benchSave, benchCompare = savedNoCache, savedSave, savedCompare
+	}()
+
+	tmp := t.TempDir()
+	report := filepath.Join(tmp, "baseline.json")
+	benchMessages, benchRuns, benchDir, benchNoCache = 60, 2, tmp, true
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+
+	benchSave, benchCompare = report, ""
+	done := captureStdout(t)
+	err := runBench(cmd, nil)
+	out := done()
+	if err != nil {
+		t.Fatalf("runBench: %v", err)
+	}
+	for _, want := range []string{"60 messages (seed 1)", "ingest", "aggregate: Senders", "Not measured: encryption overhead", "Saved to"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	benchSave, benchCompare = "", report
+	done = captureStdout(t)
+	err = runBench(cmd, nil)
+	out = done()
+	if err != nil {
+		t.Fatalf("runBench --compare: %v", err)
+	}
+	if !strings.Contains(out, "Compared with "+report) || !strings.Contains(out, "BASELINE") {
+		t.Errorf("missing comparison:\n%s", out)
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(tmp, "msgvault-bench-*"))
+	if len(matches) != 0 {
+		t.Errorf("synthetic vaults left behind: %v", matches)
+	}
+}
+
+func TestBench_MissingBaseline(t *testing.T) {
+	saved := benchCompare
+	defer func() { benchCompare = saved }()
+	benchCompare = filepath.Join(t.TempDir(), "missing.json")
+
+	if err := runBench(&cobra.Command{}, nil); err == nil || !strings.Contains(err.Error(), "read baseline") {
+		t.Errorf("runBench() error = %v, want read baseline error", err)
+	}
+}
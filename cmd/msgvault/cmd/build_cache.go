@@ -32,7 +32,7 @@ var buildCacheMu sync.Mutex
 // columns are added/removed/renamed in the COPY queries below so that
 // incremental builds automatically trigger a full rebuild instead of
 // producing Parquet files with mismatched schemas.
-const cacheSchemaVersion = 5 // v5: add conversation_type to conversations Parquet
+const cacheSchemaVersion = 6 // v6: add language to messages Parquet
 
 // syncState tracks the message and sync-run watermarks covered by the cache.
 type syncState struct {
@@ -52,7 +52,8 @@ This command exports normalized tables to Parquet files for fast aggregate queri
 DuckDB joins the Parquet files at query time, which is much faster than joining
 during export (especially for incremental updates).
 
-The cache files are stored in ~/.msgvault/analytics/:
+The cache files are stored in the analytics directory (under the cache
+directory, or the data directory when --home or data_dir is set):
   - messages/year=*/     Core message data, partitioned by year
   - participants/        Email addresses and domains
   - message_recipients/  Links messages to participants (from/to/cc/bcc)
@@ -302,6 +303,7 @@ func buildCache(dbPath, analyticsDir string, fullRebuild bool) (*buildResult, er
 			m.deleted_from_source_at,
 			m.sender_id,
 			COALESCE(TRY_CAST(m.message_type AS VARCHAR), '') as message_type,
+			TRY_CAST(m.language AS VARCHAR) as language,
 			CAST(EXTRACT(YEAR FROM m.sent_at) AS INTEGER) as year,
 			CAST(EXTRACT(MONTH FROM m.sent_at) AS INTEGER) as month
 		FROM sqlite_db.messages m
@@ -665,7 +667,8 @@ func setupSQLiteSource(duckDB *sql.DB, dbPath string) (cleanup func(), err error
 	// CSV fallback: export SQLite tables to CSV, create DuckDB views.
 	// Prefer the database's parent directory for temp files (avoids
 	// cross-device moves), but fall back through system temp and
-	// ~/.msgvault/tmp/ for read-only or restricted environments.
+	// the msgvault cache directory for read-only or restricted
+	// environments.
 	tmpDir, err := config.MkTempDir(".cache-tmp-*", filepath.Dir(dbPath))
 	if err != nil {
 		return nil, err
@@ -688,7 +691,7 @@ func setupSQLiteSource(duckDB *sql.DB, dbPath string) (cleanup func(), err error
 		// `deleted_at IS NULL` filter on this path the same way it does
 		// on the sqlite_scanner path; otherwise DuckDB binds against a
 		// CSV view that lacks the column and the export fails on Windows.
-		{"messages", "SELECT id, source_id, source_message_id, conversation_id, subject, snippet, sent_at, size_estimate, has_attachments, attachment_count, deleted_from_source_at, deleted_at, sender_id, message_type FROM messages WHERE sent_at IS NOT NULL",
+		{"messages", "SELECT id, source_id, source_message_id, conversation_id, subject, snippet, sent_at, size_estimate, has_attachments, attachment_count, deleted_from_source_at, deleted_at, sender_id, message_type, language FROM messages WHERE sent_at IS NOT NULL",
 			"types={'sent_at': 'TIMESTAMP', 'deleted_from_source_at': 'TIMESTAMP', 'deleted_at': 'TIMESTAMP'}"},
 		{"message_recipients", "SELECT message_id, participant_id, recipient_type, display_name FROM message_recipients", ""},
 		{"message_labels", "SELECT message_id, label_id FROM message_labels", ""},
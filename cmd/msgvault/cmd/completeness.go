@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var completenessCmd = &cobra.Command{
+	Use:   "completeness [email]",
+	Short: "Show how complete each account's archive is",
+	Long: `Show how complete the archive of each account, or of one, looks.
+
+The score compares the messages archived with the count the provider
+reported at the last sync (Gmail's total). Gmail's total includes Spam
+and Trash, so an archive that skips them scores a little under 100%.
+Accounts without a reported total, such as imports, have no score.
+
+Below it, a histogram shows the messages archived in each month, one
+row per year, and gaps list the runs of empty months inside the
+account's date range, which often mean a sync or import missed them.
+Accounts with fewer than a few messages in a typical month have no gaps.
+
+Examples:
+  msgvault completeness
+  msgvault completeness you@gmail.com --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("completeness"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		var sources []*store.Source
+		if len(args) == 1 {
+			src, err := resolveSource(s, args[0], "")
+			if err != nil {
+				return err
+			}
+			sources = []*store.Source{src}
+		} else if sources, err = s.ListSources(""); err != nil {
+			return fmt.Errorf("list accounts: %w", err)
+		}
+
+		results := make([]*store.Completeness, 0, len(sources))
+		for _, src := range sources {
+			c, err := s.SourceCompleteness(src.ID)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+			results = append(results, c)
+		}
+
+		if jsonOutput {
+			return printJSON(results)
+		}
+		if len(results) == 0 {
+			fmt.Println("No accounts. Use 'msgvault add-account <email>' to add one.")
+			return nil
+		}
+		for i, c := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			printCompleteness(c)
+		}
+		return nil
+	},
+}
+
+func printCompleteness(c *store.Completeness) {
+	fmt.Printf("%s (%s)\n", c.Account, c.Type)
+	switch {
+	case c.Score != nil:
+		asOf := ""
+		if c.RemoteTotalAt != nil {
+			asOf = ", as of " + i18n.Date(*c.RemoteTotalAt)
+		}
+		fmt.Printf("  Score:    %s (%s of %s messages reported%s)\n",
+			formatPercent(*c.Score), formatCount(c.Archived), formatCount(*c.RemoteTotal), asOf)
+	default:
+		fmt.Printf("  Score:    n/a (%s messages; the provider reported no total)\n", formatCount(c.Archived))
+	}
+	if len(c.Months) == 0 {
+		fmt.Println("  Coverage: no dated messages")
+		return
+	}
+	fmt.Printf("  Coverage: %s to %s\n", c.Months[0].Month, c.Months[len(c.Months)-1].Month)
+	if c.Undated > 0 {
+		fmt.Printf("  Undated:  %s messages\n", formatCount(c.Undated))
+	}
+	if len(c.Gaps) == 0 {
+		fmt.Println("  Gaps:     none")
+	}
+	for i, g := range c.Gaps {
+		label := "           "
+		if i == 0 {
+			label = "  Gaps:    "
+		}
+		if g.Months == 1 {
+			fmt.Printf("%s %s (1 month)\n", label, g.From)
+		} else {
+			fmt.Printf("%s %s to %s (%d months)\n", label, g.From, g.To, g.Months)
+		}
+	}
+	fmt.Println()
+	fmt.Println("        JFMAMJJASOND")
+	for _, row := range monthHistogram(c.Months) {
+		fmt.Println("  " + row)
+	}
+}
+
+// histogramBars are the bar heights of monthHistogram, lowest first.
+var histogramBars = []rune("▁▂▃▄▅▆▇█")
+
+// monthHistogram renders months, as FillMonths returns them, one row
+// per year: the year, a bar per month scaled to the busiest month, and
+// the year's total. An empty month is "·", and months before the first
+// or after the last are blank.
+func monthHistogram(months []store.MonthCount) []string {
+	var peak int64
+	for _, m := range months {
+		peak = max(peak, m.Count)
+	}
+	type year struct {
+		bars  [12]rune
+		total int64
+	}
+	years := map[string]*year{}
+	var order []string
+	for _, m := range months {
+		y, mon, ok := strings.Cut(m.Month, "-")
+		if !ok {
+			continue
+		}
+		var idx int
+		if _, err := fmt.Sscanf(mon, "%d", &idx); err != nil || idx < 1 || idx > 12 {
+			continue
+		}
+		row := years[y]
+		if row == nil {
+			row = &year{}
+			for i := range row.bars {
+				row.bars[i] = ' '
+			}
+			years[y] = row
+			order = append(order, y)
+		}
+		row.total += m.Count
+		switch {
+		case m.Count == 0:
+			row.bars[idx-1] = '·'
+		default:
+			level := int(m.Count * int64(len(histogramBars)-1) / peak)
+			row.bars[idx-1] = histogramBars[level]
+		}
+	}
+	out := make([]string, 0, len(order))
+	for _, y := range order {
+		out = append(out, fmt.Sprintf("%s  %s  %s", y, string(years[y].bars[:]), formatCount(years[y].total)))
+	}
+	return out
+}
+
+// formatPercent formats a fraction as a percentage, rounding down so
+// that only a whole archive shows as 100%.
+func formatPercent(f float64) string {
+	if f >= 1 {
+		return "100%"
+	}
+	return fmt.Sprintf("%.1f%%", float64(int(f*1000))/10)
+}
+
+func init() {
+	rootCmd.AddCommand(completenessCmd)
+}
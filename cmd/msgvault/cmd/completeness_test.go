@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestMonthHistogram(t *testing.T) {
+	months := store.FillMonths([]store.MonthCount{
+		{Month: "2023-11", Count: 8},
+		{Month: "2024-01", Count: 1},
+		{Month: "2024-02", Count: 4},
+	})
+	want := []string{
+		"2023            █·  8",
+		"2024  ▁▄            5",
+	}
+	if got := monthHistogram(months); !reflect.DeepEqual(got, want) {
+		t.Errorf("monthHistogram =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestFormatPercent(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{1, "100%"},
+		{1.2, "100%"},
+		{0.9999, "99.9%"},
+		{0.5, "50.0%"},
+		{0, "0.0%"},
+	}
+	for _, tt := range tests {
+		if got := formatPercent(tt.f); got != tt.want {
+			t.Errorf("formatPercent(%v) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
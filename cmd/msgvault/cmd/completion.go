@@ -2,8 +2,15 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/deletion"
+	"github.com/wesm/msgvault/internal/store"
 )
 
 var completionCmd = &cobra.Command{
@@ -33,6 +40,11 @@ Fish:
 
 PowerShell:
   PS> msgvault completion powershell | Out-String | Invoke-Expression
+
+Besides commands and flags, completion suggests values from the vault:
+account identifiers (sync, verify, --account, ...), collection names,
+label names (--label, and label: terms in search queries), deletion
+batch IDs, profiles, and API token names.
 `,
 	DisableFlagsInUseLine: true,
 	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
@@ -55,3 +67,253 @@ PowerShell:
 func init() {
 	rootCmd.AddCommand(completionCmd)
 }
+
+// completer produces suggestions for the word being completed.
+type completer func(toComplete string) []string
+
+// argCompleters maps command paths (below the root) to a completer for
+// their first positional argument.
+var argCompleters = map[string]completer{
+	"sync":              completeAccounts,
+	"sync-full":         completeAccounts,
+	"verify":            completeAccounts,
+	"remove-account":    completeAccounts,
+	"update-account":    completeAccounts,
+	"export-token":      completeAccounts,
+	"identity show":     completeAccounts,
+	"identity add":      completeAccounts,
+	"identity remove":   completeAccounts,
+	"collection show":   completeCollections,
+	"collection add":    completeCollections,
+	"collection remove": completeCollections,
+	"collection delete": completeCollections,
+	"show-deletion":     completeBatches(true),
+	"cancel-deletion":   completeBatches(false),
+	"delete-staged":     completeBatches(false),
+	"profile switch":    completeProfiles,
+	"token revoke":      completeTokens,
+	"search":            completeSearchTerms,
+}
+
+// flagCompleters completes flag values by flag name on every command
+// that defines the flag.
+var flagCompleters = map[string]completer{
+	"account":    completeAccounts,
+	"accounts":   completeList(completeAccounts),
+	"collection": completeCollections,
+	"label":      completeList(completeLabels),
+}
+
+var registerCompletionsOnce sync.Once
+
+// registerDynamicCompletions wires the completers above into the
+// command tree. It runs from ExecuteContext rather than init because
+// flags are defined by each command file's own init, which may run
+// after this file's.
+func registerDynamicCompletions(root *cobra.Command) {
+	var walk func(c *cobra.Command)
+	walk = func(c *cobra.Command) {
+		path := strings.TrimPrefix(c.CommandPath(), root.Name()+" ")
+		if complete, ok := argCompleters[path]; ok && c.ValidArgsFunction == nil {
+			c.ValidArgsFunction = func(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				if len(args) > 0 {
+					return nil, cobra.ShellCompDirectiveNoFileComp
+				}
+				return complete(toComplete), cobra.ShellCompDirectiveNoFileComp
+			}
+		}
+		for name, complete := range flagCompleters {
+			if c.LocalNonPersistentFlags().Lookup(name) == nil {
+				continue
+			}
+			_ = c.RegisterFlagCompletionFunc(name, func(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+				return complete(toComplete), cobra.ShellCompDirectiveNoFileComp
+			})
+		}
+		for _, sub := range c.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+}
+
+// completionConfig returns the loaded config. Shell completion runs
+// without PersistentPreRunE, so it resolves the profile and loads the
+// config itself; nil means no suggestions.
+func completionConfig() *config.Config {
+	if cfg != nil {
+		return cfg
+	}
+	home, _, err := resolveProfileHome(profileFlag, cfgFile, homeDir)
+	if err != nil {
+		return nil
+	}
+	c, err := config.Load(cfgFile, home)
+	if err != nil {
+		return nil
+	}
+	return c
+}
+
+// withCompletionStore runs fn against the vault's database. A vault
+// that does not exist yet yields nothing rather than being created by
+// a tab press.
+func withCompletionStore(fn func(st *store.Store) ([]string, error)) []string {
+	c := completionConfig()
+	if c == nil {
+		return nil
+	}
+	dsn := c.DatabaseDSN()
+	if dbPath, err := c.DatabasePath(); err == nil {
+		if _, err := os.Stat(dbPath); err != nil {
+			return nil
+		}
+	}
+	st, err := store.Open(dsn)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = st.Close() }()
+	values, err := fn(st)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// filterPrefix keeps the values starting with prefix, sorted and
+// without duplicates. Values may carry a tab-separated description.
+func filterPrefix(values []string, prefix string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		name, _, _ := strings.Cut(v, "\t")
+		if strings.HasPrefix(name, prefix) && !seen[name] {
+			seen[name] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// completeList completes the last element of a comma-separated list.
+func completeList(complete completer) completer {
+	return func(toComplete string) []string {
+		done, last := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			done, last = toComplete[:i+1], toComplete[i+1:]
+		}
+		var out []string
+		for _, v := range complete(last) {
+			out = append(out, done+v)
+		}
+		return out
+	}
+}
+
+func completeAccounts(toComplete string) []string {
+	return filterPrefix(withCompletionStore(func(st *store.Store) ([]string, error) {
+		sources, err := st.ListSources("")
+		if err != nil {
+			return nil, err
+		}
+		var ids []string
+		for _, src := range sources {
+			ids = append(ids, src.Identifier+"\t"+src.SourceType)
+		}
+		return ids, nil
+	}), toComplete)
+}
+
+func completeCollections(toComplete string) []string {
+	return filterPrefix(withCompletionStore(func(st *store.Store) ([]string, error) {
+		collections, err := st.ListCollections()
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, c := range collections {
+			names = append(names, c.Name)
+		}
+		return names, nil
+	}), toComplete)
+}
+
+func completeLabels(toComplete string) []string {
+	return filterPrefix(withCompletionStore(func(st *store.Store) ([]string, error) {
+		return st.ListLabelNames()
+	}), toComplete)
+}
+
+// completeSearchTerms completes label names inside label: terms of a
+// search query; other words are left to the user.
+func completeSearchTerms(toComplete string) []string {
+	partial, ok := strings.CutPrefix(toComplete, "label:")
+	if !ok {
+		return nil
+	}
+	partial = strings.TrimPrefix(partial, `"`)
+	var out []string
+	for _, name := range completeLabels(partial) {
+		if strings.ContainsAny(name, " \t") {
+			name = `"` + name + `"`
+		}
+		out = append(out, "label:"+name)
+	}
+	return out
+}
+
+// completeBatches completes deletion batch IDs: every batch, or only
+// those still pending or in progress.
+func completeBatches(all bool) completer {
+	return func(toComplete string) []string {
+		c := completionConfig()
+		if c == nil {
+			return nil
+		}
+		mgr, err := deletion.NewManager(filepath.Join(c.Data.DataDir, "deletions"))
+		if err != nil {
+			return nil
+		}
+		lists := []func() ([]*deletion.Manifest, error){mgr.ListPending, mgr.ListInProgress}
+		if all {
+			lists = append(lists, mgr.ListCompleted, mgr.ListFailed, mgr.ListCancelled)
+		}
+		var ids []string
+		for _, list := range lists {
+			manifests, err := list()
+			if err != nil {
+				continue
+			}
+			for _, m := range manifests {
+				ids = append(ids, m.ID+"\t"+string(m.Status))
+			}
+		}
+		return filterPrefix(ids, toComplete)
+	}
+}
+
+func completeProfiles(toComplete string) []string {
+	names, err := config.ListProfiles(profileBase())
+	if err != nil {
+		return nil
+	}
+	return filterPrefix(names, toComplete)
+}
+
+func completeTokens(toComplete string) []string {
+	return filterPrefix(withCompletionStore(func(st *store.Store) ([]string, error) {
+		tokens, err := st.ListAPITokens()
+		if err != nil {
+			return nil, err
+		}
+		var names []string
+		for _, t := range tokens {
+			if !t.RevokedAt.Valid {
+				names = append(names, t.Name)
+			}
+		}
+		return names, nil
+	}), toComplete)
+}
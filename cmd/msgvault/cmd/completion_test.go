@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+)
+
+// setupCompletionVault points cfg at a fresh vault with two accounts,
+// labels, a collection, and one live and one revoked API token.
+func setupCompletionVault(t *testing.T) {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	savedCfg := cfg
+	t.Cleanup(func() { cfg = savedCfg })
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+
+	st, err := openLocalStoreAndInit()
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	alice, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	if _, err := st.GetOrCreateSource("imap", "bob@example.com"); err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	for _, name := range []string{"INBOX", "Work", "Work Travel"} {
+		if _, err := st.EnsureLabel(alice.ID, name, name, "user"); err != nil {
+			t.Fatalf("ensure label: %v", err)
+		}
+	}
+	if _, err := st.CreateCollection("family", "", []int64{alice.ID}); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	for _, name := range []string{"laptop", "old-laptop"} {
+		if _, _, err := st.CreateAPIToken(name, []string{"read"}); err != nil {
+			t.Fatalf("create token: %v", err)
+		}
+	}
+	if err := st.RevokeAPIToken("old-laptop"); err != nil {
+		t.Fatalf("revoke token: %v", err)
+	}
+}
+
+func TestCompleters(t *testing.T) {
+	setupCompletionVault(t)
+
+	tests := []struct {
+		name       string
+		complete   completer
+		toComplete string
+		want       []string
+	}{
+		{"all accounts", completeAccounts, "", []string{"alice@example.com\tgmail", "bob@example.com\timap"}},
+		{"account prefix", completeAccounts, "b", []string{"bob@example.com\timap"}},
+		{"account list", completeList(completeAccounts), "alice@example.com,b", []string{"alice@example.com,bob@example.com\timap"}},
+		{"labels", completeLabels, "W", []string{"Work", "Work Travel"}},
+		{"collections", completeCollections, "", []string{"All", "family"}},
+		{"revoked tokens are skipped", completeTokens, "", []string{"laptop"}},
+		{"search label terms", completeSearchTerms, "label:Wo", []string{"label:Work", `label:"Work Travel"`}},
+		{"other search terms", completeSearchTerms, "from:", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.complete(tt.toComplete)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("complete(%q) = %q, want %q", tt.toComplete, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompleters_NoVault verifies that completion against a home with
+// no database suggests nothing and does not create one.
+func TestCompleters_NoVault(t *testing.T) {
+	tmpDir := t.TempDir()
+	savedCfg := cfg
+	defer func() { cfg = savedCfg }()
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+
+	if got := completeAccounts(""); got != nil {
+		t.Errorf("completeAccounts() = %q, want none", got)
+	}
+	dbPath, err := cfg.DatabasePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+		t.Errorf("database stat = %v, want not created", err)
+	}
+}
+
+func TestRegisterDynamicCompletions(t *testing.T) {
+	setupCompletionVault(t)
+
+	root := &cobra.Command{Use: "msgvault"}
+	syncCmd := &cobra.Command{Use: "sync", Run: func(*cobra.Command, []string) {}}
+	statsCmd := &cobra.Command{Use: "stats", Run: func(*cobra.Command, []string) {}}
+	statsCmd.Flags().String("account", "", "")
+	root.AddCommand(syncCmd, statsCmd)
+
+	registerDynamicCompletions(root)
+
+	if syncCmd.ValidArgsFunction == nil {
+		t.Fatal("sync has no argument completion")
+	}
+	got, directive := syncCmd.ValidArgsFunction(syncCmd, nil, "a")
+	if !reflect.DeepEqual(got, []string{"alice@example.com\tgmail"}) || directive != cobra.ShellCompDirectiveNoFileComp {
+		t.Errorf("sync completion = %q (%v)", got, directive)
+	}
+	if got, _ := syncCmd.ValidArgsFunction(syncCmd, []string{"alice@example.com"}, ""); got != nil {
+		t.Errorf("second argument completion = %q, want none", got)
+	}
+
+	fn, ok := statsCmd.GetFlagCompletionFunc("account")
+	if !ok {
+		t.Fatal("stats --account has no completion")
+	}
+	if got, _ := fn(statsCmd, nil, "bob"); !reflect.DeepEqual(got, []string{"bob@example.com\timap"}) {
+		t.Errorf("--account completion = %q", got)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.toml for mistakes",
+	Long: `Check the configuration for problems before they surface mid-sync:
+
+  - unknown or misspelled keys
+  - invalid values (log level, cron schedules, URLs, ports)
+  - settings that only work together, such as an [oauth.apps] entry
+    without client_secrets or service_account_key, or a Microsoft
+    tenant_id without a client_id
+  - credential files that are missing or unreadable
+  - data, attachments, and log directories that are not writable
+  - keys and config files readable by other users
+
+Settings given as MSGVAULT_* environment variables are checked along
+with the file and listed. TOML syntax errors are reported, with their
+line, when the config is loaded. A lighter check covering the settings
+alone runs on every command and logs a warning for each problem.
+
+Exits non-zero when any error is found; warnings alone pass.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigValidate(cfg)
+	},
+}
+
+func runConfigValidate(c *config.Config) error {
+	issues := c.Check()
+	errs := 0
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			errs++
+		}
+	}
+
+	if jsonOutput {
+		if issues == nil {
+			issues = []config.Issue{}
+		}
+		overrides := c.EnvOverrides()
+		if overrides == nil {
+			overrides = []string{}
+		}
+		if err := printJSON(map[string]any{
+			"config_path":   c.ConfigFilePath(),
+			"env_overrides": overrides,
+			"valid":         errs == 0,
+			"issues":        issues,
+		}); err != nil {
+			return err
+		}
+	} else {
+		path := c.ConfigFilePath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			fmt.Printf("Config: %s (not found; using defaults)\n", path)
+		} else {
+			fmt.Printf("Config: %s\n", path)
+		}
+		if overrides := c.EnvOverrides(); len(overrides) > 0 {
+			fmt.Printf("Environment overrides: %s\n", strings.Join(overrides, ", "))
+		}
+		if len(issues) == 0 {
+			fmt.Println("No problems found.")
+			return nil
+		}
+		fmt.Println()
+		for _, issue := range issues {
+			fmt.Printf("  %-7s  %s\n", issue.Severity, issue)
+		}
+		fmt.Printf("\n%d error(s), %d warning(s)\n", errs, len(issues)-errs)
+	}
+
+	if errs > 0 {
+		return fmt.Errorf("config has %d error(s)", errs)
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+func TestRunConfigValidate(t *testing.T) {
+	savedJSON := jsonOutput
+	defer func() { jsonOutput = savedJSON }()
+
+	load := func(t *testing.T, content string) *config.Config {
+		t.Helper()
+		home := t.TempDir()
+		if err := os.WriteFile(filepath.Join(home, "config.toml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		c, err := config.Load("", home)
+		if err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		return c
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		jsonOutput = false
+		getOutput := captureStdout(t)
+		err := runConfigValidate(load(t, "[sync]\nrate_limit_qps = 3\n"))
+		output := getOutput()
+		if err != nil {
+			t.Fatalf("validate: %v", err)
+		}
+		if !strings.Contains(output, "No problems found.") {
+			t.Errorf("output = %q", output)
+		}
+	})
+
+	t.Run("warnings pass", func(t *testing.T) {
+		jsonOutput = false
+		getOutput := captureStdout(t)
+		err := runConfigValidate(load(t, "[sync]\nrate_limt_qps = 3\n"))
+		output := getOutput()
+		if err != nil {
+			t.Fatalf("validate: %v", err)
+		}
+		if !strings.Contains(output, "sync.rate_limt_qps") || !strings.Contains(output, "0 error(s), 1 warning(s)") {
+			t.Errorf("output = %q", output)
+		}
+	})
+
+	t.Run("errors fail with JSON report", func(t *testing.T) {
+		jsonOutput = true
+		getOutput := captureStdout(t)
+		err := runConfigValidate(load(t, "[microsoft]\ntenant_id = \"contoso\"\n"))
+		output := getOutput()
+		if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+			t.Fatalf("validate error = %v, want 1 error(s)", err)
+		}
+		var got struct {
+			Valid  bool           `json:"valid"`
+			Issues []config.Issue `json:"issues"`
+		}
+		if err := json.Unmarshal([]byte(output), &got); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, output)
+		}
+		if got.Valid || len(got.Issues) != 1 || got.Issues[0].Key != "microsoft.client_id" {
+			t.Errorf("report = %+v", got)
+		}
+	})
+}
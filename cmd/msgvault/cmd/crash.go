@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/crash"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// configureCrashReports points crash bundles at <data dir>/crash for
+// this run.
+func configureCrashReports(cmd *cobra.Command, args []string) {
+	logFile := ""
+	if logResult != nil {
+		logFile = logResult.FilePath
+	}
+	crash.Configure(crash.Options{
+		Dir:        filepath.Join(cfg.Data.DataDir, "crash"),
+		Version:    Version,
+		Args:       append([]string{cmd.CommandPath()}, sanitizeArgs(args)...),
+		ConfigPath: cfg.ConfigFilePath(),
+		LogFile:    logFile,
+		Schema:     crashSchemaInfo,
+	})
+}
+
+// crashSchemaInfo describes the database and analytics cache schema
+// for a crash bundle. It opens its own connection: the one the
+// crashed command held may be mid-transaction.
+func crashSchemaInfo() string {
+	var b strings.Builder
+	dsn := cfg.DatabaseDSN()
+	if path, err := cfg.DatabasePath(); err == nil {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			b.WriteString("database: not created yet\n")
+			return b.String()
+		}
+		fmt.Fprintf(&b, "database: SQLite %s\n", path)
+	} else {
+		b.WriteString("database: PostgreSQL\n")
+	}
+
+	s, err := store.Open(dsn)
+	if err != nil {
+		fmt.Fprintf(&b, "open failed: %v\n", err)
+		return b.String()
+	}
+	defer func() { _ = s.Close() }()
+
+	switch stale, missing, err := s.SchemaStale(); {
+	case err != nil:
+		fmt.Fprintf(&b, "schema: unknown (%v)\n", err)
+	case stale:
+		fmt.Fprintf(&b, "schema: needs migration (missing %s)\n", missing)
+	default:
+		b.WriteString("schema: current\n")
+	}
+	fmt.Fprintf(&b, "fts5: %t\n", s.FTS5Available())
+
+	var state syncState
+	data, err := os.ReadFile(filepath.Join(cfg.AnalyticsDir(), "_last_sync.json"))
+	switch {
+	case err != nil:
+		b.WriteString("analytics cache: not built\n")
+	case json.Unmarshal(data, &state) != nil:
+		b.WriteString("analytics cache: unreadable state file\n")
+	default:
+		fmt.Fprintf(&b, "analytics cache schema: v%d (this build writes v%d)\n",
+			state.SchemaVersion, cacheSchemaVersion)
+	}
+	return b.String()
+}
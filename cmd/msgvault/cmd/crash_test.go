@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestCrashSchemaInfo(t *testing.T) {
+	savedCfg := cfg
+	t.Cleanup(func() { cfg = savedCfg })
+	tmpDir := t.TempDir()
+	cfg = &config.Config{HomeDir: tmpDir, Data: config.DataConfig{DataDir: tmpDir}}
+
+	if got := crashSchemaInfo(); !strings.Contains(got, "not created yet") {
+		t.Errorf("before init = %q", got)
+	}
+
+	st, err := store.Open(filepath.Join(tmpDir, "msgvault.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	_ = st.Close()
+
+	got := crashSchemaInfo()
+	for _, want := range []string{"database: SQLite", "schema: current", "fts5:", "analytics cache: not built"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("crashSchemaInfo() missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -78,6 +78,14 @@ func runCreateSubset(cmd *cobra.Command, _ []string) error {
 	fmt.Fprintf(os.Stderr,
 		"Created subset (%s)\n", result.Elapsed.Round(time.Millisecond),
 	)
+
+	blobs, err := copySubsetRawBlobs(filepath.Join(dstDir, "msgvault.db"), filepath.Join(dstDir, "raw"))
+	if err != nil {
+		return err
+	}
+	if blobs > 0 {
+		fmt.Fprintf(os.Stderr, "Copied %d raw message files\n", blobs)
+	}
 	fmt.Printf("Sources:       %d\n", result.Sources)
 	fmt.Printf("Messages:      %d\n", result.Messages)
 	fmt.Printf("Conversations: %d\n", result.Conversations)
@@ -98,3 +106,28 @@ func runCreateSubset(cmd *cobra.Command, _ []string) error {
 
 	return nil
 }
+
+// copySubsetRawBlobs copies the raw message blobs the subset database
+// at dbPath refers to into dstDir and returns how many it copied.
+func copySubsetRawBlobs(dbPath, dstDir string) (int, error) {
+	dst, err := store.Open(dbPath)
+	if err != nil {
+		return 0, fmt.Errorf("open subset: %w", err)
+	}
+	defer func() { _ = dst.Close() }()
+	hashes, err := dst.RawBlobHashes()
+	if err != nil {
+		return 0, err
+	}
+	copied := 0
+	for _, h := range hashes {
+		ok, err := store.CopyRawBlob(cfg.RawBlobsDir(), dstDir, h)
+		if err != nil {
+			return copied, fmt.Errorf("copy raw message blob: %w", err)
+		}
+		if ok {
+			copied++
+		}
+	}
+	return copied, nil
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/attachtext"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/oauth"
+	"github.com/wesm/msgvault/internal/oplock"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/scheduler"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/userservice"
+	"github.com/wesm/msgvault/internal/virusscan"
+	"github.com/wesm/msgvault/internal/webhook"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run scheduled syncs without the API server",
+	Long: `Run until interrupted, syncing each account on its schedule from
+config.toml, without serving the API. 'msgvault serve' runs the same
+schedule alongside the API server.
+
+Each scheduled sync is delayed by a random amount up to the account's
+jitter, so accounts sharing a schedule don't all sync at once. A sync
+still running when its next turn comes is not started again, and each
+sync takes the vault's operation lock, so it never overlaps another
+msgvault command writing the archive. Only one daemon (or serve) may
+schedule a vault's syncs at a time.
+
+The daemon publishes each account's last run, next run, and last error
+to daemon-status.json in the data directory after every sync; read it
+with 'msgvault daemon status'. 'msgvault daemon install' runs the
+daemon in the background under systemd or launchd.
+
+  [daemon]
+  jitter = "5m"            # default for every account
+
+  [[accounts]]
+  email = "you@gmail.com"
+  schedule = "0 */6 * * *" # every 6 hours (cron)
+  enabled = true
+  jitter = "15m"           # optional; overrides [daemon] jitter
+
+Examples:
+  msgvault daemon
+  msgvault daemon status
+  msgvault daemon install`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("daemon"); err != nil {
+			return err
+		}
+		if !cfg.OAuth.HasAnyConfig() {
+			return errOAuthNotConfigured()
+		}
+		if len(cfg.ScheduledAccounts()) == 0 {
+			return fmt.Errorf("no scheduled accounts: add [[accounts]] with a schedule and enabled = true to config.toml (see 'msgvault daemon --help')")
+		}
+
+		lock, err := acquireDaemonLock("daemon")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+		applyParseConfig(s)
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		vf, err := setupVectorFeatures(ctx, s.DB(), cfg.DatabaseDSN())
+		if err != nil {
+			return fmt.Errorf("vector features: %w", err)
+		}
+		defer func() {
+			if vf != nil && vf.Close != nil {
+				if closeErr := vf.Close(); closeErr != nil {
+					logger.Warn("closing vectors.db failed", "error", closeErr)
+				}
+			}
+		}()
+
+		sched, count, err := newSyncScheduler(s, vf, oauthManagerCache())
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("no accounts could be scheduled; check the schedules in config.toml")
+		}
+		publish := publishDaemonStatus(sched)
+		sched.Start()
+		publish()
+
+		fmt.Printf("Scheduling syncs of %d account(s). Press Ctrl+C to stop.\n", count)
+		for _, st := range sched.Report(time.Now()).Accounts {
+			fmt.Printf("  %s: next sync at %s\n", st.Email, st.NextRun.Local().Format("2006-01-02 15:04:05"))
+		}
+
+		<-ctx.Done()
+		fmt.Println("\nWaiting for running syncs to complete...")
+		select {
+		case <-sched.Stop().Done():
+		case <-time.After(30 * time.Second):
+			fmt.Println("Shutdown timed out after 30 seconds.")
+		}
+		publish()
+		return nil
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the scheduled syncs of the running daemon",
+	Long: `Show each scheduled account's last run, next run, and last error, as
+published by 'msgvault daemon' or 'msgvault serve'. When no daemon is
+running, the last status it published is shown. If the daemon is
+installed with 'msgvault daemon install', the state systemd or launchd
+reports for it is shown first.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := cfg.Data.DataDir
+		holder, err := oplock.CurrentFile(dir, oplock.DaemonFileName)
+		if err != nil {
+			return err
+		}
+		report, err := scheduler.ReadStatusFile(filepath.Join(dir, scheduler.StatusFileName))
+		if err != nil {
+			return err
+		}
+
+		service := daemonServiceStatus()
+
+		if jsonOutput {
+			out := struct {
+				Running bool                    `json:"running"`
+				Status  *scheduler.StatusReport `json:"status"`
+				Service *userservice.Status     `json:"service,omitempty"`
+			}{Running: holder != nil, Status: report, Service: service}
+			return printJSON(out)
+		}
+
+		if service != nil && service.Installed {
+			fmt.Printf("Service: %s (%s: %s)\n", service.Path, service.Manager, service.State)
+		}
+		switch {
+		case holder != nil && report != nil:
+			fmt.Printf("Daemon running (pid %d) since %s\n\n", report.PID, i18n.LongDateTime(report.Started))
+		case holder != nil:
+			fmt.Printf("Daemon running: %s\n", holder)
+			return nil
+		case report == nil:
+			fmt.Println("No daemon is running, and none has published a status.")
+			return nil
+		default:
+			fmt.Printf("No daemon is running. Last status, published %s:\n\n", i18n.LongDateTime(report.Updated))
+		}
+		printDaemonStatus(report.Accounts, holder != nil)
+		return nil
+	},
+}
+
+func printDaemonStatus(accounts []scheduler.AccountStatus, running bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACCOUNT\tSCHEDULE\tJITTER\tLAST SUCCESS\tNEXT RUN\tSTATE")
+	_, _ = fmt.Fprintln(w, "───────\t────────\t──────\t────────────\t────────\t─────")
+	for _, a := range accounts {
+		lastRun, nextRun := "never", "-"
+		if !a.LastRun.IsZero() {
+			lastRun = a.LastRun.Local().Format("2006-01-02 15:04")
+		}
+		if running && !a.NextRun.IsZero() {
+			nextRun = a.NextRun.Local().Format("2006-01-02 15:04")
+		}
+		jitter := a.Jitter
+		if jitter == "" {
+			jitter = "-"
+		}
+		state := "ok"
+		switch {
+		case a.Running && running:
+			state = "syncing"
+		case a.LastError != "":
+			state = "failed: " + truncate(a.LastError, 60)
+		case a.LastAttempt.IsZero():
+			state = "waiting"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Email, a.Schedule, jitter, lastRun, nextRun, state)
+	}
+	_ = w.Flush()
+}
+
+// acquireDaemonLock takes the vault's daemon lock for operation, so
+// only one daemon schedules the vault's syncs.
+func acquireDaemonLock(operation string) (*oplock.Lock, error) {
+	l, err := oplock.TryAcquireFile(cfg.Data.DataDir, oplock.DaemonFileName, operation)
+	if errors.Is(err, oplock.ErrBusy) {
+		return nil, fmt.Errorf("another daemon is already scheduling syncs for this vault: %w", err)
+	}
+	return l, err
+}
+
+// publishDaemonStatus registers a run hook that writes sched's status
+// report to the data directory after every sync, and returns a
+// function that writes it on demand.
+func publishDaemonStatus(sched *scheduler.Scheduler) func() {
+	started := time.Now()
+	path := filepath.Join(cfg.Data.DataDir, scheduler.StatusFileName)
+	publish := func() {
+		if err := scheduler.WriteStatusFile(path, sched.Report(started)); err != nil {
+			logger.Warn("publish daemon status", "error", err)
+		}
+	}
+	sched.AddRunHook(func(scheduler.AccountStatus) { publish() })
+	return publish
+}
+
+// newSyncScheduler builds the scheduler shared by 'daemon' and 'serve':
+// an incremental sync of each scheduled account in config, with the
+// embed job, rules, virus scan, notifiers, and webhooks run after each
+// sync, and due reminders notified. It returns the number of accounts
+// scheduled.
+func newSyncScheduler(s *store.Store, vf *vectorFeatures, getOAuthMgr func(string) (*oauth.Manager, error)) (*scheduler.Scheduler, int, error) {
+	// vf is captured and used inside runScheduledSync to wire the embed
+	// enqueuer into each per-run Syncer; it is nil when vector search
+	// is disabled.
+	notifier, err := newNotifier()
+	if err != nil {
+		return nil, 0, err
+	}
+	syncFunc := func(ctx context.Context, email string) error {
+		summary, err := runScheduledSync(ctx, email, s, getOAuthMgr, vf)
+		notifyScheduledSync(ctx, notifier, email, summary, err)
+		return err
+	}
+
+	sched := scheduler.New(syncFunc).WithLogger(logger)
+
+	count, errs := sched.AddAccountsFromConfig(cfg)
+	for _, err := range errs {
+		logger.Error("failed to schedule account", "error", err)
+	}
+
+	// Register the embed job (cron-driven plus optional post-sync hook).
+	// Only when vector search is enabled and wired.
+	if vf != nil {
+		embedJob := &scheduler.EmbedJob{
+			Worker:      vf.Worker,
+			Backend:     vf.Backend,
+			VectorsDB:   vf.VectorsDB,
+			Fingerprint: vf.Cfg.Embeddings.Fingerprint(),
+			Log:         logger,
+		}
+		schedule := cfg.Vector.Embed.Schedule.Cron
+		if err := sched.SetEmbedJob(
+			embedJob, schedule, cfg.Vector.Embed.Schedule.RunAfterSync,
+		); err != nil {
+			return nil, 0, fmt.Errorf("register embed job: %w", err)
+		}
+		logger.Info("embed scheduled",
+			"cron", schedule,
+			"run_after_sync", cfg.Vector.Embed.Schedule.RunAfterSync,
+		)
+	}
+
+	// Rules run first so webhooks see the labels they apply.
+	if len(cfg.Rules) > 0 {
+		engine, err := rules.New(cfg.Rules, s, cfg.AttachmentsDir(), logger)
+		if err != nil {
+			return nil, 0, fmt.Errorf("configure rules: %w", err)
+		}
+		hook, err := engine.PostSyncHook()
+		if err != nil {
+			return nil, 0, fmt.Errorf("configure rules: %w", err)
+		}
+		engine.SetNotify(ruleMatchNotifier(notifier))
+		sched.AddPostSyncHook(hook)
+		logger.Info("rules configured", "count", engine.Len())
+	}
+
+	// New attachments are scanned, and their text indexed, before webhooks
+	// announce the sync.
+	if len(cfg.VirusScan.Command) > 0 {
+		sc, err := virusscan.New(cfg.VirusScan.Command)
+		if err != nil {
+			return nil, 0, fmt.Errorf("configure virus scan: %w", err)
+		}
+		sched.AddPostSyncHook(virusscan.PostSyncHook(s, cfg.AttachmentsDir(), sc, cfg.VirusScan.Quarantine, logger))
+		logger.Info("virus scan configured", "command", cfg.VirusScan.Command[0],
+			"quarantine", cfg.VirusScan.Quarantine)
+	}
+	if cfg.Parse.IndexAttachments {
+		sched.AddPostSyncHook(attachtext.PostSyncHook(s, cfg.AttachmentsDir(), logger))
+		logger.Info("attachment text indexing configured")
+	}
+
+	if notifier.Len() > 0 {
+		logger.Info("notifiers configured", "count", notifier.Len())
+	}
+
+	// Reminders come due on their own time, not after a sync.
+	if err := sched.AddJob("reminders", reminderSchedule, remindDueReminders(s, notifier)); err != nil {
+		return nil, 0, err
+	}
+
+	// Webhooks fire after each successful scheduled sync.
+	if len(cfg.Webhooks) > 0 {
+		dispatcher, err := webhook.New(cfg.Webhooks, s, logger)
+		if err != nil {
+			return nil, 0, fmt.Errorf("configure webhooks: %w", err)
+		}
+		sched.AddPostSyncHook(dispatcher.Run)
+		logger.Info("webhooks configured", "count", len(cfg.Webhooks))
+	}
+
+	return sched, count, nil
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStatusCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
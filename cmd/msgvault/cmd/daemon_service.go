@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/userservice"
+)
+
+var daemonInstallEnv []string
+
+// newUserService returns the service manager 'daemon install' uses;
+// tests replace it.
+var newUserService = userservice.New
+
+var daemonInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Run the daemon in the background under systemd or launchd",
+	Long: `Install 'msgvault daemon' as a background service of the current user,
+and start it: a systemd user unit on Linux, or a launchd agent on macOS.
+It starts again at login and after a crash. On Windows, use
+'msgvault service install' instead.
+
+The service runs this msgvault binary against this vault (--home, and
+--config if given). Services don't inherit your shell's environment, so
+PATH, proxy settings, and every MSGVAULT_* variable set now, such as a
+key the vault reads from the environment, are copied into the unit file.
+Name other variables to copy with --env NAME, or set them with
+--env NAME=VALUE. The unit file is readable only by you, but it holds
+those values in plain text.
+
+Running install again replaces the service. Under systemd, the daemon
+logs to the journal (journalctl --user -u msgvault); under launchd, to
+daemon.out.log and daemon.err.log in the logs directory. On Linux, run
+'loginctl enable-linger' to keep the daemon running while you are
+logged out.
+
+Examples:
+  msgvault daemon install
+  msgvault daemon install --env GOOGLE_APPLICATION_CREDENTIALS`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("daemon install"); err != nil {
+			return err
+		}
+		if len(cfg.ScheduledAccounts()) == 0 {
+			return fmt.Errorf("no scheduled accounts: add [[accounts]] with a schedule and enabled = true to config.toml (see 'msgvault daemon --help')")
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("find current executable: %w", err)
+		}
+		spec, err := daemonServiceSpec(exe, os.Environ(), daemonInstallEnv)
+		if err != nil {
+			return err
+		}
+		m, err := newUserService()
+		if err != nil {
+			return err
+		}
+		path, err := m.Install(spec)
+		if err != nil {
+			return userServiceError(err)
+		}
+		fmt.Printf("Installed %s and started the daemon.\n", path)
+		fmt.Printf("Copied %d environment variable(s): %s\n", len(spec.Env), strings.Join(envNames(spec.Env), ", "))
+		fmt.Println("Check on it with: msgvault daemon status")
+		return nil
+	},
+}
+
+var daemonUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop the background daemon and remove its service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, err := newUserService()
+		if err != nil {
+			return err
+		}
+		path, err := m.Path()
+		if err != nil {
+			return userServiceError(err)
+		}
+		if err := m.Uninstall(); err != nil {
+			return userServiceError(err)
+		}
+		fmt.Printf("Stopped the daemon and removed %s.\n", path)
+		return nil
+	},
+}
+
+// userServiceError points Windows users at 'service install'.
+func userServiceError(err error) error {
+	if errors.Is(err, userservice.ErrUnsupported) {
+		return fmt.Errorf("%w; on Windows, use 'msgvault service install'", err)
+	}
+	return err
+}
+
+// daemonServiceEnv lists the variables the service needs from environ
+// besides MSGVAULT_*. HOME and the like are set by the service manager.
+var daemonServiceEnv = []string{
+	"PATH", "LANG", "TZ",
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy",
+}
+
+// daemonServiceSpec returns the service that runs the daemon from exe
+// with the environment copied from environ: daemonServiceEnv, every
+// MSGVAULT_* variable, and extra, each a NAME to copy or NAME=VALUE.
+// MSGVAULT_HOME and MSGVAULT_PROFILE are left out, since --home pins
+// the vault.
+func daemonServiceSpec(exe string, environ, extra []string) (userservice.Spec, error) {
+	args, err := vaultArgs()
+	if err != nil {
+		return userservice.Spec{}, err
+	}
+	current := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			current[k] = v
+		}
+	}
+	env := map[string]string{}
+	for k, v := range current {
+		switch {
+		case k == "MSGVAULT_HOME" || k == "MSGVAULT_PROFILE":
+		case strings.HasPrefix(k, "MSGVAULT_"):
+			env[k] = v
+		}
+	}
+	for _, k := range daemonServiceEnv {
+		if v, ok := current[k]; ok && v != "" {
+			env[k] = v
+		}
+	}
+	for _, e := range extra {
+		k, v, hasValue := strings.Cut(e, "=")
+		if k == "" {
+			return userservice.Spec{}, fmt.Errorf("invalid --env %q: want NAME or NAME=VALUE", e)
+		}
+		if !hasValue {
+			var ok bool
+			if v, ok = current[k]; !ok {
+				return userservice.Spec{}, fmt.Errorf("--env %s: the variable is not set", k)
+			}
+		}
+		env[k] = v
+	}
+	return userservice.Spec{
+		Exe:    exe,
+		Args:   append(args, "daemon"),
+		Env:    env,
+		LogDir: cfg.LogsDir(),
+	}, nil
+}
+
+// envNames returns the sorted names of env.
+func envNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for k := range env {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// daemonServiceStatus returns the installed service's status, or nil
+// where there is no systemd or launchd.
+func daemonServiceStatus() *userservice.Status {
+	m, err := newUserService()
+	if err != nil {
+		return nil
+	}
+	st, err := m.Status()
+	if err != nil {
+		logger.Debug("daemon service status", "error", err)
+		return nil
+	}
+	return &st
+}
+
+func init() {
+	daemonInstallCmd.Flags().StringArrayVar(&daemonInstallEnv, "env", nil,
+		"environment variable to copy into the service, as NAME or NAME=VALUE (repeatable)")
+	daemonCmd.AddCommand(daemonInstallCmd, daemonUninstallCmd)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+func TestDaemonServiceSpec(t *testing.T) {
+	savedCfg, savedFile := cfg, cfgFile
+	t.Cleanup(func() { cfg, cfgFile = savedCfg, savedFile })
+	home := t.TempDir()
+	cfg = &config.Config{HomeDir: home}
+	cfg.Data.DataDir = home
+	cfgFile = ""
+
+	environ := []string{
+		"PATH=/usr/bin",
+		"HOME=/home/alice",
+		"MSGVAULT_KEY=s3cret",
+		"MSGVAULT_HOME=/elsewhere",
+		"MSGVAULT_PROFILE=work",
+		"GOOGLE_APPLICATION_CREDENTIALS=/home/alice/sa.json",
+		"HTTPS_PROXY=",
+	}
+	tests := []struct {
+		name    string
+		extra   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{"defaults", nil, map[string]string{"PATH": "/usr/bin", "MSGVAULT_KEY": "s3cret"}, ""},
+		{"copy named", []string{"GOOGLE_APPLICATION_CREDENTIALS"}, map[string]string{
+			"PATH": "/usr/bin", "MSGVAULT_KEY": "s3cret", "GOOGLE_APPLICATION_CREDENTIALS": "/home/alice/sa.json",
+		}, ""},
+		{"set value", []string{"TZ=UTC", "MSGVAULT_KEY=other"}, map[string]string{
+			"PATH": "/usr/bin", "MSGVAULT_KEY": "other", "TZ": "UTC",
+		}, ""},
+		{"unset name", []string{"NOPE"}, nil, "not set"},
+		{"empty name", []string{"=x"}, nil, "invalid --env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := daemonServiceSpec("/usr/local/bin/msgvault", environ, tt.extra)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(spec.Env, tt.want) {
+				t.Errorf("Env = %v, want %v", spec.Env, tt.want)
+			}
+			if want := []string{"--home", home, "daemon"}; !slices.Equal(spec.Args, want) {
+				t.Errorf("Args = %q, want %q", spec.Args, want)
+			}
+			if spec.Exe != "/usr/local/bin/msgvault" || spec.LogDir != cfg.LogsDir() {
+				t.Errorf("Exe, LogDir = %q, %q", spec.Exe, spec.LogDir)
+			}
+		})
+	}
+}
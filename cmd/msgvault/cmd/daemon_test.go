@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/oplock"
+	"github.com/wesm/msgvault/internal/scheduler"
+)
+
+func TestAcquireDaemonLock(t *testing.T) {
+	savedCfg := cfg
+	defer func() { cfg = savedCfg }()
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{HomeDir: tmpDir, Data: config.DataConfig{DataDir: tmpDir}}
+
+	held, err := acquireDaemonLock("daemon")
+	if err != nil {
+		t.Fatalf("acquireDaemonLock() error = %v", err)
+	}
+	defer func() { _ = held.Release() }()
+
+	// The daemon lock doesn't block the vault's operation lock.
+	op, err := oplock.TryAcquire(tmpDir, "sync")
+	if err != nil {
+		t.Fatalf("TryAcquire(sync) while daemon runs error = %v", err)
+	}
+	_ = op.Release()
+
+	_, err = acquireDaemonLock("serve")
+	if !errors.Is(err, oplock.ErrBusy) {
+		t.Fatalf("second acquireDaemonLock() error = %v, want ErrBusy", err)
+	}
+	for _, want := range []string{"another daemon", "daemon (pid"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := acquireDaemonLock("serve")
+	if err != nil {
+		t.Fatalf("acquireDaemonLock() after release error = %v", err)
+	}
+	_ = l.Release()
+}
+
+func TestPublishDaemonStatus(t *testing.T) {
+	savedCfg := cfg
+	defer func() { cfg = savedCfg }()
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{HomeDir: tmpDir, Data: config.DataConfig{DataDir: tmpDir}}
+
+	sched := scheduler.New(nil)
+	if err := sched.AddAccount("alice@example.com", "0 * * * *"); err != nil {
+		t.Fatal(err)
+	}
+	publishDaemonStatus(sched)()
+
+	r, err := scheduler.ReadStatusFile(filepath.Join(tmpDir, scheduler.StatusFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || len(r.Accounts) != 1 || r.Accounts[0].Email != "alice@example.com" {
+		t.Fatalf("published status = %+v, want alice@example.com", r)
+	}
+}
@@ -79,6 +79,14 @@ var (
 )
 
 func runDeduplicate(cmd *cobra.Command, _ []string) error {
+	if !dedupDryRun || len(dedupUndo) > 0 {
+		lock, err := acquireOpLock(cmd.Context(), "deduplicate")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+	}
+
 	st, err := openStoreAndInit()
 	if err != nil {
 		return err
@@ -601,7 +609,7 @@ func init() {
 		"Skip database backup before merging (backup covers pre-dedup state for all sources, not per-batch)")
 	deduplicateCmd.Flags().StringVar(&dedupPrefer, "prefer", "",
 		"Comma-separated source type preference order "+
-			"(default: gmail,imap,mbox,emlx,hey)")
+			"(default: gmail,outlook,imap,mbox,emlx,hey)")
 	deduplicateCmd.Flags().BoolVar(&dedupContentHash, "content-hash", false,
 		"Also detect duplicates by normalized raw MIME content")
 	deduplicateCmd.Flags().StringArrayVar(&dedupUndo, "undo", nil,
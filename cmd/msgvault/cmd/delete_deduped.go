@@ -49,6 +49,12 @@ func runDeleteDeduped(cmd *cobra.Command, _ []string) error {
 		return fmt.Errorf("must specify --batch or --all-hidden")
 	}
 
+	lock, err := acquireOpLock(cmd.Context(), "delete-deduped")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
 	st, err := openStoreAndInit()
 	if err != nil {
 		return err
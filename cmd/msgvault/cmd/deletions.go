@@ -58,6 +58,22 @@ func runListDeletionsForManager(mgr *deletion.Manager, w io.Writer) error {
 		return fmt.Errorf("list cancelled deletions: %w", err)
 	}
 
+	if jsonOutput {
+		batches := []deletionBatchJSON{}
+		for _, group := range [][]*deletion.Manifest{pending, inProgress, completed, failed, cancelled} {
+			for _, m := range group {
+				batches = append(batches, deletionBatchJSON{
+					ID:          m.ID,
+					Status:      string(m.Status),
+					Messages:    len(m.GmailIDs),
+					CreatedAt:   m.CreatedAt,
+					Description: m.Description,
+				})
+			}
+		}
+		return printJSONTo(w, batches)
+	}
+
 	if len(pending) == 0 && len(inProgress) == 0 && len(completed) == 0 && len(failed) == 0 && len(cancelled) == 0 {
 		_, _ = fmt.Fprintln(w, "No deletion batches found.")
 		_, _ = fmt.Fprintln(w, "\nTo stage messages for deletion, use the TUI or create a manifest manually.")
@@ -90,6 +106,15 @@ func runListDeletionsForManager(mgr *deletion.Manager, w io.Writer) error {
 	return nil
 }
 
+// deletionBatchJSON is one batch in 'list-deletions --json' output.
+type deletionBatchJSON struct {
+	ID          string    `json:"id"`
+	Status      string    `json:"status"`
+	Messages    int       `json:"messages"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description string    `json:"description,omitempty"`
+}
+
 var showDeletionCmd = &cobra.Command{
 	Use:   "show-deletion <batch-id>",
 	Short: "Show details of a deletion batch",
@@ -108,6 +133,9 @@ var showDeletionCmd = &cobra.Command{
 			return fmt.Errorf("get manifest: %w", err)
 		}
 
+		if jsonOutput {
+			return printJSON(manifest)
+		}
 		fmt.Print(manifest.FormatSummary())
 		return nil
 	},
@@ -255,6 +283,14 @@ Examples:
   MSGVAULT_ENABLE_REMOTE_DELETE=1 msgvault delete-staged --permanent
   MSGVAULT_ENABLE_REMOTE_DELETE=1 msgvault delete-staged --yes`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if !deleteDryRun {
+			lock, err := acquireOpLock(cmd.Context(), "delete-staged")
+			if err != nil {
+				return err
+			}
+			defer func() { _ = lock.Release() }()
+		}
+
 		deletionsDir := filepath.Join(cfg.Data.DataDir, "deletions")
 		manager, err := deletion.NewManager(deletionsDir)
 		if err != nil {
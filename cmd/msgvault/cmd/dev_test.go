@@ -0,0 +1,16 @@
+package cmd
+
+import "testing"
+
+func TestParseMessageCount(t *testing.T) {
+	for in, want := range map[string]int{"5000": 5000, "100k": 100_000, "1M": 1_000_000, " 2k ": 2000} {
+		if got, err := parseMessageCount(in); err != nil || got != want {
+			t.Errorf("parseMessageCount(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "k", "-5", "0", "1g", "1.5k"} {
+		if _, err := parseMessageCount(in); err == nil {
+			t.Errorf("parseMessageCount(%q) succeeded", in)
+		}
+	}
+}
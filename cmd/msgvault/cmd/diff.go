@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	diffSince   string
+	diffAccount string
+	diffLimit   int
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff --since <date|sync>",
+	Short: "Show what changed in the archive since a date or sync",
+	Long: `Show a changelog of the archive: the messages archived, the messages
+deleted from their account, and the labels added or removed since a point
+in time.
+
+--since takes a date (2026-05-01), a time ago (3d, 2w, 1m or 1y), the ID of
+a sync run, or last-sync for the start of the latest sync (of --account, or
+of any account).
+
+Label changes are those msgvault saw happen: the ones incremental Gmail
+syncs report, and the edits made with 'msgvault labels add' and 'remove'.
+Each list shows the newest --limit changes; the counts cover them all.
+
+Examples:
+  msgvault diff --since 2026-05-01
+  msgvault diff --since 7d --account you@gmail.com
+  msgvault diff --since last-sync --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("diff"); err != nil {
+			return err
+		}
+		if diffSince == "" {
+			return fmt.Errorf("--since is required")
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		scope, err := ResolveAccountFlag(s, diffAccount)
+		if err != nil {
+			return err
+		}
+		since, err := resolveDiffSince(s, diffSince, scope.SourceIDs(), time.Now())
+		if err != nil {
+			return err
+		}
+		d, err := s.ArchiveChanges(since, scope.SourceIDs(), diffLimit)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(d)
+		}
+		printArchiveDiff(d)
+		return nil
+	},
+}
+
+// resolveDiffSince resolves a --since value to the time it names.
+func resolveDiffSince(s *store.Store, value string, sourceIDs []int64, now time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "last-sync" {
+		return lastSyncStart(s, sourceIDs)
+	}
+	if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+		run, err := s.GetSyncRun(id)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("look up sync run %d: %w", id, err)
+		}
+		if run == nil {
+			return time.Time{}, fmt.Errorf("no sync run %d", id)
+		}
+		return run.StartedAt, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	m := reminderDurationRe.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q (want a date like 2026-05-01, 3d, 2w, 1m or 1y, a sync run ID, or last-sync)", value)
+	}
+	n, _ := strconv.Atoi(m[1])
+	return addPeriod(now, -n, m[2]), nil
+}
+
+// lastSyncStart returns when the latest sync of the given sources, or
+// of any source, started.
+func lastSyncStart(s *store.Store, sourceIDs []int64) (time.Time, error) {
+	if len(sourceIDs) == 0 {
+		sources, err := s.ListSources("")
+		if err != nil {
+			return time.Time{}, fmt.Errorf("list accounts: %w", err)
+		}
+		for _, src := range sources {
+			sourceIDs = append(sourceIDs, src.ID)
+		}
+	}
+	var latest time.Time
+	for _, id := range sourceIDs {
+		run, err := s.GetLatestSync(id)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("look up latest sync: %w", err)
+		}
+		if run != nil && run.StartedAt.After(latest) {
+			latest = run.StartedAt
+		}
+	}
+	if latest.IsZero() {
+		return time.Time{}, fmt.Errorf("no sync has run yet")
+	}
+	return latest, nil
+}
+
+func printArchiveDiff(d *store.ArchiveDiff) {
+	fmt.Printf("Changes since %s\n\n", i18n.DateTime(d.Since.Local()))
+	if d.IsEmpty() {
+		fmt.Println("Nothing changed.")
+		return
+	}
+	fmt.Printf("Archived:          %s\n", formatCount(d.Added))
+	fmt.Printf("Deleted remotely:  %s\n", formatCount(d.Deleted))
+	fmt.Printf("Label changes:     %s\n", formatCount(d.LabelChanges))
+
+	printDiffMessages("Archived", d.AddedList, d.Added)
+	printDiffMessages("Deleted remotely", d.DeletedList, d.Deleted)
+	if len(d.LabelList) > 0 {
+		fmt.Printf("\nLabel changes:\n")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "WHEN\tID\tLABEL\tSUBJECT")
+		_, _ = fmt.Fprintln(w, "────\t──\t─────\t───────")
+		for _, c := range d.LabelList {
+			sign := "+"
+			if c.Action == store.LabelChangeRemove {
+				sign = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s%s\t%s\n", i18n.DateTime(c.At.Local()), c.MessageID,
+				sign, truncate(c.Label, 30), truncate(c.Subject, 50))
+		}
+		_ = w.Flush()
+		printDiffMore(len(d.LabelList), d.LabelChanges)
+	}
+}
+
+func printDiffMessages(title string, msgs []store.DiffMessage, total int64) {
+	if len(msgs) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "WHEN\tID\tFROM\tSUBJECT")
+	_, _ = fmt.Fprintln(w, "────\t──\t────\t───────")
+	for _, m := range msgs {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", i18n.DateTime(m.At.Local()), m.ID,
+			truncate(m.From, 30), truncate(m.Subject, 50))
+	}
+	_ = w.Flush()
+	printDiffMore(len(msgs), total)
+}
+
+func printDiffMore(shown int, total int64) {
+	if more := total - int64(shown); more > 0 {
+		fmt.Printf("…and %s more\n", formatCount(more))
+	}
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffSince, "since", "", "date, time ago (7d), sync run ID, or last-sync")
+	diffCmd.Flags().StringVar(&diffAccount, "account", "", "only changes in this account")
+	diffCmd.Flags().IntVar(&diffLimit, "limit", 20, "most changes of each kind to list")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDiffSince(t *testing.T) {
+	now := time.Date(2026, 3, 31, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"7d", time.Date(2026, 3, 24, 10, 0, 0, 0, time.Local), false},
+		{"2w", time.Date(2026, 3, 17, 10, 0, 0, 0, time.Local), false},
+		{"1M", now.AddDate(0, -1, 0), false},
+		{"1y", time.Date(2025, 3, 31, 10, 0, 0, 0, time.Local), false},
+		{"2026-01-15", time.Date(2026, 1, 15, 0, 0, 0, 0, time.Local), false},
+		{"yesterday", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		// None of these name a sync, so no store is needed.
+		got, err := resolveDiffSince(nil, tt.in, nil, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("resolveDiffSince(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("resolveDiffSince(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export messages to local files",
+	Long: `Export messages from the archive to local files that other mail tools
+can read.
+
+Examples:
+  msgvault export mbox --out archive.mbox
+  msgvault export mbox --query "from:alice@example.com" --out alice.mbox
+  msgvault export eml --query "label:Receipts" --out receipts --layout year
+  msgvault export maildir ~/Maildir/archive
+  msgvault export takeout --out takeout.zip
+  msgvault export metadata --out metadata.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
@@ -72,6 +72,10 @@ func runExportAttachment(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if err := checkNotQuarantined(contentHash); err != nil {
+		return err
+	}
+
 	// Construct storage path: attachmentsDir/hash[:2]/hash
 	attachmentsDir := cfg.AttachmentsDir()
 	storagePath := filepath.Join(attachmentsDir, contentHash[:2], contentHash)
@@ -87,6 +91,24 @@ func runExportAttachment(cmd *cobra.Command, args []string) error {
 	return exportAttachmentBinary(storagePath, contentHash)
 }
 
+// checkNotQuarantined refuses to export attachment content the virus
+// scanner quarantined.
+func checkNotQuarantined(contentHash string) error {
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+	quarantined, err := s.AttachmentQuarantined(contentHash)
+	if err != nil {
+		return err
+	}
+	if quarantined {
+		return fmt.Errorf("attachment %s: %w", contentHash, export.ErrQuarantined)
+	}
+	return nil
+}
+
 func exportAttachmentAsJSON(storagePath, contentHash string) error {
 	data, err := readAttachmentFile(storagePath, contentHash)
 	if err != nil {
@@ -154,8 +176,18 @@ func exportAttachmentBinary(storagePath, contentHash string) error {
 	return nil
 }
 
-func openAttachmentFile(storagePath string) (*os.File, error) {
-	f, err := os.Open(storagePath)
+// openStoredAttachment opens the attachment stored at storagePath, or
+// compressed beside it, decompressing it as needed.
+func openStoredAttachment(storagePath string) (io.ReadSeekCloser, error) {
+	f, err := export.OpenAttachmentPath(storagePath)
+	if os.IsNotExist(err) {
+		f, err = export.OpenAttachmentPath(storagePath + export.CompressedSuffix)
+	}
+	return f, err
+}
+
+func openAttachmentFile(storagePath string) (io.ReadSeekCloser, error) {
+	f, err := openStoredAttachment(storagePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("attachment not found: %s", filepath.Base(storagePath))
@@ -166,13 +198,18 @@ func openAttachmentFile(storagePath string) (*os.File, error) {
 }
 
 func readAttachmentFile(storagePath, contentHash string) ([]byte, error) {
-	data, err := os.ReadFile(storagePath)
+	f, err := openStoredAttachment(storagePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, fmt.Errorf("attachment not found: no file for hash %s", contentHash)
 		}
 		return nil, fmt.Errorf("read attachment: %w", err)
 	}
+	defer func() { _ = f.Close() }()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read attachment: %w", err)
+	}
 	return data, nil
 }
 
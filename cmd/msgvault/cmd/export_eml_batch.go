@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Folder layouts for 'msgvault export eml'.
+const (
+	emlLayoutFlat   = "flat"
+	emlLayoutYear   = "year"
+	emlLayoutLabel  = "label"
+	emlLayoutSender = "sender"
+)
+
+var (
+	exportEMLBatchQuery  string
+	exportEMLBatchOut    string
+	exportEMLBatchLayout string
+)
+
+var exportEMLBatchCmd = &cobra.Command{
+	Use:   "eml",
+	Short: "Export messages as one .eml file each",
+	Long: `Export messages to a directory, one .eml file per message, which any mail
+client can open. Each file holds the raw MIME stored when the message was
+synced or imported, attachments included. Messages without raw MIME, such as
+chat messages, are skipped.
+
+Files are named <id>-<subject>.eml and, with --layout, sorted into folders:
+
+  flat    all files in the output directory (default)
+  year    one folder per year sent, as 2024/
+  label   one folder per label, with a copy of the message in each of its
+          labels' folders and unlabeled messages under Unlabeled/
+  sender  one folder per sender address
+
+Exporting again into the same directory overwrites the files it wrote before.
+Use --query to export only the messages matching a search (same syntax as
+'msgvault search'); without it, every message is exported.
+
+Examples:
+  msgvault export eml --out mail
+  msgvault export eml --query "label:Receipts after:2024-01-01" --out receipts --layout year
+  msgvault export eml --query "from:alice@example.com" --out alice --layout label`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("export eml"); err != nil {
+			return err
+		}
+		switch exportEMLBatchLayout {
+		case emlLayoutFlat, emlLayoutYear, emlLayoutLabel, emlLayoutSender:
+		default:
+			return fmt.Errorf("unknown --layout %q (want flat, year, label, or sender)", exportEMLBatchLayout)
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		q, err := parseLocalQuery(s, exportEMLBatchQuery)
+		if err != nil {
+			return err
+		}
+
+		res, err := exportEMLFiles(s, q, exportEMLBatchOut, exportEMLBatchLayout,
+			func(done, total int) {
+				if done%1000 == 0 {
+					fmt.Fprintf(os.Stderr, "Exported %s of %s messages...\n",
+						formatCount(int64(done)), formatCount(int64(total)))
+				}
+			})
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(res)
+		}
+		fmt.Printf("Exported %s messages to %s (%s files)\n",
+			formatCount(int64(res.Exported)), res.Output, formatCount(int64(res.Files)))
+		if res.Skipped > 0 {
+			fmt.Printf("Skipped %s messages without raw MIME\n", formatCount(int64(res.Skipped)))
+		}
+		return nil
+	},
+}
+
+// emlExportResult counts what exportEMLFiles did.
+type emlExportResult struct {
+	Output   string `json:"output"`
+	Exported int    `json:"exported"`
+	Files    int    `json:"files"`   // more than Exported with the label layout
+	Skipped  int    `json:"skipped"` // messages without raw MIME
+}
+
+// exportEMLFiles writes the raw MIME of each message matching q to an
+// .eml file under dir, in the folders layout calls for. progress, if
+// not nil, is called after each message.
+func exportEMLFiles(s *store.Store, q *search.Query, dir, layout string, progress func(done, total int)) (emlExportResult, error) {
+	res := emlExportResult{Output: dir}
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return res, fmt.Errorf("search: %w", err)
+	}
+	if err := fileutil.SecureMkdirAll(dir, 0o700); err != nil {
+		return res, fmt.Errorf("create output directory: %w", err)
+	}
+
+	for i, m := range msgs {
+		raw, err := s.GetMessageRawMIME(m.ID)
+		if err != nil {
+			return res, fmt.Errorf("read message %d: %w", m.ID, err)
+		}
+		if raw == nil {
+			res.Skipped++
+		} else {
+			name := emlFilename(m)
+			for _, folder := range emlFolders(m, layout) {
+				sub := filepath.Join(dir, folder)
+				if err := fileutil.SecureMkdirAll(sub, 0o700); err != nil {
+					return res, fmt.Errorf("create %s: %w", sub, err)
+				}
+				p := filepath.Join(sub, name)
+				if err := fileutil.SecureWriteFile(p, raw, emlFileMode); err != nil {
+					return res, fmt.Errorf("write %s: %w", p, err)
+				}
+				res.Files++
+			}
+			res.Exported++
+		}
+		if progress != nil {
+			progress(i+1, len(msgs))
+		}
+	}
+	return res, nil
+}
+
+// emlFilename names a message's .eml file by its ID, which keeps names
+// unique, and its subject, which makes them readable.
+func emlFilename(m store.APIMessage) string {
+	subject := strings.Join(strings.Fields(export.SanitizeFilename(m.Subject)), " ")
+	if r := []rune(subject); len(r) > 60 {
+		subject = strings.TrimSpace(string(r[:60]))
+	}
+	if subject == "" {
+		return fmt.Sprintf("%d.eml", m.ID)
+	}
+	return fmt.Sprintf("%d-%s.eml", m.ID, subject)
+}
+
+// emlFolders returns the folders, relative to the output directory, to
+// write a message's file into.
+func emlFolders(m store.APIMessage, layout string) []string {
+	switch layout {
+	case emlLayoutYear:
+		if m.SentAt.IsZero() {
+			return []string{"Undated"}
+		}
+		return []string{fmt.Sprintf("%04d", m.SentAt.Year())}
+	case emlLayoutLabel:
+		var folders []string
+		for _, l := range m.Labels {
+			folders = append(folders, emlFolderName(l, "Unlabeled"))
+		}
+		if len(folders) == 0 {
+			folders = []string{"Unlabeled"}
+		}
+		return folders
+	case emlLayoutSender:
+		return []string{emlFolderName(strings.ToLower(m.From), "Unknown sender")}
+	default:
+		return []string{"."}
+	}
+}
+
+// emlFolderName makes a label or address safe to use as one folder name.
+func emlFolderName(s, fallback string) string {
+	s = strings.TrimSpace(export.SanitizeFilename(s))
+	if s == "" || s == "." || s == ".." {
+		return fallback
+	}
+	return s
+}
+
+func init() {
+	exportEMLBatchCmd.Flags().StringVar(&exportEMLBatchQuery, "query", "", "only export messages matching this search query")
+	exportEMLBatchCmd.Flags().StringVarP(&exportEMLBatchOut, "out", "o", "", "output directory")
+	exportEMLBatchCmd.Flags().StringVar(&exportEMLBatchLayout, "layout", emlLayoutFlat, "folder layout: flat, year, label, or sender")
+	_ = exportEMLBatchCmd.MarkFlagRequired("out")
+	exportCmd.AddCommand(exportEMLBatchCmd)
+}
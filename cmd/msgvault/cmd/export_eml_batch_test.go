@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestExportEMLFiles(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	for i, m := range []struct{ from, subject, date string }{
+		{"bob@example.com", "Plans for Friday", "Mon, 01 Jan 2024 12:00:00 +0000"},
+		{"carol@example.com", "Re: invoice 3/4", "Tue, 02 Jan 2024 12:00:00 +0000"},
+		{"Bob@example.com", "Budget", "Fri, 03 Mar 2023 09:00:00 +0000"},
+	} {
+		raw := email.NewMessage().From(m.from).To("alice@example.com").
+			Subject(m.subject).Date(m.date).Body("Hello.\r\n").Bytes()
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "",
+			nil, "msg-"+m.subject, "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest message %d: %v", i, err)
+		}
+	}
+	// A message stored without raw MIME is skipped.
+	if _, err := st.DB().Exec(`DELETE FROM message_raw WHERE message_id = 2`); err != nil {
+		t.Fatal(err)
+	}
+
+	var labels []int64
+	for _, name := range []string{"Receipts", "Work"} {
+		id, err := st.EnsureLabel(src.ID, name, name, "user")
+		if err != nil {
+			t.Fatalf("ensure label: %v", err)
+		}
+		labels = append(labels, id)
+	}
+	if err := st.AddMessageLabels(1, labels); err != nil {
+		t.Fatalf("label message: %v", err)
+	}
+
+	tests := []struct {
+		layout string
+		want   []string
+	}{
+		{emlLayoutFlat, []string{"1-Plans for Friday.eml", "3-Budget.eml"}},
+		{emlLayoutYear, []string{"2023/3-Budget.eml", "2024/1-Plans for Friday.eml"}},
+		{emlLayoutSender, []string{"bob@example.com/1-Plans for Friday.eml", "bob@example.com/3-Budget.eml"}},
+		{emlLayoutLabel, []string{"Receipts/1-Plans for Friday.eml", "Unlabeled/3-Budget.eml", "Work/1-Plans for Friday.eml"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "out")
+			res, err := exportEMLFiles(st, search.Parse(""), dir, tt.layout, nil)
+			if err != nil {
+				t.Fatalf("exportEMLFiles: %v", err)
+			}
+			if res.Exported != 2 || res.Skipped != 1 || res.Files != len(tt.want) {
+				t.Errorf("result = %+v, want 2 exported, 1 skipped, %d files", res, len(tt.want))
+			}
+			var got []string
+			err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, p)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return err
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("files = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	dir := t.TempDir()
+	if _, err := exportEMLFiles(st, search.Parse("subject:Budget"), dir, emlLayoutFlat, nil); err != nil {
+		t.Fatalf("exportEMLFiles: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "3-Budget.eml"))
+	if err != nil {
+		t.Fatalf("read exported file: %v", err)
+	}
+	if !strings.Contains(string(data), "Subject: Budget") {
+		t.Errorf("exported file = %q, want the raw message", data)
+	}
+}
+
+func TestEMLFolders(t *testing.T) {
+	m := store.APIMessage{ID: 7, Subject: "  Q3 / Q4   plans ", Labels: []string{"Work/Projects", "..", "INBOX"}}
+	if got, want := emlFolders(m, emlLayoutLabel), []string{"Work_Projects", "Unlabeled", "INBOX"}; !slices.Equal(got, want) {
+		t.Errorf("label folders = %q, want %q", got, want)
+	}
+	if got := emlFolders(m, emlLayoutYear); !slices.Equal(got, []string{"Undated"}) {
+		t.Errorf("year folders of an undated message = %q", got)
+	}
+	if got := emlFolders(m, emlLayoutSender); !slices.Equal(got, []string{"Unknown sender"}) {
+		t.Errorf("sender folders without a sender = %q", got)
+	}
+	if got := emlFilename(m); got != "7-Q3 _ Q4 plans.eml" {
+		t.Errorf("emlFilename = %q", got)
+	}
+}
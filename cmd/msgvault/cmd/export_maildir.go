@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/maildir"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var exportMaildirQuery string
+
+var exportMaildirCmd = &cobra.Command{
+	Use:   "maildir <dir>",
+	Short: "Export messages to a Maildir++ folder tree",
+	Long: `Export messages to a Maildir++ hierarchy, which mutt, neomutt, notmuch,
+Dovecot, and most other Unix mail tools read directly.
+
+Labels become folders: INBOX is the top-level maildir, SENT, DRAFT, TRASH and
+SPAM become .Sent, .Drafts, .Trash and .Junk, and each other label becomes a
+folder of its own, nested labels such as "Work/Projects" as .Work.Projects.
+A message with several labels is written to each of their folders; one with
+no folder label goes to .Archive. UNREAD, STARRED, and DRAFT are kept as the
+messages' Maildir flags.
+
+Each message is written as it was received, from the raw MIME stored when it
+was synced or imported. Messages without raw MIME, such as chat messages, are
+skipped. File names are derived from the message IDs, so exporting again into
+the same directory overwrites the earlier copies rather than adding new ones.
+
+Use --query to export only the messages matching a search (same syntax as
+'msgvault search'); without it, every message is exported.
+
+Examples:
+  msgvault export maildir ~/Maildir/archive
+  msgvault export maildir receipts --query "label:Receipts after:2024-01-01"
+  notmuch --config=notmuch.cfg new   # with database.path set to the export`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("export maildir"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		q, err := parseLocalQuery(s, exportMaildirQuery)
+		if err != nil {
+			return err
+		}
+
+		res, err := exportMaildir(s, q, args[0], func(done, total int) {
+			if done%1000 == 0 {
+				fmt.Fprintf(os.Stderr, "Exported %s of %s messages...\n",
+					formatCount(int64(done)), formatCount(int64(total)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(res)
+		}
+		fmt.Printf("Exported %s messages to %s (%s files in %s folders)\n",
+			formatCount(int64(res.Exported)), res.Output,
+			formatCount(int64(res.Files)), formatCount(int64(res.Folders)))
+		if res.Skipped > 0 {
+			fmt.Printf("Skipped %s messages without raw MIME\n", formatCount(int64(res.Skipped)))
+		}
+		return nil
+	},
+}
+
+// maildirSystemFolders maps the system labels that are folders to the
+// folder names mail clients use for them.
+var maildirSystemFolders = map[string]string{
+	"INBOX": "",
+	"SENT":  "Sent",
+	"DRAFT": "Drafts",
+	"TRASH": "Trash",
+	"SPAM":  "Junk",
+}
+
+// maildirArchiveFolder holds messages with no folder label.
+const maildirArchiveFolder = "Archive"
+
+// maildirExportResult counts what exportMaildir did.
+type maildirExportResult struct {
+	Output   string `json:"output"`
+	Exported int    `json:"exported"`
+	Files    int    `json:"files"`   // a message has a file per folder
+	Folders  int    `json:"folders"` // including the inbox
+	Skipped  int    `json:"skipped"` // messages without raw MIME
+}
+
+// exportMaildir writes the raw MIME of the messages matching q into a
+// Maildir++ hierarchy at dir, a copy in the folder of each of their
+// labels. progress, if not nil, is called after each message.
+func exportMaildir(s *store.Store, q *search.Query, dir string, progress func(done, total int)) (maildirExportResult, error) {
+	res := maildirExportResult{Output: dir}
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return res, fmt.Errorf("search: %w", err)
+	}
+	md, err := maildir.Create(dir)
+	if err != nil {
+		return res, err
+	}
+
+	folders := map[string]bool{"": true}
+	for i, m := range msgs {
+		raw, err := s.GetMessageRawMIME(m.ID)
+		if err != nil {
+			return res, fmt.Errorf("read message %d: %w", m.ID, err)
+		}
+		if raw == nil {
+			res.Skipped++
+		} else {
+			key := fmt.Sprintf("%d.%d.msgvault", m.SentAt.Unix(), m.ID)
+			flags := maildirFlags(m.Labels)
+			for _, folder := range maildirFolders(m.Labels) {
+				if _, err := md.Deliver(folder, key, flags, raw); err != nil {
+					return res, fmt.Errorf("write message %d: %w", m.ID, err)
+				}
+				folders[folder] = true
+				res.Files++
+			}
+			res.Exported++
+		}
+		if progress != nil {
+			progress(i+1, len(msgs))
+		}
+	}
+	res.Folders = len(folders)
+	return res, nil
+}
+
+// maildirFolders returns the folders a message with these labels is
+// written to.
+func maildirFolders(labels []string) []string {
+	var folders []string
+	seen := map[string]bool{}
+	for _, l := range labels {
+		folder, ok := maildirSystemFolders[l]
+		if !ok {
+			if store.IsSystemLabel(l) {
+				continue // UNREAD, STARRED and the like are flags, not folders
+			}
+			if folder = maildir.FolderName(l); folder == "" {
+				continue
+			}
+		}
+		if !seen[folder] {
+			seen[folder] = true
+			folders = append(folders, folder)
+		}
+	}
+	if len(folders) == 0 {
+		folders = []string{maildirArchiveFolder}
+	}
+	return folders
+}
+
+// maildirFlags returns the Maildir flags of a message with these labels.
+func maildirFlags(labels []string) []rune {
+	var flags []rune
+	seen := true
+	for _, l := range labels {
+		switch l {
+		case "UNREAD":
+			seen = false
+		case "STARRED":
+			flags = append(flags, maildir.FlagFlagged)
+		case "DRAFT":
+			flags = append(flags, maildir.FlagDraft)
+		}
+	}
+	if seen {
+		flags = append(flags, maildir.FlagSeen)
+	}
+	return flags
+}
+
+func init() {
+	exportMaildirCmd.Flags().StringVar(&exportMaildirQuery, "query", "", "only export messages matching this search query")
+	exportCmd.AddCommand(exportMaildirCmd)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestExportMaildir(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	for i, from := range []string{"bob@example.com", "carol@example.com", "dan@example.com"} {
+		raw := email.NewMessage().From(from).To("alice@example.com").
+			Subject("Plans").Date("Mon, 01 Jan 2024 12:00:00 +0000").Body("Hello.\r\n").Bytes()
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "",
+			nil, "msg-"+from, "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest message %d: %v", i, err)
+		}
+	}
+	label := func(msgID int64, names ...string) {
+		var ids []int64
+		for _, n := range names {
+			id, err := st.EnsureLabel(src.ID, n, n, "user")
+			if err != nil {
+				t.Fatalf("ensure label: %v", err)
+			}
+			ids = append(ids, id)
+		}
+		if err := st.AddMessageLabels(msgID, ids); err != nil {
+			t.Fatalf("label message: %v", err)
+		}
+	}
+	label(1, "INBOX", "UNREAD", "Work/Projects")
+	label(2, "SENT", "STARRED")
+	// Message 3 has no labels and goes to the archive.
+
+	dir := filepath.Join(t.TempDir(), "Maildir")
+	res, err := exportMaildir(st, search.Parse(""), dir, nil)
+	if err != nil {
+		t.Fatalf("exportMaildir: %v", err)
+	}
+	if res.Exported != 3 || res.Files != 4 || res.Folders != 4 {
+		t.Errorf("result = %+v, want 3 messages in 4 files and 4 folders", res)
+	}
+
+	var got []string
+	err = filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() && d.Name() != "maildirfolder" {
+			rel, _ := filepath.Rel(dir, p)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := func(id int) string { return fmt.Sprintf("1704110400.%d.msgvault:2,", id) }
+	want := []string{
+		".Archive/cur/" + key(3) + "S",
+		".Sent/cur/" + key(2) + "FS",
+		".Work.Projects/cur/" + key(1),
+		"cur/" + key(1),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("files = %q\nwant %q", got, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "cur", key(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "From: bob@example.com\n") || strings.Contains(string(data), "\r") {
+		t.Errorf("exported message = %q", data)
+	}
+
+	// Exporting again overwrites rather than duplicating.
+	if _, err := exportMaildir(st, search.Parse(""), dir, nil); err != nil {
+		t.Fatalf("exportMaildir again: %v", err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "cur")); len(entries) != 1 {
+		t.Errorf("inbox has %d files after exporting twice, want 1", len(entries))
+	}
+}
+
+func TestMaildirFolders(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   []string
+	}{
+		{nil, []string{"Archive"}},
+		{[]string{"UNREAD", "IMPORTANT", "CATEGORY_UPDATES"}, []string{"Archive"}},
+		{[]string{"INBOX", "Receipts"}, []string{"", "Receipts"}},
+		{[]string{"TRASH", "SPAM", "DRAFT"}, []string{"Trash", "Junk", "Drafts"}},
+		{[]string{"Work/Projects", "Work.Projects"}, []string{"Work.Projects", "Work_Projects"}},
+	}
+	for _, tt := range tests {
+		if got := maildirFolders(tt.labels); !slices.Equal(got, tt.want) {
+			t.Errorf("maildirFolders(%q) = %q, want %q", tt.labels, got, tt.want)
+		}
+	}
+}
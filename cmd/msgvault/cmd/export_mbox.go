@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/mbox"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	exportMboxQuery string
+	exportMboxOut   string
+)
+
+var exportMboxCmd = &cobra.Command{
+	Use:   "mbox",
+	Short: "Export messages to an mbox file",
+	Long: `Export messages to a single mbox file, which Thunderbird, notmuch, mutt,
+and most other mail tools can open or import.
+
+Each message is written exactly as it was received, from the raw MIME stored
+when it was synced or imported, oldest first. Messages without raw MIME, such
+as chat messages, are skipped. The file uses the mboxrd format: body lines
+starting with "From " are escaped with '>'.
+
+Use --query to export only the messages matching a search (same syntax as
+'msgvault search'); without it, every message is exported.
+
+Examples:
+  msgvault export mbox --out archive.mbox
+  msgvault export mbox --query "label:Receipts after:2024-01-01" --out receipts.mbox
+  msgvault export mbox --query "from:alice@example.com" --out - | gzip > alice.mbox.gz`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("export mbox"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		q, err := parseLocalQuery(s, exportMboxQuery)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var f *os.File
+		if exportMboxOut != stdoutSentinel {
+			f, err = fileutil.SecureOpenFile(exportMboxOut, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, emlFileMode)
+			if err != nil {
+				return fmt.Errorf("create output file: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+
+		exported, skipped, err := exportMbox(s, q, out, func(done, total int) {
+			if done%1000 == 0 {
+				fmt.Fprintf(os.Stderr, "Exported %s of %s messages...\n",
+					formatCount(int64(done)), formatCount(int64(total)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			fmt.Fprintf(os.Stderr, "Exported %s messages.\n", formatCount(int64(exported)))
+			return nil
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("write %s: %w", exportMboxOut, err)
+		}
+
+		if jsonOutput {
+			return printJSON(map[string]any{
+				"output":   exportMboxOut,
+				"exported": exported,
+				"skipped":  skipped,
+			})
+		}
+		fmt.Printf("Exported %s messages to %s\n", formatCount(int64(exported)), exportMboxOut)
+		if skipped > 0 {
+			fmt.Printf("Skipped %s messages without raw MIME\n", formatCount(int64(skipped)))
+		}
+		return nil
+	},
+}
+
+// exportMbox writes the raw MIME of the messages matching q to w as
+// mbox, oldest first, and returns how many it wrote and how many it
+// skipped for having no raw MIME. progress, if not nil, is called after
+// each message.
+func exportMbox(s *store.Store, q *search.Query, w io.Writer, progress func(done, total int)) (exported, skipped int, err error) {
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("search: %w", err)
+	}
+
+	mw := mbox.NewWriter(w)
+	for i, m := range msgs {
+		raw, err := s.GetMessageRawMIME(m.ID)
+		if err != nil {
+			return exported, skipped, fmt.Errorf("read message %d: %w", m.ID, err)
+		}
+		if raw == nil {
+			skipped++
+		} else {
+			if err := mw.WriteMessage(m.From, m.SentAt, raw); err != nil {
+				return exported, skipped, fmt.Errorf("write message %d: %w", m.ID, err)
+			}
+			exported++
+		}
+		if progress != nil {
+			progress(i+1, len(msgs))
+		}
+	}
+	if err := mw.Flush(); err != nil {
+		return exported, skipped, fmt.Errorf("write mbox: %w", err)
+	}
+	return exported, skipped, nil
+}
+
+func init() {
+	exportMboxCmd.Flags().StringVar(&exportMboxQuery, "query", "", "only export messages matching this search query")
+	exportMboxCmd.Flags().StringVarP(&exportMboxOut, "out", "o", "", "output mbox file (use - for stdout)")
+	_ = exportMboxCmd.MarkFlagRequired("out")
+	exportCmd.AddCommand(exportMboxCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/mbox"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestExportMbox(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	for i, from := range []string{"bob@example.com", "carol@example.com"} {
+		raw := email.NewMessage().From(from).To("alice@example.com").
+			Subject("Plans").Date("Mon, 01 Jan 2024 12:00:00 +0000").
+			Body("From where I stand, yes.\r\n").Bytes()
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "",
+			nil, "msg-"+from, "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest message %d: %v", i, err)
+		}
+	}
+	// A message stored without raw MIME is skipped.
+	if _, err := st.DB().Exec(`DELETE FROM message_raw WHERE message_id = 2`); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	exported, skipped, err := exportMbox(st, search.Parse(""), &buf, nil)
+	if err != nil {
+		t.Fatalf("exportMbox: %v", err)
+	}
+	if exported != 1 || skipped != 1 {
+		t.Errorf("exported, skipped = %d, %d; want 1, 1", exported, skipped)
+	}
+
+	r := mbox.NewReader(&buf)
+	msg, err := r.Next()
+	if err != nil {
+		t.Fatalf("read exported mbox: %v", err)
+	}
+	if !strings.HasPrefix(msg.FromLine, "From bob@example.com Mon Jan  1 12:00:00 2024") {
+		t.Errorf("FromLine = %q", msg.FromLine)
+	}
+	if raw := string(msg.Raw); !strings.Contains(raw, "\nFrom where I stand, yes.\n") || strings.Contains(raw, "\r") {
+		t.Errorf("exported message = %q", raw)
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("second message: %v, want io.EOF", err)
+	}
+
+	buf.Reset()
+	if exported, _, err := exportMbox(st, search.Parse("from:carol@example.com"), &buf, nil); err != nil || exported != 0 {
+		t.Errorf("exportMbox(from:carol) = %d, %v; want 0 exported", exported, err)
+	}
+}
@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	stdmime "mime"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/mbox"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	exportTakeoutQuery string
+	exportTakeoutOut   string
+)
+
+// Paths of the files in a Takeout-style export, as Google Takeout lays
+// out a Gmail export.
+const (
+	takeoutMboxPath   = "Takeout/Mail/All mail Including Spam and Trash.mbox"
+	takeoutLabelsPath = "Takeout/Mail/labels.json"
+)
+
+var exportTakeoutCmd = &cobra.Command{
+	Use:   "takeout",
+	Short: "Export messages as a Google Takeout-style zip",
+	Long: `Export messages to a zip laid out like a Google Takeout Gmail export, for
+tools that already understand Takeout.
+
+The zip holds Takeout/Mail/All mail Including Spam and Trash.mbox, with each
+message's raw MIME oldest first, as Takeout writes it: messages synced from
+Gmail carry their Gmail message ID on the "From " separator line and
+X-GM-THRID and X-Gmail-Labels headers, with labels named as Takeout names
+them (Inbox, Sent, Category Updates, ...). Messages from other sources carry
+their labels but no Gmail IDs. Messages without raw MIME, such as chat
+messages, are skipped.
+
+Beside the mbox, Takeout/Mail/labels.json lists each exported label with its
+type and message count.
+
+'msgvault import takeout' reads the zip back, threads and labels included.
+
+Use --query to export only the messages matching a search (same syntax as
+'msgvault search'); without it, every message is exported.
+
+Examples:
+  msgvault export takeout --out takeout.zip
+  msgvault export takeout --query "label:Receipts after:2024-01-01" --out receipts.zip`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("export takeout"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		q, err := parseLocalQuery(s, exportTakeoutQuery)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		var f *os.File
+		if exportTakeoutOut != stdoutSentinel {
+			f, err = fileutil.SecureOpenFile(exportTakeoutOut, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, emlFileMode)
+			if err != nil {
+				return fmt.Errorf("create output file: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+
+		exported, skipped, err := exportTakeout(s, q, out, func(done, total int) {
+			if done%1000 == 0 {
+				fmt.Fprintf(os.Stderr, "Exported %s of %s messages...\n",
+					formatCount(int64(done)), formatCount(int64(total)))
+			}
+		})
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			fmt.Fprintf(os.Stderr, "Exported %s messages.\n", formatCount(int64(exported)))
+			return nil
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("write %s: %w", exportTakeoutOut, err)
+		}
+
+		if jsonOutput {
+			return printJSON(map[string]any{
+				"output":   exportTakeoutOut,
+				"exported": exported,
+				"skipped":  skipped,
+			})
+		}
+		fmt.Printf("Exported %s messages to %s\n", formatCount(int64(exported)), exportTakeoutOut)
+		if skipped > 0 {
+			fmt.Printf("Skipped %s messages without raw MIME\n", formatCount(int64(skipped)))
+		}
+		return nil
+	},
+}
+
+// takeoutLabelNames maps Gmail system label IDs to the names Takeout
+// writes in X-Gmail-Labels.
+var takeoutLabelNames = map[string]string{
+	"INBOX":     "Inbox",
+	"SENT":      "Sent",
+	"STARRED":   "Starred",
+	"IMPORTANT": "Important",
+	"SPAM":      "Spam",
+	"TRASH":     "Trash",
+	"DRAFT":     "Drafts",
+	"UNREAD":    "Unread",
+	"CHAT":      "Chat",
+}
+
+// takeoutLabelName returns the name Takeout gives a label and whether it
+// is a Gmail system label.
+func takeoutLabelName(label string) (name string, system bool) {
+	if name, ok := takeoutLabelNames[label]; ok {
+		return name, true
+	}
+	if cat, ok := strings.CutPrefix(label, "CATEGORY_"); ok && cat != "" {
+		return "Category " + cat[:1] + strings.ToLower(cat[1:]), true
+	}
+	return label, false
+}
+
+// takeoutLabelsFile is the labels sidecar written beside the mbox.
+type takeoutLabelsFile struct {
+	Labels []takeoutLabelEntry `json:"labels"`
+}
+
+type takeoutLabelEntry struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // "system" or "user"
+	Messages int    `json:"messages"`
+}
+
+// exportTakeout writes the messages matching q to w as a zip laid out
+// like a Google Takeout Gmail export, oldest first, and returns how many
+// it wrote and how many it skipped for having no raw MIME. progress, if
+// not nil, is called after each message.
+func exportTakeout(s *store.Store, q *search.Query, w io.Writer, progress func(done, total int)) (exported, skipped int, err error) {
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("search: %w", err)
+	}
+	ids := make([]int64, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	gmailIDs, err := s.GmailIDsForMessages(ids)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := time.Now()
+	zw := zip.NewWriter(w)
+	mf, err := zw.CreateHeader(&zip.FileHeader{Name: takeoutMboxPath, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return 0, 0, fmt.Errorf("write zip: %w", err)
+	}
+	mw := mbox.NewWriter(mf)
+	counts := make(map[string]*takeoutLabelEntry)
+	for i, m := range msgs {
+		raw, err := s.GetMessageRawMIME(m.ID)
+		if err != nil {
+			return exported, skipped, fmt.Errorf("read message %d: %w", m.ID, err)
+		}
+		if raw == nil {
+			skipped++
+		} else {
+			names := takeoutMessageLabels(m.Labels)
+			for _, l := range m.Labels {
+				name, system := takeoutLabelName(l)
+				e, ok := counts[name]
+				if !ok {
+					e = &takeoutLabelEntry{Name: name, Type: "user"}
+					if system {
+						e.Type = "system"
+					}
+					counts[name] = e
+				}
+				e.Messages++
+			}
+
+			sender := m.From
+			var thread string
+			if g, ok := gmailIDs[m.ID]; ok {
+				if id := gmailDecimalID(g.MessageID); id != "" {
+					sender = id + "@xxx"
+				}
+				thread = gmailDecimalID(g.ThreadID)
+			}
+			if err := mw.WriteMessage(sender, m.SentAt, withTakeoutHeaders(raw, thread, names)); err != nil {
+				return exported, skipped, fmt.Errorf("write message %d: %w", m.ID, err)
+			}
+			exported++
+		}
+		if progress != nil {
+			progress(i+1, len(msgs))
+		}
+	}
+	if err := mw.Flush(); err != nil {
+		return exported, skipped, fmt.Errorf("write mbox: %w", err)
+	}
+
+	var labels takeoutLabelsFile
+	labels.Labels = make([]takeoutLabelEntry, 0, len(counts))
+	for _, e := range counts {
+		labels.Labels = append(labels.Labels, *e)
+	}
+	slices.SortFunc(labels.Labels, func(a, b takeoutLabelEntry) int { return strings.Compare(a.Name, b.Name) })
+	data, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		return exported, skipped, fmt.Errorf("encode labels: %w", err)
+	}
+	lf, err := zw.CreateHeader(&zip.FileHeader{Name: takeoutLabelsPath, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return exported, skipped, fmt.Errorf("write zip: %w", err)
+	}
+	if _, err := lf.Write(append(data, '\n')); err != nil {
+		return exported, skipped, fmt.Errorf("write labels: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return exported, skipped, fmt.Errorf("write zip: %w", err)
+	}
+	return exported, skipped, nil
+}
+
+// takeoutMessageLabels returns the X-Gmail-Labels names for a message
+// with the given labels, sorted, followed by the Archived and Opened
+// pseudo-labels Takeout adds to messages outside the inbox and to read
+// messages.
+func takeoutMessageLabels(labels []string) []string {
+	names := make([]string, 0, len(labels)+2)
+	for _, l := range labels {
+		name, _ := takeoutLabelName(l)
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+	if !slices.Contains(labels, "INBOX") {
+		names = append(names, "Archived")
+	}
+	if !slices.Contains(labels, "UNREAD") {
+		names = append(names, "Opened")
+	}
+	return names
+}
+
+// gmailDecimalID converts a hex Gmail API ID to the decimal form Takeout
+// writes, or returns "" if id is not one.
+func gmailDecimalID(id string) string {
+	n, err := strconv.ParseUint(id, 16, 64)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(n, 10)
+}
+
+// takeoutHeaderNames are the headers withTakeoutHeaders writes,
+// lower-cased.
+var takeoutHeaderNames = []string{"x-gm-thrid", "x-gmail-labels"}
+
+// withTakeoutHeaders returns raw with X-GM-THRID (if thread is not
+// empty) and X-Gmail-Labels headers in front, replacing any it already
+// has, as messages imported from Takeout do.
+func withTakeoutHeaders(raw []byte, thread string, labels []string) []byte {
+	var buf bytes.Buffer
+	if thread != "" {
+		buf.WriteString("X-GM-THRID: " + thread + "\r\n")
+	}
+	var csvBuf strings.Builder
+	cw := csv.NewWriter(&csvBuf)
+	_ = cw.Write(labels)
+	cw.Flush()
+	value := strings.TrimRight(csvBuf.String(), "\n")
+	buf.WriteString("X-Gmail-Labels: " + stdmime.QEncoding.Encode("utf-8", value) + "\r\n")
+
+	// Copy the header block without earlier copies of these headers,
+	// then the body as is.
+	skipping := false
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line, raw = raw[:i+1], raw[i+1:]
+		} else {
+			raw = nil
+		}
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			buf.Write(line)
+			break
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			name, _, _ := bytes.Cut(line, []byte(":"))
+			skipping = slices.Contains(takeoutHeaderNames, strings.ToLower(strings.TrimSpace(string(name))))
+		}
+		if !skipping {
+			buf.Write(line)
+		}
+	}
+	buf.Write(raw)
+	return buf.Bytes()
+}
+
+func init() {
+	exportTakeoutCmd.Flags().StringVar(&exportTakeoutQuery, "query", "", "only export messages matching this search query")
+	exportTakeoutCmd.Flags().StringVarP(&exportTakeoutOut, "out", "o", "", "output zip file (use - for stdout)")
+	_ = exportTakeoutCmd.MarkFlagRequired("out")
+	exportCmd.AddCommand(exportTakeoutCmd)
+}
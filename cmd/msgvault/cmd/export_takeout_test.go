@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/mbox"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestExportTakeout(t *testing.T) {
+	openStore := func() *store.Store {
+		t.Helper()
+		st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+		if err != nil {
+			t.Fatalf("open store: %v", err)
+		}
+		t.Cleanup(func() { _ = st.Close() })
+		if err := st.InitSchema(); err != nil {
+			t.Fatalf("init schema: %v", err)
+		}
+		return st
+	}
+	st := openStore()
+	src, err := st.GetOrCreateSource("gmail", "alice@gmail.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	var labelIDs []int64
+	for _, l := range [][3]string{
+		{"INBOX", "INBOX", "system"},
+		{"CATEGORY_UPDATES", "CATEGORY_UPDATES", "system"},
+		{"Label_1", "Trips, 2024", "user"},
+	} {
+		id, err := st.EnsureLabel(src.ID, l[0], l[1], l[2])
+		if err != nil {
+			t.Fatalf("ensure label %s: %v", l[0], err)
+		}
+		labelIDs = append(labelIDs, id)
+	}
+	raw := email.NewMessage().From("bob@example.com").To("alice@gmail.com").
+		Subject("Itinerary").Date("Mon, 01 Jan 2024 12:00:00 +0000").
+		Header("X-Gmail-Labels", "Stale").
+		Body("See you there.\r\n").Bytes()
+	if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@gmail.com", "",
+		labelIDs, "18cf06223648b478", "hash", raw, time.Time{}, slog.Default()); err != nil {
+		t.Fatalf("ingest message: %v", err)
+	}
+	if _, err := st.DB().Exec(`UPDATE conversations SET source_conversation_id = '18cf06223648b478'`); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	exported, skipped, err := exportTakeout(st, search.Parse(""), &buf, nil)
+	if err != nil {
+		t.Fatalf("exportTakeout: %v", err)
+	}
+	if exported != 1 || skipped != 0 {
+		t.Errorf("exported, skipped = %d, %d; want 1, 0", exported, skipped)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = data
+	}
+
+	msg, err := mbox.NewReader(bytes.NewReader(files[takeoutMboxPath])).Next()
+	if err != nil {
+		t.Fatalf("read exported mbox: %v", err)
+	}
+	if !strings.HasPrefix(msg.FromLine, "From 1787654321098765432@xxx ") {
+		t.Errorf("FromLine = %q", msg.FromLine)
+	}
+	hdr, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(msg.Raw))).ReadMIMEHeader()
+	if err != nil {
+		t.Fatalf("read headers: %v", err)
+	}
+	if got := hdr.Get("X-GM-THRID"); got != "1787654321098765432" {
+		t.Errorf("X-GM-THRID = %q", got)
+	}
+	if got, want := hdr.Values("X-Gmail-Labels"), []string{`Category Updates,Inbox,"Trips, 2024",Opened`}; !slices.Equal(got, want) {
+		t.Errorf("X-Gmail-Labels = %q, want %q", got, want)
+	}
+
+	var labels takeoutLabelsFile
+	if err := json.Unmarshal(files[takeoutLabelsPath], &labels); err != nil {
+		t.Fatalf("decode labels: %v", err)
+	}
+	wantLabels := []takeoutLabelEntry{
+		{Name: "Category Updates", Type: "system", Messages: 1},
+		{Name: "Inbox", Type: "system", Messages: 1},
+		{Name: "Trips, 2024", Type: "user", Messages: 1},
+	}
+	if !slices.Equal(labels.Labels, wantLabels) {
+		t.Errorf("labels = %+v, want %+v", labels.Labels, wantLabels)
+	}
+
+	// The export imports back as Takeout with its IDs and labels.
+	mboxPath := filepath.Join(t.TempDir(), "All mail Including Spam and Trash.mbox")
+	if err := os.WriteFile(mboxPath, files[takeoutMboxPath], 0600); err != nil {
+		t.Fatalf("write mbox: %v", err)
+	}
+	dst := openStore()
+	if _, err := importer.ImportMbox(context.Background(), dst, mboxPath, importer.MboxImportOptions{
+		SourceType: "gmail", Identifier: "alice@gmail.com", Takeout: true, NoResume: true,
+	}); err != nil {
+		t.Fatalf("ImportMbox: %v", err)
+	}
+	var msgID, threadID string
+	if err := dst.DB().QueryRow(`
+		SELECT m.source_message_id, c.source_conversation_id
+		FROM messages m JOIN conversations c ON c.id = m.conversation_id`).Scan(&msgID, &threadID); err != nil {
+		t.Fatalf("query imported message: %v", err)
+	}
+	if msgID != "18cf06223648b478" || threadID != "18cf06223648b478" {
+		t.Errorf("imported IDs = %q, %q", msgID, threadID)
+	}
+	var n int
+	if err := dst.DB().QueryRow(`SELECT COUNT(*) FROM message_labels`).Scan(&n); err != nil || n != 3 {
+		t.Errorf("imported labels = %d, %v; want 3", n, err)
+	}
+}
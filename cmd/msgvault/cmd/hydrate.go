@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/sync"
+)
+
+var hydrateLimit int
+
+var hydrateCmd = &cobra.Command{
+	Use:   "hydrate [email]",
+	Short: "Download the messages a metadata-only sync left as placeholders",
+	Long: `Download in full the messages that 'sync-full --metadata-only' stored
+as placeholders, newest first: their bodies, attachments and raw MIME.
+
+With no email, every Gmail account with placeholders is hydrated. Run it
+again to continue after an interruption or a --limit; a plain 'sync-full'
+hydrates every placeholder as well.
+
+Examples:
+  msgvault hydrate
+  msgvault hydrate you@gmail.com --limit 10000`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("hydrate"); err != nil {
+			return err
+		}
+		if hydrateLimit < 0 {
+			return fmt.Errorf("--limit must be a non-negative number")
+		}
+
+		lock, err := acquireOpLock(cmd.Context(), "hydrate")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+		applyParseConfig(s)
+
+		sources, err := hydrateSources(s, args)
+		if err != nil {
+			return err
+		}
+		if len(sources) == 0 {
+			fmt.Println("No placeholders to hydrate.")
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		vf, err := setupVectorFeatures(ctx, s.DB(), cfg.DatabaseDSN())
+		if err != nil {
+			return fmt.Errorf("vector features: %w", err)
+		}
+		defer func() {
+			if vf != nil && vf.Close != nil {
+				if closeErr := vf.Close(); closeErr != nil {
+					logger.Warn("closing vectors.db failed", "error", closeErr)
+				}
+			}
+		}()
+
+		getOAuthMgr := oauthManagerCache()
+		for _, src := range sources {
+			client, err := gmailIncrementalClient(ctx, getOAuthMgr, src)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+			opts := sync.DefaultOptions()
+			opts.AttachmentsDir = cfg.AttachmentsDir()
+			opts.FetchWorkers, opts.FetchRate = cfg.Sync.FetchWorkers, cfg.Sync.FetchRate
+			opts.SkipAttachments = cfg.Sync.SkipAttachments
+			if cfg.Sync.WriteBatch > 0 {
+				opts.WriteBatch = cfg.Sync.WriteBatch
+			}
+			syncer := sync.New(client, s, opts).WithLogger(logger).WithProgress(&CLIProgress{})
+			if vf != nil {
+				syncer.SetEmbedEnqueuer(vf.Enqueuer)
+			}
+
+			fmt.Printf("Hydrating %s\n\n", src.Identifier)
+			summary, err := syncer.Hydrate(ctx, src, hydrateLimit)
+			_ = client.Close()
+			if err != nil {
+				if ctx.Err() != nil {
+					fmt.Println("\nHydrate interrupted. Run again to continue.")
+					return nil
+				}
+				return fmt.Errorf("%s: hydrate: %w", src.Identifier, err)
+			}
+
+			left, err := s.CountMetadataOnlyMessages(src.ID)
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			fmt.Printf("  Duration:      %s\n", summary.Duration.Round(time.Second))
+			fmt.Printf("  Hydrated:      %d of %d\n", summary.MessagesUpdated, summary.MessagesFound)
+			fmt.Printf("  Downloaded:    %.2f MB\n", float64(summary.BytesDownloaded)/(1024*1024))
+			if summary.Errors > 0 {
+				fmt.Printf("  Errors:        %d\n", summary.Errors)
+			}
+			fmt.Printf("  Placeholders:  %d left\n\n", left)
+		}
+		return nil
+	},
+}
+
+// hydrateSources returns the Gmail accounts to hydrate: the one named,
+// or every one with placeholders.
+func hydrateSources(s *store.Store, args []string) ([]*store.Source, error) {
+	if len(args) == 1 {
+		src, err := resolveSource(s, args[0], "gmail")
+		if err != nil {
+			return nil, err
+		}
+		return []*store.Source{src}, nil
+	}
+
+	all, err := s.ListSources("gmail")
+	if err != nil {
+		return nil, err
+	}
+	var sources []*store.Source
+	for _, src := range all {
+		n, err := s.CountMetadataOnlyMessages(src.ID)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			sources = append(sources, src)
+		}
+	}
+	return sources, nil
+}
+
+func init() {
+	hydrateCmd.Flags().IntVar(&hydrateLimit, "limit", 0, "Hydrate at most this many messages per account (0 = all)")
+	rootCmd.AddCommand(hydrateCmd)
+}
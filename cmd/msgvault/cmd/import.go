@@ -70,6 +70,7 @@ func runWhatsAppImport(cmd *cobra.Command, sourcePath string) error {
 		return fmt.Errorf("open database: %w", err)
 	}
 	defer func() { _ = s.Close() }()
+	applyParseConfig(s)
 
 	if err := s.InitSchema(); err != nil {
 		return fmt.Errorf("init schema: %w", err)
@@ -236,16 +237,23 @@ func (p *ImportCLIProgress) OnError(err error) {
 var importType string
 
 var importCmd = &cobra.Command{
-	Use:        "import [path]",
-	Short:      "Import messages (deprecated: use import-whatsapp)",
-	Deprecated: "use import-whatsapp instead",
-	Hidden:     true,
-	Args:       cobra.ExactArgs(1),
+	Use:   "import",
+	Short: "Import messages from local files",
+	Long: `Import messages from local files.
+
+Examples:
+  msgvault import mbox ~/Takeout/Mail/All\ mail\ Including\ Spam\ and\ Trash.mbox
+  msgvault import vault /mnt/backup/laptop-msgvault
+  msgvault import metadata metadata.json`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if importType == "" && len(args) == 0 {
+			return cmd.Help()
+		}
 		if err := MustBeLocal("import"); err != nil {
 			return err
 		}
-		if strings.ToLower(importType) != "whatsapp" {
+		if strings.ToLower(importType) != "whatsapp" || len(args) == 0 {
 			return fmt.Errorf(
 				"unsupported import type %q; use import-whatsapp",
 				importType,
@@ -276,5 +284,8 @@ func init() {
 	importCmd.Flags().StringVar(&importContacts, "contacts", "", "path to contacts .vcf file")
 	importCmd.Flags().IntVar(&importLimit, "limit", 0, "limit number of messages")
 	importCmd.Flags().StringVar(&importDisplayName, "display-name", "", "display name for the phone owner")
+	for _, name := range []string{"type", "phone", "media-dir", "contacts", "limit", "display-name"} {
+		_ = importCmd.Flags().MarkHidden(name)
+	}
 	rootCmd.AddCommand(importCmd)
 }
@@ -156,6 +156,7 @@ Examples:
 			return fmt.Errorf("open database: %w", err)
 		}
 		defer func() { _ = st.Close() }()
+		applyParseConfig(st)
 
 		if err := st.InitSchema(); err != nil {
 			return fmt.Errorf("init schema: %w", err)
@@ -509,3 +509,99 @@ func TestImportMboxCmd_EndToEnd_ZipResumeAcrossFiles(t *testing.T) {
 		}
 	}
 }
+
+func TestImportMboxSubcommand_DetectsAccount(t *testing.T) {
+	tmp := t.TempDir()
+
+	prevCfg := cfg
+	prevLogger := logger
+	prevAccount := importMboxAccount
+	prevSourceType := importMboxSourceType
+	prevNoResume := importMboxNoResume
+	prevCfgFile := cfgFile
+	prevHomeDir := homeDir
+	prevOut := rootCmd.OutOrStdout()
+	prevErr := rootCmd.ErrOrStderr()
+	t.Cleanup(func() {
+		cfg = prevCfg
+		logger = prevLogger
+		importMboxAccount = prevAccount
+		importMboxSourceType = prevSourceType
+		importMboxNoResume = prevNoResume
+		cfgFile = prevCfgFile
+		homeDir = prevHomeDir
+		rootCmd.SetOut(prevOut)
+		rootCmd.SetErr(prevErr)
+		rootCmd.SetArgs(nil)
+	})
+
+	raw := email.NewMessage().
+		From("Bob <bob@example.com>").
+		To("Alice <alice@example.com>").
+		Subject("Hello").
+		Date("Mon, 01 Jan 2024 12:00:00 +0000").
+		Header("Message-ID", "<msg1@example.com>").
+		Header("Delivered-To", "Alice@Example.com").
+		Body("Hi Alice.\n").
+		Bytes()
+	mboxPath := filepath.Join(tmp, "takeout.mbox")
+	data := append([]byte("From bob@example.com Mon Jan 1 12:00:00 2024\n"), raw...)
+	if err := os.WriteFile(mboxPath, append(data, '\n'), 0600); err != nil {
+		t.Fatalf("write mbox: %v", err)
+	}
+
+	var out strings.Builder
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs([]string{"--home", tmp, "import", "mbox", mboxPath, "--no-resume"})
+	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+		t.Fatalf("import mbox: %v", err)
+	}
+	if !strings.Contains(out.String(), "Importing as alice@example.com") {
+		t.Errorf("output missing detected account:\n%s", out.String())
+	}
+
+	st, err := store.Open(filepath.Join(tmp, "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	var sources, messages int
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM sources WHERE source_type = 'mbox' AND identifier = 'alice@example.com'`).Scan(&sources); err != nil {
+		t.Fatalf("count sources: %v", err)
+	}
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&messages); err != nil {
+		t.Fatalf("count messages: %v", err)
+	}
+	if sources != 1 || messages != 1 {
+		t.Fatalf("sources = %d, messages = %d, want 1, 1", sources, messages)
+	}
+}
+
+func TestDetectMboxAccount(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers string
+		want    string
+	}{
+		{"delivered-to wins", "Delivered-To: alice@example.com\nTo: bob@example.com\n", "alice@example.com"},
+		{"falls back to to", "To: Bob <Bob@Example.com>, carol@example.com\n", "bob@example.com"},
+		{"no recipients", "Subject: hi\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "x.mbox")
+			content := "From x Mon Jan 1 12:00:00 2024\n" + tt.headers + "\nbody\n"
+			if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+				t.Fatalf("write mbox: %v", err)
+			}
+			got, err := detectMboxAccount(path)
+			if err != nil {
+				t.Fatalf("detectMboxAccount: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("detectMboxAccount = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
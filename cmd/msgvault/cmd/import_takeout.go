@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var importTakeoutCmd = &cobra.Command{
+	Use:   "takeout <zip>",
+	Short: "Import a Google Takeout Gmail export",
+	Long: `Import a Google Takeout export of Gmail into the account's Gmail source.
+
+The export may be the Takeout .zip or the .mbox file inside it. Takeout
+records each message's Gmail labels (X-Gmail-Labels), thread (X-GM-THRID),
+and message ID, so messages are threaded and labelled as a Gmail sync would
+store them. This lets you seed an archive offline: after the import, add the
+account with 'msgvault add-account' and run 'msgvault sync-full', which
+downloads only the mail that is not already archived, and then keep it
+current with 'msgvault sync'.
+
+The account is taken from the messages' Delivered-To header. Pass --account
+when that is not the Gmail address the export came from.
+
+Examples:
+  msgvault import takeout ~/Downloads/takeout-20240101T000000Z-001.zip
+  msgvault import takeout takeout.zip --account you@gmail.com
+`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("import takeout"); err != nil {
+			return err
+		}
+		return runMboxImport(cmd, importMboxAccount, args[0], true)
+	},
+}
+
+func init() {
+	importTakeoutCmd.Flags().StringVar(&importMboxAccount, "account", "", "Gmail address the export belongs to (default: detected from Delivered-To)")
+	importTakeoutCmd.Flags().BoolVar(&importMboxNoResume, "no-resume", false, "Do not resume from an interrupted import")
+	importTakeoutCmd.Flags().IntVar(&importMboxCheckpointInterval, "checkpoint-interval", 200, "Save progress every N messages")
+	importTakeoutCmd.Flags().BoolVar(&importMboxNoAttachments, "no-attachments", false, "Do not store attachments (disk or database)")
+	importTakeoutCmd.Flags().BoolVar(&noDefaultIdentityImportMbox, "no-default-identity", false, noDefaultIdentityHelp)
+	importCmd.AddCommand(importTakeoutCmd)
+}
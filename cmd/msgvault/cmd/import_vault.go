@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var importVaultCmd = &cobra.Command{
+	Use:   "vault <other-data-dir>",
+	Short: "Merge another msgvault archive into this one",
+	Long: `Merge another msgvault data directory (for example, a vault built on a
+different machine or for a different set of accounts) into the current
+archive.
+
+Sources, participants, conversations, and labels are matched onto existing
+rows. Messages already present (same account and source message ID, or
+same RFC822 Message-ID) are not duplicated; their labels are merged
+instead. Attachment files are copied from the other vault's attachments
+directory, skipping any content already stored here.
+
+The argument may be a data directory containing msgvault.db or a path to
+the database file itself. The other vault is opened read-only.
+
+Examples:
+  msgvault import vault /mnt/backup/laptop-msgvault
+  msgvault import vault ~/old-vault/msgvault.db`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImportVault,
+}
+
+// legacyImportVaultCmd keeps the original top-level spelling working.
+var legacyImportVaultCmd = &cobra.Command{
+	Use:        "import-vault <other-data-dir>",
+	Short:      "Merge another msgvault archive into this one",
+	Deprecated: "use 'msgvault import vault' instead",
+	Args:       cobra.ExactArgs(1),
+	RunE:       runImportVault,
+}
+
+func init() {
+	importCmd.AddCommand(importVaultCmd)
+	rootCmd.AddCommand(legacyImportVaultCmd)
+}
+
+func runImportVault(cmd *cobra.Command, args []string) error {
+	if err := MustBeLocal("import vault"); err != nil {
+		return err
+	}
+
+	lock, err := acquireOpLock(cmd.Context(), "import vault")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	srcDBPath, srcAttachmentsDir, err := resolveVaultPaths(args[0])
+	if err != nil {
+		return err
+	}
+
+	s, err := openStoreAndInitForIngest()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	fmt.Printf("Merging %s into %s...\n", srcDBPath, cfg.DatabaseDSN())
+
+	result, err := s.MergeVault(cmd.Context(), srcDBPath)
+	if err != nil {
+		if result == nil {
+			return fmt.Errorf("merge vault: %w", err)
+		}
+		// Rows were committed; only post-merge bookkeeping failed.
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	var copied, present, failed int
+	for _, p := range result.AttachmentPaths {
+		ok, err := export.CopyAttachmentFile(srcAttachmentsDir, cfg.AttachmentsDir(), p)
+		switch {
+		case err != nil:
+			failed++
+			logger.Warn("failed to copy attachment", "path", p, "error", err)
+		case ok:
+			copied++
+		default:
+			present++
+		}
+	}
+
+	srcRawDir := filepath.Join(filepath.Dir(srcAttachmentsDir), "raw")
+	var rawCopied int
+	for _, h := range result.RawBlobHashes {
+		ok, err := store.CopyRawBlob(srcRawDir, cfg.RawBlobsDir(), h)
+		switch {
+		case err != nil:
+			failed++
+			logger.Warn("failed to copy raw message blob", "hash", h, "error", err)
+		case ok:
+			rawCopied++
+		}
+	}
+
+	if err := runPostSourceCreateMigrations(s); err != nil {
+		return fmt.Errorf("post-source-create migrations: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Merge complete!")
+	fmt.Printf("  Duration:       %s\n", result.Elapsed.Round(time.Millisecond))
+	fmt.Printf("  Accounts:       %d added, %d matched\n", result.SourcesAdded, result.SourcesMatched)
+	fmt.Printf("  Messages:       %d added, %d already present\n", result.MessagesAdded, result.MessagesSkipped)
+	fmt.Printf("  Conversations:  %d added\n", result.Conversations)
+	fmt.Printf("  Participants:   %d added\n", result.Participants)
+	fmt.Printf("  Labels:         %d added, %d message labels merged\n", result.Labels, result.LabelLinksAdded)
+	fmt.Printf("  Attachments:    %d rows, %d files copied, %d already stored\n",
+		result.AttachmentsAdded, copied, present)
+	if len(result.RawBlobHashes) > 0 {
+		fmt.Printf("  Raw blobs:      %d files copied\n", rawCopied)
+	}
+	if failed > 0 {
+		fmt.Printf("  Errors:         %d attachment or raw message files could not be copied (see log)\n", failed)
+	}
+
+	rebuildCacheAfterWrite(cfg.DatabaseDSN())
+	return nil
+}
+
+// resolveVaultPaths accepts either a msgvault data directory or a direct
+// path to its database file and returns (dbPath, attachmentsDir).
+func resolveVaultPaths(arg string) (string, string, error) {
+	p, err := filepath.Abs(arg)
+	if err != nil {
+		return "", "", fmt.Errorf("resolve path: %w", err)
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", "", fmt.Errorf("vault not found: %w", err)
+	}
+	if info.IsDir() {
+		dbPath := filepath.Join(p, "msgvault.db")
+		if _, err := os.Stat(dbPath); err != nil {
+			return "", "", fmt.Errorf("no msgvault.db in %s", p)
+		}
+		return dbPath, filepath.Join(p, "attachments"), nil
+	}
+	return p, filepath.Join(filepath.Dir(p), "attachments"), nil
+}
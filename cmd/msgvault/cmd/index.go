@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// ftsRebuildHint is the command that builds the search index.
+const ftsRebuildHint = "msgvault index rebuild --fts"
+
+var indexRebuildFTS bool
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage the search index",
+	Long: `Manage the full-text search index. 'msgvault stats fts' shows how much of
+the archive it covers.`,
+}
+
+var indexRebuildCmd = &cobra.Command{
+	Use:   "rebuild --fts",
+	Short: "Rebuild the full-text search index from scratch",
+	Long: `Drop and recreate the messages_fts virtual table, then repopulate it
+from messages / message_bodies / message_recipients / participants.
+
+Sync indexes new mail as it arrives, so run this after 'msgvault stats fts'
+or 'msgvault status' reports the index incomplete, such as after an
+upgrade or an import from an older vault. Search and the TUI no longer
+build the index themselves; until it is rebuilt, they find only the
+messages already in it.
+
+Also use this to recover from FTS5 shadow-table corruption that surfaces as
+"malformed inverted index for FTS5 table main.messages_fts" in
+'msgvault verify' output. SQLite's own 'rebuild' pragma reads from the
+same corrupt shadow tables and cannot clear this state.
+
+This command only fixes the derived search index. Core-table corruption
+(e.g., "Rowid out of order" in messages / message_bodies B-trees) requires
+a different recovery path — see 'msgvault verify' output.
+
+Peak extra disk usage is roughly the size of the FTS5 shadow tables
+(a few percent of the SQLite database). Stop 'msgvault serve' and any
+MCP clients before running this command — it needs an exclusive write lock.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !indexRebuildFTS {
+			return fmt.Errorf("name the index to rebuild: --fts")
+		}
+		return runRebuildFTS(cmd, "index rebuild")
+	},
+}
+
+var rebuildFTSCmd = &cobra.Command{
+	Use:        "rebuild-fts",
+	Short:      "Rebuild the full-text search index from scratch",
+	Deprecated: "use '" + ftsRebuildHint + "' instead",
+	Args:       cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRebuildFTS(cmd, "rebuild-fts")
+	},
+}
+
+// runRebuildFTS rebuilds the search index under the vault lock, naming
+// the operation op.
+func runRebuildFTS(cmd *cobra.Command, op string) error {
+	if err := MustBeLocal(op); err != nil {
+		return err
+	}
+	lock, err := acquireOpLock(cmd.Context(), op)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	dbPath := cfg.DatabaseDSN()
+	s, err := store.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	if err := s.InitSchema(); err != nil {
+		return fmt.Errorf("init schema: %w", err)
+	}
+
+	fmt.Fprintln(os.Stderr, "Rebuilding full-text search index...")
+	n, err := s.RebuildFTS(printFTSProgress)
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		if s.IsBusyError(err) {
+			return fmt.Errorf(
+				"database is busy — stop 'msgvault serve' and any MCP " +
+					"clients, then retry",
+			)
+		}
+		return fmt.Errorf("rebuild FTS: %w", err)
+	}
+	fmt.Fprintf(os.Stderr,
+		"\r  [%s] 100%%  %d messages indexed.\n",
+		strings.Repeat("=", 30), n)
+	return nil
+}
+
+// printFTSProgress draws a progress bar for an index rebuild on stderr.
+func printFTSProgress(done, total int64) {
+	if total <= 0 {
+		return
+	}
+	if done > total {
+		done = total
+	}
+	pct := int(done * 100 / total)
+	barWidth := 30
+	filled := barWidth * pct / 100
+	bar := strings.Repeat("=", filled) +
+		strings.Repeat(" ", barWidth-filled)
+	fmt.Fprintf(os.Stderr, "\r  [%s] %3d%%", bar, pct)
+}
+
+// warnIncompleteFTS tells the user on stderr when the search index
+// misses enough of the archive that results will be incomplete.
+func warnIncompleteFTS(s *store.Store) {
+	if !s.FTS5Available() || !s.NeedsFTSBackfill() {
+		return
+	}
+	fmt.Fprintf(os.Stderr,
+		"Warning: the search index is incomplete, so results may be missing "+
+			"messages; run '%s' to build it\n", ftsRebuildHint)
+}
+
+func init() {
+	indexRebuildCmd.Flags().BoolVar(&indexRebuildFTS, "fts", false, "rebuild the full-text search index")
+	indexCmd.AddCommand(indexRebuildCmd)
+	rootCmd.AddCommand(indexCmd, rebuildFTSCmd)
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/attachtext"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var indexAttachmentsReindex bool
+
+var indexAttachmentsCmd = &cobra.Command{
+	Use:   "index-attachments",
+	Short: "Index the text of stored attachments for in:attachments",
+	Long: `Extract the text of stored PDF, DOCX and plain-text attachments into the
+search index, so 'in:attachments' finds words inside them:
+
+  msgvault search 'in:attachments "lease renewal"'
+
+A file shared by several messages is read once. PDFs are read without a
+layout engine: encrypted ones, scans without a text layer, and text in fonts
+with an encoding of their own yield nothing.
+
+Only files not read before are indexed, and with index_attachments = true
+under [parse] sync indexes new attachments itself; use --reindex to read
+every file again.
+
+Examples:
+  msgvault index-attachments
+  msgvault index-attachments --reindex`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("index-attachments"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		stats, err := attachtext.Run(cmd.Context(), s, cfg.AttachmentsDir(), attachtext.Options{
+			Reindex: indexAttachmentsReindex,
+			Progress: func(done, total int) {
+				if done%100 == 0 {
+					fmt.Fprintf(os.Stderr, "Read %s of %s files...\n",
+						formatCount(int64(done)), formatCount(int64(total)))
+				}
+			},
+		}, logger)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(stats)
+		}
+		fmt.Printf("Read %s files, %s with text\n",
+			formatCount(int64(stats.Files)), formatCount(int64(stats.Indexed)))
+		if stats.Errors > 0 {
+			fmt.Printf("Could not read %s files; run with --verbose for details\n", formatCount(int64(stats.Errors)))
+		}
+		return nil
+	},
+}
+
+// indexAttachmentText indexes the text of the attachments a sync
+// stored, when index_attachments is set. Like scanAttachments, it warns
+// rather than failing the sync.
+func indexAttachmentText(ctx context.Context, s *store.Store) {
+	if !cfg.Parse.IndexAttachments {
+		return
+	}
+	if _, err := attachtext.Run(ctx, s, cfg.AttachmentsDir(), attachtext.Options{}, logger); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: attachment text not indexed: %v\n", err)
+	}
+}
+
+func init() {
+	indexAttachmentsCmd.Flags().BoolVar(&indexAttachmentsReindex, "reindex", false, "read files again even if already indexed")
+	rootCmd.AddCommand(indexAttachmentsCmd)
+}
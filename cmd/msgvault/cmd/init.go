@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// initTestSyncDays is how far back the wizard's test sync reaches.
+const initTestSyncDays = 7
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Guided first-run setup: config, storage, OAuth, and a test sync",
+	Long: `Guided first-run wizard that walks through everything needed to
+start archiving:
+
+  1. Choose where the database and attachments are stored
+  2. Locate Google OAuth credentials (client_secret.json)
+  3. Review encryption at rest, then save config.toml
+  4. Optionally authorize a Gmail account and sync its last 7 days
+
+Re-running init offers the current settings as defaults, so it is safe
+to use for changing storage paths or credentials later. For configuring
+a remote NAS server, see 'msgvault setup'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runInitWizard(cmd, bufio.NewReader(os.Stdin), runInitTestSync)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
+
+// runInitWizard drives the init prompts. testSync is called with the
+// account to authorize and sync once the config is saved; tests pass a
+// stub so the wizard runs without a browser or network.
+func runInitWizard(cmd *cobra.Command, reader *bufio.Reader, testSync func(cmd *cobra.Command, email string) error) error {
+	fmt.Println("Welcome to msgvault!")
+	fmt.Println()
+
+	if err := cfg.EnsureHomeDir(); err != nil {
+		return fmt.Errorf("create home directory: %w", err)
+	}
+
+	// Step 1: Storage locations
+	fmt.Println("Step 1: Storage")
+	fmt.Println("---------------")
+	dataDir := promptPath(reader, "Data directory (database, tokens, logs)", cfg.Data.DataDir)
+	defaultAttachments := cfg.Data.AttachmentsDir
+	if defaultAttachments == "" {
+		defaultAttachments = filepath.Join(dataDir, "attachments")
+	}
+	attachmentsDir := promptPath(reader, "Attachments directory", defaultAttachments)
+
+	cfg.Data.DataDir = dataDir
+	cfg.Data.AttachmentsDir = ""
+	if attachmentsDir != filepath.Join(dataDir, "attachments") {
+		cfg.Data.AttachmentsDir = attachmentsDir
+	}
+	for _, dir := range []string{dataDir, cfg.AttachmentsDir()} {
+		if err := fileutil.SecureMkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("create %s: %w", dir, err)
+		}
+	}
+
+	// Step 2: OAuth credentials
+	fmt.Println()
+	fmt.Println("Step 2: OAuth Credentials")
+	fmt.Println("--------------------------")
+	fmt.Println("Gmail accounts need a Google OAuth credential. Skip this if you")
+	fmt.Println("only archive IMAP accounts or import MBOX/Takeout files.")
+	if promptYesNo(reader, "Archive a Gmail account?") {
+		secretsPath, err := setupOAuthSecrets(reader)
+		if err != nil {
+			return err
+		}
+		if secretsPath != "" {
+			cfg.OAuth.ClientSecrets = secretsPath
+		}
+	}
+
+	// Step 3: Encryption at rest
+	fmt.Println()
+	fmt.Println("Step 3: Encryption at Rest")
+	fmt.Println("---------------------------")
+	fmt.Println("This build of msgvault stores the archive unencrypted. To protect")
+	fmt.Println("it, keep the data and attachments directories on an encrypted")
+	fmt.Println("volume (FileVault, BitLocker, or LUKS).")
+
+	// Save before the test sync, which reads credentials from cfg.
+	if err := cfg.Save(); err != nil {
+		return fmt.Errorf("save config: %w", err)
+	}
+	fmt.Printf("\nConfiguration saved to %s\n", cfg.ConfigFilePath())
+
+	// Step 4: Test sync
+	email := ""
+	if cfg.OAuth.HasAnyConfig() {
+		fmt.Println()
+		fmt.Println("Step 4: Test Sync (Optional)")
+		fmt.Println("-----------------------------")
+		fmt.Printf("Authorize a Gmail account and sync its last %d days to check\n", initTestSyncDays)
+		fmt.Println("that everything works.")
+		fmt.Print("Gmail address (blank to skip): ")
+		email, _ = reader.ReadString('\n')
+		email = strings.TrimSpace(email)
+	}
+	if email != "" {
+		if err := testSync(cmd, email); err != nil {
+			return fmt.Errorf("test sync: %w", err)
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Setup complete! Next steps:")
+	fmt.Println()
+	if email != "" {
+		fmt.Println("  Archive the rest of the mailbox:")
+		fmt.Printf("     msgvault sync-full %s\n", email)
+	} else {
+		fmt.Println("  Add an account:")
+		fmt.Println("     msgvault add-account you@gmail.com")
+		fmt.Println("     msgvault add-imap --host imap.example.com --username you@example.com")
+		fmt.Println()
+		fmt.Println("  Sync it:")
+		fmt.Println("     msgvault sync-full you@gmail.com")
+	}
+	fmt.Println()
+	fmt.Println("For more help: msgvault --help")
+	return nil
+}
+
+// runInitTestSync authorizes email (opening a browser if it has no
+// token yet) and syncs its most recent initTestSyncDays of mail.
+func runInitTestSync(cmd *cobra.Command, email string) error {
+	addAccountCmd.SetContext(cmd.Context())
+	if err := addAccountCmd.RunE(addAccountCmd, []string{email}); err != nil {
+		return err
+	}
+
+	savedAfter := syncAfter
+	defer func() { syncAfter = savedAfter }()
+	syncAfter = time.Now().AddDate(0, 0, -initTestSyncDays).Format("2006-01-02")
+
+	fmt.Printf("\nSyncing messages since %s...\n", syncAfter)
+	syncFullCmd.SetContext(cmd.Context())
+	return syncFullCmd.RunE(syncFullCmd, []string{email})
+}
+
+// promptPath asks for a directory, returning def when the answer is
+// blank. ~ is expanded and relative answers are made absolute.
+func promptPath(reader *bufio.Reader, prompt, def string) string {
+	fmt.Printf("%s [%s]: ", prompt, def)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	answer = config.ExpandPath(answer)
+	if abs, err := filepath.Abs(answer); err == nil {
+		answer = abs
+	}
+	return answer
+}
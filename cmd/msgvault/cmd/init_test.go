@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+)
+
+func TestInitWizard(t *testing.T) {
+	secrets := filepath.Join(t.TempDir(), "client_secret.json")
+	if err := os.WriteFile(secrets, []byte(`{"installed":{"client_id":"test"}}`), 0600); err != nil {
+		t.Fatalf("write secrets: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		input           func(home string) string
+		wantAttachments string // relative to home; empty means default
+		wantSecrets     string
+		wantSync        string
+	}{
+		{
+			name:  "defaults without gmail",
+			input: func(string) string { return "\n\nn\n" },
+		},
+		{
+			name: "custom attachments and test sync",
+			input: func(home string) string {
+				return "\n" + filepath.Join(home, "bulk") + "\ny\n" + secrets + "\nalice@example.com\n"
+			},
+			wantAttachments: "bulk",
+			wantSecrets:     secrets,
+			wantSync:        "alice@example.com",
+		},
+		{
+			name:        "gmail but skip test sync",
+			input:       func(string) string { return "\n\n\n" + secrets + "\n\n" },
+			wantSecrets: secrets,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savedCfg := cfg
+			defer func() { cfg = savedCfg }()
+			home := t.TempDir()
+			cfg = config.NewDefaultConfig()
+			cfg.HomeDir = home
+			cfg.Data.DataDir = home
+
+			var synced string
+			done := captureStdout(t)
+			err := runInitWizard(&cobra.Command{}, bufio.NewReader(strings.NewReader(tt.input(home))),
+				func(_ *cobra.Command, email string) error {
+					synced = email
+					return nil
+				})
+			out := done()
+			if err != nil {
+				t.Fatalf("runInitWizard: %v\n%s", err, out)
+			}
+			if synced != tt.wantSync {
+				t.Errorf("test sync for %q, want %q", synced, tt.wantSync)
+			}
+
+			loaded, err := config.Load("", home)
+			if err != nil {
+				t.Fatalf("load saved config: %v", err)
+			}
+			wantAtt := filepath.Join(home, "attachments")
+			if tt.wantAttachments != "" {
+				wantAtt = filepath.Join(home, tt.wantAttachments)
+			}
+			if got := loaded.AttachmentsDir(); got != wantAtt {
+				t.Errorf("AttachmentsDir = %q, want %q", got, wantAtt)
+			}
+			if _, err := os.Stat(wantAtt); err != nil {
+				t.Errorf("attachments dir not created: %v", err)
+			}
+			if loaded.OAuth.ClientSecrets != tt.wantSecrets {
+				t.Errorf("ClientSecrets = %q, want %q", loaded.OAuth.ClientSecrets, tt.wantSecrets)
+			}
+			if !strings.Contains(out, "unencrypted") {
+				t.Errorf("missing encryption note:\n%s", out)
+			}
+		})
+	}
+}
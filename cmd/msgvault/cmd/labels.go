@@ -0,0 +1,420 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/labelpush"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	labelsPushForce     bool
+	labelsPushDryRun    bool
+	labelsHistoryAt     string
+	labelsHistoryMonths int
+	labelsHistoryLimit  int
+)
+
+var labelsCmd = &cobra.Command{
+	Use:   "labels",
+	Short: "Edit message labels and push the edits to Gmail",
+	Long: `Edit message labels in the vault and push the edits back to Gmail.
+
+Label edits of Gmail messages, whether made here or by labeling rules, are
+queued. 'msgvault labels push' applies them to Gmail, one messages.modify
+call per message. A vault-only label is matched to the Gmail label of the
+same name, which is created if the account has none.
+
+Before pushing, the account's Gmail history is checked: if Gmail added or
+removed the same label on a message after the edit was queued, the edit is
+a conflict and stays queued. Sync and push again, push with --force to
+overwrite Gmail, or discard the queue.`,
+}
+
+var labelsAddCmd = &cobra.Command{
+	Use:   "add <label> <message-id>...",
+	Short: "Add a label to messages",
+	Long: `Add a label to messages, by internal or source message ID. Each message
+gets its account's label of that name, or a vault-only label if its account
+has none.
+
+Examples:
+  msgvault labels add Receipts 12345 18c1d2e3f4a5b6c7`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEditLabel(cmd, args[0], args[1:], true)
+	},
+}
+
+var labelsRemoveCmd = &cobra.Command{
+	Use:   "remove <label> <message-id>...",
+	Short: "Remove a label from messages",
+	Long: `Remove a label from messages, by internal or source message ID.
+
+Examples:
+  msgvault labels remove INBOX 12345 12346`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runEditLabel(cmd, args[0], args[1:], false)
+	},
+}
+
+func runEditLabel(cmd *cobra.Command, label string, refs []string, add bool) error {
+	if err := MustBeLocal("labels " + cmd.Name()); err != nil {
+		return err
+	}
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	engine := query.NewSQLiteEngine(s.DB())
+	ids := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		resolved, err := resolveMessage(engine, cmd, ref)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, resolved.ID)
+	}
+	if err := s.EditMessagesLabel(label, ids, add); err != nil {
+		return err
+	}
+	verb, prep := "Added", "to"
+	if !add {
+		verb, prep = "Removed", "from"
+	}
+	fmt.Printf("%s label %q %s %d message(s). Run 'msgvault labels push' to apply it in Gmail.\n",
+		verb, label, prep, len(ids))
+	return nil
+}
+
+var labelsPendingCmd = &cobra.Command{
+	Use:   "pending [email]",
+	Short: "List label edits waiting to be pushed to Gmail",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("labels pending"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		sourceID, err := labelsSourceID(s, args)
+		if err != nil {
+			return err
+		}
+		changes, err := s.PendingLabelChanges(sourceID)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if changes == nil {
+				changes = []store.PendingLabelChange{}
+			}
+			return printJSON(changes)
+		}
+		if len(changes) == 0 {
+			fmt.Println("No label edits waiting to be pushed.")
+			return nil
+		}
+		printLabelChanges(changes)
+		return nil
+	},
+}
+
+var labelsPushCmd = &cobra.Command{
+	Use:   "push [email]",
+	Short: "Apply queued label edits to Gmail",
+	Long: `Apply the queued label edits of one Gmail account, or of every Gmail
+account with edits queued, to Gmail.
+
+Examples:
+  msgvault labels push
+  msgvault labels push you@gmail.com --dry-run
+  msgvault labels push you@gmail.com --force`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("labels push"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		var sources []*store.Source
+		if len(args) == 1 {
+			source, err := resolveSource(s, args[0], "gmail")
+			if err != nil {
+				return err
+			}
+			sources = []*store.Source{source}
+		} else if sources, err = s.ListSources("gmail"); err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		getOAuthMgr := oauthManagerCache()
+		opts := labelpush.Options{Force: labelsPushForce, DryRun: labelsPushDryRun}
+		conflicts := 0
+		for _, src := range sources {
+			changes, err := s.PendingLabelChanges(src.ID)
+			if err != nil {
+				return err
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			client, err := buildAPIClient(ctx, src, getOAuthMgr, nil)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+			modifier, ok := client.(labelpush.Client)
+			if !ok {
+				_ = client.Close()
+				return fmt.Errorf("%s: client cannot modify labels", src.Identifier)
+			}
+			result, err := labelpush.New(s, modifier).WithLogger(logger).Push(ctx, src.ID, opts)
+			_ = client.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+
+			if labelsPushDryRun {
+				fmt.Printf("%s: would push %d message(s), %d conflict(s)\n",
+					src.Identifier, result.Pushed, result.Conflicts)
+			} else {
+				fmt.Printf("%s: pushed %d message(s), %d gone from Gmail, %d conflict(s), %d failed\n",
+					src.Identifier, result.Pushed, result.Gone, result.Conflicts, result.Failed)
+			}
+			if len(result.Conflicted) > 0 {
+				printLabelChanges(result.Conflicted)
+			}
+			conflicts += result.Conflicts
+		}
+		if conflicts > 0 {
+			fmt.Println("\nGmail changed the same labels on the conflicting messages since the edits")
+			fmt.Println("were queued. Sync and push again, push with --force to overwrite Gmail,")
+			fmt.Println("or run 'msgvault labels discard' to drop the edits.")
+		}
+		return nil
+	},
+}
+
+var labelsDiscardCmd = &cobra.Command{
+	Use:   "discard [email]",
+	Short: "Drop queued label edits without pushing them",
+	Long: `Drop the queued label edits of one account, or of every account, without
+pushing them to Gmail. The edits stay in the vault.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("labels discard"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		sourceID, err := labelsSourceID(s, args)
+		if err != nil {
+			return err
+		}
+		n, err := s.DiscardLabelChanges(sourceID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Discarded %d queued label edit(s)\n", n)
+		return nil
+	},
+}
+
+var labelsHistoryCmd = &cobra.Command{
+	Use:   "history <label>",
+	Short: "Show which messages had a label in the past",
+	Long: `Show how many messages had a label at the end of each recent month, or
+with --at, which messages had it at the end of a day.
+
+Label history is recorded from the label changes each sync reads from
+Gmail history, and from label edits made in the archive. Changes are
+dated when a sync saw them. Before its first recorded change, a message
+is taken to have had its labels since it arrived, so history is most
+accurate from when incremental syncs began.
+
+Examples:
+  msgvault labels history INBOX
+  msgvault labels history INBOX --at 2023-01-01
+  msgvault labels history Receipts --months 24`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("labels history"); err != nil {
+			return err
+		}
+		var day time.Time
+		if labelsHistoryAt != "" {
+			var err error
+			if day, err = time.Parse("2006-01-02", labelsHistoryAt); err != nil {
+				return fmt.Errorf("invalid --at date %q: use YYYY-MM-DD", labelsHistoryAt)
+			}
+		}
+		if labelsHistoryMonths < 1 {
+			return fmt.Errorf("--months must be at least 1")
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		label := args[0]
+		if labelsHistoryAt != "" {
+			return printLabelMembersAt(s, label, day)
+		}
+		return printLabelVolume(s, label, labelHistoryMonths(time.Now(), labelsHistoryMonths))
+	},
+}
+
+// printLabelMembersAt prints the messages that had label at the end of
+// day.
+func printLabelMembersAt(s *store.Store, label string, day time.Time) error {
+	at := day.AddDate(0, 0, 1).Add(-time.Second)
+	count, err := s.LabelCountAt(label, at)
+	if err != nil {
+		return err
+	}
+	members, err := s.LabelMembersAt(label, at, labelsHistoryLimit)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if members == nil {
+			members = []store.LabelMember{}
+		}
+		return printJSON(map[string]any{
+			"label":    label,
+			"date":     day.Format("2006-01-02"),
+			"count":    count,
+			"messages": members,
+		})
+	}
+	fmt.Printf("%d messages had %s at the end of %s.\n", count, label, day.Format("2006-01-02"))
+	if len(members) == 0 {
+		return nil
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tDATE\tACCOUNT\tSUBJECT")
+	_, _ = fmt.Fprintln(w, "──\t────\t───────\t───────")
+	for _, m := range members {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", m.MessageID, i18n.Date(m.SentAt), m.Account, truncate(m.Subject, 60))
+	}
+	_ = w.Flush()
+	if more := count - int64(len(members)); more > 0 {
+		fmt.Printf("\n... and %d more (raise --limit to list them)\n", more)
+	}
+	return nil
+}
+
+// labelMonthCount is how many messages had a label at the end of a
+// month.
+type labelMonthCount struct {
+	Month string `json:"month"`
+	Count int64  `json:"count"`
+}
+
+// printLabelVolume prints how many messages had label at each of ends,
+// as a bar chart.
+func printLabelVolume(s *store.Store, label string, ends []time.Time) error {
+	counts := make([]labelMonthCount, 0, len(ends))
+	var most int64
+	for _, end := range ends {
+		n, err := s.LabelCountAt(label, end)
+		if err != nil {
+			return err
+		}
+		counts = append(counts, labelMonthCount{Month: end.Format("2006-01"), Count: n})
+		most = max(most, n)
+	}
+	if jsonOutput {
+		return printJSON(map[string]any{"label": label, "months": counts})
+	}
+	fmt.Printf("Messages with %s at the end of each month:\n\n", label)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, c := range counts {
+		bar := ""
+		if most > 0 {
+			bar = strings.Repeat("█", int(c.Count*40/most))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", c.Month, i18n.Number(c.Count), bar)
+	}
+	_ = w.Flush()
+	return nil
+}
+
+// labelHistoryMonths returns the ends of the last months months up to
+// now, oldest first; the current month ends now.
+func labelHistoryMonths(now time.Time, months int) []time.Time {
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	ends := make([]time.Time, 0, months)
+	for i := months - 1; i >= 0; i-- {
+		end := first.AddDate(0, 1-i, 0).Add(-time.Second)
+		if end.After(now) {
+			end = now
+		}
+		ends = append(ends, end)
+	}
+	return ends
+}
+
+// labelsSourceID resolves the optional [email] argument of a labels
+// subcommand to a source ID, or 0 for every account.
+func labelsSourceID(s *store.Store, args []string) (int64, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	source, err := resolveSource(s, args[0], "gmail")
+	if err != nil {
+		return 0, err
+	}
+	return source.ID, nil
+}
+
+func printLabelChanges(changes []store.PendingLabelChange) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACCOUNT\tMESSAGE\tACTION\tLABEL\tQUEUED")
+	_, _ = fmt.Fprintln(w, "───────\t───────\t──────\t─────\t──────")
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Account, c.SourceMessageID,
+			c.Action, c.LabelName, i18n.Date(c.QueuedAt))
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	labelsPushCmd.Flags().BoolVar(&labelsPushForce, "force", false, "push edits even when Gmail changed the same labels since they were queued")
+	labelsPushCmd.Flags().BoolVar(&labelsPushDryRun, "dry-run", false, "show what would be pushed without changing Gmail")
+	labelsCmd.AddCommand(labelsAddCmd)
+	labelsCmd.AddCommand(labelsRemoveCmd)
+	labelsCmd.AddCommand(labelsPendingCmd)
+	labelsCmd.AddCommand(labelsPushCmd)
+	labelsHistoryCmd.Flags().StringVar(&labelsHistoryAt, "at", "", "list the messages that had the label at the end of this day (YYYY-MM-DD)")
+	labelsHistoryCmd.Flags().IntVar(&labelsHistoryMonths, "months", 12, "number of months to chart")
+	labelsHistoryCmd.Flags().IntVar(&labelsHistoryLimit, "limit", 50, "most messages to list with --at")
+	labelsCmd.AddCommand(labelsDiscardCmd)
+	labelsCmd.AddCommand(labelsHistoryCmd)
+	rootCmd.AddCommand(labelsCmd)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLabelHistoryMonths(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 30, 0, 0, time.UTC)
+	got := labelHistoryMonths(now, 3)
+	want := []time.Time{
+		time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC),
+		time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC),
+		now,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("labelHistoryMonths() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Equal(want[i]) {
+			t.Errorf("end %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/remote"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -169,22 +170,9 @@ func outputAccountsJSON(stats []accountStats) error {
 	return enc.Encode(output)
 }
 
-// formatCount formats a number with thousand separators.
+// formatCount formats a number with the locale's thousand separators.
 func formatCount(n int64) string {
-	if n < 1000 {
-		return fmt.Sprintf("%d", n)
-	}
-
-	// Format with commas
-	s := fmt.Sprintf("%d", n)
-	result := make([]byte, 0, len(s)+(len(s)-1)/3)
-	for i, c := range s {
-		if i > 0 && (len(s)-i)%3 == 0 {
-			result = append(result, ',')
-		}
-		result = append(result, byte(c))
-	}
-	return string(result)
+	return i18n.Number(n)
 }
 
 type accountStats struct {
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var listHeadersLimit int
+
+var listHeadersCmd = &cobra.Command{
+	Use:   "list-headers [header]",
+	Short: "List the mailing lists or addresses your mail arrives through",
+	Long: `List the values of an indexed header field, ranked by how many messages
+carry them: the mailing lists of List-Id (the default), the addresses and
+aliases mail was delivered to with delivered-to, or any header named in
+index_headers under [parse].
+
+Each value can be searched for: 'list:' matches List-Id, 'deliveredto:'
+Delivered-To, and 'header:Name=value' any indexed header.
+
+Headers are indexed as messages are synced or imported. Run 'msgvault
+reparse' to index them for messages stored earlier, or after changing
+index_headers.
+
+Examples:
+  msgvault list-headers
+  msgvault list-headers delivered-to --limit 20
+  msgvault list-headers x-original-to --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("list-headers"); err != nil {
+			return err
+		}
+		name := "list-id"
+		if len(args) == 1 {
+			name = strings.ToLower(args[0])
+		}
+		if !headerIndexed(name) {
+			return fmt.Errorf("header %q is not indexed; add it to index_headers under [parse] and run 'msgvault reparse'", name)
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		counts, err := s.HeaderValueCounts(name, listHeadersLimit)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if counts == nil {
+				counts = []store.HeaderValueCount{}
+			}
+			return printJSON(counts)
+		}
+		if len(counts) == 0 {
+			fmt.Printf("No messages with a %s header indexed.\n", name)
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "VALUE\tMESSAGES")
+		_, _ = fmt.Fprintln(w, "─────\t────────")
+		for _, c := range counts {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", truncate(c.Value, 60), formatCount(c.Messages))
+		}
+		_ = w.Flush()
+		fmt.Printf("\nShowing %d results\n", len(counts))
+		return nil
+	},
+}
+
+// headerIndexed reports whether a header field is indexed, by default
+// or through index_headers.
+func headerIndexed(name string) bool {
+	return slices.Contains(store.DefaultIndexedHeaders, strings.ToLower(name)) ||
+		slices.ContainsFunc(cfg.Parse.IndexHeaders, func(h string) bool { return strings.EqualFold(strings.TrimSpace(h), name) })
+}
+
+// warnUnindexedHeaders warns about header: filters on header fields
+// that are not indexed, which can never match.
+func warnUnindexedHeaders(q *search.Query) {
+	for _, h := range q.Headers {
+		if !headerIndexed(h.Name) {
+			fmt.Fprintf(os.Stderr,
+				"Warning: header %q is not indexed, so header:%s matches nothing; add it to index_headers under [parse] and run 'msgvault reparse'\n",
+				h.Name, h.Name)
+		}
+	}
+	for _, group := range q.Or {
+		for _, sub := range group {
+			warnUnindexedHeaders(sub)
+		}
+	}
+	for _, sub := range q.Not {
+		warnUnindexedHeaders(sub)
+	}
+}
+
+func init() {
+	listHeadersCmd.Flags().IntVar(&listHeadersLimit, "limit", 50, "Maximum number of values to list")
+	rootCmd.AddCommand(listHeadersCmd)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var listInfectedCmd = &cobra.Command{
+	Use:   "list-infected",
+	Short: "List attachments the virus scanner flagged",
+	Long: `List the attachments 'msgvault scan-attachments' found infected, with the
+signature the scanner reported and whether each is quarantined.
+
+Use 'msgvault show-message <id>' to read the message, and
+'msgvault release-attachment <content-hash>' to lift a false positive's
+quarantine.
+
+Examples:
+  msgvault list-infected
+  msgvault list-infected --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("list-infected"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		infected, err := s.InfectedAttachments()
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if infected == nil {
+				infected = []store.InfectedAttachment{}
+			}
+			return printJSON(infected)
+		}
+		if len(infected) == 0 {
+			fmt.Println("No infected attachments found.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "MESSAGE\tFILENAME\tSIGNATURE\tQUARANTINED\tSCANNED\tCONTENT HASH")
+		_, _ = fmt.Fprintln(w, "───────\t────────\t─────────\t───────────\t───────\t────────────")
+		for _, a := range infected {
+			quarantined := "no"
+			if a.Quarantined {
+				quarantined = "yes"
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", a.MessageID, truncate(a.Filename, 30),
+				truncate(a.Signature, 30), quarantined, i18n.Date(a.ScannedAt), a.ContentHash)
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+var releaseAttachmentCmd = &cobra.Command{
+	Use:   "release-attachment <content-hash>",
+	Short: "Lift the quarantine of an attachment",
+	Long: `Lift the virus scanner's quarantine of an attachment, as after a false
+positive, so it can be exported and downloaded again. The scanner's verdict
+is kept; a later 'msgvault scan-attachments --rescan' that still finds the
+file infected quarantines it again.
+
+Get the content hash from 'msgvault list-infected'.
+
+Example:
+  msgvault release-attachment 61ccf192b5bd358738802dc2676d3ceab856f47d26dd29681ac3d335bfd5bbd0`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("release-attachment"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		n, err := s.ReleaseAttachment(args[0])
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("no quarantined attachment with content hash %s", args[0])
+		}
+		fmt.Printf("Released %d attachment(s)\n", n)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listInfectedCmd)
+	rootCmd.AddCommand(releaseAttachmentCmd)
+}
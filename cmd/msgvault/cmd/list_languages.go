@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var listLanguagesCmd = &cobra.Command{
+	Use:   "list-languages",
+	Short: "List the languages your messages are written in",
+	Long: `List the languages of your messages, most common first, with how many
+messages and senders there are in each.
+
+The language of each message is detected from its subject and the text its
+author wrote, leaving out quoted replies, as it is synced or imported. Messages
+too short to tell are counted as unknown. Run 'msgvault reparse' to detect the
+language of messages stored before this feature existed.
+
+Search a language with the lang: operator and its code, e.g. 'lang:de'.
+
+Examples:
+  msgvault list-languages
+  msgvault list-languages --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("list-languages"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		stats, err := s.LanguageStats()
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if stats == nil {
+				stats = []store.LanguageStat{}
+			}
+			return printJSON(stats)
+		}
+		if len(stats) == 0 {
+			fmt.Println("No messages found.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CODE\tLANGUAGE\tMESSAGES\tSENDERS")
+		_, _ = fmt.Fprintln(w, "────\t────────\t────────\t───────")
+		for _, st := range stats {
+			code, name := st.Language, st.Name
+			if code == "" {
+				code, name = "-", "(unknown)"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", code, name,
+				formatCount(st.Messages), formatCount(st.Senders))
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listLanguagesCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	linkDomainsTrackers bool
+	linkDomainsLimit    int
+)
+
+var listLinkDomainsCmd = &cobra.Command{
+	Use:   "list-link-domains",
+	Short: "List the domains your mail links to, or tracks you from",
+	Long: `List the domains linked from your email's HTML bodies, ranked by how many
+messages link to them.
+
+With --trackers, list the domains that serve tracking pixels instead: tiny or
+hidden images, or images from known open-tracking services, that report when
+a message is opened. These are the domains that track you most.
+
+Links are recorded as messages are synced or imported. Run 'msgvault reparse'
+to record them for messages stored before this feature existed.
+
+Examples:
+  msgvault list-link-domains
+  msgvault list-link-domains --trackers --limit 20
+  msgvault list-link-domains --trackers --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("list-link-domains"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		kind := mime.LinkAnchor
+		if linkDomainsTrackers {
+			kind = mime.LinkTracker
+		}
+		stats, err := s.TopLinkDomains(kind, linkDomainsLimit)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if stats == nil {
+				stats = []store.LinkDomainStat{}
+			}
+			return printJSON(stats)
+		}
+		if len(stats) == 0 {
+			if linkDomainsTrackers {
+				fmt.Println("No tracking pixels found.")
+			} else {
+				fmt.Println("No links found.")
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "DOMAIN\tMESSAGES\tSENDERS")
+		_, _ = fmt.Fprintln(w, "──────\t────────\t───────")
+		for _, st := range stats {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", truncate(st.Domain, 50),
+				formatCount(st.Messages), formatCount(st.Senders))
+		}
+		_ = w.Flush()
+		fmt.Printf("\nShowing %d results\n", len(stats))
+		return nil
+	},
+}
+
+func init() {
+	listLinkDomainsCmd.Flags().BoolVar(&linkDomainsTrackers, "trackers", false, "List domains serving tracking pixels")
+	listLinkDomainsCmd.Flags().IntVar(&linkDomainsLimit, "limit", 50, "Maximum number of domains to list")
+	rootCmd.AddCommand(listLinkDomainsCmd)
+}
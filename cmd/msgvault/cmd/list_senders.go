@@ -5,7 +5,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/query"
-	"github.com/wesm/msgvault/internal/store"
 )
 
 var listSendersCmd = &cobra.Command{
@@ -13,6 +12,9 @@ var listSendersCmd = &cobra.Command{
 	Short: "List top senders by message count",
 	Long: `List email senders ranked by message count, size, or attachment size.
 
+Uses remote server if [remote].url is configured, otherwise uses local database.
+Use --local to force local database.
+
 Use this command to see who sends you the most email. Results can be filtered
 by date range and output as JSON for programmatic use.
 
@@ -26,23 +28,11 @@ Examples:
 			return err
 		}
 
-		// Open database
-		dbPath := cfg.DatabaseDSN()
-		s, err := store.Open(dbPath)
+		engine, closeEngine, err := OpenQueryEngine()
 		if err != nil {
-			return fmt.Errorf("open database: %w", err)
-		}
-		defer func() { _ = s.Close() }()
-
-		if err := s.InitSchema(); err != nil {
-			return fmt.Errorf("init schema: %w", err)
-		}
-		if err := runStartupMigrations(s); err != nil {
-			return fmt.Errorf("startup migrations: %w", err)
+			return err
 		}
-
-		// Create query engine
-		engine := query.NewSQLiteEngine(s.DB())
+		defer closeEngine()
 
 		// Execute aggregation
 		results, err := engine.Aggregate(cmd.Context(), query.ViewSenders, opts)
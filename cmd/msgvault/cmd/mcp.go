@@ -18,6 +18,7 @@ var mcpForceSQL bool
 var mcpNoSQLiteScanner bool
 var mcpHTTPAddr string
 var mcpHTTPAllowInsecure bool
+var mcpReadOnly bool
 
 var mcpCmd = &cobra.Command{
 	Use:   "mcp",
@@ -28,6 +29,10 @@ This allows Claude Desktop (or any MCP client) to query your email archive
 using tools like search_messages, get_message, list_messages, get_stats,
 aggregate, and stage_deletion.
 
+Use --read-only to withhold the tools that change anything outside the
+conversation (export_attachment and stage_deletion), so the assistant
+can search and read the archive but not write files or stage deletions.
+
 Add to Claude Desktop config:
   {
     "mcpServers": {
@@ -63,7 +68,7 @@ Add to Claude Desktop config:
 			fmt.Fprintf(os.Stderr,
 				"Warning: full-text search index needs populating; "+
 					"body-text search will return incomplete results "+
-					"until 'msgvault tui' or 'msgvault search' is run\n")
+					"until '%s' is run\n", ftsRebuildHint)
 		}
 
 		var engine query.Engine
@@ -114,6 +119,7 @@ Add to Claude Desktop config:
 			Engine:         engine,
 			AttachmentsDir: cfg.AttachmentsDir(),
 			DataDir:        cfg.Data.DataDir,
+			ReadOnly:       mcpReadOnly,
 		}
 		if vf != nil {
 			opts.HybridEngine = vf.HybridEngine
@@ -145,6 +151,8 @@ func init() {
 			"built-in authentication, so any reachable client can read your "+
 			"archive. Only set this on trusted networks (Tailscale, "+
 			"VPN-only) or behind an authenticating reverse proxy.")
+	mcpCmd.Flags().BoolVar(&mcpReadOnly, "read-only", false,
+		"Only expose tools that read the archive (no export_attachment or stage_deletion)")
 	_ = mcpCmd.Flags().MarkHidden("no-sqlite-scanner")
 }
 
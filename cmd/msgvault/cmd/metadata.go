@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var exportMetadataOut string
+
+var exportMetadataCmd = &cobra.Command{
+	Use:   "metadata",
+	Short: "Export your local notes, pins, tags, reminders, and saved searches to a JSON file",
+	Long: `Export everything you recorded locally about messages — notes, pins, tags,
+and reminders — and your saved searches to a portable JSON file. Messages
+are identified by their account and source message ID, with the RFC822
+Message-ID as a fallback, so the file can be imported with 'msgvault import
+metadata' into a rebuilt vault, or into another vault that archives the same
+accounts, such as a server vault.
+
+Examples:
+  msgvault export metadata --out metadata.json
+  msgvault export metadata --out - | ssh server msgvault import metadata -`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("export metadata"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		file, err := s.ExportMetadata()
+		if err != nil {
+			return err
+		}
+		if exportMetadataOut == stdoutSentinel {
+			return printJSONTo(cmd.OutOrStdout(), file)
+		}
+
+		f, err := fileutil.SecureOpenFile(exportMetadataOut, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, emlFileMode)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		if err := printJSONTo(f, file); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("write %s: %w", exportMetadataOut, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("write %s: %w", exportMetadataOut, err)
+		}
+
+		if jsonOutput {
+			return printJSON(map[string]any{
+				"output":         exportMetadataOut,
+				"messages":       len(file.Messages),
+				"saved_searches": len(file.SavedSearches),
+			})
+		}
+		fmt.Printf("Exported metadata for %s messages and %s saved searches to %s\n",
+			formatCount(int64(len(file.Messages))), formatCount(int64(len(file.SavedSearches))), exportMetadataOut)
+		return nil
+	},
+}
+
+var importMetadataCmd = &cobra.Command{
+	Use:   "metadata <file>",
+	Short: "Import notes, pins, tags, reminders, and saved searches exported by 'export metadata'",
+	Long: `Import a file written by 'msgvault export metadata' (use - for stdin).
+
+Each message in the file is looked up in this vault by its account and
+source message ID, or failing that by its RFC822 Message-ID. Imported tags
+are added to the ones a message already has, pins are set, and imported
+notes and reminders replace existing ones, as do saved searches of the same
+name; nothing is removed, so importing a file twice is harmless. Messages
+this vault does not have are counted and skipped.
+
+Examples:
+  msgvault import metadata metadata.json`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("import metadata"); err != nil {
+			return err
+		}
+
+		in := cmd.InOrStdin()
+		if args[0] != stdoutSentinel {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("open metadata file: %w", err)
+			}
+			defer func() { _ = f.Close() }()
+			in = f
+		}
+		var file store.MetadataFile
+		if err := json.NewDecoder(in).Decode(&file); err != nil {
+			return fmt.Errorf("read metadata file: %w", err)
+		}
+
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		stats, err := s.ImportMetadata(&file)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(stats)
+		}
+		fmt.Printf("Imported metadata for %s messages and %s saved searches\n",
+			formatCount(int64(stats.Imported)), formatCount(int64(stats.SavedSearches)))
+		if stats.Unmatched > 0 {
+			fmt.Printf("Skipped %s messages not in this vault\n", formatCount(int64(stats.Unmatched)))
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportMetadataCmd.Flags().StringVarP(&exportMetadataOut, "out", "o", "", "output JSON file (use - for stdout)")
+	_ = exportMetadataCmd.MarkFlagRequired("out")
+	exportCmd.AddCommand(exportMetadataCmd)
+	importCmd.AddCommand(importMetadataCmd)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	migrateRawTo     string
+	migrateRawVacuum bool
+)
+
+var migrateRawCmd = &cobra.Command{
+	Use:   "migrate-raw",
+	Short: "Move stored raw messages between the database and blob files",
+	Long: `Move the raw MIME and chat data stored for each message out of the
+database into content-addressed files under <data_dir>/raw, or back.
+
+With [data] raw_blob_store = true, msgvault keeps the raw data of messages
+archived from then on in files named by the SHA-256 of their compressed
+content, like attachments, rather than in the database. The database stays
+small, so backing it up and copying it are quick, and identical messages
+share one file. migrate-raw moves the messages already archived; run it
+after turning the setting on, or with --to database after turning it off.
+Data keeps its compression; 'msgvault recompress' changes that.
+
+Files no message refers to any more, such as those of deleted messages,
+are removed at the end. The database file keeps its size until it is
+vacuumed; pass --vacuum to reclaim the space freed by moving data out.
+
+Examples:
+  msgvault migrate-raw --vacuum
+  msgvault migrate-raw --to database`,
+	Args: cobra.NoArgs,
+	RunE: runMigrateRaw,
+}
+
+func runMigrateRaw(cmd *cobra.Command, _ []string) error {
+	if err := MustBeLocal("migrate-raw"); err != nil {
+		return err
+	}
+	var toBlobs bool
+	switch migrateRawTo {
+	case "files":
+		toBlobs = true
+	case "database":
+	default:
+		return fmt.Errorf("unknown --to %q (want files or database)", migrateRawTo)
+	}
+
+	jsonOut, restore := humanOutputToStderr()
+	defer restore()
+
+	lock, err := acquireOpLock(cmd.Context(), "migrate-raw")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	reported := 0
+	stats, err := s.MoveRawData(toBlobs, 500, func(st store.MoveRawStats) {
+		if st.Moved-reported >= 10000 {
+			reported = st.Moved
+			fmt.Fprintf(os.Stderr, "Moved %s raw messages...\n", formatCount(int64(st.Moved)))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("move raw messages: %w", err)
+	}
+	pruned, prunedBytes, err := s.PruneRawBlobs()
+	if err != nil {
+		return fmt.Errorf("remove unused raw message files: %w", err)
+	}
+
+	if migrateRawVacuum {
+		fmt.Fprintln(os.Stderr, "Vacuuming the database...")
+		if _, err := s.DB().Exec("VACUUM"); err != nil {
+			return fmt.Errorf("vacuum: %w", err)
+		}
+	}
+
+	if jsonOutput {
+		return printJSONTo(jsonOut, map[string]any{
+			"to":           migrateRawTo,
+			"moved":        stats.Moved,
+			"failed":       stats.Failed,
+			"bytes":        stats.Bytes,
+			"pruned":       pruned,
+			"pruned_bytes": prunedBytes,
+		})
+	}
+	where := "the database"
+	if toBlobs {
+		where = cfg.RawBlobsDir()
+	}
+	fmt.Printf("Moved %s raw messages (%s) to %s.\n",
+		formatCount(int64(stats.Moved)), formatSize(stats.Bytes), where)
+	if stats.Failed > 0 {
+		fmt.Printf("%s raw message files could not be read and were left where they were.\n",
+			formatCount(int64(stats.Failed)))
+	}
+	if pruned > 0 {
+		fmt.Printf("Removed %s unused raw message files (%s).\n", formatCount(int64(pruned)), formatSize(prunedBytes))
+	}
+	if toBlobs && !cfg.Data.RawBlobStore {
+		fmt.Println("Set raw_blob_store = true under [data], or messages archived from now on stay in the database.")
+	}
+	if !toBlobs && cfg.Data.RawBlobStore {
+		fmt.Println("Set raw_blob_store = false under [data], or messages archived from now on go to files.")
+	}
+	if !migrateRawVacuum && toBlobs && stats.Moved > 0 {
+		fmt.Println("Run 'msgvault migrate-raw --vacuum' or VACUUM the database to reclaim the freed space.")
+	}
+	return nil
+}
+
+func init() {
+	migrateRawCmd.Flags().StringVar(&migrateRawTo, "to", "files", "where to move raw messages: files or database")
+	migrateRawCmd.Flags().BoolVar(&migrateRawVacuum, "vacuum", false, "vacuum the database afterwards to reclaim space")
+	rootCmd.AddCommand(migrateRawCmd)
+}
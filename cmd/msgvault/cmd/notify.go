@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/notify"
+	"github.com/wesm/msgvault/internal/rules"
+)
+
+// ruleMatchPreview caps how many subjects a rule match notification lists.
+const ruleMatchPreview = 5
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Check notification settings",
+	Long: `Notifiers report vault events on the desktop, through ntfy.sh, by
+email, or to a Slack channel. Each [[notifiers]] entry in config.toml
+subscribes to some of these events (all of them when events is empty):
+
+  sync_complete  an account finished syncing
+  sync_error     an account failed to sync
+  rule_match     a rule with notify = true matched new mail
+  reminder_due   a reminder set with 'msgvault remind' came due
+
+Example:
+  [[notifiers]]
+  name = "phone"
+  type = "ntfy"
+  url = "https://ntfy.sh/my-msgvault-topic"
+  events = ["sync_error", "rule_match"]`,
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test [name]",
+	Short: "Send a test notification",
+	Long: `Send a test notification through the named notifier, or through all
+of them, regardless of the events each one subscribes to.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runNotifyTest,
+}
+
+func runNotifyTest(cmd *cobra.Command, args []string) error {
+	d, err := newNotifier()
+	if err != nil {
+		return err
+	}
+	name := ""
+	if len(args) == 1 {
+		name = args[0]
+	}
+	err = d.Test(cmd.Context(), name, notify.Event{
+		Type:    "test",
+		Title:   "Test notification",
+		Message: "Notifications from this vault will arrive here.",
+	})
+	if err != nil {
+		return fmt.Errorf("send test notification: %w", err)
+	}
+	fmt.Println("Test notification sent.")
+	return nil
+}
+
+// newNotifier returns the dispatcher for the configured notifiers, or
+// nil when there are none.
+func newNotifier() (*notify.Dispatcher, error) {
+	if len(cfg.Notifiers) == 0 {
+		return nil, nil
+	}
+	d, err := notify.New(cfg.Notifiers, logger)
+	if err != nil {
+		return nil, fmt.Errorf("configure notifiers: %w", err)
+	}
+	return d, nil
+}
+
+// notifySyncResults sends one event per account a sync command
+// finished. Interrupted runs are not reported.
+func notifySyncResults(ctx context.Context, d *notify.Dispatcher, results []*syncResult) {
+	for _, r := range results {
+		switch r.Status {
+		case syncResultCompleted:
+			d.Send(ctx, notify.Event{
+				Type:    notify.EventSyncComplete,
+				Title:   "Synced " + r.Account,
+				Message: syncCompleteMessage(r.MessagesAdded, time.Duration(r.DurationMs)*time.Millisecond),
+				Account: r.Account,
+			})
+		case syncResultInterrupted:
+		default:
+			msg := r.Error
+			if msg == "" {
+				msg = r.Status
+			}
+			d.Send(ctx, notify.Event{
+				Type:    notify.EventSyncError,
+				Title:   "Sync of " + r.Account + " failed",
+				Message: msg,
+				Account: r.Account,
+			})
+		}
+	}
+}
+
+// notifyScheduledSync reports the outcome of a scheduled sync.
+func notifyScheduledSync(ctx context.Context, d *notify.Dispatcher, email string, summary *gmail.SyncSummary, err error) {
+	if err != nil {
+		if ctx.Err() != nil {
+			return // shutting down
+		}
+		d.Send(ctx, notify.Event{
+			Type:    notify.EventSyncError,
+			Title:   "Sync of " + email + " failed",
+			Message: err.Error(),
+			Account: email,
+		})
+		return
+	}
+	d.Send(ctx, notify.Event{
+		Type:    notify.EventSyncComplete,
+		Title:   "Synced " + email,
+		Message: syncCompleteMessage(summary.MessagesAdded, summary.Duration),
+		Account: email,
+	})
+}
+
+func syncCompleteMessage(added int64, took time.Duration) string {
+	return fmt.Sprintf("%s new message(s) in %s", formatCount(added), took.Round(time.Second))
+}
+
+// ruleMatchNotifier returns a rules.Engine notify function that sends
+// rule_match events through d.
+func ruleMatchNotifier(d *notify.Dispatcher) func(ctx context.Context, m rules.Match) {
+	return func(ctx context.Context, m rules.Match) {
+		lines := make([]string, 0, ruleMatchPreview+1)
+		for i, msg := range m.Messages {
+			if i == ruleMatchPreview {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(m.Messages)-i))
+				break
+			}
+			lines = append(lines, fmt.Sprintf("%s: %s", msg.From, msg.Subject))
+		}
+		d.Send(ctx, notify.Event{
+			Type:    notify.EventRuleMatch,
+			Title:   fmt.Sprintf("Rule %s matched %d message(s)", m.Rule, len(m.Messages)),
+			Message: strings.Join(lines, "\n"),
+		})
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+}
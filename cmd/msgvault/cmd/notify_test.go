@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/notify"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// slackReceiver collects the text of Slack webhook posts.
+type slackReceiver struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (r *slackReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var payload map[string]string
+	_ = json.NewDecoder(req.Body).Decode(&payload)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.texts = append(r.texts, payload["text"])
+}
+
+// setupSlackNotifier configures one Slack notifier subscribed to events
+// and returns its receiver and dispatcher.
+func setupSlackNotifier(t *testing.T, events ...string) (*slackReceiver, *notify.Dispatcher) {
+	t.Helper()
+	recv := &slackReceiver{}
+	srv := httptest.NewServer(recv)
+	t.Cleanup(srv.Close)
+
+	savedCfg := cfg
+	t.Cleanup(func() { cfg = savedCfg })
+	cfg = &config.Config{Notifiers: []config.NotifierConfig{
+		{Name: "team", Type: "slack", URL: srv.URL, Events: events},
+	}}
+	d, err := newNotifier()
+	if err != nil {
+		t.Fatalf("newNotifier: %v", err)
+	}
+	return recv, d
+}
+
+func TestNewNotifier_NoneConfigured(t *testing.T) {
+	savedCfg := cfg
+	defer func() { cfg = savedCfg }()
+	cfg = &config.Config{}
+
+	d, err := newNotifier()
+	if err != nil || d != nil {
+		t.Errorf("newNotifier() = %v, %v; want nil, nil", d, err)
+	}
+}
+
+func TestNotifySyncResults(t *testing.T) {
+	recv, d := setupSlackNotifier(t)
+
+	notifySyncResults(context.Background(), d, []*syncResult{
+		{Account: "alice@example.com", Status: syncResultCompleted, MessagesAdded: 12, DurationMs: 3000},
+		{Account: "bob@example.com", Status: syncResultFailed, Error: "token expired"},
+		{Account: "carol@example.com", Status: syncResultInterrupted},
+	})
+
+	if len(recv.texts) != 2 {
+		t.Fatalf("got %d notifications, want 2: %q", len(recv.texts), recv.texts)
+	}
+	if want := "*msgvault: Synced alice@example.com*\n12 new message(s) in 3s"; recv.texts[0] != want {
+		t.Errorf("completion = %q, want %q", recv.texts[0], want)
+	}
+	if want := "*msgvault: Sync of bob@example.com failed*\ntoken expired"; recv.texts[1] != want {
+		t.Errorf("failure = %q, want %q", recv.texts[1], want)
+	}
+}
+
+func TestNotifyScheduledSync_EventFilter(t *testing.T) {
+	recv, d := setupSlackNotifier(t, notify.EventSyncError)
+
+	notifyScheduledSync(context.Background(), d, "alice@example.com",
+		&gmail.SyncSummary{MessagesAdded: 1, Duration: time.Second}, nil)
+	notifyScheduledSync(context.Background(), d, "alice@example.com", nil, errors.New("rate limited"))
+
+	if len(recv.texts) != 1 || !strings.Contains(recv.texts[0], "rate limited") {
+		t.Errorf("notifications = %q, want only the error", recv.texts)
+	}
+}
+
+func TestRuleMatchNotifier(t *testing.T) {
+	recv, d := setupSlackNotifier(t, notify.EventRuleMatch)
+
+	var msgs []store.APIMessage
+	for range ruleMatchPreview + 2 {
+		msgs = append(msgs, store.APIMessage{From: "shop@example.com", Subject: "Your receipt"})
+	}
+	ruleMatchNotifier(d)(context.Background(), rules.Match{Rule: "receipts", Notify: true, Messages: msgs})
+
+	if len(recv.texts) != 1 {
+		t.Fatalf("got %d notifications, want 1", len(recv.texts))
+	}
+	text := recv.texts[0]
+	if !strings.Contains(text, "Rule receipts matched 7 message(s)") ||
+		!strings.Contains(text, "shop@example.com: Your receipt") ||
+		!strings.HasSuffix(text, "…and 2 more") {
+		t.Errorf("unexpected text:\n%s", text)
+	}
+}
+
+func TestNotifyTest(t *testing.T) {
+	recv, _ := setupSlackNotifier(t, notify.EventSyncError)
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	done := captureStdout(t)
+	err := runNotifyTest(cmd, []string{"team"})
+	out := done()
+	if err != nil {
+		t.Fatalf("runNotifyTest: %v", err)
+	}
+	if !strings.Contains(out, "Test notification sent.") || len(recv.texts) != 1 {
+		t.Errorf("output %q, %d notifications", out, len(recv.texts))
+	}
+
+	if err := runNotifyTest(cmd, []string{"pager"}); err == nil ||
+		!strings.Contains(err.Error(), `no notifier named "pager"`) {
+		t.Errorf("unknown notifier error = %v", err)
+	}
+}
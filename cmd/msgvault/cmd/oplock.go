@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/wesm/msgvault/internal/oplock"
+)
+
+// acquireOpLock takes the vault's operation lock for a command that
+// writes to the archive, so two such commands never overlap. When
+// another operation holds the lock it fails right away, or with --wait
+// queues until that operation finishes or ctx is cancelled.
+func acquireOpLock(ctx context.Context, operation string) (*oplock.Lock, error) {
+	dir := cfg.Data.DataDir
+	if !waitForLock {
+		l, err := oplock.TryAcquire(dir, operation)
+		if errors.Is(err, oplock.ErrBusy) {
+			return nil, fmt.Errorf("%w (pass --wait to queue behind it)", err)
+		}
+		return l, err
+	}
+	return oplock.Acquire(ctx, dir, operation, func(busy *oplock.BusyError) {
+		if busy.Holder != nil {
+			fmt.Fprintf(os.Stderr, "Waiting for %s to finish...\n", busy.Holder)
+		} else {
+			fmt.Fprintln(os.Stderr, "Waiting for another msgvault operation to finish...")
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/oplock"
+)
+
+func TestAcquireOpLock(t *testing.T) {
+	savedCfg, savedWait := cfg, waitForLock
+	defer func() { cfg, waitForLock = savedCfg, savedWait }()
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{HomeDir: tmpDir, Data: config.DataConfig{DataDir: tmpDir}}
+
+	held, err := acquireOpLock(context.Background(), "sync")
+	if err != nil {
+		t.Fatalf("acquireOpLock() error = %v", err)
+	}
+	defer func() { _ = held.Release() }()
+
+	waitForLock = false
+	_, err = acquireOpLock(context.Background(), "deduplicate")
+	if !errors.Is(err, oplock.ErrBusy) {
+		t.Fatalf("busy acquireOpLock() error = %v, want ErrBusy", err)
+	}
+	for _, want := range []string{"sync (pid", "--wait"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q missing %q", err, want)
+		}
+	}
+
+	waitForLock = true
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := acquireOpLock(ctx, "deduplicate"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("--wait acquireOpLock() error = %v, want deadline exceeded", err)
+	}
+
+	if err := held.Release(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := acquireOpLock(context.Background(), "deduplicate")
+	if err != nil {
+		t.Fatalf("acquireOpLock() after release error = %v", err)
+	}
+	_ = l.Release()
+}
@@ -3,12 +3,14 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/query"
 )
 
@@ -115,18 +117,36 @@ func formatSize(bytes int64) string {
 
 	switch {
 	case bytes >= GB:
-		return fmt.Sprintf("%.1fG", float64(bytes)/float64(GB))
+		return i18n.Decimal(float64(bytes)/float64(GB), 1) + "G"
 	case bytes >= MB:
-		return fmt.Sprintf("%.1fM", float64(bytes)/float64(MB))
+		return i18n.Decimal(float64(bytes)/float64(MB), 1) + "M"
 	case bytes >= KB:
-		return fmt.Sprintf("%.1fK", float64(bytes)/float64(KB))
+		return i18n.Decimal(float64(bytes)/float64(KB), 1) + "K"
 	default:
 		return fmt.Sprintf("%dB", bytes)
 	}
 }
 
 func printJSON(v any) error {
-	enc := json.NewEncoder(os.Stdout)
+	return printJSONTo(os.Stdout, v)
+}
+
+func printJSONTo(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
 	return enc.Encode(v)
 }
+
+// humanOutputToStderr keeps stdout clean for a single JSON document
+// under --json: until restore is called, os.Stdout points at stderr so
+// progress lines from long-running commands stay visible without
+// corrupting the output. It returns the writer the JSON belongs on.
+// Without --json it changes nothing.
+func humanOutputToStderr() (jsonOut io.Writer, restore func()) {
+	stdout := os.Stdout
+	if !jsonOutput {
+		return stdout, func() {}
+	}
+	os.Stdout = os.Stderr
+	return stdout, func() { os.Stdout = stdout }
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+)
+
+var profileCreateSwitch bool
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage vault profiles",
+	Long: `Profiles keep separate vaults (for example work, personal, and
+family-archive) side by side. Each profile has its own config.toml,
+database, OAuth tokens, and attachments under profiles/<name> in the
+msgvault data directory; the "default" profile is the data directory
+itself (with config.toml in the config directory).
+
+The profile in use is chosen by, in order:
+  --profile <name>
+  the MSGVAULT_PROFILE environment variable
+  the last 'msgvault profile switch'
+  the default profile
+
+--home and --config select a vault directly and bypass profiles.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles, marking the active one",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		base := profileBase()
+		names, err := config.ListProfiles(base)
+		if err != nil {
+			return err
+		}
+		active := profileFlag
+		if active == "" {
+			if active, err = config.ActiveProfile(base); err != nil {
+				return err
+			}
+		}
+		if jsonOutput {
+			out := []profileJSON{}
+			for _, name := range names {
+				home, _ := config.ProfileHome(base, name)
+				out = append(out, profileJSON{Name: name, Home: home, Active: name == active})
+			}
+			return printJSON(out)
+		}
+		for _, name := range names {
+			marker := " "
+			if name == active {
+				marker = "*"
+			}
+			home, _ := config.ProfileHome(base, name)
+			fmt.Printf("%s %-20s %s\n", marker, name, home)
+		}
+		return nil
+	},
+}
+
+// profileJSON is one profile in 'profile list --json' output.
+type profileJSON struct {
+	Name   string `json:"name"`
+	Home   string `json:"home"`
+	Active bool   `json:"active"`
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an empty profile",
+	Long: `Create an empty profile. Run commands against it with --profile <name>
+(for example 'msgvault --profile work init'), or pass --switch to make
+it the default for later commands.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		base := profileBase()
+		home, err := config.CreateProfile(base, name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created profile %q in %s\n", name, home)
+		if profileCreateSwitch {
+			if err := config.SetActiveProfile(base, name); err != nil {
+				return err
+			}
+			fmt.Printf("Switched to profile %q.\n", name)
+		}
+		fmt.Printf("\nSet it up with: msgvault --profile %s init\n", name)
+		return nil
+	},
+}
+
+var profileSwitchCmd = &cobra.Command{
+	Use:   "switch <name>",
+	Short: "Make a profile the default for later commands",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		base := profileBase()
+		ok, err := config.ProfileExists(base, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("profile %q does not exist (create it with 'msgvault profile create %s')", name, name)
+		}
+		if err := config.SetActiveProfile(base, name); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to profile %q.\n", name)
+		return nil
+	},
+}
+
+// profileBase is the directory profiles live under: MSGVAULT_HOME or
+// the default data directory. The profile subcommands skip config
+// loading (so a deleted active profile can still be switched away
+// from) and resolve it themselves.
+func profileBase() string {
+	return config.DefaultDirs().Data
+}
+
+// resolveProfileHome picks the home directory to load config from.
+// An explicit --home or --config wins and disables profiles (the
+// returned profile name is empty). Otherwise the profile comes from
+// --profile, MSGVAULT_PROFILE, or the last 'profile switch'; the
+// default profile returns an empty home so config.Load uses
+// DefaultHome as it always has.
+func resolveProfileHome(flagProfile, configPath, home string) (string, string, error) {
+	if configPath != "" || home != "" {
+		if flagProfile != "" {
+			return "", "", fmt.Errorf("--profile cannot be combined with --home or --config")
+		}
+		return home, "", nil
+	}
+
+	base := profileBase()
+	name := flagProfile
+	if name == "" {
+		var err error
+		if name, err = config.ActiveProfile(base); err != nil {
+			return "", "", err
+		}
+	}
+	if name == config.DefaultProfile {
+		return "", name, nil
+	}
+
+	ok, err := config.ProfileExists(base, name)
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		return "", "", fmt.Errorf("profile %q does not exist (create it with 'msgvault profile create %s')", name, name)
+	}
+	profileHome, err := config.ProfileHome(base, name)
+	if err != nil {
+		return "", "", err
+	}
+	return profileHome, name, nil
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileSwitchCmd)
+
+	profileCreateCmd.Flags().BoolVar(&profileCreateSwitch, "switch", false, "Also make the new profile the default")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+func TestResolveProfileHome(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("MSGVAULT_HOME", base)
+	t.Setenv("MSGVAULT_PROFILE", "")
+	if _, err := config.CreateProfile(base, "work"); err != nil {
+		t.Fatalf("create work: %v", err)
+	}
+	if _, err := config.CreateProfile(base, "personal"); err != nil {
+		t.Fatalf("create personal: %v", err)
+	}
+	if err := config.SetActiveProfile(base, "personal"); err != nil {
+		t.Fatalf("switch: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		flag     string
+		env      string
+		cfgPath  string
+		home     string
+		wantHome string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "switched profile", wantHome: filepath.Join(base, "profiles", "personal"), wantName: "personal"},
+		{name: "env overrides switch", env: "work", wantHome: filepath.Join(base, "profiles", "work"), wantName: "work"},
+		{name: "flag overrides env", flag: "default", env: "work", wantHome: "", wantName: "default"},
+		{name: "unknown profile", flag: "nope", wantErr: true},
+		{name: "home bypasses profiles", home: "/tmp/elsewhere", wantHome: "/tmp/elsewhere"},
+		{name: "config bypasses profiles", cfgPath: "/tmp/c.toml", wantHome: ""},
+		{name: "flag conflicts with home", flag: "work", home: "/tmp/elsewhere", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MSGVAULT_PROFILE", tt.env)
+			home, name, err := resolveProfileHome(tt.flag, tt.cfgPath, tt.home)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if home != tt.wantHome || name != tt.wantName {
+				t.Errorf("got (%q, %q), want (%q, %q)", home, name, tt.wantHome, tt.wantName)
+			}
+		})
+	}
+}
@@ -15,12 +15,20 @@ import (
 	"github.com/wesm/msgvault/internal/query"
 )
 
-var queryFormat string
+var (
+	queryFormat string
+	querySQL    string
+)
 
 var queryCmd = &cobra.Command{
 	Use:   "query [sql]",
 	Short: "Run a SQL query against the analytics cache",
-	Long: `Run arbitrary SQL against the Parquet analytics cache.
+	Long: `Run arbitrary SQL against the Parquet analytics cache with DuckDB.
+
+The cache holds one set of Parquet files per table and is brought up to date
+before the query runs, as 'msgvault build-cache' would. Give the SQL as the
+argument or with --sql; --sql - reads it from stdin, for long queries kept
+in a file.
 
 The following views are available:
   messages, participants, message_recipients, labels,
@@ -41,9 +49,15 @@ Output formats:
 Examples:
   msgvault query "SELECT from_email, COUNT(*) AS n FROM v_messages GROUP BY 1 ORDER BY 2 DESC LIMIT 10"
   msgvault query --format csv "SELECT * FROM v_senders ORDER BY message_count DESC"
-  msgvault query --format table "SELECT name, message_count FROM v_labels"`,
-	Args: cobra.ExactArgs(1),
+  msgvault query --format table "SELECT name, message_count FROM v_labels"
+  msgvault query --sql - --format table < top-domains.sql`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		sqlStr, err := querySQLText(args, querySQL, cmd.Flags().Changed("sql"), cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+
 		dbPath := cfg.DatabaseDSN()
 		analyticsDir := cfg.AnalyticsDir()
 
@@ -72,11 +86,34 @@ Examples:
 		}
 
 		return executeQuery(
-			analyticsDir, args[0], queryFormat, os.Stdout,
+			analyticsDir, sqlStr, queryFormat, os.Stdout,
 		)
 	},
 }
 
+// querySQLText returns the SQL to run from the command's argument or
+// its --sql flag, reading it from stdin when the flag is "-".
+func querySQLText(args []string, flag string, flagSet bool, stdin io.Reader) (string, error) {
+	switch {
+	case flagSet && len(args) > 0:
+		return "", fmt.Errorf("give the SQL as an argument or with --sql, not both")
+	case len(args) > 0:
+		return args[0], nil
+	case !flagSet:
+		return "", fmt.Errorf("no SQL given; pass it as an argument or with --sql")
+	case flag == "-":
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return "", fmt.Errorf("read SQL from stdin: %w", err)
+		}
+		flag = string(data)
+	}
+	if strings.TrimSpace(flag) == "" {
+		return "", fmt.Errorf("empty SQL query")
+	}
+	return flag, nil
+}
+
 // executeQuery opens an in-memory DuckDB, registers views over
 // the Parquet files in analyticsDir, runs the SQL, and writes
 // the results in the requested format.
@@ -268,4 +305,8 @@ func init() {
 		&queryFormat, "format", "json",
 		"Output format: json, csv, or table",
 	)
+	queryCmd.Flags().StringVar(
+		&querySQL, "sql", "",
+		"SQL to run (use - to read it from stdin)",
+	)
 }
@@ -187,3 +187,33 @@ func TestQueryCommand_MissingCache(t *testing.T) {
 		t.Fatal("expected error for missing cache, got nil")
 	}
 }
+
+func TestQuerySQLText(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		flag    string
+		flagSet bool
+		stdin   string
+		want    string
+		wantErr bool
+	}{
+		{name: "argument", args: []string{"SELECT 1"}, want: "SELECT 1"},
+		{name: "flag", flag: "SELECT 2", flagSet: true, want: "SELECT 2"},
+		{name: "stdin", flag: "-", flagSet: true, stdin: "SELECT 3\n", want: "SELECT 3\n"},
+		{name: "both", args: []string{"SELECT 1"}, flag: "SELECT 2", flagSet: true, wantErr: true},
+		{name: "neither", wantErr: true},
+		{name: "empty stdin", flag: "-", flagSet: true, stdin: "  \n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := querySQLText(tt.args, tt.flag, tt.flagSet, strings.NewReader(tt.stdin))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("querySQLText error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("querySQLText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// quotaBarWidth is the width of the longest bar of the quota chart.
+const quotaBarWidth = 30
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota [email]",
+	Short: "Chart Google storage used against archive size over time",
+	Long: `Show the Google storage quota of each Gmail account, or of one, as
+recorded by its syncs, and chart the storage the account uses outside
+Google Drive against the size of its archive, month by month.
+
+Google reports no figure for Gmail alone: the storage used outside Drive
+is Gmail and Google Photos together. Archiving mail and then deleting it
+from Gmail shows as that line falling while the archive keeps growing.
+The archive size counts the messages deleted from Gmail after archiving.
+
+Syncs record the quota, one sample a day, with storage_quota = true under
+[sync]. Google reports it through the Drive API, which must be enabled in
+the project of the OAuth app; accounts added before the setting was on
+need 'msgvault add-account --force' to grant the extra scope.
+
+Examples:
+  msgvault quota
+  msgvault quota you@gmail.com --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("quota"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		var sources []*store.Source
+		if len(args) == 1 {
+			src, err := resolveSource(s, args[0], "gmail")
+			if err != nil {
+				return err
+			}
+			sources = []*store.Source{src}
+		} else if sources, err = s.ListSources("gmail"); err != nil {
+			return fmt.Errorf("list accounts: %w", err)
+		}
+
+		type accountQuota struct {
+			Account string                     `json:"account"`
+			Samples []store.StorageQuotaSample `json:"samples"`
+		}
+		results := make([]accountQuota, 0, len(sources))
+		for _, src := range sources {
+			samples, err := s.StorageQuotaHistory(src.ID)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+			if samples == nil {
+				samples = []store.StorageQuotaSample{}
+			}
+			results = append(results, accountQuota{Account: src.Identifier, Samples: samples})
+		}
+
+		if jsonOutput {
+			return printJSON(results)
+		}
+		if len(results) == 0 {
+			fmt.Println("No Gmail accounts. Use 'msgvault add-account <email>' to add one.")
+			return nil
+		}
+		for i, r := range results {
+			if i > 0 {
+				fmt.Println()
+			}
+			printQuota(r.Account, r.Samples)
+		}
+		if !cfg.Sync.StorageQuota {
+			fmt.Println()
+			fmt.Println("Set storage_quota = true under [sync] for syncs to record the quota.")
+		}
+		return nil
+	},
+}
+
+func printQuota(account string, samples []store.StorageQuotaSample) {
+	fmt.Println(account)
+	if len(samples) == 0 {
+		fmt.Println("  No storage quota recorded yet.")
+		return
+	}
+	last := samples[len(samples)-1]
+	switch {
+	case last.LimitBytes > 0:
+		fmt.Printf("  Used:     %s of %s (%s), as of %s\n", formatSize(last.UsageBytes),
+			formatSize(last.LimitBytes), formatPercent(float64(last.UsageBytes)/float64(last.LimitBytes)),
+			i18n.Date(last.RecordedAt))
+	default:
+		fmt.Printf("  Used:     %s, no limit, as of %s\n", formatSize(last.UsageBytes), i18n.Date(last.RecordedAt))
+	}
+	fmt.Printf("  Drive:    %s (%s in trash)\n", formatSize(last.DriveBytes), formatSize(last.DriveTrashBytes))
+	fmt.Printf("  Gmail and Photos: %s\n", formatSize(last.OutsideDriveBytes()))
+	fmt.Printf("  Archive:  %s\n", formatSize(last.ArchiveBytes))
+	fmt.Println()
+	for _, row := range quotaChart(samples) {
+		fmt.Println("  " + row)
+	}
+}
+
+// quotaChart renders samples, oldest first, as two bars a month, the
+// month's last sample: the storage used outside Drive and the archive
+// size, both scaled to the largest value of either.
+func quotaChart(samples []store.StorageQuotaSample) []string {
+	var months []store.StorageQuotaSample
+	for _, q := range samples {
+		if n := len(months); n > 0 && months[n-1].Day[:7] == q.Day[:7] {
+			months[n-1] = q
+			continue
+		}
+		months = append(months, q)
+	}
+	var peak int64
+	for _, q := range months {
+		peak = max(peak, q.OutsideDriveBytes(), q.ArchiveBytes)
+	}
+	bar := func(n int64) string {
+		if peak == 0 || n == 0 {
+			return "·"
+		}
+		return strings.Repeat(string(histogramBars[len(histogramBars)-1]), max(int(n*quotaBarWidth/peak), 1))
+	}
+	out := make([]string, 0, 2*len(months))
+	for _, q := range months {
+		out = append(out,
+			fmt.Sprintf("%s  Gmail    %s %s", q.Day[:7], bar(q.OutsideDriveBytes()), formatSize(q.OutsideDriveBytes())),
+			fmt.Sprintf("         Archive  %s %s", bar(q.ArchiveBytes), formatSize(q.ArchiveBytes)))
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(quotaCmd)
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestQuotaChart(t *testing.T) {
+	const mb = 1 << 20
+	got := quotaChart([]store.StorageQuotaSample{
+		{Day: "2024-01-05", UsageBytes: 40 * mb, DriveBytes: 10 * mb, ArchiveBytes: 0},
+		{Day: "2024-01-31", UsageBytes: 40 * mb, DriveBytes: 10 * mb, ArchiveBytes: 15 * mb},
+		{Day: "2024-02-10", UsageBytes: 12 * mb, DriveBytes: 10 * mb, ArchiveBytes: 1 << 10},
+	})
+	want := []string{
+		"2024-01  Gmail    ██████████████████████████████ 30.0M",
+		"         Archive  ███████████████ 15.0M",
+		"2024-02  Gmail    ██ 2.0M",
+		"         Archive  █ 1.0K",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("quotaChart =\n%q\nwant\n%q", got, want)
+	}
+}
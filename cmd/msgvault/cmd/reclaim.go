@@ -0,0 +1,320 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/dedup"
+	"github.com/wesm/msgvault/internal/deletion"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+const (
+	// reclaimMaxLists caps how many mailing lists are offered, one by one.
+	reclaimMaxLists = 20
+	// reclaimSampleSize is how many messages of a step are shown.
+	reclaimSampleSize = 5
+)
+
+var (
+	reclaimTarget  string
+	reclaimAccount string
+	reclaimYes     bool
+	reclaimDryRun  bool
+)
+
+var reclaimCmd = &cobra.Command{
+	Use:   "reclaim --target <size>",
+	Short: "Plan which archived mail to delete from your accounts to free space",
+	Long: `Build a plan that frees --target of storage in your Gmail and IMAP
+accounts by deleting mail that is safely archived, and stage it as deletion
+batches to review. Nothing is deleted from an account, or from the archive.
+
+The plan is built from four reports, offered in turn until the target is
+reached:
+
+  1. duplicates   extra copies of a message in the same account
+  2. newsletters  mailing list messages, a list at a time, largest first
+  3. attachments  messages with the largest attachments
+  4. largest      the largest messages of any kind
+
+Each step shows what it holds and asks whether to add it to the plan:
+y adds it, n skips it, and q stops with the plan so far. --yes adds every
+step without asking. Messages already deleted from their account are never
+offered, and sizes are those the account reported.
+
+The plan is staged as one deletion batch per account. Review the batches
+with 'msgvault list-deletions' and 'msgvault show-deletion', cancel any
+with 'msgvault cancel-deletion', and run them with 'msgvault delete-staged'
+(which moves the messages to the trash by default). --dry-run prints the
+plan without staging it.
+
+Examples:
+  msgvault reclaim --target 5GB
+  msgvault reclaim --target 500M --account you@gmail.com
+  msgvault reclaim --target 2G --yes --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("reclaim"); err != nil {
+			return err
+		}
+		target := search.ParseSize(reclaimTarget)
+		if target == nil || *target <= 0 {
+			return fmt.Errorf("invalid --target %q (want a size like 5GB, 500M or 750K)", reclaimTarget)
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		scope, err := ResolveAccountFlag(s, reclaimAccount)
+		if err != nil {
+			return err
+		}
+		out := cmd.OutOrStdout()
+		plan, err := planReclaim(s, *target, scope.SourceIDs(), cmd.InOrStdin(), out, reclaimYes)
+		if err != nil {
+			return err
+		}
+		if len(plan) == 0 {
+			_, _ = fmt.Fprintln(out, "\nNothing added to the plan.")
+			return nil
+		}
+		printReclaimPlan(out, plan, *target)
+		if reclaimDryRun {
+			return nil
+		}
+
+		mgr, err := deletion.NewManager(filepath.Join(cfg.Data.DataDir, "deletions"))
+		if err != nil {
+			return fmt.Errorf("create deletion manager: %w", err)
+		}
+		manifests, err := stageReclaimPlan(mgr, plan, *target)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "\nStaged %d deletion batch(es):\n", len(manifests))
+		for _, m := range manifests {
+			_, _ = fmt.Fprintf(out, "  %s  %s messages, %s (%s)\n", m.ID,
+				formatCount(int64(m.Summary.MessageCount)), formatSize(m.Summary.TotalSizeBytes), m.Filters.Account)
+		}
+		_, _ = fmt.Fprintln(out, "\nReview them with 'msgvault show-deletion <batch-id>' and run them with 'msgvault delete-staged'.")
+		return nil
+	},
+}
+
+// reclaimStep is one proposal of a reclaim plan: messages of a
+// category, or of one mailing list.
+type reclaimStep struct {
+	Title      string
+	Candidates []store.ReclaimCandidate
+}
+
+func (st *reclaimStep) size() int64 {
+	var n int64
+	for _, c := range st.Candidates {
+		n += c.Size
+	}
+	return n
+}
+
+// planReclaim offers the steps of each reclaim category in turn until
+// the steps accepted free target bytes, and returns those steps. The
+// answers are read from in; yes accepts every step without asking. A
+// q, or the end of in, stops with the steps accepted so far.
+func planReclaim(s *store.Store, target int64, sourceIDs []int64, in io.Reader, out io.Writer, yes bool) ([]reclaimStep, error) {
+	scanner := bufio.NewScanner(in)
+	chosen := make(map[int64]bool)
+	var plan []reclaimStep
+	var freed int64
+	for _, kind := range store.ReclaimCategories {
+		if freed >= target {
+			break
+		}
+		steps, err := reclaimSteps(s, kind, sourceIDs, chosen, target-freed)
+		if err != nil {
+			return nil, err
+		}
+		for _, step := range steps {
+			if freed >= target {
+				break
+			}
+			printReclaimStep(out, &step, freed, target)
+			if !yes {
+				_, _ = fmt.Fprint(out, "Add to the plan? [y/N/q]: ")
+				if !scanner.Scan() {
+					_, _ = fmt.Fprintln(out)
+					return plan, scanner.Err()
+				}
+				answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+				if answer == "q" || answer == "quit" {
+					return plan, nil
+				}
+				if answer != "y" && answer != "yes" {
+					continue
+				}
+			}
+			plan = append(plan, step)
+			freed += step.size()
+			for _, c := range step.Candidates {
+				chosen[c.ID] = true
+			}
+		}
+	}
+	return plan, nil
+}
+
+// reclaimSteps returns the steps of a reclaim category, leaving out
+// the messages already chosen. Attachments and the largest messages
+// make one step of the fewest messages that free need bytes.
+func reclaimSteps(s *store.Store, kind string, sourceIDs []int64, chosen map[int64]bool, need int64) ([]reclaimStep, error) {
+	var steps []reclaimStep
+	var total int64
+	err := s.ReclaimCandidates(kind, sourceIDs, func(c store.ReclaimCandidate) bool {
+		if chosen[c.ID] {
+			return true
+		}
+		if kind == store.ReclaimNewsletters {
+			if len(steps) == 0 || steps[len(steps)-1].Candidates[0].Group != c.Group {
+				if len(steps) == reclaimMaxLists {
+					return false
+				}
+				steps = append(steps, reclaimStep{Title: "Mailing list " + c.Group})
+			}
+		} else if len(steps) == 0 {
+			steps = append(steps, reclaimStep{Title: reclaimTitle(kind)})
+		}
+		last := &steps[len(steps)-1]
+		last.Candidates = append(last.Candidates, c)
+		total += c.Size
+		return kind == store.ReclaimDuplicates || kind == store.ReclaimNewsletters || total < need
+	})
+	return steps, err
+}
+
+func reclaimTitle(kind string) string {
+	switch kind {
+	case store.ReclaimDuplicates:
+		return "Duplicate copies"
+	case store.ReclaimAttachments:
+		return "Messages with the largest attachments"
+	default:
+		return "Largest messages"
+	}
+}
+
+func printReclaimStep(out io.Writer, step *reclaimStep, freed, target int64) {
+	_, _ = fmt.Fprintf(out, "\n%s: %s messages, %s (plan so far: %s of %s)\n", step.Title,
+		formatCount(int64(len(step.Candidates))), formatSize(step.size()), formatSize(freed), formatSize(target))
+	for i, c := range step.Candidates {
+		if i == reclaimSampleSize {
+			_, _ = fmt.Fprintf(out, "  …and %s more\n", formatCount(int64(len(step.Candidates)-i)))
+			break
+		}
+		_, _ = fmt.Fprintf(out, "  %7s  %s  %-30s  %s\n", formatSize(c.Size), c.SentAt.Format("2006-01-02"),
+			truncate(c.From, 30), truncate(c.Subject, 50))
+	}
+}
+
+func printReclaimPlan(out io.Writer, plan []reclaimStep, target int64) {
+	var count, freed int64
+	_, _ = fmt.Fprintln(out, "\nPlan:")
+	for _, step := range plan {
+		_, _ = fmt.Fprintf(out, "  %-45s %10s messages  %8s\n", truncate(step.Title, 45),
+			formatCount(int64(len(step.Candidates))), formatSize(step.size()))
+		count += int64(len(step.Candidates))
+		freed += step.size()
+	}
+	_, _ = fmt.Fprintf(out, "  %-45s %10s messages  %8s\n", "Total", formatCount(count), formatSize(freed))
+	if freed < target {
+		_, _ = fmt.Fprintf(out, "\nThe plan frees %s, short of the %s target.\n", formatSize(freed), formatSize(target))
+	}
+}
+
+// stageReclaimPlan saves a reclaim plan as pending deletion manifests,
+// one per account.
+func stageReclaimPlan(mgr *deletion.Manager, plan []reclaimStep, target int64) ([]*deletion.Manifest, error) {
+	byAccount := make(map[string][]store.ReclaimCandidate)
+	for _, step := range plan {
+		for _, c := range step.Candidates {
+			byAccount[c.Account] = append(byAccount[c.Account], c)
+		}
+	}
+	accounts := make([]string, 0, len(byAccount))
+	for a := range byAccount {
+		accounts = append(accounts, a)
+	}
+	slices.Sort(accounts)
+
+	var manifests []*deletion.Manifest
+	for _, account := range accounts {
+		cands := byAccount[account]
+		ids := make([]string, len(cands))
+		for i, c := range cands {
+			ids[i] = c.SourceMessageID
+		}
+		m := deletion.NewManifest("reclaim "+formatSize(target), ids)
+		m.ID += "-" + dedup.SanitizeFilenameComponent(account)
+		m.CreatedBy = "reclaim"
+		m.Filters.Account = account
+		m.Summary = reclaimSummary(account, cands)
+		if err := mgr.SaveManifest(m); err != nil {
+			return manifests, fmt.Errorf("save deletion batch for %s: %w", account, err)
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests, nil
+}
+
+// reclaimSummary summarizes the messages of one account's deletion
+// batch.
+func reclaimSummary(account string, cands []store.ReclaimCandidate) *deletion.Summary {
+	sum := &deletion.Summary{MessageCount: len(cands), Accounts: []string{account}}
+	senders := make(map[string]int)
+	for _, c := range cands {
+		sum.TotalSizeBytes += c.Size
+		if c.From != "" {
+			senders[c.From]++
+		}
+		if c.SentAt.IsZero() {
+			continue
+		}
+		day := c.SentAt.Format("2006-01-02")
+		if sum.DateRange[0] == "" || day < sum.DateRange[0] {
+			sum.DateRange[0] = day
+		}
+		if day > sum.DateRange[1] {
+			sum.DateRange[1] = day
+		}
+	}
+	for sender, n := range senders {
+		sum.TopSenders = append(sum.TopSenders, deletion.SenderCount{Sender: sender, Count: n})
+	}
+	sort.Slice(sum.TopSenders, func(i, j int) bool {
+		if sum.TopSenders[i].Count != sum.TopSenders[j].Count {
+			return sum.TopSenders[i].Count > sum.TopSenders[j].Count
+		}
+		return sum.TopSenders[i].Sender < sum.TopSenders[j].Sender
+	})
+	if len(sum.TopSenders) > 10 {
+		sum.TopSenders = sum.TopSenders[:10]
+	}
+	return sum
+}
+
+func init() {
+	reclaimCmd.Flags().StringVar(&reclaimTarget, "target", "", "storage to free, e.g. 5GB or 500M (required)")
+	reclaimCmd.Flags().StringVar(&reclaimAccount, "account", "", "only plan deletions in this account")
+	reclaimCmd.Flags().BoolVarP(&reclaimYes, "yes", "y", false, "add every step to the plan without asking")
+	reclaimCmd.Flags().BoolVar(&reclaimDryRun, "dry-run", false, "print the plan without staging it")
+	_ = reclaimCmd.MarkFlagRequired("target")
+	rootCmd.AddCommand(reclaimCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/deletion"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestPlanReclaim(t *testing.T) {
+	f := storetest.New(t)
+	list := f.NewMessage().WithSourceMessageID("list-1").WithSize(300_000).Create(t, f.Store)
+	f.NewMessage().WithSourceMessageID("big-1").WithSize(2_000_000).Create(t, f.Store)
+	f.NewMessage().WithSourceMessageID("big-2").WithSize(1_500_000).Create(t, f.Store)
+	f.NewMessage().WithSourceMessageID("big-3").WithSize(1_000_000).Create(t, f.Store)
+	_, err := f.Store.DB().Exec(`INSERT INTO message_headers (message_id, name, value) VALUES (?, 'list-id', 'news.example.com')`, list)
+	testutil.MustNoErr(t, err, "index List-Id")
+
+	// The mailing list is skipped; the largest messages are cut to the
+	// fewest that reach the target.
+	var out bytes.Buffer
+	plan, err := planReclaim(f.Store, 3_000_000, nil, strings.NewReader("n\ny\n"), &out, false)
+	testutil.MustNoErr(t, err, "planReclaim")
+	if len(plan) != 1 || plan[0].Title != "Largest messages" || len(plan[0].Candidates) != 2 || plan[0].size() != 3_500_000 {
+		t.Fatalf("plan = %+v, want the two largest messages\n%s", plan, out.String())
+	}
+	if !strings.Contains(out.String(), "Mailing list news.example.com: 1 messages") {
+		t.Errorf("output does not offer the mailing list:\n%s", out.String())
+	}
+
+	// The end of the answers stops with the plan so far.
+	plan, err = planReclaim(f.Store, 3_000_000, nil, strings.NewReader("y\n"), &bytes.Buffer{}, false)
+	testutil.MustNoErr(t, err, "planReclaim")
+	if len(plan) != 1 || plan[0].Title != "Mailing list news.example.com" {
+		t.Fatalf("plan = %+v, want only the mailing list", plan)
+	}
+
+	// --yes takes every step, the largest messages only as far as needed.
+	plan, err = planReclaim(f.Store, 2_000_000, nil, nil, &bytes.Buffer{}, true)
+	testutil.MustNoErr(t, err, "planReclaim")
+	if len(plan) != 2 || len(plan[1].Candidates) != 1 || plan[1].Candidates[0].SourceMessageID != "big-1" {
+		t.Fatalf("plan = %+v, want the list and big-1", plan)
+	}
+
+	mgr, err := deletion.NewManager(t.TempDir())
+	testutil.MustNoErr(t, err, "NewManager")
+	manifests, err := stageReclaimPlan(mgr, plan, 2_000_000)
+	testutil.MustNoErr(t, err, "stageReclaimPlan")
+	pending, err := mgr.ListPending()
+	testutil.MustNoErr(t, err, "ListPending")
+	if len(manifests) != 1 || len(pending) != 1 {
+		t.Fatalf("staged %d manifests, %d pending; want 1", len(manifests), len(pending))
+	}
+	m := pending[0]
+	if m.Filters.Account != "test@example.com" || len(m.GmailIDs) != 2 || m.Summary.TotalSizeBytes != 2_300_000 {
+		t.Errorf("manifest = %+v, summary %+v", m, m.Summary)
+	}
+}
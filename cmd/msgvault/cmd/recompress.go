@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	recompressCodec     string
+	recompressTrainDict bool
+	recompressSamples   int
+	recompressVacuum    bool
+)
+
+var recompressCmd = &cobra.Command{
+	Use:   "recompress",
+	Short: "Rewrite stored raw messages with another compression codec",
+	Long: `Rewrite the raw MIME and chat data stored for each message with the
+codec set by [data] raw_compression, or by --codec.
+
+msgvault compresses raw messages with zlib unless raw_compression is set
+to "zstd", which compresses better and decompresses faster. Changing the
+setting only affects messages archived from then on; recompress migrates
+the messages already in the vault. Set raw_compression along with
+--codec, or new messages keep the old codec. Messages already stored
+with the codec are skipped, so an interrupted run can simply be started
+again.
+
+With --train-dict, a zstd dictionary is first trained on the headers of
+the newest messages (--samples of them) and stored in the vault. Headers
+repeat from message to message, so a dictionary shrinks them well, above
+all in archives of many small messages. New messages are compressed with
+the newest dictionary too, and messages compressed with an older one are
+rewritten with it.
+
+The database file keeps its size until it is vacuumed; pass --vacuum to
+reclaim the space freed once the rewrite is done.
+
+Examples:
+  msgvault recompress --codec zstd --train-dict --vacuum
+  msgvault recompress`,
+	Args: cobra.NoArgs,
+	RunE: runRecompress,
+}
+
+func runRecompress(cmd *cobra.Command, _ []string) error {
+	if err := MustBeLocal("recompress"); err != nil {
+		return err
+	}
+	codec := rawCompressionCodec(cfg.Data.RawCompression)
+	if cmd.Flags().Changed("codec") {
+		codec = rawCompressionCodec(recompressCodec)
+	}
+	if !slices.Contains(store.RawCompressions, codec) {
+		return fmt.Errorf("unknown codec %q (want %s)", codec, strings.Join(store.RawCompressions, " or "))
+	}
+	if recompressTrainDict && codec != store.RawCompressionZstd {
+		return fmt.Errorf("--train-dict needs the zstd codec")
+	}
+
+	jsonOut, restore := humanOutputToStderr()
+	defer restore()
+
+	lock, err := acquireOpLock(cmd.Context(), "recompress")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+	s.SetRawCompression(codec)
+
+	result := map[string]any{"codec": codec}
+	if recompressTrainDict {
+		id, size, err := s.TrainRawDictionary(recompressSamples)
+		if err != nil {
+			return fmt.Errorf("train dictionary: %w", err)
+		}
+		fmt.Printf("Trained dictionary %d (%s) on message headers.\n", id, formatSize(int64(size)))
+		result["dictionary_id"] = id
+	}
+
+	reported := 0
+	stats, err := s.RecompressRaw(500, func(st store.RecompressStats) {
+		if st.Scanned-reported >= 10000 {
+			reported = st.Scanned
+			fmt.Fprintf(os.Stderr, "Scanned %s raw messages, rewrote %s...\n",
+				formatCount(int64(st.Scanned)), formatCount(int64(st.Rewritten)))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("recompress: %w", err)
+	}
+
+	if recompressVacuum {
+		fmt.Fprintln(os.Stderr, "Vacuuming the database...")
+		if _, err := s.DB().Exec("VACUUM"); err != nil {
+			return fmt.Errorf("vacuum: %w", err)
+		}
+	}
+
+	if jsonOutput {
+		result["scanned"] = stats.Scanned
+		result["rewritten"] = stats.Rewritten
+		result["failed"] = stats.Failed
+		result["bytes_before"] = stats.BytesBefore
+		result["bytes_after"] = stats.BytesAfter
+		return printJSONTo(jsonOut, result)
+	}
+	fmt.Printf("Rewrote %s of %s raw messages with %s",
+		formatCount(int64(stats.Rewritten)), formatCount(int64(stats.Scanned)), codec)
+	if stats.BytesBefore > 0 {
+		fmt.Printf(": %s to %s (%.0f%% smaller)", formatSize(stats.BytesBefore), formatSize(stats.BytesAfter),
+			100*(1-float64(stats.BytesAfter)/float64(stats.BytesBefore)))
+	}
+	fmt.Println(".")
+	if stats.Failed > 0 {
+		fmt.Printf("%s raw messages could not be decompressed and were left as they were.\n",
+			formatCount(int64(stats.Failed)))
+	}
+	if !recompressVacuum && stats.Rewritten > 0 {
+		fmt.Println("Run 'msgvault recompress --vacuum' or VACUUM the database to reclaim the freed space.")
+	}
+	return nil
+}
+
+func init() {
+	recompressCmd.Flags().StringVar(&recompressCodec, "codec", "", "codec to rewrite with: zlib or zstd (default [data] raw_compression)")
+	recompressCmd.Flags().BoolVar(&recompressTrainDict, "train-dict", false, "train a zstd dictionary on message headers first")
+	recompressCmd.Flags().IntVar(&recompressSamples, "samples", 2000, "messages to train the dictionary on")
+	recompressCmd.Flags().BoolVar(&recompressVacuum, "vacuum", false, "vacuum the database afterwards to reclaim space")
+	rootCmd.AddCommand(recompressCmd)
+}
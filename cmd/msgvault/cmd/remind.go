@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/notify"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// reminderSchedule is how often 'serve' and 'daemon' look for reminders
+// that have come due.
+const reminderSchedule = "*/5 * * * *"
+
+// reminderPreview caps how many reminders one notification lists.
+const reminderPreview = 5
+
+var (
+	remindClear  bool
+	remindersDue bool
+)
+
+var remindCmd = &cobra.Command{
+	Use:   "remind <message-id> [when] [note]",
+	Short: "Show or set a reminder to look at a message again",
+	Long: `Set a reminder on a message, show the one it has, or remove it. Like notes,
+reminders are kept only in this archive.
+
+When is a date (2026-05-01) or a time from now: 3d, 2w, 11m or 1y for days,
+weeks, months or years. A message has at most one reminder; setting another
+replaces it.
+
+Due reminders are found with 'msgvault search is:due' and listed by
+'msgvault reminders --due'. The TUI shows how many are due when it starts,
+and 'serve' and 'daemon' send a reminder_due notification through the
+configured notifiers as each comes due.
+
+Examples:
+  msgvault remind 12345 11m "renew the contract"
+  msgvault remind 12345 2026-05-01
+  msgvault remind 12345
+  msgvault remind 12345 --clear`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAnnotatedMessage(cmd, args[0], func(s *store.Store, id int64) error {
+			if remindClear {
+				if len(args) > 1 {
+					return fmt.Errorf("--clear takes no time or note")
+				}
+				if err := s.ClearReminder(id); err != nil {
+					return err
+				}
+				fmt.Printf("Removed the reminder on message %d\n", id)
+				return nil
+			}
+			if len(args) == 1 {
+				r, err := s.GetReminder(id)
+				if err != nil {
+					return err
+				}
+				if jsonOutput {
+					return printJSON(r)
+				}
+				if r == nil {
+					fmt.Printf("Message %d has no reminder\n", id)
+					return nil
+				}
+				fmt.Printf("Due %s\n", i18n.DateTime(r.DueAt.Local()))
+				if r.Note != "" {
+					fmt.Println(r.Note)
+				}
+				return nil
+			}
+			due, err := parseReminderTime(args[1], time.Now())
+			if err != nil {
+				return err
+			}
+			if err := s.SetReminder(id, due, strings.Join(args[2:], " ")); err != nil {
+				return err
+			}
+			fmt.Printf("Message %d is due again %s\n", id, i18n.DateTime(due))
+			return nil
+		})
+	},
+}
+
+var remindersCmd = &cobra.Command{
+	Use:   "reminders",
+	Short: "List reminders on messages",
+	Long: `List the reminders set with 'msgvault remind', soonest due first, or with
+--due only those that have come due.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("reminders"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		var dueBy time.Time
+		if remindersDue {
+			dueBy = time.Now()
+		}
+		reminders, err := s.ListReminders(dueBy, false)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if reminders == nil {
+				reminders = []store.Reminder{}
+			}
+			return printJSON(reminders)
+		}
+		if len(reminders) == 0 {
+			if remindersDue {
+				fmt.Println("No reminders due.")
+			} else {
+				fmt.Println("No reminders. Set one with 'msgvault remind <message-id> <when>'.")
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "DUE\tID\tFROM\tSUBJECT\tNOTE")
+		_, _ = fmt.Fprintln(w, "───\t──\t────\t───────\t────")
+		for _, r := range reminders {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", i18n.DateTime(r.DueAt.Local()), r.MessageID,
+				truncate(r.From, 30), truncate(r.Subject, 50), truncate(r.Note, 40))
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+var reminderDurationRe = regexp.MustCompile(`^(\d+)([dwmy])$`)
+
+// parseReminderTime parses when a reminder comes due: a date, due at
+// the start of that day, or a number of days, weeks, months or years
+// from now.
+func parseReminderTime(value string, now time.Time) (time.Time, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	m := reminderDurationRe.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid reminder time %q (want a date like 2026-05-01, or 3d, 2w, 11m or 1y)", value)
+	}
+	n, _ := strconv.Atoi(m[1])
+	return addPeriod(now, n, m[2]), nil
+}
+
+// addPeriod adds n days, weeks, months or years to t, for unit d, w, m
+// or y, as matched by reminderDurationRe.
+func addPeriod(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "m":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+// remindDueReminders returns the scheduler job that logs the reminders
+// come due since it last ran and sends them through d.
+func remindDueReminders(s *store.Store, d *notify.Dispatcher) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		due, err := s.ListReminders(time.Now(), true)
+		if err != nil {
+			logger.Warn("list due reminders", "error", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		ids := make([]int64, 0, len(due))
+		lines := make([]string, 0, reminderPreview+1)
+		for i, r := range due {
+			logger.Info("reminder due", "message_id", r.MessageID, "subject", r.Subject)
+			ids = append(ids, r.MessageID)
+			if i == reminderPreview {
+				lines = append(lines, fmt.Sprintf("…and %d more", len(due)-i))
+			} else if i < reminderPreview {
+				line := fmt.Sprintf("%s: %s", r.From, r.Subject)
+				if r.Note != "" {
+					line += " (" + r.Note + ")"
+				}
+				lines = append(lines, line)
+			}
+		}
+		d.Send(ctx, notify.Event{
+			Type:    notify.EventReminderDue,
+			Title:   fmt.Sprintf("%d reminder(s) due", len(due)),
+			Message: strings.Join(lines, "\n"),
+		})
+		if err := s.MarkRemindersNotified(ids); err != nil {
+			logger.Warn("mark reminders notified", "error", err)
+		}
+	}
+}
+
+func init() {
+	remindCmd.Flags().BoolVar(&remindClear, "clear", false, "remove the reminder")
+	remindersCmd.Flags().BoolVar(&remindersDue, "due", false, "list only reminders that have come due")
+	rootCmd.AddCommand(remindCmd)
+	rootCmd.AddCommand(remindersCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	now := time.Date(2026, 1, 31, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"3d", time.Date(2026, 2, 3, 10, 0, 0, 0, time.Local), false},
+		{"2W", time.Date(2026, 2, 14, 10, 0, 0, 0, time.Local), false},
+		{"11m", now.AddDate(0, 11, 0), false},
+		{"1y", time.Date(2027, 1, 31, 10, 0, 0, 0, time.Local), false},
+		{"2026-05-01", time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local), false},
+		{"tomorrow", time.Time{}, true},
+		{"3h", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseReminderTime(tt.in, now)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReminderTime(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseReminderTime(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
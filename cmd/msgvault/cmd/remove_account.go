@@ -51,6 +51,12 @@ func runRemoveAccount(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	lock, err := acquireOpLock(cmd.Context(), "remove-account")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
 	yes, err := cmd.Flags().GetBool("yes")
 	if err != nil {
 		return fmt.Errorf("read --yes flag: %w", err)
@@ -205,6 +211,18 @@ func runRemoveAccount(cmd *cobra.Command, args []string) error {
 				)
 			}
 		}
+	case "outlook":
+		msMgr := microsoft.NewGraphManager(
+			cfg.Microsoft.ClientID,
+			cfg.Microsoft.EffectiveTenantID(),
+			cfg.TokensDir(),
+			logger,
+		)
+		if err := msMgr.DeleteToken(source.Identifier); err != nil {
+			fmt.Fprintf(os.Stderr,
+				"Warning: could not remove Microsoft token: %v\n", err,
+			)
+		}
 	}
 
 	// Remove analytics cache (shared across accounts, needs full rebuild)
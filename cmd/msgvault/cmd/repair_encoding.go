@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"compress/zlib"
 	"database/sql"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 	"unicode/utf8"
@@ -38,6 +36,12 @@ This is useful after a sync that may have produced invalid UTF-8 due to
 charset detection issues in the MIME parser.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
+		lock, err := acquireOpLock(cmd.Context(), "repair-encoding")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
 		dbPath := cfg.DatabaseDSN()
 		s, err := store.Open(dbPath)
 		if err != nil {
@@ -195,7 +199,7 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 	// Query all messages with their raw data
 	rows, err := db.Query(`
 		SELECT m.id, m.subject, mb.body_text, mb.body_html, m.snippet,
-		       mr.raw_data, mr.compression
+		       mr.raw_data, mr.compression, mr.blob_hash
 		FROM messages m
 		LEFT JOIN message_bodies mb ON mb.message_id = m.id
 		LEFT JOIN message_raw mr ON mr.message_id = m.id
@@ -259,11 +263,16 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 				if r.newHTML.Valid {
 					bodyHTML = r.newHTML.String
 				}
+				// A repaired body_text no longer lines up with any
+				// quoted trail and signature folded off it; drop them
+				// ('reparse' folds the body again).
 				query := s.Rebind(`INSERT INTO message_bodies (message_id, body_text, body_html)
 					VALUES (?, ?, ?)
 					ON CONFLICT(message_id) DO UPDATE SET
 						body_text = COALESCE(excluded.body_text, message_bodies.body_text),
-						body_html = COALESCE(excluded.body_html, message_bodies.body_html)`)
+						body_html = COALESCE(excluded.body_html, message_bodies.body_html),
+						body_quoted = CASE WHEN excluded.body_text IS NULL THEN message_bodies.body_quoted END,
+						body_signature = CASE WHEN excluded.body_text IS NULL THEN message_bodies.body_signature END`)
 				if _, err := tx.Exec(query, r.id, bodyText, bodyHTML); err != nil {
 					if rbErr := tx.Rollback(); rbErr != nil {
 						logger.Warn("rollback failed", "error", rbErr)
@@ -294,9 +303,9 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 		var id int64
 		var subject, bodyText, bodyHTML, snippet sql.NullString
 		var rawData []byte
-		var compression sql.NullString
+		var compression, blobHash sql.NullString
 
-		if err := rows.Scan(&id, &subject, &bodyText, &bodyHTML, &snippet, &rawData, &compression); err != nil {
+		if err := rows.Scan(&id, &subject, &bodyText, &bodyHTML, &snippet, &rawData, &compression, &blobHash); err != nil {
 			fmt.Fprintf(os.Stderr, "warning: skipping message row: scan error: %v\n", err)
 			stats.skippedRows++
 			continue
@@ -316,7 +325,7 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 		// Subject
 		if subject.Valid && !utf8.ValidString(subject.String) {
 			if parsed == nil {
-				parsed = tryParseMIME(rawData, compression)
+				parsed = tryParseMIME(s, rawData, compression, blobHash)
 			}
 			if parsed != nil && utf8.ValidString(parsed.Subject) {
 				repair.newSubject = sql.NullString{String: parsed.Subject, Valid: true}
@@ -330,7 +339,7 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 		// Body text
 		if bodyText.Valid && !utf8.ValidString(bodyText.String) {
 			if parsed == nil {
-				parsed = tryParseMIME(rawData, compression)
+				parsed = tryParseMIME(s, rawData, compression, blobHash)
 			}
 			if parsed != nil && utf8.ValidString(parsed.GetBodyText()) {
 				repair.newBody = sql.NullString{String: parsed.GetBodyText(), Valid: true}
@@ -344,7 +353,7 @@ func repairMessageFields(s *store.Store, stats *repairStats) (reembedNeededIDs [
 		// Body HTML
 		if bodyHTML.Valid && !utf8.ValidString(bodyHTML.String) {
 			if parsed == nil {
-				parsed = tryParseMIME(rawData, compression)
+				parsed = tryParseMIME(s, rawData, compression, blobHash)
 			}
 			if parsed != nil && utf8.ValidString(parsed.BodyHTML) {
 				repair.newHTML = sql.NullString{String: parsed.BodyHTML, Valid: true}
@@ -672,22 +681,16 @@ func repairOtherStrings(s *store.Store, stats *repairStats) error {
 }
 
 // tryParseMIME attempts to parse raw MIME data, returning nil on failure
-func tryParseMIME(rawData []byte, compression sql.NullString) *mime.Message {
-	if len(rawData) == 0 {
+func tryParseMIME(s *store.Store, rawData []byte, compression, blobHash sql.NullString) *mime.Message {
+	rawData, err := store.LoadRaw(rawData, blobHash)
+	if err != nil || len(rawData) == 0 {
 		return nil
 	}
 
 	// Decompress if needed
-	if compression.Valid && compression.String == "zlib" {
-		r, err := zlib.NewReader(io.NopCloser(&byteReader{data: rawData}))
-		if err != nil {
-			return nil
-		}
-		rawData, err = io.ReadAll(r)
-		_ = r.Close()
-		if err != nil {
-			return nil
-		}
+	rawData, err = s.DecompressRaw(rawData, compression.String)
+	if err != nil {
+		return nil
 	}
 
 	parsed, err := mime.Parse(rawData)
@@ -697,21 +700,6 @@ func tryParseMIME(rawData []byte, compression sql.NullString) *mime.Message {
 	return parsed
 }
 
-// byteReader wraps a byte slice for use with zlib.NewReader
-type byteReader struct {
-	data []byte
-	pos  int
-}
-
-func (r *byteReader) Read(p []byte) (n int, err error) {
-	if r.pos >= len(r.data) {
-		return 0, io.EOF
-	}
-	n = copy(p, r.data[r.pos:])
-	r.pos += n
-	return n, nil
-}
-
 func init() {
 	rootCmd.AddCommand(repairEncodingCmd)
 }
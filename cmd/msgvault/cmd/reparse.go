@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var reparseQuery string
+
+var reparseCmd = &cobra.Command{
+	Use:   "reparse",
+	Short: "Re-parse stored raw MIME to refresh bodies, snippets, and participants",
+	Long: `Parse the raw MIME stored for each message again and update its
+subject, text and HTML bodies, snippet, date, sender, and recipients
+wherever the result differs from what is stored.
+
+msgvault keeps every message's original MIME, so fixes to charset and
+encoding handling can reach mail archived before them without a resync.
+Labels, conversations, and attachments are left alone. Gmail snippets
+come from the Gmail API rather than the MIME and are kept; other
+sources get snippets regenerated from the body. Quoted reply trails and
+signatures are folded off email bodies, or unfolded, to match the
+[parse] fold_replies setting.
+
+Use --query to limit the run to messages matching a search (same syntax
+as 'search'). Updated messages are re-indexed for full-text search and
+queued for re-embedding, and the analytics cache is rebuilt.
+
+Examples:
+  msgvault reparse
+  msgvault reparse --query "from:bob@example.com before:2015-01-01"`,
+	Args: cobra.NoArgs,
+	RunE: runReparse,
+}
+
+func runReparse(cmd *cobra.Command, _ []string) error {
+	// Under --json, progress and cache-rebuild output go to stderr and
+	// stdout gets only the summary.
+	jsonOut, restore := humanOutputToStderr()
+	defer restore()
+
+	ctx := cmd.Context()
+	lock, err := acquireOpLock(ctx, "reparse")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = lock.Release() }()
+
+	dbPath := cfg.DatabaseDSN()
+	s, err := store.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+	applyParseConfig(s)
+
+	if err := s.InitSchema(); err != nil {
+		return fmt.Errorf("init schema: %w", err)
+	}
+	if err := runStartupMigrations(s); err != nil {
+		return fmt.Errorf("startup migrations: %w", err)
+	}
+
+	opts := importer.ReparseOptions{}
+	if strings.TrimSpace(reparseQuery) != "" {
+		q, err := parseLocalQuery(s, reparseQuery)
+		if err != nil {
+			return err
+		}
+		msgs, err := rules.Matching(s, q, 0, 0)
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		opts.IDs = make([]int64, len(msgs))
+		for i, m := range msgs {
+			opts.IDs[i] = m.ID
+		}
+		if len(opts.IDs) == 0 {
+			if jsonOutput {
+				return printJSONTo(jsonOut, map[string]any{"scanned": 0, "updated": 0, "failed": 0})
+			}
+			fmt.Println("No messages match.")
+			return nil
+		}
+	}
+	opts.Progress = func(done, total int) {
+		if done%1000 != 0 {
+			return
+		}
+		if total > 0 {
+			fmt.Fprintf(os.Stderr, "Re-parsed %s of %s messages...\n",
+				formatCount(int64(done)), formatCount(int64(total)))
+		} else {
+			fmt.Fprintf(os.Stderr, "Re-parsed %s messages...\n", formatCount(int64(done)))
+		}
+	}
+
+	res, err := importer.Reparse(ctx, s, opts, logger)
+	if err != nil {
+		return fmt.Errorf("reparse: %w", err)
+	}
+
+	if len(res.UpdatedIDs) > 0 {
+		reenqueueEmbeddings(ctx, s, dbPath, res.UpdatedIDs)
+		if _, err := buildCache(dbPath, cfg.AnalyticsDir(), true); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cache rebuild failed: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Run 'msgvault build-cache --full-rebuild' to retry.\n")
+		}
+	}
+
+	if jsonOutput {
+		return printJSONTo(jsonOut, map[string]any{
+			"scanned": res.Scanned,
+			"updated": res.Updated,
+			"failed":  res.Failed,
+		})
+	}
+	fmt.Printf("Re-parsed %s messages: %s updated, %s unchanged",
+		formatCount(int64(res.Scanned)), formatCount(int64(res.Updated)),
+		formatCount(int64(res.Scanned-res.Updated-res.Failed)))
+	if res.Failed > 0 {
+		fmt.Printf(", %s could not be parsed (see the log)", formatCount(int64(res.Failed)))
+	}
+	fmt.Println(".")
+	return nil
+}
+
+// reenqueueEmbeddings queues ids for re-embedding so semantic search
+// reflects their new text. It does nothing when vector search is
+// disabled or not built in, and failures are only warnings.
+func reenqueueEmbeddings(ctx context.Context, s *store.Store, dbPath string, ids []int64) {
+	vf, err := setupVectorFeatures(ctx, s.DB(), dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to open vectors.db for re-enqueue: %v\n", err)
+		return
+	}
+	if vf == nil {
+		return
+	}
+	defer func() {
+		if err := vf.Close(); err != nil {
+			logger.Warn("closing vectors.db failed", "error", err)
+		}
+	}()
+	if err := vf.Enqueuer.EnqueueMessages(ctx, ids); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to re-enqueue %d messages for re-embedding: %v\n", len(ids), err)
+	}
+}
+
+func init() {
+	reparseCmd.Flags().StringVar(&reparseQuery, "query", "",
+		"only re-parse messages matching this search query")
+	rootCmd.AddCommand(reparseCmd)
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+// setupReparseVault points cfg at a temp vault with one message from
+// bob and one from carol, and garbles the stored subject of bob's.
+func setupReparseVault(t *testing.T) *store.Store {
+	t.Helper()
+	savedCfg, savedQuery := cfg, reparseQuery
+	t.Cleanup(func() { cfg, reparseQuery = savedCfg, savedQuery })
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{HomeDir: tmpDir, Data: config.DataConfig{DataDir: tmpDir}}
+	st, err := store.Open(filepath.Join(tmpDir, "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	for i, from := range []string{"bob@example.com", "carol@example.com"} {
+		raw := email.NewMessage().From(from).To("alice@example.com").
+			Subject("Café plans").Body("See you there").Bytes()
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "",
+			nil, "msg-"+from, "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest message %d: %v", i, err)
+		}
+	}
+	if _, err := st.DB().Exec(`UPDATE messages SET subject = 'CafÃ© plans'`); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestReparse_Query(t *testing.T) {
+	st := setupReparseVault(t)
+	reparseQuery = "from:bob@example.com"
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	done := captureStdout(t)
+	if err := runReparse(cmd, nil); err != nil {
+		t.Fatalf("runReparse: %v", err)
+	}
+	if out := done(); !strings.Contains(out, "Re-parsed 1 messages: 1 updated, 0 unchanged.") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	rows, err := st.DB().Query(`SELECT subject FROM messages ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	var subjects []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatal(err)
+		}
+		subjects = append(subjects, s)
+	}
+	if len(subjects) != 2 || subjects[0] != "Café plans" || subjects[1] != "CafÃ© plans" {
+		t.Errorf("subjects = %q, want only bob's message repaired", subjects)
+	}
+}
+
+func TestReparse_JSON(t *testing.T) {
+	setupReparseVault(t)
+	saved := jsonOutput
+	jsonOutput = true
+	defer func() { jsonOutput = saved }()
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	done := captureStdout(t)
+	if err := runReparse(cmd, nil); err != nil {
+		t.Fatalf("runReparse: %v", err)
+	}
+	var got map[string]int
+	if err := json.Unmarshal([]byte(done()), &got); err != nil {
+		t.Fatalf("stdout is not a single JSON document: %v", err)
+	}
+	if got["scanned"] != 2 || got["updated"] != 2 || got["failed"] != 0 {
+		t.Errorf("summary = %v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var riskCmd = &cobra.Command{
+	Use:   "risk",
+	Short: "Score archived mail for spam and phishing",
+	Long: `Score archived email for spam and phishing, and report the messages most
+likely to be either.
+
+'risk scan' scores each message from its headers, the authentication results
+recorded when it was delivered, its links and its attachments. Configure
+domain blocklists and an rspamd server in the [risk] section of config.toml
+to score more thoroughly. 'risk report' lists the riskiest messages, and
+is:suspicious finds them in searches.
+
+Examples:
+  msgvault risk scan
+  msgvault risk report
+  msgvault search "is:suspicious after:2025-01-01"`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(riskCmd)
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/risk"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	riskReportMinScore int
+	riskReportLimit    int
+)
+
+var riskReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "List the messages most likely to be spam or phishing",
+	Long: `List the messages 'msgvault risk scan' scored highest, with what counted
+against each. By default only suspicious messages, scoring 5 or more, are
+listed; lower --min-score to see more.
+
+Use 'msgvault show-message <id>' to read one.
+
+Examples:
+  msgvault risk report
+  msgvault risk report --min-score 3 --limit 200
+  msgvault risk report --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("risk report"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		entries, err := riskReport(s, riskReportMinScore, riskReportLimit)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			if entries == nil {
+				entries = []riskReportEntry{}
+			}
+			return printJSON(entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No suspicious messages found. Run 'msgvault risk scan' to score new mail.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tSCORE\tDATE\tFROM\tSUBJECT\tREASONS")
+		_, _ = fmt.Fprintln(w, "──\t─────\t────\t────\t───────\t───────")
+		for _, e := range entries {
+			ruleNames := make([]string, len(e.Reasons))
+			for i, r := range e.Reasons {
+				ruleNames[i] = r.Rule
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\n", e.ID, e.Score, i18n.Date(e.SentAt),
+				truncate(e.From, 30), truncate(e.Subject, 40), strings.Join(ruleNames, ", "))
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+// riskReportEntry is one message in a risk report.
+type riskReportEntry struct {
+	ID      int64         `json:"id"`
+	Score   int           `json:"score"`
+	SentAt  time.Time     `json:"sent_at"`
+	From    string        `json:"from"`
+	Subject string        `json:"subject"`
+	Reasons []risk.Reason `json:"reasons"`
+}
+
+// riskReport returns up to limit messages scoring at least minScore,
+// riskiest first.
+func riskReport(s *store.Store, minScore, limit int) ([]riskReportEntry, error) {
+	risky, err := s.RiskiestMessages(minScore, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(risky))
+	for i, r := range risky {
+		ids[i] = r.MessageID
+	}
+	msgs, err := s.GetMessagesSummariesByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]store.APIMessage, len(msgs))
+	for _, m := range msgs {
+		byID[m.ID] = m
+	}
+
+	var entries []riskReportEntry
+	for _, r := range risky {
+		m := byID[r.MessageID]
+		entries = append(entries, riskReportEntry{
+			ID:      r.MessageID,
+			Score:   r.Score,
+			SentAt:  m.SentAt,
+			From:    m.From,
+			Subject: m.Subject,
+			Reasons: r.Reasons,
+		})
+	}
+	return entries, nil
+}
+
+func init() {
+	riskReportCmd.Flags().IntVar(&riskReportMinScore, "min-score", risk.SuspiciousScore, "list messages scoring at least this")
+	riskReportCmd.Flags().IntVar(&riskReportLimit, "limit", 50, "maximum number of messages to list")
+	riskCmd.AddCommand(riskReportCmd)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/risk"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	riskScanQuery   string
+	riskScanRescore bool
+)
+
+var riskScanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Score messages for spam and phishing",
+	Long: `Score email for spam and phishing. Each message gets a risk score, the sum
+of the points of what counts against it:
+
+  display-name-spoof    the display name shows another domain's address
+  reply-to-mismatch     replies go to a different domain than the sender's
+  dmarc/spf/dkim-fail   the receiving server's checks failed
+  link-text-mismatch    a link's text shows one site but leads to another
+  ip-link               a link goes to a bare IP address
+  blocklisted-link      a link goes to a domain on a [risk] blocklist
+  urgent-subject        the subject pushes the reader to act now
+  dangerous-attachment  an attachment runs code when opened
+  rspamd                rspamd's own score, when [risk] rspamd_url is set
+
+Messages scoring 5 or more count as suspicious. Only messages not scored yet
+are scanned, so a scan after each sync is quick; use --rescore after changing
+the blocklists. Messages without raw MIME, such as chat messages, are skipped.
+
+Examples:
+  msgvault risk scan
+  msgvault risk scan --query "after:2025-01-01" --rescore`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("risk scan"); err != nil {
+			return err
+		}
+		scorer, err := newRiskScorer(cfg.Risk)
+		if err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		q, err := parseLocalQuery(s, riskScanQuery)
+		if err != nil {
+			return err
+		}
+
+		res, err := riskScan(cmd.Context(), s, scorer, q, riskScanRescore,
+			func(done, total int) {
+				if done%1000 == 0 {
+					fmt.Fprintf(os.Stderr, "Scored %s of %s messages...\n",
+						formatCount(int64(done)), formatCount(int64(total)))
+				}
+			})
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(res)
+		}
+		fmt.Printf("Scored %s messages, %s suspicious\n",
+			formatCount(int64(res.Scored)), formatCount(int64(res.Suspicious)))
+		if res.Skipped > 0 {
+			fmt.Printf("Skipped %s messages without raw MIME\n", formatCount(int64(res.Skipped)))
+		}
+		if res.Suspicious > 0 {
+			fmt.Println("Run 'msgvault risk report' to review them.")
+		}
+		return nil
+	},
+}
+
+// newRiskScorer builds a scorer from the [risk] settings.
+func newRiskScorer(rc config.RiskConfig) (*risk.Scorer, error) {
+	scorer := &risk.Scorer{}
+	if len(rc.Blocklists) > 0 {
+		b, err := risk.LoadBlocklist(rc.Blocklists...)
+		if err != nil {
+			return nil, err
+		}
+		scorer.Blocklist = b
+	}
+	if rc.RspamdURL != "" {
+		scorer.Rspamd = risk.NewRspamd(rc.RspamdURL)
+	}
+	return scorer, nil
+}
+
+// riskScanResult counts what riskScan did.
+type riskScanResult struct {
+	Scored     int `json:"scored"`
+	Suspicious int `json:"suspicious"`
+	Skipped    int `json:"skipped"` // messages without raw MIME
+}
+
+// riskScan scores the messages matching q, leaving out those already
+// scored unless rescore is set. progress, if not nil, is called after
+// each message.
+func riskScan(ctx context.Context, s *store.Store, scorer *risk.Scorer, q *search.Query, rescore bool, progress func(done, total int)) (riskScanResult, error) {
+	var res riskScanResult
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return res, fmt.Errorf("search: %w", err)
+	}
+	scored := map[int64]bool{}
+	if !rescore {
+		if scored, err = s.RiskScoredIDs(); err != nil {
+			return res, err
+		}
+	}
+
+	var todo []int64
+	for _, m := range msgs {
+		if !scored[m.ID] {
+			todo = append(todo, m.ID)
+		}
+	}
+	for i, id := range todo {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		raw, err := s.GetMessageRawMIME(id)
+		if err != nil {
+			return res, fmt.Errorf("read message %d: %w", id, err)
+		}
+		if raw == nil {
+			res.Skipped++
+		} else {
+			auth, err := s.GetMessageAuth(id)
+			if err != nil {
+				return res, err
+			}
+			r, err := scorer.Score(ctx, raw, auth)
+			if err != nil {
+				return res, fmt.Errorf("score message %d: %w", id, err)
+			}
+			if err := s.SetMessageRisk(id, r); err != nil {
+				return res, err
+			}
+			res.Scored++
+			if r.Suspicious() {
+				res.Suspicious++
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(todo))
+		}
+	}
+	return res, nil
+}
+
+func init() {
+	riskScanCmd.Flags().StringVar(&riskScanQuery, "query", "", "only score messages matching this search query")
+	riskScanCmd.Flags().BoolVar(&riskScanRescore, "rescore", false, "score messages again even if already scored")
+	riskCmd.AddCommand(riskScanCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/risk"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestRiskScanAndReport(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	msgs := map[string][]byte{
+		"msg-ok": email.NewMessage().From("bob@example.com").To("alice@example.com").
+			Subject("Lunch").Body("See you at noon.\r\n").Bytes(),
+		"msg-phish": email.NewMessage().From(`"support@bank.example" <bob@phish.example>`).
+			To("alice@example.com").Subject("Verify your account").
+			Header("Reply-To", "help@elsewhere.example").
+			Body("Log in at http://192.0.2.7/login today.\r\n").Bytes(),
+		"msg-chat": email.NewMessage().From("carol@example.com").To("alice@example.com").
+			Subject("Hi").Body("Hello.\r\n").Bytes(),
+	}
+	for id, raw := range msgs {
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "",
+			nil, id, "hash-"+id, raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest %s: %v", id, err)
+		}
+	}
+	var chatID int64
+	if err := st.DB().QueryRow(`SELECT id FROM messages WHERE source_message_id = 'msg-chat'`).Scan(&chatID); err != nil {
+		t.Fatal(err)
+	}
+	// A message stored without raw MIME is skipped.
+	if _, err := st.DB().Exec(`DELETE FROM message_raw WHERE message_id = ?`, chatID); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	res, err := riskScan(ctx, st, &risk.Scorer{}, search.Parse(""), false, nil)
+	if err != nil {
+		t.Fatalf("riskScan: %v", err)
+	}
+	if res != (riskScanResult{Scored: 2, Suspicious: 1, Skipped: 1}) {
+		t.Errorf("riskScan = %+v, want 2 scored, 1 suspicious, 1 skipped", res)
+	}
+
+	// Scored messages are not scanned again without rescore.
+	if res, err = riskScan(ctx, st, &risk.Scorer{}, search.Parse(""), false, nil); err != nil || res.Scored != 0 {
+		t.Errorf("second riskScan = %+v, %v; want nothing scored", res, err)
+	}
+	if res, err = riskScan(ctx, st, &risk.Scorer{}, search.Parse("subject:lunch"), true, nil); err != nil || res.Scored != 1 {
+		t.Errorf("rescore = %+v, %v; want one message scored", res, err)
+	}
+
+	entries, err := riskReport(st, risk.SuspiciousScore, 10)
+	if err != nil {
+		t.Fatalf("riskReport: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("riskReport = %+v, want one message", entries)
+	}
+	e := entries[0]
+	if e.Subject != "Verify your account" || e.From != "bob@phish.example" || e.Score < risk.SuspiciousScore {
+		t.Errorf("report entry = %+v, want the phishing message", e)
+	}
+	rulesSeen := make(map[string]bool)
+	for _, r := range e.Reasons {
+		rulesSeen[r.Rule] = true
+	}
+	for _, want := range []string{risk.RuleDisplayNameSpoof, risk.RuleReplyToMismatch, risk.RuleIPLink, risk.RuleUrgentSubject} {
+		if !rulesSeen[want] {
+			t.Errorf("reasons %+v lack %s", e.Reasons, want)
+		}
+	}
+}
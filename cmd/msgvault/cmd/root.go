@@ -11,26 +11,34 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/crash"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/logging"
 	"github.com/wesm/msgvault/internal/oauth"
 	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/tracing"
 	"golang.org/x/oauth2"
 )
 
 var (
-	cfgFile    string
-	homeDir    string
-	verbose    bool
-	useLocal   bool // Force local database even when remote is configured
-	logFile    string
-	logLevel   string
-	noLogFile  bool
-	logSQL     bool
-	logSQLSlow int64
-	cfg        *config.Config
+	cfgFile     string
+	homeDir     string
+	profileFlag string // --profile; activeProfile is the resolved one
+	verbose     bool
+	useLocal    bool // Force local database even when remote is configured
+	logFile     string
+	logLevel    string
+	logFormat   string
+	noLogFile   bool
+	logSQL      bool
+	logSQLSlow  int64
+	jsonOutput  bool // --json on commands without their own --json flag
+	waitForLock bool // --wait: queue behind a running operation
+	cfg         *config.Config
 	// logger is always non-nil so code paths outside the normal
 	// PersistentPreRunE flow (tests, library embeds) don't have
 	// to nil-check before calling logger.Info. PersistentPreRunE
@@ -38,6 +46,13 @@ var (
 	// CLI startup.
 	logger    = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
 	logResult *logging.Result // non-nil after PersistentPreRunE runs
+
+	// activeProfile is the vault profile in use, set by
+	// PersistentPreRunE ("" when --home or --config chose the vault).
+	activeProfile string
+
+	// tracingShutdown flushes spans; set by PersistentPreRunE.
+	tracingShutdown func(context.Context) error
 )
 
 var rootCmd = &cobra.Command{
@@ -54,13 +69,29 @@ in a single binary.`,
 		if cmd.Name() == "version" || cmd.Name() == "update" ||
 			cmd.Name() == "quickstart" || cmd.Name() == "completion" ||
 			cmd.Name() == cobra.ShellCompRequestCmd ||
-			cmd.Name() == cobra.ShellCompNoDescRequestCmd {
+			cmd.Name() == cobra.ShellCompNoDescRequestCmd ||
+			(cmd.HasParent() && cmd.Parent() == profileCmd) {
 			return nil
 		}
 
+		// Move a pre-XDG ~/.msgvault into place before anything
+		// resolves paths from it.
+		if cfgFile == "" && homeDir == "" {
+			if dirs, moved, err := config.MigrateLegacyHome(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: migrating %s: %v\n", config.LegacyHome(), err)
+			} else if moved {
+				fmt.Fprintf(os.Stderr, "Moved %s to %s (config: %s, cache: %s)\n",
+					config.LegacyHome(), dirs.Data, dirs.Config, dirs.Cache)
+			}
+		}
+
 		// Load config first; logging options live under [log].
-		var err error
-		cfg, err = config.Load(cfgFile, homeDir)
+		home, name, err := resolveProfileHome(profileFlag, cfgFile, homeDir)
+		if err != nil {
+			return err
+		}
+		activeProfile = name
+		cfg, err = config.Load(cfgFile, home)
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
 		}
@@ -70,6 +101,9 @@ in a single binary.`,
 				cfg.HomeDir, err,
 			)
 		}
+		// Raw messages kept outside the database are read wherever the
+		// vault is opened, by the query engines too.
+		store.SetRawBlobDir(cfg.RawBlobsDir())
 
 		// Resolve logging options. CLI flags override config;
 		// --verbose forces debug level regardless of other
@@ -95,20 +129,33 @@ in a single binary.`,
 			logResult = nil
 		}
 
+		format := logFormat
+		if format == "" {
+			format = cfg.Log.Format
+		}
 		logResult, err = logging.BuildHandler(logging.Options{
 			LogsDir:       logsDir,
 			FilePath:      logFile,
 			FileDisabled:  fileDisabled,
 			LevelOverride: levelOverride,
 			LevelString:   levelString,
+			Format:        format,
+			MaxFileBytes:  int64(cfg.Log.MaxSizeMB) * 1024 * 1024,
+			KeepRotated:   cfg.Log.MaxFiles,
 		})
 		if err != nil {
 			return fmt.Errorf("build logger: %w", err)
 		}
-		logger = slog.New(logResult.Handler)
+		// Recent records are also kept in memory for crash reports.
+		logger = slog.New(crash.CaptureLogs(logResult.Handler))
 		// logResult.RunID is available for any command that needs it.
 		slog.SetDefault(logger)
 
+		// Translations follow [ui] language, else the POSIX locale.
+		if err := i18n.Set(i18n.Detect(cfg.UI.Language)); err != nil {
+			logger.Warn("failed to load translations", "error", err)
+		}
+
 		// Configure the store's SQL logging adapter now that
 		// slog.Default is set. Flag overrides config; a zero
 		// SlowMs falls back to the built-in default (100 ms).
@@ -122,6 +169,21 @@ in a single binary.`,
 			FullTrace: sqlTrace,
 		})
 
+		// Tracing is off unless [tracing].exporter is set. Spans
+		// are flushed by ExecuteContext on the way out.
+		if tracingShutdown != nil {
+			_ = tracingShutdown(context.Background())
+		}
+		tracingShutdown, err = tracing.Setup(cmd.Context(), tracing.Options{
+			Exporter:    cfg.Tracing.Exporter,
+			Endpoint:    cfg.Tracing.Endpoint,
+			Insecure:    cfg.Tracing.Insecure,
+			SampleRatio: cfg.Tracing.SampleRatio,
+		})
+		if err != nil {
+			return fmt.Errorf("set up tracing: %w", err)
+		}
+
 		// Startup header: one structured line per run that
 		// captures everything you'd want to correlate later.
 		// Positional args may contain email addresses, search
@@ -135,13 +197,25 @@ in a single binary.`,
 			"os", runtime.GOOS,
 			"arch", runtime.GOARCH,
 			"config_path", cfg.ConfigFilePath(),
+			"profile", activeProfile,
 			"data_dir", cfg.Data.DataDir,
 			"log_file", logResult.FilePath,
 			"level", logResult.Level.String(),
+			"language", i18n.Current().Tag,
 		)
 		logger.Debug("msgvault startup args",
 			"args", sanitizeArgs(args),
 		)
+		configureCrashReports(cmd, args)
+
+		// Cheap settings-only check on every run; 'config validate'
+		// does the full check and prints the issues itself.
+		if cmd != configValidateCmd {
+			for _, issue := range cfg.CheckSettings() {
+				logger.Warn("config problem (run 'msgvault config validate')",
+					"key", issue.Key, "severity", issue.Severity, "problem", issue.Message)
+			}
+		}
 		return nil
 	},
 	// Note: log file closing is handled by ExecuteContext's deferred
@@ -190,24 +264,32 @@ func sanitizeArgs(args []string) []string {
 }
 
 // recoverAndLogPanic catches a panic and records it as a single
-// structured log line with a stack trace before re-raising the
-// process exit. Called in a deferred statement at the top of
-// Execute/ExecuteContext so crashes always leave a trail on disk.
+// structured log line with a stack trace, writes a crash report, and
+// tells the user how to file a bug before exiting. Called in a
+// deferred statement at the top of Execute/ExecuteContext so crashes
+// always leave a trail on disk. A *crash.Recovered (re-raised by the
+// TUI) is reported with the stack of the original panic.
 func recoverAndLogPanic() {
 	r := recover()
 	if r == nil {
 		return
 	}
+	value, stack := crash.Unwrap(r, debug.Stack())
 	if logger != nil {
 		logger.Error("msgvault panic",
-			"panic", fmt.Sprint(r),
-			"stack", string(debug.Stack()),
+			"panic", fmt.Sprint(value),
+			"stack", string(stack),
 		)
 	} else {
 		fmt.Fprintf(os.Stderr,
-			"msgvault panic: %v\n%s\n", r, debug.Stack(),
+			"msgvault panic: %v\n%s\n", value, stack,
 		)
 	}
+	path, err := crash.Report(value, stack)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Fprint(os.Stderr, "\n"+crash.Instructions(value, path))
 	if logResult != nil {
 		logResult.Close()
 	}
@@ -236,8 +318,20 @@ func ExecuteContext(ctx context.Context) error {
 	}()
 	defer recoverAndLogPanic()
 
+	registerCompletionsOnce.Do(func() { registerDynamicCompletions(rootCmd) })
 	err := rootCmd.ExecuteContext(ctx)
 
+	// Flush buffered spans. Bounded so an unreachable collector
+	// can't hold up exit.
+	if tracingShutdown != nil {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if terr := tracingShutdown(flushCtx); terr != nil && logger != nil {
+			logger.Warn("flush traces", "error", terr)
+		}
+		cancel()
+		tracingShutdown = nil
+	}
+
 	// Record the exit outcome so users can see the per-run
 	// result in the log without parsing error messages.
 	if logger != nil {
@@ -284,6 +378,16 @@ func errOAuthNotConfigured() error {
 	return fmt.Errorf("OAuth client secrets not configured.%s", oauthSetupHint())
 }
 
+// homeSecretsPath is where the OAuth setup hint suggests copying a
+// client_secret.json.
+func homeSecretsPath() string {
+	home := config.DefaultHome()
+	if cfg != nil {
+		home = cfg.HomeDir
+	}
+	return filepath.Join(home, "client_secret.json")
+}
+
 // tryFindClientSecrets looks for client_secret*.json in common locations
 // and returns a hint if found.
 func tryFindClientSecrets() string {
@@ -312,7 +416,7 @@ To use this file, add to %s:
   client_secrets = %q
 
 Or copy the file to your msgvault home directory:
-  cp %q ~/.msgvault/client_secret.json`, matches[0], configPath, matches[0], matches[0])
+  cp %q %s`, matches[0], configPath, matches[0], matches[0], homeSecretsPath())
 		}
 	}
 	return ""
@@ -433,7 +537,7 @@ func oauthManagerCache() func(appName string) (*oauth.Manager, error) {
 		if err != nil {
 			return nil, err
 		}
-		mgr, err := oauth.NewManager(secretsPath, cfg.TokensDir(), logger)
+		mgr, err := oauth.NewManagerWithScopes(secretsPath, cfg.TokensDir(), logger, gmailScopes())
 		if err != nil {
 			return nil, wrapOAuthError(fmt.Errorf("create oauth manager: %w", err))
 		}
@@ -442,6 +546,16 @@ func oauthManagerCache() func(appName string) (*oauth.Manager, error) {
 	}
 }
 
+// gmailScopes returns the OAuth scopes msgvault asks Gmail accounts
+// for: oauth.Scopes, plus the scope the storage quota is read with
+// when [sync] storage_quota is on.
+func gmailScopes() []string {
+	if cfg.Sync.StorageQuota {
+		return oauth.ScopesStorageQuota
+	}
+	return oauth.Scopes
+}
+
 // sourceOAuthApp extracts the oauth app name from a Source, returning ""
 // for the default app.
 func sourceOAuthApp(src *store.Source) string {
@@ -452,14 +566,22 @@ func sourceOAuthApp(src *store.Source) string {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: ~/.msgvault/config.toml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default: config.toml in the msgvault config directory)")
 	rootCmd.PersistentFlags().StringVar(&homeDir, "home", "", "home directory (overrides MSGVAULT_HOME)")
+	rootCmd.PersistentFlags().StringVar(&profileFlag, "profile", "",
+		"vault profile to use (overrides MSGVAULT_PROFILE and 'profile switch')")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output (implies --log-level=debug)")
+	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false,
+		"print structured JSON instead of human-readable text")
 	rootCmd.PersistentFlags().BoolVar(&useLocal, "local", false, "force local database (override remote config)")
+	rootCmd.PersistentFlags().BoolVar(&waitForLock, "wait", false,
+		"wait for another operation on this vault to finish instead of failing")
 	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "",
 		"override log file path (default: <data dir>/logs/msgvault-YYYY-MM-DD.log)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "",
 		"log level: debug, info, warn, error (default: info)")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "",
+		"stderr log format: text or json (default: text; log files are always json)")
 	rootCmd.PersistentFlags().BoolVar(&noLogFile, "no-log-file", false,
 		"disable the log file for this run (stderr output stays on)")
 	rootCmd.PersistentFlags().BoolVar(&logSQL, "log-sql", false,
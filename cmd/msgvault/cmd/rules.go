@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/notify"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var rulesTestLimit int
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Inspect and preview filter rules",
+	Long: `Filter rules run on newly synced mail, like Gmail filters. Each
+[[rules]] entry in config.toml pairs a search query with actions:
+
+  label            apply a vault-local label
+  category         file under a Gmail category (personal, social,
+                   promotions, updates, forums)
+  skip_attachments drop the extracted attachment files (the raw MIME
+                   keeps them)
+  notify           report each match
+
+Rules run after 'sync' and 'sync-full' and after every scheduled sync
+in 'serve', in the order they are listed.
+
+Example:
+  [[rules]]
+  name = "receipts"
+  query = "from:shop@example.com subject:receipt"
+  label = "Receipts"
+  skip_attachments = true`,
+}
+
+var rulesListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the configured rules",
+	Args:  cobra.NoArgs,
+	RunE:  runRulesList,
+}
+
+var rulesTestCmd = &cobra.Command{
+	Use:   "test <query>",
+	Short: "Preview which archived messages a rule query matches",
+	Long: `Preview a rule query against the whole archive without applying
+anything. The query uses the same syntax as 'search' and the [[rules]]
+query field.
+
+Examples:
+  msgvault rules test "from:shop@example.com subject:receipt"
+  msgvault rules test "larger:10M has:attachment" --limit 5`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRulesTest,
+}
+
+func runRulesList(_ *cobra.Command, _ []string) error {
+	if jsonOutput {
+		return printJSON(cfg.Rules)
+	}
+	if len(cfg.Rules) == 0 {
+		fmt.Println("No rules configured. Add [[rules]] entries to config.toml.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tQUERY\tACTIONS")
+	for _, r := range cfg.Rules {
+		var actions []string
+		if r.Label != "" {
+			actions = append(actions, "label="+r.Label)
+		}
+		if r.Category != "" {
+			actions = append(actions, "category="+r.Category)
+		}
+		if r.SkipAttachments {
+			actions = append(actions, "skip_attachments")
+		}
+		if r.Notify {
+			actions = append(actions, "notify")
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Query, strings.Join(actions, ", "))
+	}
+	_ = w.Flush()
+	return nil
+}
+
+func runRulesTest(_ *cobra.Command, args []string) error {
+	if strings.TrimSpace(args[0]) == "" {
+		return fmt.Errorf("query is required")
+	}
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.Close() }()
+
+	q, err := parseLocalQuery(s, args[0])
+	if err != nil {
+		return err
+	}
+	q.HideDeleted = true
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+
+	shown := msgs
+	if rulesTestLimit > 0 && len(shown) > rulesTestLimit {
+		shown = shown[len(shown)-rulesTestLimit:]
+	}
+	if jsonOutput {
+		return printJSON(map[string]any{
+			"total":   len(msgs),
+			"results": shown,
+		})
+	}
+	if len(msgs) == 0 {
+		fmt.Println("No messages match.")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tDATE\tFROM\tSUBJECT")
+	for _, m := range shown {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n",
+			m.ID, m.SentAt.Format("2006-01-02"), truncate(m.From, 30), truncate(m.Subject, 50))
+	}
+	_ = w.Flush()
+	fmt.Printf("\n%s messages match; showing the newest %d\n", formatCount(int64(len(msgs))), len(shown))
+	return nil
+}
+
+// applyRules runs the configured rules over messages stored after
+// message ID low and prints what they matched; matches of rules with
+// notify set go to notifier. Rule failures are reported but do not
+// fail the sync that stored the messages.
+func applyRules(ctx context.Context, s *store.Store, low int64, notifier *notify.Dispatcher) {
+	if len(cfg.Rules) == 0 {
+		return
+	}
+	engine, err := rules.New(cfg.Rules, s, cfg.AttachmentsDir(), logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: rules not applied: %v\n", err)
+		return
+	}
+	engine.SetNotify(ruleMatchNotifier(notifier))
+	matches, err := engine.Apply(ctx, low, 0)
+	for _, m := range matches {
+		fmt.Printf("Rule %s: %d new message(s)\n", m.Rule, len(m.Messages))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(rulesCmd)
+	rulesCmd.AddCommand(rulesListCmd)
+	rulesCmd.AddCommand(rulesTestCmd)
+	rulesTestCmd.Flags().IntVarP(&rulesTestLimit, "limit", "n", 20, "Show at most this many matches (0 for all)")
+}
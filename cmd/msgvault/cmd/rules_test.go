@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// setupRulesVault points cfg at a temp vault holding one receipt and
+// one newsletter from alice@example.com.
+func setupRulesVault(t *testing.T, rules ...config.RuleConfig) *store.Store {
+	t.Helper()
+	savedCfg := cfg
+	t.Cleanup(func() { cfg = savedCfg })
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+		Rules:   rules,
+	}
+
+	st, err := store.Open(filepath.Join(tmpDir, "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	convID, err := st.EnsureConversation(src.ID, "thread-1", "Thread")
+	if err != nil {
+		t.Fatalf("ensure conversation: %v", err)
+	}
+	for i, subject := range []string{"Your receipt", "Weekly newsletter"} {
+		if _, err := st.UpsertMessage(&store.Message{
+			ConversationID:  convID,
+			SourceID:        src.ID,
+			SourceMessageID: "msg-" + subject,
+			MessageType:     "email",
+			Subject:         sql.NullString{String: subject, Valid: true},
+			SizeEstimate:    int64(1000 + i),
+		}); err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+	}
+	return st
+}
+
+func TestRulesTest(t *testing.T) {
+	setupRulesVault(t)
+
+	done := captureStdout(t)
+	if err := runRulesTest(&cobra.Command{}, []string{"subject:receipt"}); err != nil {
+		t.Fatalf("runRulesTest: %v", err)
+	}
+	out := done()
+	if !strings.Contains(out, "Your receipt") || strings.Contains(out, "Weekly newsletter") {
+		t.Errorf("unexpected matches:\n%s", out)
+	}
+	if !strings.Contains(out, "1 messages match") {
+		t.Errorf("missing match count:\n%s", out)
+	}
+}
+
+func TestRulesTest_JSON(t *testing.T) {
+	setupRulesVault(t)
+	saved := jsonOutput
+	jsonOutput = true
+	defer func() { jsonOutput = saved }()
+
+	done := captureStdout(t)
+	if err := runRulesTest(&cobra.Command{}, []string{"subject:newsletter"}); err != nil {
+		t.Fatalf("runRulesTest: %v", err)
+	}
+	var got struct {
+		Total   int                `json:"total"`
+		Results []store.APIMessage `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(done()), &got); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if got.Total != 1 || len(got.Results) != 1 || got.Results[0].Subject != "Weekly newsletter" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestRulesList(t *testing.T) {
+	setupRulesVault(t, config.RuleConfig{
+		Name: "receipts", Query: "subject:receipt", Label: "Receipts", SkipAttachments: true,
+	})
+
+	done := captureStdout(t)
+	if err := runRulesList(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("runRulesList: %v", err)
+	}
+	out := done()
+	if !strings.Contains(out, "receipts") || !strings.Contains(out, "label=Receipts, skip_attachments") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestApplyRules(t *testing.T) {
+	st := setupRulesVault(t, config.RuleConfig{
+		Name: "receipts", Query: "subject:receipt", Label: "Receipts",
+	})
+
+	done := captureStdout(t)
+	applyRules(t.Context(), st, 0, nil)
+	out := done()
+	if !strings.Contains(out, "Rule receipts: 1 new message(s)") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	var n int
+	err := st.DB().QueryRow(`
+		SELECT COUNT(*) FROM message_labels ml
+		JOIN labels l ON l.id = ml.label_id
+		WHERE l.name = 'Receipts' AND l.source_id IS NULL`).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d messages labelled Receipts, want 1", n)
+	}
+}
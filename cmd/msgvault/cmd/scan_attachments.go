@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/virusscan"
+)
+
+var scanAttachmentsRescan bool
+
+var scanAttachmentsCmd = &cobra.Command{
+	Use:   "scan-attachments",
+	Short: "Scan stored attachments for viruses",
+	Long: `Run the [virus_scan] command, such as clamdscan, over the stored attachment
+files and record its verdict on each attachment. A file shared by several
+messages is scanned once.
+
+With quarantine = true in [virus_scan], infected files are quarantined: they
+can no longer be exported or downloaded, from the CLI, the TUI or the API,
+until released with 'msgvault release-attachment'.
+
+Only files without a verdict are scanned, and sync scans new attachments
+itself when [virus_scan] is configured; use --rescan after the scanner's
+signatures are updated.
+
+Examples:
+  msgvault scan-attachments
+  msgvault scan-attachments --rescan`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("scan-attachments"); err != nil {
+			return err
+		}
+		if len(cfg.VirusScan.Command) == 0 {
+			return errors.New("no virus scanner configured; set command in the [virus_scan] section of config.toml")
+		}
+		sc, err := virusscan.New(cfg.VirusScan.Command)
+		if err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		stats, err := virusscan.Run(cmd.Context(), s, cfg.AttachmentsDir(), sc, virusscan.Options{
+			Rescan:     scanAttachmentsRescan,
+			Quarantine: cfg.VirusScan.Quarantine,
+			Progress: func(done, total int) {
+				if done%100 == 0 {
+					fmt.Fprintf(os.Stderr, "Scanned %s of %s files...\n",
+						formatCount(int64(done)), formatCount(int64(total)))
+				}
+			},
+		}, logger)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			return printJSON(stats)
+		}
+		fmt.Printf("Scanned %s files, %s infected\n",
+			formatCount(int64(stats.Scanned)), formatCount(int64(stats.Infected)))
+		if stats.Errors > 0 {
+			fmt.Printf("Could not scan %s files; run with --verbose for details\n", formatCount(int64(stats.Errors)))
+		}
+		if stats.Infected > 0 {
+			fmt.Println("Run 'msgvault list-infected' to review them.")
+		}
+		return nil
+	},
+}
+
+// scanAttachments scans the attachments a sync stored, when a virus
+// scanner is configured. Like applyRules, it warns rather than failing
+// the sync.
+func scanAttachments(ctx context.Context, s *store.Store) {
+	if len(cfg.VirusScan.Command) == 0 {
+		return
+	}
+	sc, err := virusscan.New(cfg.VirusScan.Command)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: attachments not scanned: %v\n", err)
+		return
+	}
+	stats, err := virusscan.Run(ctx, s, cfg.AttachmentsDir(), sc,
+		virusscan.Options{Quarantine: cfg.VirusScan.Quarantine}, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: virus scan: %v\n", err)
+		return
+	}
+	if stats.Infected > 0 {
+		fmt.Fprintf(os.Stderr, "Virus scan: %d infected attachment(s); see 'msgvault list-infected'\n", stats.Infected)
+	}
+}
+
+func init() {
+	scanAttachmentsCmd.Flags().BoolVar(&scanAttachmentsRescan, "rescan", false, "scan files again even if already scanned")
+	rootCmd.AddCommand(scanAttachmentsCmd)
+}
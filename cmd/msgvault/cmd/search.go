@@ -2,12 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/store"
@@ -21,6 +24,7 @@ var (
 	searchCollection string
 	searchMode       string
 	searchExplain    bool
+	searchSort       string
 )
 
 var searchCmd = &cobra.Command{
@@ -39,19 +43,67 @@ Supported operators (local mode only - remote uses simple text search):
   subject:     Subject text search
   label:       Gmail label (or l: shorthand)
   has:         has:attachment - messages with attachments
-  before:      Messages before date (YYYY-MM-DD)
-  after:       Messages after date (YYYY-MM-DD)
+  filename:    Attachment name or extension (filename:pdf)
+  is:          is:unread, is:starred, is:important (-is:unread for read
+               mail), is:dkim-pass, is:suspicious (scored by 'msgvault
+               risk scan'), is:pinned, or is:due (reminder set with
+               'msgvault remind' has come due)
+  note:        Text in your local note on the message
+  tag:         Local tag (set with 'msgvault tag')
+  lang:        Detected language code (lang:de, lang:fr)
+  list:        Mailing list, matched in its List-Id (list:golang-nuts)
+  deliveredto: Address mail was delivered to, from Delivered-To
+  header:      Indexed header field containing a value
+               (header:X-Mailer=Outlook); header:X-Mailer for any value.
+               List-Id and Delivered-To are indexed; add others to
+               index_headers under [parse]
+  before:      Messages before date (YYYY-MM-DD, or today, yesterday,
+               this_week, last_week, this_month, last_month, this_year
+               or last_year, each meaning its first day)
+  after:       Messages after date (same forms as before:)
   older_than:  Relative date (7d, 2w, 1m, 1y)
   newer_than:  Relative date
   larger:      Size filter (5M, 100K)
   smaller:     Size filter
+  saved:       A search saved with 'msgvault search save' (saved:receipts)
+  regex:       RE2 pattern matched case-insensitively in the subject or
+               body (regex:"INV-\d{6}"); needs words, from:, to:,
+               label:, a date or another indexed filter beside it
+  in:          in:attachments searches the words of its group in the text
+               of PDF, DOCX and text attachments (in:attachments lease);
+               index it with 'msgvault index-attachments'
 
 Bare words and "quoted phrases" perform full-text search.
 
+Terms are combined with AND. Join terms with OR to match either, put -
+or NOT before a term or a (group) to exclude it, and group terms with
+parentheses. OR binds tighter than AND, and is an operator only in
+capitals.
+
+Results come newest first. --sort relevance ranks full-text matches by
+BM25 instead, a match in the subject weighing more than one in the body;
+--sort size puts the largest messages first.
+
+Name a search you run often with 'msgvault search save <name> <query>',
+list them with 'msgvault search list-saved', and remove one with
+'msgvault search delete-saved <name>'. To search for the words save,
+list-saved or delete-saved alone, quote them: msgvault search '"save"'.
+
+--format jsonl or csv writes the id, date, from, to, subject, size and
+labels of every match, instead of a screenful, to stdout or the --out
+file for analysis elsewhere. --limit still caps the export if given.
+
 Examples:
   msgvault search from:alice@example.com has:attachment
+  msgvault search 'from:alice OR from:bob -subject:spam'
+  msgvault search 'invoice (label:work OR label:billing) NOT has:attachment'
   msgvault search subject:meeting after:2024-01-01
   msgvault search project report newer_than:30d
+  msgvault search quarterly budget --sort relevance
+  msgvault search saved:receipts newer_than:1y
+  msgvault search 'invoice regex:"INV-\d{6}"'
+  msgvault search 'from:landlord (in:attachments "lease renewal")'
+  msgvault search label:INBOX after:2024-01-01 --format csv --out inbox.csv
   msgvault search '"exact phrase"' label:INBOX`,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -75,6 +127,12 @@ Examples:
 			if searchMode != "fts" {
 				return fmt.Errorf("--mode is not supported in remote mode")
 			}
+			if cmd.Flags().Changed("sort") {
+				return fmt.Errorf("--sort is not supported in remote mode")
+			}
+			if searchFormat != "" {
+				return fmt.Errorf("--format is not supported in remote mode")
+			}
 			return runRemoteSearch(queryStr)
 		}
 
@@ -82,6 +140,32 @@ Examples:
 		if searchMode != "fts" && searchMode != "vector" && searchMode != "hybrid" {
 			return fmt.Errorf("invalid --mode: %q (want fts|vector|hybrid)", searchMode)
 		}
+		if searchFormat != "" && searchFormat != "jsonl" && searchFormat != "csv" {
+			return fmt.Errorf("invalid --format: %q (want jsonl|csv)", searchFormat)
+		}
+		if searchOut != "" && searchFormat == "" {
+			return fmt.Errorf("--out requires --format jsonl or csv")
+		}
+		if searchFormat != "" && searchMode != "fts" {
+			return fmt.Errorf("--format is not supported with --mode=%s", searchMode)
+		}
+		if hasSavedTerm(queryStr) {
+			expanded, err := expandSavedQuery(queryStr)
+			if err != nil {
+				return err
+			}
+			queryStr = expanded
+		}
+		sortField, ok := searchSortFields[searchSort]
+		if !ok {
+			return fmt.Errorf("invalid --sort: %q (want date|relevance|size)", searchSort)
+		}
+		if searchMode != "fts" && cmd.Flags().Changed("sort") {
+			return fmt.Errorf("--sort is not supported with --mode=%s (results are ranked by the mode)", searchMode)
+		}
+		if sortField == search.SortRelevance && len(search.Parse(queryStr).TextTerms) == 0 {
+			return fmt.Errorf("--sort relevance ranks full-text matches; %q has no free-text terms", queryStr)
+		}
 		if searchMode != "fts" && searchOffset > 0 {
 			return fmt.Errorf("--offset is not supported with --mode=%s (pagination is single-page)", searchMode)
 		}
@@ -125,10 +209,28 @@ Examples:
 			}
 			return runHybridSearch(cmd, queryStr, searchMode, searchExplain, scope)
 		}
-		return runLocalSearch(cmd, queryStr, scope, scopedStore)
+		return runLocalSearch(cmd, queryStr, sortField, scope, scopedStore)
 	},
 }
 
+// expandSavedQuery opens the local store just long enough to expand the
+// saved:name terms of queryStr.
+func expandSavedQuery(queryStr string) (string, error) {
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = s.Close() }()
+	return s.ExpandSavedSearches(queryStr)
+}
+
+// searchSortFields maps the --sort values to search orderings.
+var searchSortFields = map[string]search.SortField{
+	"date":      search.SortDate,
+	"relevance": search.SortRelevance,
+	"size":      search.SortSize,
+}
+
 // initLocalSchema opens the local store, runs InitSchema and the
 // startup migrations, then closes it. Used by the unscoped vector/
 // hybrid path so the raw sql.DB that runHybridSearch opens sees a
@@ -222,7 +324,7 @@ func runRemoteSearch(queryStr string) error {
 	}
 
 	if len(results) == 0 {
-		fmt.Println("No messages found.")
+		fmt.Println(i18n.T("No messages found."))
 		return nil
 	}
 
@@ -240,11 +342,12 @@ func runRemoteSearch(queryStr string) error {
 // InitSchema + runStartupMigrations pass). When scopedStore is nil
 // (no scope flag supplied), runLocalSearch opens and initializes
 // its own store.
-func runLocalSearch(cmd *cobra.Command, queryStr string, scope Scope, scopedStore *store.Store) error {
+func runLocalSearch(cmd *cobra.Command, queryStr string, sort search.SortField, scope Scope, scopedStore *store.Store) error {
 	// Parse the query and apply any pre-resolved scope before the
 	// emptiness check so a bare --account/--collection is enough to
 	// produce a non-empty query.
 	q := search.Parse(queryStr)
+	q.Sort = sort
 	if !scope.IsEmpty() {
 		q.AccountIDs = scope.SourceIDs()
 	}
@@ -289,11 +392,16 @@ func runLocalSearch(cmd *cobra.Command, queryStr string, scope Scope, scopedStor
 		)
 	}
 
-	fmt.Fprintf(os.Stderr, "Searching...")
-
-	if err := ensureFTSIndex(s); err != nil {
-		return err
+	warnIncompleteFTS(s)
+	warnUnindexedHeaders(q)
+	if searchFormat != "" {
+		limit := 0
+		if cmd.Flags().Changed("limit") {
+			limit = searchLimit
+		}
+		return runSearchExport(cmd.Context(), s, q, limit)
 	}
+	fmt.Fprintf(os.Stderr, "Searching...")
 
 	// Log the search operation. Raw query text and account
 	// identifiers may contain PII — log coarse metadata at
@@ -331,7 +439,7 @@ func runLocalSearch(cmd *cobra.Command, queryStr string, scope Scope, scopedStor
 	)
 
 	if len(results) == 0 {
-		fmt.Println("No messages found.")
+		fmt.Println(i18n.T("No messages found."))
 		return nil
 	}
 
@@ -343,11 +451,10 @@ func runLocalSearch(cmd *cobra.Command, queryStr string, scope Scope, scopedStor
 
 func outputSearchResultsTable(results []query.MessageSummary) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "ID\tDATE\tFROM\tSUBJECT\tSIZE")
-	_, _ = fmt.Fprintln(w, "──\t────\t────\t───────\t────")
+	writeSearchTableHeader(w)
 
 	for _, msg := range results {
-		date := msg.SentAt.Format("2006-01-02")
+		date := i18n.Date(msg.SentAt)
 		from := truncate(msg.FromEmail, 30)
 		subject := truncate(msg.Subject, 50)
 		size := formatSize(msg.SizeEstimate)
@@ -355,17 +462,28 @@ func outputSearchResultsTable(results []query.MessageSummary) error {
 	}
 
 	_ = w.Flush()
-	fmt.Printf("\nShowing %d results\n", len(results))
+	fmt.Println("\n" + i18n.T("Showing %d results", len(results)))
 	return nil
 }
 
+// writeSearchTableHeader writes the column headings of a search
+// results table, each underlined to its own width.
+func writeSearchTableHeader(w io.Writer) {
+	cols := []string{"ID", i18n.T("DATE"), i18n.T("FROM"), i18n.T("SUBJECT"), i18n.T("SIZE")}
+	rules := make([]string, len(cols))
+	for i, c := range cols {
+		rules[i] = strings.Repeat("─", utf8.RuneCountInString(c))
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(cols, "\t"))
+	_, _ = fmt.Fprintln(w, strings.Join(rules, "\t"))
+}
+
 func outputRemoteSearchResultsTable(results []store.APIMessage, total int64) error {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "ID\tDATE\tFROM\tSUBJECT\tSIZE")
-	_, _ = fmt.Fprintln(w, "──\t────\t────\t───────\t────")
+	writeSearchTableHeader(w)
 
 	for _, msg := range results {
-		date := msg.SentAt.Format("2006-01-02")
+		date := i18n.Date(msg.SentAt)
 		from := truncate(msg.From, 30)
 		subject := truncate(msg.Subject, 50)
 		size := formatSize(msg.SizeEstimate)
@@ -373,7 +491,7 @@ func outputRemoteSearchResultsTable(results []store.APIMessage, total int64) err
 	}
 
 	_ = w.Flush()
-	fmt.Printf("\nShowing %d of %d results\n", len(results), total)
+	fmt.Println("\n" + i18n.T("Showing %d of %d results", len(results), total))
 	return nil
 }
 
@@ -418,33 +536,8 @@ func init() {
 	searchCmd.MarkFlagsMutuallyExclusive("account", "collection")
 	searchCmd.Flags().StringVar(&searchMode, "mode", "fts", "Search mode: fts|vector|hybrid")
 	searchCmd.Flags().BoolVar(&searchExplain, "explain", false, "Include per-signal scores in output (hybrid/vector modes)")
-}
-
-// ensureFTSIndex checks if the FTS search index needs to be built and
-// runs a one-time backfill if so. Shows a live progress bar since this
-// can take a while on large archives. Blocks until complete.
-func ensureFTSIndex(s *store.Store) error {
-	if !s.NeedsFTSBackfill() {
-		return nil
-	}
-	fmt.Fprintf(os.Stderr, "Building search index (one-time)...\n")
-	n, err := s.BackfillFTS(func(done, total int64) {
-		if total <= 0 {
-			return
-		}
-		if done > total {
-			done = total
-		}
-		pct := int(done * 100 / total)
-		barWidth := 30
-		filled := barWidth * pct / 100
-		bar := strings.Repeat("=", filled) + strings.Repeat(" ", barWidth-filled)
-		fmt.Fprintf(os.Stderr, "\r  [%s] %3d%%", bar, pct)
-	})
-	if err != nil {
-		fmt.Fprintln(os.Stderr)
-		return fmt.Errorf("build search index: %w", err)
-	}
-	fmt.Fprintf(os.Stderr, "\r  [%s] 100%%  %d messages indexed.\n", strings.Repeat("=", 30), n)
-	return nil
+	searchCmd.Flags().StringVar(&searchSort, "sort", "date", "Result order: date|relevance|size")
+	searchCmd.Flags().StringVar(&searchFormat, "format", "", "Write every match's metadata as jsonl|csv instead of a table")
+	searchCmd.Flags().StringVarP(&searchOut, "out", "o", "", "File to write --format output to (default stdout)")
+	searchCmd.MarkFlagsMutuallyExclusive("json", "format")
 }
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var (
+	searchFormat string
+	searchOut    string
+)
+
+// searchExportPageSize is how many matches 'search --format' fetches
+// and writes at a time, so an export of the whole archive never holds
+// more than one page in memory.
+const searchExportPageSize = 500
+
+// searchExportColumns are the CSV columns of 'search --format csv', in
+// the order of the fields of searchExportRecord.
+var searchExportColumns = []string{"id", "date", "from", "to", "subject", "size", "labels"}
+
+// searchExportRecord is the metadata of one message written by
+// 'search --format'.
+type searchExportRecord struct {
+	ID      int64     `json:"id"`
+	Date    time.Time `json:"date"`
+	From    string    `json:"from"`
+	To      []string  `json:"to"`
+	Subject string    `json:"subject"`
+	Size    int64     `json:"size"`
+	Labels  []string  `json:"labels"`
+}
+
+func newSearchExportRecord(msg store.APIMessage) searchExportRecord {
+	rec := searchExportRecord{
+		ID:      msg.ID,
+		Date:    msg.SentAt.UTC(),
+		From:    msg.From,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Size:    msg.SizeEstimate,
+		Labels:  msg.Labels,
+	}
+	if rec.To == nil {
+		rec.To = []string{}
+	}
+	if rec.Labels == nil {
+		rec.Labels = []string{}
+	}
+	return rec
+}
+
+// searchRecordWriter writes search results in one export format.
+type searchRecordWriter interface {
+	Write(rec searchExportRecord) error
+	Flush() error
+}
+
+// newSearchRecordWriter returns the writer for format, jsonl or csv.
+// A CSV export starts with its header row.
+func newSearchRecordWriter(w io.Writer, format string) (searchRecordWriter, error) {
+	switch format {
+	case "jsonl":
+		return jsonlSearchWriter{enc: json.NewEncoder(w)}, nil
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(searchExportColumns); err != nil {
+			return nil, fmt.Errorf("write csv header: %w", err)
+		}
+		return csvSearchWriter{cw: cw}, nil
+	}
+	return nil, fmt.Errorf("invalid --format: %q (want jsonl|csv)", format)
+}
+
+// jsonlSearchWriter writes one JSON object per line.
+type jsonlSearchWriter struct {
+	enc *json.Encoder
+}
+
+func (w jsonlSearchWriter) Write(rec searchExportRecord) error {
+	return w.enc.Encode(rec)
+}
+
+func (w jsonlSearchWriter) Flush() error { return nil }
+
+// csvSearchWriter writes one row per message; the to and labels
+// columns join their values with semicolons.
+type csvSearchWriter struct {
+	cw *csv.Writer
+}
+
+func (w csvSearchWriter) Write(rec searchExportRecord) error {
+	return w.cw.Write([]string{
+		strconv.FormatInt(rec.ID, 10),
+		rec.Date.Format(time.RFC3339),
+		rec.From,
+		strings.Join(rec.To, ";"),
+		rec.Subject,
+		strconv.FormatInt(rec.Size, 10),
+		strings.Join(rec.Labels, ";"),
+	})
+}
+
+func (w csvSearchWriter) Flush() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+// runSearchExport writes the metadata of every message matching q to
+// --out, or stdout, in --format.
+func runSearchExport(ctx context.Context, s *store.Store, q *search.Query, limit int) error {
+	if searchOut == "" {
+		_, err := writeSearchExport(ctx, s, q, limit, os.Stdout)
+		return err
+	}
+	f, err := fileutil.SecureOpenFile(searchOut, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, emlFileMode)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	written, err := writeSearchExport(ctx, s, q, limit, f)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", searchOut, err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %s messages to %s\n", formatCount(int64(written)), searchOut)
+	return nil
+}
+
+// writeSearchExport writes the matches of q to out and returns how
+// many it wrote. Matches are fetched a page at a time in search order,
+// starting at --offset; a limit of 0 exports them all.
+func writeSearchExport(ctx context.Context, s *store.Store, q *search.Query, limit int, out io.Writer) (int, error) {
+	w, err := newSearchRecordWriter(out, searchFormat)
+	if err != nil {
+		return 0, err
+	}
+
+	engine := query.NewSQLiteEngine(s.DB())
+	written := 0
+	for offset := searchOffset; limit <= 0 || offset-searchOffset < limit; offset += searchExportPageSize {
+		pageSize := searchExportPageSize
+		if limit > 0 {
+			pageSize = min(pageSize, limit-(offset-searchOffset))
+		}
+		results, err := engine.Search(ctx, q, pageSize, offset)
+		if err != nil {
+			return written, query.HintRepairEncoding(fmt.Errorf("search: %w", err))
+		}
+		if len(results) == 0 {
+			break
+		}
+		ids := make([]int64, len(results))
+		for i, r := range results {
+			ids[i] = r.ID
+		}
+		msgs, err := s.GetMessagesSummariesByIDs(ids)
+		if err != nil {
+			return written, err
+		}
+		for _, msg := range msgs {
+			if err := w.Write(newSearchExportRecord(msg)); err != nil {
+				return written, fmt.Errorf("write %s: %w", searchFormat, err)
+			}
+			written++
+		}
+		if err := w.Flush(); err != nil {
+			return written, fmt.Errorf("write %s: %w", searchFormat, err)
+		}
+		if len(results) < pageSize {
+			break
+		}
+	}
+	return written, nil
+}
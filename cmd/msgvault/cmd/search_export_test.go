@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestWriteSearchExport(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("gmail", "alice@gmail.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	inbox, err := st.EnsureLabel(src.ID, "INBOX", "INBOX", "system")
+	if err != nil {
+		t.Fatalf("ensure label: %v", err)
+	}
+	for i := 1; i <= 3; i++ {
+		raw := email.NewMessage().From("bob@example.com").To("alice@gmail.com, carol@example.com").
+			Subject(fmt.Sprintf("Report, part %d", i)).
+			Date(fmt.Sprintf("Mon, 0%d Jan 2024 12:00:00 +0000", i)).
+			Body("Numbers attached.\r\n").Bytes()
+		if err := importer.IngestRawMessage(context.Background(), st, src.ID, "alice@gmail.com", "",
+			[]int64{inbox}, fmt.Sprintf("m%d", i), "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingest message %d: %v", i, err)
+		}
+	}
+
+	savedFormat, savedOffset := searchFormat, searchOffset
+	t.Cleanup(func() { searchFormat, searchOffset = savedFormat, savedOffset })
+
+	t.Run("csv", func(t *testing.T) {
+		searchFormat, searchOffset = "csv", 0
+		var buf bytes.Buffer
+		written, err := writeSearchExport(context.Background(), st, search.Parse("from:bob@example.com"), 0, &buf)
+		if err != nil {
+			t.Fatalf("writeSearchExport: %v", err)
+		}
+		if written != 3 {
+			t.Errorf("written = %d, want 3", written)
+		}
+		rows, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("read csv: %v", err)
+		}
+		if len(rows) != 4 {
+			t.Fatalf("got %d rows, want header and 3: %q", len(rows), rows)
+		}
+		if got := strings.Join(rows[0], ","); got != "id,date,from,to,subject,size,labels" {
+			t.Errorf("header = %q", got)
+		}
+		// Newest first, as search prints them.
+		row := rows[1]
+		if row[1] != "2024-01-03T12:00:00Z" || row[2] != "bob@example.com" ||
+			row[3] != "alice@gmail.com;carol@example.com" || row[4] != "Report, part 3" || row[6] != "INBOX" {
+			t.Errorf("first row = %q", row)
+		}
+	})
+
+	t.Run("jsonl with limit and offset", func(t *testing.T) {
+		searchFormat, searchOffset = "jsonl", 1
+		var buf bytes.Buffer
+		written, err := writeSearchExport(context.Background(), st, search.Parse("report"), 1, &buf)
+		if err != nil {
+			t.Fatalf("writeSearchExport: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if written != 1 || len(lines) != 1 {
+			t.Fatalf("written = %d, lines = %q; want 1", written, lines)
+		}
+		var rec searchExportRecord
+		if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+			t.Fatalf("decode %q: %v", lines[0], err)
+		}
+		if rec.Subject != "Report, part 2" || len(rec.To) != 2 || rec.Size == 0 {
+			t.Errorf("record = %+v", rec)
+		}
+	})
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var searchSaveCmd = &cobra.Command{
+	Use:   "save <name> <query>",
+	Short: "Save a search query under a name",
+	Long: `Save a search query under a name, replacing any query already saved
+under it. Use it in any query as saved:<name>: in 'msgvault search', in
+the --query of the export, attachments, reparse, risk scan and share
+commands, and from the TUI, where ' opens a picker of saved searches
+whose results can be staged for deletion like any other.
+
+A saved search is combined with the rest of a query as a group, so it
+can be narrowed or negated, and may itself use saved:. Names are single
+words.
+
+Examples:
+  msgvault search save receipts 'from:shop.example.com has:attachment'
+  msgvault search save team 'from:alice@example.com OR from:bob@example.com'
+  msgvault search saved:receipts after:2024-01-01
+  msgvault export mbox --query 'saved:receipts -saved:team' --out receipts.mbox`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(_ *cobra.Command, args []string) error {
+		if err := MustBeLocal("search save"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = s.Close() }()
+
+		name, queryStr := args[0], strings.Join(args[1:], " ")
+		if err := s.SaveSearch(name, queryStr); err != nil {
+			return err
+		}
+		fmt.Printf("Saved search %q: %s\n", name, queryStr)
+		return nil
+	},
+}
+
+var searchListSavedCmd = &cobra.Command{
+	Use:   "list-saved",
+	Short: "List saved searches",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		if err := MustBeLocal("search list-saved"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = s.Close() }()
+
+		searches, err := s.ListSavedSearches()
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if searches == nil {
+				searches = []store.SavedSearch{}
+			}
+			return printJSON(searches)
+		}
+		if len(searches) == 0 {
+			fmt.Println("No saved searches. Save one with 'msgvault search save <name> <query>'.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NAME\tQUERY\tUPDATED")
+		for _, ss := range searches {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", ss.Name, ss.Query, ss.UpdatedAt.Local().Format("2006-01-02 15:04"))
+		}
+		_ = w.Flush()
+		return nil
+	},
+}
+
+var searchDeleteSavedCmd = &cobra.Command{
+	Use:   "delete-saved <name>",
+	Short: "Delete a saved search",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		if err := MustBeLocal("search delete-saved"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = s.Close() }()
+
+		if err := s.DeleteSavedSearch(args[0]); err != nil {
+			if errors.Is(err, store.ErrSavedSearchNotFound) {
+				return fmt.Errorf("no search saved as %q", args[0])
+			}
+			return err
+		}
+		fmt.Printf("Deleted saved search %q.\n", args[0])
+		return nil
+	},
+}
+
+// hasSavedTerm reports whether queryStr may use a saved search, so
+// that commands only open the store to expand queries that need it.
+func hasSavedTerm(queryStr string) bool {
+	return strings.Contains(strings.ToLower(queryStr), "saved:")
+}
+
+// parseLocalQuery expands the saved:name terms of queryStr from s and
+// parses the result.
+func parseLocalQuery(s *store.Store, queryStr string) (*search.Query, error) {
+	expanded, err := s.ExpandSavedSearches(queryStr)
+	if err != nil {
+		return nil, err
+	}
+	return search.Parse(expanded), nil
+}
+
+func init() {
+	searchCmd.AddCommand(searchSaveCmd)
+	searchCmd.AddCommand(searchListSavedCmd)
+	searchCmd.AddCommand(searchDeleteSavedCmd)
+}
@@ -57,6 +57,8 @@ func resetSearchFlags() {
 	searchJSON = false
 	searchMode = "fts"
 	searchExplain = false
+	searchFormat = ""
+	searchOut = ""
 	// Cobra remembers per-flag `Changed` state on the global searchCmd
 	// across test invocations. Without clearing it, mutually-exclusive
 	// pairs (--account / --collection) trip when a subsequent test only
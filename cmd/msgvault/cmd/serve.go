@@ -16,22 +16,39 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/api"
 	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/grpcapi"
+	"github.com/wesm/msgvault/internal/imapgateway"
+	"github.com/wesm/msgvault/internal/jmap"
 	"github.com/wesm/msgvault/internal/oauth"
+	"github.com/wesm/msgvault/internal/oplock"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/scheduler"
 	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/store"
 	"github.com/wesm/msgvault/internal/sync"
+	"github.com/wesm/msgvault/internal/winservice"
 	"golang.org/x/oauth2"
 )
 
+var serveListen string
+
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Run msgvault as a daemon with scheduled sync",
 	Long: `Run msgvault as a long-running daemon that syncs email accounts on schedule.
 
 The daemon runs in the foreground and performs:
-  - HTTP API server on configured port (default: 8080)
+  - HTTP API server on configured port (default: 8080), exposing
+    search, message detail, attachments, aggregates, and stats
+    under /api/v1 (authenticated with [server] api_key)
+  - Web UI at http://<listen-address>/ui/ for search, thread view,
+    and attachment download
+  - gRPC API (proto/msgvault/v1/msgvault.proto) when [server] grpc_port
+    is set, sharing bind_addr and api_key with the HTTP API
+  - Read-only IMAP gateway when [server] imap_port is set: labels
+    appear as folders and any mail client can browse the archive
+  - Read-only JMAP endpoint at /jmap (discovered via /.well-known/jmap)
+    with the same folders and search as the IMAP gateway
   - Scheduled incremental syncs based on account config
   - Automatic cache rebuilds after each sync
 
@@ -48,17 +65,48 @@ Cron format: minute hour day-of-month month day-of-week
     0 0 * * 0     = Midnight on Sundays
     0 8,18 * * *  = 8 AM and 6 PM daily
 
-Use Ctrl+C to stop the daemon gracefully.`,
+Use --listen to override [server] bind_addr and api_port for this run:
+  msgvault serve --listen 127.0.0.1:8080
+
+Use Ctrl+C to stop the daemon gracefully.
+
+On Windows, "msgvault service install" registers the daemon as a
+Windows service that starts with the machine.`,
 	RunE: runServe,
 }
 
 func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "",
+		"address to listen on as host:port (overrides [server] bind_addr and api_port)")
 	rootCmd.AddCommand(serveCmd)
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	// Validate security posture before doing any work
-	if err := cfg.Server.ValidateSecure(); err != nil {
+	// Under the Windows service control manager, a service stop
+	// cancels the context just as Ctrl+C does in a console.
+	if winservice.IsService() {
+		return winservice.Run(cmd.Context(), func(ctx context.Context) error {
+			cmd.SetContext(ctx)
+			return serveDaemon(cmd)
+		})
+	}
+	return serveDaemon(cmd)
+}
+
+func serveDaemon(cmd *cobra.Command) error {
+	if serveListen != "" {
+		host, port, err := parseListenAddr(serveListen)
+		if err != nil {
+			return err
+		}
+		cfg.Server.BindAddr = host
+		cfg.Server.APIPort = port
+	}
+
+	// Validate security posture before doing any work. API tokens
+	// stored in the vault also satisfy the authentication requirement;
+	// that case is re-checked once the database is open.
+	if err := cfg.Server.ValidateSecure(); err != nil && !vaultHasAPITokens() {
 		return err
 	}
 	if cfg.Server.APIKey != "" && len(cfg.Server.APIKey) < 16 {
@@ -84,6 +132,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("open database: %w", err)
 	}
 	defer func() { _ = s.Close() }()
+	applyParseConfig(s)
 
 	if err := s.InitSchema(); err != nil {
 		return fmt.Errorf("init schema: %w", err)
@@ -145,54 +194,30 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	getOAuthMgr := oauthManagerCache()
 
-	// Create sync function for the scheduler. vf is captured and used
-	// inside runScheduledSync to wire the embed enqueuer into each
-	// per-run Syncer; it is nil when vector search is disabled.
-	syncFunc := func(ctx context.Context, email string) error {
-		return runScheduledSync(ctx, email, s, getOAuthMgr, vf)
+	// Only one daemon may schedule the vault's syncs.
+	if len(scheduled) > 0 {
+		lock, err := acquireDaemonLock("serve")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
 	}
 
-	// Create and configure scheduler
-	sched := scheduler.New(syncFunc).WithLogger(logger)
-
-	// Add all scheduled accounts
-	count, errs := sched.AddAccountsFromConfig(cfg)
-	if len(errs) > 0 {
-		for _, err := range errs {
-			logger.Error("failed to schedule account", "error", err)
-		}
+	sched, count, err := newSyncScheduler(s, vf, getOAuthMgr)
+	if err != nil {
+		return err
 	}
 	if count == 0 {
 		logger.Warn("no accounts scheduled - upload tokens via API and add accounts to config.toml")
 	}
-
-	// Register the embed job (cron-driven plus optional post-sync hook).
-	// Only when vector search is enabled and wired.
-	if vf != nil {
-		embedJob := &scheduler.EmbedJob{
-			Worker:      vf.Worker,
-			Backend:     vf.Backend,
-			VectorsDB:   vf.VectorsDB,
-			Fingerprint: vf.Cfg.Embeddings.Fingerprint(),
-			Log:         logger,
-		}
-		schedule := cfg.Vector.Embed.Schedule.Cron
-		if err := sched.SetEmbedJob(
-			embedJob, schedule, cfg.Vector.Embed.Schedule.RunAfterSync,
-		); err != nil {
-			return fmt.Errorf("register embed job: %w", err)
-		}
-		logger.Info("embed scheduled",
-			"cron", schedule,
-			"run_after_sync", cfg.Vector.Embed.Schedule.RunAfterSync,
-		)
-	}
+	publishStatus := publishDaemonStatus(sched)
 
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
 	// Start the scheduler
 	sched.Start()
+	publishStatus()
 
 	// Create adapters for the API interfaces
 	storeAdapter := &storeAPIAdapter{store: s}
@@ -204,6 +229,9 @@ func runServe(cmd *cobra.Command, args []string) error {
 		Store:     storeAdapter,
 		Engine:    engine,
 		Scheduler: schedAdapter,
+		Access:    storeAdapter,
+		JMAP:      jmap.NewHandler(storeAdapter, engine, logger),
+		Readiness: &readinessProbe{store: s, getOAuthMgr: getOAuthMgr},
 		Logger:    logger,
 	}
 	if vf != nil {
@@ -214,19 +242,49 @@ func runServe(cmd *cobra.Command, args []string) error {
 	apiServer := api.NewServerWithOptions(apiOpts)
 
 	// Start API server in goroutine
-	serverErr := make(chan error, 1)
+	serverErr := make(chan error, 3)
 	go func() {
 		if err := apiServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			serverErr <- err
 		}
 	}()
 
+	// Start gRPC server alongside REST when a port is configured
+	var grpcServer *grpcapi.Server
+	grpcAddr := grpcapi.Addr(cfg)
+	if grpcAddr != "" {
+		grpcServer = grpcapi.NewServer(cfg, storeAdapter, schedAdapter, logger)
+		go func() {
+			if err := grpcServer.Start(); err != nil {
+				serverErr <- fmt.Errorf("gRPC server: %w", err)
+			}
+		}()
+	}
+
+	// Start the read-only IMAP gateway when a port is configured
+	var imapServer *imapgateway.Server
+	imapAddr := imapgateway.Addr(cfg)
+	if imapAddr != "" {
+		imapServer = imapgateway.NewServer(cfg, storeAdapter, engine, logger)
+		go func() {
+			if err := imapServer.Start(); err != nil {
+				serverErr <- fmt.Errorf("IMAP gateway: %w", err)
+			}
+		}()
+	}
+
 	bindAddr := cfg.Server.BindAddr
 	if bindAddr == "" {
 		bindAddr = "127.0.0.1"
 	}
 	fmt.Printf("msgvault daemon started\n")
 	fmt.Printf("  API server: http://%s\n", net.JoinHostPort(bindAddr, strconv.Itoa(cfg.Server.APIPort)))
+	if grpcAddr != "" {
+		fmt.Printf("  gRPC server: %s\n", grpcAddr)
+	}
+	if imapAddr != "" {
+		fmt.Printf("  IMAP gateway: %s\n", imapAddr)
+	}
 	fmt.Printf("  Scheduled accounts: %d\n", count)
 	fmt.Printf("  Data directory: %s\n", cfg.Data.DataDir)
 	fmt.Println()
@@ -258,6 +316,14 @@ func runServe(cmd *cobra.Command, args []string) error {
 	if err := apiServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error("API server shutdown error", "error", err)
 	}
+	if grpcServer != nil {
+		grpcServer.Shutdown(shutdownCtx)
+	}
+	if imapServer != nil {
+		if err := imapServer.Close(); err != nil {
+			logger.Error("IMAP gateway shutdown error", "error", err)
+		}
+	}
 
 	fmt.Println("Waiting for running syncs to complete...")
 	schedCtx := sched.Stop()
@@ -269,10 +335,28 @@ func runServe(cmd *cobra.Command, args []string) error {
 	case <-time.After(30 * time.Second):
 		fmt.Println("Shutdown timed out after 30 seconds.")
 	}
+	publishStatus()
 
 	return nil
 }
 
+// parseListenAddr splits a --listen value into bind address and port.
+// An empty host (":8080") binds to loopback, matching the config default.
+func parseListenAddr(addr string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid --listen address %q: %w", addr, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid --listen port %q: must be 1-65535", portStr)
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return host, port, nil
+}
+
 // storeAPIAdapter adapts store.Store to api.MessageStore.
 // Since api.APIMessage, api.StoreStats, etc. are type aliases for store types,
 // the adapter methods are simple pass-throughs with no conversion needed.
@@ -304,6 +388,116 @@ func (a *storeAPIAdapter) SearchMessagesQuery(q *search.Query, offset, limit int
 	return a.store.SearchMessagesQuery(q, offset, limit)
 }
 
+// SourceIDsForAccounts resolves [[server.users]] account identifiers to
+// source IDs. An identifier may map to several sources (e.g. a Gmail
+// account plus an mbox import of the same address).
+func (a *storeAPIAdapter) SourceIDsForAccounts(identifiers []string) ([]int64, error) {
+	var ids []int64
+	for _, ident := range identifiers {
+		sources, err := a.store.GetSourcesByIdentifier(ident)
+		if err != nil {
+			return nil, fmt.Errorf("resolve account %s: %w", ident, err)
+		}
+		for _, src := range sources {
+			ids = append(ids, src.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (a *storeAPIAdapter) LookupAPIToken(token string) (*store.APIToken, error) {
+	return a.store.LookupAPIToken(token)
+}
+
+func (a *storeAPIAdapter) HasActiveAPITokens() (bool, error) {
+	return a.store.HasActiveAPITokens()
+}
+
+func (a *storeAPIAdapter) MessageSourceID(id int64) (int64, bool, error) {
+	return a.store.MessageSourceID(id)
+}
+
+func (a *storeAPIAdapter) AttachmentSourceID(id int64) (int64, bool, error) {
+	return a.store.AttachmentSourceID(id)
+}
+
+func (a *storeAPIAdapter) GetStatsForScope(sourceIDs []int64) (*api.StoreStats, error) {
+	return a.store.GetStatsForScope(sourceIDs)
+}
+
+func (a *storeAPIAdapter) MailboxNames(sourceIDs []int64) ([]string, error) {
+	return a.store.MailboxNames(sourceIDs)
+}
+
+func (a *storeAPIAdapter) MailboxMessages(label string, sourceIDs []int64) ([]store.MailboxMessage, error) {
+	return a.store.MailboxMessages(label, sourceIDs)
+}
+
+func (a *storeAPIAdapter) GetMessageRaw(id int64) ([]byte, error) {
+	return a.store.GetMessageRaw(id)
+}
+
+func (a *storeAPIAdapter) MailboxMessagesByID(ids []int64) ([]store.MailboxMessage, error) {
+	return a.store.MailboxMessagesByID(ids)
+}
+
+func (a *storeAPIAdapter) ConversationMailboxMessages(conversationIDs, sourceIDs []int64) ([]store.MailboxMessage, error) {
+	return a.store.ConversationMailboxMessages(conversationIDs, sourceIDs)
+}
+
+// vaultHasAPITokens reports whether the local vault holds an active API
+// token. Errors (including a database that predates API tokens) count
+// as no tokens, so the caller's original security error is reported.
+func vaultHasAPITokens() bool {
+	s, err := openLocalStore()
+	if err != nil {
+		return false
+	}
+	defer func() { _ = s.Close() }()
+	active, err := s.HasActiveAPITokens()
+	return err == nil && active
+}
+
+// readinessProbe answers the daemon-side /readyz checks.
+type readinessProbe struct {
+	store       *store.Store
+	getOAuthMgr func(string) (*oauth.Manager, error)
+}
+
+func (p *readinessProbe) PingDB(ctx context.Context) error {
+	return p.store.Ping(ctx)
+}
+
+// HasCredentials mirrors the credential resolution in runScheduledSync:
+// a service account covers every address, otherwise the account needs
+// a token from its bound OAuth app.
+func (p *readinessProbe) HasCredentials(email string) bool {
+	appName := ""
+	if src, err := findGmailSource(p.store, email); err == nil && src != nil {
+		appName = sourceOAuthApp(src)
+	}
+	if cfg.OAuth.ServiceAccountKeyFor(appName) != "" {
+		return true
+	}
+	mgr, err := p.getOAuthMgr(appName)
+	return err == nil && mgr.HasToken(email)
+}
+
+func (p *readinessProbe) LastSuccessfulSync(email string) (time.Time, error) {
+	src, err := findGmailSource(p.store, email)
+	if err != nil || src == nil {
+		return time.Time{}, err
+	}
+	run, err := p.store.GetLastSuccessfulSync(src.ID)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("last sync for %s: %w", email, err)
+	}
+	if run == nil || !run.CompletedAt.Valid {
+		return time.Time{}, nil
+	}
+	return run.CompletedAt.Time, nil
+}
+
 // schedulerAdapter adapts scheduler.Scheduler to api.SyncScheduler.
 // Since api.AccountStatus is a type alias for scheduler.AccountStatus,
 // the adapter methods are simple pass-throughs.
@@ -320,7 +514,7 @@ func (a *schedulerAdapter) TriggerSync(email string) error {
 }
 
 func (a *schedulerAdapter) AddAccount(email, schedule string) error {
-	return a.scheduler.AddAccount(email, schedule)
+	return a.scheduler.AddAccountWithJitter(email, schedule, cfg.Daemon.Jitter)
 }
 
 func (a *schedulerAdapter) IsRunning() bool {
@@ -332,11 +526,21 @@ func (a *schedulerAdapter) Status() []api.AccountStatus {
 }
 
 // runScheduledSync performs an incremental sync for a scheduled
-// account. When vf is non-nil (vector search enabled), the Syncer is
+// account and returns its summary. When vf is non-nil (vector search enabled), the Syncer is
 // configured to enqueue newly-ingested message IDs into the embedding
 // pipeline so subsequent embed runs pick them up.
-func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAuthMgr func(string) (*oauth.Manager, error), vf *vectorFeatures) error {
+func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAuthMgr func(string) (*oauth.Manager, error), vf *vectorFeatures) (*gmail.SyncSummary, error) {
 	logger.Info("starting scheduled sync", "email", email)
+
+	// Queue behind any CLI operation on the vault rather than failing
+	// the scheduled run.
+	lock, err := oplock.Acquire(ctx, cfg.Data.DataDir, "scheduled sync "+email, func(busy *oplock.BusyError) {
+		logger.Info("waiting for vault lock", "email", email, "error", busy)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("acquire vault lock: %w", err)
+	}
+	defer func() { _ = lock.Release() }()
 	startTime := time.Now()
 
 	// Look up source to get OAuth app binding. Fall back to default
@@ -344,7 +548,7 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 	appName := ""
 	src, srcErr := findGmailSource(s, email)
 	if srcErr != nil {
-		return fmt.Errorf("look up source for %s: %w", email, srcErr)
+		return nil, fmt.Errorf("look up source for %s: %w", email, srcErr)
 	}
 	if src != nil {
 		appName = sourceOAuthApp(src)
@@ -355,18 +559,18 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 
 	// Check for service account configuration
 	if saKeyPath := cfg.OAuth.ServiceAccountKeyFor(appName); saKeyPath != "" {
-		saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, oauth.Scopes)
+		saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, gmailScopes())
 		if saErr != nil {
-			return fmt.Errorf("service account for %s: %w", email, saErr)
+			return nil, fmt.Errorf("service account for %s: %w", email, saErr)
 		}
 		tokenSource, tsErr = saMgr.TokenSource(ctx, email)
 		if tsErr != nil {
-			return fmt.Errorf("service account token for %s: %w", email, tsErr)
+			return nil, fmt.Errorf("service account token for %s: %w", email, tsErr)
 		}
 	} else {
 		oauthMgr, oaErr := getOAuthMgr(appName)
 		if oaErr != nil {
-			return fmt.Errorf("resolve OAuth credentials for %s: %w", email, oaErr)
+			return nil, fmt.Errorf("resolve OAuth credentials for %s: %w", email, oaErr)
 		}
 
 		// Get token source — intentionally not using getTokenSourceWithReauth here
@@ -374,9 +578,9 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 		tokenSource, tsErr = oauthMgr.TokenSource(ctx, email)
 		if tsErr != nil {
 			if oauthMgr.HasToken(email) {
-				return fmt.Errorf("get token source: %w (token may be expired; run 'sync %s' or 'verify %s' from an interactive terminal to re-authorize)", tsErr, email, email)
+				return nil, fmt.Errorf("get token source: %w (token may be expired; run 'sync %s' or 'verify %s' from an interactive terminal to re-authorize)", tsErr, email, email)
 			}
-			return fmt.Errorf("get token source: %w (run 'add-account %s' first)", tsErr, email)
+			return nil, fmt.Errorf("get token source: %w (run 'add-account %s' first)", tsErr, email)
 		}
 	}
 
@@ -391,6 +595,14 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 	// Set up sync options
 	opts := sync.DefaultOptions()
 	opts.AttachmentsDir = cfg.AttachmentsDir()
+	opts.FetchWorkers, opts.FetchRate = cfg.Sync.FetchWorkers, cfg.Sync.FetchRate
+	if cfg.Sync.WriteBatch > 0 {
+		opts.WriteBatch = cfg.Sync.WriteBatch
+	}
+	opts.RecoverExpiredHistory = cfg.Sync.RecoverExpiredHistory
+	opts.SkipAttachments = cfg.Sync.SkipAttachments
+	opts.StorageQuota = cfg.Sync.StorageQuota
+	applyLabelOptions(opts, email)
 
 	// Create syncer (no CLI progress for daemon mode)
 	syncer := sync.New(client, s, opts).WithLogger(logger)
@@ -401,7 +613,7 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 	// Resolve source — scheduled sync is Gmail-only.
 	source, err := s.GetOrCreateSource("gmail", email)
 	if err != nil {
-		return fmt.Errorf("get source: %w", err)
+		return nil, fmt.Errorf("get source: %w", err)
 	}
 	// Auto-default-identity must run BEFORE the legacy migration retry
 	// — see comment in account_identity.go. serve is a daemon, so the
@@ -409,13 +621,13 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 	// failure path through its own logger.Warn.
 	confirmDefaultIdentity(io.Discard, s, source.ID, email, email, "account-identifier")
 	if err := runPostSourceCreateMigrations(s); err != nil {
-		return fmt.Errorf("post-source-create migrations: %w", err)
+		return nil, fmt.Errorf("post-source-create migrations: %w", err)
 	}
 
 	// Run incremental sync
 	summary, err := syncer.Incremental(ctx, source)
 	if err != nil {
-		return fmt.Errorf("incremental sync failed: %w", err)
+		return nil, fmt.Errorf("incremental sync failed: %w", err)
 	}
 
 	logger.Info("sync completed",
@@ -443,5 +655,5 @@ func runScheduledSync(ctx context.Context, email string, s *store.Store, getOAut
 		}
 	}
 
-	return nil
+	return summary, nil
 }
@@ -176,3 +176,37 @@ func TestCronExpressionValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestParseListenAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"loopback", "127.0.0.1:8080", "127.0.0.1", 8080, false},
+		{"all interfaces", "0.0.0.0:9000", "0.0.0.0", 9000, false},
+		{"empty host defaults to loopback", ":8081", "127.0.0.1", 8081, false},
+		{"ipv6", "[::1]:8080", "::1", 8080, false},
+		{"missing port", "127.0.0.1", "", 0, true},
+		{"non-numeric port", "127.0.0.1:http", "", 0, true},
+		{"port out of range", "127.0.0.1:70000", "", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port, err := parseListenAddr(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseListenAddr(%q) error = %v, wantErr = %v", tt.addr, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("parseListenAddr(%q) = (%q, %d), want (%q, %d)",
+					tt.addr, host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/winservice"
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Install msgvault serve as a Windows service",
+	Long: `Register the msgvault daemon ("msgvault serve") with the Windows
+service control manager so it starts with the machine and restarts
+after a crash.
+
+The service records the current --home (and --config, if given) so it
+uses this vault regardless of the account it runs as. It runs as
+LocalSystem; set a specific user in services.msc if syncs need that
+user's credentials or network drives.
+
+Run from an elevated (Administrator) prompt. On Linux and macOS, use
+"msgvault daemon install" to run scheduled syncs under systemd or
+launchd instead.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Register the daemon as a Windows service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("find current executable: %w", err)
+		}
+		svcArgs, err := serviceArgs()
+		if err != nil {
+			return err
+		}
+		if err := winservice.Install(exe, svcArgs); err != nil {
+			return err
+		}
+		fmt.Printf("Installed service %q. Start it with: sc start %s\n", winservice.Name, winservice.Name)
+		return nil
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the Windows service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := winservice.Uninstall(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed service %q.\n", winservice.Name)
+		return nil
+	},
+}
+
+// serviceArgs returns the arguments the service starts msgvault with.
+// Paths are made absolute because services start in the system
+// directory with the service account's environment.
+func serviceArgs() ([]string, error) {
+	args, err := vaultArgs()
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, "serve")
+	if serveListen != "" {
+		args = append(args, "--listen", serveListen)
+	}
+	return args, nil
+}
+
+// vaultArgs returns the global flags that point a background msgvault
+// at this vault: --home, and --config if given, as absolute paths.
+func vaultArgs() ([]string, error) {
+	home, err := filepath.Abs(cfg.HomeDir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve home directory: %w", err)
+	}
+	args := []string{"--home", home}
+	if cfgFile != "" {
+		path, err := filepath.Abs(cfg.ConfigFilePath())
+		if err != nil {
+			return nil, fmt.Errorf("resolve config path: %w", err)
+		}
+		args = append(args, "--config", path)
+	}
+	return args, nil
+}
+
+func init() {
+	serviceInstallCmd.Flags().StringVar(&serveListen, "listen", "",
+		"address the service listens on as host:port (overrides [server] bind_addr and api_port)")
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd)
+	rootCmd.AddCommand(serviceCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/winservice"
+)
+
+func TestServiceArgs(t *testing.T) {
+	savedCfg, savedFile, savedListen := cfg, cfgFile, serveListen
+	t.Cleanup(func() { cfg, cfgFile, serveListen = savedCfg, savedFile, savedListen })
+	home := t.TempDir()
+
+	tests := []struct {
+		name    string
+		cfgFile string
+		listen  string
+		want    []string
+	}{
+		{"home only", "", "", []string{"--home", home, "serve"}},
+		{"explicit config", "config.toml", "", []string{"--home", home, "--config", filepath.Join(home, "config.toml"), "serve"}},
+		{"listen", "", "127.0.0.1:9090", []string{"--home", home, "serve", "--listen", "127.0.0.1:9090"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg = &config.Config{HomeDir: home}
+			cfgFile, serveListen = tt.cfgFile, tt.listen
+			got, err := serviceArgs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("serviceArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceInstall_Unsupported(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("installs a real service on Windows")
+	}
+	savedCfg := cfg
+	t.Cleanup(func() { cfg = savedCfg })
+	cfg = &config.Config{HomeDir: t.TempDir()}
+
+	if err := serviceInstallCmd.RunE(serviceInstallCmd, nil); !errors.Is(err, winservice.ErrUnsupported) {
+		t.Errorf("install error = %v, want ErrUnsupported", err)
+	}
+	if err := serviceUninstallCmd.RunE(serviceUninstallCmd, nil); !errors.Is(err, winservice.ErrUnsupported) {
+		t.Errorf("uninstall error = %v, want ErrUnsupported", err)
+	}
+}
@@ -45,6 +45,8 @@ func runSetup(cmd *cobra.Command, args []string) error {
 	}
 
 	// Step 1: Find or prompt for OAuth credentials
+	fmt.Println("Step 1: OAuth Credentials")
+	fmt.Println("--------------------------")
 	secretsPath, err := setupOAuthSecrets(reader)
 	if err != nil {
 		return err
@@ -98,9 +100,6 @@ func runSetup(cmd *cobra.Command, args []string) error {
 }
 
 func setupOAuthSecrets(reader *bufio.Reader) (string, error) {
-	fmt.Println("Step 1: OAuth Credentials")
-	fmt.Println("--------------------------")
-
 	// Check if already configured
 	if cfg.OAuth.ClientSecrets != "" {
 		fmt.Printf("OAuth credentials already configured: %s\n", cfg.OAuth.ClientSecrets)
@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/rules"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// shareMaxBytes is the largest message 'msgvault share' sends, Gmail's
+// limit on a message with attachments.
+const shareMaxBytes = 25 << 20
+
+var (
+	shareQuery   string
+	shareTo      []string
+	shareFrom    string
+	shareSubject string
+	shareNote    string
+	shareLimit   int
+	shareDryRun  bool
+	shareYes     bool
+)
+
+var shareCmd = &cobra.Command{
+	Use:   "share",
+	Short: "Send matching messages as .eml attachments via Gmail",
+	Long: `Send the messages matching a search to someone as attachments of one
+email, sent through the Gmail API from one of your Gmail accounts. Each
+message is attached as an .eml file holding the raw MIME stored when it was
+synced or imported, so the recipient can open it in any mail client with its
+own attachments intact. Messages without raw MIME, such as chat messages,
+are skipped. Only .eml is supported; msgvault has no PDF renderer.
+
+--query takes the same syntax as 'msgvault search' and is required. If more
+messages match than --limit, nothing is sent: narrow the search or raise the
+limit. The email, attachments included, must stay under Gmail's 25 MB.
+
+The email is sent from the Gmail account given by --from, or from the only
+Gmail account in the vault. msgvault shows what it is about to send and asks
+before sending unless --yes is given; --dry-run only shows it.
+
+Examples:
+  msgvault share --query "label:Receipts after:2024-01-01" --to accountant@example.com
+  msgvault share --query "from:alice@example.com subject:contract" --to bob@example.com \
+      --subject "The contract thread" --message "As discussed." --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runShare,
+}
+
+// shareFile is a message to attach to the email 'msgvault share' sends.
+type shareFile struct {
+	Name string
+	Raw  []byte
+}
+
+func runShare(cmd *cobra.Command, _ []string) error {
+	if err := MustBeLocal("share"); err != nil {
+		return err
+	}
+	if strings.TrimSpace(shareQuery) == "" {
+		return fmt.Errorf("--query is required")
+	}
+	if len(shareTo) == 0 {
+		return fmt.Errorf("--to is required")
+	}
+	for _, addr := range shareTo {
+		if _, err := mail.ParseAddress(addr); err != nil {
+			return fmt.Errorf("invalid --to address %q: %w", addr, err)
+		}
+	}
+
+	jsonOut, restore := humanOutputToStderr()
+	defer restore()
+
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	src, err := shareSource(s, shareFrom)
+	if err != nil {
+		return err
+	}
+
+	q, err := parseLocalQuery(s, shareQuery)
+	if err != nil {
+		return err
+	}
+	msgs, err := rules.Matching(s, q, 0, 0)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	if len(msgs) == 0 {
+		return fmt.Errorf("no messages match %q", shareQuery)
+	}
+	if shareLimit > 0 && len(msgs) > shareLimit {
+		return fmt.Errorf("%s messages match, more than --limit %d; narrow the search or raise --limit",
+			formatCount(int64(len(msgs))), shareLimit)
+	}
+
+	var files []shareFile
+	skipped := 0
+	for _, m := range msgs {
+		raw, err := s.GetMessageRawMIME(m.ID)
+		if err != nil {
+			return fmt.Errorf("read message %d: %w", m.ID, err)
+		}
+		if raw == nil {
+			skipped++
+			continue
+		}
+		files = append(files, shareFile{Name: emlFilename(m), Raw: raw})
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("none of the %d matching messages has raw MIME to send", len(msgs))
+	}
+
+	subject := shareSubject
+	if subject == "" {
+		subject = fmt.Sprintf("%d messages from my archive", len(files))
+		if len(files) == 1 {
+			subject = "A message from my archive"
+		}
+	}
+	raw, err := buildShareMessage(src.Identifier, shareTo, subject, shareNote, files, time.Now())
+	if err != nil {
+		return err
+	}
+	if len(raw) > shareMaxBytes {
+		return fmt.Errorf("the email would be %s, over Gmail's %s limit; narrow the search",
+			formatSize(int64(len(raw))), formatSize(shareMaxBytes))
+	}
+
+	fmt.Printf("From:    %s\nTo:      %s\nSubject: %s\n", src.Identifier, strings.Join(shareTo, ", "), subject)
+	fmt.Printf("Attaching %s messages (%s):\n", formatCount(int64(len(files))), formatSize(int64(len(raw))))
+	for _, f := range files {
+		fmt.Printf("  %s\n", f.Name)
+	}
+	if skipped > 0 {
+		fmt.Printf("Skipping %s messages without raw MIME\n", formatCount(int64(skipped)))
+	}
+
+	result := map[string]any{
+		"from":     src.Identifier,
+		"to":       shareTo,
+		"subject":  subject,
+		"messages": len(files),
+		"skipped":  skipped,
+		"bytes":    len(raw),
+		"dry_run":  shareDryRun,
+	}
+	if shareDryRun {
+		if jsonOutput {
+			return printJSONTo(jsonOut, result)
+		}
+		return nil
+	}
+	if !shareYes {
+		ok, err := confirmDestructive(os.Stdin, os.Stdout, ConfirmModeYesNo)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	ctx := cmd.Context()
+	client, err := buildAPIClient(ctx, src, oauthManagerCache(), nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src.Identifier, err)
+	}
+	defer func() { _ = client.Close() }()
+	sender, ok := client.(gmail.Sender)
+	if !ok {
+		return fmt.Errorf("%s: client cannot send mail", src.Identifier)
+	}
+	id, err := sender.SendMessage(ctx, raw)
+	if err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+
+	if jsonOutput {
+		result["id"] = id
+		return printJSONTo(jsonOut, result)
+	}
+	fmt.Printf("Sent %s messages to %s.\n", formatCount(int64(len(files))), strings.Join(shareTo, ", "))
+	return nil
+}
+
+// shareSource returns the Gmail account to send from: the one named by
+// identifier, or the only Gmail account in the vault.
+func shareSource(s *store.Store, identifier string) (*store.Source, error) {
+	if identifier != "" {
+		return resolveSource(s, identifier, "gmail")
+	}
+	sources, err := s.ListSources("gmail")
+	if err != nil {
+		return nil, err
+	}
+	switch len(sources) {
+	case 0:
+		return nil, fmt.Errorf("no Gmail account to send from; add one with 'msgvault add-account'")
+	case 1:
+		return sources[0], nil
+	}
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.Identifier
+	}
+	return nil, fmt.Errorf("more than one Gmail account (%s); pick one with --from", strings.Join(names, ", "))
+}
+
+// buildShareMessage builds the email 'msgvault share' sends: note as the
+// text, then each file attached as an .eml. Files are attached as
+// message/rfc822 unless they are not valid as such, with lines over
+// RFC 5322's 998 bytes or NUL bytes, in which case they are attached
+// base64-encoded as application/octet-stream.
+func buildShareMessage(from string, to []string, subject, note string, files []shareFile, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	var head bytes.Buffer
+	fmt.Fprintf(&head, "From: %s\r\n", from)
+	fmt.Fprintf(&head, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&head, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&head, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	head.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&head, "Content-Type: %s\r\n",
+		mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": w.Boundary()}))
+	head.WriteString("\r\n")
+
+	if note == "" {
+		note = fmt.Sprintf("%d message(s) attached.", len(files))
+	}
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"8bit"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write([]byte(strings.ReplaceAll(strings.TrimRight(note, "\n"), "\n", "\r\n") + "\r\n")); err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": f.Name})
+		if validRFC822(f.Raw) {
+			part, err := w.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {"message/rfc822"},
+				"Content-Transfer-Encoding": {"8bit"},
+				"Content-Disposition":       {disposition},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if _, err := part.Write(f.Raw); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType("application/octet-stream", map[string]string{"name": f.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {disposition},
+		})
+		if err != nil {
+			return nil, err
+		}
+		enc := base64.StdEncoding.EncodeToString(f.Raw)
+		for len(enc) > 76 {
+			if _, err := fmt.Fprintf(part, "%s\r\n", enc[:76]); err != nil {
+				return nil, err
+			}
+			enc = enc[76:]
+		}
+		if _, err := fmt.Fprintf(part, "%s\r\n", enc); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return append(head.Bytes(), buf.Bytes()...), nil
+}
+
+// validRFC822 reports whether raw can be attached as message/rfc822
+// with 8bit encoding: no NUL bytes and no line over 998 bytes.
+func validRFC822(raw []byte) bool {
+	if bytes.IndexByte(raw, 0) >= 0 {
+		return false
+	}
+	for line := range bytes.SplitSeq(raw, []byte("\n")) {
+		if len(bytes.TrimSuffix(line, []byte("\r"))) > 998 {
+			return false
+		}
+	}
+	return true
+}
+
+func init() {
+	shareCmd.Flags().StringVar(&shareQuery, "query", "", "search selecting the messages to send (required)")
+	shareCmd.Flags().StringSliceVar(&shareTo, "to", nil, "recipient address (repeatable or comma-separated)")
+	shareCmd.Flags().StringVar(&shareFrom, "from", "", "Gmail account to send from (default: the only Gmail account)")
+	shareCmd.Flags().StringVar(&shareSubject, "subject", "", "subject of the email")
+	shareCmd.Flags().StringVar(&shareNote, "message", "", "text of the email")
+	shareCmd.Flags().IntVar(&shareLimit, "limit", 25, "most messages to send; more matches is an error (0 for no limit)")
+	shareCmd.Flags().BoolVar(&shareDryRun, "dry-run", false, "show what would be sent without sending")
+	shareCmd.Flags().BoolVarP(&shareYes, "yes", "y", false, "send without asking")
+	rootCmd.AddCommand(shareCmd)
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestBuildShareMessage(t *testing.T) {
+	receipt := email.NewMessage().From("shop@example.com").To("alice@example.com").
+		Subject("Your receipt").Body("Total: 7 EUR\r\n").Bytes()
+	long := append([]byte("Subject: Wide\r\n\r\n"), bytes.Repeat([]byte("x"), 1200)...)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	raw, err := buildShareMessage("alice@example.com", []string{"bob@example.com", "carol@example.com"},
+		"Reçus 2024", "See attached.\nAlice", []shareFile{
+			{Name: "1-Your receipt.eml", Raw: receipt},
+			{Name: "2-Wide.eml", Raw: long},
+		}, now)
+	if err != nil {
+		t.Fatalf("buildShareMessage: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if got := msg.Header.Get("To"); got != "bob@example.com, carol@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	dec := new(mime.WordDecoder)
+	if got, _ := dec.DecodeHeader(msg.Header.Get("Subject")); got != "Reçus 2024" {
+		t.Errorf("Subject = %q", got)
+	}
+	if got, _ := msg.Header.Date(); !got.Equal(now) {
+		t.Errorf("Date = %v, want %v", got, now)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, %v", msg.Header.Get("Content-Type"), err)
+	}
+
+	type part struct{ contentType, filename, body string }
+	var parts []part
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := r.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		body, err := io.ReadAll(p)
+		if err != nil {
+			t.Fatalf("read part: %v", err)
+		}
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(string(body), "\r\n", "")); err != nil {
+				t.Fatalf("decode part: %v", err)
+			}
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts = append(parts, part{ct, p.FileName(), string(body)})
+	}
+
+	want := []part{
+		{"text/plain", "", "See attached.\r\nAlice\r\n"},
+		{"message/rfc822", "1-Your receipt.eml", string(receipt)},
+		{"application/octet-stream", "2-Wide.eml", string(long)},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("got %d parts, want %d", len(parts), len(want))
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Errorf("part %d = %+v, want %+v", i, parts[i], want[i])
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -160,6 +161,27 @@ func outputMessageText(msg *query.MessageDetail) error {
 		fmt.Printf("Labels:  %s\n", strings.Join(msg.Labels, ", "))
 	}
 
+	// Language
+	if msg.Language != "" {
+		fmt.Printf("Lang:    %s (%s)\n", mime.LanguageName(msg.Language), msg.Language)
+	}
+
+	// Provenance
+	if msg.Auth != nil {
+		fmt.Printf("Auth:    %s\n", formatMessageAuth(msg.Auth))
+	}
+
+	// Local annotations
+	if msg.Pinned {
+		fmt.Println("Pinned:  yes")
+	}
+	if len(msg.Tags) > 0 {
+		fmt.Printf("Tags:    %s\n", strings.Join(msg.Tags, ", "))
+	}
+	if msg.Note != "" {
+		fmt.Printf("Note:    %s\n", strings.ReplaceAll(msg.Note, "\n", "\n         "))
+	}
+
 	// Attachments
 	if len(msg.Attachments) > 0 {
 		fmt.Println("\nAttachments:")
@@ -236,12 +258,60 @@ func outputMessageJSON(msg *query.MessageDetail) error {
 	if msg.ReceivedAt != nil {
 		output["received_at"] = msg.ReceivedAt.Format(time.RFC3339)
 	}
+	if msg.Language != "" {
+		output["language"] = msg.Language
+	}
+	if msg.Auth != nil {
+		output["auth"] = msg.Auth
+	}
+	if msg.Pinned {
+		output["pinned"] = true
+	}
+	if len(msg.Tags) > 0 {
+		output["tags"] = msg.Tags
+	}
+	if msg.Note != "" {
+		output["note"] = msg.Note
+	}
 
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
 	return enc.Encode(output)
 }
 
+// formatMessageAuth summarizes a message's provenance on one line, e.g.
+// "DKIM pass (example.com); reported by mx.google.com: dkim=pass
+// spf=pass dmarc=pass".
+func formatMessageAuth(a *query.MessageAuth) string {
+	var parts []string
+	if a.DKIM != "" {
+		s := "DKIM " + a.DKIM
+		if a.DKIMDomain != "" {
+			s += " (" + a.DKIMDomain + ")"
+		}
+		if a.DKIMReason != "" {
+			s += ": " + a.DKIMReason
+		}
+		parts = append(parts, s)
+	}
+	var reported []string
+	for _, r := range []struct{ method, result string }{
+		{"dkim", a.ReportedDKIM}, {"spf", a.ReportedSPF}, {"dmarc", a.ReportedDMARC},
+	} {
+		if r.result != "" {
+			reported = append(reported, r.method+"="+r.result)
+		}
+	}
+	if len(reported) > 0 {
+		by := "reported"
+		if a.AuthServID != "" {
+			by += " by " + a.AuthServID
+		}
+		parts = append(parts, by+": "+strings.Join(reported, " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
 func formatAddresses(addrs []query.Address) string {
 	parts := make([]string, len(addrs))
 	for i, addr := range addrs {
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/sync"
+)
+
+// The built-in sync sources. All speak gmail.API; buildAPIClient
+// resolves their credentials from the config and token store.
+func init() {
+	sync.Register("gmail", sync.Driver{
+		Capabilities: sync.GmailCapabilities,
+		Open:         openAPISource(sync.GmailCapabilities),
+	})
+	sync.Register("imap", sync.Driver{
+		Capabilities: sync.IMAPCapabilities,
+		Open:         openAPISource(sync.IMAPCapabilities),
+	})
+	sync.Register("outlook", sync.Driver{
+		Capabilities: sync.GraphCapabilities,
+		Open:         openAPISource(sync.GraphCapabilities),
+	})
+}
+
+func openAPISource(caps sync.Capabilities) func(context.Context, *store.Source) (sync.Source, error) {
+	return func(ctx context.Context, src *store.Source) (sync.Source, error) {
+		client, err := buildAPIClient(ctx, src, oauthManagerCache(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return sync.FromAPI(client, caps), nil
+	}
+}
+
+// isSyncable reports whether sources of this type can be synced.
+func isSyncable(sourceType string) bool {
+	_, ok := sync.Lookup(sourceType)
+	return ok
+}
+
+// sourceTypeLabel returns how a source type is written in messages.
+func sourceTypeLabel(sourceType string) string {
+	switch sourceType {
+	case "imap":
+		return "IMAP"
+	case "gmail":
+		return "Gmail"
+	case "outlook":
+		return "Outlook"
+	default:
+		return sourceType
+	}
+}
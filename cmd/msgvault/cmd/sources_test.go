@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/sync"
+)
+
+func TestBuiltinSourceDrivers(t *testing.T) {
+	for _, tt := range []struct {
+		sourceType string
+		want       sync.Capabilities
+	}{
+		{"gmail", sync.GmailCapabilities},
+		{"imap", sync.IMAPCapabilities},
+	} {
+		d, ok := sync.Lookup(tt.sourceType)
+		if !ok {
+			t.Errorf("%s: no driver registered", tt.sourceType)
+			continue
+		}
+		if d.Capabilities != tt.want {
+			t.Errorf("%s: capabilities = %+v, want %+v", tt.sourceType, d.Capabilities, tt.want)
+		}
+	}
+	for _, typ := range []string{"mbox", "apple-mail", "whatsapp"} {
+		if isSyncable(typ) {
+			t.Errorf("isSyncable(%q) = true, want false for import-only sources", typ)
+		}
+	}
+
+	// Opening goes through buildAPIClient, which reports missing config.
+	_, err := sync.Open(context.Background(), &store.Source{SourceType: "imap", Identifier: "alice@example.com"})
+	if err == nil || !strings.Contains(err.Error(), "has no config") {
+		t.Errorf("Open(imap without config) error = %v", err)
+	}
+}
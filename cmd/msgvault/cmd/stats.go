@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/store"
 )
 
@@ -82,20 +84,23 @@ Use --local to force local database.`,
 				"db_bytes", dbStats.DatabaseSize,
 			)
 
+			if jsonOutput {
+				out := newStatsOutput(dbStats)
+				out.Account, out.Collection = statsAccount, statsCollection
+				return printJSON(out)
+			}
 			if statsAccount != "" {
-				fmt.Printf("Stats for account %q:\n", scope.DisplayName())
+				fmt.Println(i18n.T("Stats for account %q:", scope.DisplayName()))
 			} else {
-				n := len(sourceIDs)
-				suffix := "s"
-				if n == 1 {
-					suffix = ""
+				if n := len(sourceIDs); n == 1 {
+					fmt.Println(i18n.T("Stats for collection %q (%d account):", scope.DisplayName(), n))
+				} else {
+					fmt.Println(i18n.T("Stats for collection %q (%d accounts):", scope.DisplayName(), n))
 				}
-				fmt.Printf("Stats for collection %q (%d account%s):\n",
-					scope.DisplayName(), n, suffix)
 			}
 
 			printStats(dbStats)
-			fmt.Printf("\nNote: Size is global (not scoped).\n")
+			fmt.Println("\n" + i18n.T("Note: Size is global (not scoped)."))
 			return nil
 		}
 
@@ -130,10 +135,19 @@ Use --local to force local database.`,
 			"db_bytes", dbStats.DatabaseSize,
 		)
 
+		if jsonOutput {
+			out := newStatsOutput(dbStats)
+			if IsRemoteMode() {
+				out.Remote = cfg.Remote.URL
+			} else {
+				out.Database = cfg.DatabaseDSN()
+			}
+			return printJSON(out)
+		}
 		if IsRemoteMode() {
-			fmt.Printf("Remote: %s\n", cfg.Remote.URL)
+			fmt.Println(i18n.T("Remote: %s", cfg.Remote.URL))
 		} else {
-			fmt.Printf("Database: %s\n", cfg.DatabaseDSN())
+			fmt.Println(i18n.T("Database: %s", cfg.DatabaseDSN()))
 		}
 
 		printStats(dbStats)
@@ -141,13 +155,50 @@ Use --local to force local database.`,
 	},
 }
 
+// statsOutput is the --json form of 'msgvault stats'. Scoped stats set
+// Account or Collection; database_bytes is always archive-wide.
+type statsOutput struct {
+	Database      string `json:"database,omitempty"`
+	Remote        string `json:"remote,omitempty"`
+	Account       string `json:"account,omitempty"`
+	Collection    string `json:"collection,omitempty"`
+	Messages      int64  `json:"messages"`
+	Threads       int64  `json:"threads"`
+	Attachments   int64  `json:"attachments"`
+	Labels        int64  `json:"labels"`
+	Accounts      int64  `json:"accounts"`
+	DatabaseBytes int64  `json:"database_bytes"`
+}
+
+func newStatsOutput(s *store.Stats) *statsOutput {
+	return &statsOutput{
+		Messages:      s.MessageCount,
+		Threads:       s.ThreadCount,
+		Attachments:   s.AttachmentCount,
+		Labels:        s.LabelCount,
+		Accounts:      s.SourceCount,
+		DatabaseBytes: s.DatabaseSize,
+	}
+}
+
 func printStats(s *store.Stats) {
-	fmt.Printf("  Messages:    %d\n", s.MessageCount)
-	fmt.Printf("  Threads:     %d\n", s.ThreadCount)
-	fmt.Printf("  Attachments: %d\n", s.AttachmentCount)
-	fmt.Printf("  Labels:      %d\n", s.LabelCount)
-	fmt.Printf("  Accounts:    %d\n", s.SourceCount)
-	fmt.Printf("  Size:        %.2f MB\n", float64(s.DatabaseSize)/(1024*1024))
+	rows := [][2]string{
+		{i18n.T("Messages:"), i18n.Number(s.MessageCount)},
+		{i18n.T("Threads:"), i18n.Number(s.ThreadCount)},
+		{i18n.T("Attachments:"), i18n.Number(s.AttachmentCount)},
+		{i18n.T("Labels:"), i18n.Number(s.LabelCount)},
+		{i18n.T("Accounts:"), i18n.Number(s.SourceCount)},
+		{i18n.T("Size:"), i18n.Decimal(float64(s.DatabaseSize)/(1024*1024), 2) + " MB"},
+	}
+	// Translated labels differ in length; align the values after the
+	// longest one.
+	width := 0
+	for _, r := range rows {
+		width = max(width, utf8.RuneCountInString(r[0]))
+	}
+	for _, r := range rows {
+		fmt.Printf("  %-*s %s\n", width, r[0], r[1])
+	}
 }
 
 func init() {
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var statsFTSCmd = &cobra.Command{
+	Use:   "fts",
+	Short: "Show full-text search index statistics",
+	Long: `Show how much of the archive the full-text search index covers, how
+much space it takes, and the newest message in it. Messages outside the
+index are missing from search results; build it with
+'msgvault index rebuild --fts'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("stats fts"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open store: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		st, err := s.FTSStats()
+		if err != nil {
+			return fmt.Errorf("get search index stats: %w", err)
+		}
+		out := newFTSStatsOutput(st, s.NeedsFTSBackfill())
+		if jsonOutput {
+			return printJSON(out)
+		}
+		printFTSStats(out)
+		return nil
+	},
+}
+
+// ftsStatsOutput is the --json form of 'msgvault stats fts'.
+type ftsStatsOutput struct {
+	// Status is "unavailable", "incomplete", or "up to date".
+	Status        string     `json:"status"`
+	Messages      int64      `json:"messages"`
+	Indexed       int64      `json:"indexed"`
+	Coverage      float64    `json:"coverage"`
+	SizeBytes     int64      `json:"size_bytes"`
+	LastIndexedID int64      `json:"last_indexed_id,omitempty"`
+	LastIndexedAt *time.Time `json:"last_indexed_at,omitempty"`
+}
+
+func newFTSStatsOutput(st *store.FTSStats, needsBackfill bool) *ftsStatsOutput {
+	out := &ftsStatsOutput{
+		Status:        "up to date",
+		Messages:      st.MessageCount,
+		Indexed:       st.IndexedCount,
+		SizeBytes:     st.SizeBytes,
+		LastIndexedID: st.LastIndexedID,
+	}
+	switch {
+	case !st.Available:
+		out.Status = "unavailable"
+	case needsBackfill:
+		out.Status = "incomplete"
+	}
+	if st.Available {
+		out.Coverage = st.Coverage()
+	}
+	if !st.LastIndexedAt.IsZero() {
+		t := st.LastIndexedAt
+		out.LastIndexedAt = &t
+	}
+	return out
+}
+
+func printFTSStats(out *ftsStatsOutput) {
+	if out.Status == "unavailable" {
+		fmt.Println(i18n.T("Full-text search is unavailable (FTS5 not compiled in)."))
+		return
+	}
+	status := i18n.T("up to date")
+	if out.Status == "incomplete" {
+		status = i18n.T("incomplete")
+	}
+	last := i18n.T("none")
+	if out.LastIndexedID != 0 {
+		last = "#" + fmt.Sprint(out.LastIndexedID)
+		if out.LastIndexedAt != nil {
+			last += ", " + i18n.T("sent %s", i18n.Date(*out.LastIndexedAt))
+		}
+	}
+	rows := [][2]string{
+		{i18n.T("Status:"), status},
+		{i18n.T("Indexed:"), i18n.T("%s of %s messages (%s%%)",
+			i18n.Number(out.Indexed), i18n.Number(out.Messages), i18n.Decimal(out.Coverage*100, 1))},
+		{i18n.T("Size:"), i18n.Decimal(float64(out.SizeBytes)/(1024*1024), 2) + " MB"},
+		{i18n.T("Last indexed:"), last},
+	}
+	width := 0
+	for _, r := range rows {
+		width = max(width, utf8.RuneCountInString(r[0]))
+	}
+	for _, r := range rows {
+		fmt.Printf("  %-*s %s\n", width, r[0], r[1])
+	}
+	if out.Status == "incomplete" {
+		fmt.Println("\n" + i18n.T("Run '%s' to index the rest.", ftsRebuildHint))
+	}
+}
+
+func init() {
+	statsCmd.AddCommand(statsFTSCmd)
+}
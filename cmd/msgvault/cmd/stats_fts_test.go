@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestNewFTSStatsOutput(t *testing.T) {
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		stats         store.FTSStats
+		needsBackfill bool
+		wantStatus    string
+		wantCoverage  float64
+		wantLastAt    bool
+	}{
+		{
+			name:       "unavailable",
+			stats:      store.FTSStats{},
+			wantStatus: "unavailable",
+		},
+		{
+			name: "up to date",
+			stats: store.FTSStats{
+				Available: true, MessageCount: 4, IndexedCount: 4,
+				LastIndexedID: 9, LastIndexedAt: sent,
+			},
+			wantStatus:   "up to date",
+			wantCoverage: 1,
+			wantLastAt:   true,
+		},
+		{
+			name: "incomplete",
+			stats: store.FTSStats{
+				Available: true, MessageCount: 4, IndexedCount: 1, LastIndexedID: 2,
+			},
+			needsBackfill: true,
+			wantStatus:    "incomplete",
+			wantCoverage:  0.25,
+		},
+		{
+			name:         "empty archive",
+			stats:        store.FTSStats{Available: true},
+			wantStatus:   "up to date",
+			wantCoverage: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := newFTSStatsOutput(&tt.stats, tt.needsBackfill)
+			if out.Status != tt.wantStatus {
+				t.Errorf("Status = %q, want %q", out.Status, tt.wantStatus)
+			}
+			if out.Coverage != tt.wantCoverage {
+				t.Errorf("Coverage = %v, want %v", out.Coverage, tt.wantCoverage)
+			}
+			if (out.LastIndexedAt != nil) != tt.wantLastAt {
+				t.Errorf("LastIndexedAt = %v, want set %v", out.LastIndexedAt, tt.wantLastAt)
+			}
+			if out.LastIndexedID != tt.stats.LastIndexedID {
+				t.Errorf("LastIndexedID = %d, want %d", out.LastIndexedID, tt.stats.LastIndexedID)
+			}
+		})
+	}
+}
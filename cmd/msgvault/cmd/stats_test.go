@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/store"
 )
 
@@ -101,3 +103,82 @@ func TestStatsCommand_EmptyCollectionRejected(t *testing.T) {
 		t.Errorf("error message = %q, want substring \"no member accounts\"", err.Error())
 	}
 }
+
+// TestStatsCommand_JSON verifies that the global --json flag switches
+// stats to a single JSON document with stable snake_case fields.
+func TestStatsCommand_JSON(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	savedCfg := cfg
+	savedLogger := logger
+	savedJSON := jsonOutput
+	defer func() {
+		cfg = savedCfg
+		logger = savedLogger
+		jsonOutput = savedJSON
+	}()
+
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	st, err := openLocalStoreAndInit()
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if _, err := st.GetOrCreateSource("gmail", "alice@example.com"); err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	_ = st.Close()
+
+	testCmd := &cobra.Command{Use: "stats", RunE: statsCmd.RunE}
+	root := newTestRootCmd()
+	root.PersistentFlags().BoolVar(&jsonOutput, "json", false, "")
+	root.AddCommand(testCmd)
+	root.SetArgs([]string{"stats", "--json"})
+
+	getOutput := captureStdout(t)
+	execErr := root.Execute()
+	output := getOutput()
+	if execErr != nil {
+		t.Fatalf("stats: %v", execErr)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	for _, key := range []string{"database", "messages", "threads", "attachments", "labels", "accounts", "database_bytes"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %q in %s", key, output)
+		}
+	}
+	if got["accounts"] != float64(1) {
+		t.Errorf("accounts = %v, want 1", got["accounts"])
+	}
+}
+
+func TestPrintStats_Localized(t *testing.T) {
+	s := &store.Stats{MessageCount: 12345, ThreadCount: 3, AttachmentCount: 7, LabelCount: 2, SourceCount: 1, DatabaseSize: 3 * 1024 * 1024 / 2}
+
+	getOutput := captureStdout(t)
+	printStats(s)
+	if got := getOutput(); !strings.Contains(got, "  Messages:    12,345\n") || !strings.Contains(got, "  Size:        1.50 MB\n") {
+		t.Errorf("English stats:\n%s", got)
+	}
+
+	if err := i18n.Set("de"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = i18n.Set("en") })
+	getOutput = captureStdout(t)
+	printStats(s)
+	got := getOutput()
+	for _, want := range []string{"  Nachrichten:    12.345\n", "  Unterhaltungen: 3\n", "  Größe:          1,50 MB\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("German stats missing %q:\n%s", want, got)
+		}
+	}
+}
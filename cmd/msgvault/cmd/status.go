@@ -0,0 +1,364 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/deletion"
+	"github.com/wesm/msgvault/internal/oplock"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show an overview of the vault's health",
+	Long: `Show one screen summarizing the local vault: storage sizes, encryption,
+each account's last sync and message counts, how much space deduplicated
+attachments and compressed raw MIME save, how much of the archive the
+full-text index covers, whether the analytics cache is current, and deletion
+batches awaiting execution.
+
+Status always inspects the local vault, even when [remote].url is set.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		st, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open store: %w", err)
+		}
+		defer func() { _ = st.Close() }()
+
+		status, err := collectVaultStatus(st)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			return printJSON(status)
+		}
+		printVaultStatus(status, time.Now())
+		return nil
+	},
+}
+
+// vaultStatus is everything 'msgvault status' reports.
+type vaultStatus struct {
+	Profile          string          `json:"profile,omitempty"`
+	ConfigPath       string          `json:"config_path"`
+	Database         string          `json:"database"`
+	DatabaseBytes    int64           `json:"database_bytes"`
+	AttachmentsDir   string          `json:"attachments_dir"`
+	AttachmentsBytes int64           `json:"attachments_bytes"`
+	Encryption       string          `json:"encryption"`
+	Accounts         []accountStatus `json:"accounts"`
+	Storage          storageStatus   `json:"storage"`
+	FTS              string          `json:"fts"`
+	Analytics        string          `json:"analytics"`
+	AnalyticsBuiltAt *time.Time      `json:"analytics_built_at,omitempty"`
+	PendingDeletions int             `json:"pending_deletions"`
+	RunningDeletions int             `json:"running_deletions"`
+	Operation        *oplock.Holder  `json:"operation,omitempty"` // holder of the vault lock
+}
+
+// accountStatus is one source's line in 'msgvault status'.
+type accountStatus struct {
+	Account         string     `json:"account"`
+	Type            string     `json:"type"`
+	Messages        int64      `json:"messages"`
+	Attachments     int64      `json:"attachments"`
+	AttachmentBytes int64      `json:"attachment_bytes"`
+	RawMIMEBytes    int64      `json:"raw_mime_bytes"`
+	Completeness    *float64   `json:"completeness,omitempty"` // archived fraction of what the provider reports
+	LastSuccess     *time.Time `json:"last_successful_sync,omitempty"`
+	LastRunStatus   string     `json:"last_run_status,omitempty"` // completed, failed, running
+	LastError       string     `json:"last_error,omitempty"`
+}
+
+// storageStatus is how the vault's live messages use space, for
+// capacity planning.
+type storageStatus struct {
+	Attachments                 int64    `json:"attachments"`
+	UniqueAttachments           int64    `json:"unique_attachments"`
+	DuplicateAttachments        int64    `json:"duplicate_attachments"`
+	AttachmentBytes             int64    `json:"attachment_bytes"`               // every attachment counted
+	UniqueAttachmentBytes       int64    `json:"unique_attachment_bytes"`        // each stored file once
+	UniqueAttachmentStoredBytes int64    `json:"unique_attachment_stored_bytes"` // after compression
+	RawMIMEMessages             int64    `json:"raw_mime_messages"`
+	RawMIMELogicalBytes         int64    `json:"raw_mime_logical_bytes"` // as received
+	RawMIMEStoredBytes          int64    `json:"raw_mime_stored_bytes"`  // after compression
+	FTSIndexed                  int64    `json:"fts_indexed"`
+	FTSCoverage                 *float64 `json:"fts_coverage,omitempty"` // fraction indexed; nil without FTS
+}
+
+func collectVaultStatus(st *store.Store) (*vaultStatus, error) {
+	vs := &vaultStatus{
+		Profile:        activeProfile,
+		ConfigPath:     cfg.ConfigFilePath(),
+		Database:       cfg.DatabaseDSN(),
+		AttachmentsDir: cfg.AttachmentsDir(),
+		// msgvault has no at-rest encryption of its own; say so rather
+		// than leave users guessing.
+		Encryption: "off",
+		Accounts:   []accountStatus{},
+	}
+
+	if dbPath, err := cfg.DatabasePath(); err == nil {
+		for _, p := range []string{dbPath, dbPath + "-wal"} {
+			if info, err := os.Stat(p); err == nil {
+				vs.DatabaseBytes += info.Size()
+			}
+		}
+	}
+	size, err := dirSize(vs.AttachmentsDir)
+	if err != nil {
+		return nil, fmt.Errorf("measure attachments: %w", err)
+	}
+	vs.AttachmentsBytes = size
+
+	sources, err := st.ListSources("")
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+	counts, err := st.GetSourceStats()
+	if err != nil {
+		return nil, fmt.Errorf("count messages: %w", err)
+	}
+	for _, src := range sources {
+		as := accountStatus{Account: src.Identifier, Type: src.SourceType}
+		if c := counts[src.ID]; c != nil {
+			as.Messages, as.Attachments, as.AttachmentBytes = c.MessageCount, c.AttachmentCount, c.AttachmentBytes
+			as.RawMIMEBytes = c.RawStoredBytes
+		}
+		remote, ok, err := st.SourceRemoteTotal(src.ID)
+		if err != nil {
+			return nil, fmt.Errorf("remote total for %s: %w", src.Identifier, err)
+		}
+		if ok && remote > 0 {
+			score := min(float64(as.Messages)/float64(remote), 1)
+			as.Completeness = &score
+		}
+		last, err := st.GetLastSuccessfulSync(src.ID)
+		if err != nil {
+			return nil, fmt.Errorf("last sync for %s: %w", src.Identifier, err)
+		}
+		if last != nil && last.CompletedAt.Valid {
+			t := last.CompletedAt.Time
+			as.LastSuccess = &t
+		}
+		latest, err := st.GetLatestSync(src.ID)
+		if err != nil {
+			return nil, fmt.Errorf("latest sync for %s: %w", src.Identifier, err)
+		}
+		if latest != nil {
+			as.LastRunStatus = latest.Status
+			if latest.Status == store.SyncStatusFailed && latest.ErrorMessage.Valid {
+				as.LastError = latest.ErrorMessage.String
+			}
+		}
+		vs.Accounts = append(vs.Accounts, as)
+	}
+
+	stats, err := st.GetStats()
+	if err != nil {
+		return nil, fmt.Errorf("get stats: %w", err)
+	}
+	vs.Storage = storageStatus{
+		Attachments:                 stats.AttachmentCount,
+		UniqueAttachments:           stats.UniqueAttachmentCount,
+		DuplicateAttachments:        stats.DuplicateAttachmentCount(),
+		AttachmentBytes:             stats.AttachmentBytes,
+		UniqueAttachmentBytes:       stats.UniqueAttachmentBytes,
+		UniqueAttachmentStoredBytes: stats.UniqueAttachmentStoredBytes,
+		RawMIMEMessages:             stats.RawMessageCount,
+		RawMIMELogicalBytes:         stats.RawLogicalBytes,
+		RawMIMEStoredBytes:          stats.RawStoredBytes,
+		FTSIndexed:                  stats.FTSIndexedCount,
+	}
+	if st.FTS5Available() {
+		coverage := stats.FTSCoverage()
+		vs.Storage.FTSCoverage = &coverage
+	}
+
+	switch {
+	case !st.FTS5Available():
+		vs.FTS = "unavailable (FTS5 not compiled in)"
+	case st.NeedsFTSBackfill():
+		vs.FTS = "incomplete (run '" + ftsRebuildHint + "')"
+	default:
+		vs.FTS = "up to date"
+	}
+
+	analyticsDir := cfg.AnalyticsDir()
+	if state, err := readCacheState(analyticsDir); err == nil {
+		t := state.LastSyncAt
+		vs.AnalyticsBuiltAt = &t
+	}
+	switch staleness := cacheNeedsBuild(cfg.DatabaseDSN(), analyticsDir); {
+	case !staleness.NeedsBuild:
+		vs.Analytics = "up to date"
+	case vs.AnalyticsBuiltAt == nil:
+		vs.Analytics = "not built (run 'msgvault build-cache')"
+	default:
+		vs.Analytics = "stale: " + staleness.Reason + " (run 'msgvault build-cache')"
+	}
+
+	mgr, err := deletion.NewManager(filepath.Join(cfg.Data.DataDir, "deletions"))
+	if err != nil {
+		return nil, fmt.Errorf("open deletions: %w", err)
+	}
+	pending, err := mgr.ListPending()
+	if err != nil {
+		return nil, fmt.Errorf("list pending deletions: %w", err)
+	}
+	running, err := mgr.ListInProgress()
+	if err != nil {
+		return nil, fmt.Errorf("list in-progress deletions: %w", err)
+	}
+	vs.PendingDeletions, vs.RunningDeletions = len(pending), len(running)
+
+	holder, err := oplock.Current(cfg.Data.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("check vault lock: %w", err)
+	}
+	vs.Operation = holder
+	return vs, nil
+}
+
+func printVaultStatus(vs *vaultStatus, now time.Time) {
+	if vs.Profile != "" {
+		fmt.Printf("Profile:     %s\n", vs.Profile)
+	}
+	fmt.Printf("Config:      %s\n", vs.ConfigPath)
+	fmt.Printf("Database:    %s (%s)\n", vs.Database, formatSize(vs.DatabaseBytes))
+	fmt.Printf("Attachments: %s (%s)\n", vs.AttachmentsDir, formatSize(vs.AttachmentsBytes))
+	fmt.Printf("Encryption:  %s (use an encrypted volume to protect the vault)\n", vs.Encryption)
+	if IsRemoteMode() {
+		fmt.Printf("Remote:      %s (not shown; status covers the local vault)\n", cfg.Remote.URL)
+	}
+
+	fmt.Println()
+	if len(vs.Accounts) == 0 {
+		fmt.Println("No accounts. Use 'msgvault add-account <email>' to add one.")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ACCOUNT\tTYPE\tMESSAGES\tATTACHMENTS\tRAW MIME\tLAST SYNC")
+		for _, a := range vs.Accounts {
+			lastSync := "never"
+			if a.LastSuccess != nil {
+				lastSync = a.LastSuccess.Local().Format("2006-01-02 15:04") + " (" + formatAgo(*a.LastSuccess, now) + ")"
+			}
+			switch a.LastRunStatus {
+			case store.SyncStatusRunning:
+				lastSync += ", syncing now"
+			case store.SyncStatusFailed:
+				lastSync += ", last run failed"
+			}
+			messages := formatCount(a.Messages)
+			if a.Completeness != nil {
+				messages += fmt.Sprintf(" (%s)", formatPercent(*a.Completeness))
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s (%s)\t%s\t%s\n",
+				a.Account, a.Type, messages,
+				formatCount(a.Attachments), formatSize(a.AttachmentBytes),
+				formatSize(a.RawMIMEBytes), lastSync)
+		}
+		_ = w.Flush()
+		for _, a := range vs.Accounts {
+			if a.LastError != "" {
+				fmt.Printf("  %s: %s\n", a.Account, a.LastError)
+			}
+		}
+	}
+
+	fmt.Println()
+	sg := vs.Storage
+	fmt.Printf("Attachments:     %s (%s) in %s unique files (%s), %s duplicates",
+		formatCount(sg.Attachments), formatSize(sg.AttachmentBytes),
+		formatCount(sg.UniqueAttachments), formatSize(sg.UniqueAttachmentBytes),
+		formatCount(sg.DuplicateAttachments))
+	if sg.UniqueAttachmentStoredBytes < sg.UniqueAttachmentBytes {
+		fmt.Printf(", %s compressed", formatSize(sg.UniqueAttachmentStoredBytes))
+	}
+	fmt.Println()
+	fmt.Printf("Raw MIME:        %s messages (%s), %s compressed\n",
+		formatCount(sg.RawMIMEMessages), formatSize(sg.RawMIMELogicalBytes), formatSize(sg.RawMIMEStoredBytes))
+	fts := vs.FTS
+	if sg.FTSCoverage != nil {
+		fts += fmt.Sprintf(", %.1f%% of messages indexed", *sg.FTSCoverage*100)
+	}
+	fmt.Printf("Full-text index: %s\n", fts)
+	analytics := vs.Analytics
+	if vs.AnalyticsBuiltAt != nil {
+		analytics += ", built " + formatAgo(*vs.AnalyticsBuiltAt, now)
+	}
+	fmt.Printf("Analytics cache: %s\n", analytics)
+	deletions := "none pending"
+	if vs.PendingDeletions > 0 || vs.RunningDeletions > 0 {
+		deletions = fmt.Sprintf("%d pending, %d in progress (see 'msgvault list-deletions')",
+			vs.PendingDeletions, vs.RunningDeletions)
+	}
+	fmt.Printf("Deletions:       %s\n", deletions)
+	if vs.Operation != nil {
+		fmt.Printf("Running:         %s\n", vs.Operation)
+	}
+}
+
+// readCacheState loads the analytics cache's build record.
+func readCacheState(analyticsDir string) (*syncState, error) {
+	data, err := os.ReadFile(filepath.Join(analyticsDir, "_last_sync.json"))
+	if err != nil {
+		return nil, err
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("parse cache state: %w", err)
+	}
+	return &state, nil
+}
+
+// dirSize sums the sizes of regular files under dir. A missing
+// directory has size zero.
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return total, err
+}
+
+// formatAgo renders how long before now t was, coarsely.
+func formatAgo(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/deletion"
+	"github.com/wesm/msgvault/internal/oplock"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+func TestVaultStatus(t *testing.T) {
+	savedCfg := cfg
+	defer func() { cfg = savedCfg }()
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+
+	st, err := openLocalStoreAndInit()
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	alice, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	if _, err := st.GetOrCreateSource("imap", "bob@example.com"); err != nil {
+		t.Fatalf("create source: %v", err)
+	}
+	convID, err := st.EnsureConversation(alice.ID, "thread-1", "Hello")
+	if err != nil {
+		t.Fatalf("conversation: %v", err)
+	}
+	msgID, err := st.UpsertMessage(&store.Message{
+		ConversationID: convID, SourceID: alice.ID, SourceMessageID: "m1", MessageType: "email",
+	})
+	if err != nil {
+		t.Fatalf("message: %v", err)
+	}
+	if err := st.UpsertAttachment(msgID, "a.pdf", "application/pdf", "ab/abcd", "abcd", 2048); err != nil {
+		t.Fatalf("attachment: %v", err)
+	}
+	syncID, err := st.StartSync(alice.ID, "full")
+	if err != nil {
+		t.Fatalf("start sync: %v", err)
+	}
+	if err := st.CompleteSync(syncID, "1"); err != nil {
+		t.Fatalf("complete sync: %v", err)
+	}
+	syncID, err = st.StartSync(alice.ID, "incremental")
+	if err != nil {
+		t.Fatalf("start sync: %v", err)
+	}
+	if err := st.FailSync(syncID, "token revoked"); err != nil {
+		t.Fatalf("fail sync: %v", err)
+	}
+
+	attDir := filepath.Join(cfg.AttachmentsDir(), "ab")
+	if err := os.MkdirAll(attDir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(attDir, "abcd"), make([]byte, 2048), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mgr, err := deletion.NewManager(filepath.Join(tmpDir, "deletions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mgr.CreateManifest("old newsletters", []string{"g1"}, deletion.Filters{}); err != nil {
+		t.Fatalf("create manifest: %v", err)
+	}
+
+	lock, err := oplock.TryAcquire(tmpDir, "sync alice@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = lock.Release() }()
+
+	vs, err := collectVaultStatus(st)
+	if err != nil {
+		t.Fatalf("collectVaultStatus: %v", err)
+	}
+	if vs.AttachmentsBytes != 2048 || vs.DatabaseBytes == 0 {
+		t.Errorf("sizes: attachments %d, database %d", vs.AttachmentsBytes, vs.DatabaseBytes)
+	}
+	if vs.PendingDeletions != 1 {
+		t.Errorf("PendingDeletions = %d, want 1", vs.PendingDeletions)
+	}
+	if vs.Operation == nil || vs.Operation.Operation != "sync alice@example.com" {
+		t.Errorf("Operation = %+v, want the held sync", vs.Operation)
+	}
+	if len(vs.Accounts) != 2 {
+		t.Fatalf("accounts = %+v", vs.Accounts)
+	}
+	var a accountStatus
+	for _, acc := range vs.Accounts {
+		if acc.Account == "alice@example.com" {
+			a = acc
+		}
+	}
+	if a.Messages != 1 || a.Attachments != 1 || a.AttachmentBytes != 2048 {
+		t.Errorf("alice counts = %+v", a)
+	}
+	if a.LastSuccess == nil || a.LastRunStatus != store.SyncStatusFailed || a.LastError != "token revoked" {
+		t.Errorf("alice sync = %+v", a)
+	}
+	if sg := vs.Storage; sg.Attachments != 1 || sg.UniqueAttachments != 1 || sg.DuplicateAttachments != 0 || sg.AttachmentBytes != 2048 {
+		t.Errorf("storage = %+v, want one unique 2048-byte attachment", sg)
+	}
+
+	done := captureStdout(t)
+	printVaultStatus(vs, time.Now())
+	out := done()
+	for _, want := range []string{"alice@example.com", "last run failed", "token revoked", "1 pending", "Encryption:  off", "never", "Running:         sync alice@example.com", "1 unique files"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestFormatAgo(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{72 * time.Hour, "3d ago"},
+	}
+	for _, tt := range tests {
+		if got := formatAgo(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("formatAgo(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
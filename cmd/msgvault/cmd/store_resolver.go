@@ -3,8 +3,11 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/wesm/msgvault/internal/mailauth"
+	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/remote"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -117,12 +120,12 @@ func OpenStore() (MessageStore, error) {
 // Unlike OpenStore, this always attempts remote connection.
 func OpenRemoteStore() (RemoteStore, error) {
 	if cfg.Remote.URL == "" {
-		return nil, fmt.Errorf("remote server not configured\n\n" +
-			"Configure in ~/.msgvault/config.toml:\n" +
-			"  [remote]\n" +
-			"  url = \"http://nas:8080\"\n" +
-			"  api_key = \"your-api-key\"\n" +
-			"  allow_insecure = true  # for trusted networks")
+		return nil, fmt.Errorf("remote server not configured\n\n"+
+			"Configure in %s:\n"+
+			"  [remote]\n"+
+			"  url = \"http://nas:8080\"\n"+
+			"  api_key = \"your-api-key\"\n"+
+			"  allow_insecure = true  # for trusted networks", cfg.ConfigFilePath())
 	}
 	return openRemoteStore()
 }
@@ -152,14 +155,60 @@ func openLocalStoreAndInit() (*store.Store, error) {
 	return s, nil
 }
 
+// applyParseConfig sets how s parses the messages it stores, from the
+// [parse] config section, and how it compresses and where it keeps
+// their raw data, from [data] raw_compression and raw_blob_store.
+// Commands that ingest or re-parse mail call it right after opening the
+// store.
+func applyParseConfig(s *store.Store) {
+	s.SetFoldReplies(cfg.Parse.FoldReplies)
+	s.SetIndexedHeaders(cfg.Parse.IndexHeaders)
+	s.SetRawCompression(rawCompressionCodec(cfg.Data.RawCompression))
+	s.SetRawBlobStore(cfg.Data.RawBlobStore)
+	if cfg.Parse.VerifyDKIM {
+		s.SetDKIMVerifier(mailauth.NewVerifier(nil))
+	}
+}
+
+// rawCompressionCodec normalizes a [data] raw_compression setting; the
+// empty setting means the default.
+func rawCompressionCodec(setting string) string {
+	if codec := strings.ToLower(strings.TrimSpace(setting)); codec != "" {
+		return codec
+	}
+	return store.RawCompressionZlib
+}
+
 // openRemoteStore creates a remote store client.
 func openRemoteStore() (*remote.Store, error) {
-	return remote.New(remote.Config{
+	return remote.New(remoteConfig())
+}
+
+func remoteConfig() remote.Config {
+	return remote.Config{
 		URL:           cfg.Remote.URL,
 		APIKey:        cfg.Remote.APIKey,
 		AllowInsecure: cfg.Remote.AllowInsecure,
 		Timeout:       30 * time.Second,
-	})
+	}
+}
+
+// OpenQueryEngine returns a query engine for read-only commands: the
+// remote server's engine in remote mode, otherwise a SQLite engine over
+// the local database. The returned function releases it.
+func OpenQueryEngine() (query.Engine, func(), error) {
+	if IsRemoteMode() {
+		e, err := remote.NewEngine(remoteConfig())
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect to remote: %w", err)
+		}
+		return e, func() { _ = e.Close() }, nil
+	}
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return nil, nil, fmt.Errorf("open database: %w", err)
+	}
+	return query.NewSQLiteEngine(s.DB()), func() { _ = s.Close() }, nil
 }
 
 // MustBeLocal returns an error if remote mode is active.
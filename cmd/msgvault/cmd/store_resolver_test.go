@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/query"
+)
+
+// TestOpenQueryEngine_RoutesByMode verifies that read-only commands
+// aggregate against the configured remote server, and fall back to the
+// local database when --local is set.
+func TestOpenQueryEngine_RoutesByMode(t *testing.T) {
+	var gotKey, gotView string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/aggregates" {
+			http.NotFound(w, r)
+			return
+		}
+		gotKey = r.Header.Get("X-API-Key")
+		gotView = r.URL.Query().Get("view_type")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"view_type":"senders","rows":[{"key":"alice@example.com","count":3}]}`))
+	}))
+	defer srv.Close()
+
+	savedCfg, savedLogger, savedLocal := cfg, logger, useLocal
+	defer func() { cfg, logger, useLocal = savedCfg, savedLogger, savedLocal }()
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	tmpDir := t.TempDir()
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+		Remote:  config.RemoteConfig{URL: srv.URL, APIKey: "secret", AllowInsecure: true},
+	}
+
+	tests := []struct {
+		name      string
+		local     bool
+		wantRows  int
+		wantCalls bool
+	}{
+		{"remote", false, 1, true},
+		{"local override", true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey, gotView = "", ""
+			useLocal = tt.local
+
+			engine, closeEngine, err := OpenQueryEngine()
+			if err != nil {
+				t.Fatalf("OpenQueryEngine: %v", err)
+			}
+			defer closeEngine()
+
+			rows, err := engine.Aggregate(context.Background(), query.ViewSenders, query.DefaultAggregateOptions())
+			if err != nil {
+				t.Fatalf("Aggregate: %v", err)
+			}
+			if len(rows) != tt.wantRows {
+				t.Errorf("rows = %d, want %d", len(rows), tt.wantRows)
+			}
+			if called := gotView != ""; called != tt.wantCalls {
+				t.Errorf("remote called = %v, want %v", called, tt.wantCalls)
+			}
+			if tt.wantCalls && (gotKey != "secret" || gotView != "senders") {
+				t.Errorf("request key=%q view=%q", gotKey, gotView)
+			}
+		})
+	}
+}
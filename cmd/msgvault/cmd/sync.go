@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"strings"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/config"
 	"github.com/wesm/msgvault/internal/gmail"
 	"github.com/wesm/msgvault/internal/oauth"
 	"github.com/wesm/msgvault/internal/store"
@@ -28,19 +30,47 @@ var syncIncrementalCmd = &cobra.Command{
 This is faster than a full sync as it only fetches changes since the last sync.
 Requires a prior full sync to establish the history ID baseline.
 
-IMAP accounts do not support incremental sync, so they are automatically
-synced using a full sync instead.
+Outlook accounts follow the Microsoft Graph delta links saved by the last
+complete sync, one per folder, in the same way.
+
+IMAP accounts have no change history. Instead, each mailbox is searched
+only for UIDs at or above the UIDNEXT recorded by the last complete
+sync; a mailbox whose UIDVALIDITY changed is listed in full again.
 
 If no email is specified, syncs all accounts that have credentials configured.
 Accounts without tokens or history IDs are skipped.
 
-If history is too old (Gmail returns 404), falls back to suggesting a full sync.
+If history is too old (Gmail returns 404, Graph 410), falls back to
+suggesting a full sync. With --recover, or recover_expired_history = true
+under [sync] in config.toml, the sync catches up on its own instead: it
+fully syncs the mail since a day before the last sync, then marks the
+archived messages the account no longer has as deleted from it.
+
+With --labels, or labels set for the account under [[accounts]] in
+config.toml, only new messages with one of those labels are archived.
 
 Examples:
   msgvault sync                 # Sync all accounts
-  msgvault sync you@gmail.com   # Sync specific account`,
+  msgvault sync you@gmail.com   # Sync specific account
+  msgvault sync --recover       # Catch up accounts whose history expired`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Under --json, progress goes to stderr and stdout gets only
+		// the per-account summary.
+		jsonOut, restore := humanOutputToStderr()
+		defer restore()
+
+		lock, err := acquireOpLock(cmd.Context(), "sync")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
+		notifier, err := newNotifier()
+		if err != nil {
+			return err
+		}
+
 		// Open database
 		dbPath := cfg.DatabaseDSN()
 		s, err := store.Open(dbPath)
@@ -48,6 +78,7 @@ Examples:
 			return fmt.Errorf("open database: %w", err)
 		}
 		defer func() { _ = s.Close() }()
+		applyParseConfig(s)
 
 		if err := s.InitSchema(); err != nil {
 			return fmt.Errorf("init schema: %w", err)
@@ -56,6 +87,12 @@ Examples:
 			return fmt.Errorf("startup migrations: %w", err)
 		}
 
+		// Rules only apply to messages stored by this run.
+		rulesLow, err := s.MaxMessageID()
+		if err != nil {
+			return fmt.Errorf("read message watermark: %w", err)
+		}
+
 		// Set up context with cancellation before any sync calls
 		// so Ctrl+C always saves checkpoints.
 		ctx, cancel := context.WithCancel(cmd.Context())
@@ -90,9 +127,10 @@ Examples:
 			source *store.Source
 			email  string
 		}
-		var gmailTargets []syncTarget
-		var imapTargets []*store.Source
+		var historyTargets []syncTarget // sources with change history
+		var fullTargets []*store.Source // sources without incremental sync
 		var syncErrors []string
+		var results []*syncResult
 
 		if len(args) == 1 {
 			// Resolve all sources for the identifier and route
@@ -104,17 +142,24 @@ Examples:
 			for _, src := range allMatches {
 				switch src.SourceType {
 				case "gmail":
-					gmailTargets = append(gmailTargets, syncTarget{source: src, email: src.Identifier})
-				case "imap":
-					imapTargets = append(imapTargets, src)
+					historyTargets = append(historyTargets, syncTarget{source: src, email: src.Identifier})
+				default:
+					if d, ok := sync.Lookup(src.SourceType); ok {
+						if d.Capabilities.History {
+							historyTargets = append(historyTargets, syncTarget{source: src, email: src.Identifier})
+						} else {
+							fullTargets = append(fullTargets, src)
+						}
+					}
 				}
 			}
-			if len(gmailTargets) == 0 && len(imapTargets) == 0 {
+			if len(historyTargets) == 0 && len(fullTargets) == 0 {
 				if len(allMatches) > 0 {
-					return fmt.Errorf("account %q exists but its source type cannot be synced (only gmail and imap are supported)", args[0])
+					return fmt.Errorf("account %q exists but its source type cannot be synced (supported: %s)",
+						args[0], strings.Join(sync.SourceTypes(), ", "))
 				}
 				// Not in DB — assume Gmail (legacy behaviour)
-				gmailTargets = []syncTarget{{email: args[0]}}
+				historyTargets = []syncTarget{{email: args[0]}}
 			}
 		} else {
 			// Discover all sources.
@@ -142,6 +187,7 @@ Examples:
 						mgr, mgrErr := getOAuthMgr(appName)
 						if mgrErr != nil {
 							syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", src.Identifier, mgrErr))
+							results = append(results, failedSyncResult(src.Identifier, src.SourceType, "incremental", mgrErr))
 							continue
 						}
 						if !mgr.HasToken(src.Identifier) {
@@ -149,23 +195,37 @@ Examples:
 							continue
 						}
 					}
-					gmailTargets = append(gmailTargets, syncTarget{source: src, email: src.Identifier})
+					historyTargets = append(historyTargets, syncTarget{source: src, email: src.Identifier})
+				case "outlook":
+					if skipMsg := outlookSkipReason(src); skipMsg != "" {
+						fmt.Println(skipMsg)
+						continue
+					}
+					if !src.SyncCursor.Valid || src.SyncCursor.String == "" {
+						fmt.Printf("Skipping %s (no delta state - run 'sync-full' first)\n", src.Identifier)
+						continue
+					}
+					historyTargets = append(historyTargets, syncTarget{source: src, email: src.Identifier})
 				case "imap":
 					skipMsg, parseErr := imapSkipReason(src)
 					if parseErr != nil {
 						syncErrors = append(syncErrors, fmt.Sprintf("%s: malformed sync_config: %v", src.Identifier, parseErr))
+						results = append(results, failedSyncResult(src.Identifier, src.SourceType, "full",
+							fmt.Errorf("malformed sync_config: %w", parseErr)))
 						continue
 					}
 					if skipMsg != "" {
 						fmt.Println(skipMsg)
 						continue
 					}
-					imapTargets = append(imapTargets, src)
+					fullTargets = append(fullTargets, src)
 				default:
-					continue
+					if d, ok := sync.Lookup(src.SourceType); ok && !d.Capabilities.History {
+						fullTargets = append(fullTargets, src)
+					}
 				}
 			}
-			if len(gmailTargets) == 0 && len(imapTargets) == 0 {
+			if len(historyTargets) == 0 && len(fullTargets) == 0 {
 				if len(syncErrors) > 0 {
 					// Surface the collected errors (e.g. broken OAuth config).
 					return fmt.Errorf("%s", syncErrors[0])
@@ -174,35 +234,59 @@ Examples:
 			}
 		}
 
-		// Sync IMAP sources via full sync.
-		for _, src := range imapTargets {
+		// Sources without change history get a full sync; IMAP narrows it
+		// to UIDs new since the last sync.
+		for _, src := range fullTargets {
 			if ctx.Err() != nil {
 				break
 			}
-			fmt.Printf("Note: IMAP account %s does not support incremental sync. Running full sync.\n\n", src.Identifier)
-			if err := runFullSync(ctx, s, getOAuthMgr, src, vf); err != nil {
+			if src.SourceType == "imap" {
+				fmt.Printf("Checking %s account %s for messages new since the last sync.\n\n",
+					sourceTypeLabel(src.SourceType), src.Identifier)
+			} else {
+				fmt.Printf("Note: %s account %s does not support incremental sync. Running full sync.\n\n",
+					sourceTypeLabel(src.SourceType), src.Identifier)
+			}
+			res, err := runFullSync(ctx, s, src, vf)
+			results = append(results, res)
+			if err != nil {
 				syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", src.Identifier, err))
 			}
 		}
 
-		// Sync Gmail sources via incremental sync.
-		for _, target := range gmailTargets {
+		// Sync Gmail and Outlook sources via incremental sync.
+		for _, target := range historyTargets {
 			if ctx.Err() != nil {
 				break
 			}
 			if target.source == nil {
 				syncErrors = append(syncErrors, fmt.Sprintf("%s: no source found - run 'sync-full' first", target.email))
+				results = append(results, failedSyncResult(target.email, "gmail", "incremental",
+					errors.New("no source found - run 'sync-full' first")))
 				continue
 			}
-			if err := runIncrementalSync(ctx, s, getOAuthMgr, target.source, vf); err != nil {
+			res, err := runIncrementalSync(ctx, s, getOAuthMgr, target.source, vf)
+			results = append(results, res)
+			if err != nil {
 				syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", target.email, err))
 				continue
 			}
 		}
 
+		applyRules(ctx, s, rulesLow, notifier)
+		scanAttachments(ctx, s)
+		indexAttachmentText(ctx, s)
+		notifySyncResults(ctx, notifier, results)
+
 		// Rebuild analytics cache.
 		rebuildCacheAfterWrite(dbPath)
 
+		if jsonOutput {
+			if err := printSyncResults(jsonOut, results); err != nil {
+				return err
+			}
+		}
+
 		if len(syncErrors) > 0 {
 			fmt.Println()
 			fmt.Println("Errors:")
@@ -217,54 +301,49 @@ Examples:
 	},
 }
 
-func runIncrementalSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (*oauth.Manager, error), source *store.Source, vf *vectorFeatures) error {
+func runIncrementalSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (*oauth.Manager, error), source *store.Source, vf *vectorFeatures) (res *syncResult, err error) {
+	res = newSyncResult(source.Identifier, source.SourceType, "incremental")
+	defer func() { res.setError(err) }()
+
 	if !source.SyncCursor.Valid || source.SyncCursor.String == "" {
-		return fmt.Errorf("no history ID - run 'sync-full' first")
+		return res, fmt.Errorf("no history ID - run 'sync-full' first")
 	}
 
 	email := source.Identifier
-	appName := sourceOAuthApp(source)
-	var tokenSource oauth2.TokenSource
-	var tsErr error
-
-	if saKeyPath := cfg.OAuth.ServiceAccountKeyFor(appName); saKeyPath != "" {
-		saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, oauth.Scopes)
-		if saErr != nil {
-			return fmt.Errorf("service account: %w", saErr)
-		}
-		tokenSource, tsErr = saMgr.TokenSource(ctx, email)
-		if tsErr != nil {
-			return tsErr
-		}
-	} else {
-		oauthMgr, oaErr := getOAuthMgr(appName)
-		if oaErr != nil {
-			return oaErr
-		}
-		interactive := isatty.IsTerminal(os.Stdin.Fd()) ||
-			isatty.IsCygwinTerminal(os.Stdin.Fd())
-		tokenSource, tsErr = getTokenSourceWithReauth(ctx, oauthMgr, email, interactive)
-		if tsErr != nil {
-			return tsErr
-		}
-	}
-
-	// Create Gmail client
-	rateLimiter := gmail.NewRateLimiter(float64(cfg.Sync.RateLimitQPS))
-	client := gmail.NewClient(tokenSource,
-		gmail.WithLogger(logger),
-		gmail.WithRateLimiter(rateLimiter),
-	)
-	defer func() { _ = client.Close() }()
 
 	// Set up sync options
 	opts := sync.DefaultOptions()
+	opts.SourceType = source.SourceType
 	opts.AttachmentsDir = cfg.AttachmentsDir()
+	opts.FetchWorkers, opts.FetchRate = cfg.Sync.FetchWorkers, cfg.Sync.FetchRate
+	if cfg.Sync.WriteBatch > 0 {
+		opts.WriteBatch = cfg.Sync.WriteBatch
+	}
+	opts.RecoverExpiredHistory = syncRecover || cfg.Sync.RecoverExpiredHistory
+	opts.SkipAttachments = syncSkipAttachments || cfg.Sync.SkipAttachments
+	opts.StorageQuota = cfg.Sync.StorageQuota
+	applyLabelOptions(opts, email)
+
+	var syncer *sync.Syncer
+	switch source.SourceType {
+	case "gmail", "":
+		client, err := gmailIncrementalClient(ctx, getOAuthMgr, source)
+		if err != nil {
+			return res, err
+		}
+		defer func() { _ = client.Close() }()
+		syncer = sync.New(client, s, opts)
+	default:
+		src, err := sync.Open(ctx, source)
+		if err != nil {
+			return res, err
+		}
+		defer func() { _ = src.Close() }()
+		syncer = sync.NewFromSource(src, s, opts)
+	}
 
-	// Create syncer with progress reporter
-	syncer := sync.New(client, s, opts).
-		WithLogger(logger).
-		WithProgress(&CLIProgress{})
+	// Attach progress reporter
+	syncer.WithLogger(logger).WithProgress(&CLIProgress{})
 	if vf != nil {
 		syncer.SetEmbedEnqueuer(vf.Enqueuer)
 	}
@@ -272,29 +351,46 @@ func runIncrementalSync(ctx context.Context, s *store.Store, getOAuthMgr func(st
 	// Run incremental sync
 	startTime := time.Now()
 	fmt.Printf("Starting incremental sync for %s\n", email)
-	fmt.Printf("Last history ID: %s\n\n", source.SyncCursor.String)
+	if source.SourceType == "gmail" || source.SourceType == "" {
+		fmt.Printf("Last history ID: %s\n\n", source.SyncCursor.String)
+	} else {
+		fmt.Printf("Continuing from the last %s sync\n\n", sourceTypeLabel(source.SourceType))
+	}
 
 	summary, err := syncer.Incremental(ctx, source)
 	if err != nil {
 		if ctx.Err() != nil {
 			fmt.Println("\nSync interrupted. Run again to resume.")
-			return nil
+			res.Status = syncResultInterrupted
+			return res, nil
 		}
 		// Check for history expired error
 		if errors.Is(err, sync.ErrHistoryExpired) {
-			fmt.Println("\nHistory ID has expired. Gmail only keeps ~7 days of history.")
-			fmt.Println("Run 'sync-full' to catch up on missed changes.")
-			return nil
+			if source.SourceType == "gmail" || source.SourceType == "" {
+				fmt.Println("\nHistory ID has expired. Gmail only keeps ~7 days of history.")
+			} else {
+				fmt.Printf("\nThe saved %s sync state has expired.\n", sourceTypeLabel(source.SourceType))
+			}
+			fmt.Println("Run 'sync --recover' or 'sync-full' to catch up on missed changes.")
+			res.Status = syncResultHistoryExpired
+			return res, nil
 		}
-		return fmt.Errorf("sync failed: %w", err)
+		return res, fmt.Errorf("sync failed: %w", err)
 	}
+	res.complete(summary)
 
 	// Print summary
 	fmt.Println()
 	fmt.Println("Sync complete!")
+	if summary.Recovered {
+		fmt.Println("  The change history had expired; caught up with a full sync.")
+	}
 	fmt.Printf("  Duration:      %s\n", summary.Duration.Round(time.Second))
 	fmt.Printf("  Changes:       %d processed, %d added\n",
 		summary.MessagesFound, summary.MessagesAdded)
+	if summary.Recovered {
+		fmt.Printf("  Deleted:       %d no longer in the account\n", summary.MessagesDeleted)
+	}
 	fmt.Printf("  Downloaded:    %.2f MB\n", float64(summary.BytesDownloaded)/(1024*1024))
 	if summary.Errors > 0 {
 		fmt.Printf("  Errors:        %d\n", summary.Errors)
@@ -307,9 +403,152 @@ func runIncrementalSync(ctx context.Context, s *store.Store, getOAuthMgr func(st
 		"elapsed", elapsed,
 	)
 
-	return nil
+	return res, nil
+}
+
+// gmailIncrementalClient builds the Gmail client for an incremental
+// sync, reauthorizing interactively when the token has been revoked.
+func gmailIncrementalClient(ctx context.Context, getOAuthMgr func(string) (*oauth.Manager, error), source *store.Source) (*gmail.Client, error) {
+	email := source.Identifier
+	appName := sourceOAuthApp(source)
+	var tokenSource oauth2.TokenSource
+	var tsErr error
+
+	if saKeyPath := cfg.OAuth.ServiceAccountKeyFor(appName); saKeyPath != "" {
+		saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, gmailScopes())
+		if saErr != nil {
+			return nil, fmt.Errorf("service account: %w", saErr)
+		}
+		tokenSource, tsErr = saMgr.TokenSource(ctx, email)
+		if tsErr != nil {
+			return nil, tsErr
+		}
+	} else {
+		oauthMgr, oaErr := getOAuthMgr(appName)
+		if oaErr != nil {
+			return nil, oaErr
+		}
+		interactive := isatty.IsTerminal(os.Stdin.Fd()) ||
+			isatty.IsCygwinTerminal(os.Stdin.Fd())
+		tokenSource, tsErr = getTokenSourceWithReauth(ctx, oauthMgr, email, interactive)
+		if tsErr != nil {
+			return nil, tsErr
+		}
+	}
+
+	rateLimiter := gmail.NewRateLimiter(float64(cfg.Sync.RateLimitQPS))
+	return gmail.NewClient(tokenSource,
+		gmail.WithLogger(logger),
+		gmail.WithRateLimiter(rateLimiter),
+	), nil
+}
+
+// syncResult statuses reported by 'sync --json' and 'sync-full --json'.
+const (
+	syncResultCompleted      = "completed"
+	syncResultFailed         = "failed"
+	syncResultInterrupted    = "interrupted"
+	syncResultHistoryExpired = "history_expired"
+)
+
+// syncResult is one account's outcome in 'sync --json' and
+// 'sync-full --json' output.
+type syncResult struct {
+	Account         string `json:"account"`
+	Type            string `json:"type"`
+	Mode            string `json:"mode"` // full or incremental
+	Status          string `json:"status"`
+	MessagesFound   int64  `json:"messages_found"`
+	MessagesAdded   int64  `json:"messages_added"`
+	MessagesUpdated int64  `json:"messages_updated"`
+	MessagesSkipped int64  `json:"messages_skipped"`
+	BytesDownloaded int64  `json:"bytes_downloaded"`
+	Errors          int64  `json:"errors"`
+	DurationMs      int64  `json:"duration_ms"`
+	Resumed         bool   `json:"resumed"`
+	Recovered       bool   `json:"recovered,omitempty"`
+	MessagesDeleted int64  `json:"messages_deleted,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// newSyncResult starts a result as failed; runs that get far enough
+// overwrite the status.
+func newSyncResult(account, sourceType, mode string) *syncResult {
+	if sourceType == "" {
+		sourceType = "gmail"
+	}
+	return &syncResult{Account: account, Type: sourceType, Mode: mode, Status: syncResultFailed}
+}
+
+func failedSyncResult(account, sourceType, mode string, err error) *syncResult {
+	res := newSyncResult(account, sourceType, mode)
+	res.setError(err)
+	return res
+}
+
+func (r *syncResult) setError(err error) {
+	if err != nil {
+		r.Status = syncResultFailed
+		r.Error = err.Error()
+	}
+}
+
+func (r *syncResult) complete(summary *gmail.SyncSummary) {
+	r.Status = syncResultCompleted
+	r.MessagesFound = summary.MessagesFound
+	r.MessagesAdded = summary.MessagesAdded
+	r.MessagesUpdated = summary.MessagesUpdated
+	r.MessagesSkipped = summary.MessagesSkipped
+	r.BytesDownloaded = summary.BytesDownloaded
+	r.Errors = summary.Errors
+	r.DurationMs = summary.Duration.Milliseconds()
+	r.Resumed = summary.WasResumed
+	r.Recovered = summary.Recovered
+	r.MessagesDeleted = summary.MessagesDeleted
+}
+
+func printSyncResults(w io.Writer, results []*syncResult) error {
+	if results == nil {
+		results = []*syncResult{}
+	}
+	return printJSONTo(w, map[string]any{"accounts": results})
+}
+
+var (
+	syncRecover          bool
+	syncIncludeLabels    []string
+	syncExcludeLabels    []string
+	syncIncludeSpamTrash bool
+	syncSkipAttachments  bool
+)
+
+// applyLabelOptions sets which labels a sync of email archives from
+// --labels, --exclude-labels and --include-spam-trash, each falling back
+// to the account's settings in config.toml.
+func applyLabelOptions(opts *sync.Options, email string) {
+	var acc config.AccountSchedule
+	if a := cfg.GetAccountSchedule(email); a != nil {
+		acc = *a
+	}
+	opts.IncludeLabels = acc.Labels
+	if len(syncIncludeLabels) > 0 {
+		opts.IncludeLabels = syncIncludeLabels
+	}
+	opts.ExcludeLabels = acc.ExcludeLabels
+	if len(syncExcludeLabels) > 0 {
+		opts.ExcludeLabels = syncExcludeLabels
+	}
+	opts.IncludeSpamTrash = syncIncludeSpamTrash || acc.IncludeSpamTrash
 }
 
 func init() {
+	syncIncrementalCmd.Flags().BoolVar(&syncRecover, "recover", false,
+		"when an account's history has expired, catch up with a full sync of the mail since the last sync")
+	syncIncrementalCmd.Flags().StringSliceVar(&syncIncludeLabels, "labels", nil,
+		"only archive new messages with one of these labels, by name or ID (default: the account's labels in config.toml)")
+	syncIncrementalCmd.Flags().StringSliceVar(&syncExcludeLabels, "exclude-labels", nil,
+		"skip new messages with any of these labels, e.g. SPAM,TRASH (default: the account's exclude_labels in config.toml)")
+	syncIncrementalCmd.Flags().BoolVar(&syncSkipAttachments, "skip-attachments", false,
+		"leave attachments in the raw MIME; extract them later with 'attachments fetch'")
 	rootCmd.AddCommand(syncIncrementalCmd)
 }
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var syncFailuresClear bool
+
+var syncFailuresCmd = &cobra.Command{
+	Use:   "failures [email]",
+	Short: "List messages that failed to sync",
+	Long: fmt.Sprintf(`List the messages a sync could not fetch, parse or store, with the class
+of error, how many times each was tried and the last error.
+
+Every sync retries the failed messages of its account before it looks for
+new mail, and forgets a failure once the message is archived or gone from
+the source. After %d attempts a message is no longer retried; fix the
+cause and run with --clear to start over.
+
+Examples:
+  msgvault sync failures
+  msgvault sync failures you@gmail.com --json
+  msgvault sync failures you@gmail.com --clear`, store.MaxSyncAttempts),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("sync failures"); err != nil {
+			return err
+		}
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		var sourceID int64
+		if len(args) == 1 {
+			source, err := resolveSource(s, args[0], "")
+			if err != nil {
+				return err
+			}
+			sourceID = source.ID
+		}
+
+		if syncFailuresClear {
+			n, err := s.ClearSyncFailures(sourceID)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Cleared %d sync failure(s)\n", n)
+			return nil
+		}
+
+		failures, err := s.ListSyncFailures(sourceID)
+		if err != nil {
+			return err
+		}
+		if jsonOutput {
+			if failures == nil {
+				failures = []store.SyncFailure{}
+			}
+			return printJSON(failures)
+		}
+		if len(failures) == 0 {
+			fmt.Println("No sync failures recorded.")
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ACCOUNT\tMESSAGE\tCLASS\tATTEMPTS\tLAST FAILED\tERROR")
+		_, _ = fmt.Fprintln(w, "───────\t───────\t─────\t────────\t───────────\t─────")
+		gaveUp := 0
+		for _, f := range failures {
+			attempts := fmt.Sprintf("%d", f.Attempts)
+			if f.Attempts >= store.MaxSyncAttempts {
+				attempts += " (gave up)"
+				gaveUp++
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", f.Account, f.SourceMessageID,
+				f.ErrorClass, attempts, i18n.Date(f.LastFailedAt), truncate(f.Error, 50))
+		}
+		_ = w.Flush()
+		if gaveUp > 0 {
+			fmt.Printf("\n%d message(s) are no longer retried. Fix the cause, then run\n"+
+				"'msgvault sync failures --clear' and a full sync to try them again.\n", gaveUp)
+		}
+		return nil
+	},
+}
+
+func init() {
+	syncFailuresCmd.Flags().BoolVar(&syncFailuresClear, "clear", false, "forget the recorded failures instead of listing them")
+	syncIncrementalCmd.AddCommand(syncFailuresCmd)
+}
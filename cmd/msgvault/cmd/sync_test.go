@@ -1,15 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/gmail"
 	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/sync"
 )
 
 // fakeClientSecrets is a minimal Google OAuth client_secret.json that
@@ -750,3 +754,152 @@ func TestSyncCmd_GmailOnlyBrokenOAuthSurfacesError(t *testing.T) {
 		})
 	}
 }
+
+// TestSyncCmd_JSONOutput verifies that under --json both sync
+// commands print only a per-account JSON summary on stdout, with
+// failures reported in it as well as through the returned error.
+func TestSyncCmd_JSONOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := tmpDir + "/msgvault.db"
+
+	s, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := s.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	if _, err := s.GetOrCreateSource("imap", "solo@example.com"); err != nil {
+		t.Fatalf("create imap source: %v", err)
+	}
+	_ = s.Close()
+
+	savedCfg := cfg
+	savedLogger := logger
+	savedJSON := jsonOutput
+	defer func() {
+		cfg = savedCfg
+		logger = savedLogger
+		jsonOutput = savedJSON
+	}()
+
+	cfg = &config.Config{
+		HomeDir: tmpDir,
+		Data:    config.DataConfig{DataDir: tmpDir},
+	}
+	logger = slog.New(slog.NewTextHandler(os.Stderr, nil))
+	jsonOutput = true
+
+	for _, tc := range []struct {
+		name string
+		runE func(*cobra.Command, []string) error
+	}{
+		{"sync", syncIncrementalCmd.RunE},
+		{"sync-full", syncFullCmd.RunE},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			testCmd := &cobra.Command{
+				Use:  tc.name + " [email]",
+				Args: cobra.MaximumNArgs(1),
+				RunE: tc.runE,
+			}
+			root := newTestRootCmd()
+			root.AddCommand(testCmd)
+			root.SetArgs([]string{tc.name, "solo@example.com"})
+
+			getOutput := captureStdout(t)
+			execErr := root.Execute()
+			output := getOutput()
+			if execErr == nil {
+				t.Fatal("expected error (no IMAP config)")
+			}
+
+			var got struct {
+				Accounts []syncResult `json:"accounts"`
+			}
+			if err := json.Unmarshal([]byte(output), &got); err != nil {
+				t.Fatalf("stdout is not a single JSON document: %v\n%s", err, output)
+			}
+			if len(got.Accounts) != 1 {
+				t.Fatalf("accounts = %+v, want 1", got.Accounts)
+			}
+			res := got.Accounts[0]
+			if res.Account != "solo@example.com" || res.Type != "imap" || res.Mode != "full" {
+				t.Errorf("result = %+v, want solo@example.com imap full", res)
+			}
+			if res.Status != syncResultFailed || !strings.Contains(res.Error, "no config") {
+				t.Errorf("status = %q, error = %q; want failed with config error", res.Status, res.Error)
+			}
+		})
+	}
+}
+
+func TestSyncResult_CompleteRecovered(t *testing.T) {
+	res := newSyncResult("alice@example.com", "", "incremental")
+	res.complete(&gmail.SyncSummary{MessagesAdded: 3, Recovered: true, MessagesDeleted: 2})
+	if res.Status != syncResultCompleted || !res.Recovered || res.MessagesDeleted != 2 || res.MessagesAdded != 3 {
+		t.Errorf("result = %+v, want completed, recovered, 3 added, 2 deleted", res)
+	}
+
+	data, err := json.Marshal(newSyncResult("bob@example.com", "", "incremental"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "recovered") || strings.Contains(string(data), "messages_deleted") {
+		t.Errorf("unrecovered result has recovery fields: %s", data)
+	}
+}
+
+func TestApplyLabelOptions(t *testing.T) {
+	savedCfg := cfg
+	savedInclude, savedExclude, savedSpamTrash := syncIncludeLabels, syncExcludeLabels, syncIncludeSpamTrash
+	t.Cleanup(func() {
+		cfg = savedCfg
+		syncIncludeLabels, syncExcludeLabels, syncIncludeSpamTrash = savedInclude, savedExclude, savedSpamTrash
+	})
+	cfg = &config.Config{Accounts: []config.AccountSchedule{{
+		Email:            "alice@example.com",
+		Labels:           []string{"INBOX", "Work"},
+		ExcludeLabels:    []string{"SPAM"},
+		IncludeSpamTrash: true,
+	}}}
+
+	tests := []struct {
+		name          string
+		include       []string
+		exclude       []string
+		spamTrash     bool
+		email         string
+		wantInclude   []string
+		wantExclude   []string
+		wantSpamTrash bool
+	}{
+		{
+			name: "config", email: "alice@example.com",
+			wantInclude: []string{"INBOX", "Work"}, wantExclude: []string{"SPAM"}, wantSpamTrash: true,
+		},
+		{name: "unconfigured account", email: "bob@example.com"},
+		{
+			name: "flags override config", email: "alice@example.com",
+			include: []string{"Travel"}, exclude: []string{"TRASH"},
+			wantInclude: []string{"Travel"}, wantExclude: []string{"TRASH"}, wantSpamTrash: true,
+		},
+		{name: "spam and trash flag", email: "bob@example.com", spamTrash: true, wantSpamTrash: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			syncIncludeLabels, syncExcludeLabels, syncIncludeSpamTrash = tt.include, tt.exclude, tt.spamTrash
+			opts := sync.DefaultOptions()
+			applyLabelOptions(opts, tt.email)
+			if !slices.Equal(opts.IncludeLabels, tt.wantInclude) {
+				t.Errorf("IncludeLabels = %q, want %q", opts.IncludeLabels, tt.wantInclude)
+			}
+			if !slices.Equal(opts.ExcludeLabels, tt.wantExclude) {
+				t.Errorf("ExcludeLabels = %q, want %q", opts.ExcludeLabels, tt.wantExclude)
+			}
+			if opts.IncludeSpamTrash != tt.wantSpamTrash {
+				t.Errorf("IncludeSpamTrash = %v, want %v", opts.IncludeSpamTrash, tt.wantSpamTrash)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"os"
 	"os/signal"
@@ -12,6 +13,7 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/graph"
 	imaplib "github.com/wesm/msgvault/internal/imap"
 	"github.com/wesm/msgvault/internal/microsoft"
 	"github.com/wesm/msgvault/internal/oauth"
@@ -26,6 +28,9 @@ var (
 	syncBefore   string
 	syncAfter    string
 	syncLimit    int
+
+	syncFetchWorkers int
+	syncMetadataOnly bool
 )
 
 var syncFullCmd = &cobra.Command{
@@ -38,15 +43,30 @@ Supports resumption from interruption - just run again to continue.
 
 If no email is specified, syncs all configured accounts sequentially.
 
+For IMAP accounts every mailbox is listed again, and the UIDs recorded
+for the next 'msgvault sync' are refreshed.
+
 Date filters:
   --after 2024-01-01     Only messages on or after this date
   --before 2024-12-31    Only messages before this date
 
+--labels INBOX,Work archives only messages with one of those labels, by
+name or ID, and lists only them from Gmail. To keep later syncs to the
+same labels, set labels = ["INBOX", "Work"] for the account under
+[[accounts]] in config.toml; --labels overrides it.
+
+--metadata-only stores each Gmail message's headers, labels and size
+without its body, which indexes a large mailbox quickly. Run 'msgvault
+hydrate' later to download the bodies; a sync-full without the flag
+downloads them too.
+
 Examples:
   msgvault sync-full                             # Sync all accounts
   msgvault sync-full you@gmail.com
   msgvault sync-full you@gmail.com --after 2024-01-01
   msgvault sync-full you@gmail.com --query "from:someone@example.com"
+  msgvault sync-full you@gmail.com --labels INBOX,Work
+  msgvault sync-full you@gmail.com --metadata-only
   msgvault sync-full you@gmail.com --noresume    # Force fresh sync`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -64,6 +84,22 @@ Examples:
 			}
 		}
 
+		// Under --json, progress goes to stderr and stdout gets only
+		// the per-account summary.
+		jsonOut, restore := humanOutputToStderr()
+		defer restore()
+
+		lock, err := acquireOpLock(cmd.Context(), "sync-full")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
+		notifier, err := newNotifier()
+		if err != nil {
+			return err
+		}
+
 		// Open database
 		dbPath := cfg.DatabaseDSN()
 		s, err := store.Open(dbPath)
@@ -71,6 +107,7 @@ Examples:
 			return fmt.Errorf("open database: %w", err)
 		}
 		defer func() { _ = s.Close() }()
+		applyParseConfig(s)
 
 		if err := s.InitSchema(); err != nil {
 			return fmt.Errorf("init schema: %w", err)
@@ -79,29 +116,38 @@ Examples:
 			return fmt.Errorf("startup migrations: %w", err)
 		}
 
+		// Rules only apply to messages stored by this run.
+		rulesLow, err := s.MaxMessageID()
+		if err != nil {
+			return fmt.Errorf("read message watermark: %w", err)
+		}
+
 		getOAuthMgr := oauthManagerCache()
 
 		// Determine which sources to sync
 		var sources []*store.Source
 		var syncErrors []string
+		var results []*syncResult
 		if len(args) == 1 {
 			// Look up all sources matching the identifier and
-			// keep only syncable types (gmail, imap). Non-syncable
-			// sources like mbox/apple-mail imports share the same
-			// identifier namespace but cannot be synced.
+			// keep only syncable types (those with a registered
+			// driver). Non-syncable sources like mbox/apple-mail
+			// imports share the same identifier namespace but
+			// cannot be synced.
 			allMatches, err := s.GetSourcesByIdentifierOrDisplayName(args[0])
 			if err != nil {
 				return fmt.Errorf("look up source: %w", err)
 			}
 			for _, src := range allMatches {
-				if src.SourceType == "gmail" || src.SourceType == "imap" {
+				if isSyncable(src.SourceType) {
 					sources = append(sources, src)
 				}
 			}
 			if len(sources) == 0 {
 				if len(allMatches) > 0 {
 					// Identifier exists but has no syncable source types.
-					return fmt.Errorf("account %q exists but its source type cannot be synced (only gmail and imap are supported)", args[0])
+					return fmt.Errorf("account %q exists but its source type cannot be synced (supported: %s)",
+						args[0], strings.Join(sync.SourceTypes(), ", "))
 				}
 				// Not in DB yet - assume Gmail (legacy behaviour)
 				sources = []*store.Source{{SourceType: "gmail", Identifier: args[0]}}
@@ -128,6 +174,7 @@ Examples:
 						mgr, err := getOAuthMgr(appName)
 						if err != nil {
 							syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", src.Identifier, err))
+							results = append(results, failedSyncResult(src.Identifier, src.SourceType, "full", err))
 							continue
 						}
 						if !mgr.HasToken(src.Identifier) {
@@ -139,15 +186,24 @@ Examples:
 					skipMsg, parseErr := imapSkipReason(src)
 					if parseErr != nil {
 						syncErrors = append(syncErrors, fmt.Sprintf("%s: malformed sync_config: %v", src.Identifier, parseErr))
+						results = append(results, failedSyncResult(src.Identifier, src.SourceType, "full",
+							fmt.Errorf("malformed sync_config: %w", parseErr)))
 						continue
 					}
 					if skipMsg != "" {
 						fmt.Println(skipMsg)
 						continue
 					}
+				case "outlook":
+					if skipMsg := outlookSkipReason(src); skipMsg != "" {
+						fmt.Println(skipMsg)
+						continue
+					}
 				default:
-					fmt.Printf("Skipping %s (unsupported source type %q)\n", src.Identifier, src.SourceType)
-					continue
+					if !isSyncable(src.SourceType) {
+						fmt.Printf("Skipping %s (unsupported source type %q)\n", src.Identifier, src.SourceType)
+						continue
+					}
 				}
 				sources = append(sources, src)
 			}
@@ -197,20 +253,41 @@ Examples:
 				if cfg.OAuth.ServiceAccountKeyFor(appName) == "" {
 					if _, err := getOAuthMgr(appName); err != nil {
 						syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", src.Identifier, err))
+						results = append(results, failedSyncResult(src.Identifier, src.SourceType, "full", err))
 						continue
 					}
 				}
 			}
 
-			if err := runFullSync(ctx, s, getOAuthMgr, src, vf); err != nil {
+			// IMAP full syncs list every mailbox again rather than
+			// starting from the UIDNEXT recorded by the last sync.
+			if src.SourceType == "imap" {
+				rescan := *src
+				rescan.SyncCursor = sql.NullString{}
+				src = &rescan
+			}
+
+			res, err := runFullSync(ctx, s, src, vf)
+			results = append(results, res)
+			if err != nil {
 				syncErrors = append(syncErrors, fmt.Sprintf("%s: %v", src.Identifier, err))
 				continue
 			}
 		}
 
+		applyRules(ctx, s, rulesLow, notifier)
+		scanAttachments(ctx, s)
+		indexAttachmentText(ctx, s)
+		notifySyncResults(ctx, notifier, results)
+
 		// Rebuild analytics cache.
 		rebuildCacheAfterWrite(dbPath)
 
+		if jsonOutput {
+			if err := printSyncResults(jsonOut, results); err != nil {
+				return err
+			}
+		}
 		if len(syncErrors) > 0 {
 			fmt.Println()
 			fmt.Println("Errors:")
@@ -228,7 +305,7 @@ Examples:
 // buildAPIClient creates the appropriate gmail.API client for the given
 // source. saScopes is used when the resolved oauth_app is backed by a
 // service account key; for browser-OAuth sources, scopes flow through the
-// caller-provided getOAuthMgr factory. Pass nil to use oauth.Scopes; pass
+// caller-provided getOAuthMgr factory. Pass nil to use gmailScopes(); pass
 // oauth.ScopesDeletion (or another set) for workflows that need elevated
 // access.
 func buildAPIClient(ctx context.Context, src *store.Source, getOAuthMgr func(string) (*oauth.Manager, error), saScopes []string) (gmail.API, error) {
@@ -241,7 +318,7 @@ func buildAPIClient(ctx context.Context, src *store.Source, getOAuthMgr func(str
 		if saKeyPath := cfg.OAuth.ServiceAccountKeyFor(appName); saKeyPath != "" {
 			scopes := saScopes
 			if len(scopes) == 0 {
-				scopes = oauth.Scopes
+				scopes = gmailScopes()
 			}
 			saMgr, err := oauth.NewServiceAccountManager(saKeyPath, scopes)
 			if err != nil {
@@ -280,21 +357,13 @@ func buildAPIClient(ctx context.Context, src *store.Source, getOAuthMgr func(str
 
 		var opts []imaplib.Option
 		opts = append(opts, imaplib.WithLogger(logger))
-
-		var since, before time.Time
-		if syncAfter != "" {
-			t, err := time.Parse("2006-01-02", syncAfter)
-			if err != nil {
-				return nil, fmt.Errorf("invalid --after date %q (expected YYYY-MM-DD): %w", syncAfter, err)
-			}
-			since = t
+		if st := imaplib.ParseUIDState(src.SyncCursor.String); st != nil {
+			opts = append(opts, imaplib.WithUIDState(st))
 		}
-		if syncBefore != "" {
-			t, err := time.Parse("2006-01-02", syncBefore)
-			if err != nil {
-				return nil, fmt.Errorf("invalid --before date %q (expected YYYY-MM-DD): %w", syncBefore, err)
-			}
-			before = t
+
+		since, before, err := syncDateRange()
+		if err != nil {
+			return nil, err
 		}
 		if !since.IsZero() || !before.IsZero() {
 			opts = append(opts, imaplib.WithDateFilter(since, before))
@@ -325,26 +394,76 @@ func buildAPIClient(ctx context.Context, src *store.Source, getOAuthMgr func(str
 			return imaplib.NewClient(imapCfg, password, opts...), nil
 		}
 
+	case "outlook":
+		if cfg.Microsoft.ClientID == "" {
+			return nil, fmt.Errorf("microsoft OAuth not configured — add a [microsoft] section with client_id to config.toml")
+		}
+		msMgr := microsoft.NewGraphManager(
+			cfg.Microsoft.ClientID,
+			cfg.Microsoft.EffectiveTenantID(),
+			cfg.TokensDir(),
+			logger,
+		)
+		tokenFn, err := msMgr.TokenSource(ctx, src.Identifier)
+		if err != nil {
+			return nil, fmt.Errorf("load Microsoft token: %w (run 'add-account --provider outlook' first)", err)
+		}
+
+		opts := []graph.Option{graph.WithLogger(logger)}
+		if st := graph.ParseDeltaState(src.SyncCursor.String); st != nil {
+			opts = append(opts, graph.WithDeltaState(st))
+		}
+		since, before, err := syncDateRange()
+		if err != nil {
+			return nil, err
+		}
+		if !since.IsZero() || !before.IsZero() {
+			opts = append(opts, graph.WithDateFilter(since, before))
+		}
+		return graph.NewClient(tokenFn, opts...), nil
+
 	default:
 		return nil, fmt.Errorf("unsupported source type %q", src.SourceType)
 	}
 }
 
-func runFullSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (*oauth.Manager, error), src *store.Source, vf *vectorFeatures) error {
-	apiClient, err := buildAPIClient(ctx, src, getOAuthMgr, nil)
+// syncDateRange parses the --after and --before flags. Zero times mean
+// no bound.
+func syncDateRange() (since, before time.Time, err error) {
+	if syncAfter != "" {
+		since, err = time.Parse("2006-01-02", syncAfter)
+		if err != nil {
+			return since, before, fmt.Errorf("invalid --after date %q (expected YYYY-MM-DD): %w", syncAfter, err)
+		}
+	}
+	if syncBefore != "" {
+		before, err = time.Parse("2006-01-02", syncBefore)
+		if err != nil {
+			return since, before, fmt.Errorf("invalid --before date %q (expected YYYY-MM-DD): %w", syncBefore, err)
+		}
+	}
+	return since, before, nil
+}
+
+func runFullSync(ctx context.Context, s *store.Store, src *store.Source, vf *vectorFeatures) (res *syncResult, err error) {
+	res = newSyncResult(src.Identifier, src.SourceType, "full")
+	defer func() { res.setError(err) }()
+
+	source, err := sync.Open(ctx, src)
 	if err != nil {
-		return err
+		return res, err
 	}
-	defer func() { _ = apiClient.Close() }()
+	defer func() { _ = source.Close() }()
+	caps := source.Capabilities()
 
-	// Build query from flags (Gmail only; IMAP date filters are
-	// handled via WithDateFilter on the client).
+	// Build query from flags (Gmail search syntax; IMAP date filters
+	// are handled via WithDateFilter on the client).
 	query := buildSyncQuery()
-	if query != "" && src.SourceType == "imap" {
+	if query != "" && !caps.Query {
 		// --after/--before are handled natively by IMAP SEARCH;
-		// only warn about --query which has no IMAP equivalent.
+		// only warn about --query which has no equivalent.
 		if syncQuery != "" {
-			fmt.Printf("Warning: --query is not supported for IMAP sources and will be ignored.\n\n")
+			fmt.Printf("Warning: --query is not supported for %s sources and will be ignored.\n\n", src.SourceType)
 		}
 		query = ""
 	}
@@ -356,18 +475,25 @@ func runFullSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (
 	opts.NoResume = syncNoResume
 	opts.Limit = syncLimit
 	opts.AttachmentsDir = cfg.AttachmentsDir()
-
-	// IMAP page tokens are numeric offsets into a message list
-	// rebuilt from live mailbox state each session. Cross-session
-	// resume is unreliable because additions or deletions shift
-	// the offsets. Already-imported messages are efficiently
-	// skipped via MessageExistsWithRawBatch.
-	if src.SourceType == "imap" {
-		opts.NoResume = true
+	opts.FetchWorkers, opts.FetchRate = cfg.Sync.FetchWorkers, cfg.Sync.FetchRate
+	if cfg.Sync.WriteBatch > 0 {
+		opts.WriteBatch = cfg.Sync.WriteBatch
 	}
+	if syncFetchWorkers > 0 {
+		opts.FetchWorkers = syncFetchWorkers
+	}
+	applyLabelOptions(opts, src.Identifier)
+	opts.MetadataOnly = syncMetadataOnly
+	opts.SkipAttachments = syncSkipAttachments || cfg.Sync.SkipAttachments
+	opts.StorageQuota = cfg.Sync.StorageQuota
+
+	// Sources without Resume (IMAP page tokens are offsets into a
+	// message list rebuilt each session) always start over; the
+	// syncer skips already-imported messages cheaply.
+	restart := opts.NoResume || !caps.Resume
 
 	// Create syncer with progress reporter
-	syncer := sync.New(apiClient, s, opts).
+	syncer := sync.NewFromSource(source, s, opts).
 		WithLogger(logger).
 		WithProgress(&CLIProgress{})
 	if vf != nil {
@@ -381,23 +507,37 @@ func runFullSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (
 		displayID = src.DisplayName.String
 	}
 	fmt.Printf("Starting full sync for %s\n", displayID)
-	if query != "" && src.SourceType != "imap" {
+	if query != "" {
 		fmt.Printf("Query: %s\n", query)
 	}
+	if len(opts.IncludeLabels) > 0 {
+		fmt.Printf("Labels: %s\n", strings.Join(opts.IncludeLabels, ", "))
+	}
+	if len(opts.ExcludeLabels) > 0 {
+		fmt.Printf("Excluding labels: %s\n", strings.Join(opts.ExcludeLabels, ", "))
+	}
+	if opts.IncludeSpamTrash {
+		fmt.Println("Including Spam and Trash")
+	}
+	if opts.MetadataOnly {
+		fmt.Println("Metadata only: run 'msgvault hydrate' later for message bodies")
+	}
 	fmt.Println()
 
 	summary, err := syncer.Full(ctx, src.Identifier)
 	if err != nil {
 		if ctx.Err() != nil {
-			if opts.NoResume {
+			if restart {
 				fmt.Println("\nSync interrupted. Run again to restart (already-imported messages will be skipped).")
 			} else {
 				fmt.Println("\nSync interrupted. Run again to resume.")
 			}
-			return nil
+			res.Status = syncResultInterrupted
+			return res, nil
 		}
-		return fmt.Errorf("sync failed: %w", err)
+		return res, fmt.Errorf("sync failed: %w", err)
 	}
+	res.complete(summary)
 
 	// Print summary
 	fmt.Println()
@@ -426,7 +566,7 @@ func runFullSync(ctx context.Context, s *store.Store, getOAuthMgr func(string) (
 		"elapsed", elapsed,
 	)
 
-	return nil
+	return res, nil
 }
 
 // buildSyncQuery constructs a Gmail search query from flags.
@@ -583,11 +723,40 @@ func imapSkipReason(src *store.Source) (string, error) {
 	return "", nil
 }
 
+// outlookSkipReason returns why an Outlook source cannot sync yet, or
+// "" when it has what it needs.
+func outlookSkipReason(src *store.Source) string {
+	if cfg.Microsoft.ClientID == "" {
+		return fmt.Sprintf("Skipping %s (Microsoft OAuth not configured — add client_id to [microsoft] in config.toml)", src.Identifier)
+	}
+	msMgr := microsoft.NewGraphManager(
+		cfg.Microsoft.ClientID,
+		cfg.Microsoft.EffectiveTenantID(),
+		cfg.TokensDir(),
+		logger,
+	)
+	if !msMgr.HasToken(src.Identifier) {
+		return fmt.Sprintf("Skipping %s (no Microsoft token — run 'add-account %s --provider outlook' first)", src.Identifier, src.Identifier)
+	}
+	return ""
+}
+
 func init() {
 	syncFullCmd.Flags().StringVar(&syncQuery, "query", "", "Gmail search query")
 	syncFullCmd.Flags().BoolVar(&syncNoResume, "noresume", false, "Force fresh sync (don't resume)")
 	syncFullCmd.Flags().StringVar(&syncBefore, "before", "", "Only messages before this date (YYYY-MM-DD)")
 	syncFullCmd.Flags().StringVar(&syncAfter, "after", "", "Only messages after this date (YYYY-MM-DD)")
 	syncFullCmd.Flags().IntVar(&syncLimit, "limit", 0, "Limit number of messages (for testing)")
+	syncFullCmd.Flags().StringSliceVar(&syncIncludeLabels, "labels", nil,
+		"Only archive messages with one of these labels, by name or ID (default: the account's labels in config.toml)")
+	syncFullCmd.Flags().StringSliceVar(&syncExcludeLabels, "exclude-labels", nil,
+		"Skip messages with any of these labels, e.g. SPAM,TRASH (default: the account's exclude_labels in config.toml)")
+	syncFullCmd.Flags().BoolVar(&syncIncludeSpamTrash, "include-spam-trash", false,
+		"Also archive Spam and Trash, which Gmail leaves out by default")
+	syncFullCmd.Flags().BoolVar(&syncMetadataOnly, "metadata-only", false,
+		"Store only headers, labels and size; download bodies later with 'hydrate'")
+	syncFullCmd.Flags().BoolVar(&syncSkipAttachments, "skip-attachments", false,
+		"Leave attachments in the raw MIME; extract them later with 'attachments fetch'")
+	syncFullCmd.Flags().IntVar(&syncFetchWorkers, "fetch-workers", 0, "Download this many messages at once (default: [sync] fetch_workers)")
 	rootCmd.AddCommand(syncFullCmd)
 }
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/importer"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var threadsRepairDryRun bool
+
+var threadsCmd = &cobra.Command{
+	Use:   "threads",
+	Short: "Fix mis-threaded conversations",
+	Long: `Fix conversations that were threaded wrongly: merge conversations that
+belong together, split messages off into a conversation of their own, or
+repair threading across the archive from the messages' References.
+
+Merges and splits are kept in the vault. A resync leaves split messages
+where you put them, and mail the source later files under a merged
+conversation joins the conversation it was merged into.`,
+}
+
+var threadsMergeCmd = &cobra.Command{
+	Use:   "merge <message-id> <message-id>...",
+	Short: "Merge the conversations of messages into one",
+	Long: `Merge the conversations of the given messages, by internal or source
+message ID, into the oldest of them. The messages must belong to one
+account.
+
+Examples:
+  msgvault threads merge 12345 12390`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runThreadEdit(cmd, args, func(s *store.Store, ids []int64) error {
+			convID, err := s.MergeThreads(ids)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged the conversations of %d message(s) into conversation %d.\n", len(ids), convID)
+			return nil
+		})
+	},
+}
+
+var threadsSplitCmd = &cobra.Command{
+	Use:   "split <message-id>...",
+	Short: "Move messages out of their conversation into a new one",
+	Long: `Move the given messages, by internal or source message ID, out of their
+conversation into a new conversation of their own. The messages must
+share a conversation, and may not be all of it.
+
+Examples:
+  msgvault threads split 12391 12392`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runThreadEdit(cmd, args, func(s *store.Store, ids []int64) error {
+			convID, err := s.SplitThread(ids)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Split %d message(s) off into conversation %d.\n", len(ids), convID)
+			return nil
+		})
+	},
+}
+
+func runThreadEdit(cmd *cobra.Command, refs []string, edit func(*store.Store, []int64) error) error {
+	if err := MustBeLocal("threads " + cmd.Name()); err != nil {
+		return err
+	}
+	s, err := openLocalStoreAndInit()
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	engine := query.NewSQLiteEngine(s.DB())
+	ids := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		resolved, err := resolveMessage(engine, cmd, ref)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, resolved.ID)
+	}
+	return edit(s, ids)
+}
+
+var threadsRepairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Normalize conversation titles and merge conversations that share References",
+	Long: `Repair email threading across the archive.
+
+Conversation titles lose their chains of reply and forward prefixes
+("Re: Fwd: RE: Lunch" becomes "Lunch"). Then each message's References
+and In-Reply-To headers are read from its stored raw MIME, and
+conversations of one account whose messages reply to each other are
+merged into the oldest of them. Messages split off with 'threads split'
+are never merged back.
+
+Examples:
+  msgvault threads repair --dry-run
+  msgvault threads repair`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("threads repair"); err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		lock, err := acquireOpLock(ctx, "threads repair")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = lock.Release() }()
+
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+
+		res, err := importer.RepairThreads(ctx, s, importer.ThreadRepairOptions{
+			DryRun: threadsRepairDryRun,
+			Progress: func(done int) {
+				if done%1000 == 0 {
+					fmt.Fprintf(os.Stderr, "Read %s messages...\n", formatCount(int64(done)))
+				}
+			},
+		}, logger)
+		if err != nil {
+			return fmt.Errorf("repair threads: %w", err)
+		}
+
+		if jsonOutput {
+			return printJSON(map[string]any{
+				"dry_run":           threadsRepairDryRun,
+				"scanned":           res.Scanned,
+				"titles_normalized": res.TitlesNormalized,
+				"merged":            res.Merged,
+				"failed":            res.Failed,
+			})
+		}
+		verb := "Merged"
+		if threadsRepairDryRun {
+			verb = "Would merge"
+		}
+		fmt.Printf("Read %s messages. %s %s conversation(s) into the ones they reply to",
+			formatCount(int64(res.Scanned)), verb, formatCount(int64(res.Merged)))
+		if threadsRepairDryRun {
+			fmt.Printf(", and normalize %s title(s)", formatCount(int64(res.TitlesNormalized)))
+		} else {
+			fmt.Printf(", and normalized %s title(s)", formatCount(int64(res.TitlesNormalized)))
+		}
+		if res.Failed > 0 {
+			fmt.Printf("; %s could not be read (see the log)", formatCount(int64(res.Failed)))
+		}
+		fmt.Println(".")
+		return nil
+	},
+}
+
+func init() {
+	threadsRepairCmd.Flags().BoolVar(&threadsRepairDryRun, "dry-run", false,
+		"report what would change without changing it")
+	threadsCmd.AddCommand(threadsMergeCmd, threadsSplitCmd, threadsRepairCmd)
+	rootCmd.AddCommand(threadsCmd)
+}
@@ -50,17 +50,36 @@ Selection & Deletion:
   D           Stage selected for deletion
   q           Quit
 
+Triage:
+  i           Step through the messages not yet triaged, oldest first
+  Space       Keep the message and move on
+  d           Mark it for deletion (staged together when triage ends)
+  #           Edit its tags
+  o           Open its thread
+  Esc         Stop; the next session picks up where this one left off
+
 Performance:
   For large archives (100k+ messages), the TUI uses Parquet files for fast
   aggregation queries. Run 'msgvault build-cache' to generate them.
   Use --force-sql to bypass Parquet and query SQLite directly (slow).
 
+Live Refresh:
+  When a sync elsewhere, such as in 'msgvault serve', adds mail, list and
+  aggregate views reload in place within seconds, keeping the cursor on
+  its row. With the Parquet cache, new mail shows once the cache is
+  rebuilt, which serve does after each sync.
+
 Remote Mode:
   When [remote].url is configured, the TUI connects to a remote msgvault server.
   Use --local to force local database. Deletion and export are disabled in remote mode.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var engine query.Engine
 		var isRemote bool
+		var annotator tui.Annotator
+		var triager tui.Triager
+		var reminders tui.Reminders
+		var savedSearches tui.SavedSearches
+		var changes tui.ChangeFeed
 
 		// Check for remote mode (unless --local flag is set)
 		if cfg.Remote.URL != "" && !forceLocalTUI {
@@ -86,6 +105,10 @@ Remote Mode:
 				return fmt.Errorf("open database: %w", err)
 			}
 			defer func() { _ = s.Close() }()
+			annotator = s
+			triager = s
+			reminders = s
+			savedSearches = s
 
 			// Ensure schema is up to date
 			if err := s.InitSchema(); err != nil {
@@ -95,13 +118,9 @@ Remote Mode:
 				return fmt.Errorf("startup migrations: %w", err)
 			}
 
-			// Build FTS index in background — TUI uses DuckDB/Parquet for
-			// aggregates and only needs FTS for deep search (Tab to switch).
-			if s.NeedsFTSBackfill() {
-				go func() {
-					_, _ = s.BackfillFTS(nil)
-				}()
-			}
+			// The TUI uses DuckDB/Parquet for aggregates and only needs
+			// FTS for deep search (Tab to switch).
+			warnIncompleteFTS(s)
 
 			analyticsDir := cfg.AnalyticsDir()
 
@@ -132,9 +151,11 @@ Remote Mode:
 					fmt.Fprintf(os.Stderr, "Warning: Failed to open Parquet engine: %v\n", err)
 					fmt.Fprintf(os.Stderr, "Falling back to SQLite (may be slow for large archives)\n")
 					engine = query.NewSQLiteEngine(s.DB())
+					changes = s
 				} else {
 					engine = duckEngine
 					defer func() { _ = duckEngine.Close() }()
+					changes = cacheChanges{analyticsDir: analyticsDir}
 				}
 			} else {
 				// Use SQLite directly
@@ -143,6 +164,7 @@ Remote Mode:
 					fmt.Fprintf(os.Stderr, "Run 'msgvault build-cache' to enable fast queries.\n")
 				}
 				engine = query.NewSQLiteEngine(s.DB())
+				changes = s
 			}
 		}
 
@@ -154,12 +176,21 @@ Remote Mode:
 
 		// Create and run TUI
 		model := tui.New(engine, tui.Options{
-			DataDir:    cfg.Data.DataDir,
-			Version:    Version,
-			IsRemote:   isRemote,
-			TextEngine: textEngine,
+			DataDir:        cfg.Data.DataDir,
+			AttachmentsDir: cfg.AttachmentsDir(),
+			Version:        Version,
+			IsRemote:       isRemote,
+			TextEngine:     textEngine,
+			Annotator:      annotator,
+			Triager:        triager,
+			Reminders:      reminders,
+			SavedSearches:  savedSearches,
+			Changes:        changes,
 		})
-		p := tea.NewProgram(model, tea.WithAltScreen())
+		// The guard ends the program on a panic so the terminal is
+		// restored before the panic reaches the crash reporter.
+		guard := tui.NewGuard(model)
+		p := tea.NewProgram(guard, tea.WithAltScreen())
 
 		// Swap the slog default to a file-only logger for the
 		// duration of the TUI. Bubble Tea owns the terminal in
@@ -176,6 +207,9 @@ Remote Mode:
 		if _, err := p.Run(); err != nil {
 			return fmt.Errorf("run tui: %w", err)
 		}
+		if r := guard.Panic(); r != nil {
+			panic(r)
+		}
 
 		return nil
 	},
@@ -191,6 +225,25 @@ type cacheStaleness struct {
 	Reason      string
 }
 
+// cacheChanges is the TUI's change feed when it reads the Parquet
+// cache: mail shows there only once a cache build has exported it, so
+// the newest exported message is what counts.
+type cacheChanges struct {
+	analyticsDir string
+}
+
+func (c cacheChanges) MaxMessageID() (int64, error) {
+	data, err := os.ReadFile(filepath.Join(c.analyticsDir, "_last_sync.json"))
+	if err != nil {
+		return 0, err
+	}
+	var state syncState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, err
+	}
+	return state.LastMessageID, nil
+}
+
 // cacheNeedsBuild checks if the analytics cache needs to be built or
 // updated. Collects all staleness signals before returning so that
 // e.g. a mixed add+delete sync correctly reports both.
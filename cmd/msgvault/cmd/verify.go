@@ -117,7 +117,7 @@ Examples:
 		// the stored refresh token (and may prompt for re-auth in TTY).
 		var tokenSource oauth2.TokenSource
 		if saKeyPath := cfg.OAuth.ServiceAccountKeyFor(appName); saKeyPath != "" {
-			saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, oauth.Scopes)
+			saMgr, saErr := oauth.NewServiceAccountManager(saKeyPath, gmailScopes())
 			if saErr != nil {
 				return fmt.Errorf("service account: %w", saErr)
 			}
@@ -130,7 +130,7 @@ Examples:
 			if secretsErr != nil {
 				return secretsErr
 			}
-			oauthMgr, mgrErr := oauth.NewManager(clientSecretsPath, cfg.TokensDir(), logger)
+			oauthMgr, mgrErr := oauth.NewManagerWithScopes(clientSecretsPath, cfg.TokensDir(), logger, gmailScopes())
 			if mgrErr != nil {
 				return wrapOAuthError(fmt.Errorf("create oauth manager: %w", mgrErr))
 			}
@@ -299,7 +299,7 @@ func runIntegrityCheck(s *store.Store) ([]string, error) {
 
 // printIntegrityRecoveryHint prints repair guidance tailored to the kind of
 // corruption reported. FTS5 shadow-table corruption is fixable with the
-// lightweight `rebuild-fts` command; core B-tree corruption needs `.recover`,
+// lightweight `index rebuild --fts` command; core B-tree corruption needs `.recover`,
 // which requires free disk roughly equal to the database size.
 func printIntegrityRecoveryHint(integrityErrors []string) {
 	var ftsErrs, coreErrs int
@@ -317,7 +317,7 @@ func printIntegrityRecoveryHint(integrityErrors []string) {
 
 	if ftsErrs > 0 {
 		fmt.Println("  Search index (FTS5) corruption:")
-		fmt.Println("    Run: msgvault rebuild-fts")
+		fmt.Println("    Run: " + ftsRebuildHint)
 		fmt.Println("    Drops and recreates messages_fts from the core tables.")
 		fmt.Println("    SQLite's 'rebuild' pragma reads from the corrupt shadow")
 		fmt.Println("    tables and cannot clear this state.")
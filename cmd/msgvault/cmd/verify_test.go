@@ -3,7 +3,7 @@ package cmd
 import "testing"
 
 // TestIsFTSIntegrityError_Classification verifies that the hint-classifier
-// cleanly separates FTS5 shadow-table errors (which rebuild-fts can fix)
+// cleanly separates FTS5 shadow-table errors (which index rebuild --fts can fix)
 // from core-table errors (which need .recover). Messages come from real
 // PRAGMA integrity_check output; the shapes below are what users will see.
 func TestIsFTSIntegrityError_Classification(t *testing.T) {
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/wesm/msgvault/internal/gmailwatch"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [email...]",
+	Short: "Sync Gmail accounts as soon as Gmail reports a change",
+	Long: `Run until interrupted, syncing Gmail accounts whenever Gmail reports a
+change through Cloud Pub/Sub push notifications instead of polling.
+
+On start, watch registers a Gmail watch (users.watch) for each account that
+publishes mailbox changes to the [watch] topic, and syncs each account once
+to catch up. It then reads notifications from the [watch] subscription and
+runs an incremental sync of each account that changed, renewing every
+watch daily, well before Gmail's seven-day expiry. On exit it stops the
+watches.
+
+With no email, every Gmail account that has completed a full sync is
+watched.
+
+Setup, once per Google Cloud project:
+  1. Create a Pub/Sub topic and a pull subscription to it.
+  2. Grant gmail-api-push@system.gserviceaccount.com the Pub/Sub Publisher
+     role on the topic.
+  3. Set them in config.toml, with credentials that may pull from the
+     subscription (or use Application Default Credentials):
+
+     [watch]
+     topic = "projects/my-project/topics/msgvault"
+     subscription = "projects/my-project/subscriptions/msgvault"
+     credentials = "~/.msgvault/pubsub-key.json"
+
+Examples:
+  msgvault watch
+  msgvault watch you@gmail.com`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := MustBeLocal("watch"); err != nil {
+			return err
+		}
+		if cfg.Watch.Topic == "" || cfg.Watch.Subscription == "" {
+			return fmt.Errorf("set topic and subscription under [watch] in config.toml first (see 'msgvault watch --help')")
+		}
+
+		s, err := openLocalStoreAndInit()
+		if err != nil {
+			return fmt.Errorf("open database: %w", err)
+		}
+		defer func() { _ = s.Close() }()
+		applyParseConfig(s)
+
+		sources, err := watchSources(s, args)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		vf, err := setupVectorFeatures(ctx, s.DB(), cfg.DatabaseDSN())
+		if err != nil {
+			return fmt.Errorf("vector features: %w", err)
+		}
+		defer func() {
+			if vf != nil && vf.Close != nil {
+				if closeErr := vf.Close(); closeErr != nil {
+					logger.Warn("closing vectors.db failed", "error", closeErr)
+				}
+			}
+		}()
+
+		sub, err := gmailwatch.OpenSubscription(ctx, cfg.Watch.Subscription, cfg.Watch.Credentials)
+		if err != nil {
+			return err
+		}
+		notifier, err := newNotifier()
+		if err != nil {
+			return err
+		}
+		getOAuthMgr := oauthManagerCache()
+		syncFunc := func(ctx context.Context, email string) error {
+			summary, err := runScheduledSync(ctx, email, s, getOAuthMgr, vf)
+			notifyScheduledSync(ctx, notifier, email, summary, err)
+			return err
+		}
+
+		runner := gmailwatch.New(sub, syncFunc, gmailwatch.Options{
+			Topic:    cfg.Watch.Topic,
+			LabelIDs: cfg.Watch.Labels,
+		}).WithLogger(logger)
+		for _, src := range sources {
+			client, err := gmailIncrementalClient(ctx, getOAuthMgr, src)
+			if err != nil {
+				return fmt.Errorf("%s: %w", src.Identifier, err)
+			}
+			defer func() { _ = client.Close() }()
+			runner.AddAccount(src.Identifier, client)
+		}
+
+		fmt.Printf("Watching %d Gmail account(s) for changes. Press Ctrl+C to stop.\n", len(sources))
+		if err := runner.Run(ctx); err != nil {
+			return err
+		}
+		fmt.Println("\nStopped watching.")
+		return nil
+	},
+}
+
+// watchSources returns the Gmail accounts to watch: those named, or
+// every one with a history ID to sync incrementally from.
+func watchSources(s *store.Store, emails []string) ([]*store.Source, error) {
+	if len(emails) > 0 {
+		sources := make([]*store.Source, 0, len(emails))
+		for _, email := range emails {
+			src, err := resolveSource(s, email, "gmail")
+			if err != nil {
+				return nil, err
+			}
+			if !src.SyncCursor.Valid || src.SyncCursor.String == "" {
+				return nil, fmt.Errorf("%s has no history ID - run 'sync-full %s' first", email, email)
+			}
+			sources = append(sources, src)
+		}
+		return sources, nil
+	}
+
+	all, err := s.ListSources("gmail")
+	if err != nil {
+		return nil, err
+	}
+	var sources []*store.Source
+	for _, src := range all {
+		if src.SyncCursor.Valid && src.SyncCursor.String != "" {
+			sources = append(sources, src)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no Gmail accounts have completed a full sync; run 'sync-full' first")
+	}
+	return sources, nil
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Principal is the authenticated caller of an API request.
+type Principal struct {
+	Name  string
+	Admin bool
+	// Accounts lists the source identifiers a non-admin may read.
+	Accounts []string
+	// AllAccounts lets a non-admin read every account. Set for API
+	// tokens, which are scoped by operation rather than by account.
+	AllAccounts bool
+	// Scopes limits which operations the principal may perform (see
+	// store.APITokenScopes). Nil allows every non-admin operation, as
+	// for keys configured in config.toml.
+	Scopes []string
+}
+
+// TokenStore resolves API tokens issued with `msgvault token create`.
+// Servers pick it up from their store when the store implements it.
+type TokenStore interface {
+	LookupAPIToken(token string) (*store.APIToken, error)
+	HasActiveAPITokens() (bool, error)
+}
+
+// anonymousPrincipal is used when no API key or users are configured:
+// the server is trusted by network position alone (loopback or
+// allow_insecure), matching the pre-users behavior.
+var anonymousPrincipal = &Principal{Name: "anonymous", Admin: true}
+
+// ResolvePrincipal maps a presented API key to its principal. The
+// [server] api_key resolves to an admin; [[server.users]] keys resolve
+// to the named user. Every configured key is compared in constant time
+// so the lookup does not reveal which entry matched.
+func ResolvePrincipal(cfg config.ServerConfig, key string) (*Principal, bool) {
+	if !cfg.RequiresAuth() {
+		return anonymousPrincipal, true
+	}
+	var match *Principal
+	if cfg.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg.APIKey)) == 1 {
+		match = &Principal{Name: "admin", Admin: true}
+	}
+	for _, u := range cfg.Users {
+		if u.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(u.APIKey)) == 1 && match == nil {
+			match = &Principal{Name: u.Name, Admin: u.Admin, Accounts: slices.Clone(u.Accounts)}
+		}
+	}
+	return match, match != nil
+}
+
+// ResolveKey maps a presented key to its principal like
+// ResolvePrincipal, additionally accepting tokens from tokens (which
+// may be nil). Once any token is active the server requires
+// authentication even when config.toml sets no key; lookup errors fail
+// closed.
+func ResolveKey(cfg config.ServerConfig, tokens TokenStore, key string) (*Principal, bool) {
+	if tokens == nil {
+		return ResolvePrincipal(cfg, key)
+	}
+	if strings.HasPrefix(key, store.APITokenPrefix) {
+		t, err := tokens.LookupAPIToken(key)
+		if err == nil {
+			return tokenPrincipal(t), true
+		}
+		if !errors.Is(err, store.ErrAPITokenNotFound) {
+			return nil, false
+		}
+	}
+	if !cfg.RequiresAuth() {
+		if active, err := tokens.HasActiveAPITokens(); err != nil || active {
+			return nil, false
+		}
+	}
+	return ResolvePrincipal(cfg, key)
+}
+
+// RequiresAuth reports whether requests must present a key: when
+// config.toml sets one or any API token is active.
+func RequiresAuth(cfg config.ServerConfig, tokens TokenStore) bool {
+	if cfg.RequiresAuth() || tokens == nil {
+		return cfg.RequiresAuth()
+	}
+	active, err := tokens.HasActiveAPITokens()
+	return err != nil || active
+}
+
+// tokenPrincipal builds the principal for a vault-issued token.
+func tokenPrincipal(t *store.APIToken) *Principal {
+	return &Principal{
+		Name:        "token:" + t.Name,
+		Admin:       t.HasScope(store.ScopeAdmin),
+		AllAccounts: true,
+		Scopes:      slices.Clone(t.Scopes),
+	}
+}
+
+// CanAccessAccount reports whether the principal may read or sync the
+// account with the given source identifier.
+func (p *Principal) CanAccessAccount(identifier string) bool {
+	return p.Admin || p.AllAccounts || slices.Contains(p.Accounts, identifier)
+}
+
+// HasScope reports whether the principal may perform operations of
+// the given scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p.Admin || p.Scopes == nil || slices.Contains(p.Scopes, scope)
+}
+
+// AccessStore resolves message and attachment ownership for per-user
+// scoping. Required when non-admin [[server.users]] are configured;
+// without it, scoped requests fail closed with 503.
+type AccessStore interface {
+	SourceIDsForAccounts(identifiers []string) ([]int64, error)
+	MessageSourceID(id int64) (int64, bool, error)
+	AttachmentSourceID(id int64) (int64, bool, error)
+	GetStatsForScope(sourceIDs []int64) (*StoreStats, error)
+}
+
+// requestScope is the per-request authorization result stored in the
+// request context by authMiddleware.
+type requestScope struct {
+	principal *Principal
+	// sourceIDs is the resolved set of readable sources for a non-admin
+	// principal. Always non-empty for non-admins (authMiddleware rejects
+	// users whose accounts resolve to nothing).
+	sourceIDs []int64
+}
+
+type scopeKey struct{}
+
+func withScope(ctx context.Context, sc *requestScope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, sc)
+}
+
+// scopeFrom returns the request's scope. Requests that bypassed
+// authMiddleware (tests calling handlers directly) are unrestricted.
+func scopeFrom(r *http.Request) *requestScope {
+	if sc, ok := r.Context().Value(scopeKey{}).(*requestScope); ok {
+		return sc
+	}
+	return &requestScope{principal: anonymousPrincipal}
+}
+
+// PrincipalFromContext returns the authenticated principal for a
+// request context, or nil when none was recorded.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	if sc, ok := ctx.Value(scopeKey{}).(*requestScope); ok {
+		return sc.principal
+	}
+	return nil
+}
+
+// RequestScope returns the authenticated caller's name and the sources
+// it may read (nil = every source), for handlers from other packages
+// mounted behind authMiddleware.
+func RequestScope(r *http.Request) (user string, sourceIDs []int64) {
+	sc := scopeFrom(r)
+	if !sc.restricted() {
+		return sc.principal.Name, nil
+	}
+	return sc.principal.Name, slices.Clone(sc.sourceIDs)
+}
+
+// restricted reports whether the scope limits which sources are visible.
+func (sc *requestScope) restricted() bool {
+	return !sc.principal.Admin && !sc.principal.AllAccounts
+}
+
+// allows reports whether a source is visible in this scope.
+func (sc *requestScope) allows(sourceID int64) bool {
+	return !sc.restricted() || slices.Contains(sc.sourceIDs, sourceID)
+}
+
+// narrow combines a caller-requested source filter with the scope. For
+// unrestricted scopes it returns the request unchanged. For restricted
+// scopes it returns the visible subset as a multi-source filter; a
+// non-nil empty slice means "match nothing", matching the query
+// package's SourceIDs convention.
+func (sc *requestScope) narrow(single *int64, multi []int64) (*int64, []int64) {
+	if !sc.restricted() {
+		return single, multi
+	}
+	requested := multi
+	if requested == nil && single != nil {
+		requested = []int64{*single}
+	}
+	if requested == nil {
+		return nil, slices.Clone(sc.sourceIDs)
+	}
+	out := make([]int64, 0, len(requested))
+	for _, id := range requested {
+		if slices.Contains(sc.sourceIDs, id) {
+			out = append(out, id)
+		}
+	}
+	return nil, out
+}
+
+// requireScope rejects requests whose principal lacks scope.
+func requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !scopeFrom(r).principal.HasScope(scope) {
+				writeError(w, http.StatusForbidden, "insufficient_scope", "This API token lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireAdmin writes 403 and returns false when the caller is not an
+// admin. Used for endpoints that mutate server state or bypass scoping.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if scopeFrom(r).principal.Admin {
+		return true
+	}
+	writeError(w, http.StatusForbidden, "forbidden", "This endpoint requires an admin API key")
+	return false
+}
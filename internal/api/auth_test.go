@@ -0,0 +1,465 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/query/querytest"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// fakeAccess maps accounts, messages, and attachments to source IDs.
+type fakeAccess struct {
+	sources     map[string]int64
+	messages    map[int64]int64
+	attachments map[int64]int64
+	scopedStats []int64
+}
+
+func (f *fakeAccess) SourceIDsForAccounts(identifiers []string) ([]int64, error) {
+	var ids []int64
+	for _, ident := range identifiers {
+		if id, ok := f.sources[ident]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeAccess) MessageSourceID(id int64) (int64, bool, error) {
+	src, ok := f.messages[id]
+	return src, ok, nil
+}
+
+func (f *fakeAccess) AttachmentSourceID(id int64) (int64, bool, error) {
+	src, ok := f.attachments[id]
+	return src, ok, nil
+}
+
+func (f *fakeAccess) GetStatsForScope(sourceIDs []int64) (*StoreStats, error) {
+	f.scopedStats = sourceIDs
+	return &StoreStats{MessageCount: 1, SourceCount: int64(len(sourceIDs))}, nil
+}
+
+// newScopedTestServer returns a server with an admin key ("admin-key"),
+// a user alice restricted to alice@example.com (source 1), and a user
+// carol whose account has no synced source.
+func newScopedTestServer(t *testing.T, engine *querytest.MockEngine) (*Server, *mockStore, *fakeAccess) {
+	t.Helper()
+	store := &mockStore{
+		stats:    &StoreStats{MessageCount: 10},
+		messages: []APIMessage{{ID: 1, Subject: "Hello"}},
+		total:    1,
+	}
+	access := &fakeAccess{
+		sources:     map[string]int64{"alice@example.com": 1, "bob@example.com": 2},
+		messages:    map[int64]int64{1: 1, 2: 2},
+		attachments: map[int64]int64{10: 1, 20: 2},
+	}
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			APIPort: 8080,
+			APIKey:  "admin-key",
+			Users: []config.ServerUser{
+				{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}},
+				{Name: "carol", APIKey: "carol-key", Accounts: []string{"carol@example.com"}},
+			},
+		},
+		Accounts: []config.AccountSchedule{
+			{Email: "alice@example.com", Schedule: "0 2 * * *", Enabled: true},
+			{Email: "bob@example.com", Schedule: "0 3 * * *", Enabled: true},
+		},
+	}
+	sched := newMockScheduler()
+	sched.scheduled["alice@example.com"] = true
+	sched.scheduled["bob@example.com"] = true
+
+	var eng query.Engine
+	if engine != nil {
+		eng = engine
+	}
+	srv := NewServerWithOptions(ServerOptions{
+		Config:    cfg,
+		Store:     store,
+		Engine:    eng,
+		Scheduler: sched,
+		Access:    access,
+		Logger:    testLogger(),
+	})
+	return srv, store, access
+}
+
+func doScoped(srv *Server, method, target, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader("{}"))
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	return w
+}
+
+func TestResolvePrincipal(t *testing.T) {
+	cfg := config.ServerConfig{
+		APIKey: "admin-key",
+		Users: []config.ServerUser{
+			{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}},
+			{Name: "ops", APIKey: "ops-key", Admin: true},
+		},
+	}
+	tests := []struct {
+		key       string
+		wantOK    bool
+		wantName  string
+		wantAdmin bool
+	}{
+		{"admin-key", true, "admin", true},
+		{"alice-key", true, "alice", false},
+		{"ops-key", true, "ops", true},
+		{"wrong", false, "", false},
+		{"", false, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			p, ok := ResolvePrincipal(cfg, tt.key)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if p.Name != tt.wantName || p.Admin != tt.wantAdmin {
+				t.Errorf("principal = %+v, want name=%s admin=%v", p, tt.wantName, tt.wantAdmin)
+			}
+		})
+	}
+
+	// No keys configured: every caller is the anonymous admin.
+	p, ok := ResolvePrincipal(config.ServerConfig{}, "")
+	if !ok || !p.Admin {
+		t.Errorf("unauthenticated config: principal = %+v, ok = %v", p, ok)
+	}
+}
+
+func TestRequestScopeNarrow(t *testing.T) {
+	one := int64(1)
+	two := int64(2)
+	sc := &requestScope{principal: &Principal{Name: "alice"}, sourceIDs: []int64{1, 3}}
+
+	tests := []struct {
+		name   string
+		single *int64
+		multi  []int64
+		want   []int64
+	}{
+		{"no filter", nil, nil, []int64{1, 3}},
+		{"single allowed", &one, nil, []int64{1}},
+		{"single denied", &two, nil, []int64{}},
+		{"multi intersected", nil, []int64{1, 2, 3}, []int64{1, 3}},
+		{"multi empty stays empty", nil, []int64{}, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSingle, gotMulti := sc.narrow(tt.single, tt.multi)
+			if gotSingle != nil {
+				t.Errorf("single = %v, want nil", *gotSingle)
+			}
+			if gotMulti == nil || !slices.Equal(gotMulti, tt.want) {
+				t.Errorf("multi = %v, want %v", gotMulti, tt.want)
+			}
+		})
+	}
+
+	admin := &requestScope{principal: anonymousPrincipal}
+	if s, m := admin.narrow(&two, nil); s != &two || m != nil {
+		t.Errorf("admin narrow changed filter: %v, %v", s, m)
+	}
+}
+
+func TestScopedAuth_StatusCodes(t *testing.T) {
+	srv, _, _ := newScopedTestServer(t, &querytest.MockEngine{
+		Messages: map[int64]*query.MessageDetail{
+			1: {ID: 1, Subject: "Mine"},
+			2: {ID: 2, Subject: "Bob's"},
+		},
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		key    string
+		want   int
+	}{
+		{"missing key", "GET", "/api/v1/stats", "", http.StatusUnauthorized},
+		{"unknown key", "GET", "/api/v1/stats", "nope", http.StatusUnauthorized},
+		{"user without synced accounts", "GET", "/api/v1/stats", "carol-key", http.StatusForbidden},
+		{"own message", "GET", "/api/v1/messages/1", "alice-key", http.StatusOK},
+		{"other source message", "GET", "/api/v1/messages/2", "alice-key", http.StatusNotFound},
+		{"unknown message", "GET", "/api/v1/messages/99", "alice-key", http.StatusNotFound},
+		{"admin reads any message", "GET", "/api/v1/messages/2", "admin-key", http.StatusOK},
+		{"other source attachment", "GET", "/api/v1/attachments/20", "alice-key", http.StatusNotFound},
+		{"other source inline", "GET", "/api/v1/messages/2/inline?cid=x", "alice-key", http.StatusNotFound},
+		{"sync own account", "POST", "/api/v1/sync/alice@example.com", "alice-key", http.StatusAccepted},
+		{"sync other account", "POST", "/api/v1/sync/bob@example.com", "alice-key", http.StatusNotFound},
+		{"add account requires admin", "POST", "/api/v1/accounts", "alice-key", http.StatusForbidden},
+		{"token upload requires admin", "POST", "/api/v1/auth/token/alice@example.com", "alice-key", http.StatusForbidden},
+		{"sql requires admin", "POST", "/api/v1/query", "alice-key", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doScoped(srv, tt.method, tt.target, tt.key)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body: %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestScopedAuth_NoAccessStoreFailsClosed(t *testing.T) {
+	srv, _, _ := newScopedTestServer(t, nil)
+	srv.access = nil
+
+	if w := doScoped(srv, "GET", "/api/v1/stats", "alice-key"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("scoped user status = %d, want 503", w.Code)
+	}
+	if w := doScoped(srv, "GET", "/api/v1/stats", "admin-key"); w.Code != http.StatusOK {
+		t.Errorf("admin status = %d, want 200", w.Code)
+	}
+}
+
+func TestScopedAuth_StatsAndSearchUseScope(t *testing.T) {
+	srv, store, access := newScopedTestServer(t, nil)
+
+	w := doScoped(srv, "GET", "/api/v1/stats", "alice-key")
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats status = %d", w.Code)
+	}
+	var stats StatsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.TotalMessages != 1 || !slices.Equal(access.scopedStats, []int64{1}) {
+		t.Errorf("stats = %+v, scope = %v; want scoped to source 1", stats, access.scopedStats)
+	}
+
+	// Plain-text searches must still go through the account-filtered path.
+	w = doScoped(srv, "GET", "/api/v1/search?q=hello", "alice-key")
+	if w.Code != http.StatusOK {
+		t.Fatalf("search status = %d (body: %s)", w.Code, w.Body.String())
+	}
+	if store.lastSearchQuery == nil || !slices.Equal(store.lastSearchQuery.AccountIDs, []int64{1}) {
+		t.Errorf("search query = %+v, want AccountIDs [1]", store.lastSearchQuery)
+	}
+}
+
+func TestScopedAuth_EngineFiltersNarrowed(t *testing.T) {
+	var gotFilter query.MessageFilter
+	var gotStats query.StatsOptions
+	engine := &querytest.MockEngine{
+		ListMessagesFunc: func(_ context.Context, f query.MessageFilter) ([]query.MessageSummary, error) {
+			gotFilter = f
+			return nil, nil
+		},
+		GetTotalStatsFunc: func(_ context.Context, opts query.StatsOptions) (*query.TotalStats, error) {
+			gotStats = opts
+			return &query.TotalStats{}, nil
+		},
+	}
+	srv, _, _ := newScopedTestServer(t, engine)
+
+	// Requesting another user's source yields a match-nothing filter.
+	if w := doScoped(srv, "GET", "/api/v1/messages/filter?source_id=2", "alice-key"); w.Code != http.StatusOK {
+		t.Fatalf("filter status = %d", w.Code)
+	}
+	if gotFilter.SourceID != nil || gotFilter.SourceIDs == nil || len(gotFilter.SourceIDs) != 0 {
+		t.Errorf("filter sources = %v / %v, want empty non-nil SourceIDs", gotFilter.SourceID, gotFilter.SourceIDs)
+	}
+
+	if w := doScoped(srv, "GET", "/api/v1/stats/total", "alice-key"); w.Code != http.StatusOK {
+		t.Fatalf("total stats status = %d", w.Code)
+	}
+	if !slices.Equal(gotStats.SourceIDs, []int64{1}) {
+		t.Errorf("stats SourceIDs = %v, want [1]", gotStats.SourceIDs)
+	}
+}
+
+func TestScopedAuth_AccountListsFiltered(t *testing.T) {
+	srv, _, _ := newScopedTestServer(t, nil)
+
+	w := doScoped(srv, "GET", "/api/v1/accounts", "alice-key")
+	if w.Code != http.StatusOK {
+		t.Fatalf("accounts status = %d", w.Code)
+	}
+	var resp struct {
+		Accounts []AccountInfo `json:"accounts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Accounts) != 1 || resp.Accounts[0].Email != "alice@example.com" {
+		t.Errorf("accounts = %+v, want only alice@example.com", resp.Accounts)
+	}
+}
+
+func TestJMAPMount_AuthAndScope(t *testing.T) {
+	srv, _, _ := newScopedTestServer(t, nil)
+	srv.jmap = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ids := RequestScope(r)
+		writeJSON(w, http.StatusOK, map[string]any{"user": user, "sources": ids, "path": r.URL.Path})
+	})
+	srv.router = srv.setupRouter()
+
+	tests := []struct {
+		name        string
+		user, pass  string
+		wantStatus  int
+		wantUser    string
+		wantSources []int64
+	}{
+		{"scoped key via basic auth", "alice", "alice-key", http.StatusOK, "alice", []int64{1}},
+		{"admin key via basic auth", "anyone", "admin-key", http.StatusOK, "admin", nil},
+		{"bad password", "alice", "nope", http.StatusUnauthorized, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/jmap/session", nil)
+			req.SetBasicAuth(tt.user, tt.pass)
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var got struct {
+				User    string  `json:"user"`
+				Sources []int64 `json:"sources"`
+				Path    string  `json:"path"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.User != tt.wantUser || !slices.Equal(got.Sources, tt.wantSources) || got.Path != "/jmap/session" {
+				t.Errorf("scope = %+v, want user %q sources %v", got, tt.wantUser, tt.wantSources)
+			}
+		})
+	}
+
+	w := doScoped(srv, http.MethodGet, "/.well-known/jmap", "")
+	if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/jmap/session" {
+		t.Errorf("well-known = %d %q, want redirect to /jmap/session", w.Code, w.Header().Get("Location"))
+	}
+}
+
+// fakeTokens implements TokenStore over fixed tokens.
+type fakeTokens struct {
+	tokens map[string]*store.APIToken
+	err    error
+}
+
+func (f *fakeTokens) LookupAPIToken(token string) (*store.APIToken, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if t, ok := f.tokens[token]; ok {
+		return t, nil
+	}
+	return nil, store.ErrAPITokenNotFound
+}
+
+func (f *fakeTokens) HasActiveAPITokens() (bool, error) {
+	return len(f.tokens) > 0, f.err
+}
+
+func newFakeTokens() *fakeTokens {
+	return &fakeTokens{tokens: map[string]*store.APIToken{
+		"mvt_reader":   {Name: "reader", Scopes: []string{store.ScopeRead}},
+		"mvt_searcher": {Name: "searcher", Scopes: []string{store.ScopeSearch}},
+		"mvt_both":     {Name: "both", Scopes: []string{store.ScopeRead, store.ScopeSearch}},
+		"mvt_admin":    {Name: "ops", Scopes: []string{store.ScopeAdmin}},
+	}}
+}
+
+func TestResolveKey(t *testing.T) {
+	withKey := config.ServerConfig{APIKey: "admin-key"}
+	noKey := config.ServerConfig{}
+
+	tests := []struct {
+		name      string
+		cfg       config.ServerConfig
+		tokens    *fakeTokens
+		key       string
+		wantOK    bool
+		wantName  string
+		wantAdmin bool
+	}{
+		{name: "config key still works", cfg: withKey, tokens: newFakeTokens(), key: "admin-key", wantOK: true, wantName: "admin", wantAdmin: true},
+		{name: "read token", cfg: withKey, tokens: newFakeTokens(), key: "mvt_reader", wantOK: true, wantName: "token:reader"},
+		{name: "admin token", cfg: withKey, tokens: newFakeTokens(), key: "mvt_admin", wantOK: true, wantName: "token:ops", wantAdmin: true},
+		{name: "unknown token", cfg: withKey, tokens: newFakeTokens(), key: "mvt_nope"},
+		{name: "tokens require auth without config key", cfg: noKey, tokens: newFakeTokens(), key: ""},
+		{name: "token accepted without config key", cfg: noKey, tokens: newFakeTokens(), key: "mvt_reader", wantOK: true, wantName: "token:reader"},
+		{name: "no active tokens stays open", cfg: noKey, tokens: &fakeTokens{}, key: "", wantOK: true, wantName: "anonymous", wantAdmin: true},
+		{name: "lookup error fails closed", cfg: noKey, tokens: &fakeTokens{err: errors.New("db locked")}, key: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := ResolveKey(tt.cfg, tt.tokens, tt.key)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if p.Name != tt.wantName || p.Admin != tt.wantAdmin {
+				t.Errorf("principal = %+v, want name %q admin %v", p, tt.wantName, tt.wantAdmin)
+			}
+			if !p.CanAccessAccount("anyone@example.com") {
+				t.Error("token and admin principals should see every account")
+			}
+		})
+	}
+}
+
+func TestTokenAuth_Scopes(t *testing.T) {
+	srv, _, _ := newScopedTestServer(t, &querytest.MockEngine{
+		Messages: map[int64]*query.MessageDetail{1: {ID: 1, Subject: "Mine"}},
+	})
+	srv.tokens = newFakeTokens()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		key    string
+		want   int
+	}{
+		{"read token reads", "GET", "/api/v1/messages/1", "mvt_reader", http.StatusOK},
+		{"read token sees every account", "GET", "/api/v1/stats", "mvt_reader", http.StatusOK},
+		{"read token cannot search", "GET", "/api/v1/search?q=hello", "mvt_reader", http.StatusForbidden},
+		{"search token searches", "GET", "/api/v1/search?q=hello", "mvt_searcher", http.StatusOK},
+		{"search token cannot read", "GET", "/api/v1/messages/1", "mvt_searcher", http.StatusForbidden},
+		{"read token cannot sync", "POST", "/api/v1/sync/alice@example.com", "mvt_both", http.StatusForbidden},
+		{"read token cannot run sql", "POST", "/api/v1/query", "mvt_both", http.StatusForbidden},
+		{"admin token syncs", "POST", "/api/v1/sync/alice@example.com", "mvt_admin", http.StatusAccepted},
+		{"config user unaffected", "GET", "/api/v1/search?q=hello", "alice-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doScoped(srv, tt.method, tt.target, tt.key)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (body: %s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
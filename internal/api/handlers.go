@@ -2,20 +2,25 @@ package api
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
+	stdmime "mime"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/export"
 	"github.com/wesm/msgvault/internal/fileutil"
 	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/query"
@@ -99,18 +104,21 @@ type MessageDetail struct {
 
 // AttachmentInfo represents attachment metadata in API responses.
 type AttachmentInfo struct {
+	ID       int64  `json:"id"`
 	Filename string `json:"filename"`
 	MimeType string `json:"mime_type"`
 	Size     int64  `json:"size_bytes"`
 }
 
-// SearchResult represents search results.
+// SearchResult represents search results. Page is omitted for cursor requests; NextCursor is set when more
+// results follow.
 type SearchResult struct {
-	Query    string           `json:"query"`
-	Total    int64            `json:"total"`
-	Page     int              `json:"page"`
-	PageSize int              `json:"page_size"`
-	Messages []MessageSummary `json:"messages"`
+	Query      string           `json:"query"`
+	Total      int64            `json:"total"`
+	Page       int              `json:"page,omitempty"`
+	PageSize   int              `json:"page_size"`
+	Messages   []MessageSummary `json:"messages"`
+	NextCursor string           `json:"next_cursor,omitempty"`
 }
 
 // hybridSearchResponse represents results from vector or hybrid search.
@@ -210,6 +218,7 @@ func messageDetailFromQuery(qMsg *query.MessageDetail) MessageDetail {
 	attachments := make([]AttachmentInfo, 0, len(qMsg.Attachments))
 	for _, att := range qMsg.Attachments {
 		attachments = append(attachments, AttachmentInfo{
+			ID:       att.ID,
 			Filename: att.Filename,
 			MimeType: att.MimeType,
 			Size:     att.Size,
@@ -272,7 +281,15 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := s.store.GetStats()
+	var (
+		stats *StoreStats
+		err   error
+	)
+	if sc := scopeFrom(r); sc.restricted() {
+		stats, err = s.access.GetStatsForScope(sc.sourceIDs)
+	} else {
+		stats, err = s.store.GetStats()
+	}
 	if err != nil {
 		s.logger.Error("failed to get stats", "error", err)
 		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve statistics")
@@ -317,6 +334,11 @@ func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
 
 	offset := (page - 1) * pageSize
 
+	if sc := scopeFrom(r); sc.restricted() {
+		s.listScopedMessages(w, r, sc, page, pageSize)
+		return
+	}
+
 	messages, total, err := s.store.ListMessages(offset, pageSize)
 	if err != nil {
 		s.logger.Error("failed to list messages", "error", err)
@@ -337,6 +359,66 @@ func (s *Server) handleListMessages(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// listScopedMessages serves /messages for a restricted principal. The
+// store's ListMessages has no source filter, so this goes through the
+// query engine (newest first) and reports the scoped message count.
+func (s *Server) listScopedMessages(w http.ResponseWriter, r *http.Request, sc *requestScope, page, pageSize int) {
+	if s.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "engine_unavailable", "Query engine not available")
+		return
+	}
+	stats, err := s.access.GetStatsForScope(sc.sourceIDs)
+	if err != nil {
+		s.logger.Error("failed to count scoped messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve messages")
+		return
+	}
+	filter := query.MessageFilter{SourceIDs: sc.sourceIDs}
+	filter.Pagination.Offset = (page - 1) * pageSize
+	filter.Pagination.Limit = pageSize
+	filter.Sorting.Field = query.MessageSortByDate
+	filter.Sorting.Direction = query.SortDesc
+	filter.HideDeletedFromSource = true
+	messages, err := s.engine.ListMessages(r.Context(), filter)
+	if err != nil {
+		s.logger.Error("failed to list scoped messages", "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve messages")
+		return
+	}
+
+	summaries := make([]MessageSummary, len(messages))
+	for i, m := range messages {
+		summaries[i] = toMessageSummaryFromQuery(m)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"total":     stats.MessageCount,
+		"page":      page,
+		"page_size": pageSize,
+		"messages":  summaries,
+	})
+}
+
+// checkMessageAccess writes 404 and returns false when a restricted
+// principal may not read the message. Missing and forbidden messages
+// are indistinguishable so IDs outside the scope can't be probed.
+func (s *Server) checkMessageAccess(w http.ResponseWriter, r *http.Request, id int64) bool {
+	sc := scopeFrom(r)
+	if !sc.restricted() {
+		return true
+	}
+	sourceID, found, err := s.access.MessageSourceID(id)
+	if err != nil {
+		s.logger.Error("failed to check message access", "id", id, "error", err)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to retrieve message")
+		return false
+	}
+	if !found || !sc.allows(sourceID) {
+		writeError(w, http.StatusNotFound, "not_found", "Message not found")
+		return false
+	}
+	return true
+}
+
 // handleGetMessage returns a single message by ID.
 // When the query engine is available, it returns separate body_html for rich
 // rendering; otherwise it falls back to the store layer (plain Body only).
@@ -347,6 +429,9 @@ func (s *Server) handleGetMessage(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid_id", "Message ID must be a number")
 		return
 	}
+	if !s.checkMessageAccess(w, r, id) {
+		return
+	}
 
 	if s.engine != nil {
 		qMsg, err := s.engine.GetMessage(r.Context(), id)
@@ -440,29 +525,57 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	if page < 1 {
-		page = 1
-	}
 	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
 	if pageSize < 1 || pageSize > 100 {
 		pageSize = 20
 	}
+	fields, err := parseSummaryFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_fields", err.Error())
+		return
+	}
+	cur, err := parseSearchCursor(r, query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_cursor", err.Error())
+		return
+	}
 
-	offset := (page - 1) * pageSize
+	page := 0
+	offset := cur.Offset
+	if r.URL.Query().Get("cursor") == "" {
+		page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+		if page < 1 {
+			page = 1
+		}
+		offset = (page - 1) * pageSize
+	}
 
 	parsedQuery := search.Parse(query)
 	parsedQuery.HideDeleted = true
+	parsedQuery.Sort, parsedQuery.SortAsc = searchSortFields[cur.Sort], cur.Asc
+	parsedQuery.SeekID = cur.SeekID
+	sc := scopeFrom(r)
+	if sc.restricted() {
+		_, parsedQuery.AccountIDs = sc.narrow(nil, parsedQuery.AccountIDs)
+		if len(parsedQuery.AccountIDs) == 0 {
+			writeSearchResult(w, SearchResult{
+				Query: query, Page: page, PageSize: pageSize, Messages: []MessageSummary{},
+			}, fields)
+			return
+		}
+	}
 
 	var (
 		messages []store.APIMessage
 		total    int64
-		err      error
 	)
-	if parsedQuery.HasOperators() {
-		messages, total, err = s.store.SearchMessagesQuery(parsedQuery, offset, pageSize)
+	// One extra row tells whether another page follows. Scoped and
+	// sorted searches always take the structured path: plain
+	// SearchMessages has neither an account filter nor sort options.
+	if parsedQuery.HasOperators() || sc.restricted() || parsedQuery.Sort != search.SortRelevance {
+		messages, total, err = s.store.SearchMessagesQuery(parsedQuery, offset, pageSize+1)
 	} else {
-		messages, total, err = s.store.SearchMessages(query, offset, pageSize)
+		messages, total, err = s.store.SearchMessages(query, offset, pageSize+1)
 	}
 	if err != nil {
 		s.logger.Error("search failed", "query", query, "error", err)
@@ -470,18 +583,157 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var next string
+	if len(messages) > pageSize {
+		messages = messages[:pageSize]
+		nc := cur
+		if parsedQuery.Sort == search.SortRelevance {
+			nc.Offset = offset + pageSize
+		} else {
+			nc.SeekID = messages[len(messages)-1].ID
+		}
+		next = nc.encode()
+	}
+
 	summaries := make([]MessageSummary, len(messages))
 	for i, m := range messages {
 		summaries[i] = toMessageSummary(m)
 	}
 
-	writeJSON(w, http.StatusOK, SearchResult{
-		Query:    query,
-		Total:    total,
-		Page:     page,
-		PageSize: pageSize,
-		Messages: summaries,
-	})
+	writeSearchResult(w, SearchResult{
+		Query:      query,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		Messages:   summaries,
+		NextCursor: next,
+	}, fields)
+}
+
+// searchSortFields maps the sort parameter of /search to store orderings.
+var searchSortFields = map[string]search.SortField{
+	"relevance": search.SortRelevance,
+	"date":      search.SortDate,
+	"size":      search.SortSize,
+}
+
+// searchCursor is the decoded form of a /search page cursor. Date and
+// size sorts resume after the last message returned, so messages
+// archived mid-scan don't shift later pages; relevance order has no
+// stable key and resumes by offset.
+type searchCursor struct {
+	Query  string `json:"q"` // fingerprint of the q parameter
+	Sort   string `json:"s"`
+	Asc    bool   `json:"a,omitempty"`
+	SeekID int64  `json:"id,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+// queryFingerprint ties a cursor to the query it was issued for.
+func queryFingerprint(q string) string {
+	sum := sha256.Sum256([]byte(q))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (c searchCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parseSearchCursor returns the paging state for a /search request:
+// the decoded cursor parameter, or a fresh cursor built from the sort
+// and direction parameters. A cursor carries its own sort order, so
+// those parameters are ignored when one is given.
+func parseSearchCursor(r *http.Request, q string) (searchCursor, error) {
+	params := r.URL.Query()
+	raw := params.Get("cursor")
+	if raw == "" {
+		sort := strings.ToLower(params.Get("sort"))
+		if sort == "" {
+			sort = "relevance"
+		}
+		if _, ok := searchSortFields[sort]; !ok {
+			return searchCursor{}, fmt.Errorf("sort must be one of relevance|date|size, got %q", sort)
+		}
+		return searchCursor{
+			Query: queryFingerprint(q),
+			Sort:  sort,
+			Asc:   parseSortDirection(params.Get("direction")) == query.SortAsc,
+		}, nil
+	}
+	if params.Get("page") != "" {
+		return searchCursor{}, errors.New("cursor and page cannot be combined")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return searchCursor{}, errors.New("malformed cursor")
+	}
+	var c searchCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return searchCursor{}, errors.New("malformed cursor")
+	}
+	if _, ok := searchSortFields[c.Sort]; !ok || c.Offset < 0 || c.SeekID < 0 {
+		return searchCursor{}, errors.New("malformed cursor")
+	}
+	if c.Query != queryFingerprint(q) {
+		return searchCursor{}, errors.New("cursor was issued for a different query")
+	}
+	return c, nil
+}
+
+// summaryFields lists the JSON names a /search fields parameter may select.
+var summaryFields = []string{
+	"id", "conversation_id", "subject", "from", "to", "cc", "bcc",
+	"sent_at", "deleted_at", "snippet", "labels", "has_attachments", "size_bytes",
+}
+
+// parseSummaryFields parses a comma-separated fields parameter. An empty
+// parameter selects every field and returns nil.
+func parseSummaryFields(v string) ([]string, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !slices.Contains(summaryFields, f) {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		fields = append(fields, f)
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("fields must name at least one field")
+	}
+	return fields, nil
+}
+
+// writeSearchResult writes res, trimming each message to fields when
+// the client asked for a subset.
+func writeSearchResult(w http.ResponseWriter, res SearchResult, fields []string) {
+	if fields == nil {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	trimmed := make([]map[string]any, len(res.Messages))
+	for i, m := range res.Messages {
+		data, _ := json.Marshal(m)
+		var all map[string]any
+		_ = json.Unmarshal(data, &all)
+		trimmed[i] = make(map[string]any, len(fields))
+		for _, f := range fields {
+			if v, ok := all[f]; ok {
+				trimmed[i][f] = v
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		SearchResult
+		Messages []map[string]any `json:"messages"`
+	}{res, trimmed})
 }
 
 // handleHybridSearch runs vector or hybrid search via the configured
@@ -524,6 +776,13 @@ func (s *Server) handleHybridSearch(
 		writeError(w, http.StatusInternalServerError, "internal_error", "filter resolution failed")
 		return
 	}
+	if sc := scopeFrom(r); sc.restricted() {
+		_, filter.SourceIDs = sc.narrow(nil, filter.SourceIDs)
+		if len(filter.SourceIDs) == 0 {
+			writeError(w, http.StatusForbidden, "forbidden", "Requested accounts are not visible to this API key")
+			return
+		}
+	}
 
 	req := hybrid.SearchRequest{
 		Mode:         hybrid.Mode(mode),
@@ -628,9 +887,14 @@ func (s *Server) handleListAccounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	principal := scopeFrom(r).principal
 	s.cfgMu.RLock()
-	cfgAccounts := make([]config.AccountSchedule, len(s.cfg.Accounts))
-	copy(cfgAccounts, s.cfg.Accounts)
+	cfgAccounts := make([]config.AccountSchedule, 0, len(s.cfg.Accounts))
+	for _, acc := range s.cfg.Accounts {
+		if principal.CanAccessAccount(acc.Email) {
+			cfgAccounts = append(cfgAccounts, acc)
+		}
+	}
 	s.cfgMu.RUnlock()
 
 	// Build source ID lookup from the engine (database sources table).
@@ -692,7 +956,9 @@ func (s *Server) handleTriggerSync(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !s.scheduler.IsScheduled(account) {
+	// Report inaccessible accounts as unscheduled so a scoped key can't
+	// enumerate which accounts exist on the server.
+	if !scopeFrom(r).principal.CanAccessAccount(account) || !s.scheduler.IsScheduled(account) {
 		writeError(w, http.StatusNotFound, "not_found", "Account is not scheduled: "+account)
 		return
 	}
@@ -718,9 +984,12 @@ func (s *Server) handleSchedulerStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	statuses := s.scheduler.Status()
-	if statuses == nil {
-		statuses = []AccountStatus{}
+	principal := scopeFrom(r).principal
+	statuses := []AccountStatus{}
+	for _, st := range s.scheduler.Status() {
+		if principal.CanAccessAccount(st.Email) {
+			statuses = append(statuses, st)
+		}
 	}
 
 	writeJSON(w, http.StatusOK, SchedulerStatusResponse{
@@ -740,6 +1009,9 @@ type tokenFile struct {
 // handleUploadToken accepts a token from a remote client and saves it.
 // POST /api/v1/auth/token/{email}
 func (s *Server) handleUploadToken(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
 	email := chi.URLParam(r, "email")
 	if email == "" {
 		writeError(w, http.StatusBadRequest, "missing_email", "Email address is required")
@@ -863,6 +1135,9 @@ type AddAccountRequest struct {
 // handleAddAccount adds an account to the config file.
 // POST /api/v1/accounts
 func (s *Server) handleAddAccount(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
 	var req AddAccountRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		s.logger.Warn("invalid account request JSON", "error", err)
@@ -951,6 +1226,10 @@ type queryRequest struct {
 // handleQuery executes a raw SQL query against DuckDB views.
 // POST /api/v1/query
 func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	// Raw SQL can read any source, so it bypasses per-user scoping.
+	if !requireAdmin(w, r) {
+		return
+	}
 	querier, ok := s.engine.(query.SQLQuerier)
 	if !ok {
 		writeError(w, http.StatusServiceUnavailable,
@@ -1322,6 +1601,7 @@ func (s *Server) handleAggregates(w http.ResponseWriter, r *http.Request) {
 	}
 
 	opts := parseAggregateOptions(r)
+	opts.SourceID, opts.SourceIDs = scopeFrom(r).narrow(opts.SourceID, opts.SourceIDs)
 
 	rows, err := s.engine.Aggregate(r.Context(), viewType, opts)
 	if err != nil {
@@ -1361,8 +1641,11 @@ func (s *Server) handleSubAggregates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sc := scopeFrom(r)
 	filter := parseMessageFilter(r)
+	filter.SourceID, filter.SourceIDs = sc.narrow(filter.SourceID, filter.SourceIDs)
 	opts := parseAggregateOptions(r)
+	opts.SourceID, opts.SourceIDs = sc.narrow(opts.SourceID, opts.SourceIDs)
 
 	rows, err := s.engine.SubAggregate(r.Context(), filter, viewType, opts)
 	if err != nil {
@@ -1391,6 +1674,7 @@ func (s *Server) handleFilteredMessages(w http.ResponseWriter, r *http.Request)
 	}
 
 	filter := parseMessageFilter(r)
+	filter.SourceID, filter.SourceIDs = scopeFrom(r).narrow(filter.SourceID, filter.SourceIDs)
 	if filter.Pagination.Limit <= 0 {
 		filter.Pagination.Limit = maxPageSize
 	}
@@ -1460,6 +1744,7 @@ func (s *Server) handleTotalStats(w http.ResponseWriter, r *http.Request) {
 			opts.GroupBy = viewType
 		}
 	}
+	opts.SourceID, opts.SourceIDs = scopeFrom(r).narrow(opts.SourceID, opts.SourceIDs)
 
 	stats, err := s.engine.GetTotalStats(r.Context(), opts)
 	if err != nil {
@@ -1520,6 +1805,7 @@ func (s *Server) handleFastSearch(w http.ResponseWriter, r *http.Request) {
 		limit = maxPageSize
 	}
 
+	filter.SourceID, filter.SourceIDs = scopeFrom(r).narrow(filter.SourceID, filter.SourceIDs)
 	q := search.Parse(queryStr)
 
 	result, err := s.engine.SearchFastWithStats(r.Context(), q, queryStr, filter, statsGroupBy, limit, offset)
@@ -1542,8 +1828,9 @@ func (s *Server) handleFastSearch(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleDeepSearch performs full-text body search via FTS5.
-// GET /api/v1/search/deep?q=invoice&offset=0&limit=100&source_id=1&hide_deleted=true
+// handleDeepSearch performs full-text body search via FTS5. Results
+// come newest first, or ranked by BM25 relevance with sort=relevance.
+// GET /api/v1/search/deep?q=invoice&offset=0&limit=100&source_id=1&hide_deleted=true&sort=relevance
 func (s *Server) handleDeepSearch(w http.ResponseWriter, r *http.Request) {
 	if s.engine == nil {
 		writeError(w, http.StatusServiceUnavailable, "engine_unavailable", "Query engine not available")
@@ -1582,8 +1869,13 @@ func (s *Server) handleDeepSearch(w http.ResponseWriter, r *http.Request) {
 		limit = 100
 	}
 
+	filter.SourceID, filter.SourceIDs = scopeFrom(r).narrow(filter.SourceID, filter.SourceIDs)
 	q := search.Parse(queryStr)
 	merged := query.MergeFilterIntoQuery(q, filter)
+	merged.Sort = search.SortDate
+	if strings.EqualFold(r.URL.Query().Get("sort"), "relevance") {
+		merged.Sort = search.SortRelevance
+	}
 
 	// Fetch one extra row to determine has_more accurately.
 	messages, err := s.engine.Search(r.Context(), merged, limit+1, offset)
@@ -1644,6 +1936,9 @@ func (s *Server) handleMessageInline(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "missing_cid", "Missing 'cid' query parameter")
 		return
 	}
+	if !s.checkMessageAccess(w, r, id) {
+		return
+	}
 
 	raw, err := s.engine.GetMessageRaw(r.Context(), id)
 	if err != nil {
@@ -1686,3 +1981,77 @@ func (s *Server) handleMessageInline(w http.ResponseWriter, r *http.Request) {
 
 	writeError(w, http.StatusNotFound, "not_found", "Inline part not found")
 }
+
+// handleGetAttachment streams a stored attachment's content by attachment
+// ID. The file is resolved through its content hash, so the client-supplied
+// ID never influences the filesystem path.
+func (s *Server) handleGetAttachment(w http.ResponseWriter, r *http.Request) {
+	if s.engine == nil {
+		writeError(w, http.StatusServiceUnavailable, "engine_unavailable", "Query engine not available")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_id", "Attachment ID must be a number")
+		return
+	}
+	if sc := scopeFrom(r); sc.restricted() {
+		sourceID, found, err := s.access.AttachmentSourceID(id)
+		if err != nil {
+			s.logger.Error("failed to check attachment access", "id", id, "error", err)
+			writeError(w, http.StatusInternalServerError, "internal_error", "Failed to load attachment")
+			return
+		}
+		if !found || !sc.allows(sourceID) {
+			writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+			return
+		}
+	}
+
+	att, err := s.engine.GetAttachment(r.Context(), id)
+	if err != nil {
+		if isEngineUnsupported(err) {
+			writeError(w, http.StatusNotImplemented, "not_supported", "Attachments are not available on this engine")
+			return
+		}
+		s.logger.Error("failed to get attachment", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to load attachment")
+		return
+	}
+	if att == nil {
+		writeError(w, http.StatusNotFound, "not_found", "Attachment not found")
+		return
+	}
+	if att.Quarantined {
+		writeError(w, http.StatusForbidden, "quarantined", "Attachment was flagged by the virus scanner and is quarantined")
+		return
+	}
+
+	f, err := export.OpenAttachment(s.cfg.AttachmentsDir(), att.ContentHash)
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, export.ErrInvalidContentHash) {
+			writeError(w, http.StatusNotFound, "not_found", "Attachment content not stored")
+			return
+		}
+		s.logger.Error("failed to open attachment", "error", err, "id", id)
+		writeError(w, http.StatusInternalServerError, "internal_error", "Failed to read attachment")
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	ct := att.MimeType
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	name := export.SanitizeFilename(att.Filename)
+	if name == "" {
+		name = "attachment"
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Disposition", stdmime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
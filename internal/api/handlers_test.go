@@ -3,7 +3,9 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +23,7 @@ import (
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/query/querytest"
 	"github.com/wesm/msgvault/internal/remote"
+	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/vector"
 	"github.com/wesm/msgvault/internal/vector/hybrid"
 )
@@ -1377,6 +1380,28 @@ func TestHandleDeepSearch(t *testing.T) {
 	}
 }
 
+func TestHandleDeepSearchSort(t *testing.T) {
+	var got []search.SortField
+	engine := &querytest.MockEngine{
+		SearchFunc: func(_ context.Context, q *search.Query, _, _ int) ([]query.MessageSummary, error) {
+			got = append(got, q.Sort)
+			return nil, nil
+		},
+	}
+	srv := newTestServerWithEngine(t, engine)
+
+	for _, path := range []string{"/api/v1/search/deep?q=agenda", "/api/v1/search/deep?q=agenda&sort=relevance"} {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body: %s", path, w.Code, w.Body.String())
+		}
+	}
+	if len(got) != 2 || got[0] != search.SortDate || got[1] != search.SortRelevance {
+		t.Errorf("sorts = %v, want date then relevance", got)
+	}
+}
+
 func TestHandleDeepSearchMissingQuery(t *testing.T) {
 	engine := &querytest.MockEngine{}
 	srv := newTestServerWithEngine(t, engine)
@@ -1470,6 +1495,144 @@ func TestHandleSearch_FTSModeUnchanged(t *testing.T) {
 	}
 }
 
+// threeMessageStore returns a mock store holding three search hits, so a
+// page_size of 2 leaves one result for a second page.
+func threeMessageStore(ms *mockStore) {
+	ms.messages = []APIMessage{
+		{ID: 3, Subject: "c", From: "alice@example.com", SentAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Subject: "b", From: "alice@example.com", SentAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: 1, Subject: "a", From: "bob@example.com", SentAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	ms.total = 3
+}
+
+func getSearch(t *testing.T, srv *Server, target string) (int, SearchResult) {
+	t.Helper()
+	req := httptest.NewRequest("GET", target, nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	var resp SearchResult
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestHandleSearch_CursorPagination(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     string
+		wantSort   search.SortField
+		wantAsc    bool
+		wantSeekID int64 // on the follow-up request
+		wantOffset int   // on the follow-up request
+	}{
+		{"relevance resumes by offset", "", search.SortRelevance, false, 0, 2},
+		{"date resumes after last id", "&sort=date", search.SortDate, false, 2, 0},
+		{"size ascending", "&sort=size&direction=asc", search.SortSize, true, 2, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, ms := newTestServerWithMockStore(t)
+			threeMessageStore(ms)
+
+			code, first := getSearch(t, srv, "/api/v1/search?q=hello&page_size=2"+tt.params)
+			if code != http.StatusOK {
+				t.Fatalf("first page status = %d", code)
+			}
+			if len(first.Messages) != 2 || first.NextCursor == "" || first.Page != 1 {
+				t.Fatalf("first page = %d messages, cursor %q, page %d", len(first.Messages), first.NextCursor, first.Page)
+			}
+			if ms.lastSearchLimit != 3 {
+				t.Errorf("limit = %d, want page_size+1", ms.lastSearchLimit)
+			}
+
+			// Sort parameters ride in the cursor; conflicting ones are ignored.
+			code, second := getSearch(t, srv, "/api/v1/search?q=hello&page_size=2&sort=relevance&cursor="+first.NextCursor)
+			if code != http.StatusOK {
+				t.Fatalf("second page status = %d", code)
+			}
+			if second.Page != 0 {
+				t.Errorf("cursor response page = %d, want omitted", second.Page)
+			}
+			if ms.lastSearchOffset != tt.wantOffset {
+				t.Errorf("offset = %d, want %d", ms.lastSearchOffset, tt.wantOffset)
+			}
+			if tt.wantSort != search.SortRelevance {
+				q := ms.lastSearchQuery
+				if q == nil || q.Sort != tt.wantSort || q.SortAsc != tt.wantAsc || q.SeekID != tt.wantSeekID {
+					t.Errorf("query = %+v, want sort %v asc %v seek %d", q, tt.wantSort, tt.wantAsc, tt.wantSeekID)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleSearch_LastPageHasNoCursor(t *testing.T) {
+	srv, ms := newTestServerWithMockStore(t)
+	threeMessageStore(ms)
+
+	_, resp := getSearch(t, srv, "/api/v1/search?q=hello&page_size=3")
+	if len(resp.Messages) != 3 || resp.NextCursor != "" {
+		t.Errorf("got %d messages, cursor %q; want 3 and no cursor", len(resp.Messages), resp.NextCursor)
+	}
+}
+
+func TestHandleSearch_PagingErrors(t *testing.T) {
+	srv, ms := newTestServerWithMockStore(t)
+	threeMessageStore(ms)
+	_, first := getSearch(t, srv, "/api/v1/search?q=hello&page_size=2&sort=date")
+
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{"unknown sort", "/api/v1/search?q=hello&sort=sender"},
+		{"garbage cursor", "/api/v1/search?q=hello&cursor=%21%21"},
+		{"cursor for another query", "/api/v1/search?q=other&cursor=" + first.NextCursor},
+		{"cursor with page", "/api/v1/search?q=hello&page=2&cursor=" + first.NextCursor},
+		{"unknown field", "/api/v1/search?q=hello&fields=id,body"},
+		{"empty field list", "/api/v1/search?q=hello&fields=,"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := getSearch(t, srv, tt.target); code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", code)
+			}
+		})
+	}
+}
+
+func TestHandleSearch_FieldSelection(t *testing.T) {
+	srv, ms := newTestServerWithMockStore(t)
+	threeMessageStore(ms)
+
+	req := httptest.NewRequest("GET", "/api/v1/search?q=hello&page_size=2&fields=id,%20subject", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Total      int64            `json:"total"`
+		NextCursor string           `json:"next_cursor"`
+		Messages   []map[string]any `json:"messages"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Total != 3 || resp.NextCursor == "" || len(resp.Messages) != 2 {
+		t.Fatalf("envelope = %+v", resp)
+	}
+	for _, m := range resp.Messages {
+		if len(m) != 2 || m["id"] == nil || m["subject"] == nil {
+			t.Errorf("message = %v, want only id and subject", m)
+		}
+	}
+}
+
 func TestHandleSearch_HybridModeNotConfigured(t *testing.T) {
 	// newTestServerWithMockStore does not inject a HybridEngine, so
 	// the server must return 503 for any vector/hybrid query.
@@ -2401,3 +2564,76 @@ func TestHandleGetMessage_EngineUnsupportedFallsBackToStore(t *testing.T) {
 		t.Errorf("subject = %q, want %q (store path response)", resp["subject"], "Test Subject")
 	}
 }
+
+func TestHandleGetAttachment(t *testing.T) {
+	content := []byte("%PDF-1.4 synthetic report")
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	engine := &querytest.MockEngine{
+		Attachments: map[int64]*query.AttachmentInfo{
+			7: {ID: 7, Filename: "report.pdf", MimeType: "application/pdf", Size: int64(len(content)), ContentHash: hash},
+			8: {ID: 8, Filename: "missing.pdf", MimeType: "application/pdf", ContentHash: strings.Repeat("ab", 32)},
+			9: {ID: 9, Filename: "nohash.bin"},
+			10: {ID: 10, Filename: "report.exe", MimeType: "application/octet-stream",
+				Size: int64(len(content)), ContentHash: hash, Quarantined: true},
+		},
+	}
+	srv := newTestServerWithEngine(t, engine)
+	srv.cfg.Data.DataDir = t.TempDir()
+	dir := filepath.Join(srv.cfg.AttachmentsDir(), hash[:2])
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, hash), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"found", "/api/v1/attachments/7", http.StatusOK},
+		{"file not stored", "/api/v1/attachments/8", http.StatusNotFound},
+		{"no content hash", "/api/v1/attachments/9", http.StatusNotFound},
+		{"unknown id", "/api/v1/attachments/99", http.StatusNotFound},
+		{"quarantined", "/api/v1/attachments/10", http.StatusForbidden},
+		{"invalid id", "/api/v1/attachments/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d, body: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+				t.Errorf("Content-Type = %q, want application/pdf", ct)
+			}
+			if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename=report.pdf` {
+				t.Errorf("Content-Disposition = %q", cd)
+			}
+			if !bytes.Equal(w.Body.Bytes(), content) {
+				t.Errorf("body = %q, want %q", w.Body.Bytes(), content)
+			}
+		})
+	}
+}
+
+func TestHandleGetAttachment_NoEngine(t *testing.T) {
+	srv, _ := newTestServerWithMockStore(t)
+
+	req := httptest.NewRequest("GET", "/api/v1/attachments/1", nil)
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ReadinessProbe supplies the daemon-side facts behind /readyz.
+type ReadinessProbe interface {
+	// PingDB checks that the database can be opened and queried.
+	PingDB(ctx context.Context) error
+	// HasCredentials reports whether a token or service account is
+	// available for the account, so scheduled syncs can authenticate.
+	HasCredentials(email string) bool
+	// LastSuccessfulSync returns when the account's last sync
+	// completed, or the zero time if none has.
+	LastSuccessfulSync(email string) (time.Time, error)
+}
+
+// Readiness check states. Only checkFail makes /readyz return 503: a
+// missing token stops one account's syncs, not the archive from
+// serving, and serve deliberately starts before tokens are uploaded.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// readinessTimeout bounds the database ping so a wedged database
+// fails the probe instead of hanging it.
+const readinessTimeout = 5 * time.Second
+
+// ReadinessResponse is the body of /readyz.
+type ReadinessResponse struct {
+	Status   string                    `json:"status"` // "ready" or "not_ready"
+	Checks   map[string]ReadinessCheck `json:"checks"`
+	Accounts []AccountReadiness        `json:"accounts,omitempty"`
+}
+
+// ReadinessCheck is the outcome of one /readyz check.
+type ReadinessCheck struct {
+	Status       string `json:"status"` // "ok", "warn", or "fail"
+	Error        string `json:"error,omitempty"`
+	FreeBytes    uint64 `json:"free_bytes,omitempty"`
+	MinFreeBytes uint64 `json:"min_free_bytes,omitempty"`
+}
+
+// AccountReadiness reports one scheduled account's sync health.
+type AccountReadiness struct {
+	Email              string     `json:"email"`
+	CredentialsPresent bool       `json:"credentials_present"`
+	LastSuccessfulSync *time.Time `json:"last_successful_sync,omitempty"`
+	LastError          string     `json:"last_error,omitempty"`
+}
+
+// handleReadyz reports whether the daemon can serve and sync: the
+// database answers, the data directory has disk headroom, and each
+// scheduled account has credentials. Per-account details name email
+// addresses, so they are only included for callers presenting an API
+// key that may see those accounts; the verdict itself needs no key so
+// systemd and Kubernetes probes can use it.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{Status: "ready", Checks: map[string]ReadinessCheck{}}
+
+	if s.readiness != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := s.readiness.PingDB(ctx)
+		cancel()
+		if err != nil {
+			s.logger.Warn("readiness: database check failed", "error", err)
+			resp.Checks["database"] = ReadinessCheck{Status: checkFail, Error: err.Error()}
+		} else {
+			resp.Checks["database"] = ReadinessCheck{Status: checkOK}
+		}
+	}
+
+	if dir := s.dataDir(); dir != "" {
+		check := ReadinessCheck{Status: checkOK, MinFreeBytes: s.cfg.Server.MinFreeDiskBytes()}
+		free, err := s.diskFree(dir)
+		switch {
+		case err != nil:
+			check.Status, check.Error = checkFail, err.Error()
+		case free < check.MinFreeBytes:
+			check.Status, check.Error = checkFail, "free disk space below min_free_disk_mb"
+		}
+		check.FreeBytes = free
+		resp.Checks["disk"] = check
+	}
+
+	accounts := s.accountReadiness()
+	if s.readiness != nil && len(accounts) > 0 {
+		check := ReadinessCheck{Status: checkOK}
+		for _, a := range accounts {
+			if !a.CredentialsPresent {
+				check.Status, check.Error = checkWarn, "some scheduled accounts have no credentials"
+				break
+			}
+		}
+		resp.Checks["credentials"] = check
+	}
+	if p, ok := ResolveKey(s.cfg.Server, s.tokens, presentedKey(r)); ok {
+		for _, a := range accounts {
+			if p.CanAccessAccount(a.Email) {
+				resp.Accounts = append(resp.Accounts, a)
+			}
+		}
+	}
+
+	status := http.StatusOK
+	for _, c := range resp.Checks {
+		if c.Status == checkFail {
+			resp.Status = "not_ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, resp)
+}
+
+// dataDir is the directory whose filesystem /readyz checks for space.
+func (s *Server) dataDir() string {
+	if s.cfg.Data.DataDir != "" {
+		return s.cfg.Data.DataDir
+	}
+	return s.cfg.HomeDir
+}
+
+// accountReadiness gathers credential and sync state for each
+// scheduled account, sorted by email.
+func (s *Server) accountReadiness() []AccountReadiness {
+	var accounts []AccountReadiness
+	if s.scheduler != nil {
+		for _, st := range s.scheduler.Status() {
+			accounts = append(accounts, AccountReadiness{Email: st.Email, LastError: st.LastError})
+		}
+	} else {
+		s.cfgMu.RLock()
+		for _, acc := range s.cfg.ScheduledAccounts() {
+			accounts = append(accounts, AccountReadiness{Email: acc.Email})
+		}
+		s.cfgMu.RUnlock()
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Email < accounts[j].Email })
+
+	if s.readiness == nil {
+		return accounts
+	}
+	for i := range accounts {
+		a := &accounts[i]
+		a.CredentialsPresent = s.readiness.HasCredentials(a.Email)
+		last, err := s.readiness.LastSuccessfulSync(a.Email)
+		if err != nil {
+			s.logger.Warn("readiness: last sync lookup failed", "email", a.Email, "error", err)
+			continue
+		}
+		if !last.IsZero() {
+			a.LastSuccessfulSync = &last
+		}
+	}
+	return accounts
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+// mockReadiness implements ReadinessProbe for tests.
+type mockReadiness struct {
+	pingErr  error
+	tokens   map[string]bool
+	lastSync map[string]time.Time
+}
+
+func (m *mockReadiness) PingDB(ctx context.Context) error { return m.pingErr }
+
+func (m *mockReadiness) HasCredentials(email string) bool { return m.tokens[email] }
+
+func (m *mockReadiness) LastSuccessfulSync(email string) (time.Time, error) {
+	return m.lastSync[email], nil
+}
+
+func TestHealthzEndpoint(t *testing.T) {
+	srv := NewServer(&config.Config{}, nil, newMockScheduler(), testLogger())
+	for _, method := range []string{"GET", "HEAD"} {
+		w := httptest.NewRecorder()
+		srv.Router().ServeHTTP(w, httptest.NewRequest(method, "/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s /healthz status = %d, want 200", method, w.Code)
+		}
+	}
+}
+
+func TestReadyzEndpoint(t *testing.T) {
+	synced := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	healthy := func() *mockReadiness {
+		return &mockReadiness{
+			tokens:   map[string]bool{"alice@example.com": true, "bob@example.com": true},
+			lastSync: map[string]time.Time{"alice@example.com": synced},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		probe      *mockReadiness
+		free       uint64
+		diskErr    error
+		key        string
+		wantStatus int
+		wantChecks map[string]string
+		wantEmails []string
+	}{
+		{
+			name:       "ready",
+			probe:      healthy(),
+			free:       10 << 30,
+			key:        "admin-secret-key",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": checkOK, "disk": checkOK, "credentials": checkOK},
+			wantEmails: []string{"alice@example.com", "bob@example.com"},
+		},
+		{
+			name:       "database down",
+			probe:      &mockReadiness{pingErr: errors.New("unable to open database file")},
+			free:       10 << 30,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": checkFail, "disk": checkOK, "credentials": checkWarn},
+		},
+		{
+			name:       "disk nearly full",
+			probe:      healthy(),
+			free:       100 << 20,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": checkOK, "disk": checkFail, "credentials": checkOK},
+		},
+		{
+			name:       "disk unreadable",
+			probe:      healthy(),
+			diskErr:    errors.New("statfs: no such file or directory"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"database": checkOK, "disk": checkFail, "credentials": checkOK},
+		},
+		{
+			name: "missing token only warns",
+			probe: &mockReadiness{
+				tokens: map[string]bool{"alice@example.com": true},
+			},
+			free:       10 << 30,
+			key:        "admin-secret-key",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": checkOK, "disk": checkOK, "credentials": checkWarn},
+			wantEmails: []string{"alice@example.com", "bob@example.com"},
+		},
+		{
+			name:       "scoped key sees own accounts",
+			probe:      healthy(),
+			free:       10 << 30,
+			key:        "bob-secret-key-0001",
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"database": checkOK, "disk": checkOK, "credentials": checkOK},
+			wantEmails: []string{"bob@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Data: config.DataConfig{DataDir: t.TempDir()},
+				Server: config.ServerConfig{
+					APIKey: "admin-secret-key",
+					Users: []config.ServerUser{
+						{Name: "bob", APIKey: "bob-secret-key-0001", Accounts: []string{"bob@example.com"}},
+					},
+				},
+			}
+			sched := newMockScheduler()
+			sched.statuses = []AccountStatus{
+				{Email: "bob@example.com", LastError: "token expired"},
+				{Email: "alice@example.com"},
+			}
+			srv := NewServerWithOptions(ServerOptions{
+				Config: cfg, Scheduler: sched, Readiness: tt.probe, Logger: testLogger(),
+			})
+			srv.diskFree = func(string) (uint64, error) { return tt.free, tt.diskErr }
+
+			req := httptest.NewRequest("GET", "/readyz", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var resp ReadinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			wantVerdict := "ready"
+			if tt.wantStatus != http.StatusOK {
+				wantVerdict = "not_ready"
+			}
+			if resp.Status != wantVerdict {
+				t.Errorf("status field = %q, want %q", resp.Status, wantVerdict)
+			}
+			if len(resp.Checks) != len(tt.wantChecks) {
+				t.Errorf("checks = %v, want %v", resp.Checks, tt.wantChecks)
+			}
+			for name, want := range tt.wantChecks {
+				if got := resp.Checks[name].Status; got != want {
+					t.Errorf("check %s = %q, want %q", name, got, want)
+				}
+			}
+			var emails []string
+			for _, a := range resp.Accounts {
+				emails = append(emails, a.Email)
+			}
+			if len(emails) != len(tt.wantEmails) {
+				t.Fatalf("accounts = %v, want %v", emails, tt.wantEmails)
+			}
+			for i := range emails {
+				if emails[i] != tt.wantEmails[i] {
+					t.Errorf("accounts = %v, want %v", emails, tt.wantEmails)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyzEndpoint_AccountDetails(t *testing.T) {
+	synced := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	cfg := &config.Config{Data: config.DataConfig{DataDir: t.TempDir()}}
+	sched := newMockScheduler()
+	sched.statuses = []AccountStatus{{Email: "alice@example.com", LastError: "rate limited"}}
+	srv := NewServerWithOptions(ServerOptions{
+		Config:    cfg,
+		Scheduler: sched,
+		Readiness: &mockReadiness{
+			tokens:   map[string]bool{"alice@example.com": true},
+			lastSync: map[string]time.Time{"alice@example.com": synced},
+		},
+		Logger: testLogger(),
+	})
+	srv.diskFree = func(string) (uint64, error) { return 10 << 30, nil }
+
+	// No keys configured: loopback callers see account details.
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	var resp ReadinessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Accounts) != 1 {
+		t.Fatalf("accounts = %+v, want alice", resp.Accounts)
+	}
+	a := resp.Accounts[0]
+	if !a.CredentialsPresent || a.LastError != "rate limited" ||
+		a.LastSuccessfulSync == nil || !a.LastSuccessfulSync.Equal(synced) {
+		t.Errorf("account = %+v", a)
+	}
+}
+
+func TestReadyzEndpoint_AnonymousHidesAccounts(t *testing.T) {
+	cfg := &config.Config{
+		Data:   config.DataConfig{DataDir: t.TempDir()},
+		Server: config.ServerConfig{APIKey: "admin-secret-key"},
+	}
+	sched := newMockScheduler()
+	sched.statuses = []AccountStatus{{Email: "alice@example.com"}}
+	srv := NewServerWithOptions(ServerOptions{
+		Config: cfg, Scheduler: sched, Readiness: &mockReadiness{}, Logger: testLogger(),
+	})
+	srv.diskFree = func(string) (uint64, error) { return 10 << 30, nil }
+
+	w := httptest.NewRecorder()
+	srv.Router().ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 without a key", w.Code)
+	}
+	var resp ReadinessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Accounts) != 0 {
+		t.Errorf("anonymous caller saw accounts: %+v", resp.Accounts)
+	}
+}
@@ -4,6 +4,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wesm/msgvault/internal/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestCORSMiddleware(t *testing.T) {
@@ -174,3 +181,71 @@ func TestRateLimitMiddleware(t *testing.T) {
 		t.Error("missing Retry-After header on rate limited response")
 	}
 }
+
+func TestTracingMiddleware(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	r := chi.NewRouter()
+	r.Use(TracingMiddleware)
+	r.Get("/messages/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "bad" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	const parentTrace = "4bf92f3577b34da6a3ce929d0e0e4736"
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantStatus  int
+		wantError   bool
+	}{
+		{name: "ok", path: "/messages/42", wantStatus: http.StatusOK},
+		{name: "server error", path: "/messages/bad", wantStatus: http.StatusInternalServerError, wantError: true},
+		{name: "propagated", path: "/messages/7", wantStatus: http.StatusOK,
+			traceparent: "00-" + parentTrace + "-00f067aa0ba902b7-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := testutil.RecordSpans(t)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			spans := sr.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("spans = %d, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Name() != "GET /messages/{id}" {
+				t.Errorf("name = %q, want route pattern", span.Name())
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("kind = %v, want server", span.SpanKind())
+			}
+			var status int64
+			for _, kv := range span.Attributes() {
+				if kv.Key == "http.response.status_code" {
+					status = kv.Value.AsInt64()
+				}
+			}
+			if status != int64(tt.wantStatus) {
+				t.Errorf("status attr = %d, want %d", status, tt.wantStatus)
+			}
+			if got := span.Status().Code == codes.Error; got != tt.wantError {
+				t.Errorf("error status = %v, want %v", got, tt.wantError)
+			}
+			if tt.traceparent != "" && span.SpanContext().TraceID().String() != parentTrace {
+				t.Errorf("trace ID = %s, want propagated %s", span.SpanContext().TraceID(), parentTrace)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package api
 
 import (
 	"context"
-	"crypto/subtle"
 	"log/slog"
 	"net"
 	"net/http"
@@ -14,12 +13,14 @@ import (
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/fileutil"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/scheduler"
 	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/store"
 	"github.com/wesm/msgvault/internal/vector"
 	"github.com/wesm/msgvault/internal/vector/hybrid"
+	"github.com/wesm/msgvault/internal/web"
 )
 
 // MessageStore defines the store operations the API needs.
@@ -56,6 +57,11 @@ type Server struct {
 	vectorCfg      vector.Config
 	backend        vector.Backend
 	scheduler      SyncScheduler
+	access         AccessStore
+	jmap           http.Handler
+	readiness      ReadinessProbe
+	tokens         TokenStore // nil unless the store issues API tokens
+	diskFree       func(path string) (uint64, error)
 	logger         *slog.Logger
 	requestTimeout time.Duration
 	router         chi.Router
@@ -73,7 +79,9 @@ type ServerOptions struct {
 	VectorCfg    vector.Config
 	Backend      vector.Backend
 	Scheduler    SyncScheduler
-	Logger       *slog.Logger
+	// Access enables per-user scoping for non-admin [[server.users]].
+	Access AccessStore
+	Logger *slog.Logger
 	// RequestTimeout caps each request via chi's gentle Timeout
 	// middleware. Zero defaults to 60s. The underlying http.Server's
 	// WriteTimeout is set to RequestTimeout + 5s so the chi timeout
@@ -81,6 +89,12 @@ type ServerOptions struct {
 	// Tests use a much shorter value to exercise the chi-timeout-fires
 	// path.
 	RequestTimeout time.Duration
+	// JMAP, when set, is mounted at /jmap behind API key authentication
+	// and advertised at /.well-known/jmap.
+	JMAP http.Handler
+	// Readiness supplies the database, credential, and sync checks
+	// behind /readyz. Nil reports only disk headroom.
+	Readiness ReadinessProbe
 }
 
 // NewServer creates a new API server.
@@ -107,6 +121,11 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 		vectorCfg:      opts.VectorCfg,
 		backend:        opts.Backend,
 		scheduler:      opts.Scheduler,
+		access:         opts.Access,
+		jmap:           opts.JMAP,
+		readiness:      opts.Readiness,
+		tokens:         tokenStoreOf(opts.Store),
+		diskFree:       fileutil.DiskFree,
 		logger:         opts.Logger,
 		requestTimeout: timeout,
 	}
@@ -114,12 +133,20 @@ func NewServerWithOptions(opts ServerOptions) *Server {
 	return s
 }
 
+// tokenStoreOf returns st's API token lookup, or nil when st doesn't
+// issue tokens.
+func tokenStoreOf(st any) TokenStore {
+	ts, _ := st.(TokenStore)
+	return ts
+}
+
 // setupRouter configures the chi router with all routes and middleware.
 func (s *Server) setupRouter() chi.Router {
 	r := chi.NewRouter()
 
 	// Standard middleware
 	r.Use(chimw.RequestID)
+	r.Use(TracingMiddleware)
 	r.Use(s.loggerMiddleware)
 	r.Use(chimw.Recoverer)
 	// chi/v5's Timeout is "gentle": it wraps the request context with a
@@ -149,45 +176,72 @@ func (s *Server) setupRouter() chi.Router {
 	s.rateLimiter = NewRateLimiter(10, 20)
 	r.Use(RateLimitMiddleware(s.rateLimiter))
 
-	// Health check (no auth required)
+	// Health and readiness checks (no auth required)
 	r.Get("/health", s.handleHealth)
+	r.Get("/healthz", s.handleHealth)
+	r.Get("/readyz", s.handleReadyz)
 	r.Head("/health", s.handleHealth)
+	r.Head("/healthz", s.handleHealth)
+	r.Head("/readyz", s.handleReadyz)
+
+	// Embedded web UI (static assets, no auth; the UI itself sends the
+	// API key on each /api/v1 call)
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ui/", http.StatusFound)
+	})
+	r.Handle("/ui/*", http.StripPrefix("/ui", web.Handler()))
+
+	// JMAP facade (RFC 8620 session discovery is unauthenticated; the
+	// session and API endpoints are not)
+	if s.jmap != nil {
+		r.Get("/.well-known/jmap", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/jmap/session", http.StatusMovedPermanently)
+		})
+		r.With(s.authMiddleware, requireScope(store.ScopeRead)).Mount("/jmap", s.jmap)
+	}
 
 	// API routes (auth required)
 	r.Route("/api/v1", func(r chi.Router) {
 		// Apply API key authentication
 		r.Use(s.authMiddleware)
 
+		// Operations are grouped by the API token scope they need;
+		// keys from config.toml pass every scope check.
+		read := r.With(requireScope(store.ScopeRead))
+		find := r.With(requireScope(store.ScopeSearch))
+		admin := r.With(requireScope(store.ScopeAdmin))
+
 		// Stats
-		r.Get("/stats", s.handleStats)
+		read.Get("/stats", s.handleStats)
 
 		// Messages
-		r.Get("/messages", s.handleListMessages)
-		r.Get("/messages/{id}", s.handleGetMessage)
-		r.Get("/messages/{id}/inline", s.handleMessageInline)
+		read.Get("/messages", s.handleListMessages)
+		read.Get("/messages/{id}", s.handleGetMessage)
+		read.Get("/messages/{id}/inline", s.handleMessageInline)
+		read.Get("/attachments/{id}", s.handleGetAttachment)
 
 		// Search
-		r.Get("/search", s.handleSearch)
+		find.Get("/search", s.handleSearch)
 
 		// TUI aggregate endpoints (require query engine)
-		r.Post("/query", s.handleQuery)
-		r.Get("/aggregates", s.handleAggregates)
-		r.Get("/aggregates/sub", s.handleSubAggregates)
-		r.Get("/messages/filter", s.handleFilteredMessages)
-		r.Get("/stats/total", s.handleTotalStats)
-		r.Get("/search/fast", s.handleFastSearch)
-		r.Get("/search/deep", s.handleDeepSearch)
+		admin.Post("/query", s.handleQuery)
+		read.Get("/aggregates", s.handleAggregates)
+		read.Get("/aggregates/sub", s.handleSubAggregates)
+		read.Get("/messages/filter", s.handleFilteredMessages)
+		read.Get("/stats/total", s.handleTotalStats)
+		find.Get("/search/fast", s.handleFastSearch)
+		find.Get("/search/deep", s.handleDeepSearch)
 
 		// Accounts and sync
-		r.Get("/accounts", s.handleListAccounts)
-		r.Post("/accounts", s.handleAddAccount)
-		r.Post("/sync/{account}", s.handleTriggerSync)
+		read.Get("/accounts", s.handleListAccounts)
+		admin.Post("/accounts", s.handleAddAccount)
+		admin.Post("/sync/{account}", s.handleTriggerSync)
 
 		// Scheduler status
-		r.Get("/scheduler/status", s.handleSchedulerStatus)
+		read.Get("/scheduler/status", s.handleSchedulerStatus)
 
 		// Token upload for headless OAuth
-		r.Post("/auth/token/{email}", s.handleUploadToken)
+		admin.Post("/auth/token/{email}", s.handleUploadToken)
 	})
 
 	return r
@@ -196,7 +250,7 @@ func (s *Server) setupRouter() chi.Router {
 // Start begins listening for HTTP requests.
 // Returns an error if the security posture is invalid.
 func (s *Server) Start() error {
-	if err := s.cfg.Server.ValidateSecure(); err != nil {
+	if err := s.cfg.Server.ValidateSecureWith(RequiresAuth(s.cfg.Server, s.tokens)); err != nil {
 		return err
 	}
 
@@ -206,8 +260,8 @@ func (s *Server) Start() error {
 	}
 	addr := net.JoinHostPort(bindAddr, strconv.Itoa(s.cfg.Server.APIPort))
 
-	if s.cfg.Server.APIKey == "" {
-		s.logger.Warn("API server running without authentication — set [server] api_key in config.toml")
+	if !RequiresAuth(s.cfg.Server, s.tokens) {
+		s.logger.Warn("API server running without authentication — set [server] api_key in config.toml or create a token with 'msgvault token create'")
 	}
 
 	// WriteTimeout must comfortably exceed the chi request timeout so
@@ -267,28 +321,31 @@ func (s *Server) loggerMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// authMiddleware validates the API key.
+// presentedKey extracts the API key a request carries, from the
+// Authorization header (bare or Bearer), X-API-Key, or the password of
+// HTTP Basic auth (JMAP clients commonly only speak Basic; the username
+// is ignored).
+func presentedKey(r *http.Request) string {
+	key := r.Header.Get("Authorization")
+	if key == "" {
+		key = r.Header.Get("X-API-Key")
+	}
+	if len(key) > 7 && key[:7] == "Bearer " {
+		key = key[7:]
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		key = password
+	}
+	return key
+}
+
+// authMiddleware validates the API key and records the caller's scope
+// in the request context. Non-admin users have their account list
+// resolved to source IDs here so handlers only deal with IDs.
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip auth if no API key configured
-		if s.cfg.Server.APIKey == "" {
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Check Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			// Also check X-API-Key header
-			authHeader = r.Header.Get("X-API-Key")
-		}
-
-		// Strip "Bearer " prefix if present
-		if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
-			authHeader = authHeader[7:]
-		}
-
-		if subtle.ConstantTimeCompare([]byte(authHeader), []byte(s.cfg.Server.APIKey)) != 1 {
+		principal, ok := ResolveKey(s.cfg.Server, s.tokens, presentedKey(r))
+		if !ok {
 			s.logger.Warn("unauthorized API request",
 				"path", r.URL.Path,
 				"remote_addr", r.RemoteAddr,
@@ -297,7 +354,26 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		scope := &requestScope{principal: principal}
+		if scope.restricted() {
+			if s.access == nil {
+				writeError(w, http.StatusServiceUnavailable, "scope_unavailable", "Per-user access control not available")
+				return
+			}
+			ids, err := s.access.SourceIDsForAccounts(principal.Accounts)
+			if err != nil {
+				s.logger.Error("failed to resolve user accounts", "user", principal.Name, "error", err)
+				writeError(w, http.StatusInternalServerError, "internal_error", "Failed to resolve account access")
+				return
+			}
+			if len(ids) == 0 {
+				writeError(w, http.StatusForbidden, "no_accounts", "No archived accounts are visible to this API key")
+				return
+			}
+			scope.sourceIDs = ids
+		}
+
+		next.ServeHTTP(w, r.WithContext(withScope(r.Context(), scope)))
 	})
 }
 
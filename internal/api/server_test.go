@@ -72,6 +72,13 @@ type mockStore struct {
 	getMessageCalls          atomic.Int32
 	getSummariesByIDsCalls   atomic.Int32
 	getSummariesByIDsLastIDs []int64
+
+	// lastSearchQuery records the query passed to SearchMessagesQuery so
+	// scoping tests can assert on the applied account filter.
+	lastSearchQuery *search.Query
+	// lastSearchOffset and lastSearchLimit record the paging of the
+	// most recent search call of either kind.
+	lastSearchOffset, lastSearchLimit int
 }
 
 func (m *mockStore) GetStats() (*StoreStats, error) {
@@ -112,10 +119,13 @@ func (m *mockStore) GetMessagesSummariesByIDs(ids []int64) ([]APIMessage, error)
 }
 
 func (m *mockStore) SearchMessages(query string, offset, limit int) ([]APIMessage, int64, error) {
+	m.lastSearchOffset, m.lastSearchLimit = offset, limit
 	return m.messages, m.total, nil
 }
 
 func (m *mockStore) SearchMessagesQuery(q *search.Query, offset, limit int) ([]APIMessage, int64, error) {
+	m.lastSearchQuery = q
+	m.lastSearchOffset, m.lastSearchLimit = offset, limit
 	return m.messages, m.total, nil
 }
 
@@ -227,6 +237,38 @@ func TestAuthMiddlewareNoKeyConfigured(t *testing.T) {
 	}
 }
 
+func TestWebUIServedWithoutAuth(t *testing.T) {
+	cfg := &config.Config{
+		Server: config.ServerConfig{
+			APIPort: 8080,
+			APIKey:  "secret-key",
+		},
+	}
+	srv := NewServer(cfg, nil, newMockScheduler(), testLogger())
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"root redirects", "/", http.StatusFound},
+		{"index", "/ui/", http.StatusOK},
+		{"script", "/ui/app.js", http.StatusOK},
+		{"missing asset", "/ui/nope.js", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			srv.Router().ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestSchedulerStatusEndpoint(t *testing.T) {
 	cfg := &config.Config{
 		Server: config.ServerConfig{APIPort: 8080},
@@ -392,6 +434,22 @@ func TestSecurityValidation(t *testing.T) {
 		{"non-loopback no key", config.ServerConfig{BindAddr: "0.0.0.0"}, true},
 		{"non-loopback ipv6 no key", config.ServerConfig{BindAddr: "::"}, true},
 		{"non-loopback insecure override", config.ServerConfig{BindAddr: "0.0.0.0", AllowInsecure: true}, false},
+		{"non-loopback users only", config.ServerConfig{BindAddr: "0.0.0.0", Users: []config.ServerUser{
+			{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}},
+		}}, false},
+		{"user missing key", config.ServerConfig{Users: []config.ServerUser{
+			{Name: "alice", Accounts: []string{"alice@example.com"}},
+		}}, true},
+		{"user without accounts or admin", config.ServerConfig{Users: []config.ServerUser{
+			{Name: "alice", APIKey: "alice-key"},
+		}}, true},
+		{"user key reuses api_key", config.ServerConfig{APIKey: "shared", Users: []config.ServerUser{
+			{Name: "alice", APIKey: "shared", Admin: true},
+		}}, true},
+		{"duplicate user names", config.ServerConfig{Users: []config.ServerUser{
+			{Name: "alice", APIKey: "k1", Admin: true},
+			{Name: "alice", APIKey: "k2", Admin: true},
+		}}, true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,201 @@
+// Package attachtext extracts the text of stored attachment files, PDF,
+// DOCX and plain text, and indexes it for in:attachments searches.
+package attachtext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/textutil"
+)
+
+// maxFileSize is the largest attachment file read for text; larger
+// ones are recorded with none.
+const maxFileSize = 64 << 20
+
+// maxTextSize bounds the text kept from one file, which is cut at a
+// character boundary.
+const maxTextSize = 1 << 20
+
+// Kind is a type of file Extract can read.
+type Kind int
+
+const (
+	KindNone Kind = iota // not a type with text to extract
+	KindText
+	KindPDF
+	KindDOCX
+)
+
+const docxType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// textExts are the filename extensions of plain text files, for
+// attachments sent with a generic content type.
+var textExts = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".log": true, ".md": true,
+}
+
+// KindOf returns the kind of an attachment of this content type and
+// filename. HTML is left out: its message usually says the same.
+func KindOf(contentType, filename string) Kind {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case ct == "application/pdf":
+		return KindPDF
+	case ct == docxType:
+		return KindDOCX
+	case ct == "text/html":
+		return KindNone
+	case strings.HasPrefix(ct, "text/"):
+		return KindText
+	case ct == "" || ct == "application/octet-stream":
+		switch {
+		case ext == ".pdf":
+			return KindPDF
+		case ext == ".docx":
+			return KindDOCX
+		case textExts[ext]:
+			return KindText
+		}
+	}
+	return KindNone
+}
+
+// Extract returns the text of data, a file of kind k. It fails for a
+// file that is damaged or, for PDF, encrypted.
+func Extract(k Kind, data []byte) (string, error) {
+	var text string
+	var err error
+	switch k {
+	case KindText:
+		text = textutil.EnsureUTF8(string(data))
+	case KindPDF:
+		text, err = extractPDF(data)
+	case KindDOCX:
+		text, err = extractDOCX(data)
+	default:
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return truncate(strings.TrimSpace(textutil.SanitizeUTF8(text)), maxTextSize), nil
+}
+
+// truncate cuts s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Stats counts what Run did.
+type Stats struct {
+	Files   int `json:"files"`   // files read or skipped
+	Indexed int `json:"indexed"` // files that yielded text
+	Errors  int `json:"errors"`  // files that could not be read
+}
+
+// Options control Run.
+type Options struct {
+	// Reindex extracts the text of files already indexed again, as
+	// after an upgrade that reads more of them.
+	Reindex bool
+	// Progress, if not nil, is called after each file.
+	Progress func(done, total int)
+}
+
+// Run extracts the text of the attachment files stored under
+// attachmentsDir that have not been indexed yet, or all of them with
+// opts.Reindex, and records it in s. A file that cannot be read is
+// logged and recorded with no text, like one of a type without any.
+func Run(ctx context.Context, s *store.Store, attachmentsDir string, opts Options, logger *slog.Logger) (Stats, error) {
+	var stats Stats
+	files, err := s.AttachmentFilesToIndex(opts.Reindex)
+	if err != nil {
+		return stats, err
+	}
+	for i, f := range files {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		var text string
+		if k := KindOf(f.MimeType, f.Filename); k != KindNone {
+			full := filepath.Join(attachmentsDir, filepath.FromSlash(f.StoragePath))
+			if text, err = extractFile(full, k); err != nil {
+				stats.Errors++
+				logger.Debug("attachment text not extracted", "path", f.StoragePath, "error", err)
+			}
+		}
+		if err := s.SetAttachmentText(f.StoragePath, text); err != nil {
+			return stats, err
+		}
+		stats.Files++
+		if text != "" {
+			stats.Indexed++
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(files))
+		}
+	}
+	return stats, nil
+}
+
+// errTooLarge is returned for a file over maxFileSize.
+var errTooLarge = errors.New("file too large to index")
+
+// extractFile reads a stored attachment file, which may be compressed,
+// and extracts its text.
+func extractFile(path string, k Kind) (string, error) {
+	f, err := export.OpenAttachmentPath(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(f, maxFileSize+1)); err != nil {
+		return "", fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+	if buf.Len() > maxFileSize {
+		return "", errTooLarge
+	}
+	return Extract(k, buf.Bytes())
+}
+
+// PostSyncHook returns a scheduler hook that indexes the text of the
+// attachments each sync stored. Failures are logged; they do not fail
+// the sync.
+func PostSyncHook(s *store.Store, attachmentsDir string, logger *slog.Logger) func(ctx context.Context, account string) {
+	var mu sync.Mutex
+	return func(ctx context.Context, account string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats, err := Run(ctx, s, attachmentsDir, Options{}, logger)
+		if err != nil {
+			logger.Error("attachment indexing failed", "account", account, "error", err)
+			return
+		}
+		if stats.Indexed > 0 {
+			logger.Info("attachments indexed", "account", account,
+				"files", stats.Files, "indexed", stats.Indexed, "errors", stats.Errors)
+		}
+	}
+}
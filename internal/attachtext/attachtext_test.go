@@ -0,0 +1,177 @@
+package attachtext
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// makePDF returns a one-page PDF drawing content, Flate-compressed if
+// compress is set.
+func makePDF(t *testing.T, content string, compress bool) []byte {
+	t.Helper()
+	stream, filter := []byte(content), ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, err := zw.Write(stream)
+		testutil.MustNoErr(t, err, "compress")
+		testutil.MustNoErr(t, zw.Close(), "compress")
+		stream, filter = buf.Bytes(), " /Filter /FlateDecode"
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n")
+	b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n")
+	fmt.Fprintf(&b, "4 0 obj\n<< /Length %d%s >>\nstream\n", len(stream), filter)
+	b.Write(stream)
+	b.WriteString("\nendstream\nendobj\ntrailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// makeDOCX returns a Word document whose body is document.
+func makeDOCX(t *testing.T, document string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	testutil.MustNoErr(t, err, "create document.xml")
+	_, err = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + document + `</w:body></w:document>`))
+	testutil.MustNoErr(t, err, "write document.xml")
+	testutil.MustNoErr(t, zw.Close(), "close docx")
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	page := "BT /F1 12 Tf 72 720 Td (Invoice \\(final\\)) Tj 0 -14 Td [(Total) -250 (due:) 120 ( 42)] TJ ET"
+	tests := []struct {
+		name string
+		kind Kind
+		data []byte
+		want string
+	}{
+		{"text", KindText, []byte("  Quarterly numbers\n"), "Quarterly numbers"},
+		{"pdf", KindPDF, makePDF(t, page, false), "Invoice (final)\nTotal due: 42"},
+		{"compressed pdf", KindPDF, makePDF(t, page, true), "Invoice (final)\nTotal due: 42"},
+		{"pdf hex and octal strings", KindPDF, makePDF(t, `BT <48656C6C6F> Tj ( w\157rld) Tj ET`, false), "Hello world"},
+		{"pdf glyph codes dropped", KindPDF, makePDF(t, `BT <00410042> Tj (kept) Tj ET`, false), "kept"},
+		{"docx", KindDOCX, makeDOCX(t, `<w:p><w:r><w:t>Lease</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">renewal </w:t></w:r></w:p><w:p><w:r><w:t>Signed</w:t></w:r></w:p>`), "Lease\trenewal \nSigned"},
+		{"none", KindNone, []byte("ignored"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.kind, tt.data)
+			testutil.MustNoErr(t, err, "Extract")
+			if got != tt.want {
+				t.Errorf("Extract = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	encrypted := bytes.Replace(makePDF(t, page, false), []byte("/Root 1 0 R"), []byte("/Root 1 0 R /Encrypt 5 0 R"), 1)
+	for name, data := range map[string][]byte{
+		"encrypted pdf": encrypted,
+		"not a pdf":     []byte("PK\x03\x04"),
+	} {
+		if _, err := Extract(KindPDF, data); err == nil {
+			t.Errorf("Extract(%s): want error", name)
+		}
+	}
+	if _, err := Extract(KindDOCX, []byte("not a zip")); err == nil {
+		t.Error("Extract(damaged docx): want error")
+	}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		contentType, filename string
+		want                  Kind
+	}{
+		{"application/pdf", "scan", KindPDF},
+		{"application/octet-stream", "Report.PDF", KindPDF},
+		{docxType, "", KindDOCX},
+		{"", "letter.docx", KindDOCX},
+		{"text/plain; charset=utf-8", "notes", KindText},
+		{"text/csv", "", KindText},
+		{"application/octet-stream", "data.csv", KindText},
+		{"text/html", "page.html", KindNone},
+		{"image/png", "scan.pdf", KindNone},
+		{"application/zip", "files.zip", KindNone},
+	}
+	for _, tt := range tests {
+		if got := KindOf(tt.contentType, tt.filename); got != tt.want {
+			t.Errorf("KindOf(%q, %q) = %v, want %v", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	f := storetest.New(t)
+	dir := t.TempDir()
+	ids := f.CreateMessages(3)
+
+	files := map[string][]byte{
+		"aa/lease":  makeDOCX(t, `<w:p><w:r><w:t>Lease renewal for flat 4B</w:t></w:r></w:p>`),
+		"bb/report": makePDF(t, "BT (Quarterly revenue report) Tj ET", true),
+		"cc/photo":  []byte("\x89PNG"),
+		"dd/broken": []byte("%PDF-1.4 /Encrypt"),
+	}
+	for rel, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		testutil.MustNoErr(t, os.MkdirAll(filepath.Dir(full), 0o700), "mkdir")
+		testutil.MustNoErr(t, os.WriteFile(full, content, 0o600), "write file")
+	}
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[0], "lease.docx", docxType, "aa/lease", "aaaa", 10), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[1], "report.pdf", "application/pdf", "bb/report", "bbbb", 10), "UpsertAttachment")
+	// The same report attached to another message is read once.
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "copy.pdf", "application/pdf", "bb/report", "bbbb", 10), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "photo.png", "image/png", "cc/photo", "cccc", 10), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "locked.pdf", "application/pdf", "dd/broken", "dddd", 10), "UpsertAttachment")
+
+	stats, err := Run(context.Background(), f.Store, dir, Options{}, slog.Default())
+	testutil.MustNoErr(t, err, "Run")
+	if stats != (Stats{Files: 4, Indexed: 2, Errors: 1}) {
+		t.Errorf("Run = %+v, want 4 files, 2 indexed, 1 error", stats)
+	}
+
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{"in:attachments revenue", 2},
+		{`in:attachments "lease renewal"`, 1},
+		{"in:attachments lease revenue", 0},
+		{"revenue", 0},
+	}
+	for _, tt := range tests {
+		_, total, err := f.Store.SearchMessagesQuery(search.Parse(tt.query), 0, 10)
+		testutil.MustNoErr(t, err, "SearchMessagesQuery "+tt.query)
+		if total != tt.want {
+			t.Errorf("%s: %d messages, want %d", tt.query, total, tt.want)
+		}
+	}
+
+	// Files already read are not read again unless asked.
+	stats, err = Run(context.Background(), f.Store, dir, Options{}, slog.Default())
+	testutil.MustNoErr(t, err, "second Run")
+	if stats.Files != 0 {
+		t.Errorf("second Run read %d files, want 0", stats.Files)
+	}
+	stats, err = Run(context.Background(), f.Store, dir, Options{Reindex: true}, slog.Default())
+	testutil.MustNoErr(t, err, "reindex")
+	if stats.Files != 4 || stats.Indexed != 2 {
+		t.Errorf("reindex = %+v, want 4 files, 2 indexed", stats)
+	}
+}
@@ -0,0 +1,78 @@
+package attachtext
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxDocumentXML bounds how much of word/document.xml is read, so a
+// zip bomb cannot exhaust memory.
+const maxDocumentXML = 32 << 20
+
+// extractDOCX returns the text of the body of a Word document: its
+// paragraphs one per line, with tabs and line breaks kept.
+func extractDOCX(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+	var doc *zip.File
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			doc = f
+			break
+		}
+	}
+	if doc == nil {
+		return "", errors.New("open docx: no word/document.xml")
+	}
+	rc, err := doc.Open()
+	if err != nil {
+		return "", fmt.Errorf("open docx: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	var b strings.Builder
+	dec := xml.NewDecoder(io.LimitReader(rc, maxDocumentXML))
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read docx: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteByte('\t')
+			case "br", "cr":
+				b.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+		if b.Len() > maxTextSize {
+			break
+		}
+	}
+	return b.String(), nil
+}
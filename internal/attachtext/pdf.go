@@ -0,0 +1,373 @@
+package attachtext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxStreamSize bounds a decompressed PDF stream.
+const maxStreamSize = 16 << 20
+
+var (
+	errNotPDF       = errors.New("not a PDF file")
+	errPDFEncrypted = errors.New("PDF is encrypted")
+)
+
+// extractPDF returns the text drawn by the page content streams of a
+// PDF, read without a layout engine: runs of text in the order they are
+// drawn, a line break where the text moves to a new line. Streams
+// compressed other than with Flate are skipped, as is text in fonts
+// with an encoding of their own, such as the two-byte glyph codes of
+// embedded subsets, which would come out as noise.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data[:min(len(data), 1024)], "\x00\t\r\n "), []byte("%PDF-")) {
+		return "", errNotPDF
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", errPDFEncrypted
+	}
+
+	var b strings.Builder
+	for pos := 0; b.Len() <= maxTextSize; {
+		i := bytes.Index(data[pos:], []byte("stream"))
+		if i < 0 {
+			break
+		}
+		kw := pos + i
+		pos = kw + len("stream")
+		if kw >= 3 && string(data[kw-3:kw]) == "end" {
+			continue
+		}
+		start := pos
+		if start < len(data) && data[start] == '\r' {
+			start++
+		}
+		if start < len(data) && data[start] == '\n' {
+			start++
+		}
+		n := bytes.Index(data[start:], []byte("endstream"))
+		if n < 0 {
+			break
+		}
+		pos = start + n + len("endstream")
+
+		dict := data[:kw]
+		if o := bytes.LastIndex(dict, []byte(" obj")); o >= 0 {
+			dict = dict[o:]
+		}
+		content, ok := decodeStream(dict, data[start:start+n])
+		if !ok {
+			continue
+		}
+		writeContentText(&b, content)
+	}
+	return b.String(), nil
+}
+
+// decodeStream returns the content of a stream with dictionary dict,
+// or false for one that is not a content stream, is compressed other
+// than with Flate, or is damaged.
+func decodeStream(dict, raw []byte) ([]byte, bool) {
+	for _, skip := range []string{"/Image", "/Length1", "/Length2", "/Length3", "/Metadata", "/XRef", "/ObjStm"} {
+		if bytes.Contains(dict, []byte(skip)) {
+			return nil, false
+		}
+	}
+	filters := bytes.Count(dict, []byte("Decode")) - bytes.Count(dict, []byte("DecodeParms"))
+	switch {
+	case filters == 0:
+		return raw, true
+	case filters > 1 || !bytes.Contains(dict, []byte("/FlateDecode")):
+		return nil, false
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, false
+	}
+	defer func() { _ = zr.Close() }()
+	// A stream cut short still yields what was decompressed before the
+	// damage.
+	out, err := io.ReadAll(io.LimitReader(zr, maxStreamSize))
+	if err != nil && len(out) == 0 {
+		return nil, false
+	}
+	return out, true
+}
+
+// writeContentText writes the text shown between BT and ET in a content
+// stream to b.
+func writeContentText(b *strings.Builder, content []byte) {
+	lx := pdfLexer{data: content}
+	var operands []pdfToken
+	inText := false
+	for {
+		tok, ok := lx.next()
+		if !ok {
+			return
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.text {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			b.WriteByte('\n')
+		case "ID":
+			lx.skipInlineImage()
+		}
+		if inText {
+			writeTextOp(b, tok.text, operands)
+		}
+		operands = operands[:0]
+	}
+}
+
+// writeTextOp writes the text a text operator shows, and the space or
+// line break its move stands for.
+func writeTextOp(b *strings.Builder, op string, operands []pdfToken) {
+	switch op {
+	case "Tj":
+		writeStrings(b, operands)
+	case "'", `"`:
+		b.WriteByte('\n')
+		writeStrings(b, operands)
+	case "TJ":
+		for _, t := range operands {
+			switch t.kind {
+			case pdfString:
+				b.WriteString(decodePDFString(t.text))
+			case pdfNumber:
+				// A large negative adjustment is a gap between words.
+				if f, err := strconv.ParseFloat(t.text, 64); err == nil && f < -200 {
+					b.WriteByte(' ')
+				}
+			}
+		}
+	case "T*", "Tm":
+		b.WriteByte('\n')
+	case "Td", "TD":
+		if len(operands) == 2 && operands[1].text != "0" {
+			b.WriteByte('\n')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+}
+
+func writeStrings(b *strings.Builder, operands []pdfToken) {
+	for _, t := range operands {
+		if t.kind == pdfString {
+			b.WriteString(decodePDFString(t.text))
+		}
+	}
+}
+
+// decodePDFString decodes the bytes of a string shown in a simple font,
+// as Latin-1, or UTF-16 after a byte order mark. Strings with control
+// bytes are glyph codes of a font with its own encoding and are dropped.
+func decodePDFString(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") && len(s)%2 == 0 {
+		u := make([]uint16, 0, len(s)/2-1)
+		for i := 2; i+1 < len(s); i += 2 {
+			u = append(u, uint16(s[i])<<8|uint16(s[i+1]))
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' {
+			return ""
+		}
+		r = append(r, rune(c))
+	}
+	return string(r)
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfNumber
+	pdfString
+	pdfOther // names, arrays and dictionary delimiters
+)
+
+type pdfToken struct {
+	kind pdfTokenKind
+	text string // the decoded bytes of a string
+}
+
+// pdfLexer splits a content stream into tokens.
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (lx *pdfLexer) next() (pdfToken, bool) {
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		switch {
+		case isPDFSpace(c):
+			lx.pos++
+		case c == '%':
+			for lx.pos < len(lx.data) && lx.data[lx.pos] != '\n' && lx.data[lx.pos] != '\r' {
+				lx.pos++
+			}
+		case c == '(':
+			lx.pos++
+			return pdfToken{kind: pdfString, text: lx.literalString()}, true
+		case c == '<' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '<',
+			c == '>' && lx.pos+1 < len(lx.data) && lx.data[lx.pos+1] == '>':
+			lx.pos += 2
+			return pdfToken{kind: pdfOther}, true
+		case c == '<':
+			lx.pos++
+			return pdfToken{kind: pdfString, text: lx.hexString()}, true
+		case c == '/':
+			lx.pos++
+			lx.regular()
+			return pdfToken{kind: pdfOther}, true
+		case isPDFDelimiter(c):
+			lx.pos++
+			return pdfToken{kind: pdfOther}, true
+		default:
+			word := lx.regular()
+			if word == "" {
+				lx.pos++
+				continue
+			}
+			if _, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfNumber, text: word}, true
+			}
+			return pdfToken{kind: pdfOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+// regular reads a run of regular characters.
+func (lx *pdfLexer) regular() string {
+	start := lx.pos
+	for lx.pos < len(lx.data) && !isPDFSpace(lx.data[lx.pos]) && !isPDFDelimiter(lx.data[lx.pos]) {
+		lx.pos++
+	}
+	return string(lx.data[start:lx.pos])
+}
+
+// literalString reads a (string) after its opening parenthesis.
+func (lx *pdfLexer) literalString() string {
+	var b []byte
+	depth := 0
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return string(b)
+			}
+			depth--
+		case '\\':
+			if lx.pos >= len(lx.data) {
+				return string(b)
+			}
+			e := lx.data[lx.pos]
+			lx.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// A line continuation.
+				if e == '\r' && lx.pos < len(lx.data) && lx.data[lx.pos] == '\n' {
+					lx.pos++
+				}
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				v := int(e - '0')
+				for i := 0; i < 2 && lx.pos < len(lx.data) && lx.data[lx.pos] >= '0' && lx.data[lx.pos] <= '7'; i++ {
+					v = v*8 + int(lx.data[lx.pos]-'0')
+					lx.pos++
+				}
+				c = byte(v)
+			default:
+				c = e
+			}
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// hexString reads a <hex string> after its opening bracket.
+func (lx *pdfLexer) hexString() string {
+	var b []byte
+	var hi byte
+	half := false
+	for lx.pos < len(lx.data) {
+		c := lx.data[lx.pos]
+		lx.pos++
+		var v byte
+		switch {
+		case c == '>':
+			if half {
+				b = append(b, hi<<4)
+			}
+			return string(b)
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if half {
+			b = append(b, hi<<4|v)
+		} else {
+			hi = v
+		}
+		half = !half
+	}
+	return string(b)
+}
+
+// skipInlineImage moves past the data of an inline image, after its ID
+// operator, to the EI that ends it.
+func (lx *pdfLexer) skipInlineImage() {
+	for i := lx.pos; i+2 < len(lx.data); i++ {
+		if isPDFSpace(lx.data[i]) && lx.data[i+1] == 'E' && lx.data[i+2] == 'I' &&
+			(i+3 == len(lx.data) || isPDFSpace(lx.data[i+3])) {
+			lx.pos = i + 3
+			return
+		}
+	}
+	lx.pos = len(lx.data)
+}
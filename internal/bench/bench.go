@@ -0,0 +1,266 @@
+// Package bench measures msgvault on a synthetic vault: ingest
+// throughput through the real sync pipeline, full-text search latency,
+// and aggregate query latency. Reports can be saved and compared, so
+// regressions and hardware choices show up as numbers.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/sync"
+)
+
+// Units results are reported in.
+const (
+	UnitMessagesPerSec = "msg/s"
+	UnitMilliseconds   = "ms"
+)
+
+// Options configures a benchmark run.
+type Options struct {
+	Messages int    // size of the synthetic vault
+	Seed     int64  // generator seed; a seed always yields the same vault
+	Runs     int    // timed repetitions of each query
+	Dir      string // directory the vault is created in; must be empty
+
+	// Realistic generates the corpus of Generate, with its charsets,
+	// threads, attachments and malformed MIME, instead of the plain one.
+	// Runs on different corpora are not comparable.
+	Realistic bool
+
+	// Analytics, when set, is called after ingest to build the Parquet
+	// analytics cache and return an engine over it, so aggregates are
+	// also timed the way the TUI runs them.
+	Analytics func(ctx context.Context, st *store.Store, dbPath string) (query.Engine, error)
+
+	// Progress, when set, is called as each stage starts.
+	Progress func(stage string)
+}
+
+// Result is one measurement.
+type Result struct {
+	Name  string  `json:"name"`
+	Unit  string  `json:"unit,omitempty"`
+	Value float64 `json:"value"`         // throughput, or median latency
+	P95   float64 `json:"p95,omitempty"` // 95th percentile latency
+	Hits  int     `json:"hits,omitempty"`
+	// Skipped explains why the measurement was not taken.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// HigherIsBetter reports whether a larger Value is an improvement.
+func (r Result) HigherIsBetter() bool { return r.Unit == UnitMessagesPerSec }
+
+// Report is the outcome of a run.
+type Report struct {
+	Messages  int       `json:"messages"`
+	Seed      int64     `json:"seed"`
+	Realistic bool      `json:"realistic,omitempty"`
+	Runs      int       `json:"runs"`
+	OS        string    `json:"os"`
+	Arch      string    `json:"arch"`
+	CPUs      int       `json:"cpus"`
+	Version   string    `json:"version,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	VaultSize int64     `json:"vault_bytes"`
+	Results   []Result  `json:"results"`
+}
+
+// ftsQueries are timed against the FTS index. Their selectivity is
+// fixed by the generator's planted terms.
+var ftsQueries = []struct{ name, q string }{
+	{"fts: common term", commonTerm},
+	{"fts: rare term", rareTerm},
+	{"fts: phrase", `"` + phrase + `"`},
+	{"fts: sender and term", "from:alice0@corp00.example.com " + commonTerm},
+	{"fts: term in date range", "budget after:2021-01-01 before:2022-01-01"},
+}
+
+var aggregateViews = []query.ViewType{query.ViewSenders, query.ViewDomains, query.ViewLabels, query.ViewTime}
+
+// Run builds a synthetic vault in opts.Dir and measures it.
+func Run(ctx context.Context, opts Options) (*Report, error) {
+	if opts.Messages <= 0 {
+		return nil, fmt.Errorf("messages must be positive, got %d", opts.Messages)
+	}
+	if opts.Runs <= 0 {
+		opts.Runs = 1
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+
+	rep := &Report{
+		Messages:  opts.Messages,
+		Seed:      opts.Seed,
+		Realistic: opts.Realistic,
+		Runs:      opts.Runs,
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		StartedAt: time.Now().UTC(),
+	}
+
+	dbPath := filepath.Join(opts.Dir, "msgvault.db")
+	st, err := store.Open(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("open vault: %w", err)
+	}
+	defer func() { _ = st.Close() }()
+	if err := st.InitSchema(); err != nil {
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+
+	progress(fmt.Sprintf("Ingesting %d synthetic messages", opts.Messages))
+	g := newGenerator(opts.Messages, opts.Seed)
+	if opts.Realistic {
+		g = newCorpusGenerator(opts.Messages, opts.Seed)
+	}
+	summary, err := ingest(ctx, st, opts.Dir, "bench", g, nil)
+	if err != nil {
+		return nil, err
+	}
+	secs := summary.Duration.Seconds()
+	rep.Results = append(rep.Results, Result{
+		Name:  "ingest",
+		Unit:  UnitMessagesPerSec,
+		Value: float64(summary.MessagesAdded) / max(secs, 1e-9),
+		Hits:  int(summary.MessagesAdded),
+	})
+
+	engine := query.NewSQLiteEngine(st.DB())
+	progress("Timing full-text search")
+	for _, fq := range ftsQueries {
+		if !st.FTS5Available() {
+			rep.Results = append(rep.Results, Result{Name: fq.name, Skipped: "FTS5 is not available"})
+			continue
+		}
+		q := search.Parse(fq.q)
+		var hits int
+		r, err := timeRuns(ctx, fq.name, opts.Runs, func() error {
+			res, err := engine.Search(ctx, q, 50, 0)
+			hits = len(res)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Hits = hits
+		rep.Results = append(rep.Results, r)
+	}
+
+	progress("Timing aggregates")
+	aggs, err := timeAggregates(ctx, "aggregate", engine, opts.Runs)
+	if err != nil {
+		return nil, err
+	}
+	rep.Results = append(rep.Results, aggs...)
+
+	if opts.Analytics != nil {
+		progress("Building analytics cache")
+		analytics, err := opts.Analytics(ctx, st, dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("analytics cache: %w", err)
+		}
+		progress("Timing cached aggregates")
+		aggs, err := timeAggregates(ctx, "cached aggregate", analytics, opts.Runs)
+		_ = analytics.Close()
+		if err != nil {
+			return nil, err
+		}
+		rep.Results = append(rep.Results, aggs...)
+	}
+
+	rep.Results = append(rep.Results, Result{
+		Name:    "encryption overhead",
+		Skipped: "vault encryption is not available in this build",
+	})
+
+	if size, err := dirSize(opts.Dir); err == nil {
+		rep.VaultSize = size
+	}
+	return rep, nil
+}
+
+// ingest syncs the messages of g into st as a source of sourceType,
+// storing attachments under dir.
+func ingest(ctx context.Context, st *store.Store, dir, sourceType string, g *generator, p gmail.SyncProgress) (*gmail.SyncSummary, error) {
+	syncOpts := sync.DefaultOptions()
+	syncOpts.SourceType = sourceType
+	syncOpts.AttachmentsDir = filepath.Join(dir, "attachments")
+	syncer := sync.NewFromSource(g, st, syncOpts).
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if p != nil {
+		syncer.WithProgress(p)
+	}
+	summary, err := syncer.Full(ctx, Account)
+	if err != nil {
+		return nil, fmt.Errorf("ingest: %w", err)
+	}
+	return summary, nil
+}
+
+func timeAggregates(ctx context.Context, prefix string, engine query.Engine, runs int) ([]Result, error) {
+	var out []Result
+	for _, view := range aggregateViews {
+		var rows int
+		r, err := timeRuns(ctx, fmt.Sprintf("%s: %s", prefix, view), runs, func() error {
+			res, err := engine.Aggregate(ctx, view, query.DefaultAggregateOptions())
+			rows = len(res)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		r.Hits = rows
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// timeRuns calls fn runs times after one untimed warm-up call and
+// returns its median and 95th percentile latency.
+func timeRuns(ctx context.Context, name string, runs int, fn func() error) (Result, error) {
+	if err := fn(); err != nil {
+		return Result{}, fmt.Errorf("%s: %w", name, err)
+	}
+	times := make([]float64, 0, runs)
+	for range runs {
+		if err := ctx.Err(); err != nil {
+			return Result{}, err
+		}
+		start := time.Now()
+		if err := fn(); err != nil {
+			return Result{}, fmt.Errorf("%s: %w", name, err)
+		}
+		times = append(times, float64(time.Since(start).Microseconds())/1000)
+	}
+	slices.Sort(times)
+	return Result{
+		Name:  name,
+		Unit:  UnitMilliseconds,
+		Value: percentile(times, 50),
+		P95:   percentile(times, 95),
+	}, nil
+}
+
+// percentile returns the p-th percentile of sorted by nearest rank.
+func percentile(sorted []float64, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
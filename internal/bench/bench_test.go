@@ -0,0 +1,149 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestGenerator_Deterministic(t *testing.T) {
+	a, b := newGenerator(100, 7), newGenerator(100, 7)
+	for _, i := range []int{0, 1, 10, 99} {
+		if !bytes.Equal(a.message(i).Raw, b.message(i).Raw) {
+			t.Errorf("message %d differs between generators with the same seed", i)
+		}
+	}
+	if bytes.Equal(a.message(1).Raw, newGenerator(100, 8).message(1).Raw) {
+		t.Error("different seeds produced the same message")
+	}
+}
+
+func TestGenerator_ListMessages(t *testing.T) {
+	g := newGenerator(listPageSize+3, 1)
+	first, err := g.ListMessages(context.Background(), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(first.Messages) != listPageSize || first.NextPageToken == "" {
+		t.Fatalf("first page: %d messages, token %q", len(first.Messages), first.NextPageToken)
+	}
+	second, err := g.ListMessages(context.Background(), "", first.NextPageToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Messages) != 3 || second.NextPageToken != "" {
+		t.Errorf("second page: %d messages, token %q", len(second.Messages), second.NextPageToken)
+	}
+	msgs, err := g.FetchMessages(context.Background(), []string{second.Messages[0].ID, "nope"})
+	if err != nil || msgs[0] == nil || msgs[1] != nil {
+		t.Errorf("FetchMessages() = %v, %v", msgs, err)
+	}
+}
+
+func TestCorpusGenerator(t *testing.T) {
+	a, b := newCorpusGenerator(500, 7), newCorpusGenerator(500, 7)
+	for _, i := range []int{0, 1, 10, 499} {
+		if !bytes.Equal(a.message(i).Raw, b.message(i).Raw) {
+			t.Errorf("message %d differs between generators with the same seed", i)
+		}
+	}
+	var replies int
+	for i := range 500 {
+		parent := int(a.parents[i])
+		if parent < 0 {
+			if int(a.roots[i]) != i {
+				t.Fatalf("message %d starts a thread rooted at %d", i, a.roots[i])
+			}
+			continue
+		}
+		replies++
+		if parent >= i || a.roots[i] != a.roots[parent] || a.threadID(i) != a.threadID(parent) {
+			t.Fatalf("message %d replies to %d in another thread", i, parent)
+		}
+		if !bytes.Contains(a.message(i).Raw, []byte("In-Reply-To: <"+messageID(parent)+"@example.com>")) {
+			t.Fatalf("message %d has no In-Reply-To for %d", i, parent)
+		}
+	}
+	if replies == 0 || replies == 499 {
+		t.Errorf("%d of 500 messages are replies", replies)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	n, err := Generate(context.Background(), GenerateOptions{Messages: 300, Seed: 1, Dir: dir})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if n != 300 {
+		t.Errorf("Generate() = %d messages, want 300", n)
+	}
+}
+
+func TestRun(t *testing.T) {
+	var stages []string
+	rep, err := Run(context.Background(), Options{
+		Messages: 300,
+		Seed:     1,
+		Runs:     2,
+		Dir:      t.TempDir(),
+		Progress: func(s string) { stages = append(stages, s) },
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(stages) == 0 || rep.VaultSize == 0 {
+		t.Errorf("stages %v, vault size %d", stages, rep.VaultSize)
+	}
+
+	byName := make(map[string]Result)
+	for _, r := range rep.Results {
+		byName[r.Name] = r
+	}
+	if ingest := byName["ingest"]; ingest.Hits != 300 || ingest.Value <= 0 {
+		t.Errorf("ingest = %+v, want 300 messages", ingest)
+	}
+	if r := byName["fts: common term"]; r.Skipped == "" && r.Hits == 0 {
+		t.Errorf("common term matched nothing: %+v", r)
+	}
+	if r := byName["aggregate: Senders"]; r.Hits == 0 || r.Unit != UnitMilliseconds {
+		t.Errorf("sender aggregate = %+v", r)
+	}
+	if r := byName["encryption overhead"]; r.Skipped == "" {
+		t.Errorf("encryption overhead = %+v, want skipped", r)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	base := &Report{Results: []Result{
+		{Name: "ingest", Unit: UnitMessagesPerSec, Value: 1000},
+		{Name: "fts: rare term", Unit: UnitMilliseconds, Value: 2},
+		{Name: "gone", Unit: UnitMilliseconds, Value: 1},
+	}}
+	cur := &Report{Results: []Result{
+		{Name: "ingest", Unit: UnitMessagesPerSec, Value: 1100},
+		{Name: "fts: rare term", Unit: UnitMilliseconds, Value: 3},
+		{Name: "encryption overhead", Skipped: "not available"},
+	}}
+
+	deltas := Compare(base, cur)
+	if len(deltas) != 2 {
+		t.Fatalf("got %d deltas, want 2: %+v", len(deltas), deltas)
+	}
+	if d := deltas[0]; d.Name != "ingest" || d.Change != 10 || !d.Better {
+		t.Errorf("ingest delta = %+v, want +10%% better", d)
+	}
+	if d := deltas[1]; d.Change != 50 || d.Better {
+		t.Errorf("fts delta = %+v, want +50%% worse", d)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if got := percentile(sorted, 50); got != 5 {
+		t.Errorf("p50 = %v, want 5", got)
+	}
+	if got := percentile(sorted, 95); got != 10 {
+		t.Errorf("p95 = %v, want 10", got)
+	}
+}
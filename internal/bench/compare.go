@@ -0,0 +1,65 @@
+package bench
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// Delta compares one result against a baseline report.
+type Delta struct {
+	Name     string
+	Unit     string
+	Baseline float64
+	Current  float64
+	// Change is the relative change in percent, positive when Current
+	// is larger.
+	Change float64
+	// Better is true when the change is an improvement.
+	Better bool
+}
+
+// Compare pairs the measured results of cur with those of the same name
+// in base. Results missing from either report, or skipped in either,
+// are left out.
+func Compare(base, cur *Report) []Delta {
+	baseline := make(map[string]Result, len(base.Results))
+	for _, r := range base.Results {
+		baseline[r.Name] = r
+	}
+	var out []Delta
+	for _, r := range cur.Results {
+		b, ok := baseline[r.Name]
+		if !ok || b.Skipped != "" || r.Skipped != "" || b.Unit != r.Unit || b.Value == 0 {
+			continue
+		}
+		change := (r.Value - b.Value) / b.Value * 100
+		out = append(out, Delta{
+			Name:     r.Name,
+			Unit:     r.Unit,
+			Baseline: b.Value,
+			Current:  r.Value,
+			Change:   change,
+			Better:   (change > 0) == r.HigherIsBetter(),
+		})
+	}
+	return out
+}
+
+// dirSize returns the total size of the regular files under dir.
+func dirSize(dir string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,419 @@
+package bench
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"math/rand"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Rates of the realistic corpus: one message (or attachment) in each
+// rate has the feature.
+const (
+	maxReplyDistance     = 30  // a reply answers one of the 30 messages before it
+	newThreadRate        = 3   // messages starting a conversation
+	newsletterRate       = 8   // conversations started by a mailing list
+	htmlRate             = 3   // messages with an HTML alternative
+	inlineImageRate      = 10  // HTML bodies with an inline image
+	corpusAttachmentRate = 8   // messages with attachments
+	largeAttachmentRate  = 200 // attachments of 256 KiB to 2 MiB
+	malformedRate        = 50  // messages with broken MIME
+)
+
+var corpusLabels = append(slices.Clone(labels),
+	&gmail.Label{ID: "UNREAD", Name: "UNREAD", Type: "system"},
+	&gmail.Label{ID: "STARRED", Name: "STARRED", Type: "system"},
+	&gmail.Label{ID: "Label_6", Name: "Work/Clients", Type: "user"},
+)
+
+var newsletters = []struct{ name, addr, list string }{
+	{"Example Weekly", "weekly@news.example.com", "weekly.news.example.com"},
+	{"Gadget Deals", "deals@shop.example.net", "deals.shop.example.net"},
+	{"Go Nuts", "golang-nuts@lists.example.org", "golang-nuts.lists.example.org"},
+	{"Town Council", "updates@council.example.org", "updates.council.example.org"},
+}
+
+// Charsets bodies are written in, weighted toward UTF-8, and the
+// non-ASCII words each can carry.
+var (
+	corpusCharsets = []string{"utf-8", "utf-8", "utf-8", "utf-8", "utf-8-base64", "iso-8859-1", "windows-1252", "iso-2022-jp"}
+	latinWords     = []string{"café", "naïve", "Grüße", "façade", "über", "señor", "résumé", "Zürich", "crème brûlée", "jalapeño"}
+	unicodeWords   = []string{"привет", "καλημέρα", "日本語", "会議", "🎉", "✅", "naïve", "Grüße"}
+	japaneseWords  = []string{"会議", "報告書", "予定", "ありがとう", "東京", "資料"}
+)
+
+// newCorpusGenerator returns a generator of the realistic corpus:
+// conversations of irregular length, bodies in several charsets and
+// transfer encodings, HTML alternatives, mailing lists, attachments of
+// varied types and sizes, and a share of malformed MIME.
+func newCorpusGenerator(count int, seed int64) *generator {
+	g := &generator{count: count, seed: seed, roots: make([]int32, count), parents: make([]int32, count)}
+	r := rand.New(rand.NewSource(seed))
+	for i := range count {
+		if i == 0 || r.Intn(newThreadRate) == 0 {
+			g.roots[i], g.parents[i] = int32(i), -1
+			continue
+		}
+		parent := i - 1 - r.Intn(min(i, maxReplyDistance))
+		g.roots[i], g.parents[i] = g.roots[parent], int32(parent)
+	}
+	return g
+}
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	Messages int   // size of the synthetic vault
+	Seed     int64 // generator seed; a seed always yields the same vault
+	Dir      string
+
+	// Progress, when set, is told how the ingest is going.
+	Progress gmail.SyncProgress
+}
+
+// Generate creates a synthetic vault in opts.Dir, laid out as a data
+// directory (msgvault.db and attachments), from the realistic corpus,
+// and returns how many messages it holds. The account is a Gmail
+// account named Account that cannot be synced.
+func Generate(ctx context.Context, opts GenerateOptions) (int64, error) {
+	if opts.Messages <= 0 {
+		return 0, fmt.Errorf("messages must be positive, got %d", opts.Messages)
+	}
+	st, err := store.Open(filepath.Join(opts.Dir, "msgvault.db"))
+	if err != nil {
+		return 0, fmt.Errorf("open vault: %w", err)
+	}
+	defer func() { _ = st.Close() }()
+	if err := st.InitSchema(); err != nil {
+		return 0, fmt.Errorf("init schema: %w", err)
+	}
+	summary, err := ingest(ctx, st, opts.Dir, "gmail", newCorpusGenerator(opts.Messages, opts.Seed), opts.Progress)
+	if err != nil {
+		return 0, err
+	}
+	return summary.MessagesAdded, nil
+}
+
+// mimePart is a MIME entity: its header lines, each ending in CRLF, and
+// its body.
+type mimePart struct {
+	header string
+	body   string
+}
+
+func multipartOf(subtype, boundary string, parts ...mimePart) mimePart {
+	var b strings.Builder
+	for _, p := range parts {
+		b.WriteString("--" + boundary + "\r\n" + p.header + "\r\n" + p.body + "\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return mimePart{
+		header: fmt.Sprintf("Content-Type: multipart/%s; boundary=%q\r\n", subtype, boundary),
+		body:   b.String(),
+	}
+}
+
+// textPart encodes text in one of corpusCharsets.
+func textPart(subtype, charset, text string) mimePart {
+	label, cte, body := charset, "8bit", text
+	switch charset {
+	case "utf-8-base64":
+		label, cte, body = "utf-8", "base64", wrapBase64([]byte(text))
+	case "iso-8859-1", "windows-1252":
+		enc := charmap.ISO8859_1
+		if charset == "windows-1252" {
+			enc = charmap.Windows1252
+		}
+		var b strings.Builder
+		w := quotedprintable.NewWriter(&b)
+		_, _ = w.Write(encodeLossy(enc, text))
+		_ = w.Close()
+		cte, body = "quoted-printable", b.String()
+	case "iso-2022-jp":
+		cte, body = "7bit", string(encodeLossy(japanese.ISO2022JP, text))
+	}
+	return mimePart{
+		header: fmt.Sprintf("Content-Type: text/%s; charset=%s\r\nContent-Transfer-Encoding: %s\r\n", subtype, label, cte),
+		body:   body,
+	}
+}
+
+func encodeLossy(enc encoding.Encoding, s string) []byte {
+	b, _ := encoding.ReplaceUnsupported(enc.NewEncoder()).Bytes([]byte(s))
+	return b
+}
+
+// encodeSubject encodes a header value as RFC 2047 encoded words in the
+// message's charset when it can hold the value, else in UTF-8.
+func encodeSubject(charset, s string) string {
+	if isASCII(s) {
+		return s
+	}
+	switch charset {
+	case "iso-8859-1", "windows-1252":
+		if b, err := charmap.ISO8859_1.NewEncoder().String(s); err == nil {
+			return mime.QEncoding.Encode("ISO-8859-1", b)
+		}
+	case "iso-2022-jp":
+		if b, err := japanese.ISO2022JP.NewEncoder().String(s); err == nil {
+			return mime.BEncoding.Encode("ISO-2022-JP", b)
+		}
+	}
+	return mime.BEncoding.Encode("UTF-8", s)
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func wrapBase64(data []byte) string {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(enc) > 76 {
+		b.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	b.WriteString(enc)
+	return b.String()
+}
+
+// attachmentPart builds attachment k of message i: a PDF, image,
+// archive or calendar invite, usually a few KiB and now and then a few
+// hundred KiB or more.
+func attachmentPart(r *rand.Rand, i, k int) mimePart {
+	kinds := []struct{ ext, mimeType, magic string }{
+		{"pdf", "application/pdf", "%PDF-1.4\n"},
+		{"png", "image/png", "\x89PNG\r\n\x1a\n"},
+		{"jpg", "image/jpeg", "\xff\xd8\xff\xe0"},
+		{"zip", "application/zip", "PK\x03\x04"},
+		{"ics", "text/calendar", "BEGIN:VCALENDAR\r\n"},
+	}
+	kind := kinds[r.Intn(len(kinds))]
+	size := 2<<10 + r.Intn(60<<10)
+	if r.Intn(largeAttachmentRate) == 0 {
+		size = 256<<10 + r.Intn(2<<20-256<<10)
+	}
+	data := make([]byte, size)
+	_, _ = r.Read(data)
+	copy(data, kind.magic)
+
+	name := fmt.Sprintf("%s-%d-%d.%s", vocabulary[r.Intn(len(vocabulary))], i, k, kind.ext)
+	if r.Intn(10) == 0 {
+		name = fmt.Sprintf("%s %d.%s", latinWords[r.Intn(len(latinWords))], i, kind.ext)
+	}
+	return mimePart{
+		header: fmt.Sprintf("Content-Type: %s\r\nContent-Disposition: %s\r\nContent-Transfer-Encoding: base64\r\n",
+			kind.mimeType, mime.FormatMediaType("attachment", map[string]string{"filename": name})),
+		body: wrapBase64(data),
+	}
+}
+
+// corpusSubject is the subject of the conversation started by message
+// root.
+func (g *generator) corpusSubject(root int) string {
+	r := rand.New(rand.NewSource(g.seed*7_919 + int64(root)))
+	ws := make([]string, 3+r.Intn(5))
+	for k := range ws {
+		ws[k] = vocabulary[r.Intn(len(vocabulary)/2)]
+	}
+	switch r.Intn(8) {
+	case 0:
+		ws = append(ws, latinWords[r.Intn(len(latinWords))])
+	case 1:
+		ws = append(ws, unicodeWords[r.Intn(len(unicodeWords))])
+	}
+	return strings.Join(ws, " ")
+}
+
+// corpusMessage builds message i of the realistic corpus. Like message,
+// it draws from a random stream of its own.
+func (g *generator) corpusMessage(i int) *gmail.RawMessage {
+	r := rand.New(rand.NewSource(g.seed*1_000_003 + int64(i)))
+	words := rand.NewZipf(r, 1.2, 4, uint64(len(vocabulary)-1))
+	text := func(n int) string {
+		ws := make([]string, n)
+		for k := range ws {
+			ws[k] = vocabulary[words.Uint64()]
+		}
+		return strings.Join(ws, " ")
+	}
+
+	root, parent := int(g.roots[i]), int(g.parents[i])
+	date := end.Add(-span + time.Duration(int64(span)/int64(max(g.count, 1))*int64(i)))
+	subject := g.corpusSubject(root)
+	if parent >= 0 {
+		subject = "Re: " + subject
+	}
+	charset := corpusCharsets[r.Intn(len(corpusCharsets))]
+
+	var body strings.Builder
+	body.WriteString(text(40 + r.Intn(260)))
+	body.WriteString(" " + commonTerm)
+	if i%phraseRate == 0 {
+		body.WriteString(" the " + phrase + " is attached")
+	}
+	if i%rareRate == 0 {
+		body.WriteString(" " + rareTerm)
+	}
+	switch charset {
+	case "iso-8859-1", "windows-1252":
+		body.WriteString(" " + latinWords[r.Intn(len(latinWords))])
+	case "iso-2022-jp":
+		body.WriteString(" " + japaneseWords[r.Intn(len(japaneseWords))])
+	default:
+		if r.Intn(3) == 0 {
+			body.WriteString(" " + unicodeWords[r.Intn(len(unicodeWords))])
+		}
+	}
+
+	name, from := sender(r.Intn(senderCount))
+	msgLabels := []string{"INBOX"}
+	list := -1
+	if parent < 0 && r.Intn(newsletterRate) == 0 {
+		list = r.Intn(len(newsletters))
+		name, from = newsletters[list].name, newsletters[list].addr
+		msgLabels = append(msgLabels, "CATEGORY_UPDATES", "Label_5")
+	} else {
+		userLabels := []string{"Label_1", "Label_2", "Label_3", "Label_4", "Label_6"}
+		msgLabels = append(msgLabels, userLabels[r.Intn(len(userLabels))])
+	}
+	if r.Intn(4) == 0 {
+		msgLabels = append(msgLabels, "UNREAD")
+	}
+	if r.Intn(30) == 0 {
+		msgLabels = append(msgLabels, "STARRED")
+	}
+	if r.Intn(10) == 0 {
+		msgLabels = append(msgLabels, "IMPORTANT")
+	}
+
+	malformed := -1
+	if r.Intn(malformedRate) == 0 {
+		malformed = r.Intn(6)
+	}
+
+	// The body: text, an HTML alternative with perhaps an inline image,
+	// and attachments.
+	top := textPart("plain", charset, body.String())
+	switch malformed {
+	case 1: // a charset no decoder knows
+		top = mimePart{
+			header: "Content-Type: text/plain; charset=x-unknown-8bit\r\nContent-Transfer-Encoding: 8bit\r\n",
+			body:   string(encodeLossy(charmap.ISO8859_1, body.String())),
+		}
+	case 2: // base64 that does not decode
+		top = mimePart{
+			header: "Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: base64\r\n",
+			body:   "VGhpcyBpcyBub3Q@@@ base64 ### " + text(10),
+		}
+	}
+	if list >= 0 || r.Intn(htmlRate) == 0 {
+		htmlBody := "<html><body><p>" + html.EscapeString(body.String()) + "</p>"
+		var inline *mimePart
+		if r.Intn(inlineImageRate) == 0 {
+			cid := fmt.Sprintf("img-%d@example.com", i)
+			htmlBody += `<img src="cid:` + cid + `" alt="logo">`
+			img := make([]byte, 1<<10+r.Intn(8<<10))
+			_, _ = r.Read(img)
+			copy(img, "\x89PNG\r\n\x1a\n")
+			inline = &mimePart{
+				header: "Content-Type: image/png\r\nContent-ID: <" + cid + ">\r\n" +
+					"Content-Disposition: inline; filename=\"logo.png\"\r\nContent-Transfer-Encoding: base64\r\n",
+				body: wrapBase64(img),
+			}
+		}
+		if list >= 0 {
+			htmlBody += fmt.Sprintf(`<p><a href="https://%s/unsubscribe/%d">Unsubscribe</a></p>`+
+				`<img src="https://%s/open/%d.gif" width="1" height="1">`,
+				newsletters[list].list, i, newsletters[list].list, i)
+		}
+		htmlPart := textPart("html", charset, htmlBody+"</body></html>")
+		if inline != nil {
+			htmlPart = multipartOf("related", fmt.Sprintf("rel-%d", i), htmlPart, *inline)
+		}
+		top = multipartOf("alternative", fmt.Sprintf("alt-%d", i), top, htmlPart)
+	}
+	if r.Intn(corpusAttachmentRate) == 0 || malformed == 0 {
+		parts := []mimePart{top}
+		for k := range 1 + r.Intn(2) {
+			parts = append(parts, attachmentPart(r, i, k))
+		}
+		boundary := fmt.Sprintf("mixed-%d", i)
+		top = multipartOf("mixed", boundary, parts...)
+		if malformed == 0 { // the closing boundary is missing
+			top.body = strings.TrimSuffix(top.body, "--"+boundary+"--\r\n")
+		}
+	}
+
+	var raw strings.Builder
+	header := func(k, v string) { fmt.Fprintf(&raw, "%s: %s\r\n", k, v) }
+	if malformed == 5 { // no usable sender and no date
+		header("From", "undisclosed-sender")
+	} else {
+		header("From", (&mail.Address{Name: name, Address: from}).String())
+		header("Date", date.Format(time.RFC1123Z))
+	}
+	header("To", Account)
+	if r.Intn(4) == 0 {
+		_, cc := sender(r.Intn(senderCount))
+		header("Cc", cc)
+	}
+	switch malformed {
+	case 3: // an encoded word that does not decode
+		header("Subject", "=?UTF-8?B?bm90*base64?= "+subject)
+	case 4: // 8-bit Latin-1 bytes, unencoded
+		header("Subject", string(encodeLossy(charmap.ISO8859_1, subject+" "+latinWords[0])))
+	default:
+		header("Subject", encodeSubject(charset, subject))
+	}
+	header("Message-ID", fmt.Sprintf("<%s@example.com>", messageID(i)))
+	if parent >= 0 {
+		header("In-Reply-To", fmt.Sprintf("<%s@example.com>", messageID(parent)))
+		refs := fmt.Sprintf("<%s@example.com>", messageID(root))
+		if parent != root {
+			refs += fmt.Sprintf(" <%s@example.com>", messageID(parent))
+		}
+		header("References", refs)
+	}
+	if list >= 0 {
+		header("List-Id", fmt.Sprintf("%q <%s>", newsletters[list].name, newsletters[list].list))
+		header("List-Unsubscribe", fmt.Sprintf("<https://%s/unsubscribe/%d>", newsletters[list].list, i))
+	}
+	header("MIME-Version", "1.0")
+	raw.WriteString(top.header + "\r\n" + top.body)
+
+	data := []byte(raw.String())
+	snippet := body.String()
+	if len(snippet) > 100 {
+		snippet = strings.ToValidUTF8(snippet[:100], "")
+	}
+	return &gmail.RawMessage{
+		ID:           messageID(i),
+		ThreadID:     g.threadID(i),
+		LabelIDs:     msgLabels,
+		Snippet:      snippet,
+		InternalDate: date.UnixMilli(),
+		SizeEstimate: int64(len(data)),
+		Raw:          data,
+	}
+}
@@ -0,0 +1,264 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/sync"
+)
+
+// Account is the address of the synthetic mailbox.
+const Account = "bench@example.com"
+
+const (
+	listPageSize   = 500
+	domainCount    = 50
+	senderCount    = 500
+	threadLength   = 3    // messages per conversation
+	attachmentRate = 10   // one message in attachmentRate has an attachment
+	phraseRate     = 20   // one message in phraseRate mentions the phrase
+	rareRate       = 1000 // one message in rareRate mentions the rare term
+)
+
+// Terms the generator plants at known rates, so query benchmarks have
+// predictable selectivity.
+const (
+	commonTerm = "project"
+	rareTerm   = "zanzibar"
+	phrase     = "quarterly report"
+)
+
+// vocabulary is ordered by frequency: the generator draws word ranks
+// from a Zipf distribution, so the first words are the most common.
+var vocabulary = strings.Fields(`
+the project update meeting please review team schedule budget client
+report next week design release notes draft plan status question
+thanks follow call agenda deadline invoice contract proposal summary
+feedback launch customer product service support issue ticket build
+server deploy test change request approval travel expense office
+holiday dinner weekend photos family school garden recipe coffee
+lunch birthday party gift order shipping delivery receipt payment
+account password security alert backup storage migration archive
+database index query cache latency throughput benchmark hardware
+network router firmware license renewal subscription newsletter
+webinar conference workshop training course certificate interview
+offer candidate resume reference onboarding handbook policy audit
+compliance legal patent trademark marketing campaign analytics
+dashboard metrics forecast revenue quarter annual board investor
+`)
+
+var firstNames = []string{
+	"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi",
+	"ivan", "judy", "mallory", "niaj", "olivia", "peggy", "rupert", "sybil",
+	"trent", "victor", "walter", "yvonne",
+}
+
+var labels = []*gmail.Label{
+	{ID: "INBOX", Name: "INBOX", Type: "system"},
+	{ID: "IMPORTANT", Name: "IMPORTANT", Type: "system"},
+	{ID: "CATEGORY_UPDATES", Name: "CATEGORY_UPDATES", Type: "system"},
+	{ID: "Label_1", Name: "Work", Type: "user"},
+	{ID: "Label_2", Name: "Personal", Type: "user"},
+	{ID: "Label_3", Name: "Receipts", Type: "user"},
+	{ID: "Label_4", Name: "Travel", Type: "user"},
+	{ID: "Label_5", Name: "Newsletters", Type: "user"},
+}
+
+// end is the date of the newest synthetic message; messages span the
+// five years before it.
+var (
+	end  = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	span = 5 * 365 * 24 * time.Hour
+)
+
+// generator is a sync.Source serving a deterministic synthetic mailbox:
+// the same seed and size always yield the same messages.
+type generator struct {
+	count int
+	seed  int64
+
+	// roots and parents, set for the realistic corpus (see
+	// newCorpusGenerator), hold the first message of each message's
+	// conversation and the message it replies to (-1 for none).
+	roots   []int32
+	parents []int32
+}
+
+func newGenerator(count int, seed int64) *generator {
+	return &generator{count: count, seed: seed}
+}
+
+func (g *generator) Capabilities() sync.Capabilities {
+	return sync.Capabilities{Threads: true, StableIDs: true}
+}
+
+func (g *generator) Profile(context.Context) (*gmail.Profile, error) {
+	return &gmail.Profile{EmailAddress: Account, MessagesTotal: int64(g.count)}, nil
+}
+
+func (g *generator) Labels(context.Context) ([]*gmail.Label, error) {
+	if g.roots != nil {
+		return corpusLabels, nil
+	}
+	return labels, nil
+}
+
+func (g *generator) ListMessages(_ context.Context, _, pageToken string) (*gmail.MessageListResponse, error) {
+	start := 0
+	if pageToken != "" {
+		n, err := strconv.Atoi(pageToken)
+		if err != nil {
+			return nil, fmt.Errorf("bad page token %q", pageToken)
+		}
+		start = n
+	}
+	stop := min(start+listPageSize, g.count)
+	resp := &gmail.MessageListResponse{ResultSizeEstimate: int64(g.count)}
+	for i := start; i < stop; i++ {
+		resp.Messages = append(resp.Messages, gmail.MessageID{ID: messageID(i), ThreadID: g.threadID(i)})
+	}
+	if stop < g.count {
+		resp.NextPageToken = strconv.Itoa(stop)
+	}
+	return resp, nil
+}
+
+func (g *generator) ListChanges(context.Context, uint64, string) (*gmail.HistoryResponse, error) {
+	return nil, fmt.Errorf("synthetic source has no history")
+}
+
+func (g *generator) FetchMessage(_ context.Context, id string) (*gmail.RawMessage, error) {
+	i, err := messageIndex(id)
+	if err != nil {
+		return nil, err
+	}
+	return g.message(i), nil
+}
+
+func (g *generator) FetchMessages(_ context.Context, ids []string) ([]*gmail.RawMessage, error) {
+	out := make([]*gmail.RawMessage, len(ids))
+	for k, id := range ids {
+		if i, err := messageIndex(id); err == nil {
+			out[k] = g.message(i)
+		}
+	}
+	return out, nil
+}
+
+func (g *generator) Close() error { return nil }
+
+func messageID(i int) string { return fmt.Sprintf("bench-%08d", i) }
+func threadID(i int) string  { return fmt.Sprintf("thread-%08d", i/threadLength) }
+
+func (g *generator) threadID(i int) string {
+	if g.roots != nil {
+		return fmt.Sprintf("thread-%08d", g.roots[i])
+	}
+	return threadID(i)
+}
+
+func messageIndex(id string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimPrefix(id, "bench-"))
+	if err != nil {
+		return 0, fmt.Errorf("unknown message %q", id)
+	}
+	return n, nil
+}
+
+func sender(n int) (name, addr string) {
+	first := firstNames[n%len(firstNames)]
+	domain := fmt.Sprintf("corp%02d.example.com", n%domainCount)
+	return fmt.Sprintf("%s %d", strings.ToUpper(first[:1])+first[1:], n), fmt.Sprintf("%s%d@%s", first, n, domain)
+}
+
+// message builds message i. Each message has its own random stream, so
+// it can be generated in any order.
+func (g *generator) message(i int) *gmail.RawMessage {
+	if g.roots != nil {
+		return g.corpusMessage(i)
+	}
+	r := rand.New(rand.NewSource(g.seed*1_000_003 + int64(i)))
+	words := rand.NewZipf(r, 1.2, 4, uint64(len(vocabulary)-1))
+	text := func(n int) string {
+		ws := make([]string, n)
+		for k := range ws {
+			ws[k] = vocabulary[words.Uint64()]
+		}
+		return strings.Join(ws, " ")
+	}
+
+	name, from := sender(r.Intn(senderCount))
+	date := end.Add(-span + time.Duration(int64(span)/int64(max(g.count, 1))*int64(i)))
+	subject := text(3 + r.Intn(5))
+	if i%threadLength != 0 {
+		subject = "Re: " + subject
+	}
+
+	var body strings.Builder
+	body.WriteString(text(40 + r.Intn(260)))
+	body.WriteString(" " + commonTerm)
+	if i%phraseRate == 0 {
+		body.WriteString(" the " + phrase + " is attached")
+	}
+	if i%rareRate == 0 {
+		body.WriteString(" " + rareTerm)
+	}
+
+	msgLabels := []string{"INBOX"}
+	msgLabels = append(msgLabels, labels[2+r.Intn(len(labels)-2)].ID)
+
+	var raw strings.Builder
+	header := func(k, v string) { fmt.Fprintf(&raw, "%s: %s\r\n", k, v) }
+	header("From", fmt.Sprintf("%q <%s>", name, from))
+	header("To", Account)
+	if r.Intn(4) == 0 {
+		_, cc := sender(r.Intn(senderCount))
+		header("Cc", cc)
+	}
+	header("Subject", subject)
+	header("Date", date.Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@example.com>", messageID(i)))
+	if i%threadLength != 0 {
+		header("In-Reply-To", fmt.Sprintf("<%s@example.com>", messageID(i-1)))
+	}
+	header("MIME-Version", "1.0")
+	if i%attachmentRate == 0 {
+		boundary := "bench-boundary-" + strconv.Itoa(i)
+		header("Content-Type", `multipart/mixed; boundary="`+boundary+`"`)
+		raw.WriteString("\r\n--" + boundary + "\r\n")
+		raw.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		raw.WriteString(body.String())
+		raw.WriteString("\r\n--" + boundary + "\r\n")
+		raw.WriteString("Content-Type: text/csv\r\n")
+		fmt.Fprintf(&raw, "Content-Disposition: attachment; filename=\"data-%d.csv\"\r\n\r\n", i)
+		for row := 0; row < 20+r.Intn(80); row++ {
+			fmt.Fprintf(&raw, "%d,%s,%d\r\n", row, vocabulary[r.Intn(len(vocabulary))], r.Int63())
+		}
+		raw.WriteString("--" + boundary + "--\r\n")
+	} else {
+		header("Content-Type", "text/plain; charset=utf-8")
+		raw.WriteString("\r\n")
+		raw.WriteString(body.String())
+		raw.WriteString("\r\n")
+	}
+
+	data := []byte(raw.String())
+	snippet := body.String()
+	if len(snippet) > 100 {
+		snippet = snippet[:100]
+	}
+	return &gmail.RawMessage{
+		ID:           messageID(i),
+		ThreadID:     threadID(i),
+		LabelIDs:     msgLabels,
+		Snippet:      snippet,
+		InternalDate: date.UnixMilli(),
+		SizeEstimate: int64(len(data)),
+		Raw:          data,
+	}
+}
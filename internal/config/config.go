@@ -2,6 +2,7 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"log/slog"
 	"net"
@@ -9,7 +10,9 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/wesm/msgvault/internal/fileutil"
@@ -26,12 +29,45 @@ type ChatConfig struct {
 // ServerConfig holds HTTP API server configuration.
 type ServerConfig struct {
 	APIPort         int      `toml:"api_port"`         // HTTP server port (default: 8080)
+	GRPCPort        int      `toml:"grpc_port"`        // gRPC server port (0 = disabled)
+	IMAPPort        int      `toml:"imap_port"`        // Read-only IMAP gateway port (0 = disabled)
 	BindAddr        string   `toml:"bind_addr"`        // Bind address (default: 127.0.0.1)
 	APIKey          string   `toml:"api_key"`          // API authentication key
 	AllowInsecure   bool     `toml:"allow_insecure"`   // Allow unauthenticated non-loopback access
 	CORSOrigins     []string `toml:"cors_origins"`     // Allowed CORS origins (empty = disabled)
 	CORSCredentials bool     `toml:"cors_credentials"` // Allow credentials in CORS
 	CORSMaxAge      int      `toml:"cors_max_age"`     // Preflight cache duration in seconds
+	MinFreeDiskMB   int      `toml:"min_free_disk_mb"` // /readyz fails below this much free space (default: 512)
+
+	// Users are additional API credentials. Non-admin users only see
+	// messages from the accounts they are granted.
+	Users []ServerUser `toml:"users"`
+}
+
+// ServerUser is a named API credential for server mode. APIKey is sent
+// the same way as [server] api_key; Accounts lists source identifiers
+// (usually email addresses) the user may read. Admin grants the same
+// unrestricted access as [server] api_key.
+type ServerUser struct {
+	Name     string   `toml:"name"`
+	APIKey   string   `toml:"api_key"`
+	Accounts []string `toml:"accounts"`
+	Admin    bool     `toml:"admin"`
+}
+
+// RequiresAuth reports whether API requests must present a key: true
+// when [server] api_key or any [[server.users]] entry is configured.
+func (s ServerConfig) RequiresAuth() bool {
+	return s.APIKey != "" || len(s.Users) > 0
+}
+
+// MinFreeDiskBytes returns the free-space floor /readyz enforces on
+// the data directory's filesystem.
+func (s ServerConfig) MinFreeDiskBytes() uint64 {
+	if s.MinFreeDiskMB <= 0 {
+		return 512 << 20
+	}
+	return uint64(s.MinFreeDiskMB) << 20
 }
 
 // IsLoopback returns true if the bind address is a loopback address.
@@ -48,10 +84,44 @@ func (s ServerConfig) IsLoopback() bool {
 // ValidateSecure returns an error if the server is configured insecurely
 // without an explicit opt-in via allow_insecure.
 func (s ServerConfig) ValidateSecure() error {
-	if !s.IsLoopback() && s.APIKey == "" && !s.AllowInsecure {
+	return s.ValidateSecureWith(false)
+}
+
+// ValidateSecureWith is ValidateSecure for a server that also accepts
+// API tokens stored in the vault; hasTokens reports whether any are
+// active, which satisfies the authentication requirement.
+func (s ServerConfig) ValidateSecureWith(hasTokens bool) error {
+	if !s.IsLoopback() && !s.RequiresAuth() && !hasTokens && !s.AllowInsecure {
 		return fmt.Errorf("refusing to start: bind address %q is not loopback and no api_key is set\n\n"+
 			"Set [server] api_key in config.toml, or set allow_insecure = true to override", s.BindAddr)
 	}
+	return s.validateUsers()
+}
+
+// validateUsers rejects [[server.users]] entries that would make key
+// lookup ambiguous or grant nothing by mistake.
+func (s ServerConfig) validateUsers() error {
+	seenNames := make(map[string]bool, len(s.Users))
+	seenKeys := map[string]bool{s.APIKey: s.APIKey != ""}
+	for i, u := range s.Users {
+		if u.Name == "" {
+			return fmt.Errorf("[[server.users]] entry %d: name is required", i+1)
+		}
+		if seenNames[u.Name] {
+			return fmt.Errorf("[[server.users]] %q: duplicate name", u.Name)
+		}
+		seenNames[u.Name] = true
+		if u.APIKey == "" {
+			return fmt.Errorf("[[server.users]] %q: api_key is required", u.Name)
+		}
+		if seenKeys[u.APIKey] {
+			return fmt.Errorf("[[server.users]] %q: api_key is already used by another user", u.Name)
+		}
+		seenKeys[u.APIKey] = true
+		if !u.Admin && len(u.Accounts) == 0 {
+			return fmt.Errorf("[[server.users]] %q: set accounts = [...] or admin = true", u.Name)
+		}
+	}
 	return nil
 }
 
@@ -60,8 +130,87 @@ type AccountSchedule struct {
 	Email    string `toml:"email"`    // Gmail account email
 	Schedule string `toml:"schedule"` // Cron expression (e.g., "0 2 * * *" for 2am daily)
 	Enabled  bool   `toml:"enabled"`  // Whether scheduled sync is active
+
+	// Jitter delays each scheduled sync by a random amount up to this
+	// long (e.g. "10m"), so accounts sharing a schedule don't all hit
+	// the API at once. Zero means [daemon] jitter.
+	Jitter time.Duration `toml:"jitter"`
+
+	// Labels limits every sync of the account to messages with one of
+	// these labels, by name or ID (e.g. ["INBOX", "Work"]). Empty
+	// archives all mail.
+	Labels []string `toml:"labels"`
+
+	// ExcludeLabels skips new messages with any of these labels (e.g.
+	// ["SPAM", "TRASH"]).
+	ExcludeLabels []string `toml:"exclude_labels"`
+
+	// IncludeSpamTrash makes full syncs archive Spam and Trash, which
+	// Gmail leaves out by default.
+	IncludeSpamTrash bool `toml:"include_spam_trash"`
+}
+
+// WebhookConfig defines an HTTP endpoint that `msgvault serve` notifies
+// when newly synced messages match a search query.
+type WebhookConfig struct {
+	Name   string `toml:"name"`   // Identifies the webhook in logs and payloads
+	URL    string `toml:"url"`    // http(s) endpoint receiving the POST
+	Query  string `toml:"query"`  // Search syntax (e.g. "subject:invoice"); empty matches all new mail
+	Secret string `toml:"secret"` // HMAC-SHA256 signing key; empty disables signing
+	// Fields limits the message fields included in the payload. Empty
+	// means all supported fields (see internal/webhook).
+	Fields []string `toml:"fields"`
+	// MaxAttempts is the number of delivery attempts, including the
+	// first. Zero means the default (3).
+	MaxAttempts int `toml:"max_attempts"`
+}
+
+// RuleConfig is a Gmail-filter-style rule applied to newly synced
+// messages that match Query.
+type RuleConfig struct {
+	Name  string `toml:"name"`  // Identifies the rule in output and logs
+	Query string `toml:"query"` // Search syntax, e.g. "from:shop.example.com"
+	Label string `toml:"label"` // Vault-local label to apply
+	// Category files matches under a Gmail category tab: personal,
+	// social, promotions, updates, or forums.
+	Category string `toml:"category"`
+	// SkipAttachments drops the extracted attachment files of matches;
+	// the raw message still holds them.
+	SkipAttachments bool `toml:"skip_attachments"`
+	Notify          bool `toml:"notify"` // Report each match
+}
+
+// RuleCategories lists the values RuleConfig.Category accepts.
+var RuleCategories = []string{"personal", "social", "promotions", "updates", "forums"}
+
+// NotifierConfig sends a notification when one of Events happens.
+type NotifierConfig struct {
+	Name string `toml:"name"` // Identifies the notifier in logs and 'notify test'
+	Type string `toml:"type"` // desktop, ntfy, email, or slack
+	// Events selects what is sent: sync_complete, sync_error,
+	// rule_match, reminder_due. Empty means all of them.
+	Events []string `toml:"events"`
+
+	// URL is the ntfy topic URL (https://ntfy.sh/<topic>) or the Slack
+	// incoming webhook URL.
+	URL   string `toml:"url"`
+	Token string `toml:"token"` // ntfy access token; empty for public topics
+
+	// SMTP settings for the email type. SMTPPort zero means 587.
+	SMTPHost     string   `toml:"smtp_host"`
+	SMTPPort     int      `toml:"smtp_port"`
+	SMTPUsername string   `toml:"smtp_username"`
+	SMTPPassword string   `toml:"smtp_password"`
+	From         string   `toml:"from"`
+	To           []string `toml:"to"`
 }
 
+// NotifierTypes lists the values NotifierConfig.Type accepts.
+var NotifierTypes = []string{"desktop", "email", "ntfy", "slack"}
+
+// NotifyEvents lists the values NotifierConfig.Events accepts.
+var NotifyEvents = []string{"sync_complete", "sync_error", "rule_match", "reminder_due"}
+
 // RemoteConfig holds configuration for a remote msgvault server.
 // Used by export-token to remember the NAS/server destination.
 type RemoteConfig struct {
@@ -79,19 +228,45 @@ type IdentityConfig struct {
 type Config struct {
 	Data      DataConfig        `toml:"data"`
 	Log       LogConfig         `toml:"log"`
+	Tracing   TracingConfig     `toml:"tracing"`
 	OAuth     OAuthConfig       `toml:"oauth"`
 	Microsoft MicrosoftConfig   `toml:"microsoft"`
 	Sync      SyncConfig        `toml:"sync"`
+	Parse     ParseConfig       `toml:"parse"`
 	Chat      ChatConfig        `toml:"chat"`
 	Server    ServerConfig      `toml:"server"`
 	Remote    RemoteConfig      `toml:"remote"`
+	UI        UIConfig          `toml:"ui"`
 	Vector    vector.Config     `toml:"vector"`
 	Identity  IdentityConfig    `toml:"identity"`
 	Accounts  []AccountSchedule `toml:"accounts"`
+	Webhooks  []WebhookConfig   `toml:"webhooks"`
+	Rules     []RuleConfig      `toml:"rules"`
+	Notifiers []NotifierConfig  `toml:"notifiers"`
+	Risk      RiskConfig        `toml:"risk"`
+	VirusScan VirusScanConfig   `toml:"virus_scan"`
+	Watch     WatchConfig       `toml:"watch"`
+	Daemon    DaemonConfig      `toml:"daemon"`
 
 	// Computed paths (not from config file)
 	HomeDir    string `toml:"-"`
 	configPath string // resolved path to the loaded config file
+
+	// unknownKeys lists keys in the loaded file that match no setting,
+	// usually typos; see Check.
+	unknownKeys []string
+
+	// envOverrides lists the MSGVAULT_* variables applied by Load.
+	envOverrides []string
+
+	// cacheDir holds rebuildable files such as the Parquet analytics
+	// cache. Empty means the data directory, as when --home, --config,
+	// or [data] data_dir moves the vault away from the defaults.
+	cacheDir string
+
+	// saved holds each setting as rendered when the config was loaded
+	// or last saved, so Save writes back only what changed since.
+	saved map[string]string
 }
 
 // LogConfig holds logging configuration. File logging is opt-in:
@@ -126,12 +301,69 @@ type LogConfig struct {
 	// is voluminous — leave off in normal use and flip it on
 	// (via config or --log-sql) only when debugging.
 	SQLTrace bool `toml:"sql_trace"`
+
+	// Format selects the stderr log encoding: "text" (default) or
+	// "json". Log files are always JSON.
+	Format string `toml:"format"`
+
+	// MaxSizeMB rotates a log file once it would grow past this
+	// many megabytes. Zero means 50.
+	MaxSizeMB int `toml:"max_size_mb"`
+
+	// MaxFiles caps how many rotated files are kept alongside the
+	// current one. Zero means 5.
+	MaxFiles int `toml:"max_files"`
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration. Tracing
+// is off unless an exporter is set; it is meant for long-running
+// serve and sync deployments where slow syncs and query latency need
+// to be followed end-to-end in a tracing backend.
+type TracingConfig struct {
+	// Exporter is "otlp" (OTLP over HTTP), "stdout" (spans as JSON
+	// on stderr, for debugging), or empty / "none" to disable.
+	Exporter string `toml:"exporter"`
+
+	// Endpoint is the OTLP collector as host:port or a full URL.
+	// Empty falls back to OTEL_EXPORTER_OTLP_ENDPOINT, then
+	// localhost:4318.
+	Endpoint string `toml:"endpoint"`
+
+	// Insecure sends OTLP over plain HTTP when Endpoint is a bare
+	// host:port.
+	Insecure bool `toml:"insecure"`
+
+	// SampleRatio is the fraction of traces recorded, between 0
+	// and 1. Zero means record every trace.
+	SampleRatio float64 `toml:"sample_ratio"`
+}
+
+// UIConfig holds settings for the TUI and CLI output.
+type UIConfig struct {
+	// Language is the language for the TUI and CLI reports, as a tag
+	// such as "de" or "fr". Empty follows LC_ALL, LC_MESSAGES, or LANG.
+	Language string `toml:"language"`
 }
 
 // DataConfig holds data storage configuration.
 type DataConfig struct {
 	DataDir     string `toml:"data_dir"`
 	DatabaseURL string `toml:"database_url"`
+	// AttachmentsDir overrides where attachment files are stored, for
+	// keeping bulky attachments on a different disk than the database.
+	// Empty means <data_dir>/attachments.
+	AttachmentsDir string `toml:"attachments_dir,omitempty"`
+	// RawCompression is the codec raw messages are compressed with as
+	// they are archived: "zlib" (the default) or "zstd", which
+	// compresses better, more so with a dictionary trained by
+	// 'msgvault recompress --train-dict'. Messages already archived
+	// keep their codec until 'msgvault recompress' rewrites them.
+	RawCompression string `toml:"raw_compression,omitempty"`
+	// RawBlobStore keeps the raw messages archived from now on in
+	// content-addressed files under <data_dir>/raw, like attachments,
+	// rather than in the database, which keeps it small. 'msgvault
+	// migrate-raw' moves the messages already archived either way.
+	RawBlobStore bool `toml:"raw_blob_store,omitempty"`
 }
 
 // OAuthApp holds configuration for a named OAuth application.
@@ -214,28 +446,142 @@ func (c *MicrosoftConfig) EffectiveTenantID() string {
 // SyncConfig holds sync-related configuration.
 type SyncConfig struct {
 	RateLimitQPS int `toml:"rate_limit_qps"`
+
+	// FetchWorkers is how many messages a sync downloads at once, each
+	// worker fetching one at a time. 0 leaves it to each source's own
+	// batch fetch.
+	FetchWorkers int `toml:"fetch_workers"`
+
+	// FetchRate caps each fetch worker at this many messages per second
+	// (0 = no cap beyond rate_limit_qps).
+	FetchRate float64 `toml:"fetch_rate"`
+
+	// WriteBatch is how many messages a sync writes to the database
+	// per transaction. 0 uses the default of 100.
+	WriteBatch int `toml:"write_batch"`
+
+	// RecoverExpiredHistory makes 'sync' and scheduled syncs catch up
+	// on their own when an account's change history has expired: a
+	// full sync of the mail since the last sync, then deleted mail is
+	// marked deleted. Without it, they fail and ask for 'sync-full'.
+	RecoverExpiredHistory bool `toml:"recover_expired_history"`
+
+	// SkipAttachments leaves attachments in the raw MIME instead of
+	// extracting them to the attachments directory as mail is synced;
+	// 'msgvault attachments fetch' extracts them later.
+	SkipAttachments bool `toml:"skip_attachments"`
+
+	// StorageQuota makes syncs of Gmail accounts record the account's
+	// Google storage quota once a day, for 'msgvault quota' to chart
+	// against the archive's size. Google reports the quota through the
+	// Drive API, which must be enabled in the OAuth app's project, and
+	// the drive.appdata scope, so accounts added before it was set need
+	// 'msgvault add-account --force' to grant it.
+	StorageQuota bool `toml:"storage_quota"`
+}
+
+// ParseConfig holds settings for how message bodies are parsed when
+// they are archived.
+type ParseConfig struct {
+	// FoldReplies stores the quoted reply trail and signature of each
+	// email body in fields of their own, so full-text search and chat
+	// see only what the sender wrote. The full body is kept either way.
+	FoldReplies bool `toml:"fold_replies"`
+
+	// VerifyDKIM checks the DKIM signatures of each email against the
+	// signer's key in DNS as it is archived, recording the result for
+	// is:dkim-pass. Authentication-Results headers are recorded either
+	// way.
+	VerifyDKIM bool `toml:"verify_dkim"`
+
+	// IndexHeaders lists header fields to index besides List-Id and
+	// Delivered-To, which always are, for 'msgvault list-headers':
+	// X-Original-To, say. Mail archived earlier picks them up with
+	// 'msgvault reparse'.
+	IndexHeaders []string `toml:"index_headers"`
+
+	// IndexAttachments extracts the text of PDF, DOCX and plain-text
+	// attachments after each sync, for in:attachments searches.
+	// 'msgvault index-attachments' indexes those archived earlier.
+	IndexAttachments bool `toml:"index_attachments"`
+}
+
+// RiskConfig holds settings for 'msgvault risk scan', which scores
+// archived email for spam and phishing.
+type RiskConfig struct {
+	// Blocklists are files of known-bad domains, one per line, such as
+	// the URLhaus or OpenPhish domain feeds. Links to a listed domain,
+	// or to a subdomain of one, count against a message.
+	Blocklists []string `toml:"blocklists"`
+
+	// RspamdURL is the address of an rspamd worker (e.g.
+	// http://localhost:11333). When set, each message is also scored
+	// by rspamd. Empty means local heuristics only.
+	RspamdURL string `toml:"rspamd_url"`
+}
+
+// WatchConfig holds settings for 'msgvault watch', which syncs Gmail
+// accounts when Gmail reports a change through Cloud Pub/Sub instead
+// of on a schedule.
+type WatchConfig struct {
+	// Topic is the Pub/Sub topic Gmail publishes mailbox changes to,
+	// "projects/<project>/topics/<name>". Gmail's push service account,
+	// gmail-api-push@system.gserviceaccount.com, must be allowed to
+	// publish to it.
+	Topic string `toml:"topic"`
+
+	// Subscription is a pull subscription to Topic,
+	// "projects/<project>/subscriptions/<name>", that watch reads the
+	// notifications from.
+	Subscription string `toml:"subscription"`
+
+	// Credentials is a service account key file allowed to pull from
+	// Subscription. Empty uses Application Default Credentials.
+	Credentials string `toml:"credentials"`
+
+	// Labels limits notifications to changes of these Gmail label IDs,
+	// e.g. ["INBOX"]. Empty means every change.
+	Labels []string `toml:"labels"`
 }
 
-// DefaultHome returns the default msgvault home directory.
-// Respects MSGVAULT_HOME environment variable and expands ~ in its value.
+// DaemonConfig holds settings for the scheduled syncs run by
+// 'msgvault daemon' and 'msgvault serve'.
+type DaemonConfig struct {
+	// Jitter is the most each scheduled sync is delayed by, for
+	// accounts that set no jitter of their own. Zero runs syncs on the
+	// minute.
+	Jitter time.Duration `toml:"jitter"`
+}
+
+// VirusScanConfig runs a virus scanner over attachments as they are
+// stored.
+type VirusScanConfig struct {
+	// Command is the scanner and its arguments, e.g. ["clamdscan",
+	// "--fdpass", "--no-summary"]; the path of each file to scan is
+	// appended. It must exit 0 for a clean file and 1 for an infected
+	// one, as clamscan and clamdscan do. Empty disables scanning.
+	Command []string `toml:"command"`
+
+	// Quarantine blocks infected attachments from being exported or
+	// downloaded until 'msgvault release-attachment' releases them.
+	Quarantine bool `toml:"quarantine"`
+}
+
+// DefaultHome returns the default msgvault home directory, where
+// config.toml lives: MSGVAULT_HOME (with ~ expanded) when set, else
+// the platform config directory (see DefaultDirs).
 func DefaultHome() string {
-	if h := os.Getenv("MSGVAULT_HOME"); h != "" {
-		return expandPath(h)
-	}
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return ".msgvault"
-	}
-	return filepath.Join(home, ".msgvault")
+	return DefaultDirs().Config
 }
 
 // NewDefaultConfig returns a configuration with default values.
 func NewDefaultConfig() *Config {
-	homeDir := DefaultHome()
+	dirs := DefaultDirs()
 	cfg := &Config{
-		HomeDir: homeDir,
+		HomeDir:  dirs.Config,
+		cacheDir: dirs.Cache,
 		Data: DataConfig{
-			DataDir: homeDir,
+			DataDir: dirs.Data,
 		},
 		Sync: SyncConfig{
 			RateLimitQPS: 5,
@@ -256,7 +602,7 @@ func NewDefaultConfig() *Config {
 }
 
 // Load reads the configuration from the specified file.
-// If path is empty, uses the default location (~/.msgvault/config.toml),
+// If path is empty, uses the default location (config.toml in DefaultHome),
 // which is optional (missing file returns defaults).
 // If path is explicitly provided, the file must exist.
 //
@@ -266,6 +612,7 @@ func Load(path, homeDir string) (*Config, error) {
 	explicit := path != ""
 
 	cfg := NewDefaultConfig()
+	defaultDataDir := cfg.Data.DataDir
 
 	// --home overrides the default home directory, just like MSGVAULT_HOME.
 	if homeDir != "" {
@@ -286,36 +633,55 @@ func Load(path, homeDir string) (*Config, error) {
 		if explicit {
 			return nil, fmt.Errorf("config file not found: %s", path)
 		}
-		// Default config file is optional
-		return cfg, nil
+		// Default config file is optional; defaults plus any
+		// environment overrides apply.
+		path = ""
 	}
 
-	cfg.configPath = path
+	if path != "" {
+		cfg.configPath = path
 
-	// When --config points to a custom location without --home,
-	// derive HomeDir and default DataDir from the config file's parent
-	// directory so that tokens, database, attachments, etc. live alongside
-	// the config.
-	if explicit && homeDir == "" {
-		cfg.HomeDir = filepath.Dir(path)
-		cfg.Data.DataDir = cfg.HomeDir
-	}
+		// When --config points to a custom location without --home,
+		// derive HomeDir and default DataDir from the config file's parent
+		// directory so that tokens, database, attachments, etc. live alongside
+		// the config.
+		if explicit && homeDir == "" {
+			cfg.HomeDir = filepath.Dir(path)
+			cfg.Data.DataDir = cfg.HomeDir
+		}
 
-	if _, err := toml.DecodeFile(path, cfg); err != nil {
-		if strings.Contains(err.Error(), "invalid escape") ||
-			strings.Contains(err.Error(), "hexadecimal digits after") {
-			return nil, fmt.Errorf("decode config: %w -- hint: Windows paths in TOML must use "+
-				"forward slashes (C:/Games/msgvault) or single quotes ('C:\\Games\\msgvault')", err)
+		md, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid escape") ||
+				strings.Contains(err.Error(), "hexadecimal digits after") {
+				return nil, fmt.Errorf("decode config: %w -- hint: Windows paths in TOML must use "+
+					"forward slashes (C:/Games/msgvault) or single quotes ('C:\\Games\\msgvault')", err)
+			}
+			return nil, fmt.Errorf("decode config: %w", err)
+		}
+		for _, key := range md.Undecoded() {
+			cfg.unknownKeys = append(cfg.unknownKeys, key.String())
 		}
-		return nil, fmt.Errorf("decode config: %w", err)
 	}
 
+	// MSGVAULT_* variables override the file (see applyEnv).
+	overrides, err := applyEnv(cfg, os.Environ())
+	if err != nil {
+		return nil, fmt.Errorf("environment override: %w", err)
+	}
+	cfg.envOverrides = overrides
+
 	// Expand ~ in paths
 	cfg.Data.DataDir = expandPath(cfg.Data.DataDir)
+	cfg.Data.AttachmentsDir = expandPath(cfg.Data.AttachmentsDir)
 	cfg.Log.Dir = expandPath(cfg.Log.Dir)
 	cfg.OAuth.ClientSecrets = expandPath(cfg.OAuth.ClientSecrets)
 	cfg.OAuth.ServiceAccountKey = expandPath(cfg.OAuth.ServiceAccountKey)
 	cfg.Vector.DBPath = expandPath(cfg.Vector.DBPath)
+	cfg.Watch.Credentials = expandPath(cfg.Watch.Credentials)
+	for i, path := range cfg.Risk.Blocklists {
+		cfg.Risk.Blocklists[i] = expandPath(path)
+	}
 	for name, app := range cfg.OAuth.Apps {
 		app.ClientSecrets = expandPath(app.ClientSecrets)
 		app.ServiceAccountKey = expandPath(app.ServiceAccountKey)
@@ -326,10 +692,15 @@ func Load(path, homeDir string) (*Config, error) {
 	// directory so behavior doesn't depend on the working directory.
 	if explicit {
 		cfg.Data.DataDir = resolveRelative(cfg.Data.DataDir, cfg.HomeDir)
+		cfg.Data.AttachmentsDir = resolveRelative(cfg.Data.AttachmentsDir, cfg.HomeDir)
 		cfg.Log.Dir = resolveRelative(cfg.Log.Dir, cfg.HomeDir)
 		cfg.OAuth.ClientSecrets = resolveRelative(cfg.OAuth.ClientSecrets, cfg.HomeDir)
 		cfg.OAuth.ServiceAccountKey = resolveRelative(cfg.OAuth.ServiceAccountKey, cfg.HomeDir)
 		cfg.Vector.DBPath = resolveRelative(cfg.Vector.DBPath, cfg.HomeDir)
+		cfg.Watch.Credentials = resolveRelative(cfg.Watch.Credentials, cfg.HomeDir)
+		for i, path := range cfg.Risk.Blocklists {
+			cfg.Risk.Blocklists[i] = resolveRelative(path, cfg.HomeDir)
+		}
 		for name, app := range cfg.OAuth.Apps {
 			app.ClientSecrets = resolveRelative(app.ClientSecrets, cfg.HomeDir)
 			app.ServiceAccountKey = resolveRelative(app.ServiceAccountKey, cfg.HomeDir)
@@ -343,6 +714,14 @@ func Load(path, homeDir string) (*Config, error) {
 	// an explicit false in the file stays false.
 	cfg.Vector.ApplyDefaults()
 
+	if cfg.Data.DataDir != defaultDataDir {
+		cfg.cacheDir = ""
+	}
+
+	if rendered, err := cfg.render(); err == nil {
+		cfg.saved = snapshotTOML(flattenTOML(rendered))
+	}
+
 	return cfg, nil
 }
 
@@ -399,18 +778,33 @@ func (c *Config) DatabasePath() (string, error) {
 	return dsn, nil
 }
 
-// AttachmentsDir returns the path to the attachments directory.
+// AttachmentsDir returns the path to the attachments directory: the
+// [data] attachments_dir override when set, else <data_dir>/attachments.
 func (c *Config) AttachmentsDir() string {
+	if c.Data.AttachmentsDir != "" {
+		return c.Data.AttachmentsDir
+	}
 	return filepath.Join(c.Data.DataDir, "attachments")
 }
 
+// RawBlobsDir returns the path to the directory raw messages kept
+// outside the database are stored in.
+func (c *Config) RawBlobsDir() string {
+	return filepath.Join(c.Data.DataDir, "raw")
+}
+
 // TokensDir returns the path to the OAuth tokens directory.
 func (c *Config) TokensDir() string {
 	return filepath.Join(c.Data.DataDir, "tokens")
 }
 
-// AnalyticsDir returns the path to the Parquet analytics directory.
+// AnalyticsDir returns the path to the Parquet analytics directory,
+// which lives in the cache directory when the vault uses the default
+// locations and under the data directory otherwise.
 func (c *Config) AnalyticsDir() string {
+	if c.cacheDir != "" {
+		return filepath.Join(c.cacheDir, "analytics")
+	}
 	return filepath.Join(c.Data.DataDir, "analytics")
 }
 
@@ -423,9 +817,16 @@ func (c *Config) LogsDir() string {
 	return filepath.Join(c.Data.DataDir, "logs")
 }
 
-// EnsureHomeDir creates the msgvault home directory if it doesn't exist.
+// EnsureHomeDir creates the msgvault home directory, and the data
+// directory when it is elsewhere, if they don't exist.
 func (c *Config) EnsureHomeDir() error {
-	return fileutil.SecureMkdirAll(c.HomeDir, 0700)
+	if err := fileutil.SecureMkdirAll(c.HomeDir, 0700); err != nil {
+		return err
+	}
+	if c.Data.DataDir == "" || c.Data.DataDir == c.HomeDir {
+		return nil
+	}
+	return fileutil.SecureMkdirAll(c.Data.DataDir, 0700)
 }
 
 // ConfigFilePath returns the path to the config file.
@@ -441,6 +842,12 @@ func (c *Config) ConfigFilePath() string {
 // Save writes the current configuration to disk atomically.
 // Uses temp file + rename to prevent partial writes on crash.
 // Enforces 0600 permissions regardless of existing file mode.
+//
+// Only settings changed since Load (or the previous Save) are written,
+// in place, so comments, ordering, and settings supplied through
+// MSGVAULT_* variables are left as they were. If the edit cannot be
+// made safely the whole file is rewritten. The previous file is kept
+// as config.toml.bak-<timestamp>; the newest configBackups are kept.
 func (c *Config) Save() error {
 	path := c.ConfigFilePath()
 
@@ -461,6 +868,26 @@ func (c *Config) Save() error {
 		return fmt.Errorf("create config directory: %w", err)
 	}
 
+	rendered, err := c.render()
+	if err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	content, ok := mergeTOML(string(existing), rendered, c.saved)
+	if !ok {
+		content = rendered
+	}
+	if len(existing) > 0 && content == string(existing) {
+		if err := os.Chmod(path, 0600); err != nil {
+			return fmt.Errorf("set config file permissions: %w", err)
+		}
+		c.saved = snapshotTOML(flattenTOML(rendered))
+		return nil
+	}
+
 	dir := filepath.Dir(path)
 	tmp, err := os.CreateTemp(dir, ".config-*.toml.tmp")
 	if err != nil {
@@ -481,8 +908,8 @@ func (c *Config) Save() error {
 		return fmt.Errorf("set config file permissions: %w", err)
 	}
 
-	if err := toml.NewEncoder(tmp).Encode(c); err != nil {
-		return fmt.Errorf("encode config: %w", err)
+	if _, err := tmp.WriteString(content); err != nil {
+		return fmt.Errorf("write config file: %w", err)
 	}
 
 	if err := tmp.Sync(); err != nil {
@@ -493,14 +920,62 @@ func (c *Config) Save() error {
 		return fmt.Errorf("close config file: %w", err)
 	}
 
+	if len(existing) > 0 {
+		if err := backupConfig(path, existing); err != nil {
+			return err
+		}
+	}
+
 	if err := os.Rename(tmpPath, path); err != nil {
 		return fmt.Errorf("rename config file: %w", err)
 	}
 
 	success = true
+	c.saved = snapshotTOML(flattenTOML(rendered))
+	return nil
+}
+
+// configBackups is how many timestamped copies of the config file Save
+// keeps.
+const configBackups = 5
+
+// backupConfig copies the previous contents of the config file next to
+// it as <name>.bak-<timestamp> and prunes all but the newest
+// configBackups copies.
+func backupConfig(path string, data []byte) error {
+	backup := path + ".bak-" + time.Now().Format("20060102-150405.000000")
+	if err := os.WriteFile(backup, data, 0600); err != nil {
+		return fmt.Errorf("back up config file: %w", err)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(path) + ".bak-"
+	var old []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), prefix) {
+			old = append(old, e.Name())
+		}
+	}
+	sort.Strings(old) // timestamps sort oldest first
+	for len(old) > configBackups {
+		_ = os.Remove(filepath.Join(filepath.Dir(path), old[0]))
+		old = old[1:]
+	}
 	return nil
 }
 
+// render encodes the config as a complete TOML document.
+func (c *Config) render() (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // ScheduledAccounts returns accounts with scheduling enabled.
 func (c *Config) ScheduledAccounts() []AccountSchedule {
 	var scheduled []AccountSchedule
@@ -512,6 +987,15 @@ func (c *Config) ScheduledAccounts() []AccountSchedule {
 	return scheduled
 }
 
+// AccountJitter returns the most an account's scheduled syncs are
+// delayed by: its own jitter, or else [daemon] jitter.
+func (c *Config) AccountJitter(acc AccountSchedule) time.Duration {
+	if acc.Jitter > 0 {
+		return acc.Jitter
+	}
+	return c.Daemon.Jitter
+}
+
 // GetAccountSchedule returns the schedule for a specific account email.
 // Returns nil if the account is not configured for scheduling.
 // The returned value is a copy, so mutations won't affect the config.
@@ -532,7 +1016,7 @@ func (c *Config) GetAccountSchedule(email string) *AccountSchedule {
 // It tries the following locations in order:
 //  1. Each directory in preferredDirs (if any)
 //  2. The system default temp directory (os.TempDir())
-//  3. A "tmp" subdirectory under the msgvault home directory (~/.msgvault/tmp/)
+//  3. A "tmp" subdirectory under the msgvault cache directory
 //
 // The first successful location is used. If all locations fail, the error
 // from the system temp dir attempt is returned along with the final fallback error.
@@ -556,8 +1040,8 @@ func MkTempDir(pattern string, preferredDirs ...string) (string, error) {
 		return dir, nil
 	}
 
-	// Fallback: use ~/.msgvault/tmp/
-	fallbackBase := filepath.Join(DefaultHome(), "tmp")
+	// Fallback: use the msgvault cache directory
+	fallbackBase := filepath.Join(DefaultDirs().Cache, "tmp")
 	if err := fileutil.SecureMkdirAll(fallbackBase, 0700); err != nil {
 		return "", fmt.Errorf("create temp dir: %w (fallback also failed: %v)", sysErr, err)
 	}
@@ -587,6 +1071,12 @@ func resolveRelative(path, base string) string {
 	return filepath.Join(base, path)
 }
 
+// ExpandPath expands a leading ~ the way paths in config.toml are
+// expanded, for callers that read paths from prompts.
+func ExpandPath(path string) string {
+	return expandPath(path)
+}
+
 // expandPath expands ~ to the user's home directory.
 // Only expands paths that are exactly "~" or start with "~/".
 // It also strips surrounding single or double quotes, which Windows CMD
@@ -1,11 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestServerConfigDefaults(t *testing.T) {
@@ -135,6 +139,84 @@ func TestScheduledAccounts(t *testing.T) {
 	}
 }
 
+func TestAccountJitter(t *testing.T) {
+	tmpDir := t.TempDir()
+	configContent := `
+[daemon]
+jitter = "5m"
+
+[[accounts]]
+email = "alice@example.com"
+schedule = "0 2 * * *"
+enabled = true
+
+[[accounts]]
+email = "bob@example.com"
+schedule = "0 2 * * *"
+enabled = true
+jitter = "30s"
+`
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := Load(configPath, "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if got := cfg.AccountJitter(cfg.Accounts[0]); got != 5*time.Minute {
+		t.Errorf("AccountJitter(alice) = %s, want the [daemon] default 5m", got)
+	}
+	if got := cfg.AccountJitter(cfg.Accounts[1]); got != 30*time.Second {
+		t.Errorf("AccountJitter(bob) = %s, want its own 30s", got)
+	}
+}
+
+func TestAccountLabelSettings(t *testing.T) {
+	tmpDir := t.TempDir()
+	configContent := `
+[[accounts]]
+email = "alice@example.com"
+labels = ["INBOX", "Work"]
+exclude_labels = ["SPAM", "TRASH"]
+include_spam_trash = true
+
+[[accounts]]
+email = "bob@example.com"
+schedule = "0 2 * * *"
+enabled = true
+`
+	configPath := filepath.Join(tmpDir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	cfg, err := Load(configPath, "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	alice := cfg.GetAccountSchedule("alice@example.com")
+	if alice == nil {
+		t.Fatal("alice@example.com not configured")
+	}
+	if want := []string{"INBOX", "Work"}; !reflect.DeepEqual(alice.Labels, want) {
+		t.Errorf("Labels = %q, want %q", alice.Labels, want)
+	}
+	if want := []string{"SPAM", "TRASH"}; !reflect.DeepEqual(alice.ExcludeLabels, want) {
+		t.Errorf("ExcludeLabels = %q, want %q", alice.ExcludeLabels, want)
+	}
+	if !alice.IncludeSpamTrash {
+		t.Error("IncludeSpamTrash = false, want true")
+	}
+
+	bob := cfg.GetAccountSchedule("bob@example.com")
+	if bob.Labels != nil || bob.ExcludeLabels != nil || bob.IncludeSpamTrash {
+		t.Errorf("bob@example.com label settings = %q, %q, %v; want none",
+			bob.Labels, bob.ExcludeLabels, bob.IncludeSpamTrash)
+	}
+}
+
 func TestGetAccountSchedule(t *testing.T) {
 	cfg := &Config{
 		Accounts: []AccountSchedule{
@@ -508,6 +590,33 @@ client_secrets = "secrets/client.json"
 	}
 }
 
+func TestAttachmentsDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.toml")
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"default under data_dir", "[data]\ndata_dir = \"data\"\n", filepath.Join(tmpDir, "data", "attachments")},
+		{"override", "[data]\ndata_dir = \"data\"\nattachments_dir = \"bulk/att\"\n", filepath.Join(tmpDir, "bulk", "att")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			cfg, err := Load(configPath, "")
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if got := cfg.AttachmentsDir(); got != tt.want {
+				t.Errorf("AttachmentsDir() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLoadExplicitPathWithTilde(t *testing.T) {
 	// Explicit --config with ~ should be expanded before stat
 	home, err := os.UserHomeDir()
@@ -1623,6 +1732,79 @@ client_secrets = "/path/to/acme.json"
 	}
 }
 
+func TestSave_PreservesCommentsAndSkipsEnvOverrides(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.toml")
+	original := `# Personal archive
+[sync]
+rate_limit_qps = 5 # stay well under quota
+
+[server]
+api_port = 8080
+`
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MSGVAULT_SERVER_API_PORT", "9999")
+
+	cfg, err := Load("", tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	cfg.Remote.URL = "http://nas:8080"
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := original + "\n[remote]\nurl = \"http://nas:8080\"\n"
+	if string(got) != want {
+		t.Errorf("config after Save =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestSave_KeepsTimestampedBackups(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := NewDefaultConfig()
+	cfg.HomeDir = tmpDir
+
+	for i := 1; i <= configBackups+3; i++ {
+		cfg.Sync.RateLimitQPS = i
+		if err := cfg.Save(); err != nil {
+			t.Fatalf("Save() #%d error = %v", i, err)
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(tmpDir, "config.toml.bak-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != configBackups {
+		t.Fatalf("backups = %d, want %d: %v", len(matches), configBackups, matches)
+	}
+	// The newest backup holds the config as it was before the last Save.
+	sort.Strings(matches)
+	data, err := os.ReadFile(matches[len(matches)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), fmt.Sprintf("rate_limit_qps = %d", configBackups+2)) {
+		t.Errorf("newest backup =\n%s\nwant rate_limit_qps = %d", data, configBackups+2)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(matches[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm()&0077 != 0 {
+			t.Errorf("backup perm = %04o, want no group/other access", info.Mode().Perm())
+		}
+	}
+}
+
 func TestSave_AllowInsecureRoundTrip(t *testing.T) {
 	tmpDir := t.TempDir()
 
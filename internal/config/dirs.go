@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// Dirs are the base directories of a msgvault installation.
+type Dirs struct {
+	Config string // config.toml
+	Data   string // database, tokens, attachments, logs, profiles
+	Cache  string // Parquet analytics cache, update checks, temp files
+}
+
+// LegacyHome returns ~/.msgvault, where msgvault kept everything
+// before it followed platform conventions.
+func LegacyHome() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".msgvault"
+	}
+	return filepath.Join(home, ".msgvault")
+}
+
+// PlatformDirs returns the conventional locations for msgvault's files:
+//
+//	Linux, BSD: $XDG_CONFIG_HOME/msgvault   (~/.config/msgvault)
+//	            $XDG_DATA_HOME/msgvault     (~/.local/share/msgvault)
+//	            $XDG_CACHE_HOME/msgvault    (~/.cache/msgvault)
+//	macOS:      ~/Library/Application Support/msgvault (config and data)
+//	            ~/Library/Caches/msgvault
+//	Windows:    %AppData%\msgvault (config)
+//	            %LocalAppData%\msgvault (data), with a Cache subdirectory
+func PlatformDirs() (Dirs, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Dirs{}, err
+	}
+	switch runtime.GOOS {
+	case "windows":
+		appData := os.Getenv("AppData")
+		if appData == "" {
+			appData = filepath.Join(home, "AppData", "Roaming")
+		}
+		local := os.Getenv("LocalAppData")
+		if local == "" {
+			local = filepath.Join(home, "AppData", "Local")
+		}
+		data := filepath.Join(local, "msgvault")
+		return Dirs{
+			Config: filepath.Join(appData, "msgvault"),
+			Data:   data,
+			Cache:  filepath.Join(data, "Cache"),
+		}, nil
+	case "darwin", "ios":
+		support := filepath.Join(home, "Library", "Application Support", "msgvault")
+		return Dirs{
+			Config: support,
+			Data:   support,
+			Cache:  filepath.Join(home, "Library", "Caches", "msgvault"),
+		}, nil
+	default:
+		return Dirs{
+			Config: filepath.Join(xdgDir("XDG_CONFIG_HOME", home, ".config"), "msgvault"),
+			Data:   filepath.Join(xdgDir("XDG_DATA_HOME", home, ".local", "share"), "msgvault"),
+			Cache:  filepath.Join(xdgDir("XDG_CACHE_HOME", home, ".cache"), "msgvault"),
+		}, nil
+	}
+}
+
+// xdgDir returns the XDG variable when it holds an absolute path (the
+// spec says relative ones must be ignored), else home joined with def.
+func xdgDir(env, home string, def ...string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, def...)...)
+}
+
+// DefaultDirs returns the directories msgvault uses when no --home or
+// --config is given. MSGVAULT_HOME puts everything in one directory. A
+// ~/.msgvault that MigrateLegacyHome has not moved yet keeps being used
+// as is, so nothing is lost before the migration runs.
+func DefaultDirs() Dirs {
+	if h := os.Getenv("MSGVAULT_HOME"); h != "" {
+		h = expandPath(h)
+		return Dirs{Config: h, Data: h, Cache: h}
+	}
+	legacy := LegacyHome()
+	platform, err := PlatformDirs()
+	if err != nil || legacyPending(legacy, platform) {
+		return Dirs{Config: legacy, Data: legacy, Cache: legacy}
+	}
+	return platform
+}
+
+// legacyPending reports whether ~/.msgvault still holds the vault:
+// it exists and the platform directories have not been set up.
+func legacyPending(legacy string, platform Dirs) bool {
+	if !isDir(legacy) {
+		return false
+	}
+	if _, err := os.Stat(filepath.Join(platform.Config, "config.toml")); err == nil {
+		return false
+	}
+	return !isDir(platform.Data)
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// MigrateLegacyHome moves a vault from ~/.msgvault to the platform
+// directories, once. The whole directory is renamed to the data
+// directory; then config.toml (and its backups) moves to the config
+// directory and the Parquet cache to the cache directory. Paths in
+// config.toml that pointed inside ~/.msgvault are rewritten to the new
+// location.
+//
+// It returns the new directories and true when it moved anything. It
+// does nothing when MSGVAULT_HOME is set, when there is nothing to
+// move, or when the database looks open (a -wal file is present), in
+// which case the next run tries again. The rename requires both
+// locations to be on one filesystem; otherwise the vault stays where
+// it is and an error explains how to move it by hand.
+func MigrateLegacyHome() (Dirs, bool, error) {
+	if os.Getenv("MSGVAULT_HOME") != "" {
+		return Dirs{}, false, nil
+	}
+	legacy := LegacyHome()
+	platform, err := PlatformDirs()
+	if err != nil || !legacyPending(legacy, platform) {
+		return Dirs{}, false, nil
+	}
+	if _, err := os.Stat(filepath.Join(legacy, "msgvault.db-wal")); err == nil {
+		return Dirs{}, false, nil
+	}
+
+	if err := fileutil.SecureMkdirAll(filepath.Dir(platform.Data), 0700); err != nil {
+		return Dirs{}, false, fmt.Errorf("create %s: %w", filepath.Dir(platform.Data), err)
+	}
+	if err := os.Rename(legacy, platform.Data); err != nil {
+		return Dirs{}, false, fmt.Errorf(
+			"move %s to %s: %w (move it yourself, or set MSGVAULT_HOME=%s to keep using it)",
+			legacy, platform.Data, err, legacy)
+	}
+
+	var errs []error
+	if platform.Config != platform.Data {
+		errs = append(errs, moveMatching(platform.Data, platform.Config, func(name string) bool {
+			return name == "config.toml" || strings.HasPrefix(name, "config.toml.bak-")
+		}))
+	}
+	if platform.Cache != platform.Data {
+		errs = append(errs, moveMatching(platform.Data, platform.Cache, func(name string) bool {
+			return name == "analytics"
+		}))
+	}
+	errs = append(errs, rewriteLegacyPaths(legacy, platform))
+	return platform, true, errors.Join(errs...)
+}
+
+// moveMatching renames the entries of from accepted by match into to.
+func moveMatching(from, to string, match func(string) bool) error {
+	entries, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !match(e.Name()) {
+			continue
+		}
+		if err := fileutil.SecureMkdirAll(to, 0700); err != nil {
+			return fmt.Errorf("create %s: %w", to, err)
+		}
+		if err := os.Rename(filepath.Join(from, e.Name()), filepath.Join(to, e.Name())); err != nil {
+			return fmt.Errorf("move %s to %s: %w", e.Name(), to, err)
+		}
+	}
+	return nil
+}
+
+// rewriteLegacyPaths points settings that named a path inside the old
+// directory at the data directory, where the files now live.
+func rewriteLegacyPaths(legacy string, dirs Dirs) error {
+	path := filepath.Join(dirs.Config, "config.toml")
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	cfg, err := Load(path, "")
+	if err != nil {
+		return err
+	}
+	moved := func(p *string) bool {
+		rel, err := filepath.Rel(legacy, *p)
+		if *p == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return false
+		}
+		*p = filepath.Join(dirs.Data, rel)
+		return true
+	}
+	changed := false
+	for _, p := range []*string{
+		&cfg.Data.DataDir, &cfg.Data.AttachmentsDir, &cfg.Log.Dir, &cfg.Vector.DBPath,
+		&cfg.OAuth.ClientSecrets, &cfg.OAuth.ServiceAccountKey,
+	} {
+		changed = moved(p) || changed
+	}
+	for name, app := range cfg.OAuth.Apps {
+		secrets, key := moved(&app.ClientSecrets), moved(&app.ServiceAccountKey)
+		if secrets || key {
+			cfg.OAuth.Apps[name] = app
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return cfg.Save()
+}
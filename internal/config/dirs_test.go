@@ -0,0 +1,169 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// setXDGHome points HOME and the XDG variables at a fresh directory so
+// tests never see, or move, the real ~/.msgvault.
+func setXDGHome(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		t.Skip("XDG directories apply to Linux and BSD")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MSGVAULT_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	return home
+}
+
+func TestPlatformDirs_XDG(t *testing.T) {
+	home := setXDGHome(t)
+
+	dirs, err := PlatformDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Dirs{
+		Config: filepath.Join(home, ".config", "msgvault"),
+		Data:   filepath.Join(home, ".local", "share", "msgvault"),
+		Cache:  filepath.Join(home, ".cache", "msgvault"),
+	}
+	if dirs != want {
+		t.Errorf("PlatformDirs() = %+v, want %+v", dirs, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, "cfg"))
+	t.Setenv("XDG_DATA_HOME", "relative/ignored")
+	dirs, err = PlatformDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs.Config != filepath.Join(home, "cfg", "msgvault") {
+		t.Errorf("Config = %q, want $XDG_CONFIG_HOME/msgvault", dirs.Config)
+	}
+	if dirs.Data != want.Data {
+		t.Errorf("Data = %q, want relative XDG_DATA_HOME ignored", dirs.Data)
+	}
+}
+
+func TestDefaultDirs(t *testing.T) {
+	home := setXDGHome(t)
+	legacy := filepath.Join(home, ".msgvault")
+	platform, err := PlatformDirs()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DefaultDirs(); got != platform {
+		t.Errorf("fresh install: DefaultDirs() = %+v, want %+v", got, platform)
+	}
+
+	if err := os.Mkdir(legacy, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got := DefaultDirs(); got != (Dirs{legacy, legacy, legacy}) {
+		t.Errorf("unmigrated: DefaultDirs() = %+v, want %s for everything", got, legacy)
+	}
+
+	t.Setenv("MSGVAULT_HOME", "/srv/vault")
+	if got := DefaultDirs(); got != (Dirs{"/srv/vault", "/srv/vault", "/srv/vault"}) {
+		t.Errorf("MSGVAULT_HOME: DefaultDirs() = %+v", got)
+	}
+}
+
+func TestMigrateLegacyHome(t *testing.T) {
+	home := setXDGHome(t)
+	legacy := filepath.Join(home, ".msgvault")
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(legacy, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("config.toml", "# my settings\n[oauth]\nclient_secrets = \""+
+		filepath.Join(legacy, "client_secret.json")+"\"\n\n[sync]\nrate_limit_qps = 3\n")
+	write("client_secret.json", "{}")
+	write("msgvault.db", "db")
+	write("tokens/alice@example.com.json", "{}")
+	write("analytics/messages/part.parquet", "pq")
+
+	dirs, moved, err := MigrateLegacyHome()
+	if err != nil {
+		t.Fatalf("MigrateLegacyHome() error = %v", err)
+	}
+	if !moved {
+		t.Fatal("MigrateLegacyHome() moved nothing")
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("%s still exists (err = %v)", legacy, err)
+	}
+	for _, path := range []string{
+		filepath.Join(dirs.Data, "msgvault.db"),
+		filepath.Join(dirs.Data, "tokens", "alice@example.com.json"),
+		filepath.Join(dirs.Data, "client_secret.json"),
+		filepath.Join(dirs.Config, "config.toml"),
+		filepath.Join(dirs.Cache, "analytics", "messages", "part.parquet"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s after migration: %v", path, err)
+		}
+	}
+
+	cfg, err := Load("", "")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ConfigFilePath() != filepath.Join(dirs.Config, "config.toml") {
+		t.Errorf("ConfigFilePath() = %q", cfg.ConfigFilePath())
+	}
+	if cfg.Data.DataDir != dirs.Data {
+		t.Errorf("DataDir = %q, want %q", cfg.Data.DataDir, dirs.Data)
+	}
+	if cfg.AnalyticsDir() != filepath.Join(dirs.Cache, "analytics") {
+		t.Errorf("AnalyticsDir() = %q, want under the cache directory", cfg.AnalyticsDir())
+	}
+	if cfg.OAuth.ClientSecrets != filepath.Join(dirs.Data, "client_secret.json") {
+		t.Errorf("client_secrets = %q, want rewritten into the data directory", cfg.OAuth.ClientSecrets)
+	}
+	data, err := os.ReadFile(cfg.ConfigFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "# my settings\n") || !strings.Contains(string(data), "rate_limit_qps = 3") {
+		t.Errorf("config.toml lost its contents:\n%s", data)
+	}
+
+	if _, moved, err := MigrateLegacyHome(); moved || err != nil {
+		t.Errorf("second MigrateLegacyHome() = moved %v, err %v; want a no-op", moved, err)
+	}
+}
+
+func TestMigrateLegacyHome_SkipsOpenDatabase(t *testing.T) {
+	home := setXDGHome(t)
+	legacy := filepath.Join(home, ".msgvault")
+	if err := os.Mkdir(legacy, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "msgvault.db-wal"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, moved, err := MigrateLegacyHome(); moved || err != nil {
+		t.Fatalf("MigrateLegacyHome() = moved %v, err %v; want skipped", moved, err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("%s should be left in place: %v", legacy, err)
+	}
+}
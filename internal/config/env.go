@@ -0,0 +1,208 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix starts every environment variable that overrides a config
+// setting.
+const envPrefix = "MSGVAULT_"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides settings from the environment, so containers and
+// daemons can be configured without editing config.toml. Each setting
+// maps to MSGVAULT_ plus its dotted TOML path, upper-cased, with dots
+// as underscores:
+//
+//	[sync] rate_limit_qps            MSGVAULT_SYNC_RATE_LIMIT_QPS
+//	[vector.embeddings] endpoint     MSGVAULT_VECTOR_EMBEDDINGS_ENDPOINT
+//	[oauth.apps.acme] client_secrets MSGVAULT_OAUTH_APPS_ACME_CLIENT_SECRETS
+//	[[accounts]] (first) email       MSGVAULT_ACCOUNTS_0_EMAIL
+//
+// Lists of strings are comma-separated. Array entries are addressed by
+// index from 0; an index past the end of the file's entries appends
+// one. Environment values win over the file. It returns the names of
+// the variables it applied.
+func applyEnv(cfg *Config, environ []string) ([]string, error) {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, envPrefix) {
+			vars[name] = value
+		}
+	}
+	if len(vars) == 0 {
+		return nil, nil
+	}
+	var applied []string
+	if err := applyEnvStruct(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(envPrefix, "_"), vars, &applied); err != nil {
+		return nil, err
+	}
+	sort.Strings(applied)
+	return applied, nil
+}
+
+func applyEnvStruct(v reflect.Value, prefix string, vars map[string]string, applied *[]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if !field.IsExported() || key == "" || key == "-" {
+			continue
+		}
+		if err := applyEnvValue(v.Field(i), prefix+"_"+strings.ToUpper(key), vars, applied); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyEnvValue(v reflect.Value, name string, vars map[string]string, applied *[]string) error {
+	switch {
+	case v.Kind() == reflect.Struct:
+		return applyEnvStruct(v, name, vars, applied)
+
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		return applyEnvMap(v, name, vars, applied)
+
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Struct:
+		for idx := 0; hasEnvPrefix(vars, fmt.Sprintf("%s_%d_", name, idx)); idx++ {
+			if idx >= v.Len() {
+				v.Set(reflect.Append(v, reflect.New(v.Type().Elem()).Elem()))
+			}
+			if err := applyEnvStruct(v.Index(idx), fmt.Sprintf("%s_%d", name, idx), vars, applied); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	raw, ok := vars[name]
+	if !ok {
+		return nil
+	}
+	if v.Kind() == reflect.Pointer {
+		elem := reflect.New(v.Type().Elem())
+		if err := setEnvScalar(elem.Elem(), name, raw); err != nil {
+			return err
+		}
+		v.Set(elem)
+	} else if err := setEnvScalar(v, name, raw); err != nil {
+		return err
+	}
+	*applied = append(*applied, name)
+	return nil
+}
+
+// applyEnvMap handles named tables such as [oauth.apps.<name>]. The
+// name is whatever sits between the map's prefix and a known field
+// suffix, lower-cased.
+func applyEnvMap(v reflect.Value, name string, vars map[string]string, applied *[]string) error {
+	elemType := v.Type().Elem()
+	if elemType.Kind() != reflect.Struct {
+		return nil
+	}
+	var suffixes []string
+	for i := 0; i < elemType.NumField(); i++ {
+		key, _, _ := strings.Cut(elemType.Field(i).Tag.Get("toml"), ",")
+		if key != "" && key != "-" {
+			suffixes = append(suffixes, "_"+strings.ToUpper(key))
+		}
+	}
+
+	entries := make(map[string]bool)
+	for envName := range vars {
+		rest, ok := strings.CutPrefix(envName, name+"_")
+		if !ok {
+			continue
+		}
+		for _, suffix := range suffixes {
+			if entry, ok := strings.CutSuffix("_"+rest, suffix); ok && entry != "" {
+				entries[strings.ToLower(entry[1:])] = true
+			}
+		}
+	}
+	if len(entries) > 0 && v.IsNil() {
+		v.Set(reflect.MakeMap(v.Type()))
+	}
+	for entry := range entries {
+		key := reflect.ValueOf(entry)
+		elem := reflect.New(elemType).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := applyEnvStruct(elem, name+"_"+strings.ToUpper(entry), vars, applied); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+	return nil
+}
+
+func setEnvScalar(v reflect.Value, name, raw string) error {
+	raw = strings.TrimSpace(raw)
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q (e.g. 30s, 5m)", name, raw)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%s: invalid boolean %q (want true or false)", name, raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: invalid integer %q", name, raw)
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%s: invalid number %q", name, raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("%s: setting cannot be overridden from the environment", name)
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("%s: setting cannot be overridden from the environment", name)
+	}
+	return nil
+}
+
+func hasEnvPrefix(vars map[string]string, prefix string) bool {
+	for name := range vars {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvOverrides returns the MSGVAULT_* variables that overrode settings
+// when the config was loaded.
+func (c *Config) EnvOverrides() []string {
+	return c.envOverrides
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name:    "scalar settings",
+			environ: []string{"MSGVAULT_SYNC_RATE_LIMIT_QPS=9", "MSGVAULT_SERVER_ALLOW_INSECURE=true", "MSGVAULT_REMOTE_URL=http://nas:8080"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Sync.RateLimitQPS != 9 || !cfg.Server.AllowInsecure || cfg.Remote.URL != "http://nas:8080" {
+					t.Errorf("sync=%d insecure=%v remote=%q", cfg.Sync.RateLimitQPS, cfg.Server.AllowInsecure, cfg.Remote.URL)
+				}
+			},
+		},
+		{
+			name:    "nested sections, durations, and pointers",
+			environ: []string{"MSGVAULT_VECTOR_EMBEDDINGS_TIMEOUT=45s", "MSGVAULT_VECTOR_PREPROCESS_STRIP_QUOTES=false"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Vector.Embeddings.Timeout != 45*time.Second {
+					t.Errorf("timeout = %v, want 45s", cfg.Vector.Embeddings.Timeout)
+				}
+				if cfg.Vector.Preprocess.StripQuotesEnabled() {
+					t.Error("strip_quotes should be false")
+				}
+			},
+		},
+		{
+			name:    "string lists are comma-separated",
+			environ: []string{"MSGVAULT_SERVER_CORS_ORIGINS=https://a.example.com, https://b.example.com,"},
+			check: func(t *testing.T, cfg *Config) {
+				want := []string{"https://a.example.com", "https://b.example.com"}
+				if !reflect.DeepEqual(cfg.Server.CORSOrigins, want) {
+					t.Errorf("cors_origins = %v, want %v", cfg.Server.CORSOrigins, want)
+				}
+			},
+		},
+		{
+			name:    "named tables",
+			environ: []string{"MSGVAULT_OAUTH_APPS_ACME_CLIENT_SECRETS=/secrets/acme.json"},
+			check: func(t *testing.T, cfg *Config) {
+				if got := cfg.OAuth.Apps["acme"].ClientSecrets; got != "/secrets/acme.json" {
+					t.Errorf("apps.acme.client_secrets = %q", got)
+				}
+			},
+		},
+		{
+			name:    "array entries by index",
+			environ: []string{"MSGVAULT_ACCOUNTS_0_EMAIL=alice@example.com", "MSGVAULT_ACCOUNTS_0_ENABLED=1"},
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.Accounts) != 1 || cfg.Accounts[0].Email != "alice@example.com" || !cfg.Accounts[0].Enabled {
+					t.Errorf("accounts = %+v", cfg.Accounts)
+				}
+			},
+		},
+		{
+			name:    "unrelated variables are ignored",
+			environ: []string{"MSGVAULT_HOME=/x", "MSGVAULT_ENABLE_REMOTE_DELETE=1", "HOME=/root"},
+			check: func(t *testing.T, cfg *Config) {
+				if !reflect.DeepEqual(cfg, NewDefaultConfig()) {
+					t.Error("config changed")
+				}
+			},
+		},
+		{
+			name:    "invalid integer",
+			environ: []string{"MSGVAULT_SYNC_RATE_LIMIT_QPS=fast"},
+			wantErr: "MSGVAULT_SYNC_RATE_LIMIT_QPS",
+		},
+		{
+			name:    "invalid boolean",
+			environ: []string{"MSGVAULT_LOG_ENABLED=maybe"},
+			wantErr: "invalid boolean",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := NewDefaultConfig()
+			_, err := applyEnv(cfg, tt.environ)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("applyEnv() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyEnv() error = %v", err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := `
+[sync]
+rate_limit_qps = 3
+
+[[accounts]]
+email = "alice@example.com"
+schedule = "0 2 * * *"
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.toml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MSGVAULT_SYNC_RATE_LIMIT_QPS", "7")
+	t.Setenv("MSGVAULT_ACCOUNTS_0_ENABLED", "true")
+	t.Setenv("MSGVAULT_DATA_DATA_DIR", "~/vault")
+
+	cfg, err := Load("", tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Sync.RateLimitQPS != 7 {
+		t.Errorf("rate_limit_qps = %d, want 7", cfg.Sync.RateLimitQPS)
+	}
+	if acc := cfg.Accounts[0]; acc.Email != "alice@example.com" || acc.Schedule != "0 2 * * *" || !acc.Enabled {
+		t.Errorf("accounts[0] = %+v, want file values plus enabled", acc)
+	}
+	home, _ := os.UserHomeDir()
+	if cfg.Data.DataDir != filepath.Join(home, "vault") {
+		t.Errorf("data_dir = %q, want ~ expanded", cfg.Data.DataDir)
+	}
+	want := []string{"MSGVAULT_ACCOUNTS_0_ENABLED", "MSGVAULT_DATA_DATA_DIR", "MSGVAULT_SYNC_RATE_LIMIT_QPS"}
+	if !reflect.DeepEqual(cfg.EnvOverrides(), want) {
+		t.Errorf("EnvOverrides() = %v, want %v", cfg.EnvOverrides(), want)
+	}
+}
+
+func TestLoadEnvWithoutConfigFile(t *testing.T) {
+	t.Setenv("MSGVAULT_SERVER_API_PORT", "9191")
+
+	cfg, err := Load("", t.TempDir())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Server.APIPort != 9191 {
+		t.Errorf("api_port = %d, want 9191", cfg.Server.APIPort)
+	}
+}
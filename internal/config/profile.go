@@ -0,0 +1,145 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// DefaultProfile names the profile that lives directly in the msgvault
+// home directory, as every vault did before profiles existed.
+const DefaultProfile = "default"
+
+// activeProfileFile, in the base home directory, records the profile
+// chosen with 'msgvault profile switch'.
+const activeProfileFile = "active-profile"
+
+var profileNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// ValidateProfileName rejects names that are not safe as a directory name.
+func ValidateProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use lowercase letters, digits, '.', '-', or '_'", name)
+	}
+	return nil
+}
+
+// ProfilesDir returns the directory holding named profiles under base.
+func ProfilesDir(base string) string {
+	return filepath.Join(base, "profiles")
+}
+
+// ProfileHome returns the home directory of a profile: base itself for
+// the default profile, else <base>/profiles/<name>. Each profile home
+// has its own config.toml, database, tokens, and attachments.
+func ProfileHome(base, name string) (string, error) {
+	if name == "" || name == DefaultProfile {
+		return base, nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(ProfilesDir(base), name), nil
+}
+
+// ActiveProfile returns the profile selected by MSGVAULT_PROFILE, else
+// the one recorded by SetActiveProfile, else DefaultProfile.
+func ActiveProfile(base string) (string, error) {
+	if p := strings.TrimSpace(os.Getenv("MSGVAULT_PROFILE")); p != "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(filepath.Join(base, activeProfileFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultProfile, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read active profile: %w", err)
+	}
+	if p := strings.TrimSpace(string(data)); p != "" {
+		return p, nil
+	}
+	return DefaultProfile, nil
+}
+
+// SetActiveProfile records name as the profile used when neither
+// --profile nor MSGVAULT_PROFILE is given.
+func SetActiveProfile(base, name string) error {
+	if name == DefaultProfile {
+		err := os.Remove(filepath.Join(base, activeProfileFile))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("reset active profile: %w", err)
+		}
+		return nil
+	}
+	if err := ValidateProfileName(name); err != nil {
+		return err
+	}
+	if err := fileutil.SecureMkdirAll(base, 0700); err != nil {
+		return fmt.Errorf("create home directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(base, activeProfileFile), []byte(name+"\n"), 0600); err != nil {
+		return fmt.Errorf("write active profile: %w", err)
+	}
+	return nil
+}
+
+// ProfileExists reports whether the profile's home directory exists.
+// The default profile always exists.
+func ProfileExists(base, name string) (bool, error) {
+	home, err := ProfileHome(base, name)
+	if err != nil {
+		return false, err
+	}
+	if home == base {
+		return true, nil
+	}
+	info, err := os.Stat(home)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("stat profile %s: %w", name, err)
+	}
+	return info.IsDir(), nil
+}
+
+// ListProfiles returns the default profile followed by every named
+// profile under base, sorted.
+func ListProfiles(base string) ([]string, error) {
+	entries, err := os.ReadDir(ProfilesDir(base))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("list profiles: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && ValidateProfileName(e.Name()) == nil && e.Name() != DefaultProfile {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...), nil
+}
+
+// CreateProfile creates an empty profile home and returns its path.
+func CreateProfile(base, name string) (string, error) {
+	if name == DefaultProfile {
+		return "", fmt.Errorf("profile %q already exists", name)
+	}
+	home, err := ProfileHome(base, name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(home); err == nil {
+		return "", fmt.Errorf("profile %q already exists", name)
+	}
+	if err := fileutil.SecureMkdirAll(home, 0700); err != nil {
+		return "", fmt.Errorf("create profile %s: %w", name, err)
+	}
+	return home, nil
+}
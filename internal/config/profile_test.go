@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestProfiles_Lifecycle(t *testing.T) {
+	t.Setenv("MSGVAULT_PROFILE", "")
+	base := t.TempDir()
+
+	if got, err := ActiveProfile(base); err != nil || got != DefaultProfile {
+		t.Fatalf("ActiveProfile() = %q, %v; want default", got, err)
+	}
+
+	home, err := CreateProfile(base, "work")
+	if err != nil {
+		t.Fatalf("CreateProfile: %v", err)
+	}
+	if want := filepath.Join(base, "profiles", "work"); home != want {
+		t.Errorf("home = %q, want %q", home, want)
+	}
+	if _, err := CreateProfile(base, "work"); err == nil {
+		t.Error("expected duplicate create to fail")
+	}
+	if _, err := CreateProfile(base, DefaultProfile); err == nil {
+		t.Error("expected creating the default profile to fail")
+	}
+	if _, err := CreateProfile(base, "family-archive"); err != nil {
+		t.Fatalf("CreateProfile: %v", err)
+	}
+
+	names, err := ListProfiles(base)
+	if err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if want := []string{"default", "family-archive", "work"}; !slices.Equal(names, want) {
+		t.Errorf("ListProfiles() = %v, want %v", names, want)
+	}
+
+	if err := SetActiveProfile(base, "work"); err != nil {
+		t.Fatalf("SetActiveProfile: %v", err)
+	}
+	if got, _ := ActiveProfile(base); got != "work" {
+		t.Errorf("ActiveProfile() = %q, want work", got)
+	}
+
+	t.Setenv("MSGVAULT_PROFILE", "family-archive")
+	if got, _ := ActiveProfile(base); got != "family-archive" {
+		t.Errorf("env should override switch: got %q", got)
+	}
+	t.Setenv("MSGVAULT_PROFILE", "")
+
+	if err := SetActiveProfile(base, DefaultProfile); err != nil {
+		t.Fatalf("SetActiveProfile(default): %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, activeProfileFile)); !os.IsNotExist(err) {
+		t.Errorf("switching to default should remove the marker, stat err = %v", err)
+	}
+}
+
+func TestProfileHome(t *testing.T) {
+	base := t.TempDir()
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", base, false},
+		{DefaultProfile, base, false},
+		{"personal", filepath.Join(base, "profiles", "personal"), false},
+		{"../escape", "", true},
+		{"Work", "", true},
+		{"a/b", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProfileHome(base, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ProfileHome() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
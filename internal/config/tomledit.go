@@ -0,0 +1,502 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Save rewrites only the settings that changed, splicing them into the
+// existing file so hand-written comments, ordering, and unknown keys
+// survive. The helpers here understand just enough TOML for that: table
+// headers, key/value lines (including values spanning several lines),
+// and comments. Anything they cannot place safely is caught by
+// mergeTOML's final check, and Save falls back to a full rewrite.
+
+type tomlItemKind int
+
+const (
+	itemKey tomlItemKind = iota
+	itemTable
+	itemArrayTable
+)
+
+// tomlItem is one header or key/value in a TOML document.
+type tomlItem struct {
+	kind    tomlItemKind
+	start   int      // first line
+	end     int      // last line; > start for multi-line values
+	path    []string // table path for headers, full key path for keys
+	table   []string // enclosing table (keys only)
+	header  string   // enclosing header line, trimmed (keys only)
+	inArray bool     // key sits inside an [[array]] entry
+	rawKey  string   // key as written, before '='
+	eq      int      // column of '=' on the start line
+	value   string   // single-line value without its comment
+	comment int      // column of a trailing comment on the end line
+}
+
+type tomlDoc struct {
+	lines []string
+	items []tomlItem
+}
+
+func parseTOMLDoc(text string) *tomlDoc {
+	doc := &tomlDoc{}
+	text = strings.TrimSuffix(text, "\n")
+	if text != "" {
+		doc.lines = strings.Split(text, "\n")
+	}
+
+	var table []string
+	header := ""
+	inArray := false
+	for i := 0; i < len(doc.lines); i++ {
+		line := doc.lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "[["):
+			inner, _, _ := strings.Cut(trimmed[2:], "]]")
+			table, header, inArray = splitTOMLKey(inner), trimmed, true
+			doc.items = append(doc.items, tomlItem{kind: itemArrayTable, start: i, end: i, path: table})
+		case strings.HasPrefix(trimmed, "["):
+			inner, _, _ := strings.Cut(trimmed[1:], "]")
+			table, header, inArray = splitTOMLKey(inner), trimmed, false
+			doc.items = append(doc.items, tomlItem{kind: itemTable, start: i, end: i, path: table})
+		default:
+			eq := keyEnd(line)
+			if eq < 0 {
+				continue
+			}
+			end, comment := scanTOMLValue(doc.lines, i, eq+1)
+			it := tomlItem{
+				kind:    itemKey,
+				start:   i,
+				end:     end,
+				path:    append(append([]string{}, table...), splitTOMLKey(line[:eq])...),
+				table:   table,
+				header:  header,
+				inArray: inArray,
+				rawKey:  strings.TrimSpace(line[:eq]),
+				eq:      eq,
+				comment: comment,
+			}
+			if end == i {
+				it.value = strings.TrimSpace(line[eq+1 : comment])
+			}
+			doc.items = append(doc.items, it)
+			i = end
+		}
+	}
+	return doc
+}
+
+// keyEnd returns the column of the '=' separating key and value, or -1
+// when the line is not a key/value.
+func keyEnd(line string) int {
+	quote := byte(0)
+	for j := 0; j < len(line); j++ {
+		switch ch := line[j]; {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				j++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '=':
+			return j
+		case ch == '#':
+			return -1
+		}
+	}
+	return -1
+}
+
+// scanTOMLValue finds the last line of the value starting at
+// lines[i][col:], following strings and brackets across lines, and the
+// column of any trailing comment on that line (its length if none).
+func scanTOMLValue(lines []string, i, col int) (end, comment int) {
+	depth := 0
+	quote := ""
+	for ; i < len(lines); i, col = i+1, 0 {
+		line := lines[i]
+		comment = len(line)
+		for j := col; j < len(line); j++ {
+			rest := line[j:]
+			switch {
+			case quote == `"""` || quote == `'''`:
+				if strings.HasPrefix(rest, quote) {
+					quote = ""
+					j += 2
+				} else if quote == `"""` && line[j] == '\\' {
+					j++
+				}
+			case quote == `"`:
+				if line[j] == '\\' {
+					j++
+				} else if line[j] == '"' {
+					quote = ""
+				}
+			case quote == `'`:
+				if line[j] == '\'' {
+					quote = ""
+				}
+			case strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, `'''`):
+				quote = rest[:3]
+				j += 2
+			case line[j] == '"' || line[j] == '\'':
+				quote = rest[:1]
+			case line[j] == '[' || line[j] == '{':
+				depth++
+			case line[j] == ']' || line[j] == '}':
+				depth--
+			case line[j] == '#':
+				comment = j
+				j = len(line)
+			}
+		}
+		if len(quote) == 3 || depth > 0 {
+			continue
+		}
+		return i, comment
+	}
+	last := len(lines) - 1
+	return last, len(lines[last])
+}
+
+// splitTOMLKey splits a dotted key into its parts, unquoting quoted
+// parts.
+func splitTOMLKey(s string) []string {
+	var parts []string
+	var cur strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' && i+1 < len(s) {
+				i++
+				cur.WriteByte(s[i])
+			} else if ch == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '.':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		case ch == ' ' || ch == '\t':
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	return append(parts, cur.String())
+}
+
+// lastContent returns the last key line belonging to the header item
+// n, or the header line itself when the table is empty.
+func (d *tomlDoc) lastContent(n int, dropped map[int]bool) int {
+	last := d.items[n].start
+	for _, it := range d.items[n+1:] {
+		if it.kind != itemKey {
+			break
+		}
+		if !dropped[it.start] {
+			last = it.end
+		}
+	}
+	return last
+}
+
+// tomlEntry is one setting of a rendered config: a key/value, or every
+// entry of an array of tables taken together.
+type tomlEntry struct {
+	path   []string
+	table  []string
+	header string
+	rawKey string
+	array  bool
+	text   string // the value, or the array's rendered blocks
+}
+
+func (e tomlEntry) id() string {
+	return entryID(e.path, e.array)
+}
+
+func entryID(path []string, array bool) string {
+	id := strings.Join(path, "\x00")
+	if array {
+		return "[[" + id
+	}
+	return id
+}
+
+func parseEntryID(id string) (path []string, array bool) {
+	id, array = strings.CutPrefix(id, "[[")
+	return strings.Split(id, "\x00"), array
+}
+
+// flattenTOML lists the settings in text, which must be the encoder's
+// own output.
+func flattenTOML(text string) []tomlEntry {
+	doc := parseTOMLDoc(text)
+	var entries []tomlEntry
+	arrays := make(map[string]int)
+	for n, it := range doc.items {
+		switch it.kind {
+		case itemArrayTable:
+			var block []string
+			for i := it.start; i <= doc.lastContent(n, nil); i++ {
+				block = append(block, strings.TrimSpace(doc.lines[i]))
+			}
+			text := strings.Join(block, "\n")
+			id := entryID(it.path, true)
+			if idx, ok := arrays[id]; ok {
+				entries[idx].text += "\n\n" + text
+				continue
+			}
+			arrays[id] = len(entries)
+			entries = append(entries, tomlEntry{path: it.path, table: it.path, array: true, text: text})
+		case itemKey:
+			if !it.inArray {
+				entries = append(entries, tomlEntry{
+					path: it.path, table: it.table, header: it.header, rawKey: it.rawKey, text: it.value,
+				})
+			}
+		}
+	}
+	return entries
+}
+
+// snapshotTOML records each setting's rendered value so a later Save
+// can tell which ones changed.
+func snapshotTOML(entries []tomlEntry) map[string]string {
+	snap := make(map[string]string, len(entries))
+	for _, e := range entries {
+		snap[e.id()] = e.text
+	}
+	return snap
+}
+
+// mergeTOML splices the settings of rendered that differ from saved
+// into existing. A nil saved treats every setting as changed. It
+// reports false when the result does not decode to the rendered values,
+// for example because existing defines a table inline.
+func mergeTOML(existing, rendered string, saved map[string]string) (string, bool) {
+	entries := flattenTOML(rendered)
+	current := make(map[string]bool, len(entries))
+	var changed []tomlEntry
+	for _, e := range entries {
+		current[e.id()] = true
+		if old, ok := saved[e.id()]; !ok || old != e.text {
+			changed = append(changed, e)
+		}
+	}
+	var removed []string
+	for id := range saved {
+		if !current[id] {
+			removed = append(removed, id)
+		}
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return existing, true
+	}
+
+	doc := parseTOMLDoc(existing)
+	keys := make(map[string]int)
+	tables := make(map[string]int)
+	blocks := make(map[string][]int)
+	for n, it := range doc.items {
+		switch {
+		case it.kind == itemKey && !it.inArray:
+			keys[entryID(it.path, false)] = n
+		case it.kind == itemTable:
+			if _, ok := tables[entryID(it.path, false)]; !ok {
+				tables[entryID(it.path, false)] = n
+			}
+		case it.kind == itemArrayTable:
+			blocks[entryID(it.path, true)] = append(blocks[entryID(it.path, true)], n)
+		}
+	}
+
+	replace := make(map[int][]string)
+	dropped := make(map[int]bool)
+	after := make(map[int][]string)
+	var head []string
+	// Settings whose table is not in the file yet are appended in
+	// groups, one per table, in the order they render.
+	var groups [][]string
+	groupOf := make(map[string]int)
+
+	drop := func(from, to int) {
+		for i := from; i <= to; i++ {
+			dropped[i] = true
+		}
+	}
+	dropBlocks := func(id string) int {
+		first := -1
+		for _, n := range blocks[id] {
+			if first < 0 {
+				first = doc.items[n].start
+			}
+			drop(doc.items[n].start, doc.lastContent(n, nil))
+		}
+		return first
+	}
+	var touched []int // tables that lost keys
+	for _, id := range removed {
+		path, array := parseEntryID(id)
+		if array {
+			dropBlocks(id)
+			continue
+		}
+		if n, ok := keys[id]; ok {
+			drop(doc.items[n].start, doc.items[n].end)
+			if t, ok := tables[entryID(path[:len(path)-1], false)]; ok {
+				touched = append(touched, t)
+			}
+		}
+	}
+
+	for _, e := range changed {
+		if e.array {
+			lines := strings.Split(e.text, "\n")
+			if first := dropBlocks(e.id()); first >= 0 {
+				replace[first] = lines
+			} else {
+				groups = append(groups, lines)
+			}
+			continue
+		}
+		if n, ok := keys[e.id()]; ok {
+			it := doc.items[n]
+			line := doc.lines[it.start]
+			suffix := ""
+			if it.start == it.end {
+				line = doc.lines[it.end]
+				ws := it.comment
+				for ws > 0 && (line[ws-1] == ' ' || line[ws-1] == '\t') {
+					ws--
+				}
+				suffix = line[ws:]
+			}
+			replace[it.start] = []string{strings.TrimRight(line[:it.eq+1], " \t") + " " + e.text + suffix}
+			drop(it.start+1, it.end)
+			continue
+		}
+		line := e.rawKey + " = " + e.text
+		if len(e.table) == 0 {
+			head = append(head, line)
+			continue
+		}
+		if n, ok := tables[entryID(e.table, false)]; ok {
+			last := doc.lastContent(n, nil)
+			indent := ""
+			if last != doc.items[n].start {
+				l := doc.lines[last]
+				indent = l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+			}
+			after[last] = append(after[last], indent+line)
+			continue
+		}
+		if g, ok := groupOf[e.header]; ok {
+			groups[g] = append(groups[g], line)
+			continue
+		}
+		groupOf[e.header] = len(groups)
+		groups = append(groups, []string{e.header, line})
+	}
+
+	// A table emptied by removals (say, a deleted [oauth.apps.<name>])
+	// would decode as an empty entry, so drop its header too.
+	for _, n := range touched {
+		if doc.lastContent(n, dropped) == doc.items[n].start && !hasEntryUnder(entries, doc.items[n].path) {
+			dropped[doc.items[n].start] = true
+		}
+	}
+
+	out := append([]string{}, head...)
+	gap := false // a dropped line may leave two blank lines meeting
+	for i, line := range doc.lines {
+		switch r, ok := replace[i]; {
+		case ok:
+			out = append(out, r...)
+			gap = false
+		case dropped[i]:
+			gap = true
+		case strings.TrimSpace(line) == "" && gap && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "":
+		default:
+			out = append(out, line)
+			gap = gap && strings.TrimSpace(line) == ""
+		}
+		out = append(out, after[i]...)
+	}
+	for len(out) > 0 && strings.TrimSpace(out[len(out)-1]) == "" {
+		out = out[:len(out)-1]
+	}
+	for _, g := range groups {
+		if len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "" {
+			out = append(out, "")
+		}
+		out = append(out, g...)
+	}
+	merged := strings.Join(out, "\n") + "\n"
+
+	return merged, mergedMatches(merged, rendered, changed, removed)
+}
+
+func hasEntryUnder(entries []tomlEntry, table []string) bool {
+	for _, e := range entries {
+		if len(e.path) > len(table) && reflect.DeepEqual(e.path[:len(table)], table) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergedMatches reports whether merged decodes as a config and agrees
+// with rendered on every setting that was written or removed.
+func mergedMatches(merged, rendered string, changed []tomlEntry, removed []string) bool {
+	var got, want map[string]any
+	if _, err := toml.Decode(merged, &got); err != nil {
+		return false
+	}
+	if _, err := toml.Decode(rendered, &want); err != nil {
+		return false
+	}
+	if _, err := toml.Decode(merged, &Config{}); err != nil {
+		return false
+	}
+	var paths [][]string
+	for _, e := range changed {
+		paths = append(paths, e.path)
+	}
+	for _, id := range removed {
+		path, _ := parseEntryID(id)
+		paths = append(paths, path)
+	}
+	for _, path := range paths {
+		if !reflect.DeepEqual(lookupTOML(got, path), lookupTOML(want, path)) {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupTOML(m map[string]any, path []string) any {
+	var v any = m
+	for _, p := range path {
+		table, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = table[p]
+	}
+	return v
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeTOML(t *testing.T) {
+	const rendered = `[sync]
+  rate_limit_qps = 9
+
+[server]
+  api_port = 8080
+  cors_origins = ["https://a.example.com"]
+
+[remote]
+  url = "http://nas:8080"
+
+[[accounts]]
+  email = "alice@example.com"
+  enabled = true
+`
+	tests := []struct {
+		name     string
+		existing string
+		saved    map[string]string
+		want     string
+	}{
+		{
+			name: "changed value keeps comments and order",
+			existing: `# my vault
+[server]
+api_port = 8080
+
+[sync]
+# be gentle
+rate_limit_qps = 5  # per second
+`,
+			saved: map[string]string{
+				"sync\x00rate_limit_qps":    "5",
+				"server\x00api_port":        "8080",
+				"server\x00cors_origins":    `["https://a.example.com"]`,
+				"remote\x00url":             `"http://nas:8080"`,
+				"[[accounts":                "[[accounts]]\nemail = \"alice@example.com\"\nenabled = true",
+				"unrelated\x00never_loaded": "1",
+			},
+			want: `# my vault
+[server]
+api_port = 8080
+
+[sync]
+# be gentle
+rate_limit_qps = 9  # per second
+`,
+		},
+		{
+			name: "new keys join their table or append one",
+			existing: `[server]
+api_port = 8080
+`,
+			saved: map[string]string{
+				"sync\x00rate_limit_qps": "9",
+				"server\x00api_port":     "8080",
+				"[[accounts":             "[[accounts]]\nemail = \"alice@example.com\"\nenabled = true",
+			},
+			want: `[server]
+api_port = 8080
+cors_origins = ["https://a.example.com"]
+
+[remote]
+url = "http://nas:8080"
+`,
+		},
+		{
+			name: "multi-line values are replaced whole",
+			existing: `[server]
+cors_origins = [
+  "https://old.example.com",
+]
+api_port = 8080
+`,
+			saved: map[string]string{
+				"sync\x00rate_limit_qps": "9",
+				"server\x00api_port":     "8080",
+				"server\x00cors_origins": `["https://old.example.com"]`,
+				"remote\x00url":          `"http://nas:8080"`,
+				"[[accounts":             "[[accounts]]\nemail = \"alice@example.com\"\nenabled = true",
+			},
+			want: `[server]
+cors_origins = ["https://a.example.com"]
+api_port = 8080
+`,
+		},
+		{
+			name: "array of tables is replaced in place",
+			existing: `# scheduled syncs
+[[accounts]]
+email = "bob@example.com"
+schedule = "0 2 * * *"
+
+[[accounts]]
+email = "carol@example.com"
+
+# the end
+`,
+			saved: map[string]string{
+				"sync\x00rate_limit_qps": "9",
+				"server\x00api_port":     "8080",
+				"server\x00cors_origins": `["https://a.example.com"]`,
+				"remote\x00url":          `"http://nas:8080"`,
+			},
+			want: `# scheduled syncs
+[[accounts]]
+email = "alice@example.com"
+enabled = true
+
+# the end
+`,
+		},
+		{
+			name: "removed settings and emptied tables are dropped",
+			existing: `[remote]
+url = "http://nas:8080"
+
+[oauth.apps.acme]
+client_secrets = "/secrets/acme.json"
+`,
+			saved: map[string]string{
+				"sync\x00rate_limit_qps":                  "9",
+				"server\x00api_port":                      "8080",
+				"server\x00cors_origins":                  `["https://a.example.com"]`,
+				"remote\x00url":                           `"http://nas:8080"`,
+				"[[accounts":                              "[[accounts]]\nemail = \"alice@example.com\"\nenabled = true",
+				"oauth\x00apps\x00acme\x00client_secrets": `"/secrets/acme.json"`,
+			},
+			want: `[remote]
+url = "http://nas:8080"
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mergeTOML(tt.existing, rendered, tt.saved)
+			if !ok {
+				t.Fatalf("mergeTOML() not ok:\n%s", got)
+			}
+			if got != tt.want {
+				t.Errorf("mergeTOML() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMergeTOML_InlineTableFallsBack verifies that a setting defined in
+// a form the editor cannot patch is reported rather than duplicated.
+func TestMergeTOML_InlineTableFallsBack(t *testing.T) {
+	existing := "remote = { url = \"http://old:8080\" }\n"
+	rendered := "[remote]\n  url = \"http://nas:8080\"\n"
+	if got, ok := mergeTOML(existing, rendered, nil); ok {
+		t.Errorf("mergeTOML() ok, want fallback; got:\n%s", got)
+	}
+}
+
+func TestScanTOMLValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		lines       []string
+		wantEnd     int
+		wantComment int
+	}{
+		{"plain", []string{`a = 1 # c`}, 0, 6},
+		{"hash in string", []string{`a = "x#y"`}, 0, 9},
+		{"array across lines", []string{`a = [`, `  1, # one`, `]`}, 2, 1},
+		{"multi-line string", []string{`a = """`, `x = [`, `"""`}, 2, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			end, comment := scanTOMLValue(tt.lines, 0, strings.Index(tt.lines[0], "=")+1)
+			if end != tt.wantEnd || comment != tt.wantComment {
+				t.Errorf("scanTOMLValue() = (%d, %d), want (%d, %d)", end, comment, tt.wantEnd, tt.wantComment)
+			}
+		})
+	}
+}
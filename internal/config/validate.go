@@ -0,0 +1,388 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	"github.com/wesm/msgvault/internal/fileutil"
+	"github.com/wesm/msgvault/internal/i18n"
+)
+
+// Issue severities reported by Check and CheckSettings.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is one problem found in a configuration.
+type Issue struct {
+	Severity string `json:"severity"` // SeverityError or SeverityWarning
+	Key      string `json:"key"`      // dotted setting, e.g. "oauth.client_secrets"
+	Message  string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return i.Key + ": " + i.Message
+}
+
+type issueList []Issue
+
+func (l *issueList) errorf(key, format string, args ...any) {
+	*l = append(*l, Issue{Severity: SeverityError, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *issueList) warnf(key, format string, args ...any) {
+	*l = append(*l, Issue{Severity: SeverityWarning, Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// CheckSettings reports problems visible in the settings alone:
+// unknown keys, invalid values, and combinations that cannot work. It
+// touches nothing on disk, so it is cheap enough to run on every
+// command.
+func (c *Config) CheckSettings() []Issue {
+	var l issueList
+
+	for _, key := range c.unknownKeys {
+		l.warnf(key, "unknown setting (misspelled, or under the wrong [section]?)")
+	}
+
+	switch strings.ToLower(strings.TrimSpace(c.Log.Level)) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		l.errorf("log.level", "unknown level %q (want debug, info, warn, or error)", c.Log.Level)
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Log.Format)) {
+	case "", "text", "json":
+	default:
+		l.errorf("log.format", "unknown format %q (want text or json)", c.Log.Format)
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Tracing.Exporter)) {
+	case "", "none", "otlp", "stdout":
+	default:
+		l.errorf("tracing.exporter", "unknown exporter %q (want otlp, stdout, or none)", c.Tracing.Exporter)
+	}
+	switch strings.ToLower(strings.TrimSpace(c.Data.RawCompression)) {
+	case "", "zlib", "zstd":
+	default:
+		l.errorf("data.raw_compression", "unknown codec %q (want zlib or zstd)", c.Data.RawCompression)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		l.errorf("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	if lang := strings.TrimSpace(c.UI.Language); lang != "" {
+		if _, ok := i18n.Match(lang); !ok {
+			l.warnf("ui.language", "no translation for %q; using English (available: %s)",
+				lang, strings.Join(i18n.Supported(), ", "))
+		}
+	}
+
+	for _, name := range sortedAppNames(c.OAuth.Apps) {
+		app := c.OAuth.Apps[name]
+		if app.ClientSecrets == "" && app.ServiceAccountKey == "" {
+			l.errorf("oauth.apps."+name, "set client_secrets or service_account_key")
+		}
+	}
+	if c.Microsoft.TenantID != "" && c.Microsoft.ClientID == "" {
+		l.errorf("microsoft.client_id", "required when microsoft.tenant_id is set")
+	}
+	if c.Sync.RateLimitQPS < 0 {
+		l.errorf("sync.rate_limit_qps", "must not be negative, got %d", c.Sync.RateLimitQPS)
+	}
+	if c.Sync.FetchWorkers < 0 {
+		l.errorf("sync.fetch_workers", "must not be negative, got %d", c.Sync.FetchWorkers)
+	}
+	if c.Sync.FetchRate < 0 {
+		l.errorf("sync.fetch_rate", "must not be negative, got %g", c.Sync.FetchRate)
+	}
+	if c.Sync.WriteBatch < 0 {
+		l.errorf("sync.write_batch", "must not be negative, got %d", c.Sync.WriteBatch)
+	}
+
+	if c.Remote.URL != "" {
+		if !isHTTPURL(c.Remote.URL) {
+			l.errorf("remote.url", "must be an http or https URL with a host (got %q)", c.Remote.URL)
+		}
+	} else if c.Remote.APIKey != "" {
+		l.warnf("remote.api_key", "set without remote.url, so it is never used")
+	}
+
+	for _, p := range []struct {
+		key  string
+		port int
+	}{
+		{"server.api_port", c.Server.APIPort},
+		{"server.grpc_port", c.Server.GRPCPort},
+		{"server.imap_port", c.Server.IMAPPort},
+	} {
+		if p.port < 0 || p.port > 65535 {
+			l.errorf(p.key, "must be between 0 and 65535, got %d", p.port)
+		}
+	}
+	if err := c.Server.validateUsers(); err != nil {
+		l.errorf("server.users", "%v", err)
+	}
+
+	cronParser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	for i, acc := range c.Accounts {
+		key := fmt.Sprintf("accounts[%d]", i)
+		if acc.Email == "" {
+			l.errorf(key+".email", "required")
+		}
+		if acc.Schedule != "" {
+			if _, err := cronParser.Parse(acc.Schedule); err != nil {
+				l.errorf(key+".schedule", "invalid cron expression %q: %v", acc.Schedule, err)
+			}
+		} else if acc.Enabled {
+			l.warnf(key+".schedule", "enabled without a schedule, so it never runs")
+		}
+		if acc.Jitter < 0 {
+			l.errorf(key+".jitter", "must not be negative, got %s", acc.Jitter)
+		}
+	}
+	if c.Daemon.Jitter < 0 {
+		l.errorf("daemon.jitter", "must not be negative, got %s", c.Daemon.Jitter)
+	}
+
+	for i, wh := range c.Webhooks {
+		key := fmt.Sprintf("webhooks[%d]", i)
+		if wh.URL == "" {
+			l.errorf(key+".url", "required")
+		} else if !isHTTPURL(wh.URL) {
+			l.errorf(key+".url", "must be an http or https URL with a host (got %q)", wh.URL)
+		}
+		if wh.MaxAttempts < 0 {
+			l.errorf(key+".max_attempts", "must not be negative, got %d", wh.MaxAttempts)
+		}
+	}
+
+	ruleNames := make(map[string]bool)
+	for i, r := range c.Rules {
+		key := fmt.Sprintf("rules[%d]", i)
+		switch {
+		case r.Name == "":
+			l.errorf(key+".name", "required")
+		case ruleNames[r.Name]:
+			l.errorf(key+".name", "duplicate rule name %q", r.Name)
+		}
+		ruleNames[r.Name] = true
+		if strings.TrimSpace(r.Query) == "" {
+			l.errorf(key+".query", "required (a rule matching all mail is almost always a mistake)")
+		}
+		if r.Category != "" && !slices.Contains(RuleCategories, r.Category) {
+			l.errorf(key+".category", "must be one of %s, got %q", strings.Join(RuleCategories, ", "), r.Category)
+		}
+		if r.Label == "" && r.Category == "" && !r.SkipAttachments && !r.Notify {
+			l.warnf(key, "has no action (label, category, skip_attachments, or notify), so it does nothing")
+		}
+	}
+
+	notifierNames := make(map[string]bool)
+	for i, n := range c.Notifiers {
+		key := fmt.Sprintf("notifiers[%d]", i)
+		switch {
+		case n.Name == "":
+			l.errorf(key+".name", "required")
+		case notifierNames[n.Name]:
+			l.errorf(key+".name", "duplicate notifier name %q", n.Name)
+		}
+		notifierNames[n.Name] = true
+		for _, ev := range n.Events {
+			if !slices.Contains(NotifyEvents, ev) {
+				l.errorf(key+".events", "unknown event %q (valid: %s)", ev, strings.Join(NotifyEvents, ", "))
+			}
+		}
+		switch n.Type {
+		case "desktop":
+		case "ntfy", "slack":
+			if n.URL == "" {
+				l.errorf(key+".url", "required for type %s", n.Type)
+			} else if !isHTTPURL(n.URL) {
+				l.errorf(key+".url", "must be an http or https URL with a host (got %q)", n.URL)
+			}
+		case "email":
+			if n.SMTPHost == "" {
+				l.errorf(key+".smtp_host", "required for type email")
+			}
+			if n.SMTPPort < 0 || n.SMTPPort > 65535 {
+				l.errorf(key+".smtp_port", "must be between 1 and 65535, got %d", n.SMTPPort)
+			}
+			if n.From == "" {
+				l.errorf(key+".from", "required for type email")
+			}
+			if len(n.To) == 0 {
+				l.errorf(key+".to", "required for type email")
+			}
+		case "":
+			l.errorf(key+".type", "required (one of %s)", strings.Join(NotifierTypes, ", "))
+		default:
+			l.errorf(key+".type", "must be one of %s, got %q", strings.Join(NotifierTypes, ", "), n.Type)
+		}
+	}
+
+	if c.Risk.RspamdURL != "" && !isHTTPURL(c.Risk.RspamdURL) {
+		l.errorf("risk.rspamd_url", "must be an http or https URL with a host (got %q)", c.Risk.RspamdURL)
+	}
+
+	if c.Watch.Topic != "" && !isPubSubName(c.Watch.Topic, "topics") {
+		l.errorf("watch.topic", "must look like projects/<project>/topics/<name>, got %q", c.Watch.Topic)
+	}
+	if c.Watch.Subscription != "" && !isPubSubName(c.Watch.Subscription, "subscriptions") {
+		l.errorf("watch.subscription", "must look like projects/<project>/subscriptions/<name>, got %q", c.Watch.Subscription)
+	}
+	if (c.Watch.Topic == "") != (c.Watch.Subscription == "") {
+		l.errorf("watch", "set both topic and subscription, or neither")
+	}
+
+	if c.VirusScan.Quarantine && len(c.VirusScan.Command) == 0 {
+		l.warnf("virus_scan.quarantine", "set without a command, so nothing is scanned or quarantined")
+	}
+
+	if c.Vector.Enabled {
+		if err := c.Vector.Validate(); err != nil {
+			// vector.Config errors already lead with their key.
+			key, msg, ok := strings.Cut(err.Error(), ": ")
+			if !ok || !strings.HasPrefix(key, "vector.") {
+				key, msg = "vector", err.Error()
+			}
+			l.errorf(key, "%s", msg)
+		}
+	}
+
+	return l
+}
+
+// Check reports everything CheckSettings does plus problems with the
+// files and directories the config points at: missing or unreadable
+// credentials, data directories that are not writable, and secrets
+// readable by other users.
+func (c *Config) Check() []Issue {
+	l := issueList(c.CheckSettings())
+
+	checkFile := func(key, path string, secret bool) {
+		if path == "" {
+			return
+		}
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			l.errorf(key, "file not found: %s", path)
+			return
+		}
+		if err != nil {
+			l.errorf(key, "%v", err)
+			return
+		}
+		if info.IsDir() {
+			l.errorf(key, "%s is a directory, expected a file", path)
+			return
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			l.errorf(key, "cannot read %s: %v", path, err)
+			return
+		}
+		_ = f.Close()
+		if secret && groupOrWorldReadable(info) {
+			l.warnf(key, "%s is readable by other users; run 'chmod 600 %s'", path, path)
+		}
+	}
+	checkDir := func(key, path string) {
+		if path == "" {
+			return
+		}
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			l.warnf(key, "%s does not exist yet; it is created on first use", path)
+			return
+		}
+		if err != nil {
+			l.errorf(key, "%v", err)
+			return
+		}
+		if !info.IsDir() {
+			l.errorf(key, "%s is not a directory", path)
+			return
+		}
+		f, err := os.CreateTemp(path, ".msgvault-check-*")
+		if err != nil {
+			l.errorf(key, "%s is not writable: %v", path, err)
+			return
+		}
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+	}
+
+	checkFile("oauth.client_secrets", c.OAuth.ClientSecrets, false)
+	checkFile("oauth.service_account_key", c.OAuth.ServiceAccountKey, true)
+	for _, name := range sortedAppNames(c.OAuth.Apps) {
+		app := c.OAuth.Apps[name]
+		checkFile("oauth.apps."+name+".client_secrets", app.ClientSecrets, false)
+		checkFile("oauth.apps."+name+".service_account_key", app.ServiceAccountKey, true)
+	}
+	checkFile("watch.credentials", c.Watch.Credentials, true)
+	for i, path := range c.Risk.Blocklists {
+		checkFile(fmt.Sprintf("risk.blocklists[%d]", i), path, false)
+	}
+	checkDir("data.data_dir", c.Data.DataDir)
+	checkDir("data.attachments_dir", c.Data.AttachmentsDir)
+	checkDir("log.dir", c.Log.Dir)
+
+	if c.configPath != "" && c.hasSecrets() {
+		if info, err := os.Stat(c.configPath); err == nil && groupOrWorldReadable(info) {
+			l.warnf("config", "%s holds API keys but is readable by other users; run 'chmod 600 %s'",
+				c.configPath, c.configPath)
+		}
+	}
+
+	return l
+}
+
+// hasSecrets reports whether the config file itself holds credentials.
+func (c *Config) hasSecrets() bool {
+	if c.Server.APIKey != "" || c.Remote.APIKey != "" || len(c.Server.Users) > 0 {
+		return true
+	}
+	for _, wh := range c.Webhooks {
+		if wh.Secret != "" {
+			return true
+		}
+	}
+	for _, n := range c.Notifiers {
+		if n.Token != "" || n.SMTPPassword != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// groupOrWorldReadable reports whether anyone but the owner can read
+// the file. Windows permissions are ACL-based, so it never reports
+// there.
+func groupOrWorldReadable(info os.FileInfo) bool {
+	return fileutil.ExposedTo(info, 0o044)
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+func sortedAppNames(apps map[string]OAuthApp) []string {
+	names := make([]string, 0, len(apps))
+	for name := range apps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isPubSubName reports whether name is a Pub/Sub resource name of the
+// given kind, "projects/<project>/<kind>/<name>".
+func isPubSubName(name, kind string) bool {
+	parts := strings.Split(name, "/")
+	return len(parts) == 4 && parts[0] == "projects" && parts[1] != "" && parts[2] == kind && parts[3] != ""
+}
@@ -0,0 +1,295 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// loadTOML writes content as config.toml in a fresh home and loads it.
+func loadTOML(t *testing.T, content string) *Config {
+	t.Helper()
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "config.toml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load("", tmpDir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return cfg
+}
+
+func findIssue(issues []Issue, key string) *Issue {
+	for i := range issues {
+		if issues[i].Key == key {
+			return &issues[i]
+		}
+	}
+	return nil
+}
+
+func TestCheckSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		key      string // "" means no issues expected
+		severity string
+		contains string
+	}{
+		{
+			name:    "defaults are clean",
+			content: "",
+		},
+		{
+			name: "valid settings are clean",
+			content: `
+[log]
+level = "debug"
+format = "json"
+
+[[accounts]]
+email = "alice@example.com"
+schedule = "0 2 * * *"
+enabled = true
+`,
+		},
+		{
+			name:     "misspelled key",
+			content:  "[sync]\nrate_limt_qps = 3\n",
+			key:      "sync.rate_limt_qps",
+			severity: SeverityWarning,
+			contains: "unknown setting",
+		},
+		{
+			name:     "key in wrong section",
+			content:  "[server]\nclient_secrets = \"/x.json\"\n",
+			key:      "server.client_secrets",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "bad log level",
+			content:  "[log]\nlevel = \"loud\"\n",
+			key:      "log.level",
+			severity: SeverityError,
+			contains: "loud",
+		},
+		{
+			name:     "bad tracing exporter",
+			content:  "[tracing]\nexporter = \"jaeger\"\n",
+			key:      "tracing.exporter",
+			severity: SeverityError,
+		},
+		{
+			name:    "zstd raw compression",
+			content: "[data]\nraw_compression = \"zstd\"\n",
+		},
+		{
+			name:     "bad raw compression",
+			content:  "[data]\nraw_compression = \"brotli\"\n",
+			key:      "data.raw_compression",
+			severity: SeverityError,
+			contains: "brotli",
+		},
+		{
+			name:    "supported language",
+			content: "[ui]\nlanguage = \"de-AT\"\n",
+		},
+		{
+			name:     "untranslated language",
+			content:  "[ui]\nlanguage = \"ja\"\n",
+			key:      "ui.language",
+			severity: SeverityWarning,
+			contains: "using English",
+		},
+		{
+			name:     "oauth app without credentials",
+			content:  "[oauth.apps.acme]\n",
+			key:      "oauth.apps.acme",
+			severity: SeverityError,
+			contains: "client_secrets or service_account_key",
+		},
+		{
+			name:     "tenant without client id",
+			content:  "[microsoft]\ntenant_id = \"contoso\"\n",
+			key:      "microsoft.client_id",
+			severity: SeverityError,
+		},
+		{
+			name:     "remote key without url",
+			content:  "[remote]\napi_key = \"k\"\n",
+			key:      "remote.api_key",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "remote url without scheme",
+			content:  "[remote]\nurl = \"nas:8080\"\n",
+			key:      "remote.url",
+			severity: SeverityError,
+		},
+		{
+			name:     "port out of range",
+			content:  "[server]\napi_port = 70000\n",
+			key:      "server.api_port",
+			severity: SeverityError,
+		},
+		{
+			name:     "bad cron schedule",
+			content:  "[[accounts]]\nemail = \"alice@example.com\"\nschedule = \"every day\"\n",
+			key:      "accounts[0].schedule",
+			severity: SeverityError,
+			contains: "invalid cron expression",
+		},
+		{
+			name:     "enabled without schedule",
+			content:  "[[accounts]]\nemail = \"alice@example.com\"\nenabled = true\n",
+			key:      "accounts[0].schedule",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "webhook without url",
+			content:  "[[webhooks]]\nname = \"invoices\"\n",
+			key:      "webhooks[0].url",
+			severity: SeverityError,
+		},
+		{
+			name:     "rule without query",
+			content:  "[[rules]]\nname = \"receipts\"\nlabel = \"Receipts\"\n",
+			key:      "rules[0].query",
+			severity: SeverityError,
+		},
+		{
+			name:     "rule with unknown category",
+			content:  "[[rules]]\nname = \"news\"\nquery = \"from:news.example.com\"\ncategory = \"newsletters\"\n",
+			key:      "rules[0].category",
+			severity: SeverityError,
+			contains: "promotions",
+		},
+		{
+			name:     "rule without action",
+			content:  "[[rules]]\nname = \"news\"\nquery = \"from:news.example.com\"\n",
+			key:      "rules[0]",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "rspamd url without scheme",
+			content:  "[risk]\nrspamd_url = \"localhost:11333\"\n",
+			key:      "risk.rspamd_url",
+			severity: SeverityError,
+		},
+		{
+			name:     "watch topic without project",
+			content:  "[watch]\ntopic = \"gmail-changes\"\nsubscription = \"projects/p/subscriptions/msgvault\"\n",
+			key:      "watch.topic",
+			severity: SeverityError,
+		},
+		{
+			name:     "negative daemon jitter",
+			content:  "[daemon]\njitter = \"-5m\"\n",
+			key:      "daemon.jitter",
+			severity: SeverityError,
+		},
+		{
+			name:     "watch topic without subscription",
+			content:  "[watch]\ntopic = \"projects/p/topics/gmail\"\n",
+			key:      "watch",
+			severity: SeverityError,
+		},
+		{
+			name:     "quarantine without scanner",
+			content:  "[virus_scan]\nquarantine = true\n",
+			key:      "virus_scan.quarantine",
+			severity: SeverityWarning,
+		},
+		{
+			name:     "notifier with unknown type",
+			content:  "[[notifiers]]\nname = \"phone\"\ntype = \"pager\"\n",
+			key:      "notifiers[0].type",
+			severity: SeverityError,
+			contains: "ntfy",
+		},
+		{
+			name:     "notifier with unknown event",
+			content:  "[[notifiers]]\nname = \"laptop\"\ntype = \"desktop\"\nevents = [\"sync_done\"]\n",
+			key:      "notifiers[0].events",
+			severity: SeverityError,
+			contains: "sync_complete",
+		},
+		{
+			name:     "email notifier without recipients",
+			content:  "[[notifiers]]\nname = \"mail\"\ntype = \"email\"\nsmtp_host = \"smtp.example.com\"\nfrom = \"vault@example.com\"\n",
+			key:      "notifiers[0].to",
+			severity: SeverityError,
+		},
+		{
+			name:     "server user without accounts",
+			content:  "[[server.users]]\nname = \"bob\"\napi_key = \"k\"\n",
+			key:      "server.users",
+			severity: SeverityError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := loadTOML(t, tt.content).CheckSettings()
+			if tt.key == "" {
+				if len(issues) != 0 {
+					t.Fatalf("CheckSettings() = %v, want none", issues)
+				}
+				return
+			}
+			issue := findIssue(issues, tt.key)
+			if issue == nil {
+				t.Fatalf("CheckSettings() = %v, want issue for %s", issues, tt.key)
+			}
+			if issue.Severity != tt.severity {
+				t.Errorf("severity = %q, want %q", issue.Severity, tt.severity)
+			}
+			if !strings.Contains(issue.Message, tt.contains) {
+				t.Errorf("message = %q, want it to contain %q", issue.Message, tt.contains)
+			}
+		})
+	}
+}
+
+func TestCheck_Files(t *testing.T) {
+	tmpDir := t.TempDir()
+	keyPath := filepath.Join(tmpDir, "sa.json")
+	if err := os.WriteFile(keyPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notADir := filepath.Join(tmpDir, "file")
+	if err := os.WriteFile(notADir, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := NewDefaultConfig()
+	cfg.HomeDir = tmpDir
+	cfg.Data.DataDir = tmpDir
+	cfg.Data.AttachmentsDir = notADir
+	cfg.Log.Dir = filepath.Join(tmpDir, "logs")
+	cfg.OAuth.ClientSecrets = filepath.Join(tmpDir, "missing.json")
+	cfg.OAuth.ServiceAccountKey = keyPath
+
+	issues := cfg.Check()
+
+	if issue := findIssue(issues, "oauth.client_secrets"); issue == nil || !strings.Contains(issue.Message, "not found") {
+		t.Errorf("client_secrets issue = %v, want file not found", issue)
+	}
+	if issue := findIssue(issues, "data.attachments_dir"); issue == nil || issue.Severity != SeverityError {
+		t.Errorf("attachments_dir issue = %v, want error for a non-directory", issue)
+	}
+	if issue := findIssue(issues, "log.dir"); issue == nil || issue.Severity != SeverityWarning {
+		t.Errorf("log.dir issue = %v, want warning for a missing directory", issue)
+	}
+	if issue := findIssue(issues, "data.data_dir"); issue != nil {
+		t.Errorf("data_dir issue = %v, want none", issue)
+	}
+	if runtime.GOOS != "windows" {
+		if issue := findIssue(issues, "oauth.service_account_key"); issue == nil || !strings.Contains(issue.Message, "chmod 600") {
+			t.Errorf("service_account_key issue = %v, want permission warning", issue)
+		}
+	}
+}
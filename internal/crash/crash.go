@@ -0,0 +1,187 @@
+// Package crash writes a diagnostic bundle when msgvault panics. The
+// bundle is a plain-text file holding the panic and its stack, the
+// command line, the config file with secrets redacted, the last log
+// lines, and the state of the database schema — enough to file a
+// useful bug report without a reproduction.
+//
+// Configure is called once at startup; Report is called from the
+// places that recover panics (the CLI's top-level recover, sync, and
+// the TUI). Until Configure runs, Report writes nothing.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IssuesURL is where bug reports are filed.
+const IssuesURL = "https://github.com/wesm/msgvault/issues/new"
+
+// logTailLines is how many log lines a bundle includes.
+const logTailLines = 200
+
+// Options configures the bundles Report writes.
+type Options struct {
+	// Dir is where bundles are written. Empty disables reporting.
+	Dir string
+
+	Version string
+
+	// Args is the command line, already stripped of secrets.
+	Args []string
+
+	// ConfigPath is the config file; its secret values are redacted
+	// in the bundle.
+	ConfigPath string
+
+	// LogFile is the current log file, if file logging is on. Its
+	// tail is preferred over the in-memory log lines, because it
+	// also holds records logged while the TUI owned the terminal.
+	LogFile string
+
+	// Schema describes the database schema. It may be nil.
+	Schema func() string
+}
+
+var (
+	mu   sync.Mutex
+	opts Options
+	now  = time.Now
+)
+
+// Configure sets the options for later bundles.
+func Configure(o Options) {
+	mu.Lock()
+	defer mu.Unlock()
+	opts = o
+}
+
+// Recovered is a panic recovered in one place and re-raised in
+// another, such as the TUI's event loop handing a panic to the CLI's
+// top-level recover. Stack is the stack at the original panic.
+type Recovered struct {
+	Value any
+	Stack []byte
+}
+
+func (r *Recovered) String() string { return fmt.Sprint(r.Value) }
+
+// Unwrap returns the original panic value and stack of r, which is
+// either a *Recovered or a value recovered directly, in which case
+// stack is used.
+func Unwrap(r any, stack []byte) (any, []byte) {
+	if rec, ok := r.(*Recovered); ok {
+		return rec.Value, rec.Stack
+	}
+	return r, stack
+}
+
+// Report writes a bundle for the panic value r with the given stack
+// and returns its path. It returns "" without error when Configure
+// has not set a directory. Report must not panic itself: a failing
+// section is noted in the bundle and the rest is still written.
+func Report(r any, stack []byte) (string, error) {
+	mu.Lock()
+	o := opts
+	mu.Unlock()
+	if o.Dir == "" {
+		return "", nil
+	}
+	r, stack = Unwrap(r, stack)
+
+	if err := os.MkdirAll(o.Dir, 0700); err != nil {
+		return "", fmt.Errorf("create crash report directory: %w", err)
+	}
+	t := now()
+	path := filepath.Join(o.Dir, fmt.Sprintf("msgvault-crash-%s-%d.txt",
+		t.UTC().Format("20060102-150405"), os.Getpid()))
+	// Log lines can carry addresses and subjects; keep the file private.
+	if err := os.WriteFile(path, []byte(bundle(o, t, r, stack)), 0600); err != nil {
+		return "", fmt.Errorf("write crash report: %w", err)
+	}
+	return path, nil
+}
+
+// bundle renders the report text.
+func bundle(o Options, t time.Time, r any, stack []byte) string {
+	var b strings.Builder
+	b.WriteString("msgvault crash report\n")
+	b.WriteString("=====================\n\n")
+	fmt.Fprintf(&b, "Time:     %s\n", t.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "Version:  %s\n", o.Version)
+	fmt.Fprintf(&b, "Go:       %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "Command:  %s\n", strings.Join(o.Args, " "))
+	fmt.Fprintf(&b, "Panic:    %v\n", r)
+
+	section(&b, "Stack", string(stack))
+	section(&b, "Schema", safely(o.Schema))
+
+	cfgTitle := "Config (secrets redacted)"
+	cfgText := "(no config file)"
+	if o.ConfigPath != "" {
+		cfgTitle = fmt.Sprintf("Config %s (secrets redacted)", o.ConfigPath)
+		data, err := os.ReadFile(o.ConfigPath)
+		switch {
+		case err == nil:
+			cfgText = RedactConfig(string(data))
+		case os.IsNotExist(err):
+		default:
+			cfgText = fmt.Sprintf("(read failed: %v)", err)
+		}
+	}
+	section(&b, cfgTitle, cfgText)
+
+	logTitle := "Recent log lines"
+	lines := recentLines()
+	if o.LogFile != "" {
+		if tail, err := tailFile(o.LogFile, logTailLines); err == nil {
+			logTitle = "Recent log lines from " + o.LogFile
+			lines = tail
+		}
+	}
+	if len(lines) == 0 {
+		lines = []string{"(none)"}
+	}
+	section(&b, logTitle, strings.Join(lines, "\n"))
+	return b.String()
+}
+
+func section(b *strings.Builder, title, body string) {
+	fmt.Fprintf(b, "\n== %s ==\n%s\n", title, strings.TrimRight(body, "\n"))
+}
+
+// safely calls fn, turning a nil fn or a panic inside it into a note.
+func safely(fn func() string) (s string) {
+	if fn == nil {
+		return "(unavailable)"
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("(unavailable: %v)", r)
+		}
+	}()
+	return fn()
+}
+
+// Instructions returns the message printed after a crash, pointing
+// the user at the bundle and the issue tracker.
+func Instructions(r any, path string) string {
+	r, _ = Unwrap(r, nil)
+	var b strings.Builder
+	fmt.Fprintf(&b, "msgvault crashed: %v\n\n", r)
+	if path == "" {
+		fmt.Fprintf(&b, "Please report this at %s\n", IssuesURL)
+		b.WriteString("and include the command you ran and the stack trace from the log.\n")
+		return b.String()
+	}
+	fmt.Fprintf(&b, "A diagnostic report was written to:\n  %s\n\n", path)
+	fmt.Fprintf(&b, "Please report this at %s and attach the report.\n", IssuesURL)
+	b.WriteString("Secrets in your config are redacted, but recent log lines may\n")
+	b.WriteString("include email addresses or subjects; review the report before sharing.\n")
+	return b.String()
+}
@@ -0,0 +1,119 @@
+package crash
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ring holds the most recent log lines in memory, so a bundle has
+// them even when file logging is off.
+type ring struct {
+	mu    sync.Mutex
+	lines []string
+	next  int
+	full  bool
+}
+
+var recent = &ring{lines: make([]string, logTailLines)}
+
+// Write stores each line of p. slog handlers write one record per call.
+func (r *ring) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		r.lines[r.next] = line
+		r.next = (r.next + 1) % len(r.lines)
+		if r.next == 0 {
+			r.full = true
+		}
+	}
+	return len(p), nil
+}
+
+// snapshot returns the stored lines, oldest first.
+func (r *ring) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]string(nil), r.lines[:r.next]...)
+	}
+	out := append([]string(nil), r.lines[r.next:]...)
+	return append(out, r.lines[:r.next]...)
+}
+
+func recentLines() []string { return recent.snapshot() }
+
+// CaptureLogs returns a handler that passes records to h and also
+// keeps the recent ones, at info level and above or whatever h logs,
+// for crash bundles.
+func CaptureLogs(h slog.Handler) slog.Handler {
+	return &teeHandler{
+		inner: h,
+		ring:  slog.NewTextHandler(recent, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	}
+}
+
+type teeHandler struct {
+	inner slog.Handler
+	ring  slog.Handler
+}
+
+func (t *teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo || t.inner.Enabled(ctx, level)
+}
+
+func (t *teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	_ = t.ring.Handle(ctx, r.Clone())
+	if !t.inner.Enabled(ctx, r.Level) {
+		return nil
+	}
+	return t.inner.Handle(ctx, r)
+}
+
+func (t *teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &teeHandler{inner: t.inner.WithAttrs(attrs), ring: t.ring.WithAttrs(attrs)}
+}
+
+func (t *teeHandler) WithGroup(name string) slog.Handler {
+	return &teeHandler{inner: t.inner.WithGroup(name), ring: t.ring.WithGroup(name)}
+}
+
+// tailFile returns the last n lines of the file at path.
+func tailFile(path string, n int) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	// Log files rotate at a few MB; reading only the end keeps a
+	// crash in a long-running daemon from loading the whole file.
+	const maxTail = 256 * 1024
+	partial := false
+	if st, err := f.Stat(); err == nil && st.Size() > maxTail {
+		if _, err := f.Seek(st.Size()-maxTail, io.SeekStart); err != nil {
+			return nil, err
+		}
+		partial = true
+	}
+	var lines []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if partial {
+			// The seek landed mid-line.
+			partial = false
+			continue
+		}
+		lines = append(lines, sc.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines, sc.Err()
+}
@@ -38,13 +38,11 @@ package dedup
 import (
 	"bufio"
 	"bytes"
-	"compress/zlib"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/textproto"
 	"path/filepath"
@@ -128,7 +126,7 @@ type Config struct {
 
 // DefaultSourcePreference is the default source-type authority order.
 var DefaultSourcePreference = []string{
-	"gmail", "imap", "mbox", "emlx", "hey",
+	"gmail", "outlook", "imap", "mbox", "emlx", "hey",
 }
 
 // remoteSourceTypes lists source types whose messages can be deleted
@@ -501,7 +499,7 @@ type hashResult struct {
 // scanNormalizedHashGroups hashes raw MIME after stripping transport-specific
 // headers. It skips messages already matched by the primary Message-ID pass.
 // Returns the duplicate groups plus a count of candidates skipped due to
-// zlib decompression failure.
+// decompression failure.
 func (e *Engine) scanNormalizedHashGroups(
 	excludeIDs map[int64]bool,
 ) ([]DuplicateGroup, int, error) {
@@ -550,28 +548,14 @@ func (e *Engine) scanNormalizedHashGroups(
 		go func() {
 			defer wg.Done()
 			for item := range work {
-				raw := item.rawData
-				if item.compress == "zlib" {
-					r, err := zlib.NewReader(bytes.NewReader(raw))
-					if err != nil {
-						if decompressionFailures.Add(1) <= maxDecompressionWarns {
-							e.logger.Warn("content-hash: zlib open failed",
-								"message_id", item.candidate.ID, "err", err)
-						}
-						results <- hashResult{skipped: true}
-						continue
+				raw, err := e.store.DecompressRaw(item.rawData, item.compress)
+				if err != nil {
+					if decompressionFailures.Add(1) <= maxDecompressionWarns {
+						e.logger.Warn("content-hash: decompress failed",
+							"message_id", item.candidate.ID, "err", err)
 					}
-					decompressed, err := io.ReadAll(r)
-					_ = r.Close()
-					if err != nil {
-						if decompressionFailures.Add(1) <= maxDecompressionWarns {
-							e.logger.Warn("content-hash: zlib read failed",
-								"message_id", item.candidate.ID, "err", err)
-						}
-						results <- hashResult{skipped: true}
-						continue
-					}
-					raw = decompressed
+					results <- hashResult{skipped: true}
+					continue
 				}
 
 				matched := false
@@ -664,7 +648,7 @@ func (e *Engine) scanNormalizedHashGroups(
 		groups = append(groups, g)
 	}
 	if skipped > maxDecompressionWarns {
-		e.logger.Warn("content-hash: additional zlib failures suppressed",
+		e.logger.Warn("content-hash: additional decompression failures suppressed",
 			"suppressed", skipped-maxDecompressionWarns)
 	}
 	return groups, skipped, nil
@@ -39,6 +39,10 @@ func ValidateContentHash(hash string) error {
 	return nil
 }
 
+// ErrQuarantined is reported for attachments the virus scanner flagged
+// and quarantined; they are never exported.
+var ErrQuarantined = errors.New("quarantined by the virus scanner (see 'msgvault list-infected')")
+
 // ExportStats contains structured results of an attachment export operation.
 type ExportStats struct {
 	Count      int
@@ -67,6 +71,10 @@ func Attachments(zipFilename, attachmentsDir string, attachments []query.Attachm
 			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", att.Filename, err))
 			continue
 		}
+		if att.Quarantined {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", att.Filename, ErrQuarantined))
+			continue
+		}
 
 		n, err := addAttachmentToZip(zipWriter, attachmentsDir, att, usedNames)
 		if err != nil {
@@ -141,11 +149,7 @@ func isWriteError(err error) bool {
 }
 
 func addAttachmentToZip(zw *zip.Writer, root string, att query.AttachmentInfo, usedNames map[string]int) (int64, error) {
-	storagePath, err := StoragePath(root, att.ContentHash)
-	if err != nil {
-		return 0, err
-	}
-	srcFile, err := os.Open(storagePath)
+	srcFile, err := OpenAttachment(root, att.ContentHash)
 	if err != nil {
 		return 0, err
 	}
@@ -227,7 +231,9 @@ func ValidateOutputPath(outputPath string) error {
 
 // StoragePath returns the content-addressed file path for an attachment:
 // attachmentsDir/<hash[:2]>/<hash>. Returns an error if the content hash
-// is invalid (prevents panics from short/empty strings).
+// is invalid (prevents panics from short/empty strings). A compressed
+// attachment is stored at this path plus CompressedSuffix; OpenAttachment
+// reads either.
 func StoragePath(attachmentsDir, contentHash string) (string, error) {
 	if err := ValidateContentHash(contentHash); err != nil {
 		return "", err
@@ -269,6 +275,10 @@ func AttachmentsToDir(outputDir, attachmentsDir string, attachments []query.Atta
 			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", att.Filename, err))
 			continue
 		}
+		if att.Quarantined {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", att.Filename, ErrQuarantined))
+			continue
+		}
 
 		filename := resolveUniqueFilename(att.Filename, att.ContentHash, usedNames)
 		exported, err := exportAttachmentToFile(outputDir, attachmentsDir, att.ContentHash, filename)
@@ -287,11 +297,7 @@ func AttachmentsToDir(outputDir, attachmentsDir string, attachments []query.Atta
 // storage to outputDir/filename. Uses O_EXCL to avoid overwriting; appends
 // _1, _2, etc. on conflict.
 func exportAttachmentToFile(outputDir, attachmentsDir, contentHash, filename string) (ExportedFile, error) {
-	srcPath, err := StoragePath(attachmentsDir, contentHash)
-	if err != nil {
-		return ExportedFile{}, err
-	}
-	src, err := os.Open(srcPath)
+	src, err := OpenAttachment(attachmentsDir, contentHash)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return ExportedFile{}, fmt.Errorf("attachment file not found for hash %s", contentHash)
@@ -195,6 +195,20 @@ func TestAttachmentsToDir(t *testing.T) {
 			wantErrors: 1,
 			wantNames:  []string{"good.txt"},
 		},
+		{
+			name: "quarantined file is skipped",
+			setup: func(t *testing.T, attachDir string) []query.AttachmentInfo {
+				h1 := createAttachmentFile(t, attachDir, []byte("infected"))
+				h2 := createAttachmentFile(t, attachDir, []byte("clean"))
+				return []query.AttachmentInfo{
+					{Filename: "invoice.exe", ContentHash: h1, Quarantined: true},
+					{Filename: "notes.txt", ContentHash: h2},
+				}
+			},
+			wantFiles:  1,
+			wantErrors: 1,
+			wantNames:  []string{"notes.txt"},
+		},
 		{
 			name: "duplicate filenames get deduped within batch",
 			setup: func(t *testing.T, attachDir string) []query.AttachmentInfo {
@@ -0,0 +1,241 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressedSuffix marks an attachment file stored zstd-compressed:
+// <hash[:2]>/<hash>.zst holds the attachment whose content hashes to
+// <hash>.
+const CompressedSuffix = ".zst"
+
+// minCompressSize is the smallest attachment worth compressing; below
+// it the saving is a few hundred bytes at most.
+const minCompressSize = 4 << 10
+
+// compressibleTypes are content types, beyond text/*, that are not
+// compressed already. Office Open XML documents are zip containers but
+// often still shrink; the saving check decides.
+var compressibleTypes = map[string]bool{
+	"application/json":              true,
+	"application/xml":               true,
+	"application/javascript":        true,
+	"application/rtf":               true,
+	"application/sql":               true,
+	"application/x-sh":              true,
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+	"application/vnd.ms-outlook":    true,
+	"message/rfc822":                true,
+}
+
+// compressibleExts are the filename extensions of compressible files,
+// for attachments sent with a generic content type.
+var compressibleExts = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".log": true, ".md": true,
+	".htm": true, ".html": true, ".xml": true, ".json": true, ".ics": true,
+	".vcf": true, ".eml": true, ".rtf": true, ".doc": true, ".xls": true,
+	".ppt": true, ".msg": true, ".docx": true, ".xlsx": true, ".pptx": true,
+}
+
+// Compressible reports whether an attachment of this content type and
+// filename is worth compressing at rest: text, structured text, and
+// office documents. Images, video, archives and PDFs are compressed
+// already and are stored as they are.
+func Compressible(contentType, filename string) bool {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	switch {
+	case strings.HasPrefix(ct, "text/"),
+		strings.HasSuffix(ct, "+xml"),
+		strings.HasSuffix(ct, "+json"),
+		strings.HasPrefix(ct, "application/vnd.openxmlformats-officedocument."),
+		compressibleTypes[ct]:
+		return true
+	case ct == "" || ct == "application/octet-stream":
+		return compressibleExts[strings.ToLower(filepath.Ext(filename))]
+	}
+	return false
+}
+
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+		if err != nil {
+			panic(fmt.Sprintf("zstd encoder: %v", err))
+		}
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+		if err != nil {
+			panic(fmt.Sprintf("zstd decoder: %v", err))
+		}
+		return dec
+	})
+)
+
+// compressContent returns content compressed, and whether that saves
+// at least an eighth of its size; otherwise it is better stored as is.
+func compressContent(content []byte) ([]byte, bool) {
+	if len(content) < minCompressSize {
+		return nil, false
+	}
+	z := zstdEncoder().EncodeAll(content, make([]byte, 0, len(content)/2))
+	if len(z) > len(content)-len(content)/8 {
+		return nil, false
+	}
+	return z, true
+}
+
+// IsCompressedPath reports whether a storage path or file path names a
+// compressed attachment file.
+func IsCompressedPath(p string) bool {
+	return strings.HasSuffix(p, CompressedSuffix)
+}
+
+// OpenAttachment opens the stored content of the attachment with this
+// content hash, decompressing it if it is stored compressed.
+func OpenAttachment(attachmentsDir, contentHash string) (io.ReadSeekCloser, error) {
+	p, err := StoragePath(attachmentsDir, contentHash)
+	if err != nil {
+		return nil, err
+	}
+	f, err := OpenAttachmentPath(p)
+	if os.IsNotExist(err) {
+		if zf, zerr := OpenAttachmentPath(p + CompressedSuffix); !os.IsNotExist(zerr) {
+			return zf, zerr
+		}
+	}
+	return f, err
+}
+
+// OpenAttachmentPath opens the attachment file at path, which may be
+// compressed. A compressed file is decompressed as it is read, so a copy
+// from front to back holds no more than the decoder's window in memory;
+// seeking back decompresses again from the start.
+func OpenAttachmentPath(path string) (io.ReadSeekCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !IsCompressedPath(path) {
+		return f, nil
+	}
+	z, err := newZstdFile(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("decompress %s: %w", filepath.Base(path), err)
+	}
+	return z, nil
+}
+
+// zstdFile reads the decompressed content of a compressed attachment
+// file. Seek only records the new position; the next Read moves the
+// decoder there.
+type zstdFile struct {
+	f    *os.File
+	dec  *zstd.Decoder
+	pos  int64 // offset in the decompressed content
+	dpos int64 // offset the decoder has reached
+	size int64 // decompressed size, or -1 until known
+}
+
+func newZstdFile(f *os.File) (*zstdFile, error) {
+	// Files are written as a single frame by compressContent, whose
+	// header records the decompressed size, so seeking to the end need
+	// not decompress anything.
+	size := int64(-1)
+	buf := make([]byte, zstd.HeaderMaxSize)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	var h zstd.Header
+	if h.Decode(buf[:n]) == nil && h.HasFCS {
+		size = int64(h.FrameContentSize)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+	if err != nil {
+		return nil, err
+	}
+	return &zstdFile{f: f, dec: dec, size: size}, nil
+}
+
+func (z *zstdFile) Read(p []byte) (int, error) {
+	if err := z.moveDecoder(); err != nil {
+		return 0, err
+	}
+	n, err := z.dec.Read(p)
+	z.pos += int64(n)
+	z.dpos = z.pos
+	return n, err
+}
+
+// moveDecoder brings the decoder to pos: forward by decompressing and
+// discarding, back by starting again from the beginning of the file.
+// Past the end it returns io.EOF, as a file read would.
+func (z *zstdFile) moveDecoder() error {
+	if z.pos < z.dpos {
+		if _, err := z.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := z.dec.Reset(z.f); err != nil {
+			return err
+		}
+		z.dpos = 0
+	}
+	if z.pos > z.dpos {
+		n, err := io.CopyN(io.Discard, z.dec, z.pos-z.dpos)
+		z.dpos += n
+		return err
+	}
+	return nil
+}
+
+func (z *zstdFile) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = z.pos + offset
+	case io.SeekEnd:
+		if z.size < 0 {
+			// No size in the frame header: decompress to the end to
+			// learn it.
+			n, err := io.Copy(io.Discard, z.dec)
+			z.dpos += n
+			if err != nil {
+				return z.pos, err
+			}
+			z.size = z.dpos
+		}
+		target = z.size + offset
+	default:
+		return z.pos, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if target < 0 {
+		return z.pos, fmt.Errorf("seek: negative position %d", target)
+	}
+	z.pos = target
+	return z.pos, nil
+}
+
+func (z *zstdFile) Close() error {
+	z.dec.Close()
+	return z.f.Close()
+}
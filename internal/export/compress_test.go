@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/query"
+)
+
+func TestCompressible(t *testing.T) {
+	tests := []struct {
+		contentType, filename string
+		want                  bool
+	}{
+		{"text/plain; charset=utf-8", "notes.txt", true},
+		{"text/csv", "q3.csv", true},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "report.docx", true},
+		{"application/msword", "old.doc", true},
+		{"image/svg+xml", "logo.svg", true},
+		{"application/octet-stream", "export.CSV", true},
+		{"application/octet-stream", "photo.jpg", false},
+		{"image/jpeg", "photo.jpg", false},
+		{"application/pdf", "invoice.pdf", false},
+		{"application/zip", "files.zip", false},
+	}
+	for _, tt := range tests {
+		if got := Compressible(tt.contentType, tt.filename); got != tt.want {
+			t.Errorf("Compressible(%q, %q) = %v, want %v", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestStoreAttachmentFile_CompressesText(t *testing.T) {
+	tmp := t.TempDir()
+	content := []byte(strings.Repeat("Quarterly numbers for alice@example.com\n", 500))
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	att := &mime.Attachment{Filename: "report.txt", ContentType: "text/plain", Content: content}
+	storagePath, storedSize, err := StoreAttachmentFile(tmp, att)
+	if err != nil {
+		t.Fatalf("StoreAttachmentFile: %v", err)
+	}
+	if want := path.Join(hash[:2], hash) + CompressedSuffix; storagePath != want {
+		t.Fatalf("storage path = %q, want %q", storagePath, want)
+	}
+	info, err := os.Stat(filepath.Join(tmp, filepath.FromSlash(storagePath)))
+	if err != nil {
+		t.Fatalf("stat stored file: %v", err)
+	}
+	if storedSize != info.Size() || storedSize >= int64(len(content)) {
+		t.Errorf("stored size = %d (file %d), want the compressed size below %d", storedSize, info.Size(), len(content))
+	}
+
+	// Storing the same content again reuses the compressed file.
+	again, _, err := StoreAttachmentFile(tmp, &mime.Attachment{Filename: "copy.txt", ContentType: "text/plain", Content: content})
+	if err != nil || again != storagePath {
+		t.Fatalf("second store = %q, %v; want %q", again, err, storagePath)
+	}
+
+	f, err := OpenAttachment(tmp, hash)
+	if err != nil {
+		t.Fatalf("OpenAttachment: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	got, err := io.ReadAll(f)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("OpenAttachment read %d bytes, %v; want the original %d", len(got), err, len(content))
+	}
+
+	// Exports write the original content.
+	out := t.TempDir()
+	res := AttachmentsToDir(out, tmp, []query.AttachmentInfo{{Filename: "report.txt", ContentHash: hash}})
+	if len(res.Errors) > 0 || len(res.Files) != 1 || res.Files[0].Size != int64(len(content)) {
+		t.Fatalf("AttachmentsToDir = %+v, want one file of %d bytes", res, len(content))
+	}
+
+	// A copy into another vault keeps the file compressed.
+	dst := t.TempDir()
+	copied, err := CopyAttachmentFile(tmp, dst, storagePath)
+	if err != nil || !copied {
+		t.Fatalf("CopyAttachmentFile = %v, %v; want copied", copied, err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(storagePath))); err != nil {
+		t.Errorf("copied file missing: %v", err)
+	}
+}
+
+func TestStoreAttachmentFile_LeavesIncompressibleAlone(t *testing.T) {
+	tmp := t.TempDir()
+	content := []byte(strings.Repeat("x", 8<<10))
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	storagePath, storedSize, err := StoreAttachmentFile(tmp, &mime.Attachment{
+		Filename: "photo.jpg", ContentType: "image/jpeg", Content: content,
+	})
+	if err != nil {
+		t.Fatalf("StoreAttachmentFile: %v", err)
+	}
+	if storagePath != path.Join(hash[:2], hash) || storedSize != int64(len(content)) {
+		t.Errorf("stored %q in %d bytes, want it uncompressed", storagePath, storedSize)
+	}
+}
+
+func TestOpenAttachmentPath_SeeksCompressed(t *testing.T) {
+	tmp := t.TempDir()
+	var b strings.Builder
+	for i := 0; b.Len() < 1<<20; i++ {
+		fmt.Fprintf(&b, "line %d of the quarterly report\n", i)
+	}
+	content := []byte(b.String())
+	storagePath, _, err := StoreAttachmentFile(tmp, &mime.Attachment{Filename: "report.txt", ContentType: "text/plain", Content: content})
+	if err != nil || !IsCompressedPath(storagePath) {
+		t.Fatalf("StoreAttachmentFile = %q, %v; want a compressed file", storagePath, err)
+	}
+
+	f, err := OpenAttachmentPath(filepath.Join(tmp, filepath.FromSlash(storagePath)))
+	if err != nil {
+		t.Fatalf("OpenAttachmentPath: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if size, err := f.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+		t.Fatalf("Seek(0, SeekEnd) = %d, %v; want %d", size, err, len(content))
+	}
+	readAt := func(off int64, whence int, n int) {
+		t.Helper()
+		pos, err := f.Seek(off, whence)
+		if err != nil {
+			t.Fatalf("Seek(%d, %d): %v", off, whence, err)
+		}
+		got := make([]byte, n)
+		if _, err := io.ReadFull(f, got); err != nil {
+			t.Fatalf("read at %d: %v", pos, err)
+		}
+		if want := content[pos : pos+int64(n)]; !bytes.Equal(got, want) {
+			t.Fatalf("read at %d = %q, want %q", pos, got, want)
+		}
+	}
+	readAt(700_000, io.SeekStart, 64)
+	readAt(100, io.SeekCurrent, 64)
+	readAt(5, io.SeekStart, 64) // back: decompresses again
+	readAt(-64, io.SeekEnd, 64)
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek(0, SeekStart): %v", err)
+	}
+	if got, err := io.ReadAll(f); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("ReadAll after rewind = %d bytes, %v; want %d", len(got), err, len(content))
+	}
+}
+
+func TestOpenAttachmentPath_SeekEndDoesNotDecompress(t *testing.T) {
+	tmp := t.TempDir()
+	content := bytes.Repeat([]byte("the quarterly report\n"), 50_000)
+	storagePath, _, err := StoreAttachmentFile(tmp, &mime.Attachment{Filename: "report.txt", ContentType: "text/plain", Content: content})
+	if err != nil || !IsCompressedPath(storagePath) {
+		t.Fatalf("StoreAttachmentFile = %q, %v; want a compressed file", storagePath, err)
+	}
+
+	f, err := OpenAttachmentPath(filepath.Join(tmp, filepath.FromSlash(storagePath)))
+	if err != nil {
+		t.Fatalf("OpenAttachmentPath: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	z := f.(*zstdFile)
+
+	// Measuring the size, as http.ServeContent does, must not
+	// decompress anything.
+	if size, err := f.Seek(0, io.SeekEnd); err != nil || size != int64(len(content)) {
+		t.Fatalf("Seek(0, SeekEnd) = %d, %v; want %d", size, err, len(content))
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Seek(0, SeekStart): %v", err)
+	}
+	if z.dpos != 0 {
+		t.Fatalf("decompressed %d bytes while seeking, want 0", z.dpos)
+	}
+
+	if got, err := io.ReadAll(f); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("ReadAll = %d bytes, %v; want %d", len(got), err, len(content))
+	}
+	if z.dpos != int64(len(content)) {
+		t.Fatalf("decompressed %d bytes reading once, want %d", z.dpos, len(content))
+	}
+}
@@ -8,11 +8,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"sync"
-	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/wesm/msgvault/internal/fileutil"
 	"github.com/wesm/msgvault/internal/mime"
 )
@@ -84,7 +83,8 @@ func ensureSubdirSafe(baseDir, hashPrefix string) error {
 
 // writeAtomicFile writes data to a temp file alongside fullPath and renames
 // it into place. On rename conflict (concurrent writer), validates the
-// existing file instead.
+// existing file instead. expectedSize is the size of the content, which
+// is larger than data when fullPath is compressed.
 func writeAtomicFile(fullPath string, data []byte, expectedSize int64, expectedHash string) error {
 	dir := filepath.Dir(fullPath)
 	base := filepath.Base(fullPath)
@@ -128,19 +128,21 @@ func writeAtomicFile(fullPath string, data []byte, expectedSize int64, expectedH
 }
 
 // StoreAttachmentFile stores att.Content on disk under attachmentsDir using
-// content-addressed storage (hash[:2]/hash). It validates existing files when
-// de-duping. If attachmentsDir is a symlink, it is resolved before writing.
+// content-addressed storage (hash[:2]/hash). Compressible attachments are
+// stored zstd-compressed as hash[:2]/hash.zst when that saves space. It
+// validates existing files, compressed or not, when de-duping. If
+// attachmentsDir is a symlink, it is resolved before writing.
 //
-// Returns the storage path relative to attachmentsDir (e.g. "ab/<hash>"), or
-// empty string if nothing was stored.
-func StoreAttachmentFile(attachmentsDir string, att *mime.Attachment) (string, error) {
+// Returns the storage path relative to attachmentsDir (e.g. "ab/<hash>")
+// and the size of the file on disk, or an empty path if nothing was stored.
+func StoreAttachmentFile(attachmentsDir string, att *mime.Attachment) (string, int64, error) {
 	if attachmentsDir == "" || len(att.Content) == 0 {
-		return "", nil
+		return "", 0, nil
 	}
 
 	contentHash, err := resolveContentHash(att.Content, att.ContentHash)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 	att.ContentHash = contentHash
 
@@ -149,46 +151,47 @@ func StoreAttachmentFile(attachmentsDir string, att *mime.Attachment) (string, e
 
 	baseDir, err := prepareStorageDir(attachmentsDir)
 	if err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	if err := ensureSubdirSafe(baseDir, hashPrefix); err != nil {
-		return "", err
+		return "", 0, err
 	}
 
 	fullPath := filepath.Join(baseDir, hashPrefix, contentHash)
 	expectedSize := int64(len(att.Content))
 
-	if _, err := os.Lstat(fullPath); err == nil {
-		if err := validateExistingAttachmentFile(fullPath, expectedSize, contentHash); err != nil {
-			return "", err
+	for _, suffix := range []string{"", CompressedSuffix} {
+		if st, err := os.Lstat(fullPath + suffix); err == nil {
+			if err := validateExistingAttachmentFile(fullPath+suffix, expectedSize, contentHash); err != nil {
+				return "", 0, err
+			}
+			return storagePath + suffix, st.Size(), nil
+		} else if !os.IsNotExist(err) {
+			return "", 0, fmt.Errorf("lstat attachment file: %w", err)
 		}
-		return storagePath, nil
-	} else if !os.IsNotExist(err) {
-		return "", fmt.Errorf("lstat attachment file: %w", err)
 	}
 
-	if err := writeAtomicFile(fullPath, att.Content, expectedSize, contentHash); err != nil {
-		return "", err
+	data := att.Content
+	if Compressible(att.ContentType, att.Filename) {
+		if z, ok := compressContent(att.Content); ok {
+			data = z
+			fullPath += CompressedSuffix
+			storagePath += CompressedSuffix
+		}
 	}
-	return storagePath, nil
+	if err := writeAtomicFile(fullPath, data, expectedSize, contentHash); err != nil {
+		return "", 0, err
+	}
+	return storagePath, int64(len(data)), nil
 }
 
 func validateExistingAttachmentFile(fullPath string, expectedSize int64, expectedHash string) error {
-	var f *os.File
-	var err error
-	const maxRetries = 5
-	for attempt := range maxRetries {
-		f, err = openNoFollow(fullPath)
-		if err == nil {
-			break
-		}
-		if runtime.GOOS != "windows" || attempt == maxRetries-1 {
-			return fmt.Errorf(
-				"open attachment file for validation: %w", err,
-			)
-		}
-		time.Sleep(time.Duration(attempt+1) * 10 * time.Millisecond)
+	f, err := fileutil.RetryOpen(func() (*os.File, error) {
+		return openNoFollow(fullPath)
+	})
+	if err != nil {
+		return fmt.Errorf("open attachment file for validation: %w", err)
 	}
 	defer func() { _ = f.Close() }()
 
@@ -199,7 +202,8 @@ func validateExistingAttachmentFile(fullPath string, expectedSize int64, expecte
 	if !st.Mode().IsRegular() {
 		return fmt.Errorf("attachment file %q is not a regular file", fullPath)
 	}
-	if st.Size() != expectedSize {
+	compressed := IsCompressedPath(fullPath)
+	if !compressed && st.Size() != expectedSize {
 		return fmt.Errorf("attachment file %q has size %d, want %d", fullPath, st.Size(), expectedSize)
 	}
 
@@ -211,10 +215,23 @@ func validateExistingAttachmentFile(fullPath string, expectedSize int64, expecte
 		}
 	}
 
+	var r io.Reader = f
+	if compressed {
+		dec, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return fmt.Errorf("decompress attachment file: %w", err)
+		}
+		defer dec.Close()
+		r = dec
+	}
 	h := sha256.New()
-	if _, err := io.Copy(h, f); err != nil {
+	n, err := io.Copy(h, r)
+	if err != nil {
 		return fmt.Errorf("hash attachment file: %w", err)
 	}
+	if n != expectedSize {
+		return fmt.Errorf("attachment file %q has size %d, want %d", fullPath, n, expectedSize)
+	}
 	gotHash := hex.EncodeToString(h.Sum(nil))
 	if gotHash != expectedHash {
 		return fmt.Errorf("attachment file %q has hash %q, want %q", fullPath, gotHash, expectedHash)
@@ -222,3 +239,61 @@ func validateExistingAttachmentFile(fullPath string, expectedSize int64, expecte
 	validatedAttachmentFiles.Store(key, modTime)
 	return nil
 }
+
+// CopyAttachmentFile copies a content-addressed attachment from another
+// vault's attachments directory into dstDir, preserving its storage path
+// ("ab/<hash>", or "ab/<hash>.zst" when compressed). Returns false without
+// error when dstDir already holds a valid copy. The source file is verified
+// against its hash before it is installed, so a corrupt file in the other
+// vault is never propagated.
+func CopyAttachmentFile(srcDir, dstDir, storagePath string) (bool, error) {
+	suffix := ""
+	if IsCompressedPath(storagePath) {
+		suffix = CompressedSuffix
+	}
+	hash := strings.TrimSuffix(path.Base(storagePath), suffix)
+	if err := ValidateContentHash(hash); err != nil {
+		return false, fmt.Errorf("invalid storage path %q: %w", storagePath, err)
+	}
+	if storagePath != path.Join(hash[:2], hash)+suffix {
+		return false, fmt.Errorf("invalid storage path %q: want %s/<hash>", storagePath, hash[:2])
+	}
+
+	srcPath := filepath.Join(srcDir, hash[:2], hash+suffix)
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return false, fmt.Errorf("read source attachment: %w", err)
+	}
+	content := data
+	if suffix != "" {
+		if content, err = zstdDecoder().DecodeAll(data, nil); err != nil {
+			return false, fmt.Errorf("source attachment %s: decompress: %w", srcPath, err)
+		}
+	}
+	if _, err := resolveContentHash(content, hash); err != nil {
+		return false, fmt.Errorf("source attachment %s: %w", srcPath, err)
+	}
+
+	baseDir, err := prepareStorageDir(dstDir)
+	if err != nil {
+		return false, err
+	}
+	if err := ensureSubdirSafe(baseDir, hash[:2]); err != nil {
+		return false, err
+	}
+	fullPath := filepath.Join(baseDir, hash[:2], hash+suffix)
+
+	if _, err := os.Lstat(fullPath); err == nil {
+		if err := validateExistingAttachmentFile(fullPath, int64(len(content)), hash); err != nil {
+			return false, err
+		}
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, fmt.Errorf("lstat attachment file: %w", err)
+	}
+
+	if err := writeAtomicFile(fullPath, data, int64(len(content)), hash); err != nil {
+		return false, err
+	}
+	return true, nil
+}
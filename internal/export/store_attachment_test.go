@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -38,7 +39,7 @@ func TestStoreAttachmentFile_ExistingFileHashMismatch_ReturnsError(t *testing.T)
 		ContentHash: hash,
 		Content:     content,
 	}
-	_, err := StoreAttachmentFile(tmp, att)
+	_, _, err := StoreAttachmentFile(tmp, att)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -64,7 +65,7 @@ func TestStoreAttachmentFile_ProvidedContentHashMismatch_ReturnsError(t *testing
 		ContentHash: badHash,
 		Content:     content,
 	}
-	_, err := StoreAttachmentFile(tmp, att)
+	_, _, err := StoreAttachmentFile(tmp, att)
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -95,7 +96,7 @@ func TestStoreAttachmentFile_ProvidedContentHashUppercase_AcceptedAndCanonicaliz
 		ContentHash: upper,
 		Content:     content,
 	}
-	gotStoragePath, err := StoreAttachmentFile(tmp, att)
+	gotStoragePath, _, err := StoreAttachmentFile(tmp, att)
 	if err != nil {
 		t.Fatalf("StoreAttachmentFile: %v", err)
 	}
@@ -119,6 +120,36 @@ func TestStoreAttachmentFile_ConcurrentWriters_SameHash_NoError(t *testing.T) {
 	sum := sha256.Sum256(content)
 	hash := hex.EncodeToString(sum[:])
 
+	// Text compresses, so every writer races to store hash.zst.
+	wantStoragePath := path.Join(hash[:2], hash) + CompressedSuffix
+	for _, got := range storeConcurrently(t, tmp, "a.txt", "text/plain", content, hash) {
+		if got != wantStoragePath {
+			t.Fatalf("storage path mismatch: got %q, want %q", got, wantStoragePath)
+		}
+	}
+	assertStoredContent(t, filepath.Join(tmp, filepath.FromSlash(wantStoragePath)), hash)
+}
+
+func TestStoreAttachmentFile_ConcurrentWriters_SameHash_Uncompressed(t *testing.T) {
+	tmp := t.TempDir()
+
+	content := bytes.Repeat([]byte("a"), 1<<20) // 1 MiB
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	wantStoragePath := path.Join(hash[:2], hash)
+	for _, got := range storeConcurrently(t, tmp, "a.bin", "application/octet-stream", content, hash) {
+		if got != wantStoragePath {
+			t.Fatalf("storage path mismatch: got %q, want %q", got, wantStoragePath)
+		}
+	}
+	assertStoredContent(t, filepath.Join(tmp, hash[:2], hash), hash)
+}
+
+// storeConcurrently stores content from several goroutines at once and
+// returns the storage path each got.
+func storeConcurrently(t *testing.T, dir, filename, contentType string, content []byte, hash string) []string {
+	t.Helper()
 	const n = 8
 	start := make(chan struct{})
 	errCh := make(chan error, n)
@@ -132,13 +163,13 @@ func TestStoreAttachmentFile_ConcurrentWriters_SameHash_NoError(t *testing.T) {
 			<-start
 
 			att := &mime.Attachment{
-				Filename:    "a.txt",
-				ContentType: "text/plain",
+				Filename:    filename,
+				ContentType: contentType,
 				Size:        len(content),
 				ContentHash: hash,
 				Content:     content,
 			}
-			p, err := StoreAttachmentFile(tmp, att)
+			p, _, err := StoreAttachmentFile(dir, att)
 			errCh <- err
 			if err == nil {
 				pathCh <- p
@@ -154,16 +185,23 @@ func TestStoreAttachmentFile_ConcurrentWriters_SameHash_NoError(t *testing.T) {
 			t.Fatalf("store: %v", err)
 		}
 	}
-
-	wantStoragePath := path.Join(hash[:2], hash)
-	for i := 0; i < n; i++ {
-		if got := <-pathCh; got != wantStoragePath {
-			t.Fatalf("storage path mismatch: got %q, want %q", got, wantStoragePath)
-		}
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = <-pathCh
 	}
+	return paths
+}
 
-	fullPath := filepath.Join(tmp, hash[:2], hash)
-	b, err := os.ReadFile(fullPath)
+// assertStoredContent checks that the attachment file at fullPath,
+// compressed or not, holds content hashing to hash.
+func assertStoredContent(t *testing.T, fullPath, hash string) {
+	t.Helper()
+	f, err := OpenAttachmentPath(fullPath)
+	if err != nil {
+		t.Fatalf("open stored file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	b, err := io.ReadAll(f)
 	if err != nil {
 		t.Fatalf("read stored file: %v", err)
 	}
@@ -0,0 +1,16 @@
+package fileutil
+
+import "testing"
+
+func TestDiskFree(t *testing.T) {
+	free, err := DiskFree(t.TempDir())
+	if err != nil {
+		t.Fatalf("DiskFree: %v", err)
+	}
+	if free == 0 {
+		t.Error("DiskFree reported 0 bytes for a writable temp dir")
+	}
+	if _, err := DiskFree(t.TempDir() + "/missing"); err == nil {
+		t.Error("DiskFree on missing path: expected error")
+	}
+}
@@ -0,0 +1,70 @@
+package fileutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExposedTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Windows reports 0666 for any writable file, so mode bits say
+	// nothing about who can read it there.
+	if got := ExposedTo(info, 0o044); got != ModeBitsEnforced {
+		t.Errorf("ExposedTo(0644, 0o044) = %v, want %v", got, ModeBitsEnforced)
+	}
+	if ExposedTo(info, 0o022) {
+		t.Error("ExposedTo(0644, 0o022) = true, want false")
+	}
+}
+
+func TestExecutableName(t *testing.T) {
+	got := ExecutableName("msgvault")
+	if runtime.GOOS == "windows" {
+		if got != "msgvault.exe" || ExecutableName("msgvault.EXE") != "msgvault.EXE" {
+			t.Errorf("ExecutableName() = %q", got)
+		}
+		return
+	}
+	if got != "msgvault" {
+		t.Errorf("ExecutableName() = %q, want msgvault", got)
+	}
+}
+
+func TestRetryOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	f, err := RetryOpen(func() (*os.File, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("sharing violation")
+		}
+		return os.Open(path)
+	})
+	if runtime.GOOS == "windows" {
+		if err != nil || calls != 2 {
+			t.Fatalf("RetryOpen() err = %v after %d calls, want success on retry", err, calls)
+		}
+		_ = f.Close()
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), "sharing violation") || calls != 1 {
+		t.Errorf("RetryOpen() err = %v after %d calls, want first error without retry", err, calls)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package fileutil
+
+import "os"
+
+// ModeBitsEnforced reports whether Unix permission bits control who
+// can read a file on this platform.
+const ModeBitsEnforced = true
+
+// ExposedTo reports whether info's permission bits grant any of mask.
+// Callers use it to refuse or warn about secrets readable by others.
+func ExposedTo(info os.FileInfo, mask os.FileMode) bool {
+	return info.Mode().Perm()&mask != 0
+}
+
+// ExecutableName returns the file name of an executable called name.
+func ExecutableName(name string) string {
+	return name
+}
+
+// SyncDir flushes the entries of directory dir to disk, so a file
+// renamed into it survives a crash.
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
+
+// RetryOpen opens a file with open. Unix has no mandatory sharing
+// locks, so a failure is returned at once.
+func RetryOpen(open func() (*os.File, error)) (*os.File, error) {
+	return open()
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+package fileutil
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// ModeBitsEnforced reports whether Unix permission bits control who
+// can read a file on this platform. Windows uses ACLs (see
+// SecureWriteFile), and os.Stat reports 0666 for every writable file.
+const ModeBitsEnforced = false
+
+// ExposedTo always reports false on Windows: mode bits do not reflect
+// the file's ACL.
+func ExposedTo(info os.FileInfo, mask os.FileMode) bool {
+	return false
+}
+
+// ExecutableName returns the file name of an executable called name.
+func ExecutableName(name string) string {
+	if strings.HasSuffix(strings.ToLower(name), ".exe") {
+		return name
+	}
+	return name + ".exe"
+}
+
+const retryAttempts = 5
+
+var retryDelay = 10 * time.Millisecond
+
+// SyncDir does nothing: Windows cannot flush a directory, and NTFS
+// journals renames itself.
+func SyncDir(dir string) error {
+	return nil
+}
+
+// RetryOpen opens a file with open, retrying briefly on failure.
+// Virus scanners and the search indexer open freshly written files
+// without FILE_SHARE_DELETE, so opening them can fail for a few
+// milliseconds after they are created.
+func RetryOpen(open func() (*os.File, error)) (*os.File, error) {
+	var f *os.File
+	var err error
+	for attempt := range retryAttempts {
+		if f, err = open(); err == nil || attempt == retryAttempts-1 {
+			break
+		}
+		time.Sleep(time.Duration(attempt+1) * retryDelay)
+	}
+	return f, err
+}
@@ -1,7 +1,10 @@
 // Package gmail provides a Gmail API client with rate limiting and retry logic.
 package gmail
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // AccountReader provides read access to account-level Gmail data.
 type AccountReader interface {
@@ -41,6 +44,66 @@ type MessageDeleter interface {
 	BatchDeleteMessages(ctx context.Context, messageIDs []string) error
 }
 
+// LabelModifier provides write operations for Gmail labels. It is kept
+// out of API, which sync and deletion use, as only label push-back
+// needs it.
+type LabelModifier interface {
+	// CreateLabel creates a user label and returns it.
+	CreateLabel(ctx context.Context, name string) (*Label, error)
+
+	// ModifyMessage adds and removes labels on a message.
+	ModifyMessage(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error
+}
+
+// MetadataReader fetches messages without their bodies. Like
+// LabelModifier it is kept out of API, as only metadata-only sync
+// needs it.
+type MetadataReader interface {
+	// GetMessageMetadata fetches a message's headers, labels and size.
+	// The RawMessage is MetadataOnly.
+	GetMessageMetadata(ctx context.Context, messageID string) (*RawMessage, error)
+
+	// GetMessagesMetadataBatch is GetMessagesRawBatch for metadata.
+	GetMessagesMetadataBatch(ctx context.Context, messageIDs []string) ([]*RawMessage, error)
+}
+
+// Watcher registers push notifications of mailbox changes. Like
+// LabelModifier it is kept out of API, as only watch mode needs it.
+type Watcher interface {
+	// Watch asks Gmail to publish a notification to a Cloud Pub/Sub
+	// topic whenever the mailbox changes, for the given labels or for
+	// every label when labelIDs is empty. The watch must be renewed
+	// before it expires.
+	Watch(ctx context.Context, topic string, labelIDs []string) (*WatchResponse, error)
+
+	// StopWatch stops the mailbox's push notifications.
+	StopWatch(ctx context.Context) error
+}
+
+// Sender sends mail from the account. Like LabelModifier it is kept
+// out of API, as only 'msgvault share' needs it.
+type Sender interface {
+	// SendMessage sends a raw RFC 5322 message and returns the ID Gmail
+	// gives the sent copy.
+	SendMessage(ctx context.Context, raw []byte) (string, error)
+}
+
+// StorageQuota is the Google account's storage quota, which Gmail,
+// Drive and Photos share, in bytes.
+type StorageQuota struct {
+	Limit             int64 // 0 for unlimited
+	Usage             int64 // across all services
+	UsageInDrive      int64
+	UsageInDriveTrash int64
+}
+
+// QuotaReader reads the account's storage quota from the Drive API,
+// which needs the oauth.ScopeStorageQuota scope. Like LabelModifier it
+// is kept out of API, as only syncs tracking the quota need it.
+type QuotaReader interface {
+	GetStorageQuota(ctx context.Context) (*StorageQuota, error)
+}
+
 // API defines the interface for Gmail operations.
 // This interface enables mocking for tests without hitting the real API.
 type API interface {
@@ -60,6 +123,12 @@ type Profile struct {
 	HistoryID     uint64
 }
 
+// WatchResponse describes a registered watch.
+type WatchResponse struct {
+	HistoryID  uint64    // the mailbox's history ID when the watch started
+	Expiration time.Time // when Gmail stops sending notifications
+}
+
 // Label represents a Gmail label.
 type Label struct {
 	ID                    string
@@ -69,6 +138,11 @@ type Label struct {
 	MessagesUnread        int64
 	MessageListVisibility string
 	LabelListVisibility   string
+
+	// TextColor and BackgroundColor are the label's colors as hex
+	// strings ("#ffffff"); empty when the label has no color.
+	TextColor       string
+	BackgroundColor string
 }
 
 // MessageListResponse contains a page of message IDs.
@@ -82,6 +156,9 @@ type MessageListResponse struct {
 type MessageID struct {
 	ID       string
 	ThreadID string
+	// LabelIDs are the message's labels where the listing reports
+	// them, as Gmail history records do; nil when unknown.
+	LabelIDs []string
 }
 
 // RawMessage contains the raw MIME data for a message.
@@ -94,6 +171,10 @@ type RawMessage struct {
 	InternalDate int64 // Unix milliseconds
 	SizeEstimate int64
 	Raw          []byte // Decoded from base64url
+
+	// MetadataOnly means the message was fetched without its body: Raw
+	// holds its headers alone, as a MIME message with an empty body.
+	MetadataOnly bool
 }
 
 // HistoryResponse contains changes since a history ID.
@@ -22,6 +22,7 @@ import (
 
 const (
 	baseURL        = "https://gmail.googleapis.com/gmail/v1"
+	driveAboutURL  = "https://www.googleapis.com/drive/v3/about?fields=storageQuota"
 	maxRetries     = 12  // Covers ~10 minutes of network outages
 	maxBackoff     = 600 // Max backoff in seconds
 	defaultTimeout = 30 * time.Second
@@ -97,6 +98,9 @@ func (c *Client) request(ctx context.Context, op Operation, method, path string,
 	}
 
 	reqURL := baseURL + path
+	if strings.HasPrefix(path, "https://") {
+		reqURL = path // another Google API
+	}
 
 	var lastErr error
 	for attempt := 0; attempt <= maxRetries; attempt++ {
@@ -227,6 +231,13 @@ type gmailLabel struct {
 	MessagesUnread        int64  `json:"messagesUnread"`
 	MessageListVisibility string `json:"messageListVisibility"`
 	LabelListVisibility   string `json:"labelListVisibility"`
+
+	Color *gmailLabelColor `json:"color,omitempty"`
+}
+
+type gmailLabelColor struct {
+	TextColor       string `json:"textColor"`
+	BackgroundColor string `json:"backgroundColor"`
 }
 
 type listLabelsResponse struct {
@@ -234,8 +245,9 @@ type listLabelsResponse struct {
 }
 
 type gmailMessageRef struct {
-	ID       string `json:"id"`
-	ThreadID string `json:"threadId"`
+	ID       string   `json:"id"`
+	ThreadID string   `json:"threadId"`
+	LabelIDs []string `json:"labelIds"`
 }
 
 type listMessagesResponse struct {
@@ -255,6 +267,43 @@ type rawMessageResponse struct {
 	Raw          string   `json:"raw"` // base64url encoded (unpadded)
 }
 
+// metadataMessageResponse is a message fetched with format=metadata.
+type metadataMessageResponse struct {
+	ID           string   `json:"id"`
+	ThreadID     string   `json:"threadId"`
+	LabelIDs     []string `json:"labelIds"`
+	Snippet      string   `json:"snippet"`
+	HistoryID    string   `json:"historyId"`
+	InternalDate string   `json:"internalDate"`
+	SizeEstimate int64    `json:"sizeEstimate"`
+	Payload      struct {
+		Headers []messageHeader `json:"headers"`
+	} `json:"payload"`
+}
+
+// messageHeader is one header of a message's payload.
+type messageHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// headerOnlyMIME returns headers as a MIME message with an empty plain
+// text body. The message's own MIME structure headers are dropped, as
+// they describe a body it no longer has.
+func headerOnlyMIME(headers []messageHeader) []byte {
+	var b bytes.Buffer
+	for _, h := range headers {
+		name := strings.ToLower(h.Name)
+		if strings.HasPrefix(name, "content-") || name == "mime-version" {
+			continue
+		}
+		value := strings.NewReplacer("\r", " ", "\n", " ").Replace(h.Value)
+		fmt.Fprintf(&b, "%s: %s\r\n", h.Name, value)
+	}
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	return b.Bytes()
+}
+
 // decodeBase64URL decodes a base64url-encoded string, tolerating optional padding.
 // Gmail typically returns unpadded base64url, but this function handles both cases.
 // If padding is present, it validates that padding is correct (rejects malformed padding).
@@ -337,6 +386,10 @@ func (c *Client) ListLabels(ctx context.Context) ([]*Label, error) {
 			MessageListVisibility: l.MessageListVisibility,
 			LabelListVisibility:   l.LabelListVisibility,
 		}
+		if l.Color != nil {
+			labels[i].TextColor = l.Color.TextColor
+			labels[i].BackgroundColor = l.Color.BackgroundColor
+		}
 	}
 	return labels, nil
 }
@@ -422,6 +475,48 @@ func isRateLimitError(body []byte) bool {
 
 // GetMessagesRawBatch fetches multiple messages in parallel with rate limiting.
 func (c *Client) GetMessagesRawBatch(ctx context.Context, messageIDs []string) ([]*RawMessage, error) {
+	return c.getMessagesBatch(ctx, messageIDs, c.GetMessageRaw)
+}
+
+// GetMessageMetadata fetches a message's headers, labels and size
+// without its body.
+func (c *Client) GetMessageMetadata(ctx context.Context, messageID string) (*RawMessage, error) {
+	path := fmt.Sprintf("/users/%s/messages/%s?format=metadata", c.userID, messageID)
+	data, err := c.request(ctx, OpMessagesGet, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp metadataMessageResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse message metadata: %w", err)
+	}
+
+	historyID, _ := strconv.ParseUint(resp.HistoryID, 10, 64)
+	internalDate, _ := strconv.ParseInt(resp.InternalDate, 10, 64)
+
+	return &RawMessage{
+		ID:           resp.ID,
+		ThreadID:     resp.ThreadID,
+		LabelIDs:     resp.LabelIDs,
+		Snippet:      resp.Snippet,
+		HistoryID:    historyID,
+		InternalDate: internalDate,
+		SizeEstimate: resp.SizeEstimate,
+		Raw:          headerOnlyMIME(resp.Payload.Headers),
+		MetadataOnly: true,
+	}, nil
+}
+
+// GetMessagesMetadataBatch fetches the metadata of multiple messages in
+// parallel with rate limiting.
+func (c *Client) GetMessagesMetadataBatch(ctx context.Context, messageIDs []string) ([]*RawMessage, error) {
+	return c.getMessagesBatch(ctx, messageIDs, c.GetMessageMetadata)
+}
+
+// getMessagesBatch fetches messages in parallel with get. Failed
+// fetches leave nil entries.
+func (c *Client) getMessagesBatch(ctx context.Context, messageIDs []string, get func(context.Context, string) (*RawMessage, error)) ([]*RawMessage, error) {
 	if len(messageIDs) == 0 {
 		return nil, nil
 	}
@@ -443,7 +538,7 @@ func (c *Client) GetMessagesRawBatch(ctx context.Context, messageIDs []string) (
 				return ctx.Err()
 			}
 
-			msg, err := c.GetMessageRaw(ctx, id)
+			msg, err := get(ctx, id)
 			if err != nil {
 				// Log but don't fail the batch - allow partial results.
 				// 404s are expected (message deleted between history scan and fetch),
@@ -575,5 +670,160 @@ func (c *Client) BatchDeleteMessages(ctx context.Context, messageIDs []string) e
 	return err
 }
 
+// CreateLabel creates a user label, shown in the label list and on
+// messages.
+func (c *Client) CreateLabel(ctx context.Context, name string) (*Label, error) {
+	bodyBytes, err := json.Marshal(gmailLabel{
+		Name:                  name,
+		MessageListVisibility: "show",
+		LabelListVisibility:   "labelShow",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal body: %w", err)
+	}
+
+	path := fmt.Sprintf("/users/%s/labels", c.userID)
+	data, err := c.request(ctx, OpLabelsCreate, "POST", path, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var l gmailLabel
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("parse label: %w", err)
+	}
+	return &Label{
+		ID:                    l.ID,
+		Name:                  l.Name,
+		Type:                  l.Type,
+		MessageListVisibility: l.MessageListVisibility,
+		LabelListVisibility:   l.LabelListVisibility,
+	}, nil
+}
+
+// ModifyMessage adds and removes labels on a message.
+func (c *Client) ModifyMessage(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error {
+	body := struct {
+		AddLabelIDs    []string `json:"addLabelIds,omitempty"`
+		RemoveLabelIDs []string `json:"removeLabelIds,omitempty"`
+	}{AddLabelIDs: addLabelIDs, RemoveLabelIDs: removeLabelIDs}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal body: %w", err)
+	}
+
+	path := fmt.Sprintf("/users/%s/messages/%s/modify", c.userID, messageID)
+	_, err = c.request(ctx, OpMessagesModify, "POST", path, bodyBytes)
+	return err
+}
+
+// SendMessage sends a raw RFC 5322 message from the account.
+func (c *Client) SendMessage(ctx context.Context, raw []byte) (string, error) {
+	bodyBytes, err := json.Marshal(struct {
+		Raw string `json:"raw"`
+	}{Raw: base64.URLEncoding.EncodeToString(raw)})
+	if err != nil {
+		return "", fmt.Errorf("marshal body: %w", err)
+	}
+
+	path := fmt.Sprintf("/users/%s/messages/send", c.userID)
+	data, err := c.request(ctx, OpMessagesSend, "POST", path, bodyBytes)
+	if err != nil {
+		return "", err
+	}
+
+	var sent struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return "", fmt.Errorf("parse sent message: %w", err)
+	}
+	return sent.ID, nil
+}
+
+// GetStorageQuota reads the account's storage quota from the Drive API.
+func (c *Client) GetStorageQuota(ctx context.Context) (*StorageQuota, error) {
+	data, err := c.request(ctx, OpStorageQuota, "GET", driveAboutURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The Drive API sends int64 values as strings.
+	var resp struct {
+		StorageQuota struct {
+			Limit             int64 `json:"limit,string"`
+			Usage             int64 `json:"usage,string"`
+			UsageInDrive      int64 `json:"usageInDrive,string"`
+			UsageInDriveTrash int64 `json:"usageInDriveTrash,string"`
+		} `json:"storageQuota"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse storage quota: %w", err)
+	}
+	q := resp.StorageQuota
+	return &StorageQuota{
+		Limit:             q.Limit,
+		Usage:             q.Usage,
+		UsageInDrive:      q.UsageInDrive,
+		UsageInDriveTrash: q.UsageInDriveTrash,
+	}, nil
+}
+
 // Ensure Client implements API interface.
+type watchResponse struct {
+	HistoryID  string `json:"historyId"`
+	Expiration string `json:"expiration"` // Unix milliseconds
+}
+
+// Watch registers push notifications of mailbox changes to a Cloud
+// Pub/Sub topic ("projects/<project>/topics/<topic>").
+func (c *Client) Watch(ctx context.Context, topic string, labelIDs []string) (*WatchResponse, error) {
+	body := struct {
+		TopicName           string   `json:"topicName"`
+		LabelIDs            []string `json:"labelIds,omitempty"`
+		LabelFilterBehavior string   `json:"labelFilterBehavior,omitempty"`
+	}{TopicName: topic, LabelIDs: labelIDs}
+	if len(labelIDs) > 0 {
+		body.LabelFilterBehavior = "include"
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal body: %w", err)
+	}
+
+	path := fmt.Sprintf("/users/%s/watch", c.userID)
+	data, err := c.request(ctx, OpWatch, "POST", path, bodyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp watchResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse watch: %w", err)
+	}
+	historyID, _ := strconv.ParseUint(resp.HistoryID, 10, 64)
+	expiration, err := strconv.ParseInt(resp.Expiration, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse watch expiration %q: %w", resp.Expiration, err)
+	}
+	return &WatchResponse{
+		HistoryID:  historyID,
+		Expiration: time.UnixMilli(expiration),
+	}, nil
+}
+
+// StopWatch stops the mailbox's push notifications.
+func (c *Client) StopWatch(ctx context.Context) error {
+	path := fmt.Sprintf("/users/%s/stop", c.userID)
+	_, err := c.request(ctx, OpStop, "POST", path, nil)
+	return err
+}
+
 var _ API = (*Client)(nil)
+var _ LabelModifier = (*Client)(nil)
+var _ Watcher = (*Client)(nil)
+var _ Sender = (*Client)(nil)
+var _ QuotaReader = (*Client)(nil)
+var _ MetadataReader = (*Client)(nil)
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // Gmail API error reason constants for tests.
@@ -420,3 +421,177 @@ func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	req.URL.Host = strings.TrimPrefix(t.base, "http://")
 	return t.wrapped.RoundTrip(req)
 }
+
+func TestWatch(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"historyId":  "12345",
+			"expiration": "1704067200000",
+		})
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		httpClient:  &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		userID:      "me",
+		logger:      slog.Default(),
+		rateLimiter: NewRateLimiter(1000),
+	}
+	resp, err := client.Watch(context.Background(), "projects/p/topics/gmail", []string{"INBOX"})
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+	if !strings.HasSuffix(gotPath, "/users/me/watch") {
+		t.Errorf("path = %q, want .../users/me/watch", gotPath)
+	}
+	if gotBody["topicName"] != "projects/p/topics/gmail" || gotBody["labelFilterBehavior"] != "include" {
+		t.Errorf("body = %v, want the topic and an include filter", gotBody)
+	}
+	if resp.HistoryID != 12345 || !resp.Expiration.Equal(time.UnixMilli(1704067200000)) {
+		t.Errorf("Watch() = %+v, want history 12345 expiring at 1704067200000 ms", resp)
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	var gotPath string
+	var gotBody map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		_, _ = w.Write([]byte(`{"id": "18c0ffee", "threadId": "18c0ffee"}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		httpClient:  &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		userID:      "me",
+		logger:      slog.Default(),
+		rateLimiter: NewRateLimiter(1000),
+	}
+	raw := []byte("From: alice@example.com\r\nTo: bob@example.com\r\nSubject: Receipts?\r\n\r\nAttached.\r\n")
+	id, err := client.SendMessage(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("SendMessage() error = %v", err)
+	}
+	if id != "18c0ffee" {
+		t.Errorf("SendMessage() = %q, want 18c0ffee", id)
+	}
+	if !strings.HasSuffix(gotPath, "/users/me/messages/send") {
+		t.Errorf("path = %q, want .../users/me/messages/send", gotPath)
+	}
+	if got, err := base64.URLEncoding.DecodeString(gotBody["raw"]); err != nil || string(got) != string(raw) {
+		t.Errorf("raw = %q (%v), want the message base64url-encoded", got, err)
+	}
+}
+
+func TestGetStorageQuota(t *testing.T) {
+	var gotURL string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		_, _ = w.Write([]byte(`{"storageQuota": {"limit": "16106127360", "usage": "9663676416",
+			"usageInDrive": "2147483648", "usageInDriveTrash": "1048576"}}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		httpClient:  &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		userID:      "me",
+		logger:      slog.Default(),
+		rateLimiter: NewRateLimiter(1000),
+	}
+	q, err := client.GetStorageQuota(context.Background())
+	if err != nil {
+		t.Fatalf("GetStorageQuota() error = %v", err)
+	}
+	want := StorageQuota{Limit: 15 << 30, Usage: 9 << 30, UsageInDrive: 2 << 30, UsageInDriveTrash: 1 << 20}
+	if *q != want {
+		t.Errorf("GetStorageQuota() = %+v, want %+v", *q, want)
+	}
+	if gotURL != "/drive/v3/about?fields=storageQuota" {
+		t.Errorf("URL = %q, want the Drive about endpoint", gotURL)
+	}
+}
+
+func TestListLabels_Colors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"labels": [
+			{"id": "INBOX", "name": "INBOX", "type": "system"},
+			{"id": "Label_1", "name": "Clients/Acme", "type": "user",
+			 "labelListVisibility": "labelShowIfUnread",
+			 "color": {"textColor": "#ffffff", "backgroundColor": "#4a86e8"}}
+		]}`))
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		httpClient:  &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		userID:      "me",
+		logger:      slog.Default(),
+		rateLimiter: NewRateLimiter(1000),
+	}
+	labels, err := client.ListLabels(context.Background())
+	if err != nil {
+		t.Fatalf("ListLabels() error = %v", err)
+	}
+	if len(labels) != 2 {
+		t.Fatalf("ListLabels() returned %d labels, want 2", len(labels))
+	}
+	if labels[0].TextColor != "" || labels[0].BackgroundColor != "" {
+		t.Errorf("INBOX colors = %q/%q, want none", labels[0].TextColor, labels[0].BackgroundColor)
+	}
+	got := labels[1]
+	if got.TextColor != "#ffffff" || got.BackgroundColor != "#4a86e8" || got.LabelListVisibility != "labelShowIfUnread" {
+		t.Errorf("Clients/Acme = %+v, want colors #ffffff/#4a86e8 shown if unread", got)
+	}
+}
+
+func TestGetMessageMetadata(t *testing.T) {
+	var gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":           "msg1",
+			"threadId":     "thread1",
+			"labelIds":     []string{"INBOX"},
+			"internalDate": "1704067200000",
+			"sizeEstimate": 52000,
+			"payload": map[string]any{
+				"headers": []map[string]string{
+					{"name": "From", "value": "Alice <alice@example.com>"},
+					{"name": "Subject", "value": "Quarterly\r\n report"},
+					{"name": "Content-Type", "value": `multipart/mixed; boundary="b1"`},
+					{"name": "MIME-Version", "value": "1.0"},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	client := &Client{
+		httpClient:  &http.Client{Transport: &rewriteTransport{base: srv.URL, wrapped: http.DefaultTransport}},
+		userID:      "me",
+		concurrency: 1,
+		logger:      slog.Default(),
+		rateLimiter: NewRateLimiter(1000),
+	}
+	msg, err := client.GetMessageMetadata(context.Background(), "msg1")
+	if err != nil {
+		t.Fatalf("GetMessageMetadata() error = %v", err)
+	}
+	if gotQuery != "format=metadata" {
+		t.Errorf("query = %q, want format=metadata", gotQuery)
+	}
+	if !msg.MetadataOnly || msg.SizeEstimate != 52000 || msg.ThreadID != "thread1" {
+		t.Errorf("message = %+v, want metadata-only msg1 of 52000 bytes", msg)
+	}
+	want := "From: Alice <alice@example.com>\r\n" +
+		"Subject: Quarterly   report\r\n" +
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n"
+	if string(msg.Raw) != want {
+		t.Errorf("Raw = %q, want %q", msg.Raw, want)
+	}
+}
@@ -1,9 +1,14 @@
 package gmail
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"maps"
+	"net/mail"
+	"slices"
 	"sync"
+	"time"
 )
 
 // MockAPI is a mock implementation of the Gmail API for testing.
@@ -13,6 +18,10 @@ type MockAPI struct {
 	// Profile to return
 	Profile *Profile
 
+	// StorageQuota to return; nil makes GetStorageQuota fail as for a
+	// token without the scope.
+	StorageQuota *StorageQuota
+
 	// Labels to return
 	Labels []*Label
 
@@ -39,17 +48,35 @@ type MockAPI struct {
 	ListMessagesError error
 	GetMessageError   map[string]error // Per-message errors
 	HistoryError      error
+	WatchError        error
+
+	// WatchExpiration is when watches registered with Watch expire;
+	// zero means seven days from the call.
+	WatchExpiration time.Time
 
 	// Call tracking for assertions
 	ProfileCalls      int
 	LabelsCalls       int
 	ListMessagesCalls int
-	LastQuery         string // Last query passed to ListMessages
+	LastQuery         string   // Last query passed to ListMessages
+	ListQueries       []string // Every query passed to ListMessages, in order
 	GetMessageCalls   []string
 	HistoryCalls      []uint64
 	TrashCalls        []string
 	DeleteCalls       []string
 	BatchDeleteCalls  [][]string
+	CreateLabelCalls  []string
+	ModifyCalls       []ModifyCall
+	WatchCalls        []string // topics
+	StopWatchCalls    int
+	SentMessages      [][]byte // raw messages passed to SendMessage
+}
+
+// ModifyCall records one ModifyMessage call.
+type ModifyCall struct {
+	MessageID      string
+	AddLabelIDs    []string
+	RemoveLabelIDs []string
 }
 
 // NewMockAPI creates a new mock API with empty state.
@@ -89,26 +116,33 @@ func (m *MockAPI) ListLabels(ctx context.Context) ([]*Label, error) {
 		return nil, m.LabelsError
 	}
 	if m.Labels == nil {
-		return []*Label{
-			{ID: "INBOX", Name: "INBOX", Type: "system"},
-			{ID: "SENT", Name: "SENT", Type: "system"},
-			{ID: "STARRED", Name: "STARRED", Type: "system"},
-			{ID: "TRASH", Name: "TRASH", Type: "system"},
-			{ID: "UNREAD", Name: "UNREAD", Type: "system"},
-			{ID: "IMPORTANT", Name: "IMPORTANT", Type: "system"},
-			{ID: "SPAM", Name: "SPAM", Type: "system"},
-			{ID: "DRAFT", Name: "DRAFT", Type: "system"},
-		}, nil
+		return defaultMockLabels(), nil
 	}
 	return m.Labels, nil
 }
 
+// defaultMockLabels returns the system labels ListLabels returns when
+// Labels is nil.
+func defaultMockLabels() []*Label {
+	return []*Label{
+		{ID: "INBOX", Name: "INBOX", Type: "system"},
+		{ID: "SENT", Name: "SENT", Type: "system"},
+		{ID: "STARRED", Name: "STARRED", Type: "system"},
+		{ID: "TRASH", Name: "TRASH", Type: "system"},
+		{ID: "UNREAD", Name: "UNREAD", Type: "system"},
+		{ID: "IMPORTANT", Name: "IMPORTANT", Type: "system"},
+		{ID: "SPAM", Name: "SPAM", Type: "system"},
+		{ID: "DRAFT", Name: "DRAFT", Type: "system"},
+	}
+}
+
 // ListMessages returns mock message IDs with pagination.
 func (m *MockAPI) ListMessages(ctx context.Context, query string, pageToken string) (*MessageListResponse, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.ListMessagesCalls++
 	m.LastQuery = query
+	m.ListQueries = append(m.ListQueries, query)
 
 	if m.ListMessagesError != nil {
 		return nil, m.ListMessagesError
@@ -197,6 +231,40 @@ func (m *MockAPI) GetMessagesRawBatch(ctx context.Context, messageIDs []string)
 	return results, nil
 }
 
+// GetMessageMetadata returns the headers of a mock message, as the
+// real client's metadata fetch does.
+func (m *MockAPI) GetMessageMetadata(ctx context.Context, messageID string) (*RawMessage, error) {
+	msg, err := m.GetMessageRaw(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	meta := *msg
+	meta.MetadataOnly = true
+	var headers []messageHeader
+	if parsed, err := mail.ReadMessage(bytes.NewReader(msg.Raw)); err == nil {
+		for _, name := range slices.Sorted(maps.Keys(parsed.Header)) {
+			for _, value := range parsed.Header[name] {
+				headers = append(headers, messageHeader{Name: name, Value: value})
+			}
+		}
+	}
+	meta.Raw = headerOnlyMIME(headers)
+	return &meta, nil
+}
+
+// GetMessagesMetadataBatch fetches the metadata of multiple messages.
+func (m *MockAPI) GetMessagesMetadataBatch(ctx context.Context, messageIDs []string) ([]*RawMessage, error) {
+	results := make([]*RawMessage, len(messageIDs))
+	for i, id := range messageIDs {
+		msg, err := m.GetMessageMetadata(ctx, id)
+		if err != nil {
+			continue
+		}
+		results[i] = msg
+	}
+	return results, nil
+}
+
 // ListHistory returns mock history records.
 func (m *MockAPI) ListHistory(ctx context.Context, startHistoryID uint64, pageToken string) (*HistoryResponse, error) {
 	m.mu.Lock()
@@ -308,12 +376,99 @@ func (m *MockAPI) Reset() {
 	m.LabelsCalls = 0
 	m.ListMessagesCalls = 0
 	m.LastQuery = ""
+	m.ListQueries = nil
 	m.GetMessageCalls = nil
 	m.HistoryCalls = nil
 	m.TrashCalls = nil
 	m.DeleteCalls = nil
 	m.BatchDeleteCalls = nil
+	m.CreateLabelCalls = nil
+	m.ModifyCalls = nil
+	m.WatchCalls = nil
+	m.StopWatchCalls = 0
+	m.SentMessages = nil
+}
+
+// CreateLabel records a create call and adds the label to Labels,
+// with the name as its ID.
+func (m *MockAPI) CreateLabel(ctx context.Context, name string) (*Label, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.CreateLabelCalls = append(m.CreateLabelCalls, name)
+	if m.Labels == nil {
+		m.Labels = defaultMockLabels()
+	}
+	l := &Label{ID: "Label_" + name, Name: name, Type: "user"}
+	m.Labels = append(m.Labels, l)
+	return l, nil
+}
+
+// ModifyMessage records a modify call. Messages with a per-message
+// error in GetMessageError fail with it; unknown messages are not
+// found.
+func (m *MockAPI) ModifyMessage(ctx context.Context, messageID string, addLabelIDs, removeLabelIDs []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err, ok := m.GetMessageError[messageID]; ok && err != nil {
+		return err
+	}
+	if _, ok := m.Messages[messageID]; !ok {
+		return &NotFoundError{Path: "/messages/" + messageID}
+	}
+	m.ModifyCalls = append(m.ModifyCalls, ModifyCall{
+		MessageID:      messageID,
+		AddLabelIDs:    addLabelIDs,
+		RemoveLabelIDs: removeLabelIDs,
+	})
+	return nil
+}
+
+// Watch records a watch call and returns the mock's history ID.
+func (m *MockAPI) Watch(ctx context.Context, topic string, labelIDs []string) (*WatchResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.WatchCalls = append(m.WatchCalls, topic)
+	if m.WatchError != nil {
+		return nil, m.WatchError
+	}
+	expiration := m.WatchExpiration
+	if expiration.IsZero() {
+		expiration = time.Now().Add(7 * 24 * time.Hour)
+	}
+	return &WatchResponse{HistoryID: m.HistoryID, Expiration: expiration}, nil
+}
+
+// StopWatch records a stop call.
+func (m *MockAPI) StopWatch(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StopWatchCalls++
+	return nil
+}
+
+// SendMessage records a sent message and returns an ID for it.
+func (m *MockAPI) SendMessage(ctx context.Context, raw []byte) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SentMessages = append(m.SentMessages, raw)
+	return fmt.Sprintf("sent%d", len(m.SentMessages)), nil
+}
+
+// GetStorageQuota returns StorageQuota.
+func (m *MockAPI) GetStorageQuota(ctx context.Context) (*StorageQuota, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.StorageQuota == nil {
+		return nil, fmt.Errorf("forbidden (403): insufficient authentication scopes")
+	}
+	q := *m.StorageQuota
+	return &q, nil
 }
 
 // Ensure MockAPI implements API interface.
 var _ API = (*MockAPI)(nil)
+var _ LabelModifier = (*MockAPI)(nil)
+var _ Watcher = (*MockAPI)(nil)
+var _ MetadataReader = (*MockAPI)(nil)
+var _ Sender = (*MockAPI)(nil)
+var _ QuotaReader = (*MockAPI)(nil)
@@ -25,21 +25,30 @@ const (
 	OpMessagesDelete                       // 10 units
 	OpMessagesBatchDelete                  // 50 units
 	OpProfile                              // 1 unit
+	OpMessagesModify                       // 5 units
+	OpLabelsCreate                         // 5 units
+	OpWatch                                // 100 units
+	OpStop                                 // 50 units
+	OpMessagesSend                         // 100 units
+	OpStorageQuota                         // 1 unit; a Drive API call, paced with the rest
 )
 
 // Cost returns the quota cost for an operation.
 func (o Operation) Cost() int {
 	switch o {
-	case OpMessagesGet, OpMessagesGetRaw, OpMessagesList, OpMessagesTrash:
+	case OpMessagesGet, OpMessagesGetRaw, OpMessagesList, OpMessagesTrash,
+		OpMessagesModify, OpLabelsCreate:
 		return 5
 	case OpMessagesDelete:
 		return 10
-	case OpMessagesBatchDelete:
+	case OpWatch, OpMessagesSend:
+		return 100
+	case OpMessagesBatchDelete, OpStop:
 		return 50
 	case OpHistoryList:
 		return 2
 	default:
-		return 1 // OpLabelsList, OpProfile, unknown
+		return 1 // OpLabelsList, OpProfile, OpStorageQuota, unknown
 	}
 }
 
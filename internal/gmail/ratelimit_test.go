@@ -186,6 +186,12 @@ func TestOperationCost(t *testing.T) {
 		{OpMessagesDelete, 10},
 		{OpMessagesBatchDelete, 50},
 		{OpProfile, 1},
+		{OpMessagesModify, 5},
+		{OpLabelsCreate, 5},
+		{OpWatch, 100},
+		{OpStop, 50},
+		{OpMessagesSend, 100},
+		{OpStorageQuota, 1},
 		{Operation(999), 1}, // Unknown operation defaults to 1
 	}
 
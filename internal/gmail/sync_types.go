@@ -31,6 +31,12 @@ type SyncSummary struct {
 	FinalHistoryID   uint64
 	WasResumed       bool
 	ResumedFromToken string
+	// Recovered means the change history had expired, so the sync
+	// caught up with a full sync instead (see sync.Syncer.Recover).
+	Recovered bool
+	// MessagesDeleted counts archived messages a recovery found
+	// deleted from the source.
+	MessagesDeleted int64
 }
 
 // SyncProgressWithDate is an optional extension of SyncProgress
@@ -0,0 +1,132 @@
+package gmailwatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// PubSubScope is the OAuth scope for pulling from a subscription.
+const PubSubScope = "https://www.googleapis.com/auth/pubsub"
+
+const pubsubBaseURL = "https://pubsub.googleapis.com/v1"
+
+// Message is one message pulled from a subscription.
+type Message struct {
+	AckID string
+	Data  []byte
+}
+
+// Subscription pulls messages from a Cloud Pub/Sub pull subscription
+// through the Pub/Sub REST API.
+type Subscription struct {
+	name       string // projects/<project>/subscriptions/<name>
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewSubscription returns a Subscription for the named subscription
+// that sends requests with httpClient, which must add credentials.
+func NewSubscription(httpClient *http.Client, name string) *Subscription {
+	return &Subscription{name: name, httpClient: httpClient, baseURL: pubsubBaseURL}
+}
+
+// OpenSubscription returns a Subscription authorized by a service
+// account key file, or by Application Default Credentials when
+// credentialsFile is empty.
+func OpenSubscription(ctx context.Context, name, credentialsFile string) (*Subscription, error) {
+	var ts oauth2.TokenSource
+	if credentialsFile != "" {
+		data, err := os.ReadFile(credentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("read Pub/Sub credentials: %w", err)
+		}
+		conf, err := google.JWTConfigFromJSON(data, PubSubScope)
+		if err != nil {
+			return nil, fmt.Errorf("parse Pub/Sub credentials %s: %w", credentialsFile, err)
+		}
+		ts = conf.TokenSource(ctx)
+	} else {
+		var err error
+		if ts, err = google.DefaultTokenSource(ctx, PubSubScope); err != nil {
+			return nil, fmt.Errorf("find Pub/Sub credentials (set [watch] credentials): %w", err)
+		}
+	}
+	return NewSubscription(oauth2.NewClient(ctx, ts), name), nil
+}
+
+type pullResponse struct {
+	ReceivedMessages []struct {
+		AckID   string `json:"ackId"`
+		Message struct {
+			Data string `json:"data"` // standard base64
+		} `json:"message"`
+	} `json:"receivedMessages"`
+}
+
+// Pull returns up to max messages. It waits a while for one to arrive,
+// and may return none.
+func (s *Subscription) Pull(ctx context.Context, max int) ([]Message, error) {
+	data, err := s.post(ctx, "pull", map[string]int{"maxMessages": max})
+	if err != nil {
+		return nil, err
+	}
+	var resp pullResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("parse pull response: %w", err)
+	}
+	msgs := make([]Message, 0, len(resp.ReceivedMessages))
+	for _, rm := range resp.ReceivedMessages {
+		payload, err := base64.StdEncoding.DecodeString(rm.Message.Data)
+		if err != nil {
+			return nil, fmt.Errorf("decode message data: %w", err)
+		}
+		msgs = append(msgs, Message{AckID: rm.AckID, Data: payload})
+	}
+	return msgs, nil
+}
+
+// Acknowledge tells Pub/Sub the messages were handled, so they are not
+// delivered again.
+func (s *Subscription) Acknowledge(ctx context.Context, ackIDs []string) error {
+	if len(ackIDs) == 0 {
+		return nil
+	}
+	_, err := s.post(ctx, "acknowledge", map[string][]string{"ackIds": ackIDs})
+	return err
+}
+
+func (s *Subscription) post(ctx context.Context, method string, body any) ([]byte, error) {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal body: %w", err)
+	}
+	url := fmt.Sprintf("%s/%s:%s", s.baseURL, s.name, method)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub %s: %w", method, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read pubsub %s response: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("pubsub %s: HTTP %d: %s", method, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	return data, nil
+}
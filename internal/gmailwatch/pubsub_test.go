@@ -0,0 +1,59 @@
+package gmailwatch
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestSubscription_PullAndAcknowledge(t *testing.T) {
+	var acked []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects/p/subscriptions/msgvault:pull":
+			data := base64.StdEncoding.EncodeToString([]byte(`{"emailAddress":"alice@example.com","historyId":42}`))
+			_, _ = w.Write([]byte(`{"receivedMessages":[{"ackId":"ack-1","message":{"data":"` + data + `"}}]}`))
+		case "/projects/p/subscriptions/msgvault:acknowledge":
+			var body struct {
+				AckIDs []string `json:"ackIds"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			acked = body.AckIDs
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.Error(w, `{"error":{"code":404}}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	sub := NewSubscription(srv.Client(), "projects/p/subscriptions/msgvault")
+	sub.baseURL = srv.URL
+	ctx := context.Background()
+
+	msgs, err := sub.Pull(ctx, 10)
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if len(msgs) != 1 || msgs[0].AckID != "ack-1" {
+		t.Fatalf("Pull = %+v, want ack-1", msgs)
+	}
+	if n, err := decodeNotification(msgs[0].Data); err != nil || n.HistoryID != 42 {
+		t.Errorf("notification = %+v, %v; want history 42", n, err)
+	}
+	if err := sub.Acknowledge(ctx, []string{"ack-1"}); err != nil {
+		t.Fatalf("Acknowledge: %v", err)
+	}
+	if !slices.Equal(acked, []string{"ack-1"}) {
+		t.Errorf("acked = %v, want [ack-1]", acked)
+	}
+
+	missing := NewSubscription(srv.Client(), "projects/p/subscriptions/gone")
+	missing.baseURL = srv.URL
+	if _, err := missing.Pull(ctx, 10); err == nil {
+		t.Error("Pull of a missing subscription succeeded, want an error")
+	}
+}
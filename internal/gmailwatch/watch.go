@@ -0,0 +1,291 @@
+// Package gmailwatch syncs Gmail accounts when Gmail reports a change
+// through Cloud Pub/Sub push notifications, instead of polling.
+//
+// Gmail publishes a notification to a Pub/Sub topic whenever a watched
+// mailbox changes (users.watch). A Runner keeps each account's watch
+// registered, pulls the notifications from a subscription to the
+// topic, and runs an incremental sync of each account they name.
+package gmailwatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+)
+
+// DefaultRenewBefore is how long before a watch expires it is renewed.
+// Gmail watches last seven days; Google recommends renewing daily.
+const DefaultRenewBefore = 6 * 24 * time.Hour
+
+// pullMax is the most notifications read per pull.
+const pullMax = 100
+
+// Subscriber reads notifications from a Pub/Sub subscription.
+type Subscriber interface {
+	// Pull returns up to max messages, waiting a while for one to
+	// arrive. It may return none.
+	Pull(ctx context.Context, max int) ([]Message, error)
+
+	// Acknowledge marks messages handled so they are not redelivered.
+	Acknowledge(ctx context.Context, ackIDs []string) error
+}
+
+// SyncFunc runs an incremental sync of an account.
+type SyncFunc func(ctx context.Context, email string) error
+
+// Notification is what Gmail publishes when a watched mailbox changes.
+type Notification struct {
+	EmailAddress string
+	HistoryID    uint64
+}
+
+// decodeNotification parses a notification's Pub/Sub message data.
+func decodeNotification(data []byte) (Notification, error) {
+	var raw struct {
+		EmailAddress string      `json:"emailAddress"`
+		HistoryID    json.Number `json:"historyId"` // a string or a number
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return Notification{}, fmt.Errorf("parse notification: %w", err)
+	}
+	if raw.EmailAddress == "" {
+		return Notification{}, fmt.Errorf("parse notification: no emailAddress")
+	}
+	historyID, err := strconv.ParseUint(raw.HistoryID.String(), 10, 64)
+	if err != nil {
+		return Notification{}, fmt.Errorf("parse notification historyId %q: %w", raw.HistoryID, err)
+	}
+	return Notification{EmailAddress: raw.EmailAddress, HistoryID: historyID}, nil
+}
+
+// Options configures a Runner.
+type Options struct {
+	// Topic is the Pub/Sub topic watches publish to,
+	// "projects/<project>/topics/<name>".
+	Topic string
+
+	// LabelIDs limits notifications to changes of these labels. Empty
+	// means every change.
+	LabelIDs []string
+
+	// RenewBefore is how long before expiry a watch is renewed. Zero
+	// means DefaultRenewBefore.
+	RenewBefore time.Duration
+
+	// RetryDelay is how long to wait after a failed pull. Zero means 30
+	// seconds.
+	RetryDelay time.Duration
+}
+
+type account struct {
+	email  string
+	client gmail.Watcher
+
+	// expires is when the account's watch runs out; zero until the
+	// watch is registered. renewAt is when to renew it next.
+	expires time.Time
+	renewAt time.Time
+
+	// handled is the newest history ID a completed sync is known to
+	// cover; notifications at or below it need no sync.
+	handled uint64
+}
+
+// Runner keeps Gmail watches registered and syncs accounts as their
+// notifications arrive. A Runner is not safe for concurrent use.
+type Runner struct {
+	sub      Subscriber
+	sync     SyncFunc
+	opts     Options
+	logger   *slog.Logger
+	now      func() time.Time
+	idleWait time.Duration // pause after a pull that returned nothing
+
+	accounts map[string]*account // by lower-cased email
+	order    []*account
+}
+
+// New creates a Runner that reads notifications from sub and runs sync
+// for each account they name.
+func New(sub Subscriber, sync SyncFunc, opts Options) *Runner {
+	if opts.RenewBefore <= 0 {
+		opts.RenewBefore = DefaultRenewBefore
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = 30 * time.Second
+	}
+	return &Runner{
+		sub:      sub,
+		sync:     sync,
+		opts:     opts,
+		logger:   slog.Default(),
+		now:      time.Now,
+		idleWait: time.Second,
+		accounts: make(map[string]*account),
+	}
+}
+
+// WithLogger sets the logger.
+func (r *Runner) WithLogger(logger *slog.Logger) *Runner {
+	r.logger = logger
+	return r
+}
+
+// AddAccount watches an account's mailbox through client.
+func (r *Runner) AddAccount(email string, client gmail.Watcher) {
+	a := &account{email: email, client: client}
+	r.accounts[strings.ToLower(email)] = a
+	r.order = append(r.order, a)
+}
+
+// Run registers a watch for every account, syncs each once to catch
+// up on changes made before the watch, and then syncs accounts as
+// notifications arrive, renewing watches before they expire, until ctx
+// is done. It stops the watches on the way out.
+//
+// A failure to register an account's first watch is returned; failed
+// syncs and renewals are logged and retried on the next notification
+// or pass.
+func (r *Runner) Run(ctx context.Context) error {
+	defer r.stopWatches(ctx)
+	for _, a := range r.order {
+		if err := r.renew(ctx, a); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range r.order {
+		r.syncAccount(ctx, a, a.handled)
+	}
+
+	for ctx.Err() == nil {
+		for _, a := range r.order {
+			if r.now().Before(a.renewAt) {
+				continue
+			}
+			if err := r.renew(ctx, a); err != nil && ctx.Err() == nil {
+				r.logger.Warn("renew Gmail watch", "email", a.email, "error", err)
+				a.renewAt = r.now().Add(r.opts.RetryDelay)
+			}
+		}
+
+		msgs, err := r.sub.Pull(ctx, pullMax)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			r.logger.Warn("pull Gmail notifications", "error", err)
+			r.wait(ctx, r.opts.RetryDelay)
+			continue
+		}
+		if len(msgs) == 0 {
+			r.wait(ctx, r.idleWait)
+			continue
+		}
+		r.handle(ctx, msgs)
+	}
+	return nil
+}
+
+// handle acknowledges a pull's notifications and syncs each account
+// they name once, however many name it.
+func (r *Runner) handle(ctx context.Context, msgs []Message) {
+	latest := make(map[*account]uint64)
+	var due []*account
+	ackIDs := make([]string, 0, len(msgs))
+	for _, m := range msgs {
+		ackIDs = append(ackIDs, m.AckID)
+		n, err := decodeNotification(m.Data)
+		if err != nil {
+			r.logger.Warn("skip Gmail notification", "error", err)
+			continue
+		}
+		a := r.accounts[strings.ToLower(n.EmailAddress)]
+		if a == nil {
+			r.logger.Debug("notification for an account not watched", "email", n.EmailAddress)
+			continue
+		}
+		if n.HistoryID <= a.handled {
+			continue
+		}
+		if _, ok := latest[a]; !ok {
+			due = append(due, a)
+		}
+		latest[a] = max(latest[a], n.HistoryID)
+	}
+
+	// Acknowledged before syncing: a sync that fails is retried on the
+	// account's next notification, and an unacknowledged message would
+	// come back while the sync runs.
+	if err := r.sub.Acknowledge(ctx, ackIDs); err != nil {
+		r.logger.Warn("acknowledge Gmail notifications", "error", err)
+	}
+	for _, a := range due {
+		r.syncAccount(ctx, a, latest[a])
+	}
+}
+
+// syncAccount syncs an account; on success every change up to
+// historyID, which was notified before the sync began, is handled.
+func (r *Runner) syncAccount(ctx context.Context, a *account, historyID uint64) {
+	if ctx.Err() != nil {
+		return
+	}
+	r.logger.Info("syncing after Gmail notification", "email", a.email, "history_id", historyID)
+	if err := r.sync(ctx, a.email); err != nil {
+		if ctx.Err() == nil {
+			r.logger.Error("sync after Gmail notification", "email", a.email, "error", err)
+		}
+		return
+	}
+	a.handled = max(a.handled, historyID)
+}
+
+// renew registers or renews an account's watch.
+func (r *Runner) renew(ctx context.Context, a *account) error {
+	resp, err := a.client.Watch(ctx, r.opts.Topic, r.opts.LabelIDs)
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", a.email, err)
+	}
+	if a.expires.IsZero() {
+		// Changes up to the watch's start are covered by the first sync.
+		a.handled = resp.HistoryID
+	}
+	a.expires = resp.Expiration
+	a.renewAt = a.expires.Add(-r.opts.RenewBefore)
+	r.logger.Info("Gmail watch registered", "email", a.email, "expires", a.expires)
+	return nil
+}
+
+// stopWatches stops every registered watch, so Gmail stops publishing
+// notifications nobody reads.
+func (r *Runner) stopWatches(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	for _, a := range r.order {
+		if a.expires.IsZero() {
+			continue
+		}
+		if err := a.client.StopWatch(ctx); err != nil {
+			r.logger.Warn("stop Gmail watch", "email", a.email, "error", err)
+		}
+	}
+}
+
+func (r *Runner) wait(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
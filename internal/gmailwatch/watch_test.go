@@ -0,0 +1,166 @@
+package gmailwatch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+)
+
+// fakeSubscriber returns one scripted pull per call and cancels the
+// run once the script is used up.
+type fakeSubscriber struct {
+	pulls  [][]Message
+	acked  []string
+	cancel context.CancelFunc
+	onPull func(n int) // called before each pull, numbered from 0
+	n      int
+}
+
+func (f *fakeSubscriber) Pull(ctx context.Context, max int) ([]Message, error) {
+	if f.onPull != nil {
+		f.onPull(f.n)
+	}
+	if f.n >= len(f.pulls) {
+		f.cancel()
+		return nil, ctx.Err()
+	}
+	f.n++
+	return f.pulls[f.n-1], nil
+}
+
+func (f *fakeSubscriber) Acknowledge(ctx context.Context, ackIDs []string) error {
+	f.acked = append(f.acked, ackIDs...)
+	return nil
+}
+
+func note(ackID, email, historyID string) Message {
+	return Message{AckID: ackID, Data: []byte(`{"emailAddress":"` + email + `","historyId":` + historyID + `}`)}
+}
+
+func newTestRunner(sub *fakeSubscriber, synced *[]string) *Runner {
+	r := New(sub, func(ctx context.Context, email string) error {
+		*synced = append(*synced, email)
+		return nil
+	}, Options{Topic: "projects/p/topics/gmail"})
+	r.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r.idleWait = 0
+	return r
+}
+
+func TestDecodeNotification(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    Notification
+		wantErr bool
+	}{
+		{"number", `{"emailAddress":"alice@example.com","historyId":9876}`, Notification{"alice@example.com", 9876}, false},
+		{"string", `{"emailAddress":"alice@example.com","historyId":"9876"}`, Notification{"alice@example.com", 9876}, false},
+		{"no email", `{"historyId":9876}`, Notification{}, true},
+		{"bad history", `{"emailAddress":"alice@example.com","historyId":"x"}`, Notification{}, true},
+		{"not json", `hello`, Notification{}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := decodeNotification([]byte(tc.data))
+			if (err != nil) != tc.wantErr || got != tc.want {
+				t.Errorf("decodeNotification() = %+v, %v; want %+v, error %v", got, err, tc.want, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunner_SyncsOnNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, bob := gmail.NewMockAPI(), gmail.NewMockAPI()
+	alice.HistoryID, bob.HistoryID = 100, 200
+	sub := &fakeSubscriber{cancel: cancel, pulls: [][]Message{
+		{
+			note("a1", "alice@example.com", "101"),
+			note("a2", "Alice@Example.com", "105"), // coalesced with a1
+			note("b1", "bob@example.com", "150"),   // older than bob's watch
+			note("c1", "carol@example.com", "1"),   // not watched
+			{AckID: "junk", Data: []byte("junk")},
+		},
+		{},
+		{note("a3", "alice@example.com", "104")}, // covered by the last sync
+		{note("b2", "bob@example.com", "201")},
+	}}
+
+	var synced []string
+	r := newTestRunner(sub, &synced)
+	r.AddAccount("alice@example.com", alice)
+	r.AddAccount("bob@example.com", bob)
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{
+		"alice@example.com", "bob@example.com", // catch-up
+		"alice@example.com", // a1 and a2
+		"bob@example.com",   // b2
+	}
+	if !slices.Equal(synced, want) {
+		t.Errorf("synced = %v, want %v", synced, want)
+	}
+	if !slices.Equal(sub.acked, []string{"a1", "a2", "b1", "c1", "junk", "a3", "b2"}) {
+		t.Errorf("acked = %v, want every message", sub.acked)
+	}
+	for name, m := range map[string]*gmail.MockAPI{"alice": alice, "bob": bob} {
+		if len(m.WatchCalls) != 1 || m.WatchCalls[0] != "projects/p/topics/gmail" || m.StopWatchCalls != 1 {
+			t.Errorf("%s: watch calls %v, stop calls %d; want one watch of the topic, stopped on exit",
+				name, m.WatchCalls, m.StopWatchCalls)
+		}
+	}
+}
+
+func TestRunner_RenewsWatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	alice := gmail.NewMockAPI()
+	alice.WatchExpiration = start.Add(7 * 24 * time.Hour)
+	sub := &fakeSubscriber{cancel: cancel, pulls: [][]Message{{}, {}, {}}}
+	// Before the third pull, a day has passed: the watch is due.
+	sub.onPull = func(n int) {
+		if n == 1 {
+			now = start.Add(25 * time.Hour)
+			alice.WatchExpiration = now.Add(7 * 24 * time.Hour)
+		}
+	}
+
+	var synced []string
+	r := newTestRunner(sub, &synced)
+	r.now = func() time.Time { return now }
+	r.AddAccount("alice@example.com", alice)
+	if err := r.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(alice.WatchCalls) != 2 {
+		t.Errorf("watch calls = %d, want the first watch and one renewal", len(alice.WatchCalls))
+	}
+}
+
+func TestRunner_FirstWatchFails(t *testing.T) {
+	alice := gmail.NewMockAPI()
+	alice.WatchError = errors.New("topic not found")
+	var synced []string
+	r := newTestRunner(&fakeSubscriber{cancel: func() {}}, &synced)
+	r.AddAccount("alice@example.com", alice)
+	if err := r.Run(context.Background()); err == nil {
+		t.Fatal("Run succeeded, want the watch error")
+	}
+	if len(synced) != 0 || alice.StopWatchCalls != 0 {
+		t.Errorf("synced %v, %d stop calls; want nothing done", synced, alice.StopWatchCalls)
+	}
+}
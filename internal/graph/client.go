@@ -0,0 +1,631 @@
+// Package graph provides a Microsoft Graph mail client implementing
+// gmail.API, so Outlook.com and Microsoft 365 mailboxes sync the same
+// way Gmail does. Messages are fetched as raw MIME, mail folders map
+// onto labels, and per-folder delta queries stand in for the Gmail
+// History API.
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gmailapi "github.com/wesm/msgvault/internal/gmail"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultBaseURL is the Microsoft Graph v1.0 endpoint.
+	DefaultBaseURL = "https://graph.microsoft.com/v1.0"
+
+	maxRetries     = 8
+	maxBackoff     = 120 * time.Second
+	defaultTimeout = 60 * time.Second
+	pageSize       = 100
+
+	// messageFields are the message properties listings select; the
+	// body itself is always fetched as MIME.
+	messageFields = "id,conversationId,parentFolderId,receivedDateTime,bodyPreview,isRead,flag"
+)
+
+// Client implements gmail.API for a Microsoft Graph mailbox.
+type Client struct {
+	httpClient  *http.Client
+	token       func(context.Context) (string, error)
+	baseURL     string
+	logger      *slog.Logger
+	concurrency int
+	since       time.Time
+	before      time.Time
+
+	// sleep waits between retries; tests replace it.
+	sleep func(context.Context, time.Duration) error
+
+	mu      sync.Mutex
+	folders []folder
+	labelOf map[string]string      // folder ID -> label ID
+	meta    map[string]messageJSON // listed messages awaiting fetch
+	prev    DeltaState             // delta links from the last sync
+	next    DeltaState             // delta links reached this session
+	started bool                   // this session listed from the start
+	listed  bool                   // next covers every folder
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithLogger sets the logger for the client.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Client) { c.logger = logger }
+}
+
+// WithBaseURL points the client at another Graph endpoint, such as a
+// national cloud or a test server.
+func WithBaseURL(u string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimSuffix(u, "/") }
+}
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithDeltaState continues from the delta links recorded by the last
+// sync, enabling ListHistory.
+func WithDeltaState(st DeltaState) Option {
+	return func(c *Client) { c.prev = st }
+}
+
+// WithDateFilter limits full listings to messages received on or
+// after since and before before. Zero values mean no limit.
+func WithDateFilter(since, before time.Time) Option {
+	return func(c *Client) {
+		c.since = since
+		c.before = before
+	}
+}
+
+// NewClient creates a Graph mail client. token returns a current
+// access token with the Mail.Read scope.
+func NewClient(token func(context.Context) (string, error), opts ...Option) *Client {
+	c := &Client{
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+		token:       token,
+		baseURL:     DefaultBaseURL,
+		logger:      slog.Default(),
+		concurrency: 4,
+		sleep:       sleepContext,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Close releases resources held by the client.
+func (c *Client) Close() error {
+	return nil
+}
+
+// request sends a GET to a path under the base URL, or to an absolute
+// next or delta link Graph returned, retrying throttled and failed
+// requests.
+func (c *Client) request(ctx context.Context, pathOrLink string) ([]byte, error) {
+	reqURL := pathOrLink
+	if strings.HasPrefix(pathOrLink, "/") {
+		reqURL = c.baseURL + pathOrLink
+	} else if !strings.HasPrefix(pathOrLink, c.baseURL+"/") {
+		// Links carry the access token's authority; never send it
+		// anywhere but Graph.
+		return nil, fmt.Errorf("refusing link outside %s", c.baseURL)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		accessToken, err := c.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		// Immutable IDs survive moves between folders, so a moved
+		// message is recognized rather than downloaded again.
+		req.Header.Set("Prefer", `IdType="ImmutableId", odata.maxpagesize=`+strconv.Itoa(pageSize))
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("http request: %w", err)
+			if err := c.sleep(ctx, backoff(attempt, "")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("read response: %w", err)
+			continue
+		}
+
+		switch code := resp.StatusCode; {
+		case code >= 200 && code < 300:
+			return body, nil
+		case code == http.StatusTooManyRequests || code >= 500:
+			c.logger.Debug("graph request throttled or failed, retrying",
+				"status", code, "attempt", attempt)
+			lastErr = fmt.Errorf("graph returned %d", code)
+			if err := c.sleep(ctx, backoff(attempt, resp.Header.Get("Retry-After"))); err != nil {
+				return nil, err
+			}
+		case code == http.StatusUnauthorized:
+			return nil, fmt.Errorf("unauthorized (401): token may be invalid — run 'msgvault add-account --provider outlook' again")
+		case code == http.StatusForbidden:
+			return nil, fmt.Errorf("forbidden (403): %s", graphErrorMessage(body))
+		case code == http.StatusNotFound, code == http.StatusGone:
+			// 410 Gone is an expired delta link; like Gmail's 404 for
+			// old history, it means a full sync is needed.
+			return nil, &gmailapi.NotFoundError{Path: redactLink(pathOrLink)}
+		default:
+			return nil, fmt.Errorf("request failed (%d): %s", code, graphErrorMessage(body))
+		}
+	}
+	return nil, fmt.Errorf("max retries exceeded: %w", lastErr)
+}
+
+// backoff returns how long to wait before retry attempt+1: the
+// server's Retry-After when given, otherwise exponential.
+func backoff(attempt int, retryAfter string) time.Duration {
+	d := time.Duration(1<<min(attempt, 7)) * time.Second
+	if secs, err := strconv.Atoi(retryAfter); err == nil && secs >= 0 {
+		d = time.Duration(secs) * time.Second
+	}
+	return min(d, maxBackoff)
+}
+
+// graphErrorMessage extracts the message from a Graph error body.
+func graphErrorMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && e.Error.Code != "" {
+		return e.Error.Code + ": " + e.Error.Message
+	}
+	return string(body)
+}
+
+// redactLink drops the query string, which for delta links holds the
+// sync state, from a path used in errors.
+func redactLink(link string) string {
+	if i := strings.IndexByte(link, '?'); i >= 0 {
+		return link[:i]
+	}
+	return link
+}
+
+func (c *Client) getJSON(ctx context.Context, pathOrLink string, v any) error {
+	body, err := c.request(ctx, pathOrLink)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// Graph JSON response types.
+
+type page[T any] struct {
+	Value     []T    `json:"value"`
+	NextLink  string `json:"@odata.nextLink"`
+	DeltaLink string `json:"@odata.deltaLink"`
+}
+
+type messageJSON struct {
+	ID               string    `json:"id"`
+	ConversationID   string    `json:"conversationId"`
+	ParentFolderID   string    `json:"parentFolderId"`
+	ReceivedDateTime time.Time `json:"receivedDateTime"`
+	BodyPreview      string    `json:"bodyPreview"`
+	IsRead           *bool     `json:"isRead"`
+	Flag             *struct {
+		FlagStatus string `json:"flagStatus"`
+	} `json:"flag"`
+	Removed *struct {
+		Reason string `json:"reason"`
+	} `json:"@removed"`
+}
+
+// GetProfile returns the mailbox address and its message count.
+// Graph has no account-wide history ID, so HistoryID is zero; the
+// delta links returned by Cursor take its place.
+func (c *Client) GetProfile(ctx context.Context) (*gmailapi.Profile, error) {
+	var me struct {
+		Mail              string `json:"mail"`
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+	if err := c.getJSON(ctx, "/me?$select=mail,userPrincipalName", &me); err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	folders, err := c.folderList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p := &gmailapi.Profile{EmailAddress: me.Mail}
+	if p.EmailAddress == "" {
+		p.EmailAddress = me.UserPrincipalName
+	}
+	for _, f := range folders {
+		p.MessagesTotal += f.total
+	}
+	return p, nil
+}
+
+// ListLabels returns the mail folders as labels, plus UNREAD and
+// STARRED for the read and flagged states.
+func (c *Client) ListLabels(ctx context.Context) ([]*gmailapi.Label, error) {
+	folders, err := c.folderList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	labels := make([]*gmailapi.Label, 0, len(folders)+2)
+	for _, f := range folders {
+		labels = append(labels, &gmailapi.Label{
+			ID:            f.label,
+			Name:          f.name,
+			Type:          f.labelType,
+			MessagesTotal: f.total,
+		})
+	}
+	labels = append(labels,
+		&gmailapi.Label{ID: labelUnread, Name: labelUnread, Type: "system"},
+		&gmailapi.Label{ID: labelStarred, Name: labelStarred, Type: "system"},
+	)
+	return labels, nil
+}
+
+// ListMessages lists every folder through a fresh delta query, one
+// page at a time, so that finishing the listing leaves a delta link
+// per folder for the next incremental sync. The query is ignored;
+// date limits come from WithDateFilter.
+func (c *Client) ListMessages(ctx context.Context, _ string, pageToken string) (*gmailapi.MessageListResponse, error) {
+	folders, err := c.folderList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	i, link, err := parsePageToken(pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &gmailapi.MessageListResponse{}
+	c.mu.Lock()
+	if pageToken == "" || c.next == nil {
+		// A listing picked up from another session's page token lacks
+		// the earlier folders' delta links, so it cannot set a cursor.
+		c.next = DeltaState{}
+		c.listed = false
+		c.started = pageToken == ""
+		for _, f := range folders {
+			resp.ResultSizeEstimate += f.total
+		}
+	}
+	// The Syncer fetches one page before listing the next, so only
+	// the latest page's metadata is kept.
+	c.meta = make(map[string]messageJSON)
+	c.mu.Unlock()
+
+	// Pages without new messages, such as the last page of a folder
+	// or an empty folder, are skipped: an empty response ends the sync.
+	for i < len(folders) {
+		f := folders[i]
+		if link == "" {
+			link = c.initialDeltaPath(f.id)
+		}
+		var p page[messageJSON]
+		if err := c.getJSON(ctx, link, &p); err != nil {
+			return nil, fmt.Errorf("list folder %s: %w", f.name, err)
+		}
+		for _, m := range p.Value {
+			if m.Removed != nil || !c.inRange(m.ReceivedDateTime) {
+				continue
+			}
+			c.remember(m)
+			resp.Messages = append(resp.Messages, gmailapi.MessageID{ID: m.ID, ThreadID: m.ConversationID})
+		}
+
+		if p.NextLink != "" {
+			link = p.NextLink
+		} else {
+			c.mu.Lock()
+			c.next[f.id] = p.DeltaLink
+			c.mu.Unlock()
+			i++
+			link = ""
+		}
+		if i == len(folders) {
+			c.mu.Lock()
+			c.listed = c.started
+			c.mu.Unlock()
+			return resp, nil
+		}
+		if len(resp.Messages) > 0 {
+			resp.NextPageToken = pageTokenFor(i, link)
+			return resp, nil
+		}
+	}
+	return resp, nil
+}
+
+// inRange applies the date filter to a listed message.
+func (c *Client) inRange(received time.Time) bool {
+	if !c.since.IsZero() && received.Before(c.since) {
+		return false
+	}
+	if !c.before.IsZero() && !received.Before(c.before) {
+		return false
+	}
+	return true
+}
+
+// initialDeltaPath starts a delta query for a folder. The since date,
+// when set, narrows it on the server; delta queries keep the filter
+// in their links.
+func (c *Client) initialDeltaPath(folderID string) string {
+	q := url.Values{}
+	q.Set("$select", messageFields)
+	if !c.since.IsZero() {
+		q.Set("$filter", "receivedDateTime ge "+c.since.UTC().Format(time.RFC3339))
+	}
+	return "/me/mailFolders/" + url.PathEscape(folderID) + "/messages/delta?" + q.Encode()
+}
+
+// pageTokenFor encodes a listing position: the folder index and, mid
+// folder, the next link to follow.
+func pageTokenFor(folder int, link string) string {
+	if link == "" {
+		return strconv.Itoa(folder)
+	}
+	return strconv.Itoa(folder) + " " + link
+}
+
+func parsePageToken(token string) (int, string, error) {
+	if token == "" {
+		return 0, "", nil
+	}
+	idx, link, _ := strings.Cut(token, " ")
+	i, err := strconv.Atoi(idx)
+	if err != nil || i < 0 {
+		return 0, "", fmt.Errorf("invalid page token %q", token)
+	}
+	return i, link, nil
+}
+
+func (c *Client) remember(m messageJSON) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.meta == nil {
+		c.meta = make(map[string]messageJSON)
+	}
+	c.meta[m.ID] = m
+}
+
+// Cursor returns the delta links to store as the sync cursor, or ""
+// if this session has not listed every folder.
+func (c *Client) Cursor() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.listed {
+		return ""
+	}
+	return c.next.String()
+}
+
+// ListHistory follows each folder's delta link from the last sync and
+// reports the changes as Gmail-style history: every added or changed
+// message as added, with its folder and read and flagged states as
+// label changes, and messages gone from every folder as deleted. A
+// message that left one folder and appeared in another is a move. All
+// folders are read in one call, so the response has no next page.
+//
+// Without delta links to continue from, or when Graph has expired
+// them, it returns a *gmail.NotFoundError, which the Syncer treats as
+// expired history.
+func (c *Client) ListHistory(ctx context.Context, _ uint64, _ string) (*gmailapi.HistoryResponse, error) {
+	if len(c.prev) == 0 {
+		return nil, &gmailapi.NotFoundError{Path: "delta links"}
+	}
+	folders, err := c.folderList(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	next := DeltaState{}
+	changed := make(map[string]messageJSON)
+	var order []string
+	removedFrom := make(map[string]string) // message ID -> folder ID
+	for _, f := range folders {
+		// A folder created since the last sync starts from scratch,
+		// so everything in it counts as added.
+		link, ok := c.prev[f.id]
+		if !ok {
+			link = c.initialDeltaPath(f.id)
+		}
+		for {
+			var p page[messageJSON]
+			if err := c.getJSON(ctx, link, &p); err != nil {
+				return nil, fmt.Errorf("delta for folder %s: %w", f.name, err)
+			}
+			for _, m := range p.Value {
+				if m.Removed != nil {
+					removedFrom[m.ID] = f.id
+					continue
+				}
+				if _, seen := changed[m.ID]; !seen {
+					order = append(order, m.ID)
+				}
+				changed[m.ID] = m
+			}
+			if p.NextLink == "" {
+				next[f.id] = p.DeltaLink
+				break
+			}
+			link = p.NextLink
+		}
+	}
+
+	c.mu.Lock()
+	c.meta = make(map[string]messageJSON, len(changed))
+	c.mu.Unlock()
+
+	resp := &gmailapi.HistoryResponse{}
+	for _, id := range order {
+		m := changed[id]
+		c.remember(m)
+		ref := gmailapi.MessageID{ID: m.ID, ThreadID: m.ConversationID}
+		add, remove := c.stateLabels(m)
+		if from, ok := removedFrom[id]; ok && from != m.ParentFolderID {
+			remove = append(remove, c.labelFor(from))
+		}
+		rec := gmailapi.HistoryRecord{
+			MessagesAdded: []gmailapi.HistoryMessage{{Message: ref}},
+			LabelsAdded:   []gmailapi.HistoryLabelChange{{Message: ref, LabelIDs: add}},
+		}
+		if len(remove) > 0 {
+			rec.LabelsRemoved = []gmailapi.HistoryLabelChange{{Message: ref, LabelIDs: remove}}
+		}
+		resp.History = append(resp.History, rec)
+	}
+	var deleted []string
+	for id := range removedFrom {
+		if _, moved := changed[id]; !moved {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		resp.History = append(resp.History, gmailapi.HistoryRecord{
+			MessagesDeleted: []gmailapi.HistoryMessage{{Message: gmailapi.MessageID{ID: id}}},
+		})
+	}
+
+	c.mu.Lock()
+	c.next = next
+	c.listed = true
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// GetMessageRaw fetches a message's MIME content and, unless it was
+// just listed, its folder and state.
+func (c *Client) GetMessageRaw(ctx context.Context, messageID string) (*gmailapi.RawMessage, error) {
+	c.mu.Lock()
+	m, ok := c.meta[messageID]
+	c.mu.Unlock()
+	escaped := url.PathEscape(messageID)
+	if !ok {
+		if err := c.getJSON(ctx, "/me/messages/"+escaped+"?$select="+messageFields, &m); err != nil {
+			return nil, err
+		}
+	}
+	raw, err := c.request(ctx, "/me/messages/"+escaped+"/$value")
+	if err != nil {
+		return nil, err
+	}
+
+	add, _ := c.stateLabels(m)
+	msg := &gmailapi.RawMessage{
+		ID:           messageID,
+		ThreadID:     m.ConversationID,
+		LabelIDs:     add,
+		Snippet:      m.BodyPreview,
+		SizeEstimate: int64(len(raw)),
+		Raw:          raw,
+	}
+	if !m.ReceivedDateTime.IsZero() {
+		msg.InternalDate = m.ReceivedDateTime.UnixMilli()
+	}
+	return msg, nil
+}
+
+// GetMessagesRawBatch fetches messages in parallel. Results are in the
+// order of messageIDs, with nil for messages that could not be fetched.
+func (c *Client) GetMessagesRawBatch(ctx context.Context, messageIDs []string) ([]*gmailapi.RawMessage, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	results := make([]*gmailapi.RawMessage, len(messageIDs))
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(c.concurrency)
+	for i, id := range messageIDs {
+		g.Go(func() error {
+			msg, err := c.GetMessageRaw(ctx, id)
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				var nfe *gmailapi.NotFoundError
+				if errors.As(err, &nfe) {
+					c.logger.Debug("message deleted before fetch", "id", id)
+				} else {
+					c.logger.Warn("failed to fetch message", "id", id, "error", err)
+				}
+				return nil
+			}
+			results[i] = msg
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// TrashMessage is not supported: msgvault only requests read access
+// to Outlook mailboxes.
+func (c *Client) TrashMessage(context.Context, string) error {
+	return errReadOnly
+}
+
+// DeleteMessage is not supported; see TrashMessage.
+func (c *Client) DeleteMessage(context.Context, string) error {
+	return errReadOnly
+}
+
+// BatchDeleteMessages is not supported; see TrashMessage.
+func (c *Client) BatchDeleteMessages(context.Context, []string) error {
+	return errReadOnly
+}
+
+var errReadOnly = errors.New("outlook accounts are read-only: msgvault does not delete messages through Microsoft Graph")
@@ -0,0 +1,449 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gmailapi "github.com/wesm/msgvault/internal/gmail"
+)
+
+// fakeMessage is a message in the fake mailbox.
+type fakeMessage struct {
+	folder  string
+	read    bool
+	flagged bool
+}
+
+// change is one entry in the fake mailbox's change log: a message
+// appearing in (or changing within) a folder, or leaving it.
+type change struct {
+	version int
+	folder  string
+	id      string
+	removed bool
+}
+
+// fakeGraph serves enough of the Graph mail API for the client: the
+// user, folders, delta queries with two-message pages, and MIME.
+type fakeGraph struct {
+	t   *testing.T
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	messages map[string]*fakeMessage
+	log      []change
+	version  int
+	expired  bool // delta links answer 410 Gone
+	requests []string
+}
+
+func newFakeGraph(t *testing.T) *fakeGraph {
+	g := &fakeGraph{t: t, messages: make(map[string]*fakeMessage)}
+	g.srv = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.srv.Close)
+	return g
+}
+
+func (g *fakeGraph) client(opts ...Option) *Client {
+	c := NewClient(func(context.Context) (string, error) { return "test-token", nil },
+		append([]Option{WithBaseURL(g.srv.URL + "/v1.0")}, opts...)...)
+	c.sleep = func(context.Context, time.Duration) error { return nil }
+	return c
+}
+
+// put adds or updates a message, logging a move out of its old folder.
+func (g *fakeGraph) put(id, folder string, read, flagged bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.version++
+	if old, ok := g.messages[id]; ok && old.folder != folder {
+		g.log = append(g.log, change{g.version, old.folder, id, true})
+	}
+	g.messages[id] = &fakeMessage{folder: folder, read: read, flagged: flagged}
+	g.log = append(g.log, change{g.version, folder, id, false})
+}
+
+func (g *fakeGraph) remove(id string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.version++
+	g.log = append(g.log, change{g.version, g.messages[id].folder, id, true})
+	delete(g.messages, id)
+}
+
+var fakeFolders = []struct {
+	id, name, parent string
+}{
+	{"f-inbox", "Inbox", ""},
+	{"f-sent", "Sent Items", ""},
+	{"f-projects", "Projects", ""},
+	{"f-2024", "2024", "f-projects"},
+}
+
+func (g *fakeGraph) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		http.Error(w, "no token", http.StatusUnauthorized)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Prefer"), `IdType="ImmutableId"`) {
+		g.t.Errorf("%s requested without immutable IDs", r.URL.Path)
+	}
+	g.requests = append(g.requests, r.URL.Path)
+	path := strings.TrimPrefix(r.URL.Path, "/v1.0")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case path == "/me":
+		writeJSON(w, map[string]any{"mail": "alice@example.com"})
+	case path == "/me/mailFolders/inbox":
+		writeJSON(w, map[string]any{"id": "f-inbox"})
+	case path == "/me/mailFolders/sentitems":
+		writeJSON(w, map[string]any{"id": "f-sent"})
+	case len(parts) == 3 && parts[1] == "mailFolders":
+		http.Error(w, `{"error":{"code":"ErrorFolderNotFound","message":"not found"}}`, http.StatusNotFound)
+	case path == "/me/mailFolders" || strings.HasSuffix(path, "/childFolders"):
+		parent := ""
+		if len(parts) == 4 {
+			parent = parts[2]
+		}
+		var value []map[string]any
+		for _, f := range fakeFolders {
+			if f.parent != parent {
+				continue
+			}
+			children := 0
+			for _, c := range fakeFolders {
+				if c.parent == f.id {
+					children++
+				}
+			}
+			value = append(value, map[string]any{"id": f.id, "displayName": f.name, "childFolderCount": children, "totalItemCount": 1})
+		}
+		writeJSON(w, map[string]any{"value": value})
+	case len(parts) == 5 && parts[4] == "delta":
+		g.serveDelta(w, r, parts[2])
+	case len(parts) == 4 && parts[3] == "$value":
+		if _, ok := g.messages[parts[2]]; !ok {
+			http.Error(w, "gone", http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "Message-ID: <%s@example.com>\r\nSubject: %s\r\n\r\nbody\r\n", parts[2], parts[2])
+	case len(parts) == 3 && parts[1] == "messages":
+		m, ok := g.messages[parts[2]]
+		if !ok {
+			http.Error(w, "gone", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, g.messageJSON(parts[2], m))
+	default:
+		http.Error(w, "unexpected "+path, http.StatusBadRequest)
+	}
+}
+
+// serveDelta answers a folder's delta query. The skip parameter pages
+// through the result; since limits it to changes after a version.
+func (g *fakeGraph) serveDelta(w http.ResponseWriter, r *http.Request, folder string) {
+	q := r.URL.Query()
+	if q.Get("$select") == "" {
+		g.t.Errorf("delta for %s without $select", folder)
+	}
+	since, _ := strconv.Atoi(q.Get("since"))
+	if since > 0 && g.expired {
+		http.Error(w, `{"error":{"code":"SyncStateNotFound","message":"expired"}}`, http.StatusGone)
+		return
+	}
+
+	// Latest entry per message in this folder since the version.
+	latest := map[string]change{}
+	var ids []string
+	for _, c := range g.log {
+		if c.folder != folder || c.version <= since {
+			continue
+		}
+		if _, ok := latest[c.id]; !ok {
+			ids = append(ids, c.id)
+		}
+		latest[c.id] = c
+	}
+	var items []map[string]any
+	for _, id := range ids {
+		c := latest[id]
+		m, ok := g.messages[id]
+		switch {
+		case c.removed && (!ok || m.folder != folder):
+			items = append(items, map[string]any{"id": id, "@removed": map[string]string{"reason": "deleted"}})
+		case ok && m.folder == folder:
+			items = append(items, g.messageJSON(id, m))
+		}
+	}
+
+	skip, _ := strconv.Atoi(q.Get("skip"))
+	end := min(skip+2, len(items))
+	resp := map[string]any{"value": items[skip:end]}
+	link := *r.URL
+	lq := link.Query()
+	if end < len(items) {
+		lq.Set("skip", strconv.Itoa(end))
+		link.RawQuery = lq.Encode()
+		resp["@odata.nextLink"] = g.srv.URL + link.Path + "?" + link.RawQuery
+	} else {
+		lq.Del("skip")
+		lq.Set("since", strconv.Itoa(g.version))
+		link.RawQuery = lq.Encode()
+		resp["@odata.deltaLink"] = g.srv.URL + link.Path + "?" + link.RawQuery
+	}
+	writeJSON(w, resp)
+}
+
+func (g *fakeGraph) messageJSON(id string, m *fakeMessage) map[string]any {
+	flag := "notFlagged"
+	if m.flagged {
+		flag = "flagged"
+	}
+	return map[string]any{
+		"id":               id,
+		"conversationId":   "conv-" + id,
+		"parentFolderId":   m.folder,
+		"receivedDateTime": "2024-03-01T10:00:00Z",
+		"bodyPreview":      "preview of " + id,
+		"isRead":           m.read,
+		"flag":             map[string]string{"flagStatus": flag},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestClient_ListLabels(t *testing.T) {
+	g := newFakeGraph(t)
+	labels, err := g.client().ListLabels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, l := range labels {
+		got = append(got, l.ID+"="+l.Name+"/"+l.Type)
+	}
+	want := []string{
+		"INBOX=INBOX/system",
+		"SENT=SENT/system",
+		"f-projects=Projects/user",
+		"f-2024=Projects/2024/user",
+		"UNREAD=UNREAD/system",
+		"STARRED=STARRED/system",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("labels = %v\nwant %v", got, want)
+	}
+}
+
+// listAll lists every page of a full sync, returning the message IDs.
+func listAll(t *testing.T, c *Client) []string {
+	t.Helper()
+	var ids []string
+	token := ""
+	for {
+		resp, err := c.ListMessages(context.Background(), "", token)
+		if err != nil {
+			t.Fatalf("ListMessages: %v", err)
+		}
+		for _, m := range resp.Messages {
+			ids = append(ids, m.ID)
+		}
+		if len(resp.Messages) == 0 || resp.NextPageToken == "" {
+			return ids
+		}
+		token = resp.NextPageToken
+	}
+}
+
+func TestClient_FullListing(t *testing.T) {
+	g := newFakeGraph(t)
+	for _, id := range []string{"m1", "m2", "m3"} {
+		g.put(id, "f-inbox", true, false)
+	}
+	g.put("m4", "f-2024", false, true)
+
+	c := g.client()
+	if c.Cursor() != "" {
+		t.Error("Cursor before listing should be empty")
+	}
+	ids := listAll(t, c)
+	if want := []string{"m1", "m2", "m3", "m4"}; !slices.Equal(ids, want) {
+		t.Errorf("listed %v, want %v", ids, want)
+	}
+
+	st := ParseDeltaState(c.Cursor())
+	if len(st) != len(fakeFolders) {
+		t.Fatalf("cursor has %d delta links, want one per folder: %v", len(st), st)
+	}
+
+	msg, err := c.GetMessageRaw(context.Background(), "m4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ThreadID != "conv-m4" || msg.Snippet != "preview of m4" ||
+		!slices.Equal(msg.LabelIDs, []string{"f-2024", "UNREAD", "STARRED"}) ||
+		!strings.Contains(string(msg.Raw), "Subject: m4") ||
+		msg.InternalDate != time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).UnixMilli() {
+		t.Errorf("GetMessageRaw = %+v", msg)
+	}
+}
+
+func TestClient_ListHistory(t *testing.T) {
+	g := newFakeGraph(t)
+	g.put("keep", "f-inbox", false, false)
+	g.put("move", "f-inbox", true, false)
+	g.put("gone", "f-inbox", true, false)
+	first := g.client()
+	listAll(t, first)
+	cursor := first.Cursor()
+
+	g.put("keep", "f-inbox", true, true) // read and flagged
+	g.put("move", "f-projects", true, false)
+	g.remove("gone")
+	g.put("new", "f-sent", true, false)
+
+	c := g.client(WithDeltaState(ParseDeltaState(cursor)))
+	resp, err := c.ListHistory(context.Background(), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.NextPageToken != "" {
+		t.Errorf("NextPageToken = %q, want all changes in one response", resp.NextPageToken)
+	}
+
+	type summary struct{ added, labelsAdded, labelsRemoved string }
+	got := map[string]summary{}
+	var deleted []string
+	for _, rec := range resp.History {
+		for _, m := range rec.MessagesDeleted {
+			deleted = append(deleted, m.Message.ID)
+		}
+		if len(rec.MessagesAdded) == 0 {
+			continue
+		}
+		s := summary{added: rec.MessagesAdded[0].Message.ThreadID}
+		for _, l := range rec.LabelsAdded {
+			s.labelsAdded = strings.Join(l.LabelIDs, ",")
+		}
+		for _, l := range rec.LabelsRemoved {
+			s.labelsRemoved = strings.Join(l.LabelIDs, ",")
+		}
+		got[rec.MessagesAdded[0].Message.ID] = s
+	}
+	want := map[string]summary{
+		"keep": {"conv-keep", "INBOX,STARRED", "UNREAD"},
+		"move": {"conv-move", "f-projects", "UNREAD,STARRED,INBOX"},
+		"new":  {"conv-new", "SENT", "UNREAD,STARRED"},
+	}
+	for id, w := range want {
+		if got[id] != w {
+			t.Errorf("%s: got %+v, want %+v", id, got[id], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("changed messages = %v, want %v", got, want)
+	}
+	if !slices.Equal(deleted, []string{"gone"}) {
+		t.Errorf("deleted = %v, want [gone]", deleted)
+	}
+
+	// The new cursor continues from here: nothing has changed since.
+	next := g.client(WithDeltaState(ParseDeltaState(c.Cursor())))
+	resp, err = next.ListHistory(context.Background(), 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.History) != 0 {
+		t.Errorf("second ListHistory = %+v, want no changes", resp.History)
+	}
+}
+
+func TestClient_ListHistoryNeedsFullSync(t *testing.T) {
+	g := newFakeGraph(t)
+	g.put("m1", "f-inbox", true, false)
+
+	var nfe *gmailapi.NotFoundError
+	if _, err := g.client().ListHistory(context.Background(), 0, ""); !errors.As(err, &nfe) {
+		t.Errorf("ListHistory without delta links: err = %v, want NotFoundError", err)
+	}
+
+	first := g.client()
+	listAll(t, first)
+	g.expired = true
+	c := g.client(WithDeltaState(ParseDeltaState(first.Cursor())))
+	if _, err := c.ListHistory(context.Background(), 0, ""); !errors.As(err, &nfe) {
+		t.Errorf("ListHistory with expired links: err = %v, want NotFoundError", err)
+	}
+}
+
+func TestClient_RetriesThrottledRequests(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "7")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		writeJSON(w, map[string]any{"userPrincipalName": "bob@example.com", "value": []any{}})
+	}))
+	defer srv.Close()
+
+	c := NewClient(func(context.Context) (string, error) { return "t", nil }, WithBaseURL(srv.URL))
+	var waits []time.Duration
+	c.sleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	var me struct {
+		UserPrincipalName string `json:"userPrincipalName"`
+	}
+	if err := c.getJSON(context.Background(), "/me", &me); err != nil {
+		t.Fatal(err)
+	}
+	if me.UserPrincipalName != "bob@example.com" {
+		t.Errorf("userPrincipalName = %q", me.UserPrincipalName)
+	}
+	if !slices.Equal(waits, []time.Duration{7 * time.Second, 7 * time.Second}) {
+		t.Errorf("waits = %v, want Retry-After honored twice", waits)
+	}
+}
+
+func TestClient_RefusesForeignLinks(t *testing.T) {
+	c := NewClient(func(context.Context) (string, error) {
+		t.Fatal("token requested for a foreign link")
+		return "", nil
+	})
+	if _, err := c.request(context.Background(), "https://attacker.example.com/v1.0/me"); err == nil {
+		t.Error("request to a link outside Graph succeeded")
+	}
+}
+
+func TestDeltaState_RoundTrip(t *testing.T) {
+	st := DeltaState{"f-inbox": "https://graph.microsoft.com/v1.0/x?$deltatoken=a"}
+	if got := ParseDeltaState(st.String()); !maps.Equal(got, st) {
+		t.Errorf("round trip = %v, want %v", got, st)
+	}
+	for _, cursor := range []string{"", "12345", "{bad"} {
+		if got := ParseDeltaState(cursor); got != nil {
+			t.Errorf("ParseDeltaState(%q) = %v, want nil", cursor, got)
+		}
+	}
+}
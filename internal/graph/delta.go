@@ -0,0 +1,37 @@
+package graph
+
+import "encoding/json"
+
+// DeltaState maps mail folder IDs to the delta links Graph returned at
+// the end of the last complete listing. Following a folder's link
+// yields only the messages added, changed, or removed since. It is
+// stored as the source's sync cursor, so incremental Outlook sync
+// needs no schema of its own.
+type DeltaState map[string]string
+
+// ParseDeltaState decodes a sync cursor written by DeltaState.String.
+// Anything else, including an empty cursor, yields nil, which means
+// there is nothing to continue from and a full sync is needed.
+func ParseDeltaState(cursor string) DeltaState {
+	if cursor == "" || cursor[0] != '{' {
+		return nil
+	}
+	var st DeltaState
+	if err := json.Unmarshal([]byte(cursor), &st); err != nil {
+		return nil
+	}
+	return st
+}
+
+// String encodes the state as a sync cursor.
+func (st DeltaState) String() string {
+	if len(st) == 0 {
+		return ""
+	}
+	// encoding/json sorts map keys, so equal states encode equally.
+	data, err := json.Marshal(st)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
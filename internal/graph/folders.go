@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	gmailapi "github.com/wesm/msgvault/internal/gmail"
+)
+
+// State labels, named as in Gmail.
+const (
+	labelUnread  = "UNREAD"
+	labelStarred = "STARRED"
+)
+
+// wellKnownFolders maps Graph's well-known folder names to the Gmail
+// system labels they correspond to, so an Outlook inbox files under
+// INBOX like any other account's.
+var wellKnownFolders = []struct{ name, label string }{
+	{"inbox", "INBOX"},
+	{"sentitems", "SENT"},
+	{"drafts", "DRAFT"},
+	{"deleteditems", "TRASH"},
+	{"junkemail", "SPAM"},
+}
+
+// folder is a mail folder and the label it maps to. Other folders use
+// their Graph ID as the label ID and their path, such as
+// "Projects/2024", as its name.
+type folder struct {
+	id        string
+	label     string
+	name      string
+	labelType string
+	total     int64
+}
+
+type folderJSON struct {
+	ID               string `json:"id"`
+	DisplayName      string `json:"displayName"`
+	ChildFolderCount int    `json:"childFolderCount"`
+	TotalItemCount   int64  `json:"totalItemCount"`
+}
+
+// folderList returns the mailbox's folders, loading them on first use.
+func (c *Client) folderList(ctx context.Context) ([]folder, error) {
+	c.mu.Lock()
+	folders := c.folders
+	c.mu.Unlock()
+	if folders != nil {
+		return folders, nil
+	}
+
+	system := make(map[string]string) // folder ID -> system label
+	for _, wk := range wellKnownFolders {
+		var f folderJSON
+		err := c.getJSON(ctx, "/me/mailFolders/"+wk.name+"?$select=id", &f)
+		var nfe *gmailapi.NotFoundError
+		if errors.As(err, &nfe) {
+			continue // e.g. a mailbox without a junk folder
+		}
+		if err != nil {
+			return nil, fmt.Errorf("look up %s folder: %w", wk.name, err)
+		}
+		system[f.ID] = wk.label
+	}
+
+	folders = []folder{}
+	if err := c.walkFolders(ctx, "/me/mailFolders", "", system, &folders); err != nil {
+		return nil, err
+	}
+	labelOf := make(map[string]string, len(folders))
+	for _, f := range folders {
+		labelOf[f.id] = f.label
+	}
+
+	c.mu.Lock()
+	c.folders = folders
+	c.labelOf = labelOf
+	c.mu.Unlock()
+	return folders, nil
+}
+
+// walkFolders appends the folders listed at path, and their
+// subfolders, to out.
+func (c *Client) walkFolders(ctx context.Context, path, parent string, system map[string]string, out *[]folder) error {
+	link := path + "?$top=" + strconv.Itoa(pageSize)
+	for link != "" {
+		var p page[folderJSON]
+		if err := c.getJSON(ctx, link, &p); err != nil {
+			return fmt.Errorf("list mail folders: %w", err)
+		}
+		for _, fj := range p.Value {
+			name := fj.DisplayName
+			if parent != "" {
+				name = parent + "/" + name
+			}
+			f := folder{id: fj.ID, label: fj.ID, name: name, labelType: "user", total: fj.TotalItemCount}
+			if label, ok := system[fj.ID]; ok {
+				f.label, f.name, f.labelType = label, label, "system"
+			}
+			*out = append(*out, f)
+			if fj.ChildFolderCount > 0 {
+				child := "/me/mailFolders/" + url.PathEscape(fj.ID) + "/childFolders"
+				if err := c.walkFolders(ctx, child, name, system, out); err != nil {
+					return err
+				}
+			}
+		}
+		link = p.NextLink
+	}
+	return nil
+}
+
+// labelFor returns the label ID of a folder. A folder not seen when
+// folders were listed keeps its Graph ID.
+func (c *Client) labelFor(folderID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if label, ok := c.labelOf[folderID]; ok {
+		return label
+	}
+	return folderID
+}
+
+// stateLabels returns the labels a message has (its folder, UNREAD,
+// and STARRED) and the state labels it lacks.
+func (c *Client) stateLabels(m messageJSON) (has, lacks []string) {
+	if m.ParentFolderID != "" {
+		has = append(has, c.labelFor(m.ParentFolderID))
+	}
+	if m.IsRead != nil && !*m.IsRead {
+		has = append(has, labelUnread)
+	} else {
+		lacks = append(lacks, labelUnread)
+	}
+	if m.Flag != nil && m.Flag.FlagStatus == "flagged" {
+		has = append(has, labelStarred)
+	} else {
+		lacks = append(lacks, labelStarred)
+	}
+	return has, lacks
+}
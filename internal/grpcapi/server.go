@@ -0,0 +1,341 @@
+// Package grpcapi serves the msgvault gRPC API defined in
+// proto/msgvault/v1/msgvault.proto. It shares the store, scheduler, and
+// API key with the REST server in internal/api.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/api"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	msgvaultv1 "github.com/wesm/msgvault/proto/msgvault/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultSearchLimit applies when SearchRequest.limit is zero.
+	defaultSearchLimit = 100
+	// maxSearchLimit caps a single Search stream.
+	maxSearchLimit = 10000
+	// searchPageSize is how many rows each store query fetches while
+	// streaming search results.
+	searchPageSize = 100
+)
+
+// Server implements msgvaultv1.MsgvaultServiceServer.
+type Server struct {
+	msgvaultv1.UnimplementedMsgvaultServiceServer
+
+	cfg       *config.Config
+	store     api.MessageStore
+	scheduler api.SyncScheduler
+	tokens    api.TokenStore // nil unless the store issues API tokens
+	logger    *slog.Logger
+	grpc      *grpc.Server
+}
+
+// methodScopes is the API token scope each RPC needs. RPCs missing
+// from the map need admin.
+var methodScopes = map[string]string{
+	"GetStats":     store.ScopeRead,
+	"GetMessage":   store.ScopeRead,
+	"ListAccounts": store.ScopeRead,
+	"Search":       store.ScopeSearch,
+}
+
+// NewServer creates a gRPC server. store and sched may be nil; the
+// corresponding RPCs then return codes.Unavailable.
+func NewServer(cfg *config.Config, st api.MessageStore, sched api.SyncScheduler, logger *slog.Logger) *Server {
+	s := &Server{
+		cfg:       cfg,
+		store:     st,
+		scheduler: sched,
+		logger:    logger,
+	}
+	s.tokens, _ = st.(api.TokenStore)
+	s.grpc = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	msgvaultv1.RegisterMsgvaultServiceServer(s.grpc, s)
+	return s
+}
+
+// Addr returns the configured listen address, or "" when the gRPC
+// server is disabled ([server] grpc_port unset).
+func Addr(cfg *config.Config) string {
+	if cfg.Server.GRPCPort == 0 {
+		return ""
+	}
+	bindAddr := cfg.Server.BindAddr
+	if bindAddr == "" {
+		bindAddr = "127.0.0.1"
+	}
+	return net.JoinHostPort(bindAddr, strconv.Itoa(cfg.Server.GRPCPort))
+}
+
+// Start listens on the configured address and serves until Shutdown is
+// called. Returns an error if the security posture is invalid.
+func (s *Server) Start() error {
+	if err := s.cfg.Server.ValidateSecureWith(api.RequiresAuth(s.cfg.Server, s.tokens)); err != nil {
+		return err
+	}
+	addr := Addr(s.cfg)
+	if addr == "" {
+		return errors.New("grpc server disabled: [server] grpc_port is not set")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("starting gRPC server", "addr", addr)
+	return s.Serve(lis)
+}
+
+// Serve accepts connections on lis. Exposed for tests that listen on
+// an ephemeral port.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// Shutdown stops the server, waiting for in-flight RPCs until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+}
+
+// authorize validates the API key carried in request metadata using the
+// same key resolution as the REST authMiddleware, then checks the API
+// token scope the method needs. Per-user account scoping is only
+// enforced by the REST API, so keys belonging to non-admin
+// [[server.users]] entries are refused here rather than granted
+// unscoped access.
+func (s *Server) authorize(ctx context.Context, fullMethod string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := ""
+	if v := md.Get("authorization"); len(v) > 0 {
+		key = strings.TrimPrefix(v[0], "Bearer ")
+	} else if v := md.Get("x-api-key"); len(v) > 0 {
+		key = v[0]
+	}
+	principal, ok := api.ResolveKey(s.cfg.Server, s.tokens, key)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "invalid or missing API key")
+	}
+	if !principal.Admin && !principal.AllAccounts {
+		return status.Error(codes.PermissionDenied, "account-scoped API keys are not supported over gRPC")
+	}
+	scope, ok := methodScopes[path.Base(fullMethod)]
+	if !ok {
+		scope = store.ScopeAdmin
+	}
+	if !principal.HasScope(scope) {
+		return status.Errorf(codes.PermissionDenied, "API token lacks the %s scope", scope)
+	}
+	return nil
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.authorize(ctx, info.FullMethod); err != nil {
+		s.logger.Warn("unauthorized gRPC request", "method", info.FullMethod)
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.authorize(ss.Context(), info.FullMethod); err != nil {
+		s.logger.Warn("unauthorized gRPC request", "method", info.FullMethod)
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// GetStats implements MsgvaultServiceServer.
+func (s *Server) GetStats(ctx context.Context, _ *msgvaultv1.GetStatsRequest) (*msgvaultv1.GetStatsResponse, error) {
+	if s.store == nil {
+		return nil, status.Error(codes.Unavailable, "database not available")
+	}
+	stats, err := s.store.GetStats()
+	if err != nil {
+		s.logger.Error("failed to get stats", "error", err)
+		return nil, status.Error(codes.Internal, "failed to retrieve statistics")
+	}
+	return &msgvaultv1.GetStatsResponse{
+		TotalMessages:     stats.MessageCount,
+		TotalThreads:      stats.ThreadCount,
+		TotalAccounts:     stats.SourceCount,
+		TotalLabels:       stats.LabelCount,
+		TotalAttachments:  stats.AttachmentCount,
+		DatabaseSizeBytes: stats.DatabaseSize,
+	}, nil
+}
+
+// Search implements MsgvaultServiceServer. Results are fetched from the
+// store a page at a time so large limits don't buffer in memory.
+func (s *Server) Search(req *msgvaultv1.SearchRequest, stream grpc.ServerStreamingServer[msgvaultv1.MessageSummary]) error {
+	if s.store == nil {
+		return status.Error(codes.Unavailable, "database not available")
+	}
+	if strings.TrimSpace(req.GetQuery()) == "" {
+		return status.Error(codes.InvalidArgument, "query is required")
+	}
+	limit := int(req.GetLimit())
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	offset := max(int(req.GetOffset()), 0)
+
+	parsed := search.Parse(req.GetQuery())
+	parsed.HideDeleted = true
+	parsed.Sort = search.SortRelevance
+
+	sent := 0
+	for sent < limit {
+		if err := stream.Context().Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		pageSize := min(searchPageSize, limit-sent)
+
+		var (
+			messages []store.APIMessage
+			err      error
+		)
+		if parsed.HasOperators() {
+			messages, _, err = s.store.SearchMessagesQuery(parsed, offset+sent, pageSize)
+		} else {
+			messages, _, err = s.store.SearchMessages(req.GetQuery(), offset+sent, pageSize)
+		}
+		if err != nil {
+			s.logger.Error("gRPC search failed", "query", req.GetQuery(), "error", err)
+			return status.Error(codes.Internal, "search failed")
+		}
+		for i := range messages {
+			if err := stream.Send(toSummary(&messages[i])); err != nil {
+				return err
+			}
+		}
+		sent += len(messages)
+		if len(messages) < pageSize {
+			break
+		}
+	}
+	return nil
+}
+
+// GetMessage implements MsgvaultServiceServer.
+func (s *Server) GetMessage(ctx context.Context, req *msgvaultv1.GetMessageRequest) (*msgvaultv1.Message, error) {
+	if s.store == nil {
+		return nil, status.Error(codes.Unavailable, "database not available")
+	}
+	msg, err := s.store.GetMessage(req.GetId())
+	if err != nil {
+		s.logger.Error("failed to get message", "id", req.GetId(), "error", err)
+		return nil, status.Error(codes.Internal, "failed to retrieve message")
+	}
+	if msg == nil {
+		return nil, status.Errorf(codes.NotFound, "message %d not found", req.GetId())
+	}
+
+	out := &msgvaultv1.Message{
+		Summary: toSummary(msg),
+		Cc:      msg.Cc,
+		Bcc:     msg.Bcc,
+		Body:    msg.Body,
+	}
+	for _, att := range msg.Attachments {
+		out.Attachments = append(out.Attachments, &msgvaultv1.Attachment{
+			Id:        att.ID,
+			Filename:  att.Filename,
+			MimeType:  att.MimeType,
+			SizeBytes: att.Size,
+		})
+	}
+	return out, nil
+}
+
+// ListAccounts implements MsgvaultServiceServer. Accounts come from the
+// scheduler rather than cfg.Accounts, which the REST add-account
+// endpoint mutates under its own lock.
+func (s *Server) ListAccounts(ctx context.Context, _ *msgvaultv1.ListAccountsRequest) (*msgvaultv1.ListAccountsResponse, error) {
+	if s.scheduler == nil {
+		return nil, status.Error(codes.Unavailable, "scheduler not available")
+	}
+	resp := &msgvaultv1.ListAccountsResponse{}
+	for _, st := range s.scheduler.Status() {
+		resp.Accounts = append(resp.Accounts, &msgvaultv1.Account{
+			Email:      st.Email,
+			Schedule:   st.Schedule,
+			Enabled:    true,
+			LastSyncAt: timestampOrNil(st.LastRun),
+			NextSyncAt: timestampOrNil(st.NextRun),
+		})
+	}
+	return resp, nil
+}
+
+// TriggerSync implements MsgvaultServiceServer.
+func (s *Server) TriggerSync(ctx context.Context, req *msgvaultv1.TriggerSyncRequest) (*msgvaultv1.TriggerSyncResponse, error) {
+	if s.scheduler == nil {
+		return nil, status.Error(codes.Unavailable, "scheduler not available")
+	}
+	email := req.GetEmail()
+	if email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+	if !s.scheduler.IsScheduled(email) {
+		return nil, status.Errorf(codes.NotFound, "account is not scheduled: %s", email)
+	}
+	if err := s.scheduler.TriggerSync(email); err != nil {
+		s.logger.Error("failed to trigger sync", "account", email, "error", err)
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	s.logger.Info("sync triggered via gRPC", "account", email)
+	return &msgvaultv1.TriggerSyncResponse{Message: "Sync started for " + email}, nil
+}
+
+func toSummary(m *store.APIMessage) *msgvaultv1.MessageSummary {
+	return &msgvaultv1.MessageSummary{
+		Id:             m.ID,
+		ConversationId: m.ConversationID,
+		Subject:        m.Subject,
+		From:           m.From,
+		To:             m.To,
+		SentAt:         timestampOrNil(m.SentAt),
+		Snippet:        m.Snippet,
+		Labels:         m.Labels,
+		HasAttachments: m.HasAttachments,
+		SizeBytes:      m.SizeEstimate,
+	}
+}
+
+func timestampOrNil(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,388 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/api"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	msgvaultv1 "github.com/wesm/msgvault/proto/msgvault/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeStore struct {
+	messages []store.APIMessage
+}
+
+func (f *fakeStore) GetStats() (*api.StoreStats, error) {
+	return &api.StoreStats{MessageCount: int64(len(f.messages)), ThreadCount: 2, SourceCount: 1}, nil
+}
+
+func (f *fakeStore) ListMessages(offset, limit int) ([]api.APIMessage, int64, error) {
+	return f.page(offset, limit), int64(len(f.messages)), nil
+}
+
+func (f *fakeStore) GetMessage(id int64) (*api.APIMessage, error) {
+	for i := range f.messages {
+		if f.messages[i].ID == id {
+			m := f.messages[i]
+			return &m, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) GetMessagesSummariesByIDs(ids []int64) ([]api.APIMessage, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) SearchMessages(query string, offset, limit int) ([]api.APIMessage, int64, error) {
+	return f.page(offset, limit), int64(len(f.messages)), nil
+}
+
+func (f *fakeStore) SearchMessagesQuery(q *search.Query, offset, limit int) ([]api.APIMessage, int64, error) {
+	return f.page(offset, limit), int64(len(f.messages)), nil
+}
+
+func (f *fakeStore) page(offset, limit int) []store.APIMessage {
+	if offset >= len(f.messages) {
+		return nil
+	}
+	end := min(offset+limit, len(f.messages))
+	return f.messages[offset:end]
+}
+
+type fakeScheduler struct {
+	triggered []string
+}
+
+func (f *fakeScheduler) IsScheduled(email string) bool { return email == "alice@example.com" }
+func (f *fakeScheduler) TriggerSync(email string) error {
+	f.triggered = append(f.triggered, email)
+	return nil
+}
+func (f *fakeScheduler) AddAccount(email, schedule string) error { return nil }
+func (f *fakeScheduler) IsRunning() bool                         { return true }
+func (f *fakeScheduler) Status() []api.AccountStatus {
+	return []api.AccountStatus{{
+		Email:    "alice@example.com",
+		Schedule: "0 2 * * *",
+		NextRun:  time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC),
+	}}
+}
+
+func newTestClient(t *testing.T, apiKey string, st api.MessageStore, sched api.SyncScheduler) msgvaultv1.MsgvaultServiceClient {
+	t.Helper()
+	return newTestClientWithServerConfig(t, config.ServerConfig{APIKey: apiKey}, st, sched)
+}
+
+func newTestClientWithServerConfig(t *testing.T, serverCfg config.ServerConfig, st api.MessageStore, sched api.SyncScheduler) msgvaultv1.MsgvaultServiceClient {
+	t.Helper()
+	cfg := &config.Config{Server: serverCfg}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := NewServer(cfg, st, sched, logger)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() { srv.Shutdown(context.Background()) })
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return msgvaultv1.NewMsgvaultServiceClient(conn)
+}
+
+func testMessages(n int) []store.APIMessage {
+	msgs := make([]store.APIMessage, n)
+	for i := range msgs {
+		msgs[i] = store.APIMessage{
+			ID:      int64(i + 1),
+			Subject: "Message",
+			From:    "bob@example.com",
+			To:      []string{"alice@example.com"},
+			SentAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return msgs
+}
+
+func TestAuth(t *testing.T) {
+	client := newTestClient(t, "secret-key", &fakeStore{}, &fakeScheduler{})
+
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+	}{
+		{"no key", nil, codes.Unauthenticated},
+		{"wrong key", metadata.Pairs("authorization", "Bearer wrong"), codes.Unauthenticated},
+		{"bearer", metadata.Pairs("authorization", "Bearer secret-key"), codes.OK},
+		{"x-api-key", metadata.Pairs("x-api-key", "secret-key"), codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.md != nil {
+				ctx = metadata.NewOutgoingContext(ctx, tt.md)
+			}
+			_, err := client.GetStats(ctx, &msgvaultv1.GetStatsRequest{})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %v, want %v (err=%v)", got, tt.wantCode, err)
+			}
+
+			// Streaming RPCs go through the stream interceptor.
+			stream, err := client.Search(ctx, &msgvaultv1.SearchRequest{Query: "hello"})
+			if err == nil {
+				_, err = stream.Recv()
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("search code = %v, want %v (err=%v)", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+func TestAuth_ScopedUserDenied(t *testing.T) {
+	client := newTestClientWithServerConfig(t, config.ServerConfig{
+		APIKey: "admin-key",
+		Users: []config.ServerUser{
+			{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}},
+			{Name: "ops", APIKey: "ops-key", Admin: true},
+		},
+	}, &fakeStore{}, &fakeScheduler{})
+
+	tests := []struct {
+		key      string
+		wantCode codes.Code
+	}{
+		{"admin-key", codes.OK},
+		{"ops-key", codes.OK},
+		{"alice-key", codes.PermissionDenied},
+		{"nope", codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-api-key", tt.key))
+			_, err := client.GetStats(ctx, &msgvaultv1.GetStatsRequest{})
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %v, want %v (err=%v)", got, tt.wantCode, err)
+			}
+		})
+	}
+}
+
+// tokenStore is a fakeStore that also issues API tokens.
+type tokenStore struct {
+	fakeStore
+	tokens map[string]*store.APIToken
+}
+
+func (f *tokenStore) LookupAPIToken(token string) (*store.APIToken, error) {
+	if t, ok := f.tokens[token]; ok {
+		return t, nil
+	}
+	return nil, store.ErrAPITokenNotFound
+}
+
+func (f *tokenStore) HasActiveAPITokens() (bool, error) { return len(f.tokens) > 0, nil }
+
+func TestAuth_TokenScopes(t *testing.T) {
+	st := &tokenStore{
+		fakeStore: fakeStore{messages: testMessages(3)},
+		tokens: map[string]*store.APIToken{
+			"mvt_reader":   {Name: "reader", Scopes: []string{store.ScopeRead}},
+			"mvt_searcher": {Name: "searcher", Scopes: []string{store.ScopeSearch}},
+			"mvt_admin":    {Name: "ops", Scopes: []string{store.ScopeAdmin}},
+		},
+	}
+	client := newTestClient(t, "", st, &fakeScheduler{})
+
+	call := func(ctx context.Context, method string) error {
+		switch method {
+		case "GetStats":
+			_, err := client.GetStats(ctx, &msgvaultv1.GetStatsRequest{})
+			return err
+		case "Search":
+			stream, err := client.Search(ctx, &msgvaultv1.SearchRequest{Query: "hello"})
+			if err != nil {
+				return err
+			}
+			_, err = stream.Recv()
+			return err
+		default:
+			_, err := client.TriggerSync(ctx, &msgvaultv1.TriggerSyncRequest{Email: "alice@example.com"})
+			return err
+		}
+	}
+
+	tests := []struct {
+		key      string
+		method   string
+		wantCode codes.Code
+	}{
+		{"", "GetStats", codes.Unauthenticated},
+		{"mvt_reader", "GetStats", codes.OK},
+		{"mvt_reader", "Search", codes.PermissionDenied},
+		{"mvt_searcher", "Search", codes.OK},
+		{"mvt_searcher", "GetStats", codes.PermissionDenied},
+		{"mvt_reader", "TriggerSync", codes.PermissionDenied},
+		{"mvt_admin", "TriggerSync", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.method, func(t *testing.T) {
+			ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+tt.key))
+			if got := status.Code(call(ctx, tt.method)); got != tt.wantCode {
+				t.Errorf("code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	client := newTestClient(t, "", &fakeStore{messages: testMessages(3)}, nil)
+
+	resp, err := client.GetStats(context.Background(), &msgvaultv1.GetStatsRequest{})
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if resp.GetTotalMessages() != 3 || resp.GetTotalThreads() != 2 || resp.GetTotalAccounts() != 1 {
+		t.Errorf("unexpected stats: %+v", resp)
+	}
+}
+
+func TestSearch_StreamsAcrossPages(t *testing.T) {
+	client := newTestClient(t, "", &fakeStore{messages: testMessages(250)}, nil)
+
+	tests := []struct {
+		name   string
+		req    *msgvaultv1.SearchRequest
+		wantN  int
+		wantID int64 // ID of the first streamed result
+	}{
+		{"default limit", &msgvaultv1.SearchRequest{Query: "hello"}, defaultSearchLimit, 1},
+		{"limit spans pages", &msgvaultv1.SearchRequest{Query: "hello", Limit: 230}, 230, 1},
+		{"offset near end", &msgvaultv1.SearchRequest{Query: "from:bob@example.com", Offset: 240, Limit: 50}, 10, 241},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.Search(context.Background(), tt.req)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			var got []*msgvaultv1.MessageSummary
+			for {
+				m, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Recv: %v", err)
+				}
+				got = append(got, m)
+			}
+			if len(got) != tt.wantN {
+				t.Fatalf("got %d results, want %d", len(got), tt.wantN)
+			}
+			if got[0].GetId() != tt.wantID {
+				t.Errorf("first id = %d, want %d", got[0].GetId(), tt.wantID)
+			}
+			if got[0].GetSentAt().AsTime().Year() != 2024 {
+				t.Errorf("sent_at = %v, want 2024", got[0].GetSentAt().AsTime())
+			}
+		})
+	}
+}
+
+func TestSearch_EmptyQuery(t *testing.T) {
+	client := newTestClient(t, "", &fakeStore{}, nil)
+
+	stream, err := client.Search(context.Background(), &msgvaultv1.SearchRequest{Query: "  "})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("code = %v, want InvalidArgument", status.Code(err))
+	}
+}
+
+func TestGetMessage(t *testing.T) {
+	msgs := testMessages(1)
+	msgs[0].Body = "hello alice"
+	msgs[0].Attachments = []store.APIAttachment{{ID: 9, Filename: "a.pdf", MimeType: "application/pdf", Size: 42}}
+	client := newTestClient(t, "", &fakeStore{messages: msgs}, nil)
+
+	got, err := client.GetMessage(context.Background(), &msgvaultv1.GetMessageRequest{Id: 1})
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if got.GetBody() != "hello alice" || got.GetSummary().GetFrom() != "bob@example.com" {
+		t.Errorf("unexpected message: %+v", got)
+	}
+	if len(got.GetAttachments()) != 1 || got.GetAttachments()[0].GetId() != 9 {
+		t.Errorf("attachments = %+v", got.GetAttachments())
+	}
+
+	_, err = client.GetMessage(context.Background(), &msgvaultv1.GetMessageRequest{Id: 99})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("missing message code = %v, want NotFound", status.Code(err))
+	}
+}
+
+func TestAccountsAndTriggerSync(t *testing.T) {
+	sched := &fakeScheduler{}
+	client := newTestClient(t, "", nil, sched)
+	ctx := context.Background()
+
+	accts, err := client.ListAccounts(ctx, &msgvaultv1.ListAccountsRequest{})
+	if err != nil {
+		t.Fatalf("ListAccounts: %v", err)
+	}
+	if len(accts.GetAccounts()) != 1 || accts.GetAccounts()[0].GetEmail() != "alice@example.com" {
+		t.Fatalf("accounts = %+v", accts.GetAccounts())
+	}
+	if accts.GetAccounts()[0].GetLastSyncAt() != nil {
+		t.Errorf("last_sync_at should be unset for an account that never ran")
+	}
+
+	resp, err := client.TriggerSync(ctx, &msgvaultv1.TriggerSyncRequest{Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("TriggerSync: %v", err)
+	}
+	if !strings.Contains(resp.GetMessage(), "alice@example.com") || len(sched.triggered) != 1 {
+		t.Errorf("resp = %q, triggered = %v", resp.GetMessage(), sched.triggered)
+	}
+
+	_, err = client.TriggerSync(ctx, &msgvaultv1.TriggerSyncRequest{Email: "carol@example.com"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("unscheduled account code = %v, want NotFound", status.Code(err))
+	}
+
+	// Nil store reports Unavailable rather than panicking.
+	_, err = client.GetStats(ctx, &msgvaultv1.GetStatsRequest{})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("nil store code = %v, want Unavailable", status.Code(err))
+	}
+}
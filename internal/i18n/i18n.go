@@ -0,0 +1,234 @@
+// Package i18n translates user-facing strings and formats numbers and
+// dates for the user's locale.
+//
+// Messages are keyed by their English text, gettext-style, so call
+// sites stay readable and a missing translation falls back to English:
+//
+//	fmt.Println(i18n.T("Messages: %d", n))
+//
+// Catalogs are JSON files under locales/, one per language, embedded
+// in the binary. English needs no catalog. The locale comes from
+// [ui] language in config.toml, else LC_ALL, LC_MESSAGES, or LANG.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed locales/*.json
+var catalogFS embed.FS
+
+// Locale holds one language's messages and formatting conventions.
+type Locale struct {
+	Tag  string `json:"-"`
+	Name string `json:"name"` // the language's own name for itself
+
+	// DecimalSep and GroupSep are the decimal and thousands
+	// separators.
+	DecimalSep string `json:"decimal"`
+	GroupSep   string `json:"group"`
+
+	// DateLayout and DateTimeLayout are Go time layouts for short
+	// dates and date-times. They keep the English widths (10 and 16
+	// columns) so table layouts don't shift between languages.
+	DateLayout     string `json:"date"`
+	DateTimeLayout string `json:"datetime"`
+
+	// Weekdays (Sunday first) and Months are abbreviated names for
+	// long dates.
+	Weekdays []string `json:"weekdays"`
+	Months   []string `json:"months"`
+
+	Messages map[string]string `json:"messages"`
+}
+
+// english is the source language; its messages are the keys.
+var english = &Locale{
+	Tag:            "en",
+	Name:           "English",
+	DecimalSep:     ".",
+	GroupSep:       ",",
+	DateLayout:     "2006-01-02",
+	DateTimeLayout: "2006-01-02 15:04",
+}
+
+var (
+	mu      sync.RWMutex
+	current = english
+)
+
+// Supported returns the available language tags, English first.
+func Supported() []string {
+	tags := []string{"en"}
+	entries, _ := catalogFS.ReadDir("locales")
+	for _, e := range entries {
+		tags = append(tags, strings.TrimSuffix(e.Name(), path.Ext(e.Name())))
+	}
+	slices.Sort(tags[1:])
+	return tags
+}
+
+// Load returns the locale for tag, which must be one of Supported.
+func Load(tag string) (*Locale, error) {
+	if tag == "en" {
+		return english, nil
+	}
+	data, err := catalogFS.ReadFile("locales/" + tag + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("unsupported language %q (available: %s)", tag, strings.Join(Supported(), ", "))
+	}
+	l := &Locale{}
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("parse %s catalog: %w", tag, err)
+	}
+	l.Tag = tag
+	return l, nil
+}
+
+// Set makes tag the active locale.
+func Set(tag string) error {
+	l, err := Load(tag)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	current = l
+	mu.Unlock()
+	return nil
+}
+
+// Current returns the active locale.
+func Current() *Locale {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Detect returns the supported language that best matches configured
+// (a BCP 47 tag such as "de" or "pt-BR"), or when that is empty, the
+// POSIX locale environment. It returns "en" when nothing matches.
+func Detect(configured string) string {
+	candidates := []string{configured}
+	if configured == "" {
+		candidates = []string{os.Getenv("LC_ALL"), os.Getenv("LC_MESSAGES"), os.Getenv("LANG")}
+	}
+	for _, c := range candidates {
+		c = posixToBCP47(c)
+		if c == "" {
+			continue
+		}
+		// The first non-empty setting wins, as in setlocale(3).
+		tag, _ := Match(c)
+		return tag
+	}
+	return "en"
+}
+
+// posixToBCP47 turns "de_DE.UTF-8@euro" into "de-DE". The C and POSIX
+// locales mean "no preference".
+func posixToBCP47(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, ".@"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "C" || s == "POSIX" {
+		return ""
+	}
+	return strings.ReplaceAll(s, "_", "-")
+}
+
+// Match returns the supported language closest to tag, and false
+// (with "en") when none is close enough.
+func Match(tag string) (string, bool) {
+	supported := Supported()
+	tags := make([]language.Tag, len(supported))
+	for i, s := range supported {
+		tags[i] = language.Make(s)
+	}
+	_, idx, conf := language.NewMatcher(tags).Match(language.Make(tag))
+	if conf == language.No {
+		return "en", false
+	}
+	return supported[idx], true
+}
+
+// T returns the active locale's translation of msg, formatted with
+// args as by fmt.Sprintf. msg is returned untranslated when the
+// catalog has no entry for it.
+func T(msg string, args ...any) string {
+	return Current().T(msg, args...)
+}
+
+// T translates msg into l and formats it with args.
+func (l *Locale) T(msg string, args ...any) string {
+	if tr, ok := l.Messages[msg]; ok && tr != "" {
+		msg = tr
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// Number formats n with the active locale's thousands separator.
+func Number(n int64) string { return Current().Number(n) }
+
+// Number formats n with l's thousands separator.
+func (l *Locale) Number(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= 3 {
+		return sign + s
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteString(l.GroupSep)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Decimal formats f with prec decimal places and the active locale's
+// decimal separator.
+func Decimal(f float64, prec int) string { return Current().Decimal(f, prec) }
+
+// Decimal formats f with prec decimal places and l's decimal separator.
+func (l *Locale) Decimal(f float64, prec int) string {
+	return strings.Replace(strconv.FormatFloat(f, 'f', prec, 64), ".", l.DecimalSep, 1)
+}
+
+// Date formats t as a short date in the active locale.
+func Date(t time.Time) string { return t.Format(Current().DateLayout) }
+
+// DateTime formats t as a short date and time in the active locale.
+func DateTime(t time.Time) string { return t.Format(Current().DateTimeLayout) }
+
+// LongDateTime formats t with weekday, month name, and zone, as in a
+// message header.
+func LongDateTime(t time.Time) string { return Current().LongDateTime(t) }
+
+// LongDateTime formats t with l's weekday and month names.
+func (l *Locale) LongDateTime(t time.Time) string {
+	if len(l.Weekdays) != 7 || len(l.Months) != 12 {
+		return t.Format("Mon, 02 Jan 2006 15:04:05 MST")
+	}
+	return fmt.Sprintf("%s, %s %s %s", l.Weekdays[t.Weekday()], t.Format("02"),
+		l.Months[t.Month()-1], t.Format("2006 15:04:05 MST"))
+}
@@ -0,0 +1,206 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setLocale activates tag for the duration of the test.
+func setLocale(t *testing.T, tag string) {
+	t.Helper()
+	if err := Set(tag); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = Set("en") })
+}
+
+func TestSupported(t *testing.T) {
+	got := Supported()
+	if got[0] != "en" || !slices.Contains(got, "de") || !slices.Contains(got, "fr") || !slices.Contains(got, "es") {
+		t.Errorf("Supported() = %v", got)
+	}
+	if err := Set("ja"); err == nil || !strings.Contains(err.Error(), "unsupported language") {
+		t.Errorf("Set(ja) error = %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		env        map[string]string
+		want       string
+	}{
+		{"configured wins", "fr", map[string]string{"LANG": "de_DE.UTF-8"}, "fr"},
+		{"configured region", "es-MX", nil, "es"},
+		{"LANG", "", map[string]string{"LANG": "de_DE.UTF-8"}, "de"},
+		{"LC_ALL over LANG", "", map[string]string{"LC_ALL": "fr_CA.UTF-8", "LANG": "de_DE.UTF-8"}, "fr"},
+		{"LC_MESSAGES over LANG", "", map[string]string{"LC_MESSAGES": "es_ES@euro", "LANG": "de_DE"}, "es"},
+		{"C locale", "", map[string]string{"LANG": "C.UTF-8"}, "en"},
+		{"unsupported", "", map[string]string{"LANG": "ja_JP.UTF-8"}, "en"},
+		{"nothing set", "", nil, "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+				t.Setenv(k, tt.env[k])
+			}
+			if got := Detect(tt.configured); got != tt.want {
+				t.Errorf("Detect(%q) = %q, want %q", tt.configured, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	if got := T("Search: %q", "alice"); got != `Search: "alice"` {
+		t.Errorf("en T() = %q", got)
+	}
+	setLocale(t, "de")
+	if got := T("Search: %q", "alice"); got != `Suche: "alice"` {
+		t.Errorf("de T() = %q", got)
+	}
+	if got := T("Not in any catalog %d", 3); got != "Not in any catalog 3" {
+		t.Errorf("untranslated T() = %q", got)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	when := time.Date(2026, 3, 1, 14, 5, 9, 0, time.UTC)
+	tests := []struct {
+		tag                  string
+		number, negative     string
+		decimal              string
+		date, dateTime, long string
+	}{
+		{"en", "1,234,567", "-1,000", "3.14", "2026-03-01", "2026-03-01 14:05", "Sun, 01 Mar 2026 14:05:09 UTC"},
+		{"de", "1.234.567", "-1.000", "3,14", "01.03.2026", "01.03.2026 14:05", "So, 01 Mär 2026 14:05:09 UTC"},
+		{"fr", "1\u00a0234\u00a0567", "-1\u00a0000", "3,14", "01/03/2026", "01/03/2026 14:05", "dim., 01 mars 2026 14:05:09 UTC"},
+		{"es", "1.234.567", "-1.000", "3,14", "01/03/2026", "01/03/2026 14:05", "dom, 01 mar 2026 14:05:09 UTC"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			setLocale(t, tt.tag)
+			if got := Number(1234567); got != tt.number {
+				t.Errorf("Number() = %q, want %q", got, tt.number)
+			}
+			if got := Number(-1000); got != tt.negative {
+				t.Errorf("Number(-1000) = %q, want %q", got, tt.negative)
+			}
+			if got := Number(999); got != "999" {
+				t.Errorf("Number(999) = %q", got)
+			}
+			if got := Decimal(3.14159, 2); got != tt.decimal {
+				t.Errorf("Decimal() = %q, want %q", got, tt.decimal)
+			}
+			// Short dates keep the English widths so tables line up.
+			if got := Date(when); got != tt.date || len([]rune(got)) != 10 {
+				t.Errorf("Date() = %q, want %q", got, tt.date)
+			}
+			if got := DateTime(when); got != tt.dateTime || len([]rune(got)) != 16 {
+				t.Errorf("DateTime() = %q, want %q", got, tt.dateTime)
+			}
+			if got := LongDateTime(when); got != tt.long {
+				t.Errorf("LongDateTime() = %q, want %q", got, tt.long)
+			}
+		})
+	}
+}
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// TestCatalogs checks that each catalog is well formed, keeps the
+// format verbs of every message, and translates every literal message
+// passed to T in the source tree.
+func TestCatalogs(t *testing.T) {
+	used := literalMessages(t)
+	if len(used) == 0 {
+		t.Fatal("found no i18n.T calls in the source tree")
+	}
+	for _, tag := range Supported()[1:] {
+		t.Run(tag, func(t *testing.T) {
+			l, err := Load(tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Name == "" || l.DecimalSep == "" || l.GroupSep == "" || len(l.Weekdays) != 7 || len(l.Months) != 12 {
+				t.Errorf("incomplete locale settings: %+v", l)
+			}
+			for key, tr := range l.Messages {
+				if !slices.Equal(verbRe.FindAllString(key, -1), verbRe.FindAllString(tr, -1)) {
+					t.Errorf("%q -> %q changes the format verbs", key, tr)
+				}
+			}
+			for _, key := range used {
+				if _, ok := l.Messages[key]; !ok {
+					t.Errorf("missing translation for %q", key)
+				}
+			}
+		})
+	}
+}
+
+// literalMessages returns the string literals passed to i18n.T in the
+// repository's Go files.
+func literalMessages(t *testing.T) []string {
+	t.Helper()
+	root := filepath.Join("..", "..")
+	seen := map[string]bool{}
+	fset := token.NewFileSet()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name := d.Name(); name == "vendor" || name == "node_modules" || (strings.HasPrefix(name, ".") && path != root) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		f, err := parser.ParseFile(fset, path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "T" {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				if s, err := strconv.Unquote(lit.Value); err == nil {
+					seen[s] = true
+				}
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make([]string, 0, len(seen))
+	for s := range seen {
+		out = append(out, s)
+	}
+	slices.Sort(out)
+	return out
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -37,6 +39,14 @@ func WithDateFilter(since, before time.Time) Option {
 	}
 }
 
+// WithUIDState makes listing incremental: a mailbox whose UIDVALIDITY
+// matches prev is searched only for UIDs from its recorded UIDNEXT on.
+// Mailboxes missing from prev, or whose UIDVALIDITY changed, are listed
+// in full. Ignored when a date filter is set.
+func WithUIDState(prev UIDState) Option {
+	return func(c *Client) { c.prevUIDs = prev }
+}
+
 // fetchChunkSize is the maximum number of UIDs per UID FETCH command.
 // Large FETCH sets cause server-side timeouts on big mailboxes; chunking
 // keeps each round-trip short.
@@ -65,6 +75,9 @@ type Client struct {
 	seenRFC822IDs    map[string]bool      // dedup across All Mail + Trash/Spam
 	since            time.Time            // IMAP SINCE date filter (zero = no filter)
 	before           time.Time            // IMAP BEFORE date filter (zero = no filter)
+	prevUIDs         UIDState             // UID state of the last complete sync (nil = list everything)
+	listedUIDs       UIDState             // UID state of mailboxes listed this session
+	selectedUIDs     MailboxUIDs          // UIDVALIDITY/UIDNEXT reported when selectedMailbox was selected
 }
 
 // NewClient creates a new IMAP client.
@@ -181,10 +194,12 @@ func (c *Client) selectMailbox(mailbox string) error {
 	if c.selectedMailbox == mailbox {
 		return nil
 	}
-	if _, err := c.conn.Select(mailbox, nil).Wait(); err != nil {
+	data, err := c.conn.Select(mailbox, nil).Wait()
+	if err != nil {
 		return fmt.Errorf("SELECT %q: %w", mailbox, err)
 	}
 	c.selectedMailbox = mailbox
+	c.selectedUIDs = MailboxUIDs{Validity: data.UIDValidity, Next: uint32(data.UIDNext)}
 	return nil
 }
 
@@ -285,6 +300,27 @@ func (c *Client) enumerateMailbox(
 		criteria.Before = c.before
 	}
 
+	// With a date filter the listing is partial, so UIDs are neither
+	// used nor recorded.
+	trackUIDs := c.since.IsZero() && c.before.IsZero() && c.selectedUIDs.Validity != 0
+	cur := c.selectedUIDs
+	var fromUID imap.UID
+	if prev, ok := c.prevUIDs[mailbox]; ok && trackUIDs {
+		switch {
+		case prev.Validity != cur.Validity:
+			c.logger.Info("UIDVALIDITY changed, listing mailbox in full",
+				"mailbox", mailbox, "old", prev.Validity, "new", cur.Validity)
+		case cur.Next != 0 && cur.Next <= prev.Next:
+			c.recordUIDs(mailbox, cur)
+			return nil, nil
+		case prev.Next > 0:
+			fromUID = imap.UID(prev.Next)
+			// "n:*" always matches the highest UID, even below n, so
+			// results are filtered below as well.
+			criteria.UID = []imap.UIDSet{{imap.UIDRange{Start: fromUID, Stop: 0}}}
+		}
+	}
+
 	searchData, err := c.conn.UIDSearch(
 		criteria,
 		nil,
@@ -313,14 +349,46 @@ func (c *Client) enumerateMailbox(
 		}
 	}
 
+	if trackUIDs {
+		c.recordUIDs(mailbox, cur)
+	}
 	uidSet, ok := searchData.All.(imap.UIDSet)
 	if !ok {
 		return nil, nil
 	}
 	uids, _ := uidSet.Nums()
+	if fromUID > 0 {
+		uids = slices.DeleteFunc(uids, func(uid imap.UID) bool { return uid < fromUID })
+	}
 	return uids, nil
 }
 
+// recordUIDs notes that mailbox was listed up to uids.Next.
+// Caller must hold mu.
+func (c *Client) recordUIDs(mailbox string, uids MailboxUIDs) {
+	if c.listedUIDs == nil {
+		c.listedUIDs = make(UIDState)
+	}
+	c.listedUIDs[mailbox] = uids
+}
+
+// Cursor returns the UID state to store once every listed message has
+// been fetched: the state of the mailboxes listed this session, over
+// the starting state for any that were skipped. It returns "" when a
+// date filter limited the listing, which leaves the stored state as it
+// was.
+func (c *Client) Cursor() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.since.IsZero() || !c.before.IsZero() {
+		return ""
+	}
+	st := make(UIDState, len(c.prevUIDs)+len(c.listedUIDs))
+	maps.Copy(st, c.prevUIDs)
+	maps.Copy(st, c.listedUIDs)
+	return st.String()
+}
+
 // fetchMailboxMessageIDs fetches RFC822 Message-ID headers for all
 // UIDs in the given mailbox using ENVELOPE. Returns a map of
 // Message-ID → true for all messages found.
@@ -508,6 +576,10 @@ func (c *Client) buildMessageListCache(ctx context.Context) error {
 		c.logger.Debug("listed mailbox", "mailbox", mailbox, "count", len(uids))
 	}
 
+	if messages == nil {
+		// Nothing new; keep the empty list from being rebuilt.
+		messages = []gmailapi.MessageID{}
+	}
 	c.messageListCache = messages
 	return nil
 }
@@ -0,0 +1,43 @@
+package imap
+
+import "encoding/json"
+
+// MailboxUIDs records where listing of one mailbox left off. UIDs in
+// a mailbox only grow while its UIDVALIDITY stays the same, so every
+// message added since has a UID of at least Next.
+type MailboxUIDs struct {
+	Validity uint32 `json:"v"`
+	Next     uint32 `json:"n"`
+}
+
+// UIDState maps mailbox names to their UIDs as of the last complete
+// sync. It is stored as the source's sync cursor, so incremental IMAP
+// sync needs no schema of its own.
+type UIDState map[string]MailboxUIDs
+
+// ParseUIDState decodes a sync cursor written by UIDState.String. Any
+// other cursor, including the "0" stored by syncs before UID tracking,
+// yields nil, which means every mailbox is listed in full.
+func ParseUIDState(cursor string) UIDState {
+	if cursor == "" || cursor[0] != '{' {
+		return nil
+	}
+	var st UIDState
+	if err := json.Unmarshal([]byte(cursor), &st); err != nil {
+		return nil
+	}
+	return st
+}
+
+// String encodes the state as a sync cursor.
+func (st UIDState) String() string {
+	if len(st) == 0 {
+		return ""
+	}
+	// encoding/json sorts map keys, so equal states encode equally.
+	data, err := json.Marshal(st)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,197 @@
+package imap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	imap "github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	"github.com/emersion/go-imap/v2/imapserver/imapmemserver"
+)
+
+func TestParseUIDState(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		want   UIDState
+	}{
+		{"empty", "", nil},
+		{"legacy history ID", "0", nil},
+		{"malformed", "{not json", nil},
+		{"state", `{"INBOX":{"v":7,"n":42}}`, UIDState{"INBOX": {Validity: 7, Next: 42}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseUIDState(tt.cursor)
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseUIDState(%q) = %v, want %v", tt.cursor, got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("ParseUIDState(%q)[%q] = %v, want %v", tt.cursor, k, got[k], v)
+				}
+			}
+		})
+	}
+
+	st := UIDState{"INBOX": {Validity: 7, Next: 42}, "Archive": {Validity: 3, Next: 9}}
+	if got := ParseUIDState(st.String()); len(got) != 2 || got["Archive"] != st["Archive"] {
+		t.Errorf("round trip = %v", got)
+	}
+	if got := (UIDState{}).String(); got != "" {
+		t.Errorf("empty state String() = %q", got)
+	}
+}
+
+// literal is an imap.LiteralReader over a string.
+type literal struct{ *strings.Reader }
+
+func (l literal) Size() int64 { return int64(l.Len()) }
+
+// startMemServer runs an in-memory IMAP server with one user whose
+// INBOX holds n messages, and returns the user and a client config.
+func startMemServer(t *testing.T, n int) (*imapmemserver.User, *Config) {
+	t.Helper()
+	mem := imapmemserver.New()
+	user := imapmemserver.NewUser("alice@example.com", "secret")
+	if err := user.Create("INBOX", nil); err != nil {
+		t.Fatal(err)
+	}
+	mem.AddUser(user)
+	appendMessages(t, user, "INBOX", n)
+
+	srv := imapserver.New(&imapserver.Options{
+		NewSession: func(*imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return mem.NewSession(), nil, nil
+		},
+		Caps:         imap.CapSet{imap.CapIMAP4rev1: {}},
+		InsecureAuth: true,
+	})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	host, port, _ := net.SplitHostPort(lis.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return user, &Config{Host: host, Port: p, Username: "alice@example.com"}
+}
+
+func appendMessages(t *testing.T, user *imapmemserver.User, mailbox string, n int) {
+	t.Helper()
+	for i := range n {
+		raw := fmt.Sprintf("From: bob@example.com\r\nTo: alice@example.com\r\n"+
+			"Subject: %s message %d\r\n\r\nHello\r\n", mailbox, i)
+		if _, err := user.Append(mailbox, literal{strings.NewReader(raw)}, &imap.AppendOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listAll lists every message ID the client returns, and its cursor.
+func listAll(t *testing.T, cfg *Config, opts ...Option) ([]string, string) {
+	t.Helper()
+	c := NewClient(cfg, "secret", opts...)
+	defer func() { _ = c.Close() }()
+
+	var ids []string
+	token := ""
+	for {
+		resp, err := c.ListMessages(context.Background(), "", token)
+		if err != nil {
+			t.Fatalf("ListMessages: %v", err)
+		}
+		for _, m := range resp.Messages {
+			ids = append(ids, m.ID)
+		}
+		if resp.NextPageToken == "" {
+			break
+		}
+		token = resp.NextPageToken
+	}
+	return ids, c.Cursor()
+}
+
+func TestClient_IncrementalListing(t *testing.T) {
+	user, cfg := startMemServer(t, 3)
+
+	ids, cursor := listAll(t, cfg)
+	if len(ids) != 3 {
+		t.Fatalf("first sync listed %v, want 3 messages", ids)
+	}
+	st := ParseUIDState(cursor)
+	if st["INBOX"].Next != 4 || st["INBOX"].Validity == 0 {
+		t.Fatalf("cursor after first sync = %q", cursor)
+	}
+
+	// Nothing new: the mailbox is not searched at all.
+	ids, again := listAll(t, cfg, WithUIDState(st))
+	if len(ids) != 0 || again != cursor {
+		t.Errorf("unchanged mailbox listed %v, cursor %q", ids, again)
+	}
+
+	appendMessages(t, user, "INBOX", 2)
+	ids, cursor = listAll(t, cfg, WithUIDState(st))
+	if want := []string{"INBOX|4", "INBOX|5"}; !slices.Equal(ids, want) {
+		t.Errorf("incremental sync listed %v, want %v", ids, want)
+	}
+	st = ParseUIDState(cursor)
+	if st["INBOX"].Next != 6 {
+		t.Errorf("cursor after incremental sync = %q", cursor)
+	}
+
+	// A mailbox the state doesn't know is listed in full.
+	if err := user.Create("Archive", nil); err != nil {
+		t.Fatal(err)
+	}
+	appendMessages(t, user, "Archive", 1)
+	ids, cursor = listAll(t, cfg, WithUIDState(st))
+	if want := []string{"Archive|1"}; !slices.Equal(ids, want) {
+		t.Errorf("new mailbox listed %v, want %v", ids, want)
+	}
+	if st := ParseUIDState(cursor); st["Archive"].Next != 2 || st["INBOX"].Next != 6 {
+		t.Errorf("cursor after new mailbox = %q", cursor)
+	}
+}
+
+func TestClient_UIDValidityChange(t *testing.T) {
+	user, cfg := startMemServer(t, 2)
+	_, cursor := listAll(t, cfg)
+	st := ParseUIDState(cursor)
+
+	// Re-creating the mailbox resets its UIDs under a new UIDVALIDITY.
+	if err := user.Delete("INBOX"); err != nil {
+		t.Fatal(err)
+	}
+	if err := user.Create("INBOX", nil); err != nil {
+		t.Fatal(err)
+	}
+	appendMessages(t, user, "INBOX", 3)
+
+	ids, cursor := listAll(t, cfg, WithUIDState(st))
+	if want := []string{"INBOX|1", "INBOX|2", "INBOX|3"}; !slices.Equal(ids, want) {
+		t.Errorf("listed %v after UIDVALIDITY change, want %v", ids, want)
+	}
+	got := ParseUIDState(cursor)["INBOX"]
+	if got.Validity == st["INBOX"].Validity || got.Next != 4 {
+		t.Errorf("cursor after UIDVALIDITY change = %q", cursor)
+	}
+}
+
+func TestClient_CursorWithDateFilter(t *testing.T) {
+	_, cfg := startMemServer(t, 1)
+	st := UIDState{"INBOX": {Validity: 1, Next: 1}}
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, cursor := listAll(t, cfg, WithUIDState(st), WithDateFilter(since, since.AddDate(1, 0, 0)))
+	if cursor != "" {
+		t.Errorf("date-filtered sync returned cursor %q, want none", cursor)
+	}
+}
@@ -0,0 +1,186 @@
+package imapgateway
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+var errSearchTooBroad = &imap.Error{
+	Type: imap.StatusResponseTypeNo,
+	Code: imap.ResponseCodeLimit,
+	Text: "Search matches too many messages; narrow the criteria",
+}
+
+// matcher reports whether the message at a sequence number matches.
+type matcher func(seqNum uint32, m store.MailboxMessage) bool
+
+func (sess *session) Search(kind imapserver.NumKind, criteria *imap.SearchCriteria, options *imap.SearchOptions) (*imap.SearchData, error) {
+	v := sess.selected
+	match, err := sess.compile(v, criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &imap.SearchData{}
+	var (
+		seqSet imap.SeqSet
+		uidSet imap.UIDSet
+	)
+	for i, m := range v.msgs {
+		seqNum := uint32(i + 1)
+		if !match(seqNum, m) {
+			continue
+		}
+		uid := imap.UID(m.ID)
+		uidSet.AddNum(uid)
+		num := seqNum
+		if kind == imapserver.NumKindUID {
+			num = uint32(uid)
+		} else {
+			seqSet.AddNum(seqNum)
+		}
+		if data.Min == 0 || num < data.Min {
+			data.Min = num
+		}
+		data.Max = max(data.Max, num)
+		data.Count++
+	}
+	if kind == imapserver.NumKindUID {
+		data.All = uidSet
+	} else {
+		data.All = seqSet
+	}
+	if options.ReturnSave {
+		v.searchRes = uidSet
+	}
+	return data, nil
+}
+
+// compile turns search criteria into a matcher over the selected
+// mailbox. Address, subject, text, and sent-date criteria are answered by
+// the query engine (one search per criteria node, so NOT and OR compose
+// normally); flags, internal dates, sizes, and number sets are checked
+// against the mailbox snapshot.
+func (sess *session) compile(v *mailboxView, c *imap.SearchCriteria) (matcher, error) {
+	var preds []matcher
+
+	var f mailview.Filter
+	for _, h := range c.Header {
+		if !f.AddHeader(h.Key, h.Value) {
+			return nil, &imap.Error{
+				Type: imap.StatusResponseTypeNo,
+				Code: imap.ResponseCodeCannot,
+				Text: "Unsupported search header: " + h.Key,
+			}
+		}
+	}
+	f.AddText(c.Body...)
+	f.AddText(c.Text...)
+	f.SentSince, f.SentBefore = c.SentSince, c.SentBefore
+	if !f.IsEmpty() {
+		ids, err := sess.engineMatches(f.Query(sess.sourceIDs))
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return ids[m.ID] })
+	}
+
+	for _, set := range c.SeqNum {
+		set := v.staticNumSet(set).(imap.SeqSet)
+		preds = append(preds, func(seqNum uint32, _ store.MailboxMessage) bool { return set.Contains(seqNum) })
+	}
+	for _, set := range c.UID {
+		set := v.staticNumSet(set).(imap.UIDSet)
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return set.Contains(imap.UID(m.ID)) })
+	}
+	if !c.Since.IsZero() {
+		since := mailview.DateOnly(c.Since)
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return !m.InternalDate.Before(since) })
+	}
+	if !c.Before.IsZero() {
+		before := mailview.DateOnly(c.Before)
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return m.InternalDate.Before(before) })
+	}
+	if c.Larger > 0 {
+		larger := c.Larger
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return m.Size > larger })
+	}
+	if c.Smaller > 0 {
+		smaller := c.Smaller
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return m.Size < smaller })
+	}
+	for _, f := range c.Flag {
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return hasFlag(m, f) })
+	}
+	for _, f := range c.NotFlag {
+		preds = append(preds, func(_ uint32, m store.MailboxMessage) bool { return !hasFlag(m, f) })
+	}
+	for i := range c.Not {
+		inner, err := sess.compile(v, &c.Not[i])
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, func(seqNum uint32, m store.MailboxMessage) bool { return !inner(seqNum, m) })
+	}
+	for i := range c.Or {
+		left, err := sess.compile(v, &c.Or[i][0])
+		if err != nil {
+			return nil, err
+		}
+		right, err := sess.compile(v, &c.Or[i][1])
+		if err != nil {
+			return nil, err
+		}
+		preds = append(preds, func(seqNum uint32, m store.MailboxMessage) bool {
+			return left(seqNum, m) || right(seqNum, m)
+		})
+	}
+
+	return func(seqNum uint32, m store.MailboxMessage) bool {
+		for _, p := range preds {
+			if !p(seqNum, m) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// engineMatches runs q through the query engine and returns the matching
+// message IDs.
+func (sess *session) engineMatches(q *search.Query) (map[int64]bool, error) {
+	if sess.srv.searcher == nil {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeCannot,
+			Text: "Content search is not available",
+		}
+	}
+	ids, err := mailview.Match(context.Background(), sess.srv.searcher, q)
+	if errors.Is(err, mailview.ErrTooBroad) {
+		return nil, errSearchTooBroad
+	}
+	if err != nil {
+		sess.srv.logger.Error("imap: search failed", "error", err)
+		return nil, errUnavailable
+	}
+	return ids, nil
+}
+
+func hasFlag(m store.MailboxMessage, f imap.Flag) bool {
+	switch {
+	case strings.EqualFold(string(f), string(imap.FlagSeen)):
+		return m.Seen
+	case strings.EqualFold(string(f), string(imap.FlagFlagged)):
+		return m.Flagged
+	}
+	// No other flag is ever set in the archive.
+	return false
+}
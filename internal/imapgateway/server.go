@@ -0,0 +1,153 @@
+// Package imapgateway serves the archive over IMAP so ordinary mail
+// clients can browse it, including mail long since deleted from the
+// provider. The gateway is read-only: labels appear as mailboxes, a
+// virtual "All Mail" mailbox holds every email message, and SEARCH is
+// answered by the query engine. Mutating commands are refused.
+package imapgateway
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"strconv"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	"github.com/wesm/msgvault/internal/api"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Store is the archive access the gateway needs.
+type Store interface {
+	mailview.Store
+	GetMessageRaw(id int64) ([]byte, error)
+	SourceIDsForAccounts(identifiers []string) ([]int64, error)
+}
+
+// Server is the read-only IMAP gateway.
+type Server struct {
+	cfg      *config.Config
+	store    Store
+	searcher mailview.Searcher
+	tokens   api.TokenStore // nil unless the store issues API tokens
+	logger   *slog.Logger
+	imap     *imapserver.Server
+}
+
+// NewServer creates a gateway. searcher may be nil, in which case
+// SEARCH only supports criteria evaluated locally (flags, dates, sizes,
+// and sequence sets).
+func NewServer(cfg *config.Config, st Store, searcher mailview.Searcher, logger *slog.Logger) *Server {
+	s := &Server{cfg: cfg, store: st, searcher: searcher, logger: logger}
+	s.tokens, _ = st.(api.TokenStore)
+	s.imap = imapserver.New(&imapserver.Options{
+		NewSession: func(*imapserver.Conn) (imapserver.Session, *imapserver.GreetingData, error) {
+			return &session{srv: s}, nil, nil
+		},
+		Caps: imap.CapSet{
+			imap.CapIMAP4rev1:  {},
+			imap.CapIdle:       {},
+			imap.CapSpecialUse: {},
+		},
+		Logger: slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+		// Credentials travel in the clear, matching the REST API's
+		// posture; ValidateSecure still requires a key off-loopback.
+		InsecureAuth: true,
+	})
+	return s
+}
+
+// Addr returns the configured listen address, or "" when the gateway is
+// disabled ([server] imap_port unset).
+func Addr(cfg *config.Config) string {
+	if cfg.Server.IMAPPort == 0 {
+		return ""
+	}
+	bindAddr := cfg.Server.BindAddr
+	if bindAddr == "" {
+		bindAddr = "127.0.0.1"
+	}
+	return net.JoinHostPort(bindAddr, strconv.Itoa(cfg.Server.IMAPPort))
+}
+
+// Start listens on the configured address and serves until Close is
+// called. Returns an error if the security posture is invalid.
+func (s *Server) Start() error {
+	if err := s.cfg.Server.ValidateSecureWith(api.RequiresAuth(s.cfg.Server, s.tokens)); err != nil {
+		return err
+	}
+	addr := Addr(s.cfg)
+	if addr == "" {
+		return errors.New("imap gateway disabled: [server] imap_port is not set")
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("starting IMAP gateway", "addr", addr)
+	return s.Serve(lis)
+}
+
+// Serve accepts connections on lis. Exposed for tests that listen on
+// an ephemeral port.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.imap.Serve(lis)
+}
+
+// Close stops accepting connections and closes open sessions.
+func (s *Server) Close() error {
+	return s.imap.Close()
+}
+
+// login resolves IMAP credentials. The password is an API key or API
+// token (which needs the read scope); the username must match the
+// [[server.users]] name for scoped keys and is ignored otherwise.
+// Returns the sources the session may read (nil for every source).
+func (s *Server) login(username, password string) ([]int64, error) {
+	if !api.RequiresAuth(s.cfg.Server, s.tokens) {
+		return nil, nil
+	}
+	principal, ok := api.ResolveKey(s.cfg.Server, s.tokens, password)
+	if !ok || !principal.HasScope(store.ScopeRead) {
+		return nil, imapserver.ErrAuthFailed
+	}
+	if principal.Admin || principal.AllAccounts {
+		return nil, nil
+	}
+	if username != principal.Name {
+		return nil, imapserver.ErrAuthFailed
+	}
+	ids, err := s.store.SourceIDsForAccounts(principal.Accounts)
+	if err != nil {
+		s.logger.Error("imap: failed to resolve user accounts", "user", principal.Name, "error", err)
+		return nil, errUnavailable
+	}
+	if len(ids) == 0 {
+		return nil, &imap.Error{
+			Type: imap.StatusResponseTypeNo,
+			Code: imap.ResponseCodeAuthorizationFailed,
+			Text: "No synced accounts are visible to this user",
+		}
+	}
+	return ids, nil
+}
+
+var (
+	errReadOnly = &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Code: imap.ResponseCodeNoPerm,
+		Text: "The msgvault archive is read-only",
+	}
+	errUnavailable = &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Code: imap.ResponseCodeUnavailable,
+		Text: "Archive temporarily unavailable",
+	}
+	errNoMailbox = &imap.Error{
+		Type: imap.StatusResponseTypeNo,
+		Code: imap.ResponseCodeNonExistent,
+		Text: "No such mailbox",
+	}
+)
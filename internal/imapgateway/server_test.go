@@ -0,0 +1,447 @@
+package imapgateway
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapclient"
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+type fakeMessage struct {
+	store.MailboxMessage
+	sourceID int64
+	labels   []string
+	raw      []byte
+}
+
+type fakeStore struct {
+	messages []fakeMessage
+	sources  map[string][]int64
+}
+
+func (f *fakeStore) visible(m fakeMessage, sourceIDs []int64) bool {
+	return len(sourceIDs) == 0 || slices.Contains(sourceIDs, m.sourceID)
+}
+
+func (f *fakeStore) MailboxNames(sourceIDs []int64) ([]string, error) {
+	var names []string
+	for _, m := range f.messages {
+		if f.visible(m, sourceIDs) {
+			names = append(names, m.labels...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+func (f *fakeStore) MailboxMessages(label string, sourceIDs []int64) ([]store.MailboxMessage, error) {
+	var out []store.MailboxMessage
+	for _, m := range f.messages {
+		if f.visible(m, sourceIDs) && (label == "" || slices.Contains(m.labels, label)) {
+			out = append(out, m.MailboxMessage)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) GetMessageRaw(id int64) ([]byte, error) {
+	for _, m := range f.messages {
+		if m.ID == id && m.raw != nil {
+			return m.raw, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeStore) SourceIDsForAccounts(identifiers []string) ([]int64, error) {
+	var ids []int64
+	for _, ident := range identifiers {
+		ids = append(ids, f.sources[ident]...)
+	}
+	return ids, nil
+}
+
+// fakeSearcher returns a fixed set of IDs and records the queries it
+// receives.
+type fakeSearcher struct {
+	mu      sync.Mutex
+	ids     []int64
+	queries []search.Query
+}
+
+func (f *fakeSearcher) Search(_ context.Context, q *search.Query, limit, offset int) ([]query.MessageSummary, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, *q)
+	var out []query.MessageSummary
+	for _, id := range f.ids {
+		out = append(out, query.MessageSummary{ID: id})
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func (f *fakeSearcher) recorded() []search.Query {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.queries)
+}
+
+func (f *fakeSearcher) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = nil
+}
+
+const rawHello = "From: Bob <bob@example.com>\r\n" +
+	"To: alice@example.com\r\n" +
+	"Subject: Hello\r\n" +
+	"Date: Mon, 01 Jan 2024 10:00:00 +0000\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Hi Alice\r\n"
+
+func newFakeStore() *fakeStore {
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	return &fakeStore{
+		messages: []fakeMessage{
+			{
+				MailboxMessage: store.MailboxMessage{ID: 10, Size: int64(len(rawHello)), InternalDate: date, Seen: true},
+				sourceID:       1,
+				labels:         []string{"INBOX"},
+				raw:            []byte(rawHello),
+			},
+			{
+				MailboxMessage: store.MailboxMessage{ID: 11, Size: 500, InternalDate: date.AddDate(0, 1, 0), Flagged: true},
+				sourceID:       1,
+				labels:         []string{"INBOX", "Work/Projects"},
+			},
+			{
+				MailboxMessage: store.MailboxMessage{ID: 20, Size: 700, InternalDate: date.AddDate(0, 2, 0)},
+				sourceID:       2,
+				labels:         []string{"Receipts"},
+			},
+		},
+		sources: map[string][]int64{"alice@example.com": {1}},
+	}
+}
+
+func startGateway(t *testing.T, cfg *config.Config, st Store, searcher mailview.Searcher) *imapclient.Client {
+	t.Helper()
+	srv := NewServer(cfg, st, searcher, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := imapclient.DialInsecure(lis.Addr().String(), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func login(t *testing.T, c *imapclient.Client) {
+	t.Helper()
+	if err := c.Login("alice", "any").Wait(); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+}
+
+func TestGateway_ListMailboxes(t *testing.T) {
+	c := startGateway(t, &config.Config{}, newFakeStore(), nil)
+	login(t, c)
+
+	list, err := c.List("", "*", nil).Collect()
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	attrs := make(map[string][]imap.MailboxAttr)
+	for _, l := range list {
+		attrs[l.Mailbox] = l.Attrs
+	}
+	for _, want := range []string{"INBOX", "All Mail", "Work", "Work/Projects", "Receipts"} {
+		if _, ok := attrs[want]; !ok {
+			t.Errorf("mailbox %q missing from %v", want, attrs)
+		}
+	}
+	if !slices.Contains(attrs["All Mail"], imap.MailboxAttrAll) {
+		t.Errorf("All Mail attrs = %v, want \\All", attrs["All Mail"])
+	}
+	if !slices.Contains(attrs["Work"], imap.MailboxAttrNoSelect) {
+		t.Errorf("Work attrs = %v, want \\Noselect for label-less parent", attrs["Work"])
+	}
+}
+
+func TestGateway_SelectAndFetch(t *testing.T) {
+	c := startGateway(t, &config.Config{}, newFakeStore(), nil)
+	login(t, c)
+
+	data, err := c.Select("INBOX", nil).Wait()
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if data.NumMessages != 2 || data.UIDNext != 12 || data.UIDValidity != uidValidity {
+		t.Errorf("select data = %+v", data)
+	}
+
+	section := &imap.FetchItemBodySection{}
+	msgs, err := c.Fetch(imap.SeqSetNum(1, 2), &imap.FetchOptions{
+		UID:         true,
+		Flags:       true,
+		Envelope:    true,
+		RFC822Size:  true,
+		BodySection: []*imap.FetchItemBodySection{section},
+	}).Collect()
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("fetched %d messages, want 2", len(msgs))
+	}
+
+	first := msgs[0]
+	if first.UID != 10 || first.Envelope == nil || first.Envelope.Subject != "Hello" {
+		t.Errorf("first message = uid %d, envelope %+v", first.UID, first.Envelope)
+	}
+	if !slices.Contains(first.Flags, imap.FlagSeen) {
+		t.Errorf("flags = %v, want \\Seen", first.Flags)
+	}
+	if body := string(first.FindBodySection(section)); !strings.Contains(body, "Hi Alice") {
+		t.Errorf("body = %q", body)
+	}
+
+	// Messages without stored raw MIME are served with an empty body
+	// rather than failing the whole FETCH.
+	second := msgs[1]
+	if second.UID != 11 || strings.TrimSpace(string(second.FindBodySection(section))) != "" {
+		t.Errorf("second message = uid %d, body %q", second.UID, second.FindBodySection(section))
+	}
+	if !slices.Contains(second.Flags, imap.FlagFlagged) || slices.Contains(second.Flags, imap.FlagSeen) {
+		t.Errorf("second flags = %v", second.Flags)
+	}
+}
+
+func TestGateway_Search(t *testing.T) {
+	// The engine matches message 20 too, but it isn't in INBOX.
+	searcher := &fakeSearcher{ids: []int64{11, 20}}
+	c := startGateway(t, &config.Config{}, newFakeStore(), searcher)
+	login(t, c)
+	if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		criteria *imap.SearchCriteria
+		want     []imap.UID
+		engine   bool
+	}{
+		{
+			name: "from header uses engine",
+			criteria: &imap.SearchCriteria{
+				Header: []imap.SearchCriteriaHeaderField{{Key: "From", Value: "Bob@example.com"}},
+			},
+			want:   []imap.UID{11},
+			engine: true,
+		},
+		{
+			name:     "flag evaluated locally",
+			criteria: &imap.SearchCriteria{NotFlag: []imap.Flag{imap.FlagSeen}},
+			want:     []imap.UID{11},
+		},
+		{
+			name:     "internal date evaluated locally",
+			criteria: &imap.SearchCriteria{Before: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+			want:     []imap.UID{10},
+		},
+		{
+			name: "not engine criterion",
+			criteria: &imap.SearchCriteria{Not: []imap.SearchCriteria{{
+				Text: []string{"invoice"},
+			}}},
+			want:   []imap.UID{10},
+			engine: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher.reset()
+			data, err := c.UIDSearch(tt.criteria, nil).Wait()
+			if err != nil {
+				t.Fatalf("search: %v", err)
+			}
+			if got := data.AllUIDs(); !slices.Equal(got, tt.want) {
+				t.Errorf("uids = %v, want %v", got, tt.want)
+			}
+			if got := len(searcher.recorded()); tt.engine != (got > 0) {
+				t.Errorf("engine queries = %d, want engine used: %v", got, tt.engine)
+			}
+		})
+	}
+
+	if _, err := c.UIDSearch(&imap.SearchCriteria{
+		Header: []imap.SearchCriteriaHeaderField{{Key: "X-Mailer", Value: "foo"}},
+	}, nil).Wait(); err == nil {
+		t.Error("expected unsupported header search to fail")
+	}
+}
+
+func TestGateway_SearchPassesAccountScope(t *testing.T) {
+	searcher := &fakeSearcher{ids: []int64{10}}
+	cfg := &config.Config{Server: config.ServerConfig{
+		Users: []config.ServerUser{{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}}},
+	}}
+	c := startGateway(t, cfg, newFakeStore(), searcher)
+	if err := c.Login("alice", "alice-key").Wait(); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if _, err := c.Select("All Mail", nil).Wait(); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	data, err := c.UIDSearch(&imap.SearchCriteria{Text: []string{"hello"}}, nil).Wait()
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if got := data.AllUIDs(); !slices.Equal(got, []imap.UID{10}) {
+		t.Errorf("uids = %v", got)
+	}
+	if qs := searcher.recorded(); len(qs) != 1 || !slices.Equal(qs[0].AccountIDs, []int64{1}) {
+		t.Errorf("queries = %+v, want AccountIDs [1]", qs)
+	}
+}
+
+func TestGateway_Login(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{
+		APIKey: "admin-key",
+		Users: []config.ServerUser{
+			{Name: "alice", APIKey: "alice-key", Accounts: []string{"alice@example.com"}},
+			{Name: "bob", APIKey: "bob-key", Accounts: []string{"nobody@example.com"}},
+		},
+	}}
+	tests := []struct {
+		name      string
+		user      string
+		pass      string
+		wantErr   bool
+		wantBoxes []string
+	}{
+		{"admin key sees everything", "whoever", "admin-key", false, []string{"INBOX", "Receipts"}},
+		{"scoped user", "alice", "alice-key", false, []string{"INBOX"}},
+		{"username must match key", "bob", "alice-key", true, nil},
+		{"wrong key", "alice", "nope", true, nil},
+		{"user without synced accounts", "bob", "bob-key", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := startGateway(t, cfg, newFakeStore(), nil)
+			err := c.Login(tt.user, tt.pass).Wait()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected login failure")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("login: %v", err)
+			}
+			list, err := c.List("", "*", nil).Collect()
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			var names []string
+			for _, l := range list {
+				names = append(names, l.Mailbox)
+			}
+			for _, want := range tt.wantBoxes {
+				if !slices.Contains(names, want) {
+					t.Errorf("mailboxes = %v, missing %q", names, want)
+				}
+			}
+			if !slices.Contains(tt.wantBoxes, "Receipts") && slices.Contains(names, "Receipts") {
+				t.Errorf("scoped user sees another account's labels: %v", names)
+			}
+		})
+	}
+}
+
+// tokenStore is a fakeStore that also issues API tokens.
+type tokenStore struct {
+	*fakeStore
+	tokens map[string]*store.APIToken
+}
+
+func (f *tokenStore) LookupAPIToken(token string) (*store.APIToken, error) {
+	if t, ok := f.tokens[token]; ok {
+		return t, nil
+	}
+	return nil, store.ErrAPITokenNotFound
+}
+
+func (f *tokenStore) HasActiveAPITokens() (bool, error) { return len(f.tokens) > 0, nil }
+
+func TestGateway_TokenLogin(t *testing.T) {
+	tests := []struct {
+		name    string
+		pass    string
+		wantErr bool
+	}{
+		{"read token", "mvt_reader", false},
+		{"search-only token", "mvt_searcher", true},
+		{"unknown token", "mvt_nope", true},
+		{"tokens require auth even without a config key", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := &tokenStore{fakeStore: newFakeStore(), tokens: map[string]*store.APIToken{
+				"mvt_reader":   {Name: "reader", Scopes: []string{store.ScopeRead}},
+				"mvt_searcher": {Name: "searcher", Scopes: []string{store.ScopeSearch}},
+			}}
+			c := startGateway(t, &config.Config{}, st, nil)
+			err := c.Login("anyone", tt.pass).Wait()
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("login err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGateway_ReadOnly(t *testing.T) {
+	c := startGateway(t, &config.Config{}, newFakeStore(), nil)
+	login(t, c)
+	if _, err := c.Select("INBOX", nil).Wait(); err != nil {
+		t.Fatalf("select: %v", err)
+	}
+
+	if err := c.Create("New", nil).Wait(); err == nil {
+		t.Error("CREATE should be refused")
+	}
+	if err := c.Store(imap.SeqSetNum(1), &imap.StoreFlags{
+		Op:    imap.StoreFlagsAdd,
+		Flags: []imap.Flag{imap.FlagDeleted},
+	}, nil).Close(); err == nil {
+		t.Error("STORE should be refused")
+	}
+	if _, err := c.Select("Nope", nil).Wait(); err == nil {
+		t.Error("SELECT of unknown mailbox should fail")
+	}
+}
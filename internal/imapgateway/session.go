@@ -0,0 +1,383 @@
+package imapgateway
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/emersion/go-imap/v2"
+	"github.com/emersion/go-imap/v2/imapserver"
+	"github.com/emersion/go-message/textproto"
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// uidValidity is constant: UIDs are archive message IDs, which are
+// never reused.
+const uidValidity = 1
+
+// session is one authenticated IMAP connection.
+type session struct {
+	srv       *Server
+	loggedIn  bool
+	sourceIDs []int64 // nil = every source
+	selected  *mailboxView
+}
+
+// mailboxView is a snapshot of a selected mailbox. The archive only
+// grows between syncs, so the snapshot is not refreshed until the
+// client selects the mailbox again.
+type mailboxView struct {
+	name      string
+	msgs      []store.MailboxMessage // ascending ID; sequence number = index+1
+	searchRes imap.UIDSet
+}
+
+var _ imapserver.Session = (*session)(nil)
+
+func (sess *session) Close() error { return nil }
+
+func (sess *session) Login(username, password string) error {
+	ids, err := sess.srv.login(username, password)
+	if err != nil {
+		sess.srv.logger.Warn("imap login failed", "user", username)
+		return err
+	}
+	sess.loggedIn = true
+	sess.sourceIDs = ids
+	return nil
+}
+
+func (sess *session) mailboxes() (*mailview.Mailboxes, error) {
+	mb, err := mailview.LoadMailboxes(sess.srv.store, sess.sourceIDs)
+	if err != nil {
+		sess.srv.logger.Error("imap: failed to list mailboxes", "error", err)
+		return nil, errUnavailable
+	}
+	return mb, nil
+}
+
+func (sess *session) load(name string) ([]store.MailboxMessage, error) {
+	mb, err := sess.mailboxes()
+	if err != nil {
+		return nil, err
+	}
+	label, ok := mb.Resolve(name)
+	if !ok {
+		return nil, errNoMailbox
+	}
+	msgs, err := sess.srv.store.MailboxMessages(label, sess.sourceIDs)
+	if err != nil {
+		sess.srv.logger.Error("imap: failed to list messages", "mailbox", name, "error", err)
+		return nil, errUnavailable
+	}
+	// IMAP UIDs are 32-bit. Archives never get close, but drop anything
+	// that wouldn't fit rather than wrap around.
+	return slices.DeleteFunc(msgs, func(m store.MailboxMessage) bool {
+		return m.ID <= 0 || m.ID > math.MaxUint32
+	}), nil
+}
+
+func (sess *session) Select(mailbox string, _ *imap.SelectOptions) (*imap.SelectData, error) {
+	msgs, err := sess.load(mailbox)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(mailbox, mailview.InboxName) {
+		mailbox = mailview.InboxName
+	}
+	sess.selected = &mailboxView{name: mailbox, msgs: msgs}
+
+	data := &imap.SelectData{
+		Flags:          []imap.Flag{imap.FlagSeen, imap.FlagFlagged},
+		PermanentFlags: []imap.Flag{}, // nothing can be changed
+		NumMessages:    uint32(len(msgs)),
+		UIDNext:        uidNext(msgs),
+		UIDValidity:    uidValidity,
+	}
+	for i, m := range msgs {
+		if !m.Seen {
+			data.FirstUnseenSeqNum = uint32(i + 1)
+			break
+		}
+	}
+	return data, nil
+}
+
+func (sess *session) List(w *imapserver.ListWriter, ref string, patterns []string, options *imap.ListOptions) error {
+	if len(patterns) == 0 {
+		return w.WriteList(&imap.ListData{
+			Attrs: []imap.MailboxAttr{imap.MailboxAttrNoSelect},
+			Delim: mailview.Delim,
+		})
+	}
+	mb, err := sess.mailboxes()
+	if err != nil {
+		return err
+	}
+	for _, name := range mb.Names() {
+		if !slices.ContainsFunc(patterns, func(p string) bool {
+			return imapserver.MatchList(name, mailview.Delim, ref, p)
+		}) {
+			continue
+		}
+		parent := mb.IsParent(name)
+		data := &imap.ListData{Mailbox: name, Delim: mailview.Delim}
+		if parent {
+			if options.SelectSubscribed || options.SelectSpecialUse {
+				continue
+			}
+			data.Attrs = append(data.Attrs, imap.MailboxAttrNoSelect)
+		} else {
+			// Every mailbox reports as subscribed so clients that only
+			// show subscribed folders still display the whole archive.
+			data.Attrs = append(data.Attrs, imap.MailboxAttrSubscribed)
+		}
+		if name == mailview.AllMailName {
+			data.Attrs = append(data.Attrs, imap.MailboxAttrAll)
+		} else if options.SelectSpecialUse {
+			continue
+		}
+		if options.ReturnStatus != nil && !parent {
+			status, err := sess.Status(name, options.ReturnStatus)
+			if err != nil {
+				return err
+			}
+			data.Status = status
+		}
+		if err := w.WriteList(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sess *session) Status(mailbox string, options *imap.StatusOptions) (*imap.StatusData, error) {
+	msgs, err := sess.load(mailbox)
+	if err != nil {
+		return nil, err
+	}
+	data := &imap.StatusData{Mailbox: mailbox}
+	if options.NumMessages {
+		n := uint32(len(msgs))
+		data.NumMessages = &n
+	}
+	if options.UIDNext {
+		data.UIDNext = uidNext(msgs)
+	}
+	if options.UIDValidity {
+		data.UIDValidity = uidValidity
+	}
+	if options.NumUnseen {
+		var n uint32
+		for _, m := range msgs {
+			if !m.Seen {
+				n++
+			}
+		}
+		data.NumUnseen = &n
+	}
+	if options.NumDeleted {
+		var n uint32
+		data.NumDeleted = &n
+	}
+	if options.NumRecent {
+		var n uint32
+		data.NumRecent = &n
+	}
+	if options.Size {
+		var size int64
+		for _, m := range msgs {
+			size += m.Size
+		}
+		data.Size = &size
+	}
+	return data, nil
+}
+
+func (sess *session) Fetch(w *imapserver.FetchWriter, numSet imap.NumSet, options *imap.FetchOptions) error {
+	v := sess.selected
+	numSet = v.staticNumSet(numSet)
+	for i, m := range v.msgs {
+		seqNum := uint32(i + 1)
+		if !v.contains(numSet, seqNum, m) {
+			continue
+		}
+		if err := sess.fetchMessage(w.CreateMessage(seqNum), m, options); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sess *session) fetchMessage(w *imapserver.FetchResponseWriter, m store.MailboxMessage, options *imap.FetchOptions) error {
+	w.WriteUID(imap.UID(m.ID))
+	if options.Flags {
+		w.WriteFlags(messageFlags(m))
+	}
+	if options.InternalDate {
+		w.WriteInternalDate(m.InternalDate)
+	}
+
+	needRaw := options.Envelope || options.BodyStructure != nil ||
+		len(options.BodySection) > 0 || len(options.BinarySection) > 0 ||
+		len(options.BinarySectionSize) > 0
+	var raw []byte
+	if needRaw {
+		var err error
+		raw, err = sess.srv.store.GetMessageRaw(m.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			sess.srv.logger.Error("imap: failed to load raw message", "id", m.ID, "error", err)
+			return errUnavailable
+		}
+		// Messages synced without raw MIME are served as empty bodies.
+	}
+
+	if options.RFC822Size {
+		size := m.Size
+		if raw != nil {
+			size = int64(len(raw))
+		}
+		w.WriteRFC822Size(size)
+	}
+	if options.Envelope {
+		header, err := textproto.ReadHeader(bufio.NewReader(bytes.NewReader(raw)))
+		if err != nil {
+			header = textproto.Header{}
+		}
+		w.WriteEnvelope(imapserver.ExtractEnvelope(header))
+	}
+	if options.BodyStructure != nil {
+		w.WriteBodyStructure(imapserver.ExtractBodyStructure(bytes.NewReader(raw)))
+	}
+	for _, bs := range options.BodySection {
+		buf := imapserver.ExtractBodySection(bytes.NewReader(raw), bs)
+		if err := writeSection(w.WriteBodySection(bs, int64(len(buf))), buf); err != nil {
+			return err
+		}
+	}
+	for _, bs := range options.BinarySection {
+		buf := imapserver.ExtractBinarySection(bytes.NewReader(raw), bs)
+		if err := writeSection(w.WriteBinarySection(bs, int64(len(buf))), buf); err != nil {
+			return err
+		}
+	}
+	for _, bss := range options.BinarySectionSize {
+		w.WriteBinarySectionSize(bss, imapserver.ExtractBinarySectionSize(bytes.NewReader(raw), bss))
+	}
+	return w.Close()
+}
+
+func writeSection(wc interface {
+	Write([]byte) (int, error)
+	Close() error
+}, buf []byte) error {
+	_, writeErr := wc.Write(buf)
+	closeErr := wc.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	return closeErr
+}
+
+func messageFlags(m store.MailboxMessage) []imap.Flag {
+	var flags []imap.Flag
+	if m.Seen {
+		flags = append(flags, imap.FlagSeen)
+	}
+	if m.Flagged {
+		flags = append(flags, imap.FlagFlagged)
+	}
+	return flags
+}
+
+func uidNext(msgs []store.MailboxMessage) imap.UID {
+	if len(msgs) == 0 {
+		return 1
+	}
+	return imap.UID(msgs[len(msgs)-1].ID + 1)
+}
+
+func (v *mailboxView) contains(numSet imap.NumSet, seqNum uint32, m store.MailboxMessage) bool {
+	switch set := numSet.(type) {
+	case imap.SeqSet:
+		return set.Contains(seqNum)
+	case imap.UIDSet:
+		return set.Contains(imap.UID(m.ID))
+	}
+	return false
+}
+
+// staticNumSet resolves "*" to the highest sequence number or UID and
+// "$" to the saved search result.
+func (v *mailboxView) staticNumSet(numSet imap.NumSet) imap.NumSet {
+	if imap.IsSearchRes(numSet) {
+		return v.searchRes
+	}
+	switch set := numSet.(type) {
+	case imap.SeqSet:
+		out := slices.Clone(set)
+		last := uint32(len(v.msgs))
+		for i := range out {
+			staticRange(&out[i].Start, &out[i].Stop, last)
+		}
+		return out
+	case imap.UIDSet:
+		out := slices.Clone(set)
+		last := uint32(uidNext(v.msgs) - 1)
+		for i := range out {
+			staticRange((*uint32)(&out[i].Start), (*uint32)(&out[i].Stop), last)
+		}
+		return out
+	}
+	return numSet
+}
+
+func staticRange(start, stop *uint32, last uint32) {
+	dynamic := false
+	if *start == 0 {
+		*start = last
+		dynamic = true
+	}
+	if *stop == 0 {
+		*stop = last
+		dynamic = true
+	}
+	if dynamic && *start > *stop {
+		*start, *stop = *stop, *start
+	}
+}
+
+func (sess *session) Unselect() error {
+	sess.selected = nil
+	return nil
+}
+
+// Poll reports no changes: the selected mailbox is a snapshot.
+func (sess *session) Poll(*imapserver.UpdateWriter, bool) error { return nil }
+
+func (sess *session) Idle(_ *imapserver.UpdateWriter, stop <-chan struct{}) error {
+	<-stop
+	return nil
+}
+
+// Subscriptions are accepted and ignored; every mailbox lists as
+// subscribed.
+func (sess *session) Subscribe(string) error   { return nil }
+func (sess *session) Unsubscribe(string) error { return nil }
+
+func (sess *session) Create(string, *imap.CreateOptions) error         { return errReadOnly }
+func (sess *session) Delete(string) error                              { return errReadOnly }
+func (sess *session) Rename(string, string, *imap.RenameOptions) error { return errReadOnly }
+func (sess *session) Append(string, imap.LiteralReader, *imap.AppendOptions) (*imap.AppendData, error) {
+	return nil, errReadOnly
+}
+func (sess *session) Expunge(*imapserver.ExpungeWriter, *imap.UIDSet) error { return errReadOnly }
+func (sess *session) Store(*imapserver.FetchWriter, imap.NumSet, *imap.StoreFlags, *imap.StoreOptions) error {
+	return errReadOnly
+}
+func (sess *session) Copy(imap.NumSet, string) (*imap.CopyData, error) { return nil, errReadOnly }
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/textutil"
+)
+
+// FetchAttachmentsOptions controls FetchAttachments.
+type FetchAttachmentsOptions struct {
+	// IDs limits the run to these messages. Nil means every message
+	// with attachments still to extract.
+	IDs []int64
+
+	// AttachmentsDir is where attachment files are stored.
+	AttachmentsDir string
+
+	// Progress, if set, is called after each message with the number
+	// processed so far and the total.
+	Progress func(done, total int)
+}
+
+// FetchAttachmentsResult summarizes a FetchAttachments run.
+type FetchAttachmentsResult struct {
+	Scanned int // messages with attachments to extract
+	Fetched int // messages whose attachments were all stored
+	Files   int // attachment files stored
+	Failed  int // raw MIME missing or unparseable, or a file not stored
+}
+
+// FetchAttachments extracts the attachments of messages synced with
+// SkipAttachments from their stored raw MIME into the attachments
+// directory. Messages whose attachments are already stored are skipped,
+// so it can be run again after a failure.
+func FetchAttachments(ctx context.Context, st *store.Store, opts FetchAttachmentsOptions, log *slog.Logger) (*FetchAttachmentsResult, error) {
+	if opts.AttachmentsDir == "" {
+		return nil, fmt.Errorf("no attachments directory")
+	}
+	ids, err := st.MessagesWithPendingAttachments(opts.IDs)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &FetchAttachmentsResult{Scanned: len(ids)}
+	for i, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		n, err := fetchMessageAttachments(st, opts.AttachmentsDir, id)
+		res.Files += n
+		if err != nil {
+			res.Failed++
+			log.Warn("fetch attachments failed", "message", id, "error", err)
+		} else {
+			res.Fetched++
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(ids))
+		}
+	}
+	return res, nil
+}
+
+// fetchMessageAttachments stores the attachments in one message's raw
+// MIME and returns how many it stored.
+func fetchMessageAttachments(st *store.Store, attachmentsDir string, messageID int64) (int, error) {
+	raw, err := st.GetMessageRaw(messageID)
+	if err != nil {
+		return 0, fmt.Errorf("read raw MIME: %w", err)
+	}
+	parsed, err := mime.Parse(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse MIME: %s", textutil.FirstLine(err.Error()))
+	}
+
+	attachments, _ := mime.SplitEmbedded(parsed.Attachments)
+	stored := 0
+	for i := range attachments {
+		att := &attachments[i]
+		att.Filename = textutil.EnsureUTF8(att.Filename)
+		att.ContentType = textutil.EnsureUTF8(att.ContentType)
+		if err := storeAttachment(st, attachmentsDir, messageID, att); err != nil {
+			return stored, fmt.Errorf("store attachment %q: %w", att.Filename, err)
+		}
+		stored++
+	}
+	return stored, nil
+}
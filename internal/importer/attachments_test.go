@@ -0,0 +1,85 @@
+package importer
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestFetchAttachments(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("get/create source: %v", err)
+	}
+
+	// Ingesting without an attachments directory archives the messages
+	// as a sync with SkipAttachments does.
+	ctx := context.Background()
+	for i, name := range []string{"report.pdf", "notes.txt"} {
+		raw := email.NewMessage().
+			From("bob@example.com").
+			To("alice@example.com").
+			Subject("Files").
+			Body("Attached.").
+			WithAttachment(name, "application/octet-stream", []byte("content of "+name)).
+			Bytes()
+		if err := IngestRawMessage(ctx, st, src.ID, "alice@example.com", "", nil,
+			"msg-"+string(rune('a'+i)), "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("IngestRawMessage: %v", err)
+		}
+	}
+	raw := email.NewMessage().From("bob@example.com").To("alice@example.com").
+		Subject("No files").Body("Nothing attached.").Bytes()
+	if err := IngestRawMessage(ctx, st, src.ID, "alice@example.com", "", nil,
+		"msg-c", "hash", raw, time.Time{}, slog.Default()); err != nil {
+		t.Fatalf("IngestRawMessage: %v", err)
+	}
+
+	pending, err := st.MessagesWithPendingAttachments(nil)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("MessagesWithPendingAttachments = %v, %v; want two", pending, err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "attachments")
+	res, err := FetchAttachments(ctx, st, FetchAttachmentsOptions{
+		IDs: []int64{pending[0], 3}, AttachmentsDir: dir,
+	}, slog.Default())
+	if err != nil {
+		t.Fatalf("FetchAttachments: %v", err)
+	}
+	if res.Scanned != 1 || res.Fetched != 1 || res.Files != 1 || res.Failed != 0 {
+		t.Errorf("first run = %+v, want one message with one file", *res)
+	}
+
+	res, err = FetchAttachments(ctx, st, FetchAttachmentsOptions{AttachmentsDir: dir}, slog.Default())
+	if err != nil {
+		t.Fatalf("FetchAttachments: %v", err)
+	}
+	if res.Scanned != 1 || res.Fetched != 1 || res.Files != 1 {
+		t.Errorf("second run = %+v, want only the message left", *res)
+	}
+
+	var stored int
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&stored); err != nil {
+		t.Fatalf("count attachments: %v", err)
+	}
+	if stored != 2 {
+		t.Errorf("stored attachments = %d, want 2", stored)
+	}
+	if pending, err := st.MessagesWithPendingAttachments(nil); err != nil || len(pending) != 0 {
+		t.Errorf("MessagesWithPendingAttachments = %v, %v; want none", pending, err)
+	}
+}
@@ -33,6 +33,23 @@ func IngestRawMessage(
 	labelIDs []int64, sourceMsgID, rawHash string,
 	raw []byte, fallbackDate time.Time,
 	log *slog.Logger,
+) error {
+	return ingestRawMessage(
+		ctx, st, sourceID, identifier, attachmentsDir,
+		labelIDs, sourceMsgID, rawHash, "",
+		raw, fallbackDate, log,
+	)
+}
+
+// ingestRawMessage is IngestRawMessage with the message's thread given
+// by threadID, as Gmail exports record it. An empty threadID threads
+// the message by its References, as IngestRawMessage does.
+func ingestRawMessage(
+	ctx context.Context, st *store.Store,
+	sourceID int64, identifier, attachmentsDir string,
+	labelIDs []int64, sourceMsgID, rawHash, threadID string,
+	raw []byte, fallbackDate time.Time,
+	log *slog.Logger,
 ) error {
 	parsed, parseErr := mime.Parse(raw)
 	if parseErr != nil {
@@ -90,9 +107,11 @@ func IngestRawMessage(
 		}
 	}
 
-	threadID := threadKey(parsed, rawHash)
+	if threadID == "" {
+		threadID = threadKey(parsed, rawHash)
+	}
 
-	convSubject := subject
+	convSubject := mime.NormalizeSubject(subject)
 	if convSubject == "" {
 		convSubject = "(no subject)"
 	}
@@ -111,8 +130,11 @@ func IngestRawMessage(
 	}
 
 	snippet := snippetFromBody(bodyText)
-	hasAttachments := len(parsed.Attachments) > 0
-	attachmentCount := len(parsed.Attachments)
+	// Images embedded in the HTML body are recorded apart from the
+	// message's attachments and not counted among them.
+	attachments, inlineParts := mime.SplitEmbedded(parsed.Attachments)
+	hasAttachments := len(attachments) > 0
+	attachmentCount := len(attachments)
 
 	rec := &store.Message{
 		ConversationID:  conversationID,
@@ -143,12 +165,13 @@ func IngestRawMessage(
 
 	// Persist atomically
 	messageID, err := st.PersistMessage(&store.MessagePersistData{
-		Message:    rec,
-		BodyText:   sql.NullString{String: bodyText, Valid: bodyText != ""},
-		BodyHTML:   sql.NullString{String: bodyHTML, Valid: bodyHTML != ""},
-		RawMIME:    raw,
-		Recipients: recipientSets,
-		LabelIDs:   labelIDs,
+		Message:     rec,
+		BodyText:    sql.NullString{String: bodyText, Valid: bodyText != ""},
+		BodyHTML:    sql.NullString{String: bodyHTML, Valid: bodyHTML != ""},
+		RawMIME:     raw,
+		Recipients:  recipientSets,
+		LabelIDs:    labelIDs,
+		InlineParts: inlineParts,
 	})
 	if err != nil {
 		return err
@@ -158,8 +181,8 @@ func IngestRawMessage(
 	// Failures are logged but don't fail the ingest — the message,
 	// body, and raw MIME are already committed. The attachment count
 	// correction below updates metadata to reflect what stored.
-	for i := range parsed.Attachments {
-		att := &parsed.Attachments[i]
+	for i := range attachments {
+		att := &attachments[i]
 		if err := storeAttachment(
 			st, attachmentsDir, messageID, att,
 		); err != nil {
@@ -172,7 +195,7 @@ func IngestRawMessage(
 	}
 
 	// Correct attachment count if disk storage filtered some out
-	if attachmentsDir != "" && len(parsed.Attachments) > 0 {
+	if attachmentsDir != "" && len(attachments) > 0 {
 		var storedCount int
 		if err := st.DB().QueryRow(
 			`SELECT COUNT(*) FROM attachments WHERE message_id = ?`,
@@ -199,7 +222,7 @@ func IngestRawMessage(
 		toAddrs := joinEmails(parsed.To)
 		ccAddrs := joinEmails(parsed.Cc)
 		if err := st.UpsertFTS(
-			messageID, subject, bodyText,
+			messageID, subject, st.SearchableBody(bodyText),
 			fromAddr, toAddrs, ccAddrs,
 		); err != nil {
 			log.Warn("failed to upsert FTS",
@@ -296,12 +319,12 @@ func storeAttachment(
 	st *store.Store, attachmentsDir string,
 	messageID int64, att *mime.Attachment,
 ) error {
-	storagePath, err := export.StoreAttachmentFile(attachmentsDir, att)
+	storagePath, storedSize, err := export.StoreAttachmentFile(attachmentsDir, att)
 	if err != nil || storagePath == "" {
 		return err
 	}
-	return st.UpsertAttachment(
+	return st.UpsertStoredAttachment(
 		messageID, att.Filename, att.ContentType,
-		storagePath, att.ContentHash, len(att.Content),
+		storagePath, att.ContentHash, len(att.Content), storedSize,
 	)
 }
@@ -242,3 +242,83 @@ func TestIngestRawMessage_InvalidUTF8_RecipientLinkage(t *testing.T) {
 		}
 	}
 }
+
+// inlineImageMessage is an HTML message showing a logo by cid: URL,
+// with one real attachment.
+const inlineImageMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject: Newsletter\r\n" +
+	"Message-ID: <inline-1@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"outer\"\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/related; boundary=\"rel\"\r\n" +
+	"\r\n" +
+	"--rel\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Hi</p><img src=\"cid:logo@example.com\">\r\n" +
+	"--rel\r\n" +
+	"Content-Type: image/png; name=\"logo.png\"\r\n" +
+	"Content-ID: <logo@example.com>\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--rel--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: application/pdf; name=\"report.pdf\"\r\n" +
+	"Content-Disposition: attachment; filename=\"report.pdf\"\r\n" +
+	"\r\n" +
+	"%PDF-1.4\r\n" +
+	"--outer--\r\n"
+
+func TestIngestRawMessage_InlineImagesAreNotAttachments(t *testing.T) {
+	tmp := t.TempDir()
+	st, err := store.Open(filepath.Join(tmp, "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "bob@example.com")
+	if err != nil {
+		t.Fatalf("get/create source: %v", err)
+	}
+
+	err = IngestRawMessage(
+		context.Background(), st,
+		src.ID, "bob@example.com", filepath.Join(tmp, "attachments"),
+		nil, "source-msg-1", "fakehash",
+		[]byte(inlineImageMessage), time.Time{}, slog.Default(),
+	)
+	if err != nil {
+		t.Fatalf("IngestRawMessage: %v", err)
+	}
+
+	var msgID int64
+	var hasAttachments bool
+	var count, stored int
+	if err := st.DB().QueryRow(
+		`SELECT id, has_attachments, attachment_count FROM messages`,
+	).Scan(&msgID, &hasAttachments, &count); err != nil {
+		t.Fatalf("select message: %v", err)
+	}
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM attachments`).Scan(&stored); err != nil {
+		t.Fatalf("count attachments: %v", err)
+	}
+	if !hasAttachments || count != 1 || stored != 1 {
+		t.Errorf("has_attachments = %v, attachment_count = %d, stored = %d; want true, 1, 1",
+			hasAttachments, count, stored)
+	}
+
+	parts, err := st.GetInlineParts(msgID)
+	if err != nil {
+		t.Fatalf("GetInlineParts: %v", err)
+	}
+	if len(parts) != 1 || parts[0].ContentID != "logo@example.com" || parts[0].Filename != "logo.png" {
+		t.Errorf("inline parts = %+v", parts)
+	}
+}
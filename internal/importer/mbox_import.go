@@ -27,6 +27,11 @@ type MboxImportOptions struct {
 	// Labels, if non-empty, are applied to all imported messages.
 	Labels []string
 
+	// Takeout reads the file as a Google Takeout Gmail export: each
+	// message keeps its Gmail message ID, thread, and labels, so that a
+	// later Gmail sync of the account finds it already archived.
+	Takeout bool
+
 	// NoResume forces a fresh import even if an active sync run exists for the source.
 	NoResume bool
 
@@ -96,6 +101,9 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 	ingestFn := opts.IngestFunc
 	if ingestFn == nil {
 		ingestFn = ingestRawEmail
+		if opts.Takeout {
+			ingestFn = ingestTakeoutEmail
+		}
 	}
 	log := opts.Logger
 	if log == nil {
@@ -204,6 +212,7 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 		}
 		labelIDs = append(labelIDs, labelID)
 	}
+	takeoutLabels := make(map[string]int64)
 
 	// Open file and (if resuming) seek.
 	f, err := os.Open(absPath)
@@ -300,6 +309,18 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 		}
 
 		for _, p := range pending {
+			msgLabelIDs := labelIDs
+			if opts.Takeout {
+				ids, err := takeoutLabelIDs(st, src.ID, labelIDs, readTakeoutHeaders(p.Msg.Raw).Labels, takeoutLabels)
+				if err != nil {
+					cp.ErrorsCount++
+					summary.Errors++
+					log.Warn("failed to ensure Gmail labels", "source_msg", p.SourceMsg, "error", err)
+				} else {
+					msgLabelIDs = ids
+				}
+			}
+
 			if err := ctx.Err(); err != nil {
 				summary.FinalOffset = lastCheckpointOffset
 				summary.Duration = time.Since(start)
@@ -340,9 +361,9 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 				summary.MessagesSkipped++
 
 				// Add labels to existing message (same pattern as emlx importer).
-				if len(labelIDs) > 0 {
+				if len(msgLabelIDs) > 0 {
 					if msgID, ok := existingWithRaw[p.SourceMsg]; ok && msgID > 0 {
-						if err := st.AddMessageLabels(msgID, labelIDs); err != nil {
+						if err := st.AddMessageLabels(msgID, msgLabelIDs); err != nil {
 							log.Warn("failed to add labels to existing message",
 								"source_message_id", p.SourceMsg, "error", err)
 						} else {
@@ -380,7 +401,7 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 				}
 			}
 
-			if err := ingestFn(ctx, st, src.ID, opts.Identifier, opts.AttachmentsDir, labelIDs, p.SourceMsg, p.RawHash, p.Msg, log); err != nil {
+			if err := ingestFn(ctx, st, src.ID, opts.Identifier, opts.AttachmentsDir, msgLabelIDs, p.SourceMsg, p.RawHash, p.Msg, log); err != nil {
 				cp.ErrorsCount++
 				summary.Errors++
 				log.Warn("failed to ingest message", "source_msg", p.SourceMsg, "next_offset", p.NextOffset, "error", err)
@@ -454,6 +475,11 @@ func ImportMbox(ctx context.Context, st *store.Store, mboxPath string, opts Mbox
 		rawHash := hex.EncodeToString(sum[:])
 		nextOffset := r.NextFromOffset()
 		sourceMsgID := fmt.Sprintf("mbox-%s-%d", rawHash, msgSeq)
+		if opts.Takeout {
+			if id := takeoutMessageID(msg.FromLine); id != "" {
+				sourceMsgID = id
+			}
+		}
 		pending = append(pending, pendingMboxMessage{
 			Msg:        msg,
 			RawHash:    rawHash,
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/textutil"
+)
+
+// reparseBatchSize is how many candidates are listed per query when
+// reparsing the whole archive.
+const reparseBatchSize = 500
+
+// ReparseOptions controls Reparse.
+type ReparseOptions struct {
+	// IDs limits the run to these messages. Nil means every message
+	// with stored raw MIME.
+	IDs []int64
+
+	// Progress, if set, is called after each message with the number
+	// processed so far and the total (0 when unknown).
+	Progress func(done, total int)
+}
+
+// ReparseResult summarizes a Reparse run.
+type ReparseResult struct {
+	Scanned int
+	Updated int
+	Failed  int // raw MIME missing or still unparseable
+
+	// UpdatedIDs lists the messages whose stored fields changed, for
+	// callers that re-index or re-embed them.
+	UpdatedIDs []int64
+}
+
+// Reparse parses the stored raw MIME of messages again and updates
+// their subject, bodies, snippet, sender, date, and recipients when
+// the result differs from what is stored. It lets parser fixes reach
+// mail archived before them without a resync. Gmail snippets come from
+// the Gmail API rather than the MIME, so they are kept.
+func Reparse(ctx context.Context, st *store.Store, opts ReparseOptions, log *slog.Logger) (*ReparseResult, error) {
+	res := &ReparseResult{}
+	process := func(batch []store.ReparseCandidate, total int) error {
+		for _, c := range batch {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			updated, err := reparseMessage(st, c, log)
+			res.Scanned++
+			switch {
+			case err != nil:
+				res.Failed++
+				log.Warn("reparse failed", "message", c.ID, "error", err)
+			case updated:
+				res.Updated++
+				res.UpdatedIDs = append(res.UpdatedIDs, c.ID)
+			}
+			if opts.Progress != nil {
+				opts.Progress(res.Scanned, total)
+			}
+		}
+		return nil
+	}
+
+	if opts.IDs != nil {
+		batch, err := st.ReparseCandidatesByID(opts.IDs)
+		if err != nil {
+			return nil, err
+		}
+		return res, process(batch, len(batch))
+	}
+	for after := int64(0); ; {
+		batch, err := st.ReparseCandidates(after, reparseBatchSize)
+		if err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			return res, nil
+		}
+		if err := process(batch, 0); err != nil {
+			return res, err
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// reparseMessage re-derives one message's fields from its raw MIME and
+// reports whether anything changed. A message whose MIME still fails
+// to parse is left as it is.
+func reparseMessage(st *store.Store, c store.ReparseCandidate, log *slog.Logger) (bool, error) {
+	raw, err := st.GetMessageRaw(c.ID)
+	if err != nil {
+		return false, fmt.Errorf("read raw MIME: %w", err)
+	}
+	parsed, err := mime.Parse(raw)
+	if err != nil {
+		return false, fmt.Errorf("parse MIME: %s", textutil.FirstLine(err.Error()))
+	}
+
+	subject := textutil.EnsureUTF8(parsed.Subject)
+	bodyText := textutil.EnsureUTF8(parsed.GetBodyText())
+	bodyHTML := textutil.EnsureUTF8(parsed.BodyHTML)
+	for _, addrs := range [][]mime.Address{parsed.From, parsed.To, parsed.Cc, parsed.Bcc} {
+		for i := range addrs {
+			addrs[i].Email = textutil.SanitizeUTF8(addrs[i].Email)
+			addrs[i].Name = textutil.SanitizeUTF8(addrs[i].Name)
+			addrs[i].Domain = textutil.SanitizeUTF8(addrs[i].Domain)
+		}
+	}
+
+	_, embedded := mime.SplitEmbedded(parsed.Attachments)
+	for i := range embedded {
+		embedded[i].Filename = textutil.EnsureUTF8(embedded[i].Filename)
+		embedded[i].ContentType = textutil.EnsureUTF8(embedded[i].ContentType)
+	}
+
+	all := make([]mime.Address, 0, len(parsed.From)+len(parsed.To)+len(parsed.Cc)+len(parsed.Bcc))
+	all = append(all, parsed.From...)
+	all = append(all, parsed.To...)
+	all = append(all, parsed.Cc...)
+	all = append(all, parsed.Bcc...)
+	participantMap, err := st.EnsureParticipantsBatch(all)
+	if err != nil {
+		return false, fmt.Errorf("ensure participants: %w", err)
+	}
+
+	r := &store.ReparsedMessage{
+		Subject:  sql.NullString{String: subject, Valid: subject != ""},
+		BodyText: sql.NullString{String: bodyText, Valid: bodyText != ""},
+		BodyHTML: sql.NullString{String: bodyHTML, Valid: bodyHTML != ""},
+		Recipients: []store.RecipientSet{
+			buildRecipientSet("from", parsed.From, participantMap),
+			buildRecipientSet("to", parsed.To, participantMap),
+			buildRecipientSet("cc", parsed.Cc, participantMap),
+			buildRecipientSet("bcc", parsed.Bcc, participantMap),
+		},
+		InlineParts: embedded,
+		Raw:         raw,
+	}
+	if c.SourceType != "gmail" {
+		if snippet := snippetFromBody(bodyText); snippet != "" {
+			r.Snippet = sql.NullString{String: snippet, Valid: true}
+		}
+	}
+	if !parsed.Date.IsZero() {
+		r.SentAt = sql.NullTime{Time: parsed.Date.UTC(), Valid: true}
+	}
+	if len(parsed.From) > 0 && parsed.From[0].Email != "" {
+		if id, ok := participantMap[parsed.From[0].Email]; ok {
+			r.SenderID = sql.NullInt64{Int64: id, Valid: true}
+		}
+	}
+
+	updated, err := st.ApplyReparse(c.ID, r)
+	if err != nil || !updated {
+		return false, err
+	}
+	// FTS: best-effort, as in IngestRawMessage
+	if st.FTS5Available() {
+		if err := st.UpsertFTS(c.ID, subject, st.SearchableBody(bodyText),
+			joinEmails(parsed.From), joinEmails(parsed.To), joinEmails(parsed.Cc)); err != nil {
+			log.Warn("failed to upsert FTS", "message", c.ID, "error", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestReparse(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("mbox", "alice@example.com")
+	if err != nil {
+		t.Fatalf("get/create source: %v", err)
+	}
+
+	ctx := context.Background()
+	for i, subject := range []string{"Quarterly report", "Lunch"} {
+		raw := email.NewMessage().
+			From("Bob Smith <bob@example.com>").
+			To("alice@example.com").
+			Header("Cc", "carol@example.com").
+			Subject(subject).
+			Body("Numbers attached.\nMore below.").
+			Bytes()
+		if err := IngestRawMessage(ctx, st, src.ID, "alice@example.com", "", nil,
+			"msg-"+string(rune('a'+i)), "hash", raw, time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("IngestRawMessage: %v", err)
+		}
+	}
+
+	// Simulate what an older parser stored for the first message.
+	db := st.DB()
+	for _, q := range []string{
+		`UPDATE messages SET subject = 'Quarterly r?port', snippet = NULL WHERE id = 1`,
+		`UPDATE message_bodies SET body_text = 'garbled' WHERE message_id = 1`,
+		`DELETE FROM message_recipients WHERE message_id = 1 AND recipient_type = 'cc'`,
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	res, err := Reparse(ctx, st, ReparseOptions{}, slog.Default())
+	if err != nil {
+		t.Fatalf("Reparse: %v", err)
+	}
+	if res.Scanned != 2 || res.Updated != 1 || res.Failed != 0 || len(res.UpdatedIDs) != 1 || res.UpdatedIDs[0] != 1 {
+		t.Errorf("result = %+v, want 2 scanned, message 1 updated", res)
+	}
+
+	var subject, snippet, body string
+	var ccCount int
+	if err := db.QueryRow(`
+		SELECT m.subject, m.snippet, mb.body_text,
+		       (SELECT COUNT(*) FROM message_recipients r WHERE r.message_id = m.id AND r.recipient_type = 'cc')
+		FROM messages m JOIN message_bodies mb ON mb.message_id = m.id WHERE m.id = 1
+	`).Scan(&subject, &snippet, &body, &ccCount); err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Quarterly report" || snippet != "Numbers attached." || body != "Numbers attached.\nMore below.\n" || ccCount != 1 {
+		t.Errorf("after reparse: subject=%q snippet=%q body=%q cc=%d", subject, snippet, body, ccCount)
+	}
+
+	// A second run finds nothing left to fix.
+	res, err = Reparse(ctx, st, ReparseOptions{}, slog.Default())
+	if err != nil {
+		t.Fatalf("second Reparse: %v", err)
+	}
+	if res.Updated != 0 {
+		t.Errorf("second run updated %d messages, want 0", res.Updated)
+	}
+
+	// IDs limits the run.
+	res, err = Reparse(ctx, st, ReparseOptions{IDs: []int64{2}}, slog.Default())
+	if err != nil {
+		t.Fatalf("Reparse by ID: %v", err)
+	}
+	if res.Scanned != 1 {
+		t.Errorf("Reparse by ID scanned %d, want 1", res.Scanned)
+	}
+}
+
+func TestReparse_KeepsGmailSnippet(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("get/create source: %v", err)
+	}
+	raw := email.NewMessage().From("bob@example.com").To("alice@example.com").
+		Subject("Hi").Body("Body line").Bytes()
+	if err := IngestRawMessage(context.Background(), st, src.ID, "alice@example.com", "", nil,
+		"gm-1", "hash", raw, time.Time{}, slog.Default()); err != nil {
+		t.Fatalf("IngestRawMessage: %v", err)
+	}
+	if _, err := st.DB().Exec(`UPDATE messages SET snippet = 'From the Gmail API', subject = 'old' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Reparse(context.Background(), st, ReparseOptions{}, slog.Default()); err != nil {
+		t.Fatalf("Reparse: %v", err)
+	}
+	var subject string
+	var snippet sql.NullString
+	if err := st.DB().QueryRow(`SELECT subject, snippet FROM messages WHERE id = 1`).Scan(&subject, &snippet); err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Hi" || snippet.String != "From the Gmail API" {
+		t.Errorf("subject=%q snippet=%q, want Hi and the Gmail snippet", subject, snippet.String)
+	}
+}
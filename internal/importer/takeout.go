@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"log/slog"
+	stdmime "mime"
+	"net/textproto"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/mbox"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Google Takeout exports Gmail as mbox with three extras: the From
+// separator line carries the message's X-GM-MSGID, and each message has
+// X-GM-THRID and X-Gmail-Labels headers. The IDs are the decimal forms
+// of the hex IDs the Gmail API uses, so messages imported from Takeout
+// line up with those a later Gmail sync of the same account downloads.
+
+// takeoutSkipLabels are pseudo-labels Takeout adds that are not Gmail
+// labels.
+var takeoutSkipLabels = map[string]bool{
+	"Archived": true,
+	"Opened":   true,
+}
+
+// takeoutSystemLabels maps Takeout's names for Gmail system labels to
+// their API IDs, which Gmail also reports as their names.
+var takeoutSystemLabels = map[string]string{
+	"Inbox":     "INBOX",
+	"Sent":      "SENT",
+	"Starred":   "STARRED",
+	"Important": "IMPORTANT",
+	"Spam":      "SPAM",
+	"Trash":     "TRASH",
+	"Drafts":    "DRAFT",
+	"Draft":     "DRAFT",
+	"Unread":    "UNREAD",
+	"Chat":      "CHAT",
+}
+
+// takeoutLabel is a Gmail label named in an X-Gmail-Labels header.
+type takeoutLabel struct {
+	ID   string // Gmail API label ID for system labels, else the name
+	Name string
+	Type string // "system" or "user"
+}
+
+// parseTakeoutLabel maps a label name from X-Gmail-Labels to the label
+// a Gmail sync records. ok is false for Takeout's pseudo-labels.
+func parseTakeoutLabel(name string) (l takeoutLabel, ok bool) {
+	name = strings.TrimSpace(name)
+	if name == "" || takeoutSkipLabels[name] {
+		return takeoutLabel{}, false
+	}
+	if id, ok := takeoutSystemLabels[name]; ok {
+		return takeoutLabel{ID: id, Name: id, Type: "system"}, true
+	}
+	if cat, ok := strings.CutPrefix(name, "Category "); ok && cat != "" {
+		id := "CATEGORY_" + strings.ToUpper(cat)
+		return takeoutLabel{ID: id, Name: id, Type: "system"}, true
+	}
+	return takeoutLabel{ID: name, Name: name, Type: "user"}, true
+}
+
+// takeoutHeaders holds the Gmail headers of a Takeout message.
+type takeoutHeaders struct {
+	ThreadID string // hex, as the Gmail API reports it; "" if absent
+	Labels   []takeoutLabel
+}
+
+// readTakeoutHeaders reads the Gmail headers from a raw message.
+func readTakeoutHeaders(raw []byte) takeoutHeaders {
+	hdr, _ := textproto.NewReader(bufio.NewReader(bytes.NewReader(raw))).ReadMIMEHeader()
+	var h takeoutHeaders
+	h.ThreadID = gmailHexID(hdr.Get("X-GM-THRID"))
+
+	v := hdr.Get("X-Gmail-Labels")
+	if decoded, err := new(stdmime.WordDecoder).DecodeHeader(v); err == nil {
+		v = decoded
+	}
+	// Labels containing commas are quoted.
+	r := csv.NewReader(strings.NewReader(v))
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+	names, _ := r.Read()
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if l, ok := parseTakeoutLabel(name); ok && !seen[l.ID] {
+			seen[l.ID] = true
+			h.Labels = append(h.Labels, l)
+		}
+	}
+	return h
+}
+
+// takeoutMessageID returns the Gmail API message ID from a Takeout
+// separator line, "From 1750123456789012345@xxx Mon Jan 01 ...", or ""
+// if the line does not carry one.
+func takeoutMessageID(fromLine string) string {
+	fields := strings.Fields(fromLine)
+	if len(fields) < 2 {
+		return ""
+	}
+	id, ok := strings.CutSuffix(fields[1], "@xxx")
+	if !ok {
+		return ""
+	}
+	return gmailHexID(id)
+}
+
+// gmailHexID converts a decimal Gmail ID to the hex form the API uses.
+func gmailHexID(decimal string) string {
+	n, err := strconv.ParseUint(strings.TrimSpace(decimal), 10, 64)
+	if err != nil || n == 0 {
+		return ""
+	}
+	return strconv.FormatUint(n, 16)
+}
+
+// takeoutLabelIDs ensures a Takeout message's labels exist for the
+// source and returns their IDs after base. cache maps Gmail label IDs
+// to label rows across messages.
+func takeoutLabelIDs(st *store.Store, sourceID int64, base []int64, labels []takeoutLabel, cache map[string]int64) ([]int64, error) {
+	if len(labels) == 0 {
+		return base, nil
+	}
+	ids := append([]int64(nil), base...)
+	for _, l := range labels {
+		id, ok := cache[l.ID]
+		if !ok {
+			var err error
+			id, err = st.EnsureLabel(sourceID, l.ID, l.Name, l.Type)
+			if err != nil {
+				return nil, err
+			}
+			cache[l.ID] = id
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// ingestTakeoutEmail ingests a Takeout message into its Gmail thread.
+func ingestTakeoutEmail(
+	ctx context.Context, st *store.Store,
+	sourceID int64, identifier, attachmentsDir string,
+	labelIDs []int64, sourceMsgID, rawHash string,
+	msg *mbox.Message, log *slog.Logger,
+) error {
+	var fallbackDate time.Time
+	if t, ok := parseFromLineDate(msg.FromLine); ok {
+		fallbackDate = t
+	}
+	return ingestRawMessage(
+		ctx, st, sourceID, identifier, attachmentsDir,
+		labelIDs, sourceMsgID, rawHash, readTakeoutHeaders(msg.Raw).ThreadID,
+		msg.Raw, fallbackDate, log,
+	)
+}
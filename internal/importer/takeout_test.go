@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestParseTakeoutLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		want takeoutLabel
+		ok   bool
+	}{
+		{"Inbox", takeoutLabel{ID: "INBOX", Name: "INBOX", Type: "system"}, true},
+		{"Drafts", takeoutLabel{ID: "DRAFT", Name: "DRAFT", Type: "system"}, true},
+		{"Category Promotions", takeoutLabel{ID: "CATEGORY_PROMOTIONS", Name: "CATEGORY_PROMOTIONS", Type: "system"}, true},
+		{"Work/Projects", takeoutLabel{ID: "Work/Projects", Name: "Work/Projects", Type: "user"}, true},
+		{"Opened", takeoutLabel{}, false},
+		{"Archived", takeoutLabel{}, false},
+		{" ", takeoutLabel{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTakeoutLabel(tt.name)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseTakeoutLabel(%q) = %+v, %v; want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestReadTakeoutHeaders(t *testing.T) {
+	raw := "X-GM-THRID: 1787654321098765432\r\n" +
+		"X-Gmail-Labels: Inbox,Opened,\"Clients, Past\",=?UTF-8?Q?Re=C3=A7us?=,Inbox\r\n" +
+		"Subject: hi\r\n\r\nbody\r\n"
+	h := readTakeoutHeaders([]byte(raw))
+	if h.ThreadID != "18cf06223648b478" {
+		t.Errorf("ThreadID = %q, want 18cf06223648b478", h.ThreadID)
+	}
+	var ids []string
+	for _, l := range h.Labels {
+		ids = append(ids, l.ID)
+	}
+	if want := []string{"INBOX", "Clients, Past", "Reçus"}; !slices.Equal(ids, want) {
+		t.Errorf("labels = %q, want %q", ids, want)
+	}
+
+	if h := readTakeoutHeaders([]byte("Subject: plain\r\n\r\nbody\r\n")); h.ThreadID != "" || len(h.Labels) != 0 {
+		t.Errorf("headers of non-Takeout message = %+v", h)
+	}
+}
+
+func TestTakeoutMessageID(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"From 1787654321098765432@xxx Mon Jan 01 12:00:00 +0000 2024", "18cf06223648b478"},
+		{"From alice@example.com Mon Jan 1 12:00:00 2024", ""},
+		{"From xyz@xxx Mon Jan 1 12:00:00 2024", ""},
+		{"From", ""},
+	}
+	for _, tt := range tests {
+		if got := takeoutMessageID(tt.line); got != tt.want {
+			t.Errorf("takeoutMessageID(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestImportMbox_Takeout(t *testing.T) {
+	tmp := t.TempDir()
+	st, err := store.Open(filepath.Join(tmp, "msgvault.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+
+	raw1 := email.NewMessage().
+		From("Bob <bob@example.com>").
+		To("Alice <alice@gmail.com>").
+		Subject("Plans").
+		Date("Mon, 01 Jan 2024 12:00:00 +0000").
+		Header("Message-ID", "<plans@example.com>").
+		Header("X-GM-THRID", "1787654321098765432").
+		Header("X-Gmail-Labels", "Inbox,Unread,Category Personal,Trips").
+		Body("Lunch?\n").
+		Bytes()
+	// A reply without References still joins the Gmail thread.
+	raw2 := email.NewMessage().
+		From("Alice <alice@gmail.com>").
+		To("Bob <bob@example.com>").
+		Subject("Re: Plans").
+		Date("Mon, 01 Jan 2024 13:00:00 +0000").
+		Header("Message-ID", "<reply@example.com>").
+		Header("X-GM-THRID", "1787654321098765432").
+		Header("X-Gmail-Labels", "Sent,Opened,Trips").
+		Body("Sure.\n").
+		Bytes()
+
+	var b strings.Builder
+	b.WriteString("From 1787654321098765432@xxx Mon Jan 01 12:00:00 +0000 2024\n")
+	b.Write(raw1)
+	b.WriteString("\nFrom 1787654321098765433@xxx Mon Jan 01 13:00:00 +0000 2024\n")
+	b.Write(raw2)
+	b.WriteString("\n")
+	mboxPath := filepath.Join(tmp, "All mail Including Spam and Trash.mbox")
+	if err := os.WriteFile(mboxPath, []byte(b.String()), 0600); err != nil {
+		t.Fatalf("write mbox: %v", err)
+	}
+
+	summary, err := ImportMbox(context.Background(), st, mboxPath, MboxImportOptions{
+		SourceType: "gmail",
+		Identifier: "alice@gmail.com",
+		Takeout:    true,
+		NoResume:   true,
+	})
+	if err != nil {
+		t.Fatalf("ImportMbox: %v", err)
+	}
+	if summary.MessagesAdded != 2 || summary.Errors != 0 {
+		t.Fatalf("summary = %+v", summary)
+	}
+
+	rows, err := st.DB().Query(`
+		SELECT m.source_message_id, c.source_conversation_id
+		FROM messages m JOIN conversations c ON c.id = m.conversation_id
+		ORDER BY m.source_message_id`)
+	if err != nil {
+		t.Fatalf("query messages: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []string
+	for rows.Next() {
+		var id, thread string
+		if err := rows.Scan(&id, &thread); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, id+" "+thread)
+	}
+	want := []string{"18cf06223648b478 18cf06223648b478", "18cf06223648b479 18cf06223648b478"}
+	if !slices.Equal(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+
+	labelsOf := func(sourceMsgID string) []string {
+		t.Helper()
+		rows, err := st.DB().Query(`
+			SELECT l.source_label_id FROM labels l
+			JOIN message_labels ml ON ml.label_id = l.id
+			JOIN messages m ON m.id = ml.message_id
+			WHERE m.source_message_id = ? ORDER BY l.source_label_id`, sourceMsgID)
+		if err != nil {
+			t.Fatalf("query labels: %v", err)
+		}
+		defer func() { _ = rows.Close() }()
+		var out []string
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				t.Fatalf("scan label: %v", err)
+			}
+			out = append(out, id)
+		}
+		return out
+	}
+	if got, want := labelsOf("18cf06223648b478"), []string{"CATEGORY_PERSONAL", "INBOX", "Trips", "UNREAD"}; !slices.Equal(got, want) {
+		t.Errorf("labels of first message = %q, want %q", got, want)
+	}
+	if got, want := labelsOf("18cf06223648b479"), []string{"SENT", "Trips"}; !slices.Equal(got, want) {
+		t.Errorf("labels of reply = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/textutil"
+)
+
+// ThreadRepairOptions controls RepairThreads.
+type ThreadRepairOptions struct {
+	// DryRun reports what would change without writing.
+	DryRun bool
+
+	// Progress, if set, is called after each message read with the
+	// number read so far.
+	Progress func(done int)
+}
+
+// ThreadRepairResult summarizes a RepairThreads run.
+type ThreadRepairResult struct {
+	Scanned          int
+	TitlesNormalized int
+	Merged           int // conversations merged into another
+	Failed           int // raw MIME missing or unreadable
+}
+
+// RepairThreads fixes mis-threaded email. It strips Re:/Fwd: chains
+// from conversation titles, then merges conversations of one account
+// whose messages reply to each other: a message whose References or
+// In-Reply-To names a message in another conversation brings the two
+// together, into the older one. Messages the user split off are never
+// merged back.
+func RepairThreads(ctx context.Context, st *store.Store, opts ThreadRepairOptions, log *slog.Logger) (*ThreadRepairResult, error) {
+	res := &ThreadRepairResult{}
+	n, err := st.NormalizeConversationTitles(opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	res.TitlesNormalized = n
+
+	// Read the threading headers of every message, then union the
+	// conversations linked by replies; each set's root is its oldest
+	// conversation.
+	type sourceMessage struct {
+		sourceID int64
+		id       string
+	}
+	type replies struct {
+		msg  store.ThreadingMessage
+		refs []string
+	}
+	convOf := make(map[sourceMessage]int64)
+	var linked []replies
+	for after := int64(0); ; {
+		batch, err := st.ThreadingMessages(after, reparseBatchSize)
+		if err != nil {
+			return res, err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		for _, m := range batch {
+			if err := ctx.Err(); err != nil {
+				return res, err
+			}
+			res.Scanned++
+			if opts.Progress != nil {
+				opts.Progress(res.Scanned)
+			}
+			raw, err := st.GetMessageRaw(m.ID)
+			if err != nil {
+				res.Failed++
+				log.Warn("thread repair: read raw MIME failed", "message", m.ID, "error", err)
+				continue
+			}
+			id, refs, err := mime.ThreadHeaders(raw)
+			if err != nil {
+				res.Failed++
+				log.Warn("thread repair: read headers failed", "message", m.ID,
+					"error", textutil.FirstLine(err.Error()))
+				continue
+			}
+			if id = normalizeMessageID(id); id != "" {
+				convOf[sourceMessage{m.SourceID, id}] = m.ConversationID
+			}
+			if len(refs) > 0 {
+				linked = append(linked, replies{m, refs})
+			}
+		}
+		after = batch[len(batch)-1].ID
+	}
+
+	parent := make(map[int64]int64)
+	var find func(int64) int64
+	find = func(c int64) int64 {
+		p, ok := parent[c]
+		if !ok || p == c {
+			return c
+		}
+		root := find(p)
+		parent[c] = root
+		return root
+	}
+	for _, r := range linked {
+		for _, ref := range r.refs {
+			conv, ok := convOf[sourceMessage{r.msg.SourceID, normalizeMessageID(ref)}]
+			if !ok {
+				continue
+			}
+			a, b := find(r.msg.ConversationID), find(conv)
+			if a == b {
+				continue
+			}
+			if b < a {
+				a, b = b, a
+			}
+			parent[b] = a
+		}
+	}
+
+	groups := make(map[int64][]int64)
+	for c := range parent {
+		if root := find(c); root != c {
+			groups[root] = append(groups[root], c)
+		}
+	}
+	roots := make([]int64, 0, len(groups))
+	for root := range groups {
+		roots = append(roots, root)
+	}
+	slices.Sort(roots)
+	for _, root := range roots {
+		from := groups[root]
+		slices.Sort(from)
+		res.Merged += len(from)
+		if opts.DryRun {
+			continue
+		}
+		if err := st.MergeConversations(root, from...); err != nil {
+			return res, err
+		}
+		log.Info("thread repair: merged conversations", "into", root, "from", from)
+	}
+	return res, nil
+}
@@ -0,0 +1,105 @@
+package importer
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestRepairThreads(t *testing.T) {
+	st, err := store.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	if err := st.InitSchema(); err != nil {
+		t.Fatalf("init schema: %v", err)
+	}
+	src, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("get/create source: %v", err)
+	}
+
+	// Three messages the source filed in three threads, though the
+	// second replies to the first.
+	ctx := context.Background()
+	for _, m := range []struct {
+		id, thread, subject string
+		headers             map[string]string
+	}{
+		{"a", "t1", "Lunch", map[string]string{"Message-ID": "<a@example.com>"}},
+		{"b", "t2", "Re: Lunch", map[string]string{"Message-ID": "<b@example.com>", "In-Reply-To": "<a@example.com>"}},
+		{"c", "t3", "Dinner", map[string]string{"Message-ID": "<c@example.com>"}},
+	} {
+		b := email.NewMessage().
+			From("Bob <bob@example.com>").
+			To("alice@example.com").
+			Subject(m.subject).
+			Body("See you there.")
+		for k, v := range m.headers {
+			b = b.Header(k, v)
+		}
+		if err := ingestRawMessage(ctx, st, src.ID, "alice@example.com", "", nil,
+			m.id, "hash-"+m.id, m.thread, b.Bytes(), time.Time{}, slog.Default()); err != nil {
+			t.Fatalf("ingestRawMessage(%s): %v", m.id, err)
+		}
+	}
+	if _, err := st.DB().Exec(`UPDATE conversations SET title = 'RE: Re: Lunch' WHERE source_conversation_id = 't2'`); err != nil {
+		t.Fatal(err)
+	}
+
+	convOf := func(sourceMsgID string) int64 {
+		t.Helper()
+		var conv int64
+		if err := st.DB().QueryRow(`SELECT conversation_id FROM messages WHERE source_message_id = ?`, sourceMsgID).Scan(&conv); err != nil {
+			t.Fatal(err)
+		}
+		return conv
+	}
+
+	res, err := RepairThreads(ctx, st, ThreadRepairOptions{DryRun: true}, slog.Default())
+	if err != nil {
+		t.Fatalf("RepairThreads dry run: %v", err)
+	}
+	if res.Scanned != 3 || res.Merged != 1 || res.TitlesNormalized != 1 || res.Failed != 0 {
+		t.Errorf("dry run = %+v, want 3 scanned, 1 merged, 1 title", res)
+	}
+	if convOf("a") == convOf("b") {
+		t.Fatal("dry run merged conversations")
+	}
+
+	res, err = RepairThreads(ctx, st, ThreadRepairOptions{}, slog.Default())
+	if err != nil {
+		t.Fatalf("RepairThreads: %v", err)
+	}
+	if res.Merged != 1 {
+		t.Errorf("Merged = %d, want 1", res.Merged)
+	}
+	if convOf("a") != convOf("b") {
+		t.Error("reply not merged into the conversation it replies to")
+	}
+	if convOf("c") == convOf("a") {
+		t.Error("unrelated conversation merged")
+	}
+
+	// Splitting the reply off again sticks: a second repair leaves it.
+	bID := int64(0)
+	if err := st.DB().QueryRow(`SELECT id FROM messages WHERE source_message_id = 'b'`).Scan(&bID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.SplitThread([]int64{bID}); err != nil {
+		t.Fatalf("SplitThread: %v", err)
+	}
+	res, err = RepairThreads(ctx, st, ThreadRepairOptions{}, slog.Default())
+	if err != nil {
+		t.Fatalf("RepairThreads after split: %v", err)
+	}
+	if res.Merged != 0 || convOf("a") == convOf("b") {
+		t.Errorf("repair after split merged %d conversations, want the split kept", res.Merged)
+	}
+}
@@ -0,0 +1,470 @@
+// Package jmap serves the archive over JMAP (RFC 8620 core, RFC 8621
+// mail) as a single read-only account. Mailboxes, filters, and content
+// search come from the mailview package, so JMAP clients see the same
+// folders and search results as the IMAP gateway. Methods that would
+// change the archive fail with accountReadOnly, and *changes methods
+// fail with cannotCalculateChanges so clients refetch instead of
+// diffing.
+package jmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/wesm/msgvault/internal/api"
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Capability URNs.
+const (
+	CapCore = "urn:ietf:params:jmap:core"
+	CapMail = "urn:ietf:params:jmap:mail"
+)
+
+const (
+	// AccountID is the ID of the single archive account.
+	AccountID = "archive"
+	// state is constant because changes are never calculated.
+	state = "0"
+
+	maxSizeRequest    = 10 << 20
+	maxCallsInRequest = 16
+	maxObjectsInGet   = 500
+)
+
+// Store is the archive access the JMAP facade needs.
+type Store interface {
+	mailview.Store
+	MailboxMessagesByID(ids []int64) ([]store.MailboxMessage, error)
+	ConversationMailboxMessages(conversationIDs, sourceIDs []int64) ([]store.MailboxMessage, error)
+	GetMessage(id int64) (*store.APIMessage, error)
+	GetMessageRaw(id int64) ([]byte, error)
+}
+
+// Handler is the JMAP HTTP handler. Mount it at /jmap behind API
+// authentication; it serves /session, /api, and /download.
+type Handler struct {
+	store    Store
+	searcher mailview.Searcher
+	logger   *slog.Logger
+	router   chi.Router
+
+	// scope returns the caller's name and readable sources (nil = all).
+	scope func(r *http.Request) (string, []int64)
+}
+
+// NewHandler creates a JMAP handler. searcher may be nil, in which case
+// Email/query only supports filters evaluated without the query engine
+// (mailboxes, keywords, dates, and sizes).
+func NewHandler(st Store, searcher mailview.Searcher, logger *slog.Logger) *Handler {
+	h := &Handler{
+		store:    st,
+		searcher: searcher,
+		logger:   logger,
+		scope:    api.RequestScope,
+	}
+	r := chi.NewRouter()
+	r.Get("/session", h.handleSession)
+	r.Post("/api", h.handleAPI)
+	r.Get("/download/{accountId}/{blobId}/{name}", h.handleDownload)
+	r.Post("/upload/{accountId}/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusForbidden, "", "The msgvault archive is read-only")
+	})
+	r.Get("/eventsource/", func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, http.StatusNotImplemented, "", "Push notifications are not supported")
+	})
+	h.router = r
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleSession(w http.ResponseWriter, r *http.Request) {
+	user, _ := h.scope(r)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	base := scheme + "://" + r.Host + strings.TrimSuffix(r.URL.Path, "/session")
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"capabilities": map[string]any{
+			CapCore: map[string]any{
+				"maxSizeUpload":         0,
+				"maxConcurrentUpload":   1,
+				"maxSizeRequest":        maxSizeRequest,
+				"maxConcurrentRequests": 4,
+				"maxCallsInRequest":     maxCallsInRequest,
+				"maxObjectsInGet":       maxObjectsInGet,
+				"maxObjectsInSet":       0,
+				"collationAlgorithms":   []string{"i;ascii-casemap"},
+			},
+			CapMail: map[string]any{},
+		},
+		"accounts": map[string]any{
+			AccountID: map[string]any{
+				"name":       "msgvault archive",
+				"isPersonal": true,
+				"isReadOnly": true,
+				"accountCapabilities": map[string]any{
+					CapMail: map[string]any{
+						"maxMailboxesPerEmail":       nil,
+						"maxMailboxDepth":            nil,
+						"maxSizeMailboxName":         255,
+						"maxSizeAttachmentsPerEmail": 0,
+						"emailQuerySortOptions":      sortProperties,
+						"mayCreateTopLevelMailbox":   false,
+					},
+				},
+			},
+		},
+		"primaryAccounts": map[string]string{CapMail: AccountID},
+		"username":        user,
+		"apiUrl":          base + "/api",
+		"downloadUrl":     base + "/download/{accountId}/{blobId}/{name}?accept={type}",
+		"uploadUrl":       base + "/upload/{accountId}/",
+		"eventSourceUrl":  base + "/eventsource/?types={types}&closeafter={closeafter}&ping={ping}",
+		"state":           state,
+	})
+}
+
+// invocation is a method call or response: a [name, arguments, callId]
+// triple on the wire.
+type invocation struct {
+	Name   string
+	Args   json.RawMessage
+	CallID string
+}
+
+func (inv *invocation) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("invocation must have 3 elements, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &inv.Name); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[2], &inv.CallID); err != nil {
+		return err
+	}
+	inv.Args = raw[1]
+	return nil
+}
+
+func (inv invocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{inv.Name, inv.Args, inv.CallID})
+}
+
+type request struct {
+	Using       []string     `json:"using"`
+	MethodCalls []invocation `json:"methodCalls"`
+}
+
+type response struct {
+	MethodResponses []invocation `json:"methodResponses"`
+	SessionState    string       `json:"sessionState"`
+}
+
+// methodError is a JMAP method-level error (RFC 8620 §3.6.2).
+type methodError struct {
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+}
+
+func errInvalidArguments(format string, args ...any) *methodError {
+	return &methodError{Type: "invalidArguments", Description: fmt.Sprintf(format, args...)}
+}
+
+var (
+	errAccountNotFound = &methodError{Type: "accountNotFound"}
+	errServerFail      = &methodError{Type: "serverFail"}
+)
+
+// call is the per-invocation context handed to method implementations.
+type call struct {
+	h         *Handler
+	r         *http.Request
+	sourceIDs []int64
+	// mailboxMsgs caches mailbox listings for the whole request.
+	mailboxMsgs map[string][]store.MailboxMessage
+}
+
+type method func(c *call, args json.RawMessage) (any, *methodError)
+
+var methods = map[string]method{
+	"Core/echo": func(_ *call, args json.RawMessage) (any, *methodError) {
+		return args, nil
+	},
+	"Mailbox/get":  (*call).mailboxGet,
+	"Email/query":  (*call).emailQuery,
+	"Email/get":    (*call).emailGet,
+	"Thread/get":   (*call).threadGet,
+	"Mailbox/set":  readOnly,
+	"Email/set":    readOnly,
+	"Email/import": readOnly,
+	"Email/copy":   readOnly,
+}
+
+func readOnly(c *call, args json.RawMessage) (any, *methodError) {
+	if err := c.checkAccount(args); err != nil {
+		return nil, err
+	}
+	return nil, &methodError{Type: "accountReadOnly"}
+}
+
+func (h *Handler) handleAPI(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(w, r)
+	if err != nil {
+		writeProblem(w, http.StatusRequestEntityTooLarge, "urn:ietf:params:jmap:error:limit", "Request exceeds maxSizeRequest")
+		return
+	}
+	if !json.Valid(body) {
+		writeProblem(w, http.StatusBadRequest, "urn:ietf:params:jmap:error:notJSON", "Request body is not JSON")
+		return
+	}
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil || req.MethodCalls == nil {
+		writeProblem(w, http.StatusBadRequest, "urn:ietf:params:jmap:error:notRequest", "Request is not a JMAP Request object")
+		return
+	}
+	for _, u := range req.Using {
+		if u != CapCore && u != CapMail {
+			writeProblem(w, http.StatusBadRequest, "urn:ietf:params:jmap:error:unknownCapability", "Unknown capability: "+u)
+			return
+		}
+	}
+	if len(req.MethodCalls) > maxCallsInRequest {
+		writeProblem(w, http.StatusBadRequest, "urn:ietf:params:jmap:error:limit", "Too many method calls")
+		return
+	}
+
+	_, sourceIDs := h.scope(r)
+	c := &call{h: h, r: r, sourceIDs: sourceIDs, mailboxMsgs: make(map[string][]store.MailboxMessage)}
+	resp := response{MethodResponses: []invocation{}, SessionState: state}
+	for _, inv := range req.MethodCalls {
+		result, mErr := c.invoke(inv, req.Using, resp.MethodResponses)
+		out := invocation{Name: inv.Name, CallID: inv.CallID}
+		if mErr != nil {
+			out.Name = "error"
+			result = mErr
+		}
+		if out.Args, err = json.Marshal(result); err != nil {
+			h.logger.Error("jmap: failed to encode response", "method", inv.Name, "error", err)
+			out.Name, out.Args = "error", json.RawMessage(`{"type":"serverFail"}`)
+		}
+		resp.MethodResponses = append(resp.MethodResponses, out)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (c *call) invoke(inv invocation, using []string, prior []invocation) (any, *methodError) {
+	fn, ok := methods[inv.Name]
+	if !ok || (inv.Name != "Core/echo" && !slices.Contains(using, CapMail)) {
+		if strings.HasSuffix(inv.Name, "/changes") || strings.HasSuffix(inv.Name, "/queryChanges") {
+			return nil, &methodError{Type: "cannotCalculateChanges"}
+		}
+		return nil, &methodError{Type: "unknownMethod"}
+	}
+	args, mErr := resolveReferences(inv.Args, prior)
+	if mErr != nil {
+		return nil, mErr
+	}
+	return fn(c, args)
+}
+
+// resolveReferences replaces "#name" arguments with the value their
+// ResultReference points to in an earlier response (RFC 8620 §3.7).
+func resolveReferences(raw json.RawMessage, prior []invocation) (json.RawMessage, *methodError) {
+	var args map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, errInvalidArguments("arguments must be an object")
+	}
+	changed := false
+	for key, val := range args {
+		if !strings.HasPrefix(key, "#") {
+			continue
+		}
+		name := key[1:]
+		if _, dup := args[name]; dup {
+			return nil, errInvalidArguments("both %q and %q given", name, key)
+		}
+		var ref struct {
+			ResultOf string `json:"resultOf"`
+			Name     string `json:"name"`
+			Path     string `json:"path"`
+		}
+		if err := json.Unmarshal(val, &ref); err != nil {
+			return nil, &methodError{Type: "invalidResultReference"}
+		}
+		idx := slices.IndexFunc(prior, func(p invocation) bool {
+			return p.CallID == ref.ResultOf && p.Name == ref.Name
+		})
+		if idx < 0 {
+			return nil, &methodError{Type: "invalidResultReference", Description: "no response for " + ref.ResultOf}
+		}
+		var doc any
+		if err := json.Unmarshal(prior[idx].Args, &doc); err != nil {
+			return nil, &methodError{Type: "invalidResultReference"}
+		}
+		resolved, ok := evalPointer(doc, ref.Path)
+		if !ok {
+			return nil, &methodError{Type: "invalidResultReference", Description: "path not found: " + ref.Path}
+		}
+		b, err := json.Marshal(resolved)
+		if err != nil {
+			return nil, &methodError{Type: "invalidResultReference"}
+		}
+		delete(args, key)
+		args[name] = b
+		changed = true
+	}
+	if !changed {
+		return raw, nil
+	}
+	out, err := json.Marshal(args)
+	if err != nil {
+		return nil, errServerFail
+	}
+	return out, nil
+}
+
+// evalPointer evaluates a JSON Pointer with the JMAP "*" extension: "*"
+// maps the rest of the path over an array, flattening array results.
+func evalPointer(doc any, path string) (any, bool) {
+	if path == "" {
+		return doc, true
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+	token, rest := path[1:], ""
+	if i := strings.IndexByte(token, '/'); i >= 0 {
+		token, rest = token[:i], token[i:]
+	}
+	token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+	switch v := doc.(type) {
+	case map[string]any:
+		child, ok := v[token]
+		if !ok {
+			return nil, false
+		}
+		return evalPointer(child, rest)
+	case []any:
+		if token == "*" {
+			out := []any{}
+			for _, item := range v {
+				res, ok := evalPointer(item, rest)
+				if !ok {
+					return nil, false
+				}
+				if arr, isArr := res.([]any); isArr {
+					out = append(out, arr...)
+				} else {
+					out = append(out, res)
+				}
+			}
+			return out, true
+		}
+		i, err := strconv.Atoi(token)
+		if err != nil || i < 0 || i >= len(v) {
+			return nil, false
+		}
+		return evalPointer(v[i], rest)
+	}
+	return nil, false
+}
+
+func (c *call) checkAccount(raw json.RawMessage) *methodError {
+	var args struct {
+		AccountID string `json:"accountId"`
+	}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return errInvalidArguments("%v", err)
+	}
+	if args.AccountID != AccountID {
+		return errAccountNotFound
+	}
+	return nil
+}
+
+func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request) {
+	if chi.URLParam(r, "accountId") != AccountID {
+		writeProblem(w, http.StatusNotFound, "", "Account not found")
+		return
+	}
+	id, ok := parseID(chi.URLParam(r, "blobId"), blobPrefix)
+	_, sourceIDs := h.scope(r)
+	if ok {
+		msgs, err := h.store.MailboxMessagesByID([]int64{id})
+		if err != nil {
+			h.logger.Error("jmap: failed to load message", "id", id, "error", err)
+			writeProblem(w, http.StatusInternalServerError, "", "Failed to load blob")
+			return
+		}
+		ok = len(msgs) == 1 && visible(msgs[0], sourceIDs)
+	}
+	var raw []byte
+	if ok {
+		var err error
+		raw, err = h.store.GetMessageRaw(id)
+		ok = err == nil
+	}
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "", "Blob not found")
+		return
+	}
+
+	ct := r.URL.Query().Get("accept")
+	if ct == "" {
+		ct = "message/rfc822"
+	}
+	w.Header().Set("Content-Type", ct)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", chi.URLParam(r, "name")))
+	w.Header().Set("Cache-Control", "private, immutable, max-age=31536000")
+	_, _ = w.Write(raw)
+}
+
+func visible(m store.MailboxMessage, sourceIDs []int64) bool {
+	return sourceIDs == nil || slices.Contains(sourceIDs, m.SourceID)
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := buf.ReadFrom(http.MaxBytesReader(w, r.Body, maxSizeRequest))
+	return buf.Bytes(), err
+}
+
+func writeJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+// writeProblem writes an RFC 7807 problem details response, the format
+// JMAP uses for request-level errors.
+func writeProblem(w http.ResponseWriter, status int, typ, detail string) {
+	if typ == "" {
+		typ = "about:blank"
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type":   typ,
+		"status": status,
+		"detail": detail,
+	})
+}
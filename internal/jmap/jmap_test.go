@@ -0,0 +1,533 @@
+package jmap
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+type fakeMessage struct {
+	store.MailboxMessage
+	labels []string
+	detail store.APIMessage
+	raw    []byte
+}
+
+type fakeStore struct {
+	messages []fakeMessage
+}
+
+func (f *fakeStore) visible(m fakeMessage, sourceIDs []int64) bool {
+	return len(sourceIDs) == 0 || slices.Contains(sourceIDs, m.SourceID)
+}
+
+func (f *fakeStore) MailboxNames(sourceIDs []int64) ([]string, error) {
+	var names []string
+	for _, m := range f.messages {
+		if f.visible(m, sourceIDs) {
+			names = append(names, m.labels...)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+func (f *fakeStore) MailboxMessages(label string, sourceIDs []int64) ([]store.MailboxMessage, error) {
+	var out []store.MailboxMessage
+	for _, m := range f.messages {
+		if f.visible(m, sourceIDs) && (label == "" || slices.Contains(m.labels, label)) {
+			out = append(out, m.MailboxMessage)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) MailboxMessagesByID(ids []int64) ([]store.MailboxMessage, error) {
+	var out []store.MailboxMessage
+	for _, m := range f.messages {
+		if slices.Contains(ids, m.ID) {
+			out = append(out, m.MailboxMessage)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) ConversationMailboxMessages(conversationIDs, sourceIDs []int64) ([]store.MailboxMessage, error) {
+	var out []store.MailboxMessage
+	for _, m := range f.messages {
+		if f.visible(m, sourceIDs) && slices.Contains(conversationIDs, m.ConversationID) {
+			out = append(out, m.MailboxMessage)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) GetMessage(id int64) (*store.APIMessage, error) {
+	for _, m := range f.messages {
+		if m.ID == id {
+			d := m.detail
+			d.ID = id
+			d.Labels = m.labels
+			return &d, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeStore) GetMessageRaw(id int64) ([]byte, error) {
+	for _, m := range f.messages {
+		if m.ID == id && m.raw != nil {
+			return m.raw, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// fakeSearcher matches messages whose ID is listed and records queries.
+type fakeSearcher struct {
+	ids     []int64
+	queries []search.Query
+}
+
+func (f *fakeSearcher) Search(_ context.Context, q *search.Query, limit, offset int) ([]query.MessageSummary, error) {
+	f.queries = append(f.queries, *q)
+	var out []query.MessageSummary
+	for _, id := range f.ids {
+		out = append(out, query.MessageSummary{ID: id})
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func newFakeStore() *fakeStore {
+	date := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	return &fakeStore{messages: []fakeMessage{
+		{
+			MailboxMessage: store.MailboxMessage{ID: 1, SourceID: 1, ConversationID: 7, Size: 100, InternalDate: date, Seen: true},
+			labels:         []string{"INBOX"},
+			detail: store.APIMessage{
+				Subject: "Hello", From: "bob@example.com", To: []string{"alice@example.com"},
+				SentAt: date, Snippet: "Hi Alice", Body: "Hi Alice, how are you?",
+			},
+			raw: []byte("Subject: Hello\r\n\r\nHi Alice\r\n"),
+		},
+		{
+			MailboxMessage: store.MailboxMessage{ID: 2, SourceID: 1, ConversationID: 7, Size: 300, InternalDate: date.AddDate(0, 0, 1), Flagged: true},
+			labels:         []string{"INBOX", "Work/Projects"},
+			detail:         store.APIMessage{Subject: "Re: Hello", From: "alice@example.com"},
+		},
+		{
+			MailboxMessage: store.MailboxMessage{ID: 3, SourceID: 2, ConversationID: 9, Size: 200, InternalDate: date.AddDate(0, 0, 2)},
+			labels:         []string{"Receipts"},
+			detail:         store.APIMessage{Subject: "Your receipt", From: "shop@example.com"},
+		},
+	}}
+}
+
+type testClient struct {
+	t       *testing.T
+	handler *Handler
+}
+
+func newTestClient(t *testing.T, searcher *fakeSearcher, sourceIDs []int64) *testClient {
+	t.Helper()
+	h := NewHandler(newFakeStore(), searcher, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if searcher == nil {
+		h.searcher = nil
+	}
+	h.scope = func(*http.Request) (string, []int64) { return "alice", sourceIDs }
+	return &testClient{t: t, handler: h}
+}
+
+// call sends one JMAP request and returns the method responses.
+func (tc *testClient) call(calls ...[]any) [][]any {
+	tc.t.Helper()
+	body, _ := json.Marshal(map[string]any{
+		"using":       []string{CapCore, CapMail},
+		"methodCalls": calls,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	tc.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		tc.t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		MethodResponses [][]any `json:"methodResponses"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		tc.t.Fatalf("decode response: %v", err)
+	}
+	return resp.MethodResponses
+}
+
+func args(resp []any) map[string]any {
+	return resp[1].(map[string]any)
+}
+
+func strs(v any) []string {
+	var out []string
+	for _, s := range v.([]any) {
+		out = append(out, s.(string))
+	}
+	return out
+}
+
+func TestSession(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "http://vault.example.com/jmap/session", nil)
+	w := httptest.NewRecorder()
+	// The handler is mounted under /jmap; simulate chi's prefix strip.
+	req.URL.Path = "/session"
+	tc.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	var s map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &s); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if s["username"] != "alice" {
+		t.Errorf("username = %v", s["username"])
+	}
+	if s["apiUrl"] != "http://vault.example.com/api" {
+		t.Errorf("apiUrl = %v", s["apiUrl"])
+	}
+	acct := s["accounts"].(map[string]any)[AccountID].(map[string]any)
+	if acct["isReadOnly"] != true {
+		t.Errorf("account should be read-only: %v", acct)
+	}
+}
+
+func TestMailboxGet(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	resp := tc.call([]any{"Mailbox/get", map[string]any{"accountId": AccountID}, "0"})
+	list := args(resp[0])["list"].([]any)
+
+	byName := make(map[string]map[string]any)
+	for _, item := range list {
+		mb := item.(map[string]any)
+		byName[mb["name"].(string)] = mb
+	}
+	inbox := byName["INBOX"]
+	if inbox["role"] != "inbox" || inbox["totalEmails"] != float64(2) || inbox["unreadEmails"] != float64(1) {
+		t.Errorf("INBOX = %v", inbox)
+	}
+	if inbox["totalThreads"] != float64(1) {
+		t.Errorf("INBOX threads = %v, want 1", inbox["totalThreads"])
+	}
+	if byName["All Mail"]["role"] != "all" || byName["All Mail"]["totalEmails"] != float64(3) {
+		t.Errorf("All Mail = %v", byName["All Mail"])
+	}
+	projects := byName["Projects"]
+	if projects == nil || projects["parentId"] != mailboxID("Work") {
+		t.Errorf("Projects = %v, want child of Work", projects)
+	}
+	if byName["Work"]["myRights"].(map[string]any)["mayReadItems"] != false {
+		t.Errorf("label-less parent should not be readable: %v", byName["Work"])
+	}
+}
+
+func TestEmailQuery(t *testing.T) {
+	searcher := &fakeSearcher{ids: []int64{1, 3}}
+	tests := []struct {
+		name   string
+		args   map[string]any
+		want   []string
+		engine bool
+	}{
+		{
+			name: "default sort newest first",
+			args: map[string]any{},
+			want: []string{"M3", "M2", "M1"},
+		},
+		{
+			name: "in mailbox",
+			args: map[string]any{"filter": map[string]any{"inMailbox": mailboxID("INBOX")}},
+			want: []string{"M2", "M1"},
+		},
+		{
+			name:   "from uses engine",
+			args:   map[string]any{"filter": map[string]any{"from": "bob@example.com"}},
+			want:   []string{"M3", "M1"},
+			engine: true,
+		},
+		{
+			name: "operator combines engine and keyword",
+			args: map[string]any{"filter": map[string]any{
+				"operator": "AND",
+				"conditions": []any{
+					map[string]any{"text": "hello"},
+					map[string]any{"inMailbox": mailboxID("INBOX")},
+				},
+			}},
+			want:   []string{"M1"},
+			engine: true,
+		},
+		{
+			name: "not keyword",
+			args: map[string]any{"filter": map[string]any{
+				"operator":   "NOT",
+				"conditions": []any{map[string]any{"hasKeyword": "$seen"}},
+			}},
+			want: []string{"M3", "M2"},
+		},
+		{
+			name: "sort by size ascending with limit",
+			args: map[string]any{"sort": []any{map[string]any{"property": "size", "isAscending": true}}, "limit": 2},
+			want: []string{"M1", "M3"},
+		},
+		{
+			name: "collapse threads",
+			args: map[string]any{"collapseThreads": true},
+			want: []string{"M3", "M2"},
+		},
+		{
+			name: "anchor",
+			args: map[string]any{"anchor": "M2", "anchorOffset": 1},
+			want: []string{"M1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher.queries = nil
+			tc := newTestClient(t, searcher, nil)
+			tt.args["accountId"] = AccountID
+			resp := tc.call([]any{"Email/query", tt.args, "q"})
+			if resp[0][0] != "Email/query" {
+				t.Fatalf("response = %v", resp[0])
+			}
+			if got := strs(args(resp[0])["ids"]); !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+			if tt.engine != (len(searcher.queries) > 0) {
+				t.Errorf("engine queries = %d, want engine used: %v", len(searcher.queries), tt.engine)
+			}
+		})
+	}
+}
+
+func TestEmailQuery_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		searcher *fakeSearcher
+		args     map[string]any
+		wantType string
+	}{
+		{"wrong account", nil, map[string]any{"accountId": "other"}, "accountNotFound"},
+		{"unknown sort", nil, map[string]any{"accountId": AccountID, "sort": []any{map[string]any{"property": "from"}}}, "unsupportedSort"},
+		{"thread keyword filter", nil, map[string]any{"accountId": AccountID, "filter": map[string]any{"allInThreadHaveKeyword": "$seen"}}, "unsupportedFilter"},
+		{"unsupported header", &fakeSearcher{}, map[string]any{"accountId": AccountID, "filter": map[string]any{"header": []string{"X-Mailer", "x"}}}, "unsupportedFilter"},
+		{"text without engine", nil, map[string]any{"accountId": AccountID, "filter": map[string]any{"text": "hello"}}, "unsupportedFilter"},
+		{"missing anchor", nil, map[string]any{"accountId": AccountID, "anchor": "M99"}, "anchorNotFound"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := newTestClient(t, tt.searcher, nil)
+			resp := tc.call([]any{"Email/query", tt.args, "q"})
+			if resp[0][0] != "error" || args(resp[0])["type"] != tt.wantType {
+				t.Errorf("response = %v, want error %s", resp[0], tt.wantType)
+			}
+		})
+	}
+}
+
+func TestEmailGet_WithResultReference(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	resp := tc.call(
+		[]any{"Email/query", map[string]any{
+			"accountId": AccountID,
+			"filter":    map[string]any{"inMailbox": mailboxID("INBOX")},
+		}, "q"},
+		[]any{"Email/get", map[string]any{
+			"accountId":           AccountID,
+			"#ids":                map[string]any{"resultOf": "q", "name": "Email/query", "path": "/ids"},
+			"fetchTextBodyValues": true,
+		}, "g"},
+	)
+	if resp[1][0] != "Email/get" {
+		t.Fatalf("response = %v", resp[1])
+	}
+	list := args(resp[1])["list"].([]any)
+	if len(list) != 2 {
+		t.Fatalf("got %d emails, want 2", len(list))
+	}
+	first := list[1].(map[string]any)
+	if first["id"] != "M1" || first["subject"] != "Hello" || first["threadId"] != "T7" || first["blobId"] != "B1" {
+		t.Errorf("email = %v", first)
+	}
+	if kw := first["keywords"].(map[string]any); kw["$seen"] != true {
+		t.Errorf("keywords = %v", kw)
+	}
+	mailboxes := first["mailboxIds"].(map[string]any)
+	if mailboxes[mailboxID("INBOX")] != true || mailboxes[mailboxID("All Mail")] != true {
+		t.Errorf("mailboxIds = %v", mailboxes)
+	}
+	from := first["from"].([]any)[0].(map[string]any)
+	if from["email"] != "bob@example.com" {
+		t.Errorf("from = %v", from)
+	}
+	body := first["bodyValues"].(map[string]any)["1"].(map[string]any)
+	if body["value"] != "Hi Alice, how are you?" {
+		t.Errorf("bodyValues = %v", body)
+	}
+}
+
+func TestEmailGet_PropertiesAndNotFound(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	resp := tc.call([]any{"Email/get", map[string]any{
+		"accountId":  AccountID,
+		"ids":        []string{"M2", "M404", "bogus"},
+		"properties": []string{"size"},
+	}, "g"})
+	a := args(resp[0])
+	list := a["list"].([]any)
+	if len(list) != 1 {
+		t.Fatalf("list = %v", list)
+	}
+	email := list[0].(map[string]any)
+	if len(email) != 2 || email["id"] != "M2" || email["size"] != float64(300) {
+		t.Errorf("email = %v, want only id and size", email)
+	}
+	if nf := strs(a["notFound"]); !slices.Equal(nf, []string{"bogus", "M404"}) {
+		t.Errorf("notFound = %v", nf)
+	}
+}
+
+func TestScopedAccess(t *testing.T) {
+	tc := newTestClient(t, nil, []int64{1})
+
+	resp := tc.call([]any{"Email/get", map[string]any{"accountId": AccountID, "ids": []string{"M1", "M3"}}, "g"})
+	a := args(resp[0])
+	if len(a["list"].([]any)) != 1 || !slices.Equal(strs(a["notFound"]), []string{"M3"}) {
+		t.Errorf("scoped Email/get = %v, want M3 hidden", a)
+	}
+
+	resp = tc.call([]any{"Email/query", map[string]any{"accountId": AccountID}, "q"})
+	if got := strs(args(resp[0])["ids"]); slices.Contains(got, "M3") {
+		t.Errorf("scoped Email/query leaked M3: %v", got)
+	}
+
+	resp = tc.call([]any{"Thread/get", map[string]any{"accountId": AccountID, "ids": []string{"T7", "T9"}}, "t"})
+	a = args(resp[0])
+	thread := a["list"].([]any)[0].(map[string]any)
+	if !slices.Equal(strs(thread["emailIds"]), []string{"M1", "M2"}) || !slices.Equal(strs(a["notFound"]), []string{"T9"}) {
+		t.Errorf("scoped Thread/get = %v", a)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/download/archive/B3/msg.eml", nil)
+	w := httptest.NewRecorder()
+	tc.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("scoped download of another account's blob = %d, want 404", w.Code)
+	}
+}
+
+func TestReadOnlyAndUnknownMethods(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	resp := tc.call(
+		[]any{"Email/set", map[string]any{"accountId": AccountID, "destroy": []string{"M1"}}, "s"},
+		[]any{"Email/changes", map[string]any{"accountId": AccountID, "sinceState": "0"}, "c"},
+		[]any{"Calendar/get", map[string]any{"accountId": AccountID}, "x"},
+		[]any{"Core/echo", map[string]any{"hello": true}, "e"},
+	)
+	want := []string{"accountReadOnly", "cannotCalculateChanges", "unknownMethod"}
+	for i, typ := range want {
+		if resp[i][0] != "error" || args(resp[i])["type"] != typ {
+			t.Errorf("response %d = %v, want %s", i, resp[i], typ)
+		}
+	}
+	if resp[3][0] != "Core/echo" || args(resp[3])["hello"] != true {
+		t.Errorf("echo = %v", resp[3])
+	}
+}
+
+func TestDownload(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/download/archive/B1/hello.eml", nil)
+	w := httptest.NewRecorder()
+	tc.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "message/rfc822" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "Subject: Hello") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+
+	// Message 2 has no stored raw MIME.
+	req = httptest.NewRequest(http.MethodGet, "/download/archive/B2/x.eml", nil)
+	w = httptest.NewRecorder()
+	tc.handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing raw = %d, want 404", w.Code)
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	tc := newTestClient(t, nil, nil)
+	tests := []struct {
+		name     string
+		body     string
+		wantType string
+	}{
+		{"not json", "{", "urn:ietf:params:jmap:error:notJSON"},
+		{"not request", `{"using": []}`, "urn:ietf:params:jmap:error:notRequest"},
+		{"unknown capability", `{"using": ["urn:example:nope"], "methodCalls": []}`, "urn:ietf:params:jmap:error:unknownCapability"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			tc.handler.ServeHTTP(w, req)
+			var p map[string]any
+			_ = json.Unmarshal(w.Body.Bytes(), &p)
+			if w.Code != http.StatusBadRequest || p["type"] != tt.wantType {
+				t.Errorf("status = %d, problem = %v, want %s", w.Code, p, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestEvalPointer(t *testing.T) {
+	var doc any
+	_ = json.Unmarshal([]byte(`{"list": [{"threadId": "T1", "emailIds": ["M1", "M2"]}, {"threadId": "T2", "emailIds": ["M3"]}]}`), &doc)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/list/*/threadId", `["T1","T2"]`},
+		{"/list/*/emailIds", `["M1","M2","M3"]`},
+		{"/list/1/threadId", `"T2"`},
+	}
+	for _, tt := range tests {
+		got, ok := evalPointer(doc, tt.path)
+		if !ok {
+			t.Errorf("%s: not found", tt.path)
+			continue
+		}
+		b, _ := json.Marshal(got)
+		if string(b) != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, b, tt.want)
+		}
+	}
+	if _, ok := evalPointer(doc, "/missing"); ok {
+		t.Error("missing path should not resolve")
+	}
+}
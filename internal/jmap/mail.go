@@ -0,0 +1,804 @@
+package jmap
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/mailview"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Object ID prefixes. JMAP IDs are opaque strings; these keep the
+// namespaces apart so an email ID can't be passed off as a thread ID.
+const (
+	emailPrefix   = "M"
+	blobPrefix    = "B"
+	threadPrefix  = "T"
+	mailboxPrefix = "m"
+)
+
+const (
+	defaultQueryLimit = 256
+	maxQueryLimit     = 1000
+)
+
+var sortProperties = []string{"receivedAt", "size", "id"}
+
+func formatID(prefix string, id int64) string {
+	return prefix + strconv.FormatInt(id, 10)
+}
+
+func parseID(s, prefix string) (int64, bool) {
+	rest, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(rest, 10, 64)
+	return id, err == nil && id > 0
+}
+
+// mailboxID encodes a mailbox name into the JMAP Id character set.
+func mailboxID(name string) string {
+	return mailboxPrefix + base64.RawURLEncoding.EncodeToString([]byte(name))
+}
+
+func mailboxName(id string) (string, bool) {
+	rest, ok := strings.CutPrefix(id, mailboxPrefix)
+	if !ok {
+		return "", false
+	}
+	b, err := base64.RawURLEncoding.DecodeString(rest)
+	return string(b), err == nil
+}
+
+// mailboxRoles maps well-known label names to RFC 8621 roles.
+var mailboxRoles = map[string]string{
+	"inbox":     "inbox",
+	"all mail":  "all",
+	"sent":      "sent",
+	"draft":     "drafts",
+	"drafts":    "drafts",
+	"spam":      "junk",
+	"trash":     "trash",
+	"important": "important",
+	"starred":   "flagged",
+}
+
+func (c *call) mailboxes() (*mailview.Mailboxes, *methodError) {
+	mb, err := mailview.LoadMailboxes(c.h.store, c.sourceIDs)
+	if err != nil {
+		c.h.logger.Error("jmap: failed to list mailboxes", "error", err)
+		return nil, errServerFail
+	}
+	return mb, nil
+}
+
+// mailboxMessages lists a mailbox's messages, cached for the request.
+func (c *call) mailboxMessages(label string) ([]store.MailboxMessage, *methodError) {
+	if msgs, ok := c.mailboxMsgs[label]; ok {
+		return msgs, nil
+	}
+	msgs, err := c.h.store.MailboxMessages(label, c.sourceIDs)
+	if err != nil {
+		c.h.logger.Error("jmap: failed to list messages", "label", label, "error", err)
+		return nil, errServerFail
+	}
+	c.mailboxMsgs[label] = msgs
+	return msgs, nil
+}
+
+type getArgs struct {
+	AccountID  string    `json:"accountId"`
+	IDs        *[]string `json:"ids"`
+	Properties []string  `json:"properties"`
+}
+
+func (c *call) mailboxGet(raw json.RawMessage) (any, *methodError) {
+	var args getArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, errInvalidArguments("%v", err)
+	}
+	if args.AccountID != AccountID {
+		return nil, errAccountNotFound
+	}
+	mb, mErr := c.mailboxes()
+	if mErr != nil {
+		return nil, mErr
+	}
+
+	var names []string
+	notFound := []string{}
+	if args.IDs == nil {
+		names = mb.Names()
+	} else {
+		for _, id := range *args.IDs {
+			name, ok := mailboxName(id)
+			if ok {
+				_, ok = mb.Resolve(name)
+				ok = ok || mb.IsParent(name)
+			}
+			if !ok {
+				notFound = append(notFound, id)
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	list := []map[string]any{}
+	for _, name := range names {
+		obj, mErr := c.mailboxObject(mb, name)
+		if mErr != nil {
+			return nil, mErr
+		}
+		list = append(list, pick(obj, args.Properties))
+	}
+	return map[string]any{
+		"accountId": AccountID,
+		"state":     state,
+		"list":      list,
+		"notFound":  notFound,
+	}, nil
+}
+
+func (c *call) mailboxObject(mb *mailview.Mailboxes, name string) (map[string]any, *methodError) {
+	var total, unread, totalThreads, unreadThreads int
+	if !mb.IsParent(name) {
+		label, _ := mb.Resolve(name)
+		msgs, mErr := c.mailboxMessages(label)
+		if mErr != nil {
+			return nil, mErr
+		}
+		threads := make(map[int64]bool) // conversation -> has unread
+		for _, m := range msgs {
+			total++
+			if !m.Seen {
+				unread++
+			}
+			threads[m.ConversationID] = threads[m.ConversationID] || !m.Seen
+		}
+		totalThreads = len(threads)
+		for _, hasUnread := range threads {
+			if hasUnread {
+				unreadThreads++
+			}
+		}
+	}
+
+	display := name
+	var parentID any
+	if i := strings.LastIndexByte(name, mailview.Delim); i > 0 {
+		display = name[i+1:]
+		parentID = mailboxID(name[:i])
+	}
+	var role any
+	if r, ok := mailboxRoles[strings.ToLower(name)]; ok {
+		role = r
+	}
+	sortOrder := 10
+	if name == mailview.InboxName {
+		sortOrder = 0
+	}
+	return map[string]any{
+		"id":            mailboxID(name),
+		"name":          display,
+		"parentId":      parentID,
+		"role":          role,
+		"sortOrder":     sortOrder,
+		"totalEmails":   total,
+		"unreadEmails":  unread,
+		"totalThreads":  totalThreads,
+		"unreadThreads": unreadThreads,
+		"myRights": map[string]bool{
+			"mayReadItems":   !mb.IsParent(name),
+			"mayAddItems":    false,
+			"mayRemoveItems": false,
+			"maySetSeen":     false,
+			"maySetKeywords": false,
+			"mayCreateChild": false,
+			"mayRename":      false,
+			"mayDelete":      false,
+			"maySubmit":      false,
+		},
+		"isSubscribed": true,
+	}, nil
+}
+
+// filterCondition is an RFC 8621 Email/query FilterCondition, or a
+// FilterOperator when Operator is set.
+type filterCondition struct {
+	Operator   string            `json:"operator"`
+	Conditions []filterCondition `json:"conditions"`
+
+	InMailbox          *string    `json:"inMailbox"`
+	InMailboxOtherThan []string   `json:"inMailboxOtherThan"`
+	Before             *time.Time `json:"before"`
+	After              *time.Time `json:"after"`
+	MinSize            *int64     `json:"minSize"`
+	MaxSize            *int64     `json:"maxSize"`
+	HasKeyword         *string    `json:"hasKeyword"`
+	NotKeyword         *string    `json:"notKeyword"`
+	HasAttachment      *bool      `json:"hasAttachment"`
+	Text               string     `json:"text"`
+	From               string     `json:"from"`
+	To                 string     `json:"to"`
+	Cc                 string     `json:"cc"`
+	Bcc                string     `json:"bcc"`
+	Subject            string     `json:"subject"`
+	Body               string     `json:"body"`
+	Header             []string   `json:"header"`
+}
+
+type comparator struct {
+	Property    string `json:"property"`
+	IsAscending *bool  `json:"isAscending"`
+}
+
+type queryArgs struct {
+	AccountID       string          `json:"accountId"`
+	Filter          json.RawMessage `json:"filter"`
+	Sort            []comparator    `json:"sort"`
+	Position        int             `json:"position"`
+	Anchor          *string         `json:"anchor"`
+	AnchorOffset    int             `json:"anchorOffset"`
+	Limit           *int            `json:"limit"`
+	CalculateTotal  bool            `json:"calculateTotal"`
+	CollapseThreads bool            `json:"collapseThreads"`
+}
+
+// predicate reports whether a message matches a filter.
+type predicate func(m store.MailboxMessage) bool
+
+func (c *call) emailQuery(raw json.RawMessage) (any, *methodError) {
+	var args queryArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, errInvalidArguments("%v", err)
+	}
+	if args.AccountID != AccountID {
+		return nil, errAccountNotFound
+	}
+	filter, mErr := parseFilter(args.Filter)
+	if mErr != nil {
+		return nil, mErr
+	}
+	for _, cmp := range args.Sort {
+		if !slices.Contains(sortProperties, cmp.Property) {
+			return nil, &methodError{Type: "unsupportedSort", Description: "cannot sort by " + cmp.Property}
+		}
+	}
+
+	// A top-level mailbox condition picks the base listing; everything
+	// else narrows it.
+	base := ""
+	if f := filter; f != nil && f.Operator == "" && f.InMailbox != nil {
+		name, ok := mailboxName(*f.InMailbox)
+		if !ok {
+			return nil, &methodError{Type: "unsupportedFilter", Description: "unknown mailbox " + *f.InMailbox}
+		}
+		mb, mErr := c.mailboxes()
+		if mErr != nil {
+			return nil, mErr
+		}
+		if base, ok = mb.Resolve(name); !ok {
+			return nil, &methodError{Type: "unsupportedFilter", Description: "unknown mailbox " + *f.InMailbox}
+		}
+	}
+	msgs, mErr := c.mailboxMessages(base)
+	if mErr != nil {
+		return nil, mErr
+	}
+	match := func(store.MailboxMessage) bool { return true }
+	if filter != nil {
+		if match, mErr = c.compile(filter); mErr != nil {
+			return nil, mErr
+		}
+	}
+
+	var hits []store.MailboxMessage
+	for _, m := range msgs {
+		if match(m) {
+			hits = append(hits, m)
+		}
+	}
+	slices.SortStableFunc(hits, compareBy(args.Sort))
+	if args.CollapseThreads {
+		seen := make(map[int64]bool)
+		hits = slices.DeleteFunc(hits, func(m store.MailboxMessage) bool {
+			dup := seen[m.ConversationID]
+			seen[m.ConversationID] = true
+			return dup
+		})
+	}
+
+	limit := defaultQueryLimit
+	if args.Limit != nil {
+		if *args.Limit < 0 {
+			return nil, errInvalidArguments("limit must not be negative")
+		}
+		limit = *args.Limit
+	}
+	limit = min(limit, maxQueryLimit)
+
+	start := args.Position
+	if args.Anchor != nil {
+		id, _ := parseID(*args.Anchor, emailPrefix)
+		idx := slices.IndexFunc(hits, func(m store.MailboxMessage) bool { return m.ID == id })
+		if idx < 0 {
+			return nil, &methodError{Type: "anchorNotFound"}
+		}
+		start = idx + args.AnchorOffset
+	} else if start < 0 {
+		start += len(hits)
+	}
+	start = max(0, min(start, len(hits)))
+	end := min(start+limit, len(hits))
+
+	ids := make([]string, 0, end-start)
+	for _, m := range hits[start:end] {
+		ids = append(ids, formatID(emailPrefix, m.ID))
+	}
+	result := map[string]any{
+		"accountId":           AccountID,
+		"queryState":          state,
+		"canCalculateChanges": false,
+		"position":            start,
+		"ids":                 ids,
+	}
+	if args.CalculateTotal {
+		result["total"] = len(hits)
+	}
+	if args.Limit != nil && limit != *args.Limit {
+		result["limit"] = limit
+	}
+	return result, nil
+}
+
+// parseFilter decodes a filter, rejecting conditions this server does
+// not implement (such as the thread keyword conditions).
+func parseFilter(raw json.RawMessage) (*filterCondition, *methodError) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	var f filterCondition
+	if err := dec.Decode(&f); err != nil {
+		return nil, &methodError{Type: "unsupportedFilter", Description: err.Error()}
+	}
+	return &f, nil
+}
+
+// compareBy orders messages by the requested comparators, newest first
+// when none are given. Ties fall back to ID so paging is stable.
+func compareBy(sort []comparator) func(a, b store.MailboxMessage) int {
+	if len(sort) == 0 {
+		desc := false
+		sort = []comparator{{Property: "receivedAt", IsAscending: &desc}}
+	}
+	return func(a, b store.MailboxMessage) int {
+		for _, s := range sort {
+			var r int
+			switch s.Property {
+			case "receivedAt":
+				r = a.InternalDate.Compare(b.InternalDate)
+			case "size":
+				r = cmp.Compare(a.Size, b.Size)
+			case "id":
+				r = cmp.Compare(a.ID, b.ID)
+			}
+			if s.IsAscending != nil && !*s.IsAscending {
+				r = -r
+			}
+			if r != 0 {
+				return r
+			}
+		}
+		return cmp.Compare(b.ID, a.ID)
+	}
+}
+
+// compile turns a filter into a predicate. Text, address, subject, body,
+// header, and attachment conditions are answered by the query engine
+// through mailview (one search per condition, so operators compose);
+// mailbox, keyword, date, and size conditions are checked against the
+// listing.
+func (c *call) compile(f *filterCondition) (predicate, *methodError) {
+	if f.Operator != "" {
+		var preds []predicate
+		for i := range f.Conditions {
+			p, mErr := c.compile(&f.Conditions[i])
+			if mErr != nil {
+				return nil, mErr
+			}
+			preds = append(preds, p)
+		}
+		switch f.Operator {
+		case "AND":
+			return func(m store.MailboxMessage) bool {
+				return !slices.ContainsFunc(preds, func(p predicate) bool { return !p(m) })
+			}, nil
+		case "OR":
+			return func(m store.MailboxMessage) bool {
+				return slices.ContainsFunc(preds, func(p predicate) bool { return p(m) })
+			}, nil
+		case "NOT":
+			return func(m store.MailboxMessage) bool {
+				return !slices.ContainsFunc(preds, func(p predicate) bool { return p(m) })
+			}, nil
+		}
+		return nil, &methodError{Type: "unsupportedFilter", Description: "unknown operator " + f.Operator}
+	}
+
+	var preds []predicate
+	if f.InMailbox != nil {
+		in, mErr := c.mailboxSet(*f.InMailbox)
+		if mErr != nil {
+			return nil, mErr
+		}
+		preds = append(preds, func(m store.MailboxMessage) bool { return in[m.ID] })
+	}
+	for _, id := range f.InMailboxOtherThan {
+		in, mErr := c.mailboxSet(id)
+		if mErr != nil {
+			return nil, mErr
+		}
+		preds = append(preds, func(m store.MailboxMessage) bool { return !in[m.ID] })
+	}
+	if f.Before != nil {
+		before := *f.Before
+		preds = append(preds, func(m store.MailboxMessage) bool { return m.InternalDate.Before(before) })
+	}
+	if f.After != nil {
+		after := *f.After
+		preds = append(preds, func(m store.MailboxMessage) bool { return !m.InternalDate.Before(after) })
+	}
+	if f.MinSize != nil {
+		minSize := *f.MinSize
+		preds = append(preds, func(m store.MailboxMessage) bool { return m.Size >= minSize })
+	}
+	if f.MaxSize != nil {
+		maxSize := *f.MaxSize
+		preds = append(preds, func(m store.MailboxMessage) bool { return m.Size < maxSize })
+	}
+	if f.HasKeyword != nil {
+		kw := *f.HasKeyword
+		preds = append(preds, func(m store.MailboxMessage) bool { return hasKeyword(m, kw) })
+	}
+	if f.NotKeyword != nil {
+		kw := *f.NotKeyword
+		preds = append(preds, func(m store.MailboxMessage) bool { return !hasKeyword(m, kw) })
+	}
+
+	var mf mailview.Filter
+	mf.AddHeader("from", f.From)
+	mf.AddHeader("to", f.To)
+	mf.AddHeader("cc", f.Cc)
+	mf.AddHeader("bcc", f.Bcc)
+	mf.AddHeader("subject", f.Subject)
+	mf.AddText(f.Text, f.Body)
+	mf.HasAttachment = f.HasAttachment
+	if len(f.Header) > 0 {
+		value := ""
+		if len(f.Header) > 1 {
+			value = f.Header[1]
+		}
+		if !mf.AddHeader(f.Header[0], value) {
+			return nil, &methodError{Type: "unsupportedFilter", Description: "cannot search header " + f.Header[0]}
+		}
+	}
+	if !mf.IsEmpty() {
+		if c.h.searcher == nil {
+			return nil, &methodError{Type: "unsupportedFilter", Description: "content search is not available"}
+		}
+		ids, err := mailview.Match(c.r.Context(), c.h.searcher, mf.Query(c.sourceIDs))
+		if errors.Is(err, mailview.ErrTooBroad) {
+			return nil, &methodError{Type: "unsupportedFilter", Description: err.Error()}
+		}
+		if err != nil {
+			c.h.logger.Error("jmap: search failed", "error", err)
+			return nil, errServerFail
+		}
+		preds = append(preds, func(m store.MailboxMessage) bool { return ids[m.ID] })
+	}
+
+	return func(m store.MailboxMessage) bool {
+		for _, p := range preds {
+			if !p(m) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// mailboxSet returns the IDs of messages in a mailbox.
+func (c *call) mailboxSet(id string) (map[int64]bool, *methodError) {
+	mb, mErr := c.mailboxes()
+	if mErr != nil {
+		return nil, mErr
+	}
+	name, ok := mailboxName(id)
+	label := ""
+	if ok {
+		label, ok = mb.Resolve(name)
+	}
+	if !ok {
+		// Unknown and label-less parent mailboxes are simply empty.
+		return map[int64]bool{}, nil
+	}
+	msgs, mErr := c.mailboxMessages(label)
+	if mErr != nil {
+		return nil, mErr
+	}
+	set := make(map[int64]bool, len(msgs))
+	for _, m := range msgs {
+		set[m.ID] = true
+	}
+	return set, nil
+}
+
+func hasKeyword(m store.MailboxMessage, kw string) bool {
+	switch strings.ToLower(kw) {
+	case "$seen":
+		return m.Seen
+	case "$flagged":
+		return m.Flagged
+	}
+	// No other keyword is ever set in the archive.
+	return false
+}
+
+func keywords(m store.MailboxMessage) map[string]bool {
+	kw := map[string]bool{}
+	if m.Seen {
+		kw["$seen"] = true
+	}
+	if m.Flagged {
+		kw["$flagged"] = true
+	}
+	return kw
+}
+
+type emailGetArgs struct {
+	getArgs
+	FetchTextBodyValues bool `json:"fetchTextBodyValues"`
+	FetchHTMLBodyValues bool `json:"fetchHTMLBodyValues"`
+	FetchAllBodyValues  bool `json:"fetchAllBodyValues"`
+	MaxBodyValueBytes   int  `json:"maxBodyValueBytes"`
+}
+
+// defaultEmailProperties are returned when Email/get omits properties.
+// Header-derived properties the archive doesn't keep (messageId,
+// replyTo, ...) and per-part attachments are not offered; clients can
+// download the raw message via blobId.
+var defaultEmailProperties = []string{
+	"id", "blobId", "threadId", "mailboxIds", "keywords", "size",
+	"receivedAt", "from", "to", "cc", "bcc", "subject", "sentAt",
+	"hasAttachment", "preview", "textBody", "bodyValues",
+}
+
+// summaryProperties can be answered from the listing without loading
+// the message details.
+var summaryProperties = []string{"id", "blobId", "threadId", "keywords", "size", "receivedAt"}
+
+func (c *call) emailGet(raw json.RawMessage) (any, *methodError) {
+	var args emailGetArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, errInvalidArguments("%v", err)
+	}
+	if args.AccountID != AccountID {
+		return nil, errAccountNotFound
+	}
+	if args.IDs == nil {
+		return nil, &methodError{Type: "requestTooLarge", Description: "ids must be given"}
+	}
+	if len(*args.IDs) > maxObjectsInGet {
+		return nil, &methodError{Type: "requestTooLarge"}
+	}
+	props := args.Properties
+	if props == nil {
+		props = defaultEmailProperties
+	}
+	needDetail := slices.ContainsFunc(props, func(p string) bool { return !slices.Contains(summaryProperties, p) })
+
+	found, notFound := c.lookupEmails(*args.IDs)
+	if found == nil {
+		return nil, errServerFail
+	}
+	list := []map[string]any{}
+	for _, m := range found {
+		obj := map[string]any{
+			"id":         formatID(emailPrefix, m.ID),
+			"blobId":     formatID(blobPrefix, m.ID),
+			"threadId":   formatID(threadPrefix, m.ConversationID),
+			"keywords":   keywords(m),
+			"size":       m.Size,
+			"receivedAt": m.InternalDate.UTC().Format(time.RFC3339),
+		}
+		if needDetail {
+			detail, err := c.h.store.GetMessage(m.ID)
+			if err != nil {
+				c.h.logger.Error("jmap: failed to load message", "id", m.ID, "error", err)
+				return nil, errServerFail
+			}
+			if detail == nil {
+				notFound = append(notFound, formatID(emailPrefix, m.ID))
+				continue
+			}
+			addDetail(obj, detail, &args)
+		}
+		list = append(list, pick(obj, props))
+	}
+	return map[string]any{
+		"accountId": AccountID,
+		"state":     state,
+		"list":      list,
+		"notFound":  notFound,
+	}, nil
+}
+
+// lookupEmails resolves email IDs to visible messages. It returns a nil
+// found slice on a store error.
+func (c *call) lookupEmails(ids []string) (found []store.MailboxMessage, notFound []string) {
+	notFound = []string{}
+	var nums []int64
+	for _, id := range ids {
+		if n, ok := parseID(id, emailPrefix); ok {
+			nums = append(nums, n)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	msgs, err := c.h.store.MailboxMessagesByID(nums)
+	if err != nil {
+		c.h.logger.Error("jmap: failed to load messages", "error", err)
+		return nil, nil
+	}
+	byID := make(map[int64]store.MailboxMessage, len(msgs))
+	for _, m := range msgs {
+		if visible(m, c.sourceIDs) {
+			byID[m.ID] = m
+		}
+	}
+	found = []store.MailboxMessage{}
+	for _, n := range nums {
+		if m, ok := byID[n]; ok {
+			found = append(found, m)
+		} else {
+			notFound = append(notFound, formatID(emailPrefix, n))
+		}
+	}
+	return found, notFound
+}
+
+func addDetail(obj map[string]any, m *store.APIMessage, args *emailGetArgs) {
+	mailboxIDs := map[string]bool{mailboxID(mailview.AllMailName): true}
+	for _, l := range m.Labels {
+		if name := mailview.NameForLabel(l); name != "" {
+			mailboxIDs[mailboxID(name)] = true
+		}
+	}
+	obj["mailboxIds"] = mailboxIDs
+	obj["from"] = addresses([]string{m.From})
+	obj["to"] = addresses(m.To)
+	obj["cc"] = addresses(m.Cc)
+	obj["bcc"] = addresses(m.Bcc)
+	obj["subject"] = m.Subject
+	obj["sentAt"] = m.SentAt.UTC().Format(time.RFC3339)
+	obj["hasAttachment"] = m.HasAttachments
+	obj["preview"] = m.Snippet
+
+	// The archive keeps one decoded body per message: the text part, or
+	// the HTML part when there is no text.
+	bodyType := "text/plain"
+	if strings.HasPrefix(http.DetectContentType([]byte(m.Body)), "text/html") {
+		bodyType = "text/html"
+	}
+	part := map[string]any{"partId": "1", "blobId": nil, "type": bodyType, "size": len(m.Body)}
+	obj["textBody"] = []any{part}
+	obj["htmlBody"] = []any{part}
+
+	values := map[string]any{}
+	if args.FetchAllBodyValues ||
+		(args.FetchTextBodyValues && bodyType == "text/plain") ||
+		(args.FetchHTMLBodyValues && bodyType == "text/html") {
+		value, truncated := m.Body, false
+		if args.MaxBodyValueBytes > 0 && len(value) > args.MaxBodyValueBytes {
+			value = strings.ToValidUTF8(value[:args.MaxBodyValueBytes], "")
+			truncated = true
+		}
+		values["1"] = map[string]any{"value": value, "isEncodingProblem": false, "isTruncated": truncated}
+	}
+	obj["bodyValues"] = values
+}
+
+func addresses(emails []string) []map[string]any {
+	out := []map[string]any{}
+	for _, e := range emails {
+		if e != "" {
+			out = append(out, map[string]any{"name": nil, "email": e})
+		}
+	}
+	return out
+}
+
+func (c *call) threadGet(raw json.RawMessage) (any, *methodError) {
+	var args getArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, errInvalidArguments("%v", err)
+	}
+	if args.AccountID != AccountID {
+		return nil, errAccountNotFound
+	}
+	if args.IDs == nil {
+		return nil, &methodError{Type: "requestTooLarge", Description: "ids must be given"}
+	}
+	if len(*args.IDs) > maxObjectsInGet {
+		return nil, &methodError{Type: "requestTooLarge"}
+	}
+
+	notFound := []string{}
+	var convIDs []int64
+	for _, id := range *args.IDs {
+		if n, ok := parseID(id, threadPrefix); ok {
+			convIDs = append(convIDs, n)
+		} else {
+			notFound = append(notFound, id)
+		}
+	}
+	msgs, err := c.h.store.ConversationMailboxMessages(convIDs, c.sourceIDs)
+	if err != nil {
+		c.h.logger.Error("jmap: failed to load threads", "error", err)
+		return nil, errServerFail
+	}
+	// Threads list emails oldest first.
+	slices.SortStableFunc(msgs, func(a, b store.MailboxMessage) int {
+		return a.InternalDate.Compare(b.InternalDate)
+	})
+	emails := make(map[int64][]string)
+	for _, m := range msgs {
+		emails[m.ConversationID] = append(emails[m.ConversationID], formatID(emailPrefix, m.ID))
+	}
+
+	list := []map[string]any{}
+	for _, conv := range convIDs {
+		ids, ok := emails[conv]
+		if !ok {
+			notFound = append(notFound, formatID(threadPrefix, conv))
+			continue
+		}
+		list = append(list, pick(map[string]any{
+			"id":       formatID(threadPrefix, conv),
+			"emailIds": ids,
+		}, args.Properties))
+	}
+	return map[string]any{
+		"accountId": AccountID,
+		"state":     state,
+		"list":      list,
+		"notFound":  notFound,
+	}, nil
+}
+
+// pick returns obj restricted to props plus "id", which is always
+// returned. A nil props returns every property.
+func pick(obj map[string]any, props []string) map[string]any {
+	if props == nil {
+		return obj
+	}
+	out := map[string]any{"id": obj["id"]}
+	for _, p := range props {
+		if v, ok := obj[p]; ok {
+			out[p] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,266 @@
+// Package labelpush applies label edits made in the vault, by rules or
+// in the TUI, back to Gmail.
+package labelpush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Client is the part of the Gmail API a push needs.
+type Client interface {
+	gmail.AccountReader
+	gmail.LabelModifier
+
+	// ListHistory returns changes since the given history ID.
+	ListHistory(ctx context.Context, startHistoryID uint64, pageToken string) (*gmail.HistoryResponse, error)
+}
+
+var _ Client = (*gmail.Client)(nil)
+
+// Options configures a push.
+type Options struct {
+	// Force pushes changes even when Gmail changed the same label on
+	// the message since the change was queued.
+	Force bool
+
+	// DryRun reports what a push would do without changing Gmail or the
+	// queue.
+	DryRun bool
+}
+
+// Result summarizes a push. Counts are of messages, not label changes.
+type Result struct {
+	Pushed    int // modified in Gmail; their changes are resolved
+	Gone      int // deleted from Gmail; their changes are dropped
+	Conflicts int // changed in Gmail too; their changes stay queued
+	Failed    int // Gmail refused the change; their changes stay queued
+
+	// Conflicted lists the changes held back by conflicts.
+	Conflicted []store.PendingLabelChange
+}
+
+// Pusher pushes the pending label changes of one Gmail account.
+type Pusher struct {
+	store  *store.Store
+	client Client
+	logger *slog.Logger
+}
+
+// New creates a Pusher.
+func New(st *store.Store, client Client) *Pusher {
+	return &Pusher{store: st, client: client, logger: slog.Default()}
+}
+
+// WithLogger sets the logger.
+func (p *Pusher) WithLogger(logger *slog.Logger) *Pusher {
+	p.logger = logger
+	return p
+}
+
+// Push applies the pending label changes of a source to Gmail with one
+// messages.modify call per message.
+//
+// A change conflicts when Gmail's history shows the same label added
+// to or removed from the message after the history ID the vault had
+// synced to when the change was queued. Unless opts.Force is set, a
+// message with a conflicting change is left alone and its changes stay
+// queued; syncing first and pushing again, or discarding them,
+// resolves that. If Gmail no longer has history back to a change's
+// base, every change is treated as conflicting.
+func (p *Pusher) Push(ctx context.Context, sourceID int64, opts Options) (*Result, error) {
+	changes, err := p.store.PendingLabelChanges(sourceID)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	if len(changes) == 0 {
+		return result, nil
+	}
+
+	var remote map[string]map[string]uint64
+	if !opts.Force {
+		if remote, err = p.remoteEdits(ctx, changes); err != nil {
+			return nil, err
+		}
+	}
+	labelIDs, err := p.resolveLabels(ctx, changes, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, msg := range groupByMessage(changes) {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if !opts.Force && conflicts(msg, labelIDs, remote) {
+			result.Conflicts++
+			result.Conflicted = append(result.Conflicted, msg...)
+			continue
+		}
+
+		var add, remove []string
+		for _, c := range msg {
+			if c.Action == store.LabelChangeAdd {
+				add = append(add, labelIDs[c.LabelID])
+			} else {
+				remove = append(remove, labelIDs[c.LabelID])
+			}
+		}
+		if opts.DryRun {
+			result.Pushed++
+			continue
+		}
+
+		msgID := msg[0].SourceMessageID
+		err := p.client.ModifyMessage(ctx, msgID, add, remove)
+		var notFound *gmail.NotFoundError
+		switch {
+		case errors.As(err, &notFound):
+			result.Gone++
+		case err != nil:
+			p.logger.Warn("push label changes", "message", msgID, "error", err)
+			result.Failed++
+			continue
+		default:
+			result.Pushed++
+		}
+		if err := p.store.ResolveLabelChanges(changeIDs(msg)); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// remoteEdits collects, per Gmail message and label ID, the history ID
+// of Gmail's latest edit of that label since the oldest base of changes.
+// It returns nil when that history is gone, so every change conflicts.
+func (p *Pusher) remoteEdits(ctx context.Context, changes []store.PendingLabelChange) (map[string]map[string]uint64, error) {
+	var start uint64
+	for _, c := range changes {
+		base, err := strconv.ParseUint(c.BaseHistoryID, 10, 64)
+		if err != nil {
+			return nil, nil // no usable base: nothing can be checked
+		}
+		if start == 0 || base < start {
+			start = base
+		}
+	}
+
+	edits := make(map[string]map[string]uint64)
+	note := func(changes []gmail.HistoryLabelChange, historyID uint64) {
+		for _, lc := range changes {
+			byLabel := edits[lc.Message.ID]
+			if byLabel == nil {
+				byLabel = make(map[string]uint64)
+				edits[lc.Message.ID] = byLabel
+			}
+			for _, labelID := range lc.LabelIDs {
+				byLabel[labelID] = max(byLabel[labelID], historyID)
+			}
+		}
+	}
+
+	pageToken := ""
+	for {
+		resp, err := p.client.ListHistory(ctx, start, pageToken)
+		if err != nil {
+			var notFound *gmail.NotFoundError
+			if errors.As(err, &notFound) {
+				p.logger.Warn("history expired; treating all label changes as conflicts", "start", start)
+				return nil, nil
+			}
+			return nil, fmt.Errorf("list history: %w", err)
+		}
+		for _, h := range resp.History {
+			note(h.LabelsAdded, h.ID)
+			note(h.LabelsRemoved, h.ID)
+		}
+		if resp.NextPageToken == "" {
+			return edits, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+// conflicts reports whether Gmail edited any of the labels of a
+// message's changes after the change was queued. A nil remote means
+// the history could not be checked.
+func conflicts(msg []store.PendingLabelChange, labelIDs map[int64]string, remote map[string]map[string]uint64) bool {
+	if remote == nil {
+		return true
+	}
+	for _, c := range msg {
+		base, _ := strconv.ParseUint(c.BaseHistoryID, 10, 64)
+		if remote[c.SourceMessageID][labelIDs[c.LabelID]] > base {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLabels maps the vault label of each change to its Gmail label
+// ID. A vault-local label is matched to the Gmail label of the same
+// name, which is created if the account has none (unless dryRun).
+func (p *Pusher) resolveLabels(ctx context.Context, changes []store.PendingLabelChange, dryRun bool) (map[int64]string, error) {
+	ids := make(map[int64]string)
+	var byName map[string]string
+	for _, c := range changes {
+		if _, ok := ids[c.LabelID]; ok {
+			continue
+		}
+		if c.SourceLabelID != "" {
+			ids[c.LabelID] = c.SourceLabelID
+			continue
+		}
+		if byName == nil {
+			labels, err := p.client.ListLabels(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("list labels: %w", err)
+			}
+			byName = make(map[string]string, len(labels))
+			for _, l := range labels {
+				byName[l.Name] = l.ID
+			}
+		}
+		id, ok := byName[c.LabelName]
+		if !ok && !dryRun {
+			label, err := p.client.CreateLabel(ctx, c.LabelName)
+			if err != nil {
+				return nil, fmt.Errorf("create label %q: %w", c.LabelName, err)
+			}
+			id = label.ID
+			byName[c.LabelName] = id
+		}
+		ids[c.LabelID] = id
+	}
+	return ids, nil
+}
+
+// groupByMessage splits changes, which PendingLabelChanges orders by
+// message, into one slice per message.
+func groupByMessage(changes []store.PendingLabelChange) [][]store.PendingLabelChange {
+	var groups [][]store.PendingLabelChange
+	start := 0
+	for i := 1; i <= len(changes); i++ {
+		if i == len(changes) || changes[i].MessageID != changes[start].MessageID {
+			groups = append(groups, changes[start:i])
+			start = i
+		}
+	}
+	return groups
+}
+
+func changeIDs(changes []store.PendingLabelChange) []int64 {
+	ids := make([]int64, len(changes))
+	for i, c := range changes {
+		ids[i] = c.ID
+	}
+	return ids
+}
@@ -0,0 +1,142 @@
+package labelpush
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// pushFixture queues one label change on each of g1..g5, synced to
+// history 100:
+//
+//	g1 removes INBOX      pushes
+//	g2 adds local Travel  pushes, creating Travel in Gmail
+//	g3 removes INBOX      conflicts: Gmail removed INBOX at 105
+//	g4 removes INBOX      gone from Gmail
+//	g5 removes INBOX      Gmail refuses it
+func pushFixture(t *testing.T) (*storetest.Fixture, *gmail.MockAPI) {
+	t.Helper()
+	f := storetest.New(t)
+	testutil.MustNoErr(t, f.Store.UpdateSourceSyncCursor(f.Source.ID, "100"), "UpdateSourceSyncCursor")
+	inbox := f.EnsureLabels(map[string]string{"INBOX": "INBOX"}, "system")["INBOX"]
+
+	mock := gmail.NewMockAPI()
+	var ids []int64
+	for _, id := range []string{"g1", "g2", "g3", "g4", "g5"} {
+		msgID := f.CreateMessage(id)
+		testutil.MustNoErr(t, f.Store.AddMessageLabels(msgID, []int64{inbox}), "AddMessageLabels")
+		ids = append(ids, msgID)
+		if id != "g4" {
+			mock.AddMessage(id, []byte("Subject: hi\r\n\r\nhi"), []string{"INBOX"})
+		}
+	}
+	testutil.MustNoErr(t, f.Store.UnlabelMessages(inbox, []int64{ids[0], ids[2], ids[3], ids[4]}), "UnlabelMessages")
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Travel", []int64{ids[1]}, true), "EditMessagesLabel")
+
+	mock.HistoryID = 110
+	mock.HistoryRecords = []gmail.HistoryRecord{
+		{ID: 95, LabelsRemoved: []gmail.HistoryLabelChange{{Message: gmail.MessageID{ID: "g1"}, LabelIDs: []string{"INBOX"}}}},
+		{ID: 105, LabelsRemoved: []gmail.HistoryLabelChange{{Message: gmail.MessageID{ID: "g3"}, LabelIDs: []string{"INBOX"}}}},
+		{ID: 106, LabelsAdded: []gmail.HistoryLabelChange{{Message: gmail.MessageID{ID: "g1"}, LabelIDs: []string{"STARRED"}}}},
+	}
+	mock.GetMessageError["g5"] = errors.New("backend error")
+	return f, mock
+}
+
+// pendingMessages returns the Gmail IDs of messages with queued changes.
+func pendingMessages(t *testing.T, f *storetest.Fixture) []string {
+	t.Helper()
+	changes, err := f.Store.PendingLabelChanges(f.Source.ID)
+	testutil.MustNoErr(t, err, "PendingLabelChanges")
+	var ids []string
+	for _, c := range changes {
+		ids = append(ids, c.SourceMessageID)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestPush(t *testing.T) {
+	f, mock := pushFixture(t)
+	p := New(f.Store, mock)
+
+	result, err := p.Push(context.Background(), f.Source.ID, Options{})
+	testutil.MustNoErr(t, err, "Push")
+	if result.Pushed != 2 || result.Conflicts != 1 || result.Gone != 1 || result.Failed != 1 {
+		t.Errorf("result = %+v, want 2 pushed, 1 conflict, 1 gone, 1 failed", result)
+	}
+	if len(result.Conflicted) != 1 || result.Conflicted[0].SourceMessageID != "g3" {
+		t.Errorf("Conflicted = %+v, want g3", result.Conflicted)
+	}
+	if !slices.Equal(mock.CreateLabelCalls, []string{"Travel"}) {
+		t.Errorf("CreateLabelCalls = %v, want [Travel]", mock.CreateLabelCalls)
+	}
+	want := []gmail.ModifyCall{
+		{MessageID: "g1", RemoveLabelIDs: []string{"INBOX"}},
+		{MessageID: "g2", AddLabelIDs: []string{"Label_Travel"}},
+	}
+	if len(mock.ModifyCalls) != len(want) {
+		t.Fatalf("ModifyCalls = %+v, want %+v", mock.ModifyCalls, want)
+	}
+	for i, call := range mock.ModifyCalls {
+		if call.MessageID != want[i].MessageID ||
+			!slices.Equal(call.AddLabelIDs, want[i].AddLabelIDs) ||
+			!slices.Equal(call.RemoveLabelIDs, want[i].RemoveLabelIDs) {
+			t.Errorf("ModifyCalls[%d] = %+v, want %+v", i, call, want[i])
+		}
+	}
+	if got := pendingMessages(t, f); !slices.Equal(got, []string{"g3", "g5"}) {
+		t.Errorf("pending after push = %v, want the conflict and the failure", got)
+	}
+
+	// Forcing pushes the conflict; the failure stays queued.
+	result, err = p.Push(context.Background(), f.Source.ID, Options{Force: true})
+	testutil.MustNoErr(t, err, "Push --force")
+	if result.Pushed != 1 || result.Conflicts != 0 || result.Failed != 1 {
+		t.Errorf("forced result = %+v, want 1 pushed and 1 failed", result)
+	}
+	if got := pendingMessages(t, f); !slices.Equal(got, []string{"g5"}) {
+		t.Errorf("pending after forced push = %v, want [g5]", got)
+	}
+}
+
+func TestPush_DryRun(t *testing.T) {
+	f, mock := pushFixture(t)
+	result, err := New(f.Store, mock).Push(context.Background(), f.Source.ID, Options{DryRun: true})
+	testutil.MustNoErr(t, err, "Push")
+	if result.Pushed != 4 || result.Conflicts != 1 {
+		t.Errorf("result = %+v, want 4 to push and 1 conflict", result)
+	}
+	if len(mock.ModifyCalls) != 0 || len(mock.CreateLabelCalls) != 0 {
+		t.Errorf("dry run changed Gmail: modify %v, create %v", mock.ModifyCalls, mock.CreateLabelCalls)
+	}
+	if got := pendingMessages(t, f); len(got) != 5 {
+		t.Errorf("pending after dry run = %v, want all 5", got)
+	}
+}
+
+func TestPush_HistoryExpired(t *testing.T) {
+	f, mock := pushFixture(t)
+	mock.HistoryError = &gmail.NotFoundError{Path: "/history"}
+
+	result, err := New(f.Store, mock).Push(context.Background(), f.Source.ID, Options{})
+	testutil.MustNoErr(t, err, "Push")
+	if result.Pushed != 0 || result.Conflicts != 5 || len(mock.ModifyCalls) != 0 {
+		t.Errorf("result = %+v with %d modify calls, want every message held back", result, len(mock.ModifyCalls))
+	}
+}
+
+func TestPush_Nothing(t *testing.T) {
+	f := storetest.New(t)
+	mock := gmail.NewMockAPI()
+	result, err := New(f.Store, mock).Push(context.Background(), f.Source.ID, Options{})
+	testutil.MustNoErr(t, err, "Push")
+	if result.Pushed+result.Conflicts+result.Gone+result.Failed != 0 || len(mock.HistoryCalls) != 0 {
+		t.Errorf("result = %+v, history calls %v; want nothing done", result, mock.HistoryCalls)
+	}
+}
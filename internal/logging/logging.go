@@ -10,7 +10,8 @@
 //     msgvault-YYYY-MM-DD.log (UTC).
 //  2. On-disk logs are structured JSON so they're greppable with
 //     jq and mechanically parseable. Interactive stderr output
-//     stays human-readable text.
+//     stays human-readable text unless Format is "json", for
+//     daemons whose stderr is collected by a log shipper.
 //  3. Every run gets a run_id attribute, attached to every log
 //     line, so you can grep one invocation out of a shared file
 //     even when two commands run in parallel.
@@ -37,6 +38,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -65,14 +67,20 @@ type Options struct {
 	// "info".
 	LevelString string
 
-	// MaxFileBytes caps a single log file. When a file exceeds
-	// this size at open time, it is rotated to a numbered
-	// suffix (.1, .2, ...) before the current run appends. Zero
-	// means 50 MiB.
+	// Format selects the stderr encoding: "text" (the default
+	// when empty) or "json". The log file is always JSON.
+	Format string
+
+	// MaxFileBytes caps a single log file. When a write would
+	// push the file past this size (or the file is already over
+	// it at open time), it is rotated to a numbered suffix
+	// (.1, .2, ...) and a fresh file is started. This applies to
+	// both daily files and an explicit FilePath. Zero means
+	// 50 MiB.
 	MaxFileBytes int64
 
 	// KeepRotated caps how many rotated siblings are kept per
-	// day. Older ones are deleted. Zero means 5.
+	// file. Older ones are deleted. Zero means 5.
 	KeepRotated int
 
 	// Stderr is the writer used for interactive output. Nil
@@ -174,24 +182,31 @@ func BuildHandler(opts Options) (*Result, error) {
 
 	res := &Result{Level: level, RunID: newRunID()}
 
-	// Always build the stderr text handler.
-	stderrH := slog.NewTextHandler(stderr, &slog.HandlerOptions{
-		Level: level,
-	})
+	// Always build the stderr handler.
+	var stderrH slog.Handler
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", "text":
+		stderrH = slog.NewTextHandler(stderr, &slog.HandlerOptions{Level: level})
+	case "json":
+		stderrH = slog.NewJSONHandler(stderr, &slog.HandlerOptions{Level: level})
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", opts.Format)
+	}
 	handlers := []slog.Handler{stderrH}
 
 	// Best-effort file handler.
 	if !opts.FileDisabled && (opts.LogsDir != "" || opts.FilePath != "") {
 		var path string
-		var f *os.File
+		var f *rotatingFile
 		var err error
 		if opts.FilePath != "" {
-			// Explicit path: use it directly, no rotation.
+			// Explicit path: use it directly, skipping the daily
+			// name but keeping size-based rotation.
 			if mkErr := os.MkdirAll(filepath.Dir(opts.FilePath), 0o755); mkErr != nil {
 				err = fmt.Errorf("mkdir for log file: %w", mkErr)
 			} else {
 				path = opts.FilePath
-				f, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+				f, err = openRotatingFile(path, opts.MaxFileBytes, opts.KeepRotated)
 			}
 		} else {
 			path, f, err = openDailyLogFile(
@@ -257,13 +272,13 @@ func newRunID() string {
 }
 
 // openDailyLogFile opens (or creates) <dir>/msgvault-YYYY-MM-DD.log
-// in append mode. If the file already exists and exceeds maxBytes,
-// it is rotated out to a numbered sibling (.1, .2, ...) before
-// being reopened fresh. Older siblings beyond keepRotated are
-// deleted.
+// as a rotating file. If the file already exists and exceeds
+// maxBytes, it is rotated out to a numbered sibling (.1, .2, ...)
+// before being reopened fresh. Older siblings beyond keepRotated
+// are deleted.
 func openDailyLogFile(
 	dir string, now time.Time, maxBytes int64, keepRotated int,
-) (string, *os.File, error) {
+) (string, *rotatingFile, error) {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return "", nil, fmt.Errorf("mkdir logs dir: %w", err)
 	}
@@ -271,22 +286,93 @@ func openDailyLogFile(
 		"msgvault-%s.log", now.UTC().Format("2006-01-02"),
 	)
 	path := filepath.Join(dir, name)
+	f, err := openRotatingFile(path, maxBytes, keepRotated)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, f, nil
+}
+
+// rotatingFile is an append-only log file that rotates itself once
+// a write would grow it past maxBytes, so a long-running daemon's
+// log stays bounded rather than only being checked at startup.
+type rotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	size     int64
+	maxBytes int64
+	keep     int
+}
 
+// openRotatingFile opens path for appending, rotating it first if
+// it is already at or over maxBytes.
+func openRotatingFile(path string, maxBytes int64, keep int) (*rotatingFile, error) {
 	if fi, err := os.Stat(path); err == nil && fi.Size() >= maxBytes {
-		if rotErr := rotate(path, keepRotated); rotErr != nil {
-			return "", nil, fmt.Errorf("rotate: %w", rotErr)
+		if rotErr := rotate(path, keep); rotErr != nil {
+			return nil, fmt.Errorf("rotate: %w", rotErr)
 		}
 	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
-		return "", nil, fmt.Errorf("stat log file: %w", err)
+		return nil, fmt.Errorf("stat log file: %w", err)
 	}
+	r := &rotatingFile{path: path, maxBytes: maxBytes, keep: keep}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
 
+func (r *rotatingFile) open() error {
 	f, err := os.OpenFile(
-		path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600,
+		r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600,
 	)
 	if err != nil {
-		return "", nil, fmt.Errorf("open log file: %w", err)
+		return fmt.Errorf("open log file: %w", err)
 	}
-	return path, f, nil
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	r.f, r.size = f, fi.Size()
+	return nil
+}
+
+// Write appends p, rotating first when p would overflow the file.
+// A failed rotation keeps appending to the current file: an
+// oversized log beats a lost record.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxBytes {
+		_ = r.f.Close()
+		rotErr := rotate(r.path, r.keep)
+		if err := r.open(); err != nil {
+			r.f = nil
+			return 0, err
+		}
+		if rotErr != nil {
+			r.size = 0 // don't retry on every write
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the underlying file. Safe to call more than once.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
 }
 
 // rotate moves path -> path.1, path.1 -> path.2, etc., up to
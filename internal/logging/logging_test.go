@@ -204,3 +204,90 @@ func TestMultiHandler_FansOutAndFiltersByLevel(t *testing.T) {
 		t.Errorf("json handler lost run_id: %q", jsonBuf.String())
 	}
 }
+
+func TestBuildHandler_Format(t *testing.T) {
+	tests := []struct {
+		format   string
+		wantJSON bool
+		wantErr  bool
+	}{
+		{"", false, false},
+		{"text", false, false},
+		{"JSON", true, false},
+		{"yaml", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var stderr bytes.Buffer
+			res, err := BuildHandler(Options{FileDisabled: true, Format: tt.format, Stderr: &stderr})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error for unknown format")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildHandler: %v", err)
+			}
+			defer res.Close()
+			slog.New(res.Handler).Info("hello", "account", "alice@example.com")
+
+			var rec map[string]any
+			isJSON := json.Unmarshal(bytes.TrimSpace(stderr.Bytes()), &rec) == nil
+			if isJSON != tt.wantJSON {
+				t.Fatalf("stderr JSON = %v, want %v: %q", isJSON, tt.wantJSON, stderr.String())
+			}
+			if isJSON && (rec["run_id"] != res.RunID || rec["account"] != "alice@example.com") {
+				t.Errorf("record = %v", rec)
+			}
+		})
+	}
+}
+
+func TestBuildHandler_RotatesExplicitFileWhileRunning(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "daemon.log")
+
+	res, err := BuildHandler(Options{
+		FilePath:     path,
+		MaxFileBytes: 300,
+		KeepRotated:  2,
+		Stderr:       &bytes.Buffer{},
+	})
+	if err != nil {
+		t.Fatalf("BuildHandler: %v", err)
+	}
+	defer res.Close()
+
+	logger := slog.New(res.FileHandler)
+	for i := 0; i < 20; i++ {
+		logger.Info("a line long enough to fill the file quickly", "i", i)
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s: %v", p, err)
+		}
+		if fi.Size() > 300 {
+			t.Errorf("%s size = %d, want <= 300", p, fi.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("rotated files beyond KeepRotated should be deleted, stat err = %v", err)
+	}
+
+	// The newest record is in the current file, intact.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &rec); err != nil {
+		t.Fatalf("last line is not JSON: %v", err)
+	}
+	if rec["i"] != float64(19) {
+		t.Errorf("last record i = %v, want 19", rec["i"])
+	}
+}
@@ -0,0 +1,143 @@
+package mailauth
+
+import "strings"
+
+// AuthResults is a parsed Authentication-Results header (RFC 8601).
+type AuthResults struct {
+	AuthServID string
+	Results    []MethodResult
+}
+
+// MethodResult is one method's result, such as dkim=pass, with its
+// properties keyed as written, e.g. "header.d" or "smtp.mailfrom".
+type MethodResult struct {
+	Method string
+	Result string
+	Props  map[string]string
+}
+
+// ParseAuthResults parses an unfolded Authentication-Results value.
+// Comments are dropped and anything malformed is skipped, so a header
+// that cannot be read yields no results rather than an error.
+func ParseAuthResults(value string) AuthResults {
+	parts := splitOutsideQuotes(stripComments(value), ';')
+	if len(parts) == 0 {
+		return AuthResults{}
+	}
+	// The authserv-id may be followed by a version number.
+	id, _, _ := strings.Cut(strings.TrimSpace(parts[0]), " ")
+	ar := AuthResults{AuthServID: id}
+
+	for _, part := range parts[1:] {
+		words := splitOutsideQuotes(normalizeEquals(strings.TrimSpace(part)), ' ')
+		if len(words) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(words[0], "=")
+		if !ok {
+			continue
+		}
+		method, _, _ = strings.Cut(method, "/")
+		mr := MethodResult{
+			Method: strings.ToLower(method),
+			Result: strings.ToLower(result),
+			Props:  make(map[string]string),
+		}
+		for _, w := range words[1:] {
+			if k, v, ok := strings.Cut(w, "="); ok {
+				mr.Props[strings.ToLower(k)] = strings.Trim(v, `"`)
+			}
+		}
+		ar.Results = append(ar.Results, mr)
+	}
+	return ar
+}
+
+// Summary returns the result recorded for method: "pass" if any
+// result for it passed, otherwise the first one, or "" if the method
+// is absent.
+func (ar AuthResults) Summary(method string) string {
+	first := ""
+	for _, r := range ar.Results {
+		if r.Method != method {
+			continue
+		}
+		if r.Result == Pass {
+			return Pass
+		}
+		if first == "" {
+			first = r.Result
+		}
+	}
+	return first
+}
+
+// stripComments removes parenthesized comments, which may nest, from
+// a structured header value.
+func stripComments(s string) string {
+	var sb strings.Builder
+	depth := 0
+	inQuote := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			if depth == 0 {
+				sb.WriteByte(c)
+				sb.WriteByte(s[i+1])
+			}
+			i++
+		case c == '"' && depth == 0:
+			inQuote = !inQuote
+			sb.WriteByte(c)
+		case c == '(' && !inQuote:
+			depth++
+		case c == ')' && !inQuote && depth > 0:
+			depth--
+			if depth == 0 {
+				sb.WriteByte(' ')
+			}
+		case depth == 0:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// normalizeEquals removes whitespace around "=" so that "dkim = pass"
+// reads as one word.
+func normalizeEquals(s string) string {
+	for _, pair := range [][2]string{{" =", "="}, {"= ", "="}, {"\t=", "="}, {"=\t", "="}} {
+		for strings.Contains(s, pair[0]) {
+			s = strings.ReplaceAll(s, pair[0], pair[1])
+		}
+	}
+	return s
+}
+
+// splitOutsideQuotes splits s at sep, ignoring separators inside
+// double quotes. With sep ' ', runs of whitespace split once and
+// empty parts are dropped.
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	inQuote := false
+	add := func(p string) {
+		if sep != ' ' || strings.TrimSpace(p) != "" {
+			parts = append(parts, strings.TrimSpace(p))
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == sep, sep == ' ' && c == '\t':
+			add(s[start:i])
+			start = i + 1
+		}
+	}
+	add(s[start:])
+	return parts
+}
@@ -0,0 +1,91 @@
+package mailauth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseAuthResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantID    string
+		wantDKIM  string
+		wantSPF   string
+		wantDMARC string
+	}{
+		{
+			name: "gmail",
+			value: `mx.google.com; dkim=pass header.i=@example.com header.s=sel header.b=abc123;` +
+				` spf=pass (google.com: domain of alice@example.com designates 192.0.2.1 as permitted sender)` +
+				` smtp.mailfrom=alice@example.com; dmarc=pass (p=REJECT sp=REJECT dis=NONE) header.from=example.com`,
+			wantID:    "mx.google.com",
+			wantDKIM:  Pass,
+			wantSPF:   Pass,
+			wantDMARC: Pass,
+		},
+		{
+			name:     "version and spacing",
+			value:    `mail.example.org 1; spf = softfail smtp.mailfrom=bob@example.com; dkim=fail reason="bad; sig"; dkim=pass header.d=example.com`,
+			wantID:   "mail.example.org",
+			wantDKIM: Pass,
+			wantSPF:  "softfail",
+		},
+		{
+			name:   "none",
+			value:  "mx.example.net; none",
+			wantID: "mx.example.net",
+		},
+		{
+			name:     "nested comment",
+			value:    "mx.example.net (a (nested) comment); dkim=neutral (no key (yet))",
+			wantID:   "mx.example.net",
+			wantDKIM: "neutral",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ar := ParseAuthResults(tt.value)
+			if ar.AuthServID != tt.wantID {
+				t.Errorf("AuthServID = %q, want %q", ar.AuthServID, tt.wantID)
+			}
+			for method, want := range map[string]string{"dkim": tt.wantDKIM, "spf": tt.wantSPF, "dmarc": tt.wantDMARC} {
+				if got := ar.Summary(method); got != want {
+					t.Errorf("Summary(%q) = %q, want %q", method, got, want)
+				}
+			}
+		})
+	}
+
+	ar := ParseAuthResults(`mx.google.com; spf=pass smtp.mailfrom="alice@example.com"`)
+	if got := ar.Results[0].Props["smtp.mailfrom"]; got != "alice@example.com" {
+		t.Errorf("smtp.mailfrom = %q", got)
+	}
+}
+
+func TestCheck_AuthenticationResults(t *testing.T) {
+	msg := "Authentication-Results: mx.example.net;\r\n" +
+		"\tdkim=pass header.d=example.com; spf=fail smtp.mailfrom=example.com\r\n" +
+		"Received-SPF: pass (mx.example.net: domain of example.com) client-ip=192.0.2.1\r\n" +
+		"Authentication-Results: forged.example.org; dmarc=pass\r\n" +
+		"From: alice@example.com\r\n" +
+		"\r\n" +
+		"Hi\r\n"
+	got := Check(context.Background(), []byte(msg), nil)
+	want := Result{
+		AuthServID:   "mx.example.net",
+		ReportedDKIM: Pass,
+		ReportedSPF:  Fail,
+		AuthResults:  "mx.example.net;\tdkim=pass header.d=example.com; spf=fail smtp.mailfrom=example.com",
+	}
+	if got != want {
+		t.Errorf("Check = %+v\nwant %+v", got, want)
+	}
+
+	// Without SPF in Authentication-Results, Received-SPF is used.
+	msg = "Received-SPF: Pass (mx.example.net: domain of example.com) client-ip=192.0.2.1\r\n" +
+		"From: alice@example.com\r\n\r\nHi\r\n"
+	if got := Check(context.Background(), []byte(msg), nil); got.ReportedSPF != Pass {
+		t.Errorf("ReportedSPF from Received-SPF = %q", got.ReportedSPF)
+	}
+}
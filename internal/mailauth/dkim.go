@@ -0,0 +1,423 @@
+package mailauth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSignatures caps how many DKIM-Signature fields of one message are
+// checked, so a message stuffed with signatures cannot cost a key
+// lookup each.
+const maxSignatures = 5
+
+// lookupTimeout bounds each DNS query for a signing key.
+const lookupTimeout = 10 * time.Second
+
+// Resolver looks up DNS TXT records. *net.Resolver satisfies it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Verifier verifies DKIM signatures (RFC 6376), fetching signing keys
+// over DNS. Keys are cached for the Verifier's lifetime, since a sync
+// or import sees the same few selectors over and over. It is safe for
+// concurrent use.
+//
+// Signature expiry (x=) is not enforced: an archive verifies mail long
+// after delivery, and what it asks is whether the message is what the
+// signer signed. A key the signer has since rotated out of DNS makes
+// old mail a permerror, which is as much as can be said for it.
+type Verifier struct {
+	resolver Resolver
+
+	mu   sync.Mutex
+	keys map[string]keyLookup
+}
+
+type keyLookup struct {
+	key crypto.PublicKey
+	err error
+}
+
+// NewVerifier returns a Verifier using r, or the system resolver when
+// r is nil.
+func NewVerifier(r Resolver) *Verifier {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &Verifier{resolver: r, keys: make(map[string]keyLookup)}
+}
+
+// dkimOutcome is the verification result for a message or a single
+// signature.
+type dkimOutcome struct {
+	Result string
+	Domain string
+	Reason string
+}
+
+// verifyMessage checks each DKIM signature of a message: it passes
+// when any signature does.
+func (v *Verifier) verifyMessage(ctx context.Context, fields []headerField, body string) dkimOutcome {
+	var first dkimOutcome
+	n := 0
+	for i, f := range fields {
+		if !f.is("DKIM-Signature") {
+			continue
+		}
+		if n++; n > maxSignatures {
+			break
+		}
+		out := v.verifySignature(ctx, fields, i, body)
+		if out.Result == Pass {
+			return out
+		}
+		if first.Result == "" {
+			first = out
+		}
+	}
+	if first.Result == "" {
+		return dkimOutcome{Result: None}
+	}
+	return first
+}
+
+// signature is a parsed DKIM-Signature field.
+type signature struct {
+	algorithm   string
+	domain      string
+	selector    string
+	headers     []string
+	bodyHash    []byte
+	sig         []byte
+	headerCanon string
+	bodyCanon   string
+	length      int64 // l=, or -1 for the whole body
+}
+
+// errPerm and errTemp classify why a signature could not be checked.
+var (
+	errPerm = errors.New(PermError)
+	errTemp = errors.New(TempError)
+)
+
+func (v *Verifier) verifySignature(ctx context.Context, fields []headerField, idx int, body string) dkimOutcome {
+	sig, err := parseSignature(fields[idx].value())
+	if err != nil {
+		return outcome(sig, err)
+	}
+
+	canonBody := canonicalBody(body, sig.bodyCanon)
+	if sig.length >= 0 {
+		if sig.length > int64(len(canonBody)) {
+			return dkimOutcome{Result: Fail, Domain: sig.domain, Reason: "body is shorter than l="}
+		}
+		canonBody = canonBody[:sig.length]
+	}
+	bh := sha256.Sum256([]byte(canonBody))
+	if !bytes.Equal(bh[:], sig.bodyHash) {
+		return dkimOutcome{Result: Fail, Domain: sig.domain, Reason: "body hash did not verify"}
+	}
+
+	key, err := v.lookupKey(ctx, sig.selector, sig.domain)
+	if err != nil {
+		return outcome(sig, err)
+	}
+
+	h := sha256.New()
+	used := make(map[int]bool)
+	for _, name := range sig.headers {
+		// Each listed name takes the bottom-most instance not already
+		// taken; a name with none left contributes nothing.
+		for i := len(fields) - 1; i >= 0; i-- {
+			if !used[i] && fields[i].is(name) {
+				used[i] = true
+				h.Write([]byte(canonicalHeader(fields[i].raw, sig.headerCanon) + "\r\n"))
+				break
+			}
+		}
+	}
+	h.Write([]byte(canonicalHeader(stripSignatureValue(fields[idx].raw), sig.headerCanon)))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(sig.algorithm, "rsa-") {
+			return dkimOutcome{Result: PermError, Domain: sig.domain, Reason: "key type does not match a="}
+		}
+		err = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig.sig)
+	case ed25519.PublicKey:
+		if !strings.HasPrefix(sig.algorithm, "ed25519-") {
+			return dkimOutcome{Result: PermError, Domain: sig.domain, Reason: "key type does not match a="}
+		}
+		if !ed25519.Verify(k, digest, sig.sig) {
+			err = errors.New("ed25519 verification failed")
+		}
+	}
+	if err != nil {
+		return dkimOutcome{Result: Fail, Domain: sig.domain, Reason: "signature did not verify"}
+	}
+	return dkimOutcome{Result: Pass, Domain: sig.domain}
+}
+
+// outcome turns an error wrapping errPerm or errTemp into a result.
+func outcome(sig *signature, err error) dkimOutcome {
+	out := dkimOutcome{Result: PermError}
+	if errors.Is(err, errTemp) {
+		out.Result = TempError
+	}
+	out.Reason = strings.TrimPrefix(err.Error(), out.Result+": ")
+	if sig != nil {
+		out.Domain = sig.domain
+	}
+	return out
+}
+
+func parseSignature(value string) (*signature, error) {
+	tags, err := parseTags(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range []string{"v", "a", "b", "bh", "d", "h", "s"} {
+		if tags[t] == "" {
+			return nil, fmt.Errorf("%w: missing %s= tag", errPerm, t)
+		}
+	}
+	sig := &signature{
+		algorithm: strings.ToLower(tags["a"]),
+		domain:    strings.ToLower(tags["d"]),
+		selector:  tags["s"],
+		length:    -1,
+	}
+	if tags["v"] != "1" {
+		return sig, fmt.Errorf("%w: unsupported version v=%s", errPerm, tags["v"])
+	}
+	switch sig.algorithm {
+	case "rsa-sha256", "ed25519-sha256":
+	case "rsa-sha1":
+		// RFC 8301 forbids treating rsa-sha1 signatures as valid.
+		return sig, fmt.Errorf("%w: rsa-sha1 signatures are no longer accepted", errPerm)
+	default:
+		return sig, fmt.Errorf("%w: unsupported algorithm a=%s", errPerm, sig.algorithm)
+	}
+
+	if sig.bodyHash, err = decodeBase64(tags["bh"]); err != nil {
+		return sig, fmt.Errorf("%w: malformed bh= tag", errPerm)
+	}
+	if sig.sig, err = decodeBase64(tags["b"]); err != nil {
+		return sig, fmt.Errorf("%w: malformed b= tag", errPerm)
+	}
+
+	hasFrom := false
+	for _, name := range strings.Split(tags["h"], ":") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		sig.headers = append(sig.headers, name)
+		hasFrom = hasFrom || strings.EqualFold(name, "From")
+	}
+	if !hasFrom {
+		return sig, fmt.Errorf("%w: From is not signed", errPerm)
+	}
+
+	sig.headerCanon, sig.bodyCanon = "simple", "simple"
+	if c := strings.ToLower(tags["c"]); c != "" {
+		hc, bc, hasBody := strings.Cut(c, "/")
+		sig.headerCanon = hc
+		if hasBody {
+			sig.bodyCanon = bc
+		}
+	}
+	for _, c := range []string{sig.headerCanon, sig.bodyCanon} {
+		if c != "simple" && c != "relaxed" {
+			return sig, fmt.Errorf("%w: unsupported canonicalization c=%s", errPerm, tags["c"])
+		}
+	}
+
+	if l := tags["l"]; l != "" {
+		if sig.length, err = strconv.ParseInt(l, 10, 64); err != nil || sig.length < 0 {
+			return sig, fmt.Errorf("%w: malformed l= tag", errPerm)
+		}
+	}
+	if i := tags["i"]; i != "" {
+		_, iDomain, _ := strings.Cut(i, "@")
+		iDomain = strings.ToLower(iDomain)
+		if iDomain != sig.domain && !strings.HasSuffix(iDomain, "."+sig.domain) {
+			return sig, fmt.Errorf("%w: i= is not within d=", errPerm)
+		}
+	}
+	return sig, nil
+}
+
+// lookupKey fetches and parses the public key for selector and domain,
+// caching the result, failures included.
+func (v *Verifier) lookupKey(ctx context.Context, selector, domain string) (crypto.PublicKey, error) {
+	name := selector + "._domainkey." + domain
+	v.mu.Lock()
+	cached, ok := v.keys[name]
+	v.mu.Unlock()
+	if ok {
+		return cached.key, cached.err
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	records, err := v.resolver.LookupTXT(lookupCtx, name)
+
+	var res keyLookup
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		res.err = fmt.Errorf("%w: no key at %s", errPerm, name)
+	case err != nil:
+		res.err = fmt.Errorf("%w: looking up %s: %v", errTemp, name, err)
+	case len(records) == 0:
+		res.err = fmt.Errorf("%w: no key at %s", errPerm, name)
+	default:
+		res.key, res.err = parseKeyRecord(records[0])
+	}
+
+	// A cancelled caller says nothing about the key; don't cache it.
+	if ctx.Err() == nil {
+		v.mu.Lock()
+		v.keys[name] = res
+		v.mu.Unlock()
+	}
+	return res.key, res.err
+}
+
+// parseKeyRecord parses a DKIM key record (RFC 6376 section 3.6.1).
+func parseKeyRecord(record string) (crypto.PublicKey, error) {
+	tags, err := parseTags(record)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := tags["v"]; ok && v != "DKIM1" {
+		return nil, fmt.Errorf("%w: unsupported key record version %s", errPerm, v)
+	}
+	if h := tags["h"]; h != "" && !strings.Contains(strings.ToLower(h), "sha256") {
+		return nil, fmt.Errorf("%w: key does not allow sha256", errPerm)
+	}
+	if tags["p"] == "" {
+		return nil, fmt.Errorf("%w: key has been revoked", errPerm)
+	}
+	der, err := decodeBase64(tags["p"])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed key", errPerm)
+	}
+
+	switch k := strings.ToLower(tags["k"]); k {
+	case "", "rsa":
+		pub, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			// Some publishers use the bare PKCS #1 form.
+			if rsaKey, err2 := x509.ParsePKCS1PublicKey(der); err2 == nil {
+				return rsaKey, nil
+			}
+			return nil, fmt.Errorf("%w: malformed RSA key", errPerm)
+		}
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("%w: key is not RSA", errPerm)
+		}
+		return rsaKey, nil
+	case "ed25519":
+		if len(der) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: malformed Ed25519 key", errPerm)
+		}
+		return ed25519.PublicKey(der), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type k=%s", errPerm, k)
+	}
+}
+
+// parseTags parses a DKIM tag=value list. Whitespace around names and
+// values is dropped.
+func parseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(unfold(s), ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: malformed tag %q", errPerm, part)
+		}
+		name = strings.TrimSpace(name)
+		if _, dup := tags[name]; dup {
+			return nil, fmt.Errorf("%w: duplicate %s= tag", errPerm, name)
+		}
+		tags[name] = strings.TrimSpace(value)
+	}
+	return tags, nil
+}
+
+// decodeBase64 decodes base64 that may contain folding whitespace.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+			return -1
+		}
+		return r
+	}, s)
+	return base64.StdEncoding.DecodeString(s)
+}
+
+// reSignatureValue matches the b= tag of a DKIM-Signature, whose value
+// is left out when the signature hashes its own field.
+var reSignatureValue = regexp.MustCompile(`(^|;)(\s*b\s*=)[^;]*`)
+
+func stripSignatureValue(raw string) string {
+	name, value, _ := strings.Cut(raw, ":")
+	return name + ":" + reSignatureValue.ReplaceAllString(value, "${1}${2}")
+}
+
+// canonicalHeader canonicalizes one header field (RFC 6376 section
+// 3.4.1 and 3.4.2), without the trailing CRLF.
+func canonicalHeader(raw, canon string) string {
+	if canon == "simple" {
+		return raw
+	}
+	name, value, _ := strings.Cut(raw, ":")
+	name = strings.ToLower(strings.TrimRight(name, " \t"))
+	value = strings.Trim(collapseWSP(unfold(value)), " ")
+	return name + ":" + value
+}
+
+// canonicalBody canonicalizes a CRLF body (RFC 6376 sections 3.4.3
+// and 3.4.4).
+func canonicalBody(body, canon string) string {
+	if canon == "relaxed" {
+		lines := strings.Split(body, "\r\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight(collapseWSP(l), " ")
+		}
+		for len(lines) > 0 && lines[len(lines)-1] == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) == 0 {
+			return ""
+		}
+		return strings.Join(lines, "\r\n") + "\r\n"
+	}
+	for strings.HasSuffix(body, "\r\n") {
+		body = body[:len(body)-2]
+	}
+	return body + "\r\n"
+}
@@ -0,0 +1,265 @@
+package mailauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeResolver serves TXT records from a map and counts lookups.
+type fakeResolver struct {
+	records map[string]string
+	err     error
+	lookups int
+}
+
+func (r *fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	r.lookups++
+	if r.err != nil {
+		return nil, r.err
+	}
+	rec, ok := r.records[name]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return []string{rec}, nil
+}
+
+// sign adds a DKIM-Signature for domain example.com, selector sel, to
+// a CRLF message.
+func sign(t *testing.T, msg string, key crypto.Signer, canon string) string {
+	t.Helper()
+	fields, body := splitMessage([]byte(msg))
+	hc, bc, _ := strings.Cut(canon, "/")
+	bh := sha256.Sum256([]byte(canonicalBody(body, bc)))
+
+	algo := "rsa-sha256"
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		algo = "ed25519-sha256"
+	}
+	sigField := "DKIM-Signature: v=1; a=" + algo + "; c=" + canon + "; d=example.com; s=sel;\r\n" +
+		"\th=From:Subject; bh=" + base64.StdEncoding.EncodeToString(bh[:]) + "; b="
+
+	h := sha256.New()
+	for _, name := range []string{"From", "Subject"} {
+		for i := len(fields) - 1; i >= 0; i-- {
+			if fields[i].is(name) {
+				h.Write([]byte(canonicalHeader(fields[i].raw, hc) + "\r\n"))
+				break
+			}
+		}
+	}
+	h.Write([]byte(canonicalHeader(sigField, hc)))
+	digest := h.Sum(nil)
+
+	var sig []byte
+	var err error
+	if _, ok := key.(ed25519.PrivateKey); ok {
+		sig, err = key.Sign(rand.Reader, digest, crypto.Hash(0))
+	} else {
+		sig, err = key.Sign(rand.Reader, digest, crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sigField + base64.StdEncoding.EncodeToString(sig) + "\r\n" + msg
+}
+
+func keyRecord(t *testing.T, pub crypto.PublicKey) string {
+	t.Helper()
+	if k, ok := pub.(ed25519.PublicKey); ok {
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(k)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+}
+
+const testMessage = "From: Alice <alice@example.com>\r\n" +
+	"To: bob@example.com\r\n" +
+	"Subject:  Quarterly   report\r\n" +
+	"\r\n" +
+	"Numbers attached.  \r\n" +
+	"\r\n\r\n"
+
+func TestVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaRecord := keyRecord(t, rsaKey.Public())
+	edRecord := keyRecord(t, edKey.Public())
+
+	tests := []struct {
+		name       string
+		msg        string
+		record     string
+		dnsErr     error
+		want       string
+		wantReason string
+	}{
+		{
+			name:   "rsa relaxed",
+			msg:    sign(t, testMessage, rsaKey, "relaxed/relaxed"),
+			record: rsaRecord,
+			want:   Pass,
+		},
+		{
+			name:   "ed25519 simple",
+			msg:    sign(t, testMessage, edKey, "simple/simple"),
+			record: edRecord,
+			want:   Pass,
+		},
+		{
+			name:   "stored with bare LFs",
+			msg:    strings.ReplaceAll(sign(t, testMessage, rsaKey, "relaxed/simple"), "\r\n", "\n"),
+			record: rsaRecord,
+			want:   Pass,
+		},
+		{
+			name:       "body altered",
+			msg:        strings.Replace(sign(t, testMessage, rsaKey, "relaxed/relaxed"), "Numbers", "Figures", 1),
+			record:     rsaRecord,
+			want:       Fail,
+			wantReason: "body hash did not verify",
+		},
+		{
+			name:       "subject altered",
+			msg:        strings.Replace(sign(t, testMessage, rsaKey, "relaxed/relaxed"), "Quarterly", "Annual", 1),
+			record:     rsaRecord,
+			want:       Fail,
+			wantReason: "signature did not verify",
+		},
+		{
+			name:   "relaxed whitespace changes still pass",
+			msg:    strings.Replace(sign(t, testMessage, rsaKey, "relaxed/relaxed"), "Subject:  Quarterly", "Subject: Quarterly", 1),
+			record: rsaRecord,
+			want:   Pass,
+		},
+		{
+			name:       "no key published",
+			msg:        sign(t, testMessage, rsaKey, "relaxed/relaxed"),
+			want:       PermError,
+			wantReason: "no key at sel._domainkey.example.com",
+		},
+		{
+			name:       "key revoked",
+			msg:        sign(t, testMessage, rsaKey, "relaxed/relaxed"),
+			record:     "v=DKIM1; p=",
+			want:       PermError,
+			wantReason: "key has been revoked",
+		},
+		{
+			name:   "dns failure",
+			msg:    sign(t, testMessage, rsaKey, "relaxed/relaxed"),
+			dnsErr: errors.New("connection refused"),
+			want:   TempError,
+		},
+		{
+			name:       "rsa-sha1",
+			msg:        strings.Replace(sign(t, testMessage, rsaKey, "relaxed/relaxed"), "a=rsa-sha256", "a=rsa-sha1", 1),
+			record:     rsaRecord,
+			want:       PermError,
+			wantReason: "rsa-sha1 signatures are no longer accepted",
+		},
+		{
+			name: "unsigned",
+			msg:  testMessage,
+			want: None,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &fakeResolver{records: map[string]string{}, err: tt.dnsErr}
+			if tt.record != "" {
+				r.records["sel._domainkey.example.com"] = tt.record
+			}
+			got := Check(context.Background(), []byte(tt.msg), NewVerifier(r))
+			if got.DKIM != tt.want {
+				t.Fatalf("DKIM = %q (%s), want %q", got.DKIM, got.DKIMReason, tt.want)
+			}
+			if tt.wantReason != "" && got.DKIMReason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", got.DKIMReason, tt.wantReason)
+			}
+			if tt.want != None && got.DKIMDomain != "example.com" {
+				t.Errorf("domain = %q", got.DKIMDomain)
+			}
+		})
+	}
+}
+
+func TestVerifier_CachesKeys(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &fakeResolver{records: map[string]string{
+		"sel._domainkey.example.com": keyRecord(t, edKey.Public()),
+	}}
+	v := NewVerifier(r)
+	msg := []byte(sign(t, testMessage, edKey, "relaxed/relaxed"))
+	for range 3 {
+		if got := Check(context.Background(), msg, v); got.DKIM != Pass {
+			t.Fatalf("DKIM = %q (%s)", got.DKIM, got.DKIMReason)
+		}
+	}
+	if r.lookups != 1 {
+		t.Errorf("lookups = %d, want 1", r.lookups)
+	}
+}
+
+func TestCheck_WithoutVerifier(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Check(context.Background(), []byte(sign(t, testMessage, edKey, "relaxed/relaxed")), nil)
+	if !got.IsZero() {
+		t.Errorf("Check without verifier or Authentication-Results = %+v, want zero", got)
+	}
+}
+
+// TestCanonicalization uses the example from RFC 6376 section 3.4.5.
+func TestCanonicalization(t *testing.T) {
+	msg := "A: X\r\nB : Y\t\r\n\tZ  \r\n\r\n C \r\nD \t E\r\n\r\n\r\n"
+	fields, body := splitMessage([]byte(msg))
+
+	var relaxed, simple strings.Builder
+	for _, f := range fields {
+		relaxed.WriteString(canonicalHeader(f.raw, "relaxed") + "\r\n")
+		simple.WriteString(canonicalHeader(f.raw, "simple") + "\r\n")
+	}
+	if got, want := relaxed.String(), "a:X\r\nb:Y Z\r\n"; got != want {
+		t.Errorf("relaxed headers = %q, want %q", got, want)
+	}
+	if got, want := simple.String(), "A: X\r\nB : Y\t\r\n\tZ  \r\n"; got != want {
+		t.Errorf("simple headers = %q, want %q", got, want)
+	}
+	if got, want := canonicalBody(body, "relaxed"), " C\r\nD E\r\n"; got != want {
+		t.Errorf("relaxed body = %q, want %q", got, want)
+	}
+	if got, want := canonicalBody(body, "simple"), " C \r\nD \t E\r\n"; got != want {
+		t.Errorf("simple body = %q, want %q", got, want)
+	}
+	if got := canonicalBody("", "simple"); got != "\r\n" {
+		t.Errorf("simple empty body = %q", got)
+	}
+	if got := canonicalBody("", "relaxed"); got != "" {
+		t.Errorf("relaxed empty body = %q", got)
+	}
+}
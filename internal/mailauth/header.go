@@ -0,0 +1,94 @@
+package mailauth
+
+import (
+	"bytes"
+	"strings"
+)
+
+// headerField is one header field as it appears in the message.
+type headerField struct {
+	name string
+	// raw is the whole field, name and folding included, with CRLF
+	// line breaks and without the final one.
+	raw string
+}
+
+func (f headerField) is(name string) bool {
+	return strings.EqualFold(f.name, name)
+}
+
+// value returns everything after the field's colon, still folded.
+func (f headerField) value() string {
+	_, v, _ := strings.Cut(f.raw, ":")
+	return v
+}
+
+// splitMessage splits raw MIME into its header fields, in order, and
+// its body. Line endings are normalized to CRLF first, as DKIM is
+// computed over the message as sent, while archives such as mbox
+// often store bare LFs.
+func splitMessage(raw []byte) ([]headerField, string) {
+	msg := toCRLF(raw)
+
+	var fields []headerField
+	rest := msg
+	for rest != "" {
+		if strings.HasPrefix(rest, "\r\n") {
+			return fields, rest[2:]
+		}
+		line, after, found := strings.Cut(rest, "\r\n")
+		if !found {
+			after = ""
+		}
+		rest = after
+		if (line[0] == ' ' || line[0] == '\t') && len(fields) > 0 {
+			fields[len(fields)-1].raw += "\r\n" + line
+			continue
+		}
+		name, _, ok := strings.Cut(line, ":")
+		if !ok {
+			// Not a header field: the header has ended without the
+			// blank line, so the rest is body.
+			return fields, line + "\r\n" + rest
+		}
+		fields = append(fields, headerField{name: strings.TrimRight(name, " \t"), raw: line})
+	}
+	return fields, ""
+}
+
+// toCRLF returns b with every line ending as CRLF.
+func toCRLF(b []byte) string {
+	if !bytes.Contains(b, []byte("\n")) {
+		return string(b)
+	}
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// unfold removes header folding line breaks.
+func unfold(s string) string {
+	return strings.ReplaceAll(s, "\r\n", "")
+}
+
+// collapseWSP replaces each run of spaces and tabs with one space.
+func collapseWSP(s string) string {
+	if !strings.ContainsAny(s, " \t") {
+		return s
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	inWSP := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == ' ' || c == '\t' {
+			if !inWSP {
+				sb.WriteByte(' ')
+			}
+			inWSP = true
+			continue
+		}
+		inWSP = false
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,97 @@
+// Package mailauth records where archived email came from: it verifies
+// DKIM signatures against the raw MIME and reads the
+// Authentication-Results the receiving server wrote when the message
+// was delivered.
+package mailauth
+
+import (
+	"context"
+	"strings"
+)
+
+// Result values, as written in Authentication-Results (RFC 8601).
+const (
+	Pass      = "pass"
+	Fail      = "fail"
+	None      = "none"
+	TempError = "temperror"
+	PermError = "permerror"
+)
+
+// Result is the provenance of one message.
+type Result struct {
+	// DKIM is this archive's own verification of the message's DKIM
+	// signatures: Pass when any signature verifies, None when there
+	// are none, and otherwise the first signature's result. Empty
+	// when the message was not verified.
+	DKIM string
+	// DKIMDomain is the signing domain (d=) of the passing signature,
+	// or of the first one when none passes.
+	DKIMDomain string
+	// DKIMReason explains a result other than Pass or None.
+	DKIMReason string
+
+	// AuthServID names the server that wrote the topmost
+	// Authentication-Results header, the one added on delivery; the
+	// Reported fields are the results it recorded. ReportedSPF falls
+	// back to the Received-SPF header.
+	AuthServID    string
+	ReportedDKIM  string
+	ReportedSPF   string
+	ReportedDMARC string
+	// AuthResults is the raw value of that header.
+	AuthResults string
+}
+
+// IsZero reports whether r records nothing at all.
+func (r Result) IsZero() bool {
+	return r == Result{}
+}
+
+// Check reads the message's Authentication-Results and, when v is not
+// nil, verifies its DKIM signatures. Only the topmost
+// Authentication-Results header is read: headers further down may
+// have been written by the sender and prove nothing.
+func Check(ctx context.Context, raw []byte, v *Verifier) Result {
+	fields, body := splitMessage(raw)
+	var r Result
+
+	for _, f := range fields {
+		if !f.is("Authentication-Results") {
+			continue
+		}
+		r.AuthResults = strings.TrimSpace(unfold(f.value()))
+		ar := ParseAuthResults(r.AuthResults)
+		r.AuthServID = ar.AuthServID
+		r.ReportedDKIM = ar.Summary("dkim")
+		r.ReportedSPF = ar.Summary("spf")
+		r.ReportedDMARC = ar.Summary("dmarc")
+		break
+	}
+	if r.ReportedSPF == "" {
+		for _, f := range fields {
+			if f.is("Received-SPF") {
+				r.ReportedSPF = receivedSPFResult(f.value())
+				break
+			}
+		}
+	}
+
+	if v != nil {
+		d := v.verifyMessage(ctx, fields, body)
+		r.DKIM, r.DKIMDomain, r.DKIMReason = d.Result, d.Domain, d.Reason
+	}
+	return r
+}
+
+// receivedSPFResult returns the result keyword that starts a
+// Received-SPF header (RFC 7208 section 9.1), such as "pass".
+func receivedSPFResult(value string) string {
+	word, _, _ := strings.Cut(strings.TrimSpace(unfold(value)), " ")
+	word = strings.ToLower(word)
+	switch word {
+	case Pass, Fail, None, TempError, PermError, "softfail", "neutral":
+		return word
+	}
+	return ""
+}
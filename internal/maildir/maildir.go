@@ -0,0 +1,113 @@
+// Package maildir writes messages into a Maildir++ hierarchy, the
+// layout mutt, notmuch, Dovecot and most other Unix mail tools read. The
+// top-level maildir is the inbox; each other folder is a sibling
+// directory named after it with a leading dot, nested folders joined by
+// dots, as in .Work.Projects.
+package maildir
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// Message flags, as recorded in the ":2," info suffix of file names.
+const (
+	FlagDraft   = 'D'
+	FlagFlagged = 'F'
+	FlagReplied = 'R'
+	FlagSeen    = 'S'
+	FlagTrashed = 'T'
+)
+
+// Maildir is a Maildir++ hierarchy on disk.
+type Maildir struct {
+	root string
+}
+
+// Create creates the maildir at root, with its cur, new and tmp
+// directories, if it does not exist yet.
+func Create(root string) (*Maildir, error) {
+	if err := makeMaildir(root); err != nil {
+		return nil, err
+	}
+	return &Maildir{root: root}, nil
+}
+
+func makeMaildir(dir string) error {
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if err := fileutil.SecureMkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return fmt.Errorf("create maildir %s: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// FolderName converts a label or mailbox path, with / between levels
+// as in "Work/Projects", to its Maildir++ folder name, "Work.Projects".
+// Dots within a level, which Maildir++ would read as separators, become
+// underscores. An empty name is the inbox.
+func FolderName(label string) string {
+	var parts []string
+	for _, p := range strings.Split(label, "/") {
+		p = strings.TrimSpace(strings.Map(func(r rune) rune {
+			switch r {
+			case '.', '\\', '\x00', '\n', '\r':
+				return '_'
+			}
+			return r
+		}, p))
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// Deliver writes a message into folder, as named by FolderName ("" for
+// the inbox), creating the folder if needed, and returns the path of
+// the file. key must be unique within the folder; delivering the same
+// key with the same flags again overwrites the file. The message is
+// written with LF line endings and, as Maildir requires, appears under
+// cur only once it is complete.
+func (m *Maildir) Deliver(folder, key string, flags []rune, raw []byte) (string, error) {
+	if strings.ContainsAny(key, "/:\\") || key == "" || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid maildir key %q", key)
+	}
+	dir := m.root
+	if folder != "" {
+		dir = filepath.Join(m.root, "."+folder)
+		if err := makeMaildir(dir); err != nil {
+			return "", err
+		}
+		// Dovecot and Courier expect this marker in each subfolder.
+		marker := filepath.Join(dir, "maildirfolder")
+		if _, err := os.Stat(marker); os.IsNotExist(err) {
+			if err := fileutil.SecureWriteFile(marker, nil, 0o600); err != nil {
+				return "", fmt.Errorf("mark maildir folder: %w", err)
+			}
+		}
+	}
+
+	flags = slices.Clone(flags)
+	slices.Sort(flags)
+	flags = slices.Compact(flags)
+	name := key + ":2," + string(flags)
+
+	tmp := filepath.Join(dir, "tmp", key)
+	data := bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	if err := fileutil.SecureWriteFile(tmp, data, 0o600); err != nil {
+		return "", fmt.Errorf("write message: %w", err)
+	}
+	final := filepath.Join(dir, "cur", name)
+	if err := os.Rename(tmp, final); err != nil {
+		_ = os.Remove(tmp)
+		return "", fmt.Errorf("deliver message: %w", err)
+	}
+	return final, nil
+}
@@ -0,0 +1,73 @@
+package maildir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFolderName(t *testing.T) {
+	tests := []struct {
+		label, want string
+	}{
+		{"", ""},
+		{"Receipts", "Receipts"},
+		{"Work/Projects", "Work.Projects"},
+		{"v1.2 notes", "v1_2 notes"},
+		{"/Work//Archive/", "Work.Archive"},
+	}
+	for _, tt := range tests {
+		if got := FolderName(tt.label); got != tt.want {
+			t.Errorf("FolderName(%q) = %q, want %q", tt.label, got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "mail")
+	md, err := Create(root)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, sub := range []string{"cur", "new", "tmp"} {
+		if fi, err := os.Stat(filepath.Join(root, sub)); err != nil || !fi.IsDir() {
+			t.Errorf("%s missing: %v", sub, err)
+		}
+	}
+
+	raw := []byte("From: bob@example.com\r\nSubject: Hi\r\n\r\nHello.\r\n")
+	p, err := md.Deliver("", "1700000000.1.msgvault", []rune{FlagSeen, FlagFlagged, FlagSeen}, raw)
+	if err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if want := filepath.Join(root, "cur", "1700000000.1.msgvault:2,FS"); p != want {
+		t.Errorf("Deliver path = %s, want %s", p, want)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "From: bob@example.com\nSubject: Hi\n\nHello.\n"; string(data) != want {
+		t.Errorf("delivered message = %q, want %q", data, want)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(root, "tmp")); len(entries) != 0 {
+		t.Errorf("tmp not empty after delivery: %v", entries)
+	}
+
+	p, err = md.Deliver("Work.Projects", "1700000000.2.msgvault", nil, raw)
+	if err != nil {
+		t.Fatalf("Deliver to folder: %v", err)
+	}
+	if want := filepath.Join(root, ".Work.Projects", "cur", "1700000000.2.msgvault:2,"); p != want {
+		t.Errorf("Deliver path = %s, want %s", p, want)
+	}
+	if _, err := os.Stat(filepath.Join(root, ".Work.Projects", "maildirfolder")); err != nil {
+		t.Errorf("maildirfolder marker missing: %v", err)
+	}
+
+	for _, key := range []string{"", "..", "a/b", "a:2,S"} {
+		if _, err := md.Deliver("", key, nil, raw); err == nil {
+			t.Errorf("Deliver with key %q should fail", key)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// Package mailview presents the archive as a set of mailboxes for the
+// protocol gateways (IMAP and JMAP). Labels become mailboxes, a virtual
+// "All Mail" mailbox holds every email message, and message filters are
+// translated to search.Query so the query engine answers content
+// searches the same way for every protocol.
+package mailview
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+const (
+	// Delim separates levels of nested label names.
+	Delim = '/'
+	// InboxName is the mailbox backed by the INBOX label. It always
+	// exists, even when empty.
+	InboxName = "INBOX"
+	// AllMailName is the virtual mailbox holding every email message.
+	AllMailName = "All Mail"
+)
+
+// Store is the archive access the gateways share. Source ID slices
+// restrict results to those sources; nil means every source.
+type Store interface {
+	MailboxNames(sourceIDs []int64) ([]string, error)
+	MailboxMessages(label string, sourceIDs []int64) ([]store.MailboxMessage, error)
+}
+
+// Searcher runs structured searches. query.Engine satisfies it.
+type Searcher interface {
+	Search(ctx context.Context, q *search.Query, limit, offset int) ([]query.MessageSummary, error)
+}
+
+// Mailboxes is the mailbox tree visible to one caller.
+type Mailboxes struct {
+	boxes   map[string]string // mailbox name -> backing label ("" = All Mail)
+	parents map[string]bool   // path prefixes with no label of their own
+}
+
+// LoadMailboxes builds the mailbox tree for the given sources.
+func LoadMailboxes(st Store, sourceIDs []int64) (*Mailboxes, error) {
+	labels, err := st.MailboxNames(sourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	boxes := map[string]string{InboxName: InboxName, AllMailName: ""}
+	for _, l := range labels {
+		switch {
+		case strings.EqualFold(l, InboxName):
+			boxes[InboxName] = l
+		case l == AllMailName:
+			// The virtual mailbox wins; a label of the same name stays
+			// reachable through search.
+		default:
+			boxes[l] = l
+		}
+	}
+	parents := make(map[string]bool)
+	for name := range boxes {
+		for i, r := range name {
+			if r != Delim {
+				continue
+			}
+			if p := name[:i]; p != "" {
+				if _, ok := boxes[p]; !ok {
+					parents[p] = true
+				}
+			}
+		}
+	}
+	return &Mailboxes{boxes: boxes, parents: parents}, nil
+}
+
+// Resolve maps a mailbox name to its backing label ("" for All Mail).
+// INBOX is matched case-insensitively.
+func (mb *Mailboxes) Resolve(name string) (label string, ok bool) {
+	if strings.EqualFold(name, InboxName) {
+		name = InboxName
+	}
+	label, ok = mb.boxes[name]
+	return label, ok
+}
+
+// Names returns every mailbox name, including label-less parents, sorted.
+func (mb *Mailboxes) Names() []string {
+	names := make([]string, 0, len(mb.boxes)+len(mb.parents))
+	for name := range mb.boxes {
+		names = append(names, name)
+	}
+	for name := range mb.parents {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// IsParent reports whether name exists only as the prefix of nested
+// labels and so holds no messages of its own.
+func (mb *Mailboxes) IsParent(name string) bool {
+	return mb.parents[name]
+}
+
+// NameForLabel returns the mailbox that shows a label, or "" when the
+// label has none (the All Mail name collision).
+func NameForLabel(label string) string {
+	switch {
+	case strings.EqualFold(label, InboxName):
+		return InboxName
+	case label == AllMailName:
+		return ""
+	}
+	return label
+}
+
+// Filter is a protocol-neutral content filter. Each field narrows the
+// result (AND); fields with several values require all of them.
+type Filter struct {
+	From, To, Cc, Bcc []string
+	Subject           []string
+	Text              []string // subject and body
+	HasAttachment     *bool
+	// SentSince and SentBefore compare the sent date only; zero means
+	// unbounded.
+	SentSince, SentBefore time.Time
+}
+
+// AddHeader adds a header search. It reports false for headers the
+// archive doesn't index. An empty value means "header present", which
+// every archived message satisfies closely enough to ignore.
+func (f *Filter) AddHeader(key, value string) bool {
+	var terms *[]string
+	switch strings.ToLower(key) {
+	case "from":
+		terms = &f.From
+	case "to":
+		terms = &f.To
+	case "cc":
+		terms = &f.Cc
+	case "bcc":
+		terms = &f.Bcc
+	case "subject":
+		terms = &f.Subject
+	default:
+		return false
+	}
+	if value = strings.TrimSpace(value); value != "" {
+		*terms = append(*terms, strings.ToLower(value))
+	}
+	return true
+}
+
+// AddText adds full-text terms, skipping blank ones.
+func (f *Filter) AddText(terms ...string) {
+	for _, t := range terms {
+		if t = strings.TrimSpace(t); t != "" {
+			f.Text = append(f.Text, t)
+		}
+	}
+}
+
+// IsEmpty reports whether the filter matches everything.
+func (f *Filter) IsEmpty() bool {
+	return len(f.From)+len(f.To)+len(f.Cc)+len(f.Bcc)+len(f.Subject)+len(f.Text) == 0 &&
+		f.HasAttachment == nil && f.SentSince.IsZero() && f.SentBefore.IsZero()
+}
+
+// Query translates the filter to a search.Query limited to sourceIDs.
+// Source-deleted messages are included: the archive is the record.
+func (f *Filter) Query(sourceIDs []int64) *search.Query {
+	q := &search.Query{
+		FromAddrs:     slices.Clone(f.From),
+		ToAddrs:       slices.Clone(f.To),
+		CcAddrs:       slices.Clone(f.Cc),
+		BccAddrs:      slices.Clone(f.Bcc),
+		SubjectTerms:  slices.Clone(f.Subject),
+		TextTerms:     slices.Clone(f.Text),
+		HasAttachment: f.HasAttachment,
+		AccountIDs:    sourceIDs,
+	}
+	if !f.SentSince.IsZero() {
+		t := DateOnly(f.SentSince)
+		q.AfterDate = &t
+	}
+	if !f.SentBefore.IsZero() {
+		t := DateOnly(f.SentBefore)
+		q.BeforeDate = &t
+	}
+	return q
+}
+
+const (
+	searchPageSize = 1000
+	// maxMatches bounds how many IDs one engine search may collect. A
+	// broader search is refused rather than answered partially.
+	maxMatches = 100000
+)
+
+// ErrTooBroad is returned by Match when a filter matches more messages
+// than a gateway will hold in memory.
+var ErrTooBroad = errors.New("search matches too many messages")
+
+// Match runs q through the searcher and returns the matching IDs.
+func Match(ctx context.Context, s Searcher, q *search.Query) (map[int64]bool, error) {
+	ids := make(map[int64]bool)
+	for offset := 0; ; offset += searchPageSize {
+		page, err := s.Search(ctx, q, searchPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page {
+			ids[m.ID] = true
+		}
+		if len(ids) > maxMatches {
+			return nil, ErrTooBroad
+		}
+		if len(page) < searchPageSize {
+			return ids, nil
+		}
+	}
+}
+
+// DateOnly drops the time of day, for protocols whose date criteria
+// compare whole days.
+func DateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
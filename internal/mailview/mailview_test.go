@@ -0,0 +1,115 @@
+package mailview
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+type fakeStore struct {
+	labels []string
+}
+
+func (f *fakeStore) MailboxNames([]int64) ([]string, error) { return f.labels, nil }
+
+func (f *fakeStore) MailboxMessages(string, []int64) ([]store.MailboxMessage, error) {
+	return nil, nil
+}
+
+func TestLoadMailboxes(t *testing.T) {
+	mb, err := LoadMailboxes(&fakeStore{labels: []string{"inbox", "Receipts/2024/Q1", "All Mail", "SENT"}}, nil)
+	if err != nil {
+		t.Fatalf("LoadMailboxes: %v", err)
+	}
+	want := []string{"All Mail", "INBOX", "Receipts", "Receipts/2024", "Receipts/2024/Q1", "SENT"}
+	if got := mb.Names(); !slices.Equal(got, want) {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		name      string
+		wantLabel string
+		wantOK    bool
+	}{
+		{"INBOX", "inbox", true},
+		{"Inbox", "inbox", true},
+		{"All Mail", "", true},
+		{"Receipts/2024/Q1", "Receipts/2024/Q1", true},
+		{"Receipts", "", false},
+		{"Nope", "", false},
+	}
+	for _, tt := range tests {
+		label, ok := mb.Resolve(tt.name)
+		if label != tt.wantLabel || ok != tt.wantOK {
+			t.Errorf("Resolve(%q) = %q, %v; want %q, %v", tt.name, label, ok, tt.wantLabel, tt.wantOK)
+		}
+	}
+	if !mb.IsParent("Receipts/2024") || mb.IsParent("SENT") {
+		t.Error("IsParent should only report label-less path prefixes")
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	var f Filter
+	if !f.IsEmpty() {
+		t.Error("zero Filter should be empty")
+	}
+	if !f.AddHeader("From", " Bob@Example.com ") || !f.AddHeader("subject", "Invoice") {
+		t.Fatal("from/subject headers should be supported")
+	}
+	if f.AddHeader("X-Mailer", "foo") {
+		t.Error("X-Mailer should be unsupported")
+	}
+	f.AddText("hello", "  ")
+	f.SentSince = time.Date(2024, 3, 5, 15, 30, 0, 0, time.UTC)
+
+	q := f.Query([]int64{7})
+	if !slices.Equal(q.FromAddrs, []string{"bob@example.com"}) || !slices.Equal(q.SubjectTerms, []string{"invoice"}) {
+		t.Errorf("address terms = %v / %v", q.FromAddrs, q.SubjectTerms)
+	}
+	if !slices.Equal(q.TextTerms, []string{"hello"}) {
+		t.Errorf("TextTerms = %v", q.TextTerms)
+	}
+	if q.AfterDate == nil || !q.AfterDate.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AfterDate = %v, want start of day", q.AfterDate)
+	}
+	if !slices.Equal(q.AccountIDs, []int64{7}) || q.HideDeleted {
+		t.Errorf("scope = %v hideDeleted=%v", q.AccountIDs, q.HideDeleted)
+	}
+}
+
+type pagedSearcher struct {
+	total int
+	calls int
+}
+
+func (p *pagedSearcher) Search(_ context.Context, _ *search.Query, limit, offset int) ([]query.MessageSummary, error) {
+	p.calls++
+	var out []query.MessageSummary
+	for i := offset; i < min(offset+limit, p.total); i++ {
+		out = append(out, query.MessageSummary{ID: int64(i + 1)})
+	}
+	return out, nil
+}
+
+func TestMatch(t *testing.T) {
+	s := &pagedSearcher{total: searchPageSize + 5}
+	ids, err := Match(context.Background(), s, &search.Query{})
+	if err != nil {
+		t.Fatalf("Match: %v", err)
+	}
+	if len(ids) != searchPageSize+5 || s.calls != 2 {
+		t.Errorf("got %d ids in %d calls", len(ids), s.calls)
+	}
+
+	_, err = Match(context.Background(), &pagedSearcher{total: maxMatches + searchPageSize}, &search.Query{})
+	if !errors.Is(err, ErrTooBroad) {
+		t.Errorf("err = %v, want ErrTooBroad", err)
+	}
+}
@@ -0,0 +1,75 @@
+package mbox
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+// separatorDateLayout is the asctime layout of the date on a "From "
+// separator line.
+const separatorDateLayout = "Mon Jan _2 15:04:05 2006"
+
+// Writer writes messages to an MBOX stream in mboxrd format: each
+// message follows a "From " separator line and body lines matching
+// ^>*From  are escaped with one more '>', which Reader removes again.
+// Line endings are written as LF, as mail clients expect in mbox files.
+type Writer struct {
+	bw *bufio.Writer
+}
+
+// NewWriter creates a new MBOX writer. Call Flush when done.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{bw: bufio.NewWriter(w)}
+}
+
+// WriteMessage appends a message. sender and date fill in the separator
+// line; an empty sender is written as MAILER-DAEMON and a zero date as
+// the Unix epoch.
+func (w *Writer) WriteMessage(sender string, date time.Time, raw []byte) error {
+	if sender = strings.Join(strings.Fields(sender), ""); sender == "" {
+		sender = "MAILER-DAEMON"
+	}
+	if date.IsZero() {
+		date = time.Unix(0, 0)
+	}
+	if _, err := w.bw.WriteString("From " + sender + " " + date.UTC().Format(separatorDateLayout) + "\n"); err != nil {
+		return err
+	}
+
+	for len(raw) > 0 {
+		line := raw
+		if i := bytes.IndexByte(raw, '\n'); i >= 0 {
+			line, raw = raw[:i], raw[i+1:]
+		} else {
+			raw = nil
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if needsFromEscape(line) {
+			if err := w.bw.WriteByte('>'); err != nil {
+				return err
+			}
+		}
+		if _, err := w.bw.Write(line); err != nil {
+			return err
+		}
+		if err := w.bw.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	// A blank line separates messages.
+	return w.bw.WriteByte('\n')
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.bw.Flush()
+}
+
+// needsFromEscape reports whether line matches ^>*From , the lines
+// mboxrd escapes.
+func needsFromEscape(line []byte) bool {
+	return bytes.HasPrefix(bytes.TrimLeft(line, ">"), fromPrefix)
+}
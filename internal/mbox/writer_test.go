@@ -0,0 +1,76 @@
+package mbox
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_WriteMessage(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	date := time.Date(2024, 3, 5, 9, 4, 5, 0, time.FixedZone("CET", 3600))
+	raw := "Subject: One\r\n\r\nFrom the start\r\n>From quoted\r\nEnd"
+	if err := w.WriteMessage("alice@example.com", date, []byte(raw)); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := w.WriteMessage("", time.Time{}, []byte("Subject: Two\n\nBody\n")); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"From alice@example.com Tue Mar  5 08:04:05 2024",
+		"Subject: One",
+		"",
+		">From the start",
+		">>From quoted",
+		"End",
+		"",
+		"From MAILER-DAEMON Thu Jan  1 00:00:00 1970",
+		"Subject: Two",
+		"",
+		"Body",
+		"",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	msgs := []string{
+		"Subject: One\n\nFrom here on\n>From there\nBye\n",
+		"Subject: Two\n\nBody\n",
+	}
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, m := range msgs {
+		if err := w.WriteMessage("bob@example.com", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), []byte(m)); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	r := NewReader(&buf)
+	for i, want := range msgs {
+		msg, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next() %d: %v", i, err)
+		}
+		if got := strings.TrimRight(string(msg.Raw), "\n"); got != strings.TrimRight(want, "\n") {
+			t.Errorf("message %d = %q, want %q", i, got, want)
+		}
+	}
+	if _, err := r.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() after last message = %v, want io.EOF", err)
+	}
+}
@@ -118,23 +118,25 @@ func getDateArg(args map[string]any, key string) (*time.Time, error) {
 // readAttachmentFile reads the content-addressed attachment file after
 // validating the hash and checking size limits.
 func (h *handlers) readAttachmentFile(contentHash string) ([]byte, error) {
-	filePath, err := export.StoragePath(h.attachmentsDir, contentHash)
-	if err != nil {
+	if err := export.ValidateContentHash(contentHash); err != nil {
 		return nil, fmt.Errorf("attachment has invalid content hash")
 	}
 
-	f, err := os.Open(filePath)
+	f, err := export.OpenAttachment(h.attachmentsDir, contentHash)
 	if err != nil {
 		return nil, fmt.Errorf("attachment file not available: %v", err)
 	}
 	defer func() { _ = f.Close() }()
 
-	info, err := f.Stat()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("attachment file not available: %v", err)
 	}
-	if info.Size() > maxAttachmentSize {
-		return nil, fmt.Errorf("attachment too large: %d bytes (max %d)", info.Size(), maxAttachmentSize)
+	if size > maxAttachmentSize {
+		return nil, fmt.Errorf("attachment too large: %d bytes (max %d)", size, maxAttachmentSize)
 	}
 
 	data, err := io.ReadAll(io.LimitReader(f, maxAttachmentSize+1))
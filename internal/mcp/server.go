@@ -83,6 +83,10 @@ type ServeOptions struct {
 	// Backend is optional. When nil, find_similar_messages rejects all
 	// calls with a vector_not_enabled error.
 	Backend vector.Backend
+	// ReadOnly leaves out the tools with side effects (export_attachment
+	// writes files, stage_deletion queues deletions), so an assistant
+	// can only read the archive.
+	ReadOnly bool
 }
 
 // newMCPServer builds an MCP server with all tools registered from opts.
@@ -107,12 +111,14 @@ func newMCPServer(opts ServeOptions) *server.MCPServer {
 	s.AddTool(searchMessagesTool(vectorAvailable), h.searchMessages)
 	s.AddTool(getMessageTool(), h.getMessage)
 	s.AddTool(getAttachmentTool(), h.getAttachment)
-	s.AddTool(exportAttachmentTool(), h.exportAttachment)
 	s.AddTool(listMessagesTool(), h.listMessages)
 	s.AddTool(getStatsTool(), h.getStats)
 	s.AddTool(aggregateTool(), h.aggregate)
-	s.AddTool(stageDeletionTool(), h.stageDeletion)
 	s.AddTool(searchByDomainsTool(), h.searchByDomains)
+	if !opts.ReadOnly {
+		s.AddTool(exportAttachmentTool(), h.exportAttachment)
+		s.AddTool(stageDeletionTool(), h.stageDeletion)
+	}
 	if opts.Backend != nil {
 		s.AddTool(findSimilarMessagesTool(), h.findSimilarMessages)
 	}
@@ -183,7 +189,7 @@ func ServeHTTPWithOptions(ctx context.Context, opts ServeOptions, addr string) e
 func searchMessagesTool(vectorAvailable bool) mcp.Tool {
 	if !vectorAvailable {
 		return mcp.NewTool(ToolSearchMessages,
-			mcp.WithDescription("Search emails using Gmail-like query syntax. Supports from:, to:, subject:, label:, has:attachment, before:, after:, and free text. (This server is not configured for vector search; only keyword FTS is available.)"),
+			mcp.WithDescription("Search emails using Gmail-like query syntax. Supports from:, to:, cc:, bcc:, subject:, label:, has:attachment, filename: (attachment name or extension), before:, after:, lang: (ISO 639-1 code, e.g. lang:de), is:unread, is:starred, is:important, is:suspicious (likely spam or phishing), is:pinned, note: and tag: (the user's local notes and tags), and free text. (This server is not configured for vector search; only keyword FTS is available.)"),
 			mcp.WithReadOnlyHintAnnotation(true),
 			mcp.WithString("query",
 				mcp.Required(),
@@ -195,7 +201,7 @@ func searchMessagesTool(vectorAvailable bool) mcp.Tool {
 		)
 	}
 	return mcp.NewTool(ToolSearchMessages,
-		mcp.WithDescription("Search emails using Gmail-like query syntax. Supports from:, to:, subject:, label:, has:attachment, before:, after:, and free text. Vector search is configured: set mode=vector for pure semantic search or mode=hybrid to fuse BM25 and vector ranking via RRF. Vector/hybrid modes require free-text terms in the query; filter-only queries must use mode=fts."),
+		mcp.WithDescription("Search emails using Gmail-like query syntax. Supports from:, to:, cc:, bcc:, subject:, label:, has:attachment, filename: (attachment name or extension), before:, after:, lang: (ISO 639-1 code, e.g. lang:de), is:unread, is:starred, is:important, is:suspicious (likely spam or phishing), is:pinned, note: and tag: (the user's local notes and tags), and free text. Vector search is configured: set mode=vector for pure semantic search or mode=hybrid to fuse BM25 and vector ranking via RRF. Vector/hybrid modes require free-text terms in the query; filter-only queries must use mode=fts."),
 		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("query",
 			mcp.Required(),
@@ -1455,6 +1455,37 @@ func TestSearchMessagesTool_AdvertisesVectorModesOnlyWhenAvailable(t *testing.T)
 	}
 }
 
+func TestNewMCPServer_ReadOnlyOmitsSideEffectTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		readOnly bool
+		want     map[string]bool
+	}{
+		{"default", false, map[string]bool{ToolExportAttachment: true, ToolStageDeletion: true}},
+		{"read-only", true, map[string]bool{ToolExportAttachment: false, ToolStageDeletion: false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newMCPServer(ServeOptions{Engine: &querytest.MockEngine{}, ReadOnly: tt.readOnly})
+			for name, want := range tt.want {
+				if got := s.GetTool(name) != nil; got != want {
+					t.Errorf("tool %s registered = %v, want %v", name, got, want)
+				}
+			}
+			for _, name := range []string{ToolSearchMessages, ToolGetMessage, ToolGetAttachment, ToolGetStats, ToolAggregate} {
+				if s.GetTool(name) == nil {
+					t.Errorf("read tool %s missing", name)
+				}
+			}
+			for name, tool := range s.ListTools() {
+				if tt.readOnly && (tool.Tool.Annotations.ReadOnlyHint == nil || !*tool.Tool.Annotations.ReadOnlyHint) {
+					t.Errorf("read-only server exposes %s without a read-only hint", name)
+				}
+			}
+		})
+	}
+}
+
 func TestFindSimilarMessages_MissingID(t *testing.T) {
 	h := &handlers{
 		engine:  &querytest.MockEngine{},
@@ -32,6 +32,9 @@ const (
 	// ScopeIMAPPersonal is the IMAP scope for personal Microsoft accounts
 	// (hotmail.com, outlook.com, live.com, etc.).
 	ScopeIMAPPersonal = "https://outlook.office.com/IMAP.AccessAsUser.All"
+	// ScopeGraphMail is the Microsoft Graph scope for reading mail. Unlike
+	// IMAP, it is the same for personal and organizational accounts.
+	ScopeGraphMail = "https://graph.microsoft.com/Mail.Read"
 
 	// MicrosoftConsumerTenantID is the fixed tenant ID for all personal
 	// Microsoft accounts (outlook.com, hotmail.com, live.com, etc.).
@@ -101,6 +104,7 @@ type Manager struct {
 	tenantID  string
 	tokensDir string
 	logger    *slog.Logger
+	graph     bool // authorizes Microsoft Graph rather than IMAP
 
 	// browserFlowFn overrides browserFlow for testing. Returns (token, nonce, error).
 	browserFlowFn func(ctx context.Context, email string, scopes []string) (*oauth2.Token, string, error)
@@ -123,6 +127,23 @@ func NewManager(clientID, tenantID, tokensDir string, logger *slog.Logger) *Mana
 	}
 }
 
+// NewGraphManager returns a Manager for Microsoft Graph mail access.
+// Its tokens are stored apart from IMAP tokens, so one address can be
+// authorized for both.
+func NewGraphManager(clientID, tenantID, tokensDir string, logger *slog.Logger) *Manager {
+	m := NewManager(clientID, tenantID, tokensDir, logger)
+	m.graph = true
+	return m
+}
+
+// scopesFor returns the scopes to request for email.
+func (m *Manager) scopesFor(email string) []string {
+	if m.graph {
+		return []string{ScopeGraphMail, "offline_access", "openid", "email"}
+	}
+	return scopesForEmail(email)
+}
+
 func (m *Manager) oauthConfig(scopes []string) *oauth2.Config {
 	return m.oauthConfigWithTenant(m.tenantID, scopes)
 }
@@ -140,7 +161,7 @@ func (m *Manager) oauthConfigWithTenant(tenantID string, scopes []string) *oauth
 }
 
 func (m *Manager) Authorize(ctx context.Context, email string) error {
-	scopes := scopesForEmail(email)
+	scopes := m.scopesFor(email)
 	flow := m.doBrowserFlow
 	token, nonce, err := flow(ctx, email, scopes)
 	if err != nil {
@@ -155,7 +176,7 @@ func (m *Manager) Authorize(ctx context.Context, email string) error {
 	// The tid claim from the ID token is authoritative for account type.
 	// We must restart the browser flow (not just refresh) because consent
 	// for a different IMAP resource requires interactive authorization.
-	if claims.TenantID != "" {
+	if claims.TenantID != "" && !m.graph {
 		correctIMAPScope := imapScopeForTenant(claims.TenantID)
 		if scopes[0] != correctIMAPScope {
 			m.logger.Info("correcting IMAP scope based on tenant ID, re-authorizing",
@@ -214,7 +235,7 @@ func (m *Manager) TokenSource(ctx context.Context, email string) (func(context.C
 
 	scopes := tf.Scopes
 	if len(scopes) == 0 {
-		scopes = scopesForEmail(email)
+		scopes = m.scopesFor(email)
 	}
 
 	// Migrate pre-migration tokens: if the token file has no tenant_id but the
@@ -229,7 +250,7 @@ func (m *Manager) TokenSource(ctx context.Context, email string) (func(context.C
 	// Validate persisted scopes against tenant ID to detect stale tokens
 	// from before scope-correction was added. Tokens without a tenant_id
 	// are pre-migration and skip this check (backward compatible).
-	if tf.TenantID != "" && len(tf.Scopes) > 0 {
+	if tf.TenantID != "" && len(tf.Scopes) > 0 && !m.graph {
 		correctScope := imapScopeForTenant(tf.TenantID)
 		if tf.Scopes[0] != correctScope {
 			m.logger.Debug("stale IMAP scope detected",
@@ -585,6 +606,9 @@ type tokenFile struct {
 
 func (m *Manager) TokenPath(email string) string {
 	safe := sanitizeEmail(email)
+	if m.graph {
+		return filepath.Join(m.tokensDir, "microsoft_graph_"+safe+".json")
+	}
 	return filepath.Join(m.tokensDir, "microsoft_"+safe+".json")
 }
 
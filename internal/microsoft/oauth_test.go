@@ -540,6 +540,50 @@ func TestAuthorize_PersistsTenantID(t *testing.T) {
 	}
 }
 
+func TestAuthorize_Graph(t *testing.T) {
+	// Graph tokens use one scope for every tenant and are stored apart
+	// from the IMAP token for the same address.
+	dir := t.TempDir()
+	m := NewGraphManager("test-client", "common", dir, slog.Default())
+	m.verifyIDTokenFn = testVerifyFn
+
+	callCount := 0
+	m.browserFlowFn = func(ctx context.Context, email string, scopes []string) (*oauth2.Token, string, error) {
+		callCount++
+		if scopes[0] != ScopeGraphMail {
+			t.Errorf("scope = %q, want %q", scopes[0], ScopeGraphMail)
+		}
+		idToken := makeIDToken(map[string]any{
+			"email": "user@custom-domain.com",
+			"tid":   MicrosoftConsumerTenantID,
+		})
+		tok := (&oauth2.Token{
+			AccessToken:  "access-token",
+			RefreshToken: "refresh-token",
+			TokenType:    "Bearer",
+		}).WithExtra(map[string]any{"id_token": idToken})
+		return tok, "test-nonce", nil
+	}
+
+	if err := m.Authorize(t.Context(), "user@custom-domain.com"); err != nil {
+		t.Fatal(err)
+	}
+	if callCount != 1 {
+		t.Errorf("browserFlowFn called %d times, want 1 (no IMAP scope correction)", callCount)
+	}
+
+	imapMgr := NewManager("test-client", "common", dir, slog.Default())
+	if imapMgr.HasToken("user@custom-domain.com") {
+		t.Error("Graph authorization wrote the IMAP token")
+	}
+	if !m.HasToken("user@custom-domain.com") {
+		t.Fatal("Graph token not saved")
+	}
+	if _, err := m.TokenSource(t.Context(), "user@custom-domain.com"); err != nil {
+		t.Errorf("TokenSource: %v", err)
+	}
+}
+
 func TestTokenSource_StaleScopeReturnsError(t *testing.T) {
 	dir := t.TempDir()
 	m := &Manager{
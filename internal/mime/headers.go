@@ -0,0 +1,78 @@
+package mime
+
+import (
+	"bytes"
+	stdmime "mime"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// maxHeaderValueLen caps how much of a header value is indexed.
+const maxHeaderValueLen = 1000
+
+// Header is one header field of a message, as indexed for search.
+type Header struct {
+	Name  string // lowercased
+	Value string
+}
+
+// IndexedHeaders returns the values of the named header fields of a raw
+// message, in the order they appear. Only the header block is read.
+// Values are normalized for matching: the ID of a List-Id without the
+// list's description, each address of an address header such as
+// Delivered-To, and other values decoded with runs of space collapsed.
+// Addresses and list IDs are lowercased.
+func IndexedHeaders(raw []byte, names []string) ([]Header, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	var out []Header
+	seen := make(map[Header]bool)
+	add := func(name, value string) {
+		if len(value) > maxHeaderValueLen {
+			value = value[:maxHeaderValueLen]
+		}
+		h := Header{Name: name, Value: value}
+		if value != "" && !seen[h] {
+			seen[h] = true
+			out = append(out, h)
+		}
+	}
+	dec := stdmime.WordDecoder{}
+	for _, name := range names {
+		name = strings.ToLower(name)
+		for _, v := range msg.Header[textproto.CanonicalMIMEHeaderKey(name)] {
+			switch name {
+			case "list-id":
+				add(name, listID(v))
+			case "delivered-to", "x-original-to", "envelope-to":
+				for _, a := range strings.Split(v, ",") {
+					if addr, err := mail.ParseAddress(a); err == nil {
+						add(name, strings.ToLower(addr.Address))
+					} else {
+						add(name, strings.ToLower(strings.TrimSpace(a)))
+					}
+				}
+			default:
+				if d, err := dec.DecodeHeader(v); err == nil {
+					v = d
+				}
+				add(name, strings.Join(strings.Fields(v), " "))
+			}
+		}
+	}
+	return out, nil
+}
+
+// listID returns the list ID of a List-Id header, the part in angle
+// brackets after the optional description, lowercased.
+func listID(v string) string {
+	if i := strings.LastIndexByte(v, '<'); i >= 0 {
+		if j := strings.IndexByte(v[i:], '>'); j > 0 {
+			v = v[i+1 : i+j]
+		}
+	}
+	return strings.ToLower(strings.TrimSpace(v))
+}
@@ -0,0 +1,29 @@
+package mime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIndexedHeaders(t *testing.T) {
+	raw := "From: bob@example.com\r\n" +
+		"Delivered-To: Alice+Lists@Example.com\r\n" +
+		"Delivered-To: alice+lists@example.com\r\n" +
+		"List-Id: \"Go Nuts\" <Golang-Nuts.googlegroups.com>\r\n" +
+		"X-Mailer: =?utf-8?q?Mail=C3=A9?=\r\n  2.0\r\n" +
+		"X-Ignored: yes\r\n" +
+		"\r\n" +
+		"List-Id: <in-body.example.com>\r\n"
+	got, err := IndexedHeaders([]byte(raw), []string{"List-Id", "delivered-to", "x-mailer", "x-missing"})
+	if err != nil {
+		t.Fatalf("IndexedHeaders: %v", err)
+	}
+	want := []Header{
+		{Name: "list-id", Value: "golang-nuts.googlegroups.com"},
+		{Name: "delivered-to", Value: "alice+lists@example.com"},
+		{Name: "x-mailer", Value: "Mailé 2.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("IndexedHeaders =\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package mime
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Language detection works in two steps. Text mostly in a script used
+// by a single language (or a small family whose most common member is
+// assumed) is tagged by its script. Text in the Latin script is tagged
+// by counting the most common short words of each language it might be
+// in; these occur so often that a few sentences are enough to tell the
+// languages apart.
+
+const (
+	// maxLanguageSample caps how much of a body is examined, in bytes.
+	maxLanguageSample = 8 << 10
+
+	// minLanguageLetters is the fewest letters a text must have for
+	// its language to be detected.
+	minLanguageLetters = 20
+
+	// minStopwordHits is the fewest common words of a Latin-script
+	// language a text must contain to be tagged with it.
+	minStopwordHits = 3
+)
+
+// stopwords lists frequent words of each Latin-script language,
+// chosen to overlap as little as possible between languages.
+var stopwords = map[string]map[string]bool{
+	"en": wordSet("the and of to is that for it with was on are this you have not but at by from they we will which your would there their been has can if our all what when"),
+	"de": wordSet("der die das und ist nicht ich sie ein eine mit dem zu von auf für sich auch wir ihr bei nach wie aber oder wird sind noch werden haben hat diese ihnen uns"),
+	"fr": wordSet("le les et est une des du pour dans pas vous nous qui sur avec ce sont mais elle je au aux cette être ne par merci bonjour très"),
+	"es": wordSet("el los las y que del por para con una su lo al como más pero sus ya está muy también hay este esta gracias usted hola"),
+	"it": wordSet("il lo gli della che di è per non sono come ma anche questo questa ho più nel alla dei delle grazie ciao molto"),
+	"pt": wordSet("os não uma um com do da dos das em no na mas você são muito isso obrigado olá"),
+	"nl": wordSet("het een en van dat niet op te zijn voor ik je hij ze er maar ook als aan bij naar dit wordt heeft bedankt"),
+	"sv": wordSet("och att det som är på för jag inte till av har ett men vi så från kan eller hade tack"),
+	"pl": wordSet("nie się że jest jak ale co tak od już przez dla jego może są być czy dziękuję"),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// languageNames are the English names of the languages DetectLanguage
+// reports.
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"he": "Hebrew",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"nl": "Dutch",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ru": "Russian",
+	"sv": "Swedish",
+	"th": "Thai",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// LanguageName returns the English name of a language code reported by
+// DetectLanguage, or the code itself if it is not one.
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// DetectLanguage returns the ISO 639-1 code of the language text is
+// written in, or "" if the text is too short or too mixed to tell.
+func DetectLanguage(text string) string {
+	if len(text) > maxLanguageSample {
+		text = strings.ToValidUTF8(text[:maxLanguageSample], "")
+	}
+
+	var letters, latin, cyrillic, ukrainian, greek, arabic, hebrew, cjk, kana, hangul, thai int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				ukrainian++
+			}
+		case unicode.Is(unicode.Greek, r):
+			greek++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.Is(unicode.Hebrew, r):
+			hebrew++
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			kana++
+			cjk++
+		case unicode.Is(unicode.Han, r):
+			cjk++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Thai, r):
+			thai++
+		}
+	}
+	if letters < minLanguageLetters {
+		return ""
+	}
+
+	// A script other than Latin makes up most of the letters.
+	for _, s := range []struct {
+		count int
+		lang  string
+	}{
+		{cyrillic, "ru"}, {greek, "el"}, {arabic, "ar"}, {hebrew, "he"},
+		{cjk, "zh"}, {hangul, "ko"}, {thai, "th"},
+	} {
+		if s.count*2 <= letters {
+			continue
+		}
+		switch {
+		case s.lang == "ru" && ukrainian > 0:
+			return "uk"
+		case s.lang == "zh" && kana > 0:
+			return "ja"
+		}
+		return s.lang
+	}
+	if latin*2 <= letters {
+		return ""
+	}
+
+	hits := make(map[string]int)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, w := range words {
+		for lang, set := range stopwords {
+			if set[w] {
+				hits[lang]++
+			}
+		}
+	}
+	best, bestHits, runnerUp := "", 0, 0
+	for lang, n := range hits {
+		switch {
+		case n > bestHits:
+			best, bestHits, runnerUp = lang, n, bestHits
+		case n > runnerUp:
+			runnerUp = n
+		}
+	}
+	// A tie means the text is mixed, or in a language not listed.
+	if bestHits < minStopwordHits || bestHits == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,58 @@
+package mime
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "Hi Bob, thanks for the notes. I will send the report to you and the team by Friday, if that works for all of us.", "en"},
+		{"german", "Hallo Bob, vielen Dank für die Unterlagen. Ich schicke dir den Bericht bis Freitag, wenn das für euch auch passt.", "de"},
+		{"french", "Bonjour Bob, merci pour les notes. Je vous envoie le rapport avant vendredi, si cela vous convient.", "fr"},
+		{"spanish", "Hola Bob, gracias por las notas. Te envío el informe antes del viernes, si te parece bien a ti y al equipo.", "es"},
+		{"italian", "Ciao Bob, grazie per le note. Ti mando il rapporto entro venerdì, se questo va bene anche per il gruppo.", "it"},
+		{"portuguese", "Olá Bob, obrigado pelas notas. Vou enviar o relatório até sexta-feira, se isso estiver bem com você e com a equipe.", "pt"},
+		{"dutch", "Hallo Bob, bedankt voor de notities. Ik stuur je het rapport voor vrijdag, als dat voor het team ook goed is.", "nl"},
+		{"swedish", "Hej Bob, tack för anteckningarna. Jag skickar rapporten till dig på fredag, om det passar dig och teamet.", "sv"},
+		{"polish", "Cześć Bob, dziękuję za notatki. Wyślę raport jeszcze przed piątkiem, jeśli to jest dla ciebie w porządku, ale nie wiem czy zdążę.", "pl"},
+		{"russian", "Привет, Боб! Спасибо за заметки. Я пришлю отчёт до пятницы.", "ru"},
+		{"ukrainian", "Привіт, Бобе! Дякую за нотатки. Я надішлю звіт до п'ятниці.", "uk"},
+		{"greek", "Γεια σου Bob, ευχαριστώ για τις σημειώσεις. Θα στείλω την αναφορά.", "el"},
+		{"japanese", "ボブさん、メモをありがとうございます。金曜日までに報告書を送ります。", "ja"},
+		{"chinese", "鲍勃你好，谢谢你的笔记。我会在星期五之前把报告发给你和团队的所有人。", "zh"},
+		{"korean", "밥 안녕하세요, 메모 감사합니다. 금요일까지 보고서를 보내드리겠습니다.", "ko"},
+		{"too short", "Thanks!", ""},
+		{"no common words", "Invoice 4521 attached. Total: 300 EUR. Reference ABC-123 XYZ.", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectLanguage(tt.text); got != tt.want {
+				t.Errorf("DetectLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage_LongText(t *testing.T) {
+	// Only the start of a long body is examined; a cut through a
+	// multi-byte rune must not matter.
+	text := strings.Repeat("Ich habe die Unterlagen für dich. ", 400) + strings.Repeat("é", 10000)
+	if got := DetectLanguage(text); got != "de" {
+		t.Errorf("DetectLanguage() = %q, want %q", got, "de")
+	}
+}
+
+func TestLanguageName(t *testing.T) {
+	if got := LanguageName("de"); got != "German" {
+		t.Errorf("LanguageName(de) = %q, want German", got)
+	}
+	if got := LanguageName("xx"); got != "xx" {
+		t.Errorf("LanguageName(xx) = %q, want xx", got)
+	}
+}
@@ -0,0 +1,215 @@
+package mime
+
+import (
+	"bytes"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Kinds of Link.
+const (
+	LinkAnchor  = "link"    // an <a href> the reader can follow
+	LinkTracker = "tracker" // an image that reports the message was opened
+)
+
+// maxLinks caps the links recorded for one message, so a generated
+// body with thousands of anchors does not bloat the archive.
+const maxLinks = 500
+
+// Link is an outbound URL found in an HTML body.
+type Link struct {
+	URL    string
+	Domain string // lowercased host, without "www."
+	Kind   string // LinkAnchor or LinkTracker
+}
+
+// knownTrackers lists well-known open-tracking services. An image
+// whose host is the domain (or a subdomain of it) and whose path
+// starts with the prefix is a tracker whatever its size.
+var knownTrackers = []struct{ domain, pathPrefix string }{
+	{"mailtrack.io", ""},
+	{"awstrack.me", ""},
+	{"getnotify.com", ""},
+	{"yesware.com", ""},
+	{"google-analytics.com", "/collect"},
+	{"sendgrid.net", "/wf/open"},
+	{"list-manage.com", "/track/open"},
+	{"mandrillapp.com", "/track/open"},
+	{"exct.net", "/open.aspx"},
+}
+
+// ExtractLinks returns the http(s) links and tracking pixels in an HTML
+// body, in document order without duplicates. Ordinary images are not
+// links and are left out.
+func ExtractLinks(htmlBody string) []Link {
+	if htmlBody == "" {
+		return nil
+	}
+	var links []Link
+	seen := make(map[Link]bool)
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	for len(links) < maxLinks {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		t := z.Token()
+		var l Link
+		switch t.DataAtom {
+		case atom.A:
+			l = newLink(attr(t, "href"), LinkAnchor)
+		case atom.Img:
+			if u := attr(t, "src"); isTrackingPixel(t, u) {
+				l = newLink(u, LinkTracker)
+			}
+		}
+		if l.URL != "" && !seen[l] {
+			seen[l] = true
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// StripTrackers returns an HTML body with its tracking pixels removed,
+// so that rendering it does not report the message as opened. The rest
+// of the markup is passed through unchanged.
+func StripTrackers(htmlBody string) string {
+	var out bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			// Token() consumes the tokenizer's buffer; copy the raw
+			// bytes first.
+			raw = append([]byte(nil), raw...)
+			if t := z.Token(); t.DataAtom == atom.Img && isTrackingPixel(t, attr(t, "src")) {
+				continue
+			}
+		}
+		out.Write(raw)
+	}
+	return out.String()
+}
+
+// RenderHTML renders an HTML body as plain text, as StripHTML does, but
+// keeps each image as a "[image: alt]" placeholder. An image embedded
+// by a cid: URL without alt text is named from inline, which maps
+// Content-IDs to filenames. Tracking pixels are dropped unless
+// showTrackers is set, when they appear as "[tracker: domain]".
+func RenderHTML(htmlBody string, showTrackers bool, inline map[string]string) string {
+	var out bytes.Buffer
+	z := html.NewTokenizer(strings.NewReader(htmlBody))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := append([]byte(nil), z.Raw()...)
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			out.Write(raw)
+			continue
+		}
+		t := z.Token()
+		if t.DataAtom != atom.Img {
+			out.Write(raw)
+			continue
+		}
+		src := attr(t, "src")
+		if isTrackingPixel(t, src) {
+			if showTrackers {
+				out.WriteString(html.EscapeString("[tracker: " + newLink(src, LinkTracker).Domain + "]"))
+			}
+			continue
+		}
+		name := strings.TrimSpace(attr(t, "alt"))
+		if name == "" {
+			name = inline[ContentIDFromURL(src)]
+		}
+		if name != "" {
+			out.WriteString(html.EscapeString("[image: " + name + "]"))
+		} else {
+			out.WriteString("[image]")
+		}
+	}
+	return StripHTML(out.String())
+}
+
+// ContentIDFromURL returns the Content-ID a cid: URL refers to, or ""
+// if the URL is not a cid: URL.
+func ContentIDFromURL(u string) string {
+	u = strings.TrimSpace(u)
+	if len(u) < 4 || !strings.EqualFold(u[:4], "cid:") {
+		return ""
+	}
+	cid, err := url.PathUnescape(u[4:])
+	if err != nil {
+		cid = u[4:]
+	}
+	return strings.Trim(cid, "<>")
+}
+
+// newLink returns the link for an http(s) URL, or the zero Link for
+// anything else (mailto:, cid:, data:, relative paths).
+func newLink(raw, kind string) Link {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Link{}
+	}
+	domain := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	return Link{URL: u.String(), Domain: domain, Kind: kind}
+}
+
+// isTrackingPixel reports whether an <img> is an open tracker: served
+// by a known tracking service, or an http(s) image sized or styled to
+// be invisible.
+func isTrackingPixel(t html.Token, src string) bool {
+	u, err := url.Parse(strings.TrimSpace(src))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, kt := range knownTrackers {
+		if (host == kt.domain || strings.HasSuffix(host, "."+kt.domain)) &&
+			strings.HasPrefix(u.Path, kt.pathPrefix) {
+			return true
+		}
+	}
+
+	w, wok := pixelSize(attr(t, "width"))
+	h, hok := pixelSize(attr(t, "height"))
+	if wok && hok && w <= 1 && h <= 1 {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(t, "style")), " ", "")
+	return strings.Contains(style, "display:none") ||
+		(strings.Contains(style, "width:1px") && strings.Contains(style, "height:1px")) ||
+		(strings.Contains(style, "width:0") && strings.Contains(style, "height:0"))
+}
+
+// pixelSize parses a width or height attribute such as "1" or "1px".
+func pixelSize(v string) (int, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), "px"))
+	return n, err == nil
+}
+
+// attr returns the value of a token's attribute, or "".
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package mime
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []Link
+	}{
+		{
+			name: "anchors deduplicated in order",
+			html: `<p><a href="https://www.Example.com/a">A</a> <a href="mailto:bob@example.com">mail</a>
+				<a href="http://news.example.org/b?x=1">B</a><a href="https://www.Example.com/a">again</a>
+				<a href="/relative">rel</a><a href="cid:logo">cid</a></p>`,
+			want: []Link{
+				{URL: "https://www.Example.com/a", Domain: "example.com", Kind: LinkAnchor},
+				{URL: "http://news.example.org/b?x=1", Domain: "news.example.org", Kind: LinkAnchor},
+			},
+		},
+		{
+			name: "sized pixel",
+			html: `<img src="https://t.example.net/o.gif?id=7" width="1" height="1" alt="">`,
+			want: []Link{{URL: "https://t.example.net/o.gif?id=7", Domain: "t.example.net", Kind: LinkTracker}},
+		},
+		{
+			name: "hidden by style",
+			html: `<img src="https://pixel.example.com/p" style="display: none">`,
+			want: []Link{{URL: "https://pixel.example.com/p", Domain: "pixel.example.com", Kind: LinkTracker}},
+		},
+		{
+			name: "known tracking service at any size",
+			html: `<img src="https://u123.ct.sendgrid.net/wf/open?upn=abc" width="600">`,
+			want: []Link{{URL: "https://u123.ct.sendgrid.net/wf/open?upn=abc", Domain: "u123.ct.sendgrid.net", Kind: LinkTracker}},
+		},
+		{
+			name: "ordinary images are not links",
+			html: `<img src="https://cdn.example.com/banner.png" width="600" height="120">
+				<img src="https://u1.ct.sendgrid.net/logo.png"><img src="cid:logo" width="1" height="1">`,
+		},
+		{
+			name: "image inside an anchor",
+			html: `<a href="https://shop.example.com/"><img src="https://cdn.example.com/x.png"></a>`,
+			want: []Link{{URL: "https://shop.example.com/", Domain: "shop.example.com", Kind: LinkAnchor}},
+		},
+		{name: "empty", html: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractLinks(tt.html)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExtractLinks() = %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractLinks_Cap(t *testing.T) {
+	var b strings.Builder
+	for i := range maxLinks + 10 {
+		b.WriteString(`<a href="https://example.com/` + strings.Repeat("x", i+1) + `">x</a>`)
+	}
+	if got := len(ExtractLinks(b.String())); got != maxLinks {
+		t.Errorf("len(ExtractLinks) = %d, want %d", got, maxLinks)
+	}
+}
+
+func TestStripTrackers(t *testing.T) {
+	in := `<p>Hello <b>alice</b></p><img src="https://t.example.net/o.gif" width="1" height="1">` +
+		`<img src="https://cdn.example.com/logo.png" alt="Logo"><a href="https://example.com">link</a>`
+	want := `<p>Hello <b>alice</b></p><img src="https://cdn.example.com/logo.png" alt="Logo"><a href="https://example.com">link</a>`
+	if got := StripTrackers(in); got != want {
+		t.Errorf("StripTrackers() =\n%s\nwant\n%s", got, want)
+	}
+	if got := StripTrackers(want); got != want {
+		t.Errorf("StripTrackers changed markup without trackers:\n%s", got)
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	in := `<p>Sale &amp; more</p><img src="https://cdn.example.com/a.png" alt="Spring sale">` +
+		`<img src="https://cdn.example.com/b.png"><img src="https://mailtrack.io/trace/mail/abc.png">`
+	if got, want := RenderHTML(in, false, nil), "Sale & more\n[image: Spring sale][image]"; got != want {
+		t.Errorf("RenderHTML(hide) = %q, want %q", got, want)
+	}
+	if got, want := RenderHTML(in, true, nil), "Sale & more\n[image: Spring sale][image][tracker: mailtrack.io]"; got != want {
+		t.Errorf("RenderHTML(show) = %q, want %q", got, want)
+	}
+}
+
+func TestRenderHTML_InlineImages(t *testing.T) {
+	in := `<img src="cid:logo@example.com"><img src="CID:%3Cchart%3E" alt="Q3 chart"><img src="cid:missing">`
+	inline := map[string]string{"logo@example.com": "logo.png", "chart": "chart.png"}
+	if got, want := RenderHTML(in, false, inline), "[image: logo.png][image: Q3 chart][image]"; got != want {
+		t.Errorf("RenderHTML = %q, want %q", got, want)
+	}
+}
+
+func TestContentIDFromURL(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"cid:part1.abc@example.com", "part1.abc@example.com"},
+		{"CID:%3Cimg%40example.com%3E", "img@example.com"},
+		{" cid:logo ", "logo"},
+		{"https://example.com/a.png", ""},
+		{"cid", ""},
+	}
+	for _, tt := range tests {
+		if got := ContentIDFromURL(tt.in); got != tt.want {
+			t.Errorf("ContentIDFromURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
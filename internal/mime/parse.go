@@ -49,6 +49,27 @@ type Attachment struct {
 	IsInline    bool
 }
 
+// Embedded reports whether the part is an image shown within the HTML
+// body by its Content-ID, rather than a file attached to the message.
+// Embedded parts are not counted as attachments.
+func (a Attachment) Embedded() bool {
+	ct := strings.ToLower(strings.TrimSpace(a.ContentType))
+	return a.IsInline && a.ContentID != "" && strings.HasPrefix(ct, "image/")
+}
+
+// SplitEmbedded separates a message's attachments into the files
+// attached to it and the images embedded in its HTML body.
+func SplitEmbedded(atts []Attachment) (files, embedded []Attachment) {
+	for _, a := range atts {
+		if a.Embedded() {
+			embedded = append(embedded, a)
+		} else {
+			files = append(files, a)
+		}
+	}
+	return files, embedded
+}
+
 // Parse parses raw MIME data into a Message.
 func Parse(raw []byte) (*Message, error) {
 	env, err := enmime.ReadEnvelope(bytes.NewReader(raw))
@@ -89,6 +110,14 @@ func Parse(raw []byte) (*Message, error) {
 	// explicit Content-Disposition: attachment
 	msg.Attachments = append(msg.Attachments, processParts(env.Attachments, false)...)
 	msg.Attachments = append(msg.Attachments, processParts(env.Inlines, true)...)
+	// Parts of a multipart/related body with no Content-Disposition are
+	// neither; those with a Content-ID are images the HTML body shows
+	// by cid: reference.
+	for _, part := range env.OtherParts {
+		if part.ContentID != "" {
+			msg.Attachments = append(msg.Attachments, makeAttachment(part, true))
+		}
+	}
 
 	// Collect any parsing errors
 	for _, e := range env.Errors {
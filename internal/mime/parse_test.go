@@ -1,6 +1,7 @@
 package mime
 
 import (
+	"slices"
 	"testing"
 	"time"
 
@@ -504,3 +505,49 @@ func TestIsBodyPart_ContentTypeWithParams(t *testing.T) {
 		})
 	}
 }
+
+func TestParse_EmbeddedImages(t *testing.T) {
+	raw := "From: alice@example.com\r\n" +
+		"Content-Type: multipart/related; boundary=\"rel\"\r\n" +
+		"\r\n" +
+		"--rel\r\n" +
+		"Content-Type: text/html\r\n" +
+		"\r\n" +
+		"<img src=\"cid:a@example.com\"><img src=\"cid:b@example.com\">\r\n" +
+		"--rel\r\n" +
+		"Content-Type: image/png\r\n" +
+		"Content-ID: <a@example.com>\r\n" +
+		"\r\n" +
+		"png\r\n" +
+		"--rel\r\n" +
+		"Content-Type: image/gif\r\n" +
+		"Content-Disposition: inline; filename=\"b.gif\"\r\n" +
+		"Content-ID: <b@example.com>\r\n" +
+		"\r\n" +
+		"gif\r\n" +
+		"--rel\r\n" +
+		"Content-Type: application/pdf\r\n" +
+		"Content-Disposition: inline; filename=\"c.pdf\"\r\n" +
+		"Content-ID: <c@example.com>\r\n" +
+		"\r\n" +
+		"pdf\r\n" +
+		"--rel--\r\n"
+	msg, err := Parse([]byte(raw))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	files, embedded := SplitEmbedded(msg.Attachments)
+	var got []string
+	for _, a := range embedded {
+		got = append(got, a.ContentID)
+	}
+	slices.Sort(got)
+	if want := []string{"a@example.com", "b@example.com"}; !slices.Equal(got, want) {
+		t.Errorf("embedded Content-IDs = %v, want %v", got, want)
+	}
+	// An inline PDF is still a file the reader would save.
+	if len(files) != 1 || files[0].Filename != "c.pdf" {
+		t.Errorf("files = %+v, want c.pdf", files)
+	}
+}
@@ -0,0 +1,160 @@
+package mime
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reply is a message body split into what its author wrote and the
+// signature and quoted reply trail that follow it. Text, Signature,
+// and Quoted concatenated in that order give back the original body.
+type Reply struct {
+	Text      string
+	Signature string
+	Quoted    string
+}
+
+// maxSignatureLines caps how far above the quoted trail (or the end of
+// the body) a "-- " delimiter may sit and still start a signature, so
+// a "--" used as a divider in the middle of a long message is not
+// taken for one.
+const maxSignatureLines = 15
+
+var (
+	// reReplyHeader matches the attribution line a client writes above
+	// a quoted reply, such as "On Tue, 3 Mar 2026, Bob <bob@example.com>
+	// wrote:". Some clients wrap it, so the line may end before
+	// "wrote:"; see replyHeaderAt.
+	reReplyHeader = regexp.MustCompile(`^On\s.+\swrote:\s*$`)
+
+	// reOriginalMessage matches the separator after which the rest of
+	// the body is the earlier message, quoted without ">" prefixes.
+	reOriginalMessage = regexp.MustCompile(`^-{2,}\s*Original Message\s*-{2,}\s*$`)
+
+	// reOutlookRule is the rule Outlook draws above its reply header.
+	reOutlookRule = regexp.MustCompile(`^_{20,}\s*$`)
+
+	// reOutlookFrom matches the "From:" line of an Outlook reply header
+	// block, which must be followed by a "Sent:" or "Date:" line.
+	reOutlookFrom  = regexp.MustCompile(`^\*?From:\*?\s+\S`)
+	reOutlookSent  = regexp.MustCompile(`^\*?(?:Sent|Date):\*?\s+\S`)
+	reSigDelimiter = regexp.MustCompile(`^--[ \t]?$`)
+)
+
+// SplitReply separates the quoted reply trail and signature at the end
+// of a plain-text body from the author's own text. Only trailing blocks
+// are split off: quotes interleaved with new text, as in an inline
+// reply, stay in Text. A body that would leave no text of its own is
+// returned whole in Text.
+func SplitReply(body string) Reply {
+	lines := splitLinesKeepEnds(body)
+	offsets := make([]int, len(lines)+1)
+	for i, l := range lines {
+		offsets[i+1] = offsets[i] + len(l)
+	}
+
+	quoteStart := quotedTrailStart(lines)
+	sigStart := quoteStart
+	for i := quoteStart - 1; i >= 0 && quoteStart-i <= maxSignatureLines; i-- {
+		if reSigDelimiter.MatchString(trimLineEnd(lines[i])) {
+			sigStart = i
+			break
+		}
+	}
+	if isBlank(body[:offsets[sigStart]]) {
+		if isBlank(body[:offsets[quoteStart]]) {
+			return Reply{Text: body}
+		}
+		// A signature with nothing above it is the text itself.
+		sigStart = quoteStart
+	}
+	return Reply{
+		Text:      body[:offsets[sigStart]],
+		Signature: body[offsets[sigStart]:offsets[quoteStart]],
+		Quoted:    body[offsets[quoteStart]:],
+	}
+}
+
+// quotedTrailStart returns the index of the first line of the quoted
+// trail at the end of lines, or len(lines) if there is none.
+func quotedTrailStart(lines []string) int {
+	// An "Original Message" separator or Outlook header takes the rest
+	// of the body with it, quoted or not.
+	for i := range lines {
+		line := trimLineEnd(lines[i])
+		if reOriginalMessage.MatchString(line) {
+			return i
+		}
+		if reOutlookFrom.MatchString(line) && i+1 < len(lines) &&
+			reOutlookSent.MatchString(trimLineEnd(lines[i+1])) {
+			if i > 0 && reOutlookRule.MatchString(trimLineEnd(lines[i-1])) {
+				return i - 1
+			}
+			return i
+		}
+	}
+
+	// Otherwise the trail is the run of ">" and blank lines at the end,
+	// along with the attribution line above it.
+	start := len(lines)
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := trimLineEnd(lines[i])
+		if strings.HasPrefix(line, ">") {
+			start = i
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			break
+		}
+	}
+	if start == len(lines) {
+		return start
+	}
+	// Skip blank lines between the attribution and the quote.
+	i := start - 1
+	for i >= 0 && strings.TrimSpace(lines[i]) == "" {
+		i--
+	}
+	if h := replyHeaderAt(lines, i); h >= 0 {
+		return h
+	}
+	return start
+}
+
+// replyHeaderAt returns the first line of a reply attribution ending
+// at line i, which may be wrapped over two lines, or -1.
+func replyHeaderAt(lines []string, i int) int {
+	if i < 0 {
+		return -1
+	}
+	line := trimLineEnd(lines[i])
+	if reReplyHeader.MatchString(line) {
+		return i
+	}
+	if i > 0 && strings.HasSuffix(strings.TrimSpace(line), "wrote:") &&
+		reReplyHeader.MatchString(trimLineEnd(lines[i-1])+" "+strings.TrimSpace(line)) {
+		return i - 1
+	}
+	return -1
+}
+
+// splitLinesKeepEnds splits s after each "\n", keeping the newlines so
+// the lines concatenate back to s.
+func splitLinesKeepEnds(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func trimLineEnd(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
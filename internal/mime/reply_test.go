@@ -0,0 +1,94 @@
+package mime
+
+import "testing"
+
+func TestSplitReply(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Reply
+	}{
+		{
+			name: "no quote",
+			body: "Lunch at noon?\n",
+			want: Reply{Text: "Lunch at noon?\n"},
+		},
+		{
+			name: "top-posted reply",
+			body: "Sounds good.\n\nOn Tue, 3 Mar 2026 at 10:00, Bob <bob@example.com> wrote:\n> Lunch at noon?\n> Bob\n",
+			want: Reply{
+				Text:   "Sounds good.\n\n",
+				Quoted: "On Tue, 3 Mar 2026 at 10:00, Bob <bob@example.com> wrote:\n> Lunch at noon?\n> Bob\n",
+			},
+		},
+		{
+			name: "wrapped attribution",
+			body: "Yes.\r\nOn Tue, 3 Mar 2026 at 10:00, Bob <\r\nbob@example.com> wrote:\r\n\r\n>Lunch?\r\n",
+			want: Reply{
+				Text:   "Yes.\r\n",
+				Quoted: "On Tue, 3 Mar 2026 at 10:00, Bob <\r\nbob@example.com> wrote:\r\n\r\n>Lunch?\r\n",
+			},
+		},
+		{
+			name: "signature and quote",
+			body: "Sounds good.\n-- \nAlice\nExample Corp\n\nOn Tue, Bob wrote:\n> Lunch?\n",
+			want: Reply{
+				Text:      "Sounds good.\n",
+				Signature: "-- \nAlice\nExample Corp\n\n",
+				Quoted:    "On Tue, Bob wrote:\n> Lunch?\n",
+			},
+		},
+		{
+			name: "signature only",
+			body: "See you there.\n--\nAlice",
+			want: Reply{Text: "See you there.\n", Signature: "--\nAlice"},
+		},
+		{
+			name: "inline reply stays whole",
+			body: "> Lunch at noon?\nYes.\n> Where?\nThe usual place.\n",
+			want: Reply{Text: "> Lunch at noon?\nYes.\n> Where?\nThe usual place.\n"},
+		},
+		{
+			name: "bottom-posted reply keeps the quote above",
+			body: "> Lunch?\nYes.\n\n> Where?\n",
+			want: Reply{Text: "> Lunch?\nYes.\n\n", Quoted: "> Where?\n"},
+		},
+		{
+			name: "outlook header",
+			body: "Approved.\n\n________________________________\nFrom: Bob <bob@example.com>\nSent: Tuesday, March 3, 2026 10:00 AM\nSubject: Budget\n\nPlease approve.\n",
+			want: Reply{
+				Text:   "Approved.\n\n",
+				Quoted: "________________________________\nFrom: Bob <bob@example.com>\nSent: Tuesday, March 3, 2026 10:00 AM\nSubject: Budget\n\nPlease approve.\n",
+			},
+		},
+		{
+			name: "original message separator",
+			body: "Done.\n-----Original Message-----\nFrom: bob@example.com\nPlease do it.\n",
+			want: Reply{
+				Text:   "Done.\n",
+				Quoted: "-----Original Message-----\nFrom: bob@example.com\nPlease do it.\n",
+			},
+		},
+		{
+			name: "nothing but a quote",
+			body: "On Tue, Bob wrote:\n> Lunch?\n",
+			want: Reply{Text: "On Tue, Bob wrote:\n> Lunch?\n"},
+		},
+		{
+			name: "divider far above the end is not a signature",
+			body: "Intro\n--\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			want: Reply{Text: "Intro\n--\n1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitReply(tt.body)
+			if got != tt.want {
+				t.Errorf("SplitReply() = %+q, want %+q", got, tt.want)
+			}
+			if joined := got.Text + got.Signature + got.Quoted; joined != tt.body {
+				t.Errorf("parts join to %q, want the original body", joined)
+			}
+		})
+	}
+}
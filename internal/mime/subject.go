@@ -0,0 +1,57 @@
+package mime
+
+import (
+	"bytes"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// reSubjectPrefix matches one reply or forward prefix at the start of
+// a subject, as English and other mail clients write them: "Re:",
+// "RE[2]:", "Fwd:", "FW:", "AW:", "WG:", "SV:", "VS:", "Antw:",
+// "Rif:", "TR:", and "Réf:". A mailing list tag in brackets before the
+// prefix ("[dev] Re:") is matched along with it.
+var reSubjectPrefix = regexp.MustCompile(`(?i)^\s*(?:\[[^\]]*\]\s*)?(?:re|fwd?|aw|wg|sv|vs|antw|rif|tr|réf)\s*(?:\[\d+\]|\(\d+\))?\s*[:：]\s*`)
+
+// reForwardSuffix matches the "(fwd)" some clients append instead.
+var reForwardSuffix = regexp.MustCompile(`(?i)\s*\(fwd?\)\s*$`)
+
+// NormalizeSubject strips the chain of reply and forward prefixes from
+// a subject ("Re: Fwd: RE: Lunch" becomes "Lunch") and collapses runs
+// of whitespace, so the messages of one conversation share a title. A
+// subject that is nothing but prefixes normalizes to "".
+func NormalizeSubject(subject string) string {
+	s := subject
+	for {
+		loc := reSubjectPrefix.FindStringIndex(s)
+		if loc == nil {
+			break
+		}
+		s = s[loc[1]:]
+	}
+	s = reForwardSuffix.ReplaceAllString(s, "")
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// ThreadHeaders returns a raw message's Message-ID and the Message-IDs
+// it replies to, from its References and In-Reply-To headers, all
+// without angle brackets and references oldest first. Only the header
+// block is read.
+func ThreadHeaders(raw []byte) (messageID string, refs []string, err error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return "", nil, err
+	}
+	if ids := parseReferences(msg.Header.Get("Message-ID")); len(ids) > 0 {
+		messageID = ids[0]
+	}
+	refs = parseReferences(msg.Header.Get("References"))
+	for _, id := range parseReferences(msg.Header.Get("In-Reply-To")) {
+		if strings.Contains(id, "@") && !slices.Contains(refs, id) {
+			refs = append(refs, id)
+		}
+	}
+	return messageID, refs, nil
+}
@@ -0,0 +1,80 @@
+package mime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Lunch", "Lunch"},
+		{"Re: Lunch", "Lunch"},
+		{"RE: Fwd: re: Lunch", "Lunch"},
+		{"Re[2]: Lunch", "Lunch"},
+		{"Re(3): Lunch", "Lunch"},
+		{"FW: Lunch", "Lunch"},
+		{"AW: WG: Mittagessen", "Mittagessen"},
+		{"SV: Lunsj", "Lunsj"},
+		{"[dev] Re: Build broken", "Build broken"},
+		{"Lunch (fwd)", "Lunch"},
+		{"  Re:   Lunch   plans ", "Lunch plans"},
+		{"Re: ", ""},
+		{"Regarding: lunch", "Regarding: lunch"},
+		{"Fwd lunch", "Fwd lunch"},
+		{"Lunch re: Friday", "Lunch re: Friday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.subject, func(t *testing.T) {
+			if got := NormalizeSubject(tt.subject); got != tt.want {
+				t.Errorf("NormalizeSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThreadHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		raw    string
+		wantID string
+		want   []string
+	}{
+		{
+			name: "references and in-reply-to",
+			raw: "From: bob@example.com\r\n" +
+				"Message-ID: <c@example.com>\r\n" +
+				"References: <a@example.com>\r\n <b@example.com>\r\n" +
+				"In-Reply-To: <b@example.com>\r\n" +
+				"\r\nbody\r\n",
+			wantID: "c@example.com",
+			want:   []string{"a@example.com", "b@example.com"},
+		},
+		{
+			name: "in-reply-to only",
+			raw:  "From: bob@example.com\r\nIn-Reply-To: <c@example.com> (Alice's message)\r\n\r\nbody\r\n",
+			want: []string{"c@example.com"},
+		},
+		{
+			name: "no threading headers",
+			raw:  "From: bob@example.com\r\n\r\nbody\r\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, got, err := ThreadHeaders([]byte(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.wantID {
+				t.Errorf("ThreadHeaders() id = %q, want %q", id, tt.wantID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ThreadHeaders() refs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
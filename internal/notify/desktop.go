@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// desktop shows events as native desktop notifications through the
+// platform's command-line tool: notify-send on Linux and the BSDs,
+// osascript on macOS, and a PowerShell toast on Windows.
+type desktop struct {
+	run func(ctx context.Context, name string, args ...string) error
+}
+
+func (d *desktop) Notify(ctx context.Context, e Event) error {
+	name, args := desktopCommand(runtime.GOOS, e)
+	return d.run(ctx, name, args...)
+}
+
+// powershellAppID is the AppUserModelID toasts are shown under. Windows
+// drops toasts from unregistered IDs, so borrow PowerShell's own.
+const powershellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// desktopCommand returns the command that shows e on goos.
+func desktopCommand(goos string, e Event) (string, []string) {
+	title := "msgvault: " + e.Title
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s",
+			appleScriptString(e.Message), appleScriptString(title))
+		return "osascript", []string{"-e", script}
+	case "windows":
+		script := strings.Join([]string{
+			`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null`,
+			`$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)`,
+			`$x = $t.GetElementsByTagName('text')`,
+			`$x.Item(0).AppendChild($t.CreateTextNode(` + powershellString(title) + `)) > $null`,
+			`$x.Item(1).AppendChild($t.CreateTextNode(` + powershellString(e.Message) + `)) > $null`,
+			`[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier(` + powershellString(powershellAppID) +
+				`).Show([Windows.UI.Notifications.ToastNotification]::new($t))`,
+		}, "; ")
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}
+	default:
+		return "notify-send", []string{"--app-name=msgvault", title, e.Message}
+	}
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// powershellString quotes s as a single-quoted PowerShell literal, in
+// which only the quote itself needs escaping.
+func powershellString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func runCommand(ctx context.Context, name string, args ...string) error {
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
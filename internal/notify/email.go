@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+const defaultSMTPPort = 587
+
+// email sends events as plain-text mail over SMTP, upgrading to TLS
+// when the server offers STARTTLS.
+type email struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now  func() time.Time
+}
+
+func newEmail(c config.NotifierConfig) (*email, error) {
+	if c.SMTPHost == "" {
+		return nil, fmt.Errorf("%s: smtp_host is required", c.Name)
+	}
+	if c.From == "" || len(c.To) == 0 {
+		return nil, fmt.Errorf("%s: from and to are required", c.Name)
+	}
+	port := c.SMTPPort
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	e := &email{
+		addr: net.JoinHostPort(c.SMTPHost, strconv.Itoa(port)),
+		from: c.From,
+		to:   c.To,
+		send: smtp.SendMail,
+		now:  time.Now,
+	}
+	if c.SMTPUsername != "" {
+		e.auth = smtp.PlainAuth("", c.SMTPUsername, c.SMTPPassword, c.SMTPHost)
+	}
+	return e, nil
+}
+
+func (m *email) Notify(ctx context.Context, e Event) error {
+	// net/smtp has no context support; give up waiting on cancellation
+	// and let the send finish in the background.
+	done := make(chan error, 1)
+	go func() { done <- m.send(m.addr, m.auth, m.from, m.to, m.message(e)) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.Join(errors.New("smtp send abandoned"), ctx.Err())
+	}
+}
+
+// message formats e as an RFC 5322 message.
+func (m *email) message(e Event) []byte {
+	var b strings.Builder
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", m.from)
+	header("To", strings.Join(m.to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", oneLine("msgvault: "+e.Title)))
+	header("Date", m.now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("X-Msgvault-Event", e.Type)
+	b.WriteString("\r\n")
+	body := strings.ReplaceAll(e.Message, "\r\n", "\n")
+	b.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ntfy publishes events to an ntfy topic URL (https://ntfy.sh/<topic>
+// or a self-hosted server).
+type ntfy struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func (n *ntfy) Notify(ctx context.Context, e Event) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, strings.NewReader(e.Message))
+	if err != nil {
+		return err
+	}
+	// ntfy takes header values as-is, so keep the title on one line.
+	req.Header.Set("Title", oneLine("msgvault: "+e.Title))
+	req.Header.Set("Tags", e.Type)
+	if e.Type == EventSyncError {
+		req.Header.Set("Priority", "high")
+	}
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+	return post(n.client, req)
+}
+
+// slack posts events to a Slack incoming webhook.
+type slack struct {
+	client *http.Client
+	url    string
+}
+
+func (s *slack) Notify(ctx context.Context, e Event) error {
+	text := "*msgvault: " + e.Title + "*"
+	if e.Message != "" {
+		text += "\n" + e.Message
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return post(s.client, req)
+}
+
+// post sends req and treats any non-2xx response as an error.
+func post(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// oneLine collapses line breaks so s is safe in a header.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
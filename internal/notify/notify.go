@@ -0,0 +1,166 @@
+// Package notify sends short notifications about vault events to the
+// desktop, ntfy.sh, email, or Slack. Each configured notifier
+// subscribes to a set of event types; Send delivers an event to every
+// subscriber and logs failures rather than returning them, so a broken
+// notifier never fails the operation it reports on.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+// Event types a notifier can subscribe to.
+const (
+	EventSyncComplete = "sync_complete"
+	EventSyncError    = "sync_error"
+	EventRuleMatch    = "rule_match"
+	// EventReminderDue is sent when a local reminder on a message
+	// comes due.
+	EventReminderDue = "reminder_due"
+)
+
+// sendTimeout bounds one delivery so a hung endpoint can't stall the
+// sync that triggered it.
+const sendTimeout = 30 * time.Second
+
+// Event is one notification.
+type Event struct {
+	Type    string // one of the Event* constants
+	Title   string // one line, used as the subject or heading
+	Message string // body; may span several lines
+	Account string // account the event concerns, if any
+}
+
+// Notifier delivers events to one destination.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+type target struct {
+	name     string
+	events   []string // empty means all events
+	notifier Notifier
+}
+
+func (t target) wants(eventType string) bool {
+	return len(t.events) == 0 || slices.Contains(t.events, eventType)
+}
+
+// Dispatcher fans events out to the configured notifiers. A nil
+// *Dispatcher is valid and sends nothing.
+type Dispatcher struct {
+	targets []target
+	logger  *slog.Logger
+}
+
+// New validates the notifier configs and returns a dispatcher.
+func New(cfgs []config.NotifierConfig, logger *slog.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{logger: logger}
+	client := &http.Client{Timeout: sendTimeout}
+	names := make(map[string]bool)
+	for i, c := range cfgs {
+		n, err := newNotifier(c, client)
+		if err != nil {
+			return nil, fmt.Errorf("notifiers[%d]: %w", i, err)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("notifiers[%d]: duplicate name %q", i, c.Name)
+		}
+		names[c.Name] = true
+		d.targets = append(d.targets, target{name: c.Name, events: c.Events, notifier: n})
+	}
+	return d, nil
+}
+
+func newNotifier(c config.NotifierConfig, client *http.Client) (Notifier, error) {
+	if c.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	for _, ev := range c.Events {
+		if !slices.Contains(config.NotifyEvents, ev) {
+			return nil, fmt.Errorf("%s: unknown event %q", c.Name, ev)
+		}
+	}
+	switch c.Type {
+	case "desktop":
+		return &desktop{run: runCommand}, nil
+	case "ntfy":
+		if !isHTTPURL(c.URL) {
+			return nil, fmt.Errorf("%s: url must be an absolute http or https URL", c.Name)
+		}
+		return &ntfy{client: client, url: c.URL, token: c.Token}, nil
+	case "slack":
+		if !isHTTPURL(c.URL) {
+			return nil, fmt.Errorf("%s: url must be an absolute http or https URL", c.Name)
+		}
+		return &slack{client: client, url: c.URL}, nil
+	case "email":
+		return newEmail(c)
+	default:
+		return nil, fmt.Errorf("%s: unknown type %q", c.Name, c.Type)
+	}
+}
+
+// Len returns the number of configured notifiers.
+func (d *Dispatcher) Len() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.targets)
+}
+
+// Send delivers e to every notifier subscribed to its type. Failures
+// are logged.
+func (d *Dispatcher) Send(ctx context.Context, e Event) {
+	if d == nil {
+		return
+	}
+	for _, t := range d.targets {
+		if !t.wants(e.Type) {
+			continue
+		}
+		if err := d.deliver(ctx, t, e); err != nil {
+			d.logger.Warn("notify: delivery failed", "notifier", t.name, "event", e.Type, "error", err)
+		}
+	}
+}
+
+// Test sends e to the notifier called name, or to every notifier when
+// name is empty, ignoring event subscriptions. It returns the failures.
+func (d *Dispatcher) Test(ctx context.Context, name string, e Event) error {
+	if d == nil {
+		return errors.New("no notifiers configured")
+	}
+	var errs []error
+	found := false
+	for _, t := range d.targets {
+		if name != "" && t.name != name {
+			continue
+		}
+		found = true
+		if err := d.deliver(ctx, t, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.name, err))
+		}
+	}
+	if !found {
+		if name == "" {
+			return errors.New("no notifiers configured")
+		}
+		return fmt.Errorf("no notifier named %q", name)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, t target, e Event) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	return t.notifier.Notify(ctx, e)
+}
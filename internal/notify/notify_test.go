@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+)
+
+// recorder is a Notifier that remembers what it was sent.
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+	err    error
+}
+
+func (r *recorder) Notify(_ context.Context, e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return r.err
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfgs    []config.NotifierConfig
+		wantErr string
+	}{
+		{"desktop", []config.NotifierConfig{{Name: "laptop", Type: "desktop"}}, ""},
+		{"ntfy", []config.NotifierConfig{{Name: "phone", Type: "ntfy", URL: "https://ntfy.sh/vault"}}, ""},
+		{"missing name", []config.NotifierConfig{{Type: "desktop"}}, "name is required"},
+		{"unknown type", []config.NotifierConfig{{Name: "p", Type: "pager"}}, "unknown type"},
+		{"unknown event", []config.NotifierConfig{{Name: "p", Type: "desktop", Events: []string{"sync_done"}}}, "unknown event"},
+		{"slack without url", []config.NotifierConfig{{Name: "s", Type: "slack"}}, "url must be"},
+		{"email without host", []config.NotifierConfig{{Name: "m", Type: "email", From: "a@example.com", To: []string{"b@example.com"}}}, "smtp_host"},
+		{"duplicate", []config.NotifierConfig{{Name: "a", Type: "desktop"}, {Name: "a", Type: "desktop"}}, "duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfgs, discardLogger())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSend_FiltersByEvent(t *testing.T) {
+	all, errorsOnly := &recorder{}, &recorder{err: errors.New("unreachable")}
+	d := &Dispatcher{logger: discardLogger(), targets: []target{
+		{name: "all", notifier: all},
+		{name: "errors", events: []string{EventSyncError}, notifier: errorsOnly},
+	}}
+
+	d.Send(context.Background(), Event{Type: EventSyncComplete, Title: "done"})
+	d.Send(context.Background(), Event{Type: EventSyncError, Title: "failed"})
+
+	if len(all.events) != 2 {
+		t.Errorf("all-events notifier got %d events, want 2", len(all.events))
+	}
+	if len(errorsOnly.events) != 1 || errorsOnly.events[0].Type != EventSyncError {
+		t.Errorf("errors-only notifier got %+v", errorsOnly.events)
+	}
+}
+
+func TestSend_NilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	d.Send(context.Background(), Event{Type: EventSyncComplete})
+	if d.Len() != 0 {
+		t.Errorf("Len() = %d", d.Len())
+	}
+}
+
+func TestTest(t *testing.T) {
+	a, b := &recorder{}, &recorder{err: errors.New("boom")}
+	d := &Dispatcher{logger: discardLogger(), targets: []target{
+		{name: "a", events: []string{EventRuleMatch}, notifier: a},
+		{name: "b", notifier: b},
+	}}
+	e := Event{Type: "test", Title: "Test notification"}
+
+	if err := d.Test(context.Background(), "a", e); err != nil {
+		t.Errorf("Test(a) error = %v", err)
+	}
+	if len(a.events) != 1 || len(b.events) != 0 {
+		t.Errorf("Test(a) reached a=%d b=%d, want 1, 0", len(a.events), len(b.events))
+	}
+	if err := d.Test(context.Background(), "", e); err == nil || !strings.Contains(err.Error(), "b: boom") {
+		t.Errorf("Test(all) error = %v, want b's failure", err)
+	}
+	if err := d.Test(context.Background(), "c", e); err == nil || !strings.Contains(err.Error(), `no notifier named "c"`) {
+		t.Errorf("Test(c) error = %v", err)
+	}
+}
+
+func TestNtfy(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		got, body = r, string(b)
+	}))
+	defer srv.Close()
+
+	n := &ntfy{client: srv.Client(), url: srv.URL + "/vault", token: "tk"}
+	err := n.Notify(context.Background(), Event{
+		Type: EventSyncError, Title: "Sync of alice@example.com\nfailed", Message: "token expired",
+	})
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if got.URL.Path != "/vault" || body != "token expired" {
+		t.Errorf("request = %s %q", got.URL.Path, body)
+	}
+	if h := got.Header.Get("Title"); h != "msgvault: Sync of alice@example.com failed" {
+		t.Errorf("Title = %q", h)
+	}
+	if got.Header.Get("Priority") != "high" || got.Header.Get("Authorization") != "Bearer tk" {
+		t.Errorf("headers = %v", got.Header)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	var payload map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	s := &slack{client: srv.Client(), url: srv.URL}
+	if err := s.Notify(context.Background(), Event{Title: "Rule receipts matched 2 messages", Message: "Your receipt"}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if want := "*msgvault: Rule receipts matched 2 messages*\nYour receipt"; payload["text"] != want {
+		t.Errorf("text = %q, want %q", payload["text"], want)
+	}
+}
+
+func TestHTTP_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	s := &slack{client: srv.Client(), url: srv.URL}
+	if err := s.Notify(context.Background(), Event{Title: "x"}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Notify() error = %v, want 403", err)
+	}
+}
+
+func TestEmail(t *testing.T) {
+	m, err := newEmail(config.NotifierConfig{
+		Name: "mail", Type: "email", SMTPHost: "smtp.example.com",
+		SMTPUsername: "vault", SMTPPassword: "pw",
+		From: "vault@example.com", To: []string{"alice@example.com", "bob@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	m.send = func(addr string, _ smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+	m.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := m.Notify(context.Background(), Event{
+		Type: EventSyncComplete, Title: "Synced alice@example.com", Message: "12 new messages\nin 3s",
+	}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if gotAddr != "smtp.example.com:587" || gotFrom != "vault@example.com" || len(gotTo) != 2 {
+		t.Errorf("send(%s, %s, %v)", gotAddr, gotFrom, gotTo)
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{
+		"To: alice@example.com, bob@example.com\r\n",
+		"Subject: msgvault: Synced alice@example.com\r\n",
+		"Date: Tue, 02 Jan 2024 03:04:05 +0000\r\n",
+		"\r\n\r\n12 new messages\r\nin 3s\r\n",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestDesktopCommand(t *testing.T) {
+	e := Event{Title: `Rule "news"`, Message: "it's here"}
+	tests := []struct {
+		goos     string
+		wantName string
+		wantArg  string
+	}{
+		{"linux", "notify-send", `msgvault: Rule "news"`},
+		{"darwin", "osascript", `display notification "it's here" with title "msgvault: Rule \"news\""`},
+		{"windows", "powershell", `CreateTextNode('it''s here')`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			name, args := desktopCommand(tt.goos, e)
+			if name != tt.wantName {
+				t.Errorf("command = %s, want %s", name, tt.wantName)
+			}
+			if !strings.Contains(strings.Join(args, "\x00"), tt.wantArg) {
+				t.Errorf("args %q missing %q", args, tt.wantArg)
+			}
+		})
+	}
+}
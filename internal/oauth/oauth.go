@@ -16,6 +16,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,6 +31,15 @@ var Scopes = []string{
 	"https://www.googleapis.com/auth/gmail.modify",
 }
 
+// ScopeStorageQuota lets msgvault read the account's storage quota from
+// the Drive API. It grants access to an app-private Drive folder only,
+// which msgvault never uses, and no access to the user's files.
+const ScopeStorageQuota = "https://www.googleapis.com/auth/drive.appdata"
+
+// ScopesStorageQuota is Scopes plus ScopeStorageQuota, requested when
+// storage quota tracking is on.
+var ScopesStorageQuota = append(slices.Clone(Scopes), ScopeStorageQuota)
+
 // ScopesDeletion includes full access required for batchDelete API.
 // gmail.modify supports trash/untrash but NOT batchDelete.
 var ScopesDeletion = []string{
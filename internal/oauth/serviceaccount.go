@@ -4,8 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"runtime"
 
+	"github.com/wesm/msgvault/internal/fileutil"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 )
@@ -23,12 +23,12 @@ func NewServiceAccountManager(keyPath string, scopes []string) (*ServiceAccountM
 	if len(scopes) == 0 {
 		return nil, fmt.Errorf("service account requires at least one scope")
 	}
-	if runtime.GOOS != "windows" {
+	if fileutil.ModeBitsEnforced {
 		info, err := os.Stat(keyPath)
 		if err != nil {
 			return nil, fmt.Errorf("read service account key: %w", err)
 		}
-		if info.Mode().Perm()&0o077 != 0 {
+		if fileutil.ExposedTo(info, 0o077) {
 			return nil, fmt.Errorf(
 				"service account key permissions for %s are too open (%04o); use chmod 600 %s",
 				keyPath, info.Mode().Perm(), keyPath,
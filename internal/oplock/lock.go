@@ -0,0 +1,182 @@
+// Package oplock serializes operations that must not overlap on one
+// vault, such as two syncs writing the same account or a deduplicate
+// pass racing a sync. The lock is an OS file lock on msgvault.lock in
+// the data directory, so it is released automatically if the holder
+// crashes. The file also records who holds it, for error messages.
+package oplock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// FileName is the lock file created in the data directory.
+const FileName = "msgvault.lock"
+
+// DaemonFileName is the lock file a scheduling daemon holds for as
+// long as it runs, so only one daemon schedules a vault's syncs. It is
+// separate from FileName, which each scheduled sync still takes.
+const DaemonFileName = "daemon.lock"
+
+// pollInterval is how often Acquire retries a busy lock.
+var pollInterval = 500 * time.Millisecond
+
+// ErrBusy is matched (with errors.Is) by the error returned when
+// another operation holds the lock.
+var ErrBusy = errors.New("vault is busy")
+
+// Holder describes the operation holding the lock.
+type Holder struct {
+	Operation string    `json:"operation"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+}
+
+func (h Holder) String() string {
+	return fmt.Sprintf("%s (pid %d, running for %s)",
+		h.Operation, h.PID, time.Since(h.Started).Round(time.Second))
+}
+
+// BusyError reports the operation holding the lock. Holder is nil when
+// the holder did not record itself (it may have just taken the lock).
+type BusyError struct {
+	Holder *Holder
+}
+
+func (e *BusyError) Error() string {
+	if e.Holder == nil {
+		return "another msgvault operation is running on this vault"
+	}
+	return "another msgvault operation is running on this vault: " + e.Holder.String()
+}
+
+func (e *BusyError) Unwrap() error { return ErrBusy }
+
+// Lock is a held operation lock.
+type Lock struct {
+	f *os.File
+}
+
+// TryAcquire takes the lock for dir on behalf of operation, failing
+// with a *BusyError if another operation holds it.
+func TryAcquire(dir, operation string) (*Lock, error) {
+	return TryAcquireFile(dir, FileName, operation)
+}
+
+// TryAcquireFile is TryAcquire for the lock file name in dir instead
+// of FileName.
+func TryAcquireFile(dir, name, operation string) (*Lock, error) {
+	if err := fileutil.SecureMkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	ok, err := tryLock(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	if !ok {
+		_ = f.Close()
+		return nil, &BusyError{Holder: readHolder(path)}
+	}
+
+	// Record the holder. A failure here only costs the nicer message.
+	data, _ := json.Marshal(Holder{Operation: operation, PID: os.Getpid(), Started: time.Now()})
+	if err := f.Truncate(0); err == nil {
+		_, _ = f.WriteAt(append(data, '\n'), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+// Acquire takes the lock for dir, waiting for the current holder to
+// finish. onWait, if non-nil, is called once with the holder when the
+// lock is busy. It returns ctx's error if ctx ends first.
+func Acquire(ctx context.Context, dir, operation string, onWait func(*BusyError)) (*Lock, error) {
+	notified := false
+	for {
+		l, err := TryAcquire(dir, operation)
+		var busy *BusyError
+		if !errors.As(err, &busy) {
+			return l, err
+		}
+		if !notified && onWait != nil {
+			onWait(busy)
+			notified = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release clears the holder record and unlocks. It is safe to call on
+// a nil Lock and more than once.
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	_ = l.f.Truncate(0)
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
+
+// Current returns the operation holding the lock for dir, or nil when
+// the vault is idle. It only probes the lock, never recording itself
+// as a holder.
+func Current(dir string) (*Holder, error) {
+	return CurrentFile(dir, FileName)
+}
+
+// CurrentFile is Current for the lock file name in dir instead of
+// FileName.
+func CurrentFile(dir, name string) (*Holder, error) {
+	path := filepath.Join(dir, name)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	ok, err := tryLock(f)
+	if err != nil {
+		return nil, fmt.Errorf("lock %s: %w", path, err)
+	}
+	if ok {
+		return nil, unlock(f)
+	}
+	if h := readHolder(path); h != nil {
+		return h, nil
+	}
+	return &Holder{Operation: "unknown"}, nil
+}
+
+func readHolder(path string) *Holder {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	var h Holder
+	if json.Unmarshal(data, &h) != nil {
+		return nil
+	}
+	return &h
+}
@@ -0,0 +1,129 @@
+package oplock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTryAcquire(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := TryAcquire(dir, "sync alice@example.com")
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+
+	_, err = TryAcquire(dir, "deduplicate")
+	var busy *BusyError
+	if !errors.As(err, &busy) || !errors.Is(err, ErrBusy) {
+		t.Fatalf("second TryAcquire() error = %v, want BusyError", err)
+	}
+	if busy.Holder == nil || busy.Holder.Operation != "sync alice@example.com" {
+		t.Errorf("holder = %+v, want the sync", busy.Holder)
+	}
+
+	h, err := Current(dir)
+	if err != nil || h == nil || h.Operation != "sync alice@example.com" {
+		t.Errorf("Current() = %+v, %v; want the sync", h, err)
+	}
+
+	if err := l.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := l.Release(); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+	if h, err := Current(dir); h != nil || err != nil {
+		t.Errorf("Current() after release = %+v, %v; want idle", h, err)
+	}
+
+	l2, err := TryAcquire(dir, "deduplicate")
+	if err != nil {
+		t.Fatalf("TryAcquire() after release error = %v", err)
+	}
+	_ = l2.Release()
+}
+
+func TestCurrent_NoLockFile(t *testing.T) {
+	if h, err := Current(t.TempDir()); h != nil || err != nil {
+		t.Errorf("Current() = %+v, %v; want idle", h, err)
+	}
+}
+
+func TestAcquire_Waits(t *testing.T) {
+	saved := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = saved }()
+
+	dir := t.TempDir()
+	held, err := TryAcquire(dir, "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waited := make(chan *BusyError, 1)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = held.Release()
+	}()
+	l, err := Acquire(context.Background(), dir, "rebuild-fts", func(b *BusyError) { waited <- b })
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer func() { _ = l.Release() }()
+
+	select {
+	case b := <-waited:
+		if b.Holder == nil || b.Holder.Operation != "sync" {
+			t.Errorf("onWait holder = %+v, want sync", b.Holder)
+		}
+	default:
+		t.Error("onWait was not called")
+	}
+}
+
+func TestAcquire_ContextCancelled(t *testing.T) {
+	saved := pollInterval
+	pollInterval = 10 * time.Millisecond
+	defer func() { pollInterval = saved }()
+
+	dir := t.TempDir()
+	held, err := TryAcquire(dir, "sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = held.Release() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := Acquire(ctx, dir, "deduplicate", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Acquire() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestTryAcquireFile_Independent(t *testing.T) {
+	dir := t.TempDir()
+
+	daemon, err := TryAcquireFile(dir, DaemonFileName, "daemon")
+	if err != nil {
+		t.Fatalf("TryAcquireFile() error = %v", err)
+	}
+	defer func() { _ = daemon.Release() }()
+
+	// The daemon lock does not block operations.
+	op, err := TryAcquire(dir, "sync alice@example.com")
+	if err != nil {
+		t.Fatalf("TryAcquire() while the daemon lock is held: %v", err)
+	}
+	_ = op.Release()
+
+	if _, err := TryAcquireFile(dir, DaemonFileName, "daemon"); !errors.Is(err, ErrBusy) {
+		t.Errorf("second daemon TryAcquireFile() error = %v, want ErrBusy", err)
+	}
+	h, err := CurrentFile(dir, DaemonFileName)
+	if err != nil || h == nil || h.Operation != "daemon" {
+		t.Errorf("CurrentFile() = %+v, %v; want the daemon", h, err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"time"
 
 	_ "github.com/marcboeker/go-duckdb"
+	"github.com/wesm/msgvault/internal/risk"
 	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -152,6 +153,7 @@ func NewDuckDBEngine(analyticsDir string, sqlitePath string, sqliteDB *sql.DB, o
 		{"messages", "attachment_count"},
 		{"messages", "sender_id"},
 		{"messages", "message_type"},
+		{"messages", "language"},
 		{"conversations", "title"},
 		{"conversations", "conversation_type"},
 		{"sources", "source_type"},
@@ -267,8 +269,8 @@ func (e *DuckDBEngine) hasCol(table, col string) bool {
 // integer/boolean columns as VARCHAR, causing type mismatch errors in JOINs
 // and COALESCE expressions.
 //
-// Optional columns (phone_number, attachment_count, sender_id, message_type)
-// are handled gracefully: if the Parquet file predates their addition, they
+// Optional columns (phone_number, attachment_count, sender_id, message_type,
+// language) are handled gracefully: if the Parquet file predates their addition, they
 // are synthesised with sensible defaults instead of causing a binder error.
 func (e *DuckDBEngine) parquetCTEs() string {
 	// --- messages CTE ---
@@ -298,6 +300,11 @@ func (e *DuckDBEngine) parquetCTEs() string {
 	} else {
 		msgExtra = append(msgExtra, "'' AS message_type")
 	}
+	if e.hasCol("messages", "language") {
+		msgReplace = append(msgReplace, "CAST(language AS VARCHAR) AS language")
+	} else {
+		msgExtra = append(msgExtra, "NULL::VARCHAR AS language")
+	}
 	if e.hasCol("messages", "deleted_at") {
 		msgReplace = append(msgReplace, "TRY_CAST(deleted_at AS TIMESTAMP) AS deleted_at")
 	} else {
@@ -424,6 +431,12 @@ func escapeILIKE(s string) string {
 	return s
 }
 
+// duckdbLabelLike is the case-insensitive LIKE that label: patterns
+// (search.LabelPattern) are matched with.
+func duckdbLabelLike(col string) string {
+	return col + ` ILIKE ? ESCAPE '\'`
+}
+
 // buildWhereClause builds WHERE conditions for Parquet queries.
 // Column references use msg. prefix to be explicit since aggregate queries join multiple CTEs.
 // buildAggregateSearchConditions builds SQL conditions for a search query in aggregate views.
@@ -511,6 +524,9 @@ func (e *DuckDBEngine) buildNonTextSearchConditions(q *search.Query, keyColumns
 		args = append(args, toPattern)
 	}
 
+	// cc: and bcc: filters - match recipients of that type only
+	conditions, args = appendRecipientTypeFilters(conditions, args, q)
+
 	// subject: filter
 	for _, subj := range q.SubjectTerms {
 		subjPattern := "%" + escapeILIKE(subj) + "%"
@@ -518,7 +534,8 @@ func (e *DuckDBEngine) buildNonTextSearchConditions(q *search.Query, keyColumns
 		args = append(args, subjPattern)
 	}
 
-	// label: filter - case-insensitive substring match.
+	// label: filter - case-insensitive substring or hierarchy match
+	// (search.LabelPattern).
 	// In the Labels aggregate view (keyColumns includes the label column),
 	// filter the grouping column directly so only matching labels appear
 	// in results — not all labels from matching messages.
@@ -535,20 +552,22 @@ func (e *DuckDBEngine) buildNonTextSearchConditions(q *search.Query, keyColumns
 		// Use OR so label:arrow label:inbox shows both matching labels.
 		var labelParts []string
 		for _, label := range q.Labels {
-			labelParts = append(labelParts, labelKeyCol+` ILIKE ? ESCAPE '\'`)
-			args = append(args, "%"+escapeILIKE(label)+"%")
+			cond, condArgs := search.ParseLabelPattern(label).SQL(labelKeyCol, duckdbLabelLike)
+			labelParts = append(labelParts, cond)
+			args = append(args, condArgs...)
 		}
 		conditions = append(conditions, "("+strings.Join(labelParts, " OR ")+")")
 	} else {
 		// Non-label views: use EXISTS to filter messages by label.
 		for _, label := range q.Labels {
+			cond, condArgs := search.ParseLabelPattern(label).SQL("l_label.name", duckdbLabelLike)
 			conditions = append(conditions, `EXISTS (
 				SELECT 1 FROM ml ml_label
 				JOIN lbl l_label ON l_label.id = ml_label.label_id
 				WHERE ml_label.message_id = msg.id
-				  AND l_label.name ILIKE ? ESCAPE '\'
+				  AND `+cond+`
 			)`)
-			args = append(args, "%"+escapeILIKE(label)+"%")
+			args = append(args, condArgs...)
 		}
 	}
 
@@ -556,6 +575,17 @@ func (e *DuckDBEngine) buildNonTextSearchConditions(q *search.Query, keyColumns
 	if q.HasAttachment != nil && *q.HasAttachment {
 		conditions = append(conditions, "msg.has_attachments = 1")
 	}
+	conditions, args = appendSystemLabelFilters(conditions, args, q)
+	conditions, args = e.appendFilenameFilters(conditions, args, q)
+	if q.DKIMPass {
+		conditions = append(conditions, e.dkimPassCondition("msg"))
+	}
+	if q.Suspicious {
+		conditions = append(conditions, e.suspiciousCondition("msg"))
+	}
+	conditions, args = e.appendAnnotationFilters(conditions, args, "msg", q)
+	conditions, args = e.appendRegexFilters(conditions, args, "msg", q)
+	conditions, args = appendLanguageFilter(conditions, args, "msg.", q.Languages)
 
 	// Date filters from search query
 	if q.AfterDate != nil {
@@ -577,14 +607,218 @@ func (e *DuckDBEngine) buildNonTextSearchConditions(q *search.Query, keyColumns
 		args = append(args, *q.SmallerThan)
 	}
 
+	// OR and NOT parts. Their text terms search subject, snippet and
+	// sender whatever the view, as the grouping key cannot be negated
+	// row by row.
+	for _, alts := range q.Or {
+		parts := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			cond, condArgs := e.nestedSearchCondition(alt)
+			parts = append(parts, cond)
+			args = append(args, condArgs...)
+		}
+		conditions = append(conditions, "("+strings.Join(parts, " OR ")+")")
+	}
+	for _, neg := range q.Not {
+		cond, condArgs := e.nestedSearchCondition(neg)
+		conditions = append(conditions, "NOT "+cond)
+		args = append(args, condArgs...)
+	}
+
 	return conditions, args
 }
 
+// nestedSearchCondition builds one condition matching the messages an
+// OR alternative or negated part of an aggregate search matches.
+func (e *DuckDBEngine) nestedSearchCondition(q *search.Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	for _, term := range q.TextTerms {
+		termPattern := "%" + escapeILIKE(term) + "%"
+		conditions = append(conditions, `(
+			msg.subject ILIKE ? ESCAPE '\' OR
+			COALESCE(msg.snippet, '') ILIKE ? ESCAPE '\' OR
+			EXISTS (
+				SELECT 1 FROM mr mr_search
+				JOIN p p_search ON p_search.id = mr_search.participant_id
+				WHERE mr_search.message_id = msg.id
+				  AND mr_search.recipient_type = 'from'
+				  AND (p_search.email_address ILIKE ? ESCAPE '\' OR COALESCE(p_search.display_name, '') ILIKE ? ESCAPE '\')
+			)
+		)`)
+		args = append(args, termPattern, termPattern, termPattern, termPattern)
+	}
+	nonTextConds, nonTextArgs := e.buildNonTextSearchConditions(q)
+	conditions = append(conditions, nonTextConds...)
+	args = append(args, nonTextArgs...)
+	return andConditions(conditions), args
+}
+
 // buildWhereClause builds WHERE conditions for aggregate queries.
 // buildStatsSearchConditions builds search conditions for GetTotalStats.
 // For 1:N views (Recipients, RecipientNames, Labels), text terms filter via
 // EXISTS subqueries on the grouping dimension so stats match visible rows.
 // For 1:1 views, falls back to the default subject+sender search.
+// appendRecipientTypeFilters adds the cc: and bcc: filters of q, each
+// matching recipients of that type only, unlike to:, which matches any.
+func appendRecipientTypeFilters(conditions []string, args []interface{}, q *search.Query) ([]string, []interface{}) {
+	for _, rt := range []struct {
+		typ   string
+		addrs []string
+	}{{"cc", q.CcAddrs}, {"bcc", q.BccAddrs}} {
+		for _, addr := range rt.addrs {
+			conditions = append(conditions, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM mr mr_rt
+				JOIN p p_rt ON p_rt.id = mr_rt.participant_id
+				WHERE mr_rt.message_id = msg.id
+				  AND mr_rt.recipient_type = '%s'
+				  AND p_rt.email_address ILIKE ? ESCAPE '\'
+			)`, rt.typ))
+			args = append(args, "%"+escapeILIKE(addr)+"%")
+		}
+	}
+	return conditions, args
+}
+
+// appendSystemLabelFilters adds the is:unread, is:starred and
+// is:important filters of q, matched on the exact system label name in
+// the Parquet cache.
+func appendSystemLabelFilters(conditions []string, args []interface{}, q *search.Query) ([]string, []interface{}) {
+	for _, name := range q.SystemLabels {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM ml ml_sys
+			JOIN lbl l_sys ON l_sys.id = ml_sys.label_id
+			WHERE ml_sys.message_id = msg.id AND l_sys.name = ?
+		)`)
+		args = append(args, name)
+	}
+	return conditions, args
+}
+
+// appendFilenameFilters adds the filename: filters of q, read from the
+// attachments in the Parquet cache.
+func (e *DuckDBEngine) appendFilenameFilters(conditions []string, args []interface{}, q *search.Query) ([]string, []interface{}) {
+	for _, name := range q.Filenames {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM read_parquet('%s') a
+			WHERE CAST(a.message_id AS BIGINT) = msg.id AND a.filename ILIKE ? ESCAPE '\'
+		)`, e.parquetPath("attachments")))
+		args = append(args, "%"+escapeILIKE(name)+"%")
+	}
+	return conditions, args
+}
+
+// dkimPassCondition matches messages whose DKIM signature verified at
+// ingest. Authentication results are kept only in SQLite, not the
+// Parquet cache, so without SQLite attached nothing matches.
+func (e *DuckDBEngine) dkimPassCondition(alias string) string {
+	if !e.hasSQLite() {
+		return "FALSE"
+	}
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM sqlite_db.message_auth ma
+		WHERE ma.message_id = %s.id AND ma.dkim_result = 'pass'
+	)`, alias)
+}
+
+// suspiciousCondition matches messages 'msgvault risk scan' scored as
+// likely spam or phishing. Like authentication results, risk scores
+// are kept only in SQLite.
+func (e *DuckDBEngine) suspiciousCondition(alias string) string {
+	if !e.hasSQLite() {
+		return "FALSE"
+	}
+	return fmt.Sprintf(`EXISTS (
+		SELECT 1 FROM sqlite_db.message_risk mk
+		WHERE mk.message_id = %s.id AND mk.score >= %d
+	)`, alias, risk.SuspiciousScore)
+}
+
+// appendAnnotationFilters adds the is:pinned, is:due, note:, tag:,
+// list:, deliveredto: and in:attachments filters of q. Local notes,
+// reminders and tags, indexed headers, and attachment text are kept
+// only in SQLite too.
+func (e *DuckDBEngine) appendAnnotationFilters(conditions []string, args []interface{}, alias string, q *search.Query) ([]string, []interface{}) {
+	headers := q.HeaderFilters()
+	if !q.Pinned && !q.ReminderDue && len(q.NoteTerms) == 0 && len(q.Tags) == 0 && len(headers) == 0 &&
+		len(q.AttachmentTerms) == 0 {
+		return conditions, args
+	}
+	if !e.hasSQLite() {
+		return append(conditions, "FALSE"), args
+	}
+	if q.Pinned {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_notes mn
+			WHERE mn.message_id = %s.id AND mn.pinned = 1
+		)`, alias))
+	}
+	if q.ReminderDue {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_reminders mrd
+			WHERE mrd.message_id = %s.id AND mrd.due_at <= CAST(? AS TIMESTAMP)
+		)`, alias))
+		args = append(args, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	}
+	for _, term := range q.NoteTerms {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_notes mn
+			WHERE mn.message_id = %s.id AND mn.note ILIKE ? ESCAPE '\'
+		)`, alias))
+		args = append(args, "%"+escapeILIKE(term)+"%")
+	}
+	for _, tag := range q.Tags {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_tags mt
+			WHERE mt.message_id = %s.id AND mt.tag = ?
+		)`, alias))
+		args = append(args, tag)
+	}
+	for _, h := range headers {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_headers mh
+			WHERE mh.message_id = %s.id AND mh.name = ? AND mh.value ILIKE ? ESCAPE '\'
+		)`, alias))
+		args = append(args, h.Name, "%"+escapeILIKE(h.Value)+"%")
+	}
+	if len(q.AttachmentTerms) > 0 {
+		// The full-text index is an FTS5 table DuckDB cannot read; match
+		// the extracted text itself, all terms in one attachment.
+		likes := make([]string, len(q.AttachmentTerms))
+		for i, term := range q.AttachmentTerms {
+			likes[i] = `att.content ILIKE ? ESCAPE '\'`
+			args = append(args, "%"+escapeILIKE(term)+"%")
+		}
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.attachments a
+			JOIN sqlite_db.attachment_texts att ON att.storage_path = a.storage_path
+			WHERE a.message_id = %s.id AND %s
+		)`, alias, strings.Join(likes, " AND ")))
+	}
+	return conditions, args
+}
+
+// appendRegexFilters adds the regex: filters of q, each matching the
+// subject or, read from SQLite, the body. DuckDB runs RE2 itself, so
+// unlike the SQLite engine no candidates are read into Go; callers
+// check q.RegexMatcher first so patterns are validated the same way.
+func (e *DuckDBEngine) appendRegexFilters(conditions []string, args []interface{}, alias string, q *search.Query) ([]string, []interface{}) {
+	for _, pattern := range q.Regexes {
+		pattern = "(?i)" + pattern
+		if !e.hasSQLite() {
+			conditions = append(conditions, fmt.Sprintf("regexp_matches(COALESCE(%s.subject, ''), ?)", alias))
+			args = append(args, pattern)
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf(`(regexp_matches(COALESCE(%[1]s.subject, ''), ?) OR EXISTS (
+			SELECT 1 FROM sqlite_db.message_bodies mb
+			WHERE mb.message_id = %[1]s.id AND regexp_matches(COALESCE(mb.body_text, ''), ?)
+		))`, alias))
+		args = append(args, pattern, pattern)
+	}
+	return conditions, args
+}
+
 func (e *DuckDBEngine) buildStatsSearchConditions(searchQuery string, groupBy ViewType) ([]string, []interface{}) {
 	if searchQuery == "" {
 		return nil, nil
@@ -1458,6 +1692,12 @@ func (e *DuckDBEngine) Search(ctx context.Context, q *search.Query, limit, offse
 	if !e.hasSQLite() {
 		return nil, fmt.Errorf("Search requires SQLite: pass sqlitePath to NewDuckDBEngine")
 	}
+	if q.HasBoolean() {
+		return nil, fmt.Errorf("search with OR or negation requires the SQLite engine")
+	}
+	if _, err := q.RegexMatcher(); err != nil {
+		return nil, err
+	}
 
 	var conditions []string
 	var args []interface{}
@@ -1495,18 +1735,44 @@ func (e *DuckDBEngine) Search(ctx context.Context, q *search.Query, limit, offse
 		conditions = append(conditions, fmt.Sprintf("LOWER(p_to.email_address) IN (%s)", strings.Join(placeholders, ",")))
 	}
 
-	// Label filter
-	if len(q.Labels) > 0 {
-		joins = append(joins, `
-			JOIN sqlite_db.message_labels ml ON ml.message_id = m.id
-			JOIN sqlite_db.labels l ON l.id = ml.label_id
-		`)
-		placeholders := make([]string, len(q.Labels))
-		for i, label := range q.Labels {
+	// CC and BCC filters - EXISTS per recipient type
+	for _, rt := range []struct {
+		typ   string
+		addrs []string
+	}{{"cc", q.CcAddrs}, {"bcc", q.BccAddrs}} {
+		if len(rt.addrs) == 0 {
+			continue
+		}
+		placeholders := make([]string, len(rt.addrs))
+		for i, addr := range rt.addrs {
 			placeholders[i] = "?"
-			args = append(args, label)
+			args = append(args, addr)
 		}
-		conditions = append(conditions, fmt.Sprintf("l.name IN (%s)", strings.Join(placeholders, ",")))
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM sqlite_db.message_recipients mr_rt
+			JOIN sqlite_db.participants p_rt ON p_rt.id = mr_rt.participant_id
+			WHERE mr_rt.message_id = m.id AND mr_rt.recipient_type = '%s'
+			  AND LOWER(p_rt.email_address) IN (%s)
+		)`, rt.typ, strings.Join(placeholders, ",")))
+	}
+
+	// Label filter - EXISTS per term, matched like the SQLite engine
+	for _, label := range q.Labels {
+		cond, condArgs := search.ParseLabelPattern(label).SQL("l.name", duckdbLabelLike)
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM sqlite_db.message_labels ml
+			JOIN sqlite_db.labels l ON l.id = ml.label_id
+			WHERE ml.message_id = m.id AND `+cond+`
+		)`)
+		args = append(args, condArgs...)
+	}
+	for _, name := range q.SystemLabels {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM sqlite_db.message_labels ml_sys
+			JOIN sqlite_db.labels l_sys ON l_sys.id = ml_sys.label_id
+			WHERE ml_sys.message_id = m.id AND l_sys.name = ?
+		)`)
+		args = append(args, name)
 	}
 
 	// Subject filter (case-insensitive with ILIKE)
@@ -1521,6 +1787,22 @@ func (e *DuckDBEngine) Search(ctx context.Context, q *search.Query, limit, offse
 	if q.HasAttachment != nil && *q.HasAttachment {
 		conditions = append(conditions, "m.has_attachments = 1")
 	}
+	for _, name := range q.Filenames {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM sqlite_db.attachments a
+			WHERE a.message_id = m.id AND a.filename ILIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeILIKE(name)+"%")
+	}
+	if q.DKIMPass {
+		conditions = append(conditions, e.dkimPassCondition("m"))
+	}
+	if q.Suspicious {
+		conditions = append(conditions, e.suspiciousCondition("m"))
+	}
+	conditions, args = e.appendAnnotationFilters(conditions, args, "m", q)
+	conditions, args = e.appendRegexFilters(conditions, args, "m", q)
+	conditions, args = appendLanguageFilter(conditions, args, "m.", q.Languages)
 
 	// Date range filters
 	if q.AfterDate != nil {
@@ -1844,6 +2126,9 @@ type ParquetSyncState struct {
 // This is much faster than FTS search for large archives.
 // Searches: subject, sender email/name (case-insensitive).
 func (e *DuckDBEngine) SearchFast(ctx context.Context, q *search.Query, filter MessageFilter, limit, offset int) ([]MessageSummary, error) {
+	if _, err := MergeFilterIntoQuery(q, filter).RegexMatcher(); err != nil {
+		return nil, err
+	}
 	conditions, args := e.buildSearchConditions(q, filter)
 
 	if limit == 0 {
@@ -1964,6 +2249,9 @@ func (e *DuckDBEngine) SearchFast(ctx context.Context, q *search.Query, filter M
 // SearchFastCount returns the total count of messages matching a search query.
 // This is used for pagination UI to show "N of M results".
 func (e *DuckDBEngine) SearchFastCount(ctx context.Context, q *search.Query, filter MessageFilter) (int64, error) {
+	if _, err := MergeFilterIntoQuery(q, filter).RegexMatcher(); err != nil {
+		return 0, err
+	}
 	conditions, args := e.buildSearchConditions(q, filter)
 
 	// Count with JOINs for filters that need them
@@ -2199,6 +2487,9 @@ func (e *DuckDBEngine) computeSearchStats(ctx context.Context) *TotalStats {
 func (e *DuckDBEngine) SearchFastWithStats(ctx context.Context, q *search.Query, queryStr string,
 	filter MessageFilter, statsGroupBy ViewType, limit, offset int) (*SearchFastResult, error) {
 
+	if _, err := MergeFilterIntoQuery(q, filter).RegexMatcher(); err != nil {
+		return nil, err
+	}
 	conditions, args := e.buildSearchConditions(q, filter)
 
 	if limit == 0 {
@@ -2368,6 +2659,24 @@ func (e *DuckDBEngine) buildSearchConditions(q *search.Query, filter MessageFilt
 		args = append(args, filter.TimeRange.Period)
 	}
 
+	queryConds, queryArgs := e.searchQueryConditions(q)
+	conditions = append(conditions, queryConds...)
+	args = append(args, queryArgs...)
+
+	// Default conditions if none specified
+	if len(conditions) == 0 {
+		conditions = append(conditions, "1=1")
+	}
+
+	return conditions, args
+}
+
+// searchQueryConditions builds the SearchFast WHERE conditions of the
+// terms of q, including its OR and NOT parts.
+func (e *DuckDBEngine) searchQueryConditions(q *search.Query) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
 	// Text search terms - search subject, snippet, and from fields (fast path).
 	// Uses ILIKE for performance on Parquet scans.
 	if len(q.TextTerms) > 0 {
@@ -2417,6 +2726,9 @@ func (e *DuckDBEngine) buildSearchConditions(q *search.Query, filter MessageFilt
 		}
 	}
 
+	// CC and BCC filters - recipients of that type only
+	conditions, args = appendRecipientTypeFilters(conditions, args, q)
+
 	// Subject filter
 	if len(q.SubjectTerms) > 0 {
 		for _, term := range q.SubjectTerms {
@@ -2425,22 +2737,32 @@ func (e *DuckDBEngine) buildSearchConditions(q *search.Query, filter MessageFilt
 		}
 	}
 
-	// Label filter - case-insensitive substring match
-	if len(q.Labels) > 0 {
-		for _, label := range q.Labels {
-			conditions = append(conditions, `EXISTS (
-				SELECT 1 FROM ml
-				JOIN lbl ON lbl.id = ml.label_id
-				WHERE ml.message_id = msg.id AND lbl.name ILIKE ? ESCAPE '\'
-			)`)
-			args = append(args, "%"+escapeILIKE(label)+"%")
-		}
+	// Label filter - case-insensitive substring or hierarchy match
+	for _, label := range q.Labels {
+		cond, condArgs := search.ParseLabelPattern(label).SQL("lbl.name", duckdbLabelLike)
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM ml
+			JOIN lbl ON lbl.id = ml.label_id
+			WHERE ml.message_id = msg.id AND `+cond+`
+		)`)
+		args = append(args, condArgs...)
 	}
 
 	// Has attachment filter
 	if q.HasAttachment != nil && *q.HasAttachment {
 		conditions = append(conditions, "msg.has_attachments = 1")
 	}
+	conditions, args = appendSystemLabelFilters(conditions, args, q)
+	conditions, args = e.appendFilenameFilters(conditions, args, q)
+	if q.DKIMPass {
+		conditions = append(conditions, e.dkimPassCondition("msg"))
+	}
+	if q.Suspicious {
+		conditions = append(conditions, e.suspiciousCondition("msg"))
+	}
+	conditions, args = e.appendAnnotationFilters(conditions, args, "msg", q)
+	conditions, args = e.appendRegexFilters(conditions, args, "msg", q)
+	conditions, args = appendLanguageFilter(conditions, args, "msg.", q.Languages)
 
 	// Date range filters
 	if q.AfterDate != nil {
@@ -2465,10 +2787,30 @@ func (e *DuckDBEngine) buildSearchConditions(q *search.Query, filter MessageFilt
 	// Account filter
 	conditions, args = appendSourceFilter(conditions, args, "msg.", nil, q.AccountIDs)
 
-	// Default conditions if none specified
-	if len(conditions) == 0 {
-		conditions = append(conditions, "1=1")
+	// OR and NOT parts, each nested query a condition of its own.
+	for _, alts := range q.Or {
+		parts := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			altConds, altArgs := e.searchQueryConditions(alt)
+			parts = append(parts, andConditions(altConds))
+			args = append(args, altArgs...)
+		}
+		conditions = append(conditions, "("+strings.Join(parts, " OR ")+")")
+	}
+	for _, neg := range q.Not {
+		negConds, negArgs := e.searchQueryConditions(neg)
+		conditions = append(conditions, "NOT "+andConditions(negConds))
+		args = append(args, negArgs...)
 	}
 
 	return conditions, args
 }
+
+// andConditions joins conditions with AND into one parenthesised
+// condition, true when there are none.
+func andConditions(conditions []string) string {
+	if len(conditions) == 0 {
+		return "(1=1)"
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")"
+}
@@ -928,6 +928,14 @@ func TestDuckDBEngine_SearchFast(t *testing.T) {
 		{"HasAttachment", "has:attachment", MessageFilter{}, []string{"Re: Hello", "Question"}},
 		{"ToFilter_Bob", "to:bob", MessageFilter{}, []string{"Hello World", "Re: Hello", "Follow up"}},
 		{"ToFilter_Carol", "to:carol", MessageFilter{}, []string{"Hello World"}},
+		{"CcFilter", "cc:dan", MessageFilter{}, []string{"Re: Hello"}},
+		{"CcFilter_ToRecipient", "cc:bob", MessageFilter{}, nil},
+		{"BccFilter", "bcc:dan", MessageFilter{}, nil},
+		{"FilenameFilter", "filename:PDF", MessageFilter{}, []string{"Re: Hello"}},
+		{"FilenameFilter_Name", "filename:report", MessageFilter{}, []string{"Question"}},
+		{"IsImportant", "is:important", MessageFilter{}, []string{"Re: Hello"}},
+		{"IsStarred", "is:starred", MessageFilter{}, nil},
+		{"NotImportant", "label:Work -is:important", MessageFilter{}, []string{"Hello World", "Question"}},
 
 		// Context filters (search + MessageFilter)
 		{"ContextFilter_SenderAlice", "Hello", MessageFilter{Sender: "alice@example.com"}, []string{"Hello World", "Re: Hello"}},
@@ -942,6 +950,12 @@ func TestDuckDBEngine_SearchFast(t *testing.T) {
 		{"CaseInsensitive_Mixed", "HeLLo", MessageFilter{}, []string{"Hello World", "Re: Hello"}},
 		{"CaseInsensitive_Sender_Upper", "ALICE", MessageFilter{}, []string{"Hello World", "Re: Hello", "Follow up"}},
 		{"CaseInsensitive_Sender_Lower", "alice", MessageFilter{}, []string{"Hello World", "Re: Hello", "Follow up"}},
+
+		// Boolean operators
+		{"Or", "subject:final OR subject:question", MessageFilter{}, []string{"Question", "Final"}},
+		{"Negation", "from:alice -hello", MessageFilter{}, []string{"Follow up"}},
+		{"NegatedGroup", "NOT (label:work OR has:attachment)", MessageFilter{}, []string{"Follow up", "Final"}},
+		{"OrWithText", "(hello OR question) -subject:re", MessageFilter{}, []string{"Hello World", "Question"}},
 	}
 
 	for _, tt := range tests {
@@ -1049,6 +1063,27 @@ func TestDuckDBEngine_SearchFast_DateFilter(t *testing.T) {
 	}
 }
 
+// TestDuckDBEngine_SearchFast_Regex verifies regex: with regexp_matches
+// on the subject when no SQLite body table is attached.
+func TestDuckDBEngine_SearchFast_Regex(t *testing.T) {
+	engine := newParquetEngine(t)
+
+	// Alice's subjects: Hello World, Re: Hello, Follow up
+	results := searchFast(t, engine, `from:alice@example.com regex:^(re|fwd):`, MessageFilter{})
+	if len(results) != 1 {
+		t.Errorf("regex:^(re|fwd): expected 1 result, got %d", len(results))
+	}
+
+	results = searchFast(t, engine, `from:alice@example.com regex:"(?-i)^hello"`, MessageFilter{})
+	if len(results) != 0 {
+		t.Errorf("case-sensitive regex: expected 0 results, got %d", len(results))
+	}
+
+	if _, err := engine.SearchFast(context.Background(), search.Parse("regex:hello"), MessageFilter{}, 100, 0); err == nil {
+		t.Error("regex without indexed filter: expected error")
+	}
+}
+
 // TestDuckDBEngine_AggregateBySender_DateFilter verifies date filters on aggregates.
 func TestDuckDBEngine_AggregateBySender_DateFilter(t *testing.T) {
 	engine := newParquetEngine(t)
@@ -1981,6 +2016,16 @@ func TestAggregateBySender_WithSearchQuery(t *testing.T) {
 			searchQuery: "label:work",
 			wantSenders: []string{"alice@example.com", "bob@company.org"}, // msg1 (alice) and msg4 (bob)
 		},
+		{
+			name:        "filename and cc filters",
+			searchQuery: "filename:xlsx OR cc:dan",
+			wantSenders: []string{"alice@example.com", "bob@company.org"}, // msg2 (alice, cc dan) and msg4 (bob)
+		},
+		{
+			name:        "negated text and OR",
+			searchQuery: "(has:attachment OR subject:final) -hello",
+			wantSenders: []string{"bob@company.org"}, // msg4 and msg5 (bob); msg2 (alice) is "Re: Hello"
+		},
 	}
 
 	for _, tt := range tests {
@@ -2004,6 +2049,9 @@ func TestAggregateBySender_WithSearchQuery(t *testing.T) {
 					t.Errorf("expected sender %q in results, got: %v", want, rows)
 				}
 			}
+			if len(rows) != len(tt.wantSenders) {
+				t.Errorf("expected %d senders, got %d: %v", len(tt.wantSenders), len(rows), rows)
+			}
 		})
 	}
 }
@@ -53,6 +53,7 @@ type MessageDetail struct {
 	DeletedAt            *time.Time `json:"deleted_at,omitempty"` // When message was deleted from source (nil if not deleted)
 	SizeEstimate         int64      `json:"size_estimate"`
 	HasAttachments       bool       `json:"has_attachments"`
+	Language             string     `json:"language,omitempty"` // ISO 639-1 code detected at ingest; "" if unknown
 
 	// Participants
 	From []Address `json:"from"`
@@ -64,9 +65,51 @@ type MessageDetail struct {
 	BodyText string `json:"body_text"`
 	BodyHTML string `json:"body_html"`
 
+	// BodySignature and BodyQuoted are the signature and quoted reply
+	// trail folded off the end of BodyText when the message was parsed
+	// with [parse] fold_replies. BodyText still includes them.
+	BodySignature string `json:"body_signature,omitempty"`
+	BodyQuoted    string `json:"body_quoted,omitempty"`
+
 	// Metadata
 	Labels      []string         `json:"labels"`
 	Attachments []AttachmentInfo `json:"attachments"`
+
+	// LabelColors holds the colors of those Labels that have one in
+	// Gmail, by label name.
+	LabelColors map[string]LabelColor `json:"label_colors,omitempty"`
+
+	// InlineParts are the images embedded in BodyHTML, which refers to
+	// them as cid:ContentID. They are not among Attachments.
+	InlineParts []InlinePartInfo `json:"inline_parts,omitempty"`
+
+	// Auth is the message's recorded provenance, nil if there is none.
+	Auth *MessageAuth `json:"auth,omitempty"`
+
+	// Note, Pinned and Tags are the user's local annotations, which are
+	// never synced to the source.
+	Note   string   `json:"note,omitempty"`
+	Pinned bool     `json:"pinned,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// LabelColor is a label's colors, as "#rrggbb" strings.
+type LabelColor struct {
+	Background string `json:"background"`
+	Text       string `json:"text,omitempty"`
+}
+
+// MessageAuth is where a message came from: the archive's own check of
+// its DKIM signatures at ingest, and the results the receiving server
+// reported in Authentication-Results.
+type MessageAuth struct {
+	DKIM          string `json:"dkim,omitempty"` // empty if not verified
+	DKIMDomain    string `json:"dkim_domain,omitempty"`
+	DKIMReason    string `json:"dkim_reason,omitempty"`
+	AuthServID    string `json:"authserv_id,omitempty"`
+	ReportedDKIM  string `json:"reported_dkim,omitempty"`
+	ReportedSPF   string `json:"reported_spf,omitempty"`
+	ReportedDMARC string `json:"reported_dmarc,omitempty"`
 }
 
 // Address represents an email address with optional display name.
@@ -82,6 +125,17 @@ type AttachmentInfo struct {
 	MimeType    string
 	Size        int64
 	ContentHash string
+	// Quarantined is set when the virus scanner flagged the content
+	// and it must not be exported.
+	Quarantined bool
+}
+
+// InlinePartInfo represents an image embedded in a message's HTML body.
+type InlinePartInfo struct {
+	ContentID string
+	Filename  string
+	MimeType  string
+	Size      int64
 }
 
 // ViewType represents the type of aggregate view.
@@ -107,6 +107,12 @@ func (e *PostgreSQLEngine) GetAttachment(ctx context.Context, id int64) (*Attach
 	if err != nil {
 		return nil, fmt.Errorf("get attachment: %w", err)
 	}
+	var quarantined bool
+	if e.db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM attachments WHERE content_hash = $1 AND quarantined = 1)
+	`, att.ContentHash).Scan(&quarantined) == nil {
+		att.Quarantined = quarantined
+	}
 	return &att, nil
 }
 
@@ -1,12 +1,9 @@
 package query
 
 import (
-	"bytes"
-	"compress/zlib"
 	"context"
 	"database/sql"
 	"fmt"
-	"io"
 	"strings"
 
 	"github.com/wesm/msgvault/internal/mime"
@@ -21,6 +18,21 @@ const emailOnlyFilterMsg = "(msg.message_type = 'email' OR msg.message_type IS N
 // NULL and empty string handle old data where message_type was not yet populated.
 const emailOnlyFilterM = "(m.message_type = 'email' OR m.message_type IS NULL OR m.message_type = '')"
 
+// appendLanguageFilter restricts to messages in any of langs, as
+// detected at ingest. prefix is the messages alias with its dot.
+func appendLanguageFilter(conditions []string, args []any, prefix string, langs []string) ([]string, []any) {
+	if len(langs) == 0 {
+		return conditions, args
+	}
+	placeholders := make([]string, len(langs))
+	for i, lang := range langs {
+		placeholders[i] = "?"
+		args = append(args, lang)
+	}
+	conditions = append(conditions, fmt.Sprintf("%slanguage IN (%s)", prefix, strings.Join(placeholders, ",")))
+	return conditions, args
+}
+
 // fetchLabelsForMessageList adds labels to message summaries using a batch query.
 // tablePrefix is "" for direct SQLite or "sqlite_db." for DuckDB's sqlite_scan.
 func fetchLabelsForMessageList(ctx context.Context, db *sql.DB, tablePrefix string, messages []MessageSummary) error {
@@ -68,7 +80,7 @@ func fetchLabelsForMessageList(ctx context.Context, db *sql.DB, tablePrefix stri
 // tablePrefix is "" for direct SQLite or "sqlite_db." for DuckDB's sqlite_scan.
 func fetchMessageLabelsDetail(ctx context.Context, db *sql.DB, tablePrefix string, msg *MessageDetail) error {
 	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
-		SELECT l.name
+		SELECT l.name, l.color, l.text_color
 		FROM %smessage_labels ml
 		JOIN %slabels l ON l.id = ml.label_id
 		WHERE ml.message_id = ?
@@ -80,10 +92,17 @@ func fetchMessageLabelsDetail(ctx context.Context, db *sql.DB, tablePrefix strin
 
 	for rows.Next() {
 		var name string
-		if err := rows.Scan(&name); err != nil {
+		var color, textColor sql.NullString
+		if err := rows.Scan(&name, &color, &textColor); err != nil {
 			return err
 		}
 		msg.Labels = append(msg.Labels, name)
+		if color.String != "" {
+			if msg.LabelColors == nil {
+				msg.LabelColors = make(map[string]LabelColor)
+			}
+			msg.LabelColors[name] = LabelColor{Background: color.String, Text: textColor.String}
+		}
 	}
 
 	return rows.Err()
@@ -144,36 +163,49 @@ func fetchAttachmentsShared(ctx context.Context, db *sql.DB, tablePrefix string,
 		}
 		msg.Attachments = append(msg.Attachments, att)
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
-	return rows.Err()
+	for i := range msg.Attachments {
+		msg.Attachments[i].Quarantined = attachmentQuarantinedShared(ctx, db, tablePrefix, msg.Attachments[i].ContentHash)
+	}
+	return nil
+}
+
+// attachmentQuarantinedShared reports whether attachment content is
+// quarantined. It is best-effort: databases from before virus scanning
+// lack the column, and nothing in them is quarantined.
+func attachmentQuarantinedShared(ctx context.Context, db *sql.DB, tablePrefix, contentHash string) bool {
+	if contentHash == "" {
+		return false
+	}
+	var n int
+	err := db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*) FROM %sattachments WHERE content_hash = ? AND quarantined = 1
+	`, tablePrefix), contentHash).Scan(&n)
+	return err == nil && n > 0
 }
 
 // extractBodyFromRawShared extracts text body from compressed MIME data.
 // tablePrefix is "" for direct SQLite or "sqlite_db." for DuckDB's sqlite_scan.
 func extractBodyFromRawShared(ctx context.Context, db *sql.DB, tablePrefix string, messageID int64) (string, error) {
 	var compressed []byte
-	var compression sql.NullString
+	var compression, blobHash sql.NullString
 
 	err := db.QueryRowContext(ctx, fmt.Sprintf(`
-		SELECT raw_data, compression FROM %smessage_raw WHERE message_id = ?
-	`, tablePrefix), messageID).Scan(&compressed, &compression)
+		SELECT raw_data, compression, blob_hash FROM %smessage_raw WHERE message_id = ?
+	`, tablePrefix), messageID).Scan(&compressed, &compression, &blobHash)
 	if err != nil {
 		return "", err
 	}
+	if compressed, err = store.LoadRaw(compressed, blobHash); err != nil {
+		return "", err
+	}
 
-	var rawData []byte
-	if compression.Valid && compression.String == "zlib" {
-		r, err := zlib.NewReader(bytes.NewReader(compressed))
-		if err != nil {
-			return "", err
-		}
-		defer func() { _ = r.Close() }()
-		rawData, err = io.ReadAll(r)
-		if err != nil {
-			return "", err
-		}
-	} else {
-		rawData = compressed
+	rawData, err := store.DecompressRaw(ctx, db, tablePrefix, compressed, compression.String)
+	if err != nil {
+		return "", err
 	}
 
 	parsed, err := mime.Parse(rawData)
@@ -191,35 +223,29 @@ func extractBodyFromRawShared(ctx context.Context, db *sql.DB, tablePrefix strin
 // the list/search endpoints apply via store.LiveMessagesWhere.
 func getMessageRawShared(ctx context.Context, db *sql.DB, tablePrefix string, messageID int64) ([]byte, error) {
 	var compressed []byte
-	var compression sql.NullString
+	var compression, blobHash sql.NullString
 
 	err := db.QueryRowContext(ctx, fmt.Sprintf(`
-		SELECT mr.raw_data, mr.compression
+		SELECT mr.raw_data, mr.compression, mr.blob_hash
 		FROM %smessage_raw mr
 		JOIN %smessages m ON m.id = mr.message_id
 		WHERE mr.message_id = ? AND %s
-	`, tablePrefix, tablePrefix, store.LiveMessagesWhere("m", true)), messageID).Scan(&compressed, &compression)
+	`, tablePrefix, tablePrefix, store.LiveMessagesWhere("m", true)), messageID).Scan(&compressed, &compression, &blobHash)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query message_raw for id %d: %w", messageID, err)
 	}
-
-	if compression.Valid && compression.String == "zlib" {
-		r, err := zlib.NewReader(bytes.NewReader(compressed))
-		if err != nil {
-			return nil, fmt.Errorf("zlib reader for id %d: %w", messageID, err)
-		}
-		defer func() { _ = r.Close() }()
-		raw, err := io.ReadAll(r)
-		if err != nil {
-			return nil, fmt.Errorf("zlib decompress message_raw id %d: %w", messageID, err)
-		}
-		return raw, nil
+	if compressed, err = store.LoadRaw(compressed, blobHash); err != nil {
+		return nil, fmt.Errorf("load message_raw id %d: %w", messageID, err)
 	}
 
-	return compressed, nil
+	raw, err := store.DecompressRaw(ctx, db, tablePrefix, compressed, compression.String)
+	if err != nil {
+		return nil, fmt.Errorf("decompress message_raw id %d: %w", messageID, err)
+	}
+	return raw, nil
 }
 
 // getMessageByQueryShared retrieves a full message detail by an arbitrary WHERE clause.
@@ -293,6 +319,18 @@ func getMessageByQueryShared(ctx context.Context, db *sql.DB, tablePrefix string
 		return nil, fmt.Errorf("get message body: %w", err)
 	}
 
+	// Folded reply parts: best-effort, since databases from before
+	// reply folding lack the columns.
+	if msg.BodyText != "" {
+		var quoted, signature sql.NullString
+		if db.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT body_quoted, body_signature FROM %smessage_bodies WHERE message_id = ?
+		`, tablePrefix), msg.ID).Scan(&quoted, &signature) == nil {
+			msg.BodyQuoted = quoted.String
+			msg.BodySignature = signature.String
+		}
+	}
+
 	// If body is empty, try to extract from raw MIME
 	if msg.BodyText == "" && msg.BodyHTML == "" {
 		if body, err := extractBodyFromRawShared(ctx, db, tablePrefix, msg.ID); err == nil && body != "" {
@@ -300,6 +338,19 @@ func getMessageByQueryShared(ctx context.Context, db *sql.DB, tablePrefix string
 		}
 	}
 
+	// Language: best-effort, since databases from before language
+	// detection lack the column.
+	var lang sql.NullString
+	if db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT language FROM %smessages WHERE id = ?
+	`, tablePrefix), msg.ID).Scan(&lang) == nil {
+		msg.Language = lang.String
+	}
+
+	msg.Auth = fetchMessageAuthShared(ctx, db, tablePrefix, msg.ID)
+	msg.InlineParts = fetchInlinePartsShared(ctx, db, tablePrefix, msg.ID)
+	fetchAnnotationShared(ctx, db, tablePrefix, &msg)
+
 	// Fetch participants
 	if err := fetchParticipantsShared(ctx, db, tablePrefix, &msg); err != nil {
 		return nil, fmt.Errorf("fetch participants: %w", err)
@@ -334,3 +385,79 @@ func collectGmailIDs(rows *sql.Rows) ([]string, error) {
 	}
 	return ids, nil
 }
+
+// fetchMessageAuthShared loads a message's authentication results.
+// It is best-effort: databases from before they were recorded lack the
+// table, and a message without any has no row.
+func fetchMessageAuthShared(ctx context.Context, db *sql.DB, tablePrefix string, id int64) *MessageAuth {
+	var dkim, domain, reason, servID, repDKIM, repSPF, repDMARC sql.NullString
+	err := db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT dkim_result, dkim_domain, dkim_reason, authserv_id,
+			reported_dkim, reported_spf, reported_dmarc
+		FROM %smessage_auth WHERE message_id = ?
+	`, tablePrefix), id).Scan(&dkim, &domain, &reason, &servID, &repDKIM, &repSPF, &repDMARC)
+	if err != nil {
+		return nil
+	}
+	return &MessageAuth{
+		DKIM:          dkim.String,
+		DKIMDomain:    domain.String,
+		DKIMReason:    reason.String,
+		AuthServID:    servID.String,
+		ReportedDKIM:  repDKIM.String,
+		ReportedSPF:   repSPF.String,
+		ReportedDMARC: repDMARC.String,
+	}
+}
+
+// fetchInlinePartsShared loads the images embedded in a message's HTML
+// body. It is best-effort: databases from before they were recorded
+// lack the table.
+func fetchInlinePartsShared(ctx context.Context, db *sql.DB, tablePrefix string, id int64) []InlinePartInfo {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT content_id, COALESCE(filename, ''), COALESCE(mime_type, ''), COALESCE(size, 0)
+		FROM %smessage_inline_parts WHERE message_id = ? ORDER BY content_id
+	`, tablePrefix), id)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = rows.Close() }()
+
+	var parts []InlinePartInfo
+	for rows.Next() {
+		var p InlinePartInfo
+		if err := rows.Scan(&p.ContentID, &p.Filename, &p.MimeType, &p.Size); err != nil {
+			return nil
+		}
+		parts = append(parts, p)
+	}
+	return parts
+}
+
+// fetchAnnotationShared loads a message's local note, pin and tags. It
+// is best-effort: databases from before annotations lack the tables.
+func fetchAnnotationShared(ctx context.Context, db *sql.DB, tablePrefix string, msg *MessageDetail) {
+	var note sql.NullString
+	var pinned int
+	if db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT note, pinned FROM %smessage_notes WHERE message_id = ?
+	`, tablePrefix), msg.ID).Scan(&note, &pinned) == nil {
+		msg.Note = note.String
+		msg.Pinned = pinned != 0
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT tag FROM %smessage_tags WHERE message_id = ? ORDER BY tag
+	`, tablePrefix), msg.ID)
+	if err != nil {
+		return
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var tag string
+		if rows.Scan(&tag) != nil {
+			return
+		}
+		msg.Tags = append(msg.Tags, tag)
+	}
+}
@@ -3,12 +3,14 @@ package query
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/wesm/msgvault/internal/risk"
 	"github.com/wesm/msgvault/internal/search"
 	"github.com/wesm/msgvault/internal/store"
 )
@@ -59,6 +61,18 @@ func (e *SQLiteEngine) hasFTSTable(ctx context.Context) bool {
 	return e.ftsResult
 }
 
+// hasAttachmentFTSTable reports whether the attachments_fts table exists.
+// It is looked up on each call: only in:attachments searches ask, and
+// a database can gain the table when a newer msgvault opens it.
+func (e *SQLiteEngine) hasAttachmentFTSTable(ctx context.Context) bool {
+	var count int
+	err := e.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM sqlite_master
+		WHERE type='table' AND name='attachments_fts'
+	`).Scan(&count)
+	return err == nil && count > 0
+}
+
 // Close is a no-op for SQLiteEngine since it doesn't own the connection.
 func (e *SQLiteEngine) Close() error {
 	return nil
@@ -70,6 +84,12 @@ func escapeSQLiteLike(s string) string {
 	return r.Replace(s)
 }
 
+// sqliteLabelLike is the case-insensitive LIKE that label: patterns
+// (search.LabelPattern) are matched with.
+func sqliteLabelLike(col string) string {
+	return `LOWER(` + col + `) LIKE LOWER(?) ESCAPE '\'`
+}
+
 // aggDimension describes the variable parts of an aggregate query for a given ViewType.
 type aggDimension struct {
 	keyExpr   string // SQL expression for the grouping key
@@ -461,8 +481,11 @@ func (e *SQLiteEngine) SubAggregate(ctx context.Context, filter MessageFilter, g
 	filterConditions = append(filterConditions, optsConds...)
 	args = append(args, optsArgs...)
 
-	searchJoins, searchConds, searchArgs :=
+	searchJoins, searchConds, searchArgs, err :=
 		e.buildAggregateSearchParts(ctx, opts.SearchQuery, groupBy)
+	if err != nil {
+		return nil, err
+	}
 	filterConditions = append(filterConditions, searchConds...)
 	args = append(args, searchArgs...)
 	if searchJoins != "" {
@@ -476,8 +499,11 @@ func (e *SQLiteEngine) SubAggregate(ctx context.Context, filter MessageFilter, g
 func (e *SQLiteEngine) Aggregate(ctx context.Context, groupBy ViewType, opts AggregateOptions) ([]AggregateRow, error) {
 	conditions, args := optsToFilterConditions(opts, "m.")
 
-	searchJoins, searchConds, searchArgs :=
+	searchJoins, searchConds, searchArgs, err :=
 		e.buildAggregateSearchParts(ctx, opts.SearchQuery, groupBy)
+	if err != nil {
+		return nil, err
+	}
 	conditions = append(conditions, searchConds...)
 	args = append(args, searchArgs...)
 
@@ -491,9 +517,9 @@ func (e *SQLiteEngine) Aggregate(ctx context.Context, groupBy ViewType, opts Agg
 // search, filters the grouping column directly.
 func (e *SQLiteEngine) buildAggregateSearchParts(
 	ctx context.Context, searchQuery string, groupBy ViewType,
-) (string, []string, []interface{}) {
+) (string, []string, []interface{}, error) {
 	if searchQuery == "" {
-		return "", nil, nil
+		return "", nil, nil, nil
 	}
 
 	q := search.Parse(searchQuery)
@@ -508,18 +534,21 @@ func (e *SQLiteEngine) buildAggregateSearchParts(
 	if groupBy == ViewLabels && len(q.Labels) > 0 {
 		var labelParts []string
 		for _, label := range q.Labels {
-			labelParts = append(labelParts,
-				`LOWER(l.name) LIKE LOWER(?) ESCAPE '\'`)
-			args = append(args,
-				"%"+escapeSQLiteLike(label)+"%")
+			cond, condArgs := search.ParseLabelPattern(label).
+				SQL("l.name", sqliteLabelLike)
+			labelParts = append(labelParts, cond)
+			args = append(args, condArgs...)
 		}
 		conditions = append(conditions,
 			"("+strings.Join(labelParts, " OR ")+")")
 		q.Labels = nil
 	}
 
-	searchConds, searchArgs, searchJns, ftsJoin :=
+	searchConds, searchArgs, searchJns, ftsJoin, err :=
 		e.buildSearchQueryParts(ctx, q)
+	if err != nil {
+		return "", nil, nil, err
+	}
 	conditions = append(conditions, searchConds...)
 	args = append(args, searchArgs...)
 	var joinParts []string
@@ -532,7 +561,7 @@ func (e *SQLiteEngine) buildAggregateSearchParts(
 	if len(joinParts) > 0 {
 		joins = strings.Join(joinParts, "\n")
 	}
-	return joins, conditions, args
+	return joins, conditions, args, nil
 }
 
 // executeAggregate is the shared implementation for Aggregate and SubAggregate.
@@ -839,6 +868,7 @@ func (e *SQLiteEngine) GetAttachment(ctx context.Context, id int64) (*Attachment
 	if err != nil {
 		return nil, fmt.Errorf("get attachment: %w", err)
 	}
+	att.Quarantined = attachmentQuarantinedShared(ctx, e.db, "", att.ContentHash)
 	return &att, nil
 }
 
@@ -882,7 +912,11 @@ func (e *SQLiteEngine) GetTotalStats(ctx context.Context, opts StatsOptions) (*T
 	var searchFTSJoin string
 	if opts.SearchQuery != "" {
 		q := search.Parse(opts.SearchQuery)
-		searchConditions, searchArgs, searchJoins, searchFTSJoin = e.buildSearchQueryParts(ctx, q)
+		var err error
+		searchConditions, searchArgs, searchJoins, searchFTSJoin, err = e.buildSearchQueryParts(ctx, q)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Build WHERE clause for messages — always use m. prefix since we alias
@@ -1181,19 +1215,95 @@ func (e *SQLiteEngine) SearchByDomains(ctx context.Context, domains []string, af
 		limit = 1000
 	}
 
-	return e.executeSearchQuery(ctx, conditions, args, nil, "", limit, offset)
+	return e.executeSearchQuery(ctx, conditions, args, nil, "", "m.sent_at DESC", limit, offset)
 }
 
 // Search performs a Gmail-style search query.
 // buildSearchQueryParts builds the WHERE conditions, args, joins, and FTS join
 // for a search query. This is shared between Search and SearchFastCount.
-func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Query) (conditions []string, args []interface{}, joins []string, ftsJoin string) {
+// It fails only for regex: terms it cannot apply (see appendRegexFilter).
+func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Query) (conditions []string, args []interface{}, joins []string, ftsJoin string, err error) {
 	// Restrict to email messages only; NULL and '' handle pre-message_type data.
 	conditions = append(conditions, emailOnlyFilterM)
 	// Exclude rows soft-deleted by deduplicate; gate source-deleted on
 	// q.HideDeleted via the helper.
 	conditions = append(conditions, store.LiveMessagesWhere("m", q.HideDeleted))
 
+	filterConds, filterArgs := e.searchFilterConditions(ctx, q)
+	conditions = append(conditions, filterConds...)
+	args = append(args, filterArgs...)
+
+	// Full-text search: use FTS5 if available, fall back to LIKE
+	if len(q.TextTerms) > 0 {
+		if e.hasFTSTable(ctx) {
+			// Use FTS5 for efficient full-text search.
+			ftsJoin = "JOIN messages_fts fts ON fts.rowid = m.id"
+			conditions = append(conditions, "messages_fts MATCH ?")
+			args = append(args, sqliteFTSExpression(q.TextTerms))
+		} else {
+			// Fall back to LIKE-based search on subject/snippet only
+			// Body text is in a separate table; use FTS for body search
+			for _, term := range q.TextTerms {
+				likeTerm := "%" + term + "%"
+				conditions = append(conditions, "(m.subject LIKE ? OR m.snippet LIKE ?)")
+				args = append(args, likeTerm, likeTerm)
+			}
+		}
+	}
+
+	conditions, args, err = e.appendRegexFilter(ctx, q, conditions, args, ftsJoin)
+	return conditions, args, joins, ftsJoin, err
+}
+
+// appendRegexFilter narrows conditions, the other filters of q, to the
+// messages whose subject or body match its regex: terms. SQLite cannot
+// run RE2 patterns, so the messages the other filters find are read and
+// tested here, and those that match added as a list of IDs.
+// search.Query.RegexMatcher refuses queries whose other filters do not
+// use an index, which would read every message.
+func (e *SQLiteEngine) appendRegexFilter(ctx context.Context, q *search.Query, conditions []string, args []interface{}, ftsJoin string) ([]string, []interface{}, error) {
+	matcher, err := q.RegexMatcher()
+	if err != nil || matcher == nil {
+		return conditions, args, err
+	}
+
+	rows, err := e.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT DISTINCT m.id, COALESCE(m.subject, ''), COALESCE(mb.body_text, '')
+		FROM messages m
+		LEFT JOIN message_bodies mb ON mb.message_id = m.id
+		%s
+		WHERE %s
+	`, ftsJoin, strings.Join(conditions, " AND ")), args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("regex candidates: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var subject, body string
+		if err := rows.Scan(&id, &subject, &body); err != nil {
+			return nil, nil, fmt.Errorf("scan regex candidate: %w", err)
+		}
+		if matcher.Match(subject, body) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("regex candidates: %w", err)
+	}
+	blob, err := json.Marshal(ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode regex matches: %w", err)
+	}
+	return append(conditions, "m.id IN (SELECT value FROM json_each(?))"), append(args, string(blob)), nil
+}
+
+// searchFilterConditions returns the WHERE conditions and args of the
+// filters of q other than its text terms, including its OR and NOT
+// parts.
+func (e *SQLiteEngine) searchFilterConditions(ctx context.Context, q *search.Query) (conditions []string, args []interface{}) {
 	// From filter - uses EXISTS to avoid join multiplication in aggregates.
 	// Handles both exact addresses and @domain patterns.
 	if len(q.FromAddrs) > 0 {
@@ -1266,16 +1376,29 @@ func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Quer
 		)`, strings.Join(placeholders, ",")))
 	}
 
-	// Label filter - case-insensitive substring match using EXISTS
-	// so each label term can match a different row in message_labels.
+	// Label filter - case-insensitive substring or hierarchy match
+	// (search.LabelPattern) using EXISTS so each label term can match a
+	// different row in message_labels.
 	for _, label := range q.Labels {
+		cond, condArgs := search.ParseLabelPattern(label).
+			SQL("l_lbl.name", sqliteLabelLike)
 		conditions = append(conditions, `EXISTS (
 			SELECT 1 FROM message_labels ml_lbl
 			JOIN labels l_lbl ON l_lbl.id = ml_lbl.label_id
 			WHERE ml_lbl.message_id = m.id
-			  AND LOWER(l_lbl.name) LIKE LOWER(?) ESCAPE '\'
+			  AND `+cond+`
+		)`)
+		args = append(args, condArgs...)
+	}
+
+	// is:unread, is:starred, is:important - exact system label name
+	for _, name := range q.SystemLabels {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_labels ml_sys
+			JOIN labels l_sys ON l_sys.id = ml_sys.label_id
+			WHERE ml_sys.message_id = m.id AND l_sys.name = ?
 		)`)
-		args = append(args, "%"+escapeSQLiteLike(label)+"%")
+		args = append(args, name)
 	}
 
 	// Subject filter
@@ -1291,6 +1414,93 @@ func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Quer
 		conditions = append(conditions, "m.has_attachments = 1")
 	}
 
+	// Attachment filename filter - substring, case-insensitive
+	for _, name := range q.Filenames {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM attachments a
+			WHERE a.message_id = m.id AND LOWER(a.filename) LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeSQLiteLike(name)+"%")
+	}
+
+	// DKIM verified at ingest
+	if q.DKIMPass {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_auth ma
+			WHERE ma.message_id = m.id AND ma.dkim_result = 'pass'
+		)`)
+	}
+
+	// Scored as likely spam or phishing by 'msgvault risk scan'
+	if q.Suspicious {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM message_risk mk
+			WHERE mk.message_id = m.id AND mk.score >= %d
+		)`, risk.SuspiciousScore))
+	}
+
+	// Local pins, reminders, notes and tags
+	if q.Pinned {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_notes mn
+			WHERE mn.message_id = m.id AND mn.pinned = 1
+		)`)
+	}
+	if q.ReminderDue {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_reminders mrd
+			WHERE mrd.message_id = m.id AND mrd.due_at <= ?
+		)`)
+		args = append(args, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	}
+	for _, term := range q.NoteTerms {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_notes mn
+			WHERE mn.message_id = m.id AND mn.note LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeSQLiteLike(term)+"%")
+	}
+	for _, tag := range q.Tags {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_tags mt
+			WHERE mt.message_id = m.id AND mt.tag = ?
+		)`)
+		args = append(args, tag)
+	}
+
+	// Text extracted from attachments, for in:attachments
+	if len(q.AttachmentTerms) > 0 {
+		var match string
+		if e.hasAttachmentFTSTable(ctx) {
+			match = "att.id IN (SELECT rowid FROM attachments_fts WHERE attachments_fts MATCH ?)"
+			args = append(args, sqliteFTSExpression(q.AttachmentTerms))
+		} else {
+			likes := make([]string, len(q.AttachmentTerms))
+			for i, term := range q.AttachmentTerms {
+				likes[i] = `att.content LIKE ? ESCAPE '\'`
+				args = append(args, "%"+escapeSQLiteLike(term)+"%")
+			}
+			match = strings.Join(likes, " AND ")
+		}
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM attachments a
+			JOIN attachment_texts att ON att.storage_path = a.storage_path
+			WHERE a.message_id = m.id AND `+match+`
+		)`)
+	}
+
+	// Indexed headers: mailing lists and delivery addresses
+	for _, h := range q.HeaderFilters() {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_headers mh
+			WHERE mh.message_id = m.id AND mh.name = ? AND mh.value LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, h.Name, "%"+escapeSQLiteLike(h.Value)+"%")
+	}
+
+	// Language detected at ingest
+	conditions, args = appendLanguageFilter(conditions, args, "m.", q.Languages)
+
 	// Date range filters
 	if q.AfterDate != nil {
 		conditions = append(conditions, "m.sent_at >= ?")
@@ -1311,26 +1521,39 @@ func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Quer
 		args = append(args, *q.SmallerThan)
 	}
 
-	// Full-text search: use FTS5 if available, fall back to LIKE
+	// Account filter
+	conditions, args = appendSourceFilter(conditions, args, "m.", nil, q.AccountIDs)
+
+	// OR and NOT parts, each nested query a condition of its own.
+	for _, alts := range q.Or {
+		parts := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			cond, condArgs := e.searchSubqueryCondition(ctx, alt)
+			parts = append(parts, cond)
+			args = append(args, condArgs...)
+		}
+		conditions = append(conditions, "("+strings.Join(parts, " OR ")+")")
+	}
+	for _, neg := range q.Not {
+		cond, condArgs := e.searchSubqueryCondition(ctx, neg)
+		conditions = append(conditions, "NOT "+cond)
+		args = append(args, condArgs...)
+	}
+
+	return conditions, args
+}
+
+// searchSubqueryCondition returns q, a query nested in another with OR
+// or NOT, as a single parenthesised condition. Its text terms match
+// through FTS5 without a join, or by LIKE without FTS5.
+func (e *SQLiteEngine) searchSubqueryCondition(ctx context.Context, q *search.Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
 	if len(q.TextTerms) > 0 {
 		if e.hasFTSTable(ctx) {
-			// Use FTS5 for efficient full-text search.
-			// Prefix matching (*) enables partial word matches.
-			// Multiple terms are AND-ed: all must appear (in any column).
-			ftsJoin = "JOIN messages_fts fts ON fts.rowid = m.id"
-			ftsTerms := make([]string, len(q.TextTerms))
-			for i, term := range q.TextTerms {
-				// Quote all terms to prevent FTS5 special chars
-				// (-, :, (, ), etc.) from being parsed as query syntax.
-				term = strings.ReplaceAll(term, "\"", "\"\"")
-				term = strings.ReplaceAll(term, "*", "")
-				ftsTerms[i] = fmt.Sprintf("\"%s\"*", term)
-			}
-			conditions = append(conditions, "messages_fts MATCH ?")
-			args = append(args, strings.Join(ftsTerms, " "))
+			conditions = append(conditions, "m.id IN (SELECT rowid FROM messages_fts WHERE messages_fts MATCH ?)")
+			args = append(args, sqliteFTSExpression(q.TextTerms))
 		} else {
-			// Fall back to LIKE-based search on subject/snippet only
-			// Body text is in a separate table; use FTS for body search
 			for _, term := range q.TextTerms {
 				likeTerm := "%" + term + "%"
 				conditions = append(conditions, "(m.subject LIKE ? OR m.snippet LIKE ?)")
@@ -1338,29 +1561,73 @@ func (e *SQLiteEngine) buildSearchQueryParts(ctx context.Context, q *search.Quer
 			}
 		}
 	}
+	filterConds, filterArgs := e.searchFilterConditions(ctx, q)
+	conditions = append(conditions, filterConds...)
+	args = append(args, filterArgs...)
+	if len(conditions) == 0 {
+		return "(1=1)", nil
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
 
-	// Account filter
-	conditions, args = appendSourceFilter(conditions, args, "m.", nil, q.AccountIDs)
-
-	return conditions, args, joins, ftsJoin
+// sqliteFTSExpression builds an FTS5 MATCH expression from text terms.
+// Prefix matching (*) enables partial word matches, and all terms must
+// appear (in any column).
+func sqliteFTSExpression(terms []string) string {
+	ftsTerms := make([]string, len(terms))
+	for i, term := range terms {
+		// Quote all terms to prevent FTS5 special chars
+		// (-, :, (, ), etc.) from being parsed as query syntax.
+		term = strings.ReplaceAll(term, "\"", "\"\"")
+		term = strings.ReplaceAll(term, "*", "")
+		ftsTerms[i] = fmt.Sprintf("\"%s\"*", term)
+	}
+	return strings.Join(ftsTerms, " ")
 }
 
 func (e *SQLiteEngine) Search(ctx context.Context, q *search.Query, limit, offset int) ([]MessageSummary, error) {
-	conditions, args, joins, ftsJoin := e.buildSearchQueryParts(ctx, q)
-	return e.executeSearchQuery(ctx, conditions, args, joins, ftsJoin, limit, offset)
+	conditions, args, joins, ftsJoin, err := e.buildSearchQueryParts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return e.executeSearchQuery(ctx, conditions, args, joins, ftsJoin, searchOrderBy(q, ftsJoin != ""), limit, offset)
 }
 
 // SearchFast searches using the same FTS5 path as Search but merges
 // MessageFilter context into the query (drill-down filters, hide-deleted, etc.).
 func (e *SQLiteEngine) SearchFast(ctx context.Context, q *search.Query, filter MessageFilter, limit, offset int) ([]MessageSummary, error) {
 	mergedQuery := MergeFilterIntoQuery(q, filter)
-	conditions, args, joins, ftsJoin := e.buildSearchQueryParts(ctx, mergedQuery)
-	return e.executeSearchQuery(ctx, conditions, args, joins, ftsJoin, limit, offset)
+	conditions, args, joins, ftsJoin, err := e.buildSearchQueryParts(ctx, mergedQuery)
+	if err != nil {
+		return nil, err
+	}
+	return e.executeSearchQuery(ctx, conditions, args, joins, ftsJoin, searchOrderBy(mergedQuery, ftsJoin != ""), limit, offset)
+}
+
+// searchOrderBy returns the ORDER BY clause of a search for q. By
+// default results come newest first. search.SortRelevance ranks FTS5
+// matches by BM25 relevance (see store.SQLiteFTSRank), newest first
+// among equals, and falls back to newest first without FTS terms.
+func searchOrderBy(q *search.Query, fts bool) string {
+	dir := "DESC"
+	if q.SortAsc {
+		dir = "ASC"
+	}
+	switch q.Sort {
+	case search.SortRelevance:
+		if fts {
+			return store.SQLiteFTSRank + ", m.sent_at DESC"
+		}
+		return "m.sent_at DESC"
+	case search.SortSize:
+		return "COALESCE(m.size_estimate, 0) " + dir + ", m.id " + dir
+	}
+	return "m.sent_at " + dir + ", m.id " + dir
 }
 
 // executeSearchQuery runs a search query built from conditions/joins and returns
 // paginated MessageSummary results. Shared by Search and SearchFast.
-func (e *SQLiteEngine) executeSearchQuery(ctx context.Context, conditions []string, args []interface{}, joins []string, ftsJoin string, limit, offset int) ([]MessageSummary, error) {
+func (e *SQLiteEngine) executeSearchQuery(ctx context.Context, conditions []string, args []interface{}, joins []string, ftsJoin, orderBy string, limit, offset int) ([]MessageSummary, error) {
 	if limit == 0 {
 		limit = 100
 	}
@@ -1395,9 +1662,9 @@ func (e *SQLiteEngine) executeSearchQuery(ctx context.Context, conditions []stri
 		%s
 		%s
 		WHERE %s
-		ORDER BY m.sent_at DESC
+		ORDER BY %s
 		LIMIT ? OFFSET ?
-	`, ftsJoin, strings.Join(joins, "\n"), whereClause)
+	`, ftsJoin, strings.Join(joins, "\n"), whereClause, orderBy)
 
 	args = append(args, limit, offset)
 
@@ -1475,6 +1742,7 @@ func MergeFilterIntoQuery(q *search.Query, filter MessageFilter) *search.Query {
 	merged.BccAddrs = append([]string(nil), q.BccAddrs...)
 	merged.SubjectTerms = append([]string(nil), q.SubjectTerms...)
 	merged.Labels = append([]string(nil), q.Labels...)
+	merged.Languages = append([]string(nil), q.Languages...)
 	// Deep-copy AccountIDs alongside the other slices so the merged
 	// query never aliases the original's slice header. Filter overrides
 	// below replace the deep-copied slice when set.
@@ -1596,7 +1864,10 @@ func timePeriodToBounds(period string) (after, before time.Time, ok bool) {
 // Uses the same query logic as SearchFast to ensure consistent counts.
 func (e *SQLiteEngine) SearchFastCount(ctx context.Context, q *search.Query, filter MessageFilter) (int64, error) {
 	mergedQuery := MergeFilterIntoQuery(q, filter)
-	conditions, args, joins, ftsJoin := e.buildSearchQueryParts(ctx, mergedQuery)
+	conditions, args, joins, ftsJoin, err := e.buildSearchQueryParts(ctx, mergedQuery)
+	if err != nil {
+		return 0, err
+	}
 
 	whereClause := strings.Join(conditions, " AND ")
 	if whereClause == "" {
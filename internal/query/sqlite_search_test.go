@@ -2,12 +2,14 @@ package query
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/testutil/dbtest"
 	"github.com/wesm/msgvault/internal/testutil/ptr"
 )
 
@@ -162,6 +164,30 @@ func TestSearch_WithFTS_SpecialChars(t *testing.T) {
 	}
 }
 
+func TestSearch_Boolean(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantCount int
+	}{
+		{"from:alice@example.com OR subject:final", 4},
+		{"-from:alice@example.com", 2},
+		{"from:alice@example.com -hello", 1},
+		{"(hello OR question) -subject:re", 2},
+		{"NOT (label:work OR has:attachment)", 2},
+	}
+	for _, fts := range []bool{false, true} {
+		env := newTestEnv(t)
+		if fts {
+			env.EnableFTS()
+		}
+		for _, tc := range tests {
+			t.Run(fmt.Sprintf("%s/fts=%v", tc.query, fts), func(t *testing.T) {
+				assertSearchCount(t, env, search.Parse(tc.query), tc.wantCount)
+			})
+		}
+	}
+}
+
 func TestHasFTSTable(t *testing.T) {
 	env := newTestEnv(t)
 
@@ -464,6 +490,166 @@ func TestMergeFilterIntoQuery_SliceAliasingMutation(t *testing.T) {
 	}
 }
 
+func TestSearch_Language(t *testing.T) {
+	env := newTestEnv(t)
+	for id, lang := range map[int]string{1: "de", 2: "de", 3: "fr"} {
+		if _, err := env.DB.Exec(`UPDATE messages SET language = ? WHERE id = ?`, lang, id); err != nil {
+			t.Fatalf("set language: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse("lang:de"), 2)
+	assertSearchCount(t, env, search.Parse("lang:de lang:fr"), 3)
+	assertSearchCount(t, env, search.Parse("lang:es"), 0)
+
+	// A message's language is shown with it.
+	msg, err := env.Engine.GetMessage(env.Ctx, 3)
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	if msg.Language != "fr" {
+		t.Errorf("Language = %q, want fr", msg.Language)
+	}
+}
+
+func TestSearch_Suspicious(t *testing.T) {
+	env := newTestEnv(t)
+	for id, score := range map[int]int{1: 9, 2: 5, 3: 2} {
+		if _, err := env.DB.Exec(`INSERT INTO message_risk (message_id, score, scored_at) VALUES (?, ?, CURRENT_TIMESTAMP)`, id, score); err != nil {
+			t.Fatalf("set risk: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse("is:suspicious"), 2)
+}
+
+func TestSearch_ReminderDue(t *testing.T) {
+	env := newTestEnv(t)
+	for id, due := range map[int]string{1: "2020-01-01 00:00:00", 2: "2099-01-01 00:00:00"} {
+		if _, err := env.DB.Exec(`INSERT INTO message_reminders (message_id, due_at, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)`, id, due); err != nil {
+			t.Fatalf("set reminder: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse("is:due"), 1)
+}
+
+func TestSearch_Headers(t *testing.T) {
+	env := newTestEnv(t)
+	for _, h := range []struct {
+		id          int
+		name, value string
+	}{
+		{1, "list-id", "golang-nuts.googlegroups.com"},
+		{2, "list-id", "announce.lists.example.com"},
+		{2, "delivered-to", "alias@example.com"},
+		{3, "x-mailer", "Microsoft Outlook 16.0"},
+	} {
+		if _, err := env.DB.Exec(`INSERT INTO message_headers (message_id, name, value) VALUES (?, ?, ?)`, h.id, h.name, h.value); err != nil {
+			t.Fatalf("index header: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse("list:golang-nuts"), 1)
+	assertSearchCount(t, env, search.Parse("list:example.com"), 1)
+	assertSearchCount(t, env, search.Parse("deliveredto:alias@example.com"), 1)
+	assertSearchCount(t, env, search.Parse("deliveredto:golang-nuts"), 0)
+	assertSearchCount(t, env, search.Parse("header:X-Mailer=outlook"), 1)
+	assertSearchCount(t, env, search.Parse(`header:x-mailer="Outlook 16"`), 1)
+	assertSearchCount(t, env, search.Parse("header:x-mailer"), 1)
+	assertSearchCount(t, env, search.Parse("header:list-id=golang-nuts"), 1)
+	assertSearchCount(t, env, search.Parse("header:x-mailer=thunderbird"), 0)
+	assertSearchCount(t, env, search.Parse("-header:x-mailer"), 4)
+}
+
+func TestSearch_Regex(t *testing.T) {
+	env := newTestEnv(t)
+	for id, body := range map[int]string{
+		1: "Invoice INV-20240115 attached",
+		2: "Re: invoice inv-42",
+		4: "Order #12345 shipped",
+	} {
+		if _, err := env.DB.Exec(`UPDATE message_bodies SET body_text = ? WHERE message_id = ?`, body, id); err != nil {
+			t.Fatalf("set body: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse(`from:alice@example.com regex:inv-\d{8}`), 1)
+	assertSearchCount(t, env, search.Parse(`from:alice@example.com regex:inv-\d+`), 2)
+	assertSearchCount(t, env, search.Parse(`from:alice@example.com regex:"(?-i)INV-\d+"`), 1)
+	assertSearchCount(t, env, search.Parse(`from:bob@company.org regex:"order #\d+"`), 1)
+	assertSearchCount(t, env, search.Parse(`after:2024-01-01 regex:^(Re|Fwd):`), 1)
+	assertSearchCount(t, env, search.Parse(`from:bob@company.org regex:inv-`), 0)
+
+	count, err := env.Engine.SearchFastCount(env.Ctx, search.Parse(`from:alice@example.com regex:"^(hello|follow)"`), MessageFilter{})
+	if err != nil {
+		t.Fatalf("SearchFastCount: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("SearchFastCount = %d, want 2", count)
+	}
+
+	for _, query := range []string{"regex:invoice", "from:alice@example.com -regex:invoice", "from:alice@example.com regex:(inv"} {
+		if _, err := env.Engine.Search(env.Ctx, search.Parse(query), 100, 0); err == nil {
+			t.Errorf("Search(%q): want error", query)
+		}
+	}
+}
+
+func TestSearch_AttachmentText(t *testing.T) {
+	env := newTestEnv(t)
+	// Message 2 has doc.pdf and image.png; message 4 has report.xlsx.
+	for path, text := range map[string]string{
+		"ab/hash1": "Lease renewal for flat 4B",
+		"cd/hash2": "",
+		"ef/hash3": "Quarterly revenue by region",
+	} {
+		if _, err := env.DB.Exec(`INSERT INTO attachment_texts (storage_path, content, extracted_at) VALUES (?, ?, CURRENT_TIMESTAMP)`, path, text); err != nil {
+			t.Fatalf("index attachment text: %v", err)
+		}
+	}
+
+	assertSearchCount(t, env, search.Parse("in:attachments lease"), 1)
+	assertSearchCount(t, env, search.Parse(`in:attachments "lease renewal"`), 1)
+	assertSearchCount(t, env, search.Parse("in:attachments lease revenue"), 0)
+	assertSearchCount(t, env, search.Parse("in:attachments revenue from:bob@company.org"), 1)
+	assertSearchCount(t, env, search.Parse("-(in:attachments revenue)"), 4)
+	assertSearchCount(t, env, search.Parse("lease"), 0)
+}
+
+func TestSearch_CcBccFilename(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.DB.Exec(`INSERT INTO message_recipients (message_id, participant_id, recipient_type, display_name) VALUES
+		(3, 3, 'cc', 'Carol'), (5, 3, 'bcc', 'Carol')`); err != nil {
+		t.Fatalf("add recipients: %v", err)
+	}
+
+	assertSearchCount(t, env, search.Parse("cc:carol@example.com"), 1)
+	assertSearchCount(t, env, search.Parse("bcc:carol@example.com"), 1)
+	assertSearchCount(t, env, search.Parse("cc:bob@company.org"), 0)
+	assertSearchCount(t, env, search.Parse("filename:PDF"), 1)
+	assertSearchCount(t, env, search.Parse("filename:report.xlsx"), 1)
+	assertSearchCount(t, env, search.Parse("filename:doc OR filename:report"), 2)
+	assertSearchCount(t, env, search.Parse("filename:zip"), 0)
+}
+
+func TestSearch_SystemLabels(t *testing.T) {
+	env := newTestEnv(t)
+	if _, err := env.DB.Exec(`INSERT INTO labels (id, source_id, source_label_id, name, label_type) VALUES
+		(4, 1, 'UNREAD', 'UNREAD', 'system'), (5, 1, 'Label_9', 'Unread later', 'user')`); err != nil {
+		t.Fatalf("add labels: %v", err)
+	}
+	if _, err := env.DB.Exec(`INSERT INTO message_labels (message_id, label_id) VALUES (3, 4), (4, 5)`); err != nil {
+		t.Fatalf("label messages: %v", err)
+	}
+
+	assertSearchCount(t, env, search.Parse("is:unread"), 1)
+	assertSearchCount(t, env, search.Parse("-is:unread"), 4)
+	assertSearchCount(t, env, search.Parse("is:important"), 1)
+	assertSearchCount(t, env, search.Parse("is:important OR is:unread"), 2)
+	assertSearchCount(t, env, search.Parse("is:starred"), 0)
+}
+
 func TestSearch_HideDeleted(t *testing.T) {
 	env := newTestEnv(t)
 
@@ -496,3 +682,84 @@ func TestSearch_HideDeleted(t *testing.T) {
 		t.Errorf("Search via merged query: expected 4, got %d", len(results))
 	}
 }
+
+func TestSearch_LabelHierarchy(t *testing.T) {
+	env := newTestEnv(t)
+	for id, name := range map[int64]string{
+		1: "Clients",
+		2: "Clients/Acme",
+		3: "Clients/Acme/Invoices",
+		4: "OldClients/Beta",
+	} {
+		env.AddMessageLabel(id, env.AddLabel(dbtest.LabelOpts{Name: name}))
+	}
+
+	assertSearchCount(t, env, search.Parse("label:Clients/**"), 3)
+	assertSearchCount(t, env, search.Parse("label:clients/acme/**"), 2)
+	assertSearchCount(t, env, search.Parse("label:Clients/*"), 1)
+	assertSearchCount(t, env, search.Parse("label:Clients"), 4) // substring
+}
+
+func TestGetMessage_LabelColors(t *testing.T) {
+	env := newTestEnv(t)
+	labelID := env.AddLabel(dbtest.LabelOpts{Name: "Clients/Acme"})
+	env.AddMessageLabel(1, labelID)
+	if _, err := env.DB.Exec(`UPDATE labels SET color = '#4a86e8', text_color = '#ffffff' WHERE id = ?`, labelID); err != nil {
+		t.Fatalf("set label color: %v", err)
+	}
+
+	msg, err := env.Engine.GetMessage(env.Ctx, 1)
+	if err != nil {
+		t.Fatalf("GetMessage: %v", err)
+	}
+	want := LabelColor{Background: "#4a86e8", Text: "#ffffff"}
+	if got := msg.LabelColors["Clients/Acme"]; got != want {
+		t.Errorf("LabelColors[Clients/Acme] = %+v, want %+v", got, want)
+	}
+	if len(msg.LabelColors) != 1 {
+		t.Errorf("LabelColors = %v, want only the colored label", msg.LabelColors)
+	}
+}
+
+func TestSearch_Relevance(t *testing.T) {
+	env := newTestEnv(t)
+	inSubject := env.AddMessage(dbtest.MessageOpts{Subject: "Budget review", SentAt: "2020-01-10 09:00:00", SizeEstimate: 500})
+	inBody := env.AddMessage(dbtest.MessageOpts{Subject: "Lunch on Friday", SentAt: "2024-06-01 12:00:00", SizeEstimate: 9000})
+	if _, err := env.DB.Exec(`INSERT INTO message_bodies (message_id, body_text) VALUES
+		(?, 'The numbers are in the attached file.'),
+		(?, 'Before lunch we could go over the budget, the travel plans, the office move and the rest of the agenda.')`,
+		inSubject, inBody); err != nil {
+		t.Fatalf("add bodies: %v", err)
+	}
+	env.EnableFTS()
+
+	ids := func(sort search.SortField) []int64 {
+		t.Helper()
+		q := search.Parse("budget")
+		q.Sort = sort
+		var got []int64
+		for _, r := range env.MustSearch(q, 10, 0) {
+			got = append(got, r.ID)
+		}
+		return got
+	}
+	// A match in the subject outranks a newer one in the body.
+	if diff := cmp.Diff([]int64{inSubject, inBody}, ids(search.SortRelevance)); diff != "" {
+		t.Errorf("relevance order (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int64{inBody, inSubject}, ids(search.SortDate)); diff != "" {
+		t.Errorf("date order (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]int64{inBody, inSubject}, ids(search.SortSize)); diff != "" {
+		t.Errorf("size order (-want +got):\n%s", diff)
+	}
+	// Relevance is opt-in: a query that leaves Sort unset, as MCP and
+	// bench do, still lists the newest first.
+	var unset []int64
+	for _, r := range env.MustSearch(search.Parse("budget"), 10, 0) {
+		unset = append(unset, r.ID)
+	}
+	if diff := cmp.Diff([]int64{inBody, inSubject}, unset); diff != "" {
+		t.Errorf("unset sort order (-want +got):\n%s", diff)
+	}
+}
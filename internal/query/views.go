@@ -196,6 +196,11 @@ func createBaseViews(db *sql.DB, analyticsDir string, optCols map[string]map[str
 						replaceExpr: "COALESCE(CAST(message_type AS VARCHAR), '') AS message_type",
 						defaultExpr: "'' AS message_type",
 					},
+					{
+						name:        "language",
+						replaceExpr: "CAST(language AS VARCHAR) AS language",
+						defaultExpr: "NULL::VARCHAR AS language",
+					},
 				},
 			},
 			probe: colsFor("messages"),
@@ -506,6 +506,7 @@ func (e *Engine) GetMessage(ctx context.Context, id int64) (*query.MessageDetail
 	// Convert attachments
 	for _, att := range msg.Attachments {
 		detail.Attachments = append(detail.Attachments, query.AttachmentInfo{
+			ID:       att.ID,
 			Filename: att.Filename,
 			MimeType: att.MimeType,
 			Size:     att.Size,
@@ -610,6 +611,9 @@ func (e *Engine) Search(ctx context.Context, q *search.Query, limit, offset int)
 	if q.HideDeleted {
 		params.Set("hide_deleted", "true")
 	}
+	if q.Sort == search.SortRelevance {
+		params.Set("sort", "relevance")
+	}
 
 	path := "/api/v1/search/deep?" + params.Encode()
 
@@ -786,6 +790,68 @@ func buildSearchQueryString(q *search.Query) string {
 	if q.HasAttachment != nil && *q.HasAttachment {
 		parts = append(parts, "has:attachment")
 	}
+	for _, name := range q.Filenames {
+		if strings.ContainsAny(name, " \t") {
+			name = `"` + name + `"`
+		}
+		parts = append(parts, "filename:"+name)
+	}
+	if q.DKIMPass {
+		parts = append(parts, "is:dkim-pass")
+	}
+	if q.Suspicious {
+		parts = append(parts, "is:suspicious")
+	}
+	if q.Pinned {
+		parts = append(parts, "is:pinned")
+	}
+	if q.ReminderDue {
+		parts = append(parts, "is:due")
+	}
+	for _, name := range q.SystemLabels {
+		parts = append(parts, "is:"+strings.ToLower(name))
+	}
+	for _, term := range q.NoteTerms {
+		if strings.ContainsAny(term, " \t") {
+			term = `"` + term + `"`
+		}
+		parts = append(parts, "note:"+term)
+	}
+	for _, tag := range q.Tags {
+		parts = append(parts, "tag:"+tag)
+	}
+	for _, list := range q.ListIDs {
+		parts = append(parts, "list:"+list)
+	}
+	for _, addr := range q.DeliveredTo {
+		parts = append(parts, "deliveredto:"+addr)
+	}
+	for _, h := range q.Headers {
+		term := h.Name
+		if h.Value != "" {
+			term += "=" + h.Value
+		}
+		if strings.ContainsAny(term, " \t") {
+			term = `"` + term + `"`
+		}
+		parts = append(parts, "header:"+term)
+	}
+	for _, pattern := range q.Regexes {
+		parts = append(parts, `regex:"`+pattern+`"`)
+	}
+	if len(q.AttachmentTerms) > 0 {
+		group := []string{"in:attachments"}
+		for _, term := range q.AttachmentTerms {
+			if strings.ContainsAny(term, " \t") {
+				term = `"` + term + `"`
+			}
+			group = append(group, term)
+		}
+		parts = append(parts, "("+strings.Join(group, " ")+")")
+	}
+	for _, lang := range q.Languages {
+		parts = append(parts, "lang:"+lang)
+	}
 	if q.BeforeDate != nil {
 		parts = append(parts, "before:"+q.BeforeDate.Format("2006-01-02"))
 	}
@@ -798,6 +864,16 @@ func buildSearchQueryString(q *search.Query) string {
 	if q.SmallerThan != nil {
 		parts = append(parts, fmt.Sprintf("smaller:%d", *q.SmallerThan))
 	}
+	for _, alts := range q.Or {
+		group := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			group = append(group, "("+buildSearchQueryString(alt)+")")
+		}
+		parts = append(parts, "("+strings.Join(group, " OR ")+")")
+	}
+	for _, neg := range q.Not {
+		parts = append(parts, "-("+buildSearchQueryString(neg)+")")
+	}
 
 	result := ""
 	for i, part := range parts {
@@ -185,6 +185,7 @@ type messageDetailResponse struct {
 
 // attachmentResponse matches the API attachment format.
 type attachmentResponse struct {
+	ID       int64  `json:"id"`
 	Filename string `json:"filename"`
 	MimeType string `json:"mime_type"`
 	Size     int64  `json:"size_bytes"`
@@ -293,6 +294,7 @@ func (s *Store) GetMessage(id int64) (*store.APIMessage, error) {
 	attachments := make([]store.APIAttachment, len(mr.Attachments))
 	for i, a := range mr.Attachments {
 		attachments[i] = store.APIAttachment{
+			ID:       a.ID,
 			Filename: a.Filename,
 			MimeType: a.MimeType,
 			Size:     a.Size,
@@ -0,0 +1,90 @@
+package risk
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Blocklist is a set of known-bad domains.
+type Blocklist struct {
+	domains map[string]bool
+}
+
+// LoadBlocklist reads the blocklist files at paths into one list.
+func LoadBlocklist(paths ...string) (*Blocklist, error) {
+	b := &Blocklist{domains: make(map[string]bool)}
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, fmt.Errorf("open blocklist: %w", err)
+		}
+		err = b.read(f)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read blocklist %s: %w", p, err)
+		}
+	}
+	return b, nil
+}
+
+// ParseBlocklist reads a blocklist from r. Each line holds a domain, a
+// URL (as in phishing URL feeds), or a hosts-file entry such as
+// "0.0.0.0 bad.example"; blank lines and # comments are skipped.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	b := &Blocklist{domains: make(map[string]bool)}
+	if err := b.read(r); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Blocklist) read(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		entry := fields[len(fields)-1]
+		if strings.Contains(entry, "://") {
+			entry = linkHost(entry)
+		}
+		entry = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(entry), "."), "www.")
+		if entry != "" {
+			b.domains[entry] = true
+		}
+	}
+	return sc.Err()
+}
+
+// Len returns the number of domains listed.
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.domains)
+}
+
+// Contains reports whether host or a domain it belongs to is listed. A
+// nil Blocklist lists nothing.
+func (b *Blocklist) Contains(host string) bool {
+	if b == nil {
+		return false
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for host != "" {
+		if b.domains[host] {
+			return true
+		}
+		_, parent, ok := strings.Cut(host, ".")
+		if !ok {
+			break
+		}
+		host = parent
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package risk
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBlocklist(t *testing.T) {
+	b, err := ParseBlocklist(strings.NewReader(`# phishing domains
+bad.example
+0.0.0.0 tracker.example   # hosts-file entry
+https://www.phish.example/login.php
+
+WWW.Upper.Example.
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", b.Len())
+	}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"bad.example", true},
+		{"login.bad.example", true},
+		{"notbad.example", false},
+		{"tracker.example", true},
+		{"phish.example", true},
+		{"upper.example", true},
+		{"example", false},
+		{"0.0.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := b.Contains(tt.host); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	var none *Blocklist
+	if none.Contains("bad.example") || none.Len() != 0 {
+		t.Error("nil Blocklist should list nothing")
+	}
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	c := filepath.Join(dir, "c.txt")
+	if err := os.WriteFile(a, []byte("bad.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(c, []byte("worse.example\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := LoadBlocklist(a, c)
+	if err != nil {
+		t.Fatalf("LoadBlocklist: %v", err)
+	}
+	if !b.Contains("bad.example") || !b.Contains("worse.example") {
+		t.Error("LoadBlocklist should merge every file")
+	}
+
+	if _, err := LoadBlocklist(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("LoadBlocklist of a missing file should fail")
+	}
+}
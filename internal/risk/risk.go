@@ -0,0 +1,327 @@
+// Package risk scores archived email for spam and phishing. Scoring is
+// local: it looks at the headers, the authentication results recorded
+// on delivery, the links in the body and the attachments, and can ask
+// an rspamd server for its opinion too. Nothing about the message
+// leaves the machine unless rspamd is configured.
+package risk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/mailauth"
+	"github.com/wesm/msgvault/internal/mime"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/publicsuffix"
+)
+
+// SuspiciousScore is the score from which a message counts as
+// suspicious, for is:suspicious and 'msgvault risk report'.
+const SuspiciousScore = 5
+
+// Rules a message can be scored by, as recorded in Reason.Rule.
+const (
+	RuleDisplayNameSpoof    = "display-name-spoof"
+	RuleReplyToMismatch     = "reply-to-mismatch"
+	RuleDMARCFail           = "dmarc-fail"
+	RuleSPFFail             = "spf-fail"
+	RuleDKIMFail            = "dkim-fail"
+	RuleLinkTextMismatch    = "link-text-mismatch"
+	RuleIPLink              = "ip-link"
+	RuleBlocklistedLink     = "blocklisted-link"
+	RuleUrgentSubject       = "urgent-subject"
+	RuleDangerousAttachment = "dangerous-attachment"
+	RuleRspamd              = "rspamd"
+)
+
+// Reason is one finding that added to a message's score.
+type Reason struct {
+	Rule   string `json:"rule"`
+	Points int    `json:"points"`
+	Detail string `json:"detail"`
+}
+
+// Result is the risk score of one message: the sum of the points of
+// its reasons.
+type Result struct {
+	Score   int      `json:"score"`
+	Reasons []Reason `json:"reasons,omitempty"`
+}
+
+// Suspicious reports whether the score reaches SuspiciousScore.
+func (r Result) Suspicious() bool {
+	return r.Score >= SuspiciousScore
+}
+
+func (r *Result) add(rule string, points int, format string, args ...any) {
+	r.Score += points
+	r.Reasons = append(r.Reasons, Reason{Rule: rule, Points: points, Detail: fmt.Sprintf(format, args...)})
+}
+
+// urgentPhrases are subject phrases typical of phishing, which pushes
+// the reader to act before thinking.
+var urgentPhrases = []string{
+	"verify your account",
+	"confirm your account",
+	"confirm your identity",
+	"account suspended",
+	"account has been suspended",
+	"account will be closed",
+	"unusual sign-in",
+	"unusual activity",
+	"password expires",
+	"password has expired",
+	"urgent action required",
+	"action required immediately",
+	"final notice",
+}
+
+// dangerousExtensions are attachment types that run code when opened.
+var dangerousExtensions = []string{
+	".exe", ".scr", ".com", ".pif", ".bat", ".cmd", ".msi", ".hta",
+	".js", ".jse", ".vbs", ".vbe", ".wsf", ".ps1", ".jar", ".lnk",
+	".iso", ".img",
+}
+
+// emailInName finds an address written into a display name, as in
+// "support@bank.example" <x@other.example>.
+var emailInName = regexp.MustCompile(`[A-Za-z0-9._%+-]+@([A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)+)`)
+
+// textURL finds http(s) URLs in a plain-text body.
+var textURL = regexp.MustCompile(`https?://[^\s<>"')\]]+`)
+
+// Scorer scores messages. The zero value applies the built-in
+// heuristics only.
+type Scorer struct {
+	// Blocklist, if not nil, lists domains whose links count against a
+	// message.
+	Blocklist *Blocklist
+	// Rspamd, if not nil, adds rspamd's score to each message's.
+	Rspamd *Rspamd
+}
+
+// Score scores a message from its raw MIME and the authentication
+// results recorded for it, which may be nil.
+func (s *Scorer) Score(ctx context.Context, raw []byte, auth *mailauth.Result) (Result, error) {
+	msg, err := mime.Parse(raw)
+	if err != nil {
+		return Result{}, fmt.Errorf("parse message: %w", err)
+	}
+
+	var r Result
+	scoreSender(&r, msg)
+	if auth != nil {
+		scoreAuth(&r, *auth)
+	}
+	s.scoreLinks(&r, msg)
+
+	subject := strings.ToLower(msg.Subject)
+	for _, p := range urgentPhrases {
+		if strings.Contains(subject, p) {
+			r.add(RuleUrgentSubject, 1, "subject contains %q", p)
+			break
+		}
+	}
+
+	for _, att := range msg.Attachments {
+		name := strings.ToLower(strings.TrimSpace(att.Filename))
+		if slices.Contains(dangerousExtensions, path.Ext(name)) {
+			r.add(RuleDangerousAttachment, 5, "executable attachment %s", att.Filename)
+			break
+		}
+	}
+
+	if s.Rspamd != nil {
+		rs, err := s.Rspamd.Check(ctx, raw)
+		if err != nil {
+			return Result{}, fmt.Errorf("rspamd: %w", err)
+		}
+		if points := rs.Points(); points > 0 {
+			r.add(RuleRspamd, points, "rspamd scored %.1f (%s)", rs.Score, rs.Action)
+		}
+	}
+	return r, nil
+}
+
+// scoreSender checks the From and Reply-To headers for a sender
+// pretending to be someone else.
+func scoreSender(r *Result, msg *mime.Message) {
+	if len(msg.From) == 0 {
+		return
+	}
+	from := msg.From[0]
+	fromOrg := orgDomain(from.Domain)
+
+	if m := emailInName.FindStringSubmatch(from.Name); m != nil && orgDomain(m[1]) != fromOrg {
+		r.add(RuleDisplayNameSpoof, 4, "display name shows %s but the address is %s", m[0], from.Email)
+	}
+	for _, rt := range msg.ReplyTo {
+		if rt.Domain != "" && fromOrg != "" && orgDomain(rt.Domain) != fromOrg {
+			r.add(RuleReplyToMismatch, 2, "replies go to %s, not the sender's domain %s", rt.Email, from.Domain)
+			break
+		}
+	}
+}
+
+// scoreAuth counts authentication failures. The receiving server's
+// verdicts are taken at their word; this archive's own DKIM check
+// counts when the server recorded none.
+func scoreAuth(r *Result, auth mailauth.Result) {
+	if auth.ReportedDMARC == mailauth.Fail {
+		r.add(RuleDMARCFail, 3, "DMARC failed at %s", serverName(auth))
+	}
+	switch auth.ReportedSPF {
+	case mailauth.Fail:
+		r.add(RuleSPFFail, 2, "SPF failed at %s", serverName(auth))
+	case "softfail":
+		r.add(RuleSPFFail, 1, "SPF soft-failed at %s", serverName(auth))
+	}
+	switch {
+	case auth.ReportedDKIM == mailauth.Fail:
+		r.add(RuleDKIMFail, 2, "DKIM failed at %s", serverName(auth))
+	case auth.ReportedDKIM == "" && auth.DKIM == mailauth.Fail:
+		r.add(RuleDKIMFail, 2, "DKIM signature of %s does not verify", auth.DKIMDomain)
+	}
+}
+
+func serverName(auth mailauth.Result) string {
+	if auth.AuthServID != "" {
+		return auth.AuthServID
+	}
+	return "the receiving server"
+}
+
+// scoreLinks checks the links in the body: anchors whose text shows a
+// different site than they lead to, links to bare IP addresses, and
+// links to blocklisted domains.
+func (s *Scorer) scoreLinks(r *Result, msg *mime.Message) {
+	anchors := htmlAnchors(msg.BodyHTML)
+
+	for _, a := range anchors {
+		shown := shownDomain(a.text)
+		target := linkHost(a.href)
+		if shown != "" && target != "" && net.ParseIP(target) == nil && orgDomain(shown) != orgDomain(target) {
+			r.add(RuleLinkTextMismatch, 4, "link text shows %s but leads to %s", shown, target)
+			break
+		}
+	}
+
+	hrefs := make([]string, 0, len(anchors))
+	for _, a := range anchors {
+		hrefs = append(hrefs, a.href)
+	}
+	hrefs = append(hrefs, textURL.FindAllString(msg.BodyText, -1)...)
+
+	var ipHost string
+	var listed []string
+	for _, h := range hrefs {
+		host := linkHost(h)
+		if host == "" {
+			continue
+		}
+		if net.ParseIP(host) != nil {
+			if ipHost == "" {
+				ipHost = host
+			}
+			continue
+		}
+		if s.Blocklist.Contains(host) && !slices.Contains(listed, host) {
+			listed = append(listed, host)
+		}
+	}
+	if ipHost != "" {
+		r.add(RuleIPLink, 3, "links to the bare IP address %s", ipHost)
+	}
+	if len(listed) > 0 {
+		r.add(RuleBlocklistedLink, 5, "links to blocklisted %s", strings.Join(listed, ", "))
+	}
+}
+
+type anchor struct {
+	href string
+	text string
+}
+
+// htmlAnchors returns the <a href> links of an HTML body with the text
+// each one shows.
+func htmlAnchors(body string) []anchor {
+	if body == "" {
+		return nil
+	}
+	var anchors []anchor
+	var cur *anchor
+	var text strings.Builder
+	z := html.NewTokenizer(strings.NewReader(body))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return anchors
+		case html.StartTagToken:
+			t := z.Token()
+			if t.DataAtom != atom.A {
+				continue
+			}
+			for _, at := range t.Attr {
+				if at.Key == "href" {
+					cur = &anchor{href: strings.TrimSpace(at.Val)}
+					text.Reset()
+				}
+			}
+		case html.TextToken:
+			if cur != nil {
+				text.Write(z.Text())
+			}
+		case html.EndTagToken:
+			if t := z.Token(); t.DataAtom == atom.A && cur != nil {
+				cur.text = strings.TrimSpace(text.String())
+				anchors = append(anchors, *cur)
+				cur = nil
+			}
+		}
+	}
+}
+
+// shownDomain returns the host a link's text presents as its target,
+// as in "https://bank.example/login" or "www.bank.example", or "" if
+// the text does not look like a web address.
+func shownDomain(text string) string {
+	if strings.ContainsAny(text, " \t\n") || !strings.Contains(text, ".") || strings.Contains(text, "@") {
+		return ""
+	}
+	if !strings.Contains(text, "://") {
+		if !strings.HasPrefix(strings.ToLower(text), "www.") {
+			return ""
+		}
+		text = "http://" + text
+	}
+	return linkHost(text)
+}
+
+// linkHost returns the lowercased host of an http(s) URL, without
+// "www.", or "" for anything else.
+func linkHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// orgDomain returns the registrable domain of host (mail.bank.co.uk
+// gives bank.co.uk), so that subdomains of one organisation compare
+// equal.
+func orgDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
@@ -0,0 +1,155 @@
+package risk
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mailauth"
+)
+
+// message builds a raw email from header lines and an HTML body.
+func message(headers []string, htmlBody string) []byte {
+	return []byte(strings.Join(headers, "\r\n") +
+		"\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=utf-8\r\n\r\n" + htmlBody + "\r\n")
+}
+
+func rules(r Result) []string {
+	var out []string
+	for _, reason := range r.Reasons {
+		out = append(out, reason.Rule)
+	}
+	return out
+}
+
+func TestScore(t *testing.T) {
+	blocklist, err := ParseBlocklist(strings.NewReader("bad.example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Scorer{Blocklist: blocklist}
+
+	tests := []struct {
+		name      string
+		headers   []string
+		body      string
+		auth      *mailauth.Result
+		wantRules []string
+	}{
+		{
+			name:    "clean",
+			headers: []string{"From: Alice <alice@example.com>", "Reply-To: alice@mail.example.com", "Subject: Lunch"},
+			body:    `<p>See <a href="https://example.com/menu">https://www.example.com/menu</a></p>`,
+			auth:    &mailauth.Result{ReportedDKIM: mailauth.Pass, ReportedSPF: mailauth.Pass, ReportedDMARC: mailauth.Pass},
+		},
+		{
+			name:      "display name spoof",
+			headers:   []string{`From: "support@bank.example" <bob@phish.example>`, "Subject: Hello"},
+			body:      "<p>hi</p>",
+			wantRules: []string{RuleDisplayNameSpoof},
+		},
+		{
+			name:      "reply-to elsewhere",
+			headers:   []string{"From: bank@bank.example", "Reply-To: bank@freemail.example", "Subject: Hello"},
+			body:      "<p>hi</p>",
+			wantRules: []string{RuleReplyToMismatch},
+		},
+		{
+			name:      "auth failures",
+			headers:   []string{"From: bank@bank.example", "Subject: Hello"},
+			body:      "<p>hi</p>",
+			auth:      &mailauth.Result{AuthServID: "mx.example.net", ReportedSPF: "softfail", ReportedDMARC: mailauth.Fail, ReportedDKIM: mailauth.Fail},
+			wantRules: []string{RuleDMARCFail, RuleSPFFail, RuleDKIMFail},
+		},
+		{
+			name:      "own dkim check fails",
+			headers:   []string{"From: bank@bank.example", "Subject: Hello"},
+			body:      "<p>hi</p>",
+			auth:      &mailauth.Result{DKIM: mailauth.Fail, DKIMDomain: "bank.example"},
+			wantRules: []string{RuleDKIMFail},
+		},
+		{
+			name:      "link text mismatch",
+			headers:   []string{"From: bank@bank.example", "Subject: Hello"},
+			body:      `<a href="https://login.phish.example/x">https://www.bank.example/login</a>`,
+			wantRules: []string{RuleLinkTextMismatch},
+		},
+		{
+			name:      "ip and blocklisted links",
+			headers:   []string{"From: bank@bank.example", "Subject: Your account suspended"},
+			body:      `<a href="http://192.0.2.7/login">here</a> <a href="https://cdn.bad.example/a">there</a>`,
+			wantRules: []string{RuleIPLink, RuleBlocklistedLink, RuleUrgentSubject},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Score(context.Background(), message(tt.headers, tt.body), tt.auth)
+			if err != nil {
+				t.Fatalf("Score: %v", err)
+			}
+			if !slices.Equal(rules(got), tt.wantRules) {
+				t.Errorf("rules = %v, want %v", rules(got), tt.wantRules)
+			}
+			sum := 0
+			for _, r := range got.Reasons {
+				sum += r.Points
+			}
+			if got.Score != sum {
+				t.Errorf("Score = %d, want the sum of its reasons %d", got.Score, sum)
+			}
+		})
+	}
+}
+
+func TestScore_DangerousAttachment(t *testing.T) {
+	raw := []byte("From: alice@example.com\r\nSubject: Invoice\r\nMIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=b\r\n\r\n" +
+		"--b\r\nContent-Type: text/plain\r\n\r\nPlease see the invoice.\r\n" +
+		"--b\r\nContent-Type: application/octet-stream\r\n" +
+		"Content-Disposition: attachment; filename=\"invoice.pdf.exe\"\r\n\r\nMZ\r\n" +
+		"--b--\r\n")
+	got, err := (&Scorer{}).Score(context.Background(), raw, nil)
+	if err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	if !slices.Equal(rules(got), []string{RuleDangerousAttachment}) {
+		t.Errorf("rules = %v, want [%s]", rules(got), RuleDangerousAttachment)
+	}
+	if !got.Suspicious() {
+		t.Errorf("Suspicious() = false with score %d", got.Score)
+	}
+}
+
+func TestScore_PlainTextLinks(t *testing.T) {
+	blocklist, err := ParseBlocklist(strings.NewReader("bad.example\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := []byte("From: alice@example.com\r\nSubject: Hi\r\n\r\nLog in at https://bad.example/login now.\r\n")
+	got, err := (&Scorer{Blocklist: blocklist}).Score(context.Background(), raw, nil)
+	if err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	if !slices.Equal(rules(got), []string{RuleBlocklistedLink}) {
+		t.Errorf("rules = %v, want [%s]", rules(got), RuleBlocklistedLink)
+	}
+}
+
+func TestShownDomain(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"https://www.bank.example/login", "bank.example"},
+		{"www.bank.example", "bank.example"},
+		{"bank.example", ""}, // too often a plain word with a dot
+		{"Click here", ""},
+		{"alice@example.com", ""},
+	}
+	for _, tt := range tests {
+		if got := shownDomain(tt.text); got != tt.want {
+			t.Errorf("shownDomain(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
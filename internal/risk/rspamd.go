@@ -0,0 +1,70 @@
+package risk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rspamdTimeout bounds one rspamd check.
+const rspamdTimeout = 30 * time.Second
+
+// Rspamd asks an rspamd worker to score messages, through its
+// /checkv2 HTTP endpoint.
+type Rspamd struct {
+	url    string
+	client *http.Client
+}
+
+// NewRspamd returns a client for the rspamd worker at baseURL, such as
+// http://localhost:11333.
+func NewRspamd(baseURL string) *Rspamd {
+	return &Rspamd{
+		url:    strings.TrimSuffix(baseURL, "/") + "/checkv2",
+		client: &http.Client{Timeout: rspamdTimeout},
+	}
+}
+
+// RspamdResult is rspamd's verdict on a message.
+type RspamdResult struct {
+	Score         float64 `json:"score"`
+	RequiredScore float64 `json:"required_score"`
+	Action        string  `json:"action"` // e.g. "no action", "add header", "reject"
+}
+
+// Points converts rspamd's score to risk points: its score rounded,
+// with a negative (ham) score counting nothing.
+func (r RspamdResult) Points() int {
+	if r.Score <= 0 {
+		return 0
+	}
+	return int(math.Round(r.Score))
+}
+
+// Check sends a message to rspamd and returns its verdict.
+func (r *Rspamd) Check(ctx context.Context, raw []byte) (RspamdResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(raw))
+	if err != nil {
+		return RspamdResult{}, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return RspamdResult{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return RspamdResult{}, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var res RspamdResult
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&res); err != nil {
+		return RspamdResult{}, fmt.Errorf("decode response: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRspamd(t *testing.T) {
+	var gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, _ = io.WriteString(w, `{"score": 7.6, "required_score": 15, "action": "add header", "symbols": {}}`)
+	}))
+	defer srv.Close()
+
+	raw := []byte("From: alice@example.com\r\nSubject: Hi\r\n\r\nHello.\r\n")
+	got, err := (&Scorer{Rspamd: NewRspamd(srv.URL + "/")}).Score(context.Background(), raw, nil)
+	if err != nil {
+		t.Fatalf("Score: %v", err)
+	}
+	if gotPath != "/checkv2" {
+		t.Errorf("path = %q, want /checkv2", gotPath)
+	}
+	if gotBody != string(raw) {
+		t.Errorf("rspamd got body %q, want the raw message", gotBody)
+	}
+	if !slices.Equal(rules(got), []string{RuleRspamd}) || got.Score != 8 {
+		t.Errorf("got %+v, want one rspamd reason scoring 8", got)
+	}
+}
+
+func TestRspamd_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	_, err := NewRspamd(srv.URL).Check(context.Background(), []byte("Subject: x\r\n\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("Check error = %v, want the server's message", err)
+	}
+}
+
+func TestRspamdResult_Points(t *testing.T) {
+	tests := []struct {
+		score float64
+		want  int
+	}{
+		{-3.2, 0},
+		{0, 0},
+		{2.4, 2},
+		{2.5, 3},
+	}
+	for _, tt := range tests {
+		if got := (RspamdResult{Score: tt.score}).Points(); got != tt.want {
+			t.Errorf("Points() for %v = %d, want %d", tt.score, got, tt.want)
+		}
+	}
+}
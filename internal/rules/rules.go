@@ -0,0 +1,238 @@
+// Package rules applies Gmail-filter-style rules to newly synced mail.
+// Each rule is a search query plus actions: apply a vault-local label,
+// file the message under a category tab, drop its extracted attachment
+// files, or report the match. Rules run right after messages are
+// stored, by the sync commands and as a post-sync hook in the daemon.
+package rules
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// pageSize is how many matches are fetched per search page.
+const pageSize = 500
+
+// Store is the subset of store.Store the engine needs.
+type Store interface {
+	MaxMessageID() (int64, error)
+	SearchMessagesQuery(q *search.Query, offset, limit int) ([]store.APIMessage, int64, error)
+	EnsureLocalLabel(name string) (int64, error)
+	LabelMessages(labelID int64, messageIDs []int64) error
+	CategorizeMessages(category string, messageIDs []int64) error
+	DropAttachments(messageIDs []int64) ([]string, error)
+}
+
+// Match reports the messages one rule matched in a run.
+type Match struct {
+	Rule       string
+	Notify     bool
+	Messages   []store.APIMessage
+	FilesFreed int // attachment files deleted by skip_attachments
+}
+
+type rule struct {
+	cfg   config.RuleConfig
+	query *search.Query
+}
+
+// Engine applies the configured rules.
+type Engine struct {
+	store          Store
+	rules          []rule
+	attachmentsDir string
+	logger         *slog.Logger
+	notify         func(ctx context.Context, m Match)
+
+	mu        sync.Mutex // serializes hook runs so each message is seen once
+	watermark int64      // highest message ID the hook has considered
+}
+
+// New validates the rule configs and returns an engine. attachmentsDir
+// is where skip_attachments deletes files from.
+func New(cfgs []config.RuleConfig, st Store, attachmentsDir string, logger *slog.Logger) (*Engine, error) {
+	e := &Engine{store: st, attachmentsDir: attachmentsDir, logger: logger}
+	names := make(map[string]bool)
+	for i, c := range cfgs {
+		if err := validate(c); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("rules[%d]: duplicate name %q", i, c.Name)
+		}
+		names[c.Name] = true
+		q := search.Parse(c.Query)
+		q.HideDeleted = true
+		e.rules = append(e.rules, rule{cfg: c, query: q})
+	}
+	return e, nil
+}
+
+func validate(c config.RuleConfig) error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(c.Query) == "" {
+		return fmt.Errorf("%s: query is required", c.Name)
+	}
+	if c.Category != "" && !slices.Contains(config.RuleCategories, c.Category) {
+		return fmt.Errorf("%s: category must be one of %s, got %q",
+			c.Name, strings.Join(config.RuleCategories, ", "), c.Category)
+	}
+	return nil
+}
+
+// SetNotify sets the function called with each match of a rule that
+// has notify set.
+func (e *Engine) SetNotify(fn func(ctx context.Context, m Match)) {
+	e.notify = fn
+}
+
+// Len returns the number of rules.
+func (e *Engine) Len() int { return len(e.rules) }
+
+// Apply runs every rule, in order, over the messages with
+// low < id <= high and returns the rules that matched anything. A
+// rule that fails is reported in the returned error; the remaining
+// rules still run.
+func (e *Engine) Apply(ctx context.Context, low, high int64) ([]Match, error) {
+	var matches []Match
+	var errs []error
+	for _, r := range e.rules {
+		if err := ctx.Err(); err != nil {
+			return matches, err
+		}
+		msgs, err := Matching(e.store, r.query, low, high)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.cfg.Name, err))
+			continue
+		}
+		if len(msgs) == 0 {
+			continue
+		}
+		m, err := e.act(r, msgs)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", r.cfg.Name, err))
+		}
+		if m.Notify && e.notify != nil {
+			e.notify(ctx, m)
+		}
+		matches = append(matches, m)
+	}
+	return matches, errors.Join(errs...)
+}
+
+// act applies r's actions to msgs.
+func (e *Engine) act(r rule, msgs []store.APIMessage) (Match, error) {
+	m := Match{Rule: r.cfg.Name, Notify: r.cfg.Notify, Messages: msgs}
+	ids := make([]int64, len(msgs))
+	for i := range msgs {
+		ids[i] = msgs[i].ID
+	}
+
+	if r.cfg.Label != "" {
+		labelID, err := e.store.EnsureLocalLabel(r.cfg.Label)
+		if err != nil {
+			return m, err
+		}
+		if err := e.store.LabelMessages(labelID, ids); err != nil {
+			return m, fmt.Errorf("apply label %q: %w", r.cfg.Label, err)
+		}
+	}
+	if r.cfg.Category != "" {
+		if err := e.store.CategorizeMessages(r.cfg.Category, ids); err != nil {
+			return m, fmt.Errorf("set category %s: %w", r.cfg.Category, err)
+		}
+	}
+	if r.cfg.SkipAttachments {
+		paths, err := e.store.DropAttachments(ids)
+		if err != nil {
+			return m, fmt.Errorf("drop attachments: %w", err)
+		}
+		for _, p := range paths {
+			if err := os.Remove(filepath.Join(e.attachmentsDir, p)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				e.logger.Warn("rules: failed to delete attachment file", "rule", r.cfg.Name, "path", p, "error", err)
+				continue
+			}
+			m.FilesFreed++
+		}
+	}
+	if r.cfg.Notify {
+		for _, msg := range msgs {
+			e.logger.Info("rule matched", "rule", r.cfg.Name, "id", msg.ID, "from", msg.From, "subject", msg.Subject)
+		}
+	}
+	return m, nil
+}
+
+// PostSyncHook returns a scheduler.PostSyncHook that applies the rules
+// to every message added since the previous run, starting with mail
+// synced after this call. Failures are logged.
+func (e *Engine) PostSyncHook() (func(ctx context.Context, account string), error) {
+	wm, err := e.store.MaxMessageID()
+	if err != nil {
+		return nil, err
+	}
+	e.watermark = wm
+	return func(ctx context.Context, account string) {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		high, err := e.store.MaxMessageID()
+		if err != nil {
+			e.logger.Error("rules: failed to read message watermark", "error", err)
+			return
+		}
+		if high <= e.watermark {
+			return
+		}
+		low := e.watermark
+		e.watermark = high
+
+		matches, err := e.Apply(ctx, low, high)
+		for _, m := range matches {
+			e.logger.Info("rule applied", "rule", m.Rule, "account", account, "messages", len(m.Messages))
+		}
+		if err != nil {
+			e.logger.Error("rules: apply failed", "account", account, "error", err)
+		}
+	}, nil
+}
+
+// Matching returns the messages with low < id <= high that match q,
+// oldest first. A high of zero means no upper bound.
+func Matching(st Store, q *search.Query, low, high int64) ([]store.APIMessage, error) {
+	qc := *q
+	qc.AfterMessageID = low
+	var out []store.APIMessage
+	for offset := 0; ; offset += pageSize {
+		page, _, err := st.SearchMessagesQuery(&qc, offset, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page {
+			if high == 0 || m.ID <= high {
+				out = append(out, m)
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	slices.SortFunc(out, func(a, b store.APIMessage) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return out, nil
+}
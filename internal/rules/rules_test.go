@@ -0,0 +1,254 @@
+package rules
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// fakeStore matches messages by subject substring and records the
+// actions applied to them.
+type fakeStore struct {
+	mu         sync.Mutex
+	messages   []store.APIMessage
+	labels     map[string]int64
+	labelled   map[int64][]int64 // label ID -> message IDs
+	categories map[string][]int64
+	dropped    []int64
+	orphans    []string // returned by DropAttachments
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		labels:     make(map[string]int64),
+		labelled:   make(map[int64][]int64),
+		categories: make(map[string][]int64),
+	}
+}
+
+func (f *fakeStore) add(id int64, subject string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, store.APIMessage{ID: id, Subject: subject, From: "bob@example.com"})
+}
+
+func (f *fakeStore) MaxMessageID() (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m int64
+	for _, msg := range f.messages {
+		m = max(m, msg.ID)
+	}
+	return m, nil
+}
+
+func (f *fakeStore) SearchMessagesQuery(q *search.Query, offset, limit int) ([]store.APIMessage, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var hits []store.APIMessage
+	for _, m := range f.messages {
+		if m.ID <= q.AfterMessageID {
+			continue
+		}
+		if len(q.SubjectTerms) > 0 && !strings.Contains(m.Subject, q.SubjectTerms[0]) {
+			continue
+		}
+		hits = append(hits, m)
+	}
+	if offset >= len(hits) {
+		return nil, int64(len(hits)), nil
+	}
+	return hits[offset:min(offset+limit, len(hits))], int64(len(hits)), nil
+}
+
+func (f *fakeStore) EnsureLocalLabel(name string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id, ok := f.labels[name]; ok {
+		return id, nil
+	}
+	id := int64(len(f.labels) + 1)
+	f.labels[name] = id
+	return id, nil
+}
+
+func (f *fakeStore) LabelMessages(labelID int64, ids []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.labelled[labelID] = append(f.labelled[labelID], ids...)
+	return nil
+}
+
+func (f *fakeStore) CategorizeMessages(category string, ids []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.categories[category] = append(f.categories[category], ids...)
+	return nil
+}
+
+func (f *fakeStore) DropAttachments(ids []int64) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropped = append(f.dropped, ids...)
+	return f.orphans, nil
+}
+
+func newTestEngine(t *testing.T, st Store, dir string, cfgs ...config.RuleConfig) *Engine {
+	t.Helper()
+	e, err := New(cfgs, st, dir, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return e
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfgs    []config.RuleConfig
+		wantErr string
+	}{
+		{"valid", []config.RuleConfig{{Name: "receipts", Query: "subject:receipt", Label: "Receipts"}}, ""},
+		{"missing name", []config.RuleConfig{{Query: "subject:receipt"}}, "name is required"},
+		{"missing query", []config.RuleConfig{{Name: "all", Label: "x"}}, "query is required"},
+		{"bad category", []config.RuleConfig{{Name: "a", Query: "x", Category: "spam"}}, "category must be one of"},
+		{"duplicate", []config.RuleConfig{{Name: "a", Query: "x"}, {Name: "a", Query: "y"}}, "duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfgs, newFakeStore(), t.TempDir(), slog.Default())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("New() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("New() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApply_Actions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "aa"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "aa", "hash"), []byte("pdf"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	st := newFakeStore()
+	st.orphans = []string{"aa/hash", "bb/already-gone"}
+	st.add(1, "Your receipt")
+	st.add(2, "Weekly newsletter")
+	st.add(3, "Another receipt")
+
+	e := newTestEngine(t, st, dir,
+		config.RuleConfig{Name: "receipts", Query: "subject:receipt", Label: "Receipts", SkipAttachments: true},
+		config.RuleConfig{Name: "news", Query: "subject:newsletter", Category: "updates", Notify: true},
+		config.RuleConfig{Name: "nothing", Query: "subject:lottery", Label: "Lottery"},
+	)
+
+	var notified []string
+	e.SetNotify(func(_ context.Context, m Match) { notified = append(notified, m.Rule) })
+
+	matches, err := e.Apply(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if !slices.Equal(notified, []string{"news"}) {
+		t.Errorf("notified %v, want only the notify rule", notified)
+	}
+	if len(matches) != 2 {
+		t.Fatalf("got %d matches, want 2: %+v", len(matches), matches)
+	}
+	if matches[0].Rule != "receipts" || len(matches[0].Messages) != 2 || matches[0].FilesFreed != 2 {
+		t.Errorf("receipts match = %+v", matches[0])
+	}
+	if matches[1].Rule != "news" || !matches[1].Notify {
+		t.Errorf("news match = %+v", matches[1])
+	}
+
+	if got := st.labelled[st.labels["Receipts"]]; !slices.Equal(got, []int64{1, 3}) {
+		t.Errorf("Receipts labelled %v, want [1 3]", got)
+	}
+	if _, ok := st.labels["Lottery"]; ok {
+		t.Error("label created for a rule that matched nothing")
+	}
+	if got := st.categories["updates"]; !slices.Equal(got, []int64{2}) {
+		t.Errorf("updates category = %v, want [2]", got)
+	}
+	if !slices.Equal(st.dropped, []int64{1, 3}) {
+		t.Errorf("dropped attachments of %v, want [1 3]", st.dropped)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "aa", "hash")); !os.IsNotExist(err) {
+		t.Errorf("attachment file still exists: %v", err)
+	}
+}
+
+func TestApply_Range(t *testing.T) {
+	st := newFakeStore()
+	for id := int64(1); id <= 5; id++ {
+		st.add(id, "receipt")
+	}
+	e := newTestEngine(t, st, t.TempDir(),
+		config.RuleConfig{Name: "receipts", Query: "subject:receipt", Label: "Receipts"})
+
+	if _, err := e.Apply(context.Background(), 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	if got := st.labelled[st.labels["Receipts"]]; !slices.Equal(got, []int64{3, 4}) {
+		t.Errorf("labelled %v, want [3 4]", got)
+	}
+}
+
+func TestPostSyncHook_OnlyNewMessages(t *testing.T) {
+	st := newFakeStore()
+	st.add(1, "old receipt")
+
+	e := newTestEngine(t, st, t.TempDir(),
+		config.RuleConfig{Name: "receipts", Query: "subject:receipt", Label: "Receipts"})
+	hook, err := e.PostSyncHook()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook(context.Background(), "alice@example.com")
+	if len(st.labelled) != 0 {
+		t.Fatalf("pre-existing mail was labelled: %v", st.labelled)
+	}
+
+	st.add(2, "new receipt")
+	hook(context.Background(), "alice@example.com")
+	hook(context.Background(), "alice@example.com")
+	if got := st.labelled[st.labels["Receipts"]]; !slices.Equal(got, []int64{2}) {
+		t.Errorf("labelled %v, want [2] exactly once", got)
+	}
+}
+
+func TestMatching_Pages(t *testing.T) {
+	st := newFakeStore()
+	for id := int64(1); id <= pageSize+10; id++ {
+		st.add(id, "receipt")
+	}
+	q := search.Parse("subject:receipt")
+	got, err := Matching(st, q, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != pageSize+10 || got[0].ID != 1 || got[len(got)-1].ID != pageSize+10 {
+		t.Errorf("got %d matches (first %d), want %d in ID order", len(got), got[0].ID, pageSize+10)
+	}
+}
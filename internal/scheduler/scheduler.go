@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -16,6 +17,15 @@ import (
 // It receives the account email and should perform incremental sync + cache build.
 type SyncFunc func(ctx context.Context, email string) error
 
+// PostSyncHook runs after each successful sync of an account, in the
+// sync's goroutine. Hooks must respect ctx cancellation so Stop can
+// drain promptly.
+type PostSyncHook func(ctx context.Context, email string)
+
+// RunHook runs after every sync of an account, successful or not, with
+// the account's updated status.
+type RunHook func(status AccountStatus)
+
 // AccountStatus represents the sync status of a scheduled account.
 type AccountStatus struct {
 	Email     string    `json:"email"`
@@ -24,6 +34,14 @@ type AccountStatus struct {
 	NextRun   time.Time `json:"next_run"`
 	Schedule  string    `json:"schedule"`
 	LastError string    `json:"last_error,omitempty"`
+
+	// LastAttempt is when the latest sync, successful or not, started,
+	// and LastDurationMS how long it took.
+	LastAttempt    time.Time `json:"last_attempt,omitempty"`
+	LastDurationMS int64     `json:"last_duration_ms,omitempty"`
+
+	// Jitter is the most each scheduled sync is delayed by, e.g. "5m".
+	Jitter string `json:"jitter,omitempty"`
 }
 
 // Scheduler manages cron-based email sync scheduling.
@@ -38,6 +56,12 @@ type Scheduler struct {
 	running   map[string]bool         // email -> currently syncing
 	lastRun   map[string]time.Time    // email -> last successful run
 	lastErr   map[string]error        // email -> last error
+	attempted map[string]time.Time    // email -> start of last run
+	took      map[string]time.Duration
+	jitter    map[string]time.Duration // email -> most a scheduled run is delayed
+
+	// randDelay picks a delay in [0, max); swapped in tests.
+	randDelay func(max time.Duration) time.Duration
 
 	// Embed job state (optional). Set via SetEmbedJob; cron.EntryID 0
 	// may be valid, so embedEntrySet tracks whether an entry exists.
@@ -46,6 +70,9 @@ type Scheduler struct {
 	embedEntrySet     bool
 	runEmbedAfterSync bool
 
+	postSyncHooks []PostSyncHook
+	runHooks      []RunHook
+
 	ctx     context.Context    // cancelled on Stop
 	cancel  context.CancelFunc // cancels ctx
 	wg      sync.WaitGroup     // tracks running sync goroutines
@@ -67,8 +94,14 @@ func New(syncFunc SyncFunc) *Scheduler {
 		running:   make(map[string]bool),
 		lastRun:   make(map[string]time.Time),
 		lastErr:   make(map[string]error),
-		ctx:       ctx,
-		cancel:    cancel,
+		attempted: make(map[string]time.Time),
+		took:      make(map[string]time.Duration),
+		jitter:    make(map[string]time.Duration),
+		randDelay: func(max time.Duration) time.Duration {
+			return time.Duration(rand.Int64N(int64(max)))
+		},
+		ctx:    ctx,
+		cancel: cancel,
 	}
 }
 
@@ -81,6 +114,15 @@ func (s *Scheduler) WithLogger(logger *slog.Logger) *Scheduler {
 // AddAccount schedules sync for an account using the given cron expression.
 // Returns an error if the cron expression is invalid.
 func (s *Scheduler) AddAccount(email, cronExpr string) error {
+	return s.AddAccountWithJitter(email, cronExpr, 0)
+}
+
+// AddAccountWithJitter is AddAccount with each scheduled sync delayed
+// by a random amount up to jitter, so accounts sharing a schedule
+// don't all sync at once. A delayed sync counts as running, so the
+// next tick can't start another. Syncs started by TriggerSync are not
+// delayed.
+func (s *Scheduler) AddAccountWithJitter(email, cronExpr string, jitter time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -89,6 +131,7 @@ func (s *Scheduler) AddAccount(email, cronExpr string) error {
 		s.cron.Remove(entryID)
 		delete(s.jobs, email)
 		delete(s.schedules, email)
+		delete(s.jitter, email)
 	}
 
 	// Validate and add the cron job
@@ -100,8 +143,12 @@ func (s *Scheduler) AddAccount(email, cronExpr string) error {
 		}
 		s.running[email] = true
 		s.wg.Add(1)
+		var delay time.Duration
+		if max := s.jitter[email]; max > 0 {
+			delay = s.randDelay(max)
+		}
 		s.mu.Unlock()
-		s.runSync(email)
+		s.runSync(email, delay)
 	})
 	if err != nil {
 		return fmt.Errorf("invalid cron expression %q: %w", cronExpr, err)
@@ -109,9 +156,13 @@ func (s *Scheduler) AddAccount(email, cronExpr string) error {
 
 	s.jobs[email] = entryID
 	s.schedules[email] = cronExpr
+	if jitter > 0 {
+		s.jitter[email] = jitter
+	}
 	s.logger.Info("scheduled sync",
 		"email", email,
 		"schedule", cronExpr,
+		"jitter", jitter,
 		"next_run", s.cron.Entry(entryID).Next)
 
 	return nil
@@ -124,7 +175,7 @@ func (s *Scheduler) AddAccountsFromConfig(cfg *config.Config) (int, []error) {
 	scheduled := 0
 
 	for _, acc := range cfg.ScheduledAccounts() {
-		if err := s.AddAccount(acc.Email, acc.Schedule); err != nil {
+		if err := s.AddAccountWithJitter(acc.Email, acc.Schedule, cfg.AccountJitter(acc)); err != nil {
 			errors = append(errors, fmt.Errorf("%s: %w", acc.Email, err))
 		} else {
 			scheduled++
@@ -143,6 +194,7 @@ func (s *Scheduler) RemoveAccount(email string) {
 		s.cron.Remove(entryID)
 		delete(s.jobs, email)
 		delete(s.schedules, email)
+		delete(s.jitter, email)
 		s.logger.Info("removed schedule", "email", email)
 	}
 }
@@ -202,6 +254,44 @@ func (s *Scheduler) SetEmbedJob(job *EmbedJob, schedule string, runAfterSync boo
 	return nil
 }
 
+// AddPostSyncHook registers fn to run after every successful sync.
+// Hooks run in registration order, after the post-sync embed job.
+func (s *Scheduler) AddPostSyncHook(fn PostSyncHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.postSyncHooks = append(s.postSyncHooks, fn)
+}
+
+// AddRunHook registers fn to run after every sync, successful or not.
+func (s *Scheduler) AddRunHook(fn RunHook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runHooks = append(s.runHooks, fn)
+}
+
+// AddJob runs fn on a cron schedule alongside the syncs, for periodic
+// work that belongs to no account. A run still going when the next is
+// due makes that one skip.
+func (s *Scheduler) AddJob(name, schedule string, fn func(ctx context.Context)) error {
+	if err := ValidateCronExpr(schedule); err != nil {
+		return fmt.Errorf("invalid %s cron expression %q: %w", name, schedule, err)
+	}
+	var running sync.Mutex
+	entryID, err := s.cron.AddFunc(schedule, func() {
+		if s.isStopped() || !running.TryLock() {
+			return
+		}
+		defer running.Unlock()
+		fn(s.ctx)
+	})
+	if err != nil {
+		return fmt.Errorf("register %s cron: %w", name, err)
+	}
+	s.logger.Info("scheduled job", "job", name, "schedule", schedule,
+		"next_run", s.cron.Entry(entryID).Next)
+	return nil
+}
+
 // isStopped reports s.stopped under a read lock. Used by cron
 // callbacks that only need to abort on shutdown.
 func (s *Scheduler) isStopped() bool {
@@ -257,9 +347,10 @@ func (s *Scheduler) Stop() context.Context {
 	return ctx
 }
 
-// runSync executes sync for an account (called by cron or TriggerSync).
-// The caller must have already called wg.Add(1) and set running[email] = true.
-func (s *Scheduler) runSync(email string) {
+// runSync executes sync for an account (called by cron or TriggerSync),
+// after waiting delay. The caller must have already called wg.Add(1)
+// and set running[email] = true.
+func (s *Scheduler) runSync(email string, delay time.Duration) {
 	defer s.wg.Done()
 	defer func() {
 		s.mu.Lock()
@@ -267,12 +358,25 @@ func (s *Scheduler) runSync(email string) {
 		s.mu.Unlock()
 	}()
 
+	if delay > 0 {
+		s.logger.Debug("delaying scheduled sync", "email", email, "jitter", delay)
+		t := time.NewTimer(delay)
+		select {
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+	}
+
 	s.logger.Info("starting scheduled sync", "email", email)
 	start := time.Now()
 
 	err := s.syncFunc(s.ctx, email)
 
 	s.mu.Lock()
+	s.attempted[email] = start
+	s.took[email] = time.Since(start)
 	if err != nil {
 		s.lastErr[email] = err
 		s.logger.Error("scheduled sync failed",
@@ -286,7 +390,16 @@ func (s *Scheduler) runSync(email string) {
 			"email", email,
 			"duration", time.Since(start))
 	}
+	runHooks := s.runHooks
+	var status AccountStatus
+	if len(runHooks) > 0 {
+		status = s.statusLocked(email)
+		status.Running = false // this run is over
+	}
 	s.mu.Unlock()
+	for _, hook := range runHooks {
+		hook(status)
+	}
 
 	// Post-sync embed hook: only fire on successful sync, and only
 	// when configured. Runs synchronously in this goroutine so it's
@@ -299,10 +412,17 @@ func (s *Scheduler) runSync(email string) {
 	if s.runEmbedAfterSync && s.embedJob != nil && !s.stopped {
 		postSync = s.embedJob
 	}
+	hooks := s.postSyncHooks
 	s.mu.RUnlock()
 	if postSync != nil {
 		postSync.Run(s.ctx)
 	}
+	for _, hook := range hooks {
+		if s.ctx.Err() != nil {
+			return
+		}
+		hook(s.ctx, email)
+	}
 }
 
 // IsScheduled returns true if the account has been added to the scheduler.
@@ -333,7 +453,7 @@ func (s *Scheduler) TriggerSync(email string) error {
 
 	s.running[email] = true
 	s.wg.Add(1)
-	go s.runSync(email)
+	go s.runSync(email, 0)
 	return nil
 }
 
@@ -343,23 +463,35 @@ func (s *Scheduler) Status() []AccountStatus {
 	defer s.mu.RUnlock()
 
 	var statuses []AccountStatus
-	for email, entryID := range s.jobs {
-		entry := s.cron.Entry(entryID)
-		status := AccountStatus{
-			Email:    email,
-			Running:  s.running[email],
-			LastRun:  s.lastRun[email],
-			NextRun:  entry.Next,
-			Schedule: s.schedules[email],
-		}
-		if err := s.lastErr[email]; err != nil {
-			status.LastError = err.Error()
-		}
-		statuses = append(statuses, status)
+	for email := range s.jobs {
+		statuses = append(statuses, s.statusLocked(email))
 	}
 	return statuses
 }
 
+// statusLocked returns a scheduled account's status. The caller must
+// hold s.mu.
+func (s *Scheduler) statusLocked(email string) AccountStatus {
+	status := AccountStatus{
+		Email:          email,
+		Running:        s.running[email],
+		LastRun:        s.lastRun[email],
+		Schedule:       s.schedules[email],
+		LastAttempt:    s.attempted[email],
+		LastDurationMS: s.took[email].Milliseconds(),
+	}
+	if entryID, ok := s.jobs[email]; ok {
+		status.NextRun = s.cron.Entry(entryID).Next
+	}
+	if err := s.lastErr[email]; err != nil {
+		status.LastError = err.Error()
+	}
+	if j := s.jitter[email]; j > 0 {
+		status.Jitter = j.String()
+	}
+	return status
+}
+
 // ValidateCronExpr validates a cron expression without scheduling anything.
 func ValidateCronExpr(expr string) error {
 	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
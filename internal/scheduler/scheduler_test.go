@@ -434,6 +434,149 @@ func TestStatusAfterSyncError(t *testing.T) {
 	t.Error("test@gmail.com not found in status")
 }
 
+func TestPostSyncHooks(t *testing.T) {
+	failing := errors.New("sync failed")
+	s := New(func(ctx context.Context, email string) error {
+		if email == "bad@gmail.com" {
+			return failing
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	var calls []string
+	s.AddPostSyncHook(func(ctx context.Context, email string) {
+		mu.Lock()
+		calls = append(calls, "first:"+email)
+		mu.Unlock()
+	})
+	s.AddPostSyncHook(func(ctx context.Context, email string) {
+		mu.Lock()
+		calls = append(calls, "second:"+email)
+		mu.Unlock()
+	})
+
+	for _, email := range []string{"good@gmail.com", "bad@gmail.com"} {
+		if err := s.AddAccount(email, "0 0 1 1 *"); err != nil {
+			t.Fatalf("AddAccount(%s): %v", email, err)
+		}
+		if err := s.TriggerSync(email); err != nil {
+			t.Fatalf("TriggerSync(%s): %v", email, err)
+		}
+	}
+	s.wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"first:good@gmail.com", "second:good@gmail.com"}
+	if len(calls) != len(want) || calls[0] != want[0] || calls[1] != want[1] {
+		t.Errorf("hook calls = %v, want %v (hooks must not run after failed syncs)", calls, want)
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	s := New(func(ctx context.Context, email string) error {
+		if email == "bad@gmail.com" {
+			return errors.New("sync failed")
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	got := make(map[string]AccountStatus)
+	s.AddRunHook(func(status AccountStatus) {
+		mu.Lock()
+		got[status.Email] = status
+		mu.Unlock()
+	})
+	for _, email := range []string{"good@gmail.com", "bad@gmail.com"} {
+		if err := s.AddAccount(email, "0 0 1 1 *"); err != nil {
+			t.Fatalf("AddAccount(%s): %v", email, err)
+		}
+		if err := s.TriggerSync(email); err != nil {
+			t.Fatalf("TriggerSync(%s): %v", email, err)
+		}
+	}
+	s.wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	good, bad := got["good@gmail.com"], got["bad@gmail.com"]
+	if good.LastRun.IsZero() || good.LastAttempt.IsZero() || good.LastError != "" || good.Running {
+		t.Errorf("good status = %+v, want a finished successful run", good)
+	}
+	if bad.LastError != "sync failed" || bad.LastAttempt.IsZero() || !bad.LastRun.IsZero() {
+		t.Errorf("bad status = %+v, want a failed attempt and no successful run", bad)
+	}
+}
+
+func TestJitterDelaysScheduledSync(t *testing.T) {
+	started := make(chan time.Time, 2)
+	s := New(func(ctx context.Context, email string) error {
+		started <- time.Now()
+		return nil
+	})
+	s.randDelay = func(max time.Duration) time.Duration {
+		if max != time.Minute {
+			t.Errorf("randDelay(%s), want the account's 1m jitter", max)
+		}
+		return 50 * time.Millisecond
+	}
+	if err := s.AddAccountWithJitter("test@gmail.com", "0 0 1 1 *", time.Minute); err != nil {
+		t.Fatalf("AddAccountWithJitter: %v", err)
+	}
+
+	s.mu.RLock()
+	job := s.cron.Entry(s.jobs["test@gmail.com"]).Job
+	s.mu.RUnlock()
+
+	fired := time.Now()
+	go job.Run()
+	time.Sleep(10 * time.Millisecond)
+	job.Run() // the delayed sync counts as running, so this is skipped
+	s.wg.Wait()
+
+	if len(started) != 1 {
+		t.Fatalf("syncs started = %d, want 1", len(started))
+	}
+	if delay := (<-started).Sub(fired); delay < 50*time.Millisecond {
+		t.Errorf("sync started %s after the tick, want at least the 50ms jitter", delay)
+	}
+	for _, st := range s.Status() {
+		if st.Jitter != "1m0s" {
+			t.Errorf("Status().Jitter = %q, want 1m0s", st.Jitter)
+		}
+	}
+}
+
+func TestJitterDelayEndsOnStop(t *testing.T) {
+	var calls atomic.Int32
+	s := New(func(ctx context.Context, email string) error {
+		calls.Add(1)
+		return nil
+	})
+	s.randDelay = func(time.Duration) time.Duration { return time.Hour }
+	if err := s.AddAccountWithJitter("test@gmail.com", "0 0 1 1 *", 2*time.Hour); err != nil {
+		t.Fatalf("AddAccountWithJitter: %v", err)
+	}
+	s.Start()
+
+	s.mu.RLock()
+	job := s.cron.Entry(s.jobs["test@gmail.com"]).Job
+	s.mu.RUnlock()
+	go job.Run()
+	time.Sleep(10 * time.Millisecond)
+
+	select {
+	case <-s.Stop().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not end the jitter delay")
+	}
+	if calls.Load() != 0 {
+		t.Errorf("sync ran %d times, want 0 after Stop", calls.Load())
+	}
+}
+
 func TestTriggerSyncAfterStop(t *testing.T) {
 	s := New(func(ctx context.Context, email string) error {
 		return nil
@@ -1027,6 +1170,19 @@ func TestScheduler_SetEmbedJob_AddsCronEntry(t *testing.T) {
 	}
 }
 
+func TestScheduler_AddJob(t *testing.T) {
+	s := New(func(ctx context.Context, email string) error { return nil })
+	if err := s.AddJob("reminders", "not a cron", func(context.Context) {}); err == nil {
+		t.Fatal("AddJob with invalid cron = nil, want error")
+	}
+	if err := s.AddJob("reminders", "*/5 * * * *", func(context.Context) {}); err != nil {
+		t.Fatalf("AddJob = %v", err)
+	}
+	if n := len(s.cron.Entries()); n != 1 {
+		t.Errorf("cron entries = %d, want 1", n)
+	}
+}
+
 func TestScheduler_SetEmbedJob_InvalidCron(t *testing.T) {
 	s := New(func(ctx context.Context, email string) error { return nil })
 	backend := &fakeBackend{}
@@ -0,0 +1,83 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// StatusFileName is the file in the data directory where a running
+// daemon publishes its StatusReport.
+const StatusFileName = "daemon-status.json"
+
+// StatusReport is what a daemon publishes about its scheduled syncs
+// for 'msgvault daemon status' to read.
+type StatusReport struct {
+	PID      int             `json:"pid"`
+	Started  time.Time       `json:"started"`
+	Updated  time.Time       `json:"updated"`
+	Accounts []AccountStatus `json:"accounts"`
+}
+
+// Report returns a StatusReport of every scheduled account, by email,
+// for a daemon that started at started.
+func (s *Scheduler) Report(started time.Time) StatusReport {
+	accounts := s.Status()
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Email < accounts[j].Email })
+	return StatusReport{
+		PID:      os.Getpid(),
+		Started:  started,
+		Updated:  time.Now(),
+		Accounts: accounts,
+	}
+}
+
+// WriteStatusFile replaces the report at path. Readers never see a
+// partly written file.
+func WriteStatusFile(path string, r StatusReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal daemon status: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".daemon-status-*")
+	if err != nil {
+		return fmt.Errorf("write daemon status: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write daemon status: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write daemon status: %w", err)
+	}
+	if err := fileutil.SecureChmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("write daemon status: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write daemon status: %w", err)
+	}
+	return nil
+}
+
+// ReadStatusFile reads the report at path. It returns nil, nil if no
+// daemon has written one.
+func ReadStatusFile(path string) (*StatusReport, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read daemon status: %w", err)
+	}
+	var r StatusReport
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse daemon status %s: %w", path, err)
+	}
+	return &r, nil
+}
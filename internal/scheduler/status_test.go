@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStatusFile_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StatusFileName)
+
+	if r, err := ReadStatusFile(path); r != nil || err != nil {
+		t.Fatalf("ReadStatusFile() before any write = %+v, %v; want nil, nil", r, err)
+	}
+
+	s := New(func(ctx context.Context, email string) error { return nil })
+	for _, email := range []string{"bob@example.com", "alice@example.com"} {
+		if err := s.AddAccountWithJitter(email, "0 2 * * *", 5*time.Minute); err != nil {
+			t.Fatalf("AddAccountWithJitter(%s): %v", email, err)
+		}
+	}
+	started := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := WriteStatusFile(path, s.Report(started)); err != nil {
+		t.Fatalf("WriteStatusFile() error = %v", err)
+	}
+
+	r, err := ReadStatusFile(path)
+	if err != nil {
+		t.Fatalf("ReadStatusFile() error = %v", err)
+	}
+	if r.PID != os.Getpid() || !r.Started.Equal(started) {
+		t.Errorf("report = pid %d started %s, want pid %d started %s", r.PID, r.Started, os.Getpid(), started)
+	}
+	if len(r.Accounts) != 2 || r.Accounts[0].Email != "alice@example.com" || r.Accounts[1].Email != "bob@example.com" {
+		t.Fatalf("accounts = %+v, want alice then bob", r.Accounts)
+	}
+	if a := r.Accounts[0]; a.Schedule != "0 2 * * *" || a.Jitter != "5m0s" {
+		t.Errorf("alice = %+v, want her schedule and jitter", a)
+	}
+}
+
+func TestReadStatusFile_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StatusFileName)
+	if err := os.WriteFile(path, []byte("{not json"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadStatusFile(path); err == nil {
+		t.Error("ReadStatusFile() of a corrupt file succeeded, want an error")
+	}
+}
@@ -0,0 +1,54 @@
+package search
+
+import "strings"
+
+// LabelPattern is how a label: term matches label names, as LIKE
+// patterns escaped with '\'. Gmail nests labels by naming them with
+// "/" ("Clients/Acme/Invoices"), so a term ending in "/**" matches a
+// label and everything under it, and one ending in "/*" matches only
+// the labels directly under it. Any other term matches labels whose
+// name contains it.
+type LabelPattern struct {
+	Any []string // a name matches if it is LIKE any of these
+	Not string   // and, if set, is not LIKE this
+}
+
+// ParseLabelPattern returns the pattern a label: term stands for.
+func ParseLabelPattern(term string) LabelPattern {
+	if parent, ok := strings.CutSuffix(term, "/**"); ok && parent != "" {
+		p := escapeLike(parent)
+		return LabelPattern{Any: []string{p, p + "/%"}}
+	}
+	if parent, ok := strings.CutSuffix(term, "/*"); ok && parent != "" {
+		p := escapeLike(parent)
+		return LabelPattern{Any: []string{p + "/%"}, Not: p + "/%/%"}
+	}
+	return LabelPattern{Any: []string{"%" + escapeLike(term) + "%"}}
+}
+
+// SQL returns a condition matching the label name in col, and its
+// args. like renders a case-insensitive LIKE of an expression against
+// one placeholder escaped with '\', such as
+// `LOWER(name) LIKE LOWER(?) ESCAPE '\'`.
+func (p LabelPattern) SQL(col string, like func(col string) string) (string, []any) {
+	parts := make([]string, len(p.Any))
+	args := make([]any, 0, len(p.Any)+1)
+	for i, pat := range p.Any {
+		parts[i] = like(col)
+		args = append(args, pat)
+	}
+	cond := strings.Join(parts, " OR ")
+	if len(parts) > 1 {
+		cond = "(" + cond + ")"
+	}
+	if p.Not != "" {
+		cond = "(" + cond + " AND NOT " + like(col) + ")"
+		args = append(args, p.Not)
+	}
+	return cond, args
+}
+
+// escapeLike escapes the LIKE wildcards in s with '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,52 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelPattern(t *testing.T) {
+	tests := []struct {
+		term string
+		want LabelPattern
+	}{
+		{"inbox", LabelPattern{Any: []string{"%inbox%"}}},
+		{"Clients/**", LabelPattern{Any: []string{"Clients", "Clients/%"}}},
+		{"Clients/*", LabelPattern{Any: []string{"Clients/%"}, Not: "Clients/%/%"}},
+		{"Clients/Acme/**", LabelPattern{Any: []string{"Clients/Acme", "Clients/Acme/%"}}},
+		{"100%_done/**", LabelPattern{Any: []string{`100\%\_done`, `100\%\_done/%`}}},
+		{"/**", LabelPattern{Any: []string{"%/**%"}}},
+		{"Clients/", LabelPattern{Any: []string{"%Clients/%"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			if got := ParseLabelPattern(tt.term); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLabelPattern(%q) = %+v, want %+v", tt.term, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelPattern_SQL(t *testing.T) {
+	like := func(col string) string { return col + ` ILIKE ?` }
+	tests := []struct {
+		term     string
+		wantCond string
+		wantArgs []any
+	}{
+		{"inbox", `l.name ILIKE ?`, []any{"%inbox%"}},
+		{"Clients/**", `(l.name ILIKE ? OR l.name ILIKE ?)`, []any{"Clients", "Clients/%"}},
+		{"Clients/*", `(l.name ILIKE ? AND NOT l.name ILIKE ?)`, []any{"Clients/%", "Clients/%/%"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.term, func(t *testing.T) {
+			cond, args := ParseLabelPattern(tt.term).SQL("l.name", like)
+			if cond != tt.wantCond {
+				t.Errorf("cond = %q, want %q", cond, tt.wantCond)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
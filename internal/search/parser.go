@@ -10,22 +10,71 @@ import (
 
 // Query represents a parsed search query with all supported filters.
 type Query struct {
-	TextTerms     []string   // Full-text search terms
-	FromAddrs     []string   // from: filters
-	ToAddrs       []string   // to: filters
-	CcAddrs       []string   // cc: filters
-	BccAddrs      []string   // bcc: filters
-	SubjectTerms  []string   // subject: filters
-	Labels        []string   // label: filters
-	HasAttachment *bool      // has:attachment
-	BeforeDate    *time.Time // before: filter
-	AfterDate     *time.Time // after: filter
-	LargerThan    *int64     // larger: filter (bytes)
-	SmallerThan   *int64     // smaller: filter (bytes)
-	AccountIDs    []int64    // in: account filter (one or more source IDs)
-	HideDeleted   bool       // exclude messages where deleted_from_source_at IS NOT NULL
+	TextTerms     []string       // Full-text search terms
+	FromAddrs     []string       // from: filters
+	ToAddrs       []string       // to: filters
+	CcAddrs       []string       // cc: filters
+	BccAddrs      []string       // bcc: filters
+	SubjectTerms  []string       // subject: filters
+	Labels        []string       // label: filters
+	HasAttachment *bool          // has:attachment
+	Filenames     []string       // filename: filters (attachment names, lowercased)
+	BeforeDate    *time.Time     // before: filter
+	AfterDate     *time.Time     // after: filter
+	LargerThan    *int64         // larger: filter (bytes)
+	SmallerThan   *int64         // smaller: filter (bytes)
+	AccountIDs    []int64        // in: account filter (one or more source IDs)
+	HideDeleted   bool           // exclude messages where deleted_from_source_at IS NOT NULL
+	DKIMPass      bool           // is:dkim-pass
+	Suspicious    bool           // is:suspicious
+	Pinned        bool           // is:pinned
+	ReminderDue   bool           // is:due (a local reminder has come due)
+	SystemLabels  []string       // is:unread, is:starred, is:important (UNREAD, STARRED, IMPORTANT)
+	Languages     []string       // lang: filters (ISO 639-1 codes)
+	NoteTerms     []string       // note: filters (text in local notes)
+	Tags          []string       // tag: filters (local tags, lowercased)
+	ListIDs       []string       // list: filters (List-Id, lowercased)
+	DeliveredTo   []string       // deliveredto: filters (Delivered-To, lowercased)
+	Headers       []HeaderFilter // header: filters (Name=Value on an indexed header)
+	Regexes       []string       // regex: filters (RE2 patterns on subject and body)
+
+	// AttachmentTerms are the words and phrases of a query, or of a
+	// group, with in:attachments: they match the text extracted from
+	// a message's attachments rather than the message itself, all
+	// within one attachment.
+	AttachmentTerms []string
+
+	// Or and Not hold the parts of the query joined with OR or negated
+	// with NOT or a leading -. A message matches when it matches every
+	// filter above, at least one query of each group in Or, and none of
+	// the queries in Not.
+	Or  [][]*Query
+	Not []*Query
+
+	// AfterMessageID restricts results to messages with id greater than
+	// this value. Set programmatically (e.g. by webhooks watching for
+	// newly synced mail); never produced by Parse.
+	AfterMessageID int64
+
+	// Sort and SortAsc order results; the zero value lists the newest
+	// first; relevance ranking must be asked for. SeekID continues
+	// a date- or size-sorted listing after the message with that ID. All
+	// three are set programmatically (e.g. by the paginated search API);
+	// never produced by Parse.
+	Sort    SortField
+	SortAsc bool
+	SeekID  int64
 }
 
+// SortField selects the ordering of search results.
+type SortField int
+
+const (
+	SortDate      SortField = iota // sent date
+	SortRelevance                  // FTS rank, then newest first
+	SortSize                       // estimated message size
+)
+
 // IsEmpty returns true if the query has no search criteria.
 func (q *Query) IsEmpty() bool {
 	return len(q.TextTerms) == 0 &&
@@ -36,11 +85,27 @@ func (q *Query) IsEmpty() bool {
 		len(q.SubjectTerms) == 0 &&
 		len(q.Labels) == 0 &&
 		q.HasAttachment == nil &&
+		len(q.Filenames) == 0 &&
 		q.BeforeDate == nil &&
 		q.AfterDate == nil &&
 		q.LargerThan == nil &&
 		q.SmallerThan == nil &&
-		len(q.AccountIDs) == 0
+		!q.DKIMPass &&
+		!q.Suspicious &&
+		!q.Pinned &&
+		!q.ReminderDue &&
+		len(q.SystemLabels) == 0 &&
+		len(q.Languages) == 0 &&
+		len(q.NoteTerms) == 0 &&
+		len(q.Tags) == 0 &&
+		len(q.ListIDs) == 0 &&
+		len(q.DeliveredTo) == 0 &&
+		len(q.Headers) == 0 &&
+		len(q.Regexes) == 0 &&
+		len(q.AttachmentTerms) == 0 &&
+		len(q.AccountIDs) == 0 &&
+		len(q.Or) == 0 &&
+		len(q.Not) == 0
 }
 
 // operatorFn handles a parsed operator:value pair by applying it to the query.
@@ -142,14 +207,83 @@ var operators = map[string]operatorFn{
 			q.HasAttachment = &b
 		}
 	},
-	"before": func(q *Query, v string, _ time.Time) {
+	"filename": func(q *Query, v string, _ time.Time) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			q.Filenames = append(q.Filenames, v)
+		}
+	},
+	"is": func(q *Query, v string, _ time.Time) {
+		switch strings.ToLower(v) {
+		case "dkim-pass":
+			q.DKIMPass = true
+		case "suspicious":
+			q.Suspicious = true
+		case "pinned":
+			q.Pinned = true
+		case "due":
+			q.ReminderDue = true
+		case "unread", "starred", "important":
+			q.SystemLabels = append(q.SystemLabels, strings.ToUpper(v))
+		default:
+			// Not a state msgvault tracks: search for it as text.
+			q.TextTerms = append(q.TextTerms, "is:"+v)
+		}
+	},
+	"lang": func(q *Query, v string, _ time.Time) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			q.Languages = append(q.Languages, v)
+		}
+	},
+	"note": func(q *Query, v string, _ time.Time) {
+		if v = strings.TrimSpace(v); v != "" {
+			q.NoteTerms = append(q.NoteTerms, v)
+		}
+	},
+	"tag": func(q *Query, v string, _ time.Time) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			q.Tags = append(q.Tags, v)
+		}
+	},
+	"list": func(q *Query, v string, _ time.Time) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			q.ListIDs = append(q.ListIDs, v)
+		}
+	},
+	"header": func(q *Query, v string, _ time.Time) {
+		name, value, _ := strings.Cut(v, "=")
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			q.Headers = append(q.Headers, HeaderFilter{Name: name, Value: unquote(strings.TrimSpace(value))})
+		}
+	},
+	"regex": func(q *Query, v string, _ time.Time) {
+		if v != "" {
+			q.Regexes = append(q.Regexes, v)
+		}
+	},
+	"in": func(q *Query, v string, _ time.Time) {
+		// in:attachments scopes the text terms of its group; parseAnd
+		// looks for it before applying them.
+		if !strings.EqualFold(v, "attachments") {
+			q.TextTerms = append(q.TextTerms, "in:"+v)
+		}
+	},
+	"deliveredto": func(q *Query, v string, _ time.Time) {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			q.DeliveredTo = append(q.DeliveredTo, v)
+		}
+	},
+	"before": func(q *Query, v string, now time.Time) {
 		if t := parseDate(v); t != nil {
 			q.BeforeDate = t
+		} else if t := parseDateKeyword(v, now); t != nil {
+			q.BeforeDate = t
 		}
 	},
-	"after": func(q *Query, v string, _ time.Time) {
+	"after": func(q *Query, v string, now time.Time) {
 		if t := parseDate(v); t != nil {
 			q.AfterDate = t
+		} else if t := parseDateKeyword(v, now); t != nil {
+			q.AfterDate = t
 		}
 	},
 	"older_than": func(q *Query, v string, now time.Time) {
@@ -163,12 +297,12 @@ var operators = map[string]operatorFn{
 		}
 	},
 	"larger": func(q *Query, v string, _ time.Time) {
-		if size := parseSize(v); size != nil {
+		if size := ParseSize(v); size != nil {
 			q.LargerThan = size
 		}
 	},
 	"smaller": func(q *Query, v string, _ time.Time) {
-		if size := parseSize(v); size != nil {
+		if size := ParseSize(v); size != nil {
 			q.SmallerThan = size
 		}
 	},
@@ -189,42 +323,211 @@ func NewParser() *Parser {
 // Supported operators:
 //   - from:, to:, cc:, bcc: - address filters
 //   - subject: - subject text search
-//   - label: or l: - label filter
+//   - label: or l: - label filter (substring; Parent/** for a label and
+//     all labels nested under it, Parent/* for its direct children)
 //   - has:attachment - attachment filter
-//   - before:, after: - date filters (YYYY-MM-DD)
+//   - filename: - attachment name or extension (substring, e.g. pdf)
+//   - is:dkim-pass - messages whose DKIM signature verified at ingest
+//   - is:suspicious - messages 'msgvault risk scan' scored as likely spam or phishing
+//   - is:pinned - messages pinned locally
+//   - is:due - messages whose local reminder has come due
+//   - is:unread, is:starred, is:important - messages with the UNREAD,
+//     STARRED or IMPORTANT system label (-is:unread for read messages)
+//   - note: - text in a message's local note
+//   - tag: - local tag
+//   - lang: - language detected at ingest (ISO 639-1 code, e.g. de)
+//   - list: - mailing list, by its List-Id (substring, e.g. golang-nuts)
+//   - deliveredto: - address in Delivered-To (substring, e.g. alias@example.com)
+//   - header: - indexed header field containing a value
+//     (header:X-Mailer=Outlook; header:X-Mailer for any value)
+//   - regex: - RE2 pattern matched case-insensitively against subject and
+//     body (regex:"invoice #\d{4,}"); needs an indexed filter alongside,
+//     see Query.RegexMatcher
+//   - in:attachments - the words and phrases of the query, or of the
+//     parenthesised group it is in, match text extracted from attachments
+//   - before:, after: - date filters (YYYY-MM-DD, or today, yesterday,
+//     this_week, last_week, this_month, last_month, this_year, last_year)
 //   - older_than:, newer_than: - relative date filters (e.g., 7d, 2w, 1m, 1y)
 //   - larger:, smaller: - size filters (e.g., 5M, 100K)
 //   - Bare words and "quoted phrases" - full-text search
+//
+// Terms are AND-ed. OR between terms matches either, NOT or a leading -
+// negates the term or group after it, and parentheses group terms, as in
+// "(from:alice OR from:bob) -subject:spam". OR binds tighter than the
+// implicit AND, as in Gmail: "a b OR c" means a AND (b OR c). OR, AND and
+// NOT are operators only in capitals.
 func (p *Parser) Parse(queryStr string) *Query {
 	q := &Query{}
 	now := time.Now().UTC()
 	if p.Now != nil {
 		now = p.Now()
 	}
-	tokens := tokenize(queryStr)
+	ps := &parseState{tokens: tokenize(queryStr), now: now}
+	for ps.pos < len(ps.tokens) {
+		ps.parseAnd(q)
+		// A ")" without a matching "(" is skipped.
+		ps.pos++
+	}
+	return q
+}
+
+// parseState walks the tokens of a query.
+type parseState struct {
+	tokens []string
+	pos    int
+	now    time.Time
+
+	// inAttachments is set while parsing a group with in:attachments.
+	inAttachments bool
+}
+
+func (ps *parseState) peek() string {
+	if ps.pos < len(ps.tokens) {
+		return ps.tokens[ps.pos]
+	}
+	return ""
+}
+
+// parseAnd applies terms to q up to the end of the enclosing group.
+func (ps *parseState) parseAnd(q *Query) {
+	outer := ps.inAttachments
+	ps.inAttachments = outer || ps.groupInAttachments()
+	for ps.pos < len(ps.tokens) && ps.peek() != ")" {
+		ps.parseOr(q)
+	}
+	ps.inAttachments = outer
+}
 
-	for _, token := range tokens {
-		if isQuotedPhrase(token) {
-			q.TextTerms = append(q.TextTerms, unquote(token))
-			continue
+// groupInAttachments reports whether the group starting at the current
+// token has in:attachments outside the groups nested in it.
+func (ps *parseState) groupInAttachments() bool {
+	depth := 0
+	for _, token := range ps.tokens[ps.pos:] {
+		switch {
+		case token == "(":
+			depth++
+		case token == ")":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case depth == 0 && strings.EqualFold(token, "in:attachments"):
+			return true
 		}
+	}
+	return false
+}
 
-		if idx := strings.Index(token, ":"); idx != -1 {
-			op := strings.ToLower(token[:idx])
-			value := unquote(token[idx+1:])
+// parseOr applies one unit, or a run of units joined by OR, to q.
+func (ps *parseState) parseOr(q *Query) {
+	start := ps.pos
+	ps.skipUnit()
+	if ps.peek() != "OR" {
+		ps.pos = start
+		ps.parseUnit(q)
+		return
+	}
+	ps.pos = start
+	var alts []*Query
+	for {
+		alt := &Query{}
+		ps.parseUnit(alt)
+		if !alt.IsEmpty() {
+			alts = append(alts, alt)
+		}
+		if ps.peek() != "OR" {
+			break
+		}
+		ps.pos++
+	}
+	if len(alts) > 0 {
+		q.Or = append(q.Or, alts)
+	}
+}
 
-			if handler, ok := operators[op]; ok {
-				handler(q, value, now)
-			} else {
-				q.TextTerms = append(q.TextTerms, token)
+// parseUnit applies a term, a negated unit, or a parenthesised group
+// to q.
+func (ps *parseState) parseUnit(q *Query) {
+	token := ps.peek()
+	ps.pos++
+	switch {
+	case token == "" || token == "OR" || token == "AND":
+		// OR without a left side, and the implicit AND, do nothing.
+	case token == "(":
+		ps.parseAnd(q)
+		if ps.peek() == ")" {
+			ps.pos++
+		}
+	case token == "-" || token == "NOT":
+		if ps.peek() == ")" {
+			return
+		}
+		neg := &Query{}
+		ps.parseUnit(neg)
+		if !neg.IsEmpty() {
+			q.Not = append(q.Not, neg)
+		}
+	case len(token) > 1 && token[0] == '-':
+		neg := &Query{}
+		ps.applyTerm(neg, token[1:])
+		q.Not = append(q.Not, neg)
+	default:
+		ps.applyTerm(q, token)
+	}
+}
+
+// applyTerm applies a term to q, its text terms matching attachments in
+// a group with in:attachments.
+func (ps *parseState) applyTerm(q *Query, token string) {
+	n := len(q.TextTerms)
+	applyTerm(q, token, ps.now)
+	if ps.inAttachments && len(q.TextTerms) > n {
+		q.AttachmentTerms = append(q.AttachmentTerms, q.TextTerms[n:]...)
+		q.TextTerms = q.TextTerms[:n]
+	}
+}
+
+// skipUnit moves past one unit without applying it.
+func (ps *parseState) skipUnit() {
+	token := ps.peek()
+	ps.pos++
+	switch token {
+	case "(":
+		for depth := 1; depth > 0 && ps.pos < len(ps.tokens); ps.pos++ {
+			switch ps.tokens[ps.pos] {
+			case "(":
+				depth++
+			case ")":
+				depth--
 			}
-			continue
 		}
+	case "-", "NOT":
+		if ps.peek() != ")" {
+			ps.skipUnit()
+		}
+	}
+}
 
-		q.TextTerms = append(q.TextTerms, token)
+// applyTerm applies a single search term to q.
+func applyTerm(q *Query, token string, now time.Time) {
+	if isQuotedPhrase(token) {
+		q.TextTerms = append(q.TextTerms, unquote(token))
+		return
 	}
 
-	return q
+	if idx := strings.Index(token, ":"); idx != -1 {
+		op := strings.ToLower(token[:idx])
+		value := unquote(token[idx+1:])
+
+		if handler, ok := operators[op]; ok {
+			handler(q, value, now)
+		} else {
+			q.TextTerms = append(q.TextTerms, token)
+		}
+		return
+	}
+
+	q.TextTerms = append(q.TextTerms, token)
 }
 
 // Parse is a convenience function that parses using default settings.
@@ -247,9 +550,12 @@ func isQuotedPhrase(token string) bool {
 
 // tokenize splits a query string, preserving quoted phrases and operator:value pairs.
 // Handles cases like subject:"foo bar" where the operator and quoted value should stay together.
+// A "(" opening a word and the ")" closing it are tokens of their own, as is a
+// "-" before a "(".
 func tokenize(queryStr string) []string {
 	var tokens []string
 	var current strings.Builder
+	depth := 0
 	inQuotes := false
 	quoteChar := rune(0)
 	// Track if we just saw a colon (for op:"value" handling)
@@ -290,6 +596,22 @@ func tokenize(queryStr string) []string {
 			}
 			quoteChar = 0
 			opQuoted = false
+		} else if char == '(' && !inQuotes && (current.Len() == 0 || current.String() == "-") {
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			tokens = append(tokens, "(")
+			depth++
+			afterColon = false
+		} else if char == ')' && !inQuotes && depth > 0 {
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			tokens = append(tokens, ")")
+			depth--
+			afterColon = false
 		} else if char == ' ' && !inQuotes {
 			if current.Len() > 0 {
 				tokens = append(tokens, current.String())
@@ -298,7 +620,9 @@ func tokenize(queryStr string) []string {
 			afterColon = false
 		} else {
 			current.WriteRune(char)
-			afterColon = (char == ':')
+			// header:Name="value" quotes its value after the =.
+			afterColon = char == ':' ||
+				(char == '=' && strings.HasPrefix(strings.ToLower(current.String()), "header:"))
 		}
 	}
 
@@ -349,9 +673,9 @@ func parseRelativeDate(value string, now time.Time) *time.Time {
 	case "w":
 		result = now.AddDate(0, 0, -amount*7)
 	case "m":
-		result = now.AddDate(0, -amount, 0)
+		result = addMonths(now, -amount)
 	case "y":
-		result = now.AddDate(-amount, 0, 0)
+		result = addMonths(now, -12*amount)
 	default:
 		return nil
 	}
@@ -359,6 +683,49 @@ func parseRelativeDate(value string, now time.Time) *time.Time {
 	return &result
 }
 
+// addMonths adds n months to t, keeping its day of the month unless the
+// month is shorter: a month before March 31 is the last day of
+// February, not March 3 as time.AddDate would have it.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), lastDay)-1)
+}
+
+// parseDateKeyword parses the named days before: and after: accept,
+// such as yesterday or last_week, to the start of the day, week, month
+// or year they name, relative to now. Weeks start on Monday.
+func parseDateKeyword(value string, now time.Time) *time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	year := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, now.Location())
+
+	var result time.Time
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(value)), "-", "_") {
+	case "today":
+		result = today
+	case "yesterday":
+		result = today.AddDate(0, 0, -1)
+	case "this_week":
+		result = week
+	case "last_week":
+		result = week.AddDate(0, 0, -7)
+	case "this_month":
+		result = month
+	case "last_month":
+		result = month.AddDate(0, -1, 0)
+	case "this_year":
+		result = year
+	case "last_year":
+		result = year.AddDate(-1, 0, 0)
+	default:
+		return nil
+	}
+	result = result.UTC()
+	return &result
+}
+
 // HasOperators returns true if the query contains any structured
 // operators beyond plain text terms.
 func (q *Query) HasOperators() bool {
@@ -369,14 +736,56 @@ func (q *Query) HasOperators() bool {
 		len(q.SubjectTerms) > 0 ||
 		len(q.Labels) > 0 ||
 		q.HasAttachment != nil ||
+		len(q.Filenames) > 0 ||
 		q.BeforeDate != nil ||
 		q.AfterDate != nil ||
 		q.LargerThan != nil ||
-		q.SmallerThan != nil
+		q.SmallerThan != nil ||
+		q.DKIMPass ||
+		q.Suspicious ||
+		q.Pinned ||
+		q.ReminderDue ||
+		len(q.SystemLabels) > 0 ||
+		len(q.Languages) > 0 ||
+		len(q.NoteTerms) > 0 ||
+		len(q.Tags) > 0 ||
+		len(q.ListIDs) > 0 ||
+		len(q.DeliveredTo) > 0 ||
+		len(q.Headers) > 0 ||
+		len(q.Regexes) > 0 ||
+		len(q.AttachmentTerms) > 0 ||
+		len(q.Or) > 0 ||
+		len(q.Not) > 0
+}
+
+// HeaderFilter matches messages with an indexed header field whose
+// value contains Value.
+type HeaderFilter struct {
+	Name  string // lowercased header name, e.g. list-id
+	Value string
+}
+
+// HeaderFilters returns the list:, deliveredto: and header: filters of
+// q as filters on the header fields they match.
+func (q *Query) HeaderFilters() []HeaderFilter {
+	var out []HeaderFilter
+	for _, v := range q.ListIDs {
+		out = append(out, HeaderFilter{Name: "list-id", Value: v})
+	}
+	for _, v := range q.DeliveredTo {
+		out = append(out, HeaderFilter{Name: "delivered-to", Value: v})
+	}
+	return append(out, q.Headers...)
+}
+
+// HasBoolean reports whether q has parts joined with OR or negated.
+func (q *Query) HasBoolean() bool {
+	return len(q.Or) > 0 || len(q.Not) > 0
 }
 
-// parseSize parses size strings like 5M, 100K, 1G into bytes.
-func parseSize(value string) *int64 {
+// ParseSize parses size strings like 5M, 100K, 1G or 5GB into bytes,
+// returning nil when value is not a size.
+func ParseSize(value string) *int64 {
 	value = strings.TrimSpace(strings.ToUpper(value))
 	multipliers := map[string]int64{
 		"K":  1024,
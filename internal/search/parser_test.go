@@ -135,6 +135,16 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Recipients",
+			tests: []testCase{
+				{
+					name:  "cc and bcc",
+					query: "cc:Bob@Example.com bcc:example.org",
+					want:  Query{CcAddrs: []string{"bob@example.com"}, BccAddrs: []string{"@example.org"}},
+				},
+			},
+		},
 		{
 			name: "Labels",
 			tests: []testCase{
@@ -173,6 +183,160 @@ func TestParse(t *testing.T) {
 					query: "has:attachment",
 					want:  Query{HasAttachment: ptr.Bool(true)},
 				},
+				{
+					name:  "attachment filenames are lowercased",
+					query: "filename:PDF filename:Invoice",
+					want:  Query{Filenames: []string{"pdf", "invoice"}},
+				},
+			},
+		},
+		{
+			name: "Is",
+			tests: []testCase{
+				{
+					name:  "dkim pass",
+					query: "is:DKIM-Pass",
+					want:  Query{DKIMPass: true},
+				},
+				{
+					name:  "suspicious",
+					query: "is:suspicious from:bank.example",
+					want:  Query{Suspicious: true, FromAddrs: []string{"bank.example"}},
+				},
+				{
+					name:  "pinned",
+					query: "is:pinned",
+					want:  Query{Pinned: true},
+				},
+				{
+					name:  "reminder due",
+					query: "is:due",
+					want:  Query{ReminderDue: true},
+				},
+				{
+					name:  "system labels",
+					query: "is:Unread is:starred is:IMPORTANT",
+					want:  Query{SystemLabels: []string{"UNREAD", "STARRED", "IMPORTANT"}},
+				},
+				{
+					name:  "negated system label",
+					query: "-is:unread from:alice@example.com",
+					want: Query{
+						FromAddrs: []string{"alice@example.com"},
+						Not:       []*Query{{SystemLabels: []string{"UNREAD"}}},
+					},
+				},
+				{
+					name:  "unknown state is text",
+					query: "is:muted",
+					want:  Query{TextTerms: []string{"is:muted"}},
+				},
+			},
+		},
+		{
+			name: "Lang",
+			tests: []testCase{
+				{
+					name:  "language code",
+					query: "lang:DE",
+					want:  Query{Languages: []string{"de"}},
+				},
+				{
+					name:  "several languages",
+					query: "lang:de lang:fr invoice",
+					want:  Query{Languages: []string{"de", "fr"}, TextTerms: []string{"invoice"}},
+				},
+			},
+		},
+		{
+			name: "Annotations",
+			tests: []testCase{
+				{
+					name:  "note text",
+					query: `note:"call back" note:invoice`,
+					want:  Query{NoteTerms: []string{"call back", "invoice"}},
+				},
+				{
+					name:  "tags are lowercased",
+					query: "tag:Follow-Up tag:tax",
+					want:  Query{Tags: []string{"follow-up", "tax"}},
+				},
+			},
+		},
+		{
+			name: "Headers",
+			tests: []testCase{
+				{
+					name:  "mailing list",
+					query: "list:Golang-Nuts release",
+					want:  Query{ListIDs: []string{"golang-nuts"}, TextTerms: []string{"release"}},
+				},
+				{
+					name:  "delivered to",
+					query: "deliveredto:Alias@Example.com",
+					want:  Query{DeliveredTo: []string{"alias@example.com"}},
+				},
+				{
+					name:  "header with value",
+					query: "header:X-Mailer=Outlook invoice",
+					want: Query{
+						Headers:   []HeaderFilter{{Name: "x-mailer", Value: "Outlook"}},
+						TextTerms: []string{"invoice"},
+					},
+				},
+				{
+					name:  "header with quoted value",
+					query: `header:X-Mailer="Apple Mail" header:"X-Original-To=me@example.com"`,
+					want: Query{Headers: []HeaderFilter{
+						{Name: "x-mailer", Value: "Apple Mail"},
+						{Name: "x-original-to", Value: "me@example.com"},
+					}},
+				},
+				{
+					name:  "header without value",
+					query: "header:X-Mailer",
+					want:  Query{Headers: []HeaderFilter{{Name: "x-mailer"}}},
+				},
+				{
+					name:  "negated header",
+					query: "-header:x-mailer=outlook",
+					want:  Query{Not: []*Query{{Headers: []HeaderFilter{{Name: "x-mailer", Value: "outlook"}}}}},
+				},
+			},
+		},
+		{
+			name: "Regex",
+			tests: []testCase{
+				{
+					name:  "regex with words",
+					query: `invoice regex:INV-\d+`,
+					want:  Query{TextTerms: []string{"invoice"}, Regexes: []string{`INV-\d+`}},
+				},
+				{
+					name:  "quoted regex keeps case and spaces",
+					query: `from:bob regex:"Order #\d{4,}"`,
+					want:  Query{FromAddrs: []string{"bob"}, Regexes: []string{`Order #\d{4,}`}},
+				},
+			},
+		},
+		{
+			name: "InAttachments",
+			tests: []testCase{
+				{
+					name:  "words in attachments",
+					query: `in:attachments lease "net 30"`,
+					want:  Query{AttachmentTerms: []string{"lease", "net 30"}},
+				},
+				{
+					name:  "scoped to its group",
+					query: `invoice (in:attachments "net 30")`,
+					want:  Query{TextTerms: []string{"invoice"}, AttachmentTerms: []string{"net 30"}},
+				},
+				{
+					name:  "other in: values stay words",
+					query: "in:inbox",
+					want:  Query{TextTerms: []string{"in:inbox"}},
+				},
 			},
 		},
 		{
@@ -285,6 +449,76 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "BooleanOperators",
+			tests: []testCase{
+				{
+					name:  "OR between operators",
+					query: "from:alice OR from:bob -subject:spam",
+					want: Query{
+						Or:  [][]*Query{{{FromAddrs: []string{"alice"}}, {FromAddrs: []string{"bob"}}}},
+						Not: []*Query{{SubjectTerms: []string{"spam"}}},
+					},
+				},
+				{
+					name:  "OR binds tighter than AND",
+					query: "invoice alice OR bob",
+					want: Query{
+						TextTerms: []string{"invoice"},
+						Or:        [][]*Query{{{TextTerms: []string{"alice"}}, {TextTerms: []string{"bob"}}}},
+					},
+				},
+				{
+					name:  "grouped alternatives",
+					query: `(from:alice@example.com subject:report) OR "status update" has:attachment`,
+					want: Query{
+						Or: [][]*Query{{
+							{FromAddrs: []string{"alice@example.com"}, SubjectTerms: []string{"report"}},
+							{TextTerms: []string{"status update"}},
+						}},
+						HasAttachment: ptr.Bool(true),
+					},
+				},
+				{
+					name:  "group without OR is AND-ed",
+					query: "(from:alice label:work) meeting",
+					want: Query{
+						FromAddrs: []string{"alice"},
+						Labels:    []string{"work"},
+						TextTerms: []string{"meeting"},
+					},
+				},
+				{
+					name:  "negated group and NOT",
+					query: `report -(label:spam OR label:trash) NOT "weekly digest"`,
+					want: Query{
+						TextTerms: []string{"report"},
+						Not: []*Query{
+							{Or: [][]*Query{{{Labels: []string{"spam"}}, {Labels: []string{"trash"}}}}},
+							{TextTerms: []string{"weekly digest"}},
+						},
+					},
+				},
+				{
+					name:  "lowercase or and inner hyphen are words",
+					query: "black or white e-mail",
+					want:  Query{TextTerms: []string{"black", "or", "white", "e-mail"}},
+				},
+				{
+					name:  "parentheses inside a word stay",
+					query: "f(x) (a OR b",
+					want: Query{
+						TextTerms: []string{"f(x)"},
+						Or:        [][]*Query{{{TextTerms: []string{"a"}}, {TextTerms: []string{"b"}}}},
+					},
+				},
+				{
+					name:  "dangling operators are dropped",
+					query: "OR alice OR -",
+					want:  Query{Or: [][]*Query{{{TextTerms: []string{"alice"}}}}},
+				},
+			},
+		},
 	}
 
 	for _, group := range testGroups {
@@ -338,6 +572,65 @@ func TestParse_RelativeDates(t *testing.T) {
 	}
 }
 
+// TestParse_RelativeDatesMonthEnd verifies month and year arithmetic
+// clamps to the end of shorter months instead of spilling into the next.
+func TestParse_RelativeDatesMonthEnd(t *testing.T) {
+	tests := []struct {
+		name  string
+		now   time.Time
+		query string
+		want  Query
+	}{
+		{"month before March 31", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC), "newer_than:1m",
+			Query{AfterDate: ptr.Time(ptr.Date(2025, 2, 28))}},
+		{"month before March 31, leap year", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), "newer_than:1m",
+			Query{AfterDate: ptr.Time(ptr.Date(2024, 2, 29))}},
+		{"three months before May 31", time.Date(2025, 5, 31, 0, 0, 0, 0, time.UTC), "newer_than:3m",
+			Query{AfterDate: ptr.Time(ptr.Date(2025, 2, 28))}},
+		{"months across the year", time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC), "older_than:14m",
+			Query{BeforeDate: ptr.Time(ptr.Date(2023, 11, 15))}},
+		{"years from February 29", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), "older_than:2y",
+			Query{BeforeDate: ptr.Time(ptr.Date(2022, 2, 28))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Parser{Now: func() time.Time { return tt.now }}
+			assertQueryEqual(t, *p.Parse(tt.query), tt.want)
+		})
+	}
+}
+
+func TestParse_DateKeywords(t *testing.T) {
+	// A Sunday, so this week began on Monday, March 25.
+	fixedNow := time.Date(2024, 3, 31, 15, 30, 0, 0, time.UTC)
+	p := &Parser{Now: func() time.Time { return fixedNow }}
+
+	tests := []struct {
+		query string
+		want  Query
+	}{
+		{"after:today", Query{AfterDate: ptr.Time(ptr.Date(2024, 3, 31))}},
+		{"after:yesterday", Query{AfterDate: ptr.Time(ptr.Date(2024, 3, 30))}},
+		{"after:this_week", Query{AfterDate: ptr.Time(ptr.Date(2024, 3, 25))}},
+		{"before:last_week", Query{BeforeDate: ptr.Time(ptr.Date(2024, 3, 18))}},
+		{"before:Last-Week", Query{BeforeDate: ptr.Time(ptr.Date(2024, 3, 18))}},
+		{"after:last_month before:this_month", Query{
+			AfterDate:  ptr.Time(ptr.Date(2024, 2, 1)),
+			BeforeDate: ptr.Time(ptr.Date(2024, 3, 1)),
+		}},
+		{"after:last_year before:this_year", Query{
+			AfterDate:  ptr.Time(ptr.Date(2023, 1, 1)),
+			BeforeDate: ptr.Time(ptr.Date(2024, 1, 1)),
+		}},
+		{"after:someday", Query{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assertQueryEqual(t, *p.Parse(tt.query), tt.want)
+		})
+	}
+}
+
 // TestParse_TopLevelWrapper ensures the convenience Parse() function
 // works correctly with relative date operators (verifies wiring to NewParser).
 func TestParse_TopLevelWrapper(t *testing.T) {
@@ -392,6 +685,23 @@ func TestQuery_IsEmpty(t *testing.T) {
 		{"from:alice@example.com", false},
 		{"hello", false},
 		{"has:attachment", false},
+		{"filename:pdf", false},
+		{"is:dkim-pass", false},
+		{"is:suspicious", false},
+		{"lang:de", false},
+		{"is:pinned", false},
+		{"is:due", false},
+		{"is:unread", false},
+		{"note:invoice", false},
+		{"tag:tax", false},
+		{"list:golang-nuts", false},
+		{"deliveredto:alias@example.com", false},
+		{"header:x-mailer=outlook", false},
+		{"regex:inv-[0-9]+", false},
+		{"in:attachments lease", false},
+		{"in:attachments", true},
+		{"-label:spam", false},
+		{"OR -", true},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,97 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ErrRegexNeedsFilter is returned for a query with regex: terms but no
+// filter an index can answer, which would test every message in the
+// archive.
+var ErrRegexNeedsFilter = errors.New("regex: needs another filter to narrow the search, such as words, from:, label:, after: or account")
+
+// ErrRegexInGroup is returned for a regex: term joined with OR or
+// negated; regex: only narrows the messages the rest of a query finds.
+var ErrRegexInGroup = errors.New("regex: cannot be joined with OR or negated")
+
+// RegexMatcher tests messages against the regex: terms of a query.
+type RegexMatcher struct {
+	res []*regexp.Regexp
+}
+
+// RegexMatcher compiles the regex: terms of q, or returns nil if it has
+// none. Patterns use RE2 syntax and match case-insensitively unless they
+// turn that off with (?-i). It fails when a pattern does not compile,
+// when a regex: term is inside an OR or NOT part, and when q has no
+// indexed filter (see HasIndexedFilter) to limit the messages tested.
+func (q *Query) RegexMatcher() (*RegexMatcher, error) {
+	if q.hasNestedRegex() {
+		return nil, ErrRegexInGroup
+	}
+	if len(q.Regexes) == 0 {
+		return nil, nil
+	}
+	if !q.HasIndexedFilter() {
+		return nil, ErrRegexNeedsFilter
+	}
+	m := &RegexMatcher{res: make([]*regexp.Regexp, len(q.Regexes))}
+	for i, pattern := range q.Regexes {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %q: %w", pattern, err)
+		}
+		m.res[i] = re
+	}
+	return m, nil
+}
+
+// Match reports whether every pattern matches the subject or the body.
+func (m *RegexMatcher) Match(subject, body string) bool {
+	for _, re := range m.res {
+		if !re.MatchString(subject) && !re.MatchString(body) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasNestedRegex reports whether any OR or NOT part of q has regex:
+// terms.
+func (q *Query) hasNestedRegex() bool {
+	for _, alts := range q.Or {
+		for _, alt := range alts {
+			if len(alt.Regexes) > 0 || alt.hasNestedRegex() {
+				return true
+			}
+		}
+	}
+	for _, neg := range q.Not {
+		if len(neg.Regexes) > 0 || neg.hasNestedRegex() {
+			return true
+		}
+	}
+	return false
+}
+
+// HasIndexedFilter reports whether q has a filter the database answers
+// from an index: full-text words in a message or its attachments, an
+// address, a label or system label, a date bound, an account, an indexed
+// header, a tag, or a pin or reminder. regex: terms are tested only on the messages these find.
+func (q *Query) HasIndexedFilter() bool {
+	return len(q.TextTerms) > 0 ||
+		len(q.AttachmentTerms) > 0 ||
+		len(q.FromAddrs) > 0 ||
+		len(q.ToAddrs) > 0 ||
+		len(q.CcAddrs) > 0 ||
+		len(q.BccAddrs) > 0 ||
+		len(q.Labels) > 0 ||
+		len(q.SystemLabels) > 0 ||
+		q.AfterDate != nil ||
+		q.BeforeDate != nil ||
+		len(q.AccountIDs) > 0 ||
+		len(q.HeaderFilters()) > 0 ||
+		len(q.Tags) > 0 ||
+		q.Pinned ||
+		q.ReminderDue
+}
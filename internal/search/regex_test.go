@@ -0,0 +1,43 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuery_RegexMatcher(t *testing.T) {
+	m, err := Parse(`from:bob regex:INV-\d{4} regex:paid`).RegexMatcher()
+	if err != nil {
+		t.Fatalf("RegexMatcher: %v", err)
+	}
+	tests := []struct {
+		subject, body string
+		want          bool
+	}{
+		{"inv-2024 is paid", "", true},
+		{"Invoice INV-2024", "Marked PAID.", true},
+		{"INV-12", "paid", false},
+		{"INV-2024", "still owed", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.subject, tt.body); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.subject, tt.body, got, tt.want)
+		}
+	}
+
+	if m, err := Parse("from:bob").RegexMatcher(); m != nil || err != nil {
+		t.Errorf("no regex: got %v, %v; want nil, nil", m, err)
+	}
+	if _, err := Parse("regex:foo larger:1M").RegexMatcher(); !errors.Is(err, ErrRegexNeedsFilter) {
+		t.Errorf("regex without indexed filter: err = %v, want ErrRegexNeedsFilter", err)
+	}
+	if _, err := Parse("from:bob (regex:foo OR regex:bar)").RegexMatcher(); !errors.Is(err, ErrRegexInGroup) {
+		t.Errorf("regex in OR: err = %v, want ErrRegexInGroup", err)
+	}
+	if _, err := Parse("from:bob -regex:foo").RegexMatcher(); !errors.Is(err, ErrRegexInGroup) {
+		t.Errorf("negated regex: err = %v, want ErrRegexInGroup", err)
+	}
+	if _, err := Parse("from:bob regex:(unclosed").RegexMatcher(); err == nil {
+		t.Error("invalid pattern: want error")
+	}
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// savedOp is the operator that names a saved search in a query.
+const savedOp = "saved:"
+
+// ExpandSaved replaces each saved:name term of queryStr with the query
+// saved under name, in parentheses, so that it combines with the rest
+// of queryStr like any group: "saved:receipts after:2024-01-01" and
+// "-saved:newsletters" both work. lookup returns the query saved under
+// a name. A saved query may itself use saved:, but not refer back to
+// itself. A queryStr without saved: terms is returned unchanged.
+func ExpandSaved(queryStr string, lookup func(name string) (string, error)) (string, error) {
+	return expandSaved(queryStr, lookup, nil)
+}
+
+func expandSaved(queryStr string, lookup func(string) (string, error), outer []string) (string, error) {
+	tokens := tokenize(queryStr)
+	if !slices.ContainsFunc(tokens, func(t string) bool { return savedName(strings.TrimPrefix(t, "-")) != "" }) {
+		return queryStr, nil
+	}
+	out := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		term, neg := token, false
+		if len(term) > 1 && term[0] == '-' {
+			term, neg = term[1:], true
+		}
+		name := savedName(term)
+		if name == "" {
+			out = append(out, token)
+			continue
+		}
+		if slices.Contains(outer, name) {
+			return "", fmt.Errorf("saved search %q refers to itself", name)
+		}
+		saved, err := lookup(name)
+		if err != nil {
+			return "", err
+		}
+		expanded, err := expandSaved(saved, lookup, append(outer, name))
+		if err != nil {
+			return "", err
+		}
+		if neg {
+			out = append(out, "-")
+		}
+		out = append(out, "(", expanded, ")")
+	}
+	return strings.Join(out, " "), nil
+}
+
+// savedName returns the name of a saved:name term, or "" if token is
+// not one.
+func savedName(token string) string {
+	if len(token) <= len(savedOp) || !strings.EqualFold(token[:len(savedOp)], savedOp) {
+		return ""
+	}
+	return unquote(token[len(savedOp):])
+}
@@ -0,0 +1,54 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandSaved(t *testing.T) {
+	saved := map[string]string{
+		"receipts": "from:shop.example.com has:attachment",
+		"team":     "from:alice OR from:bob",
+		"both":     "saved:receipts saved:team",
+		"loop":     "invoice saved:loop2",
+		"loop2":    "saved:loop",
+	}
+	lookup := func(name string) (string, error) {
+		if q, ok := saved[name]; ok {
+			return q, nil
+		}
+		return "", fmt.Errorf("no search saved as %q", name)
+	}
+
+	tests := []struct {
+		query, want string
+	}{
+		{"from:alice invoice", "from:alice invoice"},
+		{"saved:receipts after:2024-01-01", "( from:shop.example.com has:attachment ) after:2024-01-01"},
+		{"invoice -saved:team", "invoice - ( from:alice OR from:bob )"},
+		{"SAVED:both", "( ( from:shop.example.com has:attachment ) ( from:alice OR from:bob ) )"},
+		{`subject:"saved:team"`, `subject:"saved:team"`},
+	}
+	for _, tt := range tests {
+		got, err := ExpandSaved(tt.query, lookup)
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandSaved(%q) = %q, %v; want %q", tt.query, got, err, tt.want)
+		}
+	}
+
+	// The expansion parses as the saved query, grouped.
+	got, _ := ExpandSaved("invoice -saved:team", lookup)
+	want := Parse("invoice -(from:alice OR from:bob)")
+	if q := Parse(got); !reflect.DeepEqual(q, want) {
+		t.Errorf("Parse(%q) = %+v, want %+v", got, q, want)
+	}
+
+	if _, err := ExpandSaved("saved:missing", lookup); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("ExpandSaved(saved:missing) error = %v, want unknown name", err)
+	}
+	if _, err := ExpandSaved("saved:loop", lookup); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+		t.Errorf("ExpandSaved(saved:loop) error = %v, want a cycle", err)
+	}
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrMessageNotFound is returned when annotating a message that does not
+// exist.
+var ErrMessageNotFound = errors.New("message not found")
+
+// Annotation is what the user recorded locally about a message: a free
+// text note, whether it is pinned, and tags of their own. Annotations are
+// never synced back to the message's source.
+type Annotation struct {
+	Note   string   `json:"note,omitempty"`
+	Pinned bool     `json:"pinned"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+// IsEmpty reports whether nothing is recorded.
+func (a Annotation) IsEmpty() bool {
+	return a.Note == "" && !a.Pinned && len(a.Tags) == 0
+}
+
+// NormalizeTag lowercases a tag and checks that it is one word.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", errors.New("empty tag")
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return r <= ' ' || r == ',' }) {
+		return "", fmt.Errorf("invalid tag %q: tags cannot contain spaces or commas", tag)
+	}
+	return tag, nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		n, err := NormalizeTag(t)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	slices.Sort(out)
+	return slices.Compact(out), nil
+}
+
+func (s *Store) checkMessageExists(messageID int64) error {
+	var one int
+	err := s.db.QueryRow(`SELECT 1 FROM messages WHERE id = ?`, messageID).Scan(&one)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("message %d: %w", messageID, ErrMessageNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("check message: %w", err)
+	}
+	return nil
+}
+
+// GetAnnotation returns what is recorded about a message; the zero
+// Annotation if nothing is.
+func (s *Store) GetAnnotation(messageID int64) (Annotation, error) {
+	var a Annotation
+	var note sql.NullString
+	var pinned int
+	err := s.db.QueryRow(`
+		SELECT note, pinned FROM message_notes WHERE message_id = ?
+	`, messageID).Scan(&note, &pinned)
+	if err != nil && err != sql.ErrNoRows {
+		return a, fmt.Errorf("get note: %w", err)
+	}
+	a.Note = note.String
+	a.Pinned = pinned != 0
+
+	rows, err := s.db.Query(`
+		SELECT tag FROM message_tags WHERE message_id = ? ORDER BY tag
+	`, messageID)
+	if err != nil {
+		return a, fmt.Errorf("get tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return a, fmt.Errorf("scan tag: %w", err)
+		}
+		a.Tags = append(a.Tags, tag)
+	}
+	return a, rows.Err()
+}
+
+// SetMessageNote replaces a message's note; an empty note removes it.
+func (s *Store) SetMessageNote(messageID int64, note string) error {
+	if err := s.checkMessageExists(messageID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO message_notes (message_id, note, pinned, updated_at)
+		VALUES (?, ?, 0, %s)
+		ON CONFLICT(message_id) DO UPDATE SET
+			note = excluded.note,
+			updated_at = excluded.updated_at
+	`, s.dialect.Now()), messageID, nullIfEmpty(strings.TrimSpace(note)))
+	if err != nil {
+		return fmt.Errorf("set note: %w", err)
+	}
+	return s.pruneMessageNote(messageID)
+}
+
+// SetMessagePinned pins or unpins a message.
+func (s *Store) SetMessagePinned(messageID int64, pinned bool) error {
+	if err := s.checkMessageExists(messageID); err != nil {
+		return err
+	}
+	p := 0
+	if pinned {
+		p = 1
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO message_notes (message_id, pinned, updated_at)
+		VALUES (?, ?, %s)
+		ON CONFLICT(message_id) DO UPDATE SET
+			pinned = excluded.pinned,
+			updated_at = excluded.updated_at
+	`, s.dialect.Now()), messageID, p)
+	if err != nil {
+		return fmt.Errorf("set pinned: %w", err)
+	}
+	return s.pruneMessageNote(messageID)
+}
+
+// pruneMessageNote drops a message's notes row once it holds neither a
+// note nor a pin.
+func (s *Store) pruneMessageNote(messageID int64) error {
+	_, err := s.db.Exec(`
+		DELETE FROM message_notes WHERE message_id = ? AND note IS NULL AND pinned = 0
+	`, messageID)
+	if err != nil {
+		return fmt.Errorf("prune note: %w", err)
+	}
+	return nil
+}
+
+// AddMessageTags tags a message. Tags are lowercased; tags the message
+// already has are left as they are.
+func (s *Store) AddMessageTags(messageID int64, tags []string) error {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if err := s.checkMessageExists(messageID); err != nil {
+		return err
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		for _, tag := range tags {
+			if _, err := tx.Exec(fmt.Sprintf(`
+				INSERT INTO message_tags (message_id, tag, created_at)
+				VALUES (?, ?, %s)
+				ON CONFLICT(message_id, tag) DO NOTHING
+			`, s.dialect.Now()), messageID, tag); err != nil {
+				return fmt.Errorf("add tag %s: %w", tag, err)
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveMessageTags removes tags from a message.
+func (s *Store) RemoveMessageTags(messageID int64, tags []string) error {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		for _, tag := range tags {
+			if _, err := tx.Exec(`
+				DELETE FROM message_tags WHERE message_id = ? AND tag = ?
+			`, messageID, tag); err != nil {
+				return fmt.Errorf("remove tag %s: %w", tag, err)
+			}
+		}
+		return nil
+	})
+}
+
+// SetMessageTags replaces a message's tags with tags.
+func (s *Store) SetMessageTags(messageID int64, tags []string) error {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+	if err := s.checkMessageExists(messageID); err != nil {
+		return err
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		if _, err := tx.Exec(`DELETE FROM message_tags WHERE message_id = ?`, messageID); err != nil {
+			return fmt.Errorf("clear tags: %w", err)
+		}
+		for _, tag := range tags {
+			if _, err := tx.Exec(fmt.Sprintf(`
+				INSERT INTO message_tags (message_id, tag, created_at) VALUES (?, ?, %s)
+			`, s.dialect.Now()), messageID, tag); err != nil {
+				return fmt.Errorf("add tag %s: %w", tag, err)
+			}
+		}
+		return nil
+	})
+}
+
+// TagCount is a local tag and how many messages carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// ListTags returns the local tags in use on live messages, most used
+// first.
+func (s *Store) ListTags() ([]TagCount, error) {
+	rows, err := s.db.Query(`
+		SELECT mt.tag, COUNT(*)
+		FROM message_tags mt
+		JOIN messages m ON m.id = mt.message_id
+		WHERE ` + LiveMessagesWhere("m", true) + `
+		GROUP BY mt.tag
+		ORDER BY COUNT(*) DESC, mt.tag
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []TagCount
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, fmt.Errorf("scan tag: %w", err)
+		}
+		out = append(out, tc)
+	}
+	return out, rows.Err()
+}
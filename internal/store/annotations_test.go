@@ -0,0 +1,119 @@
+package store_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"Tax", "tax", false},
+		{"  follow-up ", "follow-up", false},
+		{"2024", "2024", false},
+		{"", "", true},
+		{"two words", "", true},
+		{"a,b", "", true},
+	}
+	for _, tt := range tests {
+		got, err := store.NormalizeTag(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeTag(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestStore_Annotations(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+
+	a, err := f.Store.GetAnnotation(ids[0])
+	testutil.MustNoErr(t, err, "GetAnnotation")
+	if !a.IsEmpty() {
+		t.Fatalf("GetAnnotation before annotating = %+v, want empty", a)
+	}
+
+	testutil.MustNoErr(t, f.Store.SetMessageNote(ids[0], "  renew the lease by May "), "SetMessageNote")
+	testutil.MustNoErr(t, f.Store.SetMessagePinned(ids[0], true), "SetMessagePinned")
+	testutil.MustNoErr(t, f.Store.AddMessageTags(ids[0], []string{"Lease", "home", "lease"}), "AddMessageTags")
+	testutil.MustNoErr(t, f.Store.AddMessageTags(ids[0], []string{"home"}), "AddMessageTags again")
+
+	a, err = f.Store.GetAnnotation(ids[0])
+	testutil.MustNoErr(t, err, "GetAnnotation")
+	if a.Note != "renew the lease by May" || !a.Pinned || !slices.Equal(a.Tags, []string{"home", "lease"}) {
+		t.Errorf("GetAnnotation = %+v, want note, pin and tags [home lease]", a)
+	}
+
+	testutil.MustNoErr(t, f.Store.RemoveMessageTags(ids[0], []string{"HOME"}), "RemoveMessageTags")
+	testutil.MustNoErr(t, f.Store.SetMessageTags(ids[1], []string{"lease", "tax"}), "SetMessageTags")
+	testutil.MustNoErr(t, f.Store.SetMessageTags(ids[1], []string{"tax"}), "SetMessageTags again")
+	testutil.MustNoErr(t, f.Store.AddMessageTags(ids[2], []string{"tax"}), "AddMessageTags")
+
+	tags, err := f.Store.ListTags()
+	testutil.MustNoErr(t, err, "ListTags")
+	want := []store.TagCount{{Tag: "tax", Count: 2}, {Tag: "lease", Count: 1}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("ListTags = %+v, want %+v", tags, want)
+	}
+
+	tests := []struct {
+		query string
+		want  int64
+	}{
+		{"is:pinned", 1},
+		{"note:lease", 1},
+		{`note:"by may"`, 1},
+		{"note:mortgage", 0},
+		{"tag:tax", 2},
+		{"tag:Lease", 1},
+		{"tag:tax is:pinned", 0},
+	}
+	for _, tt := range tests {
+		_, total, err := f.Store.SearchMessagesQuery(search.Parse(tt.query), 0, 10)
+		testutil.MustNoErr(t, err, "SearchMessagesQuery "+tt.query)
+		if total != tt.want {
+			t.Errorf("%s: %d results, want %d", tt.query, total, tt.want)
+		}
+	}
+
+	// Clearing the note and the pin removes the row altogether.
+	testutil.MustNoErr(t, f.Store.SetMessageNote(ids[0], ""), "clear note")
+	testutil.MustNoErr(t, f.Store.SetMessagePinned(ids[0], false), "unpin")
+	var rows int
+	testutil.MustNoErr(t, f.Store.DB().QueryRow(
+		`SELECT COUNT(*) FROM message_notes WHERE message_id = ?`, ids[0]).Scan(&rows), "count notes")
+	if rows != 0 {
+		t.Errorf("message_notes rows after clearing = %d, want 0", rows)
+	}
+}
+
+func TestStore_AnnotationErrors(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(1)
+
+	if err := f.Store.SetMessageNote(99999, "x"); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("SetMessageNote on a missing message = %v, want ErrMessageNotFound", err)
+	}
+	if err := f.Store.SetMessagePinned(99999, true); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("SetMessagePinned on a missing message = %v, want ErrMessageNotFound", err)
+	}
+	if err := f.Store.AddMessageTags(99999, []string{"tax"}); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("AddMessageTags on a missing message = %v, want ErrMessageNotFound", err)
+	}
+	if err := f.Store.AddMessageTags(ids[0], []string{"two words"}); err == nil {
+		t.Error("AddMessageTags with an invalid tag should fail")
+	}
+}
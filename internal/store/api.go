@@ -2,10 +2,14 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/wesm/msgvault/internal/risk"
 	"github.com/wesm/msgvault/internal/search"
 )
 
@@ -31,11 +35,23 @@ type APIMessage struct {
 
 // APIAttachment represents attachment metadata for API responses.
 type APIAttachment struct {
+	ID       int64
 	Filename string
 	MimeType string
 	Size     int64
 }
 
+// MaxMessageID returns the highest message ID in the archive, or 0 when
+// it is empty. Message IDs only grow, so callers can use the result as a
+// watermark for "messages added since" queries.
+func (s *Store) MaxMessageID() (int64, error) {
+	var id int64
+	if err := s.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM messages").Scan(&id); err != nil {
+		return 0, fmt.Errorf("max message id: %w", err)
+	}
+	return id, nil
+}
+
 // ListMessages returns a paginated list of messages with batch-loaded recipients and labels.
 func (s *Store) ListMessages(offset, limit int) ([]APIMessage, int64, error) {
 	// Get total count. Use the canonical live-messages predicate so
@@ -163,12 +179,12 @@ func (s *Store) GetMessage(id int64) (*APIMessage, error) {
 	}
 
 	// Get attachments
-	attRows, err := s.db.Query("SELECT filename, mime_type, size FROM attachments WHERE message_id = ?", id)
+	attRows, err := s.db.Query("SELECT id, filename, mime_type, size FROM attachments WHERE message_id = ?", id)
 	if err == nil {
 		defer func() { _ = attRows.Close() }()
 		for attRows.Next() {
 			var att APIAttachment
-			if err := attRows.Scan(&att.Filename, &att.MimeType, &att.Size); err == nil {
+			if err := attRows.Scan(&att.ID, &att.Filename, &att.MimeType, &att.Size); err == nil {
 				m.Attachments = append(m.Attachments, att)
 			}
 		}
@@ -342,6 +358,115 @@ func (s *Store) SearchMessagesQuery(
 		args = append(args, ftsExpr)
 	}
 
+	filterConds, filterArgs := s.searchFilterConditions(q)
+	conditions = append(conditions, filterConds...)
+	args = append(args, filterArgs...)
+
+	if q.AfterMessageID > 0 {
+		conditions = append(conditions, "m.id > ?")
+		args = append(args, q.AfterMessageID)
+	}
+
+	conditions, args, err := s.appendRegexFilter(q, conditions, args, ftsJoin)
+	if err != nil {
+		if !errors.Is(err, errRegexCandidates) {
+			return nil, 0, err
+		}
+		if usesFTS(q) {
+			return s.searchMessagesQueryNoFTS(q, offset, limit)
+		}
+		return nil, 0, err
+	}
+
+	whereClause := strings.Join(conditions, " AND ")
+
+	// The seek condition narrows the page, not the total, so it joins
+	// only the results query.
+	orderBy, seekCond, seekArgs := searchOrder(q, ftsEnabled, ftsOrder)
+	resultWhere := whereClause
+	if seekCond != "" {
+		resultWhere += " AND " + seekCond
+	}
+
+	// Count query.
+	countSQL := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM messages m
+		%s
+		WHERE %s
+	`, ftsJoin, whereClause)
+
+	var total int64
+	if err := s.db.QueryRow(countSQL, args...).Scan(&total); err != nil {
+		if usesFTS(q) {
+			return s.searchMessagesQueryNoFTS(q, offset, limit)
+		}
+		return nil, 0, fmt.Errorf("count search results: %w", err)
+	}
+
+	// Results query.
+	searchSQL := fmt.Sprintf(`
+		SELECT
+			m.id,
+			COALESCE(m.conversation_id, 0) as conversation_id,
+			COALESCE(m.subject, '') as subject,
+			COALESCE(p.email_address, '') as from_email,
+			COALESCE(m.sent_at, m.received_at, m.internal_date) as sent_at,
+			COALESCE(m.snippet, '') as snippet,
+			m.has_attachments,
+			m.size_estimate
+		FROM messages m
+		%s
+		LEFT JOIN message_recipients mr
+			ON mr.message_id = m.id AND mr.recipient_type = 'from'
+		LEFT JOIN participants p ON p.id = mr.participant_id
+		WHERE %s
+		ORDER BY %s
+		LIMIT ? OFFSET ?
+	`, ftsJoin, resultWhere, orderBy)
+
+	// If the dialect's order-by fragment has ? placeholders, bind the FTS
+	// expression that many extra times — right after the WHERE args and
+	// before LIMIT/OFFSET so Rebind assigns them the correct positions.
+	resultArgs := make([]interface{}, 0, len(args)+len(seekArgs)+ftsOrderArgCount+2)
+	resultArgs = append(resultArgs, args...)
+	resultArgs = append(resultArgs, seekArgs...)
+	if ftsEnabled && q.Sort == search.SortRelevance {
+		for i := 0; i < ftsOrderArgCount; i++ {
+			resultArgs = append(resultArgs, ftsExpr)
+		}
+	}
+	resultArgs = append(resultArgs, limit, offset)
+	rows, err := s.db.Query(searchSQL, resultArgs...)
+	if err != nil {
+		// FTS5 not available -- fall back if we used it.
+		if usesFTS(q) {
+			return s.searchMessagesQueryNoFTS(q, offset, limit)
+		}
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	messages, ids, err := scanMessageRows(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(ids) > 0 {
+		if err := s.batchPopulate(messages, ids); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return messages, total, nil
+}
+
+// searchFilterConditions returns the WHERE conditions and args of the
+// filters of q other than its text terms, including its OR and NOT parts.
+func (s *Store) searchFilterConditions(q *search.Query) ([]string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
 	// from: filter
 	for _, addr := range q.FromAddrs {
 		conditions = append(conditions, `EXISTS (
@@ -396,14 +521,14 @@ func (s *Store) SearchMessagesQuery(
 
 	// label: filter
 	for _, lbl := range q.Labels {
+		cond, condArgs := search.ParseLabelPattern(strings.ToLower(lbl)).SQL("l2.name", lowerLike)
 		conditions = append(conditions, `EXISTS (
 			SELECT 1 FROM message_labels ml2
 			JOIN labels l2 ON l2.id = ml2.label_id
 			WHERE ml2.message_id = m.id
-			AND LOWER(l2.name) LIKE ? ESCAPE '\'
+			AND `+cond+`
 		)`)
-		args = append(args,
-			"%"+escapeLike(strings.ToLower(lbl))+"%")
+		args = append(args, condArgs...)
 	}
 
 	// subject: filter
@@ -419,6 +544,101 @@ func (s *Store) SearchMessagesQuery(
 			"m.has_attachments = 1")
 	}
 
+	// filename:
+	for _, name := range q.Filenames {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM attachments a
+			WHERE a.message_id = m.id AND LOWER(a.filename) LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeLike(name)+"%")
+	}
+
+	// is:dkim-pass
+	if q.DKIMPass {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_auth ma
+			WHERE ma.message_id = m.id AND ma.dkim_result = 'pass'
+		)`)
+	}
+
+	// is:suspicious
+	if q.Suspicious {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM message_risk mk
+			WHERE mk.message_id = m.id AND mk.score >= %d
+		)`, risk.SuspiciousScore))
+	}
+
+	// is:pinned
+	if q.Pinned {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_notes mn
+			WHERE mn.message_id = m.id AND mn.pinned = 1
+		)`)
+	}
+
+	// is:due
+	if q.ReminderDue {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_reminders mrd
+			WHERE mrd.message_id = m.id AND mrd.due_at <= ?
+		)`)
+		args = append(args, time.Now().UTC().Format(reminderTimeFormat))
+	}
+
+	// is:unread, is:starred, is:important
+	for _, name := range q.SystemLabels {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_labels ml2
+			JOIN labels l2 ON l2.id = ml2.label_id
+			WHERE ml2.message_id = m.id AND l2.name = ?
+		)`)
+		args = append(args, name)
+	}
+
+	// note:
+	for _, term := range q.NoteTerms {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_notes mn
+			WHERE mn.message_id = m.id AND mn.note LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+
+	// in:attachments
+	if len(q.AttachmentTerms) > 0 {
+		cond, condArgs := s.attachmentTextCondition(q.AttachmentTerms)
+		conditions = append(conditions, cond)
+		args = append(args, condArgs...)
+	}
+
+	// tag:
+	for _, tag := range q.Tags {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_tags mt
+			WHERE mt.message_id = m.id AND mt.tag = ?
+		)`)
+		args = append(args, tag)
+	}
+
+	// list: / deliveredto:
+	for _, h := range q.HeaderFilters() {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM message_headers mh
+			WHERE mh.message_id = m.id AND mh.name = ? AND mh.value LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, h.Name, "%"+escapeLike(h.Value)+"%")
+	}
+
+	// lang:
+	if len(q.Languages) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(q.Languages)), ",")
+		conditions = append(conditions, "m.language IN ("+placeholders+")")
+		for _, lang := range q.Languages {
+			args = append(args, lang)
+		}
+	}
+
 	// larger: / smaller:
 	if q.LargerThan != nil {
 		conditions = append(conditions, "m.size_estimate > ?")
@@ -441,80 +661,134 @@ func (s *Store) SearchMessagesQuery(
 		args = append(args, q.BeforeDate.Format(time.RFC3339))
 	}
 
-	whereClause := strings.Join(conditions, " AND ")
-
-	// Count query.
-	countSQL := fmt.Sprintf(`
-		SELECT COUNT(*)
-		FROM messages m
-		%s
-		WHERE %s
-	`, ftsJoin, whereClause)
-
-	var total int64
-	if err := s.db.QueryRow(countSQL, args...).Scan(&total); err != nil {
-		if ftsEnabled {
-			return s.searchMessagesQueryNoFTS(q, offset, limit)
+	// in: account filter
+	if len(q.AccountIDs) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(q.AccountIDs)), ",")
+		conditions = append(conditions, "m.source_id IN ("+placeholders+")")
+		for _, id := range q.AccountIDs {
+			args = append(args, id)
 		}
-		return nil, 0, fmt.Errorf("count search results: %w", err)
 	}
 
-	// Results query.
-	orderBy := "COALESCE(m.sent_at, m.received_at, m.internal_date) DESC"
-	if ftsEnabled {
-		orderBy = ftsOrder + ", " + orderBy
+	// OR and NOT parts, each nested query a condition of its own.
+	for _, alts := range q.Or {
+		parts := make([]string, 0, len(alts))
+		for _, alt := range alts {
+			cond, condArgs := s.searchSubqueryCondition(alt)
+			parts = append(parts, cond)
+			args = append(args, condArgs...)
+		}
+		conditions = append(conditions, "("+strings.Join(parts, " OR ")+")")
 	}
-	searchSQL := fmt.Sprintf(`
-		SELECT
-			m.id,
-			COALESCE(m.conversation_id, 0) as conversation_id,
-			COALESCE(m.subject, '') as subject,
-			COALESCE(p.email_address, '') as from_email,
-			COALESCE(m.sent_at, m.received_at, m.internal_date) as sent_at,
-			COALESCE(m.snippet, '') as snippet,
-			m.has_attachments,
-			m.size_estimate
+	for _, neg := range q.Not {
+		cond, condArgs := s.searchSubqueryCondition(neg)
+		conditions = append(conditions, "NOT "+cond)
+		args = append(args, condArgs...)
+	}
+
+	return conditions, args
+}
+
+// errRegexCandidates wraps a failure to read the messages regex: terms
+// are tested on, which SearchMessagesQuery retries without FTS like its
+// other queries.
+var errRegexCandidates = errors.New("regex candidates")
+
+// appendRegexFilter narrows conditions, the other filters of q, to the
+// messages whose subject or body match its regex: terms. The database
+// cannot run RE2 patterns, so the messages the other filters find are
+// read and tested here, and those that match added as a list of IDs.
+// search.Query.RegexMatcher refuses queries whose other filters do not
+// use an index, which would read every message.
+func (s *Store) appendRegexFilter(q *search.Query, conditions []string, args []interface{}, ftsJoin string) ([]string, []interface{}, error) {
+	matcher, err := q.RegexMatcher()
+	if err != nil || matcher == nil {
+		return conditions, args, err
+	}
+
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT m.id, COALESCE(m.subject, ''), COALESCE(mb.body_text, '')
 		FROM messages m
 		%s
-		LEFT JOIN message_recipients mr
-			ON mr.message_id = m.id AND mr.recipient_type = 'from'
-		LEFT JOIN participants p ON p.id = mr.participant_id
+		LEFT JOIN message_bodies mb ON mb.message_id = m.id
 		WHERE %s
-		ORDER BY %s
-		LIMIT ? OFFSET ?
-	`, ftsJoin, whereClause, orderBy)
-
-	// If the dialect's order-by fragment has ? placeholders, bind the FTS
-	// expression that many extra times — right after the WHERE args and
-	// before LIMIT/OFFSET so Rebind assigns them the correct positions.
-	resultArgs := make([]interface{}, 0, len(args)+ftsOrderArgCount+2)
-	resultArgs = append(resultArgs, args...)
-	for i := 0; i < ftsOrderArgCount; i++ {
-		resultArgs = append(resultArgs, ftsExpr)
-	}
-	resultArgs = append(resultArgs, limit, offset)
-	rows, err := s.db.Query(searchSQL, resultArgs...)
+	`, ftsJoin, strings.Join(conditions, " AND ")), args...)
 	if err != nil {
-		// FTS5 not available -- fall back if we used it.
-		if ftsEnabled {
-			return s.searchMessagesQueryNoFTS(q, offset, limit)
-		}
-		return nil, 0, err
+		return nil, nil, fmt.Errorf("%w: %w", errRegexCandidates, err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	messages, ids, err := scanMessageRows(rows)
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		var subject, body string
+		if err := rows.Scan(&id, &subject, &body); err != nil {
+			return nil, nil, fmt.Errorf("scan regex candidate: %w", err)
+		}
+		if matcher.Match(subject, body) {
+			ids = append(ids, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errRegexCandidates, err)
+	}
+	blob, err := json.Marshal(ids)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, fmt.Errorf("encode regex matches: %w", err)
 	}
+	return append(conditions, s.dialect.IDListCondition("m.id")), append(args, string(blob)), nil
+}
 
-	if len(ids) > 0 {
-		if err := s.batchPopulate(messages, ids); err != nil {
-			return nil, 0, err
+// searchSubqueryCondition returns q, a query nested in another with OR
+// or NOT, as a single parenthesised condition. Its text terms match
+// through the full-text index without a join.
+func (s *Store) searchSubqueryCondition(q *search.Query) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if len(q.TextTerms) > 0 {
+		conditions = append(conditions, s.dialect.FTSMatchCondition())
+		args = append(args, buildFTSExpression(q.TextTerms))
+	}
+	filterConds, filterArgs := s.searchFilterConditions(q)
+	conditions = append(conditions, filterConds...)
+	args = append(args, filterArgs...)
+	if len(conditions) == 0 {
+		return "(1=1)", nil
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
+
+// searchOrder returns the ORDER BY clause for q and, when q.SeekID is
+// set on a date or size sort, a condition selecting the rows after that
+// message. The seek compares against the anchor row's own sort value, so
+// cursors never carry database-formatted dates.
+func searchOrder(q *search.Query, ftsEnabled bool, ftsOrder string) (orderBy, seekCond string, seekArgs []interface{}) {
+	const dateExpr = "COALESCE(%[1]s.sent_at, %[1]s.received_at, %[1]s.internal_date)"
+	var expr, anchor string
+	switch q.Sort {
+	case search.SortRelevance:
+		orderBy = fmt.Sprintf(dateExpr, "m") + " DESC"
+		if ftsEnabled {
+			orderBy = ftsOrder + ", " + orderBy
 		}
+		return orderBy, "", nil
+	case search.SortSize:
+		expr, anchor = "m.size_estimate", "a.size_estimate"
+	default:
+		expr, anchor = fmt.Sprintf(dateExpr, "m"), fmt.Sprintf(dateExpr, "a")
 	}
 
-	return messages, total, nil
+	dir, cmp := "DESC", "<"
+	if q.SortAsc {
+		dir, cmp = "ASC", ">"
+	}
+	orderBy = fmt.Sprintf("%s %s, m.id %s", expr, dir, dir)
+	if q.SeekID > 0 {
+		sub := fmt.Sprintf("(SELECT %s FROM messages a WHERE a.id = ?)", anchor)
+		seekCond = fmt.Sprintf("(%[1]s %[2]s %[3]s OR (%[1]s = %[3]s AND m.id %[2]s ?))", expr, cmp, sub)
+		seekArgs = []interface{}{q.SeekID, q.SeekID, q.SeekID}
+	}
+	return orderBy, seekCond, seekArgs
 }
 
 // buildFTSExpression builds an FTS5 MATCH expression from text terms.
@@ -530,10 +804,39 @@ func buildFTSExpression(terms []string) string {
 func (s *Store) searchMessagesQueryNoFTS(
 	q *search.Query, offset, limit int,
 ) ([]APIMessage, int64, error) {
-	fallbackQ := *q
-	fallbackQ.SubjectTerms = append(fallbackQ.SubjectTerms, q.TextTerms...)
-	fallbackQ.TextTerms = nil
-	return s.SearchMessagesQuery(&fallbackQ, offset, limit)
+	return s.SearchMessagesQuery(withoutTextTerms(q), offset, limit)
+}
+
+// usesFTS reports whether q, or a query nested in it, has text terms.
+func usesFTS(q *search.Query) bool {
+	if len(q.TextTerms) > 0 {
+		return true
+	}
+	for _, alts := range q.Or {
+		if slices.ContainsFunc(alts, usesFTS) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(q.Not, usesFTS)
+}
+
+// withoutTextTerms returns a copy of q, and of the queries nested in it,
+// with its text terms searched as subject terms.
+func withoutTextTerms(q *search.Query) *search.Query {
+	c := *q
+	c.SubjectTerms = append(slices.Clone(q.SubjectTerms), q.TextTerms...)
+	c.TextTerms = nil
+	c.Or = make([][]*search.Query, len(q.Or))
+	for i, alts := range q.Or {
+		for _, alt := range alts {
+			c.Or[i] = append(c.Or[i], withoutTextTerms(alt))
+		}
+	}
+	c.Not = make([]*search.Query, len(q.Not))
+	for i, neg := range q.Not {
+		c.Not[i] = withoutTextTerms(neg)
+	}
+	return &c
 }
 
 // escapeLike escapes SQL LIKE special characters (%, _) so they are
@@ -545,6 +848,12 @@ func escapeLike(s string) string {
 	return s
 }
 
+// lowerLike matches col case-insensitively against a lower-cased LIKE
+// pattern escaped with '\'.
+func lowerLike(col string) string {
+	return `LOWER(` + col + `) LIKE ? ESCAPE '\'`
+}
+
 // searchMessagesLike is a fallback search using LIKE with batch-loaded recipients and labels.
 func (s *Store) searchMessagesLike(query string, offset, limit int) ([]APIMessage, int64, error) {
 	likePattern := "%" + escapeLike(query) + "%"
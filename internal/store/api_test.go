@@ -2,10 +2,13 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"path/filepath"
 	"slices"
 	"testing"
 	"time"
+
+	"github.com/wesm/msgvault/internal/search"
 )
 
 func TestEscapeLike(t *testing.T) {
@@ -164,6 +167,258 @@ func TestParseSQLiteTime(t *testing.T) {
 	}
 }
 
+func TestSearchMessagesQuery_AccountIDs(t *testing.T) {
+	st := openTestStore(t)
+
+	alice, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	bob, err := st.GetOrCreateSource("gmail", "bob@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	aliceConv, err := st.EnsureConversation(alice.ID, "thread-a", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	bobConv, err := st.EnsureConversation(bob.ID, "thread-b", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	aliceMsg := seedMessage(t, st, alice.ID, aliceConv, "msg-a", "Invoice for alice", "snippet")
+	seedMessage(t, st, bob.ID, bobConv, "msg-b", "Invoice for bob", "snippet")
+
+	tests := []struct {
+		name       string
+		accountIDs []int64
+		wantTotal  int64
+	}{
+		{"no filter", nil, 2},
+		{"single account", []int64{alice.ID}, 1},
+		{"both accounts", []int64{alice.ID, bob.ID}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &search.Query{SubjectTerms: []string{"Invoice"}, AccountIDs: tt.accountIDs}
+			msgs, total, err := st.SearchMessagesQuery(q, 0, 10)
+			if err != nil {
+				t.Fatalf("SearchMessagesQuery: %v", err)
+			}
+			if total != tt.wantTotal || int64(len(msgs)) != tt.wantTotal {
+				t.Errorf("total = %d, len = %d, want %d", total, len(msgs), tt.wantTotal)
+			}
+			if tt.wantTotal == 1 && msgs[0].ID != aliceMsg {
+				t.Errorf("got message %d, want %d", msgs[0].ID, aliceMsg)
+			}
+		})
+	}
+}
+
+func TestSearchMessagesQuery_Boolean(t *testing.T) {
+	st := openTestStore(t)
+
+	source, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	convID, err := st.EnsureConversation(source.ID, "thread-1", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	invoice := seedMessage(t, st, source.ID, convID, "msg-1", "Invoice March", "snippet")
+	receipt := seedMessage(t, st, source.ID, convID, "msg-2", "Receipt March", "snippet")
+	spam := seedMessage(t, st, source.ID, convID, "msg-3", "Invoice spam", "snippet")
+	seedMessage(t, st, source.ID, convID, "msg-4", "Newsletter", "snippet")
+	for id, subject := range map[int64]string{invoice: "Invoice March", receipt: "Receipt March", spam: "Invoice spam"} {
+		if err := st.UpsertFTS(id, subject, "", "", "", ""); err != nil {
+			t.Fatalf("UpsertFTS: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{"subject:invoice OR subject:receipt", []int64{invoice, receipt, spam}},
+		{"(subject:invoice OR subject:receipt) -subject:spam", []int64{invoice, receipt}},
+		{"subject:march NOT (invoice OR newsletter)", []int64{receipt}},
+		{"march OR spam -receipt", []int64{invoice, spam}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			msgs, total, err := st.SearchMessagesQuery(search.Parse(tt.query), 0, 10)
+			if err != nil {
+				t.Fatalf("SearchMessagesQuery: %v", err)
+			}
+			var got []int64
+			for _, m := range msgs {
+				got = append(got, m.ID)
+			}
+			slices.Sort(got)
+			if total != int64(len(tt.want)) || !slices.Equal(got, tt.want) {
+				t.Errorf("got %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchMessagesQuery_Regex(t *testing.T) {
+	st := openTestStore(t)
+
+	source, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	convID, err := st.EnsureConversation(source.ID, "thread-1", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	numbered := seedMessage(t, st, source.ID, convID, "msg-1", "Invoice INV-2024-001", "snippet")
+	inBody := seedMessage(t, st, source.ID, convID, "msg-2", "Invoice attached", "snippet")
+	seedMessage(t, st, source.ID, convID, "msg-3", "Invoice draft", "snippet")
+	if err := st.UpsertMessageBody(inBody,
+		sql.NullString{String: "Reference inv-2024-117", Valid: true}, sql.NullString{}); err != nil {
+		t.Fatalf("UpsertMessageBody: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []int64
+	}{
+		{`subject:invoice regex:inv-\d{4}-\d{3}`, []int64{numbered, inBody}},
+		{`subject:invoice regex:"(?-i)INV-"`, []int64{numbered}},
+		{`subject:invoice regex:inv-2024 regex:117$`, []int64{inBody}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			// subject: scans rows, so the account supplies the indexed filter.
+			q := search.Parse(tt.query)
+			q.AccountIDs = []int64{source.ID}
+			msgs, total, err := st.SearchMessagesQuery(q, 0, 10)
+			if err != nil {
+				t.Fatalf("SearchMessagesQuery: %v", err)
+			}
+			var got []int64
+			for _, m := range msgs {
+				got = append(got, m.ID)
+			}
+			slices.Sort(got)
+			if total != int64(len(tt.want)) || !slices.Equal(got, tt.want) {
+				t.Errorf("got %v (total %d), want %v", got, total, tt.want)
+			}
+		})
+	}
+
+	if _, _, err := st.SearchMessagesQuery(search.Parse("subject:invoice regex:invoice"), 0, 10); !errors.Is(err, search.ErrRegexNeedsFilter) {
+		t.Errorf("regex without filter: err = %v, want ErrRegexNeedsFilter", err)
+	}
+}
+
+func TestSearchMessagesQuery_AfterMessageID(t *testing.T) {
+	st := openTestStore(t)
+
+	maxID, err := st.MaxMessageID()
+	if err != nil {
+		t.Fatalf("MaxMessageID: %v", err)
+	}
+	if maxID != 0 {
+		t.Errorf("MaxMessageID on empty store = %d, want 0", maxID)
+	}
+
+	source, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	convID, err := st.EnsureConversation(source.ID, "thread-1", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	first := seedMessage(t, st, source.ID, convID, "msg-1", "Invoice 1", "snippet")
+	second := seedMessage(t, st, source.ID, convID, "msg-2", "Invoice 2", "snippet")
+
+	maxID, err = st.MaxMessageID()
+	if err != nil {
+		t.Fatalf("MaxMessageID: %v", err)
+	}
+	if maxID != second {
+		t.Errorf("MaxMessageID = %d, want %d", maxID, second)
+	}
+
+	q := &search.Query{SubjectTerms: []string{"Invoice"}, AfterMessageID: first}
+	msgs, total, err := st.SearchMessagesQuery(q, 0, 10)
+	if err != nil {
+		t.Fatalf("SearchMessagesQuery: %v", err)
+	}
+	if total != 1 || len(msgs) != 1 || msgs[0].ID != second {
+		t.Errorf("got total=%d msgs=%v, want only message %d", total, msgs, second)
+	}
+}
+
+func TestSearchMessagesQuery_SortAndSeek(t *testing.T) {
+	st := openTestStore(t)
+
+	source, err := st.GetOrCreateSource("gmail", "alice@example.com")
+	if err != nil {
+		t.Fatalf("GetOrCreateSource: %v", err)
+	}
+	convID, err := st.EnsureConversation(source.ID, "thread-1", "Thread")
+	if err != nil {
+		t.Fatalf("EnsureConversation: %v", err)
+	}
+	// a is oldest and largest; b and c share a date so the ID breaks the tie.
+	a := seedMessage(t, st, source.ID, convID, "msg-a", "Invoice a", "snippet")
+	b := seedMessage(t, st, source.ID, convID, "msg-b", "Invoice b", "snippet")
+	c := seedMessage(t, st, source.ID, convID, "msg-c", "Invoice c", "snippet")
+	for _, u := range []struct {
+		id   int64
+		date string
+		size int
+	}{
+		{a, "2024-01-01 09:00:00", 300},
+		{b, "2024-02-01 09:00:00", 100},
+		{c, "2024-02-01 09:00:00", 200},
+	} {
+		if _, err := st.DB().Exec(`UPDATE messages SET sent_at = ?, size_estimate = ? WHERE id = ?`, u.date, u.size, u.id); err != nil {
+			t.Fatalf("update message %d: %v", u.id, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		sort   search.SortField
+		asc    bool
+		seekID int64
+		want   []int64
+	}{
+		{"date desc", search.SortDate, false, 0, []int64{c, b, a}},
+		{"date desc after tie", search.SortDate, false, c, []int64{b, a}},
+		{"date asc", search.SortDate, true, 0, []int64{a, b, c}},
+		{"date asc after a", search.SortDate, true, a, []int64{b, c}},
+		{"size desc after a", search.SortSize, false, a, []int64{c, b}},
+		{"size asc", search.SortSize, true, 0, []int64{b, c, a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &search.Query{SubjectTerms: []string{"Invoice"}, Sort: tt.sort, SortAsc: tt.asc, SeekID: tt.seekID}
+			msgs, total, err := st.SearchMessagesQuery(q, 0, 10)
+			if err != nil {
+				t.Fatalf("SearchMessagesQuery: %v", err)
+			}
+			if total != 3 {
+				t.Errorf("total = %d, want 3 (seek must not narrow the count)", total)
+			}
+			got := make([]int64, len(msgs))
+			for i, m := range msgs {
+				got[i] = m.ID
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetMessageCcBcc(t *testing.T) {
 	st := openTestStore(t)
 
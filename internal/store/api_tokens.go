@@ -0,0 +1,219 @@
+package store
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix starts every vault-issued API token, so tokens are
+// recognizable in config files and secret scanners.
+const APITokenPrefix = "mvt_"
+
+// API token scopes. Read covers messages, attachments, stats, and
+// aggregates; search covers the search endpoints; admin grants
+// everything, including account management and raw SQL.
+const (
+	ScopeRead   = "read"
+	ScopeSearch = "search"
+	ScopeAdmin  = "admin"
+)
+
+// APITokenScopes lists the valid scopes in display order.
+var APITokenScopes = []string{ScopeRead, ScopeSearch, ScopeAdmin}
+
+// ErrAPITokenNotFound is returned when no active token matches.
+var ErrAPITokenNotFound = errors.New("API token not found")
+
+// apiTokenTouchInterval limits how often last_used_at is rewritten, so
+// a busy client doesn't turn every read into a write.
+const apiTokenTouchInterval = time.Minute
+
+// APIToken is a vault-issued API token. The token itself is never
+// stored; only its hash is.
+type APIToken struct {
+	ID         int64
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+// HasScope reports whether the token grants scope. Admin implies
+// every scope.
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+// hashAPIToken returns the stored form of a token. Tokens carry 256
+// bits of randomness, so a plain SHA-256 is enough: there is nothing
+// to brute-force that a slow hash would protect.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeScopes validates scopes and returns them deduplicated in
+// APITokenScopes order.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (%s)", strings.Join(APITokenScopes, ", "))
+	}
+	for _, sc := range scopes {
+		if !slices.Contains(APITokenScopes, sc) {
+			return nil, fmt.Errorf("unknown scope %q (want %s)", sc, strings.Join(APITokenScopes, ", "))
+		}
+	}
+	var out []string
+	for _, sc := range APITokenScopes {
+		if slices.Contains(scopes, sc) {
+			out = append(out, sc)
+		}
+	}
+	return out, nil
+}
+
+// CreateAPIToken issues a new token with the given name and scopes and
+// returns it with its metadata. The token cannot be recovered later.
+func (s *Store) CreateAPIToken(name string, scopes []string) (string, *APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, fmt.Errorf("generate token: %w", err)
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(buf)
+
+	if _, err := s.db.Exec(
+		`INSERT INTO api_tokens (name, token_hash, scopes) VALUES (?, ?, ?)`,
+		name, hashAPIToken(token), strings.Join(scopes, ","),
+	); err != nil {
+		if isSQLiteError(err, "UNIQUE constraint failed") {
+			return "", nil, fmt.Errorf("token %q already exists", name)
+		}
+		return "", nil, fmt.Errorf("insert token: %w", err)
+	}
+	t, err := scanAPIToken(s.db.QueryRow(
+		`SELECT id, name, scopes, created_at, last_used_at, revoked_at
+		 FROM api_tokens WHERE name = ?`, name,
+	))
+	if err != nil {
+		return "", nil, fmt.Errorf("read back token: %w", err)
+	}
+	return token, t, nil
+}
+
+// ListAPITokens returns every token, revoked ones included, by name.
+func (s *Store) ListAPITokens() ([]*APIToken, error) {
+	rows, err := s.db.Query(
+		`SELECT id, name, scopes, created_at, last_used_at, revoked_at
+		 FROM api_tokens ORDER BY name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken revokes the active token with the given name. Revoked
+// tokens stay listed so their history remains visible.
+func (s *Store) RevokeAPIToken(name string) error {
+	res, err := s.db.Exec(
+		`UPDATE api_tokens SET revoked_at = ?
+		 WHERE name = ? AND revoked_at IS NULL`,
+		time.Now().UTC(), name,
+	)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	if n == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// LookupAPIToken returns the active token matching a presented token,
+// or ErrAPITokenNotFound, and records the use.
+func (s *Store) LookupAPIToken(token string) (*APIToken, error) {
+	if !strings.HasPrefix(token, APITokenPrefix) {
+		return nil, ErrAPITokenNotFound
+	}
+	t, err := scanAPIToken(s.db.QueryRow(
+		`SELECT id, name, scopes, created_at, last_used_at, revoked_at
+		 FROM api_tokens WHERE token_hash = ? AND revoked_at IS NULL`,
+		hashAPIToken(token),
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPITokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if !t.LastUsedAt.Valid || now.Sub(t.LastUsedAt.Time) >= apiTokenTouchInterval {
+		if _, err := s.db.Exec(
+			`UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now, t.ID,
+		); err != nil {
+			return nil, fmt.Errorf("record token use: %w", err)
+		}
+		t.LastUsedAt = sql.NullTime{Time: now, Valid: true}
+	}
+	return t, nil
+}
+
+// HasActiveAPITokens reports whether any unrevoked token exists. The
+// servers require authentication whenever one does.
+func (s *Store) HasActiveAPITokens() (bool, error) {
+	var exists bool
+	err := s.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM api_tokens WHERE revoked_at IS NULL)`,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("check tokens: %w", err)
+	}
+	return exists, nil
+}
+
+func scanAPIToken(row interface {
+	Scan(dest ...any) error
+}) (*APIToken, error) {
+	var t APIToken
+	var scopes string
+	if err := row.Scan(
+		&t.ID, &t.Name, &scopes, &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	t.Scopes = strings.Split(scopes, ",")
+	return &t, nil
+}
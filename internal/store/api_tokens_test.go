@@ -0,0 +1,99 @@
+package store_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+)
+
+func TestAPITokens_Lifecycle(t *testing.T) {
+	st := testutil.NewTestStore(t)
+
+	active, err := st.HasActiveAPITokens()
+	testutil.MustNoErr(t, err, "HasActiveAPITokens()")
+	if active {
+		t.Fatal("fresh store reports active tokens")
+	}
+
+	token, created, err := st.CreateAPIToken("ci", []string{"search", "read", "search"})
+	testutil.MustNoErr(t, err, "CreateAPIToken()")
+	if !strings.HasPrefix(token, store.APITokenPrefix) || len(token) < 40 {
+		t.Errorf("token = %q, want %s prefix and 256 bits", token, store.APITokenPrefix)
+	}
+	if !slices.Equal(created.Scopes, []string{"read", "search"}) {
+		t.Errorf("scopes = %v, want [read search]", created.Scopes)
+	}
+
+	got, err := st.LookupAPIToken(token)
+	testutil.MustNoErr(t, err, "LookupAPIToken()")
+	if got.ID != created.ID || !got.LastUsedAt.Valid {
+		t.Errorf("lookup = %+v, want id %d with last use recorded", got, created.ID)
+	}
+	if got.HasScope(store.ScopeAdmin) || !got.HasScope(store.ScopeSearch) {
+		t.Errorf("HasScope mismatch for %v", got.Scopes)
+	}
+
+	// The token itself is never stored.
+	var stored int
+	testutil.MustNoErr(t, st.DB().QueryRow(
+		`SELECT COUNT(*) FROM api_tokens WHERE token_hash = ? OR name = ?`, token, token,
+	).Scan(&stored), "count raw token")
+	if stored != 0 {
+		t.Error("raw token found in api_tokens")
+	}
+
+	for _, bad := range []string{"", "mvt_wrong", token + "x", strings.TrimPrefix(token, store.APITokenPrefix)} {
+		if _, err := st.LookupAPIToken(bad); !errors.Is(err, store.ErrAPITokenNotFound) {
+			t.Errorf("LookupAPIToken(%q) err = %v, want ErrAPITokenNotFound", bad, err)
+		}
+	}
+
+	testutil.MustNoErr(t, st.RevokeAPIToken("ci"), "RevokeAPIToken()")
+	if _, err := st.LookupAPIToken(token); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("revoked token still resolves: %v", err)
+	}
+	if err := st.RevokeAPIToken("ci"); !errors.Is(err, store.ErrAPITokenNotFound) {
+		t.Errorf("second revoke err = %v, want ErrAPITokenNotFound", err)
+	}
+	active, err = st.HasActiveAPITokens()
+	testutil.MustNoErr(t, err, "HasActiveAPITokens()")
+	if active {
+		t.Error("only token revoked, still reports active tokens")
+	}
+
+	list, err := st.ListAPITokens()
+	testutil.MustNoErr(t, err, "ListAPITokens()")
+	if len(list) != 1 || !list[0].RevokedAt.Valid {
+		t.Errorf("list = %+v, want the revoked token", list)
+	}
+}
+
+func TestAPITokens_CreateValidation(t *testing.T) {
+	st := testutil.NewTestStore(t)
+	_, _, err := st.CreateAPIToken("dup", []string{"read"})
+	testutil.MustNoErr(t, err, "CreateAPIToken()")
+
+	tests := []struct {
+		name    string
+		token   string
+		scopes  []string
+		wantErr string
+	}{
+		{"empty name", " ", []string{"read"}, "name is required"},
+		{"no scopes", "x", nil, "at least one scope"},
+		{"unknown scope", "x", []string{"write"}, `unknown scope "write"`},
+		{"duplicate name", "dup", []string{"read"}, "already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := st.CreateAPIToken(tt.token, tt.scopes)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+)
+
+// pendingAttachmentsWhere is the condition on messages m that their
+// attachments are still to be extracted from their raw MIME: fewer are
+// stored than they have, and the raw MIME is.
+const pendingAttachmentsWhere = `m.has_attachments = ?
+	AND (SELECT COUNT(*) FROM attachments a WHERE a.message_id = m.id) < m.attachment_count
+	AND EXISTS (SELECT 1 FROM message_raw mr WHERE mr.message_id = m.id)`
+
+// MessagesWithPendingAttachments returns the IDs, in order, of the
+// messages archived without extracting their attachments, as a sync
+// with SkipAttachments leaves them. With ids, only those messages are
+// considered; with nil, every message.
+func (s *Store) MessagesWithPendingAttachments(ids []int64) ([]int64, error) {
+	var out []int64
+	if ids == nil {
+		rows, err := s.db.Query(`
+			SELECT m.id FROM messages m
+			WHERE `+pendingAttachmentsWhere+` AND `+LiveMessagesWhere("m", false)+`
+			ORDER BY m.id`, true)
+		if err != nil {
+			return nil, fmt.Errorf("list messages with pending attachments: %w", err)
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("scan message with pending attachments: %w", err)
+			}
+			out = append(out, id)
+		}
+		return out, rows.Err()
+	}
+
+	err := queryInChunks(s.db, ids, []interface{}{true}, `
+		SELECT m.id FROM messages m
+		WHERE `+pendingAttachmentsWhere+` AND `+LiveMessagesWhere("m", false)+`
+			AND m.id IN (%s)`,
+		func(rows *loggedRows) error {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+			out = append(out, id)
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("list messages with pending attachments: %w", err)
+	}
+	slices.Sort(out)
+	return out, nil
+}
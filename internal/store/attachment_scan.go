@@ -0,0 +1,130 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Virus scan verdicts recorded in attachments.scan_result.
+const (
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	ScanError    = "error" // the scanner could not scan the file
+)
+
+// AttachmentFilesToScan returns the storage paths, relative to the
+// attachments directory, of the stored attachment files to scan: those
+// with no verdict yet, or with rescan set, all of them. A file shared
+// by several attachments is listed once.
+func (s *Store) AttachmentFilesToScan(rescan bool) ([]string, error) {
+	where := "WHERE scan_result IS NULL"
+	if rescan {
+		where = ""
+	}
+	rows, err := s.db.Query(`
+		SELECT DISTINCT storage_path FROM attachments ` + where + `
+		ORDER BY storage_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments to scan: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("scan attachment path: %w", err)
+		}
+		paths = append(paths, p)
+	}
+	return paths, rows.Err()
+}
+
+// SetAttachmentScanResult records a virus scan verdict for every
+// attachment stored at storagePath. An infected file is quarantined
+// when quarantine is set; any other verdict releases it.
+func (s *Store) SetAttachmentScanResult(storagePath, result, signature string, quarantine bool) error {
+	quarantined := 0
+	if result == ScanInfected && quarantine {
+		quarantined = 1
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`
+		UPDATE attachments
+		SET scan_result = ?, scan_signature = ?, scanned_at = %s, quarantined = ?
+		WHERE storage_path = ?
+	`, s.dialect.Now()), result, nullIfEmpty(signature), quarantined, storagePath)
+	if err != nil {
+		return fmt.Errorf("record scan result: %w", err)
+	}
+	return nil
+}
+
+// AttachmentQuarantined reports whether the attachment content with
+// this hash is quarantined.
+func (s *Store) AttachmentQuarantined(contentHash string) (bool, error) {
+	var n int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM attachments WHERE content_hash = ? AND quarantined = 1
+	`, contentHash).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("check quarantine: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReleaseAttachment lifts the quarantine of the attachment content
+// with this hash, as after a false positive, and returns how many
+// attachments that released. The infected verdict is kept.
+func (s *Store) ReleaseAttachment(contentHash string) (int64, error) {
+	res, err := s.db.Exec(`
+		UPDATE attachments SET quarantined = 0 WHERE content_hash = ? AND quarantined = 1
+	`, contentHash)
+	if err != nil {
+		return 0, fmt.Errorf("release attachment: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// InfectedAttachment is an attachment the virus scanner flagged.
+type InfectedAttachment struct {
+	ID          int64     `json:"id"`
+	MessageID   int64     `json:"message_id"`
+	Filename    string    `json:"filename"`
+	ContentHash string    `json:"content_hash"`
+	Signature   string    `json:"signature"`
+	Quarantined bool      `json:"quarantined"`
+	ScannedAt   time.Time `json:"scanned_at"`
+}
+
+// InfectedAttachments returns the attachments the virus scanner
+// flagged, most recently scanned first.
+func (s *Store) InfectedAttachments() ([]InfectedAttachment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, message_id, COALESCE(filename, ''), COALESCE(content_hash, ''),
+			COALESCE(scan_signature, ''), COALESCE(quarantined, 0), scanned_at
+		FROM attachments
+		WHERE scan_result = ?
+		ORDER BY scanned_at DESC, id DESC
+	`, ScanInfected)
+	if err != nil {
+		return nil, fmt.Errorf("list infected attachments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []InfectedAttachment
+	for rows.Next() {
+		var a InfectedAttachment
+		var quarantined int
+		var scannedAt sql.NullString
+		if err := rows.Scan(&a.ID, &a.MessageID, &a.Filename, &a.ContentHash,
+			&a.Signature, &quarantined, &scannedAt); err != nil {
+			return nil, fmt.Errorf("scan infected attachment: %w", err)
+		}
+		a.Quarantined = quarantined != 0
+		a.ScannedAt = parseSQLiteTime(scannedAt.String)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
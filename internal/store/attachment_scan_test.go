@@ -0,0 +1,79 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_AttachmentScan(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+
+	// The same invoice is attached to two messages; one file is stored.
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[0], "invoice.exe", "application/octet-stream",
+		"ab/abc123", "abc123", 10), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[1], "invoice.exe", "application/octet-stream",
+		"ab/abc123", "abc123", 10), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "notes.txt", "text/plain",
+		"de/def456", "def456", 5), "UpsertAttachment")
+
+	paths, err := f.Store.AttachmentFilesToScan(false)
+	testutil.MustNoErr(t, err, "AttachmentFilesToScan")
+	if len(paths) != 2 {
+		t.Fatalf("AttachmentFilesToScan = %v, want each stored file once", paths)
+	}
+
+	testutil.MustNoErr(t, f.Store.SetAttachmentScanResult("ab/abc123", store.ScanInfected, "Eicar-Signature", true),
+		"SetAttachmentScanResult")
+	testutil.MustNoErr(t, f.Store.SetAttachmentScanResult("de/def456", store.ScanClean, "", true),
+		"SetAttachmentScanResult")
+
+	if paths, err = f.Store.AttachmentFilesToScan(false); err != nil || len(paths) != 0 {
+		t.Errorf("AttachmentFilesToScan after scanning = %v, %v; want none", paths, err)
+	}
+	if paths, err = f.Store.AttachmentFilesToScan(true); err != nil || len(paths) != 2 {
+		t.Errorf("AttachmentFilesToScan(rescan) = %v, %v; want both files", paths, err)
+	}
+
+	for _, tc := range []struct {
+		hash string
+		want bool
+	}{{"abc123", true}, {"def456", false}, {"missing", false}} {
+		got, err := f.Store.AttachmentQuarantined(tc.hash)
+		testutil.MustNoErr(t, err, "AttachmentQuarantined")
+		if got != tc.want {
+			t.Errorf("AttachmentQuarantined(%s) = %v, want %v", tc.hash, got, tc.want)
+		}
+	}
+
+	infected, err := f.Store.InfectedAttachments()
+	testutil.MustNoErr(t, err, "InfectedAttachments")
+	if len(infected) != 2 || infected[0].Signature != "Eicar-Signature" || !infected[0].Quarantined {
+		t.Errorf("InfectedAttachments = %+v, want both quarantined copies of the invoice", infected)
+	}
+	if len(infected) > 0 && infected[0].ScannedAt.IsZero() {
+		t.Error("InfectedAttachments should report when each file was scanned")
+	}
+
+	n, err := f.Store.ReleaseAttachment("abc123")
+	testutil.MustNoErr(t, err, "ReleaseAttachment")
+	if n != 2 {
+		t.Errorf("ReleaseAttachment released %d attachments, want 2", n)
+	}
+	if q, _ := f.Store.AttachmentQuarantined("abc123"); q {
+		t.Error("attachment still quarantined after release")
+	}
+	if infected, _ = f.Store.InfectedAttachments(); len(infected) != 2 {
+		t.Errorf("release should keep the verdict, got %+v", infected)
+	}
+
+	// Scanning without quarantine records the verdict only.
+	testutil.MustNoErr(t, f.Store.SetAttachmentScanResult("ab/abc123", store.ScanInfected, "Eicar-Signature", false),
+		"SetAttachmentScanResult")
+	if q, _ := f.Store.AttachmentQuarantined("abc123"); q {
+		t.Error("infected attachment quarantined with quarantine off")
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import "fmt"
+
+// AttachmentFile is a stored attachment file and the type its
+// attachments were sent as.
+type AttachmentFile struct {
+	StoragePath string // relative to the attachments directory
+	MimeType    string
+	Filename    string
+}
+
+// AttachmentFilesToIndex returns the stored attachment files whose text
+// has not been extracted yet, or with reindex set, all of them. A file
+// shared by several attachments is listed once, with the type and name
+// of one of them.
+func (s *Store) AttachmentFilesToIndex(reindex bool) ([]AttachmentFile, error) {
+	where := "WHERE NOT EXISTS (SELECT 1 FROM attachment_texts att WHERE att.storage_path = a.storage_path)"
+	if reindex {
+		where = ""
+	}
+	rows, err := s.db.Query(`
+		SELECT a.storage_path, MAX(COALESCE(a.mime_type, '')), MAX(COALESCE(a.filename, ''))
+		FROM attachments a ` + where + `
+		GROUP BY a.storage_path
+		ORDER BY a.storage_path
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list attachments to index: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var files []AttachmentFile
+	for rows.Next() {
+		var f AttachmentFile
+		if err := rows.Scan(&f.StoragePath, &f.MimeType, &f.Filename); err != nil {
+			return nil, fmt.Errorf("scan attachment file: %w", err)
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// SetAttachmentText records the text extracted from the attachment file
+// at storagePath and indexes it for in:attachments searches. Empty text
+// marks a file with none, such as an image, so it is not read again.
+func (s *Store) SetAttachmentText(storagePath, text string) error {
+	return s.withTx(func(tx *loggedTx) error {
+		_, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO attachment_texts (storage_path, content, extracted_at)
+			VALUES (?, ?, %[1]s)
+			ON CONFLICT (storage_path) DO UPDATE SET content = excluded.content, extracted_at = %[1]s
+		`, s.dialect.Now()), storagePath, text)
+		if err != nil {
+			return fmt.Errorf("record attachment text: %w", err)
+		}
+		if !s.fts5Available {
+			return nil
+		}
+		var id int64
+		if err := tx.QueryRow(`SELECT id FROM attachment_texts WHERE storage_path = ?`, storagePath).Scan(&id); err != nil {
+			return fmt.Errorf("record attachment text: %w", err)
+		}
+		if err := s.dialect.AttachmentFTSUpsert(tx, id, text); err != nil {
+			return fmt.Errorf("index attachment text: %w", err)
+		}
+		return nil
+	})
+}
+
+// attachmentTextCondition returns a condition on messages m that one of
+// their attachments contains every term, through the full-text index or
+// by LIKE without one.
+func (s *Store) attachmentTextCondition(terms []string) (string, []interface{}) {
+	var match string
+	var args []interface{}
+	if s.fts5Available {
+		match = s.dialect.AttachmentFTSMatchCondition()
+		args = append(args, buildFTSExpression(terms))
+	} else {
+		for i, term := range terms {
+			if i > 0 {
+				match += " AND "
+			}
+			match += `att.content LIKE ? ESCAPE '\'`
+			args = append(args, "%"+escapeLike(term)+"%")
+		}
+	}
+	return `EXISTS (
+		SELECT 1 FROM attachments a
+		JOIN attachment_texts att ON att.storage_path = a.storage_path
+		WHERE a.message_id = m.id AND ` + match + `
+	)`, args
+}
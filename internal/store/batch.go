@@ -0,0 +1,123 @@
+package store
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/wesm/msgvault/internal/mailauth"
+)
+
+// MessageBatch collects messages to persist and writes up to size of
+// them in one transaction, instead of one transaction each as
+// PersistMessage does. Each message is still written atomically: it
+// gets its own savepoint, so one that fails is rolled back alone.
+//
+// Queued messages are not visible to other queries until the batch is
+// flushed. A MessageBatch is not safe for concurrent use.
+type MessageBatch struct {
+	s       *Store
+	size    int
+	pending []pendingMessage
+}
+
+type pendingMessage struct {
+	data *MessagePersistData
+	auth mailauth.Result
+	done func(messageID int64, err error)
+}
+
+// BeginBatch returns an empty batch that flushes every size messages.
+// A size below 1 flushes every message.
+func (s *Store) BeginBatch(size int) *MessageBatch {
+	return &MessageBatch{s: s, size: max(size, 1)}
+}
+
+// Add queues a message, flushing the batch once it is full. done is
+// called when the message has been written, with its ID, or with the
+// error that kept it out. The returned error is Flush's.
+func (b *MessageBatch) Add(data *MessagePersistData, done func(messageID int64, err error)) error {
+	// Checked before the transaction, as in PersistMessage.
+	b.pending = append(b.pending, pendingMessage{data: data, auth: b.s.checkAuth(data), done: done})
+	if len(b.pending) >= b.size {
+		return b.Flush()
+	}
+	return nil
+}
+
+// Len returns the number of queued messages.
+func (b *MessageBatch) Len() int {
+	return len(b.pending)
+}
+
+// Flush writes the queued messages in one transaction, then calls each
+// message's done in the order they were added. A message that fails
+// only fails itself; an error returned here means the transaction as a
+// whole failed, and every queued message's done got it.
+func (b *MessageBatch) Flush() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	pending := b.pending
+	b.pending = nil
+
+	ids := make([]int64, len(pending))
+	errs := make([]error, len(pending))
+	err := b.s.withTx(func(tx *loggedTx) error {
+		for i, p := range pending {
+			var err error
+			if ids[i], errs[i], err = b.s.persistInSavepoint(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("write batch of %d messages: %w", len(pending), err)
+	}
+	for i, p := range pending {
+		if err != nil {
+			p.done(0, err)
+		} else {
+			p.done(ids[i], errs[i])
+		}
+	}
+	return err
+}
+
+// persistInSavepoint writes one queued message, rolling back only its
+// own writes if it fails. msgErr is the message's failure; err is a
+// failure of the savepoint itself, which leaves tx unusable.
+func (s *Store) persistInSavepoint(tx *loggedTx, p pendingMessage) (id int64, msgErr, err error) {
+	if _, err := tx.Exec(`SAVEPOINT persist_message`); err != nil {
+		return 0, nil, fmt.Errorf("savepoint: %w", err)
+	}
+	id, msgErr = s.persistMessageTx(tx, p.data, p.auth)
+	if msgErr != nil {
+		if _, err := tx.Exec(`ROLLBACK TO SAVEPOINT persist_message`); err != nil {
+			return 0, msgErr, fmt.Errorf("roll back to savepoint: %w", err)
+		}
+		id = 0
+	}
+	if _, err := tx.Exec(`RELEASE SAVEPOINT persist_message`); err != nil {
+		return 0, msgErr, fmt.Errorf("release savepoint: %w", err)
+	}
+	return id, msgErr, nil
+}
+
+// indexFTSTx indexes a message for full-text search in tx. A failure
+// is logged and rolled back without failing the rest of tx.
+func (s *Store) indexFTSTx(tx *loggedTx, messageID int64, doc FTSDoc) {
+	if !s.fts5Available {
+		return
+	}
+	doc.MessageID = messageID
+	if _, err := tx.Exec(`SAVEPOINT index_fts`); err != nil {
+		slog.Warn("failed to upsert FTS", "message", messageID, "error", err)
+		return
+	}
+	if err := s.dialect.FTSUpsert(tx, doc); err != nil {
+		slog.Warn("failed to upsert FTS", "message", messageID, "error", err)
+		_, _ = tx.Exec(`ROLLBACK TO SAVEPOINT index_fts`)
+	}
+	_, _ = tx.Exec(`RELEASE SAVEPOINT index_fts`)
+}
@@ -0,0 +1,87 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func batchMessage(f *storetest.Fixture, id, body string) *store.MessagePersistData {
+	return &store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  f.ConvID,
+			SourceID:        f.Source.ID,
+			SourceMessageID: id,
+			MessageType:     "email",
+		},
+		BodyText: sql.NullString{String: body, Valid: true},
+		FTS:      &store.FTSDoc{Subject: "batch " + id, Body: body},
+	}
+}
+
+func countMessages(t *testing.T, f *storetest.Fixture) int {
+	t.Helper()
+	var n int
+	err := f.Store.DB().QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&n)
+	testutil.MustNoErr(t, err, "count messages")
+	return n
+}
+
+func TestMessageBatch(t *testing.T) {
+	f := storetest.New(t)
+	batch := f.Store.BeginBatch(3)
+
+	type result struct {
+		id  int64
+		err error
+	}
+	var got []result
+	done := func(id int64, err error) { got = append(got, result{id, err}) }
+
+	broken := batchMessage(f, "broken", "no such conversation")
+	broken.Message.ConversationID = 999999
+
+	testutil.MustNoErr(t, batch.Add(batchMessage(f, "m1", "first quokka"), done), "Add m1")
+	testutil.MustNoErr(t, batch.Add(broken, done), "Add broken")
+	if batch.Len() != 2 || countMessages(t, f) != 0 || len(got) != 0 {
+		t.Fatalf("before the batch fills: Len = %d, %d messages written, %d done; want 2 queued and nothing written",
+			batch.Len(), countMessages(t, f), len(got))
+	}
+
+	// The third message fills the batch and flushes it.
+	testutil.MustNoErr(t, batch.Add(batchMessage(f, "m3", "third quokka"), done), "Add m3")
+	if batch.Len() != 0 || len(got) != 3 {
+		t.Fatalf("after the batch fills: Len = %d, %d done; want it flushed", batch.Len(), len(got))
+	}
+	if got[0].err != nil || got[0].id == 0 || got[2].err != nil || got[2].id == 0 {
+		t.Errorf("done = %+v, want m1 and m3 written", got)
+	}
+	if got[1].err == nil || got[1].id != 0 {
+		t.Errorf("done for broken = %+v, want its error and no ID", got[1])
+	}
+	if n := countMessages(t, f); n != 2 {
+		t.Errorf("%d messages written, want 2 (the failing one rolled back alone)", n)
+	}
+
+	// Each written message is indexed in the same transaction.
+	if f.Store.FTS5Available() {
+		var indexed int
+		err := f.Store.DB().QueryRow(`SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'quokka'`).Scan(&indexed)
+		testutil.MustNoErr(t, err, "search FTS")
+		if indexed != 2 {
+			t.Errorf("FTS matches = %d, want 2", indexed)
+		}
+	}
+
+	// Flush writes a partial batch; flushing an empty one is a no-op.
+	got = nil
+	testutil.MustNoErr(t, batch.Add(batchMessage(f, "m4", "fourth"), done), "Add m4")
+	testutil.MustNoErr(t, batch.Flush(), "Flush")
+	testutil.MustNoErr(t, batch.Flush(), "Flush empty")
+	if len(got) != 1 || got[0].err != nil || countMessages(t, f) != 3 {
+		t.Errorf("after Flush: done = %+v, %d messages; want m4 written", got, countMessages(t, f))
+	}
+}
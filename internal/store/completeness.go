@@ -0,0 +1,200 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// gapMinMedian is the median number of messages per month an account
+// must have for an empty month inside its date range to count as a gap.
+// Sparser accounts have quiet months as a matter of course.
+const gapMinMedian = 3
+
+// MonthCount is how many messages an account has from one month.
+type MonthCount struct {
+	Month string `json:"month"` // "2006-01"
+	Count int64  `json:"count"`
+}
+
+// Gap is a run of months inside an account's date range in which it
+// has no messages, though it usually does.
+type Gap struct {
+	From   string `json:"from"` // first empty month, "2006-01"
+	To     string `json:"to"`   // last empty month
+	Months int    `json:"months"`
+}
+
+// Completeness is how complete an account's archive looks: how many of
+// the messages the provider reports are archived, and how the archived
+// messages spread over time.
+type Completeness struct {
+	Account  string `json:"account"`
+	Type     string `json:"type"`
+	Archived int64  `json:"archived"`
+
+	// RemoteTotal is the message count the provider reported at the
+	// last sync (Gmail's messagesTotal), nil if none has been recorded.
+	RemoteTotal   *int64     `json:"remote_total,omitempty"`
+	RemoteTotalAt *time.Time `json:"remote_total_at,omitempty"`
+
+	// Score is Archived over RemoteTotal, at most 1; nil without a
+	// RemoteTotal.
+	Score *float64 `json:"score,omitempty"`
+
+	// Months holds every month from the oldest message's to the newest
+	// one's, including empty ones. Undated counts messages with no date.
+	Months  []MonthCount `json:"months"`
+	Undated int64        `json:"undated,omitempty"`
+	Gaps    []Gap        `json:"gaps"`
+}
+
+// SetSourceRemoteTotal records the message count the provider reports
+// for a source.
+func (s *Store) SetSourceRemoteTotal(sourceID, total int64) error {
+	_, err := s.db.Exec(fmt.Sprintf(`
+		UPDATE sources SET remote_message_total = ?, remote_total_at = %s
+		WHERE id = ?
+	`, s.dialect.Now()), total, sourceID)
+	if err != nil {
+		return fmt.Errorf("set remote message total: %w", err)
+	}
+	return nil
+}
+
+// SourceRemoteTotal returns the message count last recorded for a
+// source by SetSourceRemoteTotal, and false if none has been.
+func (s *Store) SourceRemoteTotal(sourceID int64) (int64, bool, error) {
+	var total sql.NullInt64
+	err := s.db.QueryRow(`SELECT remote_message_total FROM sources WHERE id = ?`, sourceID).Scan(&total)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("get remote message total: %w", err)
+	}
+	return total.Int64, total.Valid, nil
+}
+
+// SourceCompleteness measures how complete a source's archive is.
+// Messages deleted from the source are not counted, as the provider no
+// longer counts them either.
+func (s *Store) SourceCompleteness(sourceID int64) (*Completeness, error) {
+	c := &Completeness{Months: []MonthCount{}, Gaps: []Gap{}}
+	var total sql.NullInt64
+	var totalAt sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT identifier, source_type, remote_message_total, remote_total_at
+		FROM sources WHERE id = ?
+	`, sourceID).Scan(&c.Account, &c.Type, &total, &totalAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("source %d not found", sourceID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get source: %w", err)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+s.dialect.YearMonth("COALESCE(m.sent_at, m.received_at, m.internal_date)")+` AS month, COUNT(*)
+		FROM messages m
+		WHERE m.source_id = ? AND `+LiveMessagesWhere("m", true)+`
+		GROUP BY 1
+		ORDER BY 1
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("count messages by month: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var counts []MonthCount
+	for rows.Next() {
+		var month sql.NullString
+		var n int64
+		if err := rows.Scan(&month, &n); err != nil {
+			return nil, fmt.Errorf("scan month count: %w", err)
+		}
+		c.Archived += n
+		if !month.Valid || month.String == "" {
+			c.Undated += n
+			continue
+		}
+		counts = append(counts, MonthCount{Month: month.String, Count: n})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("count messages by month: %w", err)
+	}
+
+	if total.Valid {
+		c.RemoteTotal = &total.Int64
+		if totalAt.Valid {
+			c.RemoteTotalAt = &totalAt.Time
+		}
+		if total.Int64 > 0 {
+			score := min(float64(c.Archived)/float64(total.Int64), 1)
+			c.Score = &score
+		}
+	}
+	if months := FillMonths(counts); months != nil {
+		c.Months = months
+		c.Gaps = FindGaps(months)
+	}
+	return c, nil
+}
+
+// FillMonths returns counts, sorted by month, with a zero count for
+// every month missing between the first and the last. Months that
+// don't parse as "2006-01" are dropped.
+func FillMonths(counts []MonthCount) []MonthCount {
+	byMonth := make(map[time.Time]int64, len(counts))
+	var first, last time.Time
+	for _, mc := range counts {
+		t, err := time.Parse("2006-01", mc.Month)
+		if err != nil {
+			continue
+		}
+		byMonth[t] += mc.Count
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	var out []MonthCount
+	for t := first; !t.After(last); t = t.AddDate(0, 1, 0) {
+		out = append(out, MonthCount{Month: t.Format("2006-01"), Count: byMonth[t]})
+	}
+	return out
+}
+
+// FindGaps returns the runs of empty months in months, which must be
+// contiguous as FillMonths returns them. An account with fewer than
+// gapMinMedian messages in its median month has no gaps.
+func FindGaps(months []MonthCount) []Gap {
+	var nonEmpty []int64
+	for _, m := range months {
+		if m.Count > 0 {
+			nonEmpty = append(nonEmpty, m.Count)
+		}
+	}
+	gaps := []Gap{}
+	if len(nonEmpty) == 0 {
+		return gaps
+	}
+	slices.Sort(nonEmpty)
+	if nonEmpty[len(nonEmpty)/2] < gapMinMedian {
+		return gaps
+	}
+	for i := 0; i < len(months); i++ {
+		if months[i].Count > 0 {
+			continue
+		}
+		j := i
+		for j+1 < len(months) && months[j+1].Count == 0 {
+			j++
+		}
+		gaps = append(gaps, Gap{From: months[i].Month, To: months[j].Month, Months: j - i + 1})
+		i = j
+	}
+	return gaps
+}
@@ -0,0 +1,99 @@
+package store_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestFillMonths(t *testing.T) {
+	got := store.FillMonths([]store.MonthCount{
+		{Month: "2024-02", Count: 4},
+		{Month: "2023-11", Count: 2},
+		{Month: "garbage", Count: 9},
+	})
+	want := []store.MonthCount{
+		{Month: "2023-11", Count: 2},
+		{Month: "2023-12", Count: 0},
+		{Month: "2024-01", Count: 0},
+		{Month: "2024-02", Count: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FillMonths = %v, want %v", got, want)
+	}
+	if got := store.FillMonths(nil); got != nil {
+		t.Errorf("FillMonths(nil) = %v, want nil", got)
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	months := func(counts ...int64) []store.MonthCount {
+		out := make([]store.MonthCount, len(counts))
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, n := range counts {
+			out[i] = store.MonthCount{Month: start.AddDate(0, i, 0).Format("2006-01"), Count: n}
+		}
+		return out
+	}
+	tests := []struct {
+		name   string
+		months []store.MonthCount
+		want   []store.Gap
+	}{
+		{"none", months(10, 12, 9), []store.Gap{}},
+		{"one month", months(10, 0, 9), []store.Gap{{From: "2024-02", To: "2024-02", Months: 1}}},
+		{"runs", months(10, 0, 0, 9, 0, 8), []store.Gap{
+			{From: "2024-02", To: "2024-03", Months: 2},
+			{From: "2024-05", To: "2024-05", Months: 1},
+		}},
+		{"sparse account", months(1, 0, 0, 2, 0, 1), []store.Gap{}},
+		{"empty", nil, []store.Gap{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.FindGaps(tt.months); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindGaps = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_SourceCompleteness(t *testing.T) {
+	f := storetest.New(t)
+	for _, d := range []time.Time{
+		time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+	} {
+		f.NewMessage().WithSentAt(d).Create(t, f.Store)
+	}
+	f.NewMessage().Create(t, f.Store) // undated
+
+	c, err := f.Store.SourceCompleteness(f.Source.ID)
+	testutil.MustNoErr(t, err, "SourceCompleteness")
+	if c.Archived != 4 || c.Undated != 1 || c.Score != nil || c.RemoteTotal != nil {
+		t.Errorf("before remote total: %+v, want 4 archived, 1 undated, no score", c)
+	}
+	want := []store.MonthCount{{Month: "2024-01", Count: 2}, {Month: "2024-02"}, {Month: "2024-03", Count: 1}}
+	if !reflect.DeepEqual(c.Months, want) {
+		t.Errorf("Months = %v, want %v", c.Months, want)
+	}
+
+	testutil.MustNoErr(t, f.Store.SetSourceRemoteTotal(f.Source.ID, 5), "SetSourceRemoteTotal")
+	c, err = f.Store.SourceCompleteness(f.Source.ID)
+	testutil.MustNoErr(t, err, "SourceCompleteness")
+	if c.Score == nil || *c.Score != 0.8 || c.RemoteTotal == nil || *c.RemoteTotal != 5 || c.RemoteTotalAt == nil {
+		t.Errorf("after remote total: score %v total %v at %v, want 0.8 of 5", c.Score, c.RemoteTotal, c.RemoteTotalAt)
+	}
+
+	testutil.MustNoErr(t, f.Store.SetSourceRemoteTotal(f.Source.ID, 2), "SetSourceRemoteTotal")
+	c, err = f.Store.SourceCompleteness(f.Source.ID)
+	testutil.MustNoErr(t, err, "SourceCompleteness")
+	if c.Score == nil || *c.Score != 1 {
+		t.Errorf("score with more archived than reported = %v, want 1", c.Score)
+	}
+}
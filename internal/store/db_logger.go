@@ -9,6 +9,11 @@ import (
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
+
+	"github.com/wesm/msgvault/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // SQLLogOptions controls the store-level SQL logging behaviour.
@@ -105,10 +110,12 @@ func (d *loggedDB) QueryContext(
 	ctx context.Context, query string, args ...any,
 ) (*loggedRows, error) {
 	query = d.rebind(query)
+	ctx, span := startStmtSpan(ctx, "query", query)
 	start := time.Now()
 	rows, err := d.DB.QueryContext(ctx, query, args...)
 	if err != nil {
 		logStmtWith("query", query, args, err, time.Since(start))
+		tracing.End(span, &err)
 		return nil, err
 	}
 	return &loggedRows{
@@ -116,6 +123,7 @@ func (d *loggedDB) QueryContext(
 		query: query,
 		args:  args,
 		start: start,
+		span:  span,
 	}, nil
 }
 
@@ -132,6 +140,8 @@ func (d *loggedDB) QueryRowContext(
 	ctx context.Context, query string, args ...any,
 ) *sql.Row {
 	query = d.rebind(query)
+	ctx, span := startStmtSpan(ctx, "queryrow", query)
+	defer span.End()
 	start := time.Now()
 	row := d.DB.QueryRowContext(ctx, query, args...)
 	logStmtWith("queryrow", query, args, nil, time.Since(start))
@@ -149,10 +159,12 @@ func (d *loggedDB) Exec(
 // available so write sizes show up in the log.
 func (d *loggedDB) ExecContext(
 	ctx context.Context, query string, args ...any,
-) (sql.Result, error) {
+) (res sql.Result, err error) {
 	query = d.rebind(query)
+	ctx, span := startStmtSpan(ctx, "exec", query)
+	defer tracing.End(span, &err)
 	start := time.Now()
-	res, err := d.DB.ExecContext(ctx, query, args...)
+	res, err = d.DB.ExecContext(ctx, query, args...)
 	elapsed := time.Since(start)
 	rowsAffected := int64(-1)
 	if err == nil && res != nil {
@@ -295,6 +307,7 @@ type loggedRows struct {
 	query     string
 	args      []any
 	start     time.Time
+	span      trace.Span
 	finalized bool
 }
 
@@ -341,6 +354,25 @@ func (r *loggedRows) finalize(closeErr error) {
 		logErr = r.Err()
 	}
 	logStmtWith("query", r.query, r.args, logErr, time.Since(r.start))
+	if r.span != nil {
+		tracing.End(r.span, &logErr)
+	}
+}
+
+// startStmtSpan starts a child span for one SQL statement. Statements
+// only join a trace that is already recording — a sync run or API
+// request — so untraced commands and background work don't emit a
+// root span per query. The span carries the normalized statement but
+// never the bound argument values.
+func startStmtSpan(ctx context.Context, kind, query string) (context.Context, trace.Span) {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx, noop.Span{}
+	}
+	return tracing.Start(ctx, "sql "+kind,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.statement",
+			normalizeStmt(query, int(sqlLogMaxChars.Load())))),
+	)
 }
 
 // logStmtWith is the explicit form that lets callers add extra
@@ -13,6 +13,9 @@ import (
 	"unicode/utf8"
 
 	_ "github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // captureSlog installs a JSON handler over buf as the default
@@ -629,3 +632,57 @@ func decodeAll(t *testing.T, buf *bytes.Buffer) []map[string]any {
 	}
 	return out
 }
+
+func TestLoggedDB_SpansOnlyInsideTrace(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	db := openLoggedMem(t)
+
+	// No span in ctx: statements stay untraced.
+	if _, err := db.Exec("INSERT INTO t (val) VALUES (?)", "secret"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if n := len(sr.Ended()); n != 0 {
+		t.Fatalf("untraced exec produced %d spans", n)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if _, err := db.ExecContext(ctx, "INSERT INTO t (val) VALUES (?)", "secret"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT val FROM t")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	for rows.Next() {
+	}
+	_ = rows.Close()
+	if _, err := db.ExecContext(ctx, "INSERT INTO missing VALUES (1)"); err == nil {
+		t.Fatal("expected error")
+	}
+	parent.End()
+
+	var got []string
+	for _, s := range sr.Ended() {
+		if s.Name() == "parent" {
+			continue
+		}
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s not a child of parent", s.Name())
+		}
+		for _, kv := range s.Attributes() {
+			if strings.Contains(kv.Value.Emit(), "secret") {
+				t.Errorf("%s leaked bound arg in %s", s.Name(), kv.Key)
+			}
+		}
+		got = append(got, s.Name()+"/"+s.Status().Code.String())
+	}
+	want := []string{"sql exec/Unset", "sql query/Unset", "sql exec/Error"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("spans = %v, want %v", got, want)
+	}
+}
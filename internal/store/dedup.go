@@ -186,8 +186,8 @@ func (s *Store) MergeDuplicates(
 	unionLabelsSQL := s.dialect.InsertOrIgnore(`INSERT OR IGNORE INTO message_labels (message_id, label_id)
 			SELECT ?, label_id FROM message_labels WHERE message_id = ?`)
 	backfillRawSQL := s.dialect.InsertOrIgnore(`INSERT OR IGNORE INTO message_raw
-			  (message_id, raw_data, raw_format, compression)
-			SELECT ?, raw_data, raw_format, compression
+			  (message_id, raw_data, raw_format, compression, blob_hash, blob_size)
+			SELECT ?, raw_data, raw_format, compression, blob_hash, blob_size
 			FROM message_raw WHERE message_id = ?`)
 	softDeleteSQL := fmt.Sprintf(`UPDATE messages
 			SET deleted_at = %s, delete_batch_id = ?
@@ -321,7 +321,7 @@ func (s *Store) StreamMessageRaw(
 			args[i] = id
 		}
 
-		query := "SELECT message_id, raw_data, compression FROM message_raw WHERE message_id IN (" +
+		query := "SELECT message_id, raw_data, compression, blob_hash FROM message_raw WHERE message_id IN (" +
 			strings.Join(placeholders, ",") + ")"
 		rows, err := s.db.Query(query, args...)
 		if err != nil {
@@ -331,11 +331,16 @@ func (s *Store) StreamMessageRaw(
 		for rows.Next() {
 			var msgID int64
 			var rawData []byte
-			var compression sql.NullString
-			if err := rows.Scan(&msgID, &rawData, &compression); err != nil {
+			var compression, blobHash sql.NullString
+			if err := rows.Scan(&msgID, &rawData, &compression, &blobHash); err != nil {
 				_ = rows.Close()
 				return err
 			}
+			rawData, err = LoadRaw(rawData, blobHash)
+			if err != nil {
+				_ = rows.Close()
+				return fmt.Errorf("load raw message %d: %w", msgID, err)
+			}
 			comp := ""
 			if compression.Valid {
 				comp = compression.String
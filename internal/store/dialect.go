@@ -27,6 +27,11 @@ type Dialect interface {
 	// SQLite: "datetime('now')"  PostgreSQL: "NOW()"
 	Now() string
 
+	// YearMonth returns the SQL expression for the "YYYY-MM" month of a
+	// timestamp expression.
+	// SQLite: substr(expr, 1, 7)  PostgreSQL: to_char(expr, 'YYYY-MM')
+	YearMonth(expr string) string
+
 	// InsertOrIgnore rewrites a complete INSERT statement to silently ignore conflicts.
 	// SQLite: INSERT OR IGNORE INTO ...  PostgreSQL: INSERT INTO ... ON CONFLICT DO NOTHING
 	// The input sql must be a complete statement in SQLite form
@@ -61,11 +66,31 @@ type Dialect interface {
 	// placeholders. Returns: join clause, where clause, order-by clause,
 	// and the number of times the caller must re-bind the search term to
 	// satisfy ? placeholders that appear in orderBy (SQLite: 0, because
-	// "rank" is an implicit FTS5 column; PostgreSQL: 1 for ts_rank).
+	// bm25() reads the match in hand; PostgreSQL: 1 for ts_rank).
 	// Callers compose these with their own SQL and must run Rebind on the
 	// final query before execution.
 	FTSSearchClause() (join, where, orderBy string, orderArgCount int)
 
+	// FTSMatchCondition returns a condition on messages m matching the
+	// search term in one ? placeholder without FTSSearchClause's join,
+	// for text terms nested in a search's OR and NOT parts.
+	FTSMatchCondition() string
+
+	// IDListCondition returns a condition that col is one of the IDs in
+	// a JSON array bound to one ? placeholder, so a list of any length
+	// takes a single parameter.
+	// SQLite: json_each  PostgreSQL: json_array_elements_text
+	IDListCondition(col string) string
+
+	// AttachmentFTSUpsert indexes the text extracted from an attachment
+	// file, the attachment_texts row with this id.
+	// SQLite: attachments_fts row  PostgreSQL: no-op (expression index)
+	AttachmentFTSUpsert(q querier, id int64, text string) error
+
+	// AttachmentFTSMatchCondition returns a condition on attachment_texts
+	// att matching the search term in one ? placeholder.
+	AttachmentFTSMatchCondition() string
+
 	// FTSDeleteSQL returns the SQL to remove FTS entries for messages belonging to
 	// a given source. Takes one parameter: source_id.
 	FTSDeleteSQL() string
@@ -85,6 +110,14 @@ type Dialect interface {
 	// FTSClearSQL returns the SQL to clear all FTS data before a full backfill.
 	FTSClearSQL() string
 
+	// FTSIndexedCondition returns a WHERE condition that holds for the
+	// messages (table alias alias) present in the search index.
+	FTSIndexedCondition(alias string) string
+
+	// FTSSizeSQL returns a query for the approximate bytes the search
+	// index takes on disk.
+	FTSSizeSQL() string
+
 	// SchemaFTS returns the embedded filename containing FTS DDL to execute during
 	// schema initialization. Returns "" if no separate FTS schema file is needed
 	// (e.g., PostgreSQL includes tsvector in its main schema).
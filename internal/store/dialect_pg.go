@@ -40,6 +40,9 @@ func (d *PostgreSQLDialect) Rebind(query string) string {
 // Now returns the PostgreSQL expression for the current timestamp.
 func (d *PostgreSQLDialect) Now() string { return "NOW()" }
 
+// YearMonth formats the timestamp as "YYYY-MM".
+func (d *PostgreSQLDialect) YearMonth(expr string) string { return "to_char(" + expr + ", 'YYYY-MM')" }
+
 // InsertOrIgnore rewrites INSERT OR IGNORE INTO to INSERT INTO and, if the
 // statement appears complete (ends with ")" after VALUES), appends
 // " ON CONFLICT DO NOTHING". Prefix-only strings (ending with "VALUES ")
@@ -98,6 +101,29 @@ func (d *PostgreSQLDialect) FTSSearchClause() (join, where, orderBy string, orde
 		1
 }
 
+// FTSMatchCondition returns the tsvector match of FTSSearchClause, which
+// needs no join.
+func (d *PostgreSQLDialect) FTSMatchCondition() string {
+	return "m.search_fts @@ plainto_tsquery('simple', ?)"
+}
+
+// IDListCondition matches col against a JSON array of IDs.
+func (d *PostgreSQLDialect) IDListCondition(col string) string {
+	return col + " IN (SELECT value::bigint FROM json_array_elements_text(?::json))"
+}
+
+// AttachmentFTSUpsert is a no-op: the GIN index on attachment_texts
+// indexes the text as it is stored.
+func (d *PostgreSQLDialect) AttachmentFTSUpsert(q querier, id int64, text string) error {
+	return nil
+}
+
+// AttachmentFTSMatchCondition returns a tsvector match on attachment_texts
+// att that its expression index answers.
+func (d *PostgreSQLDialect) AttachmentFTSMatchCondition() string {
+	return "to_tsvector('simple', att.content) @@ plainto_tsquery('simple', ?)"
+}
+
 // FTSDeleteSQL returns the SQL to clear tsvector data for messages belonging to a source.
 func (d *PostgreSQLDialect) FTSDeleteSQL() string {
 	return `UPDATE messages SET search_fts = NULL WHERE source_id = $1`
@@ -108,7 +134,7 @@ func (d *PostgreSQLDialect) FTSDeleteSQL() string {
 func (d *PostgreSQLDialect) FTSBackfillBatchSQL() string {
 	return `UPDATE messages m SET search_fts =
 		setweight(to_tsvector('simple', COALESCE(m.subject, '')), 'A') ||
-		to_tsvector('simple', COALESCE(mb.body_text, '')) ||
+		to_tsvector('simple', ` + searchableBodySQL + `) ||
 		setweight(to_tsvector('simple', COALESCE(
 			CASE WHEN m.message_type != 'email' AND m.message_type IS NOT NULL AND m.message_type != ''
 			     THEN (SELECT COALESCE(p.phone_number, p.email_address) FROM participants p WHERE p.id = m.sender_id)
@@ -148,6 +174,18 @@ func (d *PostgreSQLDialect) FTSClearSQL() string {
 	return "UPDATE messages SET search_fts = NULL"
 }
 
+// FTSIndexedCondition matches messages whose tsvector is populated.
+func (d *PostgreSQLDialect) FTSIndexedCondition(alias string) string {
+	return alias + ".search_fts IS NOT NULL"
+}
+
+// FTSSizeSQL sums the tsvector column and its GIN index.
+func (d *PostgreSQLDialect) FTSSizeSQL() string {
+	return `SELECT
+		(SELECT COALESCE(SUM(pg_column_size(search_fts)), 0) FROM messages) +
+		pg_relation_size('messages_search_fts_idx')`
+}
+
 // SchemaFTS returns the embedded filename containing PostgreSQL FTS DDL.
 func (d *PostgreSQLDialect) SchemaFTS() string {
 	return "schema_pg.sql"
@@ -178,7 +216,7 @@ func (d *PostgreSQLDialect) CheckpointWAL(db *sql.DB) error { return nil }
 // SchemaStaleCheck returns the SQL to check whether migrations are needed.
 // PostgreSQL uses information_schema instead of pragma_table_info.
 func (d *PostgreSQLDialect) SchemaStaleCheck() string {
-	return postgresColumnExistsSQL("conversations", "conversation_type")
+	return postgresColumnExistsSQL("attachments", "stored_size")
 }
 
 // IsDuplicateColumnError returns true if the error is a "column already exists" error.
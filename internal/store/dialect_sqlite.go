@@ -19,6 +19,9 @@ func (d *SQLiteDialect) Rebind(query string) string { return query }
 // Now returns the SQLite expression for the current UTC timestamp.
 func (d *SQLiteDialect) Now() string { return "datetime('now')" }
 
+// YearMonth takes the "YYYY-MM" prefix of the stored timestamp text.
+func (d *SQLiteDialect) YearMonth(expr string) string { return "substr(" + expr + ", 1, 7)" }
+
 // InsertOrIgnore is a no-op for SQLite — the syntax is native.
 func (d *SQLiteDialect) InsertOrIgnore(sql string) string { return sql }
 
@@ -41,15 +44,43 @@ func (d *SQLiteDialect) FTSUpsert(q querier, doc FTSDoc) error {
 	return err
 }
 
+// SQLiteFTSRank orders FTS5 matches by BM25 relevance, best first. The
+// weights follow the messages_fts columns (message_id, subject, body,
+// from_addr, to_addr, cc_addr): a term in the subject counts ten times
+// one in the body, and one in the sender's address three times.
+const SQLiteFTSRank = "bm25(messages_fts, 0.0, 10.0, 1.0, 3.0, 1.0, 1.0)"
+
 // FTSSearchClause returns SQL fragments for FTS5 full-text search.
-// "rank" is an implicit FTS5 column, so orderArgCount is 0.
+// The BM25 ranking takes no arguments, so orderArgCount is 0.
 func (d *SQLiteDialect) FTSSearchClause() (join, where, orderBy string, orderArgCount int) {
 	return "JOIN messages_fts fts ON fts.rowid = m.id",
 		"messages_fts MATCH ?",
-		"rank",
+		SQLiteFTSRank,
 		0
 }
 
+// FTSMatchCondition returns an FTS5 match on messages m without a join.
+func (d *SQLiteDialect) FTSMatchCondition() string {
+	return "m.id IN (SELECT rowid FROM messages_fts WHERE messages_fts MATCH ?)"
+}
+
+// IDListCondition matches col against a JSON array of IDs.
+func (d *SQLiteDialect) IDListCondition(col string) string {
+	return col + " IN (SELECT value FROM json_each(?))"
+}
+
+// AttachmentFTSUpsert indexes attachment text in attachments_fts, which
+// keeps no copy of it.
+func (d *SQLiteDialect) AttachmentFTSUpsert(q querier, id int64, text string) error {
+	_, err := q.Exec(`INSERT OR REPLACE INTO attachments_fts(rowid, content) VALUES (?, ?)`, id, text)
+	return err
+}
+
+// AttachmentFTSMatchCondition returns an FTS5 match on attachment_texts att.
+func (d *SQLiteDialect) AttachmentFTSMatchCondition() string {
+	return "att.id IN (SELECT rowid FROM attachments_fts WHERE attachments_fts MATCH ?)"
+}
+
 // FTSDeleteSQL returns the SQL to delete a message's FTS5 entry.
 func (d *SQLiteDialect) FTSDeleteSQL() string {
 	return `DELETE FROM messages_fts WHERE message_id IN (
@@ -61,7 +92,7 @@ func (d *SQLiteDialect) FTSDeleteSQL() string {
 // Parameters: fromID(?), toID(?)
 func (d *SQLiteDialect) FTSBackfillBatchSQL() string {
 	return `INSERT OR REPLACE INTO messages_fts (rowid, message_id, subject, body, from_addr, to_addr, cc_addr)
-		SELECT m.id, m.id, COALESCE(m.subject, ''), COALESCE(mb.body_text, ''),
+		SELECT m.id, m.id, COALESCE(m.subject, ''), ` + searchableBodySQL + `,
 			COALESCE(
 				CASE WHEN m.message_type != 'email' AND m.message_type IS NOT NULL AND m.message_type != ''
 				     THEN (SELECT COALESCE(p.phone_number, p.email_address) FROM participants p WHERE p.id = m.sender_id)
@@ -104,6 +135,21 @@ func (d *SQLiteDialect) FTSClearSQL() string {
 	return "DELETE FROM messages_fts"
 }
 
+// FTSIndexedCondition matches messages with a row in messages_fts.
+func (d *SQLiteDialect) FTSIndexedCondition(alias string) string {
+	return "EXISTS (SELECT 1 FROM messages_fts WHERE messages_fts.rowid = " + alias + ".id)"
+}
+
+// FTSSizeSQL sums the FTS5 shadow tables: the inverted index blocks
+// and the index's own copy of the text. dbstat would be exact, but
+// most SQLite builds leave it out.
+func (d *SQLiteDialect) FTSSizeSQL() string {
+	return `SELECT
+		(SELECT COALESCE(SUM(LENGTH(block)), 0) FROM messages_fts_data) +
+		(SELECT COALESCE(SUM(LENGTH(c1) + LENGTH(c2) + LENGTH(c3) + LENGTH(c4) + LENGTH(c5)), 0) FROM messages_fts_content) +
+		(SELECT COALESCE(SUM(LENGTH(sz)), 0) FROM messages_fts_docsize)`
+}
+
 // SchemaFTS returns the embedded filename containing FTS5 virtual table DDL.
 func (d *SQLiteDialect) SchemaFTS() string {
 	return "schema_sqlite.sql"
@@ -159,7 +205,7 @@ func (d *SQLiteDialect) CheckpointWAL(db *sql.DB) error {
 
 // SchemaStaleCheck returns the SQL to check whether the most recent migration column exists.
 func (d *SQLiteDialect) SchemaStaleCheck() string {
-	return "SELECT COUNT(*) FROM pragma_table_info('conversations') WHERE name = 'conversation_type'"
+	return "SELECT COUNT(*) FROM pragma_table_info('attachments') WHERE name = 'stored_size'"
 }
 
 // IsDuplicateColumnError returns true if the error is "duplicate column name" from ALTER TABLE.
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ArchiveDiff is what changed in the archive since a point in time:
+// the messages archived, those deleted from their account, and the
+// labels added to or removed from messages. The counts are complete;
+// the lists hold at most the limit passed to ArchiveChanges each,
+// newest first.
+type ArchiveDiff struct {
+	Since        time.Time         `json:"since"`
+	Added        int64             `json:"added"`
+	Deleted      int64             `json:"deleted_remotely"`
+	LabelChanges int64             `json:"label_changes"`
+	AddedList    []DiffMessage     `json:"added_messages"`
+	DeletedList  []DiffMessage     `json:"deleted_messages"`
+	LabelList    []DiffLabelChange `json:"label_events"`
+}
+
+// IsEmpty reports whether nothing changed.
+func (d *ArchiveDiff) IsEmpty() bool {
+	return d.Added == 0 && d.Deleted == 0 && d.LabelChanges == 0
+}
+
+// DiffMessage is a message archived, or deleted from its account, at
+// At.
+type DiffMessage struct {
+	ID      int64     `json:"id"`
+	Account string    `json:"account"`
+	From    string    `json:"from"`
+	Subject string    `json:"subject"`
+	SentAt  time.Time `json:"sent_at"`
+	At      time.Time `json:"at"`
+}
+
+// DiffLabelChange is a label added to or removed from a message at At.
+type DiffLabelChange struct {
+	MessageID int64     `json:"message_id"`
+	Account   string    `json:"account"`
+	Subject   string    `json:"subject"`
+	Label     string    `json:"label"`
+	Action    string    `json:"action"` // LabelChangeAdd or LabelChangeRemove
+	At        time.Time `json:"at"`
+}
+
+// ArchiveChanges returns what changed in the given sources (all sources
+// when empty) since since, listing at most limit of each kind of
+// change. Label changes are those the label history recorded: Gmail
+// history records seen by incremental syncs and edits made in the
+// archive.
+func (s *Store) ArchiveChanges(since time.Time, sourceIDs []int64, limit int) (*ArchiveDiff, error) {
+	d := &ArchiveDiff{Since: since}
+	t := since.UTC().Format("2006-01-02 15:04:05")
+	scope, scopeArgs := sourceScopeClause(sourceIDs)
+	live := LiveMessagesWhere("m", false)
+
+	for _, kind := range []struct {
+		column string
+		count  *int64
+		list   *[]DiffMessage
+	}{
+		{"archived_at", &d.Added, &d.AddedList},
+		{"deleted_from_source_at", &d.Deleted, &d.DeletedList},
+	} {
+		where := `m.` + kind.column + ` >= ? AND ` + live + scope
+		args := append([]any{t}, scopeArgs...)
+		if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages m WHERE `+where, args...).Scan(kind.count); err != nil {
+			return nil, fmt.Errorf("count changed messages: %w", err)
+		}
+		list, err := s.diffMessages(kind.column, where, append(args, limit))
+		if err != nil {
+			return nil, err
+		}
+		*kind.list = list
+	}
+
+	where := `e.occurred_at >= ? AND ` + live + scope
+	args := append([]any{t}, scopeArgs...)
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM label_events e
+		JOIN messages m ON m.id = e.message_id
+		WHERE `+where, args...).Scan(&d.LabelChanges); err != nil {
+		return nil, fmt.Errorf("count label changes: %w", err)
+	}
+	rows, err := s.db.Query(`
+		SELECT e.message_id, src.identifier, COALESCE(m.subject, ''), l.name, e.action, e.occurred_at
+		FROM label_events e
+		JOIN messages m ON m.id = e.message_id
+		JOIN sources src ON src.id = m.source_id
+		JOIN labels l ON l.id = e.label_id
+		WHERE `+where+`
+		ORDER BY e.occurred_at DESC, e.id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("list label changes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var r DiffLabelChange
+		var at string
+		if err := rows.Scan(&r.MessageID, &r.Account, &r.Subject, &r.Label, &r.Action, &at); err != nil {
+			return nil, fmt.Errorf("scan label change: %w", err)
+		}
+		r.At = parseSQLiteTime(at)
+		d.LabelList = append(d.LabelList, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list label changes: %w", err)
+	}
+	return d, nil
+}
+
+// diffMessages lists the messages matching where, newest change in
+// column first.
+func (s *Store) diffMessages(column, where string, args []any) ([]DiffMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id, src.identifier,
+			COALESCE((
+				SELECT p.email_address FROM message_recipients mr
+				JOIN participants p ON p.id = mr.participant_id
+				WHERE mr.message_id = m.id AND mr.recipient_type = 'from'
+				LIMIT 1
+			), ''),
+			COALESCE(m.subject, ''), m.sent_at, m.`+column+`
+		FROM messages m
+		JOIN sources src ON src.id = m.source_id
+		WHERE `+where+`
+		ORDER BY m.`+column+` DESC, m.id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list changed messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []DiffMessage
+	for rows.Next() {
+		var m DiffMessage
+		var sentAt sql.NullString
+		var at string
+		if err := rows.Scan(&m.ID, &m.Account, &m.From, &m.Subject, &sentAt, &at); err != nil {
+			return nil, fmt.Errorf("scan changed message: %w", err)
+		}
+		m.SentAt = parseSQLiteTime(sentAt.String)
+		m.At = parseSQLiteTime(at)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
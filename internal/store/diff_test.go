@@ -0,0 +1,54 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_ArchiveChanges(t *testing.T) {
+	f := storetest.New(t)
+	inbox := f.EnsureLabels(map[string]string{"INBOX": "INBOX"}, "system")["INBOX"]
+	old := f.NewMessage().WithSourceMessageID("old").WithSubject("old news").Create(t, f.Store)
+	fresh := f.NewMessage().WithSourceMessageID("fresh").WithSubject("fresh news").Create(t, f.Store)
+	gone := f.NewMessage().WithSourceMessageID("gone").WithSubject("gone").Create(t, f.Store)
+
+	_, err := f.Store.DB().Exec(`UPDATE messages SET archived_at = '2020-01-01 00:00:00' WHERE id IN (?, ?)`, old, gone)
+	testutil.MustNoErr(t, err, "backdate archived_at")
+	testutil.MustNoErr(t, f.Store.MarkMessagesDeletedBatch(f.Source.ID, []string{"gone"}), "MarkMessagesDeletedBatch")
+	testutil.MustNoErr(t, f.Store.ApplyLabelHistory(old, []int64{inbox}, true, "100"), "add INBOX")
+
+	d, err := f.Store.ArchiveChanges(time.Now().Add(-time.Hour), nil, 10)
+	testutil.MustNoErr(t, err, "ArchiveChanges")
+	if d.Added != 1 || len(d.AddedList) != 1 || d.AddedList[0].ID != fresh {
+		t.Errorf("added = %d %+v, want message %d", d.Added, d.AddedList, fresh)
+	}
+	if d.Deleted != 1 || len(d.DeletedList) != 1 || d.DeletedList[0].ID != gone {
+		t.Errorf("deleted = %d %+v, want message %d", d.Deleted, d.DeletedList, gone)
+	}
+	if d.LabelChanges != 1 || len(d.LabelList) != 1 ||
+		d.LabelList[0].MessageID != old || d.LabelList[0].Label != "INBOX" || d.LabelList[0].Action != store.LabelChangeAdd {
+		t.Errorf("label changes = %d %+v", d.LabelChanges, d.LabelList)
+	}
+
+	// Counts cover every change; lists stop at the limit.
+	d, err = f.Store.ArchiveChanges(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), nil, 1)
+	testutil.MustNoErr(t, err, "ArchiveChanges since 2019")
+	if d.Added != 3 || len(d.AddedList) != 1 {
+		t.Errorf("added since 2019 = %d, %d listed; want 3, 1 listed", d.Added, len(d.AddedList))
+	}
+
+	d, err = f.Store.ArchiveChanges(time.Now().Add(time.Hour), nil, 10)
+	testutil.MustNoErr(t, err, "ArchiveChanges from the future")
+	if !d.IsEmpty() {
+		t.Errorf("ArchiveChanges from the future = %+v, want nothing", d)
+	}
+	d, err = f.Store.ArchiveChanges(time.Now().Add(-time.Hour), []int64{f.Source.ID + 1}, 10)
+	testutil.MustNoErr(t, err, "ArchiveChanges of another account")
+	if !d.IsEmpty() {
+		t.Errorf("ArchiveChanges of another account = %+v, want nothing", d)
+	}
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// FTSStats describes the full-text search index.
+type FTSStats struct {
+	// Available is false when this build or database has no full-text
+	// search; the other fields are then zero.
+	Available bool
+	// IndexedCount of the MessageCount live messages are in the index.
+	MessageCount int64
+	IndexedCount int64
+	// SizeBytes is the approximate space the index takes.
+	SizeBytes int64
+	// LastIndexedID is the newest message in the index, zero when the
+	// index is empty; LastIndexedAt is when it was sent.
+	LastIndexedID int64
+	LastIndexedAt time.Time
+}
+
+// Coverage is the fraction of live messages in the index.
+func (st *FTSStats) Coverage() float64 {
+	if st.MessageCount == 0 {
+		return 1
+	}
+	return float64(st.IndexedCount) / float64(st.MessageCount)
+}
+
+// FTSStats returns the size and coverage of the full-text search index.
+func (s *Store) FTSStats() (*FTSStats, error) {
+	st := &FTSStats{}
+	if !s.fts5Available {
+		return st, nil
+	}
+	st.Available = true
+
+	live := LiveMessagesWhere("m", true)
+	indexed := s.dialect.FTSIndexedCondition("m")
+	if err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM messages m WHERE " + live,
+	).Scan(&st.MessageCount); err != nil {
+		return nil, fmt.Errorf("count messages: %w", err)
+	}
+	if err := s.db.QueryRow(
+		"SELECT COUNT(*) FROM messages m WHERE " + live + " AND " + indexed,
+	).Scan(&st.IndexedCount); err != nil {
+		return nil, fmt.Errorf("count indexed messages: %w", err)
+	}
+	if err := s.db.QueryRow(s.dialect.FTSSizeSQL()).Scan(&st.SizeBytes); err != nil {
+		return nil, fmt.Errorf("get search index size: %w", err)
+	}
+
+	var sentAt sql.NullTime
+	err := s.db.QueryRow(
+		"SELECT m.id, m.sent_at FROM messages m WHERE "+indexed+" ORDER BY m.id DESC LIMIT 1",
+	).Scan(&st.LastIndexedID, &sentAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get last indexed message: %w", err)
+	}
+	if sentAt.Valid {
+		st.LastIndexedAt = sentAt.Time
+	}
+	return st, nil
+}
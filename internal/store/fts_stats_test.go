@@ -0,0 +1,62 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_FTSStats(t *testing.T) {
+	f := storetest.New(t)
+	if !f.Store.FTS5Available() {
+		st, err := f.Store.FTSStats()
+		testutil.MustNoErr(t, err, "FTSStats")
+		if st.Available || st.IndexedCount != 0 {
+			t.Errorf("FTSStats() = %+v without FTS5, want unavailable", st)
+		}
+		t.Skip("FTS5 not available")
+	}
+
+	empty, err := f.Store.FTSStats()
+	testutil.MustNoErr(t, err, "FTSStats on empty index")
+	if empty.LastIndexedID != 0 || empty.IndexedCount != 0 || empty.Coverage() != 1 {
+		t.Errorf("empty FTSStats() = %+v, coverage %v", empty, empty.Coverage())
+	}
+
+	sent := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := f.NewMessage().WithSubject("apple").WithSentAt(sent.AddDate(0, -1, 0)).Create(t, f.Store)
+	last := f.NewMessage().WithSubject("banana").WithSentAt(sent).Create(t, f.Store)
+	testutil.MustNoErr(t, f.Store.UpsertMessageBody(first,
+		sql.NullString{String: "apple pie filling", Valid: true}, sql.NullString{}),
+		"UpsertMessageBody")
+	_, err = f.Store.BackfillFTS(nil)
+	testutil.MustNoErr(t, err, "BackfillFTS")
+	// Not yet indexed.
+	f.NewMessage().WithSubject("cherry").Create(t, f.Store)
+
+	st, err := f.Store.FTSStats()
+	testutil.MustNoErr(t, err, "FTSStats")
+	want := store.FTSStats{
+		Available:     true,
+		MessageCount:  3,
+		IndexedCount:  2,
+		LastIndexedID: last,
+	}
+	if st.Available != want.Available || st.MessageCount != want.MessageCount ||
+		st.IndexedCount != want.IndexedCount || st.LastIndexedID != want.LastIndexedID {
+		t.Errorf("FTSStats() = %+v, want %+v", st, want)
+	}
+	if !st.LastIndexedAt.Equal(sent) {
+		t.Errorf("LastIndexedAt = %v, want %v", st.LastIndexedAt, sent)
+	}
+	if st.SizeBytes <= 0 {
+		t.Errorf("SizeBytes = %d, want > 0", st.SizeBytes)
+	}
+	if got := st.Coverage(); got < 0.66 || got > 0.67 {
+		t.Errorf("Coverage() = %v, want 2/3", got)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import "fmt"
+
+// GmailIDs holds the Gmail API IDs a message was synced or imported
+// with.
+type GmailIDs struct {
+	MessageID string
+	ThreadID  string // "" if the thread has no Gmail ID
+}
+
+// GmailIDsForMessages returns the Gmail IDs of the given messages that
+// came from Gmail sources, keyed by message ID. Messages from other
+// sources are left out.
+func (s *Store) GmailIDsForMessages(ids []int64) (map[int64]GmailIDs, error) {
+	result := make(map[int64]GmailIDs, len(ids))
+	err := queryInChunks(s.db, ids, nil, `
+		SELECT m.id, COALESCE(m.source_message_id, ''), COALESCE(c.source_conversation_id, '')
+		FROM messages m
+		JOIN sources src ON src.id = m.source_id
+		LEFT JOIN conversations c ON c.id = m.conversation_id
+		WHERE src.source_type = 'gmail' AND m.id IN (%s)`,
+		func(rows *loggedRows) error {
+			var id int64
+			var g GmailIDs
+			if err := rows.Scan(&id, &g.MessageID, &g.ThreadID); err != nil {
+				return err
+			}
+			result[id] = g
+			return nil
+		})
+	if err != nil {
+		return nil, fmt.Errorf("get gmail ids: %w", err)
+	}
+	return result, nil
+}
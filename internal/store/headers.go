@@ -0,0 +1,144 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// DefaultIndexedHeaders are the header fields always indexed from a
+// message's raw MIME, for the list: and deliveredto: search operators.
+var DefaultIndexedHeaders = []string{"list-id", "delivered-to"}
+
+// SetIndexedHeaders sets the header fields indexed besides
+// DefaultIndexedHeaders, such as X-Original-To or X-Mailer. Messages
+// stored earlier pick up a change when reparsed.
+func (s *Store) SetIndexedHeaders(extra []string) {
+	s.extraHeaders = nil
+	for _, name := range extra {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || slices.Contains(DefaultIndexedHeaders, name) || slices.Contains(s.extraHeaders, name) {
+			continue
+		}
+		s.extraHeaders = append(s.extraHeaders, name)
+	}
+}
+
+// indexedHeaderNames returns the header fields indexed from raw MIME.
+func (s *Store) indexedHeaderNames() []string {
+	return append(slices.Clone(DefaultIndexedHeaders), s.extraHeaders...)
+}
+
+// indexedHeaders returns the indexed header fields of raw MIME. A
+// message whose header block cannot be read has none.
+func (s *Store) indexedHeaders(raw []byte) []mime.Header {
+	if len(raw) == 0 {
+		return nil
+	}
+	headers, err := mime.IndexedHeaders(raw, s.indexedHeaderNames())
+	if err != nil {
+		return nil
+	}
+	return headers
+}
+
+// replaceMessageHeaders replaces the header fields indexed for a
+// message.
+func replaceMessageHeaders(q querier, d Dialect, messageID int64, headers []mime.Header) error {
+	if _, err := q.Exec(`DELETE FROM message_headers WHERE message_id = ?`, messageID); err != nil {
+		return err
+	}
+	insert := d.InsertOrIgnore(`INSERT OR IGNORE INTO message_headers (message_id, name, value) VALUES (?, ?, ?)`)
+	for _, h := range headers {
+		if _, err := q.Exec(insert, messageID, h.Name, h.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMessageHeaders returns the header fields indexed for a message.
+func (s *Store) GetMessageHeaders(messageID int64) ([]mime.Header, error) {
+	headers, err := messageHeaders(s.db, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("get message headers: %w", err)
+	}
+	return headers, nil
+}
+
+func messageHeaders(q linkQuerier, messageID int64) ([]mime.Header, error) {
+	rows, err := q.Query(`
+		SELECT name, value FROM message_headers
+		WHERE message_id = ? ORDER BY name, value
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var headers []mime.Header
+	for rows.Next() {
+		var h mime.Header
+		if err := rows.Scan(&h.Name, &h.Value); err != nil {
+			return nil, err
+		}
+		headers = append(headers, h)
+	}
+	return headers, rows.Err()
+}
+
+// sameHeaders reports whether two header sets hold the same fields, in
+// any order.
+func sameHeaders(a, b []mime.Header) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	cmpHeader := func(x, y mime.Header) int {
+		if c := strings.Compare(x.Name, y.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(x.Value, y.Value)
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.SortFunc(a, cmpHeader)
+	slices.SortFunc(b, cmpHeader)
+	return slices.Equal(a, b)
+}
+
+// HeaderValueCount is how many messages carry a value of an indexed
+// header field.
+type HeaderValueCount struct {
+	Value    string `json:"value"`
+	Messages int64  `json:"messages"`
+}
+
+// HeaderValueCounts returns the values of an indexed header field, such
+// as the mailing lists of list-id or the addresses of delivered-to,
+// most common first. Deleted messages are not counted.
+func (s *Store) HeaderValueCounts(name string, limit int) ([]HeaderValueCount, error) {
+	rows, err := s.db.Query(`
+		SELECT mh.value, COUNT(*)
+		FROM message_headers mh
+		JOIN messages m ON m.id = mh.message_id
+		WHERE mh.name = ? AND `+LiveMessagesWhere("m", false)+`
+		GROUP BY mh.value
+		ORDER BY COUNT(*) DESC, mh.value
+		LIMIT ?
+	`, strings.ToLower(name), limit)
+	if err != nil {
+		return nil, fmt.Errorf("header value counts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []HeaderValueCount
+	for rows.Next() {
+		var c HeaderValueCount
+		if err := rows.Scan(&c.Value, &c.Messages); err != nil {
+			return nil, fmt.Errorf("scan header value: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
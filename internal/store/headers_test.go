@@ -0,0 +1,83 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func listMessage(list, deliveredTo string) string {
+	return "From: alice@example.com\r\n" +
+		"To: " + deliveredTo + "\r\n" +
+		"Delivered-To: " + deliveredTo + "\r\n" +
+		"List-Id: Gophers <" + list + ">\r\n" +
+		"X-Mailer: Example Mail 2.0\r\n" +
+		"Subject: hello\r\n\r\nbody\r\n"
+}
+
+func TestStore_IndexedHeaders(t *testing.T) {
+	f := storetest.New(t)
+	nuts := persistRaw(t, f, "msg-1", listMessage("golang-nuts.googlegroups.com", "alias@example.com"))
+	persistRaw(t, f, "msg-2", listMessage("golang-nuts.googlegroups.com", "bob@example.org"))
+	persistRaw(t, f, "msg-3", listMessage("announce.lists.example.com", "alias@example.com"))
+
+	got, err := f.Store.GetMessageHeaders(nuts)
+	testutil.MustNoErr(t, err, "GetMessageHeaders")
+	if len(got) != 2 || got[0].Name != "delivered-to" || got[0].Value != "alias@example.com" ||
+		got[1].Name != "list-id" || got[1].Value != "golang-nuts.googlegroups.com" {
+		t.Errorf("GetMessageHeaders = %+v", got)
+	}
+
+	for query, want := range map[string]int64{
+		"list:golang-nuts":                 2,
+		"deliveredto:alias@example.com":    2,
+		"list:golang-nuts deliveredto:bob": 1,
+		"list:rust-users":                  0,
+	} {
+		_, total, err := f.Store.SearchMessagesQuery(search.Parse(query), 0, 10)
+		testutil.MustNoErr(t, err, "SearchMessagesQuery "+query)
+		if total != want {
+			t.Errorf("%s: %d results, want %d", query, total, want)
+		}
+	}
+
+	counts, err := f.Store.HeaderValueCounts("List-Id", 10)
+	testutil.MustNoErr(t, err, "HeaderValueCounts")
+	if len(counts) != 2 || counts[0] != (store.HeaderValueCount{Value: "golang-nuts.googlegroups.com", Messages: 2}) {
+		t.Errorf("HeaderValueCounts = %+v", counts)
+	}
+}
+
+func TestStore_ApplyReparseHeaders(t *testing.T) {
+	f := storetest.New(t)
+	raw := listMessage("golang-nuts.googlegroups.com", "alias@example.com")
+	id := f.CreateMessage("msg-1")
+	r := &store.ReparsedMessage{Raw: []byte(raw)}
+
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if !changed {
+		t.Error("ApplyReparse indexing headers reported no change")
+	}
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse again")
+	if changed {
+		t.Error("identical ApplyReparse reported a change")
+	}
+
+	// Headers added to the index are picked up on reparse.
+	f.Store.SetIndexedHeaders([]string{"X-Mailer", "list-id"})
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse with X-Mailer")
+	if !changed {
+		t.Error("ApplyReparse indexing X-Mailer reported no change")
+	}
+	got, err := f.Store.GetMessageHeaders(id)
+	testutil.MustNoErr(t, err, "GetMessageHeaders")
+	if len(got) != 3 || got[2].Name != "x-mailer" || got[2].Value != "Example Mail 2.0" {
+		t.Errorf("GetMessageHeaders = %+v", got)
+	}
+}
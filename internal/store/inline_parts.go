@@ -0,0 +1,102 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// InlinePart is an image embedded in a message's HTML body and
+// referenced there as cid:ContentID.
+type InlinePart struct {
+	ContentID   string
+	Filename    string
+	MimeType    string
+	Size        int64
+	ContentHash string
+}
+
+// inlinePartsFrom converts parsed embedded parts to InlineParts,
+// keeping the first of any parts that share a Content-ID.
+func inlinePartsFrom(atts []mime.Attachment) []InlinePart {
+	var parts []InlinePart
+	seen := make(map[string]bool)
+	for _, a := range atts {
+		if !a.Embedded() || seen[a.ContentID] {
+			continue
+		}
+		seen[a.ContentID] = true
+		parts = append(parts, InlinePart{
+			ContentID:   a.ContentID,
+			Filename:    a.Filename,
+			MimeType:    a.ContentType,
+			Size:        int64(a.Size),
+			ContentHash: a.ContentHash,
+		})
+	}
+	return parts
+}
+
+// replaceInlineParts replaces the inline parts recorded for a message.
+func replaceInlineParts(q querier, messageID int64, parts []InlinePart) error {
+	if _, err := q.Exec(`DELETE FROM message_inline_parts WHERE message_id = ?`, messageID); err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := q.Exec(`
+			INSERT INTO message_inline_parts (message_id, content_id, filename, mime_type, size, content_hash)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, messageID, p.ContentID, p.Filename, p.MimeType, p.Size, p.ContentHash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetInlineParts returns the images embedded in a message's HTML body,
+// ordered by Content-ID.
+func (s *Store) GetInlineParts(messageID int64) ([]InlinePart, error) {
+	parts, err := messageInlineParts(s.db, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("get inline parts: %w", err)
+	}
+	return parts, nil
+}
+
+func messageInlineParts(q linkQuerier, messageID int64) ([]InlinePart, error) {
+	rows, err := q.Query(`
+		SELECT content_id, COALESCE(filename, ''), COALESCE(mime_type, ''),
+			COALESCE(size, 0), COALESCE(content_hash, '')
+		FROM message_inline_parts
+		WHERE message_id = ? ORDER BY content_id
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var parts []InlinePart
+	for rows.Next() {
+		var p InlinePart
+		if err := rows.Scan(&p.ContentID, &p.Filename, &p.MimeType, &p.Size, &p.ContentHash); err != nil {
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+	return parts, rows.Err()
+}
+
+// sameInlineParts reports whether two sets of inline parts are the
+// same, in any order.
+func sameInlineParts(a, b []InlinePart) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	cmp := func(x, y InlinePart) int { return strings.Compare(x.ContentID, y.ContentID) }
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.SortFunc(a, cmp)
+	slices.SortFunc(b, cmp)
+	return slices.Equal(a, b)
+}
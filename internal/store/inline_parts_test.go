@@ -0,0 +1,62 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+var logoPart = mime.Attachment{
+	Filename:    "logo.png",
+	ContentType: "image/png",
+	ContentID:   "logo@example.com",
+	Size:        42,
+	ContentHash: "abc123",
+	IsInline:    true,
+}
+
+func TestStore_PersistInlineParts(t *testing.T) {
+	f := storetest.New(t)
+	pdf := mime.Attachment{Filename: "a.pdf", ContentType: "application/pdf", ContentID: "pdf@example.com", IsInline: true}
+	id, err := f.Store.PersistMessage(&store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  f.ConvID,
+			SourceID:        f.Source.ID,
+			SourceMessageID: "msg-1",
+			MessageType:     "email",
+		},
+		InlineParts: []mime.Attachment{logoPart, pdf, logoPart},
+	})
+	testutil.MustNoErr(t, err, "PersistMessage")
+
+	got, err := f.Store.GetInlineParts(id)
+	testutil.MustNoErr(t, err, "GetInlineParts")
+	want := store.InlinePart{ContentID: "logo@example.com", Filename: "logo.png", MimeType: "image/png", Size: 42, ContentHash: "abc123"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("GetInlineParts = %+v, want [%+v]", got, want)
+	}
+}
+
+func TestStore_ApplyReparseInlineParts(t *testing.T) {
+	f := storetest.New(t)
+	id := f.CreateMessage("msg-1")
+	r := &store.ReparsedMessage{InlineParts: []mime.Attachment{logoPart}}
+
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if !changed {
+		t.Error("ApplyReparse adding inline parts reported no change")
+	}
+	if parts, _ := f.Store.GetInlineParts(id); len(parts) != 1 {
+		t.Errorf("inline parts after reparse = %+v", parts)
+	}
+
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse again")
+	if changed {
+		t.Error("identical ApplyReparse reported a change")
+	}
+}
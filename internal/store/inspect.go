@@ -173,17 +173,18 @@ func (s *Store) InspectBodyText(sourceMessageID string) (string, error) {
 // InspectRawDataExists checks that raw MIME data exists for a message.
 func (s *Store) InspectRawDataExists(sourceMessageID string) (bool, error) {
 	var rawData []byte
+	var blobHash sql.NullString
 	err := s.db.QueryRow(`
-		SELECT raw_data FROM message_raw mr
+		SELECT raw_data, blob_hash FROM message_raw mr
 		JOIN messages m ON m.id = mr.message_id
-		WHERE m.source_message_id = ?`, sourceMessageID).Scan(&rawData)
+		WHERE m.source_message_id = ?`, sourceMessageID).Scan(&rawData, &blobHash)
 	if err == sql.ErrNoRows {
 		return false, nil
 	}
 	if err != nil {
 		return false, err
 	}
-	return len(rawData) > 0, nil
+	return len(rawData) > 0 || blobHash.Valid, nil
 }
 
 // InspectThreadSourceID returns the source_conversation_id for a message's thread.
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Actions of a pending label change.
+const (
+	LabelChangeAdd    = "add"
+	LabelChangeRemove = "remove"
+)
+
+// queueLabelChanges queues a label edit of messages for push to Gmail:
+// adding labelID when action is LabelChangeAdd, removing it otherwise.
+// Only messages of Gmail accounts whose labels the edit changes are
+// queued, and only for labels of their own account or vault-local
+// labels. It must run in the edit's transaction, before the edit.
+// A newer edit of the same label replaces a queued one.
+func (s *Store) queueLabelChanges(tx *loggedTx, labelID int64, messageIDs []int64, action string) error {
+	has := "NOT EXISTS"
+	if action == LabelChangeRemove {
+		has = "EXISTS"
+	}
+	err := execInChunks(tx, messageIDs, []interface{}{labelID, action, labelID, labelID}, fmt.Sprintf(`
+		INSERT INTO pending_label_changes (message_id, label_id, action, base_history_id, queued_at)
+		SELECT m.id, ?, ?, src.sync_cursor, %s
+		FROM messages m
+		JOIN sources src ON src.id = m.source_id
+		WHERE src.source_type = 'gmail'
+			AND EXISTS (SELECT 1 FROM labels l WHERE l.id = ? AND (l.source_id IS NULL OR l.source_id = m.source_id))
+			AND %s (SELECT 1 FROM message_labels ml WHERE ml.message_id = m.id AND ml.label_id = ?)
+			AND m.id IN (%%s)
+		ON CONFLICT(message_id, label_id) DO UPDATE SET
+			action = excluded.action,
+			queued_at = excluded.queued_at
+	`, s.dialect.Now(), has))
+	if err != nil {
+		return fmt.Errorf("queue label changes: %w", err)
+	}
+	return nil
+}
+
+// UnlabelMessages removes labelID from each message. The removals from
+// Gmail messages are queued for push to Gmail.
+func (s *Store) UnlabelMessages(labelID int64, messageIDs []int64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		if err := s.queueLabelChanges(tx, labelID, messageIDs, LabelChangeRemove); err != nil {
+			return err
+		}
+		if err := s.recordLabelEvents(tx, labelID, messageIDs, LabelChangeRemove, ""); err != nil {
+			return err
+		}
+		return execInChunks(tx, messageIDs, []interface{}{labelID},
+			`DELETE FROM message_labels WHERE label_id = ? AND message_id IN (%s)`)
+	})
+}
+
+// EditMessagesLabel adds the label called name to each message, or with
+// add false removes it. Each message gets its own account's label of
+// that name, or the vault-local label when its account has none; add
+// creates the local label if needed. Edits of Gmail messages are
+// queued for push to Gmail.
+func (s *Store) EditMessagesLabel(name string, messageIDs []int64, add bool) error {
+	bySource := make(map[int64][]int64)
+	err := queryInChunks(s.db, messageIDs, nil,
+		`SELECT id, source_id FROM messages WHERE id IN (%s)`,
+		func(rows *loggedRows) error {
+			var id, sourceID int64
+			if err := rows.Scan(&id, &sourceID); err != nil {
+				return err
+			}
+			bySource[sourceID] = append(bySource[sourceID], id)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("look up message accounts: %w", err)
+	}
+
+	for sourceID, ids := range bySource {
+		var labelID int64
+		err := s.db.QueryRow(`SELECT id FROM labels WHERE source_id = ? AND name = ?`, sourceID, name).Scan(&labelID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && add:
+			if labelID, err = s.EnsureLocalLabel(name); err != nil {
+				return err
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			err = s.db.QueryRow(`SELECT id FROM labels WHERE source_id IS NULL AND name = ?`, name).Scan(&labelID)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("look up label %q: %w", name, err)
+			}
+		case err != nil:
+			return fmt.Errorf("look up label %q: %w", name, err)
+		}
+		if add {
+			err = s.LabelMessages(labelID, ids)
+		} else {
+			err = s.UnlabelMessages(labelID, ids)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PendingLabelChange is a label edit waiting to be pushed to Gmail.
+type PendingLabelChange struct {
+	ID              int64     `json:"id"`
+	MessageID       int64     `json:"message_id"`
+	SourceID        int64     `json:"source_id"`
+	Account         string    `json:"account"`
+	SourceMessageID string    `json:"source_message_id"`
+	LabelID         int64     `json:"label_id"`
+	LabelName       string    `json:"label"`
+	SourceLabelID   string    `json:"source_label_id,omitempty"` // empty for a vault-local label
+	Action          string    `json:"action"`
+	BaseHistoryID   string    `json:"base_history_id,omitempty"`
+	QueuedAt        time.Time `json:"queued_at"`
+}
+
+// PendingLabelChanges returns the label edits waiting to be pushed for
+// a source, or for every source when sourceID is 0, grouped by message
+// in the order they were queued.
+func (s *Store) PendingLabelChanges(sourceID int64) ([]PendingLabelChange, error) {
+	where, args := "", []any(nil)
+	if sourceID != 0 {
+		where, args = "WHERE m.source_id = ?", []any{sourceID}
+	}
+	rows, err := s.db.Query(`
+		SELECT pc.id, pc.message_id, m.source_id, src.identifier, m.source_message_id,
+			pc.label_id, l.name, CASE WHEN l.source_id IS NULL THEN '' ELSE COALESCE(l.source_label_id, '') END,
+			pc.action, COALESCE(pc.base_history_id, ''), pc.queued_at
+		FROM pending_label_changes pc
+		JOIN messages m ON m.id = pc.message_id
+		JOIN sources src ON src.id = m.source_id
+		JOIN labels l ON l.id = pc.label_id
+		`+where+`
+		ORDER BY m.source_id, pc.message_id, pc.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list pending label changes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []PendingLabelChange
+	for rows.Next() {
+		var c PendingLabelChange
+		var queuedAt sql.NullString
+		if err := rows.Scan(&c.ID, &c.MessageID, &c.SourceID, &c.Account, &c.SourceMessageID,
+			&c.LabelID, &c.LabelName, &c.SourceLabelID, &c.Action, &c.BaseHistoryID, &queuedAt); err != nil {
+			return nil, fmt.Errorf("scan pending label change: %w", err)
+		}
+		c.QueuedAt = parseSQLiteTime(queuedAt.String)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ResolveLabelChanges forgets pending label changes once they are
+// pushed, or moot because the message is gone from Gmail.
+func (s *Store) ResolveLabelChanges(ids []int64) error {
+	if err := execInChunks(s.db, ids, nil, `DELETE FROM pending_label_changes WHERE id IN (%s)`); err != nil {
+		return fmt.Errorf("resolve label changes: %w", err)
+	}
+	return nil
+}
+
+// DiscardLabelChanges forgets the pending label changes of a source, or
+// of every source when sourceID is 0, without pushing them, and
+// returns how many it forgot. The local edits stay.
+func (s *Store) DiscardLabelChanges(sourceID int64) (int64, error) {
+	var res sql.Result
+	var err error
+	if sourceID == 0 {
+		res, err = s.db.Exec(`DELETE FROM pending_label_changes`)
+	} else {
+		res, err = s.db.Exec(`
+			DELETE FROM pending_label_changes
+			WHERE EXISTS (SELECT 1 FROM messages m WHERE m.id = pending_label_changes.message_id AND m.source_id = ?)
+		`, sourceID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("discard label changes: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,100 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// pendingActions returns the queued action per message and label name.
+func pendingActions(t *testing.T, f *storetest.Fixture) map[int64]map[string]string {
+	t.Helper()
+	changes, err := f.Store.PendingLabelChanges(0)
+	testutil.MustNoErr(t, err, "PendingLabelChanges")
+	out := make(map[int64]map[string]string)
+	for _, c := range changes {
+		if out[c.MessageID] == nil {
+			out[c.MessageID] = make(map[string]string)
+		}
+		out[c.MessageID][c.LabelName] = c.Action
+	}
+	return out
+}
+
+func TestLabelChanges_QueueEdits(t *testing.T) {
+	f := storetest.New(t)
+	testutil.MustNoErr(t, f.Store.UpdateSourceSyncCursor(f.Source.ID, "4200"), "UpdateSourceSyncCursor")
+	labels := f.EnsureLabels(map[string]string{"INBOX": "INBOX", "Label_1": "Receipts"}, "system")
+	m1, m2 := f.CreateMessage("m1"), f.CreateMessage("m2")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(m1, []int64{labels["INBOX"]}), "AddMessageLabels")
+
+	// Removing a label the message lacks and adding one it has change
+	// nothing, so nothing is queued for them.
+	testutil.MustNoErr(t, f.Store.UnlabelMessages(labels["INBOX"], []int64{m1, m2}), "UnlabelMessages")
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Receipts", []int64{m1}, true), "add Receipts")
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Receipts", []int64{m1}, true), "add Receipts again")
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Travel", []int64{m2}, true), "add local Travel")
+
+	got := pendingActions(t, f)
+	want := map[int64]map[string]string{
+		m1: {"INBOX": store.LabelChangeRemove, "Receipts": store.LabelChangeAdd},
+		m2: {"Travel": store.LabelChangeAdd},
+	}
+	if len(got) != len(want) || len(got[m1]) != 2 || len(got[m2]) != 1 {
+		t.Fatalf("pending = %v, want %v", got, want)
+	}
+	for id, byLabel := range want {
+		for name, action := range byLabel {
+			if got[id][name] != action {
+				t.Errorf("pending[%d][%s] = %q, want %q", id, name, got[id][name], action)
+			}
+		}
+	}
+	f.AssertLabelCount(m1, 1)
+
+	changes, err := f.Store.PendingLabelChanges(f.Source.ID)
+	testutil.MustNoErr(t, err, "PendingLabelChanges")
+	for _, c := range changes {
+		if c.BaseHistoryID != "4200" || c.Account != "test@example.com" || c.QueuedAt.IsZero() {
+			t.Errorf("change = %+v, want base 4200 of test@example.com with a queue time", c)
+		}
+		if wantLocal := c.LabelName == "Travel"; wantLocal != (c.SourceLabelID == "") {
+			t.Errorf("change %s: SourceLabelID = %q", c.LabelName, c.SourceLabelID)
+		}
+	}
+
+	// Undoing an edit before it is pushed flips the queued action.
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Receipts", []int64{m1}, false), "remove Receipts")
+	if got := pendingActions(t, f); got[m1]["Receipts"] != store.LabelChangeRemove {
+		t.Errorf("Receipts on m1 = %q, want remove", got[m1]["Receipts"])
+	}
+
+	testutil.MustNoErr(t, f.Store.ResolveLabelChanges([]int64{changes[0].ID}), "ResolveLabelChanges")
+	n, err := f.Store.DiscardLabelChanges(f.Source.ID)
+	testutil.MustNoErr(t, err, "DiscardLabelChanges")
+	if n != int64(len(changes)-1) {
+		t.Errorf("discarded %d, want %d", n, len(changes)-1)
+	}
+	if got := pendingActions(t, f); len(got) != 0 {
+		t.Errorf("pending after discard = %v, want none", got)
+	}
+}
+
+func TestLabelChanges_OnlyGmailSources(t *testing.T) {
+	f := storetest.New(t)
+	imap, err := f.Store.GetOrCreateSource("imap", "bob@example.com")
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+	conv, err := f.Store.EnsureConversation(imap.ID, "t1", "Thread")
+	testutil.MustNoErr(t, err, "EnsureConversation")
+	id, err := f.Store.UpsertMessage(&store.Message{
+		ConversationID: conv, SourceID: imap.ID, SourceMessageID: "i1", MessageType: "email",
+	})
+	testutil.MustNoErr(t, err, "UpsertMessage")
+
+	testutil.MustNoErr(t, f.Store.EditMessagesLabel("Travel", []int64{id}, true), "add Travel")
+	if got := pendingActions(t, f); len(got) != 0 {
+		t.Errorf("pending = %v, want none for an IMAP message", got)
+	}
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// recordLabelEvents records in the label history that labelID was
+// added to, or with action LabelChangeRemove removed from, those of
+// messageIDs whose labels it changes. historyID is the Gmail history
+// record making the change, or "" for an edit made in the archive. It
+// must run in the change's transaction, before the change.
+func (s *Store) recordLabelEvents(tx *loggedTx, labelID int64, messageIDs []int64, action, historyID string) error {
+	has := "NOT EXISTS"
+	if action == LabelChangeRemove {
+		has = "EXISTS"
+	}
+	var history any
+	if historyID != "" {
+		history = historyID
+	}
+	err := execInChunks(tx, messageIDs, []interface{}{labelID, action, history, labelID}, fmt.Sprintf(`
+		INSERT INTO label_events (message_id, label_id, action, history_id, occurred_at)
+		SELECT m.id, ?, ?, ?, %s
+		FROM messages m
+		WHERE %s (SELECT 1 FROM message_labels ml WHERE ml.message_id = m.id AND ml.label_id = ?)
+			AND m.id IN (%%s)
+	`, s.dialect.Now(), has))
+	if err != nil {
+		return fmt.Errorf("record label history: %w", err)
+	}
+	return nil
+}
+
+// ApplyLabelHistory adds labelIDs to a message, or with add false
+// removes them, as the Gmail history record historyID reports, and
+// records each change in the label history.
+func (s *Store) ApplyLabelHistory(messageID int64, labelIDs []int64, add bool, historyID string) error {
+	if len(labelIDs) == 0 {
+		return nil
+	}
+	action := LabelChangeAdd
+	if !add {
+		action = LabelChangeRemove
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		for _, labelID := range labelIDs {
+			if err := s.recordLabelEvents(tx, labelID, []int64{messageID}, action, historyID); err != nil {
+				return err
+			}
+		}
+		if !add {
+			return execInChunks(tx, labelIDs, []interface{}{messageID},
+				`DELETE FROM message_labels WHERE message_id = ? AND label_id IN (%s)`)
+		}
+		return insertInChunks(tx, chunkInsert{
+			totalRows:    len(labelIDs),
+			valuesPerRow: 2,
+			prefix:       s.dialect.InsertOrIgnorePrefix("INSERT OR IGNORE INTO message_labels (message_id, label_id) VALUES "),
+			suffix:       s.dialect.InsertOrIgnoreSuffix(),
+		}, func(start, end int) ([]string, []interface{}) {
+			values := make([]string, end-start)
+			args := make([]interface{}, 0, (end-start)*2)
+			for i := start; i < end; i++ {
+				values[i-start] = "(?, ?)"
+				args = append(args, messageID, labelIDs[i])
+			}
+			return values, args
+		})
+	})
+}
+
+// labelAtWhere is the condition on messages m that they had a label
+// called name at time at. A message's last label change up to then
+// decides; failing that, its first change after (a removal means it had
+// the label); failing both, its current labels. Messages count only
+// between arriving and being deleted from their account.
+func labelAtWhere(name string, at time.Time) (string, []any) {
+	t := at.UTC().Format("2006-01-02 15:04:05")
+	where := LiveMessagesWhere("m", false) + `
+		AND COALESCE(m.sent_at, m.received_at, m.internal_date) <= ?
+		AND (m.deleted_from_source_at IS NULL OR m.deleted_from_source_at > ?)
+		AND EXISTS (
+			SELECT 1 FROM labels l
+			WHERE l.name = ? AND (l.source_id IS NULL OR l.source_id = m.source_id)
+				AND COALESCE(
+					(SELECT e.action FROM label_events e
+					 WHERE e.message_id = m.id AND e.label_id = l.id AND e.occurred_at <= ?
+					 ORDER BY e.occurred_at DESC, e.id DESC LIMIT 1),
+					(SELECT CASE e.action WHEN 'add' THEN 'remove' ELSE 'add' END FROM label_events e
+					 WHERE e.message_id = m.id AND e.label_id = l.id AND e.occurred_at > ?
+					 ORDER BY e.occurred_at, e.id LIMIT 1),
+					(SELECT 'add' FROM message_labels ml WHERE ml.message_id = m.id AND ml.label_id = l.id)
+				) = 'add'
+		)`
+	return where, []any{t, t, name, t, t}
+}
+
+// LabelCountAt returns how many messages had the label called name at
+// time at, across every account with a label of that name.
+func (s *Store) LabelCountAt(name string, at time.Time) (int64, error) {
+	where, args := labelAtWhere(name, at)
+	var n int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages m WHERE `+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count messages labeled %q: %w", name, err)
+	}
+	return n, nil
+}
+
+// LabelMember is a message that had a label at some time.
+type LabelMember struct {
+	MessageID       int64     `json:"id"`
+	Account         string    `json:"account"`
+	SourceMessageID string    `json:"source_message_id"`
+	Subject         string    `json:"subject"`
+	SentAt          time.Time `json:"sent_at"`
+}
+
+// LabelMembersAt returns up to limit of the messages that had the label
+// called name at time at, newest first.
+func (s *Store) LabelMembersAt(name string, at time.Time, limit int) ([]LabelMember, error) {
+	where, args := labelAtWhere(name, at)
+	rows, err := s.db.Query(`
+		SELECT m.id, src.identifier, COALESCE(m.source_message_id, ''), COALESCE(m.subject, ''), m.sent_at
+		FROM messages m
+		JOIN sources src ON src.id = m.source_id
+		WHERE `+where+`
+		ORDER BY COALESCE(m.sent_at, m.received_at, m.internal_date) DESC, m.id DESC
+		LIMIT ?
+	`, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("list messages labeled %q: %w", name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []LabelMember
+	for rows.Next() {
+		var m LabelMember
+		var sentAt sql.NullString
+		if err := rows.Scan(&m.MessageID, &m.Account, &m.SourceMessageID, &m.Subject, &sentAt); err != nil {
+			return nil, fmt.Errorf("scan labeled message: %w", err)
+		}
+		m.SentAt = parseSQLiteTime(sentAt.String)
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
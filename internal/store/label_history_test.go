@@ -0,0 +1,100 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestLabelHistory_MembershipAt(t *testing.T) {
+	f := storetest.New(t)
+	inbox := f.EnsureLabels(map[string]string{"INBOX": "INBOX"}, "system")["INBOX"]
+	sent := time.Date(2022, 6, 1, 9, 0, 0, 0, time.UTC)
+	newMsg := func(subject string) int64 {
+		return f.NewMessage().WithSubject(subject).WithSentAt(sent).Create(t, f.Store)
+	}
+	backdate := func(id int64, at string) {
+		t.Helper()
+		_, err := f.Store.DB().Exec(`UPDATE label_events SET occurred_at = ? WHERE message_id = ?`, at, id)
+		testutil.MustNoErr(t, err, "backdate label events")
+	}
+
+	// always has had INBOX; added gained it and removed lost it in March.
+	always, added, removed := newMsg("always"), newMsg("added"), newMsg("removed")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(always, []int64{inbox}), "AddMessageLabels")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(removed, []int64{inbox}), "AddMessageLabels")
+	testutil.MustNoErr(t, f.Store.ApplyLabelHistory(added, []int64{inbox}, true, "100"), "add INBOX")
+	testutil.MustNoErr(t, f.Store.ApplyLabelHistory(removed, []int64{inbox}, false, "101"), "remove INBOX")
+	// A change to what the message already has is not history.
+	testutil.MustNoErr(t, f.Store.ApplyLabelHistory(always, []int64{inbox}, true, "102"), "re-add INBOX")
+	backdate(added, "2023-03-01 00:00:00")
+	backdate(removed, "2023-03-01 00:00:00")
+
+	tests := []struct {
+		at   time.Time
+		want []string
+	}{
+		{time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), []string{"always", "removed"}},
+		{time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), []string{"always", "added"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.at.Format("2006-01-02"), func(t *testing.T) {
+			n, err := f.Store.LabelCountAt("INBOX", tt.at)
+			testutil.MustNoErr(t, err, "LabelCountAt")
+			if n != int64(len(tt.want)) {
+				t.Errorf("LabelCountAt = %d, want %d", n, len(tt.want))
+			}
+			members, err := f.Store.LabelMembersAt("INBOX", tt.at, 10)
+			testutil.MustNoErr(t, err, "LabelMembersAt")
+			got := make(map[string]bool)
+			for _, m := range members {
+				got[m.Subject] = true
+				if !m.SentAt.Equal(sent) {
+					t.Errorf("%s: SentAt = %v, want %v", m.Subject, m.SentAt, sent)
+				}
+			}
+			for _, subject := range tt.want {
+				if !got[subject] {
+					t.Errorf("members = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	var events int
+	err := f.Store.DB().QueryRow(`SELECT COUNT(*) FROM label_events WHERE message_id = ?`, always).Scan(&events)
+	testutil.MustNoErr(t, err, "count label events")
+	if events != 0 {
+		t.Errorf("label events of always = %d, want 0", events)
+	}
+}
+
+func TestLabelHistory_RecordsArchiveEdits(t *testing.T) {
+	f := storetest.New(t)
+	inbox := f.EnsureLabels(map[string]string{"INBOX": "INBOX"}, "system")["INBOX"]
+	m1 := f.CreateMessage("m1")
+	testutil.MustNoErr(t, f.Store.LabelMessages(inbox, []int64{m1}), "LabelMessages")
+	testutil.MustNoErr(t, f.Store.UnlabelMessages(inbox, []int64{m1}), "UnlabelMessages")
+
+	rows, err := f.Store.DB().Query(
+		`SELECT action, history_id IS NULL FROM label_events WHERE message_id = ? ORDER BY id`, m1)
+	testutil.MustNoErr(t, err, "query label events")
+	defer func() { _ = rows.Close() }()
+	var actions []string
+	for rows.Next() {
+		var action string
+		var noHistory bool
+		testutil.MustNoErr(t, rows.Scan(&action, &noHistory), "scan label event")
+		if !noHistory {
+			t.Errorf("%s event has a history ID, want none for an archive edit", action)
+		}
+		actions = append(actions, action)
+	}
+	testutil.MustNoErr(t, rows.Err(), "iterate label events")
+	if len(actions) != 2 || actions[0] != "add" || actions[1] != "remove" {
+		t.Errorf("label events = %v, want [add remove]", actions)
+	}
+}
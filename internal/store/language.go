@@ -0,0 +1,59 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// messageLanguage detects the language of a message from its subject
+// and the author's own text, leaving out a quoted reply trail that may
+// be in another language. It returns a NULL string when the language
+// cannot be told.
+func messageLanguage(subject, bodyText sql.NullString) sql.NullString {
+	lang := mime.DetectLanguage(subject.String + "\n" + mime.SplitReply(bodyText.String).Text)
+	return sql.NullString{String: lang, Valid: lang != ""}
+}
+
+// setMessageLanguage records the detected language of a message.
+func setMessageLanguage(q querier, messageID int64, lang sql.NullString) error {
+	_, err := q.Exec(`UPDATE messages SET language = ? WHERE id = ?`, lang, messageID)
+	return err
+}
+
+// LanguageStat is how many messages are written in a language.
+type LanguageStat struct {
+	Language string `json:"language"` // ISO 639-1 code; "" if undetected
+	Name     string `json:"name"`
+	Messages int64  `json:"messages"`
+	Senders  int64  `json:"senders"` // distinct senders of those messages
+}
+
+// LanguageStats returns the number of messages in each detected
+// language, most common first. Messages whose language could not be
+// detected are counted under "". Deleted messages are not counted.
+func (s *Store) LanguageStats() ([]LanguageStat, error) {
+	rows, err := s.db.Query(`
+		SELECT COALESCE(m.language, ''), COUNT(*), COUNT(DISTINCT m.sender_id)
+		FROM messages m
+		WHERE ` + LiveMessagesWhere("m", false) + `
+		GROUP BY COALESCE(m.language, '')
+		ORDER BY COUNT(*) DESC, COALESCE(m.language, '')
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("language stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []LanguageStat
+	for rows.Next() {
+		var st LanguageStat
+		if err := rows.Scan(&st.Language, &st.Messages, &st.Senders); err != nil {
+			return nil, fmt.Errorf("scan language stat: %w", err)
+		}
+		st.Name = mime.LanguageName(st.Language)
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
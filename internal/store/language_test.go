@@ -0,0 +1,136 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+const (
+	germanBody  = "Hallo Alice, ich habe die Unterlagen für dich. Wir sehen uns auch nach dem Termin, wenn das für dich passt."
+	englishBody = "Hi Bob, the notes are attached. I will send the report to you and the team by Friday, if that works for all of us."
+)
+
+func persistBody(t *testing.T, f *storetest.Fixture, id, body string, senderID int64) int64 {
+	t.Helper()
+	msgID, err := f.Store.PersistMessage(&store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  f.ConvID,
+			SourceID:        f.Source.ID,
+			SourceMessageID: id,
+			MessageType:     "email",
+			SenderID:        sql.NullInt64{Int64: senderID, Valid: senderID != 0},
+		},
+		BodyText: sql.NullString{String: body, Valid: body != ""},
+	})
+	testutil.MustNoErr(t, err, "PersistMessage")
+	return msgID
+}
+
+func messageLanguage(t *testing.T, f *storetest.Fixture, id int64) sql.NullString {
+	t.Helper()
+	var lang sql.NullString
+	err := f.Store.DB().QueryRow(`SELECT language FROM messages WHERE id = ?`, id).Scan(&lang)
+	testutil.MustNoErr(t, err, "read language")
+	return lang
+}
+
+func TestStore_PersistMessageLanguage(t *testing.T) {
+	f := storetest.New(t)
+
+	tests := []struct {
+		name string
+		body string
+		want sql.NullString
+	}{
+		{"german", germanBody, sql.NullString{String: "de", Valid: true}},
+		{"quoted trail ignored", germanBody + "\n\nOn Tue, Bob <bob@example.com> wrote:\n> " + englishBody + "\n> " + englishBody + "\n", sql.NullString{String: "de", Valid: true}},
+		{"undetected", "Ok", sql.NullString{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := persistBody(t, f, tt.name, tt.body, 0)
+			if got := messageLanguage(t, f, id); got != tt.want {
+				t.Errorf("language = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStore_LanguageStats(t *testing.T) {
+	f := storetest.New(t)
+	alice, err := f.Store.EnsureParticipant("alice@example.com", "Alice", "example.com")
+	testutil.MustNoErr(t, err, "EnsureParticipant")
+	bob, err := f.Store.EnsureParticipant("bob@example.org", "Bob", "example.org")
+	testutil.MustNoErr(t, err, "EnsureParticipant")
+
+	persistBody(t, f, "msg-1", englishBody, alice)
+	persistBody(t, f, "msg-2", englishBody, bob)
+	persistBody(t, f, "msg-3", germanBody, bob)
+	persistBody(t, f, "msg-4", "Ok", bob)
+	deleted := persistBody(t, f, "msg-5", germanBody, alice)
+	_, err = f.Store.DB().Exec(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, deleted)
+	testutil.MustNoErr(t, err, "mark deleted")
+
+	got, err := f.Store.LanguageStats()
+	testutil.MustNoErr(t, err, "LanguageStats")
+	want := []store.LanguageStat{
+		{Language: "en", Name: "English", Messages: 2, Senders: 2},
+		{Language: "", Name: "", Messages: 1, Senders: 1},
+		{Language: "de", Name: "German", Messages: 1, Senders: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("LanguageStats = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("LanguageStats[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStore_ApplyReparseLanguage(t *testing.T) {
+	f := storetest.New(t)
+	id := f.CreateMessage("msg-1")
+	r := &store.ReparsedMessage{BodyText: sql.NullString{String: englishBody, Valid: true}}
+
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if !changed {
+		t.Error("ApplyReparse reported no change")
+	}
+	if got := messageLanguage(t, f, id); got.String != "en" {
+		t.Errorf("language after reparse = %+v, want en", got)
+	}
+
+	// A message stored before languages were detected gets one on
+	// reparse even though its body is unchanged.
+	_, err = f.Store.DB().Exec(`UPDATE messages SET language = NULL WHERE id = ?`, id)
+	testutil.MustNoErr(t, err, "clear language")
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse backfill")
+	if !changed || messageLanguage(t, f, id).String != "en" {
+		t.Error("ApplyReparse did not backfill the language")
+	}
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse again")
+	if changed {
+		t.Error("identical ApplyReparse reported a change")
+	}
+}
+
+func TestSearchMessagesQuery_Language(t *testing.T) {
+	f := storetest.New(t)
+	de := persistBody(t, f, "msg-1", germanBody, 0)
+	persistBody(t, f, "msg-2", englishBody, 0)
+
+	msgs, total, err := f.Store.SearchMessagesQuery(search.Parse("lang:de"), 0, 10)
+	testutil.MustNoErr(t, err, "SearchMessagesQuery")
+	if total != 1 || len(msgs) != 1 || msgs[0].ID != de {
+		t.Errorf("lang:de = %d results %+v, want only message %d", total, msgs, de)
+	}
+}
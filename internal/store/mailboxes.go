@@ -0,0 +1,159 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MailboxMessage is a message as listed in a label-backed mailbox, with
+// just the fields an IMAP client needs before it fetches content.
+type MailboxMessage struct {
+	ID             int64
+	SourceID       int64
+	ConversationID int64
+	Size           int64
+	InternalDate   time.Time
+	Seen           bool
+	Flagged        bool
+}
+
+// sourceScopeClause returns an "AND m.source_id IN (...)" fragment and
+// its args. Empty sourceIDs means no restriction.
+func sourceScopeClause(sourceIDs []int64) (string, []any) {
+	if len(sourceIDs) == 0 {
+		return "", nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(sourceIDs)), ",")
+	args := make([]any, len(sourceIDs))
+	for i, id := range sourceIDs {
+		args[i] = id
+	}
+	return " AND m.source_id IN (" + placeholders + ")", args
+}
+
+// MailboxNames returns the distinct names of labels applied to at least
+// one email message in the given sources (all sources when empty).
+// Messages deleted from the source server are included: the archive,
+// not the provider, is the record.
+func (s *Store) MailboxNames(sourceIDs []int64) ([]string, error) {
+	scope, args := sourceScopeClause(sourceIDs)
+	rows, err := s.db.Query(`
+		SELECT l.name
+		FROM labels l
+		WHERE EXISTS (
+			SELECT 1 FROM message_labels ml
+			JOIN messages m ON m.id = ml.message_id
+			WHERE ml.label_id = l.id
+			AND m.message_type = 'email'
+			AND `+LiveMessagesWhere("m", false)+scope+`
+		)
+		GROUP BY l.name
+		ORDER BY l.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list mailbox names: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scan mailbox name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// MailboxMessages lists the email messages carrying the named label, or
+// every email message when label is empty, ordered by ascending ID.
+// Labels are matched by name across sources so a multi-account archive
+// presents one INBOX. Source-deleted messages are included.
+func (s *Store) MailboxMessages(label string, sourceIDs []int64) ([]MailboxMessage, error) {
+	scope, args := sourceScopeClause(sourceIDs)
+	labelClause := ""
+	if label != "" {
+		labelClause = ` AND EXISTS (
+			SELECT 1 FROM message_labels ml
+			JOIN labels l ON l.id = ml.label_id
+			WHERE ml.message_id = m.id AND l.name = ?
+		)`
+		args = append(args, label)
+	}
+	return s.queryMailboxMessages(scope+labelClause, args)
+}
+
+// MailboxMessagesByID returns the listed email messages that exist,
+// ordered by ascending ID. Callers check SourceID for access.
+func (s *Store) MailboxMessagesByID(ids []int64) ([]MailboxMessage, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return s.queryMailboxMessages(" AND m.id IN ("+placeholders+")", args)
+}
+
+// ConversationMailboxMessages lists the email messages in the given
+// conversations, ordered by ascending ID.
+func (s *Store) ConversationMailboxMessages(conversationIDs, sourceIDs []int64) ([]MailboxMessage, error) {
+	if len(conversationIDs) == 0 {
+		return nil, nil
+	}
+	scope, args := sourceScopeClause(sourceIDs)
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(conversationIDs)), ",")
+	for _, id := range conversationIDs {
+		args = append(args, id)
+	}
+	return s.queryMailboxMessages(scope+" AND m.conversation_id IN ("+placeholders+")", args)
+}
+
+func (s *Store) queryMailboxMessages(where string, args []any) ([]MailboxMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT
+			m.id,
+			m.source_id,
+			COALESCE(m.conversation_id, 0),
+			m.size_estimate,
+			COALESCE(m.internal_date, m.received_at, m.sent_at),
+			COALESCE(m.is_read, 1),
+			EXISTS (
+				SELECT 1 FROM message_labels ml
+				JOIN labels l ON l.id = ml.label_id
+				WHERE ml.message_id = m.id
+				AND (l.source_label_id = 'STARRED' OR l.name = 'STARRED')
+			)
+		FROM messages m
+		WHERE m.message_type = 'email'
+		AND `+LiveMessagesWhere("m", false)+where+`
+		ORDER BY m.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list mailbox messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var msgs []MailboxMessage
+	for rows.Next() {
+		var (
+			m       MailboxMessage
+			size    sql.NullInt64
+			dateStr sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &m.SourceID, &m.ConversationID, &size, &dateStr, &m.Seen, &m.Flagged); err != nil {
+			return nil, fmt.Errorf("scan mailbox message: %w", err)
+		}
+		m.Size = size.Int64
+		if dateStr.Valid && dateStr.String != "" {
+			m.InternalDate = parseSQLiteTime(dateStr.String)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
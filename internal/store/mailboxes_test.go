@@ -0,0 +1,116 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_MailboxNamesAndMessages(t *testing.T) {
+	f := storetest.New(t)
+	labels := f.EnsureLabels(map[string]string{
+		"INBOX":   "INBOX",
+		"STARRED": "STARRED",
+		"Label_1": "Receipts/2024",
+		"Label_2": "Unused",
+	}, "user")
+
+	inbox := f.CreateMessage("inbox-msg")
+	receipt := f.CreateMessage("receipt-msg")
+	deleted := f.CreateMessage("deleted-msg")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(inbox, []int64{labels["INBOX"], labels["STARRED"]}), "label inbox")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(receipt, []int64{labels["Label_1"]}), "label receipt")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(deleted, []int64{labels["INBOX"]}), "label deleted")
+	// Deleted from Gmail, but still part of the archive.
+	testutil.MustNoErr(t, f.Store.MarkMessageDeleted(f.Source.ID, "deleted-msg"), "MarkMessageDeleted")
+
+	// A second account whose messages must not leak into scoped listings.
+	other, err := f.Store.GetOrCreateSource("gmail", "bob@example.com")
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+	otherConv, err := f.Store.EnsureConversation(other.ID, "bob-thread", "Thread")
+	testutil.MustNoErr(t, err, "EnsureConversation")
+	otherInbox, err := f.Store.EnsureLabel(other.ID, "INBOX", "INBOX", "system")
+	testutil.MustNoErr(t, err, "EnsureLabel")
+	otherMsg, err := f.Store.UpsertMessage(&store.Message{
+		ConversationID: otherConv, SourceID: other.ID, SourceMessageID: "bob-msg",
+		MessageType: "email", SizeEstimate: 10,
+	})
+	testutil.MustNoErr(t, err, "UpsertMessage")
+	testutil.MustNoErr(t, f.Store.AddMessageLabels(otherMsg, []int64{otherInbox}), "label other")
+
+	names, err := f.Store.MailboxNames([]int64{f.Source.ID})
+	testutil.MustNoErr(t, err, "MailboxNames")
+	if want := []string{"INBOX", "Receipts/2024", "STARRED"}; !slices.Equal(names, want) {
+		t.Errorf("MailboxNames = %v, want %v", names, want)
+	}
+
+	tests := []struct {
+		name      string
+		label     string
+		sourceIDs []int64
+		want      []int64
+	}{
+		{"inbox includes source-deleted", "INBOX", []int64{f.Source.ID}, []int64{inbox, deleted}},
+		{"inbox across accounts", "INBOX", nil, []int64{inbox, deleted, otherMsg}},
+		{"nested label", "Receipts/2024", nil, []int64{receipt}},
+		{"all mail scoped", "", []int64{other.ID}, []int64{otherMsg}},
+		{"unknown label", "Nope", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs, err := f.Store.MailboxMessages(tt.label, tt.sourceIDs)
+			testutil.MustNoErr(t, err, "MailboxMessages")
+			var got []int64
+			for _, m := range msgs {
+				got = append(got, m.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	msgs, err := f.Store.MailboxMessages("", []int64{other.ID})
+	testutil.MustNoErr(t, err, "MailboxMessages")
+	if msgs[0].ConversationID != otherConv {
+		t.Errorf("conversation = %d, want %d", msgs[0].ConversationID, otherConv)
+	}
+
+	msgs, err = f.Store.MailboxMessages("INBOX", []int64{f.Source.ID})
+	testutil.MustNoErr(t, err, "MailboxMessages")
+	if !msgs[0].Flagged || msgs[1].Flagged {
+		t.Errorf("flagged = %v/%v, want only the starred message", msgs[0].Flagged, msgs[1].Flagged)
+	}
+	if !msgs[0].Seen || msgs[0].Size != 1000 {
+		t.Errorf("message = %+v, want seen with size 1000", msgs[0])
+	}
+}
+
+func TestStore_MailboxMessagesByIDAndConversation(t *testing.T) {
+	f := storetest.New(t)
+	first := f.CreateMessage("first")
+	second := f.CreateMessage("second")
+
+	msgs, err := f.Store.MailboxMessagesByID([]int64{second, first, 9999})
+	testutil.MustNoErr(t, err, "MailboxMessagesByID")
+	if len(msgs) != 2 || msgs[0].ID != first || msgs[1].ID != second {
+		t.Fatalf("MailboxMessagesByID = %+v, want [%d %d]", msgs, first, second)
+	}
+	if msgs[0].SourceID != f.Source.ID {
+		t.Errorf("source = %d, want %d", msgs[0].SourceID, f.Source.ID)
+	}
+
+	thread, err := f.Store.ConversationMailboxMessages([]int64{msgs[0].ConversationID}, []int64{f.Source.ID})
+	testutil.MustNoErr(t, err, "ConversationMailboxMessages")
+	if len(thread) != 2 {
+		t.Errorf("conversation messages = %d, want 2", len(thread))
+	}
+	thread, err = f.Store.ConversationMailboxMessages([]int64{msgs[0].ConversationID}, []int64{f.Source.ID + 100})
+	testutil.MustNoErr(t, err, "ConversationMailboxMessages")
+	if len(thread) != 0 {
+		t.Errorf("out-of-scope conversation messages = %d, want 0", len(thread))
+	}
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/wesm/msgvault/internal/mailauth"
+)
+
+// SetDKIMVerifier makes PersistMessage verify the DKIM signatures of
+// each message it stores with v. With nil, the default, only the
+// Authentication-Results the receiving server wrote are recorded.
+func (s *Store) SetDKIMVerifier(v *mailauth.Verifier) {
+	s.dkim = v
+}
+
+func upsertMessageAuth(q querier, d Dialect, messageID int64, r mailauth.Result) error {
+	_, err := q.Exec(fmt.Sprintf(`
+		INSERT INTO message_auth (message_id, dkim_result, dkim_domain, dkim_reason,
+			authserv_id, reported_dkim, reported_spf, reported_dmarc, auth_results, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, %s)
+		ON CONFLICT(message_id) DO UPDATE SET
+			dkim_result = excluded.dkim_result,
+			dkim_domain = excluded.dkim_domain,
+			dkim_reason = excluded.dkim_reason,
+			authserv_id = excluded.authserv_id,
+			reported_dkim = excluded.reported_dkim,
+			reported_spf = excluded.reported_spf,
+			reported_dmarc = excluded.reported_dmarc,
+			auth_results = excluded.auth_results,
+			checked_at = excluded.checked_at
+	`, d.Now()), messageID, nullIfEmpty(r.DKIM), nullIfEmpty(r.DKIMDomain), nullIfEmpty(r.DKIMReason),
+		nullIfEmpty(r.AuthServID), nullIfEmpty(r.ReportedDKIM), nullIfEmpty(r.ReportedSPF),
+		nullIfEmpty(r.ReportedDMARC), nullIfEmpty(r.AuthResults))
+	return err
+}
+
+// GetMessageAuth returns the authentication results recorded for a
+// message, or nil if there are none.
+func (s *Store) GetMessageAuth(messageID int64) (*mailauth.Result, error) {
+	var dkim, domain, reason, servID, repDKIM, repSPF, repDMARC, raw sql.NullString
+	err := s.db.QueryRow(`
+		SELECT dkim_result, dkim_domain, dkim_reason, authserv_id,
+			reported_dkim, reported_spf, reported_dmarc, auth_results
+		FROM message_auth WHERE message_id = ?
+	`, messageID).Scan(&dkim, &domain, &reason, &servID, &repDKIM, &repSPF, &repDMARC, &raw)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get message auth: %w", err)
+	}
+	return &mailauth.Result{
+		DKIM:          dkim.String,
+		DKIMDomain:    domain.String,
+		DKIMReason:    reason.String,
+		AuthServID:    servID.String,
+		ReportedDKIM:  repDKIM.String,
+		ReportedSPF:   repSPF.String,
+		ReportedDMARC: repDMARC.String,
+		AuthResults:   raw.String,
+	}, nil
+}
+
+// nullIfEmpty stores "" as NULL.
+func nullIfEmpty(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
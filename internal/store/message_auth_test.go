@@ -0,0 +1,76 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mailauth"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// noKeys resolves no DKIM keys; the messages here are unsigned.
+type noKeys struct{}
+
+func (noKeys) LookupTXT(context.Context, string) ([]string, error) { return nil, nil }
+
+func persistRaw(t *testing.T, f *storetest.Fixture, id, raw string) int64 {
+	t.Helper()
+	msgID, err := f.Store.PersistMessage(&store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  f.ConvID,
+			SourceID:        f.Source.ID,
+			SourceMessageID: id,
+			MessageType:     "email",
+		},
+		RawMIME: []byte(raw),
+	})
+	testutil.MustNoErr(t, err, "PersistMessage")
+	return msgID
+}
+
+func TestStore_PersistMessageAuth(t *testing.T) {
+	f := storetest.New(t)
+	const withAR = "Authentication-Results: mx.example.net; dkim=pass header.d=example.com;\r\n" +
+		" spf=pass smtp.mailfrom=example.com; dmarc=pass header.from=example.com\r\n" +
+		"From: alice@example.com\r\n\r\nHi\r\n"
+
+	id := persistRaw(t, f, "msg-1", withAR)
+	got, err := f.Store.GetMessageAuth(id)
+	testutil.MustNoErr(t, err, "GetMessageAuth")
+	if got == nil || got.AuthServID != "mx.example.net" || got.ReportedDKIM != mailauth.Pass ||
+		got.ReportedSPF != mailauth.Pass || got.ReportedDMARC != mailauth.Pass || got.DKIM != "" {
+		t.Errorf("GetMessageAuth = %+v", got)
+	}
+
+	// Nothing to record: no row.
+	plain := persistRaw(t, f, "msg-2", "From: bob@example.com\r\n\r\nHi\r\n")
+	if got, err := f.Store.GetMessageAuth(plain); err != nil || got != nil {
+		t.Errorf("GetMessageAuth(unauthenticated) = %+v, %v", got, err)
+	}
+
+	// With a verifier, even an unsigned message records DKIM none.
+	f.Store.SetDKIMVerifier(mailauth.NewVerifier(noKeys{}))
+	unsigned := persistRaw(t, f, "msg-3", "From: bob@example.com\r\n\r\nHi\r\n")
+	if got, err := f.Store.GetMessageAuth(unsigned); err != nil || got == nil || got.DKIM != mailauth.None {
+		t.Errorf("GetMessageAuth(verified unsigned) = %+v, %v", got, err)
+	}
+}
+
+func TestSearchMessagesQuery_DKIMPass(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+	for i, result := range []string{"pass", "fail"} {
+		_, err := f.Store.DB().Exec(`INSERT INTO message_auth (message_id, dkim_result, checked_at)
+			VALUES (?, ?, CURRENT_TIMESTAMP)`, ids[i], result)
+		testutil.MustNoErr(t, err, "insert message_auth")
+	}
+
+	msgs, total, err := f.Store.SearchMessagesQuery(search.Parse("is:dkim-pass"), 0, 10)
+	testutil.MustNoErr(t, err, "SearchMessagesQuery")
+	if total != 1 || len(msgs) != 1 || msgs[0].ID != ids[0] {
+		t.Errorf("is:dkim-pass = %d results %+v, want only message %d", total, msgs, ids[0])
+	}
+}
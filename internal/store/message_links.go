@@ -0,0 +1,112 @@
+package store
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// replaceMessageLinks replaces the links recorded for a message.
+func replaceMessageLinks(q querier, d Dialect, messageID int64, links []mime.Link) error {
+	if _, err := q.Exec(`DELETE FROM message_links WHERE message_id = ?`, messageID); err != nil {
+		return err
+	}
+	insert := d.InsertOrIgnore(`INSERT OR IGNORE INTO message_links (message_id, kind, url, domain) VALUES (?, ?, ?, ?)`)
+	for _, l := range links {
+		if _, err := q.Exec(insert, messageID, l.Kind, l.URL, l.Domain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMessageLinks returns the links and tracking pixels recorded for a
+// message, trackers first.
+func (s *Store) GetMessageLinks(messageID int64) ([]mime.Link, error) {
+	links, err := messageLinks(s.db, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("get message links: %w", err)
+	}
+	return links, nil
+}
+
+// linkQuerier is satisfied by both the database and a transaction.
+type linkQuerier interface {
+	Query(query string, args ...any) (*loggedRows, error)
+}
+
+func messageLinks(q linkQuerier, messageID int64) ([]mime.Link, error) {
+	rows, err := q.Query(`
+		SELECT kind, url, domain FROM message_links
+		WHERE message_id = ? ORDER BY kind DESC, url
+	`, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var links []mime.Link
+	for rows.Next() {
+		var l mime.Link
+		if err := rows.Scan(&l.Kind, &l.URL, &l.Domain); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// sameLinks reports whether two link sets hold the same links, in any
+// order.
+func sameLinks(a, b []mime.Link) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	key := func(l mime.Link) string { return l.Kind + " " + l.URL }
+	ak := make([]string, len(a))
+	bk := make([]string, len(b))
+	for i := range a {
+		ak[i], bk[i] = key(a[i]), key(b[i])
+	}
+	slices.Sort(ak)
+	slices.Sort(bk)
+	return slices.Equal(ak, bk)
+}
+
+// LinkDomainStat is how often messages link to, or are tracked by, a
+// domain.
+type LinkDomainStat struct {
+	Domain   string `json:"domain"`
+	Messages int64  `json:"messages"` // messages with at least one such link
+	Senders  int64  `json:"senders"`  // distinct senders of those messages
+}
+
+// TopLinkDomains returns the domains that the most messages link to,
+// for kind mime.LinkAnchor, or carry tracking pixels from, for
+// mime.LinkTracker. Deleted messages are not counted.
+func (s *Store) TopLinkDomains(kind string, limit int) ([]LinkDomainStat, error) {
+	rows, err := s.db.Query(`
+		SELECT ml.domain, COUNT(DISTINCT ml.message_id), COUNT(DISTINCT m.sender_id)
+		FROM message_links ml
+		JOIN messages m ON m.id = ml.message_id
+		WHERE ml.kind = ? AND `+LiveMessagesWhere("m", false)+`
+		GROUP BY ml.domain
+		ORDER BY COUNT(DISTINCT ml.message_id) DESC, ml.domain
+		LIMIT ?
+	`, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("top link domains: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []LinkDomainStat
+	for rows.Next() {
+		var st LinkDomainStat
+		if err := rows.Scan(&st.Domain, &st.Messages, &st.Senders); err != nil {
+			return nil, fmt.Errorf("scan link domain: %w", err)
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,112 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+const trackedHTML = `<p>Hi <a href="https://shop.example.com/sale">sale</a></p>` +
+	`<img src="https://t.example.net/open.gif" width="1" height="1">`
+
+func persistHTML(t *testing.T, f *storetest.Fixture, id, htmlBody string, senderID int64) int64 {
+	t.Helper()
+	msgID, err := f.Store.PersistMessage(&store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  f.ConvID,
+			SourceID:        f.Source.ID,
+			SourceMessageID: id,
+			MessageType:     "email",
+			SenderID:        sql.NullInt64{Int64: senderID, Valid: senderID != 0},
+		},
+		BodyHTML: sql.NullString{String: htmlBody, Valid: htmlBody != ""},
+	})
+	testutil.MustNoErr(t, err, "PersistMessage")
+	return msgID
+}
+
+func TestStore_PersistMessageLinks(t *testing.T) {
+	f := storetest.New(t)
+	id := persistHTML(t, f, "msg-1", trackedHTML, 0)
+
+	got, err := f.Store.GetMessageLinks(id)
+	testutil.MustNoErr(t, err, "GetMessageLinks")
+	want := []mime.Link{
+		{URL: "https://t.example.net/open.gif", Domain: "t.example.net", Kind: mime.LinkTracker},
+		{URL: "https://shop.example.com/sale", Domain: "shop.example.com", Kind: mime.LinkAnchor},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("GetMessageLinks = %+v, want %+v", got, want)
+	}
+
+	// Storing the message again without HTML drops its links.
+	persistHTML(t, f, "msg-1", "", 0)
+	if got, err := f.Store.GetMessageLinks(id); err != nil || len(got) != 0 {
+		t.Errorf("GetMessageLinks after re-persist = %+v, %v", got, err)
+	}
+}
+
+func TestStore_TopLinkDomains(t *testing.T) {
+	f := storetest.New(t)
+	alice, err := f.Store.EnsureParticipant("alice@example.com", "Alice", "example.com")
+	testutil.MustNoErr(t, err, "EnsureParticipant")
+	bob, err := f.Store.EnsureParticipant("bob@example.org", "Bob", "example.org")
+	testutil.MustNoErr(t, err, "EnsureParticipant")
+
+	persistHTML(t, f, "msg-1", trackedHTML, alice)
+	persistHTML(t, f, "msg-2", trackedHTML, bob)
+	persistHTML(t, f, "msg-3", `<img src="https://mailtrack.io/trace/x.png">`, bob)
+	deleted := persistHTML(t, f, "msg-4", `<img src="https://mailtrack.io/trace/y.png">`, bob)
+	_, err = f.Store.DB().Exec(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, deleted)
+	testutil.MustNoErr(t, err, "mark deleted")
+
+	got, err := f.Store.TopLinkDomains(mime.LinkTracker, 10)
+	testutil.MustNoErr(t, err, "TopLinkDomains")
+	want := []store.LinkDomainStat{
+		{Domain: "t.example.net", Messages: 2, Senders: 2},
+		{Domain: "mailtrack.io", Messages: 1, Senders: 1},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("TopLinkDomains(tracker) = %+v, want %+v", got, want)
+	}
+
+	got, err = f.Store.TopLinkDomains(mime.LinkAnchor, 1)
+	testutil.MustNoErr(t, err, "TopLinkDomains")
+	if len(got) != 1 || got[0].Domain != "shop.example.com" {
+		t.Errorf("TopLinkDomains(link) = %+v", got)
+	}
+}
+
+func TestStore_ApplyReparseLinks(t *testing.T) {
+	f := storetest.New(t)
+	id := f.CreateMessage("msg-1")
+	r := &store.ReparsedMessage{BodyHTML: sql.NullString{String: trackedHTML, Valid: true}}
+
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if !changed {
+		t.Error("ApplyReparse adding links reported no change")
+	}
+	if links, _ := f.Store.GetMessageLinks(id); len(links) != 2 {
+		t.Errorf("links after reparse = %+v", links)
+	}
+
+	// A message stored before links were recorded gets them on
+	// reparse even though its body is unchanged.
+	_, err = f.Store.DB().Exec(`DELETE FROM message_links WHERE message_id = ?`, id)
+	testutil.MustNoErr(t, err, "clear links")
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse backfill")
+	if !changed {
+		t.Error("ApplyReparse backfilling links reported no change")
+	}
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse again")
+	if changed {
+		t.Error("identical ApplyReparse reported a change")
+	}
+}
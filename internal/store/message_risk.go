@@ -0,0 +1,123 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/wesm/msgvault/internal/risk"
+)
+
+// SetMessageRisk records the risk score of a message, replacing any
+// earlier one.
+func (s *Store) SetMessageRisk(messageID int64, r risk.Result) error {
+	reasons, err := json.Marshal(r.Reasons)
+	if err != nil {
+		return fmt.Errorf("encode risk reasons: %w", err)
+	}
+	_, err = s.db.Exec(fmt.Sprintf(`
+		INSERT INTO message_risk (message_id, score, reasons, scored_at)
+		VALUES (?, ?, ?, %s)
+		ON CONFLICT(message_id) DO UPDATE SET
+			score = excluded.score,
+			reasons = excluded.reasons,
+			scored_at = excluded.scored_at
+	`, s.dialect.Now()), messageID, r.Score, string(reasons))
+	if err != nil {
+		return fmt.Errorf("set message risk: %w", err)
+	}
+	return nil
+}
+
+// GetMessageRisk returns the risk score recorded for a message, or nil
+// if it has not been scored.
+func (s *Store) GetMessageRisk(messageID int64) (*risk.Result, error) {
+	var score int
+	var reasons sql.NullString
+	err := s.db.QueryRow(`
+		SELECT score, reasons FROM message_risk WHERE message_id = ?
+	`, messageID).Scan(&score, &reasons)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get message risk: %w", err)
+	}
+	r, err := decodeRisk(score, reasons)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// RiskScoredIDs returns the IDs of the messages that have a risk
+// score.
+func (s *Store) RiskScoredIDs() (map[int64]bool, error) {
+	rows, err := s.db.Query(`SELECT message_id FROM message_risk`)
+	if err != nil {
+		return nil, fmt.Errorf("list scored messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	ids := make(map[int64]bool)
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan scored message: %w", err)
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// MessageRisk is the risk score of one message.
+type MessageRisk struct {
+	MessageID int64 `json:"message_id"`
+	risk.Result
+	ScoredAt time.Time `json:"scored_at"`
+}
+
+// RiskiestMessages returns up to limit messages scoring at least
+// minScore, highest score first and newest first among equals.
+// Deleted messages are left out.
+func (s *Store) RiskiestMessages(minScore, limit int) ([]MessageRisk, error) {
+	rows, err := s.db.Query(`
+		SELECT mk.message_id, mk.score, mk.reasons, mk.scored_at
+		FROM message_risk mk
+		JOIN messages m ON m.id = mk.message_id
+		WHERE mk.score >= ? AND `+LiveMessagesWhere("m", true)+`
+		ORDER BY mk.score DESC, COALESCE(m.sent_at, m.received_at, m.internal_date) DESC, m.id DESC
+		LIMIT ?
+	`, minScore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list risky messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []MessageRisk
+	for rows.Next() {
+		var mr MessageRisk
+		var score int
+		var reasons, scoredAt sql.NullString
+		if err := rows.Scan(&mr.MessageID, &score, &reasons, &scoredAt); err != nil {
+			return nil, fmt.Errorf("scan risky message: %w", err)
+		}
+		if mr.Result, err = decodeRisk(score, reasons); err != nil {
+			return nil, err
+		}
+		mr.ScoredAt = parseSQLiteTime(scoredAt.String)
+		out = append(out, mr)
+	}
+	return out, rows.Err()
+}
+
+func decodeRisk(score int, reasons sql.NullString) (risk.Result, error) {
+	r := risk.Result{Score: score}
+	if reasons.Valid && reasons.String != "" {
+		if err := json.Unmarshal([]byte(reasons.String), &r.Reasons); err != nil {
+			return risk.Result{}, fmt.Errorf("decode risk reasons: %w", err)
+		}
+	}
+	return r, nil
+}
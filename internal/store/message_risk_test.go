@@ -0,0 +1,63 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/risk"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_MessageRisk(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(4)
+
+	if got, err := f.Store.GetMessageRisk(ids[0]); err != nil || got != nil {
+		t.Fatalf("GetMessageRisk before scoring = %+v, %v; want nil", got, err)
+	}
+
+	phish := risk.Result{Score: 7, Reasons: []risk.Reason{
+		{Rule: risk.RuleDMARCFail, Points: 3, Detail: "DMARC failed at mx.example.net"},
+		{Rule: risk.RuleLinkTextMismatch, Points: 4, Detail: "link text shows bank.example but leads to phish.example"},
+	}}
+	testutil.MustNoErr(t, f.Store.SetMessageRisk(ids[0], risk.Result{Score: 1}), "SetMessageRisk")
+	testutil.MustNoErr(t, f.Store.SetMessageRisk(ids[0], phish), "SetMessageRisk again")
+	testutil.MustNoErr(t, f.Store.SetMessageRisk(ids[1], risk.Result{Score: 5}), "SetMessageRisk")
+	testutil.MustNoErr(t, f.Store.SetMessageRisk(ids[2], risk.Result{}), "SetMessageRisk")
+	testutil.MustNoErr(t, f.Store.SetMessageRisk(ids[3], risk.Result{Score: 9}), "SetMessageRisk")
+	_, err := f.Store.DB().Exec(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, ids[3])
+	testutil.MustNoErr(t, err, "mark deleted")
+
+	got, err := f.Store.GetMessageRisk(ids[0])
+	testutil.MustNoErr(t, err, "GetMessageRisk")
+	if got == nil || got.Score != 7 || len(got.Reasons) != 2 || got.Reasons[1] != phish.Reasons[1] {
+		t.Errorf("GetMessageRisk = %+v, want the rescored %+v", got, phish)
+	}
+
+	scored, err := f.Store.RiskScoredIDs()
+	testutil.MustNoErr(t, err, "RiskScoredIDs")
+	if len(scored) != 4 || !scored[ids[2]] {
+		t.Errorf("RiskScoredIDs = %v, want all four messages", scored)
+	}
+
+	risky, err := f.Store.RiskiestMessages(risk.SuspiciousScore, 10)
+	testutil.MustNoErr(t, err, "RiskiestMessages")
+	var gotIDs []int64
+	for _, r := range risky {
+		gotIDs = append(gotIDs, r.MessageID)
+	}
+	if want := []int64{ids[0], ids[1]}; !slices.Equal(gotIDs, want) {
+		t.Errorf("RiskiestMessages = %v, want %v (highest first, deleted left out)", gotIDs, want)
+	}
+	if len(risky) > 0 && risky[0].ScoredAt.IsZero() {
+		t.Error("RiskiestMessages should report when each message was scored")
+	}
+
+	msgs, total, err := f.Store.SearchMessagesQuery(search.Parse("is:suspicious"), 0, 10)
+	testutil.MustNoErr(t, err, "SearchMessagesQuery")
+	if total != 2 || len(msgs) != 2 {
+		t.Errorf("is:suspicious = %d results %+v, want messages %d and %d", total, msgs, ids[0], ids[1])
+	}
+}
@@ -1,15 +1,14 @@
 package store
 
 import (
-	"bytes"
-	"compress/zlib"
+	"context"
 	"database/sql"
 	"fmt"
-	"io"
 	"math/rand"
 	"strings"
 	"time"
 
+	"github.com/wesm/msgvault/internal/mailauth"
 	"github.com/wesm/msgvault/internal/mime"
 )
 
@@ -37,6 +36,15 @@ type MessagePersistData struct {
 	RawMIME    []byte
 	Recipients []RecipientSet
 	LabelIDs   []int64
+
+	// InlineParts are the images embedded in BodyHTML by Content-ID.
+	// Parts that are not mime.Attachment.Embedded are ignored.
+	InlineParts []mime.Attachment
+
+	// FTS, if set, indexes the message for full-text search in the
+	// same transaction. Its MessageID is filled in. Indexing is
+	// best-effort: a failure is logged and the message kept.
+	FTS *FTSDoc
 }
 
 // Message represents a message in the database.
@@ -59,6 +67,10 @@ type Message struct {
 	AttachmentCount int
 	DeletedAt       sql.NullTime
 	ArchivedAt      time.Time
+
+	// MetadataOnly marks a message stored without its body, to be
+	// hydrated later.
+	MetadataOnly bool
 }
 
 // MessageExistsBatch checks which message IDs already exist in the database.
@@ -158,7 +170,7 @@ func (s *Store) EnsureConversation(sourceID int64, sourceConversationID, title s
 	// Try to get existing
 	var id int64
 	err := s.db.QueryRow(`
-		SELECT id FROM conversations
+		SELECT COALESCE(merged_into, id) FROM conversations
 		WHERE source_id = ? AND source_conversation_id = ?
 	`, sourceID, sourceConversationID).Scan(&id)
 
@@ -189,10 +201,13 @@ func upsertMessageSQL(now string) string {
 		rfc822_message_id, message_type,
 		sent_at, received_at, internal_date, sender_id, is_from_me,
 		subject, snippet, size_estimate,
-		has_attachments, attachment_count, archived_at
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, %s)
+		has_attachments, attachment_count, metadata_only, archived_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, %s)
 	ON CONFLICT(source_id, source_message_id) DO UPDATE SET
-		conversation_id = excluded.conversation_id,
+		conversation_id = COALESCE((
+			SELECT o.conversation_id FROM thread_overrides o
+			WHERE o.message_id = messages.id
+		), excluded.conversation_id),
 		rfc822_message_id = excluded.rfc822_message_id,
 		sent_at = excluded.sent_at,
 		received_at = excluded.received_at,
@@ -203,7 +218,8 @@ func upsertMessageSQL(now string) string {
 		snippet = excluded.snippet,
 		size_estimate = excluded.size_estimate,
 		has_attachments = excluded.has_attachments,
-		attachment_count = excluded.attachment_count`, now)
+		attachment_count = excluded.attachment_count,
+		metadata_only = excluded.metadata_only`, now)
 }
 
 // UpsertMessage inserts or updates a message.
@@ -218,7 +234,7 @@ func upsertMessageWith(q querier, d Dialect, msg *Message) (int64, error) {
 		msg.RFC822MessageID, msg.MessageType,
 		msg.SentAt, msg.ReceivedAt, msg.InternalDate, msg.SenderID, msg.IsFromMe,
 		msg.Subject, msg.Snippet, msg.SizeEstimate,
-		msg.HasAttachments, msg.AttachmentCount,
+		msg.HasAttachments, msg.AttachmentCount, msg.MetadataOnly,
 	}
 
 	// Use RETURNING to avoid an extra SELECT per message when supported.
@@ -246,106 +262,216 @@ func upsertMessageWith(q querier, d Dialect, msg *Message) (int64, error) {
 }
 
 // UpsertMessageBody stores the body text and HTML for a message in the separate message_bodies table.
+// The body is stored unfolded; chat and voice bodies have no reply trails.
 func (s *Store) UpsertMessageBody(messageID int64, bodyText, bodyHTML sql.NullString) error {
-	return upsertMessageBody(s.db, messageID, bodyText, bodyHTML)
+	return upsertMessageBody(s.db, messageID, bodyText, bodyHTML, bodyParts{})
 }
 
-func upsertMessageBody(q querier, messageID int64, bodyText, bodyHTML sql.NullString) error {
+// bodyParts holds the signature and quoted trail split off a body's
+// text when reply folding is on.
+type bodyParts struct {
+	quoted    sql.NullString
+	signature sql.NullString
+}
+
+func upsertMessageBody(q querier, messageID int64, bodyText, bodyHTML sql.NullString, parts bodyParts) error {
 	_, err := q.Exec(`
-		INSERT INTO message_bodies (message_id, body_text, body_html)
-		VALUES (?, ?, ?)
+		INSERT INTO message_bodies (message_id, body_text, body_html, body_quoted, body_signature)
+		VALUES (?, ?, ?, ?, ?)
 		ON CONFLICT(message_id) DO UPDATE SET
 			body_text = excluded.body_text,
-			body_html = excluded.body_html
-	`, messageID, bodyText, bodyHTML)
+			body_html = excluded.body_html,
+			body_quoted = excluded.body_quoted,
+			body_signature = excluded.body_signature
+	`, messageID, bodyText, bodyHTML, parts.quoted, parts.signature)
 	return err
 }
 
+// SetFoldReplies turns reply folding on or off for messages this Store
+// writes. With it on, the trailing quoted reply trail and signature of
+// each email's text body are also stored on their own (see
+// mime.SplitReply), and only the author's text is indexed for
+// full-text search. body_text always keeps the full text.
+func (s *Store) SetFoldReplies(on bool) {
+	s.foldReplies = on
+}
+
+// FoldReplies reports whether reply folding is on.
+func (s *Store) FoldReplies() bool {
+	return s.foldReplies
+}
+
+// foldBody returns the parts split off bodyText when reply folding is
+// on, and no parts otherwise.
+func (s *Store) foldBody(bodyText sql.NullString) bodyParts {
+	if !s.foldReplies || !bodyText.Valid {
+		return bodyParts{}
+	}
+	r := mime.SplitReply(bodyText.String)
+	return bodyParts{
+		quoted:    sql.NullString{String: r.Quoted, Valid: r.Quoted != ""},
+		signature: sql.NullString{String: r.Signature, Valid: r.Signature != ""},
+	}
+}
+
+// SearchableBody returns the part of an email body to index for
+// full-text search: the author's own text when reply folding is on,
+// otherwise all of it.
+func (s *Store) SearchableBody(bodyText string) string {
+	if !s.foldReplies {
+		return bodyText
+	}
+	return mime.SplitReply(bodyText).Text
+}
+
+// searchableBodySQL is the body indexed by FTS backfills: body_text up
+// to its stored signature and quoted trail, so rebuilt indexes match
+// what SearchableBody indexed when the message was written.
+const searchableBodySQL = `COALESCE(substr(mb.body_text, 1, length(mb.body_text) - ` +
+	`COALESCE(length(mb.body_quoted), 0) - COALESCE(length(mb.body_signature), 0)), '')`
+
 // UpsertMessageRaw stores the compressed raw MIME data for a message.
 func (s *Store) UpsertMessageRaw(messageID int64, rawData []byte) error {
-	return upsertMessageRaw(s.db, messageID, rawData)
+	return s.upsertMessageRaw(s.db, messageID, rawData, "mime")
 }
 
-func upsertMessageRaw(q querier, messageID int64, rawData []byte) error {
-	// Compress with zlib
-	var compressed bytes.Buffer
-	w := zlib.NewWriter(&compressed)
-	if _, err := w.Write(rawData); err != nil {
-		return fmt.Errorf("compress: %w", err)
+// upsertMessageRaw compresses rawData with the Store's codec and stores
+// it for a message in the given format.
+func (s *Store) upsertMessageRaw(q querier, messageID int64, rawData []byte, format string) error {
+	compressed, codec, err := s.compressRaw(rawData)
+	if err != nil {
+		return err
 	}
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("close compressor: %w", err)
+	data, blobHash, blobSize, err := s.placeRaw(compressed)
+	if err != nil {
+		return err
 	}
 
-	_, err := q.Exec(`
-		INSERT INTO message_raw (message_id, raw_data, raw_format, compression)
-		VALUES (?, ?, 'mime', 'zlib')
+	_, err = q.Exec(`
+		INSERT INTO message_raw (message_id, raw_data, raw_format, compression, blob_hash, blob_size)
+		VALUES (?, ?, ?, ?, ?, ?)
 		ON CONFLICT(message_id) DO UPDATE SET
 			raw_data = excluded.raw_data,
 			raw_format = excluded.raw_format,
-			compression = excluded.compression
-	`, messageID, compressed.Bytes())
+			compression = excluded.compression,
+			blob_hash = excluded.blob_hash,
+			blob_size = excluded.blob_size
+	`, messageID, data, format, codec, blobHash, blobSize)
 	return err
 }
 
 // GetMessageRaw retrieves and decompresses the raw MIME data for a message.
 func (s *Store) GetMessageRaw(messageID int64) ([]byte, error) {
 	var compressed []byte
-	var compression sql.NullString
+	var compression, blobHash sql.NullString
 
 	err := s.db.QueryRow(`
-		SELECT raw_data, compression FROM message_raw WHERE message_id = ?
-	`, messageID).Scan(&compressed, &compression)
+		SELECT raw_data, compression, blob_hash FROM message_raw WHERE message_id = ?
+	`, messageID).Scan(&compressed, &compression, &blobHash)
 	if err != nil {
 		return nil, err
 	}
-
-	if compression.Valid && compression.String == "zlib" {
-		r, err := zlib.NewReader(bytes.NewReader(compressed))
-		if err != nil {
-			return nil, fmt.Errorf("zlib reader: %w", err)
-		}
-		defer func() { _ = r.Close() }()
-		return io.ReadAll(r)
+	if compressed, err = LoadRaw(compressed, blobHash); err != nil {
+		return nil, err
 	}
+	return s.DecompressRaw(compressed, compression.String)
+}
 
-	return compressed, nil
+// GetMessageRawMIME returns a message's raw MIME data, decompressed,
+// or nil if the message has no raw data or keeps it in another format,
+// such as a chat export's JSON.
+func (s *Store) GetMessageRawMIME(messageID int64) ([]byte, error) {
+	var format string
+	err := s.db.QueryRow(`
+		SELECT raw_format FROM message_raw WHERE message_id = ?
+	`, messageID).Scan(&format)
+	if err == sql.ErrNoRows || (err == nil && format != "mime") {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetMessageRaw(messageID)
 }
 
 // PersistMessage atomically stores a message plus its body, raw MIME,
-// recipients, and labels in a single transaction. Returns the message ID.
+// recipients, labels, authentication results, and the links and
+// inline images in its HTML body in a single transaction. Returns the
+// message ID.
 func (s *Store) PersistMessage(data *MessagePersistData) (int64, error) {
+	// Checked before the transaction, as DKIM verification may wait on
+	// DNS.
+	auth := s.checkAuth(data)
+
 	var messageID int64
 	err := s.withTx(func(tx *loggedTx) error {
-		id, err := upsertMessageWith(tx, s.dialect, data.Message)
-		if err != nil {
-			return fmt.Errorf("upsert message: %w", err)
-		}
+		id, err := s.persistMessageTx(tx, data, auth)
 		messageID = id
+		return err
+	})
+	return messageID, err
+}
 
-		if err := upsertMessageBody(tx, messageID, data.BodyText, data.BodyHTML); err != nil {
-			return fmt.Errorf("upsert body: %w", err)
-		}
+// checkAuth checks the authentication results of a message to persist.
+func (s *Store) checkAuth(data *MessagePersistData) mailauth.Result {
+	if len(data.RawMIME) == 0 {
+		return mailauth.Result{}
+	}
+	return mailauth.Check(context.Background(), data.RawMIME, s.dkim)
+}
 
-		if len(data.RawMIME) > 0 {
-			if err := upsertMessageRaw(tx, messageID, data.RawMIME); err != nil {
-				return fmt.Errorf("store raw: %w", err)
-			}
+// persistMessageTx writes a message and its related rows in tx.
+func (s *Store) persistMessageTx(tx *loggedTx, data *MessagePersistData, auth mailauth.Result) (int64, error) {
+	messageID, err := upsertMessageWith(tx, s.dialect, data.Message)
+	if err != nil {
+		return 0, fmt.Errorf("upsert message: %w", err)
+	}
+
+	if err := upsertMessageBody(tx, messageID, data.BodyText, data.BodyHTML, s.foldBody(data.BodyText)); err != nil {
+		return 0, fmt.Errorf("upsert body: %w", err)
+	}
+
+	if len(data.RawMIME) > 0 {
+		if err := s.upsertMessageRaw(tx, messageID, data.RawMIME, "mime"); err != nil {
+			return 0, fmt.Errorf("store raw: %w", err)
+		}
+		if err := replaceMessageHeaders(tx, s.dialect, messageID, s.indexedHeaders(data.RawMIME)); err != nil {
+			return 0, fmt.Errorf("store headers: %w", err)
 		}
+	}
 
-		for _, rs := range data.Recipients {
-			if err := replaceMessageRecipientsTx(tx, messageID, rs); err != nil {
-				return fmt.Errorf("store %s recipients: %w", rs.Type, err)
-			}
+	for _, rs := range data.Recipients {
+		if err := replaceMessageRecipientsTx(tx, messageID, rs); err != nil {
+			return 0, fmt.Errorf("store %s recipients: %w", rs.Type, err)
 		}
+	}
 
-		if err := replaceMessageLabelsTx(tx, messageID, data.LabelIDs); err != nil {
-			return fmt.Errorf("store labels: %w", err)
+	if err := replaceMessageLabelsTx(tx, messageID, data.LabelIDs); err != nil {
+		return 0, fmt.Errorf("store labels: %w", err)
+	}
+
+	if !auth.IsZero() {
+		if err := upsertMessageAuth(tx, s.dialect, messageID, auth); err != nil {
+			return 0, fmt.Errorf("store authentication results: %w", err)
 		}
+	}
 
-		return nil
-	})
-	return messageID, err
+	if err := replaceMessageLinks(tx, s.dialect, messageID, mime.ExtractLinks(data.BodyHTML.String)); err != nil {
+		return 0, fmt.Errorf("store links: %w", err)
+	}
+
+	if err := replaceInlineParts(tx, messageID, inlinePartsFrom(data.InlineParts)); err != nil {
+		return 0, fmt.Errorf("store inline parts: %w", err)
+	}
+
+	if err := setMessageLanguage(tx, messageID, messageLanguage(data.Message.Subject, data.BodyText)); err != nil {
+		return 0, fmt.Errorf("store language: %w", err)
+	}
+
+	if data.FTS != nil {
+		s.indexFTSTx(tx, messageID, *data.FTS)
+	}
+	return messageID, nil
 }
 
 // Participant represents a person in the participants table.
@@ -612,6 +738,11 @@ func mergeLabelByName(
 	`, keepID, conflictID); err != nil {
 		return fmt.Errorf("reassign label associations: %w", err)
 	}
+	if _, err = q.Exec(`
+		UPDATE label_events SET label_id = ? WHERE label_id = ?
+	`, keepID, conflictID); err != nil {
+		return fmt.Errorf("reassign label history: %w", err)
+	}
 	if _, err = q.Exec(`
 		DELETE FROM labels WHERE id = ?
 	`, conflictID); err != nil {
@@ -620,10 +751,19 @@ func mergeLabelByName(
 	return nil
 }
 
-// LabelInfo holds the name and type for a label to be ensured.
+// LabelInfo holds the name, type, and display settings for a label to
+// be ensured.
 type LabelInfo struct {
 	Name string
 	Type string // "system" or "user"
+
+	// Color and TextColor are the background and text colors,
+	// "#rrggbb", or "" for none. Visibility is Gmail's
+	// labelListVisibility ("labelShow", "labelShowIfUnread",
+	// "labelHide"), or "" if unknown.
+	Color      string
+	TextColor  string
+	Visibility string
 }
 
 // IsSystemLabel returns true if the given Gmail label ID represents a system label.
@@ -683,6 +823,15 @@ func (s *Store) EnsureLabelsBatch(
 			if err != nil {
 				return err
 			}
+			if _, err := tx.Exec(`
+				UPDATE labels SET color = ?, text_color = ?, visibility = ?
+				WHERE id = ?
+			`, nullIfEmpty(info.Color), nullIfEmpty(info.TextColor),
+				nullIfEmpty(info.Visibility), id); err != nil {
+				return fmt.Errorf(
+					"update display of label %s: %w", sourceLabelID, err,
+				)
+			}
 			result[sourceLabelID] = id
 		}
 		return nil
@@ -766,6 +915,26 @@ func (s *Store) RemoveMessageLabels(messageID int64, labelIDs []int64) error {
 		`DELETE FROM message_labels WHERE message_id = ? AND label_id IN (%s)`)
 }
 
+// ListLabelNames returns every label name in the archive, sorted, with
+// names shared by several accounts listed once.
+func (s *Store) ListLabelNames() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT name FROM labels ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 // MarkMessageDeleted marks a message as deleted from the source.
 func (s *Store) MarkMessageDeleted(sourceID int64, sourceMessageID string) error {
 	_, err := s.db.Exec(fmt.Sprintf(`
@@ -776,6 +945,28 @@ func (s *Store) MarkMessageDeleted(sourceID int64, sourceMessageID string) error
 	return err
 }
 
+// PresentSourceMessageIDs returns the source message IDs of the
+// messages of sourceID not marked deleted from the source.
+func (s *Store) PresentSourceMessageIDs(sourceID int64) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT source_message_id FROM messages
+		WHERE source_id = ? AND deleted_from_source_at IS NULL
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("list source message IDs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan source message ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // MarkMessagesDeletedBatch marks multiple messages as deleted from the source in a single transaction.
 func (s *Store) MarkMessagesDeletedBatch(sourceID int64, sourceMessageIDs []string) error {
 	if len(sourceMessageIDs) == 0 {
@@ -1118,7 +1309,7 @@ func (s *Store) EnsureConversationWithType(sourceID int64, sourceConversationID,
 	// Try to get existing
 	var id int64
 	err := s.db.QueryRow(`
-		SELECT id FROM conversations
+		SELECT COALESCE(merged_into, id) FROM conversations
 		WHERE source_id = ? AND source_conversation_id = ?
 	`, sourceID, sourceConversationID).Scan(&id)
 
@@ -1255,25 +1446,7 @@ func (s *Store) UpsertReaction(messageID, participantID int64, reactionType, rea
 // UpsertMessageRawWithFormat stores compressed raw data with an explicit format.
 // Unlike UpsertMessageRaw (which hardcodes 'mime'), this accepts the format as a parameter.
 func (s *Store) UpsertMessageRawWithFormat(messageID int64, rawData []byte, format string) error {
-	// Compress with zlib
-	var compressed bytes.Buffer
-	w := zlib.NewWriter(&compressed)
-	if _, err := w.Write(rawData); err != nil {
-		return fmt.Errorf("compress: %w", err)
-	}
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("close compressor: %w", err)
-	}
-
-	_, err := s.db.Exec(`
-		INSERT INTO message_raw (message_id, raw_data, raw_format, compression)
-		VALUES (?, ?, ?, 'zlib')
-		ON CONFLICT(message_id) DO UPDATE SET
-			raw_data = excluded.raw_data,
-			raw_format = excluded.raw_format,
-			compression = excluded.compression
-	`, messageID, compressed.Bytes(), format)
-	return err
+	return s.upsertMessageRaw(s.db, messageID, rawData, format)
 }
 
 // AttachmentPathsUniqueToSource returns storage_path values for attachments
@@ -1338,6 +1511,12 @@ func (s *Store) IsAttachmentPathReferenced(storagePath string) (bool, error) {
 
 // UpsertAttachment stores an attachment record.
 func (s *Store) UpsertAttachment(messageID int64, filename, mimeType, storagePath, contentHash string, size int) error {
+	return s.UpsertStoredAttachment(messageID, filename, mimeType, storagePath, contentHash, size, int64(size))
+}
+
+// UpsertStoredAttachment stores an attachment record whose file takes
+// storedSize bytes on disk, fewer than size when it is compressed.
+func (s *Store) UpsertStoredAttachment(messageID int64, filename, mimeType, storagePath, contentHash string, size int, storedSize int64) error {
 	// Check if attachment already exists (by message_id and content_hash)
 	var existingID int64
 	err := s.db.QueryRow(`
@@ -1354,8 +1533,8 @@ func (s *Store) UpsertAttachment(messageID int64, filename, mimeType, storagePat
 
 	// Insert new attachment
 	_, err = s.db.Exec(fmt.Sprintf(`
-		INSERT INTO attachments (message_id, filename, mime_type, storage_path, content_hash, size, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, %s)
-	`, s.dialect.Now()), messageID, filename, mimeType, storagePath, contentHash, size)
+		INSERT INTO attachments (message_id, filename, mime_type, storage_path, content_hash, size, stored_size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, %s)
+	`, s.dialect.Now()), messageID, filename, mimeType, storagePath, contentHash, size, storedSize)
 	return err
 }
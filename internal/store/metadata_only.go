@@ -0,0 +1,50 @@
+package store
+
+import "fmt"
+
+// MetadataOnlyMessages returns the source message IDs of up to limit of
+// a source's messages synced without their bodies, newest first; limit
+// 0 means all. Messages deleted from the source are left out, as their
+// bodies can no longer be fetched.
+func (s *Store) MetadataOnlyMessages(sourceID int64, limit int) ([]string, error) {
+	query := `
+		SELECT m.source_message_id FROM messages m
+		WHERE m.source_id = ? AND m.metadata_only = ? AND ` + LiveMessagesWhere("m", true) + `
+		ORDER BY COALESCE(m.sent_at, m.received_at, m.internal_date) DESC, m.id DESC`
+	args := []any{sourceID, true}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list metadata-only messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan metadata-only message: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountMetadataOnlyMessages returns how many of a source's messages, or
+// every source's when sourceID is 0, await hydration.
+func (s *Store) CountMetadataOnlyMessages(sourceID int64) (int64, error) {
+	query := `SELECT COUNT(*) FROM messages m WHERE m.metadata_only = ? AND ` + LiveMessagesWhere("m", true)
+	args := []any{true}
+	if sourceID != 0 {
+		query += ` AND m.source_id = ?`
+		args = append(args, sourceID)
+	}
+	var n int64
+	if err := s.db.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count metadata-only messages: %w", err)
+	}
+	return n, nil
+}
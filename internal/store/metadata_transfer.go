@@ -0,0 +1,227 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// MetadataFormatVersion is the version of the metadata file written by
+// ExportMetadata. ImportMetadata rejects files from newer versions.
+// Version 2 added reminders, version 3 saved searches.
+const MetadataFormatVersion = 3
+
+// MetadataFile is the portable form of everything the user recorded
+// locally in a vault. Messages are identified by their source and
+// message IDs rather than by vault row IDs, so the file can be imported
+// into a rebuilt vault or into another vault of the same accounts.
+type MetadataFile struct {
+	Version       int               `json:"version"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Messages      []MessageMetadata `json:"messages"`
+	SavedSearches []SavedSearch     `json:"saved_searches,omitempty"`
+}
+
+// MessageMetadata is the annotation and reminder of one message and
+// what identifies the message in any vault.
+type MessageMetadata struct {
+	SourceType      string `json:"source_type"`
+	Account         string `json:"account"`
+	SourceMessageID string `json:"source_message_id,omitempty"`
+	RFC822MessageID string `json:"rfc822_message_id,omitempty"`
+	Annotation
+	Reminder *ReminderMetadata `json:"reminder,omitempty"`
+}
+
+// ReminderMetadata is the portable form of a message's reminder.
+type ReminderMetadata struct {
+	DueAt    time.Time `json:"due_at"`
+	Note     string    `json:"note,omitempty"`
+	Notified bool      `json:"notified,omitempty"`
+}
+
+// MetadataImportStats counts what ImportMetadata did.
+type MetadataImportStats struct {
+	Imported      int `json:"imported"`
+	Unmatched     int `json:"unmatched"` // messages not in this vault
+	SavedSearches int `json:"saved_searches"`
+}
+
+// ExportMetadata returns the annotations and reminders of every message
+// that has any, and the saved searches.
+func (s *Store) ExportMetadata() (*MetadataFile, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id, src.source_type, src.identifier,
+			COALESCE(m.source_message_id, ''), COALESCE(m.rfc822_message_id, '')
+		FROM messages m
+		JOIN sources src ON src.id = m.source_id
+		WHERE EXISTS (SELECT 1 FROM message_notes mn WHERE mn.message_id = m.id)
+		   OR EXISTS (SELECT 1 FROM message_tags mt WHERE mt.message_id = m.id)
+		   OR EXISTS (SELECT 1 FROM message_reminders mr WHERE mr.message_id = m.id)
+		ORDER BY m.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list annotated messages: %w", err)
+	}
+	type annotated struct {
+		id int64
+		mm MessageMetadata
+	}
+	var found []annotated
+	for rows.Next() {
+		var a annotated
+		if err := rows.Scan(&a.id, &a.mm.SourceType, &a.mm.Account,
+			&a.mm.SourceMessageID, &a.mm.RFC822MessageID); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan annotated message: %w", err)
+		}
+		found = append(found, a)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("list annotated messages: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list annotated messages: %w", err)
+	}
+
+	out := &MetadataFile{
+		Version:    MetadataFormatVersion,
+		ExportedAt: time.Now().UTC(),
+		Messages:   make([]MessageMetadata, 0, len(found)),
+	}
+	for _, a := range found {
+		if a.mm.Annotation, err = s.GetAnnotation(a.id); err != nil {
+			return nil, err
+		}
+		r, err := s.GetReminder(a.id)
+		if err != nil {
+			return nil, err
+		}
+		if r != nil {
+			a.mm.Reminder = &ReminderMetadata{DueAt: r.DueAt.UTC(), Note: r.Note, Notified: r.NotifiedAt != nil}
+		}
+		out.Messages = append(out.Messages, a.mm)
+	}
+	if out.SavedSearches, err = s.ListSavedSearches(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImportMetadata merges the annotations, reminders and saved searches
+// in f into this vault. Each message is found by its account and source
+// message ID, or failing that by its RFC822 Message-ID. Tags are added
+// to those the message already has, a pin is set, and a note, a
+// reminder or a saved search replaces the one the vault has; nothing is
+// removed, so importing the same file twice changes nothing.
+func (s *Store) ImportMetadata(f *MetadataFile) (MetadataImportStats, error) {
+	var stats MetadataImportStats
+	if f.Version > MetadataFormatVersion {
+		return stats, fmt.Errorf("metadata file version %d is newer than this msgvault supports (%d)",
+			f.Version, MetadataFormatVersion)
+	}
+	for _, mm := range f.Messages {
+		id, err := s.findMessageForMetadata(mm)
+		if err != nil {
+			return stats, err
+		}
+		if id == 0 {
+			stats.Unmatched++
+			continue
+		}
+		if mm.Note != "" {
+			if err := s.SetMessageNote(id, mm.Note); err != nil {
+				return stats, err
+			}
+		}
+		if mm.Pinned {
+			if err := s.SetMessagePinned(id, true); err != nil {
+				return stats, err
+			}
+		}
+		if len(mm.Tags) > 0 {
+			if err := s.AddMessageTags(id, mm.Tags); err != nil {
+				return stats, err
+			}
+		}
+		if mm.Reminder != nil {
+			if err := s.importReminder(id, mm.Reminder); err != nil {
+				return stats, err
+			}
+		}
+		stats.Imported++
+	}
+
+	// A saved search can use another as saved:name, and is checked when
+	// saved, so save those it refers to first.
+	pending := f.SavedSearches
+	for len(pending) > 0 {
+		var retry []SavedSearch
+		var lastErr error
+		for _, ss := range pending {
+			if err := s.SaveSearch(ss.Name, ss.Query); err != nil {
+				retry, lastErr = append(retry, ss), err
+				continue
+			}
+			stats.SavedSearches++
+		}
+		if len(retry) == len(pending) {
+			return stats, fmt.Errorf("import saved search %q: %w", retry[0].Name, lastErr)
+		}
+		pending = retry
+	}
+	return stats, nil
+}
+
+// importReminder sets a message's reminder to r, unless it has that
+// reminder already, so importing again does not notify it again.
+func (s *Store) importReminder(id int64, r *ReminderMetadata) error {
+	cur, err := s.GetReminder(id)
+	if err != nil {
+		return err
+	}
+	if cur != nil && cur.DueAt.Equal(r.DueAt.Truncate(time.Second)) && cur.Note == r.Note {
+		return nil
+	}
+	if err := s.SetReminder(id, r.DueAt, r.Note); err != nil {
+		return err
+	}
+	if r.Notified {
+		return s.MarkRemindersNotified([]int64{id})
+	}
+	return nil
+}
+
+// findMessageForMetadata returns the ID of the message mm describes, or
+// 0 if this vault does not have it.
+func (s *Store) findMessageForMetadata(mm MessageMetadata) (int64, error) {
+	var id int64
+	if mm.SourceMessageID != "" {
+		err := s.db.QueryRow(`
+			SELECT m.id FROM messages m
+			JOIN sources src ON src.id = m.source_id
+			WHERE src.source_type = ? AND src.identifier = ? AND m.source_message_id = ?
+			ORDER BY m.id LIMIT 1
+		`, mm.SourceType, mm.Account, mm.SourceMessageID).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("find message %s: %w", mm.SourceMessageID, err)
+		}
+	}
+	if mm.RFC822MessageID != "" {
+		err := s.db.QueryRow(`
+			SELECT m.id FROM messages m
+			WHERE m.rfc822_message_id = ?
+			ORDER BY m.deleted_at IS NOT NULL, m.id LIMIT 1
+		`, mm.RFC822MessageID).Scan(&id)
+		if err == nil {
+			return id, nil
+		}
+		if err != sql.ErrNoRows {
+			return 0, fmt.Errorf("find message %s: %w", mm.RFC822MessageID, err)
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,96 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_MetadataRoundTrip(t *testing.T) {
+	src := storetest.New(t)
+	ids := src.CreateMessages(4)
+	_, err := src.Store.DB().Exec(`UPDATE messages SET rfc822_message_id = 'abc@example.com' WHERE id = ?`, ids[2])
+	testutil.MustNoErr(t, err, "set rfc822 id")
+
+	testutil.MustNoErr(t, src.Store.SetMessageNote(ids[0], "call bob"), "SetMessageNote")
+	testutil.MustNoErr(t, src.Store.SetMessagePinned(ids[1], true), "SetMessagePinned")
+	testutil.MustNoErr(t, src.Store.AddMessageTags(ids[1], []string{"tax"}), "AddMessageTags")
+	testutil.MustNoErr(t, src.Store.AddMessageTags(ids[2], []string{"lease"}), "AddMessageTags")
+	testutil.MustNoErr(t, src.Store.AddMessageTags(ids[3], []string{"gone"}), "AddMessageTags")
+	due := time.Date(2030, 3, 1, 9, 0, 0, 0, time.UTC)
+	testutil.MustNoErr(t, src.Store.SetReminder(ids[0], due, "follow up"), "SetReminder")
+	testutil.MustNoErr(t, src.Store.MarkRemindersNotified([]int64{ids[0]}), "MarkRemindersNotified")
+	// "recent" sorts before the search it refers to.
+	testutil.MustNoErr(t, src.Store.SaveSearch("tax", "tag:tax"), "SaveSearch")
+	testutil.MustNoErr(t, src.Store.SaveSearch("recent", "saved:tax newer_than:1y"), "SaveSearch")
+
+	file, err := src.Store.ExportMetadata()
+	testutil.MustNoErr(t, err, "ExportMetadata")
+	if file.Version != store.MetadataFormatVersion || len(file.Messages) != 4 {
+		t.Fatalf("ExportMetadata = version %d with %d messages, want version %d with 4",
+			file.Version, len(file.Messages), store.MetadataFormatVersion)
+	}
+	if m := file.Messages[0]; m.Account != "test@example.com" || m.SourceMessageID != "msg-0" || m.Note != "call bob" {
+		t.Errorf("first exported message = %+v", m)
+	}
+	if r := file.Messages[0].Reminder; r == nil || !r.DueAt.Equal(due) || r.Note != "follow up" || !r.Notified {
+		t.Errorf("exported reminder = %+v", r)
+	}
+	if len(file.SavedSearches) != 2 {
+		t.Errorf("exported %d saved searches, want 2", len(file.SavedSearches))
+	}
+
+	// The destination vault has msg-0 and msg-1 under the same account,
+	// the third message only by its Message-ID, and not the fourth.
+	dst := storetest.New(t)
+	d0 := dst.CreateMessage("msg-0")
+	d1 := dst.CreateMessage("msg-1")
+	d2 := dst.CreateMessage("other-id")
+	_, err = dst.Store.DB().Exec(`UPDATE messages SET rfc822_message_id = 'abc@example.com' WHERE id = ?`, d2)
+	testutil.MustNoErr(t, err, "set rfc822 id")
+	testutil.MustNoErr(t, dst.Store.AddMessageTags(d1, []string{"keep"}), "AddMessageTags")
+
+	stats, err := dst.Store.ImportMetadata(file)
+	testutil.MustNoErr(t, err, "ImportMetadata")
+	if stats != (store.MetadataImportStats{Imported: 3, Unmatched: 1, SavedSearches: 2}) {
+		t.Errorf("ImportMetadata = %+v, want 3 imported, 1 unmatched and 2 saved searches", stats)
+	}
+	// Importing again changes nothing.
+	_, err = dst.Store.ImportMetadata(file)
+	testutil.MustNoErr(t, err, "ImportMetadata again")
+
+	tests := []struct {
+		id   int64
+		want store.Annotation
+	}{
+		{d0, store.Annotation{Note: "call bob"}},
+		{d1, store.Annotation{Pinned: true, Tags: []string{"keep", "tax"}}},
+		{d2, store.Annotation{Tags: []string{"lease"}}},
+	}
+	for _, tt := range tests {
+		got, err := dst.Store.GetAnnotation(tt.id)
+		testutil.MustNoErr(t, err, "GetAnnotation")
+		if got.Note != tt.want.Note || got.Pinned != tt.want.Pinned || !slices.Equal(got.Tags, tt.want.Tags) {
+			t.Errorf("message %d annotation = %+v, want %+v", tt.id, got, tt.want)
+		}
+	}
+
+	r, err := dst.Store.GetReminder(d0)
+	testutil.MustNoErr(t, err, "GetReminder")
+	if r == nil || !r.DueAt.Equal(due) || r.Note != "follow up" || r.NotifiedAt == nil {
+		t.Errorf("imported reminder = %+v, want due %v, notified", r, due)
+	}
+	expanded, err := dst.Store.ExpandSavedSearches("saved:recent")
+	testutil.MustNoErr(t, err, "ExpandSavedSearches")
+	if expanded != "( ( tag:tax ) newer_than:1y )" {
+		t.Errorf("saved:recent expands to %q", expanded)
+	}
+
+	if _, err := dst.Store.ImportMetadata(&store.MetadataFile{Version: store.MetadataFormatVersion + 1}); err == nil {
+		t.Error("ImportMetadata should reject a newer file version")
+	}
+}
@@ -241,7 +241,7 @@ func (s *Store) RunStartupMigrations(legacyIdentityAddresses []string) (StartupM
 // google_voice*, sms) so email addresses don't get written to them.
 func sourceTypeUsesEmailIdentity(sourceType string) bool {
 	switch sourceType {
-	case "gmail", "imap", "o365", "mbox", "hey", "apple-mail":
+	case "gmail", "outlook", "imap", "o365", "mbox", "hey", "apple-mail":
 		return true
 	}
 	return false
@@ -0,0 +1,351 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+// rawBlobDir is the directory raw message blobs are kept in. Like the
+// decoder cache it is process-wide: the query engines read raw data
+// with nothing but a *sql.DB, so a Store cannot hand them its own.
+var rawBlobDir atomic.Pointer[string]
+
+// rawBlobMinAge is how old an unreferenced blob must be before
+// PruneRawBlobs removes it, so that a blob written for a message whose
+// row is not yet committed survives.
+const rawBlobMinAge = time.Hour
+
+// SetRawBlobDir sets the directory raw message data moved out of the
+// database is kept in, content-addressed like attachments: each file
+// is named by the SHA-256 of its content under a directory named by
+// the hash's first two characters.
+func SetRawBlobDir(dir string) {
+	rawBlobDir.Store(&dir)
+}
+
+// RawBlobDir returns the directory set by SetRawBlobDir.
+func RawBlobDir() string {
+	if dir := rawBlobDir.Load(); dir != nil {
+		return *dir
+	}
+	return ""
+}
+
+// SetRawBlobStore sets whether the Store writes new raw message data
+// to files in the RawBlobDir rather than into the database. Data
+// already stored stays where it is until MoveRawData moves it.
+func (s *Store) SetRawBlobStore(enabled bool) {
+	s.rawBlobs = enabled
+}
+
+// rawBlobPath returns the path of the blob named hash in dir.
+func rawBlobPath(dir, hash string) (string, error) {
+	if len(hash) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid raw blob hash %q", hash)
+	}
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", fmt.Errorf("invalid raw blob hash %q", hash)
+	}
+	return filepath.Join(dir, hash[:2], hash), nil
+}
+
+// writeRawBlob stores data, compressed raw message data, as a blob and
+// returns its hash. The blob is on disk when it returns, so the data
+// can be dropped from the database. Data already stored is not written
+// again, but its blob is touched so PruneRawBlobs treats it as new.
+func writeRawBlob(data []byte) (string, error) {
+	dir := RawBlobDir()
+	if dir == "" {
+		return "", errors.New("no raw blob directory set")
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	p, _ := rawBlobPath(dir, hash)
+	if st, err := os.Lstat(p); err == nil && st.Mode().IsRegular() && st.Size() == int64(len(data)) {
+		now := time.Now()
+		if err := os.Chtimes(p, now, now); err != nil {
+			return "", fmt.Errorf("touch raw blob: %w", err)
+		}
+		return hash, nil
+	}
+	_, err := os.Lstat(filepath.Dir(p))
+	newDir := errors.Is(err, fs.ErrNotExist)
+	if err := fileutil.SecureMkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return "", fmt.Errorf("create raw blob directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-"+hash[:8]+"-*")
+	if err != nil {
+		return "", fmt.Errorf("create raw blob: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("write raw blob: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return "", fmt.Errorf("sync raw blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("close raw blob: %w", err)
+	}
+	if err := fileutil.SecureChmod(tmpPath, 0o600); err != nil {
+		return "", fmt.Errorf("chmod raw blob: %w", err)
+	}
+	if err := os.Rename(tmpPath, p); err != nil {
+		return "", fmt.Errorf("rename raw blob into place: %w", err)
+	}
+	if err := fileutil.SyncDir(filepath.Dir(p)); err != nil {
+		return "", fmt.Errorf("sync raw blob directory: %w", err)
+	}
+	if newDir {
+		if err := fileutil.SyncDir(dir); err != nil {
+			return "", fmt.Errorf("sync raw blob directory: %w", err)
+		}
+	}
+	return hash, nil
+}
+
+// readRawBlob reads the blob named hash and checks it against the hash.
+func readRawBlob(hash string) ([]byte, error) {
+	dir := RawBlobDir()
+	if dir == "" {
+		return nil, errors.New("no raw blob directory set")
+	}
+	p, err := rawBlobPath(dir, hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("read raw blob: %w", err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+		return nil, fmt.Errorf("raw blob %s is corrupt: content does not match its hash", hash)
+	}
+	return data, nil
+}
+
+// LoadRaw returns the compressed data of a message_raw row: raw_data,
+// or the content of the blob blobHash names if the data was moved out
+// of the database. DecompressRaw decompresses what it returns.
+func LoadRaw(data []byte, blobHash sql.NullString) ([]byte, error) {
+	if !blobHash.Valid {
+		return data, nil
+	}
+	return readRawBlob(blobHash.String)
+}
+
+// placeRaw returns the raw_data, blob_hash and blob_size to store
+// compressed raw data with: the data itself, or, if the Store writes
+// blobs, an empty raw_data and the blob it was written to.
+func (s *Store) placeRaw(compressed []byte) (data []byte, blobHash, blobSize any, err error) {
+	if !s.rawBlobs {
+		return compressed, nil, nil, nil
+	}
+	hash, err := writeRawBlob(compressed)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return []byte{}, hash, len(compressed), nil
+}
+
+// MoveRawStats counts what MoveRawData did.
+type MoveRawStats struct {
+	Moved  int   // rows whose data was moved
+	Failed int   // rows whose blob could not be read
+	Bytes  int64 // compressed bytes moved
+}
+
+// MoveRawData moves the stored raw data of every message into blob
+// files, or with toBlobs false back into the database, batchSize rows
+// per transaction. Data keeps its compression. progress, if not nil,
+// is called after each batch.
+func (s *Store) MoveRawData(toBlobs bool, batchSize int, progress func(MoveRawStats)) (MoveRawStats, error) {
+	var stats MoveRawStats
+	cond := "blob_hash IS NOT NULL"
+	if toBlobs {
+		cond = "blob_hash IS NULL"
+	}
+	type move struct {
+		id               int64
+		data             []byte
+		blobHash, blobSz any
+	}
+	var after int64
+	for {
+		rows, err := s.db.Query(`
+			SELECT message_id, raw_data, blob_hash FROM message_raw
+			WHERE message_id > ? AND `+cond+`
+			ORDER BY message_id LIMIT ?
+		`, after, batchSize)
+		if err != nil {
+			return stats, fmt.Errorf("read raw messages: %w", err)
+		}
+		var batch []move
+		n := 0
+		for rows.Next() {
+			var id int64
+			var data []byte
+			var blobHash sql.NullString
+			if err := rows.Scan(&id, &data, &blobHash); err != nil {
+				_ = rows.Close()
+				return stats, err
+			}
+			n++
+			after = id
+			if !toBlobs {
+				if data, err = LoadRaw(data, blobHash); err != nil {
+					stats.Failed++
+					continue
+				}
+				batch = append(batch, move{id: id, data: data})
+				stats.Bytes += int64(len(data))
+				continue
+			}
+			hash, err := writeRawBlob(data)
+			if err != nil {
+				_ = rows.Close()
+				return stats, err
+			}
+			batch = append(batch, move{id: id, data: []byte{}, blobHash: hash, blobSz: len(data)})
+			stats.Bytes += int64(len(data))
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, err
+		}
+		if n == 0 {
+			return stats, nil
+		}
+
+		if len(batch) > 0 {
+			err := s.withTx(func(tx *loggedTx) error {
+				for _, m := range batch {
+					if _, err := tx.Exec(`
+						UPDATE message_raw SET raw_data = ?, blob_hash = ?, blob_size = ?
+						WHERE message_id = ?
+					`, m.data, m.blobHash, m.blobSz, m.id); err != nil {
+						return fmt.Errorf("move raw message %d: %w", m.id, err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return stats, err
+			}
+			stats.Moved += len(batch)
+		}
+		if progress != nil {
+			progress(stats)
+		}
+	}
+}
+
+// PruneRawBlobs removes the blobs no message refers to any more, such
+// as those of deleted messages or of data moved back into the
+// database, and returns how many it removed and their size. Blobs
+// written or reused in the last hour are kept, as their message may
+// not be committed yet.
+func (s *Store) PruneRawBlobs() (removed int, size int64, err error) {
+	dir := RawBlobDir()
+	if dir == "" {
+		return 0, 0, nil
+	}
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, nil
+	}
+	hashes, err := s.RawBlobHashes()
+	if err != nil {
+		return 0, 0, err
+	}
+	referenced := make(map[string]bool, len(hashes))
+	for _, h := range hashes {
+		referenced[h] = true
+	}
+
+	cutoff := time.Now().Add(-rawBlobMinAge)
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || referenced[d.Name()] {
+			return nil
+		}
+		if _, err := rawBlobPath(dir, d.Name()); err != nil {
+			return nil // not a blob
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(p); err != nil {
+			return fmt.Errorf("remove raw blob: %w", err)
+		}
+		removed++
+		size += info.Size()
+		return nil
+	})
+	return removed, size, err
+}
+
+// RawBlobHashes returns the hashes of the blobs the messages of the
+// database hold their raw data in.
+func (s *Store) RawBlobHashes() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT blob_hash FROM message_raw WHERE blob_hash IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("list raw blobs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// CopyRawBlob copies the blob named hash from the blob directory srcDir
+// into dstDir, as when merging another vault, and reports whether it
+// was copied rather than present already.
+func CopyRawBlob(srcDir, dstDir, hash string) (bool, error) {
+	src, err := rawBlobPath(srcDir, hash)
+	if err != nil {
+		return false, err
+	}
+	dst, _ := rawBlobPath(dstDir, hash)
+	if _, err := os.Lstat(dst); err == nil {
+		return false, nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return false, fmt.Errorf("read raw blob: %w", err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != hash {
+		return false, fmt.Errorf("raw blob %s is corrupt: content does not match its hash", hash)
+	}
+	if err := fileutil.SecureMkdirAll(filepath.Dir(dst), 0o700); err != nil {
+		return false, fmt.Errorf("create raw blob directory: %w", err)
+	}
+	if err := fileutil.SecureWriteFile(dst, data, 0o600); err != nil {
+		return false, fmt.Errorf("write raw blob: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,165 @@
+package store_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_RawBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store.SetRawBlobDir(dir)
+	t.Cleanup(func() { store.SetRawBlobDir("") })
+
+	f := storetest.New(t)
+	ids := f.CreateMessages(6)
+
+	// Two messages archived into the database, four into blob files,
+	// two of which are identical and share one.
+	for i, id := range ids[:2] {
+		testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, rawTestMessage(i)), "UpsertMessageRaw")
+	}
+	f.Store.SetRawBlobStore(true)
+	want := [][]byte{rawTestMessage(0), rawTestMessage(1), rawTestMessage(2), rawTestMessage(3),
+		rawTestMessage(4), rawTestMessage(4)}
+	for i, id := range ids[2:] {
+		testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, want[2+i]), "UpsertMessageRaw blob")
+	}
+
+	countRows := func(cond string) int {
+		t.Helper()
+		var n int
+		testutil.MustNoErr(t, f.Store.DB().QueryRow(
+			`SELECT COUNT(*) FROM message_raw WHERE `+cond).Scan(&n), "count rows")
+		return n
+	}
+	countFiles := func() int {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+		testutil.MustNoErr(t, err, "glob blobs")
+		return len(files)
+	}
+	readAll := func() {
+		t.Helper()
+		for i, id := range ids {
+			raw, err := f.Store.GetMessageRaw(id)
+			testutil.MustNoErr(t, err, "GetMessageRaw")
+			if !bytes.Equal(raw, want[i]) {
+				t.Fatalf("message %d reads back as %q", id, raw)
+			}
+		}
+	}
+
+	if n := countRows("blob_hash IS NOT NULL AND length(raw_data) = 0"); n != 4 {
+		t.Fatalf("blob rows = %d, want 4", n)
+	}
+	if n := countFiles(); n != 3 {
+		t.Fatalf("blob files = %d, want 3", n)
+	}
+	readAll()
+
+	stats, err := f.Store.MoveRawData(true, 1, nil)
+	testutil.MustNoErr(t, err, "MoveRawData to blobs")
+	if stats.Moved != 2 || stats.Failed != 0 {
+		t.Fatalf("MoveRawData to blobs = %+v, want 2 moved", stats)
+	}
+	if n := countRows("blob_hash IS NULL"); n != 0 {
+		t.Fatalf("%d rows left in the database", n)
+	}
+	readAll()
+
+	// Recompressing keeps the data in blob files.
+	f.Store.SetRawCompression(store.RawCompressionZstd)
+	_, err = f.Store.RecompressRaw(4, nil)
+	testutil.MustNoErr(t, err, "RecompressRaw")
+	if n := countRows("blob_hash IS NOT NULL AND compression = 'zstd'"); n != 6 {
+		t.Fatalf("zstd blob rows = %d, want 6", n)
+	}
+	readAll()
+
+	// The zlib blobs are no longer referenced, but are too new to prune.
+	removed, _, err := f.Store.PruneRawBlobs()
+	testutil.MustNoErr(t, err, "PruneRawBlobs")
+	if removed != 0 {
+		t.Fatalf("pruned %d new blobs", removed)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	testutil.MustNoErr(t, err, "glob blobs")
+	for _, p := range files {
+		testutil.MustNoErr(t, os.Chtimes(p, old, old), "age blob")
+	}
+	removed, _, err = f.Store.PruneRawBlobs()
+	testutil.MustNoErr(t, err, "PruneRawBlobs")
+	if removed != 5 || countFiles() != 5 {
+		t.Fatalf("pruned %d blobs leaving %d, want 5 left of 10", removed, countFiles())
+	}
+	readAll()
+
+	stats, err = f.Store.MoveRawData(false, 4, nil)
+	testutil.MustNoErr(t, err, "MoveRawData to database")
+	if stats.Moved != 6 {
+		t.Fatalf("MoveRawData to database = %+v, want 6 moved", stats)
+	}
+	if n := countRows("blob_hash IS NULL AND length(raw_data) > 0"); n != 6 {
+		t.Fatalf("database rows = %d, want 6", n)
+	}
+	readAll()
+}
+
+func TestStore_RawBlobReuseKeepsBlob(t *testing.T) {
+	dir := t.TempDir()
+	store.SetRawBlobDir(dir)
+	t.Cleanup(func() { store.SetRawBlobDir("") })
+
+	f := storetest.New(t)
+	ids := f.CreateMessages(2)
+	f.Store.SetRawBlobStore(true)
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(ids[0], rawTestMessage(0)), "UpsertMessageRaw")
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	testutil.MustNoErr(t, err, "glob blobs")
+	if len(files) != 1 {
+		t.Fatalf("blob files = %v", files)
+	}
+
+	// The only message referring to an old blob goes, and another one
+	// is archived with the same data before the prune runs.
+	old := time.Now().Add(-2 * time.Hour)
+	testutil.MustNoErr(t, os.Chtimes(files[0], old, old), "age blob")
+	_, err = f.Store.DB().Exec(`DELETE FROM message_raw WHERE message_id = ?`, ids[0])
+	testutil.MustNoErr(t, err, "delete raw")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(ids[1], rawTestMessage(0)), "UpsertMessageRaw reuse")
+
+	info, err := os.Stat(files[0])
+	testutil.MustNoErr(t, err, "stat blob")
+	if !info.ModTime().After(old) {
+		t.Errorf("reused blob mtime = %v, want refreshed", info.ModTime())
+	}
+}
+
+func TestStore_RawBlobCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	store.SetRawBlobDir(dir)
+	t.Cleanup(func() { store.SetRawBlobDir("") })
+
+	f := storetest.New(t)
+	id := f.CreateMessages(1)[0]
+	f.Store.SetRawBlobStore(true)
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, rawTestMessage(0)), "UpsertMessageRaw")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*"))
+	testutil.MustNoErr(t, err, "glob blobs")
+	if len(files) != 1 {
+		t.Fatalf("blob files = %v", files)
+	}
+	testutil.MustNoErr(t, os.WriteFile(files[0], []byte("tampered"), 0o600), "tamper")
+	if _, err := f.Store.GetMessageRaw(id); err == nil {
+		t.Fatal("GetMessageRaw of a corrupt blob succeeded")
+	}
+}
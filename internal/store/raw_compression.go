@@ -0,0 +1,451 @@
+package store
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs raw message data is compressed with, as recorded in
+// message_raw.compression.
+const (
+	RawCompressionZlib = "zlib"
+	RawCompressionZstd = "zstd"
+)
+
+// RawCompressions lists the codecs SetRawCompression accepts.
+var RawCompressions = []string{RawCompressionZlib, RawCompressionZstd}
+
+// rawDictMaxSize caps the header samples a trained dictionary holds,
+// about the size the zstd command line trainer defaults to.
+const rawDictMaxSize = 112 << 10
+
+// rawDictSampleMax caps how much of each message's header block is
+// sampled for a dictionary.
+const rawDictSampleMax = 8 << 10
+
+// rawDictMinSamples is how many messages a dictionary needs to be
+// trained on to be worth having.
+const rawDictMinSamples = 20
+
+// RowQuerier runs a query returning one row; *sql.DB and *sql.Tx
+// implement it.
+type RowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// rawDecoders caches zstd decoders by the ID of the dictionary they
+// decode with, 0 for none. Dictionary IDs are derived from the
+// dictionary's content, so one cache serves every vault.
+var rawDecoders sync.Map
+
+// rawCodec holds the codec a Store compresses raw data with.
+type rawCodec struct {
+	mu    sync.Mutex
+	name  string        // "" means zlib
+	enc   *zstd.Encoder // built on first use
+	dict  uint32        // ID of the dictionary enc compresses with
+	ready bool
+}
+
+// SetRawCompression sets the codec raw message data is compressed with
+// from now on: "zlib" (the default) or "zstd". zstd compresses with the
+// vault's newest trained dictionary, if it has one. Data already stored
+// keeps its codec until RecompressRaw rewrites it.
+func (s *Store) SetRawCompression(codec string) {
+	s.raw.mu.Lock()
+	defer s.raw.mu.Unlock()
+	s.raw.name = codec
+	s.raw.enc = nil
+	s.raw.ready = false
+}
+
+// RawCompression returns the codec raw message data is compressed with.
+func (s *Store) RawCompression() string {
+	s.raw.mu.Lock()
+	defer s.raw.mu.Unlock()
+	if s.raw.name == "" {
+		return RawCompressionZlib
+	}
+	return s.raw.name
+}
+
+// rawEncoder returns the zstd encoder raw data is compressed with and
+// the ID of its dictionary, 0 for none.
+func (s *Store) rawEncoder() (*zstd.Encoder, uint32, error) {
+	s.raw.mu.Lock()
+	defer s.raw.mu.Unlock()
+	if s.raw.ready {
+		return s.raw.enc, s.raw.dict, nil
+	}
+	id, dict, err := s.latestRawDictionary()
+	if err != nil {
+		return nil, 0, err
+	}
+	opts := []zstd.EOption{zstd.WithEncoderLevel(zstd.SpeedBetterCompression)}
+	if dict != nil {
+		opts = append(opts, zstd.WithEncoderDict(dict))
+	}
+	enc, err := zstd.NewWriter(nil, opts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("zstd encoder: %w", err)
+	}
+	s.raw.enc, s.raw.dict, s.raw.ready = enc, id, true
+	return enc, id, nil
+}
+
+// compressRaw compresses raw data with the Store's codec and returns
+// it with the codec's name.
+func (s *Store) compressRaw(data []byte) ([]byte, string, error) {
+	switch codec := s.RawCompression(); codec {
+	case RawCompressionZlib:
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, "", fmt.Errorf("compress: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", fmt.Errorf("close compressor: %w", err)
+		}
+		return buf.Bytes(), codec, nil
+	case RawCompressionZstd:
+		enc, _, err := s.rawEncoder()
+		if err != nil {
+			return nil, "", err
+		}
+		return enc.EncodeAll(data, nil), codec, nil
+	default:
+		return nil, "", fmt.Errorf("unknown raw compression %q", codec)
+	}
+}
+
+// DecompressRaw returns message_raw data stored with the given
+// compression, decompressed. Data with no known compression is returned
+// as is.
+func (s *Store) DecompressRaw(data []byte, compression string) ([]byte, error) {
+	return DecompressRaw(context.Background(), s.db, "", data, compression)
+}
+
+// DecompressRaw returns message_raw data stored with the given
+// compression, decompressed. Data with no known compression is returned
+// as is. zstd data compressed with a dictionary reads it from the
+// raw_dictionaries table through q; tablePrefix is "" for direct SQLite
+// or "sqlite_db." for DuckDB's sqlite_scan.
+func DecompressRaw(ctx context.Context, q RowQuerier, tablePrefix string, data []byte, compression string) ([]byte, error) {
+	switch compression {
+	case RawCompressionZlib:
+		r, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("zlib reader: %w", err)
+		}
+		defer func() { _ = r.Close() }()
+		out, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("zlib decompress: %w", err)
+		}
+		return out, nil
+	case RawCompressionZstd:
+		var h zstd.Header
+		if err := h.Decode(data); err != nil {
+			return nil, fmt.Errorf("zstd header: %w", err)
+		}
+		dec, err := rawDecoder(ctx, q, tablePrefix, h.DictionaryID)
+		if err != nil {
+			return nil, err
+		}
+		out, err := dec.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("zstd decompress: %w", err)
+		}
+		return out, nil
+	}
+	return data, nil
+}
+
+// rawDecoder returns a zstd decoder for data compressed with the
+// dictionary id, loading the dictionary through q the first time.
+func rawDecoder(ctx context.Context, q RowQuerier, tablePrefix string, id uint32) (*zstd.Decoder, error) {
+	if dec, ok := rawDecoders.Load(id); ok {
+		return dec.(*zstd.Decoder), nil
+	}
+	var opts []zstd.DOption
+	if id != 0 {
+		var dict []byte
+		err := q.QueryRowContext(ctx, fmt.Sprintf(
+			`SELECT data FROM %sraw_dictionaries WHERE dict_id = ?`, tablePrefix), int64(id)).Scan(&dict)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("zstd dictionary %d not found", id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load zstd dictionary %d: %w", id, err)
+		}
+		opts = append(opts, zstd.WithDecoderDicts(dict))
+	}
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("zstd decoder: %w", err)
+	}
+	if prev, loaded := rawDecoders.LoadOrStore(id, dec); loaded {
+		dec.Close()
+		return prev.(*zstd.Decoder), nil
+	}
+	return dec, nil
+}
+
+// latestRawDictionary returns the newest trained dictionary and its ID,
+// or nil if the vault has none.
+func (s *Store) latestRawDictionary() (uint32, []byte, error) {
+	var id int64
+	var dict []byte
+	err := s.db.QueryRow(`
+		SELECT dict_id, data FROM raw_dictionaries
+		ORDER BY created_at DESC, id DESC LIMIT 1
+	`).Scan(&id, &dict)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("load zstd dictionary: %w", err)
+	}
+	return uint32(id), dict, nil
+}
+
+// rawDictionaryID derives a dictionary's ID from the samples it holds,
+// outside the range the zstd format reserves for registered dictionaries.
+func rawDictionaryID(history []byte) uint32 {
+	sum := sha256.Sum256(history)
+	const first = 1 << 15
+	return first + binary.BigEndian.Uint32(sum[:4])%(math.MaxInt32-first)
+}
+
+// TrainRawDictionary trains a zstd dictionary on the header blocks of
+// up to samples of the newest raw MIME messages, stores it, and makes
+// it the one zstd compresses new raw data with. Headers repeat from
+// message to message far more than bodies do, so a dictionary of them
+// shrinks small messages most. It returns the dictionary's ID and size.
+func (s *Store) TrainRawDictionary(samples int) (id uint32, size int, err error) {
+	rows, err := s.db.Query(`
+		SELECT raw_data, compression, blob_hash FROM message_raw
+		WHERE raw_format = 'mime'
+		ORDER BY message_id DESC LIMIT ?
+	`, samples)
+	if err != nil {
+		return 0, 0, fmt.Errorf("sample raw messages: %w", err)
+	}
+	var headers [][]byte
+	for rows.Next() {
+		var data []byte
+		var compression, blobHash sql.NullString
+		if err := rows.Scan(&data, &compression, &blobHash); err != nil {
+			_ = rows.Close()
+			return 0, 0, err
+		}
+		if data, err = LoadRaw(data, blobHash); err != nil {
+			continue
+		}
+		raw, err := s.DecompressRaw(data, compression.String)
+		if err != nil {
+			continue // an unreadable message is no loss to the sample
+		}
+		if h := rawHeaderBlock(raw); len(h) > 0 {
+			headers = append(headers, h)
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+	if len(headers) < rawDictMinSamples {
+		return 0, 0, fmt.Errorf("need at least %d raw messages to train a dictionary, found %d", rawDictMinSamples, len(headers))
+	}
+
+	// The history is every other sample, oldest first so the newest sit
+	// closest to the data being compressed, up to rawDictMaxSize. The
+	// rest tune the entropy tables to what the history does not cover.
+	var history []byte
+	for i := 0; i < len(headers) && len(history)+len(headers[i]) <= rawDictMaxSize; i += 2 {
+		history = append(headers[i][:len(headers[i]):len(headers[i])], history...)
+	}
+	id = rawDictionaryID(history)
+	dict, err := buildRawDictionary(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: headers,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+		Level:    zstd.SpeedBetterCompression,
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("build zstd dictionary: %w", err)
+	}
+
+	// A dictionary trained again on the same samples becomes the newest.
+	err = s.withTx(func(tx *loggedTx) error {
+		if _, err := tx.Exec(`DELETE FROM raw_dictionaries WHERE dict_id = ?`, int64(id)); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO raw_dictionaries (dict_id, data, sample_count, created_at)
+			VALUES (?, ?, ?, `+s.dialect.Now()+`)
+		`, int64(id), dict, len(headers))
+		return err
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("store zstd dictionary: %w", err)
+	}
+	s.SetRawCompression(s.RawCompression())
+	return id, len(dict), nil
+}
+
+// buildRawDictionary builds a zstd dictionary, turning the panic
+// zstd.BuildDict raises when the history covers every sample whole into
+// an error.
+func buildRawDictionary(o zstd.BuildDictOptions) (dict []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("samples too uniform: %v", r)
+		}
+	}()
+	return zstd.BuildDict(o)
+}
+
+// rawHeaderBlock returns the header block of a raw MIME message, blank
+// line included, capped at rawDictSampleMax.
+func rawHeaderBlock(raw []byte) []byte {
+	end := len(raw)
+	if i := bytes.Index(raw, []byte("\r\n\r\n")); i >= 0 {
+		end = i + 4
+	} else if i := bytes.Index(raw, []byte("\n\n")); i >= 0 {
+		end = i + 2
+	}
+	return raw[:min(end, rawDictSampleMax)]
+}
+
+// RecompressStats reports what RecompressRaw rewrote.
+type RecompressStats struct {
+	Scanned     int   // message_raw rows looked at
+	Rewritten   int   // rows recompressed
+	BytesBefore int64 // stored size of the rewritten rows before
+	BytesAfter  int64 // and after
+	Failed      int   // rows that could not be decompressed, left as they were
+}
+
+// RecompressRaw rewrites the message_raw rows not stored with the
+// Store's codec, or compressed with zstd and a dictionary other than the
+// newest, in batches of batchSize. Rows that cannot be decompressed are
+// counted and left alone. progress, if not nil, is called after each
+// batch.
+func (s *Store) RecompressRaw(batchSize int, progress func(RecompressStats)) (RecompressStats, error) {
+	var stats RecompressStats
+	codec := s.RawCompression()
+	var dictID uint32
+	if codec == RawCompressionZstd {
+		var err error
+		if _, dictID, err = s.rawEncoder(); err != nil {
+			return stats, err
+		}
+	}
+
+	type rewrite struct {
+		id                 int64
+		data               []byte
+		blobHash, blobSize any
+	}
+	var after int64
+	for {
+		rows, err := s.db.Query(`
+			SELECT message_id, raw_data, compression, blob_hash FROM message_raw
+			WHERE message_id > ?
+			ORDER BY message_id LIMIT ?
+		`, after, batchSize)
+		if err != nil {
+			return stats, fmt.Errorf("read raw messages: %w", err)
+		}
+		var batch []rewrite
+		n := 0
+		for rows.Next() {
+			var id int64
+			var data []byte
+			var compression, blobHash sql.NullString
+			if err := rows.Scan(&id, &data, &compression, &blobHash); err != nil {
+				_ = rows.Close()
+				return stats, err
+			}
+			n++
+			after = id
+			if data, err = LoadRaw(data, blobHash); err != nil {
+				stats.Failed++
+				continue
+			}
+			if compression.String == codec {
+				if codec != RawCompressionZstd {
+					continue
+				}
+				var h zstd.Header
+				if h.Decode(data) == nil && h.DictionaryID == dictID {
+					continue
+				}
+			}
+			raw, err := s.DecompressRaw(data, compression.String)
+			if err != nil {
+				stats.Failed++
+				continue
+			}
+			out, _, err := s.compressRaw(raw)
+			if err != nil {
+				_ = rows.Close()
+				return stats, err
+			}
+			stats.BytesBefore += int64(len(data))
+			stats.BytesAfter += int64(len(out))
+			// Data stays where it was: in a blob file or the database.
+			r := rewrite{id: id, data: out}
+			if blobHash.Valid {
+				if r.blobHash, err = writeRawBlob(out); err != nil {
+					_ = rows.Close()
+					return stats, err
+				}
+				r.data, r.blobSize = []byte{}, len(out)
+			}
+			batch = append(batch, r)
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return stats, err
+		}
+		stats.Scanned += n
+		if n == 0 {
+			return stats, nil
+		}
+
+		if len(batch) > 0 {
+			err := s.withTx(func(tx *loggedTx) error {
+				for _, r := range batch {
+					if _, err := tx.Exec(`
+						UPDATE message_raw SET raw_data = ?, compression = ?, blob_hash = ?, blob_size = ?
+						WHERE message_id = ?
+					`, r.data, codec, r.blobHash, r.blobSize, r.id); err != nil {
+						return fmt.Errorf("rewrite raw message %d: %w", r.id, err)
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return stats, err
+			}
+			stats.Rewritten += len(batch)
+		}
+		if progress != nil {
+			progress(stats)
+		}
+	}
+}
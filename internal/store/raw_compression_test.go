@@ -0,0 +1,98 @@
+package store_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// rawTestMessage returns a small raw message with headers like the
+// ones a mailbox is full of.
+func rawTestMessage(i int) []byte {
+	return []byte(fmt.Sprintf("Received: from mail.example.com (mail.example.com [192.0.2.%d])\r\n"+
+		"\tby mx.example.org with ESMTPS id abc%d\r\n"+
+		"From: Alice <alice@example.com>\r\nTo: Bob <bob@example.org>\r\n"+
+		"Subject: Weekly report %d\r\nMessage-ID: <report-%d@example.com>\r\n"+
+		"MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n"+
+		"Content-Transfer-Encoding: 7bit\r\n\r\nReport number %d.\r\n", i%250, i, i, i, i))
+}
+
+func TestStore_RawCompressionZstd(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(30)
+
+	// Messages archived with the default codec.
+	for i, id := range ids[:10] {
+		testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, rawTestMessage(i)), "UpsertMessageRaw")
+	}
+
+	f.Store.SetRawCompression(store.RawCompressionZstd)
+	for i, id := range ids[10:] {
+		testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, rawTestMessage(10+i)), "UpsertMessageRaw zstd")
+	}
+	var zstdRows int
+	testutil.MustNoErr(t, f.Store.DB().QueryRow(
+		`SELECT COUNT(*) FROM message_raw WHERE compression = 'zstd'`).Scan(&zstdRows), "count zstd")
+	if zstdRows != 20 {
+		t.Fatalf("zstd rows = %d, want 20", zstdRows)
+	}
+
+	id, size, err := f.Store.TrainRawDictionary(100)
+	testutil.MustNoErr(t, err, "TrainRawDictionary")
+	if id < 1<<15 || size == 0 {
+		t.Fatalf("TrainRawDictionary = %d, %d bytes", id, size)
+	}
+
+	// Every row is rewritten: the zlib ones for the codec, the zstd
+	// ones for the dictionary.
+	stats, err := f.Store.RecompressRaw(7, nil)
+	testutil.MustNoErr(t, err, "RecompressRaw")
+	if stats.Scanned != 30 || stats.Rewritten != 30 || stats.Failed != 0 {
+		t.Fatalf("RecompressRaw = %+v, want 30 scanned and rewritten", stats)
+	}
+	if stats.BytesAfter >= stats.BytesBefore {
+		t.Errorf("recompressed %d bytes into %d, want fewer", stats.BytesBefore, stats.BytesAfter)
+	}
+	for i, id := range ids {
+		raw, err := f.Store.GetMessageRaw(id)
+		testutil.MustNoErr(t, err, "GetMessageRaw")
+		if !bytes.Equal(raw, rawTestMessage(i)) {
+			t.Fatalf("message %d reads back as %q", id, raw)
+		}
+	}
+
+	// A second run has nothing left to do.
+	stats, err = f.Store.RecompressRaw(7, nil)
+	testutil.MustNoErr(t, err, "RecompressRaw again")
+	if stats.Scanned != 30 || stats.Rewritten != 0 {
+		t.Errorf("second RecompressRaw = %+v, want nothing rewritten", stats)
+	}
+
+	// Back to zlib.
+	f.Store.SetRawCompression(store.RawCompressionZlib)
+	stats, err = f.Store.RecompressRaw(100, nil)
+	testutil.MustNoErr(t, err, "RecompressRaw zlib")
+	if stats.Rewritten != 30 {
+		t.Errorf("RecompressRaw to zlib = %+v, want 30 rewritten", stats)
+	}
+	raw, err := f.Store.GetMessageRaw(ids[0])
+	testutil.MustNoErr(t, err, "GetMessageRaw zlib")
+	if !bytes.Equal(raw, rawTestMessage(0)) {
+		t.Errorf("message reads back as %q", raw)
+	}
+}
+
+func TestStore_TrainRawDictionaryNeedsSamples(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+	for i, id := range ids {
+		testutil.MustNoErr(t, f.Store.UpsertMessageRaw(id, rawTestMessage(i)), "UpsertMessageRaw")
+	}
+	if _, _, err := f.Store.TrainRawDictionary(100); err == nil {
+		t.Error("TrainRawDictionary on 3 messages succeeded, want an error")
+	}
+}
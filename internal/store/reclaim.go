@@ -0,0 +1,108 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Reclaim categories, in the order 'msgvault reclaim' offers them.
+const (
+	ReclaimDuplicates  = "duplicates"  // extra copies of a message in the same account
+	ReclaimNewsletters = "newsletters" // mailing list messages, grouped by list
+	ReclaimAttachments = "attachments" // messages with attachments, largest attachments first
+	ReclaimLargest     = "largest"     // any message, largest first
+)
+
+// ReclaimCategories lists the reclaim categories in the order they are
+// offered.
+var ReclaimCategories = []string{ReclaimDuplicates, ReclaimNewsletters, ReclaimAttachments, ReclaimLargest}
+
+// ReclaimCandidate is a message still in a Gmail or IMAP account whose
+// deletion there would free about Size bytes of the account's storage.
+type ReclaimCandidate struct {
+	ID              int64
+	Account         string // source identifier
+	SourceMessageID string
+	Group           string // the List-Id of a newsletter; empty otherwise
+	From            string
+	Subject         string
+	SentAt          time.Time
+	Size            int64
+}
+
+// ReclaimCandidates calls fn with each candidate message of a reclaim
+// category in the given sources (all sources when empty), until fn
+// returns false. Candidates come largest first; newsletters come a list
+// at a time, the list taking the most space first.
+//
+// Duplicates are the copies of a message after the first archived,
+// matched on Message-ID within an account. Copies dedup hid in the
+// archive still count: hiding them freed nothing in the account.
+func (s *Store) ReclaimCandidates(kind string, sourceIDs []int64, fn func(ReclaimCandidate) bool) error {
+	scope, args := sourceScopeClause(sourceIDs)
+	size := "COALESCE(m.size_estimate, 0)"
+	from := "messages m"
+	where := LiveMessagesWhere("m", true) + scope
+	group := "''"
+	order := size + " DESC, m.id"
+	switch kind {
+	case ReclaimDuplicates:
+		from = `(
+			SELECT m.*, ROW_NUMBER() OVER (
+				PARTITION BY m.source_id, m.rfc822_message_id ORDER BY m.id
+			) AS copy
+			FROM messages m
+			WHERE m.deleted_from_source_at IS NULL AND m.rfc822_message_id <> ''` + scope + `
+		) m`
+		where = "m.copy > 1"
+	case ReclaimNewsletters:
+		from = `messages m
+			JOIN (
+				SELECT message_id, MIN(value) AS list FROM message_headers
+				WHERE name = 'list-id' GROUP BY message_id
+			) lh ON lh.message_id = m.id`
+		group = "lh.list"
+		order = "SUM(" + size + ") OVER (PARTITION BY lh.list) DESC, lh.list, " + order
+	case ReclaimAttachments:
+		where += " AND m.has_attachments = 1"
+		order = "(SELECT COALESCE(SUM(a.size), 0) FROM attachments a WHERE a.message_id = m.id) DESC, " + order
+	case ReclaimLargest:
+	default:
+		return fmt.Errorf("unknown reclaim category %q", kind)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT m.id, src.identifier, m.source_message_id, `+group+`,
+			COALESCE((
+				SELECT p.email_address FROM message_recipients mr
+				JOIN participants p ON p.id = mr.participant_id
+				WHERE mr.message_id = m.id AND mr.recipient_type = 'from'
+				LIMIT 1
+			), ''),
+			COALESCE(m.subject, ''), m.sent_at, `+size+`
+		FROM `+from+`
+		JOIN sources src ON src.id = m.source_id AND src.source_type IN ('gmail', 'imap')
+		WHERE `+where+`
+		ORDER BY `+order, args...)
+	if err != nil {
+		return fmt.Errorf("list %s to reclaim: %w", kind, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var c ReclaimCandidate
+		var sentAt sql.NullString
+		if err := rows.Scan(&c.ID, &c.Account, &c.SourceMessageID, &c.Group, &c.From, &c.Subject, &sentAt, &c.Size); err != nil {
+			return fmt.Errorf("scan %s to reclaim: %w", kind, err)
+		}
+		c.SentAt = parseSQLiteTime(sentAt.String)
+		if !fn(c) {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("list %s to reclaim: %w", kind, err)
+	}
+	return nil
+}
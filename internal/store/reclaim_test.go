@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_ReclaimCandidates(t *testing.T) {
+	f := storetest.New(t)
+	small := f.NewMessage().WithSize(1_000).Create(t, f.Store)
+	big := f.NewMessage().WithSize(9_000_000).WithAttachmentCount(1).Create(t, f.Store)
+	copy1 := f.NewMessage().WithSize(5_000).Create(t, f.Store)
+	copy2 := f.NewMessage().WithSize(5_000).Create(t, f.Store)
+	copy3 := f.NewMessage().WithSize(5_000).Create(t, f.Store)
+	news1 := f.NewMessage().WithSize(20_000).Create(t, f.Store)
+	news2 := f.NewMessage().WithSize(30_000).Create(t, f.Store)
+	digest := f.NewMessage().WithSize(40_000).Create(t, f.Store)
+	gone := f.NewMessage().WithSize(50_000_000).WithAttachmentCount(2).Create(t, f.Store)
+
+	db := f.Store.DB()
+	for _, id := range []int64{copy1, copy2, copy3} {
+		_, err := db.Exec(`UPDATE messages SET rfc822_message_id = '<dup@example.com>' WHERE id = ?`, id)
+		testutil.MustNoErr(t, err, "set Message-ID")
+	}
+	for id, list := range map[int64]string{news1: "news.example.com", news2: "news.example.com", digest: "digest.example.com"} {
+		_, err := db.Exec(`INSERT INTO message_headers (message_id, name, value) VALUES (?, 'list-id', ?)`, id, list)
+		testutil.MustNoErr(t, err, "index List-Id")
+	}
+	_, err := db.Exec(`INSERT INTO attachments (message_id, filename, size, storage_path) VALUES (?, 'scan.pdf', 8000000, 'ab/abcd')`, big)
+	testutil.MustNoErr(t, err, "add attachment")
+	_, err = db.Exec(`UPDATE messages SET deleted_from_source_at = CURRENT_TIMESTAMP WHERE id = ?`, gone)
+	testutil.MustNoErr(t, err, "delete from account")
+
+	collect := func(kind string, max int) []int64 {
+		t.Helper()
+		var ids []int64
+		err := f.Store.ReclaimCandidates(kind, nil, func(c store.ReclaimCandidate) bool {
+			if c.Account != "test@example.com" || c.SourceMessageID == "" {
+				t.Errorf("%s candidate %+v", kind, c)
+			}
+			ids = append(ids, c.ID)
+			return len(ids) < max
+		})
+		testutil.MustNoErr(t, err, "ReclaimCandidates "+kind)
+		return ids
+	}
+
+	// The first copy archived is kept; a copy hidden by dedup still counts.
+	_, err = db.Exec(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP, delete_batch_id = 'b1' WHERE id = ?`, copy3)
+	testutil.MustNoErr(t, err, "hide copy")
+	if got := collect(store.ReclaimDuplicates, 10); !slices.Equal(got, []int64{copy2, copy3}) {
+		t.Errorf("duplicates = %v, want %v", got, []int64{copy2, copy3})
+	}
+	// The news list takes more space than the digest, so comes first.
+	if got := collect(store.ReclaimNewsletters, 10); !slices.Equal(got, []int64{news2, news1, digest}) {
+		t.Errorf("newsletters = %v, want %v", got, []int64{news2, news1, digest})
+	}
+	if got := collect(store.ReclaimAttachments, 10); !slices.Equal(got, []int64{big}) {
+		t.Errorf("attachments = %v, want %v", got, []int64{big})
+	}
+	if got := collect(store.ReclaimLargest, 2); !slices.Equal(got, []int64{big, digest}) {
+		t.Errorf("largest = %v, want %v", got, []int64{big, digest})
+	}
+	if got := collect(store.ReclaimLargest, 100); len(got) != 7 || got[len(got)-1] != small {
+		t.Errorf("largest = %v, want the 7 messages still in the account, %d last", got, small)
+	}
+
+	if err := f.Store.ReclaimCandidates("everything", nil, func(store.ReclaimCandidate) bool { return true }); err == nil {
+		t.Error("ReclaimCandidates with an unknown category succeeded")
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// reminderTimeFormat is how reminder times are stored: UTC, like
+// datetime('now'), so they compare as text in SQLite.
+const reminderTimeFormat = "2006-01-02 15:04:05"
+
+// Reminder is a local reminder to look at a message again. Like notes,
+// reminders are kept only in this archive.
+type Reminder struct {
+	MessageID  int64      `json:"message_id"`
+	DueAt      time.Time  `json:"due_at"`
+	Note       string     `json:"note,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	Subject    string     `json:"subject"`
+	From       string     `json:"from"`
+}
+
+// SetReminder sets a message's reminder to come due at due, replacing
+// any it had. A replaced reminder is notified again when it comes due.
+func (s *Store) SetReminder(messageID int64, due time.Time, note string) error {
+	if err := s.checkMessageExists(messageID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO message_reminders (message_id, due_at, note, created_at, notified_at)
+		VALUES (?, ?, ?, %s, NULL)
+		ON CONFLICT(message_id) DO UPDATE SET
+			due_at = excluded.due_at,
+			note = excluded.note,
+			created_at = excluded.created_at,
+			notified_at = NULL
+	`, s.dialect.Now()), messageID, due.UTC().Format(reminderTimeFormat),
+		nullIfEmpty(strings.TrimSpace(note)))
+	if err != nil {
+		return fmt.Errorf("set reminder: %w", err)
+	}
+	return nil
+}
+
+// ClearReminder removes a message's reminder, if it has one.
+func (s *Store) ClearReminder(messageID int64) error {
+	if _, err := s.db.Exec(`DELETE FROM message_reminders WHERE message_id = ?`, messageID); err != nil {
+		return fmt.Errorf("clear reminder: %w", err)
+	}
+	return nil
+}
+
+// GetReminder returns a message's reminder, or nil if it has none.
+func (s *Store) GetReminder(messageID int64) (*Reminder, error) {
+	reminders, err := s.queryReminders("r.message_id = ?", messageID)
+	if err != nil || len(reminders) == 0 {
+		return nil, err
+	}
+	return &reminders[0], nil
+}
+
+// ListReminders returns the reminders on live messages, soonest due
+// first. With dueBy set, only those due by then are returned, and with
+// unnotified only those not notified yet.
+func (s *Store) ListReminders(dueBy time.Time, unnotified bool) ([]Reminder, error) {
+	where := []string{LiveMessagesWhere("m", true)}
+	var args []any
+	if !dueBy.IsZero() {
+		where = append(where, "r.due_at <= ?")
+		args = append(args, dueBy.UTC().Format(reminderTimeFormat))
+	}
+	if unnotified {
+		where = append(where, "r.notified_at IS NULL")
+	}
+	return s.queryReminders(strings.Join(where, " AND "), args...)
+}
+
+// CountDueReminders returns how many reminders on live messages are due
+// by now.
+func (s *Store) CountDueReminders(now time.Time) (int64, error) {
+	var n int64
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM message_reminders r
+		JOIN messages m ON m.id = r.message_id
+		WHERE r.due_at <= ? AND `+LiveMessagesWhere("m", true),
+		now.UTC().Format(reminderTimeFormat)).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count due reminders: %w", err)
+	}
+	return n, nil
+}
+
+// MarkRemindersNotified records that the reminders of messageIDs were
+// notified, so they are not notified again.
+func (s *Store) MarkRemindersNotified(messageIDs []int64) error {
+	return s.withTx(func(tx *loggedTx) error {
+		for _, id := range messageIDs {
+			if _, err := tx.Exec(fmt.Sprintf(`
+				UPDATE message_reminders SET notified_at = %s WHERE message_id = ?
+			`, s.dialect.Now()), id); err != nil {
+				return fmt.Errorf("mark reminder %d notified: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) queryReminders(where string, args ...any) ([]Reminder, error) {
+	rows, err := s.db.Query(`
+		SELECT r.message_id, r.due_at, r.note, r.created_at, r.notified_at,
+			COALESCE(m.subject, ''),
+			COALESCE((
+				SELECT p.email_address FROM message_recipients mr
+				JOIN participants p ON p.id = mr.participant_id
+				WHERE mr.message_id = m.id AND mr.recipient_type = 'from'
+				LIMIT 1
+			), '')
+		FROM message_reminders r
+		JOIN messages m ON m.id = r.message_id
+		WHERE `+where+`
+		ORDER BY r.due_at, r.message_id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list reminders: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []Reminder
+	for rows.Next() {
+		var r Reminder
+		var due, created string
+		var note, notified sql.NullString
+		if err := rows.Scan(&r.MessageID, &due, &note, &created, &notified, &r.Subject, &r.From); err != nil {
+			return nil, fmt.Errorf("scan reminder: %w", err)
+		}
+		r.DueAt = parseSQLiteTime(due)
+		r.CreatedAt = parseSQLiteTime(created)
+		r.Note = note.String
+		if notified.Valid {
+			t := parseSQLiteTime(notified.String)
+			r.NotifiedAt = &t
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,67 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_Reminders(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+	now := time.Now()
+
+	if err := f.Store.SetReminder(99999, now, ""); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("SetReminder on a missing message = %v, want ErrMessageNotFound", err)
+	}
+	testutil.MustNoErr(t, f.Store.SetReminder(ids[0], now.Add(-time.Hour), " renew the contract "), "SetReminder past")
+	testutil.MustNoErr(t, f.Store.SetReminder(ids[1], now.AddDate(0, 11, 0), ""), "SetReminder future")
+	testutil.MustNoErr(t, f.Store.SetReminder(ids[2], now.Add(-2*time.Hour), ""), "SetReminder past")
+
+	r, err := f.Store.GetReminder(ids[0])
+	testutil.MustNoErr(t, err, "GetReminder")
+	if r == nil || r.Note != "renew the contract" || r.NotifiedAt != nil ||
+		r.DueAt.Sub(now.Add(-time.Hour)).Abs() > time.Second {
+		t.Fatalf("GetReminder = %+v", r)
+	}
+
+	all, err := f.Store.ListReminders(time.Time{}, false)
+	testutil.MustNoErr(t, err, "ListReminders")
+	if len(all) != 3 || all[0].MessageID != ids[2] || all[2].MessageID != ids[1] {
+		t.Fatalf("ListReminders = %+v, want 3 soonest due first", all)
+	}
+	n, err := f.Store.CountDueReminders(now)
+	testutil.MustNoErr(t, err, "CountDueReminders")
+	if n != 2 {
+		t.Errorf("CountDueReminders = %d, want 2", n)
+	}
+	_, total, err := f.Store.SearchMessagesQuery(search.Parse("is:due"), 0, 10)
+	testutil.MustNoErr(t, err, "SearchMessagesQuery is:due")
+	if total != 2 {
+		t.Errorf("is:due: %d results, want 2", total)
+	}
+
+	// Notified reminders are not notified again until they are set anew.
+	testutil.MustNoErr(t, f.Store.MarkRemindersNotified([]int64{ids[0], ids[2]}), "MarkRemindersNotified")
+	pending, err := f.Store.ListReminders(now, true)
+	testutil.MustNoErr(t, err, "ListReminders unnotified")
+	if len(pending) != 0 {
+		t.Errorf("unnotified due reminders = %+v, want none", pending)
+	}
+	testutil.MustNoErr(t, f.Store.SetReminder(ids[2], now.Add(-time.Minute), ""), "SetReminder again")
+	pending, err = f.Store.ListReminders(now, true)
+	testutil.MustNoErr(t, err, "ListReminders unnotified")
+	if len(pending) != 1 || pending[0].MessageID != ids[2] {
+		t.Errorf("unnotified due reminders = %+v, want message %d", pending, ids[2])
+	}
+
+	testutil.MustNoErr(t, f.Store.ClearReminder(ids[0]), "ClearReminder")
+	if r, err := f.Store.GetReminder(ids[0]); err != nil || r != nil {
+		t.Errorf("GetReminder after clearing = %+v, %v", r, err)
+	}
+}
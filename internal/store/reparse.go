@@ -0,0 +1,242 @@
+package store
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// ReparseCandidate is a message whose stored raw MIME can be parsed
+// again.
+type ReparseCandidate struct {
+	ID         int64
+	SourceType string
+}
+
+// reparseCandidateQuery selects messages with MIME raw data; other raw
+// formats (iMessage archives, WhatsApp JSON) have no MIME to parse.
+const reparseCandidateQuery = `
+	SELECT m.id, s.source_type
+	FROM messages m
+	JOIN sources s ON s.id = m.source_id
+	WHERE EXISTS (
+		SELECT 1 FROM message_raw mr
+		WHERE mr.message_id = m.id AND mr.raw_format = 'mime'
+	)`
+
+// ReparseCandidates returns up to limit messages with ID above afterID
+// that have raw MIME, in ID order.
+func (s *Store) ReparseCandidates(afterID int64, limit int) ([]ReparseCandidate, error) {
+	rows, err := s.db.Query(reparseCandidateQuery+` AND m.id > ? ORDER BY m.id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list reparse candidates: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []ReparseCandidate
+	for rows.Next() {
+		var c ReparseCandidate
+		if err := rows.Scan(&c.ID, &c.SourceType); err != nil {
+			return nil, fmt.Errorf("scan reparse candidate: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// ReparseCandidatesByID returns the messages among ids that have raw
+// MIME, in ID order.
+func (s *Store) ReparseCandidatesByID(ids []int64) ([]ReparseCandidate, error) {
+	var out []ReparseCandidate
+	err := queryInChunks(s.db, ids, nil, reparseCandidateQuery+` AND m.id IN (%s)`, func(rows *loggedRows) error {
+		var c ReparseCandidate
+		if err := rows.Scan(&c.ID, &c.SourceType); err != nil {
+			return err
+		}
+		out = append(out, c)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list reparse candidates: %w", err)
+	}
+	slices.SortFunc(out, func(a, b ReparseCandidate) int { return cmp.Compare(a.ID, b.ID) })
+	return out, nil
+}
+
+// ReparsedMessage holds the fields re-derived from a message's raw
+// MIME, from which the links in its HTML body are extracted, its
+// language detected and its header fields indexed again.
+// Labels, the conversation, and attachments are left alone, but the
+// images embedded in the HTML body are recorded again.
+type ReparsedMessage struct {
+	Subject    sql.NullString
+	Snippet    sql.NullString // left unchanged when not Valid
+	SentAt     sql.NullTime   // left unchanged when not Valid
+	SenderID   sql.NullInt64
+	BodyText   sql.NullString
+	BodyHTML   sql.NullString
+	Recipients []RecipientSet
+
+	// InlineParts are the message's parts; only the embedded images
+	// among them are recorded.
+	InlineParts []mime.Attachment
+
+	// Raw is the message's raw MIME, from which its header fields are
+	// indexed again.
+	Raw []byte
+}
+
+// ApplyReparse replaces a message's parsed fields with r if any of
+// them differ from what is stored, and reports whether it did. The
+// body is folded or unfolded to match the current SetFoldReplies.
+func (s *Store) ApplyReparse(messageID int64, r *ReparsedMessage) (bool, error) {
+	changed := false
+	err := s.withTx(func(tx *loggedTx) error {
+		var cur ReparsedMessage
+		var curParts bodyParts
+		var curLang sql.NullString
+		err := tx.QueryRow(`
+			SELECT m.subject, m.snippet, m.sent_at, m.sender_id, m.language, mb.body_text, mb.body_html,
+			       mb.body_quoted, mb.body_signature
+			FROM messages m
+			LEFT JOIN message_bodies mb ON mb.message_id = m.id
+			WHERE m.id = ?
+		`, messageID).Scan(&cur.Subject, &cur.Snippet, &cur.SentAt, &cur.SenderID, &curLang, &cur.BodyText, &cur.BodyHTML,
+			&curParts.quoted, &curParts.signature)
+		if err != nil {
+			return fmt.Errorf("read message: %w", err)
+		}
+		curRecipients, err := messageRecipientsTx(tx, messageID)
+		if err != nil {
+			return err
+		}
+
+		snippet, sentAt := cur.Snippet, cur.SentAt
+		if r.Snippet.Valid {
+			snippet = r.Snippet
+		}
+		if r.SentAt.Valid {
+			sentAt = r.SentAt
+		}
+		lang := messageLanguage(r.Subject, r.BodyText)
+		msgChanged := cur.Subject != r.Subject || cur.Snippet != snippet || cur.SenderID != r.SenderID ||
+			sentAt.Valid != cur.SentAt.Valid || !sentAt.Time.Equal(cur.SentAt.Time) || curLang != lang
+		parts := s.foldBody(r.BodyText)
+		bodyChanged := cur.BodyText != r.BodyText || cur.BodyHTML != r.BodyHTML || curParts != parts
+
+		if msgChanged {
+			if _, err := tx.Exec(`
+				UPDATE messages SET subject = ?, snippet = ?, sent_at = ?, sender_id = ?, language = ?
+				WHERE id = ?
+			`, r.Subject, snippet, sentAt, r.SenderID, lang, messageID); err != nil {
+				return fmt.Errorf("update message: %w", err)
+			}
+		}
+		if bodyChanged {
+			if err := upsertMessageBody(tx, messageID, r.BodyText, r.BodyHTML, parts); err != nil {
+				return fmt.Errorf("update body: %w", err)
+			}
+		}
+		recipientsChanged := false
+		for _, rs := range r.Recipients {
+			if recipientSetEqual(curRecipients[rs.Type], rs) {
+				continue
+			}
+			recipientsChanged = true
+			if err := replaceMessageRecipientsTx(tx, messageID, rs); err != nil {
+				return fmt.Errorf("store %s recipients: %w", rs.Type, err)
+			}
+		}
+		// Links are compared rather than tied to the body, so that
+		// messages stored before links were recorded pick them up.
+		links := mime.ExtractLinks(r.BodyHTML.String)
+		curLinks, err := messageLinks(tx, messageID)
+		if err != nil {
+			return fmt.Errorf("read links: %w", err)
+		}
+		linksChanged := !sameLinks(curLinks, links)
+		if linksChanged {
+			if err := replaceMessageLinks(tx, s.dialect, messageID, links); err != nil {
+				return fmt.Errorf("store links: %w", err)
+			}
+		}
+		inline := inlinePartsFrom(r.InlineParts)
+		curInline, err := messageInlineParts(tx, messageID)
+		if err != nil {
+			return fmt.Errorf("read inline parts: %w", err)
+		}
+		inlineChanged := !sameInlineParts(curInline, inline)
+		if inlineChanged {
+			if err := replaceInlineParts(tx, messageID, inline); err != nil {
+				return fmt.Errorf("store inline parts: %w", err)
+			}
+		}
+		headers := s.indexedHeaders(r.Raw)
+		curHeaders, err := messageHeaders(tx, messageID)
+		if err != nil {
+			return fmt.Errorf("read headers: %w", err)
+		}
+		headersChanged := !sameHeaders(curHeaders, headers)
+		if headersChanged {
+			if err := replaceMessageHeaders(tx, s.dialect, messageID, headers); err != nil {
+				return fmt.Errorf("store headers: %w", err)
+			}
+		}
+		changed = msgChanged || bodyChanged || recipientsChanged || linksChanged || inlineChanged || headersChanged
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("reparse message %d: %w", messageID, err)
+	}
+	return changed, nil
+}
+
+// messageRecipientsTx returns a message's recipients keyed by type,
+// in insertion order.
+func messageRecipientsTx(tx *loggedTx, messageID int64) (map[string]RecipientSet, error) {
+	rows, err := tx.Query(`
+		SELECT recipient_type, participant_id, COALESCE(display_name, '')
+		FROM message_recipients WHERE message_id = ? ORDER BY id
+	`, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("read recipients: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	out := map[string]RecipientSet{}
+	for rows.Next() {
+		var typ, name string
+		var id int64
+		if err := rows.Scan(&typ, &id, &name); err != nil {
+			return nil, fmt.Errorf("scan recipient: %w", err)
+		}
+		rs := out[typ]
+		rs.Type = typ
+		rs.ParticipantIDs = append(rs.ParticipantIDs, id)
+		rs.DisplayNames = append(rs.DisplayNames, name)
+		out[typ] = rs
+	}
+	return out, rows.Err()
+}
+
+func recipientSetEqual(a, b RecipientSet) bool {
+	if !slices.Equal(a.ParticipantIDs, b.ParticipantIDs) {
+		return false
+	}
+	for i := range a.ParticipantIDs {
+		var an, bn string
+		if i < len(a.DisplayNames) {
+			an = a.DisplayNames[i]
+		}
+		if i < len(b.DisplayNames) {
+			bn = b.DisplayNames[i]
+		}
+		if an != bn {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package store_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_ReparseCandidates(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(ids[0], []byte("Subject: a\r\n\r\nbody")), "UpsertMessageRaw")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRawWithFormat(ids[1], []byte(`{}`), "whatsapp_json"), "UpsertMessageRawWithFormat")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(ids[2], []byte("Subject: c\r\n\r\nbody")), "UpsertMessageRaw")
+
+	got, err := f.Store.ReparseCandidates(0, 10)
+	testutil.MustNoErr(t, err, "ReparseCandidates")
+	if len(got) != 2 || got[0].ID != ids[0] || got[1].ID != ids[2] {
+		t.Fatalf("ReparseCandidates = %+v, want messages %d and %d", got, ids[0], ids[2])
+	}
+	if got, _ := f.Store.ReparseCandidates(ids[0], 10); len(got) != 1 || got[0].ID != ids[2] {
+		t.Errorf("ReparseCandidates after %d = %+v", ids[0], got)
+	}
+	byID, err := f.Store.ReparseCandidatesByID([]int64{ids[2], ids[1]})
+	testutil.MustNoErr(t, err, "ReparseCandidatesByID")
+	if len(byID) != 1 || byID[0].ID != ids[2] {
+		t.Errorf("ReparseCandidatesByID = %+v, want only %d", byID, ids[2])
+	}
+}
+
+func TestStore_ApplyReparse(t *testing.T) {
+	f := storetest.New(t)
+	id := f.CreateMessage("msg-1")
+
+	r := &store.ReparsedMessage{
+		Subject:  sql.NullString{String: "Fixed subject", Valid: true},
+		BodyText: sql.NullString{String: "Fixed body", Valid: true},
+	}
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if !changed {
+		t.Error("first ApplyReparse reported no change")
+	}
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse again")
+	if changed {
+		t.Error("identical ApplyReparse reported a change")
+	}
+
+	var subject, body string
+	err = f.Store.DB().QueryRow(`
+		SELECT m.subject, mb.body_text FROM messages m
+		JOIN message_bodies mb ON mb.message_id = m.id WHERE m.id = ?`, id).Scan(&subject, &body)
+	testutil.MustNoErr(t, err, "query message")
+	if subject != "Fixed subject" || body != "Fixed body" {
+		t.Errorf("subject=%q body=%q", subject, body)
+	}
+}
+
+func TestStore_ApplyReparseFoldReplies(t *testing.T) {
+	f := storetest.New(t)
+	id := f.CreateMessage("msg-1")
+	body := "Sounds good.\n-- \nAlice\n\nOn Tue, Bob wrote:\n> Lunch?\n"
+	r := &store.ReparsedMessage{BodyText: sql.NullString{String: body, Valid: true}}
+
+	parts := func() (quoted, signature sql.NullString) {
+		t.Helper()
+		err := f.Store.DB().QueryRow(`SELECT body_quoted, body_signature FROM message_bodies
+			WHERE message_id = ?`, id).Scan(&quoted, &signature)
+		testutil.MustNoErr(t, err, "query body parts")
+		return quoted, signature
+	}
+
+	_, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse")
+	if q, s := parts(); q.Valid || s.Valid {
+		t.Errorf("unfolded parts = %q, %q, want NULL", q.String, s.String)
+	}
+
+	f.Store.SetFoldReplies(true)
+	changed, err := f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse folding")
+	if !changed {
+		t.Error("folding an unfolded body reported no change")
+	}
+	q, s := parts()
+	if q.String != "On Tue, Bob wrote:\n> Lunch?\n" || s.String != "-- \nAlice\n\n" {
+		t.Errorf("folded parts = %q, %q", q.String, s.String)
+	}
+	if got := f.Store.SearchableBody(body); got != "Sounds good.\n" {
+		t.Errorf("SearchableBody = %q", got)
+	}
+
+	f.Store.SetFoldReplies(false)
+	changed, err = f.Store.ApplyReparse(id, r)
+	testutil.MustNoErr(t, err, "ApplyReparse unfolding")
+	if q, s := parts(); !changed || q.Valid || s.Valid {
+		t.Errorf("after unfolding: changed=%v parts = %q, %q", changed, q.String, s.String)
+	}
+	if got := f.Store.SearchableBody(body); got != body {
+		t.Errorf("SearchableBody without folding = %q", got)
+	}
+}
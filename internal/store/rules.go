@@ -0,0 +1,150 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EnsureLocalLabel returns the ID of the vault-local label name,
+// creating it if needed. Local labels belong to no account (source_id
+// is NULL), so syncs never rename or remove them.
+func (s *Store) EnsureLocalLabel(name string) (int64, error) {
+	var id int64
+	err := s.withTx(func(tx *loggedTx) error {
+		err := tx.QueryRow(`SELECT id FROM labels WHERE source_id IS NULL AND name = ?`, name).Scan(&id)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO labels (source_id, source_label_id, name, label_type)
+			VALUES (NULL, NULL, ?, 'auto')
+		`, name); err != nil {
+			return fmt.Errorf("insert label: %w", err)
+		}
+		return tx.QueryRow(`SELECT id FROM labels WHERE source_id IS NULL AND name = ?`, name).Scan(&id)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ensure local label %q: %w", name, err)
+	}
+	return id, nil
+}
+
+// LabelMessages adds labelID to each message. Messages that already
+// carry the label are left alone. The additions to Gmail messages are
+// queued for push to Gmail.
+func (s *Store) LabelMessages(labelID int64, messageIDs []int64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return s.withTx(func(tx *loggedTx) error {
+		if err := s.queueLabelChanges(tx, labelID, messageIDs, LabelChangeAdd); err != nil {
+			return err
+		}
+		if err := s.recordLabelEvents(tx, labelID, messageIDs, LabelChangeAdd, ""); err != nil {
+			return err
+		}
+		return insertInChunks(tx, chunkInsert{
+			totalRows:    len(messageIDs),
+			valuesPerRow: 2,
+			prefix:       s.dialect.InsertOrIgnorePrefix("INSERT OR IGNORE INTO message_labels (message_id, label_id) VALUES "),
+			suffix:       s.dialect.InsertOrIgnoreSuffix(),
+		}, func(start, end int) ([]string, []interface{}) {
+			values := make([]string, end-start)
+			args := make([]interface{}, 0, (end-start)*2)
+			for i := start; i < end; i++ {
+				values[i-start] = "(?, ?)"
+				args = append(args, messageIDs[i], labelID)
+			}
+			return values, args
+		})
+	})
+}
+
+// CategorizeMessages files each message under the Gmail-style category
+// label CATEGORY_<CATEGORY> of its own account, the label Gmail uses for
+// its inbox tabs.
+func (s *Store) CategorizeMessages(category string, messageIDs []int64) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	bySource := make(map[int64][]int64)
+	err := queryInChunks(s.db, messageIDs, nil,
+		`SELECT id, source_id FROM messages WHERE id IN (%s)`,
+		func(rows *loggedRows) error {
+			var id, sourceID int64
+			if err := rows.Scan(&id, &sourceID); err != nil {
+				return err
+			}
+			bySource[sourceID] = append(bySource[sourceID], id)
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("look up message accounts: %w", err)
+	}
+
+	labelName := "CATEGORY_" + strings.ToUpper(category)
+	for sourceID, ids := range bySource {
+		labelID, err := s.EnsureLabel(sourceID, labelName, labelName, "system")
+		if err != nil {
+			return fmt.Errorf("ensure %s: %w", labelName, err)
+		}
+		if err := s.LabelMessages(labelID, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DropAttachments removes the attachment records of the given messages
+// and marks the messages as having none stored, as sync does when an
+// attachment fails to store. The raw MIME still holds the attachments.
+// It returns the storage paths no remaining attachment references, so
+// the caller can delete those files.
+func (s *Store) DropAttachments(messageIDs []int64) ([]string, error) {
+	if len(messageIDs) == 0 {
+		return nil, nil
+	}
+	pathSet := make(map[string]bool)
+	err := queryInChunks(s.db, messageIDs, nil, `
+		SELECT storage_path FROM attachments
+		WHERE message_id IN (%s) AND storage_path IS NOT NULL AND storage_path != ''
+	`, func(rows *loggedRows) error {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return err
+		}
+		pathSet[p] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list attachments: %w", err)
+	}
+
+	err = s.withTx(func(tx *loggedTx) error {
+		if err := execInChunks(tx, messageIDs, nil,
+			`DELETE FROM attachments WHERE message_id IN (%s)`); err != nil {
+			return fmt.Errorf("delete attachments: %w", err)
+		}
+		return execInChunks(tx, messageIDs, []interface{}{false, 0},
+			`UPDATE messages SET has_attachments = ?, attachment_count = ? WHERE id IN (%s)`)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for p := range pathSet {
+		referenced, err := s.IsAttachmentPathReferenced(p)
+		if err != nil {
+			return nil, fmt.Errorf("check attachment references: %w", err)
+		}
+		if !referenced {
+			orphaned = append(orphaned, p)
+		}
+	}
+	sort.Strings(orphaned)
+	return orphaned, nil
+}
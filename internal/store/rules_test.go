@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_EnsureLocalLabel(t *testing.T) {
+	f := storetest.New(t)
+
+	id, err := f.Store.EnsureLocalLabel("Receipts")
+	testutil.MustNoErr(t, err, "EnsureLocalLabel")
+	again, err := f.Store.EnsureLocalLabel("Receipts")
+	testutil.MustNoErr(t, err, "EnsureLocalLabel again")
+	if again != id {
+		t.Errorf("second call returned %d, want %d", again, id)
+	}
+
+	var sourceID *int64
+	err = f.Store.DB().QueryRow(`SELECT source_id FROM labels WHERE id = ?`, id).Scan(&sourceID)
+	testutil.MustNoErr(t, err, "query label")
+	if sourceID != nil {
+		t.Errorf("source_id = %d, want NULL", *sourceID)
+	}
+}
+
+func TestStore_LabelMessages(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+	labelID, err := f.Store.EnsureLocalLabel("Receipts")
+	testutil.MustNoErr(t, err, "EnsureLocalLabel")
+
+	testutil.MustNoErr(t, f.Store.LabelMessages(labelID, ids[:2]), "LabelMessages")
+	// Labelling again must not fail or duplicate.
+	testutil.MustNoErr(t, f.Store.LabelMessages(labelID, ids), "LabelMessages again")
+
+	for _, id := range ids {
+		f.AssertMessageHasLabel(id, labelID)
+		f.AssertLabelCount(id, 1)
+	}
+}
+
+func TestStore_CategorizeMessages(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(2)
+
+	testutil.MustNoErr(t, f.Store.CategorizeMessages("promotions", ids), "CategorizeMessages")
+
+	var labelID int64
+	err := f.Store.DB().QueryRow(
+		`SELECT id FROM labels WHERE source_id = ? AND name = 'CATEGORY_PROMOTIONS' AND label_type = 'system'`,
+		f.Source.ID).Scan(&labelID)
+	testutil.MustNoErr(t, err, "query category label")
+	for _, id := range ids {
+		f.AssertMessageHasLabel(id, labelID)
+	}
+}
+
+func TestStore_DropAttachments(t *testing.T) {
+	f := storetest.New(t)
+	dropped := f.NewMessage().WithSubject("Invoice").WithSnippet("").WithAttachmentCount(2).Create(t, f.Store)
+	kept := f.NewMessage().WithSubject("Report").WithSnippet("").WithAttachmentCount(1).Create(t, f.Store)
+
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(dropped, "a.pdf", "application/pdf",
+		"aa/onlyhash", "onlyhash", 10), "upsert unique attachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(dropped, "b.pdf", "application/pdf",
+		"bb/sharedhash", "sharedhash", 20), "upsert shared attachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(kept, "b.pdf", "application/pdf",
+		"bb/sharedhash", "sharedhash", 20), "upsert shared attachment in kept message")
+
+	paths, err := f.Store.DropAttachments([]int64{dropped})
+	testutil.MustNoErr(t, err, "DropAttachments")
+	if !slices.Equal(paths, []string{"aa/onlyhash"}) {
+		t.Errorf("unreferenced paths = %v, want [aa/onlyhash]", paths)
+	}
+
+	fields := f.GetMessageFields(dropped)
+	if fields.HasAttachments {
+		t.Errorf("dropped message still has has_attachments set")
+	}
+	var count, remaining int
+	err = f.Store.DB().QueryRow(`SELECT attachment_count FROM messages WHERE id = ?`, dropped).Scan(&count)
+	testutil.MustNoErr(t, err, "query attachment_count")
+	err = f.Store.DB().QueryRow(`SELECT COUNT(*) FROM attachments WHERE message_id = ?`, dropped).Scan(&remaining)
+	testutil.MustNoErr(t, err, "count attachments")
+	if count != 0 || remaining != 0 {
+		t.Errorf("attachment_count = %d, rows = %d; want 0, 0", count, remaining)
+	}
+	if fields := f.GetMessageFields(kept); !fields.HasAttachments {
+		t.Errorf("kept message lost its attachments: %+v", fields)
+	}
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wesm/msgvault/internal/search"
+)
+
+// ErrSavedSearchNotFound is returned when no search is saved under a
+// name.
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a search query saved under a name, for use as
+// saved:name in other queries.
+type SavedSearch struct {
+	Name      string    `json:"name"`
+	Query     string    `json:"query"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SaveSearch saves queryStr under name, replacing any search already
+// saved under it. Names are single words, so saved:name needs no
+// quoting.
+func (s *Store) SaveSearch(name, queryStr string) error {
+	name, queryStr = strings.TrimSpace(name), strings.TrimSpace(queryStr)
+	if name == "" {
+		return fmt.Errorf("saved search name is required")
+	}
+	if strings.ContainsAny(name, " \t\n\"'()") {
+		return fmt.Errorf("invalid saved search name %q: use a single word without quotes or parentheses", name)
+	}
+	if queryStr == "" {
+		return fmt.Errorf("saved search query is required")
+	}
+	// Catch a query that refers back to name now rather than each time
+	// it is used.
+	if _, err := search.ExpandSaved(queryStr, func(ref string) (string, error) {
+		if ref == name {
+			return queryStr, nil
+		}
+		return s.savedSearchQuery(ref)
+	}); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO saved_searches (name, query, created_at, updated_at)
+		VALUES (?, ?, %[1]s, %[1]s)
+		ON CONFLICT(name) DO UPDATE SET
+			query = excluded.query,
+			updated_at = excluded.updated_at
+	`, s.dialect.Now()), name, queryStr)
+	if err != nil {
+		return fmt.Errorf("save search: %w", err)
+	}
+	return nil
+}
+
+// GetSavedSearch returns the search saved under name, or
+// ErrSavedSearchNotFound.
+func (s *Store) GetSavedSearch(name string) (*SavedSearch, error) {
+	var ss SavedSearch
+	err := s.db.QueryRow(`
+		SELECT name, query, created_at, updated_at FROM saved_searches WHERE name = ?
+	`, name).Scan(&ss.Name, &ss.Query, &ss.CreatedAt, &ss.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get saved search: %w", err)
+	}
+	return &ss, nil
+}
+
+// ListSavedSearches returns the saved searches by name.
+func (s *Store) ListSavedSearches() ([]SavedSearch, error) {
+	rows, err := s.db.Query(`
+		SELECT name, query, created_at, updated_at FROM saved_searches ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list saved searches: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		var ss SavedSearch
+		if err := rows.Scan(&ss.Name, &ss.Query, &ss.CreatedAt, &ss.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan saved search: %w", err)
+		}
+		searches = append(searches, ss)
+	}
+	return searches, rows.Err()
+}
+
+// DeleteSavedSearch deletes the search saved under name, or returns
+// ErrSavedSearchNotFound. Queries that use it as saved:name fail until
+// it is saved again.
+func (s *Store) DeleteSavedSearch(name string) error {
+	res, err := s.db.Exec(`DELETE FROM saved_searches WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("delete saved search: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete saved search: %w", err)
+	}
+	if n == 0 {
+		return ErrSavedSearchNotFound
+	}
+	return nil
+}
+
+// ExpandSavedSearches replaces the saved:name terms of queryStr with the
+// searches saved under those names; see search.ExpandSaved.
+func (s *Store) ExpandSavedSearches(queryStr string) (string, error) {
+	return search.ExpandSaved(queryStr, s.savedSearchQuery)
+}
+
+func (s *Store) savedSearchQuery(name string) (string, error) {
+	ss, err := s.GetSavedSearch(name)
+	if errors.Is(err, ErrSavedSearchNotFound) {
+		return "", fmt.Errorf("no search saved as %q", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return ss.Query, nil
+}
@@ -0,0 +1,58 @@
+package store_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+)
+
+func TestStore_SavedSearches(t *testing.T) {
+	st := testutil.NewTestStore(t)
+
+	testutil.MustNoErr(t, st.SaveSearch("receipts", "from:shop.example.com"), "SaveSearch receipts")
+	testutil.MustNoErr(t, st.SaveSearch("team", " from:alice OR from:bob "), "SaveSearch team")
+	testutil.MustNoErr(t, st.SaveSearch("receipts", "from:shop.example.com has:attachment"), "SaveSearch replace")
+
+	list, err := st.ListSavedSearches()
+	testutil.MustNoErr(t, err, "ListSavedSearches")
+	if len(list) != 2 || list[0].Name != "receipts" || list[0].Query != "from:shop.example.com has:attachment" ||
+		list[1].Query != "from:alice OR from:bob" {
+		t.Fatalf("ListSavedSearches = %+v", list)
+	}
+
+	got, err := st.ExpandSavedSearches("saved:receipts -saved:team")
+	testutil.MustNoErr(t, err, "ExpandSavedSearches")
+	if want := "( from:shop.example.com has:attachment ) - ( from:alice OR from:bob )"; got != want {
+		t.Errorf("ExpandSavedSearches = %q, want %q", got, want)
+	}
+	if _, err := st.ExpandSavedSearches("saved:nope"); err == nil || !strings.Contains(err.Error(), `"nope"`) {
+		t.Errorf("ExpandSavedSearches(saved:nope) error = %v", err)
+	}
+
+	for _, tc := range []struct{ name, query string }{
+		{"", "from:alice"},
+		{"two words", "from:alice"},
+		{"empty", "  "},
+		{"self", "invoice saved:self"},
+		{"team", "saved:receipts OR saved:loop"}, // loop is not saved yet
+	} {
+		if err := st.SaveSearch(tc.name, tc.query); err == nil {
+			t.Errorf("SaveSearch(%q, %q) succeeded, want an error", tc.name, tc.query)
+		}
+	}
+	testutil.MustNoErr(t, st.SaveSearch("loop", "saved:team"), "SaveSearch loop")
+	if err := st.SaveSearch("team", "saved:loop"); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+		t.Errorf("SaveSearch cycle error = %v, want refers to itself", err)
+	}
+
+	testutil.MustNoErr(t, st.DeleteSavedSearch("receipts"), "DeleteSavedSearch")
+	if _, err := st.GetSavedSearch("receipts"); !errors.Is(err, store.ErrSavedSearchNotFound) {
+		t.Errorf("GetSavedSearch after delete = %v, want ErrSavedSearchNotFound", err)
+	}
+	if err := st.DeleteSavedSearch("receipts"); !errors.Is(err, store.ErrSavedSearchNotFound) {
+		t.Errorf("DeleteSavedSearch twice = %v, want ErrSavedSearchNotFound", err)
+	}
+}
@@ -21,6 +21,11 @@ CREATE TABLE IF NOT EXISTS sources (
     sync_config JSON,           -- platform-specific sync settings
     oauth_app TEXT,             -- named OAuth app binding (NULL = default)
 
+    -- Message count the provider last reported for the account (Gmail's
+    -- messagesTotal), for archive completeness
+    remote_message_total INTEGER,
+    remote_total_at DATETIME,
+
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 
@@ -82,6 +87,10 @@ CREATE TABLE IF NOT EXISTS conversations (
     -- Platform-specific metadata
     metadata JSON,
 
+    -- Set when the user (or 'threads repair') merged this conversation
+    -- into another; messages that would join it join that one instead.
+    merged_into INTEGER,
+
     created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 
@@ -146,6 +155,9 @@ CREATE TABLE IF NOT EXISTS messages (
     has_attachments BOOLEAN DEFAULT FALSE,
     attachment_count INTEGER DEFAULT 0,
 
+    -- ISO 639-1 code of the body's language, detected at ingest; NULL if unknown
+    language TEXT,
+
     -- Soft delete tracking
     deleted_at DATETIME,
     deleted_from_source_at DATETIME,
@@ -158,6 +170,10 @@ CREATE TABLE IF NOT EXISTS messages (
     -- Platform-specific metadata
     metadata JSON,
 
+    -- Synced without its body ('sync-full --metadata-only'): headers,
+    -- labels and size only, until 'msgvault hydrate' fetches the rest
+    metadata_only BOOLEAN DEFAULT FALSE,
+
     UNIQUE(source_id, source_message_id)
 );
 
@@ -208,6 +224,7 @@ CREATE TABLE IF NOT EXISTS attachments (
     filename TEXT,
     mime_type TEXT,
     size INTEGER,
+    stored_size INTEGER,            -- bytes on disk; less than size when compressed, NULL if unrecorded
 
     -- Content-addressed storage (deduplication)
     content_hash TEXT,              -- SHA-256 of content
@@ -230,7 +247,24 @@ CREATE TABLE IF NOT EXISTS attachments (
     -- Encryption
     encryption_version INTEGER DEFAULT 0,
 
-    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+    -- Virus scan ([virus_scan] in config.toml)
+    scan_result TEXT,               -- 'clean', 'infected', 'error'; NULL if not scanned
+    scan_signature TEXT,            -- what the scanner found, or why it failed
+    scanned_at DATETIME,
+    quarantined INTEGER DEFAULT 0   -- infected and blocked from export
+);
+
+-- Text extracted from stored attachment files (PDF, DOCX, plain text)
+-- for in:attachments searches, once per file however many attachments
+-- share it. A file with no text to extract has an empty row, so it is
+-- not read again; 'msgvault index-attachments --reindex' rereads all.
+CREATE TABLE IF NOT EXISTS attachment_texts (
+    id INTEGER PRIMARY KEY,
+    storage_path TEXT NOT NULL UNIQUE,  -- attachments.storage_path
+    content TEXT NOT NULL,
+    extracted_at DATETIME NOT NULL
 );
 
 -- ============================================================================
@@ -245,7 +279,9 @@ CREATE TABLE IF NOT EXISTS labels (
     source_label_id TEXT,           -- Gmail label ID
     name TEXT NOT NULL,
     label_type TEXT,                -- 'system', 'user', 'auto'
-    color TEXT,
+    color TEXT,                     -- background color, '#rrggbb'
+    text_color TEXT,                -- '#rrggbb'
+    visibility TEXT,                -- Gmail labelListVisibility: 'labelShow', 'labelShowIfUnread', 'labelHide'
 
     UNIQUE(source_id, name)
 );
@@ -258,6 +294,35 @@ CREATE TABLE IF NOT EXISTS message_labels (
     PRIMARY KEY (message_id, label_id)
 );
 
+-- Label edits made in the archive to Gmail messages, waiting for
+-- 'msgvault labels push' to apply them to Gmail. base_history_id is the
+-- account's history ID when the edit was made; Gmail changes to the
+-- message after it are a conflict.
+CREATE TABLE IF NOT EXISTS pending_label_changes (
+    id INTEGER PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,           -- 'add', 'remove'
+    base_history_id TEXT,
+    queued_at DATETIME NOT NULL,
+
+    UNIQUE (message_id, label_id)
+);
+
+-- Label changes of messages as they happened: each Gmail history record
+-- that adds or removes a label, and each edit made in the archive. A
+-- message's labels at any time follow from these and its current ones.
+-- occurred_at is when the change was seen; history_id is the Gmail
+-- history record's, NULL for edits made in the archive.
+CREATE TABLE IF NOT EXISTS label_events (
+    id INTEGER PRIMARY KEY,
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    label_id INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+    action TEXT NOT NULL,           -- 'add', 'remove'
+    history_id TEXT,
+    occurred_at DATETIME NOT NULL
+);
+
 -- ============================================================================
 -- RAW DATA STORAGE
 -- ============================================================================
@@ -266,7 +331,126 @@ CREATE TABLE IF NOT EXISTS message_labels (
 CREATE TABLE IF NOT EXISTS message_bodies (
     message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
     body_text TEXT,
-    body_html TEXT
+    body_html TEXT,
+    -- With reply folding on, the trailing signature and quoted reply
+    -- trail of body_text. body_text still holds the full text; the
+    -- author's own part is the prefix before these.
+    body_quoted TEXT,
+    body_signature TEXT
+);
+
+-- Provenance of each email: this archive's own verification of its
+-- DKIM signatures at ingest, and the results the receiving server
+-- recorded in the topmost Authentication-Results header.
+CREATE TABLE IF NOT EXISTS message_auth (
+    message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    dkim_result TEXT,       -- 'pass', 'fail', 'none', 'temperror', 'permerror'; NULL if not verified
+    dkim_domain TEXT,       -- d= of the passing signature, else of the first
+    dkim_reason TEXT,       -- why the result is not pass or none
+    authserv_id TEXT,       -- server that wrote Authentication-Results
+    reported_dkim TEXT,
+    reported_spf TEXT,      -- falls back to Received-SPF
+    reported_dmarc TEXT,
+    auth_results TEXT,      -- the raw header value
+    checked_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_auth_dkim ON message_auth(dkim_result);
+
+-- Outbound links and tracking pixels found in each email's HTML body.
+CREATE TABLE IF NOT EXISTS message_links (
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,     -- 'link' (an <a href>) or 'tracker' (an open-tracking image)
+    url TEXT NOT NULL,
+    domain TEXT NOT NULL,   -- lowercased host without "www."
+    PRIMARY KEY (message_id, kind, url)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_links_domain ON message_links(kind, domain);
+
+-- Images embedded in each email's HTML body by Content-ID (cid: URLs).
+-- They are kept apart from attachments, and not counted as such; their
+-- content stays in message_raw.
+CREATE TABLE IF NOT EXISTS message_inline_parts (
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    content_id TEXT NOT NULL,   -- without the angle brackets
+    filename TEXT,
+    mime_type TEXT,
+    size INTEGER,
+    content_hash TEXT,          -- SHA-256 of content
+    PRIMARY KEY (message_id, content_id)
+);
+
+-- Spam and phishing risk of each email, as scored by 'msgvault risk
+-- scan'. Messages not scanned yet have no row.
+CREATE TABLE IF NOT EXISTS message_risk (
+    message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    score INTEGER NOT NULL,
+    reasons TEXT,           -- JSON array of {rule, points, detail}
+    scored_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_risk_score ON message_risk(score);
+
+-- Local notes and pins on messages. Like message_tags, they live only in
+-- this archive and are never synced back to the source.
+CREATE TABLE IF NOT EXISTS message_notes (
+    message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    note TEXT,
+    pinned INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_notes_pinned ON message_notes(pinned);
+
+-- Header fields of each email indexed for search, such as List-Id for
+-- list: and Delivered-To for deliveredto:. Names are lowercased; values
+-- are normalized as mime.IndexedHeaders describes.
+CREATE TABLE IF NOT EXISTS message_headers (
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    PRIMARY KEY (message_id, name, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_headers_name_value ON message_headers(name, value);
+
+-- Local tags on messages, kept apart from the source's labels.
+CREATE TABLE IF NOT EXISTS message_tags (
+    message_id INTEGER NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,      -- lowercased
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (message_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_tags_tag ON message_tags(tag);
+
+-- Local reminders to look at a message again. Each message has at most
+-- one; notified_at records when the daemon sent its notification.
+CREATE TABLE IF NOT EXISTS message_reminders (
+    message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    due_at DATETIME NOT NULL,
+    note TEXT,
+    created_at DATETIME NOT NULL,
+    notified_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_reminders_due ON message_reminders(due_at);
+
+-- How far the user has triaged the archive in the TUI: every message
+-- with an ID up to watermark has been looked at. One row.
+CREATE TABLE IF NOT EXISTS triage_state (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    watermark INTEGER NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+-- Messages the user moved to another conversation. A resync keeps
+-- them there instead of rethreading them by the source's thread ID.
+CREATE TABLE IF NOT EXISTS thread_overrides (
+    message_id INTEGER PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+    conversation_id INTEGER NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    created_at DATETIME NOT NULL
 );
 
 -- Original message data (for re-parsing/export)
@@ -277,7 +461,24 @@ CREATE TABLE IF NOT EXISTS message_raw (
     raw_format TEXT NOT NULL,       -- 'mime', 'imessage_archive', 'whatsapp_json', 'rcs_json'
 
     compression TEXT DEFAULT 'zlib',
-    encryption_version INTEGER DEFAULT 0
+    encryption_version INTEGER DEFAULT 0,
+
+    -- Set when the data is kept in a content-addressed blob file rather
+    -- than in raw_data, which is then empty: the SHA-256 of the file's
+    -- (compressed) content, and its size.
+    blob_hash TEXT,
+    blob_size INTEGER
+);
+
+-- zstd dictionaries trained on message headers. message_raw rows
+-- compressed with one name it by dict_id in their zstd frame header;
+-- new rows are compressed with the newest.
+CREATE TABLE IF NOT EXISTS raw_dictionaries (
+    id INTEGER PRIMARY KEY,
+    dict_id INTEGER NOT NULL UNIQUE,   -- derived from the dictionary's content
+    data BLOB NOT NULL,
+    sample_count INTEGER NOT NULL,
+    created_at DATETIME NOT NULL
 );
 
 -- ============================================================================
@@ -314,6 +515,42 @@ CREATE TABLE IF NOT EXISTS sync_checkpoints (
     PRIMARY KEY (source_id, checkpoint_type)
 );
 
+-- Messages a sync could not fetch or store. Later syncs retry them
+-- until they succeed or run out of attempts.
+CREATE TABLE IF NOT EXISTS sync_failures (
+    id INTEGER PRIMARY KEY,
+    source_id INTEGER NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    source_message_id TEXT NOT NULL,
+    thread_id TEXT,
+
+    error_class TEXT NOT NULL,      -- 'fetch', 'parse', 'store'
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 1,
+
+    first_failed_at DATETIME NOT NULL,
+    last_failed_at DATETIME NOT NULL,
+
+    UNIQUE (source_id, source_message_id)
+);
+
+-- Google account storage quota, sampled by syncs with [sync]
+-- storage_quota on, one sample per day. archive_bytes is the size of the
+-- account's messages in the archive when the sample was taken.
+CREATE TABLE IF NOT EXISTS storage_quota_history (
+    id INTEGER PRIMARY KEY,
+    source_id INTEGER NOT NULL REFERENCES sources(id) ON DELETE CASCADE,
+    day TEXT NOT NULL,              -- UTC date, YYYY-MM-DD
+    recorded_at DATETIME NOT NULL,
+
+    limit_bytes INTEGER NOT NULL DEFAULT 0,  -- 0 for unlimited
+    usage_bytes INTEGER NOT NULL,
+    drive_bytes INTEGER NOT NULL,
+    drive_trash_bytes INTEGER NOT NULL,
+    archive_bytes INTEGER NOT NULL,
+
+    UNIQUE (source_id, day)
+);
+
 -- ============================================================================
 -- INDEXES
 -- ============================================================================
@@ -362,6 +599,8 @@ CREATE INDEX IF NOT EXISTS idx_attachments_storage_path ON attachments(storage_p
 -- Labels
 CREATE INDEX IF NOT EXISTS idx_labels_source ON labels(source_id);
 CREATE INDEX IF NOT EXISTS idx_message_labels_label ON message_labels(label_id);
+CREATE INDEX IF NOT EXISTS idx_label_events_message ON label_events(message_id, label_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_label_events_label ON label_events(label_id);
 
 -- Sync
 CREATE INDEX IF NOT EXISTS idx_sync_runs_source ON sync_runs(source_id, started_at DESC);
@@ -418,3 +657,33 @@ CREATE TABLE IF NOT EXISTS applied_migrations (
     name        TEXT PRIMARY KEY,
     applied_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
+
+-- ============================================================================
+-- API TOKENS
+-- ============================================================================
+
+-- Tokens issued with `msgvault token create` for the REST, gRPC, JMAP,
+-- and IMAP servers. Only the SHA-256 of each token is kept; the token
+-- itself is shown once at creation.
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           INTEGER PRIMARY KEY,
+    name         TEXT NOT NULL UNIQUE,
+    token_hash   TEXT NOT NULL UNIQUE,   -- hex SHA-256 of the token
+    scopes       TEXT NOT NULL,          -- comma-separated: read, search, admin
+    created_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    revoked_at   DATETIME
+);
+
+-- ============================================================================
+-- SAVED SEARCHES
+-- ============================================================================
+
+-- Named search queries saved with `msgvault search save`. A query may
+-- refer to another saved search as saved:name.
+CREATE TABLE IF NOT EXISTS saved_searches (
+    name       TEXT PRIMARY KEY,
+    query      TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -5,3 +5,8 @@
 -- directly on the messages table. Updates are managed via Store.UpsertFTS().
 ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_fts TSVECTOR;
 CREATE INDEX IF NOT EXISTS messages_search_fts_idx ON messages USING GIN (search_fts);
+
+-- Full-text index on attachment text for in:attachments searches; the
+-- expression matches Dialect.AttachmentFTSMatchCondition.
+CREATE INDEX IF NOT EXISTS attachment_texts_fts_idx ON attachment_texts
+    USING GIN (to_tsvector('simple', content));
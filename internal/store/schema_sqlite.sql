@@ -12,3 +12,13 @@ CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
     cc_addr,
     tokenize='unicode61 remove_diacritics 1'
 );
+
+-- Full-text search index for attachment text, by attachment_texts.id.
+-- Contentless: the text itself is kept once, in attachment_texts.
+-- Updates are managed via Store.SetAttachmentText().
+CREATE VIRTUAL TABLE IF NOT EXISTS attachments_fts USING fts5(
+    content,
+    content='',
+    contentless_delete=1,
+    tokenize='unicode61 remove_diacritics 1'
+);
@@ -219,3 +219,37 @@ func (s *Store) removeSourceExec(tx *loggedTx, sourceID int64) error {
 	}
 	return nil
 }
+
+// MessageSourceID returns the source that owns a message. found is
+// false when no message row matches; server-mode access checks use it
+// to decide whether a scoped user may read the message.
+func (s *Store) MessageSourceID(messageID int64) (sourceID int64, found bool, err error) {
+	err = s.db.QueryRow(
+		`SELECT source_id FROM messages WHERE id = ?`, messageID,
+	).Scan(&sourceID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("get message source: %w", err)
+	}
+	return sourceID, true, nil
+}
+
+// AttachmentSourceID returns the source that owns an attachment's
+// message. found is false when no attachment row matches.
+func (s *Store) AttachmentSourceID(attachmentID int64) (sourceID int64, found bool, err error) {
+	err = s.db.QueryRow(`
+		SELECT m.source_id
+		FROM attachments a
+		JOIN messages m ON m.id = a.message_id
+		WHERE a.id = ?
+	`, attachmentID).Scan(&sourceID)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("get attachment source: %w", err)
+	}
+	return sourceID, true, nil
+}
@@ -529,3 +529,41 @@ func TestInitSchema_AddsDeletedAtToLegacyMessagesTable(t *testing.T) {
 		t.Fatalf("post-migration delete_batch_id query: %v", err)
 	}
 }
+
+func TestStore_MessageAndAttachmentSourceID(t *testing.T) {
+	f := storetest.New(t)
+
+	msgID := f.CreateMessage("msg-owned")
+	err := f.Store.UpsertAttachment(msgID, "a.pdf", "application/pdf",
+		"aa/ownedhash", "ownedhash", 10)
+	testutil.MustNoErr(t, err, "upsert attachment")
+
+	var attID int64
+	err = f.Store.DB().QueryRow(
+		`SELECT id FROM attachments WHERE message_id = ?`, msgID,
+	).Scan(&attID)
+	testutil.MustNoErr(t, err, "lookup attachment id")
+
+	srcID, found, err := f.Store.MessageSourceID(msgID)
+	testutil.MustNoErr(t, err, "MessageSourceID")
+	if !found || srcID != f.Source.ID {
+		t.Errorf("MessageSourceID = (%d, %v), want (%d, true)", srcID, found, f.Source.ID)
+	}
+
+	srcID, found, err = f.Store.AttachmentSourceID(attID)
+	testutil.MustNoErr(t, err, "AttachmentSourceID")
+	if !found || srcID != f.Source.ID {
+		t.Errorf("AttachmentSourceID = (%d, %v), want (%d, true)", srcID, found, f.Source.ID)
+	}
+
+	_, found, err = f.Store.MessageSourceID(999999)
+	testutil.MustNoErr(t, err, "MessageSourceID missing")
+	if found {
+		t.Error("MessageSourceID found a nonexistent message")
+	}
+	_, found, err = f.Store.AttachmentSourceID(999999)
+	testutil.MustNoErr(t, err, "AttachmentSourceID missing")
+	if found {
+		t.Error("AttachmentSourceID found a nonexistent attachment")
+	}
+}
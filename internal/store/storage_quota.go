@@ -0,0 +1,88 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StorageQuotaSample is one day's record of a Google account's storage
+// quota next to the size of its archive.
+type StorageQuotaSample struct {
+	Day             string    `json:"day"` // UTC date, YYYY-MM-DD
+	RecordedAt      time.Time `json:"recorded_at"`
+	LimitBytes      int64     `json:"limit_bytes"` // 0 for unlimited
+	UsageBytes      int64     `json:"usage_bytes"`
+	DriveBytes      int64     `json:"drive_bytes"`
+	DriveTrashBytes int64     `json:"drive_trash_bytes"`
+	ArchiveBytes    int64     `json:"archive_bytes"`
+}
+
+// OutsideDriveBytes returns the storage the account uses outside Drive,
+// which is Gmail and Google Photos: Google reports no Gmail figure of
+// its own.
+func (q StorageQuotaSample) OutsideDriveBytes() int64 {
+	return max(q.UsageBytes-q.DriveBytes, 0)
+}
+
+// RecordStorageQuota records the storage quota a source's Google
+// account reports, with the size of the source's archived messages.
+// A source keeps one sample a day; recording again the same day
+// replaces it. Messages deleted from the source still count towards
+// the archive, as freeing quota by deleting them is what the history
+// shows.
+func (s *Store) RecordStorageQuota(sourceID, limit, usage, drive, driveTrash int64) error {
+	var archive sql.NullInt64
+	err := s.db.QueryRow(`
+		SELECT SUM(m.size_estimate) FROM messages m
+		WHERE m.source_id = ? AND `+LiveMessagesWhere("m", false),
+		sourceID).Scan(&archive)
+	if err != nil {
+		return fmt.Errorf("measure archive size: %w", err)
+	}
+	_, err = s.db.Exec(fmt.Sprintf(`
+		INSERT INTO storage_quota_history
+			(source_id, day, recorded_at, limit_bytes, usage_bytes, drive_bytes, drive_trash_bytes, archive_bytes)
+		VALUES (?, ?, %s, ?, ?, ?, ?, ?)
+		ON CONFLICT(source_id, day) DO UPDATE SET
+			recorded_at = excluded.recorded_at,
+			limit_bytes = excluded.limit_bytes,
+			usage_bytes = excluded.usage_bytes,
+			drive_bytes = excluded.drive_bytes,
+			drive_trash_bytes = excluded.drive_trash_bytes,
+			archive_bytes = excluded.archive_bytes
+	`, s.dialect.Now()), sourceID, time.Now().UTC().Format("2006-01-02"),
+		limit, usage, drive, driveTrash, archive.Int64)
+	if err != nil {
+		return fmt.Errorf("record storage quota: %w", err)
+	}
+	return nil
+}
+
+// StorageQuotaHistory returns the storage quota samples of a source,
+// oldest first.
+func (s *Store) StorageQuotaHistory(sourceID int64) ([]StorageQuotaSample, error) {
+	rows, err := s.db.Query(`
+		SELECT day, recorded_at, limit_bytes, usage_bytes, drive_bytes, drive_trash_bytes, archive_bytes
+		FROM storage_quota_history
+		WHERE source_id = ?
+		ORDER BY day
+	`, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("list storage quota history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []StorageQuotaSample
+	for rows.Next() {
+		var q StorageQuotaSample
+		var recorded sql.NullString
+		if err := rows.Scan(&q.Day, &recorded, &q.LimitBytes, &q.UsageBytes,
+			&q.DriveBytes, &q.DriveTrashBytes, &q.ArchiveBytes); err != nil {
+			return nil, fmt.Errorf("scan storage quota sample: %w", err)
+		}
+		q.RecordedAt = parseSQLiteTime(recorded.String)
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,48 @@
+package store_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_StorageQuotaHistory(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+
+	// A message deleted from Gmail still counts towards the archive;
+	// a dedup loser does not.
+	testutil.MustNoErr(t, f.Store.MarkMessageDeleted(f.Source.ID, "msg-1"), "MarkMessageDeleted")
+	_, err := f.Store.DB().Exec(`UPDATE messages SET deleted_at = CURRENT_TIMESTAMP WHERE id = ?`, ids[2])
+	testutil.MustNoErr(t, err, "hide dedup loser")
+	_, err = f.Store.DB().Exec(`
+		INSERT INTO storage_quota_history
+			(source_id, day, recorded_at, limit_bytes, usage_bytes, drive_bytes, drive_trash_bytes, archive_bytes)
+		VALUES (?, '2020-01-01', '2020-01-01 10:00:00', 100, 90, 10, 0, 0)
+	`, f.Source.ID)
+	testutil.MustNoErr(t, err, "insert old sample")
+
+	testutil.MustNoErr(t, f.Store.RecordStorageQuota(f.Source.ID, 100, 80, 30, 5), "RecordStorageQuota")
+	testutil.MustNoErr(t, f.Store.RecordStorageQuota(f.Source.ID, 100, 70, 30, 5), "RecordStorageQuota again")
+
+	samples, err := f.Store.StorageQuotaHistory(f.Source.ID)
+	testutil.MustNoErr(t, err, "StorageQuotaHistory")
+	if len(samples) != 2 {
+		t.Fatalf("got %d samples, want 2: %+v", len(samples), samples)
+	}
+	if samples[0].Day != "2020-01-01" || samples[0].OutsideDriveBytes() != 80 {
+		t.Errorf("old sample = %+v", samples[0])
+	}
+	q := samples[1]
+	if q.Day != time.Now().UTC().Format("2006-01-02") || q.RecordedAt.IsZero() {
+		t.Errorf("today's sample is dated %q, recorded at %v", q.Day, q.RecordedAt)
+	}
+	if q.LimitBytes != 100 || q.UsageBytes != 70 || q.DriveTrashBytes != 5 || q.OutsideDriveBytes() != 40 {
+		t.Errorf("today's sample = %+v, want the second recording", q)
+	}
+	if q.ArchiveBytes != 2000 {
+		t.Errorf("ArchiveBytes = %d, want 2000", q.ArchiveBytes)
+	}
+}
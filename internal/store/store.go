@@ -16,6 +16,8 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/mattn/go-sqlite3"
+
+	"github.com/wesm/msgvault/internal/mailauth"
 )
 
 //go:embed schema.sql schema_sqlite.sql schema_pg.sql
@@ -32,8 +34,13 @@ type Store struct {
 	db            *loggedDB
 	dbPath        string
 	dialect       Dialect
-	readOnly      bool // Opened via OpenReadOnly; skips WAL checkpoint on close
-	fts5Available bool // Whether FTS5 is available for full-text search
+	readOnly      bool               // Opened via OpenReadOnly; skips WAL checkpoint on close
+	fts5Available bool               // Whether FTS5 is available for full-text search
+	foldReplies   bool               // Split quoted trails and signatures off stored bodies
+	dkim          *mailauth.Verifier // Verifies DKIM on ingest; nil skips it
+	raw           rawCodec           // Codec new raw message data is compressed with
+	rawBlobs      bool               // Write new raw message data to blob files
+	extraHeaders  []string           // Headers indexed besides DefaultIndexedHeaders
 	closeCleanup  func()
 }
 
@@ -310,6 +317,13 @@ func (s *Store) DB() *sql.DB {
 	return s.db.DB
 }
 
+// Ping checks that the database answers a query against the schema,
+// which unlike sql.DB.Ping touches the database file itself.
+func (s *Store) Ping(ctx context.Context) error {
+	var n int64
+	return s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sources").Scan(&n)
+}
+
 // WithExclusiveLock executes fn while holding an exclusive write lock on the
 // database. In WAL mode this blocks concurrent writers (e.g. StartSync) while
 // allowing reads (e.g. IsAttachmentPathReferenced) to proceed. Use this to
@@ -536,7 +550,7 @@ func (s *Store) SchemaStale() (bool, string, error) {
 		return false, "", fmt.Errorf("check schema version: %w", err)
 	}
 	if count == 0 {
-		return true, "conversations.conversation_type", nil
+		return true, "attachments.stored_size", nil
 	}
 	return false, "", nil
 }
@@ -574,6 +588,22 @@ func (s *Store) InitSchema() error {
 		{`ALTER TABLE messages ADD COLUMN delete_batch_id TEXT`, "delete_batch_id"},
 		{`ALTER TABLE conversations ADD COLUMN title TEXT`, "title"},
 		{`ALTER TABLE conversations ADD COLUMN conversation_type TEXT NOT NULL DEFAULT 'email_thread'`, "conversation_type"},
+		{`ALTER TABLE message_bodies ADD COLUMN body_quoted TEXT`, "body_quoted"},
+		{`ALTER TABLE message_bodies ADD COLUMN body_signature TEXT`, "body_signature"},
+		{`ALTER TABLE messages ADD COLUMN language TEXT`, "language"},
+		{`ALTER TABLE attachments ADD COLUMN scan_result TEXT`, "scan_result"},
+		{`ALTER TABLE attachments ADD COLUMN scan_signature TEXT`, "scan_signature"},
+		{`ALTER TABLE attachments ADD COLUMN scanned_at DATETIME`, "scanned_at"},
+		{`ALTER TABLE attachments ADD COLUMN quarantined INTEGER DEFAULT 0`, "quarantined"},
+		{`ALTER TABLE labels ADD COLUMN text_color TEXT`, "text_color"},
+		{`ALTER TABLE labels ADD COLUMN visibility TEXT`, "visibility"},
+		{`ALTER TABLE conversations ADD COLUMN merged_into INTEGER`, "merged_into"},
+		{`ALTER TABLE sources ADD COLUMN remote_message_total INTEGER`, "remote_message_total"},
+		{`ALTER TABLE sources ADD COLUMN remote_total_at DATETIME`, "remote_total_at"},
+		{`ALTER TABLE messages ADD COLUMN metadata_only BOOLEAN DEFAULT FALSE`, "metadata_only"},
+		{`ALTER TABLE attachments ADD COLUMN stored_size INTEGER`, "stored_size"},
+		{`ALTER TABLE message_raw ADD COLUMN blob_hash TEXT`, "blob_hash"},
+		{`ALTER TABLE message_raw ADD COLUMN blob_size INTEGER`, "blob_size"},
 	} {
 		if _, err := s.db.Exec(m.sql); err != nil {
 			if !s.dialect.IsDuplicateColumnError(err) {
@@ -626,6 +656,39 @@ type Stats struct {
 	LabelCount      int64
 	SourceCount     int64
 	DatabaseSize    int64
+
+	// Attachments are stored once per content hash, so
+	// UniqueAttachmentCount files of UniqueAttachmentBytes back
+	// AttachmentCount attachments of AttachmentBytes. Compressed, the
+	// files take UniqueAttachmentStoredBytes on disk.
+	AttachmentBytes             int64
+	UniqueAttachmentCount       int64
+	UniqueAttachmentBytes       int64
+	UniqueAttachmentStoredBytes int64
+
+	// RawMessageCount messages keep their raw MIME: RawLogicalBytes as
+	// received, RawStoredBytes after compression.
+	RawMessageCount int64
+	RawLogicalBytes int64
+	RawStoredBytes  int64
+
+	// FTSIndexedCount is how many of the MessageCount messages are in the
+	// full-text index; zero when full-text search is unavailable.
+	FTSIndexedCount int64
+}
+
+// DuplicateAttachmentCount is how many attachments share their content
+// with another attachment, and so take no extra space.
+func (st *Stats) DuplicateAttachmentCount() int64 {
+	return st.AttachmentCount - st.UniqueAttachmentCount
+}
+
+// FTSCoverage is the fraction of messages in the full-text index.
+func (st *Stats) FTSCoverage() float64 {
+	if st.MessageCount == 0 {
+		return 1
+	}
+	return float64(st.FTSIndexedCount) / float64(st.MessageCount)
 }
 
 // GetStats returns statistics about the database.
@@ -762,6 +825,10 @@ func (s *Store) GetStatsForScope(sourceIDs []int64) (*Stats, error) {
 		}
 	}
 
+	if err := s.getStorageStats(stats, sourceIDs); err != nil {
+		return nil, err
+	}
+
 	// DatabaseSize is always the global file size; scoped stats cannot decompose it.
 	if info, err := os.Stat(s.dbPath); err == nil {
 		stats.DatabaseSize = info.Size()
@@ -769,3 +836,141 @@ func (s *Store) GetStatsForScope(sourceIDs []int64) (*Stats, error) {
 
 	return stats, nil
 }
+
+// getStorageStats fills in the attachment, raw MIME, and full-text
+// index figures of stats for the live messages of sourceIDs, or of all
+// sources when sourceIDs is empty.
+func (s *Store) getStorageStats(stats *Stats, sourceIDs []int64) error {
+	where := LiveMessagesWhere("m", true)
+	var args []any
+	if len(sourceIDs) > 0 {
+		where += " AND m.source_id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(sourceIDs)), ",") + ")"
+		for _, id := range sourceIDs {
+			args = append(args, id)
+		}
+	}
+
+	queries := []struct {
+		query string
+		dest  []any
+	}{
+		{
+			// Attachments without a content hash are counted as unique.
+			`SELECT COUNT(*), COALESCE(SUM(sz), 0), COALESCE(SUM(total), 0), COALESCE(SUM(stored), 0) FROM (
+				SELECT COALESCE(MAX(a.size), 0) AS sz, COALESCE(SUM(a.size), 0) AS total,
+					COALESCE(MAX(COALESCE(a.stored_size, a.size)), 0) AS stored
+				FROM attachments a
+				WHERE EXISTS (SELECT 1 FROM messages m WHERE m.id = a.message_id AND ` + where + `)
+				GROUP BY COALESCE(a.content_hash, a.storage_path)
+			) u`,
+			[]any{&stats.UniqueAttachmentCount, &stats.UniqueAttachmentBytes, &stats.AttachmentBytes,
+				&stats.UniqueAttachmentStoredBytes},
+		},
+		{
+			`SELECT COUNT(*), COALESCE(SUM(m.size_estimate), 0), COALESCE(SUM(COALESCE(r.blob_size, LENGTH(r.raw_data))), 0)
+			FROM message_raw r
+			JOIN messages m ON m.id = r.message_id
+			WHERE ` + where,
+			[]any{&stats.RawMessageCount, &stats.RawLogicalBytes, &stats.RawStoredBytes},
+		},
+	}
+	if s.fts5Available {
+		queries = append(queries, struct {
+			query string
+			dest  []any
+		}{
+			"SELECT COUNT(*) FROM messages m WHERE " + where + " AND " + s.dialect.FTSIndexedCondition("m"),
+			[]any{&stats.FTSIndexedCount},
+		})
+	}
+
+	for _, q := range queries {
+		if err := s.db.QueryRow(q.query, args...).Scan(q.dest...); err != nil {
+			if s.dialect.IsNoSuchTableError(err) {
+				continue
+			}
+			return fmt.Errorf("get storage stats: %w", err)
+		}
+	}
+	return nil
+}
+
+// SourceStats holds live message and attachment totals for one source.
+type SourceStats struct {
+	MessageCount    int64
+	AttachmentCount int64
+	AttachmentBytes int64
+	RawStoredBytes  int64 // raw MIME as stored, after compression
+}
+
+// GetSourceStats returns live message and attachment totals keyed by
+// source ID. Sources with no live messages are absent from the map.
+func (s *Store) GetSourceStats() (map[int64]*SourceStats, error) {
+	out := map[int64]*SourceStats{}
+	get := func(id int64) *SourceStats {
+		if out[id] == nil {
+			out[id] = &SourceStats{}
+		}
+		return out[id]
+	}
+
+	rows, err := s.db.Query(`
+		SELECT source_id, COUNT(*) FROM messages
+		WHERE ` + LiveMessagesWhere("", true) + `
+		GROUP BY source_id`)
+	if err != nil {
+		return nil, fmt.Errorf("count messages by source: %w", err)
+	}
+	for rows.Next() {
+		var id, n int64
+		if err := rows.Scan(&id, &n); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan message counts: %w", err)
+		}
+		get(id).MessageCount = n
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("count messages by source: %w", err)
+	}
+
+	rows, err = s.db.Query(`
+		SELECT m.source_id, COUNT(*), COALESCE(SUM(a.size), 0)
+		FROM attachments a
+		JOIN messages m ON m.id = a.message_id
+		WHERE ` + LiveMessagesWhere("m", true) + `
+		GROUP BY m.source_id`)
+	if err != nil {
+		return nil, fmt.Errorf("count attachments by source: %w", err)
+	}
+	for rows.Next() {
+		var id, n, size int64
+		if err := rows.Scan(&id, &n, &size); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan attachment counts: %w", err)
+		}
+		st := get(id)
+		st.AttachmentCount, st.AttachmentBytes = n, size
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("count attachments by source: %w", err)
+	}
+
+	rows, err = s.db.Query(`
+		SELECT m.source_id, COALESCE(SUM(COALESCE(r.blob_size, LENGTH(r.raw_data))), 0)
+		FROM message_raw r
+		JOIN messages m ON m.id = r.message_id
+		WHERE ` + LiveMessagesWhere("m", true) + `
+		GROUP BY m.source_id`)
+	if err != nil {
+		return nil, fmt.Errorf("measure raw MIME by source: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var id, size int64
+		if err := rows.Scan(&id, &size); err != nil {
+			return nil, fmt.Errorf("scan raw MIME size: %w", err)
+		}
+		get(id).RawStoredBytes = size
+	}
+	return out, rows.Err()
+}
@@ -1,8 +1,11 @@
 package store_test
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
@@ -23,6 +26,16 @@ func TestStore_Open(t *testing.T) {
 	}
 }
 
+func TestStore_Ping(t *testing.T) {
+	st := testutil.NewTestStore(t)
+	testutil.MustNoErr(t, st.Ping(context.Background()), "Ping()")
+
+	_ = st.Close()
+	if err := st.Ping(context.Background()); err == nil {
+		t.Error("Ping() after Close: expected error")
+	}
+}
+
 func TestStore_GetStats_Empty(t *testing.T) {
 	st := testutil.NewTestStore(t)
 
@@ -257,6 +270,30 @@ func TestStore_MessageRaw(t *testing.T) {
 	}
 }
 
+func TestStore_GetMessageRawMIME(t *testing.T) {
+	f := storetest.New(t)
+
+	mimeID := f.CreateMessage("msg-1")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(mimeID, sampleRawMessage), "UpsertMessageRaw()")
+	chatID := f.CreateMessage("msg-2")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRawWithFormat(chatID, []byte(`{"text":"hi"}`), "whatsapp_json"),
+		"UpsertMessageRawWithFormat()")
+	noRawID := f.CreateMessage("msg-3")
+
+	got, err := f.Store.GetMessageRawMIME(mimeID)
+	testutil.MustNoErr(t, err, "GetMessageRawMIME(mime)")
+	if string(got) != string(sampleRawMessage) {
+		t.Errorf("GetMessageRawMIME(mime) = %q, want %q", got, sampleRawMessage)
+	}
+	for _, id := range []int64{chatID, noRawID} {
+		got, err := f.Store.GetMessageRawMIME(id)
+		testutil.MustNoErr(t, err, "GetMessageRawMIME")
+		if got != nil {
+			t.Errorf("GetMessageRawMIME(%d) = %q, want nil", id, got)
+		}
+	}
+}
+
 func TestStore_Participant(t *testing.T) {
 	f := storetest.New(t)
 
@@ -316,6 +353,29 @@ func TestStore_Label(t *testing.T) {
 	}
 }
 
+func TestStore_ListLabelNames(t *testing.T) {
+	f := storetest.New(t)
+	other, _ := makeSecondSource(t, f, "bob@example.com")
+
+	for _, l := range []struct {
+		sourceID int64
+		id, name string
+	}{
+		{f.Source.ID, "INBOX", "INBOX"},
+		{f.Source.ID, "Label_1", "Receipts"},
+		{other.ID, "INBOX", "INBOX"},
+	} {
+		_, err := f.Store.EnsureLabel(l.sourceID, l.id, l.name, "user")
+		testutil.MustNoErr(t, err, "EnsureLabel()")
+	}
+
+	names, err := f.Store.ListLabelNames()
+	testutil.MustNoErr(t, err, "ListLabelNames()")
+	if want := []string{"INBOX", "Receipts"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListLabelNames() = %v, want %v", names, want)
+	}
+}
+
 func TestStore_EnsureLabel_NameConflict(t *testing.T) {
 	f := storetest.New(t)
 
@@ -424,6 +484,40 @@ func TestStore_EnsureLabelsBatch(t *testing.T) {
 	}
 }
 
+func TestStore_EnsureLabelsBatch_Display(t *testing.T) {
+	f := storetest.New(t)
+
+	colored := map[string]store.LabelInfo{
+		"Label_1": {Name: "Clients/Acme", Type: "user", Color: "#4a86e8", TextColor: "#ffffff", Visibility: "labelShowIfUnread"},
+	}
+	ids, err := f.Store.EnsureLabelsBatch(f.Source.ID, colored)
+	testutil.MustNoErr(t, err, "EnsureLabelsBatch() with colors")
+
+	var color, textColor, visibility sql.NullString
+	readDisplay := func() {
+		t.Helper()
+		err := f.Store.DB().QueryRow(
+			`SELECT color, text_color, visibility FROM labels WHERE id = ?`, ids["Label_1"],
+		).Scan(&color, &textColor, &visibility)
+		testutil.MustNoErr(t, err, "read label display")
+	}
+	readDisplay()
+	if color.String != "#4a86e8" || textColor.String != "#ffffff" || visibility.String != "labelShowIfUnread" {
+		t.Errorf("display = %q/%q/%q, want #4a86e8/#ffffff/labelShowIfUnread",
+			color.String, textColor.String, visibility.String)
+	}
+
+	// Removing the color in Gmail clears it on the next sync.
+	_, err = f.Store.EnsureLabelsBatch(f.Source.ID, map[string]store.LabelInfo{
+		"Label_1": {Name: "Clients/Acme", Type: "user", Visibility: "labelShow"},
+	})
+	testutil.MustNoErr(t, err, "EnsureLabelsBatch() without colors")
+	readDisplay()
+	if color.Valid || textColor.Valid || visibility.String != "labelShow" {
+		t.Errorf("display = %v/%v/%q, want no colors and labelShow", color, textColor, visibility.String)
+	}
+}
+
 func TestStore_EnsureLabelsBatch_CrossRename(t *testing.T) {
 	f := storetest.New(t)
 
@@ -633,6 +727,27 @@ func TestStore_SyncFail(t *testing.T) {
 	}
 }
 
+func TestStore_GetLatestSync(t *testing.T) {
+	f := storetest.New(t)
+
+	run, err := f.Store.GetLatestSync(f.Source.ID)
+	testutil.MustNoErr(t, err, "GetLatestSync() before any sync")
+	if run != nil {
+		t.Fatalf("expected no run, got %+v", run)
+	}
+
+	first := f.StartSync()
+	testutil.MustNoErr(t, f.Store.CompleteSync(first, "100"), "CompleteSync()")
+	second := f.StartSync()
+	testutil.MustNoErr(t, f.Store.FailSync(second, "network error"), "FailSync()")
+
+	run, err = f.Store.GetLatestSync(f.Source.ID)
+	testutil.MustNoErr(t, err, "GetLatestSync()")
+	if run == nil || run.ID != second || run.Status != "failed" {
+		t.Errorf("GetLatestSync() = %+v, want failed run %d", run, second)
+	}
+}
+
 func TestStore_GetMessage_DeletedMessageVisibleByID(t *testing.T) {
 	f := storetest.New(t)
 
@@ -1681,6 +1796,83 @@ func TestStore_GetStatsForScope_SingleSource(t *testing.T) {
 	}
 }
 
+func TestStore_GetSourceStats(t *testing.T) {
+	f := storetest.New(t)
+	srcB, convB := makeSecondSource(t, f, "b@example.com")
+
+	idsA := createMessagesForSource(t, f.Store, f.Source.ID, f.ConvID, "a", 3)
+	createMessagesForSource(t, f.Store, srcB.ID, convB, "b", 2)
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(idsA[0], "a.pdf", "application/pdf", "aa/a1", "hash-a1", 1000), "attach a1")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(idsA[1], "b.pdf", "application/pdf", "aa/a2", "hash-a2", 500), "attach a2")
+	_, err := f.Store.DB().Exec(f.Store.Rebind("UPDATE messages SET deleted_at = ? WHERE id = ?"), time.Now(), idsA[1])
+	testutil.MustNoErr(t, err, "hide message")
+
+	stats, err := f.Store.GetSourceStats()
+	testutil.MustNoErr(t, err, "GetSourceStats")
+	a, b := stats[f.Source.ID], stats[srcB.ID]
+	if a == nil || b == nil {
+		t.Fatalf("missing sources in %v", stats)
+	}
+	if a.MessageCount != 2 || a.AttachmentCount != 1 || a.AttachmentBytes != 1000 {
+		t.Errorf("source A = %+v, want 2 messages, 1 attachment, 1000 bytes", *a)
+	}
+	if b.MessageCount != 2 || b.AttachmentCount != 0 {
+		t.Errorf("source B = %+v, want 2 messages, no attachments", *b)
+	}
+}
+
+func TestStore_GetStats_Storage(t *testing.T) {
+	f := storetest.New(t)
+	srcB, convB := makeSecondSource(t, f, "b@example.com")
+
+	idsA := createMessagesForSource(t, f.Store, f.Source.ID, f.ConvID, "a", 3)
+	idsB := createMessagesForSource(t, f.Store, srcB.ID, convB, "b", 1)
+
+	// The same file attached twice, once in each source, and one other.
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(idsA[0], "a.pdf", "application/pdf", "aa/a1", "hash-a1", 1000), "attach a1")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(idsB[0], "copy.pdf", "application/pdf", "aa/a1", "hash-a1", 1000), "attach a1 copy")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(idsA[1], "b.png", "image/png", "bb/b1", "hash-b1", 300), "attach b1")
+
+	raw := bytes.Repeat([]byte("Subject: hello\r\n\r\nhello hello hello\r\n"), 100)
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(idsA[0], raw), "UpsertMessageRaw")
+	testutil.MustNoErr(t, f.Store.UpsertMessageRaw(idsB[0], raw), "UpsertMessageRaw")
+	if f.Store.FTS5Available() {
+		testutil.MustNoErr(t, f.Store.UpsertFTS(idsA[0], "hello", "hello", "alice@example.com", "", ""), "UpsertFTS")
+	}
+
+	stats, err := f.Store.GetStats()
+	testutil.MustNoErr(t, err, "GetStats")
+	if stats.AttachmentCount != 3 || stats.UniqueAttachmentCount != 2 || stats.DuplicateAttachmentCount() != 1 {
+		t.Errorf("attachments = %d, unique %d, duplicates %d; want 3, 2, 1",
+			stats.AttachmentCount, stats.UniqueAttachmentCount, stats.DuplicateAttachmentCount())
+	}
+	if stats.AttachmentBytes != 2300 || stats.UniqueAttachmentBytes != 1300 {
+		t.Errorf("attachment bytes = %d, unique %d; want 2300, 1300", stats.AttachmentBytes, stats.UniqueAttachmentBytes)
+	}
+	if stats.RawMessageCount != 2 || stats.RawLogicalBytes != 2000 {
+		t.Errorf("raw MIME = %d messages, %d bytes; want 2 messages of 2000 bytes", stats.RawMessageCount, stats.RawLogicalBytes)
+	}
+	if stats.RawStoredBytes <= 0 || stats.RawStoredBytes >= int64(2*len(raw)) {
+		t.Errorf("RawStoredBytes = %d, want compressed below %d", stats.RawStoredBytes, 2*len(raw))
+	}
+	if f.Store.FTS5Available() && stats.FTSIndexedCount != 1 {
+		t.Errorf("FTSIndexedCount = %d, want 1", stats.FTSIndexedCount)
+	}
+
+	// Scoped to source B, its one attachment is unique.
+	statsB, err := f.Store.GetStatsForScope([]int64{srcB.ID})
+	testutil.MustNoErr(t, err, "GetStatsForScope B")
+	if statsB.UniqueAttachmentCount != 1 || statsB.DuplicateAttachmentCount() != 0 || statsB.RawMessageCount != 1 {
+		t.Errorf("source B = %+v, want 1 unique attachment and 1 raw message", *statsB)
+	}
+
+	bySource, err := f.Store.GetSourceStats()
+	testutil.MustNoErr(t, err, "GetSourceStats")
+	if a := bySource[f.Source.ID]; a == nil || a.RawStoredBytes != stats.RawStoredBytes/2 {
+		t.Errorf("source A raw bytes = %+v, want %d", a, stats.RawStoredBytes/2)
+	}
+}
+
 func TestStore_GetStatsForScope_ExcludesDedupHidden(t *testing.T) {
 	f := storetest.New(t)
 	srcB, convB := makeSecondSource(t, f, "b-dedup@example.com")
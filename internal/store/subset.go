@@ -359,6 +359,60 @@ func copyData(tx *sql.Tx, rowCount int) (*CopyResult, error) {
 		return nil, fmt.Errorf("copy message_raw: %w", err)
 	}
 
+	// Raw messages compressed with a zstd dictionary need it to be read.
+	if _, err := tx.Exec(`
+		INSERT INTO raw_dictionaries SELECT * FROM src.raw_dictionaries`); err != nil {
+		return nil, fmt.Errorf("copy raw_dictionaries: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_auth SELECT * FROM src.message_auth
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_auth: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_links SELECT * FROM src.message_links
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_links: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_headers SELECT * FROM src.message_headers
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_headers: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_inline_parts SELECT * FROM src.message_inline_parts
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_inline_parts: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_risk SELECT * FROM src.message_risk
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_risk: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_notes SELECT * FROM src.message_notes
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_notes: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_tags SELECT * FROM src.message_tags
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_tags: %w", err)
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO message_reminders SELECT * FROM src.message_reminders
+		WHERE message_id IN (SELECT id FROM selected_messages)`); err != nil {
+		return nil, fmt.Errorf("copy message_reminders: %w", err)
+	}
+
 	if _, err := tx.Exec(`
 		INSERT INTO message_recipients
 		SELECT * FROM src.message_recipients
@@ -437,7 +491,7 @@ func populateFTS(db *sql.DB) error {
 			from_addr, to_addr, cc_addr
 		)
 		SELECT m.id, m.id, COALESCE(m.subject, ''),
-			COALESCE(mb.body_text, ''),
+			` + searchableBodySQL + `,
 			COALESCE(
 				CASE WHEN m.message_type != 'email' AND m.message_type IS NOT NULL AND m.message_type != ''
 				     THEN (SELECT COALESCE(p.phone_number, p.email_address) FROM participants p WHERE p.id = m.sender_id)
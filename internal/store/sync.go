@@ -294,6 +294,43 @@ func (s *Store) GetLastSuccessfulSync(sourceID int64) (*SyncRun, error) {
 	return run, err
 }
 
+// GetSyncRun returns a sync run by ID, or nil if there is none.
+func (s *Store) GetSyncRun(id int64) (*SyncRun, error) {
+	row := s.db.QueryRow(`
+		SELECT id, source_id, started_at, completed_at, status,
+		       messages_processed, messages_added, messages_updated, errors_count,
+		       error_message, cursor_before, cursor_after
+		FROM sync_runs
+		WHERE id = ?
+	`, id)
+
+	run, err := scanSyncRun(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return run, err
+}
+
+// GetLatestSync returns the most recently started sync run for a
+// source, whatever its status, or nil if the source never synced.
+func (s *Store) GetLatestSync(sourceID int64) (*SyncRun, error) {
+	row := s.db.QueryRow(`
+		SELECT id, source_id, started_at, completed_at, status,
+		       messages_processed, messages_added, messages_updated, errors_count,
+		       error_message, cursor_before, cursor_after
+		FROM sync_runs
+		WHERE source_id = ?
+		ORDER BY started_at DESC, id DESC
+		LIMIT 1
+	`, sourceID)
+
+	run, err := scanSyncRun(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return run, err
+}
+
 // Source represents a Gmail account or other message source.
 type Source struct {
 	ID           int64
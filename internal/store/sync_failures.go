@@ -0,0 +1,124 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Classes of sync failure recorded in sync_failures.error_class.
+const (
+	SyncFailureFetch = "fetch" // the source did not return the message
+	SyncFailureParse = "parse" // the message could not be parsed
+	SyncFailureStore = "store" // the message could not be written
+)
+
+// MaxSyncAttempts is how many times a sync tries a failing message
+// before it stops retrying it on its own.
+const MaxSyncAttempts = 5
+
+// SyncFailure is a message a sync could not archive.
+type SyncFailure struct {
+	ID              int64     `json:"id"`
+	SourceID        int64     `json:"source_id"`
+	Account         string    `json:"account"`
+	SourceMessageID string    `json:"source_message_id"`
+	ThreadID        string    `json:"thread_id,omitempty"`
+	ErrorClass      string    `json:"error_class"`
+	Error           string    `json:"error"`
+	Attempts        int       `json:"attempts"`
+	FirstFailedAt   time.Time `json:"first_failed_at"`
+	LastFailedAt    time.Time `json:"last_failed_at"`
+}
+
+// RecordSyncFailure records that a message failed to sync, or for a
+// message that failed before, counts another attempt.
+func (s *Store) RecordSyncFailure(sourceID int64, sourceMessageID, threadID, errorClass, errMsg string) error {
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO sync_failures
+			(source_id, source_message_id, thread_id, error_class, error, attempts, first_failed_at, last_failed_at)
+		VALUES (?, ?, ?, ?, ?, 1, %[1]s, %[1]s)
+		ON CONFLICT(source_id, source_message_id) DO UPDATE SET
+			thread_id = COALESCE(excluded.thread_id, sync_failures.thread_id),
+			error_class = excluded.error_class,
+			error = excluded.error,
+			attempts = sync_failures.attempts + 1,
+			last_failed_at = excluded.last_failed_at
+	`, s.dialect.Now()), sourceID, sourceMessageID, nullIfEmpty(threadID), errorClass, nullIfEmpty(errMsg))
+	if err != nil {
+		return fmt.Errorf("record sync failure: %w", err)
+	}
+	return nil
+}
+
+// ResolveSyncFailure forgets a failure once its message is archived or
+// gone from the source.
+func (s *Store) ResolveSyncFailure(sourceID int64, sourceMessageID string) error {
+	_, err := s.db.Exec(`
+		DELETE FROM sync_failures WHERE source_id = ? AND source_message_id = ?
+	`, sourceID, sourceMessageID)
+	if err != nil {
+		return fmt.Errorf("resolve sync failure: %w", err)
+	}
+	return nil
+}
+
+// SyncFailuresToRetry returns the failures of a source with attempts
+// left, oldest first.
+func (s *Store) SyncFailuresToRetry(sourceID int64) ([]SyncFailure, error) {
+	return s.querySyncFailures(`WHERE f.source_id = ? AND f.attempts < ?`, sourceID, MaxSyncAttempts)
+}
+
+// ListSyncFailures returns the recorded failures of a source, or of
+// every source when sourceID is 0, oldest first.
+func (s *Store) ListSyncFailures(sourceID int64) ([]SyncFailure, error) {
+	if sourceID == 0 {
+		return s.querySyncFailures("")
+	}
+	return s.querySyncFailures(`WHERE f.source_id = ?`, sourceID)
+}
+
+// ClearSyncFailures forgets the failures of a source, or of every
+// source when sourceID is 0, and returns how many it forgot.
+func (s *Store) ClearSyncFailures(sourceID int64) (int64, error) {
+	var res sql.Result
+	var err error
+	if sourceID == 0 {
+		res, err = s.db.Exec(`DELETE FROM sync_failures`)
+	} else {
+		res, err = s.db.Exec(`DELETE FROM sync_failures WHERE source_id = ?`, sourceID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("clear sync failures: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (s *Store) querySyncFailures(where string, args ...any) ([]SyncFailure, error) {
+	rows, err := s.db.Query(`
+		SELECT f.id, f.source_id, src.identifier, f.source_message_id, COALESCE(f.thread_id, ''),
+			f.error_class, COALESCE(f.error, ''), f.attempts, f.first_failed_at, f.last_failed_at
+		FROM sync_failures f
+		JOIN sources src ON src.id = f.source_id
+		`+where+`
+		ORDER BY f.first_failed_at, f.id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list sync failures: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []SyncFailure
+	for rows.Next() {
+		var f SyncFailure
+		var first, last sql.NullString
+		if err := rows.Scan(&f.ID, &f.SourceID, &f.Account, &f.SourceMessageID, &f.ThreadID,
+			&f.ErrorClass, &f.Error, &f.Attempts, &first, &last); err != nil {
+			return nil, fmt.Errorf("scan sync failure: %w", err)
+		}
+		f.FirstFailedAt = parseSQLiteTime(first.String)
+		f.LastFailedAt = parseSQLiteTime(last.String)
+		out = append(out, f)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,59 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_SyncFailures(t *testing.T) {
+	f := storetest.New(t)
+	st := f.Store
+	other, err := st.GetOrCreateSource("gmail", "bob@example.com")
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+
+	testutil.MustNoErr(t, st.RecordSyncFailure(f.Source.ID, "m1", "t1", store.SyncFailureFetch, "connection reset"), "record m1")
+	testutil.MustNoErr(t, st.RecordSyncFailure(f.Source.ID, "m1", "", store.SyncFailureParse, "bad header"), "record m1 again")
+	testutil.MustNoErr(t, st.RecordSyncFailure(f.Source.ID, "m2", "", store.SyncFailureStore, "disk full"), "record m2")
+	testutil.MustNoErr(t, st.RecordSyncFailure(other.ID, "m9", "", store.SyncFailureFetch, "timeout"), "record m9")
+	for range store.MaxSyncAttempts - 1 {
+		testutil.MustNoErr(t, st.RecordSyncFailure(f.Source.ID, "m2", "", store.SyncFailureStore, "disk full"), "record m2 again")
+	}
+
+	failures, err := st.ListSyncFailures(f.Source.ID)
+	testutil.MustNoErr(t, err, "ListSyncFailures")
+	if len(failures) != 2 {
+		t.Fatalf("ListSyncFailures = %+v, want m1 and m2", failures)
+	}
+	m1 := failures[0]
+	if m1.SourceMessageID != "m1" || m1.Attempts != 2 || m1.ErrorClass != store.SyncFailureParse ||
+		m1.Error != "bad header" || m1.ThreadID != "t1" || m1.LastFailedAt.IsZero() {
+		t.Errorf("m1 = %+v, want 2 attempts, the latest error and the first thread ID", m1)
+	}
+
+	retry, err := st.SyncFailuresToRetry(f.Source.ID)
+	testutil.MustNoErr(t, err, "SyncFailuresToRetry")
+	if len(retry) != 1 || retry[0].SourceMessageID != "m1" {
+		t.Errorf("SyncFailuresToRetry = %+v, want m1 only (m2 is out of attempts)", retry)
+	}
+
+	all, err := st.ListSyncFailures(0)
+	testutil.MustNoErr(t, err, "ListSyncFailures(0)")
+	if len(all) != 3 {
+		t.Errorf("ListSyncFailures(0) = %d failures, want 3", len(all))
+	}
+
+	testutil.MustNoErr(t, st.ResolveSyncFailure(f.Source.ID, "m1"), "ResolveSyncFailure")
+	n, err := st.ClearSyncFailures(f.Source.ID)
+	testutil.MustNoErr(t, err, "ClearSyncFailures")
+	if n != 1 {
+		t.Errorf("ClearSyncFailures = %d, want 1 (m2)", n)
+	}
+	all, err = st.ListSyncFailures(0)
+	testutil.MustNoErr(t, err, "ListSyncFailures(0)")
+	if len(all) != 1 || all[0].Account != "bob@example.com" {
+		t.Errorf("left = %+v, want bob's failure only", all)
+	}
+}
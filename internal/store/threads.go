@@ -0,0 +1,324 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wesm/msgvault/internal/mime"
+)
+
+// ErrThreadEdit is returned when messages cannot be merged or split as
+// asked, such as messages of different accounts.
+var ErrThreadEdit = errors.New("invalid thread edit")
+
+// messageConversations returns the conversation and source of each of
+// messageIDs.
+func messageConversations(q chunkQuerier, messageIDs []int64) (conv, source map[int64]int64, err error) {
+	conv = make(map[int64]int64, len(messageIDs))
+	source = make(map[int64]int64, len(messageIDs))
+	err = queryInChunks(q, messageIDs, nil,
+		`SELECT id, conversation_id, source_id FROM messages WHERE id IN (%s)`,
+		func(rows *loggedRows) error {
+			var id, convID, sourceID int64
+			if err := rows.Scan(&id, &convID, &sourceID); err != nil {
+				return err
+			}
+			conv[id] = convID
+			source[id] = sourceID
+			return nil
+		})
+	if err != nil {
+		return nil, nil, fmt.Errorf("look up conversations: %w", err)
+	}
+	for _, id := range messageIDs {
+		if _, ok := conv[id]; !ok {
+			return nil, nil, fmt.Errorf("message %d: %w", id, ErrMessageNotFound)
+		}
+	}
+	return conv, source, nil
+}
+
+// MergeThreads merges the conversations of messageIDs into the oldest
+// of them and returns its ID. The messages must belong to one account.
+// Messages the source later files under a merged conversation join the
+// merged one too.
+func (s *Store) MergeThreads(messageIDs []int64) (int64, error) {
+	conv, source, err := messageConversations(s.db, messageIDs)
+	if err != nil {
+		return 0, err
+	}
+	var convIDs []int64
+	sourceID := source[messageIDs[0]]
+	for _, id := range messageIDs {
+		if source[id] != sourceID {
+			return 0, fmt.Errorf("%w: messages %d and %d belong to different accounts",
+				ErrThreadEdit, messageIDs[0], id)
+		}
+		convIDs = append(convIDs, conv[id])
+	}
+	slices.Sort(convIDs)
+	convIDs = slices.Compact(convIDs)
+	if len(convIDs) < 2 {
+		return convIDs[0], nil
+	}
+	return convIDs[0], s.MergeConversations(convIDs[0], convIDs[1:]...)
+}
+
+// MergeConversations moves the messages of the from conversations into
+// the into conversation, and records them as merged into it so that
+// EnsureConversation resolves their thread IDs to into from now on.
+// All must be conversations of one source.
+func (s *Store) MergeConversations(into int64, from ...int64) error {
+	from = slices.DeleteFunc(slices.Clone(from), func(id int64) bool { return id == into })
+	if len(from) == 0 {
+		return nil
+	}
+	all := append([]int64{into}, from...)
+	return s.withTx(func(tx *loggedTx) error {
+		var sources []int64
+		err := queryInChunks(tx, all, nil,
+			`SELECT DISTINCT source_id FROM conversations WHERE id IN (%s)`,
+			func(rows *loggedRows) error {
+				var id int64
+				if err := rows.Scan(&id); err != nil {
+					return err
+				}
+				sources = append(sources, id)
+				return nil
+			})
+		if err != nil {
+			return fmt.Errorf("look up conversations: %w", err)
+		}
+		if len(sources) != 1 {
+			return fmt.Errorf("%w: conversations must exist and belong to one account", ErrThreadEdit)
+		}
+
+		for _, stmt := range []string{
+			`UPDATE messages SET conversation_id = ? WHERE conversation_id IN (%s)`,
+			`UPDATE thread_overrides SET conversation_id = ? WHERE conversation_id IN (%s)`,
+			`UPDATE conversations SET merged_into = ? WHERE id IN (%s)`,
+			// Earlier merges into a merged conversation now resolve to into.
+			`UPDATE conversations SET merged_into = ? WHERE merged_into IN (%s)`,
+		} {
+			if err := execInChunks(tx, from, []any{into}, stmt); err != nil {
+				return fmt.Errorf("merge conversations: %w", err)
+			}
+		}
+		if err := execInChunks(tx, from, []any{into}, s.dialect.InsertOrIgnore(`
+			INSERT OR IGNORE INTO conversation_participants (conversation_id, participant_id, role, joined_at)
+			SELECT ?, participant_id, role, joined_at FROM conversation_participants
+			WHERE conversation_id IN (%s)`)); err != nil {
+			return fmt.Errorf("merge conversation participants: %w", err)
+		}
+		if err := execInChunks(tx, from, nil,
+			`DELETE FROM conversation_participants WHERE conversation_id IN (%s)`); err != nil {
+			return fmt.Errorf("merge conversation participants: %w", err)
+		}
+		return recomputeStats(tx, all)
+	})
+}
+
+// SplitThread moves messageIDs, which must share a conversation, out
+// of it into a new conversation titled after the first of them, and
+// returns the new conversation's ID. The move is kept across resyncs.
+func (s *Store) SplitThread(messageIDs []int64) (int64, error) {
+	conv, source, err := messageConversations(s.db, messageIDs)
+	if err != nil {
+		return 0, err
+	}
+	oldConv := conv[messageIDs[0]]
+	for _, id := range messageIDs {
+		if conv[id] != oldConv {
+			return 0, fmt.Errorf("%w: messages %d and %d are in different conversations",
+				ErrThreadEdit, messageIDs[0], id)
+		}
+	}
+	var total int64
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE conversation_id = ?`, oldConv).Scan(&total); err != nil {
+		return 0, fmt.Errorf("count conversation messages: %w", err)
+	}
+	ids := slices.Clone(messageIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	if int64(len(ids)) >= total {
+		return 0, fmt.Errorf("%w: the messages are the whole conversation", ErrThreadEdit)
+	}
+
+	var subject sql.NullString
+	if err := s.db.QueryRow(`SELECT subject FROM messages WHERE id = ?`, ids[0]).Scan(&subject); err != nil {
+		return 0, fmt.Errorf("read subject: %w", err)
+	}
+	title := mime.NormalizeSubject(subject.String)
+	if title == "" {
+		title = "(no subject)"
+	}
+
+	// The split conversation's thread ID can't collide with a source's.
+	threadID := fmt.Sprintf("msgvault-split-%d-%d", ids[0], time.Now().UnixNano())
+	sourceID := source[ids[0]]
+	var newConv int64
+	err = s.withTx(func(tx *loggedTx) error {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO conversations (source_id, source_conversation_id, conversation_type, title, created_at, updated_at)
+			VALUES (?, ?, 'email_thread', ?, %s, %s)
+		`, s.dialect.Now(), s.dialect.Now()), sourceID, threadID, title); err != nil {
+			return fmt.Errorf("create conversation: %w", err)
+		}
+		if err := tx.QueryRow(`
+			SELECT id FROM conversations WHERE source_id = ? AND source_conversation_id = ?
+		`, sourceID, threadID).Scan(&newConv); err != nil {
+			return fmt.Errorf("create conversation: %w", err)
+		}
+		if err := execInChunks(tx, ids, []any{newConv},
+			`UPDATE messages SET conversation_id = ? WHERE id IN (%s)`); err != nil {
+			return fmt.Errorf("move messages: %w", err)
+		}
+		for _, id := range ids {
+			if _, err := tx.Exec(fmt.Sprintf(`
+				INSERT INTO thread_overrides (message_id, conversation_id, created_at)
+				VALUES (?, ?, %s)
+				ON CONFLICT(message_id) DO UPDATE SET conversation_id = excluded.conversation_id
+			`, s.dialect.Now()), id, newConv); err != nil {
+				return fmt.Errorf("record split: %w", err)
+			}
+		}
+		if err := execInChunks(tx, ids, []any{newConv}, s.dialect.InsertOrIgnore(fmt.Sprintf(`
+			INSERT OR IGNORE INTO conversation_participants (conversation_id, participant_id, role, joined_at)
+			SELECT ?, mr.participant_id, 'member', %s FROM message_recipients mr
+			WHERE mr.message_id IN (%%s)`, s.dialect.Now()))); err != nil {
+			return fmt.Errorf("add conversation participants: %w", err)
+		}
+		return recomputeStats(tx, []int64{oldConv, newConv})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return newConv, nil
+}
+
+// recomputeStats refreshes the denormalized stats of conversations
+// convIDs, as RecomputeConversationStats does for a whole source.
+func recomputeStats(q chunkQuerier, convIDs []int64) error {
+	err := execInChunks(q, convIDs, nil, `
+		UPDATE conversations SET
+			message_count = (
+				SELECT COUNT(*) FROM messages
+				WHERE conversation_id = conversations.id
+			),
+			participant_count = (
+				SELECT COUNT(*) FROM conversation_participants
+				WHERE conversation_id = conversations.id
+			),
+			last_message_at = (
+				SELECT MAX(COALESCE(sent_at, received_at, internal_date))
+				FROM messages
+				WHERE conversation_id = conversations.id
+			),
+			last_message_preview = (
+				SELECT snippet FROM messages
+				WHERE conversation_id = conversations.id
+				ORDER BY COALESCE(sent_at, received_at, internal_date) DESC, id DESC
+				LIMIT 1
+			)
+		WHERE id IN (%s)`)
+	if err != nil {
+		return fmt.Errorf("recompute conversation stats: %w", err)
+	}
+	return nil
+}
+
+// NormalizeConversationTitles strips reply and forward prefixes from
+// the titles of email conversations (see mime.NormalizeSubject) and
+// returns how many titles changed. With dryRun, nothing is written.
+func (s *Store) NormalizeConversationTitles(dryRun bool) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT id, title FROM conversations
+		WHERE conversation_type = 'email_thread' AND title IS NOT NULL
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("list conversation titles: %w", err)
+	}
+	type retitle struct {
+		id    int64
+		title string
+	}
+	var changes []retitle
+	for rows.Next() {
+		var id int64
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("scan conversation title: %w", err)
+		}
+		norm := mime.NormalizeSubject(title)
+		if norm == "" {
+			norm = "(no subject)"
+		}
+		if norm != title {
+			changes = append(changes, retitle{id, norm})
+		}
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("list conversation titles: %w", err)
+	}
+	if dryRun || len(changes) == 0 {
+		return len(changes), nil
+	}
+	err = s.withTx(func(tx *loggedTx) error {
+		for _, c := range changes {
+			if _, err := tx.Exec(`UPDATE conversations SET title = ? WHERE id = ?`, c.title, c.id); err != nil {
+				return fmt.Errorf("update conversation title: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+// ThreadingMessage is an email, with raw MIME, that 'threads repair'
+// may thread by its References.
+type ThreadingMessage struct {
+	ID             int64
+	SourceID       int64
+	ConversationID int64
+}
+
+// ThreadingMessages returns up to limit emails with ID above afterID
+// that have raw MIME, in ID order. Messages the user moved to another
+// conversation are left out, so repairs never undo a split.
+func (s *Store) ThreadingMessages(afterID int64, limit int) ([]ThreadingMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id, m.source_id, m.conversation_id
+		FROM messages m
+		WHERE m.id > ? AND m.message_type = 'email'
+		  AND EXISTS (
+			SELECT 1 FROM message_raw mr
+			WHERE mr.message_id = m.id AND mr.raw_format = 'mime'
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM thread_overrides o WHERE o.message_id = m.id
+		  )
+		ORDER BY m.id LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list threading messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var out []ThreadingMessage
+	for rows.Next() {
+		var m ThreadingMessage
+		if err := rows.Scan(&m.ID, &m.SourceID, &m.ConversationID); err != nil {
+			return nil, fmt.Errorf("scan threading message: %w", err)
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,150 @@
+package store_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func conversationOf(t *testing.T, st *store.Store, messageID int64) int64 {
+	t.Helper()
+	var conv int64
+	err := st.DB().QueryRow(`SELECT conversation_id FROM messages WHERE id = ?`, messageID).Scan(&conv)
+	testutil.MustNoErr(t, err, "conversation_id")
+	return conv
+}
+
+func messageCount(t *testing.T, st *store.Store, convID int64) int {
+	t.Helper()
+	var n int
+	err := st.DB().QueryRow(`SELECT message_count FROM conversations WHERE id = ?`, convID).Scan(&n)
+	testutil.MustNoErr(t, err, "message_count")
+	return n
+}
+
+func TestStore_MergeThreads(t *testing.T) {
+	f := storetest.New(t)
+	other, err := f.Store.EnsureConversation(f.Source.ID, "thread-b", "Re: Lunch")
+	testutil.MustNoErr(t, err, "EnsureConversation")
+	a := f.CreateMessage("a")
+	b := storetest.NewMessage(f.Source.ID, other).WithSourceMessageID("b").Create(t, f.Store)
+
+	into, err := f.Store.MergeThreads([]int64{b, a})
+	testutil.MustNoErr(t, err, "MergeThreads")
+	if into != f.ConvID {
+		t.Errorf("MergeThreads = %d, want the older conversation %d", into, f.ConvID)
+	}
+	if got := conversationOf(t, f.Store, b); got != f.ConvID {
+		t.Errorf("message b conversation = %d, want %d", got, f.ConvID)
+	}
+	if n := messageCount(t, f.Store, f.ConvID); n != 2 {
+		t.Errorf("merged message_count = %d, want 2", n)
+	}
+
+	// New mail the source files under the merged thread joins the merge.
+	got, err := f.Store.EnsureConversation(f.Source.ID, "thread-b", "Re: Lunch")
+	testutil.MustNoErr(t, err, "EnsureConversation after merge")
+	if got != f.ConvID {
+		t.Errorf("EnsureConversation(thread-b) = %d, want %d", got, f.ConvID)
+	}
+
+	bob, err := f.Store.GetOrCreateSource("gmail", "bob@example.com")
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+	bobConv, err := f.Store.EnsureConversation(bob.ID, "thread-c", "Lunch")
+	testutil.MustNoErr(t, err, "EnsureConversation")
+	c := storetest.NewMessage(bob.ID, bobConv).WithSourceMessageID("c").Create(t, f.Store)
+	if _, err := f.Store.MergeThreads([]int64{a, c}); !errors.Is(err, store.ErrThreadEdit) {
+		t.Errorf("MergeThreads across accounts error = %v, want ErrThreadEdit", err)
+	}
+	if _, err := f.Store.MergeThreads([]int64{a, 99999}); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("MergeThreads of missing message error = %v, want ErrMessageNotFound", err)
+	}
+}
+
+func TestStore_SplitThread(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+
+	if _, err := f.Store.SplitThread(ids); !errors.Is(err, store.ErrThreadEdit) {
+		t.Errorf("SplitThread of whole conversation error = %v, want ErrThreadEdit", err)
+	}
+
+	newConv, err := f.Store.SplitThread(ids[1:])
+	testutil.MustNoErr(t, err, "SplitThread")
+	if newConv == f.ConvID {
+		t.Fatal("SplitThread returned the original conversation")
+	}
+	for _, id := range ids[1:] {
+		if got := conversationOf(t, f.Store, id); got != newConv {
+			t.Errorf("message %d conversation = %d, want %d", id, got, newConv)
+		}
+	}
+	if n := messageCount(t, f.Store, f.ConvID); n != 1 {
+		t.Errorf("original message_count = %d, want 1", n)
+	}
+	if n := messageCount(t, f.Store, newConv); n != 2 {
+		t.Errorf("split message_count = %d, want 2", n)
+	}
+
+	// A resync rewrites the message with the source's thread, but the
+	// split is kept.
+	_, err = f.Store.UpsertMessage(&store.Message{
+		ConversationID:  f.ConvID,
+		SourceID:        f.Source.ID,
+		SourceMessageID: "msg-1",
+		MessageType:     "email",
+	})
+	testutil.MustNoErr(t, err, "UpsertMessage")
+	if got := conversationOf(t, f.Store, ids[1]); got != newConv {
+		t.Errorf("after resync conversation = %d, want split %d", got, newConv)
+	}
+
+	// Merging the split back moves its overrides along.
+	_, err = f.Store.MergeThreads([]int64{ids[0], ids[1]})
+	testutil.MustNoErr(t, err, "MergeThreads")
+	_, err = f.Store.UpsertMessage(&store.Message{
+		ConversationID:  f.ConvID,
+		SourceID:        f.Source.ID,
+		SourceMessageID: "msg-2",
+		MessageType:     "email",
+	})
+	testutil.MustNoErr(t, err, "UpsertMessage")
+	if got := conversationOf(t, f.Store, ids[2]); got != f.ConvID {
+		t.Errorf("after merge back conversation = %d, want %d", got, f.ConvID)
+	}
+}
+
+func TestStore_NormalizeConversationTitles(t *testing.T) {
+	f := storetest.New(t)
+	for thread, title := range map[string]string{
+		"t1": "Re: Fwd: Lunch",
+		"t2": "Dinner",
+		"t3": "RE: ",
+	} {
+		_, err := f.Store.EnsureConversation(f.Source.ID, thread, title)
+		testutil.MustNoErr(t, err, "EnsureConversation")
+	}
+
+	n, err := f.Store.NormalizeConversationTitles(true)
+	testutil.MustNoErr(t, err, "NormalizeConversationTitles dry run")
+	if n != 2 {
+		t.Errorf("dry run changed = %d, want 2", n)
+	}
+	n, err = f.Store.NormalizeConversationTitles(false)
+	testutil.MustNoErr(t, err, "NormalizeConversationTitles")
+	if n != 2 {
+		t.Errorf("changed = %d, want 2", n)
+	}
+	var title string
+	err = f.Store.DB().QueryRow(`SELECT title FROM conversations WHERE source_conversation_id = 't1'`).Scan(&title)
+	testutil.MustNoErr(t, err, "read title")
+	if title != "Lunch" {
+		t.Errorf("title = %q, want Lunch", title)
+	}
+	if n, _ := f.Store.NormalizeConversationTitles(false); n != 0 {
+		t.Errorf("second run changed = %d, want 0", n)
+	}
+}
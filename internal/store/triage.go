@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// TriageWatermark returns the ID of the last message triaged: every
+// message up to it has been looked at. 0 means none has.
+func (s *Store) TriageWatermark() (int64, error) {
+	var id int64
+	err := s.db.QueryRow(`SELECT watermark FROM triage_state WHERE id = 1`).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get triage watermark: %w", err)
+	}
+	return id, nil
+}
+
+// SetTriageWatermark records that every message up to messageID has
+// been triaged. The watermark only moves forward.
+func (s *Store) SetTriageWatermark(messageID int64) error {
+	_, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO triage_state (id, watermark, updated_at)
+		VALUES (1, ?, %s)
+		ON CONFLICT(id) DO UPDATE SET
+			watermark = excluded.watermark,
+			updated_at = excluded.updated_at
+		WHERE excluded.watermark > triage_state.watermark
+	`, s.dialect.Now()), messageID)
+	if err != nil {
+		return fmt.Errorf("set triage watermark: %w", err)
+	}
+	return nil
+}
+
+// UntriagedMessageIDs returns the IDs of up to limit email messages
+// after the watermark after, in the order they were archived.
+func (s *Store) UntriagedMessageIDs(after int64, limit int) ([]int64, error) {
+	rows, err := s.db.Query(`
+		SELECT m.id FROM messages m
+		WHERE m.id > ? AND m.message_type = 'email' AND `+LiveMessagesWhere("m", false)+`
+		ORDER BY m.id
+		LIMIT ?`, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list untriaged messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan untriaged message: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountUntriagedMessages returns how many email messages come after the
+// watermark after.
+func (s *Store) CountUntriagedMessages(after int64) (int64, error) {
+	var n int64
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM messages m
+		WHERE m.id > ? AND m.message_type = 'email' AND `+LiveMessagesWhere("m", false), after).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count untriaged messages: %w", err)
+	}
+	return n, nil
+}
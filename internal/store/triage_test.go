@@ -0,0 +1,45 @@
+package store_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+func TestStore_TriageWatermark(t *testing.T) {
+	f := storetest.New(t)
+	ids := f.CreateMessages(3)
+
+	wm, err := f.Store.TriageWatermark()
+	testutil.MustNoErr(t, err, "TriageWatermark")
+	if wm != 0 {
+		t.Fatalf("TriageWatermark before triage = %d, want 0", wm)
+	}
+	got, err := f.Store.UntriagedMessageIDs(wm, 10)
+	testutil.MustNoErr(t, err, "UntriagedMessageIDs")
+	if !slices.Equal(got, ids) {
+		t.Errorf("UntriagedMessageIDs(0) = %v, want %v", got, ids)
+	}
+
+	testutil.MustNoErr(t, f.Store.SetTriageWatermark(ids[1]), "SetTriageWatermark")
+	// The watermark does not move back.
+	testutil.MustNoErr(t, f.Store.SetTriageWatermark(ids[0]), "SetTriageWatermark back")
+	wm, err = f.Store.TriageWatermark()
+	testutil.MustNoErr(t, err, "TriageWatermark")
+	if wm != ids[1] {
+		t.Fatalf("TriageWatermark = %d, want %d", wm, ids[1])
+	}
+
+	got, err = f.Store.UntriagedMessageIDs(wm, 10)
+	testutil.MustNoErr(t, err, "UntriagedMessageIDs")
+	if !slices.Equal(got, ids[2:]) {
+		t.Errorf("UntriagedMessageIDs(%d) = %v, want %v", wm, got, ids[2:])
+	}
+	n, err := f.Store.CountUntriagedMessages(wm)
+	testutil.MustNoErr(t, err, "CountUntriagedMessages")
+	if n != 1 {
+		t.Errorf("CountUntriagedMessages = %d, want 1", n)
+	}
+}
@@ -0,0 +1,555 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VaultMergeResult holds the summary of a vault merge.
+type VaultMergeResult struct {
+	SourcesAdded     int64
+	SourcesMatched   int64
+	Participants     int64
+	Conversations    int64
+	Labels           int64
+	MessagesAdded    int64
+	MessagesSkipped  int64
+	LabelLinksAdded  int64
+	AttachmentsAdded int64
+
+	// AttachmentPaths lists the content-addressed storage paths
+	// referenced by merged attachments. The caller copies these files
+	// from the other vault's attachments directory; rows are only
+	// metadata.
+	AttachmentPaths []string
+
+	// RawBlobHashes lists the blobs merged messages keep their raw data
+	// in. The caller copies them from the other vault's raw blob
+	// directory, as with AttachmentPaths.
+	RawBlobHashes []string
+	Elapsed       time.Duration
+}
+
+// mergeStep is a single statement in the merge pipeline. counter,
+// when non-nil, receives the statement's RowsAffected.
+type mergeStep struct {
+	desc    string
+	sql     string
+	counter *int64
+}
+
+// MergeVault merges every source, message, and related row from the
+// SQLite database at srcDBPath into this store. Unlike CopySubset,
+// the destination already holds data, so rows are remapped onto
+// existing IDs rather than copied verbatim:
+//
+//   - sources match on (source_type, identifier)
+//   - participants match on email address, then phone number
+//   - conversations match on (source, source_conversation_id)
+//   - labels match on (source, name)
+//   - messages match on (source, source_message_id), then on the
+//     RFC822 Message-ID within the same source
+//
+// Messages that already exist are not rewritten, but their labels are
+// unioned with the other vault's labels and blank participant display
+// names are filled in. The whole merge runs in one transaction; a
+// failure leaves the destination untouched.
+//
+// The source database must be at the current schema version. Open it
+// once with this build of msgvault (e.g. 'msgvault stats --home <dir>')
+// to apply migrations first.
+func (s *Store) MergeVault(ctx context.Context, srcDBPath string) (*VaultMergeResult, error) {
+	if s.dialect.DriverName() != "sqlite3" {
+		return nil, fmt.Errorf("vault merge requires a SQLite destination database")
+	}
+
+	start := time.Now()
+
+	srcDBPath, err := filepath.Abs(filepath.Clean(srcDBPath))
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize source path: %w", err)
+	}
+	for _, r := range srcDBPath {
+		if r < 0x20 || r == 0x7F {
+			return nil, fmt.Errorf(
+				"source database path contains control character (0x%02X)", r,
+			)
+		}
+	}
+	if _, err := os.Stat(srcDBPath); err != nil {
+		return nil, fmt.Errorf("source database not found: %w", err)
+	}
+	if same, err := sameFile(srcDBPath, s.dbPath); err == nil && same {
+		return nil, fmt.Errorf("cannot merge a vault into itself: %s", srcDBPath)
+	}
+
+	src, err := OpenReadOnly(srcDBPath)
+	if err != nil {
+		return nil, fmt.Errorf("open source database: %w", err)
+	}
+	stale, col, staleErr := src.SchemaStale()
+	_ = src.Close()
+	if staleErr != nil {
+		return nil, fmt.Errorf("inspect source database: %w", staleErr)
+	}
+	if stale {
+		return nil, fmt.Errorf(
+			"source database schema is outdated (missing %s); "+
+				"open it once with this version of msgvault to migrate it", col,
+		)
+	}
+
+	_, prevMaxID, err := s.messageIDRange()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	escapedSrcPath := strings.ReplaceAll(srcDBPath, "'", "''")
+	if _, err := conn.ExecContext(ctx,
+		fmt.Sprintf("ATTACH DATABASE '%s' AS src", escapedSrcPath),
+	); err != nil {
+		return nil, fmt.Errorf("attach source database: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), "DETACH DATABASE src") }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	result, err := mergeVaultData(ctx, tx)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit merge: %w", err)
+	}
+
+	// Post-commit bookkeeping. These are derived data — failures are
+	// reported but the merged rows stay.
+	if err := s.EnsureDefaultCollection(); err != nil {
+		return result, fmt.Errorf("ensure default collection: %w", err)
+	}
+	sources, err := s.ListSources("")
+	if err != nil {
+		return result, fmt.Errorf("list sources: %w", err)
+	}
+	for _, src := range sources {
+		if err := s.RecomputeConversationStats(src.ID); err != nil {
+			return result, err
+		}
+	}
+	if s.fts5Available && result.MessagesAdded > 0 {
+		_, maxID, err := s.messageIDRange()
+		if err != nil {
+			return result, err
+		}
+		if _, err := s.backfillFTSRange(prevMaxID+1, maxID, nil); err != nil {
+			return result, fmt.Errorf("index merged messages: %w", err)
+		}
+	}
+
+	result.Elapsed = time.Since(start)
+	return result, nil
+}
+
+// mergeVaultData runs the remap-and-insert pipeline inside tx. Temp tables
+// hold src→dst ID maps for each entity so later statements can join
+// through them.
+func mergeVaultData(ctx context.Context, tx *sql.Tx) (*VaultMergeResult, error) {
+	result := &VaultMergeResult{}
+
+	steps := []mergeStep{
+		{desc: "create map tables", sql: `
+			CREATE TEMP TABLE merge_source_map (src_id INTEGER PRIMARY KEY, dst_id INTEGER NOT NULL);
+			CREATE TEMP TABLE merge_participant_map (src_id INTEGER PRIMARY KEY, dst_id INTEGER NOT NULL);
+			CREATE TEMP TABLE merge_conversation_map (src_id INTEGER PRIMARY KEY, dst_id INTEGER NOT NULL);
+			CREATE TEMP TABLE merge_label_map (src_id INTEGER PRIMARY KEY, dst_id INTEGER NOT NULL);
+			CREATE TEMP TABLE merge_message_map (
+				src_id INTEGER PRIMARY KEY, dst_id INTEGER NOT NULL, is_new INTEGER NOT NULL
+			);`},
+
+		// Sources. A newly added source keeps the other vault's sync
+		// cursor, which is consistent with the messages merged below.
+		{desc: "merge sources", counter: &result.SourcesAdded, sql: `
+			INSERT INTO main.sources
+				(source_type, identifier, display_name, last_sync_at,
+				 sync_cursor, sync_config, oauth_app)
+			SELECT ss.source_type, ss.identifier, ss.display_name, ss.last_sync_at,
+			       ss.sync_cursor, ss.sync_config, ss.oauth_app
+			FROM src.sources ss
+			WHERE NOT EXISTS (
+				SELECT 1 FROM main.sources d
+				WHERE d.source_type = ss.source_type AND d.identifier = ss.identifier
+			)`},
+		{desc: "map sources", sql: `
+			INSERT INTO merge_source_map (src_id, dst_id)
+			SELECT ss.id, d.id FROM src.sources ss
+			JOIN main.sources d
+			  ON d.source_type = ss.source_type AND d.identifier = ss.identifier`},
+
+		// Participants: email first, then phone-only contacts.
+		{desc: "merge email participants", counter: &result.Participants, sql: `
+			INSERT INTO main.participants
+				(email_address, phone_number, display_name, domain, canonical_id)
+			SELECT sp.email_address, sp.phone_number, sp.display_name, sp.domain, sp.canonical_id
+			FROM src.participants sp
+			WHERE sp.email_address IS NOT NULL
+			  AND NOT EXISTS (
+				SELECT 1 FROM main.participants d WHERE d.email_address = sp.email_address
+			  )`},
+		{desc: "merge phone participants", counter: &result.Participants, sql: `
+			INSERT INTO main.participants
+				(phone_number, display_name, canonical_id)
+			SELECT sp.phone_number, sp.display_name, sp.canonical_id
+			FROM src.participants sp
+			WHERE sp.email_address IS NULL AND sp.phone_number IS NOT NULL
+			  AND NOT EXISTS (
+				SELECT 1 FROM main.participants d
+				WHERE d.email_address IS NULL AND d.phone_number = sp.phone_number
+			  )`},
+		{desc: "map email participants", sql: `
+			INSERT OR IGNORE INTO merge_participant_map (src_id, dst_id)
+			SELECT sp.id, d.id FROM src.participants sp
+			JOIN main.participants d ON d.email_address = sp.email_address
+			WHERE sp.email_address IS NOT NULL`},
+		{desc: "map phone participants", sql: `
+			INSERT OR IGNORE INTO merge_participant_map (src_id, dst_id)
+			SELECT sp.id, MIN(d.id) FROM src.participants sp
+			JOIN main.participants d
+			  ON d.email_address IS NULL AND d.phone_number = sp.phone_number
+			WHERE sp.email_address IS NULL AND sp.phone_number IS NOT NULL
+			GROUP BY sp.id`},
+		{desc: "fill participant names", sql: `
+			UPDATE main.participants SET display_name = (
+				SELECT sp.display_name FROM src.participants sp
+				JOIN merge_participant_map pm ON pm.src_id = sp.id
+				WHERE pm.dst_id = main.participants.id
+				  AND sp.display_name IS NOT NULL AND sp.display_name != ''
+				LIMIT 1
+			)
+			WHERE (display_name IS NULL OR display_name = '')
+			  AND id IN (SELECT dst_id FROM merge_participant_map)`},
+		{desc: "merge participant identifiers", sql: `
+			INSERT OR IGNORE INTO main.participant_identifiers
+				(participant_id, identifier_type, identifier_value, display_value, is_primary)
+			SELECT pm.dst_id, pi.identifier_type, pi.identifier_value, pi.display_value, pi.is_primary
+			FROM src.participant_identifiers pi
+			JOIN merge_participant_map pm ON pm.src_id = pi.participant_id`},
+
+		// Labels. UNIQUE(source_id, name) does not dedupe NULL
+		// source_id, so the NOT EXISTS check uses IS.
+		{desc: "merge labels", counter: &result.Labels, sql: `
+			INSERT INTO main.labels (source_id, source_label_id, name, label_type, color)
+			SELECT sm.dst_id, sl.source_label_id, sl.name, sl.label_type, sl.color
+			FROM src.labels sl
+			LEFT JOIN merge_source_map sm ON sm.src_id = sl.source_id
+			WHERE (sl.source_id IS NULL OR sm.dst_id IS NOT NULL)
+			  AND NOT EXISTS (
+				SELECT 1 FROM main.labels d
+				WHERE d.source_id IS sm.dst_id AND d.name = sl.name
+			  )`},
+		{desc: "map labels", sql: `
+			INSERT OR IGNORE INTO merge_label_map (src_id, dst_id)
+			SELECT sl.id, MIN(d.id) FROM src.labels sl
+			LEFT JOIN merge_source_map sm ON sm.src_id = sl.source_id
+			JOIN main.labels d ON d.source_id IS sm.dst_id AND d.name = sl.name
+			WHERE sl.source_id IS NULL OR sm.dst_id IS NOT NULL
+			GROUP BY sl.id`},
+
+		// Conversations. Rows without a platform ID get a synthetic
+		// one so they can be mapped.
+		{desc: "merge conversations", counter: &result.Conversations, sql: `
+			INSERT INTO main.conversations
+				(source_id, source_conversation_id, conversation_type, title, metadata)
+			SELECT sm.dst_id,
+			       COALESCE(sc.source_conversation_id, 'merged-' || sc.id),
+			       sc.conversation_type, sc.title, sc.metadata
+			FROM src.conversations sc
+			JOIN merge_source_map sm ON sm.src_id = sc.source_id
+			WHERE NOT EXISTS (
+				SELECT 1 FROM main.conversations d
+				WHERE d.source_id = sm.dst_id
+				  AND d.source_conversation_id = COALESCE(sc.source_conversation_id, 'merged-' || sc.id)
+			)`},
+		{desc: "map conversations", sql: `
+			INSERT INTO merge_conversation_map (src_id, dst_id)
+			SELECT sc.id, d.id FROM src.conversations sc
+			JOIN merge_source_map sm ON sm.src_id = sc.source_id
+			JOIN main.conversations d
+			  ON d.source_id = sm.dst_id
+			 AND d.source_conversation_id = COALESCE(sc.source_conversation_id, 'merged-' || sc.id)`},
+		{desc: "merge conversation participants", sql: `
+			INSERT OR IGNORE INTO main.conversation_participants
+				(conversation_id, participant_id, role, joined_at, left_at)
+			SELECT cm.dst_id, pm.dst_id, cp.role, cp.joined_at, cp.left_at
+			FROM src.conversation_participants cp
+			JOIN merge_conversation_map cm ON cm.src_id = cp.conversation_id
+			JOIN merge_participant_map pm ON pm.src_id = cp.participant_id`},
+
+		// Messages: dedup by platform ID, then by RFC822 Message-ID.
+		{desc: "match messages by source id", sql: `
+			INSERT INTO merge_message_map (src_id, dst_id, is_new)
+			SELECT sm.id, d.id, 0 FROM src.messages sm
+			JOIN merge_source_map smap ON smap.src_id = sm.source_id
+			JOIN main.messages d
+			  ON d.source_id = smap.dst_id AND d.source_message_id = sm.source_message_id
+			WHERE sm.source_message_id IS NOT NULL`},
+		{desc: "match messages by rfc822 id", sql: `
+			INSERT OR IGNORE INTO merge_message_map (src_id, dst_id, is_new)
+			SELECT sm.id, MIN(d.id), 0 FROM src.messages sm
+			JOIN merge_source_map smap ON smap.src_id = sm.source_id
+			JOIN main.messages d
+			  ON d.source_id = smap.dst_id AND d.rfc822_message_id = sm.rfc822_message_id
+			WHERE sm.rfc822_message_id IS NOT NULL AND sm.rfc822_message_id != ''
+			GROUP BY sm.id`},
+		{desc: "insert messages", counter: &result.MessagesAdded, sql: `
+			INSERT INTO main.messages (
+				conversation_id, source_id, source_message_id, rfc822_message_id,
+				message_type, sent_at, received_at, read_at, delivered_at,
+				internal_date, sender_id, is_from_me, subject, snippet,
+				thread_position, is_read, is_delivered, is_sent, is_edited,
+				is_forwarded, size_estimate, has_attachments, attachment_count, language,
+				deleted_at, deleted_from_source_at, delete_batch_id,
+				archived_at, indexing_version, metadata
+			)
+			SELECT cm.dst_id, smap.dst_id, sm.source_message_id, sm.rfc822_message_id,
+			       sm.message_type, sm.sent_at, sm.received_at, sm.read_at, sm.delivered_at,
+			       sm.internal_date, pm.dst_id, sm.is_from_me, sm.subject, sm.snippet,
+			       sm.thread_position, sm.is_read, sm.is_delivered, sm.is_sent, sm.is_edited,
+			       sm.is_forwarded, sm.size_estimate, sm.has_attachments, sm.attachment_count, sm.language,
+			       sm.deleted_at, sm.deleted_from_source_at, sm.delete_batch_id,
+			       sm.archived_at, sm.indexing_version, sm.metadata
+			FROM src.messages sm
+			JOIN merge_source_map smap ON smap.src_id = sm.source_id
+			JOIN merge_conversation_map cm ON cm.src_id = sm.conversation_id
+			LEFT JOIN merge_participant_map pm ON pm.src_id = sm.sender_id
+			WHERE sm.source_message_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM merge_message_map mm WHERE mm.src_id = sm.id)
+			ORDER BY sm.id`},
+		{desc: "map new messages", sql: `
+			INSERT INTO merge_message_map (src_id, dst_id, is_new)
+			SELECT sm.id, d.id, 1 FROM src.messages sm
+			JOIN merge_source_map smap ON smap.src_id = sm.source_id
+			JOIN main.messages d
+			  ON d.source_id = smap.dst_id AND d.source_message_id = sm.source_message_id
+			WHERE sm.source_message_id IS NOT NULL
+			  AND NOT EXISTS (SELECT 1 FROM merge_message_map mm WHERE mm.src_id = sm.id)`},
+		{desc: "remap reply references", sql: `
+			UPDATE main.messages SET reply_to_message_id = (
+				SELECT rm.dst_id FROM merge_message_map mm
+				JOIN src.messages sm ON sm.id = mm.src_id
+				JOIN merge_message_map rm ON rm.src_id = sm.reply_to_message_id
+				WHERE mm.dst_id = main.messages.id
+			)
+			WHERE id IN (SELECT dst_id FROM merge_message_map WHERE is_new = 1)`},
+
+		// Per-message rows, only for newly inserted messages.
+		{desc: "merge message bodies", sql: `
+			INSERT INTO main.message_bodies
+				(message_id, body_text, body_html, body_quoted, body_signature)
+			SELECT mm.dst_id, sb.body_text, sb.body_html, sb.body_quoted, sb.body_signature
+			FROM src.message_bodies sb
+			JOIN merge_message_map mm ON mm.src_id = sb.message_id AND mm.is_new = 1`},
+		{desc: "merge raw messages", sql: `
+			INSERT INTO main.message_raw
+				(message_id, raw_data, raw_format, compression, encryption_version, blob_hash, blob_size)
+			SELECT mm.dst_id, sr.raw_data, sr.raw_format, sr.compression, sr.encryption_version,
+				sr.blob_hash, sr.blob_size
+			FROM src.message_raw sr
+			JOIN merge_message_map mm ON mm.src_id = sr.message_id AND mm.is_new = 1`},
+		{desc: "merge raw dictionaries", sql: `
+			INSERT OR IGNORE INTO main.raw_dictionaries (dict_id, data, sample_count, created_at)
+			SELECT dict_id, data, sample_count, created_at
+			FROM src.raw_dictionaries
+			ORDER BY id`},
+		{desc: "merge authentication results", sql: `
+			INSERT INTO main.message_auth
+				(message_id, dkim_result, dkim_domain, dkim_reason, authserv_id,
+				 reported_dkim, reported_spf, reported_dmarc, auth_results, checked_at)
+			SELECT mm.dst_id, sa.dkim_result, sa.dkim_domain, sa.dkim_reason, sa.authserv_id,
+				sa.reported_dkim, sa.reported_spf, sa.reported_dmarc, sa.auth_results, sa.checked_at
+			FROM src.message_auth sa
+			JOIN merge_message_map mm ON mm.src_id = sa.message_id AND mm.is_new = 1`},
+		{desc: "merge links", sql: `
+			INSERT INTO main.message_links (message_id, kind, url, domain)
+			SELECT mm.dst_id, sl.kind, sl.url, sl.domain
+			FROM src.message_links sl
+			JOIN merge_message_map mm ON mm.src_id = sl.message_id AND mm.is_new = 1`},
+		{desc: "merge indexed headers", sql: `
+			INSERT INTO main.message_headers (message_id, name, value)
+			SELECT mm.dst_id, sh.name, sh.value
+			FROM src.message_headers sh
+			JOIN merge_message_map mm ON mm.src_id = sh.message_id AND mm.is_new = 1`},
+		{desc: "merge inline parts", sql: `
+			INSERT INTO main.message_inline_parts
+				(message_id, content_id, filename, mime_type, size, content_hash)
+			SELECT mm.dst_id, si.content_id, si.filename, si.mime_type, si.size, si.content_hash
+			FROM src.message_inline_parts si
+			JOIN merge_message_map mm ON mm.src_id = si.message_id AND mm.is_new = 1`},
+		{desc: "merge risk scores", sql: `
+			INSERT INTO main.message_risk (message_id, score, reasons, scored_at)
+			SELECT mm.dst_id, sk.score, sk.reasons, sk.scored_at
+			FROM src.message_risk sk
+			JOIN merge_message_map mm ON mm.src_id = sk.message_id AND mm.is_new = 1`},
+		{desc: "merge notes", sql: `
+			INSERT INTO main.message_notes (message_id, note, pinned, updated_at)
+			SELECT mm.dst_id, sn.note, sn.pinned, sn.updated_at
+			FROM src.message_notes sn
+			JOIN merge_message_map mm ON mm.src_id = sn.message_id AND mm.is_new = 1`},
+		{desc: "merge tags", sql: `
+			INSERT INTO main.message_tags (message_id, tag, created_at)
+			SELECT mm.dst_id, st.tag, st.created_at
+			FROM src.message_tags st
+			JOIN merge_message_map mm ON mm.src_id = st.message_id AND mm.is_new = 1`},
+		{desc: "merge reminders", sql: `
+			INSERT INTO main.message_reminders (message_id, due_at, note, created_at, notified_at)
+			SELECT mm.dst_id, sr.due_at, sr.note, sr.created_at, sr.notified_at
+			FROM src.message_reminders sr
+			JOIN merge_message_map mm ON mm.src_id = sr.message_id AND mm.is_new = 1`},
+		{desc: "merge recipients", sql: `
+			INSERT OR IGNORE INTO main.message_recipients
+				(message_id, participant_id, recipient_type, display_name)
+			SELECT mm.dst_id, pm.dst_id, mr.recipient_type, mr.display_name
+			FROM src.message_recipients mr
+			JOIN merge_message_map mm ON mm.src_id = mr.message_id AND mm.is_new = 1
+			JOIN merge_participant_map pm ON pm.src_id = mr.participant_id`},
+		{desc: "merge reactions", sql: `
+			INSERT OR IGNORE INTO main.reactions
+				(message_id, participant_id, reaction_type, reaction_value, created_at, removed_at)
+			SELECT mm.dst_id, pm.dst_id, r.reaction_type, r.reaction_value, r.created_at, r.removed_at
+			FROM src.reactions r
+			JOIN merge_message_map mm ON mm.src_id = r.message_id AND mm.is_new = 1
+			JOIN merge_participant_map pm ON pm.src_id = r.participant_id`},
+		{desc: "merge attachments", counter: &result.AttachmentsAdded, sql: `
+			INSERT INTO main.attachments (
+				message_id, filename, mime_type, size, stored_size, content_hash, storage_path,
+				media_type, width, height, duration_ms, thumbnail_hash, thumbnail_path,
+				source_attachment_id, attachment_metadata, encryption_version,
+				scan_result, scan_signature, scanned_at, quarantined
+			)
+			SELECT mm.dst_id, a.filename, a.mime_type, a.size, a.stored_size, a.content_hash, a.storage_path,
+			       a.media_type, a.width, a.height, a.duration_ms, a.thumbnail_hash, a.thumbnail_path,
+			       a.source_attachment_id, a.attachment_metadata, a.encryption_version,
+			       a.scan_result, a.scan_signature, a.scanned_at, a.quarantined
+			FROM src.attachments a
+			JOIN merge_message_map mm ON mm.src_id = a.message_id AND mm.is_new = 1`},
+
+		// Labels are unioned for every mapped message, new or not.
+		{desc: "merge message labels", counter: &result.LabelLinksAdded, sql: `
+			INSERT OR IGNORE INTO main.message_labels (message_id, label_id)
+			SELECT mm.dst_id, lm.dst_id
+			FROM src.message_labels ml
+			JOIN merge_message_map mm ON mm.src_id = ml.message_id
+			JOIN merge_label_map lm ON lm.src_id = ml.label_id`},
+		{desc: "merge account identities", sql: `
+			INSERT OR IGNORE INTO main.account_identities
+				(source_id, address, source_signal, confirmed_at)
+			SELECT sm.dst_id, ai.address, ai.source_signal, ai.confirmed_at
+			FROM src.account_identities ai
+			JOIN merge_source_map sm ON sm.src_id = ai.source_id`},
+	}
+
+	for _, st := range steps {
+		res, err := tx.ExecContext(ctx, st.sql)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", st.desc, err)
+		}
+		if st.counter != nil {
+			n, err := res.RowsAffected()
+			if err != nil {
+				return nil, fmt.Errorf("%s rows affected: %w", st.desc, err)
+			}
+			*st.counter += n
+		}
+	}
+
+	var srcSources, srcMessages int64
+	if err := tx.QueryRowContext(ctx,
+		"SELECT (SELECT COUNT(*) FROM src.sources), (SELECT COUNT(*) FROM src.messages)",
+	).Scan(&srcSources, &srcMessages); err != nil {
+		return nil, fmt.Errorf("count source rows: %w", err)
+	}
+	result.SourcesMatched = srcSources - result.SourcesAdded
+	result.MessagesSkipped = srcMessages - result.MessagesAdded
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT a.storage_path FROM src.attachments a
+		JOIN merge_message_map mm ON mm.src_id = a.message_id AND mm.is_new = 1
+		WHERE a.storage_path != ''`)
+	if err != nil {
+		return nil, fmt.Errorf("list merged attachments: %w", err)
+	}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan attachment path: %w", err)
+		}
+		result.AttachmentPaths = append(result.AttachmentPaths, p)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close attachment rows: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate attachment paths: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `
+		SELECT DISTINCT sr.blob_hash FROM src.message_raw sr
+		JOIN merge_message_map mm ON mm.src_id = sr.message_id AND mm.is_new = 1
+		WHERE sr.blob_hash IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("list merged raw blobs: %w", err)
+	}
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan raw blob hash: %w", err)
+		}
+		result.RawBlobHashes = append(result.RawBlobHashes, h)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("close raw blob rows: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate raw blobs: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DROP TABLE IF EXISTS merge_source_map;
+		DROP TABLE IF EXISTS merge_participant_map;
+		DROP TABLE IF EXISTS merge_conversation_map;
+		DROP TABLE IF EXISTS merge_label_map;
+		DROP TABLE IF EXISTS merge_message_map;`); err != nil {
+		return nil, fmt.Errorf("drop map tables: %w", err)
+	}
+
+	return result, nil
+}
+
+// sameFile reports whether two paths refer to the same file on disk.
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
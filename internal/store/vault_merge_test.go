@@ -0,0 +1,133 @@
+package store_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/mime"
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+)
+
+// openMergeTestStore opens a fresh SQLite store at dir/msgvault.db.
+func openMergeTestStore(t *testing.T, dir string) (*store.Store, string) {
+	t.Helper()
+	dbPath := filepath.Join(dir, "msgvault.db")
+	st, err := store.Open(dbPath)
+	testutil.MustNoErr(t, err, "Open")
+	t.Cleanup(func() { _ = st.Close() })
+	testutil.MustNoErr(t, st.InitSchema(), "InitSchema")
+	return st, dbPath
+}
+
+// persistMergeMessage stores one email with a sender, a body, raw MIME,
+// and the given labels under source/thread.
+func persistMergeMessage(
+	t *testing.T, st *store.Store, sourceID int64,
+	thread, srcMsgID, from string, labels ...string,
+) int64 {
+	t.Helper()
+	convID, err := st.EnsureConversation(sourceID, thread, "Subject "+thread)
+	testutil.MustNoErr(t, err, "EnsureConversation")
+	pids, err := st.EnsureParticipantsBatch([]mime.Address{{Email: from, Name: "", Domain: "example.com"}})
+	testutil.MustNoErr(t, err, "EnsureParticipantsBatch")
+	var labelIDs []int64
+	for _, l := range labels {
+		id, err := st.EnsureLabel(sourceID, l, l, "user")
+		testutil.MustNoErr(t, err, "EnsureLabel")
+		labelIDs = append(labelIDs, id)
+	}
+	id, err := st.PersistMessage(&store.MessagePersistData{
+		Message: &store.Message{
+			ConversationID:  convID,
+			SourceID:        sourceID,
+			SourceMessageID: srcMsgID,
+			MessageType:     "email",
+			SenderID:        sql.NullInt64{Int64: pids[from], Valid: true},
+			Subject:         sql.NullString{String: "Subject " + thread, Valid: true},
+			SizeEstimate:    100,
+		},
+		BodyText: sql.NullString{String: "body of " + srcMsgID, Valid: true},
+		RawMIME:  []byte("From: " + from + "\r\nSubject: x\r\n\r\nbody"),
+		Recipients: []store.RecipientSet{
+			{Type: "from", ParticipantIDs: []int64{pids[from]}, DisplayNames: []string{""}},
+		},
+		LabelIDs: labelIDs,
+	})
+	testutil.MustNoErr(t, err, "PersistMessage")
+	return id
+}
+
+func TestMergeVault(t *testing.T) {
+	dst, _ := openMergeTestStore(t, t.TempDir())
+	src, srcPath := openMergeTestStore(t, t.TempDir())
+
+	dstA, err := dst.GetOrCreateSource("gmail", "alice@example.com")
+	testutil.MustNoErr(t, err, "dst source")
+	existingID := persistMergeMessage(t, dst, dstA.ID, "t1", "m1", "bob@example.com", "INBOX")
+
+	srcA, err := src.GetOrCreateSource("gmail", "alice@example.com")
+	testutil.MustNoErr(t, err, "src source A")
+	srcB, err := src.GetOrCreateSource("gmail", "carol@example.com")
+	testutil.MustNoErr(t, err, "src source B")
+	persistMergeMessage(t, src, srcA.ID, "t1", "m1", "bob@example.com", "Work")
+	persistMergeMessage(t, src, srcA.ID, "t2", "m2", "dave@example.com", "INBOX")
+	persistMergeMessage(t, src, srcB.ID, "t3", "m3", "bob@example.com")
+
+	res, err := dst.MergeVault(context.Background(), srcPath)
+	testutil.MustNoErr(t, err, "MergeVault")
+
+	if res.SourcesAdded != 1 || res.SourcesMatched != 1 {
+		t.Errorf("sources added/matched = %d/%d, want 1/1", res.SourcesAdded, res.SourcesMatched)
+	}
+	if res.MessagesAdded != 2 || res.MessagesSkipped != 1 {
+		t.Errorf("messages added/skipped = %d/%d, want 2/1", res.MessagesAdded, res.MessagesSkipped)
+	}
+
+	stats, err := dst.GetStats()
+	testutil.MustNoErr(t, err, "GetStats")
+	if stats.MessageCount != 3 {
+		t.Errorf("MessageCount = %d, want 3", stats.MessageCount)
+	}
+
+	// Existing message gains the other vault's label.
+	msg, err := dst.GetMessage(existingID)
+	testutil.MustNoErr(t, err, "GetMessage")
+	if len(msg.Labels) != 2 {
+		t.Errorf("labels on merged message = %v, want INBOX and Work", msg.Labels)
+	}
+
+	// New messages carry bodies, raw MIME, and remapped recipients.
+	ids, err := dst.MessageExistsBatch(dstA.ID, []string{"m2"})
+	testutil.MustNoErr(t, err, "MessageExistsBatch")
+	m2, err := dst.GetMessage(ids["m2"])
+	testutil.MustNoErr(t, err, "GetMessage m2")
+	if m2.Body != "body of m2" {
+		t.Errorf("m2 body = %q", m2.Body)
+	}
+	if m2.From != "dave@example.com" {
+		t.Errorf("m2 from = %q, want dave@example.com", m2.From)
+	}
+	raw, err := dst.GetMessageRaw(ids["m2"])
+	testutil.MustNoErr(t, err, "GetMessageRaw")
+	if len(raw) == 0 {
+		t.Error("m2 raw MIME not merged")
+	}
+
+	// Merging again is a no-op for messages.
+	res, err = dst.MergeVault(context.Background(), srcPath)
+	testutil.MustNoErr(t, err, "second MergeVault")
+	if res.MessagesAdded != 0 || res.LabelLinksAdded != 0 {
+		t.Errorf("second merge added %d messages, %d label links; want 0, 0",
+			res.MessagesAdded, res.LabelLinksAdded)
+	}
+}
+
+func TestMergeVault_RejectsSelf(t *testing.T) {
+	dst, dstPath := openMergeTestStore(t, t.TempDir())
+	if _, err := dst.MergeVault(context.Background(), dstPath); err == nil {
+		t.Fatal("expected error merging a vault into itself")
+	}
+}
@@ -0,0 +1,167 @@
+package sync
+
+import (
+	"context"
+	"errors"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// errNoMessage stands in for a fetch failure the source gave no error
+// for, as when a batch fetch leaves a message out.
+var errNoMessage = errors.New("no message returned")
+
+// loadFailures remembers which messages of a source have a recorded
+// sync failure, so archiving one resolves it.
+func (s *Syncer) loadFailures(sourceID int64) {
+	s.failed = make(map[string]bool)
+	failures, err := s.store.ListSyncFailures(sourceID)
+	if err != nil {
+		s.logger.Warn("failed to load sync failures", "error", err)
+		return
+	}
+	for _, f := range failures {
+		s.failed[f.SourceMessageID] = true
+	}
+}
+
+// recordFetchFailure records a message the source did not return. A
+// message deleted before it could be fetched is not a failure.
+func (s *Syncer) recordFetchFailure(sourceID int64, id, threadID string, err error) {
+	if isNotFound(err) {
+		s.resolveFailure(sourceID, id)
+		return
+	}
+	if err == nil {
+		err = errNoMessage
+	}
+	s.recordFailure(sourceID, id, threadID, store.SyncFailureFetch, err)
+}
+
+// recordIngestFailure records a message that was fetched but could not
+// be parsed or stored.
+func (s *Syncer) recordIngestFailure(sourceID int64, id, threadID string, err error) {
+	class := store.SyncFailureStore
+	if errors.Is(err, errParse) {
+		class = store.SyncFailureParse
+	}
+	s.recordFailure(sourceID, id, threadID, class, err)
+}
+
+func (s *Syncer) recordFailure(sourceID int64, id, threadID, class string, err error) {
+	if rerr := s.store.RecordSyncFailure(sourceID, id, threadID, class, err.Error()); rerr != nil {
+		s.logger.Warn("failed to record sync failure", "id", id, "error", rerr)
+		return
+	}
+	if s.failed != nil {
+		s.failed[id] = true
+	}
+}
+
+// resolveFailure forgets the recorded failure of a message, if it has
+// one.
+func (s *Syncer) resolveFailure(sourceID int64, id string) {
+	if !s.failed[id] {
+		return
+	}
+	if err := s.store.ResolveSyncFailure(sourceID, id); err != nil {
+		s.logger.Warn("failed to resolve sync failure", "id", id, "error", err)
+		return
+	}
+	delete(s.failed, id)
+}
+
+// retryFailures fetches again the messages earlier syncs of a source
+// failed on, one at a time, before the sync lists anything new. A
+// message that fails again counts another attempt; after
+// store.MaxSyncAttempts it is left for the user to look at with
+// "msgvault sync failures".
+func (s *Syncer) retryFailures(ctx context.Context, sourceID int64, labelMap map[string]int64, checkpoint *store.Checkpoint, summary *gmail.SyncSummary) error {
+	s.loadFailures(sourceID)
+	failures, err := s.store.SyncFailuresToRetry(sourceID)
+	if err != nil {
+		s.logger.Warn("failed to list sync failures", "error", err)
+		return nil
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(failures))
+	for i, f := range failures {
+		ids[i] = f.SourceMessageID
+	}
+	existing, err := s.store.MessageExistsWithRawBatch(sourceID, ids)
+	if err != nil {
+		s.logger.Warn("failed to check failed messages", "error", err)
+		return nil
+	}
+
+	s.logger.Info("retrying failed messages", "count", len(failures))
+	var insertedIDs []int64
+	for _, f := range failures {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id := f.SourceMessageID
+		if _, ok := existing[id]; ok {
+			s.resolveFailure(sourceID, id)
+			continue
+		}
+		checkpoint.MessagesProcessed++
+
+		raw, err := s.fetchMessage(ctx, id)
+		if err != nil || raw == nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !isNotFound(err) {
+				checkpoint.ErrorsCount++
+			}
+			s.recordFetchFailure(sourceID, id, f.ThreadID, err)
+			continue
+		}
+		// A stub with no content is a dedup skip, as in processBatch.
+		if raw.Raw == nil {
+			s.resolveFailure(sourceID, id)
+			continue
+		}
+		insertedID, err := s.ingestMessage(ctx, sourceID, raw, f.ThreadID, labelMap)
+		if errors.Is(err, errDuplicateRFC822) {
+			s.resolveFailure(sourceID, id)
+			continue
+		}
+		if err != nil {
+			s.logger.Warn("failed to ingest message on retry", "id", id, "error", err)
+			checkpoint.ErrorsCount++
+			s.recordIngestFailure(sourceID, id, f.ThreadID, err)
+			continue
+		}
+		s.resolveFailure(sourceID, id)
+		if insertedID > 0 {
+			insertedIDs = append(insertedIDs, insertedID)
+		}
+		checkpoint.MessagesAdded++
+		summary.BytesDownloaded += int64(len(raw.Raw))
+	}
+
+	if s.embedEnqueuer != nil && len(insertedIDs) > 0 {
+		if err := s.embedEnqueuer.EnqueueMessages(ctx, insertedIDs); err != nil {
+			s.logger.Warn("vector enqueue failed", "ids", len(insertedIDs), "error", err)
+		}
+	}
+	return nil
+}
+
+// hasFailuresToRetry reports whether a source has failed messages with
+// attempts left.
+func (s *Syncer) hasFailuresToRetry(sourceID int64) bool {
+	failures, err := s.store.SyncFailuresToRetry(sourceID)
+	return err == nil && len(failures) > 0
+}
+
+func isNotFound(err error) bool {
+	var notFound *gmail.NotFoundError
+	return errors.As(err, &notFound)
+}
@@ -0,0 +1,106 @@
+package sync
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// syncFailures returns the recorded sync failures of the test account.
+func syncFailures(t *testing.T, env *TestEnv) []store.SyncFailure {
+	t.Helper()
+	source := env.CreateSource(t)
+	failures, err := env.Store.ListSyncFailures(source.ID)
+	if err != nil {
+		t.Fatalf("ListSyncFailures: %v", err)
+	}
+	return failures
+}
+
+func TestFullSync_RecordsAndRetriesFailures(t *testing.T) {
+	env := newTestEnv(t)
+	seedMessages(env, 3, 12345, "msg1", "msg2", "msg3")
+	env.Mock.GetMessageError["msg2"] = errors.New("connection reset")
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(2), Errors: intPtr(1)})
+
+	failures := syncFailures(t, env)
+	if len(failures) != 1 {
+		t.Fatalf("failures after first sync = %+v, want msg2 only", failures)
+	}
+	f := failures[0]
+	if f.SourceMessageID != "msg2" || f.ErrorClass != store.SyncFailureFetch || f.Attempts != 1 || f.Account != testEmail {
+		t.Errorf("failure = %+v, want a first fetch failure of msg2", f)
+	}
+
+	// Still failing: the retry counts another attempt.
+	summary = runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(0), Errors: intPtr(2)})
+	if failures := syncFailures(t, env); len(failures) != 1 || failures[0].Attempts != 3 {
+		t.Errorf("failures after second sync = %+v, want msg2 at 3 attempts (retry and relist)", failures)
+	}
+
+	// Recovered: the retry archives it and the failure is resolved.
+	delete(env.Mock.GetMessageError, "msg2")
+	summary = runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(1), Errors: intPtr(0)})
+	if failures := syncFailures(t, env); len(failures) != 0 {
+		t.Errorf("failures after recovery = %+v, want none", failures)
+	}
+}
+
+func TestIncrementalSync_RetriesFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		retryErr  error
+		wantAdded int64
+	}{
+		{"recovered", nil, 1},
+		{"deleted from source", &gmail.NotFoundError{Path: "/messages/new1"}, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			env.CreateSourceWithHistory(t, "12340")
+			env.Mock.AddMessage("new1", testMIME(), []string{"INBOX"})
+			env.SetHistory(12350, historyAdded("new1"))
+			env.Mock.GetMessageError["new1"] = errors.New("connection reset")
+
+			summary := runIncrementalSync(t, env)
+			assertSummary(t, summary, WantSummary{Added: intPtr(0), Errors: intPtr(1)})
+			if failures := syncFailures(t, env); len(failures) != 1 || failures[0].ThreadID != "thread_new1" {
+				t.Fatalf("failures = %+v, want new1 in thread_new1", failures)
+			}
+
+			// Nothing new in history; the retry alone runs.
+			env.SetHistory(12350)
+			env.Mock.GetMessageError["new1"] = tc.retryErr
+			summary = runIncrementalSync(t, env)
+			assertSummary(t, summary, WantSummary{Added: intPtr(tc.wantAdded), Errors: intPtr(0)})
+			if failures := syncFailures(t, env); len(failures) != 0 {
+				t.Errorf("failures after retry = %+v, want none", failures)
+			}
+		})
+	}
+}
+
+func TestFullSync_StopsRetryingAfterMaxAttempts(t *testing.T) {
+	env := newTestEnv(t)
+	seedMessages(env, 1, 12345, "msg1")
+	source := env.CreateSource(t)
+	for range store.MaxSyncAttempts {
+		if err := env.Store.RecordSyncFailure(source.ID, "gone", "", store.SyncFailureFetch, "connection reset"); err != nil {
+			t.Fatalf("RecordSyncFailure: %v", err)
+		}
+	}
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(1), Errors: intPtr(0)})
+	failures := syncFailures(t, env)
+	if len(failures) != 1 || failures[0].Attempts != store.MaxSyncAttempts {
+		t.Errorf("failures = %+v, want gone left alone at %d attempts", failures, store.MaxSyncAttempts)
+	}
+}
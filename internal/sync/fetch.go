@@ -0,0 +1,104 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"golang.org/x/time/rate"
+)
+
+// fetchEach fetches the messages ids and calls fn for each, in the order
+// of ids, with nil for a message that could not be fetched and the
+// error, when known, that stopped it. fn runs on the calling goroutine,
+// so it may write to the store.
+//
+// With Options.FetchWorkers above 1, that many workers fetch messages
+// one at a time through FetchMessage, each paced to Options.FetchRate
+// messages per second when that is set, and fn is called for each
+// message as soon as it and every message before it have arrived. The
+// caller therefore still sees the page in list order and can checkpoint
+// after it exactly as with a serial fetch. Otherwise the source's own
+// FetchMessages fetches the whole batch first.
+func (s *Syncer) fetchEach(ctx context.Context, ids []string, fn func(i int, raw *gmail.RawMessage, fetchErr error)) error {
+	workers := min(s.opts.FetchWorkers, len(ids))
+	if workers <= 1 {
+		raws, err := s.fetchMessages(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for i := range ids {
+			var raw *gmail.RawMessage
+			if i < len(raws) {
+				raw = raws[i]
+			}
+			fn(i, raw, nil)
+		}
+		return nil
+	}
+
+	// Workers are stopped before they are waited for.
+	var wg gosync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]*gmail.RawMessage, len(ids))
+	errs := make([]error, len(ids))
+	done := make([]chan struct{}, len(ids))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	next := make(chan int)
+	for range workers {
+		var limiter *rate.Limiter
+		if s.opts.FetchRate > 0 {
+			limiter = rate.NewLimiter(rate.Limit(s.opts.FetchRate), 1)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					close(done[i])
+					continue
+				}
+				raw, err := s.fetchMessage(ctx, ids[i])
+				if err != nil && ctx.Err() == nil {
+					var nfe *gmail.NotFoundError
+					if errors.As(err, &nfe) {
+						s.logger.Debug("message deleted before fetch", "id", ids[i])
+					} else {
+						s.logger.Warn("failed to fetch message", "id", ids[i], "error", err)
+					}
+				}
+				results[i], errs[i] = raw, err
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		defer close(next)
+		for i := range ids {
+			select {
+			case next <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := range ids {
+		select {
+		case <-done[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fn(i, results[i], errs[i])
+	}
+	return nil
+}
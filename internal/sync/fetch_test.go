@@ -0,0 +1,115 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	testemail "github.com/wesm/msgvault/internal/testutil/email"
+)
+
+func TestFullSync_FetchWorkers(t *testing.T) {
+	env := newTestEnv(t, &Options{FetchWorkers: 4, FetchRate: 1000})
+	env.Mock.Profile.HistoryID = 12345
+	seedPagedMessages(env, 10, 4, "msg")
+	env.Mock.GetMessageError["msg6"] = errors.New("connection reset")
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(9), Errors: intPtr(1)})
+
+	// Messages are stored in list order however the workers finish.
+	rows, err := env.Store.DB().Query(`SELECT source_message_id FROM messages ORDER BY id`)
+	if err != nil {
+		t.Fatalf("list messages: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var got []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, id)
+	}
+	var want []string
+	for i := 1; i <= 10; i++ {
+		if i != 6 {
+			want = append(want, fmt.Sprintf("msg%d", i))
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("stored order = %v, want %v", got, want)
+	}
+}
+
+func TestIncrementalSync_FetchWorkers(t *testing.T) {
+	env := newTestEnv(t, &Options{FetchWorkers: 3})
+	env.CreateSourceWithHistory(t, "12340")
+	env.Mock.Profile.MessagesTotal = 3
+	env.Mock.AddMessage("new1", testMIME(), []string{"INBOX"})
+	env.Mock.AddMessage("new2", testMIME(), []string{"INBOX"})
+	env.Mock.AddMessage("new3", testMIME(), []string{"INBOX"})
+	env.SetHistory(12350,
+		historyAdded("new1"), historyAdded("new2"), historyAdded("new3"))
+
+	summary := runIncrementalSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(3)})
+}
+
+func TestFetchEach_Canceled(t *testing.T) {
+	env := newTestEnv(t, &Options{FetchWorkers: 2})
+	seedMessages(env, 3, 1, "a", "b", "c")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var seen []string
+	err := env.Syncer.fetchEach(ctx, []string{"a", "b", "c"}, func(i int, raw *gmail.RawMessage, _ error) {
+		if raw != nil {
+			seen = append(seen, raw.ID)
+		}
+		cancel()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("fetchEach after cancel = %v, want context.Canceled", err)
+	}
+	if !slices.Equal(seen, []string{"a"}) {
+		t.Errorf("fetchEach delivered %v after cancel, want only [a]", seen)
+	}
+}
+
+func TestFullSync_WriteBatch(t *testing.T) {
+	env := newTestEnv(t, &Options{WriteBatch: 3})
+	env.Mock.Profile.HistoryID = 12345
+	seedPagedMessages(env, 10, 4, "msg")
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(10), Errors: intPtr(0)})
+
+	var n int
+	if err := env.Store.DB().QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&n); err != nil {
+		t.Fatalf("count messages: %v", err)
+	}
+	if n != 10 {
+		t.Errorf("stored %d messages, want 10", n)
+	}
+}
+
+// TestFullSync_WriteBatchDedupsQueuedCopies checks that a source
+// without stable IDs still dedups two copies of a message that land in
+// the same write batch.
+func TestFullSync_WriteBatchDedupsQueuedCopies(t *testing.T) {
+	env := newTestEnv(t)
+	opts := DefaultOptions()
+	opts.SourceType = "imap"
+	env.Syncer = New(env.Mock, env.Store, opts)
+
+	raw := testemail.NewMessage().Header("Message-ID", "<same@example.com>").Body("hello").Bytes()
+	env.Mock.Profile.MessagesTotal = 2
+	env.Mock.AddMessage("INBOX|1", raw, []string{"INBOX"})
+	env.Mock.AddMessage("Archive|7", raw, []string{"INBOX"})
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(1), Errors: intPtr(0)})
+}
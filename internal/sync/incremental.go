@@ -8,12 +8,18 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/wesm/msgvault/internal/crash"
 	"github.com/wesm/msgvault/internal/gmail"
 	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-// Incremental performs an incremental sync using the Gmail History API.
-// Falls back to full sync if history is too old (404 error).
+// Incremental performs an incremental sync from the source's change
+// history (the Gmail History API for Gmail). It returns
+// ErrHistoryExpired if the cursor is too old, and an error for sources
+// without the History capability.
 //
 // The caller must resolve the correct *store.Source before calling this
 // method. This avoids ambiguity when multiple sources share the same
@@ -22,6 +28,14 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 	if source == nil {
 		return nil, fmt.Errorf("no source provided - run full sync first")
 	}
+	if !s.caps.History {
+		return nil, fmt.Errorf("%s sources do not support incremental sync - run a full sync", source.SourceType)
+	}
+	if err := s.initMetadataOnly(); err != nil {
+		return nil, err
+	}
+	ctx, span := tracing.Start(ctx, "sync.incremental", trace.WithAttributes(attribute.String("account", source.Identifier)))
+	defer tracing.End(span, &err)
 
 	startTime := time.Now()
 	summary = &gmail.SyncSummary{StartTime: startTime}
@@ -31,9 +45,15 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 		return nil, fmt.Errorf("no history ID for %s - run full sync first", source.Identifier)
 	}
 
-	startHistoryID, err := strconv.ParseUint(source.SyncCursor.String, 10, 64)
-	if err != nil {
-		return nil, fmt.Errorf("invalid history ID %q: %w", source.SyncCursor.String, err)
+	// A CursorSource reads its own position from the stored cursor;
+	// only a numeric history ID is parsed and compared here.
+	cursorSource, ownCursor := s.source.(CursorSource)
+	var startHistoryID uint64
+	if !ownCursor {
+		startHistoryID, err = strconv.ParseUint(source.SyncCursor.String, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history ID %q: %w", source.SyncCursor.String, err)
+		}
 	}
 
 	// Start sync
@@ -41,6 +61,8 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 	if err != nil {
 		return nil, fmt.Errorf("start sync: %w", err)
 	}
+	defer s.scopeLogger(source.Identifier, syncID)()
+	span.SetAttributes(attribute.Int64("sync_run_id", syncID))
 
 	// Defer failure handling — recover from panics and return as error
 	defer func() {
@@ -52,20 +74,28 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 			}
 			summary = nil
 			err = fmt.Errorf("sync panicked: %v", r)
+			if path, reportErr := crash.Report(r, stack); reportErr != nil {
+				s.logger.Error("failed to write crash report", "error", reportErr)
+			} else if path != "" {
+				err = fmt.Errorf("sync panicked: %v (crash report: %s; please file a bug at %s)", r, path, crash.IssuesURL)
+			}
 		}
 	}()
 
 	// Get profile for current history ID
-	profile, err := s.client.GetProfile(ctx)
+	profile, err := s.source.Profile(ctx)
 	if err != nil {
 		_ = s.store.FailSync(syncID, err.Error())
 		return nil, fmt.Errorf("get profile: %w", err)
 	}
 
 	s.logger.Info("incremental sync", "email", source.Identifier, "start_history", startHistoryID, "current_history", profile.HistoryID)
+	s.recordRemoteTotal(source.ID, profile.MessagesTotal)
+	s.recordStorageQuota(ctx, source.ID)
 
-	// If history IDs match, nothing to do
-	if startHistoryID >= profile.HistoryID {
+	// If history IDs match and no earlier failure awaits a retry,
+	// nothing to do
+	if !ownCursor && startHistoryID >= profile.HistoryID && !s.hasFailuresToRetry(source.ID) {
 		s.logger.Info("already up to date")
 		_ = s.store.CompleteSync(syncID, strconv.FormatUint(profile.HistoryID, 10))
 		summary.EndTime = time.Now()
@@ -81,18 +111,27 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 		return nil, fmt.Errorf("sync labels: %w", err)
 	}
 
-	// Process history
 	checkpoint := &store.Checkpoint{}
+	if err := s.retryFailures(ctx, source.ID, labelMap, checkpoint, summary); err != nil {
+		_ = s.store.FailSync(syncID, err.Error())
+		return nil, err
+	}
+
+	// Process history
 	pageToken := ""
 
 	for {
-		historyResp, err := s.client.ListHistory(ctx, startHistoryID, pageToken)
+		historyResp, err := s.source.ListChanges(ctx, startHistoryID, pageToken)
 		if err != nil {
 			// Check for 404 - history too old
 			var notFound *gmail.NotFoundError
 			if errors.As(err, &notFound) {
-				s.logger.Warn("history too old, falling back to full sync")
 				_ = s.store.FailSync(syncID, "history too old")
+				if s.opts.RecoverExpiredHistory {
+					s.logger.Warn("history too old, recovering with a full sync")
+					return s.Recover(ctx, source)
+				}
+				s.logger.Warn("history too old, falling back to full sync")
 				// Caller should trigger full sync
 				return nil, ErrHistoryExpired
 			}
@@ -130,14 +169,23 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 
 		for _, record := range historyResp.History {
 			for _, msg := range record.MessagesAdded {
-				if _, exists := existingMap[msg.Message.ID]; !exists {
-					newMsgThreads[msg.Message.ID] = msg.Message.ThreadID
+				if _, exists := existingMap[msg.Message.ID]; exists {
+					continue
 				}
+				// Skip before fetching when the record names the labels.
+				if msg.Message.LabelIDs != nil && !s.labels.match(msg.Message.LabelIDs) {
+					continue
+				}
+				newMsgThreads[msg.Message.ID] = msg.Message.ThreadID
 			}
 			for _, msg := range record.MessagesDeleted {
 				deletedSet[msg.Message.ID] = true
 			}
-			s.processLabelChanges(ctx, source.ID, record, labelMap, existingMap, updatedExisting)
+		}
+		// Label changes to messages added on this page arrive with
+		// the batch fetch below, so they are not fetched one by one.
+		for _, record := range historyResp.History {
+			s.processLabelChanges(ctx, source.ID, record, labelMap, existingMap, newMsgThreads, updatedExisting)
 		}
 		checkpoint.MessagesUpdated += int64(len(updatedExisting))
 		checkpoint.MessagesProcessed += int64(len(newMsgThreads) + len(deletedSet) + len(updatedExisting))
@@ -152,38 +200,47 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 
 		// Batch-fetch and ingest new messages
 		if len(newMsgIDs) > 0 {
-			rawMessages, fetchErr := s.client.GetMessagesRawBatch(ctx, newMsgIDs)
-			if fetchErr != nil {
-				s.logger.Warn("failed to batch fetch messages", "error", fetchErr)
-				checkpoint.ErrorsCount += int64(len(newMsgIDs))
-			} else {
-				var insertedIDs []int64
-				for i, raw := range rawMessages {
-					if raw == nil {
-						s.logger.Warn("failed to fetch message (nil response)", "id", newMsgIDs[i])
-						checkpoint.ErrorsCount++
-						continue
-					}
-					threadID := newMsgThreads[newMsgIDs[i]]
-					insertedID, err := s.ingestMessage(ctx, source.ID, raw, threadID, labelMap)
+			var insertedIDs []int64
+			fetched := 0
+			fetchErr := s.fetchEach(ctx, newMsgIDs, func(i int, raw *gmail.RawMessage, fetchErr error) {
+				fetched++
+				threadID := newMsgThreads[newMsgIDs[i]]
+				if raw == nil {
+					s.logger.Warn("failed to fetch message (nil response)", "id", newMsgIDs[i])
+					checkpoint.ErrorsCount++
+					s.recordFetchFailure(source.ID, newMsgIDs[i], threadID, fetchErr)
+					return
+				}
+				if !s.labels.match(raw.LabelIDs) {
+					return
+				}
+				id, size := newMsgIDs[i], int64(len(raw.Raw))
+				s.queueIngest(ctx, source.ID, raw, threadID, labelMap, func(insertedID int64, err error) {
 					if err != nil {
-						s.logger.Warn("failed to ingest added message", "id", newMsgIDs[i], "error", err)
+						s.logger.Warn("failed to ingest added message", "id", id, "error", err)
 						checkpoint.ErrorsCount++
-						continue
+						s.recordIngestFailure(source.ID, id, threadID, err)
+						return
 					}
+					s.resolveFailure(source.ID, id)
 					if insertedID > 0 {
 						insertedIDs = append(insertedIDs, insertedID)
 					}
 					checkpoint.MessagesAdded++
-					summary.BytesDownloaded += int64(len(raw.Raw))
-				}
+					summary.BytesDownloaded += size
+				})
+			})
+			s.flushWrites()
+			if fetchErr != nil {
+				s.logger.Warn("failed to batch fetch messages", "error", fetchErr)
+				checkpoint.ErrorsCount += int64(len(newMsgIDs) - fetched)
+			}
 
-				// Hook vector-search enqueue. Non-fatal on failure: missed
-				// IDs get picked up by full-rebuild.
-				if s.embedEnqueuer != nil && len(insertedIDs) > 0 {
-					if err := s.embedEnqueuer.EnqueueMessages(ctx, insertedIDs); err != nil {
-						s.logger.Warn("vector enqueue failed", "ids", len(insertedIDs), "error", err)
-					}
+			// Hook vector-search enqueue. Non-fatal on failure: missed
+			// IDs get picked up by full-rebuild.
+			if s.embedEnqueuer != nil && len(insertedIDs) > 0 {
+				if err := s.embedEnqueuer.EnqueueMessages(ctx, insertedIDs); err != nil {
+					s.logger.Warn("vector enqueue failed", "ids", len(insertedIDs), "error", err)
 				}
 			}
 		}
@@ -216,13 +273,18 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 	// message doesn't block all future incremental syncs.
 	// Failed messages can be recovered via full sync.
 	historyIDStr := strconv.FormatUint(profile.HistoryID, 10)
+	if ownCursor {
+		historyIDStr = cursorSource.Cursor()
+	}
 	if checkpoint.ErrorsCount > 0 {
 		s.logger.Warn("incremental sync completed with errors",
 			"errors", checkpoint.ErrorsCount,
 			"history_id", historyIDStr)
 	}
-	if err := s.store.UpdateSourceSyncCursor(source.ID, historyIDStr); err != nil {
-		s.logger.Warn("failed to update sync cursor", "error", err)
+	if historyIDStr != "" {
+		if err := s.store.UpdateSourceSyncCursor(source.ID, historyIDStr); err != nil {
+			s.logger.Warn("failed to update sync cursor", "error", err)
+		}
 	}
 
 	// Mark sync complete
@@ -244,10 +306,23 @@ func (s *Syncer) Incremental(ctx context.Context, source *store.Source) (summary
 }
 
 // processLabelChanges handles label additions and removals for messages.
-// existingMap maps source_message_id -> internal message_id for known messages.
-func (s *Syncer) processLabelChanges(ctx context.Context, sourceID int64, record gmail.HistoryRecord, labelMap map[string]int64, existingMap map[string]int64, updatedExisting map[string]struct{}) {
+// existingMap maps source_message_id -> internal message_id for known messages;
+// pending holds new messages already queued for fetching.
+func (s *Syncer) processLabelChanges(ctx context.Context, sourceID int64, record gmail.HistoryRecord, labelMap map[string]int64, existingMap map[string]int64, pending map[string]string, updatedExisting map[string]struct{}) {
 	for _, item := range record.LabelsAdded {
-		updated, err := s.handleLabelChange(ctx, sourceID, item.Message.ID, item.Message.ThreadID, item.LabelIDs, labelMap, true, existingMap)
+		if _, queued := pending[item.Message.ID]; queued {
+			continue
+		}
+		// A new message is archived once it gains an included label,
+		// judged by all its labels where the record names them.
+		labels := item.Message.LabelIDs
+		if labels == nil {
+			labels = item.LabelIDs
+		}
+		if _, exists := existingMap[item.Message.ID]; !exists && !s.labels.match(labels) {
+			continue
+		}
+		updated, err := s.handleLabelChange(ctx, sourceID, record.ID, item.Message.ID, item.Message.ThreadID, item.LabelIDs, labelMap, true, existingMap)
 		if err != nil {
 			s.logLabelChangeError("add", item.Message.ID, err)
 			continue
@@ -257,7 +332,7 @@ func (s *Syncer) processLabelChanges(ctx context.Context, sourceID int64, record
 		}
 	}
 	for _, item := range record.LabelsRemoved {
-		updated, err := s.handleLabelChange(ctx, sourceID, item.Message.ID, item.Message.ThreadID, item.LabelIDs, labelMap, false, existingMap)
+		updated, err := s.handleLabelChange(ctx, sourceID, record.ID, item.Message.ID, item.Message.ThreadID, item.LabelIDs, labelMap, false, existingMap)
 		if err != nil {
 			s.logLabelChangeError("remove", item.Message.ID, err)
 			continue
@@ -269,15 +344,16 @@ func (s *Syncer) processLabelChanges(ctx context.Context, sourceID int64, record
 }
 
 // handleLabelChange processes a label addition or removal.
-// For existing messages, applies the label diff directly without any API calls.
+// For existing messages, applies the label diff directly without any API calls,
+// recording it in the label history under historyID.
 // For unknown messages with labels being added, fetches and ingests the message.
-func (s *Syncer) handleLabelChange(ctx context.Context, sourceID int64, messageID, threadID string, gmailLabelIDs []string, labelMap map[string]int64, isAdd bool, existingMap map[string]int64) (bool, error) {
+func (s *Syncer) handleLabelChange(ctx context.Context, sourceID int64, historyID uint64, messageID, threadID string, gmailLabelIDs []string, labelMap map[string]int64, isAdd bool, existingMap map[string]int64) (bool, error) {
 	internalID, exists := existingMap[messageID]
 
 	if !exists {
 		// Message doesn't exist locally - if adding labels, we should fetch it
 		if isAdd {
-			raw, err := s.client.GetMessageRaw(ctx, messageID)
+			raw, err := s.fetchMessage(ctx, messageID)
 			if err != nil {
 				return false, err
 			}
@@ -306,10 +382,7 @@ func (s *Syncer) handleLabelChange(ctx context.Context, sourceID int64, messageI
 	}
 
 	// Apply label diff directly — no API call needed
-	if isAdd {
-		return true, s.store.AddMessageLabels(internalID, labelIDs)
-	}
-	return true, s.store.RemoveMessageLabels(internalID, labelIDs)
+	return true, s.store.ApplyLabelHistory(internalID, labelIDs, isAdd, strconv.FormatUint(historyID, 10))
 }
 
 // logLabelChangeError logs label change errors, downgrading "not found"
@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/gmail"
+)
+
+// labelFilter picks the messages a sync archives by their source
+// label IDs. The zero value matches every message.
+type labelFilter struct {
+	// include holds the label IDs of Options.IncludeLabels; a message
+	// needs one of them.
+	include map[string]bool
+	// exclude holds the label IDs of Options.ExcludeLabels; a message
+	// must have none of them.
+	exclude map[string]bool
+	// query is the Gmail search for mail the filter matches.
+	query string
+}
+
+// newLabelFilter resolves include and exclude, each a label's name or
+// ID in any case, against the source's labels.
+func newLabelFilter(labels []*gmail.Label, include, exclude []string) (labelFilter, error) {
+	var f labelFilter
+	var err error
+	var includeTerms, excludeTerms []string
+	if f.include, includeTerms, err = resolveLabels(labels, include); err != nil {
+		return labelFilter{}, err
+	}
+	if f.exclude, excludeTerms, err = resolveLabels(labels, exclude); err != nil {
+		return labelFilter{}, err
+	}
+	var terms []string
+	switch len(includeTerms) {
+	case 0:
+	case 1:
+		terms = append(terms, includeTerms[0])
+	default:
+		terms = append(terms, "{"+strings.Join(includeTerms, " ")+"}")
+	}
+	for _, t := range excludeTerms {
+		terms = append(terms, "-"+t)
+	}
+	f.query = strings.Join(terms, " ")
+	return f, nil
+}
+
+// resolveLabels returns the IDs of the labels named by names, and the
+// Gmail search term for each; nil for no names.
+func resolveLabels(labels []*gmail.Label, names []string) (map[string]bool, []string, error) {
+	if len(names) == 0 {
+		return nil, nil, nil
+	}
+	ids := make(map[string]bool, len(names))
+	terms := make([]string, 0, len(names))
+	for _, name := range names {
+		l := findLabel(labels, name)
+		if l == nil {
+			return nil, nil, fmt.Errorf("no label named %q", name)
+		}
+		if !ids[l.ID] {
+			ids[l.ID] = true
+			terms = append(terms, labelSearchTerm(l))
+		}
+	}
+	return ids, terms, nil
+}
+
+// findLabel returns the label whose ID or name is name, preferring an
+// exact ID, or nil.
+func findLabel(labels []*gmail.Label, name string) *gmail.Label {
+	name = strings.TrimSpace(name)
+	for _, l := range labels {
+		if l.ID == name {
+			return l
+		}
+	}
+	for _, l := range labels {
+		if strings.EqualFold(l.Name, name) || strings.EqualFold(l.ID, name) {
+			return l
+		}
+	}
+	return nil
+}
+
+// labelSearchTerm returns the Gmail search term for mail with label l.
+// Category tabs are searched by category; other labels by name.
+func labelSearchTerm(l *gmail.Label) string {
+	if cat, ok := strings.CutPrefix(l.ID, "CATEGORY_"); ok {
+		return "category:" + strings.ToLower(cat)
+	}
+	if strings.ContainsAny(l.Name, " \"(){}") {
+		return `label:"` + strings.ReplaceAll(l.Name, `"`, "") + `"`
+	}
+	return "label:" + l.Name
+}
+
+// match reports whether a message with labelIDs passes the filter.
+func (f labelFilter) match(labelIDs []string) bool {
+	included := f.include == nil
+	for _, id := range labelIDs {
+		if f.exclude[id] {
+			return false
+		}
+		if f.include[id] {
+			included = true
+		}
+	}
+	return included
+}
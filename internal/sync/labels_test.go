@@ -0,0 +1,256 @@
+package sync
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+)
+
+// labelFilterLabels are the labels of the label filter tests.
+var labelFilterLabels = []*gmail.Label{
+	{ID: "INBOX", Name: "INBOX", Type: "system"},
+	{ID: "CATEGORY_SOCIAL", Name: "CATEGORY_SOCIAL", Type: "system"},
+	{ID: "Label_1", Name: "Work", Type: "user"},
+	{ID: "Label_2", Name: "Work Stuff", Type: "user"},
+	{ID: "SPAM", Name: "SPAM", Type: "system"},
+	{ID: "TRASH", Name: "TRASH", Type: "system"},
+}
+
+func TestNewLabelFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		wantQuery string
+		wantIDs   []string
+		wantErr   string
+	}{
+		{name: "none"},
+		{name: "system label", include: []string{"inbox"}, wantQuery: "label:INBOX", wantIDs: []string{"INBOX"}},
+		{name: "by ID", include: []string{"Label_1"}, wantQuery: "label:Work", wantIDs: []string{"Label_1"}},
+		{
+			name:      "several",
+			include:   []string{"INBOX", "work stuff", "CATEGORY_SOCIAL"},
+			wantQuery: `{label:INBOX label:"Work Stuff" category:social}`,
+			wantIDs:   []string{"CATEGORY_SOCIAL", "INBOX", "Label_2"},
+		},
+		{name: "duplicates", include: []string{"Work", "Label_1"}, wantQuery: "label:Work", wantIDs: []string{"Label_1"}},
+		{name: "unknown", include: []string{"INBOX", "Wrok"}, wantErr: `no label named "Wrok"`},
+		{name: "exclude", exclude: []string{"spam", "TRASH"}, wantQuery: "-label:SPAM -label:TRASH"},
+		{
+			name:      "include and exclude",
+			include:   []string{"Work", "INBOX"},
+			exclude:   []string{"CATEGORY_SOCIAL"},
+			wantQuery: "{label:Work label:INBOX} -category:social",
+			wantIDs:   []string{"INBOX", "Label_1"},
+		},
+		{name: "unknown exclude", exclude: []string{"Spma"}, wantErr: `no label named "Spma"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := newLabelFilter(labelFilterLabels, tt.include, tt.exclude)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newLabelFilter() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newLabelFilter(): %v", err)
+			}
+			if f.query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", f.query, tt.wantQuery)
+			}
+			var ids []string
+			for id := range f.include {
+				ids = append(ids, id)
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("include = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestLabelFilter_Match(t *testing.T) {
+	tests := []struct {
+		include []string
+		exclude []string
+		labels  []string
+		want    bool
+	}{
+		{[]string{"Work"}, nil, []string{"INBOX", "Label_1"}, true},
+		{[]string{"Work"}, nil, []string{"INBOX"}, false},
+		{[]string{"Work"}, nil, nil, false},
+		{nil, []string{"SPAM"}, []string{"INBOX"}, true},
+		{nil, []string{"SPAM"}, []string{"SPAM", "Label_1"}, false},
+		{nil, []string{"SPAM"}, nil, true},
+		{[]string{"Work"}, []string{"TRASH"}, []string{"TRASH", "Label_1"}, false},
+	}
+	for _, tt := range tests {
+		f, err := newLabelFilter(labelFilterLabels, tt.include, tt.exclude)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.match(tt.labels); got != tt.want {
+			t.Errorf("include %v, exclude %v: match(%v) = %v, want %v",
+				tt.include, tt.exclude, tt.labels, got, tt.want)
+		}
+	}
+	if !(labelFilter{}).match(nil) {
+		t.Error("zero labelFilter does not match every message")
+	}
+}
+
+func TestFullSync_IncludeLabels(t *testing.T) {
+	env := newTestEnv(t, &Options{IncludeLabels: []string{"work"}, Query: "after:2020/01/01"})
+	env.Mock.Labels = labelFilterLabels
+	seedMessages(env, 2, 12345, "msg1")
+	env.Mock.AddMessage("msg2", testMIME(), []string{"INBOX", "Label_1"})
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(1)})
+	assertMessageCount(t, env.Store, 1)
+	assertMessageHasLabel(t, env.Store, "msg2", "Label_1")
+	if want := "after:2020/01/01 label:Work"; env.Mock.LastQuery != want {
+		t.Errorf("list query = %q, want %q", env.Mock.LastQuery, want)
+	}
+}
+
+func TestFullSync_IncludeLabelsUnknown(t *testing.T) {
+	env := newTestEnv(t, &Options{IncludeLabels: []string{"Wrok"}})
+	seedMessages(env, 1, 12345, "msg1")
+
+	if _, err := env.Syncer.Full(context.Background(), testEmail); err == nil ||
+		!strings.Contains(err.Error(), `no label named "Wrok"`) {
+		t.Fatalf("Full() error = %v, want unknown label", err)
+	}
+	assertListMessagesCalls(t, env, 0)
+}
+
+func TestIncrementalSync_IncludeLabels(t *testing.T) {
+	env := newTestEnv(t, &Options{IncludeLabels: []string{"Work"}})
+	env.Mock.Labels = labelFilterLabels
+	source := env.CreateSourceWithHistory(t, "12340")
+
+	work := []string{"INBOX", "Label_1"}
+	inbox := []string{"INBOX"}
+	for id, labels := range map[string][]string{
+		"inbox-added": inbox, "work-added": work, "unlabeled-added": work,
+		"work-labeled": work, "inbox-labeled": inbox,
+	} {
+		env.Mock.AddMessage(id, testMIME(), labels)
+	}
+	added := func(id string, labels []string) gmail.HistoryRecord {
+		r := historyAdded(id)
+		r.MessagesAdded[0].Message.LabelIDs = labels
+		return r
+	}
+	env.SetHistory(12350,
+		added("inbox-added", inbox),
+		added("work-added", work),
+		// Without labels in the record, the fetched message decides.
+		historyAdded("unlabeled-added"),
+		historyLabelAdded("work-labeled", "Label_1"),
+		historyLabelAdded("inbox-labeled", "STARRED"),
+	)
+
+	if _, err := env.Syncer.Incremental(env.Context, source); err != nil {
+		t.Fatalf("incremental sync: %v", err)
+	}
+	assertMessageCount(t, env.Store, 3)
+	for _, id := range []string{"inbox-added", "inbox-labeled"} {
+		if slices.Contains(env.Mock.GetMessageCalls, id) {
+			t.Errorf("fetched %s, which has no included label", id)
+		}
+	}
+	for _, id := range []string{"work-added", "unlabeled-added", "work-labeled"} {
+		assertMessageHasLabel(t, env.Store, id, "Label_1")
+	}
+}
+
+func TestFullSync_ExcludeLabels(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      Options
+		wantAdded int64
+		wantQuery string
+	}{
+		{
+			name:      "default",
+			opts:      Options{ExcludeLabels: []string{"SPAM", "TRASH"}},
+			wantAdded: 1,
+			wantQuery: "-label:SPAM -label:TRASH",
+		},
+		{
+			name:      "spam and trash",
+			opts:      Options{ExcludeLabels: []string{"Trash"}, IncludeSpamTrash: true},
+			wantAdded: 2,
+			wantQuery: "-label:TRASH in:anywhere",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, &tt.opts)
+			env.Mock.Labels = labelFilterLabels
+			seedMessages(env, 3, 12345, "msg1")
+			env.Mock.AddMessage("msg2", testMIME(), []string{"SPAM"})
+			env.Mock.AddMessage("msg3", testMIME(), []string{"TRASH", "Label_1"})
+
+			summary := runFullSync(t, env)
+			assertSummary(t, summary, WantSummary{Added: intPtr(tt.wantAdded)})
+			assertMessageCount(t, env.Store, tt.wantAdded)
+			if env.Mock.LastQuery != tt.wantQuery {
+				t.Errorf("list query = %q, want %q", env.Mock.LastQuery, tt.wantQuery)
+			}
+		})
+	}
+}
+
+func TestFullSync_IncludeSpamTrash(t *testing.T) {
+	env := newTestEnv(t, &Options{IncludeSpamTrash: true, Query: "after:2020/01/01"})
+	seedMessages(env, 1, 12345, "msg1")
+
+	runFullSync(t, env)
+	if want := "after:2020/01/01 in:anywhere"; env.Mock.LastQuery != want {
+		t.Errorf("list query = %q, want %q", env.Mock.LastQuery, want)
+	}
+}
+
+func TestIncrementalSync_ExcludeLabels(t *testing.T) {
+	env := newTestEnv(t, &Options{ExcludeLabels: []string{"SPAM"}})
+	env.Mock.Labels = labelFilterLabels
+	source := env.CreateSourceWithHistory(t, "12340")
+
+	spam := []string{"SPAM"}
+	inbox := []string{"INBOX"}
+	for id, labels := range map[string][]string{
+		"spam-added": spam, "inbox-added": inbox, "unlabeled-added": spam,
+	} {
+		env.Mock.AddMessage(id, testMIME(), labels)
+	}
+	added := func(id string, labels []string) gmail.HistoryRecord {
+		r := historyAdded(id)
+		r.MessagesAdded[0].Message.LabelIDs = labels
+		return r
+	}
+	env.SetHistory(12350,
+		added("spam-added", spam),
+		added("inbox-added", inbox),
+		// Without labels in the record, the fetched message decides.
+		historyAdded("unlabeled-added"),
+	)
+
+	if _, err := env.Syncer.Incremental(env.Context, source); err != nil {
+		t.Fatalf("incremental sync: %v", err)
+	}
+	assertMessageCount(t, env.Store, 1)
+	if slices.Contains(env.Mock.GetMessageCalls, "spam-added") {
+		t.Error("fetched spam-added, which has an excluded label")
+	}
+	assertMessageHasLabel(t, env.Store, "inbox-added", "INBOX")
+}
@@ -0,0 +1,132 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// initMetadataOnly makes the sync fetch only metadata when
+// Options.MetadataOnly is set, failing if the source cannot.
+func (s *Syncer) initMetadataOnly() error {
+	if !s.opts.MetadataOnly {
+		return nil
+	}
+	ms, ok := s.source.(MetadataSource)
+	if !ok {
+		sourceType := s.opts.SourceType
+		if sourceType == "" {
+			sourceType = "gmail"
+		}
+		return fmt.Errorf("%s sources do not support metadata-only sync", sourceType)
+	}
+	s.metadata = ms
+	return nil
+}
+
+// fetchMessage fetches one message, as metadata alone in a
+// metadata-only sync.
+func (s *Syncer) fetchMessage(ctx context.Context, id string) (*gmail.RawMessage, error) {
+	if s.metadata != nil {
+		return s.metadata.FetchMessageMetadata(ctx, id)
+	}
+	return s.source.FetchMessage(ctx, id)
+}
+
+// fetchMessages fetches messages, as metadata alone in a metadata-only
+// sync.
+func (s *Syncer) fetchMessages(ctx context.Context, ids []string) ([]*gmail.RawMessage, error) {
+	if s.metadata != nil {
+		return s.metadata.FetchMessagesMetadata(ctx, ids)
+	}
+	return s.source.FetchMessages(ctx, ids)
+}
+
+// Hydrate fetches in full up to limit (0 for all) of the placeholders a
+// metadata-only sync stored for source, newest first, replacing each
+// with the complete message. The summary counts hydrated messages as
+// updated; placeholders deleted from the source are marked deleted.
+func (s *Syncer) Hydrate(ctx context.Context, source *store.Source, limit int) (*gmail.SyncSummary, error) {
+	summary := &gmail.SyncSummary{StartTime: time.Now()}
+	ids, err := s.store.MetadataOnlyMessages(source.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		if err := s.hydrate(ctx, source.ID, ids, summary); err != nil {
+			return nil, err
+		}
+	}
+	summary.EndTime = time.Now()
+	summary.Duration = summary.EndTime.Sub(summary.StartTime)
+	s.progress.OnComplete(summary)
+	return summary, nil
+}
+
+// hydrate fetches and stores the messages ids of a source in full, a
+// batch at a time.
+func (s *Syncer) hydrate(ctx context.Context, sourceID int64, ids []string, summary *gmail.SyncSummary) error {
+	labelMap, err := s.syncLabels(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("sync labels: %w", err)
+	}
+	s.progress.OnStart(int64(len(ids)))
+
+	batchSize := max(s.opts.BatchSize, 1)
+	for start := 0; start < len(ids); start += batchSize {
+		batch := ids[start:min(start+batchSize, len(ids))]
+		var deleted []string
+		var messageIDs []int64
+		err := s.fetchEach(ctx, batch, func(i int, raw *gmail.RawMessage, fetchErr error) {
+			summary.MessagesFound++
+			id := batch[i]
+			if raw == nil && fetchErr == nil {
+				// A batch fetch drops the error; fetching the message
+				// alone tells a deleted message from a failure.
+				raw, fetchErr = s.fetchMessage(ctx, id)
+			}
+			if raw == nil || raw.Raw == nil {
+				if isNotFound(fetchErr) {
+					deleted = append(deleted, id)
+					return
+				}
+				s.logger.Warn("failed to fetch message to hydrate", "id", id, "error", fetchErr)
+				summary.Errors++
+				return
+			}
+			size := int64(len(raw.Raw))
+			s.queueIngest(ctx, sourceID, raw, "", labelMap, func(messageID int64, err error) {
+				if err != nil {
+					s.logger.Warn("failed to hydrate message", "id", id, "error", err)
+					summary.Errors++
+					return
+				}
+				if messageID > 0 {
+					messageIDs = append(messageIDs, messageID)
+				}
+				summary.MessagesUpdated++
+				summary.BytesDownloaded += size
+			})
+		})
+		s.flushWrites()
+		if err != nil {
+			return fmt.Errorf("fetch messages: %w", err)
+		}
+
+		if len(deleted) > 0 {
+			if err := s.store.MarkMessagesDeletedBatch(sourceID, deleted); err != nil {
+				s.logger.Warn("failed to mark messages deleted", "error", err)
+			}
+		}
+		if s.embedEnqueuer != nil && len(messageIDs) > 0 {
+			if err := s.embedEnqueuer.EnqueueMessages(ctx, messageIDs); err != nil {
+				s.logger.Warn("vector enqueue failed", "ids", len(messageIDs), "error", err)
+			}
+		}
+		s.progress.OnProgress(summary.MessagesFound, summary.MessagesUpdated, summary.MessagesFound-summary.MessagesUpdated)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package sync
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+)
+
+func TestFullSync_MetadataOnly(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetOptions(t, func(o *Options) { o.MetadataOnly = true })
+	seedMessages(env, 2, 12345)
+	env.Mock.AddMessage("msg1", testMIMEWithAttachment(), []string{"INBOX"})
+	env.Mock.AddMessage("msg2", testMIMEMultipleRecipients(), []string{"INBOX", "STARRED"})
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(2), Errors: intPtr(0)})
+	assertMessageCount(t, env.Store, 2)
+	assertAttachmentCount(t, env.Store, 0)
+	assertRecipientCount(t, env.Store, "msg2", "cc", 1)
+	assertPlaceholders(t, env, 2)
+
+	var subject string
+	err := env.Store.DB().QueryRow(`SELECT subject FROM messages WHERE source_message_id = 'msg1'`).Scan(&subject)
+	if err != nil {
+		t.Fatalf("query subject: %v", err)
+	}
+	if subject != "Test with Attachment" {
+		t.Errorf("subject = %q, want %q", subject, "Test with Attachment")
+	}
+
+	// Placeholders are archived, so a second metadata-only sync skips them.
+	summary = runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(0)})
+
+	// A full sync fetches them in full.
+	env.SetOptions(t, func(o *Options) {})
+	summary = runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(2)})
+	assertPlaceholders(t, env, 0)
+}
+
+func TestHydrate(t *testing.T) {
+	env := newTestEnv(t)
+	env.SetOptions(t, func(o *Options) { o.MetadataOnly = true })
+	seedMessages(env, 3, 12345, "msg1", "msg2", "msg3")
+	runFullSync(t, env)
+	assertPlaceholders(t, env, 3)
+
+	env.SetOptions(t, func(o *Options) {})
+	source := env.CreateSource(t)
+	summary, err := env.Syncer.Hydrate(env.Context, source, 2)
+	if err != nil {
+		t.Fatalf("Hydrate: %v", err)
+	}
+	if summary.MessagesUpdated != 2 || summary.Errors != 0 {
+		t.Errorf("hydrated %d with %d errors, want 2 and 0", summary.MessagesUpdated, summary.Errors)
+	}
+	assertPlaceholders(t, env, 1)
+
+	// A placeholder deleted from the account is marked deleted.
+	left, err := env.Store.MetadataOnlyMessages(source.ID, 0)
+	if err != nil || len(left) != 1 {
+		t.Fatalf("MetadataOnlyMessages = %v, %v; want one", left, err)
+	}
+	delete(env.Mock.Messages, left[0])
+	summary, err = env.Syncer.Hydrate(env.Context, source, 0)
+	if err != nil {
+		t.Fatalf("Hydrate: %v", err)
+	}
+	if summary.MessagesUpdated != 0 || summary.Errors != 0 {
+		t.Errorf("hydrated %d with %d errors, want 0 and 0", summary.MessagesUpdated, summary.Errors)
+	}
+	assertDeletedFromSource(t, env.Store, left[0], true)
+	assertPlaceholders(t, env, 0)
+}
+
+func TestMetadataOnly_UnsupportedSource(t *testing.T) {
+	env := newTestEnv(t)
+	opts := DefaultOptions()
+	opts.MetadataOnly = true
+	// Hiding the mock's other methods leaves a client that cannot
+	// fetch metadata alone.
+	env.Syncer = New(struct{ gmail.API }{env.Mock}, env.Store, opts)
+
+	_, err := env.Syncer.Full(env.Context, testEmail)
+	if err == nil || !strings.Contains(err.Error(), "metadata-only") {
+		t.Errorf("Full() error = %v, want a metadata-only error", err)
+	}
+	source := env.CreateSourceWithHistory(t, "1000")
+	_, err = env.Syncer.Incremental(env.Context, source)
+	if err == nil || !strings.Contains(err.Error(), "metadata-only") {
+		t.Errorf("Incremental() error = %v, want a metadata-only error", err)
+	}
+}
+
+// assertPlaceholders checks the number of metadata-only messages, and
+// that none of them has raw MIME stored.
+func assertPlaceholders(t *testing.T, env *TestEnv, want int64) {
+	t.Helper()
+	n, err := env.Store.CountMetadataOnlyMessages(0)
+	if err != nil {
+		t.Fatalf("CountMetadataOnlyMessages: %v", err)
+	}
+	if n != want {
+		t.Errorf("metadata-only messages = %d, want %d", n, want)
+	}
+	var withRaw int
+	err = env.Store.DB().QueryRow(`
+		SELECT COUNT(*) FROM messages m
+		WHERE m.metadata_only AND EXISTS (SELECT 1 FROM message_raw mr WHERE mr.message_id = m.id)
+	`).Scan(&withRaw)
+	if err != nil {
+		t.Fatalf("count placeholders with raw MIME: %v", err)
+	}
+	if withRaw != 0 {
+		t.Errorf("%d metadata-only messages have raw MIME", withRaw)
+	}
+}
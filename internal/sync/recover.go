@@ -0,0 +1,112 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// recoverOverlap is how far before the last sync a recovery starts
+// listing mail, to cover clock skew and mail delivered late.
+const recoverOverlap = 24 * time.Hour
+
+// Recover catches up a source whose change history has expired. It
+// runs a full sync of the mail since shortly before the last sync
+// (all mail for sources without the Query capability or a last sync),
+// which moves the sync cursor to now. Then it lists every message the
+// source still has and marks the archived messages it no longer has
+// as deleted from the source, as the history would have.
+func (s *Syncer) Recover(ctx context.Context, source *store.Source) (*gmail.SyncSummary, error) {
+	saved := *s.opts
+	defer func() { *s.opts = saved }()
+	s.opts.NoResume = true
+	s.opts.Limit = 0
+	if s.caps.Query && source.LastSyncAt.Valid {
+		s.opts.Query = joinQuery(saved.Query, recoveryQuery(source.LastSyncAt.Time))
+	}
+	s.logger.Info("recovering from expired history", "email", source.Identifier, "query", s.opts.Query)
+
+	summary, err := s.Full(ctx, source.Identifier)
+	if err != nil {
+		return nil, fmt.Errorf("recover with a full sync: %w", err)
+	}
+	summary.Recovered = true
+
+	deleted, err := s.reconcileDeletions(ctx, source.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// The archive is caught up; only deletions were missed.
+		s.logger.Warn("could not reconcile deletions after recovery", "error", err)
+		summary.Errors++
+		return summary, nil
+	}
+	summary.MessagesDeleted = deleted
+	return summary, nil
+}
+
+// recoveryQuery is the Gmail search for mail since recoverOverlap
+// before lastSync.
+func recoveryQuery(lastSync time.Time) string {
+	return "after:" + strconv.FormatInt(lastSync.Add(-recoverOverlap).Unix(), 10)
+}
+
+func joinQuery(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + " " + b
+}
+
+// reconcileDeletions marks the archived messages of sourceID that the
+// source no longer lists as deleted from it, and returns how many.
+func (s *Syncer) reconcileDeletions(ctx context.Context, sourceID int64) (int64, error) {
+	// Trash and Spam still hold their messages; only mail deleted for
+	// good is gone.
+	query := ""
+	if s.caps.Query {
+		query = "in:anywhere"
+	}
+	remote := make(map[string]bool)
+	pageToken := ""
+	for {
+		resp, err := s.source.ListMessages(ctx, query, pageToken)
+		if err != nil {
+			return 0, fmt.Errorf("list messages: %w", err)
+		}
+		for _, m := range resp.Messages {
+			remote[m.ID] = true
+		}
+		if pageToken = resp.NextPageToken; pageToken == "" {
+			break
+		}
+	}
+
+	archived, err := s.store.PresentSourceMessageIDs(sourceID)
+	if err != nil {
+		return 0, err
+	}
+	// An empty listing of a mailbox with archived mail is far likelier
+	// a source error than every message deleted.
+	if len(remote) == 0 && len(archived) > 0 {
+		return 0, fmt.Errorf("the source listed no messages, but %d are archived", len(archived))
+	}
+	var missing []string
+	for _, id := range archived {
+		if !remote[id] {
+			missing = append(missing, id)
+		}
+	}
+	if err := s.store.MarkMessagesDeletedBatch(sourceID, missing); err != nil {
+		return 0, fmt.Errorf("mark deleted messages: %w", err)
+	}
+	if len(missing) > 0 {
+		s.logger.Info("marked messages deleted from source", "count", len(missing))
+	}
+	return int64(len(missing)), nil
+}
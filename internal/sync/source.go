@@ -0,0 +1,261 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	gosync "sync"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// Source is a mailbox the Syncer can archive. Messages are exchanged
+// in Gmail's shape (raw MIME plus label IDs), which every built-in
+// source already speaks; a source without labels or threads simply
+// leaves those fields empty and declares so in its Capabilities.
+type Source interface {
+	// Capabilities reports which optional features the source has.
+	Capabilities() Capabilities
+
+	// Profile returns the account's address and, for sources with
+	// History, the change cursor a full sync should record.
+	Profile(ctx context.Context) (*gmail.Profile, error)
+
+	// Labels returns the account's labels or folders.
+	Labels(ctx context.Context) ([]*gmail.Label, error)
+
+	// ListMessages returns one page of message IDs for a full sync.
+	// query is only passed to sources with the Query capability.
+	ListMessages(ctx context.Context, query, pageToken string) (*gmail.MessageListResponse, error)
+
+	// ListChanges returns one page of changes since cursor. Only
+	// called on sources with the History capability; a cursor the
+	// source no longer remembers yields a *gmail.NotFoundError.
+	ListChanges(ctx context.Context, cursor uint64, pageToken string) (*gmail.HistoryResponse, error)
+
+	// FetchMessage returns one message with its raw MIME.
+	FetchMessage(ctx context.Context, id string) (*gmail.RawMessage, error)
+
+	// FetchMessages returns messages in the order of ids, with nil
+	// for any that could not be fetched.
+	FetchMessages(ctx context.Context, ids []string) ([]*gmail.RawMessage, error)
+
+	// Close releases the source's connections.
+	Close() error
+}
+
+// Capabilities describes what a Source supports beyond listing and
+// fetching messages. The Syncer adapts to a missing capability rather
+// than failing.
+type Capabilities struct {
+	// History means ListChanges works, so incremental sync is possible.
+	History bool
+
+	// Threads means message thread IDs are real conversation IDs.
+	// Without it, threads are derived from References and In-Reply-To.
+	Threads bool
+
+	// StableIDs means a message keeps its ID when it moves between
+	// folders. Without it, a message whose RFC 822 Message-ID is
+	// already archived is treated as moved rather than added.
+	StableIDs bool
+
+	// Resume means page tokens stay valid across sessions, so an
+	// interrupted full sync can continue from its checkpoint.
+	Resume bool
+
+	// Query means ListMessages accepts Gmail search syntax.
+	Query bool
+}
+
+// Capabilities of the built-in source types.
+var (
+	GmailCapabilities = Capabilities{History: true, Threads: true, StableIDs: true, Resume: true, Query: true}
+	IMAPCapabilities  = Capabilities{}
+	GraphCapabilities = Capabilities{History: true, Threads: true, StableIDs: true}
+)
+
+// CursorSource is implemented by sources that track their own sync
+// position rather than a numeric history ID. Without History, such as
+// IMAP with its per-mailbox UIDNEXT, the cursor narrows later listings
+// to what is new; with History, such as Microsoft Graph's delta links,
+// the source reads it back to list changes and ignores the cursor
+// passed to ListChanges.
+//
+// After a full sync that listed every message, or an incremental one,
+// the Syncer stores Cursor as the source's sync cursor in place of the
+// history ID; an empty Cursor leaves the stored one unchanged. Sources
+// without History only advance after a full sync without errors.
+type CursorSource interface {
+	Source
+	Cursor() string
+}
+
+// MetadataSource is implemented by sources that can fetch messages
+// without their bodies, for Options.MetadataOnly. The messages it
+// returns are MetadataOnly: Raw holds their headers alone.
+type MetadataSource interface {
+	Source
+
+	// FetchMessageMetadata is FetchMessage without the body.
+	FetchMessageMetadata(ctx context.Context, id string) (*gmail.RawMessage, error)
+
+	// FetchMessagesMetadata is FetchMessages without the bodies.
+	FetchMessagesMetadata(ctx context.Context, ids []string) ([]*gmail.RawMessage, error)
+}
+
+// QuotaSource is implemented by sources that can report the storage
+// quota of their account, for Options.StorageQuota. StorageQuota
+// returns nil when the account's provider reports none.
+type QuotaSource interface {
+	Source
+	StorageQuota(ctx context.Context) (*gmail.StorageQuota, error)
+}
+
+// FromAPI adapts a Gmail-shaped client, such as the Gmail or IMAP
+// client, to a Source with the given capabilities. The Source is a
+// CursorSource when the client has a Cursor method, and otherwise a
+// MetadataSource when the client is a gmail.MetadataReader.
+func FromAPI(client gmail.API, caps Capabilities) Source {
+	a := &apiSource{client: client, caps: caps}
+	if c, ok := client.(interface{ Cursor() string }); ok {
+		return &cursorAPISource{apiSource: a, cursor: c.Cursor}
+	}
+	if r, ok := client.(gmail.MetadataReader); ok {
+		return &metadataAPISource{apiSource: a, reader: r}
+	}
+	return a
+}
+
+type metadataAPISource struct {
+	*apiSource
+	reader gmail.MetadataReader
+}
+
+func (m *metadataAPISource) FetchMessageMetadata(ctx context.Context, id string) (*gmail.RawMessage, error) {
+	return m.reader.GetMessageMetadata(ctx, id)
+}
+
+func (m *metadataAPISource) FetchMessagesMetadata(ctx context.Context, ids []string) ([]*gmail.RawMessage, error) {
+	return m.reader.GetMessagesMetadataBatch(ctx, ids)
+}
+
+type cursorAPISource struct {
+	*apiSource
+	cursor func() string
+}
+
+func (c *cursorAPISource) Cursor() string { return c.cursor() }
+
+type apiSource struct {
+	client gmail.API
+	caps   Capabilities
+}
+
+func (a *apiSource) Capabilities() Capabilities { return a.caps }
+
+func (a *apiSource) Profile(ctx context.Context) (*gmail.Profile, error) {
+	return a.client.GetProfile(ctx)
+}
+
+func (a *apiSource) Labels(ctx context.Context) ([]*gmail.Label, error) {
+	return a.client.ListLabels(ctx)
+}
+
+func (a *apiSource) ListMessages(ctx context.Context, query, pageToken string) (*gmail.MessageListResponse, error) {
+	return a.client.ListMessages(ctx, query, pageToken)
+}
+
+func (a *apiSource) ListChanges(ctx context.Context, cursor uint64, pageToken string) (*gmail.HistoryResponse, error) {
+	return a.client.ListHistory(ctx, cursor, pageToken)
+}
+
+func (a *apiSource) FetchMessage(ctx context.Context, id string) (*gmail.RawMessage, error) {
+	return a.client.GetMessageRaw(ctx, id)
+}
+
+func (a *apiSource) FetchMessages(ctx context.Context, ids []string) ([]*gmail.RawMessage, error) {
+	return a.client.GetMessagesRawBatch(ctx, ids)
+}
+
+// StorageQuota makes every apiSource a QuotaSource, reporting no quota
+// for clients that are not a gmail.QuotaReader.
+func (a *apiSource) StorageQuota(ctx context.Context) (*gmail.StorageQuota, error) {
+	r, ok := a.client.(gmail.QuotaReader)
+	if !ok {
+		return nil, nil
+	}
+	return r.GetStorageQuota(ctx)
+}
+
+func (a *apiSource) Close() error { return a.client.Close() }
+
+// Driver opens sources of one type. Capabilities must match what the
+// opened sources report, so callers can plan (say, full versus
+// incremental sync) before connecting.
+type Driver struct {
+	Capabilities Capabilities
+	Open         func(ctx context.Context, src *store.Source) (Source, error)
+}
+
+var (
+	driversMu gosync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register makes a source type syncable. It panics if the type is
+// already registered or the driver has no Open function, like
+// database/sql.Register.
+func Register(sourceType string, d Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if d.Open == nil {
+		panic("sync: Register driver for " + sourceType + " has no Open")
+	}
+	if _, dup := drivers[sourceType]; dup {
+		panic("sync: Register called twice for source type " + sourceType)
+	}
+	drivers[sourceType] = d
+}
+
+// Lookup returns the driver registered for sourceType.
+func Lookup(sourceType string) (Driver, bool) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	d, ok := drivers[sourceType]
+	return d, ok
+}
+
+// SourceTypes returns the registered source types, sorted.
+func SourceTypes() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	types := make([]string, 0, len(drivers))
+	for t := range drivers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Open opens the Source for a stored account with its type's driver.
+func Open(ctx context.Context, src *store.Source) (Source, error) {
+	d, ok := Lookup(src.SourceType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported source type %q", src.SourceType)
+	}
+	return d.Open(ctx, src)
+}
+
+// capabilitiesFor returns the capabilities a Syncer built by New
+// assumes for sourceType.
+func capabilitiesFor(sourceType string) Capabilities {
+	if d, ok := Lookup(sourceType); ok {
+		return d.Capabilities
+	}
+	if sourceType == "imap" {
+		return IMAPCapabilities
+	}
+	return GmailCapabilities
+}
@@ -0,0 +1,236 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/gmail"
+	"github.com/wesm/msgvault/internal/store"
+	testemail "github.com/wesm/msgvault/internal/testutil/email"
+)
+
+// registerTestDriver registers a driver for the duration of the test.
+func registerTestDriver(t *testing.T, sourceType string, d Driver) {
+	t.Helper()
+	Register(sourceType, d)
+	t.Cleanup(func() {
+		driversMu.Lock()
+		delete(drivers, sourceType)
+		driversMu.Unlock()
+	})
+}
+
+func TestRegister(t *testing.T) {
+	mock := gmail.NewMockAPI()
+	registerTestDriver(t, "test-notes", Driver{
+		Capabilities: Capabilities{Threads: true},
+		Open: func(_ context.Context, src *store.Source) (Source, error) {
+			return FromAPI(mock, Capabilities{Threads: true}), nil
+		},
+	})
+
+	d, ok := Lookup("test-notes")
+	if !ok || !d.Capabilities.Threads || d.Capabilities.History {
+		t.Errorf("Lookup() = %+v, %v", d, ok)
+	}
+	if !slices.Contains(SourceTypes(), "test-notes") {
+		t.Errorf("SourceTypes() = %v, want test-notes", SourceTypes())
+	}
+	if got := capabilitiesFor("test-notes"); got != d.Capabilities {
+		t.Errorf("capabilitiesFor() = %+v, want the registered capabilities", got)
+	}
+
+	src, err := Open(context.Background(), &store.Source{SourceType: "test-notes", Identifier: "alice@example.com"})
+	if err != nil || src == nil {
+		t.Fatalf("Open() = %v, %v", src, err)
+	}
+	if _, err := Open(context.Background(), &store.Source{SourceType: "carrier-pigeon"}); err == nil ||
+		!strings.Contains(err.Error(), "unsupported source type") {
+		t.Errorf("Open(unregistered) error = %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("duplicate Register did not panic")
+			}
+		}()
+		Register("test-notes", d)
+	}()
+}
+
+func TestCapabilitiesFor_BuiltIns(t *testing.T) {
+	if got := capabilitiesFor("gmail"); got != GmailCapabilities {
+		t.Errorf("gmail = %+v", got)
+	}
+	if got := capabilitiesFor(""); got != GmailCapabilities {
+		t.Errorf("empty type = %+v, want Gmail's", got)
+	}
+	if got := capabilitiesFor("imap"); got != IMAPCapabilities {
+		t.Errorf("imap = %+v", got)
+	}
+}
+
+// TestNewFromSource_MinimalCapabilities syncs a source that declares
+// none of the optional capabilities: queries are not passed through,
+// threads come from MIME headers, and incremental sync is refused.
+func TestNewFromSource_MinimalCapabilities(t *testing.T) {
+	env := newTestEnv(t)
+	opts := DefaultOptions()
+	opts.SourceType = "test-notes"
+	opts.Query = "before:2020/01/01"
+	env.Syncer = NewFromSource(FromAPI(env.Mock, Capabilities{}), env.Store, opts)
+
+	env.Mock.Profile.MessagesTotal = 2
+	env.Mock.AddMessage("n1", testemail.NewMessage().
+		Subject("Plan").Header("Message-ID", "<plan@example.com>").Body("a").Bytes(), nil)
+	env.Mock.AddMessage("n2", testemail.NewMessage().
+		Subject("Re: Plan").Header("Message-ID", "<reply@example.com>").
+		Header("References", "<plan@example.com>").Body("b").Bytes(), nil)
+
+	summary := runFullSync(t, env)
+	assertSummary(t, summary, WantSummary{Added: intPtr(2)})
+	if env.Mock.LastQuery != "" {
+		t.Errorf("query passed to a source without Query: %q", env.Mock.LastQuery)
+	}
+	assertThreadSourceID(t, env.Store, "n2", "plan@example.com")
+
+	source, err := env.Store.GetOrCreateSource("test-notes", testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Syncer.Incremental(env.Context, source); err == nil ||
+		!strings.Contains(err.Error(), "do not support incremental sync") {
+		t.Errorf("Incremental() error = %v, want unsupported", err)
+	}
+}
+
+// cursorAPI is a mock client with a source cursor, like IMAP's UID state.
+type cursorAPI struct {
+	*gmail.MockAPI
+	cursor string
+}
+
+func (c *cursorAPI) Cursor() string { return c.cursor }
+
+// TestFull_SourceCursor checks that a source cursor replaces the
+// history ID, and only advances after a complete, error-free listing.
+func TestFull_SourceCursor(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		fail  bool
+		want  string
+	}{
+		{name: "complete", want: `{"INBOX":{"v":1,"n":3}}`},
+		{name: "limit cut listing short", limit: 1, want: "previous"},
+		{name: "fetch error", fail: true, want: "previous"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t)
+			opts := DefaultOptions()
+			opts.SourceType = "imap"
+			opts.Limit = tt.limit
+			api := &cursorAPI{MockAPI: env.Mock, cursor: `{"INBOX":{"v":1,"n":3}}`}
+			src := FromAPI(api, IMAPCapabilities)
+			if _, ok := src.(CursorSource); !ok {
+				t.Fatal("FromAPI did not expose the client's Cursor")
+			}
+			env.Syncer = NewFromSource(src, env.Store, opts)
+
+			source, err := env.Store.GetOrCreateSource("imap", testEmail)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := env.Store.UpdateSourceSyncCursor(source.ID, "previous"); err != nil {
+				t.Fatal(err)
+			}
+			env.Mock.AddMessage("INBOX|1", testemail.NewMessage().Subject("One").Bytes(), nil)
+			env.Mock.AddMessage("INBOX|2", testemail.NewMessage().Subject("Two").Bytes(), nil)
+			if tt.fail {
+				env.Mock.GetMessageError["INBOX|2"] = errors.New("connection reset")
+			}
+
+			runFullSync(t, env)
+
+			source, err = env.Store.GetOrCreateSource("imap", testEmail)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if source.SyncCursor.String != tt.want {
+				t.Errorf("sync cursor = %q, want %q", source.SyncCursor.String, tt.want)
+			}
+		})
+	}
+}
+
+// TestIncremental_SourceCursor checks that a source with History and
+// its own cursor, like Microsoft Graph's delta links, syncs without a
+// numeric history ID and stores its new cursor afterwards.
+func TestIncremental_SourceCursor(t *testing.T) {
+	env := newTestEnv(t)
+	api := &cursorAPI{MockAPI: env.Mock, cursor: `{"inbox":"delta-2"}`}
+	env.Syncer = NewFromSource(FromAPI(api, GraphCapabilities), env.Store, &Options{SourceType: "outlook"})
+
+	source, err := env.Store.GetOrCreateSource("outlook", testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.Store.UpdateSourceSyncCursor(source.ID, `{"inbox":"delta-1"}`); err != nil {
+		t.Fatal(err)
+	}
+	source.SyncCursor.String, source.SyncCursor.Valid = `{"inbox":"delta-1"}`, true
+
+	// Graph reports a new message as added with its labels in the
+	// same record; it must be fetched once, with its labels.
+	env.Mock.Profile.HistoryID = 0
+	env.Mock.AddMessage("msg1", testMIME(), []string{"INBOX", "UNREAD"})
+	added := historyAdded("msg1")
+	added.LabelsAdded = historyLabelAdded("msg1", "INBOX", "UNREAD").LabelsAdded
+	env.Mock.HistoryRecords = []gmail.HistoryRecord{added}
+
+	summary, err := env.Syncer.Incremental(env.Context, source)
+	if err != nil {
+		t.Fatalf("Incremental: %v", err)
+	}
+	if summary.MessagesAdded != 1 {
+		t.Errorf("MessagesAdded = %d, want 1", summary.MessagesAdded)
+	}
+	if len(env.Mock.GetMessageCalls) != 1 {
+		t.Errorf("GetMessage calls = %v, want one", env.Mock.GetMessageCalls)
+	}
+	assertMessageHasLabel(t, env.Store, "msg1", "UNREAD")
+
+	source, err = env.Store.GetOrCreateSource("outlook", testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.SyncCursor.String != api.cursor {
+		t.Errorf("sync cursor = %q, want %q", source.SyncCursor.String, api.cursor)
+	}
+}
+
+// TestFull_SourceCursorWithHistory checks that a history source's
+// cursor advances past failed messages, as Gmail's history ID does.
+func TestFull_SourceCursorWithHistory(t *testing.T) {
+	env := newTestEnv(t)
+	api := &cursorAPI{MockAPI: env.Mock, cursor: `{"inbox":"delta-1"}`}
+	env.Syncer = NewFromSource(FromAPI(api, GraphCapabilities), env.Store, &Options{SourceType: "outlook"})
+	env.Mock.AddMessage("msg1", testMIME(), []string{"INBOX"})
+	env.Mock.AddMessage("msg2", testMIME(), []string{"INBOX"})
+	env.Mock.GetMessageError["msg2"] = errors.New("connection reset")
+
+	runFullSync(t, env)
+
+	source, err := env.Store.GetOrCreateSource("outlook", testEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source.SyncCursor.String != api.cursor {
+		t.Errorf("sync cursor = %q, want %q", source.SyncCursor.String, api.cursor)
+	}
+}
@@ -1,4 +1,5 @@
-// Package sync provides Gmail synchronization workflows.
+// Package sync archives mail from any registered Source: Gmail, IMAP,
+// or a third-party source added with Register.
 package sync
 
 import (
@@ -12,11 +13,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wesm/msgvault/internal/crash"
 	"github.com/wesm/msgvault/internal/export"
 	"github.com/wesm/msgvault/internal/gmail"
 	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/store"
 	"github.com/wesm/msgvault/internal/textutil"
+	"github.com/wesm/msgvault/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ErrHistoryExpired indicates that the Gmail history ID is too old and a full sync is required.
@@ -30,22 +35,75 @@ type EmbedEnqueuer interface {
 
 // Options configures sync behavior.
 type Options struct {
-	// SourceType is the type of source being synced ("gmail" or "imap").
-	// Defaults to "gmail" if empty.
+	// SourceType is the type of source being synced ("gmail", "imap",
+	// or a registered type). Defaults to "gmail" if empty.
 	SourceType string
 
-	// Query is an optional Gmail search query (e.g., "before:2020/01/01")
+	// Query is an optional Gmail search query (e.g., "before:2020/01/01").
+	// Ignored by sources without the Query capability.
 	Query string
 
-	// NoResume forces a fresh sync even if a checkpoint exists
+	// IncludeLabels limits the sync to messages with one of these
+	// labels, each a name or ID. Full sync narrows its listing to them
+	// on sources with the Query capability; every sync skips other new
+	// messages. Archived messages stay when they lose the labels.
+	IncludeLabels []string
+
+	// ExcludeLabels skips new messages with any of these labels, each a
+	// name or ID, as IncludeLabels picks them.
+	ExcludeLabels []string
+
+	// IncludeSpamTrash makes full sync list Spam and Trash too, which
+	// Gmail leaves out by default. Ignored by sources without the Query
+	// capability.
+	IncludeSpamTrash bool
+
+	// MetadataOnly makes Full and Incremental fetch only each new
+	// message's headers, labels and size, storing it as a placeholder
+	// for Hydrate to fetch in full later. It needs a MetadataSource.
+	MetadataOnly bool
+
+	// RecoverExpiredHistory makes Incremental catch up with Recover
+	// when the source's change history has expired, instead of
+	// returning ErrHistoryExpired.
+	RecoverExpiredHistory bool
+
+	// StorageQuota makes Full and Incremental record the storage quota
+	// of the account, on sources that are a QuotaSource, for the
+	// history 'msgvault quota' charts. Failing to read it only warns.
+	StorageQuota bool
+
+	// NoResume forces a fresh sync even if a checkpoint exists. Sources
+	// without the Resume capability always start fresh.
 	NoResume bool
 
 	// BatchSize is the number of messages to fetch in parallel (default: 10)
 	BatchSize int
 
+	// FetchWorkers is the number of workers fetching message content
+	// concurrently. Each worker fetches one message at a time, and the
+	// messages are still stored and checkpointed in list order, so an
+	// interrupted sync resumes exactly as a serial one would. 0 or 1
+	// leaves fetching to the source's own batch fetch.
+	FetchWorkers int
+
+	// FetchRate caps each fetch worker at this many messages per
+	// second (0 = no cap beyond the source's own rate limiting).
+	FetchRate float64
+
+	// WriteBatch is the number of messages written to the store per
+	// transaction (default: 100). 0 or 1 writes each message in a
+	// transaction of its own.
+	WriteBatch int
+
 	// AttachmentsDir is where to store attachments
 	AttachmentsDir string
 
+	// SkipAttachments leaves attachments in the raw MIME rather than
+	// storing them in AttachmentsDir. Messages still record how many
+	// they have, for importer.FetchAttachments to extract later.
+	SkipAttachments bool
+
 	// Limit caps the number of messages scanned per sync (0 = unlimited).
 	// Enforced by truncating the message ID list before downloading content.
 	// The API listing call (which returns lightweight IDs, not bodies) may
@@ -53,36 +111,69 @@ type Options struct {
 	Limit int
 }
 
+// DefaultWriteBatch is the default Options.WriteBatch.
+const DefaultWriteBatch = 100
+
 // DefaultOptions returns sensible defaults.
 func DefaultOptions() *Options {
 	return &Options{
 		BatchSize:  10,
+		WriteBatch: DefaultWriteBatch,
 		SourceType: "gmail",
 	}
 }
 
-// Syncer performs Gmail synchronization.
+// Syncer archives one Source into the store.
 type Syncer struct {
-	client        gmail.API
+	source        Source
+	caps          Capabilities
 	store         *store.Store
 	logger        *slog.Logger
 	progress      gmail.SyncProgress
 	opts          *Options
 	embedEnqueuer EmbedEnqueuer
+
+	// writes batches message writes into shared transactions.
+	// pendingRFC822 holds the RFC822 Message-IDs queued in it, for
+	// the dedup check of sources without stable IDs.
+	writes        *store.MessageBatch
+	pendingRFC822 map[string]bool
+
+	// failed holds the IDs of the messages with a recorded sync
+	// failure, so archiving one of them resolves its failure.
+	failed map[string]bool
+
+	// labels picks the messages to archive; set by syncLabels.
+	labels labelFilter
+
+	// metadata fetches messages when Options.MetadataOnly is set; set
+	// by initMetadataOnly.
+	metadata MetadataSource
 }
 
-// New creates a new Syncer.
+// New creates a Syncer for a Gmail-shaped client, with the built-in
+// or registered capabilities of opts.SourceType.
 func New(client gmail.API, store *store.Store, opts *Options) *Syncer {
 	if opts == nil {
 		opts = DefaultOptions()
 	}
+	return NewFromSource(FromAPI(client, capabilitiesFor(opts.SourceType)), store, opts)
+}
+
+// NewFromSource creates a Syncer for any Source.
+func NewFromSource(source Source, store *store.Store, opts *Options) *Syncer {
+	if opts == nil {
+		opts = DefaultOptions()
+	}
 
 	return &Syncer{
-		client:   client,
+		source:   source,
+		caps:     source.Capabilities(),
 		store:    store,
 		logger:   slog.Default(),
 		progress: gmail.NullProgress{},
 		opts:     opts,
+		writes:   store.BeginBatch(opts.WriteBatch),
 	}
 }
 
@@ -104,6 +195,15 @@ func (s *Syncer) SetEmbedEnqueuer(e EmbedEnqueuer) {
 	s.embedEnqueuer = e
 }
 
+// scopeLogger tags every record of one sync run with the account and
+// sync run ID, so interleaved runs can be told apart in a shared log.
+// The returned function restores the previous logger.
+func (s *Syncer) scopeLogger(account string, syncID int64) func() {
+	base := s.logger
+	s.logger = base.With("account", account, "sync_run_id", syncID)
+	return func() { s.logger = base }
+}
+
 // syncState holds the state for a sync operation.
 type syncState struct {
 	syncID     int64
@@ -118,12 +218,15 @@ func (s *Syncer) initSyncState(sourceID int64) (*syncState, error) {
 		checkpoint: &store.Checkpoint{},
 	}
 
-	if !s.opts.NoResume {
+	if !s.opts.NoResume && s.caps.Resume {
 		activeSync, err := s.store.GetActiveSync(sourceID)
 		if err != nil {
 			return nil, fmt.Errorf("check active sync: %w", err)
 		}
-		if activeSync != nil {
+		// An interrupted Google Takeout import into this source leaves
+		// a JSON file checkpoint, which is not a page token; start a new
+		// run rather than resume it.
+		if activeSync != nil && !strings.HasPrefix(activeSync.CursorBefore.String, "{") {
 			state.syncID = activeSync.ID
 			if activeSync.CursorBefore.Valid {
 				state.pageToken = activeSync.CursorBefore.String
@@ -159,7 +262,10 @@ type batchResult struct {
 }
 
 // processBatch processes a single batch of messages from a list response.
-func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gmail.MessageListResponse, labelMap map[string]int64, checkpoint *store.Checkpoint, summary *gmail.SyncSummary) (*batchResult, error) {
+func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gmail.MessageListResponse, labelMap map[string]int64, checkpoint *store.Checkpoint, summary *gmail.SyncSummary) (_ *batchResult, err error) {
+	ctx, span := tracing.Start(ctx, "sync.batch", trace.WithAttributes(attribute.Int("messages", len(listResp.Messages))))
+	defer tracing.End(span, &err)
+
 	result := &batchResult{}
 
 	if len(listResp.Messages) == 0 {
@@ -174,8 +280,13 @@ func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gma
 		threadIDs[m.ID] = m.ThreadID
 	}
 
-	// Check which messages already exist
-	existingMap, err := s.store.MessageExistsWithRawBatch(sourceID, messageIDs)
+	// Check which messages already exist. A metadata-only sync also
+	// skips placeholders; a full one fetches them in full.
+	exists := s.store.MessageExistsWithRawBatch
+	if s.metadata != nil {
+		exists = s.store.MessageExistsBatch
+	}
+	existingMap, err := exists(sourceID, messageIDs)
 	if err != nil {
 		return nil, fmt.Errorf("check existing: %w", err)
 	}
@@ -193,24 +304,24 @@ func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gma
 
 	// Fetch and ingest new messages
 	if len(newIDs) > 0 {
-		rawMessages, err := s.client.GetMessagesRawBatch(ctx, newIDs)
-		if err != nil {
-			return nil, fmt.Errorf("fetch messages: %w", err)
-		}
-
 		var insertedIDs []int64
-		for i, raw := range rawMessages {
+		err := s.fetchEach(ctx, newIDs, func(i int, raw *gmail.RawMessage, fetchErr error) {
 			if raw == nil {
 				s.logger.Warn("failed to fetch message (nil response)", "id", newIDs[i])
 				checkpoint.ErrorsCount++
-				continue
+				s.recordFetchFailure(sourceID, newIDs[i], threadIDs[newIDs[i]], fetchErr)
+				return
 			}
 			// Non-nil stub with nil Raw signals a cross-mailbox
 			// dedup skip (e.g. same message in All Mail and Trash).
 			// Distinct from []byte{} which is a genuine empty body.
 			if raw.Raw == nil {
 				result.skipped++
-				continue
+				return
+			}
+			if !s.labels.match(raw.LabelIDs) {
+				result.skipped++
+				return
 			}
 
 			// Track oldest message date for progress display
@@ -222,23 +333,31 @@ func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gma
 				}
 			}
 
-			threadID := threadIDs[newIDs[i]]
-			insertedID, err := s.ingestMessage(ctx, sourceID, raw, threadID, labelMap)
-			if err != nil {
-				if errors.Is(err, errDuplicateRFC822) {
-					result.skipped++
-					continue
+			id, threadID, size := newIDs[i], threadIDs[newIDs[i]], int64(len(raw.Raw))
+			s.queueIngest(ctx, sourceID, raw, threadID, labelMap, func(insertedID int64, err error) {
+				if err != nil {
+					if errors.Is(err, errDuplicateRFC822) {
+						result.skipped++
+						return
+					}
+					s.logger.Warn("failed to ingest message", "id", id, "error", err)
+					checkpoint.ErrorsCount++
+					s.recordIngestFailure(sourceID, id, threadID, err)
+					return
 				}
-				s.logger.Warn("failed to ingest message", "id", raw.ID, "error", err)
-				checkpoint.ErrorsCount++
-				continue
-			}
+				s.resolveFailure(sourceID, id)
 
-			if insertedID > 0 {
-				insertedIDs = append(insertedIDs, insertedID)
-			}
-			result.added++
-			summary.BytesDownloaded += int64(len(raw.Raw))
+				if insertedID > 0 {
+					insertedIDs = append(insertedIDs, insertedID)
+				}
+				result.added++
+				summary.BytesDownloaded += size
+			})
+		})
+		// Everything queued is written before the page is checkpointed.
+		s.flushWrites()
+		if err != nil {
+			return nil, fmt.Errorf("fetch messages: %w", err)
 		}
 
 		// Hook vector-search enqueue after the batch-insert point.
@@ -255,6 +374,13 @@ func (s *Syncer) processBatch(ctx context.Context, sourceID int64, listResp *gma
 
 // Full performs a full synchronization.
 func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSummary, err error) {
+	ctx, span := tracing.Start(ctx, "sync.full", trace.WithAttributes(attribute.String("account", email)))
+	defer tracing.End(span, &err)
+
+	if err := s.initMetadataOnly(); err != nil {
+		return nil, err
+	}
+
 	startTime := time.Now()
 	summary = &gmail.SyncSummary{StartTime: startTime}
 
@@ -273,6 +399,8 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 	if err != nil {
 		return nil, err
 	}
+	defer s.scopeLogger(email, state.syncID)()
+	span.SetAttributes(attribute.Int64("sync_run_id", state.syncID), attribute.Bool("resumed", state.wasResumed))
 	summary.WasResumed = state.wasResumed
 	summary.ResumedFromToken = state.pageToken
 
@@ -286,17 +414,24 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 			}
 			summary = nil
 			err = fmt.Errorf("sync panicked: %v", r)
+			if path, reportErr := crash.Report(r, stack); reportErr != nil {
+				s.logger.Error("failed to write crash report", "error", reportErr)
+			} else if path != "" {
+				err = fmt.Errorf("sync panicked: %v (crash report: %s; please file a bug at %s)", r, path, crash.IssuesURL)
+			}
 		}
 	}()
 
 	// Get profile to verify connection and get historyId
-	profile, err := s.client.GetProfile(ctx)
+	profile, err := s.source.Profile(ctx)
 	if err != nil {
 		_ = s.store.FailSync(state.syncID, err.Error())
 		return nil, fmt.Errorf("get profile: %w", err)
 	}
 
 	s.logger.Info("syncing account", "email", profile.EmailAddress, "messages", profile.MessagesTotal)
+	s.recordRemoteTotal(source.ID, profile.MessagesTotal)
+	s.recordStorageQuota(ctx, source.ID)
 
 	// Sync labels
 	labelMap, err := s.syncLabels(ctx, source.ID)
@@ -305,14 +440,32 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 		return nil, fmt.Errorf("sync labels: %w", err)
 	}
 
+	// Retry messages earlier syncs failed on
+	if err := s.retryFailures(ctx, source.ID, labelMap, state.checkpoint, summary); err != nil {
+		_ = s.store.FailSync(state.syncID, err.Error())
+		return nil, err
+	}
+
 	// List and sync messages
+	query := s.opts.Query
+	if !s.caps.Query {
+		query = ""
+	} else {
+		if s.labels.query != "" {
+			query = joinQuery(query, s.labels.query)
+		}
+		if s.opts.IncludeSpamTrash {
+			query = joinQuery(query, "in:anywhere")
+		}
+	}
 	var totalEstimate int64
 	firstPage := true
 	pageToken := state.pageToken
+	listedAll := false // false when the limit stopped listing early
 
 	for {
 		// List messages
-		listResp, err := s.client.ListMessages(ctx, s.opts.Query, pageToken)
+		listResp, err := s.source.ListMessages(ctx, query, pageToken)
 		if err != nil {
 			_ = s.store.FailSync(state.syncID, err.Error())
 			return nil, fmt.Errorf("list messages: %w", err)
@@ -325,6 +478,7 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 		}
 
 		if len(listResp.Messages) == 0 {
+			listedAll = true
 			break
 		}
 
@@ -373,25 +527,40 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 
 		// No more pages
 		if pageToken == "" {
+			listedAll = true
 			break
 		}
 	}
 
 	// Update source with final history ID.
-	// Full sync always advances the cursor (it records the starting point
-	// for future incremental syncs), but warn when errors occurred.
+	// Full sync always advances the history cursor (it records the
+	// starting point for future incremental syncs), but warn when errors
+	// occurred.
 	historyIDStr := strconv.FormatUint(profile.HistoryID, 10)
 	if state.checkpoint.ErrorsCount > 0 {
 		s.logger.Warn("full sync completed with errors",
 			"errors", state.checkpoint.ErrorsCount,
 			"history_id", historyIDStr)
 	}
-	if err := s.store.UpdateSourceSyncCursor(source.ID, historyIDStr); err != nil {
-		s.logger.Warn("failed to update sync cursor", "error", err)
+	cursor := historyIDStr
+	if cs, ok := s.source.(CursorSource); ok {
+		// A source cursor marks everything before it as archived, so
+		// it only advances when nothing was cut off. Sources with
+		// History move past failed messages, as the history ID does;
+		// the rest relist them next time instead.
+		cursor = ""
+		if listedAll && (s.caps.History || state.checkpoint.ErrorsCount == 0) {
+			cursor = cs.Cursor()
+		}
+	}
+	if cursor != "" {
+		if err := s.store.UpdateSourceSyncCursor(source.ID, cursor); err != nil {
+			s.logger.Warn("failed to update sync cursor", "error", err)
+		}
 	}
 
 	// Mark sync complete
-	if err := s.store.CompleteSync(state.syncID, historyIDStr); err != nil {
+	if err := s.store.CompleteSync(state.syncID, cursor); err != nil {
 		s.logger.Warn("failed to complete sync", "error", err)
 	}
 
@@ -416,12 +585,17 @@ func (s *Syncer) Full(ctx context.Context, email string) (summary *gmail.SyncSum
 	return summary, nil
 }
 
-// syncLabels syncs all labels and returns a map of Gmail label ID to internal ID.
+// syncLabels syncs all labels and returns a map of Gmail label ID to
+// internal ID. It also resolves Options.IncludeLabels and
+// ExcludeLabels.
 func (s *Syncer) syncLabels(ctx context.Context, sourceID int64) (map[string]int64, error) {
-	labels, err := s.client.ListLabels(ctx)
+	labels, err := s.source.Labels(ctx)
 	if err != nil {
 		return nil, err
 	}
+	if s.labels, err = newLabelFilter(labels, s.opts.IncludeLabels, s.opts.ExcludeLabels); err != nil {
+		return nil, err
+	}
 
 	labelInfos := make(map[string]store.LabelInfo)
 	for _, l := range labels {
@@ -432,7 +606,13 @@ func (s *Syncer) syncLabels(ctx context.Context, sourceID int64) (map[string]int
 				labelType = "system"
 			}
 		}
-		labelInfos[l.ID] = store.LabelInfo{Name: l.Name, Type: labelType}
+		labelInfos[l.ID] = store.LabelInfo{
+			Name:       l.Name,
+			Type:       labelType,
+			Color:      l.BackgroundColor,
+			TextColor:  l.TextColor,
+			Visibility: l.LabelListVisibility,
+		}
 	}
 
 	return s.store.EnsureLabelsBatch(sourceID, labelInfos)
@@ -450,6 +630,7 @@ type messageData struct {
 	bcc            []mime.Address
 	gmailLabelIDs  []string
 	attachments    []mime.Attachment
+	inlineParts    []mime.Attachment
 	participantMap map[string]int64
 }
 
@@ -491,11 +672,10 @@ func (s *Syncer) parseToModel(sourceID int64, raw *gmail.RawMessage, threadID st
 			"error", errMsg)
 	}
 
-	// For IMAP sources, the API provides no real thread info —
-	// ThreadID is just the composite message ID. Derive a thread
-	// key from MIME threading headers to group related messages
-	// into conversations.
-	if s.opts.SourceType == "imap" {
+	// Sources without real thread IDs (IMAP's ThreadID is just the
+	// composite message ID) get a thread key derived from MIME
+	// threading headers to group related messages into conversations.
+	if !s.caps.Threads {
 		if derived := deriveThreadKey(parsed); derived != "" {
 			threadID = derived
 		}
@@ -519,6 +699,10 @@ func (s *Syncer) parseToModel(sourceID int64, raw *gmail.RawMessage, threadID st
 		parsed.Attachments[i].ContentType = textutil.EnsureUTF8(parsed.Attachments[i].ContentType)
 	}
 
+	// Images embedded in the HTML body are recorded apart from the
+	// message's attachments and not counted among them.
+	attachments, inlineParts := mime.SplitEmbedded(parsed.Attachments)
+
 	// Ensure participants exist in database
 	allAddresses := make([]mime.Address, 0, len(parsed.From)+len(parsed.To)+len(parsed.Cc)+len(parsed.Bcc))
 	allAddresses = append(allAddresses, parsed.From...)
@@ -538,8 +722,9 @@ func (s *Syncer) parseToModel(sourceID int64, raw *gmail.RawMessage, threadID st
 		}
 	}
 
-	// Use placeholder for conversation matching only (subject can be empty for storage)
-	convSubject := subject
+	// Title the conversation by the subject without its Re:/Fwd: chain,
+	// with a placeholder when that is empty (the message keeps its own).
+	convSubject := mime.NormalizeSubject(subject)
 	if convSubject == "" {
 		convSubject = "(no subject)"
 	}
@@ -565,8 +750,8 @@ func (s *Syncer) parseToModel(sourceID int64, raw *gmail.RawMessage, threadID st
 		Subject:         sql.NullString{String: subject, Valid: subject != ""},
 		Snippet:         sql.NullString{String: snippet, Valid: snippet != ""},
 		SizeEstimate:    raw.SizeEstimate,
-		HasAttachments:  len(parsed.Attachments) > 0,
-		AttachmentCount: len(parsed.Attachments),
+		HasAttachments:  len(attachments) > 0,
+		AttachmentCount: len(attachments),
 	}
 
 	// Set dates - always store in UTC for consistent querying
@@ -581,24 +766,34 @@ func (s *Syncer) parseToModel(sourceID int64, raw *gmail.RawMessage, threadID st
 		msg.SentAt = msg.InternalDate
 	}
 
+	// A placeholder's headers are not its raw MIME, so none is stored.
+	rawMIME := raw.Raw
+	if raw.MetadataOnly {
+		msg.MetadataOnly = true
+		rawMIME = nil
+	}
+
 	return &messageData{
 		message:        msg,
 		bodyText:       bodyText,
 		bodyHTML:       bodyHTML,
-		rawMIME:        raw.Raw,
+		rawMIME:        rawMIME,
 		from:           parsed.From,
 		to:             parsed.To,
 		cc:             parsed.Cc,
 		bcc:            parsed.Bcc,
 		gmailLabelIDs:  raw.LabelIDs,
-		attachments:    parsed.Attachments,
+		attachments:    attachments,
+		inlineParts:    inlineParts,
 		participantMap: participantMap,
 	}, nil
 }
 
-// persistMessage stores a parsed message and all related data. Returns
-// the internal message ID for hooks (e.g. vector-search enqueue).
-func (s *Syncer) persistMessage(data *messageData, labelMap map[string]int64) (int64, error) {
+// queueMessage queues a parsed message and all related data in the
+// write batch. done is called with the internal message ID (for hooks
+// such as vector-search enqueue) once the message is written, or with
+// the error that kept it out.
+func (s *Syncer) queueMessage(data *messageData, labelMap map[string]int64, done func(messageID int64, err error)) {
 	// Map Gmail label IDs to internal IDs
 	var labelIDs []int64
 	for _, gmailLabelID := range data.gmailLabelIDs {
@@ -623,61 +818,91 @@ func (s *Syncer) persistMessage(data *messageData, labelMap map[string]int64) (i
 		recipientSets = append(recipientSets, rs)
 	}
 
+	// Index for full-text search in the same transaction
+	var fts *store.FTSDoc
+	if s.store.FTS5Available() {
+		subject := ""
+		if data.message.Subject.Valid {
+			subject = data.message.Subject.String
+		}
+		fts = &store.FTSDoc{
+			Subject:  subject,
+			Body:     s.store.SearchableBody(data.bodyText),
+			FromAddr: joinEmails(data.from),
+			ToAddrs:  joinEmails(data.to),
+			CcAddrs:  joinEmails(data.cc),
+		}
+	}
+
+	if rfc822ID := data.message.RFC822MessageID; rfc822ID.Valid && !s.caps.StableIDs {
+		if s.pendingRFC822 == nil {
+			s.pendingRFC822 = make(map[string]bool)
+		}
+		s.pendingRFC822[rfc822ID.String] = true
+	}
+
 	// Persist atomically
-	messageID, err := s.store.PersistMessage(&store.MessagePersistData{
-		Message:    data.message,
-		BodyText:   sql.NullString{String: data.bodyText, Valid: data.bodyText != ""},
-		BodyHTML:   sql.NullString{String: data.bodyHTML, Valid: data.bodyHTML != ""},
-		RawMIME:    data.rawMIME,
-		Recipients: recipientSets,
-		LabelIDs:   labelIDs,
+	err := s.writes.Add(&store.MessagePersistData{
+		Message:     data.message,
+		BodyText:    sql.NullString{String: data.bodyText, Valid: data.bodyText != ""},
+		BodyHTML:    sql.NullString{String: data.bodyHTML, Valid: data.bodyHTML != ""},
+		RawMIME:     data.rawMIME,
+		Recipients:  recipientSets,
+		LabelIDs:    labelIDs,
+		InlineParts: data.inlineParts,
+		FTS:         fts,
+	}, func(messageID int64, err error) {
+		if err == nil {
+			s.storeAttachments(messageID, data.attachments)
+		}
+		done(messageID, err)
 	})
 	if err != nil {
-		return 0, err
+		s.logger.Warn("failed to write message batch", "error", err)
+	}
+	if s.writes.Len() == 0 {
+		clear(s.pendingRFC822)
 	}
+}
 
-	// Store attachments (best-effort, file I/O outside transaction)
-	if s.opts.AttachmentsDir != "" && len(data.attachments) > 0 {
-		for _, att := range data.attachments {
-			if err := s.storeAttachment(messageID, &att); err != nil {
-				s.logger.Warn("failed to store attachment", "message", messageID, "filename", att.Filename, "error", err)
-			}
-		}
+// flushWrites writes the messages queued in the write batch. A failed
+// batch is logged; each message's done callback has the error.
+func (s *Syncer) flushWrites() {
+	if err := s.writes.Flush(); err != nil {
+		s.logger.Warn("failed to write message batch", "error", err)
+	}
+	clear(s.pendingRFC822)
+}
 
-		// Correct metadata if any attachments failed to store
-		var storedCount int
-		if err := s.store.DB().QueryRow(
-			`SELECT COUNT(*) FROM attachments WHERE message_id = ?`,
-			messageID,
-		).Scan(&storedCount); err != nil {
-			s.logger.Warn("failed to count stored attachments",
-				"message", messageID, "error", err)
-		} else if storedCount != len(data.attachments) {
-			if _, err := s.store.DB().Exec(
-				`UPDATE messages SET has_attachments = ?, attachment_count = ? WHERE id = ?`,
-				storedCount > 0, storedCount, messageID,
-			); err != nil {
-				s.logger.Warn("failed to update attachment metadata",
-					"message", messageID, "error", err)
-			}
+// storeAttachments stores the attachments of a written message
+// (best-effort, file I/O outside the transaction).
+func (s *Syncer) storeAttachments(messageID int64, attachments []mime.Attachment) {
+	if s.opts.AttachmentsDir == "" || s.opts.SkipAttachments || len(attachments) == 0 {
+		return
+	}
+	for _, att := range attachments {
+		if err := s.storeAttachment(messageID, &att); err != nil {
+			s.logger.Warn("failed to store attachment", "message", messageID, "filename", att.Filename, "error", err)
 		}
 	}
 
-	// Populate FTS index (best-effort outside transaction)
-	if s.store.FTS5Available() {
-		subject := ""
-		if data.message.Subject.Valid {
-			subject = data.message.Subject.String
-		}
-		fromAddr := joinEmails(data.from)
-		toAddrs := joinEmails(data.to)
-		ccAddrs := joinEmails(data.cc)
-		if err := s.store.UpsertFTS(messageID, subject, data.bodyText, fromAddr, toAddrs, ccAddrs); err != nil {
-			s.logger.Warn("failed to upsert FTS", "message", messageID, "error", err)
+	// Correct metadata if any attachments failed to store
+	var storedCount int
+	if err := s.store.DB().QueryRow(
+		`SELECT COUNT(*) FROM attachments WHERE message_id = ?`,
+		messageID,
+	).Scan(&storedCount); err != nil {
+		s.logger.Warn("failed to count stored attachments",
+			"message", messageID, "error", err)
+	} else if storedCount != len(attachments) {
+		if _, err := s.store.DB().Exec(
+			`UPDATE messages SET has_attachments = ?, attachment_count = ? WHERE id = ?`,
+			storedCount > 0, storedCount, messageID,
+		); err != nil {
+			s.logger.Warn("failed to update attachment metadata",
+				"message", messageID, "error", err)
 		}
 	}
-
-	return messageID, nil
 }
 
 // errDuplicateRFC822 signals that a message was skipped because
@@ -686,28 +911,54 @@ func (s *Syncer) persistMessage(data *messageData, labelMap map[string]int64) (i
 // composite IDs change when messages move between mailboxes.
 var errDuplicateRFC822 = errors.New("duplicate RFC822 Message-ID")
 
+// errParse marks ingest errors from parsing a message rather than
+// storing it.
+var errParse = errors.New("parse message")
+
 // ingestMessage parses and stores a single message, returning the
 // internal message ID on success. Returns (0, errDuplicateRFC822) for
-// IMAP deduplication skips.
+// deduplication skips on sources without stable IDs. It writes
+// whatever else is queued in the write batch along with it.
 func (s *Syncer) ingestMessage(ctx context.Context, sourceID int64, raw *gmail.RawMessage, threadID string, labelMap map[string]int64) (int64, error) {
+	var messageID int64
+	var ingestErr error
+	s.queueIngest(ctx, sourceID, raw, threadID, labelMap, func(id int64, err error) {
+		messageID, ingestErr = id, err
+	})
+	s.flushWrites()
+	return messageID, ingestErr
+}
+
+// queueIngest parses a message and queues it in the write batch. done
+// is called with the internal message ID once the message is written,
+// or with the error that kept it out: at once for parse errors and
+// deduplication skips (errDuplicateRFC822), at the latest by the next
+// flushWrites otherwise.
+func (s *Syncer) queueIngest(ctx context.Context, sourceID int64, raw *gmail.RawMessage, threadID string, labelMap map[string]int64, done func(messageID int64, err error)) {
 	data, err := s.parseToModel(sourceID, raw, threadID)
 	if err != nil {
-		return 0, err
+		done(0, fmt.Errorf("%w: %w", errParse, err))
+		return
 	}
 
-	// For IMAP sources, check if a message with the same RFC822
-	// Message-ID already exists under a different composite ID.
-	// This handles messages that moved between mailboxes across
-	// syncs (e.g. All Mail → Trash changes the mailbox|uid key).
+	// For sources without stable IDs (IMAP), check if a message with
+	// the same RFC822 Message-ID already exists under a different
+	// composite ID. This handles messages that moved between mailboxes
+	// across syncs (e.g. All Mail → Trash changes the mailbox|uid key).
 	// When matched, update the existing row's composite ID and
 	// labels so future syncs skip it at the ID-filtering stage
 	// instead of re-downloading the MIME body each time.
-	if s.opts.SourceType == "imap" &&
+	if !s.caps.StableIDs &&
 		data.message.RFC822MessageID.Valid {
+		// A copy still queued must be written before it can match.
+		if s.pendingRFC822[data.message.RFC822MessageID.String] {
+			s.flushWrites()
+		}
 		existingID, err := s.store.GetMessageIDByRFC822ID(
 			sourceID, data.message.RFC822MessageID.String)
 		if err != nil {
-			return 0, fmt.Errorf("check rfc822 dedup: %w", err)
+			done(0, fmt.Errorf("check rfc822 dedup: %w", err))
+			return
 		}
 		if existingID > 0 {
 			var labelIDs []int64
@@ -721,13 +972,15 @@ func (s *Syncer) ingestMessage(ctx context.Context, sourceID int64, raw *gmail.R
 				data.message.SourceMessageID,
 				labelIDs,
 			); err != nil {
-				return 0, fmt.Errorf("update dedup message: %w", err)
+				done(0, fmt.Errorf("update dedup message: %w", err))
+				return
 			}
-			return 0, errDuplicateRFC822
+			done(0, errDuplicateRFC822)
+			return
 		}
 	}
 
-	return s.persistMessage(data, labelMap)
+	s.queueMessage(data, labelMap, done)
 }
 
 // ensureAddressUTF8 validates and converts address names to valid UTF-8 in place.
@@ -773,13 +1026,13 @@ func buildRecipientSet(recipientType string, addresses []mime.Address, participa
 
 // storeAttachment stores an attachment to disk and records it in the database.
 func (s *Syncer) storeAttachment(messageID int64, att *mime.Attachment) error {
-	storagePath, err := export.StoreAttachmentFile(s.opts.AttachmentsDir, att)
+	storagePath, storedSize, err := export.StoreAttachmentFile(s.opts.AttachmentsDir, att)
 	if err != nil || storagePath == "" {
 		return err
 	}
 
 	// Record in database
-	return s.store.UpsertAttachment(messageID, att.Filename, att.ContentType, storagePath, att.ContentHash, len(att.Content))
+	return s.store.UpsertStoredAttachment(messageID, att.Filename, att.ContentType, storagePath, att.ContentHash, len(att.Content), storedSize)
 }
 
 // joinEmails concatenates email addresses from a slice of mime.Address with spaces.
@@ -858,3 +1111,38 @@ func extractSubjectFromSnippet(snippet string) string {
 	line := textutil.FirstLine(snippet)
 	return textutil.TruncateRunes(line, 80)
 }
+
+// recordStorageQuota records the storage quota of the account when
+// Options.StorageQuota is set and the source reports one.
+func (s *Syncer) recordStorageQuota(ctx context.Context, sourceID int64) {
+	if !s.opts.StorageQuota {
+		return
+	}
+	qs, ok := s.source.(QuotaSource)
+	if !ok {
+		return
+	}
+	q, err := qs.StorageQuota(ctx)
+	if err != nil {
+		s.logger.Warn("read storage quota", "error", err)
+		return
+	}
+	if q == nil {
+		return
+	}
+	if err := s.store.RecordStorageQuota(sourceID, q.Limit, q.Usage, q.UsageInDrive, q.UsageInDriveTrash); err != nil {
+		s.logger.Warn("record storage quota", "error", err)
+	}
+}
+
+// recordRemoteTotal stores the message count the provider reports for
+// the account, which archive completeness compares against. A failure
+// only costs the completeness score, so it doesn't fail the sync.
+func (s *Syncer) recordRemoteTotal(sourceID, total int64) {
+	if total <= 0 {
+		return
+	}
+	if err := s.store.SetSourceRemoteTotal(sourceID, total); err != nil {
+		s.logger.Warn("record remote message total", "error", err)
+	}
+}
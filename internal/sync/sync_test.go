@@ -1,10 +1,14 @@
 package sync
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -12,10 +16,15 @@ import (
 	"testing"
 	"unicode/utf8"
 
+	"github.com/wesm/msgvault/internal/crash"
 	"github.com/wesm/msgvault/internal/gmail"
 	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
 	testemail "github.com/wesm/msgvault/internal/testutil/email"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 // panicOnBatchAPI wraps a MockAPI and panics when GetMessagesRawBatch is called.
@@ -45,6 +54,32 @@ func TestFullSync_PanicReturnsError(t *testing.T) {
 	}
 }
 
+func TestFullSync_PanicWritesCrashReport(t *testing.T) {
+	dir := t.TempDir()
+	crash.Configure(crash.Options{Dir: dir})
+	t.Cleanup(func() { crash.Configure(crash.Options{}) })
+
+	env := newTestEnv(t)
+	seedMessages(env, 1, 12345, "msg1")
+	env.Syncer = New(&panicOnBatchAPI{MockAPI: env.Mock}, env.Store, nil)
+
+	_, err := env.Syncer.Full(env.Context, testEmail)
+	if err == nil {
+		t.Fatal("expected error from panic recovery, got nil")
+	}
+	reports, _ := filepath.Glob(filepath.Join(dir, "msgvault-crash-*.txt"))
+	if len(reports) != 1 {
+		t.Fatalf("crash reports = %v, want one", reports)
+	}
+	if !strings.Contains(err.Error(), reports[0]) {
+		t.Errorf("error %q does not name the crash report", err)
+	}
+	data, _ := os.ReadFile(reports[0])
+	if !strings.Contains(string(data), "panicOnBatchAPI") {
+		t.Errorf("crash report lacks the panicking frame:\n%s", data)
+	}
+}
+
 // panicOnHistoryAPI wraps a MockAPI and panics when ListHistory is called.
 // Used to test that Incremental() recovers from panics gracefully.
 type panicOnHistoryAPI struct {
@@ -91,6 +126,51 @@ func TestFullSync(t *testing.T) {
 	assertMessageCount(t, env.Store, 3)
 }
 
+func TestFullSync_RecordsRemoteTotal(t *testing.T) {
+	env := newTestEnv(t)
+	seedMessages(env, 5, 12345, "msg1", "msg2")
+
+	runFullSync(t, env)
+
+	src, err := env.Store.GetSourceByIdentifier(testEmail)
+	if err != nil || src == nil {
+		t.Fatalf("get source: %v", err)
+	}
+	total, ok, err := env.Store.SourceRemoteTotal(src.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || total != 5 {
+		t.Errorf("SourceRemoteTotal = %d, %v; want 5, true", total, ok)
+	}
+}
+
+func TestFullSync_RecordsStorageQuota(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StorageQuota = true
+	env := newTestEnv(t, opts)
+	seedMessages(env, 2, 12345, "msg1", "msg2")
+	env.Mock.StorageQuota = &gmail.StorageQuota{Limit: 1000, Usage: 600, UsageInDrive: 200}
+
+	runFullSync(t, env)
+
+	src, err := env.Store.GetSourceByIdentifier(testEmail)
+	if err != nil || src == nil {
+		t.Fatalf("get source: %v", err)
+	}
+	samples, err := env.Store.StorageQuotaHistory(src.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].LimitBytes != 1000 || samples[0].OutsideDriveBytes() != 400 {
+		t.Errorf("StorageQuotaHistory = %+v, want one sample of 400 outside Drive", samples)
+	}
+
+	// A quota the account cannot read fails nothing but the sample.
+	env.Mock.StorageQuota = nil
+	runIncrementalSync(t, env)
+}
+
 func TestFullSyncResume(t *testing.T) {
 	env := newTestEnv(t)
 
@@ -356,6 +436,44 @@ func TestSyncerWithLogger(t *testing.T) {
 	}
 }
 
+func TestSync_LogRecordsCarryAccountAndRunID(t *testing.T) {
+	env := newTestEnv(t)
+	seedMessages(env, 1, 12345, "msg1")
+
+	var buf bytes.Buffer
+	base := slog.New(slog.NewJSONHandler(&buf, nil))
+	env.Syncer.WithLogger(base)
+
+	runFullSync(t, env)
+	runIncrementalSync(t, env)
+
+	runIDs := make(map[float64]bool)
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var rec map[string]any
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("log line is not JSON: %v\n%s", err, line)
+		}
+		if rec["account"] != testEmail {
+			t.Errorf("record %q account = %v, want %s", rec["msg"], rec["account"], testEmail)
+		}
+		id, ok := rec["sync_run_id"].(float64)
+		if !ok || id <= 0 {
+			t.Errorf("record %q sync_run_id = %v", rec["msg"], rec["sync_run_id"])
+		}
+		runIDs[id] = true
+	}
+	if len(runIDs) != 2 {
+		t.Errorf("saw %d sync run IDs, want one per run (2)", len(runIDs))
+	}
+
+	// The syncer's own logger is restored after each run.
+	buf.Reset()
+	env.Syncer.logger.Info("after")
+	if strings.Contains(buf.String(), "sync_run_id") {
+		t.Errorf("logger still scoped after sync: %s", buf.String())
+	}
+}
+
 func TestSyncerWithProgress(t *testing.T) {
 	env := newTestEnv(t)
 	syncer := env.Syncer.WithProgress(gmail.NullProgress{})
@@ -446,6 +564,67 @@ func TestIncrementalSyncHistoryExpired(t *testing.T) {
 	}
 }
 
+func TestIncrementalSyncHistoryExpired_Recovers(t *testing.T) {
+	env := newTestEnv(t, &Options{RecoverExpiredHistory: true, Query: "-label:chats"})
+	seedMessages(env, 3, 12340, "msg1", "msg2", "msg3")
+	runFullSync(t, env)
+
+	// While the history expires, msg2 is deleted for good and msg4
+	// arrives.
+	delete(env.Mock.Messages, "msg2")
+	env.Mock.AddMessage("msg4", testMIME(), []string{"INBOX"})
+	env.Mock.Profile.HistoryID = 99999
+	env.Mock.HistoryError = &gmail.NotFoundError{Path: "/history"}
+	env.Mock.ListQueries = nil
+
+	source, err := env.Store.GetOrCreateSource("gmail", testEmail)
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+	summary := runIncrementalSync(t, env)
+	if !summary.Recovered || summary.MessagesDeleted != 1 {
+		t.Errorf("summary Recovered = %v, MessagesDeleted = %d, want true, 1", summary.Recovered, summary.MessagesDeleted)
+	}
+	assertSummary(t, summary, WantSummary{Added: intPtr(1)})
+	assertMessageCount(t, env.Store, 3) // msg1, msg3, msg4; msg2 is deleted
+	assertDeletedFromSource(t, env.Store, "msg2", true)
+	assertDeletedFromSource(t, env.Store, "msg1", false)
+
+	wantQuery := "-label:chats " + recoveryQuery(source.LastSyncAt.Time)
+	if len(env.Mock.ListQueries) == 0 || env.Mock.ListQueries[0] != wantQuery {
+		t.Errorf("full sync queries = %q, want first %q", env.Mock.ListQueries, wantQuery)
+	}
+	if last := env.Mock.ListQueries[len(env.Mock.ListQueries)-1]; last != "in:anywhere" {
+		t.Errorf("reconcile query = %q, want in:anywhere", last)
+	}
+	if env.Syncer.opts.Query != "-label:chats" || !env.Syncer.opts.RecoverExpiredHistory {
+		t.Errorf("options not restored after recovery: %+v", env.Syncer.opts)
+	}
+
+	// The cursor moved to now, so the next sync is incremental again.
+	source, err = env.Store.GetOrCreateSource("gmail", testEmail)
+	testutil.MustNoErr(t, err, "GetOrCreateSource")
+	if source.SyncCursor.String != "99999" {
+		t.Errorf("sync cursor = %q, want 99999", source.SyncCursor.String)
+	}
+}
+
+func TestIncrementalSyncHistoryExpired_RecoverSkipsEmptyListing(t *testing.T) {
+	env := newTestEnv(t, &Options{RecoverExpiredHistory: true})
+	seedMessages(env, 2, 12340, "msg1", "msg2")
+	runFullSync(t, env)
+
+	// A listing that suddenly returns nothing must not mark the whole
+	// archive deleted.
+	env.Mock.Messages = map[string]*gmail.RawMessage{}
+	env.Mock.Profile.HistoryID = 99999
+	env.Mock.HistoryError = &gmail.NotFoundError{Path: "/history"}
+
+	summary := runIncrementalSync(t, env)
+	if summary.MessagesDeleted != 0 || summary.Errors != 1 {
+		t.Errorf("MessagesDeleted = %d, Errors = %d, want 0, 1", summary.MessagesDeleted, summary.Errors)
+	}
+	assertDeletedFromSource(t, env.Store, "msg1", false)
+}
+
 func TestIncrementalSyncProfileError(t *testing.T) {
 	env := newTestEnv(t)
 	source := env.CreateSourceWithHistory(t, "12345")
@@ -500,7 +679,9 @@ func TestIncrementalSyncWithLabelRemoved(t *testing.T) {
 	callsAfterFull := len(env.Mock.GetMessageCalls)
 
 	// Now simulate label removal via incremental
-	env.SetHistory(12350, historyLabelRemoved("msg1", "STARRED"))
+	removed := historyLabelRemoved("msg1", "STARRED")
+	removed.ID = 12345
+	env.SetHistory(12350, removed)
 
 	summary := runIncrementalSync(t, env)
 	assertSummary(t, summary, WantSummary{Found: intPtr(1)})
@@ -516,6 +697,20 @@ func TestIncrementalSyncWithLabelRemoved(t *testing.T) {
 	assertMessageNotHasLabel(t, env.Store, "msg1", "STARRED")
 	// INBOX should still be there
 	assertMessageHasLabel(t, env.Store, "msg1", "INBOX")
+
+	// The removal is in the label history under its history record.
+	var action, historyID string
+	err := env.Store.DB().QueryRow(`
+		SELECT e.action, e.history_id FROM label_events e
+		JOIN labels l ON l.id = e.label_id
+		WHERE l.source_label_id = 'STARRED'
+	`).Scan(&action, &historyID)
+	if err != nil {
+		t.Fatalf("query label history: %v", err)
+	}
+	if action != "remove" || historyID != "12345" {
+		t.Errorf("label event = %s at history %s, want remove at 12345", action, historyID)
+	}
 }
 
 func TestIncrementalSyncLabelAddedToNewMessage(t *testing.T) {
@@ -573,6 +768,28 @@ func TestFullSyncWithAttachment(t *testing.T) {
 	assertAttachmentCount(t, env.Store, 1)
 }
 
+func TestFullSync_SkipAttachments(t *testing.T) {
+	env := newTestEnv(t)
+	env.Mock.Profile.MessagesTotal = 1
+	env.Mock.Profile.HistoryID = 12345
+	env.Mock.AddMessage("msg-with-attachment", testMIMEWithAttachment(), []string{"INBOX"})
+
+	attachDir := filepath.Join(env.TmpDir, "attachments")
+	env.Syncer = New(env.Mock, env.Store, &Options{AttachmentsDir: attachDir, SkipAttachments: true})
+
+	runFullSync(t, env)
+	assertAttachmentCount(t, env.Store, 0)
+	if _, err := os.Stat(attachDir); !os.IsNotExist(err) {
+		t.Error("attachments directory should not have been created")
+	}
+
+	// The message still counts its attachment, to be fetched later.
+	pending, err := env.Store.MessagesWithPendingAttachments(nil)
+	if err != nil || len(pending) != 1 {
+		t.Errorf("MessagesWithPendingAttachments = %v, %v; want one", pending, err)
+	}
+}
+
 func TestFullSyncWithEmptyAttachment(t *testing.T) {
 	env := newTestEnv(t)
 
@@ -1120,6 +1337,29 @@ func TestInitSyncState_Resume(t *testing.T) {
 	}
 }
 
+func TestInitSyncState_SkipsImportCheckpoint(t *testing.T) {
+	env := newTestEnv(t)
+	source := env.CreateSource(t)
+
+	// An interrupted Takeout import into the same source.
+	syncID, err := env.Store.StartSync(source.ID, "import-mbox")
+	if err != nil {
+		t.Fatalf("StartSync: %v", err)
+	}
+	checkpoint := &store.Checkpoint{PageToken: `{"file":"/tmp/takeout.mbox","offset":1024}`}
+	if err := env.Store.UpdateSyncCheckpoint(syncID, checkpoint); err != nil {
+		t.Fatalf("UpdateSyncCheckpoint: %v", err)
+	}
+
+	state, err := env.Syncer.initSyncState(source.ID)
+	if err != nil {
+		t.Fatalf("initSyncState: %v", err)
+	}
+	if state.wasResumed || state.pageToken != "" || state.syncID == syncID {
+		t.Errorf("resumed import checkpoint: wasResumed=%v pageToken=%q syncID=%d", state.wasResumed, state.pageToken, state.syncID)
+	}
+}
+
 func TestInitSyncState_NoResumeOption(t *testing.T) {
 	env := newTestEnv(t)
 	env.SetOptions(t, func(o *Options) {
@@ -1791,3 +2031,61 @@ func TestIncrementalSyncLabelRemovedWithMissingRaw(t *testing.T) {
 	assertMessageNotHasLabel(t, env.Store, "msg1", "STARRED")
 	assertMessageHasLabel(t, env.Store, "msg1", "INBOX")
 }
+
+func TestSync_Spans(t *testing.T) {
+	sr := testutil.RecordSpans(t)
+	env := newTestEnv(t)
+	seedMessages(env, 2, 12345, "msg1", "msg2")
+
+	runFullSync(t, env)
+	runIncrementalSync(t, env)
+
+	byName := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, s := range sr.Ended() {
+		byName[s.Name()] = append(byName[s.Name()], s)
+	}
+	for _, name := range []string{"sync.full", "sync.incremental"} {
+		spans := byName[name]
+		if len(spans) != 1 {
+			t.Fatalf("%s spans = %d, want 1", name, len(spans))
+		}
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, kv := range spans[0].Attributes() {
+			attrs[kv.Key] = kv.Value
+		}
+		if got := attrs["account"].AsString(); got != testEmail {
+			t.Errorf("%s account = %q, want %s", name, got, testEmail)
+		}
+		if attrs["sync_run_id"].AsInt64() <= 0 {
+			t.Errorf("%s sync_run_id = %v", name, attrs["sync_run_id"])
+		}
+	}
+
+	full := byName["sync.full"][0]
+	batches := byName["sync.batch"]
+	if len(batches) == 0 {
+		t.Fatal("no sync.batch spans")
+	}
+	for _, b := range batches {
+		if b.Parent().SpanID() != full.SpanContext().SpanID() {
+			t.Errorf("sync.batch parent = %s, want sync.full %s", b.Parent().SpanID(), full.SpanContext().SpanID())
+		}
+	}
+}
+
+func TestSync_SpanRecordsFailure(t *testing.T) {
+	sr := testutil.RecordSpans(t)
+	env := newTestEnv(t)
+	env.Mock.ProfileError = errors.New("boom")
+
+	if _, err := env.Syncer.Full(context.Background(), testEmail); err == nil {
+		t.Fatal("expected error")
+	}
+	spans := sr.Ended()
+	if len(spans) != 1 || spans[0].Name() != "sync.full" {
+		t.Fatalf("spans = %v, want one sync.full", spans)
+	}
+	if spans[0].Status().Code != codes.Error {
+		t.Errorf("status = %v, want Error", spans[0].Status())
+	}
+}
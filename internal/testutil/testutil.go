@@ -8,4 +8,5 @@
 //   - security_data.go: security test vectors (PathTraversalCases)
 //   - builders.go: test data builders
 //   - encoding.go: encoding test helpers
+//   - tracing.go: span recording (RecordSpans)
 package testutil
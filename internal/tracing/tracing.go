@@ -0,0 +1,128 @@
+// Package tracing wires OpenTelemetry tracing for long-running
+// deployments. Tracing is off unless an exporter is configured; until
+// Setup installs a provider, the global no-op tracer makes every span
+// in sync, store, and server code free.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies msgvault's spans to the provider.
+const tracerName = "github.com/wesm/msgvault"
+
+// Options selects and configures the span exporter.
+type Options struct {
+	// Exporter is "otlp" (OTLP over HTTP), "stdout" (JSON spans,
+	// for debugging), or "" / "none" to leave tracing off.
+	Exporter string
+
+	// Endpoint is the OTLP collector, as host:port or a full URL.
+	// Empty uses OTEL_EXPORTER_OTLP_ENDPOINT, then localhost:4318.
+	Endpoint string
+
+	// Insecure sends OTLP over plain HTTP when Endpoint is host:port.
+	// A full URL's scheme decides on its own.
+	Insecure bool
+
+	// SampleRatio is the fraction of new traces recorded. Zero means
+	// all of them. Traces continued from a sampled parent are always
+	// recorded.
+	SampleRatio float64
+
+	// ServiceName is reported as service.name. Empty means "msgvault".
+	ServiceName string
+
+	// Writer receives stdout-exporter spans. Nil means os.Stderr, so
+	// spans don't interleave with command output.
+	Writer io.Writer
+}
+
+// Setup installs a global tracer provider and W3C trace-context
+// propagation according to opts. The returned function flushes
+// buffered spans and must be called before the process exits. With
+// tracing off, Setup changes nothing and returns a no-op.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	noop := func(context.Context) error { return nil }
+
+	var exp sdktrace.SpanExporter
+	switch strings.ToLower(strings.TrimSpace(opts.Exporter)) {
+	case "", "none":
+		return noop, nil
+	case "stdout":
+		w := opts.Writer
+		if w == nil {
+			w = os.Stderr
+		}
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		var clientOpts []otlptracehttp.Option
+		switch {
+		case strings.Contains(opts.Endpoint, "://"):
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		case opts.Endpoint != "":
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpoint(opts.Endpoint))
+			if opts.Insecure {
+				clientOpts = append(clientOpts, otlptracehttp.WithInsecure())
+			}
+		}
+		exp, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return noop, fmt.Errorf("unknown tracing exporter %q (want otlp, stdout, or none)", opts.Exporter)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("create %s exporter: %w", opts.Exporter, err)
+	}
+
+	if opts.SampleRatio < 0 || opts.SampleRatio > 1 {
+		return noop, fmt.Errorf("tracing sample ratio %v must be between 0 and 1", opts.SampleRatio)
+	}
+	sampler := sdktrace.AlwaysSample()
+	if opts.SampleRatio > 0 && opts.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(opts.SampleRatio)
+	}
+	name := opts.ServiceName
+	if name == "" {
+		name = "msgvault"
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", name))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+	return tp.Shutdown, nil
+}
+
+// Start begins a span named name as a child of any span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// End records err (if any) on span and ends it. Pass a pointer to a
+// named error return so a deferred End sees the final value.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{name: "disabled", opts: Options{}},
+		{name: "none", opts: Options{Exporter: "none"}},
+		{name: "stdout", opts: Options{Exporter: "stdout"}},
+		{name: "otlp host port", opts: Options{Exporter: "otlp", Endpoint: "collector:4318", Insecure: true}},
+		{name: "otlp url", opts: Options{Exporter: "OTLP", Endpoint: "https://collector.example.com/v1/traces"}},
+		{name: "sampled", opts: Options{Exporter: "stdout", SampleRatio: 0.25}},
+		{name: "unknown exporter", opts: Options{Exporter: "jaeger"}, wantErr: "unknown tracing exporter"},
+		{name: "bad ratio", opts: Options{Exporter: "stdout", SampleRatio: 1.5}, wantErr: "sample ratio"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prev := otel.GetTracerProvider()
+			t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+			tt.opts.Writer = &bytes.Buffer{}
+			shutdown, err := Setup(context.Background(), tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Setup: %v", err)
+			}
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetup_StdoutWritesSpans(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: "stdout", Writer: &buf})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+	_, span := Start(context.Background(), "sync.full")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"Name":"sync.full"`) {
+		t.Errorf("stdout exporter output missing span:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "msgvault") {
+		t.Errorf("stdout exporter output missing service name:\n%s", buf.String())
+	}
+}
+
+func TestEnd(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)).Tracer("test")
+
+	_, ok := tracer.Start(context.Background(), "ok")
+	var nilErr error
+	End(ok, &nilErr)
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	err := errors.New("boom")
+	End(failed, &err)
+
+	spans := sr.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want 2", len(spans))
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("ok status = %v, want Unset", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error || spans[1].Status().Description != "boom" {
+		t.Errorf("failed status = %v, want Error boom", spans[1].Status())
+	}
+	if len(spans[1].Events()) != 1 {
+		t.Errorf("failed events = %d, want recorded error", len(spans[1].Events()))
+	}
+}
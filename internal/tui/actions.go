@@ -36,6 +36,7 @@ type ActionController struct {
 	queries   query.Engine
 	deletions *deletion.Manager
 	dataDir   string
+	attachDir string // empty means <dataDir>/attachments
 }
 
 // NewActionController creates a new action controller.
@@ -48,6 +49,20 @@ func NewActionController(queries query.Engine, dataDir string, deletions *deleti
 	}
 }
 
+// WithAttachmentsDir overrides where attachments are exported from.
+func (c *ActionController) WithAttachmentsDir(dir string) *ActionController {
+	c.attachDir = dir
+	return c
+}
+
+// attachmentsDir returns the directory holding attachment files.
+func (c *ActionController) attachmentsDir() string {
+	if c.attachDir != "" {
+		return c.attachDir
+	}
+	return filepath.Join(c.dataDir, "attachments")
+}
+
 // SaveManifest initializes the deletion manager if needed and saves the manifest.
 func (c *ActionController) SaveManifest(manifest *deletion.Manifest) error {
 	if c.deletions == nil {
@@ -218,7 +233,7 @@ func (c *ActionController) ExportAttachments(detail *query.MessageDetail, select
 		return nil
 	}
 
-	attachmentsDir := filepath.Join(c.dataDir, "attachments")
+	attachmentsDir := c.attachmentsDir()
 	subject := detail.Subject
 	if subject == "" {
 		subject = "attachments"
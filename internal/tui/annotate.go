@@ -0,0 +1,151 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// annotationField is the annotation being edited in the message view.
+type annotationField int
+
+const (
+	annotateNone annotationField = iota
+	annotateNote
+	annotateTags
+)
+
+// annotationSavedMsg is returned when a note, pin or tags edit has been
+// saved.
+type annotationSavedMsg struct {
+	messageID int64
+	note      string
+	pinned    bool
+	tags      []string
+	flash     string
+	err       error
+}
+
+// annotationUnavailable returns the flash shown when annotations cannot
+// be edited, or "" if they can.
+func (m Model) annotationUnavailable() string {
+	switch {
+	case m.isRemote:
+		return "Notes and tags not available in remote mode"
+	case m.annotator == nil:
+		return "Notes and tags not available"
+	case m.messageDetail == nil:
+		return "No message loaded"
+	}
+	return ""
+}
+
+// togglePin pins or unpins the message shown.
+func (m Model) togglePin() (tea.Model, tea.Cmd) {
+	if flash := m.annotationUnavailable(); flash != "" {
+		return m.showFlash(flash)
+	}
+	d := m.messageDetail
+	res := annotationSavedMsg{messageID: d.ID, note: d.Note, pinned: !d.Pinned, tags: d.Tags, flash: "Unpinned"}
+	if res.pinned {
+		res.flash = "Pinned"
+	}
+	annotator := m.annotator
+	return m, func() tea.Msg {
+		res.err = annotator.SetMessagePinned(res.messageID, res.pinned)
+		return res
+	}
+}
+
+// startAnnotating opens the input line for editing the note or tags of
+// the message shown.
+func (m Model) startAnnotating(field annotationField) (tea.Model, tea.Cmd) {
+	if flash := m.annotationUnavailable(); flash != "" {
+		return m.showFlash(flash)
+	}
+	m.annotating = field
+	m.annotateInput = textinput.New()
+	m.annotateInput.Width = 60
+	if field == annotateNote {
+		m.annotateInput.Placeholder = "note (empty to remove)"
+		m.annotateInput.CharLimit = 2000
+		m.annotateInput.SetValue(m.messageDetail.Note)
+	} else {
+		m.annotateInput.Placeholder = "tags, separated by spaces"
+		m.annotateInput.CharLimit = 500
+		m.annotateInput.SetValue(strings.Join(m.messageDetail.Tags, " "))
+	}
+	m.annotateInput.CursorEnd()
+	m.annotateInput.Focus()
+	return m, m.annotateInput.Cursor.BlinkCmd()
+}
+
+// handleAnnotateKeys handles keys while a note or tags are edited:
+// Enter saves, Esc cancels.
+func (m Model) handleAnnotateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.annotating = annotateNone
+		return m, nil
+	case "enter":
+		field := m.annotating
+		m.annotating = annotateNone
+		if m.messageDetail == nil || m.annotator == nil {
+			return m, nil
+		}
+		d := m.messageDetail
+		res := annotationSavedMsg{messageID: d.ID, note: d.Note, pinned: d.Pinned, tags: d.Tags}
+		value := m.annotateInput.Value()
+		annotator := m.annotator
+		if field == annotateNote {
+			res.note = strings.TrimSpace(value)
+			res.flash = "Note saved"
+			if res.note == "" {
+				res.flash = "Note removed"
+			}
+			return m, func() tea.Msg {
+				res.err = annotator.SetMessageNote(res.messageID, res.note)
+				return res
+			}
+		}
+		res.tags = parseTagInput(value)
+		res.flash = "Tags saved"
+		return m, func() tea.Msg {
+			res.err = annotator.SetMessageTags(res.messageID, res.tags)
+			return res
+		}
+	default:
+		var cmd tea.Cmd
+		m.annotateInput, cmd = m.annotateInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// parseTagInput splits the tags typed into the input line, lowercased,
+// sorted and without duplicates, as they are stored.
+func parseTagInput(s string) []string {
+	tags := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// handleAnnotationSaved shows a saved annotation on the message, if it
+// is still the one shown.
+func (m Model) handleAnnotationSaved(msg annotationSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m.showFlash("Save failed: " + msg.err.Error())
+	}
+	if m.messageDetail != nil && m.messageDetail.ID == msg.messageID {
+		d := *m.messageDetail
+		d.Note = msg.note
+		d.Pinned = msg.pinned
+		d.Tags = msg.tags
+		m.messageDetail = &d
+		m.updateDetailLineCount()
+		m.clampDetailScroll()
+	}
+	return m.showFlash(msg.flash)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
 	"github.com/mattn/go-runewidth"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/search"
 )
@@ -125,7 +126,7 @@ func formatBytes(bytes int64) string {
 		div *= unit
 		exp++
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return fmt.Sprintf("%s %cB", i18n.Decimal(float64(bytes)/float64(div), 1), "KMGTPE"[exp])
 }
 
 // formatCount formats a count as a human-readable string (e.g., "1.5K", "2.3M").
@@ -134,9 +135,9 @@ func formatCount(n int64) string {
 		return fmt.Sprintf("%d", n)
 	}
 	if n < 1000000 {
-		return fmt.Sprintf("%.1fK", float64(n)/1000)
+		return i18n.Decimal(float64(n)/1000, 1) + "K"
 	}
-	return fmt.Sprintf("%.1fM", float64(n)/1000000)
+	return i18n.Decimal(float64(n)/1000000, 1) + "M"
 }
 
 // padRight pads a string with spaces to fill width terminal cells.
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"runtime/debug"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wesm/msgvault/internal/crash"
+)
+
+// crashedView replaces the UI after a panic in View, which cannot end
+// the program itself; the next key press quits.
+const crashedView = "msgvault crashed. Press any key to exit and see the crash report.\n"
+
+// Guard wraps a model so that a panic in Init, Update, or View ends
+// the program cleanly, restoring the terminal, instead of Bubble
+// Tea's own recovery printing the stack over the alt screen. After
+// the program exits, Panic returns what was recovered so the caller
+// can write a crash report.
+type Guard struct {
+	model tea.Model
+	panic *crash.Recovered
+}
+
+// NewGuard wraps m.
+func NewGuard(m tea.Model) *Guard {
+	return &Guard{model: m}
+}
+
+// Panic returns the recovered panic, or nil if the model never
+// panicked.
+func (g *Guard) Panic() *crash.Recovered {
+	return g.panic
+}
+
+// record keeps the first panic recovered.
+func (g *Guard) record(r any) {
+	if g.panic == nil {
+		g.panic = &crash.Recovered{Value: r, Stack: debug.Stack()}
+	}
+}
+
+func (g *Guard) Init() (cmd tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			g.record(r)
+			cmd = tea.Quit
+		}
+	}()
+	return g.model.Init()
+}
+
+func (g *Guard) Update(msg tea.Msg) (_ tea.Model, cmd tea.Cmd) {
+	if g.panic != nil {
+		return g, tea.Quit
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.record(r)
+			cmd = tea.Quit
+		}
+	}()
+	g.model, cmd = g.model.Update(msg)
+	return g, cmd
+}
+
+func (g *Guard) View() (view string) {
+	if g.panic != nil {
+		return crashedView
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			g.record(r)
+			view = crashedView
+		}
+	}()
+	return g.model.View()
+}
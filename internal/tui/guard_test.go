@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicModel panics in whichever method is named by panicIn.
+type panicModel struct {
+	panicIn string
+	updates int
+}
+
+func (m *panicModel) Init() tea.Cmd {
+	if m.panicIn == "init" {
+		panic("init failed")
+	}
+	return nil
+}
+
+func (m *panicModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.panicIn == "update" {
+		var nilMap map[string]int
+		nilMap["x"]++
+	}
+	m.updates++
+	return m, nil
+}
+
+func (m *panicModel) View() string {
+	if m.panicIn == "view" {
+		panic("view failed")
+	}
+	return "ok"
+}
+
+// isQuit reports whether cmd is tea.Quit.
+func isQuit(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+func TestGuard_NoPanic(t *testing.T) {
+	m := &panicModel{}
+	g := NewGuard(m)
+	if cmd := g.Init(); cmd != nil {
+		t.Errorf("Init() = non-nil cmd")
+	}
+	if _, cmd := g.Update(tea.KeyMsg{}); cmd != nil {
+		t.Errorf("Update() = non-nil cmd")
+	}
+	if g.View() != "ok" || m.updates != 1 || g.Panic() != nil {
+		t.Errorf("View() = %q, updates = %d, Panic() = %v", g.View(), m.updates, g.Panic())
+	}
+}
+
+func TestGuard_Recovers(t *testing.T) {
+	tests := []struct {
+		panicIn   string
+		wantPanic string
+	}{
+		{"init", "init failed"},
+		{"update", "assignment to entry in nil map"},
+		{"view", "view failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.panicIn, func(t *testing.T) {
+			g := NewGuard(&panicModel{panicIn: tt.panicIn})
+			var cmd tea.Cmd
+			switch tt.panicIn {
+			case "init":
+				cmd = g.Init()
+			case "update":
+				_, cmd = g.Update(tea.KeyMsg{})
+			case "view":
+				if got := g.View(); got != crashedView {
+					t.Errorf("View() = %q, want the crashed notice", got)
+				}
+				_, cmd = g.Update(tea.KeyMsg{})
+			}
+			if !isQuit(cmd) {
+				t.Error("guard did not quit after the panic")
+			}
+
+			p := g.Panic()
+			if p == nil {
+				t.Fatal("Panic() = nil")
+			}
+			if !strings.Contains(p.String(), tt.wantPanic) {
+				t.Errorf("panic = %q, want %q", p, tt.wantPanic)
+			}
+			if !strings.Contains(string(p.Stack), "panicModel") {
+				t.Errorf("stack does not reach the panicking model:\n%s", p.Stack)
+			}
+			if g.View() != crashedView {
+				t.Error("View() after a panic should show the crashed notice")
+			}
+		})
+	}
+}
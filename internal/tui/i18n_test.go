@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/query"
+)
+
+// TestHelpLinesTranslated checks that every catalog translates the
+// help modal, whose lines are translated at render time rather than
+// through literal i18n.T calls.
+func TestHelpLinesTranslated(t *testing.T) {
+	for _, tag := range i18n.Supported()[1:] {
+		l, err := i18n.Load(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range rawHelpLines {
+			if line == "" {
+				continue
+			}
+			if _, ok := l.Messages[line]; !ok {
+				t.Errorf("%s: missing translation for help line %q", tag, line)
+			}
+		}
+	}
+}
+
+func TestView_German(t *testing.T) {
+	if err := i18n.Set("de"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = i18n.Set("en") })
+
+	model := NewBuilder().WithSize(120, 20).WithViewType(query.ViewSenders).
+		WithRows(query.AggregateRow{Key: "alice@example.com", Count: 1500, TotalSize: 1536}).Build()
+	header := stripANSI(model.headerView())
+	if !strings.Contains(header, "Alle Konten") || !strings.Contains(header, "Absender") {
+		t.Errorf("header not translated:\n%s", header)
+	}
+	table := stripANSI(model.aggregateTableView())
+	for _, want := range []string{"Anzahl", "Größe", "1,5K", "1,5 KB"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("aggregate table missing %q:\n%s", want, table)
+		}
+	}
+	if footer := stripANSI(model.footerView()); !strings.Contains(footer, "? Hilfe") {
+		t.Errorf("footer not translated: %s", footer)
+	}
+
+	list := NewBuilder().WithSize(120, 20).WithLevel(levelMessageList).
+		WithMessages(query.MessageSummary{ID: 1, Subject: "Hallo", FromEmail: "bob@example.com",
+			SentAt: time.Date(2026, 3, 1, 14, 5, 0, 0, time.UTC)}).Build()
+	if got := stripANSI(list.messageListView()); !strings.Contains(got, "01.03.2026 14:05") || !strings.Contains(got, "Betreff") {
+		t.Errorf("message list not localized:\n%s", got)
+	}
+}
@@ -168,6 +168,10 @@ func (m Model) handleAggregateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "/":
 		return m, m.activateInlineSearch("search")
 
+	// Saved searches - pick one to search for
+	case "'":
+		return m.openSavedSearches()
+
 	// Selection
 	case " ": // Space to toggle selection
 		m.toggleAggregateSelection()
@@ -209,6 +213,9 @@ func (m Model) handleAggregateKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		m.loadRequestID++
 		return m, m.loadMessages()
 
+	case "i": // Triage the messages after the watermark
+		return m.startTriage()
+
 	case "d", "D": // Stage for deletion (selection or current row)
 		if m.isRemote {
 			return m.showFlash("Deletion not available in remote mode")
@@ -388,6 +395,9 @@ func (m Model) handleMessageListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "x": // Clear selection
 		m.clearAllSelections()
 
+	case "i": // Triage the messages after the watermark
+		return m.startTriage()
+
 	case "d", "D": // Stage for deletion (selection or current row)
 		if m.isRemote {
 			return m.showFlash("Deletion not available in remote mode")
@@ -407,6 +417,10 @@ func (m Model) handleMessageListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "/":
 		return m, m.activateInlineSearch("search (Tab: deep)")
 
+	// Saved searches - pick one to search for
+	case "'":
+		return m.openSavedSearches()
+
 	// Sub-grouping: switch to aggregate breakdown within current filter
 	case "tab":
 		if m.hasDrillFilter() {
@@ -505,6 +519,14 @@ func (m Model) handleMessageListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// Sorting - use explicit field list to avoid hidden coupling
 	case "s":
+		// Deep search results toggle between date and relevance order.
+		if m.searchQuery != "" && m.searchMode == searchModeDeep {
+			m.searchRelevance = !m.searchRelevance
+			m.loading = true
+			m.err = nil
+			m.searchRequestID++
+			return m, m.loadSearch(m.searchQuery)
+		}
 		msgSortFields := []query.MessageSortField{
 			query.MessageSortByDate,
 			query.MessageSortBySize,
@@ -672,6 +694,18 @@ func (m Model) handleMessageDetailKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 	}
 
+	// Route keys to the note or tags being edited
+	if m.annotating != annotateNone {
+		return m.handleAnnotateKeys(msg)
+	}
+
+	// Triage actions on the triage message
+	if m.inTriage() {
+		if m2, cmd, handled := m.handleTriageKeys(msg); handled {
+			return m2, cmd
+		}
+	}
+
 	// Handle global keys (quit, help) but not when detail search is active
 	if !m.detailSearchActive {
 		if m2, cmd, handled := m.handleGlobalKeys(msg); handled {
@@ -783,22 +817,42 @@ func (m Model) handleMessageDetailKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	// View thread
 	case "T":
-		if m.messageDetail != nil && m.messageDetail.ConversationID > 0 {
-			m.transitionBuffer = m.renderView() // Freeze screen until data loads
+		return m.openThread()
 
-			// Save current state
-			m.pushBreadcrumb()
+	// Show or hide folded quoted text and signature
+	case "Q":
+		if m.messageDetail == nil || m.messageDetail.BodyQuoted+m.messageDetail.BodySignature == "" {
+			return m.showFlash("No quoted text")
+		}
+		m.showQuoted = !m.showQuoted
+		m.updateDetailLineCount()
+		m.clampDetailScroll()
 
-			m.threadConversationID = m.messageDetail.ConversationID
-			m.threadMessages = nil
-			m.threadCursor = 0
-			m.threadScrollOffset = 0
-			m.level = levelThreadView
-			m.loading = true
-			m.err = nil
-			m.loadRequestID++
-			return m, m.loadThreadMessages(m.messageDetail.ConversationID)
+	// Render the HTML body instead of the text body, or back
+	case "H":
+		if m.messageDetail == nil || m.messageDetail.BodyHTML == "" {
+			return m.showFlash("No HTML body")
+		}
+		m.showHTML = !m.showHTML
+		m.updateDetailLineCount()
+		m.clampDetailScroll()
+
+	// Show or hide tracking pixels in a rendered HTML body
+	case "P":
+		if m.messageDetail == nil || countTrackers(m.messageDetail.BodyHTML) == 0 {
+			return m.showFlash("No tracking pixels")
 		}
+		m.showTrackers = !m.showTrackers
+		m.updateDetailLineCount()
+		m.clampDetailScroll()
+
+	// Local annotations
+	case "p":
+		return m.togglePin()
+	case "c":
+		return m.startAnnotating(annotateNote)
+	case "#":
+		return m.startAnnotating(annotateTags)
 
 	// Export attachments
 	case "e":
@@ -817,11 +871,36 @@ func (m Model) handleMessageDetailKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		} else {
 			return m.showFlash("No attachments to export")
 		}
+
+	// Preview text attachments in turn
+	case "v":
+		return m.cycleAttachmentPreview()
 	}
 
 	return m, nil
 }
 
+// openThread shows the thread of the message in the message view.
+func (m Model) openThread() (tea.Model, tea.Cmd) {
+	if m.messageDetail == nil || m.messageDetail.ConversationID <= 0 {
+		return m, nil
+	}
+	m.transitionBuffer = m.renderView() // Freeze screen until data loads
+
+	// Save current state
+	m.pushBreadcrumb()
+
+	m.threadConversationID = m.messageDetail.ConversationID
+	m.threadMessages = nil
+	m.threadCursor = 0
+	m.threadScrollOffset = 0
+	m.level = levelThreadView
+	m.loading = true
+	m.err = nil
+	m.loadRequestID++
+	return m, m.loadThreadMessages(m.messageDetail.ConversationID)
+}
+
 // handleThreadViewKeys handles keys in the thread view.
 func (m Model) handleThreadViewKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Handle global keys (quit, help)
@@ -920,6 +999,8 @@ func (m Model) handleModalKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleErrorKeys()
 	case modalHelp:
 		return m.handleHelpKeys(msg)
+	case modalSavedSearches:
+		return m.handleSavedSearchesKeys(msg)
 	}
 	return m, nil
 }
@@ -44,6 +44,10 @@ type Options struct {
 	DataDir string
 	Version string
 
+	// AttachmentsDir is where attachment files are read from for
+	// export. Empty means <DataDir>/attachments.
+	AttachmentsDir string
+
 	// AggregateLimit overrides the maximum number of aggregate rows to load.
 	// Zero uses the default (50,000).
 	AggregateLimit int
@@ -59,6 +63,39 @@ type Options struct {
 	// TextEngine provides text message query operations.
 	// When non-nil, the 'm' key toggles between Email and Texts mode.
 	TextEngine query.TextEngine
+
+	// Annotator saves local notes, pins and tags edited in the message
+	// view. Nil disables editing them.
+	Annotator Annotator
+
+	// Triager tracks how far the archive has been triaged; the 'i' key
+	// steps through the messages after that one at a time. Nil disables
+	// triage.
+	Triager Triager
+
+	// Reminders counts the local reminders that have come due, shown
+	// in the title bar at startup. Nil shows none.
+	Reminders Reminders
+
+	// SavedSearches lists the searches the ' key picks from. Nil
+	// disables the picker.
+	SavedSearches SavedSearches
+
+	// Changes reports new mail, so list and aggregate views refresh
+	// when a sync elsewhere adds some. Nil disables refreshing.
+	Changes ChangeFeed
+
+	// RefreshInterval is how often Changes is checked. Zero uses the
+	// default (5 seconds).
+	RefreshInterval time.Duration
+}
+
+// Annotator saves the user's local annotations on messages.
+// *store.Store implements it.
+type Annotator interface {
+	SetMessageNote(messageID int64, note string) error
+	SetMessagePinned(messageID int64, pinned bool) error
+	SetMessageTags(messageID int64, tags []string) error
 }
 
 // modalType represents the type of modal dialog.
@@ -75,6 +112,7 @@ const (
 	modalQuitConfirm
 	modalHelp
 	modalError
+	modalSavedSearches
 )
 
 // searchModeKind represents the search mode (fast metadata vs deep body search).
@@ -121,6 +159,16 @@ type Model struct {
 	updateAvailable  string // Latest version if update available
 	updateIsDevBuild bool   // True if running a dev build
 
+	// reminders counts due reminders; remindersDue is how many were due
+	// at startup.
+	reminders    Reminders
+	remindersDue int64
+
+	// savedSearches backs the saved search picker, which shows
+	// savedSearchItems.
+	savedSearches    SavedSearches
+	savedSearchItems []savedSearchItem
+
 	// Configurable limits
 	aggregateLimit     int
 	threadMessageLimit int
@@ -128,6 +176,26 @@ type Model struct {
 	// Remote mode (disables deletion/export)
 	isRemote bool
 
+	// annotator saves notes, pins and tags; nil when editing them is
+	// not available.
+	annotator Annotator
+
+	// triager saves the triage watermark; nil when triage is not
+	// available. triage is the state of a triage session.
+	triager Triager
+	triage  triageState
+
+	// Live refresh: changes reports new mail; changesSeen is the newest
+	// message ID the view has been refreshed for, once changesStarted.
+	// refreshPending holds a refresh back until the user is idle, and
+	// refreshKeep is the row the cursor stays on through one.
+	changes         ChangeFeed
+	refreshInterval time.Duration
+	changesStarted  bool
+	changesSeen     int64
+	refreshPending  bool
+	refreshKeep     string
+
 	// Navigation
 	breadcrumbs []navigationSnapshot
 
@@ -144,6 +212,17 @@ type Model struct {
 		hideDeletedFromSource bool // exclude messages deleted from source
 	}
 
+	// showQuoted shows the quoted reply trail and signature folded off
+	// message bodies; it stays as set while moving between messages.
+	showQuoted bool
+
+	// showHTML renders message bodies from their HTML part even when
+	// they have a text part; showTrackers keeps the tracking pixels
+	// that rendering HTML otherwise strips. Both stay as set while
+	// moving between messages.
+	showHTML     bool
+	showTrackers bool
+
 	// Pagination config
 	pageSize int // Rows visible per page
 
@@ -178,6 +257,7 @@ type Model struct {
 
 	// Search state
 	searchMode        searchModeKind  // Fast (Parquet) or Deep (FTS5)
+	searchRelevance   bool            // Deep search ranks by BM25 relevance instead of date
 	searchInput       textinput.Model // Text input for search query
 	searchTotalCount  int64           // Total matching messages (for pagination display)
 	searchOffset      int             // Current offset for pagination
@@ -239,14 +319,25 @@ func New(engine query.Engine, opts Options) Model {
 		}
 	}
 
+	refreshInterval := opts.RefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = defaultRefreshInterval
+	}
+
 	return Model{
 		engine:             engine,
 		textEngine:         textEngine,
-		actions:            NewActionController(engine, opts.DataDir, nil),
+		actions:            NewActionController(engine, opts.DataDir, nil).WithAttachmentsDir(opts.AttachmentsDir),
 		version:            opts.Version,
 		aggregateLimit:     aggLimit,
 		threadMessageLimit: threadLimit,
 		isRemote:           opts.IsRemote,
+		annotator:          opts.Annotator,
+		triager:            opts.Triager,
+		reminders:          opts.Reminders,
+		savedSearches:      opts.SavedSearches,
+		changes:            opts.Changes,
+		refreshInterval:    refreshInterval,
 		viewState: viewState{
 			level:            levelAggregates,
 			viewType:         query.ViewSenders,
@@ -276,6 +367,8 @@ func (m Model) Init() tea.Cmd {
 		m.loadStats(),
 		m.loadAccounts(),
 		m.checkForUpdate(),
+		m.checkReminders(),
+		m.checkChanges(),
 		spinnerTick(), // Start spinner for initial load
 	)
 }
@@ -578,6 +671,10 @@ func (m Model) loadSearchWithOffset(queryStr string, offset int, appendResults b
 				// Deep search: FTS5 body search
 				// Merge context filter into query to honor drill-down context
 				mergedQuery := query.MergeFilterIntoQuery(q, m.searchFilter)
+				mergedQuery.Sort = search.SortDate
+				if m.searchRelevance {
+					mergedQuery.Sort = search.SortRelevance
+				}
 				results, err = m.engine.Search(ctx, mergedQuery, searchPageSize, offset)
 				// For deep search, estimate total from result count (no separate count query)
 				if err == nil && offset == 0 {
@@ -832,6 +929,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleAccountsLoaded(msg)
 	case updateCheckMsg:
 		return m.handleUpdateCheck(msg)
+	case remindersDueMsg:
+		m.remindersDue = msg.count
+		return m, nil
+	case savedSearchesLoadedMsg:
+		return m.handleSavedSearchesLoaded(msg)
 	case messagesLoadedMsg:
 		return m.handleMessagesLoaded(msg)
 	case messageDetailLoadedMsg:
@@ -844,10 +946,20 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleFlashClear()
 	case exportResultMsg:
 		return m.handleExportResult(msg)
+	case annotationSavedMsg:
+		return m.handleAnnotationSaved(msg)
+	case triageLoadedMsg:
+		return m.handleTriageLoaded(msg)
+	case triageSavedMsg:
+		return m.handleTriageSaved(msg)
+	case attachmentPreviewMsg:
+		return m.handleAttachmentPreview(msg)
 	case searchDebounceMsg:
 		return m.handleSearchDebounce(msg)
 	case spinnerTickMsg:
 		return m.handleSpinnerTick()
+	case changesPolledMsg:
+		return m.handleChangesPolled(msg)
 	// Text mode messages
 	case textConversationsLoadedMsg:
 		return m.handleTextConversationsLoaded(msg)
@@ -992,6 +1104,7 @@ func (m Model) handleDataLoaded(msg dataLoadedMsg) (tea.Model, tea.Cmd) {
 		m.modal = modalError
 		m.modalResult = m.err.Error()
 		m.restorePosition = false // Clear flag on error to prevent stale state
+		m.refreshKeep = ""
 		return m, nil
 	}
 
@@ -1006,6 +1119,7 @@ func (m Model) handleDataLoaded(msg dataLoadedMsg) (tea.Model, tea.Cmd) {
 		m.scrollOffset = 0
 	}
 	m.restorePosition = false // Clear flag after use
+	m.keepRefreshCursor()
 
 	// When search filter is active, use distinct message stats from the
 	// filtered stats query. This avoids inflated totals from 1:N views
@@ -1075,6 +1189,7 @@ func (m Model) handleMessagesLoaded(msg messagesLoadedMsg) (tea.Model, tea.Cmd)
 		m.modal = modalError
 		m.modalResult = m.err.Error()
 		m.restorePosition = false // Clear flag on error to prevent stale state
+		m.refreshKeep = ""
 	} else {
 		m.err = nil
 		if m.modal == modalError {
@@ -1095,6 +1210,7 @@ func (m Model) handleMessagesLoaded(msg messagesLoadedMsg) (tea.Model, tea.Cmd)
 				m.cursor = 0
 				m.scrollOffset = 0
 			}
+			m.keepRefreshCursor()
 		}
 		m.restorePosition = false // Clear flag after use
 		// Update pagination offset
@@ -1122,6 +1238,7 @@ func (m Model) handleMessageDetailLoaded(msg messageDetailLoadedMsg) (tea.Model,
 		}
 		m.messageDetail = msg.detail
 		m.detailScroll = 0
+		m.attachmentPreview = nil
 		m.pendingDetailSubject = "" // Clear pending subject
 		m.updateDetailLineCount()   // Calculate line count for scroll bounds
 	}
@@ -1305,6 +1422,9 @@ func (m Model) handleSpinnerTick() (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input.
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// A key pressed while a refresh loads wins; the refresh waits.
+	m.cancelRefresh()
+
 	// Route to Texts mode handler when active
 	if m.mode == modeTexts {
 		return m.handleTextKeyPress(msg)
@@ -1,12 +1,19 @@
 package tui
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/query/querytest"
 )
 
 // =============================================================================
@@ -523,3 +530,380 @@ func TestDetailNavigationFromThreadView(t *testing.T) {
 		t.Errorf("expected threadScrollOffset=0 to keep cursor visible, got %d", m.threadScrollOffset)
 	}
 }
+
+func TestDetailToggleQuoted(t *testing.T) {
+	model := NewBuilder().
+		WithDetail(&query.MessageDetail{
+			ID:            1,
+			Subject:       "Lunch",
+			BodyText:      "Sounds good.\n-- \nAlice\nOn Tue, Bob wrote:\n> Lunch?\n",
+			BodySignature: "-- \nAlice\n",
+			BodyQuoted:    "On Tue, Bob wrote:\n> Lunch?\n",
+		}).
+		WithLevel(levelMessageDetail).
+		WithSize(100, 30).
+		Build()
+
+	body := strings.Join(model.buildDetailLines(), "\n")
+	if strings.Contains(body, "Bob wrote") || !strings.Contains(body, "press Q to show") {
+		t.Errorf("folded body shows quoted text:\n%s", body)
+	}
+
+	m, _ := sendKey(t, model, key('Q'))
+	if !m.showQuoted {
+		t.Fatal("Q did not show quoted text")
+	}
+	body = strings.Join(m.buildDetailLines(), "\n")
+	if !strings.Contains(body, "> Lunch?") || strings.Contains(body, "press Q to show") {
+		t.Errorf("expanded body:\n%s", body)
+	}
+
+	// A message without folded parts has nothing to toggle.
+	m.messageDetail = &query.MessageDetail{ID: 2, BodyText: "Hi"}
+	m, _ = sendKey(t, m, key('Q'))
+	if !m.showQuoted || m.flashMessage == "" {
+		t.Errorf("Q on unfolded message: showQuoted=%v flash=%q", m.showQuoted, m.flashMessage)
+	}
+}
+
+func TestDetailHTMLAndTrackers(t *testing.T) {
+	model := NewBuilder().
+		WithDetail(&query.MessageDetail{
+			ID:       1,
+			Subject:  "Sale",
+			BodyText: "Plain sale text",
+			BodyHTML: `<p>Rich sale</p><img src="https://t.example.net/o.gif" width="1" height="1">`,
+		}).
+		WithLevel(levelMessageDetail).
+		WithSize(100, 30).
+		Build()
+
+	body := strings.Join(model.buildDetailLines(), "\n")
+	if !strings.Contains(body, "Plain sale text") || strings.Contains(body, "tracking pixels") {
+		t.Errorf("text body:\n%s", body)
+	}
+
+	m, _ := sendKey(t, model, key('H'))
+	body = strings.Join(m.buildDetailLines(), "\n")
+	if !strings.Contains(body, "Rich sale") || strings.Contains(body, "[tracker:") ||
+		!strings.Contains(body, "1 tracking pixels removed: press P to show") {
+		t.Errorf("HTML body with trackers stripped:\n%s", body)
+	}
+
+	m, _ = sendKey(t, m, key('P'))
+	body = strings.Join(m.buildDetailLines(), "\n")
+	if !strings.Contains(body, "[tracker: t.example.net]") || strings.Contains(body, "press P to show") {
+		t.Errorf("HTML body with trackers shown:\n%s", body)
+	}
+
+	// A message without HTML has nothing to toggle.
+	m.messageDetail = &query.MessageDetail{ID: 2, BodyText: "Hi"}
+	m, _ = sendKey(t, m, key('P'))
+	if !m.showTrackers || m.flashMessage == "" {
+		t.Errorf("P without trackers: showTrackers=%v flash=%q", m.showTrackers, m.flashMessage)
+	}
+}
+
+// fakeAnnotator records the annotations the TUI saves.
+type fakeAnnotator struct {
+	notes  map[int64]string
+	pinned map[int64]bool
+	tags   map[int64][]string
+}
+
+func newFakeAnnotator() *fakeAnnotator {
+	return &fakeAnnotator{notes: map[int64]string{}, pinned: map[int64]bool{}, tags: map[int64][]string{}}
+}
+
+func (f *fakeAnnotator) SetMessageNote(id int64, note string) error {
+	f.notes[id] = note
+	return nil
+}
+
+func (f *fakeAnnotator) SetMessagePinned(id int64, pinned bool) error {
+	f.pinned[id] = pinned
+	return nil
+}
+
+func (f *fakeAnnotator) SetMessageTags(id int64, tags []string) error {
+	f.tags[id] = tags
+	return nil
+}
+
+func TestDetailAnnotations(t *testing.T) {
+	model := NewBuilder().
+		WithDetail(&query.MessageDetail{ID: 7, Subject: "Lease", BodyText: "Signed copy attached."}).
+		WithLevel(levelMessageDetail).
+		WithSize(100, 30).
+		Build()
+
+	// Without an annotator nothing can be edited.
+	m, cmd := sendKey(t, model, key('p'))
+	if cmd == nil || m.flashMessage == "" || m.annotating != annotateNone {
+		t.Fatalf("p without annotator: flash=%q", m.flashMessage)
+	}
+
+	fake := newFakeAnnotator()
+	model.annotator = fake
+
+	// Pin.
+	m, cmd = sendKey(t, model, key('p'))
+	if cmd == nil {
+		t.Fatal("p returned no command")
+	}
+	m, _ = sendMsg(t, m, cmd())
+	if !fake.pinned[7] || !m.messageDetail.Pinned || m.flashMessage != "Pinned" {
+		t.Errorf("after p: saved=%v shown=%v flash=%q", fake.pinned[7], m.messageDetail.Pinned, m.flashMessage)
+	}
+
+	// Note: typed into the input line, saved on Enter.
+	m, _ = sendKey(t, m, key('c'))
+	if m.annotating != annotateNote {
+		t.Fatal("c did not open the note input")
+	}
+	for _, r := range "renew by May" {
+		m, _ = sendKey(t, m, key(r))
+	}
+	if m.annotating != annotateNote {
+		t.Fatal("typing into the note closed the input")
+	}
+	m, cmd = sendKey(t, m, keyEnter())
+	m, _ = sendMsg(t, m, cmd())
+	if fake.notes[7] != "renew by May" || m.messageDetail.Note != "renew by May" {
+		t.Errorf("note saved %q, shown %q", fake.notes[7], m.messageDetail.Note)
+	}
+
+	// Tags, then Esc discards an edit.
+	m, _ = sendKey(t, m, key('#'))
+	m.annotateInput.SetValue("Home, lease home")
+	m, cmd = sendKey(t, m, keyEnter())
+	m, _ = sendMsg(t, m, cmd())
+	if got := strings.Join(fake.tags[7], ","); got != "home,lease" {
+		t.Errorf("tags saved = %q, want home,lease", got)
+	}
+	m, _ = sendKey(t, m, key('#'))
+	m.annotateInput.SetValue("other")
+	m, _ = sendKey(t, m, keyEsc())
+	if m.annotating != annotateNone || len(m.messageDetail.Tags) != 2 {
+		t.Errorf("esc: annotating=%v tags=%v", m.annotating, m.messageDetail.Tags)
+	}
+
+	body := strings.Join(m.buildDetailLines(), "\n")
+	for _, want := range []string{"📌 Pinned", "Tags: home, lease", "Note: renew by May"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("detail view missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestDetailAnnotationsRemote(t *testing.T) {
+	model := NewBuilder().
+		WithDetail(&query.MessageDetail{ID: 7, Subject: "Lease"}).
+		WithLevel(levelMessageDetail).
+		WithSize(100, 30).
+		Build()
+	model.isRemote = true
+	model.annotator = newFakeAnnotator()
+
+	m, _ := sendKey(t, model, key('c'))
+	if m.annotating != annotateNone || !strings.Contains(m.flashMessage, "remote") {
+		t.Errorf("c in remote mode: annotating=%v flash=%q", m.annotating, m.flashMessage)
+	}
+}
+
+// fakeTriager serves untriaged messages from a fixed list of IDs.
+type fakeTriager struct {
+	ids       []int64
+	watermark int64
+}
+
+func (f *fakeTriager) TriageWatermark() (int64, error) { return f.watermark, nil }
+
+func (f *fakeTriager) SetTriageWatermark(id int64) error {
+	f.watermark = max(f.watermark, id)
+	return nil
+}
+
+func (f *fakeTriager) UntriagedMessageIDs(after int64, limit int) ([]int64, error) {
+	var out []int64
+	for _, id := range f.ids {
+		if id > after && len(out) < limit {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTriager) CountUntriagedMessages(after int64) (int64, error) {
+	ids, _ := f.UntriagedMessageIDs(after, len(f.ids))
+	return int64(len(ids)), nil
+}
+
+// runCmds runs cmd and the commands its messages return, feeding each
+// message but spinner ticks back into the model.
+func runCmds(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	queue := []tea.Cmd{cmd}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if c == nil {
+			continue
+		}
+		switch msg := c().(type) {
+		case tea.BatchMsg:
+			queue = append(queue, msg...)
+		case spinnerTickMsg:
+		default:
+			var next tea.Cmd
+			m, next = sendMsg(t, m, msg)
+			queue = append(queue, next)
+		}
+	}
+	return m
+}
+
+func TestTriage(t *testing.T) {
+	model := NewBuilder().
+		WithMessages(query.MessageSummary{ID: 1, Subject: "Listed"}).
+		WithLevel(levelMessageList).
+		WithSize(100, 30).
+		Build()
+
+	// Without a triager, triage is not available.
+	m, _ := sendKey(t, model, key('i'))
+	if m.triage.active || m.flashMessage != "Triage not available" {
+		t.Fatalf("i without triager: active=%v flash=%q", m.triage.active, m.flashMessage)
+	}
+
+	fake := &fakeTriager{ids: []int64{3, 5, 8}, watermark: 3}
+	model.triager = fake
+	model.engine.(*querytest.MockEngine).GetMessageFunc = func(_ context.Context, id int64) (*query.MessageDetail, error) {
+		return &query.MessageDetail{ID: id, SourceMessageID: fmt.Sprintf("g%d", id), Subject: fmt.Sprintf("Message %d", id)}, nil
+	}
+
+	// Triage starts after the watermark.
+	m, cmd := sendKey(t, model, key('i'))
+	m = runCmds(t, m, cmd)
+	if !m.inTriage() || m.messageDetail == nil || m.messageDetail.ID != 5 {
+		t.Fatalf("after i: inTriage=%v detail=%+v", m.inTriage(), m.messageDetail)
+	}
+	if footer := stripANSI(m.footerView()); !strings.Contains(footer, "Space keep") || !strings.Contains(footer, "2 left") {
+		t.Errorf("triage footer = %q", footer)
+	}
+
+	// Left and right do not leave the triage queue.
+	m, _ = sendKey(t, m, key('l'))
+	if m.messageDetail.ID != 5 || !strings.HasPrefix(m.flashMessage, "Triage:") {
+		t.Errorf("l in triage: detail=%d flash=%q", m.messageDetail.ID, m.flashMessage)
+	}
+
+	// Keep moves on and saves the watermark.
+	m, cmd = sendKey(t, m, key(' '))
+	m = runCmds(t, m, cmd)
+	if fake.watermark != 5 || m.messageDetail.ID != 8 || m.triage.remaining != 1 {
+		t.Fatalf("after space: watermark=%d detail=%d remaining=%d", fake.watermark, m.messageDetail.ID, m.triage.remaining)
+	}
+
+	// Marking the last message for deletion ends triage and stages it.
+	m, cmd = sendKey(t, m, key('d'))
+	m = runCmds(t, m, cmd)
+	if m.triage.active || m.level != levelMessageList || fake.watermark != 8 {
+		t.Fatalf("after d: active=%v level=%v watermark=%d", m.triage.active, m.level, fake.watermark)
+	}
+	assertModal(t, m, modalDeleteConfirm)
+	if m.pendingManifest == nil || len(m.pendingManifest.GmailIDs) != 1 || m.pendingManifest.GmailIDs[0] != "g8" {
+		t.Errorf("pending manifest = %+v, want g8", m.pendingManifest)
+	}
+
+	// Nothing is left for the next session.
+	m.modal = modalNone
+	m, cmd = sendKey(t, m, key('i'))
+	m, _ = sendMsg(t, m, m.loadTriageBatch(-1, true)())
+	if m.triage.active || m.flashMessage != "Nothing left to triage" || cmd == nil {
+		t.Errorf("i after triage: active=%v flash=%q", m.triage.active, m.flashMessage)
+	}
+}
+
+func TestAttachmentPreview(t *testing.T) {
+	dir := t.TempDir()
+	attDir := filepath.Join(dir, "attachments")
+	stored := func(filename, contentType, content string) query.AttachmentInfo {
+		t.Helper()
+		sum := sha256.Sum256([]byte(content))
+		if _, _, err := export.StoreAttachmentFile(attDir, &mime.Attachment{
+			Filename: filename, ContentType: contentType, Content: []byte(content),
+		}); err != nil {
+			t.Fatalf("StoreAttachmentFile: %v", err)
+		}
+		return query.AttachmentInfo{
+			Filename: filename, MimeType: contentType,
+			Size: int64(len(content)), ContentHash: hex.EncodeToString(sum[:]),
+		}
+	}
+	detail := &query.MessageDetail{
+		ID:      1,
+		Subject: "Numbers",
+		Attachments: []query.AttachmentInfo{
+			stored("photo.jpg", "image/jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF"),
+			stored("q3.csv", "text/csv", "name,total\nalice,12\n\"bob, jr\",7\n"),
+			stored("config.json", "application/octet-stream", `{"owner":"alice@example.com","ids":[1,2]}`),
+		},
+	}
+	b := NewBuilder().WithDetail(detail).WithLevel(levelMessageDetail).WithSize(100, 40)
+	b.dataDir = dir
+	model := b.Build()
+
+	if footer := stripANSI(model.footerView()); !strings.Contains(footer, "v preview") {
+		t.Errorf("footer = %q, want the preview key", footer)
+	}
+
+	// The first text-like attachment is shown as a table.
+	m, cmd := sendKey(t, model, key('v'))
+	m = runCmds(t, m, cmd)
+	lines := stripANSI(strings.Join(m.buildDetailLines(), "\n"))
+	for _, want := range []string{"Preview of q3.csv:", "name    │ total", "bob, jr │ 7"} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("CSV preview missing %q:\n%s", want, lines)
+		}
+	}
+
+	// v moves on to the JSON attachment, pretty-printed.
+	m, cmd = sendKey(t, m, key('v'))
+	m = runCmds(t, m, cmd)
+	lines = stripANSI(strings.Join(m.buildDetailLines(), "\n"))
+	if !strings.Contains(lines, "Preview of config.json:") || !strings.Contains(lines, `  "owner": "alice@example.com",`) {
+		t.Errorf("JSON preview not pretty-printed:\n%s", lines)
+	}
+
+	// After the last one, v closes the preview.
+	m, _ = sendKey(t, m, key('v'))
+	if m.attachmentPreview != nil || strings.Contains(strings.Join(m.buildDetailLines(), "\n"), "Preview of") {
+		t.Errorf("preview still shown after cycling past the last attachment")
+	}
+
+	// A message without text attachments has nothing to preview.
+	m.messageDetail = &query.MessageDetail{ID: 2, Attachments: detail.Attachments[:1]}
+	m, _ = sendKey(t, m, key('v'))
+	if m.flashMessage != "No attachments to preview" {
+		t.Errorf("flash = %q", m.flashMessage)
+	}
+}
+
+func TestRenderAttachmentPreview(t *testing.T) {
+	if _, _, err := renderAttachmentPreview(previewText, []byte("MZ\x00\x00binary"), false); err == nil {
+		t.Error("binary content previewed, want an error")
+	}
+
+	// Truncated JSON is shown as is rather than reformatted.
+	lines, wrap, err := renderAttachmentPreview(previewJSON, []byte(`{"a":[1,`), true)
+	if err != nil || !wrap || len(lines) != 1 || lines[0] != `{"a":[1,` {
+		t.Errorf("truncated JSON = %q, %v, %v", lines, wrap, err)
+	}
+
+	// A TSV table is split on tabs.
+	lines, wrap, err = renderAttachmentPreview(previewCSV, []byte("id\tname\n1\talice\n2\tbob\n"), false)
+	if err != nil || wrap || len(lines) != 4 || lines[0] != "id │ name" || lines[2] != "1  │ alice" {
+		t.Errorf("TSV = %q, %v, %v", lines, wrap, err)
+	}
+}
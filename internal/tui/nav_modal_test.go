@@ -1,10 +1,13 @@
 package tui
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/store"
 )
 
 // =============================================================================
@@ -272,3 +275,58 @@ func TestFilterToggleInDrillDown(t *testing.T) {
 		t.Errorf("expected drillFilter.Sender preserved, got %q", m.drillFilter.Sender)
 	}
 }
+
+// =============================================================================
+// Saved Search Picker Tests
+// =============================================================================
+
+type fakeSavedSearches []store.SavedSearch
+
+func (f fakeSavedSearches) ListSavedSearches() ([]store.SavedSearch, error) { return f, nil }
+
+func (f fakeSavedSearches) ExpandSavedSearches(queryStr string) (string, error) {
+	if queryStr == "saved:gone" {
+		return "", errors.New(`no search saved as "gone"`)
+	}
+	return queryStr, nil
+}
+
+func TestSavedSearchPicker(t *testing.T) {
+	model := NewBuilder().WithMessages(makeMessages(3)...).WithLevel(levelMessageList).Build()
+
+	// Without saved searches the key only flashes.
+	m, _ := applyMessageListKeyWithCmd(t, model, key('\''))
+	assertModal(t, m, modalNone)
+	if m.flashMessage == "" {
+		t.Error("expected a flash without saved searches")
+	}
+
+	model.savedSearches = fakeSavedSearches{
+		{Name: "broken", Query: "saved:gone"},
+		{Name: "receipts", Query: "from:shop.example.com has:attachment"},
+	}
+	m, cmd := applyMessageListKeyWithCmd(t, model, key('\''))
+	if cmd == nil {
+		t.Fatal("expected a command loading the saved searches")
+	}
+	m, _ = sendMsg(t, m, cmd())
+	assertModal(t, m, modalSavedSearches)
+	if view := stripANSI(m.View()); !strings.Contains(view, "receipts") || !strings.Contains(view, "has:attachment") {
+		t.Errorf("picker does not list the saved searches:\n%s", view)
+	}
+
+	// A saved search that no longer expands shows why.
+	broken, _ := applyModalKey(t, m, keyEnter())
+	assertModal(t, broken, modalError)
+
+	m, _ = applyModalKey(t, m, key('j'))
+	m, cmd = applyModalKey(t, m, keyEnter())
+	assertModal(t, m, modalNone)
+	if m.searchQuery != "from:shop.example.com has:attachment" || cmd == nil {
+		t.Errorf("searchQuery = %q, cmd = %v; want the saved query searched", m.searchQuery, cmd)
+	}
+	if m.inlineSearchActive || m.preSearchMessages == nil {
+		t.Errorf("inlineSearchActive = %v, snapshot = %v; want a committed search Esc can undo",
+			m.inlineSearchActive, m.preSearchMessages != nil)
+	}
+}
@@ -60,6 +60,13 @@ type viewState struct {
 	detailSearchMatches    []int // Line indices with matches
 	detailSearchMatchIndex int   // Current match index
 
+	// Editing the note or tags of the message shown
+	annotating    annotationField
+	annotateInput textinput.Model
+
+	// Text attachment previewed below the attachment list
+	attachmentPreview *attachmentPreview
+
 	// Thread view specific
 	threadConversationID int64
 	threadMessages       []query.MessageSummary
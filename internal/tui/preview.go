@@ -0,0 +1,320 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/textutil"
+)
+
+// previewMaxBytes is how much of an attachment the preview reads.
+const previewMaxBytes = 256 << 10
+
+// previewMaxCellWidth caps the width of a CSV preview column.
+const previewMaxCellWidth = 30
+
+// previewKind is how an attachment is previewed.
+type previewKind int
+
+const (
+	previewNone previewKind = iota
+	previewText
+	previewCSV
+	previewJSON
+)
+
+// previewTextExts are extensions of text files, mostly code, that mail
+// clients often send as application/octet-stream.
+var previewTextExts = map[string]bool{
+	".txt": true, ".log": true, ".md": true, ".rst": true, ".ini": true,
+	".cfg": true, ".conf": true, ".toml": true, ".yaml": true, ".yml": true,
+	".xml": true, ".html": true, ".htm": true, ".css": true, ".sql": true,
+	".go": true, ".py": true, ".rb": true, ".rs": true, ".js": true,
+	".ts": true, ".java": true, ".kt": true, ".c": true, ".h": true,
+	".cpp": true, ".cs": true, ".php": true, ".sh": true, ".ps1": true,
+	".diff": true, ".patch": true, ".ics": true, ".vcf": true,
+}
+
+// attachmentPreviewKind returns how att is previewed; previewNone if it
+// is not a text-like type.
+func attachmentPreviewKind(att query.AttachmentInfo) previewKind {
+	ct := strings.ToLower(strings.TrimSpace(att.MimeType))
+	if i := strings.IndexByte(ct, ';'); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	ext := strings.ToLower(filepath.Ext(att.Filename))
+	switch {
+	case ct == "text/csv" || ct == "text/tab-separated-values" || ext == ".csv" || ext == ".tsv":
+		return previewCSV
+	case ct == "application/json" || strings.HasSuffix(ct, "+json") || ext == ".json":
+		return previewJSON
+	case strings.HasPrefix(ct, "text/"), strings.HasSuffix(ct, "+xml"),
+		ct == "application/xml", ct == "application/x-sh", ct == "application/sql":
+		return previewText
+	case previewTextExts[ext]:
+		return previewText
+	}
+	return previewNone
+}
+
+// attachmentPreview is an attachment shown below the attachment list
+// in the message view.
+type attachmentPreview struct {
+	index     int // index in the message's Attachments
+	messageID int64
+	lines     []string
+	wrap      bool // wrap long lines; a CSV table is cut off instead
+	truncated bool
+	loading   bool
+	err       string
+}
+
+// attachmentPreviewMsg is returned when an attachment's preview has
+// been read.
+type attachmentPreviewMsg struct {
+	messageID int64
+	index     int
+	lines     []string
+	wrap      bool
+	truncated bool
+	err       error
+}
+
+// PreviewAttachment reads up to previewMaxBytes of an attachment for a
+// preview and reports whether there was more. Compressed attachments
+// are decompressed.
+func (c *ActionController) PreviewAttachment(att query.AttachmentInfo) (data []byte, truncated bool, err error) {
+	if att.Quarantined {
+		return nil, false, errors.New("attachment is quarantined")
+	}
+	f, err := export.OpenAttachment(c.attachmentsDir(), att.ContentHash)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() { _ = f.Close() }()
+	data, err = io.ReadAll(io.LimitReader(f, previewMaxBytes+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) > previewMaxBytes {
+		return data[:previewMaxBytes], true, nil
+	}
+	return data, false, nil
+}
+
+// previewableAttachment returns the index of the first text-like
+// attachment of the message shown after index after, or -1.
+func (m Model) previewableAttachment(after int) int {
+	if m.messageDetail == nil {
+		return -1
+	}
+	for i := after + 1; i < len(m.messageDetail.Attachments); i++ {
+		if attachmentPreviewKind(m.messageDetail.Attachments[i]) != previewNone {
+			return i
+		}
+	}
+	return -1
+}
+
+// cycleAttachmentPreview previews the next text-like attachment of the
+// message shown, or closes the preview after the last one.
+func (m Model) cycleAttachmentPreview() (tea.Model, tea.Cmd) {
+	if m.isRemote {
+		return m.showFlash("Preview not available in remote mode")
+	}
+	after := -1
+	if m.attachmentPreview != nil {
+		after = m.attachmentPreview.index
+	}
+	next := m.previewableAttachment(after)
+	if next < 0 {
+		if m.attachmentPreview == nil {
+			return m.showFlash("No attachments to preview")
+		}
+		m.attachmentPreview = nil
+		m.updateDetailLineCount()
+		m.clampDetailScroll()
+		return m, nil
+	}
+
+	d := m.messageDetail
+	att := d.Attachments[next]
+	m.attachmentPreview = &attachmentPreview{index: next, messageID: d.ID, loading: true}
+	m.updateDetailLineCount()
+	actions := m.actions
+	return m, func() tea.Msg {
+		res := attachmentPreviewMsg{messageID: d.ID, index: next}
+		data, truncated, err := actions.PreviewAttachment(att)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.truncated = truncated
+		res.lines, res.wrap, res.err = renderAttachmentPreview(attachmentPreviewKind(att), data, truncated)
+		return res
+	}
+}
+
+// handleAttachmentPreview shows a preview that has been read, if its
+// attachment is still the one being previewed.
+func (m Model) handleAttachmentPreview(msg attachmentPreviewMsg) (tea.Model, tea.Cmd) {
+	p := m.attachmentPreview
+	if p == nil || p.messageID != msg.messageID || p.index != msg.index {
+		return m, nil
+	}
+	m.attachmentPreview = &attachmentPreview{
+		index:     msg.index,
+		messageID: msg.messageID,
+		lines:     msg.lines,
+		wrap:      msg.wrap,
+		truncated: msg.truncated,
+	}
+	if msg.err != nil {
+		m.attachmentPreview.err = msg.err.Error()
+	}
+	m.updateDetailLineCount()
+	m.clampDetailScroll()
+	return m, nil
+}
+
+// renderAttachmentPreview renders an attachment's content as preview
+// lines: CSV as a table, JSON pretty-printed, anything else as text.
+// truncated says data is only the start of the attachment. wrap
+// reports whether long lines should be wrapped rather than cut off.
+func renderAttachmentPreview(kind previewKind, data []byte, truncated bool) (lines []string, wrap bool, err error) {
+	if bytes.IndexByte(data[:min(len(data), 8<<10)], 0) >= 0 {
+		return nil, false, errors.New("binary content")
+	}
+	text := string(data)
+	if truncated {
+		// Drop a character cut off at the end.
+		for i := 1; i < utf8.UTFMax && len(text) > 0; i++ {
+			if r, size := utf8.DecodeLastRuneInString(text); r != utf8.RuneError || size != 1 {
+				break
+			}
+			text = text[:len(text)-1]
+		}
+	}
+	text = textutil.EnsureUTF8(text)
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+
+	switch kind {
+	case previewCSV:
+		if lines := renderCSVTable(text, truncated); lines != nil {
+			return lines, false, nil
+		}
+	case previewJSON:
+		var buf bytes.Buffer
+		if !truncated && json.Indent(&buf, []byte(text), "", "  ") == nil {
+			text = buf.String()
+		}
+	}
+	text = strings.ReplaceAll(strings.TrimRight(text, "\n"), "\t", "    ")
+	return strings.Split(text, "\n"), true, nil
+}
+
+// renderCSVTable lays CSV (or TSV) out as a table of aligned columns,
+// or returns nil if text does not parse as one.
+func renderCSVTable(text string, truncated bool) []string {
+	r := csv.NewReader(strings.NewReader(text))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	if first, _, _ := strings.Cut(text, "\n"); strings.Count(first, "\t") > strings.Count(first, ",") {
+		r.Comma = '\t'
+	}
+	var records [][]string
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if truncated && len(records) > 0 {
+				break // the row cut off at the end
+			}
+			return nil
+		}
+		records = append(records, rec)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	var widths []int
+	for _, rec := range records {
+		for i, cell := range rec {
+			w := min(runewidth.StringWidth(previewCell(cell)), previewMaxCellWidth)
+			if i >= len(widths) {
+				widths = append(widths, w)
+			} else if w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+	lines := make([]string, 0, len(records)+1)
+	for n, rec := range records {
+		cells := make([]string, len(rec))
+		for i, cell := range rec {
+			cells[i] = runewidth.FillRight(truncateRunes(previewCell(cell), previewMaxCellWidth), widths[i])
+		}
+		lines = append(lines, strings.TrimRight(strings.Join(cells, " │ "), " "))
+		if n == 0 {
+			seps := make([]string, len(widths))
+			for i, w := range widths {
+				seps[i] = strings.Repeat("─", w)
+			}
+			lines = append(lines, strings.Join(seps, "─┼─"))
+		}
+	}
+	return lines
+}
+
+// previewCell flattens a CSV cell to one line.
+func previewCell(cell string) string {
+	return strings.Join(strings.Fields(cell), " ")
+}
+
+// previewDetailLines returns the lines the attachment preview adds to
+// the message view, fitted to width.
+func (m Model) previewDetailLines(width int) []string {
+	p := m.attachmentPreview
+	d := m.messageDetail
+	if p == nil || d == nil || p.messageID != d.ID || p.index >= len(d.Attachments) {
+		return nil
+	}
+	lines := []string{"", i18n.T("Preview of %s:", d.Attachments[p.index].Filename)}
+	switch {
+	case p.loading:
+		return append(lines, i18n.T("Loading preview..."))
+	case p.err != "":
+		return append(lines, i18n.T("No preview: %s", p.err))
+	}
+	for _, l := range p.lines {
+		if p.wrap {
+			lines = append(lines, wrapText(l, width)...)
+		} else {
+			lines = append(lines, runewidth.Truncate(l, width, "…"))
+		}
+	}
+	if p.truncated {
+		lines = append(lines, i18n.T("[Preview shows the first %s]", formatBytes(previewMaxBytes)))
+	}
+	return lines
+}
+
+// attachmentPreviewKey reports whether the detail view shows the
+// preview key in its footer.
+func (m Model) attachmentPreviewKey() bool {
+	return !m.isRemote && m.previewableAttachment(-1) >= 0
+}
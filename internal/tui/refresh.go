@@ -0,0 +1,159 @@
+package tui
+
+import (
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultRefreshInterval is how often the TUI checks its ChangeFeed
+// for new mail.
+const defaultRefreshInterval = 5 * time.Second
+
+// ChangeFeed reports mail added to the archive, such as by a sync in
+// 'msgvault serve'. *store.Store implements it.
+type ChangeFeed interface {
+	// MaxMessageID returns the newest message's ID, which grows
+	// whenever messages are added.
+	MaxMessageID() (int64, error)
+}
+
+// changesPolledMsg carries the ChangeFeed's newest message ID.
+type changesPolledMsg struct {
+	high int64
+	err  error
+}
+
+// checkChanges reads the ChangeFeed at once; nil without one.
+func (m Model) checkChanges() tea.Cmd {
+	if m.changes == nil {
+		return nil
+	}
+	feed := m.changes
+	return func() tea.Msg {
+		high, err := feed.MaxMessageID()
+		return changesPolledMsg{high: high, err: err}
+	}
+}
+
+// pollChanges reads the ChangeFeed after the refresh interval.
+func (m Model) pollChanges() tea.Cmd {
+	if m.changes == nil {
+		return nil
+	}
+	feed := m.changes
+	return tea.Tick(m.refreshInterval, func(time.Time) tea.Msg {
+		high, err := feed.MaxMessageID()
+		return changesPolledMsg{high: high, err: err}
+	})
+}
+
+// handleChangesPolled refreshes the view when new mail has arrived, as
+// soon as the user is not in the middle of something, and keeps
+// polling. The first poll only records where the archive stands.
+func (m Model) handleChangesPolled(msg changesPolledMsg) (tea.Model, tea.Cmd) {
+	next := m.pollChanges()
+	if msg.err != nil {
+		return m, next
+	}
+	if !m.changesStarted {
+		m.changesStarted = true
+		m.changesSeen = msg.high
+		return m, next
+	}
+	if msg.high > m.changesSeen {
+		m.changesSeen = msg.high
+		m.refreshPending = true
+	}
+	if !m.refreshPending || !m.canRefresh() {
+		return m, next
+	}
+	m.refreshPending = false
+	model, cmd := m.refreshView()
+	return model, tea.Batch(next, cmd)
+}
+
+// canRefresh reports whether the view can reload without disturbing
+// the user: an email list or aggregate view, not loading, searching or
+// showing a modal. Message and thread views keep what they show.
+func (m Model) canRefresh() bool {
+	if m.mode != modeEmail || m.modal != modalNone || m.loading || m.inlineSearchActive ||
+		m.msgListLoadingMore || m.searchLoadingMore {
+		return false
+	}
+	switch m.level {
+	case levelAggregates, levelDrillDown:
+		return true
+	case levelMessageList:
+		// Search results page through the search, not the list.
+		return m.searchQuery == ""
+	}
+	return false
+}
+
+// refreshView reloads the current view and the totals in place,
+// keeping the cursor on the row it was on.
+func (m Model) refreshView() (tea.Model, tea.Cmd) {
+	m.restorePosition = true
+	m.refreshKeep = m.cursorKey()
+	model, cmd := m.reloadCurrentView()
+	m = model.(Model)
+	return m, tea.Batch(cmd, m.loadStats(), m.loadAccounts())
+}
+
+// cursorKey identifies the row under the cursor: an aggregate key, or
+// a message ID in a message list.
+func (m Model) cursorKey() string {
+	if m.level == levelMessageList {
+		if m.cursor >= 0 && m.cursor < len(m.messages) {
+			return strconv.FormatInt(m.messages[m.cursor].ID, 10)
+		}
+		return ""
+	}
+	if m.cursor >= 0 && m.cursor < len(m.rows) {
+		return m.rows[m.cursor].Key
+	}
+	return ""
+}
+
+// keepRefreshCursor moves the cursor after a refresh back onto the row
+// it was on, which new mail may have moved, or into range if the row is
+// gone.
+func (m *Model) keepRefreshCursor() {
+	keep := m.refreshKeep
+	m.refreshKeep = ""
+	if keep == "" {
+		return
+	}
+	n := len(m.rows)
+	if m.level == levelMessageList {
+		n = len(m.messages)
+	}
+	cursor := m.cursor
+	for i := range n {
+		m.cursor = i
+		if m.cursorKey() == keep {
+			m.ensureCursorVisible()
+			return
+		}
+	}
+	m.cursor = max(min(cursor, n-1), 0)
+	m.ensureCursorVisible()
+}
+
+// cancelRefresh drops a refresh still loading, so it cannot move the
+// cursor under the user, and retries it at the next poll.
+func (m *Model) cancelRefresh() {
+	if m.refreshKeep == "" {
+		return
+	}
+	m.refreshKeep = ""
+	m.restorePosition = false
+	m.refreshPending = true
+	if m.level == levelMessageList {
+		m.loadRequestID++
+	} else {
+		m.aggregateRequestID++
+	}
+}
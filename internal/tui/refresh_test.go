@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/wesm/msgvault/internal/query"
+)
+
+type fakeChangeFeed struct{ high int64 }
+
+func (f *fakeChangeFeed) MaxMessageID() (int64, error) { return f.high, nil }
+
+// newRefreshModel returns an aggregate view of alice and bob, with the
+// cursor on bob and the change feed already seen at 10.
+func newRefreshModel(t *testing.T) Model {
+	t.Helper()
+	m := NewBuilder().WithRows(makeRow("alice@example.com", 5), makeRow("bob@example.com", 3)).Build()
+	m.changes = &fakeChangeFeed{}
+	m.cursor = 1
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 10})
+	if !m.changesStarted || m.changesSeen != 10 || m.refreshKeep != "" {
+		t.Fatalf("first poll: started=%v seen=%d keep=%q, want a baseline of 10 and no refresh",
+			m.changesStarted, m.changesSeen, m.refreshKeep)
+	}
+	return m
+}
+
+func TestRefresh_NewMailReloadsInPlace(t *testing.T) {
+	m := newRefreshModel(t)
+	requestID := m.aggregateRequestID
+
+	m, cmd := sendMsg(t, m, changesPolledMsg{high: 10})
+	if m.aggregateRequestID != requestID || cmd == nil {
+		t.Fatalf("unchanged feed reloaded (request %d, was %d) or stopped polling", m.aggregateRequestID, requestID)
+	}
+
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 12})
+	if m.aggregateRequestID == requestID || m.refreshKeep != "bob@example.com" {
+		t.Fatalf("new mail: request %d (was %d), keep %q; want a reload keeping bob",
+			m.aggregateRequestID, requestID, m.refreshKeep)
+	}
+
+	// A new sender sorts ahead of bob; the cursor follows bob.
+	m, _ = sendMsg(t, m, dataLoadedMsg{requestID: m.aggregateRequestID, rows: []query.AggregateRow{
+		makeRow("carol@example.com", 9), makeRow("alice@example.com", 5), makeRow("bob@example.com", 3),
+	}})
+	if m.cursor != 2 || m.refreshKeep != "" {
+		t.Errorf("cursor = %d, keep = %q; want 2 on bob", m.cursor, m.refreshKeep)
+	}
+}
+
+func TestRefresh_WaitsForIdle(t *testing.T) {
+	m := newRefreshModel(t)
+	m.modal = modalHelp
+	requestID := m.aggregateRequestID
+
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 12})
+	if m.aggregateRequestID != requestID || !m.refreshPending {
+		t.Fatalf("refreshed under a modal, or forgot the new mail")
+	}
+
+	m.modal = modalNone
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 12})
+	if m.aggregateRequestID == requestID || m.refreshPending {
+		t.Errorf("pending refresh did not run once idle")
+	}
+}
+
+func TestRefresh_KeyPressCancelsLoadingRefresh(t *testing.T) {
+	m := newRefreshModel(t)
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 12})
+	refreshID := m.aggregateRequestID
+
+	m, _ = sendKey(t, m, key('k'))
+	if m.cursor != 0 || m.refreshKeep != "" || !m.refreshPending {
+		t.Fatalf("cursor = %d, keep = %q, pending = %v; want the key to win and the refresh to wait",
+			m.cursor, m.refreshKeep, m.refreshPending)
+	}
+	m, _ = sendMsg(t, m, dataLoadedMsg{requestID: refreshID, rows: []query.AggregateRow{makeRow("carol@example.com", 9)}})
+	if len(m.rows) != 2 {
+		t.Errorf("cancelled refresh replaced the rows: %v", m.rows)
+	}
+}
+
+func TestRefresh_MessageListKeepsMessage(t *testing.T) {
+	m := NewBuilder().WithLevel(levelMessageList).WithMessages(makeMessages(3)...).Build()
+	m.changes = &fakeChangeFeed{}
+	m.cursor = 2
+	kept := m.messages[2].ID
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 1})
+	m, _ = sendMsg(t, m, changesPolledMsg{high: 2})
+
+	msgs := append([]query.MessageSummary{{ID: 100, Subject: "new"}}, m.messages...)
+	m, _ = sendMsg(t, m, messagesLoadedMsg{requestID: m.loadRequestID, messages: msgs})
+	if m.cursor != 3 || m.messages[m.cursor].ID != kept {
+		t.Errorf("cursor = %d, want 3 on message %d", m.cursor, kept)
+	}
+
+	// Search results are not refreshed from the plain list.
+	m.searchQuery = "from:alice"
+	if m.canRefresh() {
+		t.Error("canRefresh() = true during a search")
+	}
+}
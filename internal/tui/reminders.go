@@ -0,0 +1,34 @@
+package tui
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Reminders counts the local reminders on messages that have come due.
+// *store.Store implements it.
+type Reminders interface {
+	CountDueReminders(now time.Time) (int64, error)
+}
+
+// remindersDueMsg is returned when the due reminders have been counted.
+type remindersDueMsg struct {
+	count int64
+}
+
+// checkReminders counts the reminders due at startup. A failure shows
+// none rather than an error.
+func (m Model) checkReminders() tea.Cmd {
+	reminders := m.reminders
+	if reminders == nil || m.isRemote {
+		return nil
+	}
+	return func() tea.Msg {
+		n, err := reminders.CountDueReminders(time.Now())
+		if err != nil {
+			return remindersDueMsg{}
+		}
+		return remindersDueMsg{count: n}
+	}
+}
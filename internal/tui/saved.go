@@ -0,0 +1,113 @@
+package tui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// SavedSearches lists the searches saved with 'msgvault search save'
+// and expands the saved:name terms of a query. *store.Store implements
+// it.
+type SavedSearches interface {
+	ListSavedSearches() ([]store.SavedSearch, error)
+	ExpandSavedSearches(queryStr string) (string, error)
+}
+
+// savedSearchItem is a saved search in the picker. query is expanded,
+// ready to search with; err is why it could not be expanded.
+type savedSearchItem struct {
+	name  string
+	query string
+	err   error
+}
+
+// savedSearchesLoadedMsg is returned when the saved searches have been
+// loaded for the picker.
+type savedSearchesLoadedMsg struct {
+	items []savedSearchItem
+	err   error
+}
+
+// openSavedSearches loads the saved searches; the picker opens when
+// they arrive.
+func (m Model) openSavedSearches() (tea.Model, tea.Cmd) {
+	switch {
+	case m.isRemote:
+		return m.showFlash("Saved searches not available in remote mode")
+	case m.savedSearches == nil:
+		return m.showFlash("Saved searches not available")
+	}
+	saved := m.savedSearches
+	return m, func() tea.Msg {
+		searches, err := saved.ListSavedSearches()
+		if err != nil {
+			return savedSearchesLoadedMsg{err: err}
+		}
+		items := make([]savedSearchItem, len(searches))
+		for i, ss := range searches {
+			items[i].name = ss.Name
+			items[i].query, items[i].err = saved.ExpandSavedSearches(ss.Query)
+		}
+		return savedSearchesLoadedMsg{items: items}
+	}
+}
+
+// handleSavedSearchesLoaded opens the picker on the saved searches.
+func (m Model) handleSavedSearchesLoaded(msg savedSearchesLoadedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.modal = modalError
+		m.modalResult = msg.err.Error()
+		return m, nil
+	}
+	if len(msg.items) == 0 {
+		return m.showFlash("No saved searches (save one with 'msgvault search save')")
+	}
+	m.savedSearchItems = msg.items
+	m.modal = modalSavedSearches
+	m.modalCursor = 0
+	return m, nil
+}
+
+func (m Model) handleSavedSearchesKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "up", "k":
+		if m.modalCursor > 0 {
+			m.modalCursor--
+		}
+	case "down", "j":
+		if m.modalCursor < len(m.savedSearchItems)-1 {
+			m.modalCursor++
+		}
+	case "enter":
+		m.modal = modalNone
+		if m.modalCursor >= len(m.savedSearchItems) {
+			return m, nil
+		}
+		item := m.savedSearchItems[m.modalCursor]
+		if item.err != nil {
+			m.modal = modalError
+			m.modalResult = item.err.Error()
+			return m, nil
+		}
+		return m.applySavedSearch(item.query)
+	case "esc":
+		m.modal = modalNone
+	}
+	return m, nil
+}
+
+// applySavedSearch searches for queryStr as if it had been typed into
+// the search bar, so Esc clears it and its results can be selected and
+// staged for deletion like any others.
+func (m Model) applySavedSearch(queryStr string) (tea.Model, tea.Cmd) {
+	m.activateInlineSearch("search")
+	m.searchInput.SetValue(queryStr)
+	if m.level == levelMessageList {
+		return m.commitInlineSearch()
+	}
+	m.exitInlineSearchMode()
+	m.searchQuery = queryStr
+	m.loading = true
+	m.aggregateRequestID++
+	return m, tea.Batch(m.startSpinner(), m.loadData())
+}
@@ -1,10 +1,14 @@
 package tui
 
 import (
+	"context"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/wesm/msgvault/internal/query"
+	"github.com/wesm/msgvault/internal/query/querytest"
+	"github.com/wesm/msgvault/internal/search"
 )
 
 func TestSearchModalOpen(t *testing.T) {
@@ -1588,3 +1592,36 @@ func TestSearchClearsStaleMessages(t *testing.T) {
 
 // TestHighlightedColumnsAligned verifies that highlighting search terms in
 // aggregate rows doesn't break column alignment.
+
+// TestDeepSearchSortToggle verifies that s on deep search results
+// toggles between date and relevance order and re-runs the search.
+func TestDeepSearchSortToggle(t *testing.T) {
+	model := NewBuilder().WithMessages(makeMessages(3)...).WithLevel(levelMessageList).Build()
+	var sorts []search.SortField
+	model.engine = &querytest.MockEngine{
+		SearchFunc: func(_ context.Context, q *search.Query, _, _ int) ([]query.MessageSummary, error) {
+			sorts = append(sorts, q.Sort)
+			return nil, nil
+		},
+	}
+	model.searchQuery = "budget"
+	model.searchMode = searchModeDeep
+
+	m, cmd := applyMessageListKeyWithCmd(t, model, key('s'))
+	if !m.searchRelevance || cmd == nil {
+		t.Fatalf("s: searchRelevance = %v, cmd = %v; want relevance and a search", m.searchRelevance, cmd)
+	}
+	_ = cmd()
+	if !strings.Contains(stripANSI(m.messageListView()), "[Deep] [by relevance]") {
+		t.Errorf("info line does not show the relevance order:\n%s", stripANSI(m.messageListView()))
+	}
+
+	m, cmd = applyMessageListKeyWithCmd(t, m, key('s'))
+	_ = cmd()
+	if m.searchRelevance || m.msgSortField != query.MessageSortByDate {
+		t.Errorf("second s: searchRelevance = %v, msgSortField = %v", m.searchRelevance, m.msgSortField)
+	}
+	if want := []search.SortField{search.SortRelevance, search.SortDate}; !slices.Equal(sorts, want) {
+		t.Errorf("searches sorted by %v, want %v", sorts, want)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/wesm/msgvault/internal/i18n"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/textutil"
 )
@@ -576,7 +577,7 @@ func (m Model) textTimelineView() string {
 		if from == "" {
 			from = "Unknown"
 		}
-		timeStr := msg.SentAt.Format("2006-01-02 15:04")
+		timeStr := i18n.DateTime(msg.SentAt)
 		// Right-justify timestamp: sender on left, time on right
 		gap := bodyWidth - len(from) - len(timeStr)
 		if gap < 2 {
@@ -0,0 +1,248 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/wesm/msgvault/internal/query"
+)
+
+// triageBatchSize is how many untriaged message IDs are loaded at once.
+const triageBatchSize = 200
+
+// Triager tracks how far the user has triaged the archive: every
+// message up to the watermark has been looked at. *store.Store
+// implements it.
+type Triager interface {
+	TriageWatermark() (int64, error)
+	SetTriageWatermark(messageID int64) error
+	UntriagedMessageIDs(after int64, limit int) ([]int64, error)
+	CountUntriagedMessages(after int64) (int64, error)
+}
+
+// triageState is the state of the triage mode, which steps through the
+// messages after the watermark one at a time in the message view.
+type triageState struct {
+	active    bool
+	depth     int     // breadcrumb depth of the triage message view
+	queue     []int64 // untriaged message IDs loaded, oldest first
+	index     int     // position in queue of the message shown
+	remaining int64   // untriaged messages left, the one shown included
+	triaged   int     // messages triaged this session
+
+	// deletions are the messages marked for deletion, staged together
+	// when triage ends.
+	deletions []query.MessageSummary
+}
+
+// triageLoadedMsg is returned when a batch of untriaged messages has
+// been loaded. start is set for the first batch of a session.
+type triageLoadedMsg struct {
+	ids       []int64
+	remaining int64
+	start     bool
+	err       error
+}
+
+// triageSavedMsg is returned when the watermark has been saved.
+type triageSavedMsg struct {
+	err error
+}
+
+// triageUnavailable returns the flash shown when triage cannot start,
+// or "" if it can.
+func (m Model) triageUnavailable() string {
+	switch {
+	case m.isRemote:
+		return "Triage not available in remote mode"
+	case m.triager == nil:
+		return "Triage not available"
+	}
+	return ""
+}
+
+// inTriage reports whether the message view shows the triage message,
+// rather than one opened from its thread.
+func (m Model) inTriage() bool {
+	return m.triage.active && m.level == levelMessageDetail && len(m.breadcrumbs) == m.triage.depth
+}
+
+// startTriage loads the first untriaged messages.
+func (m Model) startTriage() (tea.Model, tea.Cmd) {
+	if flash := m.triageUnavailable(); flash != "" {
+		return m.showFlash(flash)
+	}
+	m.loading = true
+	return m, tea.Batch(m.startSpinner(), m.loadTriageBatch(-1, true))
+}
+
+// loadTriageBatch loads the untriaged messages after the message after,
+// or after the saved watermark when after is negative.
+func (m Model) loadTriageBatch(after int64, start bool) tea.Cmd {
+	triager := m.triager
+	return safeCmdWithPanic(
+		func() tea.Msg {
+			if after < 0 {
+				wm, err := triager.TriageWatermark()
+				if err != nil {
+					return triageLoadedMsg{start: start, err: err}
+				}
+				after = wm
+			}
+			ids, err := triager.UntriagedMessageIDs(after, triageBatchSize)
+			if err != nil {
+				return triageLoadedMsg{start: start, err: err}
+			}
+			n, err := triager.CountUntriagedMessages(after)
+			return triageLoadedMsg{ids: ids, remaining: n, start: start, err: err}
+		},
+		func(r any) tea.Msg {
+			return triageLoadedMsg{start: start, err: fmt.Errorf("triage panic: %v", r)}
+		},
+	)
+}
+
+// handleTriageLoaded shows the first message of a loaded batch, or
+// ends triage when none are left.
+func (m Model) handleTriageLoaded(msg triageLoadedMsg) (tea.Model, tea.Cmd) {
+	if !msg.start && !m.triage.active {
+		return m, nil
+	}
+	m.loading = false
+	if msg.err != nil {
+		if msg.start {
+			return m.showFlash("Triage failed: " + msg.err.Error())
+		}
+		return m.finishTriage("Triage failed: " + msg.err.Error())
+	}
+	if len(msg.ids) == 0 {
+		if msg.start {
+			return m.showFlash("Nothing left to triage")
+		}
+		return m.finishTriage("Triage complete")
+	}
+	if msg.start {
+		m.transitionBuffer = m.renderView() // Freeze screen until data loads
+		m.pushBreadcrumb()
+		m.triage = triageState{active: true, depth: len(m.breadcrumbs)}
+	}
+	m.triage.queue = msg.ids
+	m.triage.index = 0
+	m.triage.remaining = msg.remaining
+	return m, m.showTriageMessage()
+}
+
+// showTriageMessage loads the triage message in the message view.
+func (m *Model) showTriageMessage() tea.Cmd {
+	m.level = levelMessageDetail
+	m.detailFromThread = false
+	m.messageDetail = nil
+	m.pendingDetailSubject = ""
+	m.detailLineCount = 0
+	m.detailScroll = 0
+	m.detailSearchQuery = ""
+	m.detailSearchMatches = nil
+	m.loading = true
+	m.err = nil
+	m.detailRequestID++
+	return m.loadMessageDetail(m.triage.queue[m.triage.index])
+}
+
+// handleTriageKeys handles the triage actions in the message view and
+// reports whether the key was one.
+func (m Model) handleTriageKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	switch msg.String() {
+	case " ":
+		m2, cmd := m.advanceTriage()
+		return m2, cmd, true
+	case "d", "D":
+		if m.messageDetail == nil {
+			m2, cmd := m.showFlash("No message loaded")
+			return m2, cmd, true
+		}
+		d := m.messageDetail
+		m.triage.deletions = append(m.triage.deletions, query.MessageSummary{
+			ID: d.ID, SourceMessageID: d.SourceMessageID, Subject: d.Subject,
+		})
+		m2, cmd := m.advanceTriage()
+		return m2, cmd, true
+	case "o":
+		// Esc in the thread view comes back to triage.
+		m2, cmd := m.openThread()
+		return m2, cmd, true
+	case "left", "h", "right", "l":
+		m2, cmd := m.showFlash("Triage: Space keep, d delete, # tag, o thread, Esc stop")
+		return m2, cmd, true
+	case "esc":
+		if m.detailSearchQuery != "" {
+			return m, nil, false
+		}
+		m2, cmd := m.finishTriage(fmt.Sprintf("Triage stopped: %d triaged, %d left", m.triage.triaged, m.triage.remaining))
+		return m2, cmd, true
+	}
+	return m, nil, false
+}
+
+// advanceTriage marks the message shown as triaged, saves the
+// watermark and moves on to the next message.
+func (m Model) advanceTriage() (tea.Model, tea.Cmd) {
+	if m.triage.index >= len(m.triage.queue) {
+		return m, nil // the next batch is loading
+	}
+	id := m.triage.queue[m.triage.index]
+	m.triage.triaged++
+	m.triage.remaining--
+	m.triage.index++
+
+	triager := m.triager
+	save := func() tea.Msg {
+		return triageSavedMsg{err: triager.SetTriageWatermark(id)}
+	}
+	if m.triage.index < len(m.triage.queue) {
+		return m, tea.Batch(save, m.showTriageMessage())
+	}
+	m.loading = true
+	return m, tea.Batch(save, m.startSpinner(), m.loadTriageBatch(id, false))
+}
+
+// handleTriageSaved reports a watermark that could not be saved.
+func (m Model) handleTriageSaved(msg triageSavedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		return m.showFlash("Save failed: " + msg.err.Error())
+	}
+	return m, nil
+}
+
+// finishTriage leaves triage for the view it started from and stages
+// the messages marked for deletion, or shows flash when none were.
+func (m Model) finishTriage(flash string) (tea.Model, tea.Cmd) {
+	deletions := m.triage.deletions
+	depth := m.triage.depth
+	m.triage = triageState{}
+	for depth > 0 && len(m.breadcrumbs) >= depth {
+		m2, _ := m.goBack()
+		m = m2.(Model)
+	}
+	if len(deletions) == 0 {
+		return m.showFlash(flash)
+	}
+
+	selection := make(map[int64]bool, len(deletions))
+	for _, d := range deletions {
+		selection[d.ID] = true
+	}
+	manifest, err := m.actions.StageForDeletion(DeletionContext{
+		MessageSelection: selection,
+		AccountFilter:    m.accountFilter,
+		Accounts:         m.accounts,
+		Messages:         deletions,
+	})
+	if err != nil {
+		m.modal = modalDeleteResult
+		m.modalResult = err.Error()
+		return m, nil
+	}
+	m.pendingManifest = manifest
+	m.modal = modalDeleteConfirm
+	return m, nil
+}
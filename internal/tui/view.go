@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/wesm/msgvault/internal/i18n"
+	"github.com/wesm/msgvault/internal/mime"
 	"github.com/wesm/msgvault/internal/query"
 	"github.com/wesm/msgvault/internal/textutil"
 )
@@ -82,25 +84,30 @@ var (
 			Foreground(lipgloss.AdaptiveColor{Light: "#000000", Dark: "#000000"}).
 			Background(lipgloss.AdaptiveColor{Light: "#e8d44d", Dark: "#e8d44d"}).
 			Bold(true)
+
+	// Label chips in message detail; labels with a Gmail color use it
+	labelChipStyle = lipgloss.NewStyle().
+			Background(bgCursor).
+			Padding(0, 1)
 )
 
 // viewTypeAbbrev returns view type name for column headers and top-level breadcrumb.
 func viewTypeAbbrev(vt query.ViewType) string {
 	switch vt {
 	case query.ViewSenders:
-		return "Sender"
+		return i18n.T("Sender")
 	case query.ViewSenderNames:
-		return "Sender Name"
+		return i18n.T("Sender Name")
 	case query.ViewRecipients:
-		return "Recipient"
+		return i18n.T("Recipient")
 	case query.ViewRecipientNames:
-		return "Recipient Name"
+		return i18n.T("Recipient Name")
 	case query.ViewDomains:
-		return "Domain"
+		return i18n.T("Domain")
 	case query.ViewLabels:
-		return "Label"
+		return i18n.T("Label")
 	case query.ViewTime:
-		return "Time"
+		return i18n.T("Time")
 	default:
 		return vt.String()
 	}
@@ -144,7 +151,7 @@ func (m Model) buildTitleBar() string {
 	// Account indicator
 	var accountStr string
 	if m.accountFilter == nil {
-		accountStr = "All Accounts"
+		accountStr = i18n.T("All Accounts")
 	} else {
 		for _, acc := range m.accounts {
 			if acc.ID == *m.accountFilter {
@@ -156,19 +163,27 @@ func (m Model) buildTitleBar() string {
 
 	// Filter indicators
 	if m.filters.attachmentsOnly {
-		accountStr += " [Attachments]"
+		accountStr += " [" + i18n.T("Attachments") + "]"
 	}
 	if m.filters.hideDeletedFromSource {
-		accountStr += " [Hide Deleted]"
+		accountStr += " [" + i18n.T("Hide Deleted") + "]"
 	}
 
-	// Update notification (right-aligned on title bar)
+	// Reminder and update notifications (right-aligned on title bar)
 	var updateNotice string
 	if m.updateAvailable != "" {
 		if m.updateIsDevBuild {
-			updateNotice = fmt.Sprintf("latest: %s — msgvault update --force", m.updateAvailable)
+			updateNotice = i18n.T("latest: %s — msgvault update --force", m.updateAvailable)
+		} else {
+			updateNotice = i18n.T("update: %s — msgvault update", m.updateAvailable)
+		}
+	}
+	if m.remindersDue > 0 {
+		reminderNotice := i18n.T("reminders due: %s — search is:due", i18n.Number(m.remindersDue))
+		if updateNotice != "" {
+			updateNotice = reminderNotice + "  " + updateNotice
 		} else {
-			updateNotice = fmt.Sprintf("update: %s — msgvault update", m.updateAvailable)
+			updateNotice = reminderNotice
 		}
 	}
 
@@ -201,17 +216,17 @@ func (m Model) buildBreadcrumb() string {
 	case levelDrillDown:
 		// Show drill context: "S: foo@example.com (by To)"
 		drillKey := m.drillFilterKey()
-		breadcrumb := fmt.Sprintf("%s: %s (by %s)", viewTypePrefix(m.drillViewType), truncateRunes(drillKey, 30), viewTypeAbbrev(m.viewType))
+		breadcrumb := i18n.T("%s: %s (by %s)", viewTypePrefix(m.drillViewType), truncateRunes(drillKey, 30), viewTypeAbbrev(m.viewType))
 		if m.viewType == query.ViewTime {
 			breadcrumb += " " + m.timeGranularity.String()
 		}
 		return breadcrumb
 	case levelMessageList:
 		if m.searchQuery != "" {
-			return "Search Results"
+			return i18n.T("Search Results")
 		}
 		if m.allMessages {
-			return "All Messages"
+			return i18n.T("All Messages")
 		}
 		if m.hasDrillFilter() {
 			drillKey := m.drillFilterKey()
@@ -226,12 +241,15 @@ func (m Model) buildBreadcrumb() string {
 		if m.messageDetail != nil {
 			subject = m.messageDetail.Subject
 		}
-		return fmt.Sprintf("Message: %s", truncateRunes(subject, 50))
+		if m.inTriage() {
+			return i18n.T("Triage: %s", truncateRunes(subject, 50))
+		}
+		return i18n.T("Message: %s", truncateRunes(subject, 50))
 	case levelThreadView:
 		if m.threadTruncated {
-			return fmt.Sprintf("Thread (showing %d of %d+ messages)", len(m.threadMessages), len(m.threadMessages))
+			return i18n.T("Thread (showing %d of %d+ messages)", len(m.threadMessages), len(m.threadMessages))
 		}
-		return fmt.Sprintf("Thread (%d messages)", len(m.threadMessages))
+		return i18n.T("Thread (%d messages)", len(m.threadMessages))
 	default:
 		return ""
 	}
@@ -245,7 +263,7 @@ func (m Model) buildStatsString() string {
 		if m.searchTotalCount == -1 {
 			msgsSuffix = "+"
 		}
-		return fmt.Sprintf("%d%s msgs | %s | %d attchs",
+		return i18n.T("%d%s msgs | %s | %d attchs",
 			m.contextStats.MessageCount,
 			msgsSuffix,
 			formatBytes(m.contextStats.TotalSize),
@@ -253,7 +271,7 @@ func (m Model) buildStatsString() string {
 		)
 	}
 	if m.stats != nil {
-		return fmt.Sprintf("%d msgs | %s | %d attchs",
+		return i18n.T("%d msgs | %s | %d attchs",
 			m.stats.MessageCount,
 			formatBytes(m.stats.TotalSize),
 			m.stats.AttachmentCount,
@@ -291,7 +309,7 @@ func (m Model) aggregateTableView() string {
 		sb.WriteString("\n")
 		sb.WriteString(separatorStyle.Render(strings.Repeat("\u2500", m.width)))
 		sb.WriteString("\n")
-		sb.WriteString(normalRowStyle.Render(padRight("   No data", m.width)))
+		sb.WriteString(normalRowStyle.Render(padRight("   "+i18n.T("No data"), m.width)))
 		sb.WriteString("\n")
 		// 1 "No data" + (pageSize-2) blanks = pageSize-1 data rows,
 		// then +1 info line = pageSize body rows total.
@@ -335,17 +353,17 @@ func (m Model) aggregateTableView() string {
 		viewLabel += si
 	}
 
-	countLabel := "Count"
+	countLabel := i18n.T("Count")
 	if si := sortIndicator(query.SortByCount); si != "" {
 		countLabel += si
 	}
 
-	sizeLabel := "Size"
+	sizeLabel := i18n.T("Size")
 	if si := sortIndicator(query.SortBySize); si != "" {
 		sizeLabel += si
 	}
 
-	attachLabel := "Attchs"
+	attachLabel := i18n.T("Attchs")
 	if si := sortIndicator(query.SortByAttachmentSize); si != "" {
 		attachLabel += si
 	}
@@ -367,6 +385,12 @@ func (m Model) aggregateTableView() string {
 		endRow = len(m.rows)
 	}
 
+	// Labels sorted by name are shown as a tree of their "/" nesting.
+	var treeKeys []string
+	if m.viewType == query.ViewLabels && m.sortField == query.SortByName && m.sortDirection == query.SortAsc {
+		treeKeys = labelTreeKeys(m.rows)
+	}
+
 	for i := m.scrollOffset; i < endRow; i++ {
 		row := m.rows[i]
 		isCursor := i == m.cursor
@@ -388,7 +412,11 @@ func (m Model) aggregateTableView() string {
 
 		// Pad key to fixed width first, then highlight — so ANSI codes
 		// don't affect column alignment.
-		key := truncateRunes(row.Key, keyWidth)
+		rowKey := row.Key
+		if treeKeys != nil {
+			rowKey = treeKeys[i]
+		}
+		key := truncateRunes(rowKey, keyWidth)
 		key = fmt.Sprintf("%-*s", keyWidth, key)
 		key = highlightTerms(key, m.searchQuery)
 
@@ -417,7 +445,7 @@ func (m Model) aggregateTableView() string {
 
 	// Show "No results" indicator when search returned zero matches
 	if len(m.rows) == 0 && !m.loading {
-		sb.WriteString(normalRowStyle.Render(padRight("   No results found", m.width)))
+		sb.WriteString(normalRowStyle.Render(padRight("   "+i18n.T("No results found"), m.width)))
 		sb.WriteString("\n")
 	}
 
@@ -438,7 +466,7 @@ func (m Model) aggregateTableView() string {
 	if m.inlineSearchActive {
 		infoContent = "/" + m.searchInput.View()
 	} else if m.searchQuery != "" {
-		infoContent = fmt.Sprintf(" Search: %q", m.searchQuery)
+		infoContent = " " + i18n.T("Search: %q", m.searchQuery)
 	}
 	sb.WriteString(m.renderInfoLine(infoContent, isLoading))
 
@@ -456,7 +484,7 @@ func (m Model) messageListView() string {
 	// When a search is active (or search bar is open), fall through to full
 	// rendering so the search bar stays visible and the user can edit their query.
 	if len(m.messages) == 0 && !m.loading && !m.inlineSearchActive && m.searchQuery == "" && m.err == nil {
-		return m.fillScreen(normalRowStyle.Render(padRight("No messages", m.width)), 1)
+		return m.fillScreen(normalRowStyle.Render(padRight(i18n.T("No messages"), m.width)), 1)
 	}
 
 	var sb strings.Builder
@@ -481,24 +509,24 @@ func (m Model) messageListView() string {
 		return ""
 	}
 
-	dateLabel := "Date"
+	dateLabel := i18n.T("Date")
 	if si := msgSortIndicator(query.MessageSortByDate); si != "" {
 		dateLabel += si
 	}
 
-	sizeLabel := "Size"
+	sizeLabel := i18n.T("Size")
 	if si := msgSortIndicator(query.MessageSortBySize); si != "" {
 		sizeLabel += si
 	}
 
-	subjectLabel := "Subject"
+	subjectLabel := i18n.T("Subject")
 	if si := msgSortIndicator(query.MessageSortBySubject); si != "" {
 		subjectLabel += si
 	}
 
 	headerRow := fmt.Sprintf("   %-*s  %-*s  %-*s  %*s",
 		dateWidth, dateLabel,
-		fromWidth, "From",
+		fromWidth, i18n.T("From"),
 		subjectWidth, subjectLabel,
 		sizeWidth, sizeLabel,
 	)
@@ -515,7 +543,7 @@ func (m Model) messageListView() string {
 
 	// Show "No results" indicator when search returned zero matches
 	if len(m.messages) == 0 && !m.loading {
-		sb.WriteString(normalRowStyle.Render(padRight("   No results found", m.width)))
+		sb.WriteString(normalRowStyle.Render(padRight("   "+i18n.T("No results found"), m.width)))
 		sb.WriteString("\n")
 	}
 
@@ -539,7 +567,7 @@ func (m Model) messageListView() string {
 		}
 
 		// Format date
-		date := msg.SentAt.Format("2006-01-02 15:04")
+		date := i18n.DateTime(msg.SentAt)
 
 		// Format from (rune-aware for international names)
 		// Sanitize untrusted metadata to prevent terminal control-sequence injection.
@@ -627,16 +655,19 @@ func (m Model) messageListView() string {
 		}
 		infoContent = modeTag + "/" + m.searchInput.View()
 	} else if m.searchQuery != "" {
-		infoContent = fmt.Sprintf(" Search: %q", m.searchQuery)
+		infoContent = " " + i18n.T("Search: %q", m.searchQuery)
 		if m.searchTotalCount > 0 {
-			infoContent += fmt.Sprintf(" (%d results)", m.searchTotalCount)
+			infoContent += " (" + i18n.T("%d results", m.searchTotalCount) + ")"
 		} else if m.searchTotalCount == 0 {
-			infoContent += " (0 results)"
+			infoContent += " (" + i18n.T("%d results", 0) + ")"
 		} else if m.searchTotalCount == -1 {
-			infoContent += fmt.Sprintf(" (%d+ results, PgDn for more)", len(m.messages))
+			infoContent += " (" + i18n.T("%d+ results, PgDn for more", len(m.messages)) + ")"
 		}
 		if m.searchMode == searchModeDeep {
 			infoContent += " [Deep]"
+			if m.searchRelevance {
+				infoContent += " [" + i18n.T("by relevance") + "]"
+			}
 		}
 	}
 	sb.WriteString(m.renderInfoLine(infoContent, isLoading))
@@ -659,49 +690,76 @@ func (m Model) buildDetailLines() []string {
 	var lines []string
 
 	// Subject
-	lines = append(lines, fmt.Sprintf("Subject: %s", msg.Subject))
+	lines = append(lines, i18n.T("Subject: %s", msg.Subject))
 	lines = append(lines, "")
 
 	// Date
-	lines = append(lines, fmt.Sprintf("Date: %s", msg.SentAt.Format("Mon, 02 Jan 2006 15:04:05 MST")))
+	lines = append(lines, i18n.T("Date: %s", i18n.LongDateTime(msg.SentAt)))
 
 	// From
 	if len(msg.From) > 0 {
 		from := formatAddresses(msg.From)
-		lines = append(lines, fmt.Sprintf("From: %s", from))
+		lines = append(lines, i18n.T("From: %s", from))
 	}
 
 	// To
 	if len(msg.To) > 0 {
 		to := formatAddresses(msg.To)
-		lines = append(lines, fmt.Sprintf("To: %s", to))
+		lines = append(lines, i18n.T("To: %s", to))
 	}
 
 	// Cc
 	if len(msg.Cc) > 0 {
 		cc := formatAddresses(msg.Cc)
-		lines = append(lines, fmt.Sprintf("Cc: %s", cc))
+		lines = append(lines, i18n.T("Cc: %s", cc))
 	}
 
 	// Bcc
 	if len(msg.Bcc) > 0 {
 		bcc := formatAddresses(msg.Bcc)
-		lines = append(lines, fmt.Sprintf("Bcc: %s", bcc))
+		lines = append(lines, i18n.T("Bcc: %s", bcc))
+	}
+
+	// Labels (drawn as chips by renderDetailLine)
+	if line := detailLabelsLine(msg); line != "" {
+		lines = append(lines, line)
 	}
 
-	// Labels
-	if len(msg.Labels) > 0 {
-		lines = append(lines, fmt.Sprintf("Labels: %s", strings.Join(msg.Labels, ", ")))
+	// Local annotations
+	if msg.Pinned {
+		lines = append(lines, "📌 "+i18n.T("Pinned"))
+	}
+	if len(msg.Tags) > 0 {
+		lines = append(lines, i18n.T("Tags: %s", strings.Join(msg.Tags, ", ")))
+	}
+	if msg.Note != "" {
+		noteLines := strings.Split(msg.Note, "\n")
+		lines = append(lines, i18n.T("Note: %s", noteLines[0]))
+		for _, l := range noteLines[1:] {
+			lines = append(lines, "      "+l)
+		}
 	}
 
 	// Attachments
 	if len(msg.Attachments) > 0 {
 		lines = append(lines, "")
-		lines = append(lines, fmt.Sprintf("Attachments (%d):", len(msg.Attachments)))
+		lines = append(lines, i18n.T("Attachments (%d):", len(msg.Attachments)))
 		for _, att := range msg.Attachments {
 			lines = append(lines, fmt.Sprintf("  📎 %s (%s)", att.Filename, formatBytes(att.Size)))
 		}
 	}
+	if len(msg.InlineParts) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, i18n.T("Inline images (%d):", len(msg.InlineParts)))
+		for _, p := range msg.InlineParts {
+			name := p.Filename
+			if name == "" {
+				name = p.ContentID
+			}
+			lines = append(lines, fmt.Sprintf("  🖼 %s (%s)", name, formatBytes(p.Size)))
+		}
+	}
+	lines = append(lines, m.previewDetailLines(m.width-2)...)
 
 	// Separator
 	lines = append(lines, "")
@@ -712,20 +770,57 @@ func (m Model) buildDetailLines() []string {
 	lines = append(lines, strings.Repeat("─", sepWidth))
 	lines = append(lines, "")
 
-	// Body - wrap lines to fit width
+	// Body - wrap lines to fit width. A folded signature and quoted
+	// trail are the end of BodyText and are cut off unless shown. The
+	// HTML body is rendered instead when there is no text body or H
+	// was pressed, with its tracking pixels stripped unless shown.
 	body := msg.BodyText
+	fromHTML := msg.BodyHTML != "" && (body == "" || m.showHTML)
+	strippedTrackers := 0
+	if fromHTML {
+		inline := make(map[string]string, len(msg.InlineParts))
+		for _, p := range msg.InlineParts {
+			inline[p.ContentID] = p.Filename
+		}
+		body = mime.RenderHTML(msg.BodyHTML, m.showTrackers, inline)
+		if !m.showTrackers {
+			strippedTrackers = countTrackers(msg.BodyHTML)
+		}
+	}
+	folded := len(msg.BodyQuoted) + len(msg.BodySignature)
+	hideQuoted := !fromHTML && folded > 0 && !m.showQuoted && folded < len(body)
+	if hideQuoted {
+		body = strings.TrimRight(body[:len(body)-folded], "\r\n")
+	}
 	if body == "" {
-		body = "(No text content)"
+		body = i18n.T("(No text content)")
 	}
 	// Strip carriage returns (CRLF -> LF) to prevent display issues
 	body = strings.ReplaceAll(body, "\r\n", "\n")
 	body = strings.ReplaceAll(body, "\r", "")
 	bodyLines := wrapText(body, m.width-2)
 	lines = append(lines, bodyLines...)
+	if hideQuoted {
+		lines = append(lines, "", i18n.T("[Quoted text hidden: press Q to show]"))
+	}
+	if strippedTrackers > 0 {
+		lines = append(lines, "", i18n.T("[%d tracking pixels removed: press P to show]", strippedTrackers))
+	}
 
 	return lines
 }
 
+// countTrackers returns how many tracking pixels an HTML body has.
+func countTrackers(htmlBody string) int {
+	n := 0
+	for _, l := range mime.ExtractLinks(htmlBody) {
+		if l.Kind == mime.LinkTracker {
+			n++
+		}
+	}
+	return n
+}
+
 // fillScreenWithPageSize fills the remaining screen space with blank lines up to the given page size.
 // Used for loading/error/empty states in all views.
 func (m Model) fillScreenWithPageSize(content string, usedLines, pageSize int) string {
@@ -761,13 +856,13 @@ func (m Model) fillScreenDetail(content string, usedLines int) string {
 func (m Model) messageDetailView() string {
 	if m.messageDetail == nil {
 		if m.loading {
-			return m.fillScreenDetail(loadingStyle.Render(padRight(m.spinnerIndicator()+" Loading message...", m.width)), 1)
+			return m.fillScreenDetail(loadingStyle.Render(padRight(m.spinnerIndicator()+" "+i18n.T("Loading message..."), m.width)), 1)
 		}
 		content := m.fillScreenDetail(normalRowStyle.Render(strings.Repeat(" ", m.width)), 1)
 		if m.modal != modalNone {
 			return m.overlayModal(content)
 		}
-		return m.fillScreenDetail(errorStyle.Render(padRight("Message not found (nil detail)", m.width)), 1)
+		return m.fillScreenDetail(errorStyle.Render(padRight(i18n.T("Message not found (nil detail)"), m.width)), 1)
 	}
 
 	lines := m.buildDetailLines()
@@ -796,9 +891,13 @@ func (m Model) messageDetailView() string {
 		detailHighlightQuery = m.searchQuery
 	}
 
+	labelsLine := detailLabelsLine(m.messageDetail)
+
 	var sb strings.Builder
 	for lineIdx, line := range visibleLines {
-		if detailHighlightQuery != "" {
+		if labelsLine != "" && line == labelsLine {
+			line = renderLabelChips(m.messageDetail)
+		} else if detailHighlightQuery != "" {
 			line = highlightTerms(line, detailHighlightQuery)
 		}
 		// Highlight current detail search match line
@@ -819,8 +918,15 @@ func (m Model) messageDetailView() string {
 		sb.WriteString("\n")
 	}
 
-	// Notification line - show detail search bar, flash, loading, or blank
-	if m.detailSearchActive {
+	// Notification line - show the note or tags being edited, detail
+	// search bar, flash, loading, or blank
+	if m.annotating != annotateNone {
+		prompt := i18n.T("Note: %s", m.annotateInput.View())
+		if m.annotating == annotateTags {
+			prompt = i18n.T("Tags: %s", m.annotateInput.View())
+		}
+		sb.WriteString(m.renderInfoLine(" "+prompt, false))
+	} else if m.detailSearchActive {
 		infoContent := "/" + m.detailSearchInput.View()
 		sb.WriteString(m.renderInfoLine(infoContent, false))
 	} else if m.detailSearchQuery != "" {
@@ -828,7 +934,7 @@ func (m Model) messageDetailView() string {
 		if len(m.detailSearchMatches) > 0 {
 			matchInfo += fmt.Sprintf(" [%d/%d]", m.detailSearchMatchIndex+1, len(m.detailSearchMatches))
 		} else {
-			matchInfo += " [no matches]"
+			matchInfo += " [" + i18n.T("no matches") + "]"
 		}
 		sb.WriteString(m.renderInfoLine(matchInfo, false))
 	} else {
@@ -846,11 +952,11 @@ func (m Model) messageDetailView() string {
 // threadView renders the thread/conversation view.
 func (m Model) threadView() string {
 	if m.loading && len(m.threadMessages) == 0 {
-		return m.fillScreen(loadingStyle.Render(padRight(m.spinnerIndicator()+" Loading thread...", m.width)), 1)
+		return m.fillScreen(loadingStyle.Render(padRight(m.spinnerIndicator()+" "+i18n.T("Loading thread..."), m.width)), 1)
 	}
 
 	if !m.loading && len(m.threadMessages) == 0 {
-		content := m.fillScreen(normalRowStyle.Render(padRight("No messages in thread", m.width)), 1)
+		content := m.fillScreen(normalRowStyle.Render(padRight(i18n.T("No messages in thread"), m.width)), 1)
 		if m.modal != modalNone {
 			return m.overlayModal(content)
 		}
@@ -869,9 +975,9 @@ func (m Model) threadView() string {
 
 	// Header row
 	headerRow := fmt.Sprintf("   %-*s  %-*s  %*s",
-		dateWidth, "Date",
-		fromSubjectWidth, "From / Subject",
-		sizeWidth, "Size",
+		dateWidth, i18n.T("Date"),
+		fromSubjectWidth, i18n.T("From / Subject"),
+		sizeWidth, i18n.T("Size"),
 	)
 	sb.WriteString(tableHeaderStyle.Render(padRight(headerRow, m.width)))
 	sb.WriteString("\n")
@@ -908,7 +1014,7 @@ func (m Model) threadView() string {
 		}
 
 		// Format date
-		dateStr := msg.SentAt.Format("2006-01-02 15:04")
+		dateStr := i18n.DateTime(msg.SentAt)
 
 		// Format from/subject with deleted indicator
 		// Sanitize untrusted metadata to prevent terminal control-sequence injection.
@@ -983,7 +1089,7 @@ func (m Model) footerView() string {
 	// Selection count
 	selCount := m.selectionCount()
 	if selCount > 0 {
-		selStr = fmt.Sprintf(" [%d selected] ", selCount)
+		selStr = " [" + i18n.T("%d selected", selCount) + "] "
 	}
 
 	switch m.level {
@@ -992,19 +1098,19 @@ func (m Model) footerView() string {
 			"↑/k",
 			"↓/j",
 			"Enter",
-			"g group",
-			"s sort",
-			"A acct",
-			"a msgs",
-			"d del",
+			i18n.T("g group"),
+			i18n.T("s sort"),
+			i18n.T("A acct"),
+			i18n.T("a msgs"),
+			i18n.T("d del"),
 		}
-		keys = append(keys, "? help")
+		keys = append(keys, i18n.T("? help"))
 		if len(m.rows) > 0 {
 			// Use TotalUnique from aggregate rows for true total count
 			totalUnique := m.rows[0].TotalUnique
 			if totalUnique > 0 && totalUnique > int64(len(m.rows)) {
 				// More rows exist than loaded - show "N of M"
-				posStr = fmt.Sprintf(" %d of %d ", m.cursor+1, totalUnique)
+				posStr = " " + i18n.T("%d of %d", m.cursor+1, totalUnique) + " "
 			} else {
 				posStr = fmt.Sprintf(" %d/%d ", m.cursor+1, len(m.rows))
 			}
@@ -1016,19 +1122,19 @@ func (m Model) footerView() string {
 			"↓/j",
 			"Enter",
 			"Esc",
-			"g group",
-			"s sort",
-			"A acct",
-			"a msgs",
-			"d del",
+			i18n.T("g group"),
+			i18n.T("s sort"),
+			i18n.T("A acct"),
+			i18n.T("a msgs"),
+			i18n.T("d del"),
 		}
-		keys = append(keys, "? help")
+		keys = append(keys, i18n.T("? help"))
 		if len(m.rows) > 0 {
 			// Use TotalUnique from aggregate rows for true total count
 			totalUnique := m.rows[0].TotalUnique
 			if totalUnique > 0 && totalUnique > int64(len(m.rows)) {
 				// More rows exist than loaded - show "N of M"
-				posStr = fmt.Sprintf(" %d of %d ", m.cursor+1, totalUnique)
+				posStr = " " + i18n.T("%d of %d", m.cursor+1, totalUnique) + " "
 			} else {
 				posStr = fmt.Sprintf(" %d/%d ", m.cursor+1, len(m.rows))
 			}
@@ -1041,10 +1147,10 @@ func (m Model) footerView() string {
 			"Enter",
 			"Esc",
 			"Space",
-			"s sort",
-			"d del",
+			i18n.T("s sort"),
+			i18n.T("d del"),
 		}
-		keys = append(keys, "/ search", "? help")
+		keys = append(keys, i18n.T("/ search"), i18n.T("? help"))
 		if len(m.messages) > 0 {
 			// Show position / total - use contextStats for actual total when drilled down,
 			// or global stats for All Messages view
@@ -1057,26 +1163,40 @@ func (m Model) footerView() string {
 			}
 			if total > int64(len(m.messages)) {
 				// More messages exist than loaded - show "N of M"
-				posStr = fmt.Sprintf(" %d of %d ", m.cursor+1, total)
+				posStr = " " + i18n.T("%d of %d", m.cursor+1, total) + " "
 			} else {
 				posStr = fmt.Sprintf(" %d/%d ", m.cursor+1, len(m.messages))
 			}
 		}
 
 	case levelMessageDetail:
+		if m.inTriage() {
+			keys = []string{
+				i18n.T("Space keep"),
+				i18n.T("d delete"),
+				i18n.T("# tag"),
+				i18n.T("o thread"),
+				i18n.T("Esc stop"),
+			}
+			posStr = " " + i18n.T("%d left", m.triage.remaining) + " "
+			break
+		}
 		keys = []string{
-			"←/→ prev/next",
-			"↑/↓ scroll",
-			"/ find",
+			i18n.T("←/→ prev/next"),
+			i18n.T("↑/↓ scroll"),
+			i18n.T("/ find"),
 		}
 		if m.detailSearchQuery != "" {
-			keys = append(keys, "n/N next/prev")
+			keys = append(keys, i18n.T("n/N next/prev"))
 		}
 		// Show export option if message has attachments
 		if m.messageDetail != nil && len(m.messageDetail.Attachments) > 0 {
-			keys = append(keys, "e export")
+			keys = append(keys, i18n.T("e export"))
 		}
-		keys = append(keys, "Esc back", "q quit")
+		if m.attachmentPreviewKey() {
+			keys = append(keys, i18n.T("v preview"))
+		}
+		keys = append(keys, i18n.T("Esc back"), i18n.T("q quit"))
 		// Show message position (N/M) in the list - reuse total from parent view
 		if len(m.messages) > 0 {
 			total := int64(len(m.messages))
@@ -1085,17 +1205,17 @@ func (m Model) footerView() string {
 			} else if m.allMessages && m.stats != nil && m.stats.MessageCount > total {
 				total = m.stats.MessageCount
 			}
-			posStr = fmt.Sprintf(" msg %d/%d ", m.detailMessageIndex+1, total)
+			posStr = " " + i18n.T("msg %d/%d", m.detailMessageIndex+1, total) + " "
 		} else {
 			posStr = ""
 		}
 
 	case levelThreadView:
 		keys = []string{
-			"↑/↓ navigate",
-			"Enter view",
-			"Esc back",
-			"q quit",
+			i18n.T("↑/↓ navigate"),
+			i18n.T("Enter view"),
+			i18n.T("Esc back"),
+			i18n.T("q quit"),
 		}
 		if len(m.threadMessages) > 0 {
 			posStr = fmt.Sprintf(" %d/%d ", m.threadCursor+1, len(m.threadMessages))
@@ -1182,7 +1302,8 @@ func min(a, b int) int {
 // overlayModal renders a modal dialog over the content.
 // rawHelpLines contains the help modal content. The first line is the title
 // (rendered with modalTitleStyle at display time). This is a package-level
-// variable so len() can be used without rebuilding the slice on every call.
+// variable so len() can be used without rebuilding the slice on every call;
+// each line is translated when rendered.
 var rawHelpLines = []string{
 	"Keyboard Shortcuts", // rendered with modalTitleStyle in overlayModal
 	"",
@@ -1199,6 +1320,7 @@ var rawHelpLines = []string{
 	"  t           Jump to Time view (cycle granularity when in Time)",
 	"  s           Cycle sort field",
 	"  v/r         Reverse sort order",
+	"  s           Date/relevance order (deep search results)",
 	"",
 	"Selection & Actions",
 	"  Space       Toggle selection",
@@ -1206,12 +1328,21 @@ var rawHelpLines = []string{
 	"  x           Clear selection",
 	"  d/D         Stage for deletion",
 	"  a           View all messages",
+	"  i           Triage messages one at a time",
 	"",
 	"Other",
 	"  /           Search",
+	"  '           Saved searches",
 	"  A           Select account",
 	"  f           Filter (attachments, deleted)",
 	"  e           Export attachments (in message view)",
+	"  v           Preview text attachments (in message view)",
+	"  Q           Show/hide quoted text (in message view)",
+	"  H           Show HTML/text body (in message view)",
+	"  P           Show/hide tracking pixels (in message view)",
+	"  p           Pin/unpin message (in message view)",
+	"  c           Edit note (in message view)",
+	"  #           Edit tags (in message view)",
 	"  m           Toggle Email/Texts mode",
 	"  q           Quit",
 	"",
@@ -1236,44 +1367,42 @@ func (m Model) renderDeleteConfirmModal() string {
 		return ""
 	}
 	var sb strings.Builder
-	sb.WriteString(modalTitleStyle.Render("Confirm Deletion"))
+	sb.WriteString(modalTitleStyle.Render(i18n.T("Confirm Deletion")))
 	sb.WriteString("\n\n")
-	_, _ = fmt.Fprintf(&sb, "Stage %d messages for deletion?\n\n", len(m.pendingManifest.GmailIDs))
-	sb.WriteString("This creates a deletion batch. Messages will NOT be\n")
-	sb.WriteString("deleted until you run 'msgvault delete-staged'\n")
-	sb.WriteString("with MSGVAULT_ENABLE_REMOTE_DELETE=1 set.\n\n")
+	sb.WriteString(i18n.T("Stage %d messages for deletion?", len(m.pendingManifest.GmailIDs)) + "\n\n")
+	sb.WriteString(i18n.T("This creates a deletion batch. Messages will NOT be\ndeleted until you run 'msgvault delete-staged'\nwith MSGVAULT_ENABLE_REMOTE_DELETE=1 set.") + "\n\n")
 	if m.pendingManifest.Filters.Account == "" {
-		sb.WriteString("! Account not set. Use --account when executing.\n\n")
+		sb.WriteString(i18n.T("! Account not set. Use --account when executing.") + "\n\n")
 	}
-	sb.WriteString("[Y] Yes, stage for deletion    [N] Cancel")
+	sb.WriteString(i18n.T("[Y] Yes, stage for deletion    [N] Cancel"))
 	return sb.String()
 }
 
 // renderDeleteResultModal renders the deletion result modal content.
 func (m Model) renderDeleteResultModal() string {
-	return modalTitleStyle.Render("Result") + "\n\n" +
+	return modalTitleStyle.Render(i18n.T("Result")) + "\n\n" +
 		m.modalResult + "\n\n" +
-		"Press any key to continue"
+		i18n.T("Press any key to continue")
 }
 
 // renderQuitConfirmModal renders the quit confirmation modal content.
 func (m Model) renderQuitConfirmModal() string {
-	return modalTitleStyle.Render("Quit?") + "\n\n" +
-		"Are you sure you want to quit?\n\n" +
-		"[Y] Yes    [N] No"
+	return modalTitleStyle.Render(i18n.T("Quit?")) + "\n\n" +
+		i18n.T("Are you sure you want to quit?") + "\n\n" +
+		i18n.T("[Y] Yes    [N] No")
 }
 
 // renderAccountSelectorModal renders the account selector modal content.
 func (m Model) renderAccountSelectorModal() string {
 	var sb strings.Builder
-	sb.WriteString(modalTitleStyle.Render("Select Account"))
+	sb.WriteString(modalTitleStyle.Render(i18n.T("Select Account")))
 	sb.WriteString("\n\n")
 	// All Accounts option
 	indicator := "○"
 	if m.modalCursor == 0 {
 		indicator = "●"
 	}
-	_, _ = fmt.Fprintf(&sb, " %s All Accounts\n", indicator)
+	_, _ = fmt.Fprintf(&sb, " %s %s\n", indicator, i18n.T("All Accounts"))
 	// Individual accounts
 	for i, acc := range m.accounts {
 		indicator = "○"
@@ -1282,14 +1411,34 @@ func (m Model) renderAccountSelectorModal() string {
 		}
 		_, _ = fmt.Fprintf(&sb, " %s %s\n", indicator, acc.Identifier)
 	}
-	sb.WriteString("\n[↑/↓] Navigate  [Enter] Select  [Esc] Cancel")
+	sb.WriteString("\n" + i18n.T("[↑/↓] Navigate  [Enter] Select  [Esc] Cancel"))
+	return sb.String()
+}
+
+// renderSavedSearchesModal renders the saved search picker.
+func (m Model) renderSavedSearchesModal() string {
+	var sb strings.Builder
+	sb.WriteString(modalTitleStyle.Render(i18n.T("Saved Searches")))
+	sb.WriteString("\n\n")
+	width := 0
+	for _, item := range m.savedSearchItems {
+		width = max(width, lipgloss.Width(item.name))
+	}
+	for i, item := range m.savedSearchItems {
+		indicator := "○"
+		if m.modalCursor == i {
+			indicator = "●"
+		}
+		_, _ = fmt.Fprintf(&sb, " %s %s  %s\n", indicator, padRight(item.name, width), truncateToWidth(item.query, 50))
+	}
+	sb.WriteString("\n" + i18n.T("[↑/↓] Navigate  [Enter] Select  [Esc] Cancel"))
 	return sb.String()
 }
 
 // renderFilterModal renders the filter toggle modal content with checkboxes.
 func (m Model) renderFilterModal() string {
 	var sb strings.Builder
-	sb.WriteString(modalTitleStyle.Render("Filter Messages"))
+	sb.WriteString(modalTitleStyle.Render(i18n.T("Filter Messages")))
 	sb.WriteString("\n\n")
 
 	type filterOption struct {
@@ -1297,8 +1446,8 @@ func (m Model) renderFilterModal() string {
 		checked bool
 	}
 	options := []filterOption{
-		{"Only with attachments", m.filters.attachmentsOnly},
-		{"Hide deleted from source", m.filters.hideDeletedFromSource},
+		{i18n.T("Only with attachments"), m.filters.attachmentsOnly},
+		{i18n.T("Hide deleted from source"), m.filters.hideDeletedFromSource},
 	}
 
 	for i, opt := range options {
@@ -1313,7 +1462,7 @@ func (m Model) renderFilterModal() string {
 		_, _ = fmt.Fprintf(&sb, "%s%s %s\n", cursor, checkbox, opt.label)
 	}
 
-	sb.WriteString("\n[↑/↓] Navigate  [Space/x] Toggle  [Enter/Esc] Apply")
+	sb.WriteString("\n" + i18n.T("[↑/↓] Navigate  [Space/x] Toggle  [Enter/Esc] Apply"))
 	return sb.String()
 }
 
@@ -1334,6 +1483,9 @@ func (m Model) renderHelpModal() string {
 	visible := rawHelpLines[m.helpScroll : m.helpScroll+maxVisible]
 	rendered := make([]string, len(visible))
 	for i, line := range visible {
+		if line != "" {
+			line = i18n.T(line)
+		}
 		if m.helpScroll+i == 0 {
 			rendered[i] = modalTitleStyle.Render(line)
 		} else {
@@ -1346,14 +1498,14 @@ func (m Model) renderHelpModal() string {
 // renderExportAttachmentsModal renders the export attachments modal content.
 func (m Model) renderExportAttachmentsModal() string {
 	if m.messageDetail == nil || len(m.messageDetail.Attachments) == 0 {
-		return modalTitleStyle.Render("Export Attachments") + "\n\n" +
-			"No attachments to export.\n\n" +
-			"[Esc] Close"
+		return modalTitleStyle.Render(i18n.T("Export Attachments")) + "\n\n" +
+			i18n.T("No attachments to export.") + "\n\n" +
+			i18n.T("[Esc] Close")
 	}
 	var sb strings.Builder
-	sb.WriteString(modalTitleStyle.Render("Export Attachments"))
+	sb.WriteString(modalTitleStyle.Render(i18n.T("Export Attachments")))
 	sb.WriteString("\n\n")
-	sb.WriteString("Select attachments to export:\n\n")
+	sb.WriteString(i18n.T("Select attachments to export:") + "\n\n")
 	for i, att := range m.messageDetail.Attachments {
 		cursor := " "
 		if i == m.exportCursor {
@@ -1372,24 +1524,24 @@ func (m Model) renderExportAttachmentsModal() string {
 			selectedCount++
 		}
 	}
-	_, _ = fmt.Fprintf(&sb, "\n%d of %d selected\n", selectedCount, len(m.messageDetail.Attachments))
-	sb.WriteString("\n[↑/↓] Navigate  [Space] Toggle  [a] All  [n] None\n")
-	sb.WriteString("[Enter] Export  [Esc] Cancel")
+	sb.WriteString("\n" + i18n.T("%d of %d selected", selectedCount, len(m.messageDetail.Attachments)) + "\n")
+	sb.WriteString("\n" + i18n.T("[↑/↓] Navigate  [Space] Toggle  [a] All  [n] None") + "\n")
+	sb.WriteString(i18n.T("[Enter] Export  [Esc] Cancel"))
 	return sb.String()
 }
 
 // renderErrorModal renders the error modal content.
 func (m Model) renderErrorModal() string {
-	return modalTitleStyle.Render("Error") + "\n\n" +
+	return modalTitleStyle.Render(i18n.T("Error")) + "\n\n" +
 		m.modalResult + "\n\n" +
-		"Press any key to dismiss"
+		i18n.T("Press any key to dismiss")
 }
 
 // renderExportResultModal renders the export result modal content.
 func (m Model) renderExportResultModal() string {
-	return modalTitleStyle.Render("Export Complete") + "\n\n" +
+	return modalTitleStyle.Render(i18n.T("Export Complete")) + "\n\n" +
 		m.modalResult + "\n\n" +
-		"Press any key to close"
+		i18n.T("Press any key to close")
 }
 
 func (m Model) overlayModal(background string) string {
@@ -1406,6 +1558,8 @@ func (m Model) overlayModal(background string) string {
 		modalContent = m.renderAccountSelectorModal()
 	case modalFilterToggle:
 		modalContent = m.renderFilterModal()
+	case modalSavedSearches:
+		modalContent = m.renderSavedSearchesModal()
 	case modalHelp:
 		modalContent = m.renderHelpModal()
 	case modalExportAttachments:
@@ -1479,3 +1633,48 @@ func (m Model) overlayModal(background string) string {
 
 	return strings.Join(bgLines, "\n")
 }
+
+// detailLabelsLine is the plain-text labels line of a message detail,
+// which detail search matches against; "" if it has no labels.
+func detailLabelsLine(msg *query.MessageDetail) string {
+	if len(msg.Labels) == 0 {
+		return ""
+	}
+	return i18n.T("Labels: %s", strings.Join(msg.Labels, ", "))
+}
+
+// renderLabelChips draws a message's labels as chips, in their Gmail
+// colors where they have one.
+func renderLabelChips(msg *query.MessageDetail) string {
+	chips := make([]string, len(msg.Labels))
+	for i, name := range msg.Labels {
+		style := labelChipStyle
+		if c, ok := msg.LabelColors[name]; ok {
+			style = style.Background(lipgloss.Color(c.Background))
+			if c.Text != "" {
+				style = style.Foreground(lipgloss.Color(c.Text))
+			}
+		}
+		chips[i] = style.Render(textutil.SanitizeTerminal(name))
+	}
+	return i18n.T("Labels: %s", strings.Join(chips, " "))
+}
+
+// labelTreeKeys returns how each row of a Labels view sorted by name
+// is shown: a label nested ("/") under a label in a row above it is
+// indented beneath that row and shows only the rest of its name.
+func labelTreeKeys(rows []query.AggregateRow) []string {
+	keys := make([]string, len(rows))
+	var ancestors []string // the current row's ancestors, outermost first
+	for i, row := range rows {
+		for len(ancestors) > 0 && !strings.HasPrefix(row.Key, ancestors[len(ancestors)-1]+"/") {
+			ancestors = ancestors[:len(ancestors)-1]
+		}
+		keys[i] = row.Key
+		if n := len(ancestors); n > 0 {
+			keys[i] = strings.Repeat("  ", n) + strings.TrimPrefix(row.Key, ancestors[n-1]+"/")
+		}
+		ancestors = append(ancestors, row.Key)
+	}
+	return keys
+}
@@ -1106,6 +1106,20 @@ func TestHeaderUpdateNoticeUnicode(t *testing.T) {
 	}
 }
 
+// TestHeaderRemindersDue verifies the title bar shows the reminders due
+// at startup alongside an update notice.
+func TestHeaderRemindersDue(t *testing.T) {
+	model := NewBuilder().WithSize(120, 20).Build()
+	updated, _ := model.Update(remindersDueMsg{count: 3})
+	model = updated.(Model)
+	model.updateAvailable = "v1.2.3"
+
+	line := stripANSI(strings.Split(model.headerView(), "\n")[0])
+	if !strings.Contains(line, "reminders due: 3 — search is:due") || !strings.Contains(line, "v1.2.3") {
+		t.Errorf("expected reminder and update notices in header, got: %s", line)
+	}
+}
+
 // TestHeaderUpdateNoticeNarrowTerminal verifies update notice is omitted when terminal is too narrow.
 func TestHeaderUpdateNoticeNarrowTerminal(t *testing.T) {
 	model := NewBuilder().WithSize(40, 20).Build()
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/wesm/msgvault/internal/query"
 )
 
 // assertHighlight checks that applyHighlight produces the expected plain text
@@ -65,3 +68,56 @@ func TestApplyHighlight(t *testing.T) {
 		})
 	}
 }
+
+func TestLabelTreeKeys(t *testing.T) {
+	rows := func(keys ...string) []query.AggregateRow {
+		r := make([]query.AggregateRow, len(keys))
+		for i, k := range keys {
+			r[i] = query.AggregateRow{Key: k}
+		}
+		return r
+	}
+	tests := []struct {
+		name string
+		rows []query.AggregateRow
+		want []string
+	}{
+		{"flat", rows("INBOX", "SENT"), []string{"INBOX", "SENT"}},
+		{
+			"nested",
+			rows("Clients", "Clients/Acme", "Clients/Acme/Invoices", "Clients/Beta", "INBOX"),
+			[]string{"Clients", "  Acme", "    Invoices", "  Beta", "INBOX"},
+		},
+		{"parent not listed", rows("Clients/Acme", "Clients/Beta"), []string{"Clients/Acme", "Clients/Beta"}},
+		{"name prefix is not a parent", rows("Client", "Clients/Acme"), []string{"Client", "Clients/Acme"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := labelTreeKeys(tt.rows); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("labelTreeKeys() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLabelChips(t *testing.T) {
+	forceColorProfile(t)
+	msg := &query.MessageDetail{
+		Labels:      []string{"INBOX", "Clients/Acme"},
+		LabelColors: map[string]query.LabelColor{"Clients/Acme": {Background: "#4a86e8", Text: "#ffffff"}},
+	}
+
+	got := renderLabelChips(msg)
+	if plain := stripANSI(got); plain != "Labels:  INBOX   Clients/Acme " {
+		t.Errorf("chips text = %q, want padded INBOX and Clients/Acme chips", plain)
+	}
+	if strings.Contains(got, labelChipStyle.Render("Clients/Acme")) {
+		t.Errorf("chips %q draw Clients/Acme without its color", got)
+	}
+	if !strings.Contains(got, labelChipStyle.Render("INBOX")) {
+		t.Errorf("chips %q do not draw INBOX as a plain chip", got)
+	}
+	if detailLabelsLine(msg) != "Labels: INBOX, Clients/Acme" {
+		t.Errorf("detailLabelsLine() = %q", detailLabelsLine(msg))
+	}
+}
@@ -210,10 +210,7 @@ func installFromArchiveTo(archivePath, expectedChecksum, dstPath string, precomp
 		}
 	}
 
-	binaryName := "msgvault"
-	if runtime.GOOS == "windows" {
-		binaryName = "msgvault.exe"
-	}
+	binaryName := fileutil.ExecutableName("msgvault")
 	srcPath := filepath.Join(extractDir, binaryName)
 	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
 		return fmt.Errorf("binary %s not found in archive", binaryName)
@@ -234,10 +231,7 @@ func installFromArchiveWithChecksum(archivePath, expectedChecksum, precomputedCh
 		return fmt.Errorf("resolve symlinks: %w", err)
 	}
 	binDir := filepath.Dir(currentExe)
-	binaryName := "msgvault"
-	if runtime.GOOS == "windows" {
-		binaryName = "msgvault.exe"
-	}
+	binaryName := fileutil.ExecutableName("msgvault")
 	dstPath := filepath.Join(binDir, binaryName)
 
 	return installFromArchiveTo(archivePath, expectedChecksum, dstPath, precomputedChecksum)
@@ -293,7 +287,7 @@ func installBinaryTo(srcPath, dstPath string) error {
 }
 
 func getCacheDir() string {
-	return config.DefaultHome()
+	return config.DefaultDirs().Cache
 }
 
 func fetchLatestRelease() (*Release, error) {
@@ -0,0 +1,93 @@
+package userservice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnit renders spec as a systemd user unit.
+func systemdUnit(spec Spec) string {
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=msgvault daemon (scheduled syncs)\n")
+	b.WriteString("After=network-online.target\n")
+	b.WriteString("Wants=network-online.target\n\n")
+	b.WriteString("[Service]\n")
+	b.WriteString("Type=simple\n")
+	words := make([]string, 0, len(spec.Args)+1)
+	for _, w := range append([]string{spec.Exe}, spec.Args...) {
+		// ExecStart expands $VAR; Environment does not.
+		words = append(words, systemdQuote(strings.ReplaceAll(w, "$", "$$")))
+	}
+	b.WriteString("ExecStart=" + strings.Join(words, " ") + "\n")
+	for _, k := range sortedKeys(spec.Env) {
+		b.WriteString("Environment=" + systemdQuote(k+"="+spec.Env[k]) + "\n")
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=30\n\n")
+	b.WriteString("[Install]\n")
+	b.WriteString("WantedBy=default.target\n")
+	return b.String()
+}
+
+// systemdQuote double-quotes s for a unit file, escaping quotes,
+// backslashes, and the % of unit specifiers.
+func systemdQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// launchdPlist renders spec as a launchd agent property list.
+func launchdPlist(spec Spec) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+`)
+	key := func(k string) { b.WriteString("\t<key>" + plistEscape(k) + "</key>\n") }
+	str := func(indent, v string) { b.WriteString(indent + "<string>" + plistEscape(v) + "</string>\n") }
+
+	key("Label")
+	str("\t", Label)
+	key("ProgramArguments")
+	b.WriteString("\t<array>\n")
+	for _, w := range append([]string{spec.Exe}, spec.Args...) {
+		str("\t\t", w)
+	}
+	b.WriteString("\t</array>\n")
+	if len(spec.Env) > 0 {
+		key("EnvironmentVariables")
+		b.WriteString("\t<dict>\n")
+		for _, k := range sortedKeys(spec.Env) {
+			b.WriteString("\t\t<key>" + plistEscape(k) + "</key>\n")
+			str("\t\t", spec.Env[k])
+		}
+		b.WriteString("\t</dict>\n")
+	}
+	key("RunAtLoad")
+	b.WriteString("\t<true/>\n")
+	// Restart after a crash, but not after a clean exit.
+	key("KeepAlive")
+	b.WriteString("\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	key("ThrottleInterval")
+	b.WriteString("\t<integer>30</integer>\n")
+	key("ProcessType")
+	str("\t", "Background")
+	if spec.LogDir != "" {
+		key("StandardOutPath")
+		str("\t", filepath.Join(spec.LogDir, "daemon.out.log"))
+		key("StandardErrorPath")
+		str("\t", filepath.Join(spec.LogDir, "daemon.err.log"))
+	}
+	b.WriteString("</dict>\n</plist>\n")
+	return b.String()
+}
+
+func plistEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
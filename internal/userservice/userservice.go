@@ -0,0 +1,256 @@
+// Package userservice installs the msgvault daemon as a background
+// service of the current user: a systemd user unit on Linux and a
+// launchd agent on macOS. On Windows use package winservice instead.
+package userservice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/wesm/msgvault/internal/fileutil"
+)
+
+const (
+	// UnitName is the name of the systemd user unit.
+	UnitName = "msgvault.service"
+	// Label is the label of the launchd agent.
+	Label = "io.msgvault.daemon"
+)
+
+// ErrUnsupported is returned on platforms without systemd or launchd.
+var ErrUnsupported = errors.New("user services are only available with systemd (Linux) and launchd (macOS)")
+
+// ErrNotInstalled is returned by Uninstall when no service is installed.
+var ErrNotInstalled = errors.New("the daemon service is not installed")
+
+// Spec describes the service to install.
+type Spec struct {
+	Exe  string
+	Args []string
+	// Env is written into the unit file, which is readable only by
+	// the user.
+	Env map[string]string
+	// LogDir receives the daemon's output under launchd. systemd sends
+	// it to the journal.
+	LogDir string
+}
+
+// Status reports whether the service is installed and running.
+type Status struct {
+	Manager   string `json:"manager"`
+	Path      string `json:"path"`
+	Installed bool   `json:"installed"`
+	Active    bool   `json:"active"`
+	// State is the service manager's word for the service's state,
+	// such as "active", "inactive", or "running".
+	State string `json:"state,omitempty"`
+}
+
+// Manager installs the service with the platform's service manager.
+type Manager struct {
+	GOOS    string
+	HomeDir string
+	UID     int
+	// Run runs a service manager command and returns its combined
+	// output.
+	Run func(name string, args ...string) ([]byte, error)
+}
+
+// New returns a Manager for the current user and platform.
+func New() (*Manager, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("find home directory: %w", err)
+	}
+	return &Manager{
+		GOOS:    runtime.GOOS,
+		HomeDir: home,
+		UID:     os.Getuid(),
+		Run: func(name string, args ...string) ([]byte, error) {
+			return exec.Command(name, args...).CombinedOutput()
+		},
+	}, nil
+}
+
+// Name returns the name of the platform's service manager.
+func (m *Manager) Name() string {
+	switch m.GOOS {
+	case "linux":
+		return "systemd"
+	case "darwin":
+		return "launchd"
+	}
+	return ""
+}
+
+// Path returns where the unit file or agent plist is installed.
+func (m *Manager) Path() (string, error) {
+	switch m.GOOS {
+	case "linux":
+		dir := os.Getenv("XDG_CONFIG_HOME")
+		if dir == "" {
+			dir = filepath.Join(m.HomeDir, ".config")
+		}
+		return filepath.Join(dir, "systemd", "user", UnitName), nil
+	case "darwin":
+		return filepath.Join(m.HomeDir, "Library", "LaunchAgents", Label+".plist"), nil
+	}
+	return "", ErrUnsupported
+}
+
+// Render returns the unit file or agent plist for spec.
+func (m *Manager) Render(spec Spec) ([]byte, error) {
+	switch m.GOOS {
+	case "linux":
+		return []byte(systemdUnit(spec)), nil
+	case "darwin":
+		return []byte(launchdPlist(spec)), nil
+	}
+	return nil, ErrUnsupported
+}
+
+// Install writes the service for spec, replacing any installed one,
+// and starts it. It returns the path written.
+func (m *Manager) Install(spec Spec) (string, error) {
+	path, err := m.Path()
+	if err != nil {
+		return "", err
+	}
+	data, err := m.Render(spec)
+	if err != nil {
+		return "", err
+	}
+	if err := fileutil.SecureMkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", fmt.Errorf("create %s: %w", filepath.Dir(path), err)
+	}
+	if spec.LogDir != "" && m.GOOS == "darwin" {
+		if err := fileutil.SecureMkdirAll(spec.LogDir, 0700); err != nil {
+			return "", fmt.Errorf("create log directory: %w", err)
+		}
+	}
+	if m.GOOS == "darwin" {
+		// A loaded agent keeps its old definition until booted out.
+		_, _ = m.Run("launchctl", "bootout", m.target())
+	}
+	if err := fileutil.SecureWriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	// WriteFile keeps the mode of a file it replaces.
+	if err := fileutil.SecureChmod(path, 0600); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	switch m.GOOS {
+	case "linux":
+		if err := m.run("systemctl", "--user", "daemon-reload"); err != nil {
+			return path, err
+		}
+		// restart, not start, so a reinstall picks up the new unit.
+		if err := m.run("systemctl", "--user", "enable", UnitName); err != nil {
+			return path, err
+		}
+		return path, m.run("systemctl", "--user", "restart", UnitName)
+	default:
+		return path, m.run("launchctl", "bootstrap", fmt.Sprintf("gui/%d", m.UID), path)
+	}
+}
+
+// Uninstall stops the service and removes its file.
+func (m *Manager) Uninstall() error {
+	path, err := m.Path()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return ErrNotInstalled
+	}
+	switch m.GOOS {
+	case "linux":
+		if err := m.run("systemctl", "--user", "disable", "--now", UnitName); err != nil {
+			return err
+		}
+	default:
+		// bootout fails when the agent isn't loaded, which is fine.
+		_, _ = m.Run("launchctl", "bootout", m.target())
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("remove %s: %w", path, err)
+	}
+	if m.GOOS == "linux" {
+		return m.run("systemctl", "--user", "daemon-reload")
+	}
+	return nil
+}
+
+// Status reports whether the service is installed and running.
+func (m *Manager) Status() (Status, error) {
+	path, err := m.Path()
+	if err != nil {
+		return Status{}, err
+	}
+	st := Status{Manager: m.Name(), Path: path}
+	if _, err := os.Stat(path); err == nil {
+		st.Installed = true
+	} else if !os.IsNotExist(err) {
+		return st, fmt.Errorf("check %s: %w", path, err)
+	}
+	switch m.GOOS {
+	case "linux":
+		// is-active exits non-zero for anything but active; its output
+		// still names the state.
+		out, _ := m.Run("systemctl", "--user", "is-active", UnitName)
+		st.State = strings.TrimSpace(string(out))
+		st.Active = st.State == "active"
+	default:
+		out, err := m.Run("launchctl", "print", m.target())
+		if err != nil {
+			if st.Installed {
+				st.State = "not loaded"
+			}
+			return st, nil
+		}
+		st.State = launchdState(string(out))
+		st.Active = st.State == "running"
+	}
+	return st, nil
+}
+
+func (m *Manager) target() string {
+	return fmt.Sprintf("gui/%d/%s", m.UID, Label)
+}
+
+func (m *Manager) run(name string, args ...string) error {
+	out, err := m.Run(name, args...)
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if msg == "" {
+			return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+		}
+		return fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, msg)
+	}
+	return nil
+}
+
+// launchdState returns the state 'launchctl print' reports.
+func launchdState(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(line), " = "); ok && k == "state" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
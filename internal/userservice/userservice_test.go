@@ -0,0 +1,214 @@
+package userservice
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeManager returns a Manager for goos that records the commands it
+// runs instead of running them; fail makes the named command fail.
+func fakeManager(t *testing.T, goos string, output string, fail string) (*Manager, *[]string) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", "")
+	var ran []string
+	return &Manager{
+		GOOS:    goos,
+		HomeDir: t.TempDir(),
+		UID:     501,
+		Run: func(name string, args ...string) ([]byte, error) {
+			cmd := strings.Join(append([]string{name}, args...), " ")
+			ran = append(ran, cmd)
+			if fail != "" && strings.HasPrefix(cmd, fail) {
+				return []byte(output), errors.New("exit status 3")
+			}
+			return []byte(output), nil
+		},
+	}, &ran
+}
+
+var testSpec = Spec{
+	Exe:    "/opt/msgvault/bin/msgvault",
+	Args:   []string{"--home", "/home/alice/.msgvault", "daemon"},
+	Env:    map[string]string{"PATH": "/usr/bin", "MSGVAULT_KEY": `s3cr%t"$x`},
+	LogDir: "/home/alice/.msgvault/logs",
+}
+
+func TestManager_Path(t *testing.T) {
+	m, _ := fakeManager(t, "linux", "", "")
+	path, err := m.Path()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(m.HomeDir, ".config", "systemd", "user", UnitName); path != want {
+		t.Errorf("linux path = %q, want %q", path, want)
+	}
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	if path, _ := m.Path(); path != filepath.Join(xdg, "systemd", "user", UnitName) {
+		t.Errorf("linux path with XDG_CONFIG_HOME = %q", path)
+	}
+
+	m.GOOS = "darwin"
+	if path, _ := m.Path(); path != filepath.Join(m.HomeDir, "Library", "LaunchAgents", Label+".plist") {
+		t.Errorf("darwin path = %q", path)
+	}
+
+	m.GOOS = "windows"
+	if _, err := m.Path(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("windows path error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit(testSpec)
+	for _, want := range []string{
+		`ExecStart="/opt/msgvault/bin/msgvault" "--home" "/home/alice/.msgvault" "daemon"`,
+		`Environment="MSGVAULT_KEY=s3cr%%t\"$x"`,
+		`Environment="PATH=/usr/bin"`,
+		"Restart=on-failure",
+		"WantedBy=default.target",
+	} {
+		if !strings.Contains(unit, want) {
+			t.Errorf("unit missing %q:\n%s", want, unit)
+		}
+	}
+	if strings.Index(unit, "MSGVAULT_KEY") > strings.Index(unit, "PATH=") {
+		t.Error("environment not sorted by name")
+	}
+
+	spec := testSpec
+	spec.Args = []string{"--home", "/srv/$vault"}
+	if unit := systemdUnit(spec); !strings.Contains(unit, `"/srv/$$vault"`) {
+		t.Errorf("ExecStart does not escape $:\n%s", unit)
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	spec := testSpec
+	spec.Env = map[string]string{"MSGVAULT_KEY": "a<b&c"}
+	plist := launchdPlist(spec)
+	for _, want := range []string{
+		"<string>" + Label + "</string>",
+		"\t\t<string>/opt/msgvault/bin/msgvault</string>\n\t\t<string>--home</string>",
+		"<key>MSGVAULT_KEY</key>\n\t\t<string>a&lt;b&amp;c</string>",
+		"<key>RunAtLoad</key>\n\t<true/>",
+		"<string>/home/alice/.msgvault/logs/daemon.err.log</string>",
+	} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("plist missing %q:\n%s", want, plist)
+		}
+	}
+
+	spec.Env, spec.LogDir = nil, ""
+	plist = launchdPlist(spec)
+	if strings.Contains(plist, "EnvironmentVariables") || strings.Contains(plist, "StandardOutPath") {
+		t.Errorf("plist has empty sections:\n%s", plist)
+	}
+}
+
+func TestManager_InstallUninstall(t *testing.T) {
+	tests := []struct {
+		goos      string
+		install   []string
+		uninstall []string
+	}{
+		{"linux",
+			[]string{"systemctl --user daemon-reload", "systemctl --user enable msgvault.service", "systemctl --user restart msgvault.service"},
+			[]string{"systemctl --user disable --now msgvault.service", "systemctl --user daemon-reload"}},
+		{"darwin",
+			[]string{"launchctl bootout gui/501/" + Label, "launchctl bootstrap gui/501 "},
+			[]string{"launchctl bootout gui/501/" + Label}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			m, ran := fakeManager(t, tt.goos, "", "")
+			spec := testSpec
+			spec.LogDir = filepath.Join(m.HomeDir, "logs")
+			path, err := m.Install(spec)
+			if err != nil {
+				t.Fatalf("Install: %v", err)
+			}
+			if tt.goos == "darwin" {
+				tt.install[1] += path
+			}
+			if !slices.Equal(*ran, tt.install) {
+				t.Errorf("install ran %q, want %q", *ran, tt.install)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("stat unit: %v", err)
+			}
+			if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+				t.Errorf("unit mode = %v, want 0600", info.Mode().Perm())
+			}
+
+			*ran = nil
+			if err := m.Uninstall(); err != nil {
+				t.Fatalf("Uninstall: %v", err)
+			}
+			if !slices.Equal(*ran, tt.uninstall) {
+				t.Errorf("uninstall ran %q, want %q", *ran, tt.uninstall)
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("unit still exists after Uninstall: %v", err)
+			}
+			if err := m.Uninstall(); !errors.Is(err, ErrNotInstalled) {
+				t.Errorf("second Uninstall error = %v, want ErrNotInstalled", err)
+			}
+		})
+	}
+}
+
+func TestManager_InstallReportsCommandOutput(t *testing.T) {
+	m, _ := fakeManager(t, "linux", "Failed to connect to bus", "systemctl --user daemon-reload")
+	_, err := m.Install(testSpec)
+	if err == nil || !strings.Contains(err.Error(), "Failed to connect to bus") {
+		t.Errorf("Install error = %v, want the command's output", err)
+	}
+}
+
+func TestManager_Status(t *testing.T) {
+	tests := []struct {
+		name    string
+		goos    string
+		install bool
+		output  string
+		fail    string
+		want    Status
+	}{
+		{"systemd active", "linux", true, "active\n", "", Status{Manager: "systemd", Installed: true, Active: true, State: "active"}},
+		{"systemd stopped", "linux", true, "inactive\n", "systemctl", Status{Manager: "systemd", Installed: true, State: "inactive"}},
+		{"systemd absent", "linux", false, "inactive\n", "systemctl", Status{Manager: "systemd", State: "inactive"}},
+		{"launchd running", "darwin", true, "gui/501/io.msgvault.daemon = {\n\tstate = running\n\tpid = 42\n}\n", "", Status{Manager: "launchd", Installed: true, Active: true, State: "running"}},
+		{"launchd not loaded", "darwin", true, "Could not find service", "launchctl", Status{Manager: "launchd", Installed: true, State: "not loaded"}},
+		{"launchd absent", "darwin", false, "Could not find service", "launchctl", Status{Manager: "launchd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, _ := fakeManager(t, tt.goos, tt.output, tt.fail)
+			path, _ := m.Path()
+			if tt.install {
+				if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("unit"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := m.Status()
+			if err != nil {
+				t.Fatalf("Status: %v", err)
+			}
+			tt.want.Path = path
+			if got != tt.want {
+				t.Errorf("Status = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
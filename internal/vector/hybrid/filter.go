@@ -3,6 +3,7 @@ package hybrid
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -33,6 +34,15 @@ func BuildFilter(ctx context.Context, db *sql.DB, q *search.Query) (vector.Filte
 	if q == nil {
 		return f, nil
 	}
+	if q.HasBoolean() {
+		return f, errors.New("OR and negation are not supported in vector search; use --mode fts")
+	}
+	if len(q.Regexes) > 0 {
+		return f, errors.New("regex: is not supported in vector search; use --mode fts")
+	}
+	if len(q.AttachmentTerms) > 0 {
+		return f, errors.New("in:attachments is not supported in vector search; use --mode fts")
+	}
 
 	groupFilters := []struct {
 		addrs []string
@@ -161,11 +171,11 @@ func resolveParticipantIDs(ctx context.Context, db *sql.DB, addrs []string) ([]i
 	return ids, nil
 }
 
-// resolveLabelIDs returns labels whose name contains any of the
-// supplied tokens as a case-insensitive substring. Mirrors the
-// `label:` behavior in internal/store/api.go (LOWER(l.name) LIKE
-// '%token%' ESCAPE '\') so vector/hybrid search agrees with the FTS
-// path on which label matches a user-supplied token.
+// resolveLabelIDs returns labels matching any of the supplied tokens
+// case-insensitively, by substring or, for "Parent/**" and
+// "Parent/*", by hierarchy (search.LabelPattern). Mirrors the `label:`
+// behavior in internal/store/api.go so vector/hybrid search agrees
+// with the FTS path on which label matches a user-supplied token.
 func resolveLabelIDs(ctx context.Context, db *sql.DB, labels []string) ([]int64, error) {
 	if len(labels) == 0 {
 		return nil, nil
@@ -173,8 +183,11 @@ func resolveLabelIDs(ctx context.Context, db *sql.DB, labels []string) ([]int64,
 	parts := make([]string, 0, len(labels))
 	args := make([]any, 0, len(labels))
 	for _, l := range labels {
-		parts = append(parts, `LOWER(name) LIKE ? ESCAPE '\'`)
-		args = append(args, "%"+escapeLike(strings.ToLower(l))+"%")
+		cond, condArgs := search.ParseLabelPattern(strings.ToLower(l)).SQL("name", func(col string) string {
+			return `LOWER(` + col + `) LIKE ? ESCAPE '\'`
+		})
+		parts = append(parts, cond)
+		args = append(args, condArgs...)
 	}
 	q := fmt.Sprintf(
 		`SELECT id FROM labels WHERE %s`,
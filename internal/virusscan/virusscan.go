@@ -0,0 +1,201 @@
+// Package virusscan runs an external virus scanner, such as clamdscan,
+// over stored attachment files and records its verdicts.
+package virusscan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wesm/msgvault/internal/export"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// scanTimeout bounds the scan of one file.
+const scanTimeout = 5 * time.Minute
+
+// Verdict is the scanner's judgement of one file.
+type Verdict struct {
+	Result    string // store.ScanClean, store.ScanInfected, or store.ScanError
+	Signature string // what was found, or why the scan failed
+}
+
+// Scanner runs a scanner command on files.
+type Scanner struct {
+	command []string
+}
+
+// New returns a scanner that runs command with the path of each file
+// appended. The command must exit 0 for a clean file and 1 for an
+// infected one, as clamscan and clamdscan do; any other exit status is
+// a scan error.
+func New(command []string) (*Scanner, error) {
+	if len(command) == 0 || strings.TrimSpace(command[0]) == "" {
+		return nil, errors.New("no scanner command configured")
+	}
+	return &Scanner{command: command}, nil
+}
+
+// Scan scans one file. It returns an error only when the scanner
+// cannot be run at all; a scanner that runs but fails to scan the file
+// gives a store.ScanError verdict.
+func (s *Scanner) Scan(ctx context.Context, path string) (Verdict, error) {
+	ctx, cancel := context.WithTimeout(ctx, scanTimeout)
+	defer cancel()
+
+	args := append(append([]string(nil), s.command[1:]...), path)
+	cmd := exec.CommandContext(ctx, s.command[0], args...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return Verdict{Result: store.ScanClean}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return Verdict{Result: store.ScanInfected, Signature: signature(out.String())}, nil
+	case errors.As(err, &exitErr):
+		if ctx.Err() != nil {
+			return Verdict{Result: store.ScanError, Signature: "scan timed out"}, nil
+		}
+		return Verdict{Result: store.ScanError, Signature: firstLine(out.String())}, nil
+	default:
+		return Verdict{}, fmt.Errorf("run %s: %w", s.command[0], err)
+	}
+}
+
+// signature extracts the malware name from clamscan-style output,
+// "<path>: <name> FOUND", falling back to the first line of output.
+func signature(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if rest, ok := strings.CutSuffix(line, " FOUND"); ok {
+			if i := strings.LastIndex(rest, ": "); i >= 0 {
+				return rest[i+2:]
+			}
+			return rest
+		}
+	}
+	return firstLine(output)
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// Stats counts what Run did.
+type Stats struct {
+	Scanned  int `json:"scanned"`
+	Infected int `json:"infected"`
+	Errors   int `json:"errors"`
+}
+
+// Options control Run.
+type Options struct {
+	// Rescan scans files that already have a verdict again, as after
+	// the scanner's signatures are updated.
+	Rescan bool
+	// Quarantine blocks infected files from export.
+	Quarantine bool
+	// Progress, if not nil, is called after each file.
+	Progress func(done, total int)
+}
+
+// Run scans the attachment files stored under attachmentsDir that have
+// no verdict yet, or all of them with opts.Rescan, and records the
+// verdicts in s. Each infected file is logged.
+func Run(ctx context.Context, s *store.Store, attachmentsDir string, sc *Scanner, opts Options, logger *slog.Logger) (Stats, error) {
+	var stats Stats
+	paths, err := s.AttachmentFilesToScan(opts.Rescan)
+	if err != nil {
+		return stats, err
+	}
+	for i, rel := range paths {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		full := filepath.Join(attachmentsDir, filepath.FromSlash(rel))
+		var v Verdict
+		if _, err := os.Stat(full); err != nil {
+			v = Verdict{Result: store.ScanError, Signature: "file not stored"}
+		} else if v, err = sc.scanStored(ctx, full); err != nil {
+			return stats, err
+		}
+		if err := s.SetAttachmentScanResult(rel, v.Result, v.Signature, opts.Quarantine); err != nil {
+			return stats, err
+		}
+		stats.Scanned++
+		switch v.Result {
+		case store.ScanInfected:
+			stats.Infected++
+			logger.Warn("infected attachment", "path", rel, "signature", v.Signature,
+				"quarantined", opts.Quarantine)
+		case store.ScanError:
+			stats.Errors++
+			logger.Debug("attachment scan failed", "path", rel, "reason", v.Signature)
+		}
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(paths))
+		}
+	}
+	return stats, nil
+}
+
+// scanStored scans a stored attachment file. A compressed one is
+// decompressed beside itself for the scanner, which reads it with the
+// same permissions, and the copy removed afterwards.
+func (s *Scanner) scanStored(ctx context.Context, full string) (Verdict, error) {
+	if !export.IsCompressedPath(full) {
+		return s.Scan(ctx, full)
+	}
+	src, err := export.OpenAttachmentPath(full)
+	if err != nil {
+		return Verdict{Result: store.ScanError, Signature: firstLine(err.Error())}, nil
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp, err := os.CreateTemp(filepath.Dir(full), filepath.Base(full)+".scan.")
+	if err != nil {
+		return Verdict{}, fmt.Errorf("decompress for scan: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return Verdict{}, fmt.Errorf("decompress for scan: %w", err)
+	}
+	return s.Scan(ctx, tmp.Name())
+}
+
+// PostSyncHook returns a scheduler hook that scans the attachments each
+// sync stored. Failures are logged; they do not fail the sync.
+func PostSyncHook(s *store.Store, attachmentsDir string, sc *Scanner, quarantine bool, logger *slog.Logger) func(ctx context.Context, account string) {
+	var mu sync.Mutex
+	return func(ctx context.Context, account string) {
+		mu.Lock()
+		defer mu.Unlock()
+		stats, err := Run(ctx, s, attachmentsDir, sc, Options{Quarantine: quarantine}, logger)
+		if err != nil {
+			logger.Error("virus scan failed", "account", account, "error", err)
+			return
+		}
+		if stats.Scanned > 0 {
+			logger.Info("attachments scanned", "account", account,
+				"scanned", stats.Scanned, "infected", stats.Infected, "errors", stats.Errors)
+		}
+	}
+}
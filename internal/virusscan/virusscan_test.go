@@ -0,0 +1,139 @@
+package virusscan
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/wesm/msgvault/internal/store"
+	"github.com/wesm/msgvault/internal/testutil"
+	"github.com/wesm/msgvault/internal/testutil/storetest"
+)
+
+// fakeScanner behaves like clamscan: files containing EICAR are
+// infected, files containing BROKEN cannot be scanned.
+var fakeScanner = []string{"sh", "-c", `
+if grep -q BROKEN "$1"; then echo "$1: Can't read file ERROR"; exit 2; fi
+if grep -q EICAR "$1"; then echo "$1: Eicar-Signature FOUND"; exit 1; fi
+echo "$1: OK"`, "scanner"}
+
+func skipWithoutShell(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake scanner needs a POSIX shell")
+	}
+}
+
+func TestScanner_Scan(t *testing.T) {
+	skipWithoutShell(t)
+	dir := t.TempDir()
+	sc, err := New(fakeScanner)
+	testutil.MustNoErr(t, err, "New")
+
+	tests := []struct {
+		name    string
+		content string
+		want    Verdict
+	}{
+		{"clean", "hello", Verdict{Result: store.ScanClean}},
+		{"infected", "X5O EICAR test", Verdict{Result: store.ScanInfected, Signature: "Eicar-Signature"}},
+		{"error", "BROKEN", Verdict{Result: store.ScanError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			testutil.MustNoErr(t, os.WriteFile(path, []byte(tt.content), 0o600), "write file")
+			got, err := sc.Scan(context.Background(), path)
+			testutil.MustNoErr(t, err, "Scan")
+			if got.Result != tt.want.Result {
+				t.Errorf("Result = %q, want %q", got.Result, tt.want.Result)
+			}
+			if tt.want.Signature != "" && got.Signature != tt.want.Signature {
+				t.Errorf("Signature = %q, want %q", got.Signature, tt.want.Signature)
+			}
+			if got.Result == store.ScanError && got.Signature == "" {
+				t.Error("a scan error should say why")
+			}
+		})
+	}
+
+	if _, err := New(nil); err == nil {
+		t.Error("New without a command should fail")
+	}
+	missing, err := New([]string{filepath.Join(dir, "no-such-scanner")})
+	testutil.MustNoErr(t, err, "New")
+	if _, err := missing.Scan(context.Background(), filepath.Join(dir, "clean")); err == nil {
+		t.Error("Scan with a missing scanner should fail")
+	}
+}
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		output string
+		want   string
+	}{
+		{"/tmp/ab/abcd: Win.Test.EICAR_HDB-1 FOUND\n", "Win.Test.EICAR_HDB-1"},
+		{"C:\\vault\\ab: Eicar FOUND", "Eicar"},
+		{"\nsomething else\n", "something else"},
+	}
+	for _, tt := range tests {
+		if got := signature(tt.output); got != tt.want {
+			t.Errorf("signature(%q) = %q, want %q", tt.output, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	skipWithoutShell(t)
+	f := storetest.New(t)
+	dir := t.TempDir()
+	ids := f.CreateMessages(3)
+
+	files := map[string]string{"aa/clean": "hello", "bb/eicar": "EICAR", "cc/missing": ""}
+	for rel, content := range files {
+		if content == "" {
+			continue
+		}
+		full := filepath.Join(dir, filepath.FromSlash(rel))
+		testutil.MustNoErr(t, os.MkdirAll(filepath.Dir(full), 0o700), "mkdir")
+		testutil.MustNoErr(t, os.WriteFile(full, []byte(content), 0o600), "write file")
+	}
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[0], "notes.txt", "text/plain", "aa/clean", "aaaa", 5), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[1], "invoice.exe", "application/octet-stream", "bb/eicar", "bbbb", 5), "UpsertAttachment")
+	// The same infected file attached to another message is scanned once.
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "copy.exe", "application/octet-stream", "bb/eicar", "bbbb", 5), "UpsertAttachment")
+	testutil.MustNoErr(t, f.Store.UpsertAttachment(ids[2], "gone.pdf", "application/pdf", "cc/missing", "cccc", 5), "UpsertAttachment")
+
+	sc, err := New(fakeScanner)
+	testutil.MustNoErr(t, err, "New")
+	stats, err := Run(context.Background(), f.Store, dir, sc, Options{Quarantine: true}, slog.Default())
+	testutil.MustNoErr(t, err, "Run")
+	if stats != (Stats{Scanned: 3, Infected: 1, Errors: 1}) {
+		t.Errorf("Run = %+v, want 3 scanned, 1 infected, 1 error", stats)
+	}
+
+	infected, err := f.Store.InfectedAttachments()
+	testutil.MustNoErr(t, err, "InfectedAttachments")
+	if len(infected) != 2 || infected[0].Signature != "Eicar-Signature" || !infected[0].Quarantined {
+		t.Errorf("InfectedAttachments = %+v, want both copies quarantined", infected)
+	}
+
+	// Files with a verdict are not scanned again unless asked.
+	stats, err = Run(context.Background(), f.Store, dir, sc, Options{}, slog.Default())
+	testutil.MustNoErr(t, err, "second Run")
+	if stats.Scanned != 0 {
+		t.Errorf("second Run scanned %d files, want 0", stats.Scanned)
+	}
+	stats, err = Run(context.Background(), f.Store, dir, sc, Options{Rescan: true}, slog.Default())
+	testutil.MustNoErr(t, err, "rescan")
+	if stats.Scanned != 3 {
+		t.Errorf("rescan scanned %d files, want 3", stats.Scanned)
+	}
+	// Without quarantine, a rescan leaves the file exportable.
+	if q, err := f.Store.AttachmentQuarantined("bbbb"); err != nil || q {
+		t.Errorf("AttachmentQuarantined = %v, %v; want false after rescan without quarantine", q, err)
+	}
+}
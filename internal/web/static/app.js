@@ -0,0 +1,206 @@
+// msgvault web UI. Talks to /api/v1 with the API key stored in
+// localStorage. All message content is inserted with textContent so
+// archived mail can never inject markup or script into the page.
+"use strict";
+
+(function () {
+  const KEY_STORAGE = "msgvault.apiKey";
+  const PAGE_SIZE = 25;
+
+  const $ = (id) => document.getElementById(id);
+  const state = { query: "", page: 1, total: 0 };
+
+  function apiKey() {
+    return localStorage.getItem(KEY_STORAGE) || "";
+  }
+
+  async function api(path) {
+    const headers = {};
+    const key = apiKey();
+    if (key) headers["X-API-Key"] = key;
+    const resp = await fetch("/api/v1" + path, { headers });
+    if (resp.status === 401) {
+      openKeyDialog();
+      throw new Error("Unauthorized: set the API key and retry.");
+    }
+    if (!resp.ok) {
+      let msg = resp.status + " " + resp.statusText;
+      try {
+        const body = await resp.json();
+        if (body.message) msg = body.message;
+      } catch (_) {
+        // Non-JSON error body; keep the status line.
+      }
+      throw new Error(msg);
+    }
+    return resp;
+  }
+
+  function el(tag, className, text) {
+    const node = document.createElement(tag);
+    if (className) node.className = className;
+    if (text !== undefined && text !== null) node.textContent = text;
+    return node;
+  }
+
+  function formatDate(iso) {
+    if (!iso) return "";
+    const d = new Date(iso);
+    return isNaN(d) ? iso : d.toLocaleString();
+  }
+
+  function formatSize(bytes) {
+    if (bytes < 1024) return bytes + " B";
+    if (bytes < 1024 * 1024) return (bytes / 1024).toFixed(1) + " KB";
+    return (bytes / (1024 * 1024)).toFixed(1) + " MB";
+  }
+
+  function setStatus(text, isError) {
+    const status = $("status");
+    status.textContent = text;
+    status.className = isError ? "error" : "muted";
+    status.hidden = !text;
+  }
+
+  function showResults() {
+    $("thread").hidden = true;
+    $("results").hidden = false;
+  }
+
+  function renderRow(m) {
+    const li = el("li");
+    li.tabIndex = 0;
+    const head = el("div", "row-head");
+    head.append(el("span", "row-from", m.from), el("span", "row-date", formatDate(m.sent_at)));
+    const subject = el("div", "row-subject", m.subject || "(no subject)");
+    if (m.has_attachments) subject.append(el("span", "label", "attachment"));
+    li.append(head, subject, el("div", "row-snippet", m.snippet));
+    const open = () => openThread(m);
+    li.addEventListener("click", open);
+    li.addEventListener("keydown", (e) => {
+      if (e.key === "Enter") open();
+    });
+    return li;
+  }
+
+  async function search(page) {
+    if (!state.query) return;
+    state.page = page;
+    showResults();
+    setStatus("Searching…");
+    const list = $("result-list");
+    list.replaceChildren();
+    $("pager").hidden = true;
+    try {
+      const params = new URLSearchParams({ q: state.query, page: String(page), page_size: String(PAGE_SIZE) });
+      const data = await (await api("/search?" + params)).json();
+      state.total = data.total || 0;
+      const messages = data.messages || [];
+      if (messages.length === 0) {
+        setStatus("No messages found.");
+        return;
+      }
+      setStatus("");
+      messages.forEach((m) => list.append(renderRow(m)));
+      const pages = Math.max(1, Math.ceil(state.total / PAGE_SIZE));
+      $("page-info").textContent = "Page " + page + " of " + pages + " (" + state.total + " messages)";
+      $("prev-page").disabled = page <= 1;
+      $("next-page").disabled = page >= pages;
+      $("pager").hidden = pages <= 1;
+    } catch (err) {
+      setStatus(err.message, true);
+    }
+  }
+
+  async function downloadAttachment(att) {
+    try {
+      const blob = await (await api("/attachments/" + att.id)).blob();
+      const url = URL.createObjectURL(blob);
+      const a = el("a");
+      a.href = url;
+      a.download = att.filename || "attachment";
+      document.body.append(a);
+      a.click();
+      a.remove();
+      setTimeout(() => URL.revokeObjectURL(url), 1000);
+    } catch (err) {
+      alert("Download failed: " + err.message);
+    }
+  }
+
+  function renderMessage(detail) {
+    const box = el("article", "message");
+    const head = el("div", "message-head");
+    head.append(el("div", null, "From: " + detail.from));
+    if (detail.to && detail.to.length) head.append(el("div", null, "To: " + detail.to.join(", ")));
+    if (detail.cc && detail.cc.length) head.append(el("div", null, "Cc: " + detail.cc.join(", ")));
+    const date = el("div", null, "Date: " + formatDate(detail.sent_at));
+    (detail.labels || []).forEach((l) => date.append(el("span", "label", l)));
+    head.append(date);
+    box.append(head, el("div", "message-body", detail.body || "(no body)"));
+    const atts = detail.attachments || [];
+    if (atts.length) {
+      const wrap = el("div", "attachments");
+      atts.forEach((att) => {
+        const b = el("button", null, att.filename + " (" + formatSize(att.size_bytes) + ")");
+        b.type = "button";
+        b.disabled = !att.id;
+        b.addEventListener("click", () => downloadAttachment(att));
+        wrap.append(b);
+      });
+      box.append(wrap);
+    }
+    return box;
+  }
+
+  async function openThread(summary) {
+    $("results").hidden = true;
+    $("thread").hidden = false;
+    $("thread-subject").textContent = summary.subject || "(no subject)";
+    const container = $("thread-messages");
+    container.replaceChildren(el("p", "muted", "Loading…"));
+    try {
+      let ids = [summary.id];
+      if (summary.conversation_id) {
+        const params = new URLSearchParams({ conversation_id: String(summary.conversation_id) });
+        try {
+          const data = await (await api("/messages/filter?" + params)).json();
+          const thread = (data.messages || []).slice();
+          thread.sort((a, b) => (a.sent_at < b.sent_at ? -1 : a.sent_at > b.sent_at ? 1 : 0));
+          if (thread.length) ids = thread.map((m) => m.id);
+        } catch (_) {
+          // Thread lookup needs the query engine; fall back to the single message.
+        }
+      }
+      const details = await Promise.all(ids.map(async (id) => (await api("/messages/" + id)).json()));
+      container.replaceChildren(...details.map(renderMessage));
+    } catch (err) {
+      container.replaceChildren(el("p", "error", err.message));
+    }
+  }
+
+  function openKeyDialog() {
+    const dialog = $("key-dialog");
+    if (dialog.open) return;
+    $("key-input").value = apiKey();
+    dialog.showModal();
+  }
+
+  $("key-dialog").addEventListener("close", () => {
+    if ($("key-dialog").returnValue !== "save") return;
+    const key = $("key-input").value.trim();
+    if (key) localStorage.setItem(KEY_STORAGE, key);
+    else localStorage.removeItem(KEY_STORAGE);
+    if (state.query) search(state.page);
+  });
+
+  $("search-form").addEventListener("submit", (e) => {
+    e.preventDefault();
+    state.query = $("search-input").value.trim();
+    search(1);
+  });
+  $("key-button").addEventListener("click", openKeyDialog);
+  $("back-button").addEventListener("click", showResults);
+  $("prev-page").addEventListener("click", () => search(state.page - 1));
+  $("next-page").addEventListener("click", () => search(state.page + 1));
+})();
@@ -0,0 +1,40 @@
+// Package web embeds the browser front-end served by `msgvault serve`.
+//
+// The UI is a static single-page app that talks to the /api/v1 endpoints
+// with the same API key the CLI's remote mode uses; the key is entered
+// once in the browser and kept in localStorage. Serving the assets
+// themselves requires no authentication.
+package web
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// contentSecurityPolicy restricts the UI to same-origin scripts and API
+// calls. Message bodies are rendered as text, never HTML, so no inline
+// script or frame sources are needed.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; " +
+	"img-src 'self' data:; connect-src 'self'; base-uri 'none'; frame-ancestors 'none'; form-action 'self'"
+
+// Handler returns an http.Handler serving the embedded UI. It expects to
+// be mounted with the mount prefix already stripped from the request path.
+func Handler() http.Handler {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		// Only reachable if the embed directive above is changed.
+		panic(err)
+	}
+	files := http.FileServer(http.FS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		w.Header().Set("Cache-Control", "no-cache")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,40 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler(t *testing.T) {
+	h := Handler()
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantType   string
+	}{
+		{"/", http.StatusOK, "text/html"},
+		{"/app.js", http.StatusOK, "javascript"},
+		{"/style.css", http.StatusOK, "text/css"},
+		{"/missing.txt", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if csp := w.Header().Get("Content-Security-Policy"); !strings.Contains(csp, "default-src 'none'") {
+				t.Errorf("Content-Security-Policy = %q, want restrictive default-src", csp)
+			}
+			if tt.wantType != "" && !strings.Contains(w.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", w.Header().Get("Content-Type"), tt.wantType)
+			}
+		})
+	}
+}
@@ -0,0 +1,352 @@
+// Package webhook delivers HTTP notifications when newly synced messages
+// match a configured search query. The daemon registers a Dispatcher as
+// a scheduler post-sync hook; each run looks for messages added since the
+// previous run and POSTs the matches to every configured endpoint.
+package webhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+const (
+	// EventMessagesMatched is the event name sent in payloads and the
+	// X-Msgvault-Event header.
+	EventMessagesMatched = "messages.matched"
+
+	// SignatureHeader carries "sha256=<hex>", the HMAC-SHA256 of
+	// "<timestamp>.<body>" keyed with the webhook secret.
+	SignatureHeader = "X-Msgvault-Signature"
+	// TimestampHeader carries the Unix time the request was signed.
+	TimestampHeader = "X-Msgvault-Timestamp"
+
+	defaultMaxAttempts = 3
+	// batchSize caps the number of messages in a single delivery.
+	batchSize = 100
+	// maxMatchesPerRun bounds how many matches one run collects per
+	// webhook, so a large backfill can't build an unbounded payload set.
+	maxMatchesPerRun = 5000
+)
+
+// Fields lists the message fields a webhook payload may include, in
+// payload order. WebhookConfig.Fields selects a subset; "id" is always
+// sent so receivers can fetch the full message through the API.
+var Fields = []string{
+	"id", "conversation_id", "subject", "from", "to",
+	"sent_at", "snippet", "labels", "has_attachments", "size_bytes",
+}
+
+// Store is the subset of store.Store the dispatcher needs.
+type Store interface {
+	MaxMessageID() (int64, error)
+	SearchMessagesQuery(q *search.Query, offset, limit int) ([]store.APIMessage, int64, error)
+}
+
+// Payload is the JSON body POSTed to a webhook.
+type Payload struct {
+	Event     string           `json:"event"`
+	Webhook   string           `json:"webhook"`
+	Query     string           `json:"query"`
+	Account   string           `json:"account"` // account whose sync triggered the run
+	Timestamp time.Time        `json:"timestamp"`
+	Messages  []map[string]any `json:"messages"`
+}
+
+type hook struct {
+	cfg         config.WebhookConfig
+	query       *search.Query
+	fields      []string
+	maxAttempts int
+}
+
+// Dispatcher finds newly arrived messages and delivers webhook payloads.
+type Dispatcher struct {
+	store  Store
+	hooks  []hook
+	client *http.Client
+	logger *slog.Logger
+
+	// retryDelay is the wait before the second attempt; it doubles
+	// for each further attempt.
+	retryDelay time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex // serializes runs so each message is sent once
+	watermark int64      // highest message ID already considered
+}
+
+// New validates the webhook configs and returns a dispatcher whose
+// watermark starts at the archive's current newest message, so only mail
+// synced after startup triggers deliveries.
+func New(cfgs []config.WebhookConfig, st Store, logger *slog.Logger) (*Dispatcher, error) {
+	d := &Dispatcher{
+		store:      st,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+		retryDelay: time.Second,
+		now:        time.Now,
+	}
+	names := make(map[string]bool)
+	for i, c := range cfgs {
+		h, err := newHook(c)
+		if err != nil {
+			return nil, fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("webhooks[%d]: duplicate name %q", i, c.Name)
+		}
+		names[c.Name] = true
+		d.hooks = append(d.hooks, h)
+	}
+	wm, err := st.MaxMessageID()
+	if err != nil {
+		return nil, err
+	}
+	d.watermark = wm
+	return d, nil
+}
+
+func newHook(c config.WebhookConfig) (hook, error) {
+	if c.Name == "" {
+		return hook{}, errors.New("name is required")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return hook{}, fmt.Errorf("%s: url must be an absolute http or https URL", c.Name)
+	}
+	if c.MaxAttempts < 0 {
+		return hook{}, fmt.Errorf("%s: max_attempts must not be negative", c.Name)
+	}
+	fields := Fields
+	if len(c.Fields) > 0 {
+		fields = []string{"id"}
+		for _, f := range c.Fields {
+			if !slices.Contains(Fields, f) {
+				return hook{}, fmt.Errorf("%s: unknown field %q", c.Name, f)
+			}
+			if !slices.Contains(fields, f) {
+				fields = append(fields, f)
+			}
+		}
+	}
+	attempts := c.MaxAttempts
+	if attempts == 0 {
+		attempts = defaultMaxAttempts
+	}
+	q := search.Parse(c.Query)
+	q.HideDeleted = true
+	return hook{cfg: c, query: q, fields: fields, maxAttempts: attempts}, nil
+}
+
+// Run is a scheduler.PostSyncHook. It delivers every message added since
+// the previous run that matches a webhook's query. Delivery failures are
+// logged and not retried on later runs; the watermark always advances so
+// one unreachable endpoint can't cause duplicate sends to the others.
+func (d *Dispatcher) Run(ctx context.Context, account string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	high, err := d.store.MaxMessageID()
+	if err != nil {
+		d.logger.Error("webhook: failed to read message watermark", "error", err)
+		return
+	}
+	if high <= d.watermark {
+		return
+	}
+	low := d.watermark
+	d.watermark = high
+
+	for _, h := range d.hooks {
+		if ctx.Err() != nil {
+			return
+		}
+		msgs, err := d.matches(h, low, high)
+		if err != nil {
+			d.logger.Error("webhook: search failed", "webhook", h.cfg.Name, "error", err)
+			continue
+		}
+		for batch := range slices.Chunk(msgs, batchSize) {
+			if err := d.deliver(ctx, h, account, batch); err != nil {
+				d.logger.Error("webhook: delivery failed",
+					"webhook", h.cfg.Name, "messages", len(batch), "error", err)
+				break
+			}
+		}
+	}
+}
+
+// matches returns the hook's matches with low < id <= high, oldest first.
+// Messages inserted after high was read are left for the next run.
+func (d *Dispatcher) matches(h hook, low, high int64) ([]store.APIMessage, error) {
+	q := *h.query
+	q.AfterMessageID = low
+	var out []store.APIMessage
+	for offset := 0; len(out) < maxMatchesPerRun; offset += batchSize {
+		page, _, err := d.store.SearchMessagesQuery(&q, offset, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range page {
+			if m.ID <= high {
+				out = append(out, m)
+			}
+		}
+		if len(page) < batchSize {
+			break
+		}
+	}
+	slices.SortFunc(out, func(a, b store.APIMessage) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	if len(out) > maxMatchesPerRun {
+		d.logger.Warn("webhook: too many matches, truncating",
+			"webhook", h.cfg.Name, "matches", len(out), "limit", maxMatchesPerRun)
+		out = out[:maxMatchesPerRun]
+	}
+	return out, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, h hook, account string, msgs []store.APIMessage) error {
+	payload := Payload{
+		Event:     EventMessagesMatched,
+		Webhook:   h.cfg.Name,
+		Query:     h.cfg.Query,
+		Account:   account,
+		Timestamp: d.now().UTC(),
+		Messages:  make([]map[string]any, len(msgs)),
+	}
+	for i := range msgs {
+		payload.Messages[i] = messageFields(&msgs[i], h.fields)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal payload: %w", err)
+	}
+	deliveryID := newDeliveryID()
+
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, h, body, deliveryID)
+		if err == nil {
+			d.logger.Info("webhook delivered",
+				"webhook", h.cfg.Name, "messages", len(msgs), "attempt", attempt)
+			return nil
+		}
+		if !retry || attempt >= h.maxAttempts {
+			return fmt.Errorf("after %d attempt(s): %w", attempt, err)
+		}
+		d.logger.Warn("webhook delivery failed, retrying",
+			"webhook", h.cfg.Name, "attempt", attempt, "retry_in", delay, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post sends one attempt. retry reports whether a failure is transient:
+// network errors, 429, and 5xx responses are retried; other statuses
+// indicate a misconfigured endpoint and are not.
+func (d *Dispatcher) post(ctx context.Context, h hook, body []byte, deliveryID string) (retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "msgvault-webhook")
+	req.Header.Set("X-Msgvault-Event", EventMessagesMatched)
+	req.Header.Set("X-Msgvault-Delivery", deliveryID)
+	req.Header.Set(TimestampHeader, ts)
+	if h.cfg.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.cfg.Secret, ts, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// Sign returns the SignatureHeader value for a request body. Receivers
+// recompute it from the TimestampHeader value and the raw body, compare
+// with hmac.Equal, and should reject stale timestamps to limit replay.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func messageFields(m *store.APIMessage, fields []string) map[string]any {
+	out := make(map[string]any, len(fields))
+	for _, f := range fields {
+		switch f {
+		case "id":
+			out[f] = m.ID
+		case "conversation_id":
+			out[f] = m.ConversationID
+		case "subject":
+			out[f] = m.Subject
+		case "from":
+			out[f] = m.From
+		case "to":
+			out[f] = nonNil(m.To)
+		case "sent_at":
+			out[f] = m.SentAt.UTC().Format(time.RFC3339)
+		case "snippet":
+			out[f] = m.Snippet
+		case "labels":
+			out[f] = nonNil(m.Labels)
+		case "has_attachments":
+			out[f] = m.HasAttachments
+		case "size_bytes":
+			out[f] = m.SizeEstimate
+		}
+	}
+	return out
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func newDeliveryID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wesm/msgvault/internal/config"
+	"github.com/wesm/msgvault/internal/search"
+	"github.com/wesm/msgvault/internal/store"
+)
+
+// fakeStore matches every message by subject substring; only the
+// AfterMessageID filter and paging are exercised here.
+type fakeStore struct {
+	mu       sync.Mutex
+	messages []store.APIMessage
+}
+
+func (f *fakeStore) add(id int64, subject string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, store.APIMessage{
+		ID:      id,
+		Subject: subject,
+		From:    "bob@example.com",
+		SentAt:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+}
+
+func (f *fakeStore) MaxMessageID() (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m int64
+	for _, msg := range f.messages {
+		m = max(m, msg.ID)
+	}
+	return m, nil
+}
+
+func (f *fakeStore) SearchMessagesQuery(q *search.Query, offset, limit int) ([]store.APIMessage, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var hits []store.APIMessage
+	for _, m := range f.messages {
+		if m.ID <= q.AfterMessageID {
+			continue
+		}
+		if len(q.SubjectTerms) > 0 && !strings.Contains(m.Subject, q.SubjectTerms[0]) {
+			continue
+		}
+		hits = append(hits, m)
+	}
+	if offset >= len(hits) {
+		return nil, int64(len(hits)), nil
+	}
+	return hits[offset:min(offset+limit, len(hits))], int64(len(hits)), nil
+}
+
+type receiver struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+	statuses []int // status per call; 200 once exhausted
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusOK
+	if n := len(r.requests); n <= len(r.statuses) {
+		status = r.statuses[n-1]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestDispatcher(t *testing.T, st Store, cfgs ...config.WebhookConfig) *Dispatcher {
+	t.Helper()
+	d, err := New(cfgs, st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	d.retryDelay = time.Millisecond
+	d.now = func() time.Time { return time.Unix(1700000000, 0) }
+	return d
+}
+
+func TestNew_Validation(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfgs    []config.WebhookConfig
+		wantErr string
+	}{
+		{"valid", []config.WebhookConfig{{Name: "a", URL: "https://example.com/hook"}}, ""},
+		{"missing name", []config.WebhookConfig{{URL: "https://example.com/hook"}}, "name is required"},
+		{"bad scheme", []config.WebhookConfig{{Name: "a", URL: "ftp://example.com"}}, "http or https"},
+		{"relative url", []config.WebhookConfig{{Name: "a", URL: "/hook"}}, "http or https"},
+		{"unknown field", []config.WebhookConfig{{Name: "a", URL: "http://example.com", Fields: []string{"body"}}}, `unknown field "body"`},
+		{"negative attempts", []config.WebhookConfig{{Name: "a", URL: "http://example.com", MaxAttempts: -1}}, "max_attempts"},
+		{"duplicate name", []config.WebhookConfig{
+			{Name: "a", URL: "http://example.com/1"},
+			{Name: "a", URL: "http://example.com/2"},
+		}, "duplicate name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfgs, &fakeStore{}, slog.Default())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRun_DeliversOnlyNewMatches(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	st := &fakeStore{}
+	st.add(1, "Invoice from before startup")
+	d := newTestDispatcher(t, st, config.WebhookConfig{
+		Name:   "invoices",
+		URL:    srv.URL,
+		Query:  "subject:Invoice",
+		Secret: "s3cret",
+	})
+
+	st.add(2, "Invoice 42")
+	st.add(3, "Lunch plans")
+	d.Run(context.Background(), "alice@example.com")
+
+	// A second run with nothing new must not resend.
+	d.Run(context.Background(), "alice@example.com")
+
+	if len(rcv.requests) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(rcv.requests))
+	}
+	req := rcv.requests[0]
+	body := rcv.bodies[0]
+
+	if got := req.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := req.Header.Get("X-Msgvault-Event"); got != EventMessagesMatched {
+		t.Errorf("event header = %q", got)
+	}
+	ts := req.Header.Get(TimestampHeader)
+	if ts != "1700000000" {
+		t.Errorf("timestamp = %q", ts)
+	}
+	if got, want := req.Header.Get(SignatureHeader), Sign("s3cret", ts, body); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if p.Webhook != "invoices" || p.Account != "alice@example.com" || p.Query != "subject:Invoice" {
+		t.Errorf("payload header fields = %+v", p)
+	}
+	if len(p.Messages) != 1 || p.Messages[0]["id"] != float64(2) || p.Messages[0]["subject"] != "Invoice 42" {
+		t.Errorf("messages = %v, want only message 2", p.Messages)
+	}
+}
+
+func TestRun_FieldsSelection(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	st := &fakeStore{}
+	d := newTestDispatcher(t, st, config.WebhookConfig{
+		Name:   "slim",
+		URL:    srv.URL,
+		Fields: []string{"subject"},
+	})
+	st.add(1, "Hello")
+	d.Run(context.Background(), "alice@example.com")
+
+	if len(rcv.bodies) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(rcv.bodies))
+	}
+	if got := rcv.requests[0].Header.Get(SignatureHeader); got != "" {
+		t.Errorf("signature set without secret: %q", got)
+	}
+	var p Payload
+	if err := json.Unmarshal(rcv.bodies[0], &p); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	msg := p.Messages[0]
+	if len(msg) != 2 || msg["id"] != float64(1) || msg["subject"] != "Hello" {
+		t.Errorf("message = %v, want only id and subject", msg)
+	}
+}
+
+func TestRun_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxAttempts  int
+		wantAttempts int
+	}{
+		{"success after transient errors", []int{500, 429}, 0, 3},
+		{"gives up after max attempts", []int{503, 503, 503, 503}, 2, 2},
+		{"client error not retried", []int{400}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv := &receiver{statuses: tt.statuses}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			st := &fakeStore{}
+			d := newTestDispatcher(t, st, config.WebhookConfig{
+				Name:        "retry",
+				URL:         srv.URL,
+				MaxAttempts: tt.maxAttempts,
+			})
+			st.add(1, "Hello")
+			d.Run(context.Background(), "alice@example.com")
+
+			if len(rcv.requests) != tt.wantAttempts {
+				t.Fatalf("attempts = %d, want %d", len(rcv.requests), tt.wantAttempts)
+			}
+			id := rcv.requests[0].Header.Get("X-Msgvault-Delivery")
+			for _, r := range rcv.requests[1:] {
+				if r.Header.Get("X-Msgvault-Delivery") != id {
+					t.Errorf("retries must reuse the delivery ID")
+				}
+			}
+		})
+	}
+}
+
+func TestRun_BatchesLargeMatchSets(t *testing.T) {
+	rcv := &receiver{}
+	srv := httptest.NewServer(rcv)
+	defer srv.Close()
+
+	st := &fakeStore{}
+	d := newTestDispatcher(t, st, config.WebhookConfig{Name: "all", URL: srv.URL})
+	for i := int64(1); i <= batchSize+5; i++ {
+		st.add(i, "Message")
+	}
+	d.Run(context.Background(), "alice@example.com")
+
+	if len(rcv.bodies) != 2 {
+		t.Fatalf("deliveries = %d, want 2", len(rcv.bodies))
+	}
+	var first, second Payload
+	_ = json.Unmarshal(rcv.bodies[0], &first)
+	_ = json.Unmarshal(rcv.bodies[1], &second)
+	if len(first.Messages) != batchSize || len(second.Messages) != 5 {
+		t.Errorf("batch sizes = %d, %d", len(first.Messages), len(second.Messages))
+	}
+	if first.Messages[0]["id"] != float64(1) {
+		t.Errorf("first batch should start with the oldest message, got %v", first.Messages[0]["id"])
+	}
+}
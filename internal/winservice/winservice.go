@@ -0,0 +1,19 @@
+// Package winservice runs the msgvault daemon as a Windows service and
+// registers it with the service control manager. On other platforms
+// Run calls the daemon directly and Install and Uninstall return
+// ErrUnsupported; use systemd or launchd there.
+package winservice
+
+import "errors"
+
+// Name is the service name registered with the service control manager.
+const Name = "msgvault"
+
+const (
+	displayName = "msgvault"
+	description = "Archives email and serves the msgvault API with scheduled syncs."
+)
+
+// ErrUnsupported is returned by Install and Uninstall on platforms
+// without a Windows service control manager.
+var ErrUnsupported = errors.New("windows services are only available on Windows")
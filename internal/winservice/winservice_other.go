@@ -0,0 +1,21 @@
+//go:build !windows
+
+package winservice
+
+import "context"
+
+// IsService reports whether the process was started by the Windows
+// service control manager. It is always false on this platform.
+func IsService() bool { return false }
+
+// Run calls run with ctx. Only Windows has a service control manager
+// to report to.
+func Run(ctx context.Context, run func(context.Context) error) error {
+	return run(ctx)
+}
+
+// Install returns ErrUnsupported on this platform.
+func Install(exePath string, args []string) error { return ErrUnsupported }
+
+// Uninstall returns ErrUnsupported on this platform.
+func Uninstall() error { return ErrUnsupported }
@@ -0,0 +1,142 @@
+//go:build windows
+
+package winservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// stopTimeout bounds how long Uninstall waits for a running service
+// to stop before deleting it.
+const stopTimeout = 45 * time.Second
+
+// IsService reports whether the process was started by the Windows
+// service control manager.
+func IsService() bool {
+	ok, err := svc.IsWindowsService()
+	return err == nil && ok
+}
+
+// Run runs the daemon under the service control manager. ctx passed
+// to run is cancelled when the service is stopped or the machine shuts
+// down, which the daemon already treats like Ctrl+C.
+func Run(ctx context.Context, run func(context.Context) error) error {
+	h := &handler{ctx: ctx, run: run}
+	if err := svc.Run(Name, h); err != nil {
+		return fmt.Errorf("run service: %w", err)
+	}
+	return h.err
+}
+
+type handler struct {
+	ctx context.Context
+	run func(context.Context) error
+	err error
+}
+
+func (h *handler) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- h.run(ctx) }()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case h.err = <-done:
+			changes <- svc.Status{State: svc.StopPending}
+			if h.err != nil {
+				// A service-specific exit code makes the SCM log the
+				// failure and apply the configured recovery actions.
+				return true, 1
+			}
+			return false, 0
+		case c := <-r:
+			switch c.Cmd {
+			case svc.Interrogate:
+				changes <- c.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
+
+// Install registers exePath, run with args, as an automatically
+// started service under the LocalSystem account. Configure the
+// account in services.msc to run as a specific user so the service
+// uses that user's msgvault home and credentials.
+func Install(exePath string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	if s, err := m.OpenService(Name); err == nil {
+		_ = s.Close()
+		return fmt.Errorf("service %q is already installed", Name)
+	}
+	s, err := m.CreateService(Name, exePath, mgr.Config{
+		DisplayName: displayName,
+		Description: description,
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("create service: %w", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	// Restart after a crash: after five seconds, then after a minute.
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return fmt.Errorf("set recovery actions: %w", err)
+	}
+	return nil
+}
+
+// Uninstall stops the service if it is running and removes it.
+func Uninstall() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer func() { _ = m.Disconnect() }()
+
+	s, err := m.OpenService(Name)
+	if err != nil {
+		return fmt.Errorf("service %q is not installed", Name)
+	}
+	defer func() { _ = s.Close() }()
+
+	status, err := s.Control(svc.Stop)
+	if err != nil && !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return fmt.Errorf("stop service: %w", err)
+	}
+	for deadline := time.Now().Add(stopTimeout); err == nil && status.State != svc.Stopped; {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within %s", stopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return fmt.Errorf("query service: %w", err)
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("delete service: %w", err)
+	}
+	return nil
+}
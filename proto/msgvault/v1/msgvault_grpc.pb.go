@@ -0,0 +1,299 @@
+// msgvault gRPC API.
+//
+// Served by `msgvault serve` when [server] grpc_port is set. Authentication
+// uses the same [server] api_key as the REST API, sent as gRPC metadata
+// "authorization: Bearer <key>" or "x-api-key: <key>".
+//
+// Regenerate the Go bindings with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: msgvault/v1/msgvault.proto
+
+package msgvaultv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MsgvaultService_GetStats_FullMethodName     = "/msgvault.v1.MsgvaultService/GetStats"
+	MsgvaultService_Search_FullMethodName       = "/msgvault.v1.MsgvaultService/Search"
+	MsgvaultService_GetMessage_FullMethodName   = "/msgvault.v1.MsgvaultService/GetMessage"
+	MsgvaultService_ListAccounts_FullMethodName = "/msgvault.v1.MsgvaultService/ListAccounts"
+	MsgvaultService_TriggerSync_FullMethodName  = "/msgvault.v1.MsgvaultService/TriggerSync"
+)
+
+// MsgvaultServiceClient is the client API for MsgvaultService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MsgvaultServiceClient interface {
+	// GetStats returns archive-wide counts.
+	GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error)
+	// Search runs a Gmail-style query and streams matching message summaries
+	// in relevance order. Clients may cancel the stream at any point.
+	Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MessageSummary], error)
+	// GetMessage returns a single message with body and attachment metadata.
+	GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// ListAccounts returns the accounts scheduled for sync.
+	ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error)
+	// TriggerSync starts an incremental sync for a scheduled account. It
+	// returns once the sync has been queued, not when it completes.
+	TriggerSync(ctx context.Context, in *TriggerSyncRequest, opts ...grpc.CallOption) (*TriggerSyncResponse, error)
+}
+
+type msgvaultServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMsgvaultServiceClient(cc grpc.ClientConnInterface) MsgvaultServiceClient {
+	return &msgvaultServiceClient{cc}
+}
+
+func (c *msgvaultServiceClient) GetStats(ctx context.Context, in *GetStatsRequest, opts ...grpc.CallOption) (*GetStatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatsResponse)
+	err := c.cc.Invoke(ctx, MsgvaultService_GetStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *msgvaultServiceClient) Search(ctx context.Context, in *SearchRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[MessageSummary], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MsgvaultService_ServiceDesc.Streams[0], MsgvaultService_Search_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SearchRequest, MessageSummary]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MsgvaultService_SearchClient = grpc.ServerStreamingClient[MessageSummary]
+
+func (c *msgvaultServiceClient) GetMessage(ctx context.Context, in *GetMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Message)
+	err := c.cc.Invoke(ctx, MsgvaultService_GetMessage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *msgvaultServiceClient) ListAccounts(ctx context.Context, in *ListAccountsRequest, opts ...grpc.CallOption) (*ListAccountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAccountsResponse)
+	err := c.cc.Invoke(ctx, MsgvaultService_ListAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *msgvaultServiceClient) TriggerSync(ctx context.Context, in *TriggerSyncRequest, opts ...grpc.CallOption) (*TriggerSyncResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TriggerSyncResponse)
+	err := c.cc.Invoke(ctx, MsgvaultService_TriggerSync_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MsgvaultServiceServer is the server API for MsgvaultService service.
+// All implementations must embed UnimplementedMsgvaultServiceServer
+// for forward compatibility.
+type MsgvaultServiceServer interface {
+	// GetStats returns archive-wide counts.
+	GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error)
+	// Search runs a Gmail-style query and streams matching message summaries
+	// in relevance order. Clients may cancel the stream at any point.
+	Search(*SearchRequest, grpc.ServerStreamingServer[MessageSummary]) error
+	// GetMessage returns a single message with body and attachment metadata.
+	GetMessage(context.Context, *GetMessageRequest) (*Message, error)
+	// ListAccounts returns the accounts scheduled for sync.
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
+	// TriggerSync starts an incremental sync for a scheduled account. It
+	// returns once the sync has been queued, not when it completes.
+	TriggerSync(context.Context, *TriggerSyncRequest) (*TriggerSyncResponse, error)
+	mustEmbedUnimplementedMsgvaultServiceServer()
+}
+
+// UnimplementedMsgvaultServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMsgvaultServiceServer struct{}
+
+func (UnimplementedMsgvaultServiceServer) GetStats(context.Context, *GetStatsRequest) (*GetStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStats not implemented")
+}
+func (UnimplementedMsgvaultServiceServer) Search(*SearchRequest, grpc.ServerStreamingServer[MessageSummary]) error {
+	return status.Errorf(codes.Unimplemented, "method Search not implemented")
+}
+func (UnimplementedMsgvaultServiceServer) GetMessage(context.Context, *GetMessageRequest) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMessage not implemented")
+}
+func (UnimplementedMsgvaultServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
+func (UnimplementedMsgvaultServiceServer) TriggerSync(context.Context, *TriggerSyncRequest) (*TriggerSyncResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TriggerSync not implemented")
+}
+func (UnimplementedMsgvaultServiceServer) mustEmbedUnimplementedMsgvaultServiceServer() {}
+func (UnimplementedMsgvaultServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeMsgvaultServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MsgvaultServiceServer will
+// result in compilation errors.
+type UnsafeMsgvaultServiceServer interface {
+	mustEmbedUnimplementedMsgvaultServiceServer()
+}
+
+func RegisterMsgvaultServiceServer(s grpc.ServiceRegistrar, srv MsgvaultServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMsgvaultServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MsgvaultService_ServiceDesc, srv)
+}
+
+func _MsgvaultService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgvaultServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MsgvaultService_GetStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgvaultServiceServer).GetStats(ctx, req.(*GetStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MsgvaultService_Search_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MsgvaultServiceServer).Search(m, &grpc.GenericServerStream[SearchRequest, MessageSummary]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MsgvaultService_SearchServer = grpc.ServerStreamingServer[MessageSummary]
+
+func _MsgvaultService_GetMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgvaultServiceServer).GetMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MsgvaultService_GetMessage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgvaultServiceServer).GetMessage(ctx, req.(*GetMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MsgvaultService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgvaultServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MsgvaultService_ListAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgvaultServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MsgvaultService_TriggerSync_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TriggerSyncRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgvaultServiceServer).TriggerSync(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MsgvaultService_TriggerSync_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgvaultServiceServer).TriggerSync(ctx, req.(*TriggerSyncRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MsgvaultService_ServiceDesc is the grpc.ServiceDesc for MsgvaultService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MsgvaultService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "msgvault.v1.MsgvaultService",
+	HandlerType: (*MsgvaultServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStats",
+			Handler:    _MsgvaultService_GetStats_Handler,
+		},
+		{
+			MethodName: "GetMessage",
+			Handler:    _MsgvaultService_GetMessage_Handler,
+		},
+		{
+			MethodName: "ListAccounts",
+			Handler:    _MsgvaultService_ListAccounts_Handler,
+		},
+		{
+			MethodName: "TriggerSync",
+			Handler:    _MsgvaultService_TriggerSync_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Search",
+			Handler:       _MsgvaultService_Search_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "msgvault/v1/msgvault.proto",
+}